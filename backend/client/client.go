@@ -0,0 +1,171 @@
+// Package client calls a Codeburg server over its HTTP API, for scripts and
+// tools that run outside it. Authenticate with an API token (see
+// /api/tokens) whose scopes cover the calls made:
+//
+//	c := client.New("https://codeburg.example.com", os.Getenv("CODEBURG_TOKEN"))
+//	tasks, _, err := c.ListTasks(ctx, client.TaskQuery{ProjectID: id})
+//
+// Errors the server reports are *Error values, which errors.Is matches
+// against service.ErrNotFound, service.ErrInvalid, service.ErrConflict and
+// service.ErrForbidden.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// The records the API takes and returns.
+type (
+	Project             = db.Project
+	UpdateProjectInput  = db.UpdateProjectInput
+	Task                = service.Task
+	TaskStatus          = service.TaskStatus
+	CreateTaskInput     = service.CreateTaskInput
+	UpdateTaskInput     = service.UpdateTaskInput
+	TaskUpdate          = service.TaskUpdate
+	Session             = service.Session
+	StartSessionOptions = service.StartSessionOptions
+	GitStatus           = service.GitStatus
+	GitFileEntry        = service.GitFileEntry
+	DiffOptions         = service.DiffOptions
+	CommitOptions       = service.CommitOptions
+	GitCommit           = service.GitCommit
+)
+
+// nextCursorHeader carries the cursor of a list's next page.
+const nextCursorHeader = "X-Next-Cursor"
+
+// Client calls one Codeburg server. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	token   string
+
+	// HTTPClient sends the requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// New returns a client for the server at baseURL (e.g.
+// "http://localhost:8080") that authenticates with token.
+func New(baseURL, token string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token}
+}
+
+// Error is an error response from the server.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("codeburg: %s (%d)", e.Message, e.StatusCode)
+}
+
+// Unwrap maps the status to the service error of the same kind.
+func (e *Error) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return service.ErrNotFound
+	case http.StatusBadRequest:
+		return service.ErrInvalid
+	case http.StatusConflict:
+		return service.ErrConflict
+	case http.StatusForbidden:
+		return service.ErrForbidden
+	}
+	return nil
+}
+
+// Page selects a page of a list: up to Limit items (the server's default
+// when 0) after Cursor, the cursor a previous page returned.
+type Page struct {
+	Cursor string
+	Limit  int
+}
+
+func (p Page) encode(q url.Values) {
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	if p.Limit > 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+}
+
+// do sends a request with body encoded as JSON, unless it is nil, and
+// decodes the response into out, unless it is nil. It returns the response
+// headers.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) (http.Header, error) {
+	u := c.baseURL + "/api" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var payload struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &payload) == nil && payload.Error != "" {
+			apiErr.Message = payload.Error
+		} else {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return resp.Header, apiErr
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+			return resp.Header, fmt.Errorf("decode %s %s response: %w", method, path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// pathID escapes an ID for a URL path.
+func pathID(id string) string {
+	return url.PathEscape(id)
+}
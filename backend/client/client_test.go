@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/service"
+)
+
+func TestClient(t *testing.T) {
+	var got []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tasks", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RawQuery)
+		w.Header().Set(nextCursorHeader, "next-page")
+		json.NewEncoder(w).Encode([]Task{{ID: "t1", Title: "Fix login"}})
+	})
+	mux.HandleFunc("POST /api/projects/{id}/tasks", func(w http.ResponseWriter, r *http.Request) {
+		var input CreateTaskInput
+		json.NewDecoder(r.Body).Decode(&input)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Task{ID: "t2", ProjectID: r.PathValue("id"), Title: input.Title})
+	})
+	mux.HandleFunc("GET /api/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"task not found"}`))
+	})
+	mux.HandleFunc("POST /api/sessions/{id}/message", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, r.PathValue("id")+":"+body["content"])
+		w.Write([]byte(`{"status":"sent"}`))
+	})
+	mux.HandleFunc("GET /api/sessions/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RawQuery)
+		w.Write([]byte(`[{"id":"m2","kind":"tool-call","tool":{"name":"Bash","state":"completed","input":{"command":"ls"}}}]`))
+	})
+	mux.HandleFunc("GET /api/tasks/{id}/git/diff", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RawQuery)
+		w.Write([]byte(`{"diff":"diff --git a/x b/x"}`))
+	})
+	mux.HandleFunc("POST /api/tasks/{id}/git/push", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer cbt_secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(srv.URL+"/", "cbt_secret")
+
+	tasks, next, err := c.ListTasks(ctx, TaskQuery{ProjectID: "p1", Category: "done", Page: Page{Limit: 5}})
+	if err != nil || len(tasks) != 1 || tasks[0].Title != "Fix login" || next != "next-page" {
+		t.Fatalf("list tasks: %+v %q %v", tasks, next, err)
+	}
+	if got[0] != "category=done&limit=5&project=p1" {
+		t.Errorf("unexpected query %q", got[0])
+	}
+
+	task, err := c.CreateTask(ctx, CreateTaskInput{ProjectID: "p1", Title: "Ship export"})
+	if err != nil || task.ID != "t2" || task.ProjectID != "p1" || task.Title != "Ship export" {
+		t.Errorf("create task: %+v %v", task, err)
+	}
+
+	_, err = c.GetTask(ctx, "missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "task not found" {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("expected errors.Is(err, service.ErrNotFound) for %v", err)
+	}
+
+	if err := c.SendMessage(ctx, "s1", "go on"); err != nil || got[1] != "s1:go on" {
+		t.Errorf("send message: %v %q", err, got[1])
+	}
+	diff, err := c.GitDiff(ctx, "t1", DiffOptions{Base: true, File: "x"})
+	if err != nil || diff != "diff --git a/x b/x" || got[2] != "base=true&file=x" {
+		t.Errorf("git diff: %q %v %q", diff, err, got[2])
+	}
+	if err := c.GitPush(ctx, "t1", false); err != nil {
+		t.Errorf("git push: %v", err)
+	}
+	messages, next, err := c.ListMessages(ctx, "s1", Page{Cursor: MessageCursor("m1")})
+	if err != nil || len(messages) != 1 || messages[0].Tool == nil || messages[0].Tool.Name != "Bash" || next != "" {
+		t.Errorf("list messages: %+v %q %v", messages, next, err)
+	}
+	if got[3] != "cursor="+MessageCursor("m1") {
+		t.Errorf("unexpected query %q", got[3])
+	}
+
+	if _, err := New(srv.URL, "wrong").ListProjects(ctx, false); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %v", err)
+	}
+}
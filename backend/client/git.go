@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GitStatus returns the state of a task's worktree.
+func (c *Client) GitStatus(ctx context.Context, taskID string) (*GitStatus, error) {
+	var status GitStatus
+	if _, err := c.do(ctx, http.MethodGet, "/tasks/"+pathID(taskID)+"/git/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GitDiff returns a diff of a task's worktree as unified diff text.
+func (c *Client) GitDiff(ctx context.Context, taskID string, opts DiffOptions) (string, error) {
+	query := url.Values{}
+	if opts.Commit != "" {
+		query.Set("commit", opts.Commit)
+	}
+	if opts.Base {
+		query.Set("base", "true")
+	}
+	if opts.Staged {
+		query.Set("staged", "true")
+	}
+	if opts.File != "" {
+		query.Set("file", opts.File)
+	}
+	var resp struct {
+		Diff string `json:"diff"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/tasks/"+pathID(taskID)+"/git/diff", query, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Diff, nil
+}
+
+// GitCommit commits the staged changes in a task's worktree.
+func (c *Client) GitCommit(ctx context.Context, taskID string, opts CommitOptions) (*GitCommit, error) {
+	var commit GitCommit
+	if _, err := c.do(ctx, http.MethodPost, "/tasks/"+pathID(taskID)+"/git/commit", nil, opts, &commit); err != nil {
+		return nil, err
+	}
+	return &commit, nil
+}
+
+// GitPull fast-forwards a task's branch.
+func (c *Client) GitPull(ctx context.Context, taskID string) error {
+	_, err := c.do(ctx, http.MethodPost, "/tasks/"+pathID(taskID)+"/git/pull", nil, nil, nil)
+	return err
+}
+
+// GitPush pushes a task's branch and sets its upstream. It needs a token
+// with the git:push scope.
+func (c *Client) GitPush(ctx context.Context, taskID string, force bool) error {
+	body := struct {
+		Force bool `json:"force,omitempty"`
+	}{force}
+	_, err := c.do(ctx, http.MethodPost, "/tasks/"+pathID(taskID)+"/git/push", nil, body, nil)
+	return err
+}
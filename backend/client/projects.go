@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateProjectInput describes a project to add: an existing repository at
+// Path, or one cloned from GitURL.
+type CreateProjectInput struct {
+	Name          string  `json:"name,omitempty"`
+	Path          string  `json:"path,omitempty"`
+	GitURL        string  `json:"githubUrl,omitempty"` // GitHub, GitLab or Gitea
+	DefaultBranch *string `json:"defaultBranch,omitempty"`
+	SetupScript   *string `json:"setupScript,omitempty"`
+}
+
+// ListProjects returns the projects, or the archived ones when archived is
+// true.
+func (c *Client) ListProjects(ctx context.Context, archived bool) ([]*Project, error) {
+	query := url.Values{}
+	if archived {
+		query.Set("archived", "true")
+	}
+	var projects []*Project
+	_, err := c.do(ctx, http.MethodGet, "/projects", query, nil, &projects)
+	return projects, err
+}
+
+func (c *Client) GetProject(ctx context.Context, id string) (*Project, error) {
+	var project Project
+	if _, err := c.do(ctx, http.MethodGet, "/projects/"+pathID(id), nil, nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// CreateProject adds a project. A clone returns once it has finished.
+func (c *Client) CreateProject(ctx context.Context, input CreateProjectInput) (*Project, error) {
+	body := struct {
+		CreateProjectInput
+		Wait bool `json:"wait,omitempty"`
+	}{input, input.GitURL != ""}
+	var project Project
+	if _, err := c.do(ctx, http.MethodPost, "/projects", nil, body, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (c *Client) UpdateProject(ctx context.Context, id string, input UpdateProjectInput) (*Project, error) {
+	var project Project
+	if _, err := c.do(ctx, http.MethodPatch, "/projects/"+pathID(id), nil, input, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// DeleteProject removes a project from Codeburg. Its repository stays on
+// disk.
+func (c *Client) DeleteProject(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/projects/"+pathID(id), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// ListSessions returns a page of a task's sessions, newest first, and the
+// cursor of the next page.
+func (c *Client) ListSessions(ctx context.Context, taskID string, page Page) ([]*Session, string, error) {
+	return c.listSessions(ctx, "/tasks/"+pathID(taskID)+"/sessions", url.Values{}, page)
+}
+
+// SearchSessions returns a page of sessions whose title matches q, in one
+// project or all of them when projectID is "".
+func (c *Client) SearchSessions(ctx context.Context, projectID, q string, page Page) ([]*Session, string, error) {
+	query := url.Values{}
+	if projectID != "" {
+		query.Set("projectId", projectID)
+	}
+	if q != "" {
+		query.Set("q", q)
+	}
+	return c.listSessions(ctx, "/sessions", query, page)
+}
+
+func (c *Client) listSessions(ctx context.Context, path string, query url.Values, page Page) ([]*Session, string, error) {
+	page.encode(query)
+	var sessions []*Session
+	header, err := c.do(ctx, http.MethodGet, path, query, nil, &sessions)
+	if err != nil {
+		return nil, "", err
+	}
+	return sessions, header.Get(nextCursorHeader), nil
+}
+
+func (c *Client) GetSession(ctx context.Context, id string) (*Session, error) {
+	var session Session
+	if _, err := c.do(ctx, http.MethodGet, "/sessions/"+pathID(id), nil, nil, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// StartSession starts an agent session in a task's worktree.
+func (c *Client) StartSession(ctx context.Context, taskID string, opts StartSessionOptions) (*Session, error) {
+	return c.startSession(ctx, "/tasks/"+pathID(taskID)+"/sessions", opts)
+}
+
+// StartProjectSession starts an agent session in a project's directory,
+// outside any task.
+func (c *Client) StartProjectSession(ctx context.Context, projectID string, opts StartSessionOptions) (*Session, error) {
+	return c.startSession(ctx, "/projects/"+pathID(projectID)+"/sessions", opts)
+}
+
+func (c *Client) startSession(ctx context.Context, path string, opts StartSessionOptions) (*Session, error) {
+	var session Session
+	if _, err := c.do(ctx, http.MethodPost, path, nil, opts, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SendMessage sends user input to a running session.
+func (c *Client) SendMessage(ctx context.Context, id, content string) error {
+	body := struct {
+		Content string `json:"content"`
+	}{content}
+	_, err := c.do(ctx, http.MethodPost, "/sessions/"+pathID(id)+"/message", nil, body, nil)
+	return err
+}
+
+// StopSession stops a session and marks it completed.
+func (c *Client) StopSession(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodPost, "/sessions/"+pathID(id)+"/stop", nil, nil, nil)
+	return err
+}
+
+// Message is an entry in a chat session's transcript.
+type Message struct {
+	ID         string       `json:"id"`
+	Seq        int64        `json:"seq,omitempty"`
+	Kind       string       `json:"kind"` // user-text, agent-text, tool-call, permission-request, system or result
+	Role       string       `json:"role,omitempty"`
+	Text       string       `json:"text,omitempty"`
+	IsThinking bool         `json:"isThinking,omitempty"`
+	Tool       *MessageTool `json:"tool,omitempty"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+// MessageTool is the tool call of a tool-call message.
+type MessageTool struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state"`
+	Input       any    `json:"input,omitempty"`
+	IsError     bool   `json:"isError,omitempty"`
+}
+
+// ListMessages returns a page of a chat session's transcript, oldest first,
+// and the cursor of the next page.
+func (c *Client) ListMessages(ctx context.Context, id string, page Page) ([]*Message, string, error) {
+	query := url.Values{}
+	page.encode(query)
+	var messages []*Message
+	header, err := c.do(ctx, http.MethodGet, "/sessions/"+pathID(id)+"/messages", query, nil, &messages)
+	if err != nil {
+		return nil, "", err
+	}
+	return messages, header.Get(nextCursorHeader), nil
+}
+
+// MessageCursor returns the cursor of the messages after the one with id,
+// to read a transcript as it grows.
+func MessageCursor(id string) string {
+	return db.EncodeCursor(id)
+}
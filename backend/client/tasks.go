@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// TaskQuery filters ListTasks. Empty fields match every task.
+type TaskQuery struct {
+	ProjectID string
+	Status    string // a board column
+	Category  string // backlog, in_progress, in_review or done
+	View      string // a saved view, by ID or name
+	Page      Page
+}
+
+// ListTasks returns a page of tasks and the cursor of the next page ("" on
+// the last one).
+func (c *Client) ListTasks(ctx context.Context, q TaskQuery) ([]*Task, string, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"project":  q.ProjectID,
+		"status":   q.Status,
+		"category": q.Category,
+		"view":     q.View,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	q.Page.encode(query)
+
+	var tasks []*Task
+	header, err := c.do(ctx, http.MethodGet, "/tasks", query, nil, &tasks)
+	if err != nil {
+		return nil, "", err
+	}
+	return tasks, header.Get(nextCursorHeader), nil
+}
+
+func (c *Client) GetTask(ctx context.Context, id string) (*Task, error) {
+	var task Task
+	if _, err := c.do(ctx, http.MethodGet, "/tasks/"+pathID(id), nil, nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// CreateTask adds a task to input.ProjectID's board.
+func (c *Client) CreateTask(ctx context.Context, input CreateTaskInput) (*Task, error) {
+	var task Task
+	if _, err := c.do(ctx, http.MethodPost, "/projects/"+pathID(input.ProjectID)+"/tasks", nil, input, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// UpdateTask changes a task. Moving it to another column runs the project's
+// workflow; the returned TaskUpdate says what it did.
+func (c *Client) UpdateTask(ctx context.Context, id string, input UpdateTaskInput) (*TaskUpdate, error) {
+	var update TaskUpdate
+	if _, err := c.do(ctx, http.MethodPatch, "/tasks/"+pathID(id), nil, input, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+// DeleteTask stops the task's sessions, removes its worktree and deletes it.
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/tasks/"+pathID(id), nil, nil, nil)
+	return err
+}
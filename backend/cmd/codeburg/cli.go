@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/miguel-bm/codeburg/client"
+)
+
+// The task, session and project commands call a running server with the
+// API token codeburg login stores, or with $CODEBURG_URL and
+// $CODEBURG_TOKEN, which take precedence.
+const (
+	serverURLEnv   = "CODEBURG_URL"
+	serverTokenEnv = "CODEBURG_TOKEN"
+)
+
+// tailInterval is how often session tail -f looks for new messages.
+const tailInterval = time.Second
+
+// credentials are the server and token codeburg login stores.
+type credentials struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+func credentialsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "cli.json")
+}
+
+// newClient returns a client for the stored server, overridden by the
+// environment.
+func newClient() (*client.Client, error) {
+	var creds credentials
+	if data, err := os.ReadFile(credentialsPath()); err == nil {
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return nil, fmt.Errorf("read %s: %w", credentialsPath(), err)
+		}
+	}
+	if v := os.Getenv(serverURLEnv); v != "" {
+		creds.Server = v
+	}
+	if v := os.Getenv(serverTokenEnv); v != "" {
+		creds.Token = v
+	}
+	if creds.Server == "" || creds.Token == "" {
+		return nil, errors.New("not logged in: run codeburg login -server URL -token TOKEN, or set " + serverURLEnv + " and " + serverTokenEnv)
+	}
+	return client.New(creds.Server, creds.Token), nil
+}
+
+// runClientCommand runs a command that calls the server and exits when it
+// fails.
+func runClientCommand(name string, args []string) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	var err error
+	switch name {
+	case "login":
+		err = runLogin(ctx, args)
+	case "task":
+		err = runTask(ctx, args)
+	case "session":
+		err = runSession(ctx, args)
+	case "project":
+		err = runProject(ctx, args)
+	}
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// subcommand picks args[0] from subcommands, or returns a usage error.
+func subcommand(command string, args []string, subcommands ...string) (string, []string, error) {
+	usage := fmt.Errorf("usage: codeburg %s <%s> [options]", command, strings.Join(subcommands, "|"))
+	if len(args) == 0 {
+		return "", nil, usage
+	}
+	for _, sub := range subcommands {
+		if args[0] == sub {
+			return sub, args[1:], nil
+		}
+	}
+	return "", nil, usage
+}
+
+func runLogin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	server := fs.String("server", "http://localhost:8080", "Server URL")
+	token := fs.String("token", "", "API token (create one in Settings or with POST /api/auth/tokens)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *token == "" {
+		return errors.New("-token is required")
+	}
+
+	// Any token-accessible call tells a bad token from one lacking a scope.
+	c := client.New(*server, *token)
+	if _, _, err := c.ListTasks(ctx, client.TaskQuery{Page: client.Page{Limit: 1}}); err != nil {
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+			return fmt.Errorf("check token: %w", err)
+		}
+	}
+
+	data, _ := json.MarshalIndent(credentials{Server: strings.TrimSuffix(*server, "/"), Token: *token}, "", "  ")
+	path := credentialsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("Logged in to %s (token stored in %s)\n", *server, path)
+	return nil
+}
+
+func runTask(ctx context.Context, args []string) error {
+	sub, args, err := subcommand("task", args, "list", "create", "move")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("task "+sub, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print JSON")
+	project := fs.String("project", "", "Project name or ID")
+	var status, category, description, priority *string
+	switch sub {
+	case "list":
+		status = fs.String("status", "", "Only tasks in this column")
+		category = fs.String("category", "", "Only tasks in columns counting as backlog, in_progress, in_review or done")
+	case "create":
+		description = fs.String("description", "", "Task description")
+		priority = fs.String("priority", "", "Task priority: urgent, high, medium or low (or p0 to p3)")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "list":
+		query := client.TaskQuery{Status: *status, Category: *category}
+		if *project != "" {
+			p, err := findProject(ctx, c, *project)
+			if err != nil {
+				return err
+			}
+			query.ProjectID = p.ID
+		}
+		var tasks []*client.Task
+		for {
+			page, next, err := c.ListTasks(ctx, query)
+			if err != nil {
+				return err
+			}
+			tasks = append(tasks, page...)
+			if next == "" {
+				break
+			}
+			query.Page = client.Page{Cursor: next}
+		}
+		if *asJSON {
+			return printJSON(tasks)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tPRIORITY\tTITLE")
+		for _, task := range tasks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", task.ID, task.Status, deref(task.Priority), task.Title)
+		}
+		return w.Flush()
+
+	case "create":
+		if fs.NArg() == 0 || *project == "" {
+			return errors.New("usage: codeburg task create -project PROJECT [-description TEXT] [-priority P] TITLE")
+		}
+		p, err := findProject(ctx, c, *project)
+		if err != nil {
+			return err
+		}
+		input := client.CreateTaskInput{ProjectID: p.ID, Title: strings.Join(fs.Args(), " ")}
+		if *description != "" {
+			input.Description = description
+		}
+		if *priority != "" {
+			input.Priority = priority
+		}
+		task, err := c.CreateTask(ctx, input)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(task)
+		}
+		fmt.Printf("Created %s in %s: %s\n", task.ID, task.Status, task.Title)
+		return nil
+
+	default: // move
+		if fs.NArg() != 2 {
+			return errors.New("usage: codeburg task move TASK-ID STATUS")
+		}
+		status := client.TaskStatus(fs.Arg(1))
+		update, err := c.UpdateTask(ctx, fs.Arg(0), client.UpdateTaskInput{Status: &status})
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(update)
+		}
+		fmt.Printf("Moved %s to %s\n", update.ID, update.Status)
+		if update.SessionStarted != nil {
+			fmt.Printf("Started session %s\n", *update.SessionStarted)
+		}
+		if update.PRCreated != nil {
+			fmt.Printf("Opened %s\n", *update.PRCreated)
+		}
+		if deref(update.WorkflowAction) == "ask" {
+			fmt.Println("The workflow asks which agent to start: run codeburg session start " + update.ID)
+		}
+		for _, warning := range update.WorktreeWarning {
+			fmt.Fprintln(os.Stderr, "warning:", warning)
+		}
+		if update.WorkflowError != nil {
+			fmt.Fprintln(os.Stderr, "workflow:", *update.WorkflowError)
+		}
+		return nil
+	}
+}
+
+func runSession(ctx context.Context, args []string) error {
+	sub, args, err := subcommand("session", args, "start", "tail")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("session "+sub, flag.ContinueOnError)
+	follow := fs.Bool("f", false, "Follow the transcript until the session ends")
+	var asJSON *bool
+	var provider, model, project *string
+	if sub == "start" {
+		asJSON = fs.Bool("json", false, "Print JSON")
+		provider = fs.String("provider", "claude", "claude, codex or terminal")
+		model = fs.String("model", "", "Model to run")
+		project = fs.String("project", "", "Start in this project's directory instead of a task (name or ID)")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if sub == "tail" {
+		if fs.NArg() != 1 {
+			return errors.New("usage: codeburg session tail [-f] SESSION-ID")
+		}
+		return tailSession(ctx, c, fs.Arg(0), *follow, os.Stdout)
+	}
+
+	opts := client.StartSessionOptions{Provider: *provider, Model: *model}
+	var session *client.Session
+	if *project != "" {
+		p, err := findProject(ctx, c, *project)
+		if err != nil {
+			return err
+		}
+		opts.Prompt = strings.Join(fs.Args(), " ")
+		session, err = c.StartProjectSession(ctx, p.ID, opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		if fs.NArg() == 0 {
+			return errors.New("usage: codeburg session start [-provider P] [-model M] [-f] TASK-ID [PROMPT]")
+		}
+		opts.Prompt = strings.Join(fs.Args()[1:], " ")
+		if session, err = c.StartSession(ctx, fs.Arg(0), opts); err != nil {
+			return err
+		}
+	}
+	if *asJSON {
+		if err := printJSON(session); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Started %s session %s\n", session.Provider, session.ID)
+	}
+	if *follow {
+		return tailSession(ctx, c, session.ID, true, os.Stdout)
+	}
+	return nil
+}
+
+// tailSession prints a chat session's transcript. With follow it keeps
+// printing new messages until the session completes or fails.
+func tailSession(ctx context.Context, c *client.Client, id string, follow bool, out io.Writer) error {
+	session, err := c.GetSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session.SessionType == "terminal" {
+		return fmt.Errorf("session %s is a terminal session; open it in the app", id)
+	}
+
+	var cursor string
+	for {
+		// Read the status first, so no message written before the session
+		// ended is missed.
+		if session, err = c.GetSession(ctx, id); err != nil {
+			return err
+		}
+		ended := session.Status == "completed" || session.Status == "error"
+		for {
+			messages, next, err := c.ListMessages(ctx, id, client.Page{Cursor: cursor, Limit: 200})
+			if err != nil {
+				return err
+			}
+			for _, message := range messages {
+				printMessage(out, message)
+			}
+			if len(messages) > 0 {
+				cursor = client.MessageCursor(messages[len(messages)-1].ID)
+			}
+			if next == "" {
+				break
+			}
+		}
+		if !follow || ended {
+			if follow {
+				fmt.Fprintf(out, "-- session %s\n", session.Status)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(tailInterval):
+		}
+	}
+}
+
+func printMessage(out io.Writer, m *client.Message) {
+	switch m.Kind {
+	case "user-text":
+		fmt.Fprintf(out, "> %s\n", m.Text)
+	case "agent-text":
+		if !m.IsThinking {
+			fmt.Fprintln(out, m.Text)
+		}
+	case "tool-call":
+		if m.Tool == nil {
+			return
+		}
+		fmt.Fprintf(out, "* %s %s\n", m.Tool.Name, toolSummary(m.Tool))
+	case "permission-request":
+		fmt.Fprintf(out, "? permission requested %s\n", m.Text)
+	default:
+		if m.Text != "" {
+			fmt.Fprintf(out, "-- %s\n", m.Text)
+		}
+	}
+}
+
+// toolSummary returns what a tool call acts on: its command, file or
+// pattern, or its description.
+func toolSummary(tool *client.MessageTool) string {
+	if input, ok := tool.Input.(map[string]any); ok {
+		for _, key := range []string{"command", "file_path", "path", "pattern", "url", "query"} {
+			if v, ok := input[key].(string); ok && v != "" {
+				return v
+			}
+		}
+	}
+	if tool.Description != "" {
+		return tool.Description
+	}
+	if tool.Title != tool.Name {
+		return tool.Title
+	}
+	return ""
+}
+
+func runProject(ctx context.Context, args []string) error {
+	_, args, err := subcommand("project", args, "add")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("project add", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print JSON")
+	name := fs.String("name", "", "Project name (default: the repository's)")
+	branch := fs.String("branch", "", "Default branch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: codeburg project add [-name NAME] [-branch BRANCH] PATH|GIT-URL")
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	input := client.CreateProjectInput{Name: *name}
+	if source := fs.Arg(0); strings.Contains(source, "://") || strings.HasPrefix(source, "git@") {
+		input.GitURL = source
+	} else {
+		path, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+		input.Path = path
+		if input.Name == "" {
+			input.Name = filepath.Base(path)
+		}
+	}
+	if *branch != "" {
+		input.DefaultBranch = branch
+	}
+	project, err := c.CreateProject(ctx, input)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(project)
+	}
+	fmt.Printf("Added %s (%s) at %s\n", project.Name, project.ID, project.Path)
+	return nil
+}
+
+// findProject returns the project named or identified by ref.
+func findProject(ctx context.Context, c *client.Client, ref string) (*client.Project, error) {
+	projects, err := c.ListProjects(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.ID == ref || p.Name == ref {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no project named %q", ref)
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
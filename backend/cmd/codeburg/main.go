@@ -9,34 +9,85 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/miguel-bm/codeburg/internal/api"
 	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/fakeprovider"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
+	"github.com/miguel-bm/codeburg/internal/version"
 )
 
 func main() {
+	// Run as the fake claude or codex CLI when started under that name.
+	fakeprovider.RunIfInvoked()
+
 	serveCmd := flag.NewFlagSet("serve", flag.ExitOnError)
 	serveHost := serveCmd.String("host", "0.0.0.0", "Host to bind to")
 	servePort := serveCmd.Int("port", 8080, "Port to listen on")
+	configPath := serveCmd.String("config", api.DefaultConfigPath(), "Configuration file (reload it with SIGHUP)")
+	fakeProviders := serveCmd.Bool("fake-providers", false, "Run sessions with scripted fake claude and codex CLIs (for development)")
+	demoMode := serveCmd.Bool("demo", false, "Serve a sample project from its own database under ~/.codeburg/demo, with fake providers")
+	migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	migrateConfig := migrateCmd.String("config", api.DefaultConfigPath(), "Configuration file")
+	selfUpdateCmd := flag.NewFlagSet("self-update", flag.ExitOnError)
+	checkOnly := selfUpdateCmd.Bool("check", false, "Only report whether a newer release is available")
+	tracing := telemetry.ConfigFromEnv()
+	tracing.BindFlags(serveCmd)
 
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: codeburg <command> [options]")
 		fmt.Println()
 		fmt.Println("Commands:")
-		fmt.Println("  serve    Start the Codeburg server")
-		fmt.Println("  migrate  Run database migrations")
+		fmt.Println("  serve        Start the Codeburg server")
+		fmt.Println("  migrate      Run database migrations")
+		fmt.Println("  version      Print the version and build details")
+		fmt.Println("  self-update  Replace this binary with the latest release")
+		fmt.Println()
+		fmt.Println("Client commands (call a running server):")
+		fmt.Println("  login        Store the server URL and API token to use")
+		fmt.Println("  project add  Add a repository as a project")
+		fmt.Println("  task         list, create or move tasks")
+		fmt.Println("  session      start a session, or tail its transcript")
 		os.Exit(1)
 	}
 
 	switch os.Args[1] {
 	case "serve":
 		serveCmd.Parse(os.Args[2:])
-		runServer(*serveHost, *servePort)
+		dbPath, demoDir := databasePath(*configPath), ""
+		if *demoMode {
+			demoDir = filepath.Join(filepath.Dir(dbPath), "demo")
+			dbPath = filepath.Join(demoDir, "codeburg.db")
+		}
+		if *fakeProviders || *demoMode {
+			installFakeProviders()
+		}
+		runServer(*serveHost, *servePort, tracing, *configPath, dbPath, demoDir)
 
 	case "migrate":
-		runMigrations()
+		migrateCmd.Parse(os.Args[2:])
+		runMigrations(databasePath(*migrateConfig))
+
+	case "version":
+		info := version.Get()
+		fmt.Printf("codeburg %s (%s, %s/%s)\n", info.Version, info.GoVersion, info.OS, info.Arch)
+		if info.Commit != "" {
+			modified := ""
+			if info.Modified {
+				modified = " (modified)"
+			}
+			fmt.Printf("commit %s%s\n", info.Commit, modified)
+		}
+
+	case "self-update":
+		selfUpdateCmd.Parse(os.Args[2:])
+		selfUpdate(*checkOnly)
+
+	case "login", "task", "session", "project":
+		runClientCommand(os.Args[1], os.Args[2:])
 
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
@@ -44,11 +95,13 @@ func main() {
 	}
 }
 
-func runServer(host string, port int) {
+// runServer serves the database at dbPath. With a demoDir, it seeds the
+// demo content there first.
+func runServer(host string, port int, tracing telemetry.Config, configPath, dbPath, demoDir string) {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
 
 	// Initialize database
-	database, err := db.Open(db.DefaultPath())
+	database, err := db.Open(dbPath)
 	if err != nil {
 		slog.Error("failed to open database", "error", err)
 		os.Exit(1)
@@ -61,8 +114,29 @@ func runServer(host string, port int) {
 		os.Exit(1)
 	}
 
+	// Export trace spans when an OTLP endpoint is configured (-otlp-endpoint,
+	// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT).
+	if tracing.Endpoint != "" {
+		slog.Info("exporting trace spans", "endpoint", tracing.Endpoint, "service", tracing.ServiceName)
+	}
+	shutdownTracing := telemetry.Setup(tracing)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("failed to flush trace spans", "error", err)
+		}
+	}()
+
 	// Create and start server
-	server := api.NewServer(database)
+	server := api.NewServerWithConfig(database, configPath)
+	if demoDir != "" {
+		if err := server.SeedDemo(demoDir); err != nil {
+			slog.Error("failed to seed demo content", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("serving demo content", "dir", demoDir)
+	}
 	addr := fmt.Sprintf("%s:%d", host, port)
 	slog.Info("starting codeburg server", "addr", addr)
 
@@ -74,6 +148,23 @@ func runServer(host string, port int) {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// SIGHUP reloads the configuration file.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			warnings, err := server.ReloadConfig()
+			if err != nil {
+				slog.Error("configuration not reloaded", "error", err)
+				continue
+			}
+			for _, warning := range warnings {
+				slog.Warn(warning)
+			}
+		}
+	}()
+
 	select {
 	case err := <-errCh:
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -95,10 +186,80 @@ func runServer(host string, port int) {
 	}
 }
 
-func runMigrations() {
+// installFakeProviders puts the fake claude and codex CLIs first on PATH
+// for the sessions this server starts.
+func installFakeProviders() {
+	dir, err := os.MkdirTemp("", "codeburg-fake-providers-")
+	if err == nil {
+		err = fakeprovider.Install(dir)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "install fake providers: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	fmt.Fprintf(os.Stderr, "Using fake claude and codex CLIs from %s (script: $%s)\n", dir, fakeprovider.ScriptEnv)
+}
+
+// databasePath returns the database path: in the data directory the
+// configuration file at configPath sets, or the default. It exits when the
+// file is invalid.
+func databasePath(configPath string) string {
+	config, err := api.LoadConfig(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if path := config.DatabasePath(); path != "" {
+		return path
+	}
+	return db.DefaultPath()
+}
+
+// selfUpdate replaces the running binary with the latest release, or with
+// checkOnly reports whether there is one.
+func selfUpdate(checkOnly bool) {
+	if !version.IsRelease() && !checkOnly {
+		fmt.Fprintf(os.Stderr, "codeburg %s is built from source; update it with git pull and just build-be\n", version.Version)
+		os.Exit(1)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	releases := &version.Releases{}
+	release, err := releases.Latest(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !version.Newer(release.Version, version.Version) {
+		fmt.Printf("codeburg %s is up to date (latest release: %s)\n", version.Version, release.Version)
+		return
+	}
+	if checkOnly {
+		fmt.Printf("codeburg %s is available (running %s): %s\n", release.Version, version.Version, release.URL)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "find executable: %v\n", err)
+		os.Exit(1)
+	}
+	if err := releases.Install(ctx, release, exe); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Updated %s to codeburg %s. Restart the server to run it.\n", exe, release.Version)
+}
+
+func runMigrations(dbPath string) {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
 
-	database, err := db.Open(db.DefaultPath())
+	database, err := db.Open(dbPath)
 	if err != nil {
 		slog.Error("failed to open database", "error", err)
 		os.Exit(1)
@@ -0,0 +1,57 @@
+// Package embed runs Codeburg inside another Go program: its HTTP API on
+// whatever server the program has, and its services to call directly.
+package embed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/miguel-bm/codeburg/internal/api"
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// Codeburg is a running Codeburg server.
+type Codeburg struct {
+	db     *db.DB
+	server *api.Server
+}
+
+// Open opens the database at path, or at ~/.codeburg/codeburg.db when path
+// is "", migrates it and starts the server's background work, such as the
+// Telegram bot when one is configured.
+func Open(path string) (*Codeburg, error) {
+	if path == "" {
+		path = db.DefaultPath()
+	}
+	database, err := db.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := database.Migrate(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+	return &Codeburg{db: database, server: api.NewServer(database)}, nil
+}
+
+// Services returns the task, git and session services.
+func (c *Codeburg) Services() service.Services {
+	return c.server.Services()
+}
+
+// Handler returns the HTTP API and web socket endpoints.
+func (c *Codeburg) Handler() http.Handler {
+	return c.server.Handler()
+}
+
+// Close stops the background work, waiting for it until ctx is done, and
+// closes the database.
+func (c *Codeburg) Close(ctx context.Context) error {
+	err := c.server.Shutdown(ctx)
+	if cerr := c.db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
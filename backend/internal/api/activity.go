@@ -0,0 +1,329 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Agent activity is counted per project, provider and hour, for heatmaps:
+// chat messages and tool calls once chat sessions finalize them, turns of
+// terminal sessions as their hooks report them, and commits made in a
+// session's work directory, looked for whenever a turn ends.
+const (
+	activityQueueSize     = 1024
+	activityFlushInterval = 30 * time.Second
+	activityMaxCommitScan = 500
+
+	defaultActivityDays = 30
+	maxActivityDays     = 366
+)
+
+type activityKind int
+
+const (
+	activityMessage activityKind = iota
+	activityToolCall
+	activityTurnEnd // looks for new commits; not counted itself
+)
+
+type activityEvent struct {
+	sessionID string
+	provider  string
+	kind      activityKind
+	at        time.Time
+}
+
+type activityTracker struct {
+	db      *db.DB
+	queue   chan activityEvent
+	workDir func(*db.AgentSession) (string, error)
+
+	// scannedAt is when each session's work directory was last looked at
+	// for commits. Only run touches it.
+	scannedAt map[string]time.Time
+}
+
+func newActivityTracker(database *db.DB, workDir func(*db.AgentSession) (string, error)) *activityTracker {
+	return &activityTracker{
+		db:        database,
+		queue:     make(chan activityEvent, activityQueueSize),
+		workDir:   workDir,
+		scannedAt: make(map[string]time.Time),
+	}
+}
+
+// enqueue hands an event to the tracker without blocking; events are
+// dropped if it cannot keep up.
+func (t *activityTracker) enqueue(ev activityEvent) {
+	if t == nil {
+		return
+	}
+	select {
+	case t.queue <- ev:
+	default:
+		slog.Debug("activity queue full, dropping event", "session_id", ev.sessionID)
+	}
+}
+
+// recordChatMessage counts a finalized chat message.
+func (t *activityTracker) recordChatMessage(msg ChatMessage) {
+	ev := activityEvent{sessionID: msg.SessionID, provider: msg.Provider, at: msg.CreatedAt}
+	switch msg.Kind {
+	case ChatMessageKindUserText, ChatMessageKindAgentText:
+		ev.kind = activityMessage
+	case ChatMessageKindToolCall:
+		ev.kind = activityToolCall
+	case ChatMessageKindResult:
+		ev.kind = activityTurnEnd
+	default:
+		return
+	}
+	t.enqueue(ev)
+}
+
+// recordTurn counts a finished turn of a terminal session, whose messages
+// Codeburg doesn't see, as one message.
+func (t *activityTracker) recordTurn(session *db.AgentSession) {
+	now := time.Now()
+	t.enqueue(activityEvent{sessionID: session.ID, provider: session.Provider, kind: activityMessage, at: now})
+	t.enqueue(activityEvent{sessionID: session.ID, provider: session.Provider, kind: activityTurnEnd, at: now})
+}
+
+// run batches queued events until ctx is cancelled.
+func (t *activityTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(activityFlushInterval)
+	defer ticker.Stop()
+
+	var batch []activityEvent
+	flush := func() {
+		if len(batch) > 0 {
+			t.flush(ctx, batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case ev := <-t.queue:
+			batch = append(batch, ev)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flush adds a batch of events to the hourly counts and looks for the
+// commits of sessions whose turns ended.
+func (t *activityTracker) flush(ctx context.Context, batch []activityEvent) {
+	type bucket struct {
+		sessionID string
+		provider  string
+		hour      time.Time
+	}
+	counts := make(map[bucket]db.ActivityCounts)
+	var scans []string
+	scanned := make(map[string]bool)
+	for _, ev := range batch {
+		key := bucket{ev.sessionID, ev.provider, ev.at.UTC().Truncate(time.Hour)}
+		c := counts[key]
+		switch ev.kind {
+		case activityMessage:
+			c.Messages++
+		case activityToolCall:
+			c.ToolCalls++
+		case activityTurnEnd:
+			if !scanned[ev.sessionID] {
+				scanned[ev.sessionID] = true
+				scans = append(scans, ev.sessionID)
+			}
+			continue
+		}
+		counts[key] = c
+	}
+
+	sessions := make(map[string]*db.AgentSession)
+	session := func(id string) *db.AgentSession {
+		if s, ok := sessions[id]; ok {
+			return s
+		}
+		s, _ := t.db.GetSession(id)
+		sessions[id] = s
+		return s
+	}
+
+	for key, c := range counts {
+		s := session(key.sessionID)
+		if s == nil {
+			continue
+		}
+		if err := t.db.AddActivity(s.ProjectID, firstNonEmpty(key.provider, s.Provider), key.hour, c); err != nil {
+			slog.Warn("failed to record activity", "session_id", key.sessionID, "error", err)
+		}
+	}
+	for _, id := range scans {
+		if s := session(id); s != nil {
+			t.scanCommits(ctx, s)
+		}
+	}
+}
+
+// scanCommits counts the commits made in a session's work directory since
+// it was last looked at, or since the session started.
+func (t *activityTracker) scanCommits(ctx context.Context, session *db.AgentSession) {
+	workDir, err := t.workDir(session)
+	if err != nil {
+		slog.Debug("no work directory to scan for commits", "session_id", session.ID, "error", err)
+		return
+	}
+	since, ok := t.scannedAt[session.ID]
+	if !ok {
+		since = session.CreatedAt
+	}
+	now := time.Now()
+
+	// Commits carry whole seconds; a minute of overlap catches any made
+	// just before the last scan, and counted ones are skipped.
+	out, err := runGitContext(ctx, workDir, "log", "-n", strconv.Itoa(activityMaxCommitScan),
+		"--format=%H %ct", "--since="+since.Add(-time.Minute).UTC().Format("2006-01-02 15:04:05 -0700"), "HEAD")
+	if err != nil {
+		slog.Debug("failed to scan for commits", "session_id", session.ID, "error", err)
+		return
+	}
+	t.scannedAt[session.ID] = now
+
+	var commits []db.ActivityCommit
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		hash, ts, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			continue
+		}
+		commits = append(commits, db.ActivityCommit{Hash: hash, At: time.Unix(unix, 0)})
+	}
+	if len(commits) == 0 {
+		return
+	}
+	if _, err := t.db.RecordActivityCommits(session.ProjectID, session.Provider, commits); err != nil {
+		slog.Warn("failed to record commits", "session_id", session.ID, "error", err)
+	}
+}
+
+type activityDay struct {
+	Date string `json:"date"`
+	db.ActivityCounts
+	Total int `json:"total"`
+}
+
+type activityHourResponse struct {
+	Hour time.Time `json:"hour"`
+	db.ActivityCounts
+	Total int `json:"total"`
+}
+
+// handleGetActivity serves GET /api/activity: agent activity over the last
+// days (30 by default), for a heatmap. Every day of the range is listed,
+// and each hour with activity. projectId and provider narrow it down, and
+// tz is the time zone days and hours are in (UTC by default).
+func (s *Server) handleGetActivity(w http.ResponseWriter, r *http.Request) {
+	s.writeActivity(w, r, r.URL.Query().Get("projectId"))
+}
+
+// handleGetProjectActivity serves GET /api/projects/{id}/activity.
+func (s *Server) handleGetProjectActivity(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	s.writeActivity(w, r, project.ID)
+}
+
+func (s *Server) writeActivity(w http.ResponseWriter, r *http.Request, projectID string) {
+	q := r.URL.Query()
+	days := defaultActivityDays
+	if raw := q.Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxActivityDays {
+			writeError(w, http.StatusBadRequest, "days must be between 1 and "+strconv.Itoa(maxActivityDays))
+			return
+		}
+		days = n
+	}
+	loc := time.UTC
+	if tz := q.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			writeError(w, http.StatusBadRequest, "unknown time zone")
+			return
+		}
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from := today.AddDate(0, 0, -(days - 1))
+	until := today.AddDate(0, 0, 1)
+
+	hours, err := s.db.ListActivity(db.ActivityFilter{
+		ProjectID: projectID,
+		Provider:  q.Get("provider"),
+		Since:     from,
+		Until:     until,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load activity")
+		return
+	}
+
+	dayIndex := make(map[string]int, days)
+	dayList := make([]activityDay, 0, days)
+	for d := from; d.Before(until); d = d.AddDate(0, 0, 1) {
+		date := d.Format(time.DateOnly)
+		dayIndex[date] = len(dayList)
+		dayList = append(dayList, activityDay{Date: date})
+	}
+
+	var totals db.ActivityCounts
+	providers := make(map[string]db.ActivityCounts)
+	projects := make(map[string]db.ActivityCounts)
+	hourList := make([]activityHourResponse, 0)
+	for _, h := range hours {
+		totals = totals.Add(h.ActivityCounts)
+		providers[h.Provider] = providers[h.Provider].Add(h.ActivityCounts)
+		projects[h.ProjectID] = projects[h.ProjectID].Add(h.ActivityCounts)
+
+		local := h.Hour.In(loc)
+		if i, ok := dayIndex[local.Format(time.DateOnly)]; ok {
+			dayList[i].ActivityCounts = dayList[i].ActivityCounts.Add(h.ActivityCounts)
+			dayList[i].Total = dayList[i].ActivityCounts.Total()
+		}
+		// Hours are listed across projects and providers, oldest first.
+		if n := len(hourList); n > 0 && hourList[n-1].Hour.Equal(local) {
+			hourList[n-1].ActivityCounts = hourList[n-1].ActivityCounts.Add(h.ActivityCounts)
+			hourList[n-1].Total = hourList[n-1].ActivityCounts.Total()
+			continue
+		}
+		hourList = append(hourList, activityHourResponse{Hour: local, ActivityCounts: h.ActivityCounts, Total: h.Total()})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"from":      from.Format(time.DateOnly),
+		"to":        today.Format(time.DateOnly),
+		"timezone":  loc.String(),
+		"totals":    totals,
+		"days":      dayList,
+		"hours":     hourList,
+		"providers": providers,
+		"projects":  projects,
+	})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// drainActivity flushes whatever the tracker has queued.
+func drainActivity(t *testing.T, tracker *activityTracker) {
+	t.Helper()
+	var batch []activityEvent
+	for len(tracker.queue) > 0 {
+		batch = append(batch, <-tracker.queue)
+	}
+	tracker.flush(t.Context(), batch)
+}
+
+type activityResponse struct {
+	From      string                       `json:"from"`
+	To        string                       `json:"to"`
+	Timezone  string                       `json:"timezone"`
+	Totals    db.ActivityCounts            `json:"totals"`
+	Days      []activityDay                `json:"days"`
+	Hours     []activityHourResponse       `json:"hours"`
+	Providers map[string]db.ActivityCounts `json:"providers"`
+	Projects  map[string]db.ActivityCounts `json:"projects"`
+}
+
+func TestActivityHeatmap(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	tracker := newActivityTracker(env.server.db, env.server.resolveSessionWorkDir)
+	env.server.activity = tracker
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	chat, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	terminal, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "codex", SessionType: "terminal"})
+
+	now := time.Now()
+	tracker.recordChatMessage(ChatMessage{SessionID: chat.ID, Provider: "claude", Kind: ChatMessageKindUserText, CreatedAt: now})
+	tracker.recordChatMessage(ChatMessage{SessionID: chat.ID, Provider: "claude", Kind: ChatMessageKindAgentText, CreatedAt: now})
+	tracker.recordChatMessage(ChatMessage{SessionID: chat.ID, Provider: "claude", Kind: ChatMessageKindToolCall, CreatedAt: now})
+	tracker.recordChatMessage(ChatMessage{SessionID: chat.ID, Provider: "claude", Kind: ChatMessageKindSystem, CreatedAt: now})
+
+	// The agent commits during its turn, which ends with a result.
+	os.WriteFile(filepath.Join(repoPath, "feature.go"), []byte("package main\n"), 0o644)
+	exec.Command("git", "-C", repoPath, "add", ".").Run()
+	if err := exec.Command("git", "-C", repoPath, "commit", "-m", "feature").Run(); err != nil {
+		t.Fatalf("git commit: %v", err)
+	}
+	tracker.recordChatMessage(ChatMessage{SessionID: chat.ID, Provider: "claude", Kind: ChatMessageKindResult, CreatedAt: now})
+	tracker.recordTurn(terminal)
+	drainActivity(t, tracker)
+
+	// Commits are counted once, however many sessions see them.
+	tracker.recordTurn(terminal)
+	drainActivity(t, tracker)
+
+	var activity activityResponse
+	decodeResponse(t, env.get("/api/activity?days=7"), &activity)
+	if len(activity.Days) != 7 || activity.Timezone != "UTC" {
+		t.Fatalf("unexpected range: %d days in %s", len(activity.Days), activity.Timezone)
+	}
+	// Both commits of the repository were made after the sessions started,
+	// give or take the scan's minute of overlap.
+	want := db.ActivityCounts{Messages: 4, ToolCalls: 1, Commits: 2}
+	if activity.Totals != want {
+		t.Errorf("totals = %+v, want %+v", activity.Totals, want)
+	}
+	var dayTotal, hourTotal int
+	for _, day := range activity.Days {
+		dayTotal += day.Total
+	}
+	for _, hour := range activity.Hours {
+		hourTotal += hour.Total
+	}
+	if dayTotal != 7 || hourTotal != 7 {
+		t.Errorf("expected 7 events across days and hours, got %d and %d", dayTotal, hourTotal)
+	}
+	if claude := activity.Providers["claude"]; claude != (db.ActivityCounts{Messages: 2, ToolCalls: 1, Commits: 2}) {
+		t.Errorf("unexpected claude activity %+v", claude)
+	}
+	if codex := activity.Providers["codex"]; codex.Messages != 2 {
+		t.Errorf("expected two terminal turns, got %+v", codex)
+	}
+
+	var codex activityResponse
+	decodeResponse(t, env.get("/api/projects/"+project.ID+"/activity?provider=codex&days=1"), &codex)
+	if codex.Totals != (db.ActivityCounts{Messages: 2}) || len(codex.Days) != 1 {
+		t.Errorf("unexpected codex activity %+v", codex)
+	}
+
+	for _, query := range []string{"?days=0", "?days=400", "?tz=Nowhere/Special"} {
+		if resp := env.get("/api/activity" + query); resp.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.Code)
+		}
+	}
+	if resp := env.get("/api/projects/nope/activity"); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown project, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+const (
+	defaultAgentAuthorName  = "{provider} via Codeburg"
+	defaultAgentAuthorEmail = "agent@codeburg"
+)
+
+// agentGitAuthor returns the commit author for an agent provider under the
+// project's agent identity, or ok=false when the project does not attribute
+// agent commits (or the provider is a plain terminal, i.e. the user).
+func agentGitAuthor(project *db.Project, provider string) (name, email string, ok bool) {
+	if project == nil || project.AgentIdentity == nil || !project.AgentIdentity.Enabled {
+		return "", "", false
+	}
+	if provider == "" || provider == "terminal" {
+		return "", "", false
+	}
+
+	name = project.AgentIdentity.Name
+	if name == "" {
+		name = defaultAgentAuthorName
+	}
+	name = strings.ReplaceAll(name, "{provider}", providerDisplayName(provider))
+	email = project.AgentIdentity.Email
+	if email == "" {
+		email = defaultAgentAuthorEmail
+	}
+	return name, email, true
+}
+
+// agentGitEnv returns GIT_AUTHOR_* variables for an agent process so its
+// commits carry the agent identity, while the committer stays the user from
+// git config. Returns nil when agent attribution is off.
+func agentGitEnv(project *db.Project, provider string) []string {
+	name, email, ok := agentGitAuthor(project, provider)
+	if !ok {
+		return nil
+	}
+	return []string{"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email}
+}
+
+// commitAuthorArgs returns the `git commit --author` arguments for a commit
+// made on behalf of the given session, or nil for the user's own commits.
+func (s *Server) commitAuthorArgs(sessionID string) ([]string, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := s.db.GetProject(session.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	name, email, ok := agentGitAuthor(project, session.Provider)
+	if !ok {
+		return nil, nil
+	}
+	return []string{"--author", name + " <" + email + ">"}, nil
+}
+
+func providerDisplayName(provider string) string {
+	switch provider {
+	case "claude":
+		return "Claude"
+	case "codex":
+		return "Codex"
+	}
+	if provider == "" {
+		return provider
+	}
+	return strings.ToUpper(provider[:1]) + provider[1:]
+}
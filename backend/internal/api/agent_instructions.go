@@ -0,0 +1,33 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// applyAgentInstructionDefaults fills the model and auto-approve setting of a
+// session request from the project's agent instructions when the request
+// leaves them unset. Plain terminal sessions are left alone.
+func applyAgentInstructionDefaults(req *StartSessionRequest, project *db.Project) {
+	if project == nil || project.AgentInstructions == nil || req.Provider == "terminal" {
+		return
+	}
+	instructions := project.AgentInstructions
+	if req.Model == "" {
+		req.Model = instructions.DefaultModel
+	}
+	if req.AutoApprove == nil && instructions.DefaultAutoApprove != nil {
+		autoApprove := *instructions.DefaultAutoApprove
+		req.AutoApprove = &autoApprove
+	}
+}
+
+// agentPrelude returns the project's system prompt prelude for an agent
+// provider, or "" when none is set.
+func agentPrelude(project *db.Project, provider string) string {
+	if project == nil || project.AgentInstructions == nil || provider == "terminal" {
+		return ""
+	}
+	return strings.TrimSpace(project.AgentInstructions.Prelude)
+}
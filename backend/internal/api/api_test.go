@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/miguel-bm/codeburg/internal/db"
 	"github.com/miguel-bm/codeburg/internal/gitclone"
 	"github.com/miguel-bm/codeburg/internal/portsuggest"
+	"github.com/miguel-bm/codeburg/internal/telegram"
 	"github.com/miguel-bm/codeburg/internal/tunnel"
 	"github.com/miguel-bm/codeburg/internal/worktree"
 )
@@ -60,6 +62,7 @@ func setupTestEnv(t *testing.T) *testEnv {
 	// Create server
 	s := &Server{
 		db:             database,
+		bgCtx:          wsCtx,
 		auth:           auth,
 		worktree:       worktree.NewManager(worktree.DefaultConfig()),
 		wsHub:          wsHub,
@@ -69,6 +72,11 @@ func setupTestEnv(t *testing.T) *testEnv {
 		portSuggest:    portsuggest.NewManager(nil),
 		gitclone:       gitclone.Config{BaseDir: filepath.Join(tmpDir, "repos")},
 		authLimiter:    newLoginRateLimiter(5, 1*time.Minute),
+		uploadLimit:    uploadLimitFromEnv(),
+		challenges:     newChallengeStore(),
+		taskUndo:       newTaskUndoStore(),
+		pipelineRuns:   newPipelineRunStore(),
+		semantic:       newSemanticIndexer(),
 		allowedOrigins: []string{"http://localhost:*"},
 	}
 	s.setupRoutes()
@@ -650,6 +658,189 @@ func TestListTasks_FilterByProject(t *testing.T) {
 	}
 }
 
+func TestTaskPriorityAndEstimate(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+
+	resp := env.post("/api/projects/"+project.ID+"/tasks", map[string]any{"title": "Outage", "priority": "P0", "estimateMinutes": 30})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", resp.Code, resp.Body.String())
+	}
+	var outage db.Task
+	decodeResponse(t, resp, &outage)
+	if outage.Priority == nil || *outage.Priority != "urgent" || outage.Estimate == nil || *outage.Estimate != 30 {
+		t.Fatalf("expected p0 stored as urgent with the estimate, got %+v", outage)
+	}
+	env.post("/api/projects/"+project.ID+"/tasks", map[string]any{"title": "Docs", "priority": "low", "estimateMinutes": 120})
+	env.post("/api/projects/"+project.ID+"/tasks", map[string]any{"title": "Idea"})
+
+	if resp := env.post("/api/projects/"+project.ID+"/tasks", map[string]any{"title": "Bad", "priority": "p7"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown priority, got %d", resp.Code)
+	}
+	if resp := env.patch("/api/tasks/"+outage.ID, map[string]any{"estimateMinutes": -5}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative estimate, got %d", resp.Code)
+	}
+
+	var tasks []db.Task
+	decodeResponse(t, env.get("/api/tasks?priority=p0,low"), &tasks)
+	if len(tasks) != 2 {
+		t.Errorf("expected the urgent and low tasks, got %d", len(tasks))
+	}
+	if resp := env.get("/api/tasks?priority=someday"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown priority filter, got %d", resp.Code)
+	}
+	decodeResponse(t, env.get("/api/tasks?sort=estimate"), &tasks)
+	if len(tasks) != 3 || tasks[0].Title != "Outage" || tasks[1].Title != "Docs" || tasks[2].Title != "Idea" {
+		t.Errorf("expected tasks by estimate, unestimated last, got %+v", tasks)
+	}
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"`+strconv.FormatInt(tgUserID, 10)+`"`)
+	reply := env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: "tasks"})
+	want := "P0 · urgent\n• [backlog] Outage (~30m)\nP3 · low\n• [backlog] Docs (~2h)\nNo priority\n• [backlog] Idea"
+	if !strings.Contains(reply, want) {
+		t.Errorf("expected /tasks grouped by priority, got %q", reply)
+	}
+}
+
+func TestReorderTask(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	var a, b db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "A"}), &a)
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "B"}), &b)
+
+	resp := env.post("/api/tasks/"+b.ID+"/reorder", map[string]any{"index": 0, "version": b.Version})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("reorder: %d %s", resp.Code, resp.Body.String())
+	}
+	var moved db.Task
+	decodeResponse(t, resp, &moved)
+	if moved.Position != 0 || moved.Version != b.Version+1 {
+		t.Fatalf("expected B first with a new version, got %+v", moved)
+	}
+
+	// A second client still holding the old version is told what changed.
+	resp = env.post("/api/tasks/"+b.ID+"/reorder", map[string]any{"index": 1, "version": b.Version})
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a stale version, got %d", resp.Code)
+	}
+	var conflict struct {
+		Error string  `json:"error"`
+		Task  db.Task `json:"task"`
+	}
+	decodeResponse(t, resp, &conflict)
+	if conflict.Task.Version != moved.Version || conflict.Error == "" {
+		t.Errorf("expected the current task in the conflict, got %+v", conflict)
+	}
+	if resp := env.patch("/api/tasks/"+b.ID, map[string]any{"title": "B2", "version": b.Version}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale update, got %d", resp.Code)
+	}
+
+	resp = env.post("/api/tasks/"+a.ID+"/reorder", map[string]any{"status": "in_review", "index": 0})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("move: %d %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, resp, &moved)
+	if moved.Status != db.TaskStatusInReview || moved.Position != 0 {
+		t.Errorf("expected A moved to review, got %+v", moved)
+	}
+	if resp := env.post("/api/tasks/"+a.ID+"/reorder", map[string]any{"status": "nowhere", "index": 0}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown column, got %d", resp.Code)
+	}
+	if resp := env.post("/api/tasks/"+a.ID+"/reorder", map[string]any{"index": -1}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative index, got %d", resp.Code)
+	}
+}
+
+// patchIfMatch makes a PATCH request with an If-Match header.
+func (e *testEnv) patchIfMatch(path string, body interface{}, etag string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest("PATCH", path, strings.NewReader(string(data)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.token)
+	req.Header.Set("If-Match", etag)
+
+	w := httptest.NewRecorder()
+	e.server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestETags(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "A"}), &task)
+
+	resp := env.get("/api/tasks/" + task.ID)
+	etag := resp.Header().Get("ETag")
+	if etag != `"`+strconv.Itoa(task.Version)+`"` {
+		t.Fatalf("expected the task version as ETag, got %q", etag)
+	}
+	resp = env.patchIfMatch("/api/tasks/"+task.ID, map[string]string{"title": "A2"}, etag)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", resp.Code, resp.Body.String())
+	}
+	if resp.Header().Get("ETag") == etag {
+		t.Error("expected a new ETag after the update")
+	}
+
+	// The first edit won; the second, from the same read, sees it.
+	resp = env.patchIfMatch("/api/tasks/"+task.ID, map[string]string{"title": "A3"}, etag)
+	if resp.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for a stale If-Match, got %d", resp.Code)
+	}
+	var stale struct {
+		Task db.Task `json:"task"`
+	}
+	decodeResponse(t, resp, &stale)
+	if stale.Task.Title != "A2" || resp.Header().Get("ETag") != `"`+strconv.Itoa(stale.Task.Version)+`"` {
+		t.Errorf("expected the current task with its ETag, got %+v", stale.Task)
+	}
+	if resp := env.patchIfMatch("/api/tasks/"+task.ID, map[string]string{"title": "A3"}, `W/"1"`); resp.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected a weak tag never to match, got %d", resp.Code)
+	}
+	if resp := env.patchIfMatch("/api/tasks/"+task.ID, map[string]string{"title": "A3"}, "*"); resp.Code != http.StatusOK {
+		t.Errorf("expected * to match any version, got %d", resp.Code)
+	}
+
+	resp = env.get("/api/projects/" + project.ID)
+	etag = resp.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag on the project")
+	}
+	if resp := env.patchIfMatch("/api/projects/"+project.ID, map[string]string{"name": "p2"}, etag); resp.Code != http.StatusOK {
+		t.Fatalf("update project: %d %s", resp.Code, resp.Body.String())
+	}
+	resp = env.patchIfMatch("/api/projects/"+project.ID, map[string]string{"name": "p3"}, etag)
+	if resp.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for a stale project, got %d", resp.Code)
+	}
+	var staleProject struct {
+		Project db.Project `json:"project"`
+	}
+	decodeResponse(t, resp, &staleProject)
+	if staleProject.Project.Name != "p2" {
+		t.Errorf("expected the current project, got %+v", staleProject.Project)
+	}
+	if resp := env.patch("/api/projects/"+project.ID, map[string]any{"name": "p3", "version": project.Version}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale body version, got %d", resp.Code)
+	}
+}
+
 func TestDeleteTask(t *testing.T) {
 	env := setupTestEnv(t)
 	env.setup("testpass123")
@@ -1149,21 +1340,21 @@ func TestCreateTunnel_InvalidPort(t *testing.T) {
 
 // --- GitHub URL Project Tests ---
 
-func TestCreateProject_InvalidGitHubURL(t *testing.T) {
+func TestCreateProject_UnsupportedGitURL(t *testing.T) {
 	env := setupTestEnv(t)
 	env.setup("testpass123")
 
 	resp := env.post("/api/projects", map[string]string{
-		"githubUrl": "https://gitlab.com/user/repo",
+		"githubUrl": "https://example.com/user/repo",
 	})
 	if resp.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 for non-GitHub URL, got %d: %s", resp.Code, resp.Body.String())
+		t.Errorf("expected 400 for unsupported host, got %d: %s", resp.Code, resp.Body.String())
 	}
 
 	var body map[string]string
 	decodeResponse(t, resp, &body)
-	if body["error"] != "invalid GitHub URL" {
-		t.Errorf("expected 'invalid GitHub URL' error, got %q", body["error"])
+	if !strings.HasPrefix(body["error"], "unsupported git URL") {
+		t.Errorf("expected 'unsupported git URL' error, got %q", body["error"])
 	}
 }
 
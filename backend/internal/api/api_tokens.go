@@ -0,0 +1,228 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// apiTokenPrefix marks personal access tokens so the auth middleware can tell
+// them apart from login JWTs without a database lookup.
+const apiTokenPrefix = "cbt_"
+
+// API token scopes. A ":write" scope also grants the matching ":read" scope;
+// git:push is separate from git:write.
+const (
+	ScopeTasksRead     = "tasks:read"
+	ScopeTasksWrite    = "tasks:write"
+	ScopeSessionsRead  = "sessions:read"
+	ScopeSessionsWrite = "sessions:write"
+	ScopeProjectsRead  = "projects:read"
+	ScopeProjectsWrite = "projects:write"
+	ScopeGitRead       = "git:read"
+	ScopeGitWrite      = "git:write"
+	ScopeGitPush       = "git:push"
+)
+
+var validAPITokenScopes = []string{
+	ScopeTasksRead, ScopeTasksWrite,
+	ScopeSessionsRead, ScopeSessionsWrite,
+	ScopeProjectsRead, ScopeProjectsWrite,
+	ScopeGitRead, ScopeGitWrite, ScopeGitPush,
+}
+
+// apiTokenContextKey holds the *db.APIToken for requests authenticated with one.
+const apiTokenContextKey contextKey = "api_token"
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// requiredTokenScope maps a matched route to the scope an API token needs to
+// call it. Routes that return "" are not available to API tokens at all.
+func requiredTokenScope(method, pattern string) string {
+	read := method == http.MethodGet
+	pick := func(readScope, writeScope string) string {
+		if read {
+			return readScope
+		}
+		return writeScope
+	}
+
+	switch {
+	case strings.HasSuffix(pattern, "/git/push"):
+		return ScopeGitPush
+	case strings.HasPrefix(pattern, "/api/tasks/{id}/git/"),
+		strings.HasPrefix(pattern, "/api/projects/{id}/git/"):
+		return pick(ScopeGitRead, ScopeGitWrite)
+	case pattern == "/api/tasks/{taskId}/sessions",
+		pattern == "/api/projects/{id}/sessions",
+		pattern == "/api/sessions",
+		read && pattern == "/api/providers/models",
+		strings.HasPrefix(pattern, "/api/sessions/{id}"):
+		return pick(ScopeSessionsRead, ScopeSessionsWrite)
+	case pattern == "/api/tasks",
+		pattern == "/api/tasks/{id}",
+		pattern == "/api/tasks/{id}/undo-status",
+		pattern == "/api/tasks/{id}/time",
+		pattern == "/api/projects/{projectId}/tasks",
+		strings.HasPrefix(pattern, "/api/tasks/{id}/labels"):
+		return pick(ScopeTasksRead, ScopeTasksWrite)
+	case read && (pattern == "/api/projects" || pattern == "/api/projects/{id}" || pattern == "/api/projects/{id}/board" ||
+		pattern == "/api/projects/{id}/board/export"):
+		return ScopeProjectsRead
+	case pattern == "/api/projects" && method == http.MethodPost:
+		// Adding a project; changing or removing one needs a login.
+		return ScopeProjectsWrite
+	case pattern == "/api/projects/{id}/import":
+		return ScopeTasksWrite
+	case pattern == "/api/projects/{id}/semantic-search":
+		// A search, so tools and assistants can call it with read access.
+		return ScopeProjectsRead
+	}
+	return ""
+}
+
+func tokenHasScope(scopes []string, need string) bool {
+	if slices.Contains(scopes, need) {
+		return true
+	}
+	if resource, ok := strings.CutSuffix(need, ":read"); ok {
+		return slices.Contains(scopes, resource+":write")
+	}
+	return false
+}
+
+// authenticateAPIToken validates a personal access token against the matched
+// route. It writes the error response and returns nil on failure.
+func (s *Server) authenticateAPIToken(w http.ResponseWriter, r *http.Request, raw string) *db.APIToken {
+	token, err := s.db.GetAPITokenByHash(hashAPIToken(raw))
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			slog.Warn("api token lookup failed", "error", err)
+		}
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return nil
+	}
+	if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+		writeError(w, http.StatusUnauthorized, "token expired")
+		return nil
+	}
+
+	pattern := ""
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		pattern = rctx.RoutePattern()
+	}
+	need := requiredTokenScope(r.Method, pattern)
+	if need == "" {
+		writeError(w, http.StatusForbidden, "endpoint not available to API tokens")
+		return nil
+	}
+	if !tokenHasScope(token.Scopes, need) {
+		writeError(w, http.StatusForbidden, "token lacks scope "+need)
+		return nil
+	}
+
+	if err := s.db.TouchAPIToken(token.ID); err != nil {
+		slog.Debug("failed to record api token use", "token_id", token.ID, "error", err)
+	}
+	return token
+}
+
+func (s *Server) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.db.ListAPITokens()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list tokens")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+type createAPITokenResponse struct {
+	*db.APIToken
+	Token string `json:"token"` // plaintext secret, only returned once
+}
+
+func (s *Server) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expiresInDays"`
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(input.Scopes) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one scope is required")
+		return
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(validAPITokenScopes, scope) {
+			writeError(w, http.StatusBadRequest, "invalid scope: "+scope)
+			return
+		}
+	}
+	if input.ExpiresInDays < 0 {
+		writeError(w, http.StatusBadRequest, "expiresInDays must be positive")
+		return
+	}
+
+	raw, err := generateAPIToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+
+	create := db.CreateAPITokenInput{
+		Name:        input.Name,
+		TokenHash:   hashAPIToken(raw),
+		TokenPrefix: raw[:len(apiTokenPrefix)+8],
+		Scopes:      input.Scopes,
+	}
+	if input.ExpiresInDays > 0 {
+		expires := time.Now().Add(time.Duration(input.ExpiresInDays) * 24 * time.Hour)
+		create.ExpiresAt = &expires
+	}
+
+	token, err := s.db.CreateAPIToken(create)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create token")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createAPITokenResponse{APIToken: token, Token: raw})
+}
+
+func (s *Server) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.DeleteAPIToken(urlParam(r, "id")); err != nil {
+		writeDBError(w, err, "token")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
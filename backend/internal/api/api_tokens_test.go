@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func (e *testEnv) requestWithBearer(method, path, token, body string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	e.server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPITokens_ScopesEnforced(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+
+	resp := env.post("/api/auth/tokens", map[string]any{
+		"name":   "ci",
+		"scopes": []string{ScopeTasksWrite},
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var created struct {
+		ID     string   `json:"id"`
+		Token  string   `json:"token"`
+		Scopes []string `json:"scopes"`
+	}
+	decodeResponse(t, resp, &created)
+	if !strings.HasPrefix(created.Token, apiTokenPrefix) {
+		t.Fatalf("expected token with %q prefix, got %q", apiTokenPrefix, created.Token)
+	}
+
+	// tasks:write allows creating and listing tasks.
+	resp = env.requestWithBearer("POST", "/api/projects/"+project.ID+"/tasks", created.Token, `{"title":"From CI"}`)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create task with token: expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := env.requestWithBearer("GET", "/api/tasks", created.Token, ""); resp.Code != http.StatusOK {
+		t.Errorf("list tasks with token: expected 200, got %d", resp.Code)
+	}
+
+	// Other resources need their own scope; account routes are never allowed.
+	if resp := env.requestWithBearer("GET", "/api/projects", created.Token, ""); resp.Code != http.StatusForbidden {
+		t.Errorf("list projects without scope: expected 403, got %d", resp.Code)
+	}
+	if resp := env.requestWithBearer("GET", "/api/auth/tokens", created.Token, ""); resp.Code != http.StatusForbidden {
+		t.Errorf("token management with token: expected 403, got %d", resp.Code)
+	}
+	if resp := env.requestWithBearer("GET", "/api/tasks", apiTokenPrefix+"bogus", ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token: expected 401, got %d", resp.Code)
+	}
+
+	var tokens []db.APIToken
+	decodeResponse(t, env.get("/api/auth/tokens"), &tokens)
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("expected one used token in list, got %+v", tokens)
+	}
+	if strings.Contains(env.get("/api/auth/tokens").Body.String(), created.Token) {
+		t.Error("token list must not expose the secret")
+	}
+
+	if resp := env.delete("/api/auth/tokens/" + created.ID); resp.Code != http.StatusNoContent {
+		t.Fatalf("revoke: expected 204, got %d", resp.Code)
+	}
+	if resp := env.requestWithBearer("GET", "/api/tasks", created.Token, ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: expected 401, got %d", resp.Code)
+	}
+}
+
+func TestAPITokens_InvalidScope(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	resp := env.post("/api/auth/tokens", map[string]any{"name": "ci", "scopes": []string{"admin"}})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.Code)
+	}
+}
+
+func TestRequiredTokenScope(t *testing.T) {
+	cases := []struct {
+		method, pattern, want string
+	}{
+		{"POST", "/api/tasks/{id}/git/push", ScopeGitPush},
+		{"POST", "/api/tasks/{id}/git/commit", ScopeGitWrite},
+		{"GET", "/api/projects/{id}/git/status", ScopeGitRead},
+		{"POST", "/api/tasks/{taskId}/sessions", ScopeSessionsWrite},
+		{"GET", "/api/sessions/{id}", ScopeSessionsRead},
+		{"PATCH", "/api/tasks/{id}", ScopeTasksWrite},
+		{"POST", "/api/projects", ScopeProjectsWrite},
+		{"GET", "/api/projects", ScopeProjectsRead},
+		{"DELETE", "/api/projects/{id}", ""},
+		{"PUT", "/api/preferences/{key}", ""},
+	}
+	for _, tc := range cases {
+		if got := requiredTokenScope(tc.method, tc.pattern); got != tc.want {
+			t.Errorf("%s %s: got %q, want %q", tc.method, tc.pattern, got, tc.want)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
 )
 
 // ArchiveInfo describes an archive file on disk.
@@ -28,8 +31,8 @@ func archivesDir() string {
 	return filepath.Join(home, ".codeburg", "archives")
 }
 
-// handleArchiveProject exports a project to a JSON file and deletes it from the DB.
-func (s *Server) handleArchiveProject(w http.ResponseWriter, r *http.Request) {
+// handleExportProject exports a project to a JSON file and deletes it from the DB.
+func (s *Server) handleExportProject(w http.ResponseWriter, r *http.Request) {
 	projectID := chi.URLParam(r, "id")
 
 	archive, err := s.db.ExportProjectArchive(projectID)
@@ -69,7 +72,7 @@ func (s *Server) handleArchiveProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Broadcast project deletion
-	s.wsHub.BroadcastGlobal("project_deleted", map[string]string{"id": projectID})
+	s.wsHub.BroadcastToProject(projectID, "project_deleted", map[string]string{"id": projectID})
 
 	writeJSON(w, http.StatusOK, map[string]string{
 		"filename": filename,
@@ -131,8 +134,8 @@ func (s *Server) handleListArchives(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, archives)
 }
 
-// handleUnarchiveProject restores a project from an archive file.
-func (s *Server) handleUnarchiveProject(w http.ResponseWriter, r *http.Request) {
+// handleRestoreArchive restores a project from an archive file.
+func (s *Server) handleRestoreArchive(w http.ResponseWriter, r *http.Request) {
 	filename := chi.URLParam(r, "filename")
 
 	// Validate filename (prevent path traversal)
@@ -173,7 +176,7 @@ func (s *Server) handleUnarchiveProject(w http.ResponseWriter, r *http.Request)
 	os.Remove(filePath)
 
 	// Broadcast
-	s.wsHub.BroadcastGlobal("project_created", archive.Project)
+	s.wsHub.BroadcastToProject(archive.Project.ID, "project_created", archive.Project)
 
 	writeJSON(w, http.StatusOK, archive.Project)
 }
@@ -200,6 +203,173 @@ func (s *Server) handleDeleteArchive(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+var errProjectArchived = errors.New("project is archived")
+
+// checkProjectWritable returns errProjectArchived for an archived project,
+// whose tasks are read-only. Writes to task data check it whichever way they
+// come in; archivedProjectGuard refuses HTTP writes before they start.
+func (s *Server) checkProjectWritable(projectID string) error {
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+	if project.ArchivedAt != nil {
+		return errProjectArchived
+	}
+	return nil
+}
+
+type archiveProjectRequest struct {
+	// CleanupWorktrees removes the tasks' worktrees, keeping their branches.
+	CleanupWorktrees bool `json:"cleanupWorktrees,omitempty"`
+}
+
+// handleArchiveProject archives a project in place: it is hidden from the
+// project list, its tasks become read-only and its running sessions stop.
+// Its warm worktrees are removed, and with cleanupWorktrees its tasks' too.
+// Archiving an archived project again only runs the cleanup.
+func (s *Server) handleArchiveProject(w http.ResponseWriter, r *http.Request) {
+	var req archiveProjectRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	project, err := s.db.SetProjectArchived(urlParam(r, "id"), true)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	s.stopProjectSessions(project.ID)
+	s.syncWorktreePool(project.ID)
+	if req.CleanupWorktrees {
+		s.cleanupProjectWorktrees(project)
+	}
+
+	s.wsHub.BroadcastToProject(project.ID, "project_updated", project)
+	writeJSON(w, http.StatusOK, project)
+}
+
+func (s *Server) handleUnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.SetProjectArchived(urlParam(r, "id"), false)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	if worktreePoolEnabled(project) {
+		s.syncWorktreePool(project.ID)
+	}
+	s.wsHub.BroadcastToProject(project.ID, "project_updated", project)
+	writeJSON(w, http.StatusOK, project)
+}
+
+// stopProjectSessions stops the active sessions of a project and its tasks.
+func (s *Server) stopProjectSessions(projectID string) {
+	sessions, err := s.db.ListActiveSessions()
+	if err != nil {
+		slog.Warn("failed to list active sessions", "project_id", projectID, "error", err)
+		return
+	}
+	for _, session := range sessions {
+		if session.ProjectID == projectID {
+			s.stopSession(session)
+		}
+	}
+}
+
+// cleanupProjectWorktrees removes the worktrees of an archived project's
+// tasks. Branches are kept so work can resume after unarchiving.
+func (s *Server) cleanupProjectWorktrees(project *db.Project) {
+	var tasks []*db.Task
+	for _, archived := range []bool{false, true} {
+		list, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID, Archived: &archived})
+		if err != nil {
+			slog.Warn("failed to list tasks for worktree cleanup", "project_id", project.ID, "error", err)
+			return
+		}
+		tasks = append(tasks, list...)
+	}
+	for _, task := range tasks {
+		if task.WorktreePath == nil || *task.WorktreePath == "" {
+			continue
+		}
+		s.removeTaskComparisons(task.ID, project)
+		if err := s.teardownTaskProvision(task, project); err != nil {
+			slog.Warn("failed to tear down task provision", "task_id", task.ID, "error", err)
+		}
+		s.removeTaskContainer(task, project)
+		err := s.worktree.Delete(worktree.DeleteOptions{
+			ProjectPath:    project.Path,
+			WorktreePath:   *task.WorktreePath,
+			TeardownScript: ptrToString(project.TeardownScript),
+		})
+		if err != nil {
+			slog.Warn("failed to remove archived task worktree", "task_id", task.ID, "error", err)
+			continue
+		}
+		empty := ""
+		if _, err := s.db.UpdateTask(task.ID, db.UpdateTaskInput{WorktreePath: &empty}); err != nil {
+			slog.Warn("failed to clear task worktree", "task_id", task.ID, "error", err)
+		}
+		s.diffStats.forget(task.ID)
+	}
+}
+
+// archivedProjectGuard rejects writes to the tasks of archived projects,
+// and sessions starting or taking messages in them, with 409. The task
+// service, attachments and imports check again with checkProjectWritable,
+// for writes that don't come through these routes.
+func (s *Server) archivedProjectGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if projectID := s.guardedProjectID(r.URL.Path); projectID != "" {
+			if project, err := s.db.GetProject(projectID); err == nil && project.ArchivedAt != nil {
+				writeError(w, http.StatusConflict, errProjectArchived.Error())
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// guardedProjectID returns the project a write request changes under the
+// archive rules, or "" for requests they allow. Routes are matched after
+// middleware runs, so this reads the path itself.
+func (s *Server) guardedProjectID(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "api" {
+		return ""
+	}
+	id := parts[2]
+	switch parts[1] {
+	case "tasks":
+		if task, err := s.db.GetTask(id); err == nil {
+			return task.ProjectID
+		}
+	case "projects":
+		if len(parts) == 4 && (parts[3] == "tasks" || parts[3] == "sessions") {
+			return id
+		}
+	case "sessions":
+		if len(parts) == 4 && parts[3] == "message" {
+			if session, err := s.db.GetSession(id); err == nil {
+				return session.ProjectID
+			}
+		}
+	case "comparisons":
+		if comparison, err := s.db.GetComparison(id); err == nil {
+			if task, err := s.db.GetTask(comparison.TaskID); err == nil {
+				return task.ProjectID
+			}
+		}
+	}
+	return ""
+}
+
 func sanitizeFilename(name string) string {
 	// Replace non-alphanumeric chars with hyphens, collapse multiples
 	var b strings.Builder
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+func TestProjectArchive(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.worktree = worktree.NewManager(worktree.Config{BaseDir: t.TempDir()})
+	repoPath := createTestGitRepoWithMain(t)
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "archive-me", "path": repoPath}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Old work"}), &task)
+
+	resp := env.post("/api/tasks/"+task.ID+"/worktree", nil)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create worktree: %d %s", resp.Code, resp.Body.String())
+	}
+	var wt WorktreeResponse
+	decodeResponse(t, resp, &wt)
+
+	resp = env.post("/api/projects/"+project.ID+"/archive", map[string]bool{"cleanupWorktrees": true})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("archive: %d %s", resp.Code, resp.Body.String())
+	}
+	var archived db.Project
+	decodeResponse(t, resp, &archived)
+	if archived.ArchivedAt == nil {
+		t.Fatal("expected archivedAt to be set")
+	}
+
+	// The worktree is gone but its branch is kept.
+	if _, err := os.Stat(wt.WorktreePath); !os.IsNotExist(err) {
+		t.Errorf("expected the worktree removed, stat err = %v", err)
+	}
+	stored, _ := env.server.db.GetTask(task.ID)
+	if stored.WorktreePath != nil && *stored.WorktreePath != "" {
+		t.Errorf("expected the task's worktree path cleared, got %q", *stored.WorktreePath)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", wt.BranchName).Run(); err != nil {
+		t.Errorf("expected branch %q kept: %v", wt.BranchName, err)
+	}
+
+	listed := func(query string) bool {
+		var projects []db.Project
+		decodeResponse(t, env.get("/api/projects"+query), &projects)
+		for _, p := range projects {
+			if p.ID == project.ID {
+				return true
+			}
+		}
+		return false
+	}
+	if listed("") {
+		t.Error("expected the archived project hidden from the default listing")
+	}
+	if !listed("?archived=true") {
+		t.Error("expected the archived project in ?archived=true")
+	}
+
+	// Tasks are read-only and sessions cannot start.
+	if resp := env.get("/api/tasks/" + task.ID); resp.Code != http.StatusOK {
+		t.Errorf("expected reading a task to work, got %d", resp.Code)
+	}
+	writes := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPatch, "/api/tasks/" + task.ID, map[string]string{"title": "New"}},
+		{http.MethodPost, "/api/tasks/" + task.ID + "/sessions", map[string]string{"provider": "terminal"}},
+		{http.MethodPost, "/api/projects/" + project.ID + "/tasks", map[string]string{"title": "More"}},
+		{http.MethodPost, "/api/projects/" + project.ID + "/sessions", map[string]string{"provider": "terminal"}},
+	}
+	for _, w := range writes {
+		if resp := env.request(w.method, w.path, w.body); resp.Code != http.StatusConflict {
+			t.Errorf("%s %s: expected 409, got %d %s", w.method, w.path, resp.Code, resp.Body.String())
+		}
+	}
+
+	// Writes that don't come through those routes are refused too.
+	if _, err := env.server.storeAttachment(task.ID, "notes.txt", "text/plain", strings.NewReader("hi")); !errors.Is(err, errProjectArchived) {
+		t.Errorf("expected storing an attachment refused, got %v", err)
+	}
+	if _, err := env.server.tasks().Create(t.Context(), db.CreateTaskInput{ProjectID: project.ID, Title: "More"}); !isConflict(err) {
+		t.Errorf("expected creating a task refused, got %v", err)
+	}
+	title := "New"
+	if _, err := env.server.tasks().Update(t.Context(), task.ID, db.UpdateTaskInput{Title: &title}); !isConflict(err) {
+		t.Errorf("expected updating a task refused, got %v", err)
+	}
+
+	resp = env.post("/api/projects/"+project.ID+"/unarchive", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unarchive: %d %s", resp.Code, resp.Body.String())
+	}
+	if !listed("") {
+		t.Error("expected the unarchived project listed again")
+	}
+	if resp := env.patch("/api/tasks/"+task.ID, map[string]string{"title": "New"}); resp.Code != http.StatusOK {
+		t.Errorf("expected tasks writable after unarchiving, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	if resp := env.post("/api/projects/nope/archive", nil); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown project, got %d", resp.Code)
+	}
+}
+
+func TestProjectExport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	project := createWorkspaceProject(t, env)
+
+	resp := env.post("/api/projects/"+project.ID+"/export", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", resp.Code, resp.Body.String())
+	}
+	if _, err := env.server.db.GetProject(project.ID); err == nil {
+		t.Error("expected the exported project deleted")
+	}
+}
+
+func isConflict(err error) bool {
+	var svcErr *service.Error
+	return errors.As(err, &svcErr) && errors.Is(svcErr.Kind, service.ErrConflict)
+}
@@ -0,0 +1,534 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+)
+
+const (
+	maxArtifactBytes     = 500 << 20 // per file
+	maxTaskArtifactBytes = 2 << 30   // per task
+
+	defaultArtifactRetention = 14 * 24 * time.Hour
+	maxArtifactRetention     = 90 * 24 * time.Hour
+
+	artifactSweepInterval = time.Hour
+	// maxArtifactLinks caps how many artifact links go into a PR description
+	// or notification.
+	maxArtifactLinks = 10
+)
+
+var (
+	errArtifactTooLarge = fmt.Errorf("artifact exceeds %d MB", maxArtifactBytes>>20)
+	errArtifactQuota    = fmt.Errorf("task artifacts exceed %d MB", maxTaskArtifactBytes>>20)
+	errArtifactNotFile  = errors.New("artifact path is not a regular file")
+	errArtifactPath     = errors.New("invalid artifact path")
+)
+
+func artifactsRoot() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "artifacts")
+}
+
+func taskArtifactsDir(taskID string) string {
+	return filepath.Join(artifactsRoot(), taskID)
+}
+
+func artifactPath(a *db.Artifact) string {
+	return filepath.Join(taskArtifactsDir(a.TaskID), a.ID)
+}
+
+// removeTaskArtifacts deletes the stored files of a task's artifacts.
+func removeTaskArtifacts(taskID string) {
+	if taskID == "" {
+		return
+	}
+	os.RemoveAll(taskArtifactsDir(taskID))
+}
+
+// artifactRetention converts a requested retention in days, defaulting when
+// unset.
+func artifactRetention(days *int) (time.Duration, error) {
+	if days == nil {
+		return defaultArtifactRetention, nil
+	}
+	retention := time.Duration(*days) * 24 * time.Hour
+	if retention <= 0 || retention > maxArtifactRetention {
+		return 0, fmt.Errorf("retentionDays must be between 1 and %d", int(maxArtifactRetention/(24*time.Hour)))
+	}
+	return retention, nil
+}
+
+// publishArtifactInput describes a file to publish from a task's worktree.
+type publishArtifactInput struct {
+	TaskID    string
+	SessionID string
+	Source    string
+	// Path is relative to the worktree.
+	Path string
+	// Name defaults to the file's base name.
+	Name      string
+	Retention time.Duration
+}
+
+// publishArtifact copies a file out of a task's worktree into the artifact
+// store and records it. It enforces the per-file and per-task size limits.
+func (s *Server) publishArtifact(workDir string, input publishArtifactInput) (*db.Artifact, error) {
+	relPath, err := normalizeRelativePath(input.Path, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errArtifactPath, err)
+	}
+	if isProtectedProjectPath(relPath) {
+		return nil, fmt.Errorf("%w: cannot publish files under .git", errArtifactPath)
+	}
+	srcPath, err := safeJoin(workDir, relPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errArtifactPath, err)
+	}
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errArtifactNotFile
+	}
+	if info.Size() > maxArtifactBytes {
+		return nil, errArtifactTooLarge
+	}
+
+	dir := taskArtifactsDir(input.TaskID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, ".publish-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	size, err := io.Copy(tmp, io.LimitReader(src, maxArtifactBytes+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size > maxArtifactBytes {
+		return nil, errArtifactTooLarge
+	}
+
+	used, err := s.db.TaskArtifactsSize(input.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if used+size > maxTaskArtifactBytes {
+		return nil, errArtifactQuota
+	}
+
+	name := sanitizeAttachmentName(input.Name)
+	if strings.TrimSpace(input.Name) == "" {
+		name = sanitizeAttachmentName(filepath.Base(relPath))
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	} else {
+		contentType = "application/octet-stream"
+	}
+	retention := input.Retention
+	if retention <= 0 {
+		retention = defaultArtifactRetention
+	}
+
+	artifact, err := s.db.CreateArtifact(db.CreateArtifactInput{
+		TaskID:      input.TaskID,
+		SessionID:   input.SessionID,
+		Name:        name,
+		Path:        filepath.ToSlash(relPath),
+		Source:      input.Source,
+		ContentType: contentType,
+		Size:        size,
+		ExpiresAt:   time.Now().Add(retention),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), artifactPath(artifact)); err != nil {
+		_ = s.db.DeleteArtifact(artifact.ID)
+		return nil, err
+	}
+
+	s.wsHub.BroadcastToTask(input.TaskID, "artifact_added", s.artifactResponse(artifact))
+	return artifact, nil
+}
+
+// publishArtifactGlobs publishes every file matching the patterns, which are
+// relative to the worktree. A pattern matching nothing is an error, since
+// the files it names were expected to be built.
+func (s *Server) publishArtifactGlobs(workDir string, patterns []string, input publishArtifactInput) ([]*db.Artifact, error) {
+	var published []*db.Artifact
+	for _, pattern := range patterns {
+		relPattern, err := normalizeRelativePath(pattern, false)
+		if err != nil {
+			return published, fmt.Errorf("%s: %w", pattern, err)
+		}
+		matches, err := filepath.Glob(filepath.Join(workDir, relPattern))
+		if err != nil {
+			return published, fmt.Errorf("%s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return published, fmt.Errorf("%s: no such file", pattern)
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(workDir, match)
+			if err != nil {
+				return published, err
+			}
+			in := input
+			in.Path = rel
+			artifact, err := s.publishArtifact(workDir, in)
+			if err != nil {
+				return published, fmt.Errorf("%s: %w", rel, err)
+			}
+			published = append(published, artifact)
+		}
+	}
+	return published, nil
+}
+
+// sessionArtifactEnv tells the processes of a session where to publish
+// artifacts, e.g.
+//
+//	curl -H "Authorization: Bearer $(cat "$CODEBURG_HOOK_TOKEN_FILE")" \
+//	  -d '{"path": "dist/app.zip"}' "$CODEBURG_ARTIFACTS_URL"
+func sessionArtifactEnv(sessionID, tokenPath, apiURL string) []string {
+	if tokenPath == "" {
+		return nil
+	}
+	return []string{
+		"CODEBURG_HOOK_TOKEN_FILE=" + tokenPath,
+		"CODEBURG_ARTIFACTS_URL=" + apiURL + "/api/sessions/" + sessionID + "/artifacts",
+	}
+}
+
+// artifactDownloadPath returns the path of a signed download link for an
+// artifact, usable without logging in until the artifact expires.
+func (s *Server) artifactDownloadPath(a *db.Artifact) (string, error) {
+	token, err := s.auth.GenerateArtifactToken(a.ID, a.ExpiresAt)
+	if err != nil {
+		return "", err
+	}
+	return "/api/artifacts/" + a.ID + "/download?token=" + url.QueryEscape(token), nil
+}
+
+type artifactResponse struct {
+	*db.Artifact
+	URL string `json:"url,omitempty"`
+}
+
+func (s *Server) artifactResponse(a *db.Artifact) artifactResponse {
+	resp := artifactResponse{Artifact: a}
+	if path, err := s.artifactDownloadPath(a); err == nil {
+		resp.URL = path
+	}
+	return resp
+}
+
+// artifactLinks returns absolute download links for up to maxArtifactLinks
+// unexpired artifacts, newest first. It returns nil when no public origin
+// is configured, since relative links are useless outside the web UI.
+func (s *Server) artifactLinks(artifacts []*db.Artifact) []notify.Link {
+	origin := s.webOrigin()
+	if origin == "" {
+		return nil
+	}
+	now := time.Now()
+	var links []notify.Link
+	for _, a := range artifacts {
+		if a.Expired(now) {
+			continue
+		}
+		path, err := s.artifactDownloadPath(a)
+		if err != nil {
+			continue
+		}
+		links = append(links, notify.Link{Title: a.Name, URL: origin + path})
+		if len(links) == maxArtifactLinks {
+			break
+		}
+	}
+	return links
+}
+
+// sessionArtifactLinks returns links to the artifacts a session published
+// on its task, for its notifications.
+func (s *Server) sessionArtifactLinks(taskID, sessionID string) []notify.Link {
+	artifacts, err := s.db.ListArtifacts(taskID)
+	if err != nil {
+		return nil
+	}
+	var own []*db.Artifact
+	for _, a := range artifacts {
+		if ptrToString(a.SessionID) == sessionID {
+			own = append(own, a)
+		}
+	}
+	return s.artifactLinks(own)
+}
+
+// artifactsMarkdown renders a task's artifacts as a Markdown section for a
+// PR description, or "" when there are none to link.
+func (s *Server) artifactsMarkdown(taskID string) string {
+	artifacts, err := s.db.ListArtifacts(taskID)
+	if err != nil {
+		return ""
+	}
+	links := s.artifactLinks(artifacts)
+	if len(links) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("### Artifacts\n\n")
+	for _, link := range links {
+		fmt.Fprintf(&b, "- [%s](%s)\n", link.Title, link.URL)
+	}
+	return b.String()
+}
+
+// sweepArtifacts deletes expired artifacts every artifactSweepInterval.
+func (s *Server) sweepArtifacts(ctx context.Context) {
+	ticker := time.NewTicker(artifactSweepInterval)
+	defer ticker.Stop()
+
+	s.removeExpiredArtifacts()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.removeExpiredArtifacts()
+		}
+	}
+}
+
+// removeExpiredArtifacts runs one sweep. It also removes the stored files of
+// tasks that no longer have any artifacts, e.g. after their project was
+// deleted.
+func (s *Server) removeExpiredArtifacts() {
+	artifacts, err := s.db.ListArtifacts("")
+	if err != nil {
+		slog.Warn("failed to list artifacts", "error", err)
+		return
+	}
+	now := time.Now()
+	live := make(map[string]bool)
+	for _, a := range artifacts {
+		if !a.Expired(now) {
+			live[a.TaskID] = true
+			continue
+		}
+		if err := s.db.DeleteArtifact(a.ID); err != nil {
+			continue
+		}
+		if err := os.Remove(artifactPath(a)); err != nil && !os.IsNotExist(err) {
+			slog.Warn("failed to remove artifact file", "artifact_id", a.ID, "error", err)
+		}
+		s.wsHub.BroadcastToTask(a.TaskID, "artifact_deleted", map[string]string{"id": a.ID})
+	}
+
+	entries, err := os.ReadDir(artifactsRoot())
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !live[entry.Name()] {
+			removeTaskArtifacts(entry.Name())
+		}
+	}
+}
+
+func (s *Server) handleListArtifacts(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	artifacts, err := s.db.ListArtifacts(taskID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list artifacts")
+		return
+	}
+	now := time.Now()
+	resp := make([]artifactResponse, 0, len(artifacts))
+	for _, a := range artifacts {
+		if !a.Expired(now) {
+			resp = append(resp, s.artifactResponse(a))
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type publishArtifactRequest struct {
+	Path          string `json:"path"`
+	Name          string `json:"name"`
+	RetentionDays *int   `json:"retentionDays"`
+}
+
+// handlePublishArtifact publishes a file from the task's worktree.
+func (s *Server) handlePublishArtifact(w http.ResponseWriter, r *http.Request) {
+	workDir, ok := s.resolveTaskWorkDir(w, r)
+	if !ok {
+		return
+	}
+	var req publishArtifactRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.respondPublishArtifact(w, workDir, req, publishArtifactInput{
+		TaskID: urlParam(r, "id"),
+		Source: db.ArtifactSourceAPI,
+	})
+}
+
+// handlePublishSessionArtifact lets a session publish a file from its task's
+// worktree. Like the hook endpoint, it accepts the session's scoped hook
+// token, which sessions find via CODEBURG_HOOK_TOKEN_FILE.
+func (s *Server) handlePublishSessionArtifact(w http.ResponseWriter, r *http.Request) {
+	sessionID := urlParam(r, "id")
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || (!s.auth.ValidateHookToken(token, sessionID) && !s.validLoginToken(token)) {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	taskID := session.TaskID
+	if taskID == "" {
+		writeError(w, http.StatusBadRequest, "session has no task")
+		return
+	}
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	workDir := ptrToString(task.WorktreePath)
+	if workDir == "" {
+		writeError(w, http.StatusBadRequest, "task has no worktree")
+		return
+	}
+
+	var req publishArtifactRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.respondPublishArtifact(w, workDir, req, publishArtifactInput{
+		TaskID:    taskID,
+		SessionID: sessionID,
+		Source:    db.ArtifactSourceSession,
+	})
+}
+
+func (s *Server) respondPublishArtifact(w http.ResponseWriter, workDir string, req publishArtifactRequest, input publishArtifactInput) {
+	retention, err := artifactRetention(req.RetentionDays)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.Path = req.Path
+	input.Name = req.Name
+	input.Retention = retention
+
+	artifact, err := s.publishArtifact(workDir, input)
+	switch {
+	case errors.Is(err, errArtifactTooLarge), errors.Is(err, errArtifactQuota):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, os.ErrNotExist):
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	case errors.Is(err, errArtifactNotFile), errors.Is(err, errArtifactPath):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		slog.Warn("failed to publish artifact", "task_id", input.TaskID, "path", req.Path, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to publish artifact")
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.artifactResponse(artifact))
+}
+
+// handleDownloadArtifact serves an artifact to holders of its signed link.
+// It is a public route so that links work from PR descriptions and chats.
+func (s *Server) handleDownloadArtifact(w http.ResponseWriter, r *http.Request) {
+	id := urlParam(r, "id")
+	if !s.auth.ValidateArtifactToken(r.URL.Query().Get("token"), id) {
+		writeError(w, http.StatusUnauthorized, "invalid or expired link")
+		return
+	}
+	artifact, err := s.db.GetArtifact(id)
+	if err != nil || artifact.Expired(time.Now()) {
+		writeError(w, http.StatusNotFound, "artifact not found")
+		return
+	}
+
+	file, err := os.Open(artifactPath(artifact))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "artifact file is missing")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read artifact")
+		return
+	}
+
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (s *Server) handleDeleteArtifact(w http.ResponseWriter, r *http.Request) {
+	artifact, err := s.db.GetArtifact(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "artifact")
+		return
+	}
+	if err := s.db.DeleteArtifact(artifact.ID); err != nil {
+		writeDBError(w, err, "artifact")
+		return
+	}
+	if err := os.Remove(artifactPath(artifact)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to remove artifact file", "artifact_id", artifact.ID, "error", err)
+	}
+
+	s.wsHub.BroadcastToTask(artifact.TaskID, "artifact_deleted", map[string]string{"id": artifact.ID})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// createArtifactTask returns a task and session whose worktree holds
+// dist/app.zip.
+func createArtifactTask(t *testing.T, env *testEnv) (*db.Task, *db.AgentSession, string) {
+	t.Helper()
+	task, session := createRunningTaskSession(t, env, "claude")
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, "dist"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "dist", "app.zip"), []byte("zipped"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{WorktreePath: &workDir}); err != nil {
+		t.Fatal(err)
+	}
+	return task, session, workDir
+}
+
+func TestArtifacts_Lifecycle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _, workDir := createArtifactTask(t, env)
+
+	resp := env.post("/api/tasks/"+task.ID+"/artifacts", map[string]any{"path": "dist/app.zip", "retentionDays": 3})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var artifact artifactResponse
+	decodeResponse(t, resp, &artifact)
+	if artifact.Name != "app.zip" || artifact.Path != "dist/app.zip" || artifact.Source != db.ArtifactSourceAPI || artifact.Size != 6 {
+		t.Fatalf("unexpected artifact: %+v", artifact.Artifact)
+	}
+	if d := time.Until(artifact.ExpiresAt); d < 71*time.Hour || d > 73*time.Hour {
+		t.Errorf("expected a 3 day retention, expires in %s", d)
+	}
+
+	// The published copy outlives changes to the worktree.
+	os.Remove(filepath.Join(workDir, "dist", "app.zip"))
+
+	var list []artifactResponse
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/artifacts"), &list)
+	if len(list) != 1 || list[0].URL == "" {
+		t.Fatalf("expected the artifact with a link, got %+v", list)
+	}
+
+	resp = env.requestWithToken("GET", list[0].URL, nil, "")
+	if resp.Code != http.StatusOK || resp.Body.String() != "zipped" {
+		t.Fatalf("unexpected download: %d %q", resp.Code, resp.Body.String())
+	}
+	if got := resp.Header().Get("Content-Disposition"); !strings.Contains(got, "filename=app.zip") {
+		t.Errorf("unexpected Content-Disposition: %q", got)
+	}
+	if resp := env.requestWithToken("GET", "/api/artifacts/"+artifact.ID+"/download?token=bogus", nil, ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a bad link token, got %d", resp.Code)
+	}
+
+	if resp := env.delete("/api/artifacts/" + artifact.ID); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if _, err := os.Stat(artifactPath(artifact.Artifact)); !os.IsNotExist(err) {
+		t.Errorf("expected artifact file removed, got %v", err)
+	}
+}
+
+func TestArtifacts_InvalidPaths(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _, _ := createArtifactTask(t, env)
+
+	cases := map[string]int{
+		"../secret":    http.StatusBadRequest,
+		"/etc/passwd":  http.StatusBadRequest,
+		"dist":         http.StatusBadRequest,
+		"missing.txt":  http.StatusNotFound,
+		".git/config":  http.StatusBadRequest,
+		"dist/app.zip": http.StatusCreated,
+	}
+	for path, want := range cases {
+		if resp := env.post("/api/tasks/"+task.ID+"/artifacts", map[string]any{"path": path}); resp.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", path, want, resp.Code, resp.Body.String())
+		}
+	}
+	resp := env.post("/api/tasks/"+task.ID+"/artifacts", map[string]any{"path": "dist/app.zip", "retentionDays": 365})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for too long a retention, got %d", resp.Code)
+	}
+}
+
+func TestArtifacts_PublishFromSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, session, _ := createArtifactTask(t, env)
+
+	hookToken, err := env.server.auth.GenerateHookToken(session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, _ := env.server.auth.GenerateHookToken("other-session")
+
+	path := "/api/sessions/" + session.ID + "/artifacts"
+	if resp := env.requestWithToken("POST", path, map[string]string{"path": "dist/app.zip"}, otherToken); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for another session's token, got %d", resp.Code)
+	}
+	resp := env.requestWithToken("POST", path, map[string]string{"path": "dist/app.zip", "name": "build.zip"}, hookToken)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var artifact artifactResponse
+	decodeResponse(t, resp, &artifact)
+	if artifact.TaskID != task.ID || ptrToString(artifact.SessionID) != session.ID || artifact.Name != "build.zip" || artifact.Source != db.ArtifactSourceSession {
+		t.Fatalf("unexpected artifact: %+v", artifact.Artifact)
+	}
+
+	// Links need a public origin.
+	if links := env.server.sessionArtifactLinks(task.ID, session.ID); links != nil {
+		t.Errorf("expected no links without an origin, got %+v", links)
+	}
+}
+
+func TestRemoveExpiredArtifacts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _, workDir := createArtifactTask(t, env)
+
+	kept, err := env.server.publishArtifact(workDir, publishArtifactInput{TaskID: task.ID, Path: "dist/app.zip", Source: db.ArtifactSourceAPI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := env.server.publishArtifact(workDir, publishArtifactInput{TaskID: task.ID, Path: "dist/app.zip", Source: db.ArtifactSourceAPI, Retention: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphanDir := taskArtifactsDir("deleted-task")
+	if err := os.MkdirAll(orphanDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	env.server.removeExpiredArtifacts()
+
+	if _, err := env.server.db.GetArtifact(expired.ID); err != db.ErrNotFound {
+		t.Errorf("expected expired artifact deleted, got %v", err)
+	}
+	if _, err := os.Stat(artifactPath(expired)); !os.IsNotExist(err) {
+		t.Errorf("expected expired artifact file removed, got %v", err)
+	}
+	if _, err := os.Stat(artifactPath(kept)); err != nil {
+		t.Errorf("expected live artifact kept: %v", err)
+	}
+	if _, err := os.Stat(orphanDir); !os.IsNotExist(err) {
+		t.Errorf("expected orphaned artifact directory removed, got %v", err)
+	}
+}
+
+func TestPublishArtifactGlobs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _, workDir := createArtifactTask(t, env)
+	os.WriteFile(filepath.Join(workDir, "dist", "app.tar.gz"), []byte("tarred"), 0o644)
+
+	published, err := env.server.publishArtifactGlobs(workDir, []string{"dist/app.*"}, publishArtifactInput{TaskID: task.ID, Source: db.ArtifactSourcePipeline})
+	if err != nil || len(published) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d (%v)", len(published), err)
+	}
+	if _, err := env.server.publishArtifactGlobs(workDir, []string{"build/*.bin"}, publishArtifactInput{TaskID: task.ID}); err == nil {
+		t.Error("expected an error for a pattern matching nothing")
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// --- Ask sessions ---
+//
+// An "ask" session is a read-only chat session for questions about the
+// code. Its agent can read and search the work directory but not edit files
+// or run commands, and each question goes out with the places in the code
+// it most likely refers to, found by keyword search, so the agent needs
+// fewer turns of its own looking around.
+
+const (
+	sessionModeAsk = "ask"
+
+	askMaxKeywords     = 8
+	askMaxFiles        = 8
+	askLinesPerFile    = 5
+	askMaxContextBytes = 8 * 1024
+)
+
+var askWordRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]{2,}`)
+
+// askStopWords are words too common in questions to say where to look.
+var askStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "any": true, "can": true, "how": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true, "does": true,
+	"did": true, "this": true, "that": true, "these": true, "those": true, "with": true,
+	"from": true, "into": true, "about": true, "there": true, "their": true, "then": true,
+	"than": true, "have": true, "has": true, "was": true, "were": true, "will": true,
+	"would": true, "should": true, "could": true, "its": true, "our": true, "get": true,
+	"use": true, "used": true, "uses": true, "using": true, "work": true, "works": true,
+	"code": true, "file": true, "files": true, "explain": true, "tell": true, "show": true,
+	"find": true, "happens": true, "some": true, "each": true, "other": true, "like": true,
+}
+
+// askReadOnlyPrelude tells the agent of an ask session what it is for.
+const askReadOnlyPrelude = "You are answering questions about this codebase. Do not modify files or run commands; read and search the code, and cite the files and lines your answer relies on."
+
+// askKeywords picks the words of a question worth searching for, longest
+// first, as those tend to be identifiers.
+func askKeywords(question string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range askWordRe.FindAllString(question, -1) {
+		lower := strings.ToLower(word)
+		if askStopWords[lower] || seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		words = append(words, word)
+	}
+	sort.SliceStable(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	if len(words) > askMaxKeywords {
+		words = words[:askMaxKeywords]
+	}
+	return words
+}
+
+// askContext finds the files under root a question most likely refers to:
+// those matching the most distinct keywords, in their content or path. It
+// returns a few matching lines of each, ready to go before the question, or
+// "" when nothing matches.
+func askContext(ctx context.Context, root, question string) (string, error) {
+	keywords := askKeywords(question)
+	if len(keywords) == 0 {
+		return "", nil
+	}
+	quoted := make([]string, len(keywords))
+	for i, word := range keywords {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	searcher, err := newFileSearcher(fileSearchRequest{
+		Query:      strings.Join(quoted, "|"),
+		Regex:      true,
+		MaxResults: maxSearchResults,
+		MaxPerFile: askLinesPerFile * 4,
+	})
+	if err != nil {
+		return "", err
+	}
+	found, err := searchFiles(ctx, root, searcher)
+	if err != nil {
+		return "", err
+	}
+
+	type rankedFile struct {
+		result   fileSearchResult
+		distinct int
+	}
+	ranked := make([]rankedFile, 0, len(found.Results))
+	for _, result := range found.Results {
+		text := strings.ToLower(result.File)
+		for _, match := range result.Matches {
+			text += "\n" + strings.ToLower(match.Content)
+		}
+		distinct := 0
+		for _, word := range keywords {
+			if strings.Contains(text, strings.ToLower(word)) {
+				distinct++
+			}
+		}
+		ranked = append(ranked, rankedFile{result: result, distinct: distinct})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].distinct != ranked[j].distinct {
+			return ranked[i].distinct > ranked[j].distinct
+		}
+		return len(ranked[i].result.Matches) > len(ranked[j].result.Matches)
+	})
+	if len(ranked) > askMaxFiles {
+		ranked = ranked[:askMaxFiles]
+	}
+	if len(ranked) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Places in the code that may be relevant, found by searching for ")
+	b.WriteString(strings.Join(keywords, ", "))
+	b.WriteString(":\n")
+	for _, file := range ranked {
+		section := "\n" + file.result.File + "\n"
+		for i, match := range file.result.Matches {
+			if i == askLinesPerFile {
+				break
+			}
+			section += fmt.Sprintf("  %d: %s\n", match.Line, strings.TrimSpace(match.Content))
+		}
+		if b.Len()+len(section) > askMaxContextBytes {
+			break
+		}
+		b.WriteString(section)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestAskKeywords(t *testing.T) {
+	got := askKeywords("How does the RateLimiter decide when to reject a request? What is the refill rate?")
+	want := []string{"RateLimiter", "request", "decide", "reject", "refill", "rate"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("askKeywords = %v, want %v", got, want)
+	}
+	if got := askKeywords("how does this work?"); len(got) != 0 {
+		t.Errorf("expected no keywords in a question of stop words, got %v", got)
+	}
+}
+
+func TestAskContext(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"limiter/limiter.go": "package limiter\n\n// RateLimiter refills tokens.\ntype RateLimiter struct{}\n\nfunc (l *RateLimiter) refill() {}\n",
+		"server.go":          "package main\n\nvar limiter = RateLimiter{}\n",
+		"README.md":          "Nothing to see here.\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := askContext(context.Background(), root, "Where does the RateLimiter refill?")
+	if err != nil {
+		t.Fatalf("askContext: %v", err)
+	}
+	first, second := strings.Index(got, "limiter/limiter.go"), strings.Index(got, "server.go")
+	if first < 0 || second < first {
+		t.Fatalf("expected the file matching both keywords first, got:\n%s", got)
+	}
+	if !strings.Contains(got, "  6: func (l *RateLimiter) refill() {}") || strings.Contains(got, "README.md") {
+		t.Errorf("unexpected context:\n%s", got)
+	}
+
+	if got, err := askContext(context.Background(), root, "what about kubernetes?"); err != nil || got != "" {
+		t.Errorf("expected no context without matches, got %q, %v", got, err)
+	}
+}
+
+func TestStartAskSession(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+
+	resp := env.post("/api/projects/"+project.ID+"/sessions", map[string]string{"provider": "terminal", "mode": "ask"})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a terminal ask session, got %d", resp.Code)
+	}
+	resp = env.post("/api/projects/"+project.ID+"/sessions", map[string]string{"provider": "codex", "mode": "edit"})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown mode, got %d", resp.Code)
+	}
+
+	resp = env.post("/api/projects/"+project.ID+"/sessions", map[string]string{"provider": "codex", "mode": "ask"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var session db.AgentSession
+	decodeResponse(t, resp, &session)
+	if !session.ReadOnly || session.SessionType != "chat" {
+		t.Fatalf("expected a read-only chat session, got %+v", session)
+	}
+
+	// A manager that loads the session from the database keeps it read-only.
+	env.server.chat.RemoveSession(session.ID)
+	state, err := env.server.chat.ensureSession(session.ID, "", "")
+	if err != nil {
+		t.Fatalf("ensureSession: %v", err)
+	}
+	if !state.readOnly {
+		t.Error("expected the reloaded session to be read-only")
+	}
+}
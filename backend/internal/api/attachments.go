@@ -0,0 +1,241 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+const (
+	maxAttachmentBytes     = 25 << 20  // per file
+	maxTaskAttachmentBytes = 200 << 20 // per task
+)
+
+var (
+	errAttachmentTooLarge = fmt.Errorf("attachment exceeds %d MB", maxAttachmentBytes>>20)
+	errAttachmentQuota    = fmt.Errorf("task attachments exceed %d MB", maxTaskAttachmentBytes>>20)
+)
+
+// inlineAttachmentTypes are served inline so the UI can preview them. Other
+// types are always downloaded, since serving user-supplied HTML or SVG from
+// this origin would let it run script.
+var inlineAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+func taskAttachmentsDir(taskID string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "attachments", taskID)
+}
+
+func attachmentPath(a *db.Attachment) string {
+	return filepath.Join(taskAttachmentsDir(a.TaskID), a.ID)
+}
+
+// removeTaskAttachments deletes the stored files of a task's attachments.
+func removeTaskAttachments(taskID string) {
+	if taskID == "" {
+		return
+	}
+	os.RemoveAll(taskAttachmentsDir(taskID))
+}
+
+// sanitizeAttachmentName keeps the base name of an uploaded file and drops
+// control characters, which would break the Content-Disposition header.
+func sanitizeAttachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// storeAttachment writes src to the task's attachment directory and records
+// it. It enforces the per-file and per-task size limits.
+func (s *Server) storeAttachment(taskID, filename, contentType string, src io.Reader) (*db.Attachment, error) {
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProjectWritable(task.ProjectID); err != nil {
+		return nil, err
+	}
+	dir := taskAttachmentsDir(taskID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	size, err := io.Copy(tmp, io.LimitReader(src, maxAttachmentBytes+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size > maxAttachmentBytes {
+		return nil, errAttachmentTooLarge
+	}
+
+	used, err := s.db.TaskAttachmentsSize(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if used+size > maxTaskAttachmentBytes {
+		return nil, errAttachmentQuota
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	} else {
+		contentType = "application/octet-stream"
+	}
+	attachment, err := s.db.CreateAttachment(db.CreateAttachmentInput{
+		TaskID:      taskID,
+		Filename:    sanitizeAttachmentName(filename),
+		ContentType: contentType,
+		Size:        size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), attachmentPath(attachment)); err != nil {
+		_ = s.db.DeleteAttachment(attachment.ID)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (s *Server) handleListAttachments(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	attachments, err := s.db.ListAttachments(taskID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list attachments")
+		return
+	}
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// handleUploadAttachment accepts a multipart upload with the file in the
+// "file" field.
+func (s *Server) handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	// Leave room for the multipart framing around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentBytes+64<<10)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, errAttachmentTooLarge.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid multipart upload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := s.storeAttachment(taskID, header.Filename, header.Header.Get("Content-Type"), file)
+	switch {
+	case errors.Is(err, errAttachmentTooLarge), errors.Is(err, errAttachmentQuota):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, errProjectArchived):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		slog.Warn("failed to store attachment", "task_id", taskID, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to store attachment")
+		return
+	}
+
+	s.wsHub.BroadcastToTask(taskID, "attachment_added", attachment)
+	writeJSON(w, http.StatusCreated, attachment)
+}
+
+func (s *Server) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, err := s.db.GetAttachment(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "attachment")
+		return
+	}
+
+	file, err := os.Open(attachmentPath(attachment))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "attachment file is missing")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read attachment")
+		return
+	}
+
+	disposition := "attachment"
+	if inlineAttachmentTypes[attachment.ContentType] {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (s *Server) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, err := s.db.GetAttachment(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "attachment")
+		return
+	}
+	if err := s.db.DeleteAttachment(attachment.ID); err != nil {
+		writeDBError(w, err, "attachment")
+		return
+	}
+	if err := os.Remove(attachmentPath(attachment)); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to remove attachment file", "attachment_id", attachment.ID, "error", err)
+	}
+
+	s.wsHub.BroadcastToTask(attachment.TaskID, "attachment_deleted", map[string]string{"id": attachment.ID})
+	w.WriteHeader(http.StatusNoContent)
+}
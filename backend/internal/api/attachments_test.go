@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func (e *testEnv) uploadAttachment(taskID, filename, contentType string, data []byte) *httptest.ResponseRecorder {
+	e.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	header.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		e.t.Fatalf("create part: %v", err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/api/tasks/"+taskID+"/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+e.token)
+	w := httptest.NewRecorder()
+	e.server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAttachments_Lifecycle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _ := createRunningTaskSession(t, env, "claude")
+
+	resp := env.uploadAttachment(task.ID, "../../notes.txt", "text/plain; charset=utf-8", []byte("hello"))
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var attachment db.Attachment
+	decodeResponse(t, resp, &attachment)
+	if attachment.Filename != "notes.txt" || attachment.ContentType != "text/plain" || attachment.Size != 5 {
+		t.Fatalf("unexpected attachment: %+v", attachment)
+	}
+
+	var list []db.Attachment
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/attachments"), &list)
+	if len(list) != 1 || list[0].ID != attachment.ID {
+		t.Fatalf("expected the uploaded attachment to be listed, got %+v", list)
+	}
+
+	resp = env.get("/api/attachments/" + attachment.ID)
+	if resp.Code != http.StatusOK || resp.Body.String() != "hello" {
+		t.Fatalf("unexpected download: %d %q", resp.Code, resp.Body.String())
+	}
+	if got := resp.Header().Get("Content-Disposition"); !strings.Contains(got, `filename=notes.txt`) {
+		t.Errorf("unexpected Content-Disposition: %q", got)
+	}
+
+	if resp := env.delete("/api/attachments/" + attachment.ID); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if _, err := os.Stat(attachmentPath(&attachment)); !os.IsNotExist(err) {
+		t.Errorf("expected attachment file removed, got %v", err)
+	}
+	if resp := env.get("/api/attachments/" + attachment.ID); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", resp.Code)
+	}
+}
+
+func TestAttachments_Limits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _ := createRunningTaskSession(t, env, "claude")
+
+	if resp := env.uploadAttachment("missing", "a.txt", "text/plain", []byte("x")); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown task, got %d", resp.Code)
+	}
+
+	resp := env.uploadAttachment(task.ID, "big.bin", "application/octet-stream", make([]byte, maxAttachmentBytes+1))
+	if resp.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for oversized file, got %d: %s", resp.Code, resp.Body.String())
+	}
+	list, err := env.server.db.ListAttachments(task.ID)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("expected no attachments recorded, got %+v (%v)", list, err)
+	}
+	entries, _ := os.ReadDir(taskAttachmentsDir(task.ID))
+	if len(entries) != 0 {
+		t.Errorf("expected no leftover files, got %d", len(entries))
+	}
+}
+
+func TestSanitizeAttachmentName(t *testing.T) {
+	cases := map[string]string{
+		"report.pdf":        "report.pdf",
+		"../../etc/passwd":  "passwd",
+		`C:\Users\me\a.png`: "a.png",
+		"bad\r\nname.txt":   "badname.txt",
+		"":                  "attachment",
+		"..":                "attachment",
+		"/":                 "attachment",
+	}
+	for in, want := range cases {
+		if got := sanitizeAttachmentName(in); got != want {
+			t.Errorf("sanitizeAttachmentName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
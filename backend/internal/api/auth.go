@@ -26,22 +26,28 @@ type AuthService struct {
 	jwtSecret  []byte
 }
 
+// Config is the configuration file; see config.go.
 type Config struct {
-	Auth AuthConfig `yaml:"auth"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Server        ServerConfig        `yaml:"server,omitempty"`
+	Tunnels       TunnelsConfig       `yaml:"tunnels,omitempty"`
+	Notifications NotificationsConfig `yaml:"notifications,omitempty"`
 }
 
 type AuthConfig struct {
-	PasswordHash string `yaml:"password_hash"`
-	Origin       string `yaml:"origin,omitempty"`
+	PasswordHash   string             `yaml:"password_hash"`
+	Origin         string             `yaml:"origin,omitempty"`
+	TrustedProxies []string           `yaml:"trusted_proxies,omitempty"` // see proxy_auth.go
+	Cookies        bool               `yaml:"cookies,omitempty"`
+	ForwardAuth    *ForwardAuthConfig `yaml:"forward_auth,omitempty"`
 }
 
 type contextKey string
 
 const userContextKey contextKey = "user"
 
-func NewAuthService() *AuthService {
+func NewAuthService(configPath string) *AuthService {
 	home, _ := os.UserHomeDir()
-	configPath := filepath.Join(home, ".codeburg", "config.yaml")
 
 	// Generate or load JWT secret
 	secretPath := filepath.Join(home, ".codeburg", ".jwt_secret")
@@ -144,8 +150,15 @@ func (a *AuthService) ValidatePassword(password string) bool {
 }
 
 func (a *AuthService) GenerateToken() (string, error) {
+	// A random ID keeps tokens issued in the same second apart, so
+	// revoking one on logout leaves the others.
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
 	claims := jwt.MapClaims{
 		"sub": "user",
+		"jti": hex.EncodeToString(jti),
 		"iat": time.Now().Unix(),
 		"exp": time.Now().Add(7 * 24 * time.Hour).Unix(), // 7 days
 	}
@@ -193,6 +206,49 @@ func (a *AuthService) GenerateHookToken(sessionID string) (string, error) {
 
 // ValidateHookToken checks that a JWT is a valid scoped hook token for the given session.
 func (a *AuthService) ValidateHookToken(tokenString, sessionID string) bool {
+	sid, ok := a.HookTokenSession(tokenString)
+	return ok && sid == sessionID
+}
+
+// HookTokenSession returns the session a valid scoped hook token is for.
+func (a *AuthService) HookTokenSession(tokenString string) (string, bool) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return a.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return "", false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+
+	scope, _ := claims["scope"].(string)
+	sid, _ := claims["sid"].(string)
+	return sid, scope == "session_hook" && sid != ""
+}
+
+// GenerateArtifactToken creates a scoped JWT that can only download one
+// artifact, valid until the artifact expires. It goes into shareable links.
+func (a *AuthService) GenerateArtifactToken(artifactID string, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":   "artifact",
+		"scope": "artifact_download",
+		"aid":   artifactID,
+		"iat":   time.Now().Unix(),
+		"exp":   expiresAt.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(a.jwtSecret)
+}
+
+// ValidateArtifactToken checks that a JWT is a valid download token for the given artifact.
+func (a *AuthService) ValidateArtifactToken(tokenString, artifactID string) bool {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -209,8 +265,8 @@ func (a *AuthService) ValidateHookToken(tokenString, sessionID string) bool {
 	}
 
 	scope, _ := claims["scope"].(string)
-	sid, _ := claims["sid"].(string)
-	return scope == "session_hook" && sid == sessionID
+	aid, _ := claims["aid"].(string)
+	return scope == "artifact_download" && aid == artifactID
 }
 
 // loginRateLimiter tracks failed auth attempts per IP.
@@ -265,15 +321,25 @@ func (rl *loginRateLimiter) reset(ip string) {
 
 // clientIP extracts the real client IP, checking reverse-proxy headers
 // in order: trusted proxy headers (CF-Connecting-IP, X-Forwarded-For), then RemoteAddr.
-// Forwarded headers are only trusted when the direct peer is a trusted proxy
-// (loopback/private/link-local).
+// Forwarded headers are only trusted when the direct peer is a trusted proxy:
+// one of auth.trusted_proxies, as resolved by the clientIPMiddleware, or
+// else a loopback, private or link-local address.
 func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+	return resolveClientIP(r, isTrustedProxy)
+}
+
+// resolveClientIP returns the address of the client behind r, taken from
+// the forwarding headers when trusted accepts the direct peer.
+func resolveClientIP(r *http.Request, trusted func(ip string) bool) string {
 	remoteIP := parseRemoteIP(r.RemoteAddr)
 	if remoteIP == "" {
 		return strings.TrimSpace(r.RemoteAddr)
 	}
 
-	if isTrustedProxy(remoteIP) {
+	if trusted(remoteIP) {
 		if ip := normalizeIP(r.Header.Get("CF-Connecting-IP")); ip != "" {
 			return ip
 		}
@@ -329,7 +395,17 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
 		if auth == "" {
-			writeError(w, http.StatusUnauthorized, "missing authorization header")
+			if !s.ambientLogin(r) {
+				writeError(w, http.StatusUnauthorized, "missing authorization header")
+				return
+			}
+			// Browsers send cookies and proxies add their header to
+			// requests from any page.
+			if r.Method != http.MethodGet && r.Method != http.MethodHead && !s.sameOriginRequest(r) {
+				writeError(w, http.StatusForbidden, "cross-origin request refused")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, "user")))
 			return
 		}
 
@@ -339,7 +415,18 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if !s.auth.ValidateToken(parts[1]) {
+		if strings.HasPrefix(parts[1], apiTokenPrefix) {
+			token := s.authenticateAPIToken(w, r, parts[1])
+			if token == nil {
+				return
+			}
+			ctx := context.WithValue(r.Context(), userContextKey, "user")
+			ctx = context.WithValue(ctx, apiTokenContextKey, token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if !s.validLoginToken(parts[1]) {
 			writeError(w, http.StatusUnauthorized, "invalid token")
 			return
 		}
@@ -360,10 +447,14 @@ func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 		hasTelegram = token != ""
 	}
 
+	_, forwardAuth := s.forwardAuthUser(r)
+
 	writeJSON(w, http.StatusOK, map[string]any{
 		"setup":       s.auth.IsSetup(),
 		"hasPasskeys": hasPasskeys,
 		"hasTelegram": hasTelegram,
+		// The proxy has logged the user in; no login is needed.
+		"forwardAuth": forwardAuth,
 	})
 }
 
@@ -410,6 +501,7 @@ func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
+	s.setSessionCookie(w, token)
 
 	writeJSON(w, http.StatusOK, map[string]string{
 		"token": token,
@@ -444,6 +536,7 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
+	s.setSessionCookie(w, token)
 
 	writeJSON(w, http.StatusOK, map[string]string{
 		"token": token,
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// --- Board columns ---
+//
+// A project's board has the built-in columns plus any it adds, each mapped
+// to the built-in status it counts as. Workflow automation runs when a task
+// moves between categories, so moving it from in_progress to a "blocked"
+// column of category in_progress changes nothing but the column.
+
+const (
+	maxBoardColumns     = 20
+	maxBoardColumnName  = 50
+	maxBoardColumnLimit = 1000
+)
+
+var boardColumnIDRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+var errWIPLimit = errors.New("column is at its WIP limit")
+
+// validateBoardColumns checks a project's board columns and fills in the
+// category of the built-in ones.
+func validateBoardColumns(columns []db.BoardColumn) error {
+	if len(columns) > maxBoardColumns {
+		return fmt.Errorf("at most %d board columns", maxBoardColumns)
+	}
+	seen := make(map[db.TaskStatus]bool)
+	for i := range columns {
+		c := &columns[i]
+		if !boardColumnIDRe.MatchString(string(c.ID)) {
+			return fmt.Errorf("invalid column id %q: use lowercase letters, digits and underscores", c.ID)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("duplicate column %q", c.ID)
+		}
+		seen[c.ID] = true
+		if db.IsBuiltinTaskStatus(c.ID) {
+			if c.Category != "" && c.Category != c.ID {
+				return fmt.Errorf("built-in column %q can't change its category", c.ID)
+			}
+			c.Category = c.ID
+		} else if !db.IsBuiltinTaskStatus(c.Category) {
+			return fmt.Errorf("column %q needs a category: backlog, in_progress, in_review or done", c.ID)
+		}
+		if len(c.Name) > maxBoardColumnName {
+			return fmt.Errorf("column %q name must be at most %d bytes", c.ID, maxBoardColumnName)
+		}
+		if c.WIPLimit < 0 || c.WIPLimit > maxBoardColumnLimit {
+			return fmt.Errorf("column %q WIP limit must be between 0 and %d", c.ID, maxBoardColumnLimit)
+		}
+	}
+	return nil
+}
+
+// removedColumnInUse returns a custom column that columns drop from the
+// project's board while tasks, archived ones included, are still in it.
+func (s *Server) removedColumnInUse(project *db.Project, columns []db.BoardColumn) (db.TaskStatus, error) {
+	kept := make(map[db.TaskStatus]bool, len(columns))
+	for _, c := range columns {
+		kept[c.ID] = true
+	}
+	archived := true
+	for _, c := range project.BoardColumns {
+		if kept[c.ID] || db.IsBuiltinTaskStatus(c.ID) {
+			continue
+		}
+		status := c.ID
+		for _, filter := range []db.TaskFilter{
+			{ProjectID: &project.ID, Status: &status},
+			{ProjectID: &project.ID, Status: &status, Archived: &archived},
+		} {
+			tasks, err := s.db.ListTasks(filter)
+			if err != nil {
+				return "", err
+			}
+			if len(tasks) > 0 {
+				return c.ID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// checkWIPLimit returns an error if the project's column for status is at
+// its WIP limit without the task taskID.
+func (s *Server) checkWIPLimit(project *db.Project, status db.TaskStatus, taskID string) error {
+	column, ok := project.Column(status)
+	if !ok || column.WIPLimit == 0 {
+		return nil
+	}
+	tasks, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID, Status: &status})
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, t := range tasks {
+		if t.ID != taskID {
+			count++
+		}
+	}
+	if count >= column.WIPLimit {
+		return fmt.Errorf("%w: %s holds at most %d tasks", errWIPLimit, column.Name, column.WIPLimit)
+	}
+	return nil
+}
+
+type boardColumnResponse struct {
+	db.BoardColumn
+	Builtin bool `json:"builtin"`
+	Count   int  `json:"count"` // unarchived tasks in the column
+}
+
+// handleGetProjectBoard returns the project's columns in board order, with
+// how many tasks each holds.
+func (s *Server) handleGetProjectBoard(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	tasks, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID})
+	if err != nil {
+		writeListError(w, err, "tasks")
+		return
+	}
+	counts := make(map[db.TaskStatus]int)
+	for _, t := range tasks {
+		counts[t.Status]++
+	}
+
+	columns := make([]boardColumnResponse, 0)
+	for _, c := range project.Board() {
+		columns = append(columns, boardColumnResponse{
+			BoardColumn: c,
+			Builtin:     db.IsBuiltinTaskStatus(c.ID),
+			Count:       counts[c.ID],
+		})
+	}
+	writeJSON(w, http.StatusOK, columns)
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/importer"
+)
+
+// Import sources, as recorded against the tasks they make.
+const (
+	importSourceJira     = "jira"
+	importSourceLinear   = "linear"
+	importSourceCodeburg = "codeburg"
+)
+
+const (
+	maxImportBytes = 20 << 20
+	importTimeout  = 2 * time.Minute
+)
+
+// importRequest is the JSON body of an import that fetches issues itself.
+// With ?source= the body is instead the file to import.
+type importRequest struct {
+	Source string `json:"source"`
+	Data   string `json:"data"` // an export, instead of fetching
+	Jira   *struct {
+		URL   string `json:"url"`
+		Email string `json:"email"`
+		Token string `json:"token"`
+		JQL   string `json:"jql"`
+	} `json:"jira"`
+	Linear *struct {
+		APIKey string `json:"apiKey"`
+		Team   string `json:"team"`
+	} `json:"linear"`
+	Update bool `json:"update"`
+	DryRun bool `json:"dryRun"`
+}
+
+type importResult struct {
+	Source         string   `json:"source"`
+	Created        int      `json:"created"`
+	Updated        int      `json:"updated"`
+	Skipped        int      `json:"skipped"`
+	LabelsCreated  int      `json:"labelsCreated"`
+	ColumnsCreated []string `json:"columnsCreated,omitempty"`
+	DryRun         bool     `json:"dryRun,omitempty"`
+}
+
+var errInvalidImport = errors.New("invalid import")
+
+// handleImportIssues imports issues into a project's board: a Jira CSV
+// export or search, a Linear GraphQL export or team, or a Codeburg board
+// dump. Issues imported before are skipped, or updated with update=true.
+func (s *Server) handleImportIssues(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	if project.ArchivedAt != nil {
+		writeError(w, http.StatusConflict, errProjectArchived.Error())
+		return
+	}
+
+	// The body can carry a whole export, so it gets a larger limit than
+	// other JSON bodies.
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxImportBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	if len(body) > maxImportBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("imports are limited to %d MiB", maxImportBytes>>20))
+		return
+	}
+	var req importRequest
+	if source := r.URL.Query().Get("source"); source != "" {
+		req.Source, req.Data = source, string(body)
+		req.Update = r.URL.Query().Get("update") == "true"
+		req.DryRun = r.URL.Query().Get("dryRun") == "true"
+	} else if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), importTimeout)
+	defer cancel()
+	board, err := readImport(ctx, req)
+	if errors.Is(err, errInvalidImport) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if req.Data == "" {
+			status = http.StatusBadGateway
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+
+	result, err := s.importIssues(project, req.Source, board, req.Update, req.DryRun)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "import failed: "+err.Error())
+		return
+	}
+	if !req.DryRun && result.Created+result.Updated > 0 {
+		s.wsHub.BroadcastToProject(project.ID, "tasks_imported", result)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readImport reads or fetches the issues an import request names, as a
+// board of them.
+func readImport(ctx context.Context, req importRequest) (*importer.Board, error) {
+	var issues []importer.Issue
+	var err error
+	switch req.Source {
+	case importSourceCodeburg:
+		if req.Data == "" {
+			return nil, fmt.Errorf("%w: data is required", errInvalidImport)
+		}
+		return importer.ReadBoard([]byte(req.Data))
+	case importSourceJira:
+		switch {
+		case req.Data != "":
+			issues, err = importer.ParseJiraCSV(strings.NewReader(req.Data))
+		case req.Jira != nil && req.Jira.URL != "" && req.Jira.JQL != "":
+			client := &importer.JiraClient{BaseURL: req.Jira.URL, Email: req.Jira.Email, Token: req.Jira.Token}
+			issues, err = client.Search(ctx, req.Jira.JQL)
+		default:
+			return nil, fmt.Errorf("%w: data, or jira.url and jira.jql, are required", errInvalidImport)
+		}
+	case importSourceLinear:
+		switch {
+		case req.Data != "":
+			issues, err = importer.ParseLinearExport([]byte(req.Data))
+		case req.Linear != nil && req.Linear.APIKey != "":
+			client := &importer.LinearClient{APIKey: req.Linear.APIKey}
+			issues, err = client.Issues(ctx, req.Linear.Team)
+		default:
+			return nil, fmt.Errorf("%w: data or linear.apiKey is required", errInvalidImport)
+		}
+	default:
+		return nil, fmt.Errorf("%w: source must be jira, linear or codeburg", errInvalidImport)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &importer.Board{Tasks: issues}, nil
+}
+
+// importIssues makes or updates a task for each issue of board. Issues map
+// onto the project's columns by status, falling back to their category and
+// then the backlog; a Codeburg dump also brings the custom columns its
+// tasks are in. Labels are created by name as needed.
+func (s *Server) importIssues(project *db.Project, source string, board *importer.Board, update, dryRun bool) (*importResult, error) {
+	result := &importResult{Source: source, DryRun: dryRun}
+
+	if source == importSourceCodeburg {
+		columns := importedColumns(project, board)
+		if len(columns) > 0 {
+			merged := append(slices.Clone(project.BoardColumns), columns...)
+			if err := validateBoardColumns(merged); err != nil {
+				return nil, fmt.Errorf("board columns: %w", err)
+			}
+			for _, c := range columns {
+				result.ColumnsCreated = append(result.ColumnsCreated, string(c.ID))
+			}
+			if !dryRun {
+				updated, err := s.db.UpdateProject(project.ID, db.UpdateProjectInput{BoardColumns: merged})
+				if err != nil {
+					return nil, err
+				}
+				project = updated
+			} else {
+				project.BoardColumns = merged
+			}
+		}
+	}
+
+	existing, err := s.db.ImportedTasks(project.ID, source)
+	if err != nil {
+		return nil, err
+	}
+	if source == importSourceCodeburg {
+		// A dump read back into its own project finds its tasks by ID.
+		tasks, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID})
+		if err != nil {
+			return nil, err
+		}
+		archived := true
+		archivedTasks, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID, Archived: &archived})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range append(tasks, archivedTasks...) {
+			if _, ok := existing[t.ID]; !ok {
+				existing[t.ID] = t.ID
+			}
+		}
+	}
+
+	labels, err := s.db.ListLabels(project.ID)
+	if err != nil {
+		return nil, err
+	}
+	labelIDs := make(map[string]string, len(labels))
+	for _, l := range labels {
+		labelIDs[strings.ToLower(l.Name)] = l.ID
+	}
+
+	for _, issue := range board.Tasks {
+		if strings.TrimSpace(issue.Title) == "" {
+			continue
+		}
+		taskID, found := existing[issue.Key]
+		found = found && issue.Key != ""
+		if found && !update {
+			result.Skipped++
+			continue
+		}
+		for _, name := range issue.Labels {
+			if _, ok := labelIDs[strings.ToLower(name)]; ok {
+				continue
+			}
+			result.LabelsCreated++
+			labelIDs[strings.ToLower(name)] = ""
+			if dryRun {
+				continue
+			}
+			label, err := s.db.CreateLabel(db.CreateLabelInput{ProjectID: project.ID, Name: name})
+			if err != nil {
+				return nil, err
+			}
+			labelIDs[strings.ToLower(name)] = label.ID
+		}
+		if found {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		if dryRun {
+			continue
+		}
+
+		status := importedStatus(project, issue)
+		input := db.UpdateTaskInput{
+			Title:       &issue.Title,
+			Description: importedDescription(source, issue),
+			Status:      &status,
+		}
+		if issue.Priority != "" {
+			input.Priority = &issue.Priority
+		}
+		if issue.Type != "" {
+			input.TaskType = &issue.Type
+		}
+		if source == importSourceCodeburg {
+			input.Pinned = &issue.Pinned
+			if issue.URL != "" {
+				input.PRURL = &issue.URL
+			}
+			if issue.Archived && project.StatusCategory(status) == db.TaskStatusDone {
+				input.SetArchived = &issue.Archived
+			}
+		}
+
+		if !found {
+			task, err := s.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: issue.Title})
+			if err != nil {
+				return nil, err
+			}
+			taskID = task.ID
+			if issue.Key != "" {
+				if err := s.db.RecordTaskImport(project.ID, source, issue.Key, taskID); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if _, err := s.db.UpdateTask(taskID, input); err != nil {
+			return nil, err
+		}
+		for _, name := range issue.Labels {
+			if err := s.db.AssignLabel(taskID, labelIDs[strings.ToLower(name)]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// importedColumns returns the custom columns of a Codeburg dump that the
+// project lacks and that tasks of the dump are in.
+func importedColumns(project *db.Project, board *importer.Board) []db.BoardColumn {
+	dumped := make(map[string]importer.Column, len(board.Columns))
+	for _, c := range board.Columns {
+		dumped[c.ID] = c
+	}
+	var columns []db.BoardColumn
+	added := make(map[db.TaskStatus]bool)
+	for _, t := range board.Tasks {
+		status := db.TaskStatus(t.Status)
+		if t.Status == "" || added[status] || project.StatusCategory(status) != "" {
+			continue
+		}
+		c := dumped[t.Status]
+		category := db.TaskStatus(firstNonEmpty(c.Category, t.Category))
+		if !boardColumnIDRe.MatchString(t.Status) || !db.IsBuiltinTaskStatus(category) {
+			continue
+		}
+		added[status] = true
+		columns = append(columns, db.BoardColumn{ID: status, Name: c.Name, Category: category, WIPLimit: c.WIPLimit})
+	}
+	return columns
+}
+
+// importedStatus is the project's column for an issue: its status if the
+// project has it, else its category, else the backlog.
+func importedStatus(project *db.Project, issue importer.Issue) db.TaskStatus {
+	if status := db.TaskStatus(issue.Status); project.StatusCategory(status) != "" {
+		return status
+	}
+	if category := db.TaskStatus(issue.Category); db.IsBuiltinTaskStatus(category) {
+		return category
+	}
+	return db.TaskStatusBacklog
+}
+
+// importedDescription is an issue's description, with a link back to it for
+// issues from other trackers.
+func importedDescription(source string, issue importer.Issue) *string {
+	description := issue.Description
+	if source != importSourceCodeburg && issue.URL != "" {
+		link := fmt.Sprintf("Imported from [%s](%s)", firstNonEmpty(issue.Key, issue.URL), issue.URL)
+		description = strings.TrimSpace(description + "\n\n" + link)
+	}
+	if description == "" {
+		return nil
+	}
+	return &description
+}
+
+// handleExportBoard writes a project's board as a Codeburg dump, JSON or
+// format=csv, that the import endpoint reads back. archived=true includes
+// archived tasks.
+func (s *Server) handleExportBoard(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	format := firstNonEmpty(r.URL.Query().Get("format"), "json")
+	if format != "json" && format != "csv" {
+		writeError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	tasks, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list tasks")
+		return
+	}
+	if r.URL.Query().Get("archived") == "true" {
+		archived := true
+		archivedTasks, err := s.db.ListTasks(db.TaskFilter{ProjectID: &project.ID, Archived: &archived})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to list tasks")
+			return
+		}
+		tasks = append(tasks, archivedTasks...)
+	}
+
+	board := &importer.Board{Project: project.Name, ExportedAt: time.Now().UTC()}
+	order := make(map[db.TaskStatus]int)
+	for i, c := range project.Board() {
+		order[c.ID] = i
+		board.Columns = append(board.Columns, importer.Column{ID: string(c.ID), Name: c.Name, Category: string(c.Category), WIPLimit: c.WIPLimit})
+	}
+	taskIDs := make([]string, len(tasks))
+	for i, t := range tasks {
+		taskIDs[i] = t.ID
+	}
+	labels, err := s.db.GetTasksLabels(taskIDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list labels")
+		return
+	}
+	slices.SortStableFunc(tasks, func(a, b *db.Task) int {
+		if order[a.Status] != order[b.Status] {
+			return order[a.Status] - order[b.Status]
+		}
+		return a.Position - b.Position
+	})
+	for _, t := range tasks {
+		issue := importer.Issue{
+			Key:      t.ID,
+			Title:    t.Title,
+			Status:   string(t.Status),
+			Type:     t.TaskType,
+			Pinned:   t.Pinned,
+			Archived: t.ArchivedAt != nil,
+		}
+		if t.Category != t.Status {
+			issue.Category = string(t.Category)
+		}
+		if t.Description != nil {
+			issue.Description = *t.Description
+		}
+		if t.Priority != nil {
+			issue.Priority = *t.Priority
+		}
+		if t.PRURL != nil {
+			issue.URL = *t.PRURL
+		}
+		for _, l := range labels[t.ID] {
+			issue.Labels = append(issue.Labels, l.Name)
+		}
+		board.Tasks = append(board.Tasks, issue)
+	}
+
+	var buf bytes.Buffer
+	contentType := "application/json"
+	if format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+		err = board.WriteCSV(&buf)
+	} else {
+		err = board.WriteJSON(&buf)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to write export")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-board.%s"`, sanitizeFilename(project.Name), format))
+	w.Write(buf.Bytes())
+}
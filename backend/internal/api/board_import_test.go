@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// postRaw posts body as is, the way a file is uploaded to the import
+// endpoint.
+func (e *testEnv) postRaw(path, contentType, body string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+e.token)
+	w := httptest.NewRecorder()
+	e.server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestImportJiraCSV(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "jira", "path": createTestGitRepo(t)}), &project)
+	path := "/api/projects/" + project.ID + "/import?source=jira"
+	csv := "Summary,Issue key,Issue Type,Status,Status Category,Priority,Labels,Labels\n" +
+		"Fix login,ABC-1,Bug,In Progress,In Progress,High,auth,web\n" +
+		"Add export,ABC-2,Story,To Do,To Do,,auth,\n"
+
+	var result importResult
+	decodeResponse(t, env.postRaw(path+"&dryRun=true", "text/csv", csv), &result)
+	if result.Created != 2 || result.LabelsCreated != 2 || !result.DryRun {
+		t.Fatalf("unexpected dry run %+v", result)
+	}
+	var tasks []db.Task
+	decodeResponse(t, env.get("/api/tasks?project="+project.ID), &tasks)
+	if len(tasks) != 0 {
+		t.Fatalf("dry run created %d tasks", len(tasks))
+	}
+
+	decodeResponse(t, env.postRaw(path, "text/csv", csv), &result)
+	if result.Created != 2 || result.LabelsCreated != 2 {
+		t.Fatalf("unexpected import %+v", result)
+	}
+	decodeResponse(t, env.get("/api/tasks?project="+project.ID), &tasks)
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %+v", tasks)
+	}
+	byTitle := make(map[string]db.Task)
+	for _, task := range tasks {
+		byTitle[task.Title] = task
+	}
+	fix := byTitle["Fix login"]
+	if fix.Status != db.TaskStatusInProgress || fix.TaskType != "bug" || fix.Priority == nil || *fix.Priority != "high" || len(fix.Labels) != 2 {
+		t.Errorf("unexpected task %+v", fix)
+	}
+
+	// Importing again skips what was imported, unless asked to update.
+	decodeResponse(t, env.postRaw(path, "text/csv", csv), &result)
+	if result.Created != 0 || result.Skipped != 2 {
+		t.Fatalf("unexpected re-import %+v", result)
+	}
+	renamed := strings.Replace(csv, "Fix login", "Fix the login", 1)
+	decodeResponse(t, env.postRaw(path+"&update=true", "text/csv", renamed), &result)
+	if result.Updated != 2 {
+		t.Fatalf("unexpected update %+v", result)
+	}
+	var updated db.Task
+	decodeResponse(t, env.get("/api/tasks/"+fix.ID), &updated)
+	if updated.Title != "Fix the login" {
+		t.Errorf("title = %q", updated.Title)
+	}
+
+	if resp := env.postRaw("/api/projects/"+project.ID+"/import?source=asana", "text/csv", csv); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown source, got %d", resp.Code)
+	}
+	if resp := env.post("/api/projects/"+project.ID+"/import", map[string]any{"source": "jira"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without data or a connection, got %d", resp.Code)
+	}
+
+	// Archived projects' boards are read-only.
+	if resp := env.post("/api/projects/"+project.ID+"/archive", nil); resp.Code != http.StatusOK {
+		t.Fatalf("archive: %d %s", resp.Code, resp.Body.String())
+	}
+	more := csv + "Write docs,ABC-3,Task,To Do,To Do,,,\n"
+	if resp := env.postRaw(path+"&update=true", "text/csv", more); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 importing into an archived project, got %d %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, env.get("/api/tasks?project="+project.ID), &tasks)
+	if len(tasks) != 2 {
+		t.Errorf("expected no task imported into the archived project, got %d", len(tasks))
+	}
+}
+
+func TestBoardExportRoundTrip(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var source, target db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "source", "path": createTestGitRepo(t)}), &source)
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "target", "path": createTestGitRepo(t)}), &target)
+	env.patch("/api/projects/"+source.ID, map[string]any{"boardColumns": []map[string]any{
+		{"id": "blocked", "name": "Blocked", "category": "in_progress", "wipLimit": 3},
+	}})
+	var first, second db.Task
+	decodeResponse(t, env.post("/api/projects/"+source.ID+"/tasks", map[string]string{"title": "First", "description": "Details"}), &first)
+	decodeResponse(t, env.post("/api/projects/"+source.ID+"/tasks", map[string]string{"title": "Second"}), &second)
+	env.patch("/api/tasks/"+second.ID, map[string]string{"status": "blocked"})
+
+	for _, format := range []string{"json", "csv"} {
+		resp := env.get("/api/projects/" + source.ID + "/board/export?format=" + format)
+		if resp.Code != http.StatusOK || !strings.Contains(resp.Header().Get("Content-Disposition"), "source-board."+format) {
+			t.Fatalf("%s export: %d %v", format, resp.Code, resp.Header())
+		}
+		dump := resp.Body.String()
+
+		// Read back into its own project, every task is already there.
+		var result importResult
+		decodeResponse(t, env.postRaw("/api/projects/"+source.ID+"/import?source=codeburg", "application/json", dump), &result)
+		if result.Skipped != 2 || result.Created != 0 {
+			t.Fatalf("%s: unexpected re-import %+v", format, result)
+		}
+
+		decodeResponse(t, env.postRaw("/api/projects/"+target.ID+"/import?source=codeburg", "application/json", dump), &result)
+		if format == "json" && (result.Created != 2 || len(result.ColumnsCreated) != 1) {
+			t.Fatalf("%s: unexpected import %+v", format, result)
+		}
+		if format == "csv" && (result.Created != 0 || result.Skipped != 2) {
+			t.Fatalf("%s: expected the tasks from the JSON import to be found, got %+v", format, result)
+		}
+	}
+
+	var board []boardColumnResponse
+	decodeResponse(t, env.get("/api/projects/"+target.ID+"/board"), &board)
+	for _, c := range board {
+		if c.ID == "blocked" && (c.Count != 1 || c.Name != "Blocked" || c.WIPLimit != 3) {
+			t.Errorf("unexpected column %+v", c)
+		}
+	}
+	var tasks []db.Task
+	decodeResponse(t, env.get("/api/tasks?project="+target.ID), &tasks)
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %+v", tasks)
+	}
+	for _, task := range tasks {
+		if task.Title == "First" && (task.Description == nil || *task.Description != "Details") {
+			t.Errorf("unexpected task %+v", task)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestProjectBoardColumns(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "board", "path": createTestGitRepo(t)}), &project)
+	var first, second db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "First"}), &first)
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Second"}), &second)
+
+	for _, columns := range [][]map[string]any{
+		{{"id": "Blocked", "category": "in_progress"}},
+		{{"id": "blocked"}},
+		{{"id": "blocked", "category": "in_progress"}, {"id": "blocked", "category": "done"}},
+		{{"id": "done", "category": "backlog"}},
+		{{"id": "blocked", "category": "in_progress", "wipLimit": -1}},
+	} {
+		if resp := env.patch("/api/projects/"+project.ID, map[string]any{"boardColumns": columns}); resp.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", columns, resp.Code)
+		}
+	}
+	resp := env.patch("/api/projects/"+project.ID, map[string]any{"boardColumns": []map[string]any{
+		{"id": "blocked", "name": "Blocked", "category": "in_progress", "wipLimit": 1},
+	}})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	if resp := env.patch("/api/tasks/"+first.ID, map[string]string{"status": "qa"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a status with no column, got %d", resp.Code)
+	}
+	var moved db.Task
+	decodeResponse(t, env.patch("/api/tasks/"+first.ID, map[string]string{"status": "blocked"}), &moved)
+	if moved.Status != "blocked" || moved.Category != db.TaskStatusInProgress || moved.StartedAt == nil {
+		t.Fatalf("expected a started task in blocked, got %+v", moved)
+	}
+	if resp := env.patch("/api/tasks/"+second.ID, map[string]string{"status": "blocked"}); resp.Code != http.StatusConflict {
+		t.Errorf("expected the WIP limit to refuse, got %d", resp.Code)
+	}
+
+	var board []boardColumnResponse
+	decodeResponse(t, env.get("/api/projects/"+project.ID+"/board"), &board)
+	if len(board) != 5 || board[1].ID != "in_progress" || board[2].ID != "blocked" || board[2].Count != 1 || board[2].Builtin {
+		t.Fatalf("unexpected board %+v", board)
+	}
+
+	var tasks []db.Task
+	decodeResponse(t, env.get("/api/tasks?category=in_progress"), &tasks)
+	if len(tasks) != 1 || tasks[0].ID != first.ID {
+		t.Errorf("expected the blocked task under in_progress, got %+v", tasks)
+	}
+
+	if resp := env.patch("/api/projects/"+project.ID, map[string]any{"boardColumns": []map[string]any{}}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 removing a column with tasks, got %d", resp.Code)
+	}
+	env.patch("/api/tasks/"+first.ID, map[string]string{"status": "backlog"})
+	if resp := env.patch("/api/projects/"+project.ID, map[string]any{"boardColumns": []map[string]any{}}); resp.Code != http.StatusOK {
+		t.Errorf("expected 200 once the column is empty, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
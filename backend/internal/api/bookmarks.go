@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// maxBookmarkLabel caps a bookmark's label, in bytes.
+const maxBookmarkLabel = 200
+
+type createBookmarkRequest struct {
+	Label string `json:"label"`
+	// Seq marks an earlier message; it defaults to the latest one.
+	Seq *int64 `json:"seq,omitempty"`
+}
+
+type renameBookmarkRequest struct {
+	Label string `json:"label"`
+}
+
+// bookmarkLabel trims and validates a bookmark label.
+func bookmarkLabel(label string) (string, bool) {
+	label = strings.TrimSpace(label)
+	return label, label != "" && len(label) <= maxBookmarkLabel
+}
+
+func (s *Server) handleListBookmarks(w http.ResponseWriter, r *http.Request) {
+	session, err := s.db.GetSession(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	bookmarks, err := s.db.ListBookmarks(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list bookmarks")
+		return
+	}
+	writeJSON(w, http.StatusOK, bookmarks)
+}
+
+// handleCreateBookmark marks the session's latest message, or the one at
+// seq, along with the commit its work directory is on.
+func (s *Server) handleCreateBookmark(w http.ResponseWriter, r *http.Request) {
+	session, err := s.db.GetSession(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+
+	var req createBookmarkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	label, ok := bookmarkLabel(req.Label)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "label is required and must be at most 200 bytes")
+		return
+	}
+
+	lastSeq, err := s.db.GetLastAgentMessageSeq(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read session messages")
+		return
+	}
+	seq := lastSeq
+	if req.Seq != nil {
+		if *req.Seq < 0 || *req.Seq > lastSeq {
+			writeError(w, http.StatusBadRequest, "seq is not a message of this session")
+			return
+		}
+		seq = *req.Seq
+	}
+
+	bookmark, err := s.db.CreateBookmark(db.CreateBookmarkInput{
+		SessionID: session.ID,
+		Label:     label,
+		Seq:       seq,
+		GitHead:   s.sessionGitHead(r.Context(), session),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create bookmark")
+		return
+	}
+	s.wsHub.BroadcastToSession(session.ID, "bookmark_added", bookmark)
+	writeJSON(w, http.StatusCreated, bookmark)
+}
+
+func (s *Server) handleRenameBookmark(w http.ResponseWriter, r *http.Request) {
+	var req renameBookmarkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	label, ok := bookmarkLabel(req.Label)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "label is required and must be at most 200 bytes")
+		return
+	}
+	bookmark, err := s.db.RenameBookmark(urlParam(r, "id"), label)
+	if err != nil {
+		writeDBError(w, err, "bookmark")
+		return
+	}
+	s.wsHub.BroadcastToSession(bookmark.SessionID, "bookmark_updated", bookmark)
+	writeJSON(w, http.StatusOK, bookmark)
+}
+
+func (s *Server) handleDeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	bookmark, err := s.db.GetBookmark(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "bookmark")
+		return
+	}
+	if err := s.db.DeleteBookmark(bookmark.ID); err != nil {
+		writeDBError(w, err, "bookmark")
+		return
+	}
+	s.wsHub.BroadcastToSession(bookmark.SessionID, "bookmark_deleted", map[string]string{"id": bookmark.ID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionGitHead returns the commit a session's work directory is on, or ""
+// when it is not a git repository or has no commits yet.
+func (s *Server) sessionGitHead(ctx context.Context, session *db.AgentSession) string {
+	workDir, err := s.resolveSessionWorkDir(session)
+	if err != nil {
+		slog.Debug("bookmark without git head", "session_id", session.ID, "error", err)
+		return ""
+	}
+	out, err := runGitContext(ctx, workDir, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
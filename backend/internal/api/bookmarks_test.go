@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestSessionBookmarks(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	project := createWorkspaceProject(t, env)
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	for seq := int64(1); seq <= 3; seq++ {
+		env.server.db.CreateAgentMessage(db.CreateAgentMessageInput{SessionID: session.ID, Seq: seq, Kind: "user-text", PayloadJSON: "{}"})
+	}
+	head, err := exec.Command("git", "-C", project.Path, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("rev-parse: %v", err)
+	}
+	url := "/api/sessions/" + session.ID + "/bookmarks"
+
+	if resp := env.post(url, createBookmarkRequest{Label: " "}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a label, got %d", resp.Code)
+	}
+	tooFar := int64(4)
+	if resp := env.post(url, createBookmarkRequest{Label: "later", Seq: &tooFar}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a seq past the last message, got %d", resp.Code)
+	}
+
+	resp := env.post(url, createBookmarkRequest{Label: "test failure reproduced"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var latest db.Bookmark
+	decodeResponse(t, resp, &latest)
+	if latest.Seq != 3 {
+		t.Errorf("expected the latest message marked, got seq %d", latest.Seq)
+	}
+	if latest.GitHead == nil || *latest.GitHead != strings.TrimSpace(string(head)) {
+		t.Errorf("expected git head %s, got %v", head, latest.GitHead)
+	}
+
+	first := int64(1)
+	resp = env.post(url, createBookmarkRequest{Label: "before the refactor", Seq: &first})
+	var earlier db.Bookmark
+	decodeResponse(t, resp, &earlier)
+
+	var list []db.Bookmark
+	decodeResponse(t, env.get(url), &list)
+	if len(list) != 2 || list[0].ID != earlier.ID || list[1].ID != latest.ID {
+		t.Fatalf("expected bookmarks in session order, got %+v", list)
+	}
+
+	resp = env.patch("/api/bookmarks/"+earlier.ID, renameBookmarkRequest{Label: "before refactor"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var renamed db.Bookmark
+	decodeResponse(t, resp, &renamed)
+	if renamed.Label != "before refactor" || renamed.Seq != 1 {
+		t.Errorf("unexpected renamed bookmark: %+v", renamed)
+	}
+
+	if resp := env.delete("/api/bookmarks/" + earlier.ID); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if resp := env.delete("/api/bookmarks/" + earlier.ID); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %d", resp.Code)
+	}
+	if resp := env.get("/api/sessions/nope/bookmarks"); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown session, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+)
+
+// Budgets preference:
+//
+//	budgets  {"enforce": false, "limits": [{"provider": "claude", "projectId": "", "monthlyTokens": 0, "monthlyUsd": 50}]}
+//
+// Each limit caps a provider's usage in a project over a calendar month
+// (UTC); an empty provider or projectId covers them all. A limit can cap
+// tokens, dollars (only Claude reports a cost) or both, and is as used as
+// the higher of the two. Notifications go out when a limit reaches 50, 80
+// and 100%, once each a month. With enforce, sessions can't start on a
+// provider and project a limit over 100% covers, until the month ends or
+// someone logged in overrides it.
+const budgetsPreference = "budgets"
+
+// budgetThresholds are the percentages of a limit that are alerted on.
+var budgetThresholds = []int{50, 80, 100}
+
+type budgetSettings struct {
+	Enforce bool          `json:"enforce"`
+	Limits  []budgetLimit `json:"limits"`
+}
+
+type budgetLimit struct {
+	Provider      string  `json:"provider,omitempty"`
+	ProjectID     string  `json:"projectId,omitempty"`
+	MonthlyTokens int64   `json:"monthlyTokens,omitempty"`
+	MonthlyUSD    float64 `json:"monthlyUsd,omitempty"`
+}
+
+// key identifies a limit for alerts and overrides.
+func (l budgetLimit) key() string {
+	return firstNonEmpty(l.Provider, "*") + "/" + firstNonEmpty(l.ProjectID, "*")
+}
+
+func (l budgetLimit) covers(projectID, provider string) bool {
+	return (l.Provider == "" || l.Provider == provider) && (l.ProjectID == "" || l.ProjectID == projectID)
+}
+
+// percent is how much of the limit used is, rounded down.
+func (l budgetLimit) percent(used db.UsageTotals) int {
+	var p float64
+	if l.MonthlyTokens > 0 {
+		p = float64(used.Tokens()) / float64(l.MonthlyTokens)
+	}
+	if l.MonthlyUSD > 0 {
+		p = math.Max(p, used.CostUSD/l.MonthlyUSD)
+	}
+	return int(p * 100)
+}
+
+type budgetStatus struct {
+	budgetLimit
+	Key           string         `json:"key"`
+	Month         string         `json:"month"`
+	Used          db.UsageTotals `json:"used"`
+	Percent       int            `json:"percent"`
+	Exceeded      bool           `json:"exceeded"`
+	OverrideUntil *time.Time     `json:"overrideUntil,omitempty"`
+	Blocking      bool           `json:"blocking"` // new sessions it covers are refused
+}
+
+// budgetExceededError refuses a session a blocking budget covers.
+type budgetExceededError struct {
+	status budgetStatus
+}
+
+func (e *budgetExceededError) Error() string {
+	return fmt.Sprintf("monthly budget %s is at %d%%; new sessions are blocked until it is overridden or the month ends",
+		e.status.Key, e.status.Percent)
+}
+
+// budgetSettings reads the budgets preference. Limits that cap nothing are
+// dropped.
+func (s *Server) budgetSettings() budgetSettings {
+	var settings budgetSettings
+	pref, err := s.db.GetPreference(db.DefaultUserID, budgetsPreference)
+	if err != nil {
+		return settings
+	}
+	if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+		slog.Warn("invalid budgets preference", "error", err)
+		return budgetSettings{}
+	}
+	limits := settings.Limits[:0]
+	for _, l := range settings.Limits {
+		if l.MonthlyTokens > 0 || l.MonthlyUSD > 0 {
+			limits = append(limits, l)
+		}
+	}
+	settings.Limits = limits
+	return settings
+}
+
+// budgetMonth returns the calendar month (UTC) now falls in, as its name
+// ("2026-10") and bounds.
+func budgetMonth(now time.Time) (string, time.Time, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01"), start, start.AddDate(0, 1, 0)
+}
+
+// budgetStatuses returns how far along each limit is this month.
+func (s *Server) budgetStatuses(settings budgetSettings, now time.Time) ([]budgetStatus, error) {
+	month, start, end := budgetMonth(now)
+	statuses := make([]budgetStatus, 0, len(settings.Limits))
+	for _, l := range settings.Limits {
+		used, err := s.db.SumUsage(db.UsageFilter{ProjectID: l.ProjectID, Provider: l.Provider, Since: start, Until: end})
+		if err != nil {
+			return nil, err
+		}
+		status := budgetStatus{budgetLimit: l, Key: l.key(), Month: month, Used: used, Percent: l.percent(used)}
+		status.Exceeded = status.Percent >= 100
+		if until, err := s.db.GetBudgetOverride(status.Key, month); err == nil && until.After(now) {
+			status.OverrideUntil = &until
+		}
+		status.Blocking = settings.Enforce && status.Exceeded && status.OverrideUntil == nil
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// checkBudget refuses a session on provider in the project when a blocking
+// budget covers it. Terminal sessions don't go through a provider and are
+// never refused.
+func (s *Server) checkBudget(projectID, provider string) error {
+	settings := s.budgetSettings()
+	if !settings.Enforce || len(settings.Limits) == 0 || provider == "terminal" {
+		return nil
+	}
+	statuses, err := s.budgetStatuses(settings, time.Now())
+	if err != nil {
+		slog.Warn("failed to check budgets", "error", err)
+		return nil
+	}
+	for _, status := range statuses {
+		if status.Blocking && status.covers(projectID, provider) {
+			return &budgetExceededError{status: status}
+		}
+	}
+	return nil
+}
+
+// recordUsage stores what a chat turn consumed and alerts on the budgets it
+// pushes over a threshold.
+func (s *Server) recordUsage(usage ChatUsage) {
+	session, err := s.db.GetSession(usage.SessionID)
+	if err != nil {
+		slog.Debug("usage for an unknown session", "session_id", usage.SessionID, "error", err)
+		return
+	}
+	err = s.db.RecordUsage(db.UsageRecord{
+		SessionID:    usage.SessionID,
+		ProjectID:    session.ProjectID,
+		Provider:     firstNonEmpty(usage.Provider, session.Provider),
+		Model:        usage.Model,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		CostUSD:      usage.CostUSD,
+	})
+	if err != nil {
+		slog.Warn("failed to record usage", "session_id", usage.SessionID, "error", err)
+		return
+	}
+	s.checkBudgetAlerts(session.ProjectID, firstNonEmpty(usage.Provider, session.Provider), time.Now())
+}
+
+// checkBudgetAlerts alerts on each limit covering the project and provider
+// that reached a threshold it hadn't this month. A limit that jumps past
+// several thresholds at once is alerted on for the highest.
+func (s *Server) checkBudgetAlerts(projectID, provider string, now time.Time) {
+	settings := s.budgetSettings()
+	if len(settings.Limits) == 0 {
+		return
+	}
+	statuses, err := s.budgetStatuses(settings, now)
+	if err != nil {
+		slog.Warn("failed to check budgets", "error", err)
+		return
+	}
+	for _, status := range statuses {
+		if !status.covers(projectID, provider) {
+			continue
+		}
+		reached := 0
+		for _, threshold := range budgetThresholds {
+			if status.Percent < threshold {
+				break
+			}
+			fresh, err := s.db.MarkBudgetAlert(status.Key, status.Month, threshold)
+			if err != nil {
+				slog.Warn("failed to mark budget alert", "budget", status.Key, "error", err)
+				break
+			}
+			if fresh {
+				reached = threshold
+			}
+		}
+		if reached > 0 {
+			s.notifyBudgetAlert(status, reached, settings.Enforce)
+		}
+	}
+}
+
+// notifyBudgetAlert tells clients and every notification channel that a
+// budget reached threshold percent.
+func (s *Server) notifyBudgetAlert(status budgetStatus, threshold int, enforce bool) {
+	slog.Info("budget threshold reached", "budget", status.Key, "threshold", threshold, "percent", status.Percent)
+	s.wsHub.BroadcastGlobal("budget_alert", map[string]any{"budget": status, "threshold": threshold})
+
+	sinks := s.notificationSinks(eventAttention)
+	if len(sinks) == 0 {
+		return
+	}
+	label := s.budgetLabel(status.budgetLimit)
+	used := budgetUsageText(status)
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		body := localize(lang, msgBudgetUsed, used)
+		if enforce && threshold >= 100 && status.OverrideUntil == nil {
+			body += "\n" + localize(lang, msgBudgetBlocked)
+		}
+		return notify.Message{
+			Title: localize(lang, msgBudgetAlert, label, threshold),
+			Body:  body,
+			URL:   s.deepLink("/settings"),
+		}
+	})
+}
+
+// budgetLabel names what a limit covers: its provider and project.
+func (s *Server) budgetLabel(l budgetLimit) string {
+	label := l.Provider
+	if l.ProjectID != "" {
+		name := l.ProjectID
+		if project, err := s.db.GetProject(l.ProjectID); err == nil {
+			name = project.Name
+		}
+		if label != "" {
+			label += " · "
+		}
+		label += name
+	}
+	return firstNonEmpty(label, "Codeburg")
+}
+
+// budgetUsageText shows usage against the limit, e.g. "$41.20 of $50" or
+// "4.1M of 5M tokens".
+func budgetUsageText(status budgetStatus) string {
+	var parts []string
+	if status.MonthlyUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f of $%s", status.Used.CostUSD, trimFloat(status.MonthlyUSD)))
+	}
+	if status.MonthlyTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%s of %s tokens", formatTokenCount(status.Used.Tokens()), formatTokenCount(status.MonthlyTokens)))
+	}
+	text := parts[0]
+	if len(parts) > 1 {
+		text += ", " + parts[1]
+	}
+	return text
+}
+
+func formatTokenCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return trimFloat(math.Floor(float64(n)/100_000)/10) + "M"
+	case n >= 1_000:
+		return trimFloat(math.Floor(float64(n)/100)/10) + "k"
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func trimFloat(f float64) string {
+	return fmt.Sprint(math.Round(f*100) / 100)
+}
+
+// handleGetBudgets serves GET /api/budgets: how far along each limit of the
+// budgets preference is this month.
+func (s *Server) handleGetBudgets(w http.ResponseWriter, r *http.Request) {
+	settings := s.budgetSettings()
+	statuses, err := s.budgetStatuses(settings, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load usage")
+		return
+	}
+	month, _, _ := budgetMonth(time.Now())
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enforce": settings.Enforce,
+		"month":   month,
+		"budgets": statuses,
+	})
+}
+
+// findBudgetLimit returns the configured limit for provider and projectID.
+func (s *Server) findBudgetLimit(provider, projectID string) (budgetLimit, bool) {
+	for _, l := range s.budgetSettings().Limits {
+		if l.Provider == provider && l.ProjectID == projectID {
+			return l, true
+		}
+	}
+	return budgetLimit{}, false
+}
+
+// handleOverrideBudget serves POST /api/budgets/override, letting sessions
+// start despite an exceeded limit for the next hours, or the rest of the
+// month by default. API tokens can't call it.
+func (s *Server) handleOverrideBudget(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Provider  string `json:"provider"`
+		ProjectID string `json:"projectId"`
+		Hours     int    `json:"hours"`
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.Hours < 0 {
+		writeError(w, http.StatusBadRequest, "hours must be positive")
+		return
+	}
+	limit, ok := s.findBudgetLimit(input.Provider, input.ProjectID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "budget not found")
+		return
+	}
+
+	now := time.Now()
+	month, _, end := budgetMonth(now)
+	until := end
+	if input.Hours > 0 {
+		until = now.Add(time.Duration(input.Hours) * time.Hour)
+		if until.After(end) {
+			until = end
+		}
+	}
+	if err := s.db.SetBudgetOverride(limit.key(), month, until); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to override budget")
+		return
+	}
+	slog.Info("budget overridden", "budget", limit.key(), "until", until)
+	writeJSON(w, http.StatusOK, map[string]any{"key": limit.key(), "month": month, "overrideUntil": until.UTC()})
+}
+
+// handleDeleteBudgetOverride serves DELETE /api/budgets/override?provider=
+// &projectId=, enforcing the limit again.
+func (s *Server) handleDeleteBudgetOverride(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, ok := s.findBudgetLimit(q.Get("provider"), q.Get("projectId"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "budget not found")
+		return
+	}
+	month, _, _ := budgetMonth(time.Now())
+	if err := s.db.DeleteBudgetOverride(limit.key(), month); err != nil {
+		writeDBError(w, err, "budget override")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
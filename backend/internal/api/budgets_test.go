@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestChatManager_ReportsUsage(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	var got []ChatUsage
+	manager.SetUsageHook(func(u ChatUsage) { got = append(got, u) })
+
+	manager.handleClaudePayload(state, map[string]any{
+		"type":           "result",
+		"subtype":        "success",
+		"total_cost_usd": 0.042,
+		"usage": map[string]any{
+			"input_tokens":                float64(12),
+			"cache_creation_input_tokens": float64(300),
+			"cache_read_input_tokens":     float64(2000),
+			"output_tokens":               float64(150),
+		},
+	})
+	manager.handleCodexPayload(state, map[string]any{
+		"type":  "turn.completed",
+		"usage": map[string]any{"input_tokens": float64(900), "cached_input_tokens": float64(800), "output_tokens": float64(40)},
+	})
+	manager.handleCodexPayload(state, map[string]any{
+		"type": "event_msg",
+		"payload": map[string]any{
+			"type": "token_count",
+			"info": map[string]any{"last_token_usage": map[string]any{"input_tokens": float64(50), "output_tokens": float64(5)}},
+		},
+	})
+	// A token count before any turn has no usage.
+	manager.handleCodexPayload(state, map[string]any{"type": "token_count", "info": nil})
+
+	want := []ChatUsage{
+		{SessionID: state.id, Provider: "claude", InputTokens: 2312, OutputTokens: 150, CostUSD: 0.042},
+		{SessionID: state.id, Provider: "codex", InputTokens: 900, OutputTokens: 40},
+		{SessionID: state.id, Provider: "codex", InputTokens: 50, OutputTokens: 5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d usage reports, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("usage %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBudgetAlertsAndEnforcement(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	received := make(chan map[string]any, 4)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	env.server.db.SetPreference(db.DefaultUserID, budgetsPreference,
+		`{"enforce": true, "limits": [{"provider": "claude", "monthlyUsd": 10}, {"provider": "codex", "monthlyTokens": 1000}, {"provider": "noop"}]}`)
+
+	env.server.recordUsage(ChatUsage{SessionID: session.ID, Provider: "claude", InputTokens: 1000, CostUSD: 4})
+	select {
+	case body := <-received:
+		t.Fatalf("expected no alert under 50%%, got %v", body)
+	default:
+	}
+
+	// 40% to 85% skips the 50% alert.
+	env.server.recordUsage(ChatUsage{SessionID: session.ID, Provider: "claude", InputTokens: 1000, CostUSD: 4.5})
+	body := <-received
+	if title, _ := body["title"].(string); !strings.Contains(title, "claude budget at 80%") {
+		t.Errorf("unexpected alert title %q", title)
+	}
+	if msg, _ := body["message"].(string); !strings.Contains(msg, "$8.50 of $10") {
+		t.Errorf("unexpected alert body %q", msg)
+	}
+
+	var budgets struct {
+		Enforce bool           `json:"enforce"`
+		Budgets []budgetStatus `json:"budgets"`
+	}
+	decodeResponse(t, env.get("/api/budgets"), &budgets)
+	if !budgets.Enforce || len(budgets.Budgets) != 2 || budgets.Budgets[0].Percent != 85 || budgets.Budgets[0].Blocking {
+		t.Fatalf("unexpected budgets %+v", budgets)
+	}
+
+	env.server.recordUsage(ChatUsage{SessionID: session.ID, Provider: "claude", CostUSD: 2})
+	body = <-received
+	if msg, _ := body["message"].(string); !strings.Contains(msg, "blocked") {
+		t.Errorf("expected the 100%% alert to say sessions are blocked, got %q", msg)
+	}
+
+	resp := env.post("/api/projects/"+project.ID+"/sessions", map[string]string{"provider": "claude"})
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 over budget, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if err := env.server.checkBudget(project.ID, "codex"); err != nil {
+		t.Errorf("expected codex to be under its own budget, got %v", err)
+	}
+	if err := env.server.checkBudget(project.ID, "terminal"); err != nil {
+		t.Errorf("expected terminal sessions never to be blocked, got %v", err)
+	}
+
+	// API tokens can't override a budget; a logged-in user can.
+	var token struct {
+		Token string `json:"token"`
+	}
+	decodeResponse(t, env.post("/api/auth/tokens", map[string]any{"name": "ci", "scopes": []string{ScopeSessionsWrite}}), &token)
+	if resp := env.requestWithBearer("POST", "/api/budgets/override", token.Token, `{"provider": "claude"}`); resp.Code != http.StatusForbidden {
+		t.Errorf("expected API tokens to be refused, got %d", resp.Code)
+	}
+	if resp := env.post("/api/budgets/override", map[string]string{"provider": "gemini"}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown budget, got %d", resp.Code)
+	}
+	resp = env.post("/api/budgets/override", map[string]any{"provider": "claude", "hours": 2})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if err := env.server.checkBudget(project.ID, "claude"); err != nil {
+		t.Errorf("expected the override to lift the block, got %v", err)
+	}
+
+	if resp := env.delete("/api/budgets/override?provider=claude"); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if err := env.server.checkBudget(project.ID, "claude"); err == nil {
+		t.Error("expected the block back once the override is removed")
+	}
+
+	// Each threshold is alerted on once a month.
+	env.server.checkBudgetAlerts(project.ID, "claude", time.Now())
+	select {
+	case body := <-received:
+		t.Errorf("expected no repeated alert, got %v", body)
+	default:
+	}
+}
+
+func TestBudgetUsageText(t *testing.T) {
+	status := budgetStatus{
+		budgetLimit: budgetLimit{MonthlyTokens: 5_000_000, MonthlyUSD: 50},
+		Used:        db.UsageTotals{InputTokens: 4_000_000, OutputTokens: 123_456, CostUSD: 41.2},
+	}
+	if got := budgetUsageText(status); got != "$41.20 of $50, 4.1M of 5M tokens" {
+		t.Errorf("unexpected usage text %q", got)
+	}
+	if got := formatTokenCount(950); got != "950" {
+		t.Errorf("unexpected count %q", got)
+	}
+	if got := formatTokenCount(12_345); got != "12.3k" {
+		t.Errorf("unexpected count %q", got)
+	}
+}
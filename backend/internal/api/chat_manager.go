@@ -9,12 +9,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
 )
 
 var (
@@ -27,16 +29,23 @@ const chatSubBufferSize = 256
 type ChatTurnResult struct {
 	SessionID   string
 	Err         error
+	ErrorText   string // provider stderr, or Err's message when stderr is empty
 	Interrupted bool
+	TimedOut    bool // the turn went quiet for its Timeout and its process was killed
 }
 
 type StartChatTurnInput struct {
-	SessionID   string
-	Provider    string
-	WorkDir     string
-	Prompt      string
-	Model       string
-	AutoApprove bool
+	SessionID    string
+	Provider     string
+	WorkDir      string
+	Prompt       string
+	Model        string
+	AutoApprove  bool
+	ReadOnly     bool
+	Env          []string      // extra environment for the provider process
+	SystemPrompt string        // project agent instructions prelude
+	Context      string        // sent before the prompt but not shown as part of it
+	Timeout      time.Duration // kills the turn after this long without output; 0 for never
 }
 
 type chatSessionState struct {
@@ -44,6 +53,7 @@ type chatSessionState struct {
 	provider          string
 	model             string
 	autoApprove       bool
+	readOnly          bool // an ask session; fixed when it is created
 	providerSessionID string
 
 	mu       sync.Mutex
@@ -53,14 +63,22 @@ type chatSessionState struct {
 	subs     map[uint64]chan ChatMessage
 	nextSub  uint64
 
-	running bool
-	cancel  context.CancelFunc
+	running  bool
+	cancel   context.CancelFunc
+	watchdog *turnWatchdog // the running turn's timeout, nil without one
+
+	// stdin is the running turn's input pipe when it takes stream-json
+	// input, else nil. stdinMu serializes writes to it.
+	stdin          io.WriteCloser
+	stdinMu        sync.Mutex
+	permissionByID map[string]int
 
 	// Claude Task/subagent normalization state (per active turn).
 	claudeUUIDToProviderSubagent      map[string]string
 	claudePromptToProviderSubagents   map[string][]string
 	claudeProviderToSessionSubagent   map[string]string
 	claudeSessionSubagentTitles       map[string]string
+	claudeSubagentParents             map[string]string // subagent ID -> the subagent that started it
 	claudeBufferedSubagentPayloads    map[string][]map[string]any
 	claudeHiddenParentTaskToolCallIDs map[string]bool
 	claudeStartedSubagents            map[string]bool
@@ -72,6 +90,26 @@ type ChatManager struct {
 
 	mu       sync.RWMutex
 	sessions map[string]*chatSessionState
+
+	// onFinalized receives each message once it will no longer change.
+	onFinalized func(ChatMessage)
+	// onPermission receives each new permission request.
+	onPermission func(ChatMessage)
+	// permissionPolicy denies permission requests without asking the user.
+	permissionPolicy func(sessionID, toolName string, input any) string
+	// onUsage receives what each turn consumed.
+	onUsage func(ChatUsage)
+}
+
+// ChatUsage is what one turn of a chat session consumed, as its provider
+// reports it. Input tokens include cached ones. Only Claude reports a cost.
+type ChatUsage struct {
+	SessionID    string
+	Provider     string
+	Model        string
+	InputTokens  int64
+	OutputTokens int64
+	CostUSD      float64
 }
 
 func NewChatManager(database *db.DB) *ChatManager {
@@ -81,6 +119,51 @@ func NewChatManager(database *db.DB) *ChatManager {
 	}
 }
 
+// SetFinalizedHook registers fn to receive every message once it is final:
+// when appended, or for tool calls when they complete. fn must not block.
+// Call before any session starts.
+func (m *ChatManager) SetFinalizedHook(fn func(ChatMessage)) {
+	m.onFinalized = fn
+}
+
+// SetUsageHook registers fn to receive the usage of every turn that
+// reports it. fn must not block. Call before any session starts.
+func (m *ChatManager) SetUsageHook(fn func(ChatUsage)) {
+	m.onUsage = fn
+}
+
+// reportUsage hands a turn's usage to the usage hook, if it consumed
+// anything.
+func (m *ChatManager) reportUsage(state *chatSessionState, provider string, input, output int64, cost float64) {
+	if m.onUsage == nil || (input == 0 && output == 0 && cost == 0) {
+		return
+	}
+	state.mu.Lock()
+	model := state.model
+	state.mu.Unlock()
+	m.onUsage(ChatUsage{
+		SessionID:    state.id,
+		Provider:     provider,
+		Model:        model,
+		InputTokens:  input,
+		OutputTokens: output,
+		CostUSD:      cost,
+	})
+}
+
+// SetPermissionHook registers fn to receive every permission request as it
+// arrives. fn must not block. Call before any session starts.
+func (m *ChatManager) SetPermissionHook(fn func(ChatMessage)) {
+	m.onPermission = fn
+}
+
+// SetPermissionPolicy registers fn to answer permission requests before
+// the user sees them: a non-empty reason denies the request with it. Call
+// before any session starts.
+func (m *ChatManager) SetPermissionPolicy(fn func(sessionID, toolName string, input any) string) {
+	m.permissionPolicy = fn
+}
+
 func (m *ChatManager) RegisterSession(sessionID, provider, model string, autoApprove bool) error {
 	state, err := m.ensureSession(sessionID, provider, model)
 	if err != nil {
@@ -141,6 +224,37 @@ func (m *ChatManager) Attach(sessionID string) ([]ChatMessage, <-chan ChatMessag
 	return snapshot, ch, cancel, nil
 }
 
+// RecentMessages returns the last limit messages of a session, oldest
+// first.
+func (m *ChatManager) RecentMessages(sessionID string, limit int) ([]ChatMessage, error) {
+	state, err := m.ensureSession(sessionID, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	start := max(len(state.messages)-limit, 0)
+	out := make([]ChatMessage, len(state.messages)-start)
+	copy(out, state.messages[start:])
+	return out, nil
+}
+
+// AppendSystemMessage adds a system message from Codeburg itself, rather
+// than the agent, to a session's conversation.
+func (m *ChatManager) AppendSystemMessage(sessionID, text string, data map[string]any) error {
+	state, err := m.ensureSession(sessionID, "", "")
+	if err != nil {
+		return err
+	}
+	_, err = m.appendMessage(state, ChatMessage{
+		Kind: ChatMessageKindSystem,
+		Text: text,
+		Data: data,
+	})
+	return err
+}
+
 func (m *ChatManager) StartTurn(input StartChatTurnInput) (<-chan ChatTurnResult, error) {
 	if strings.TrimSpace(input.Prompt) == "" {
 		return nil, fmt.Errorf("prompt is required")
@@ -156,9 +270,13 @@ func (m *ChatManager) StartTurn(input StartChatTurnInput) (<-chan ChatTurnResult
 		state.mu.Unlock()
 		return nil, ErrChatTurnBusy
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(context.Background())
 	state.running = true
-	state.cancel = cancel
+	state.cancel = func() { cancel(nil) }
+	state.watchdog = nil
+	if input.Timeout > 0 {
+		state.watchdog = newTurnWatchdog(input.Timeout, func() { cancel(errChatTurnTimedOut) })
+	}
 	resetClaudeTurnTrackingLocked(state)
 	state.mu.Unlock()
 
@@ -172,16 +290,43 @@ func (m *ChatManager) StartTurn(input StartChatTurnInput) (<-chan ChatTurnResult
 
 	resultCh := make(chan ChatTurnResult, 1)
 	go m.runTurn(state, ctx, StartChatTurnInput{
-		SessionID:   input.SessionID,
-		Provider:    state.provider,
-		WorkDir:     input.WorkDir,
-		Prompt:      strings.TrimSpace(input.Prompt),
-		Model:       state.model,
-		AutoApprove: state.autoApprove,
+		SessionID: input.SessionID,
+		Provider:  state.provider,
+		WorkDir:   input.WorkDir,
+		Prompt:    withPrelude(input.Context, strings.TrimSpace(input.Prompt)),
+		Model:     state.model,
+		// Read-only turns never ask for permission: what they may do is
+		// fixed.
+		AutoApprove:  state.autoApprove || state.readOnly,
+		ReadOnly:     state.readOnly,
+		Env:          input.Env,
+		SystemPrompt: input.SystemPrompt,
+		Timeout:      input.Timeout,
 	}, resultCh)
 	return resultCh, nil
 }
 
+// Replay records a finished turn from the provider's output lines, as if it
+// had just run: the prompt, then the messages the lines parse to.
+func (m *ChatManager) Replay(sessionID, prompt string, lines []string) error {
+	state, err := m.ensureSession(sessionID, "", "")
+	if err != nil {
+		return err
+	}
+	resetClaudeTurnTracking(state)
+	m.appendMessage(state, ChatMessage{
+		Kind:      ChatMessageKindUserText,
+		Provider:  state.provider,
+		Role:      "user",
+		Text:      strings.TrimSpace(prompt),
+		CreatedAt: time.Now().UTC(),
+	})
+	for _, line := range lines {
+		m.handleProviderLine(state, state.provider, line)
+	}
+	return nil
+}
+
 func (m *ChatManager) runTurn(state *chatSessionState, ctx context.Context, input StartChatTurnInput, resultCh chan<- ChatTurnResult) {
 	defer close(resultCh)
 
@@ -189,8 +334,17 @@ func (m *ChatManager) runTurn(state *chatSessionState, ctx context.Context, inpu
 	resumeProviderSessionID := state.providerSessionID
 	state.mu.Unlock()
 
-	command, args, err := buildChatTurnCommand(input.Provider, input.Prompt, input.Model, resumeProviderSessionID, input.AutoApprove)
+	_, span := telemetry.Start(ctx, "chat.turn",
+		telemetry.String("session.id", input.SessionID),
+		telemetry.String("session.provider", input.Provider),
+		telemetry.String("session.model", input.Model),
+		telemetry.Bool("chat.resumed", resumeProviderSessionID != ""),
+	)
+	defer span.End()
+
+	command, args, err := buildChatTurnCommand(input.Provider, input.Prompt, input.Model, resumeProviderSessionID, input.AutoApprove, input.ReadOnly, input.SystemPrompt)
 	if err != nil {
+		span.RecordError(err)
 		m.finishTurn(state)
 		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: err}
 		return
@@ -210,26 +364,75 @@ func (m *ChatManager) runTurn(state *chatSessionState, ctx context.Context, inpu
 	if input.WorkDir != "" {
 		cmd.Dir = input.WorkDir
 	}
+	if len(input.Env) > 0 {
+		cmd.Env = append(os.Environ(), input.Env...)
+	}
+	// Killing the process doesn't close pipes its children still hold.
+	cmd.WaitDelay = chatTurnKillGrace
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		turnErr := fmt.Errorf("stdout pipe: %w", err)
+		span.RecordError(turnErr)
 		m.finishTurn(state)
-		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: fmt.Errorf("stdout pipe: %w", err)}
+		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: turnErr}
 		return
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
+		turnErr := fmt.Errorf("stderr pipe: %w", err)
+		span.RecordError(turnErr)
 		m.finishTurn(state)
-		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: fmt.Errorf("stderr pipe: %w", err)}
+		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: turnErr}
 		return
 	}
+	var stdin io.WriteCloser
+	if chatPromptOnStdin(input.Provider, input.AutoApprove) {
+		stdin, err = cmd.StdinPipe()
+		if err != nil {
+			turnErr := fmt.Errorf("stdin pipe: %w", err)
+			span.RecordError(turnErr)
+			m.finishTurn(state)
+			resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: turnErr}
+			return
+		}
+	}
 
+	started := time.Now()
 	if err := cmd.Start(); err != nil {
+		turnErr := fmt.Errorf("start process: %w", err)
+		span.RecordError(turnErr)
 		m.finishTurn(state)
-		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: fmt.Errorf("start process: %w", err)}
+		resultCh <- ChatTurnResult{SessionID: input.SessionID, Err: turnErr}
 		return
 	}
 
+	if stdin != nil {
+		state.mu.Lock()
+		state.stdin = stdin
+		state.mu.Unlock()
+		if err := m.writeStdin(state, claudeUserInput(input.Prompt)); err != nil {
+			slog.Warn("failed to send prompt to provider", "session_id", input.SessionID, "error", err)
+			m.closeStdin(state)
+		}
+	}
+
+	// Once the turn is cancelled, stop reading output the killed process's
+	// children keep open.
+	readDone := make(chan struct{})
+	go func() {
+		select {
+		case <-readDone:
+			return
+		case <-ctx.Done():
+		}
+		select {
+		case <-readDone:
+		case <-time.After(chatTurnKillGrace):
+			_ = stdout.Close()
+		}
+	}()
+
 	var stderrBuf bytes.Buffer
 	var stderrWG sync.WaitGroup
 	stderrWG.Add(1)
@@ -240,19 +443,29 @@ func (m *ChatManager) runTurn(state *chatSessionState, ctx context.Context, inpu
 
 	scanner := bufio.NewScanner(stdout)
 	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	firstOutput := true
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" {
 			continue
 		}
+		if firstOutput {
+			firstOutput = false
+			span.SetAttributes(telemetry.Int("chat.first_output_ms", int(time.Since(started).Milliseconds())))
+		}
 		m.handleProviderLine(state, input.Provider, line)
+		m.touchTurn(state)
 	}
 
+	close(readDone)
 	scanErr := scanner.Err()
+	m.closeStdin(state)
+	m.expirePendingPermissions(state)
 	waitErr := cmd.Wait()
 	stderrWG.Wait()
 
-	interrupted := errors.Is(ctx.Err(), context.Canceled)
+	timedOut := errors.Is(context.Cause(ctx), errChatTurnTimedOut)
+	interrupted := ctx.Err() != nil && !timedOut
 	if interrupted && waitErr != nil {
 		waitErr = nil
 	}
@@ -267,15 +480,30 @@ func (m *ChatManager) runTurn(state *chatSessionState, ctx context.Context, inpu
 	}
 
 	var turnErr error
+	var stderrText string
 	switch {
+	case timedOut:
+		turnErr = fmt.Errorf("turn timed out after %s without output", input.Timeout)
 	case scanErr != nil && !interrupted:
 		turnErr = fmt.Errorf("read output: %w", scanErr)
 	case waitErr != nil && !interrupted:
 		turnErr = fmt.Errorf("process exit: %w", waitErr)
 	}
 
-	if turnErr != nil {
-		stderrText := strings.TrimSpace(stderrBuf.String())
+	span.RecordError(turnErr)
+	span.SetAttributes(telemetry.Bool("chat.interrupted", interrupted), telemetry.Bool("chat.timed_out", timedOut))
+	if timedOut {
+		slog.Warn("chat turn timed out", "session_id", input.SessionID, "provider", input.Provider, "timeout", input.Timeout)
+		stderrText = timedOutText(input.Timeout)
+		m.appendMessage(state, ChatMessage{
+			Kind:      ChatMessageKindSystem,
+			Provider:  input.Provider,
+			Text:      stderrText,
+			Data:      map[string]any{"type": "timeout"},
+			CreatedAt: time.Now().UTC(),
+		})
+	} else if turnErr != nil {
+		stderrText = strings.TrimSpace(stderrBuf.String())
 		if stderrText == "" {
 			stderrText = turnErr.Error()
 		}
@@ -292,15 +520,42 @@ func (m *ChatManager) runTurn(state *chatSessionState, ctx context.Context, inpu
 	resultCh <- ChatTurnResult{
 		SessionID:   input.SessionID,
 		Err:         turnErr,
+		ErrorText:   stderrText,
 		Interrupted: interrupted,
+		TimedOut:    timedOut,
 	}
 }
 
 func (m *ChatManager) finishTurn(state *chatSessionState) {
 	state.mu.Lock()
+	cancel := state.cancel
+	watchdog := state.watchdog
 	state.running = false
 	state.cancel = nil
+	state.watchdog = nil
+	state.mu.Unlock()
+	watchdog.stop()
+	if cancel != nil {
+		cancel() // releases the turn's context
+	}
+}
+
+// touchTurn restarts the running turn's timeout after it showed signs of
+// life, or pauses it while a permission request waits on the user.
+func (m *ChatManager) touchTurn(state *chatSessionState) {
+	state.mu.Lock()
+	watchdog := state.watchdog
+	waiting := false
+	for _, idx := range state.permissionByID {
+		if idx < len(state.messages) {
+			if perm := state.messages[idx].Permission; perm != nil && perm.Status == ChatPermissionPending {
+				waiting = true
+				break
+			}
+		}
+	}
 	state.mu.Unlock()
+	watchdog.touch(waiting)
 }
 
 func (m *ChatManager) handleProviderLine(state *chatSessionState, provider string, line string) {
@@ -416,6 +671,9 @@ func (m *ChatManager) handleClaudePayload(state *chatSessionState, payload map[s
 					if taskTitle != "" {
 						state.claudeSessionSubagentTitles[sessionSubagentForTask] = taskTitle
 					}
+					if sessionSubagentID != "" {
+						state.claudeSubagentParents[sessionSubagentForTask] = sessionSubagentID
+					}
 					state.claudeHiddenParentTaskToolCallIDs[callID] = true
 					buffered := consumeClaudeBufferedSubagentPayloads(state, callID)
 					for _, bufferedPayload := range buffered {
@@ -497,6 +755,12 @@ func (m *ChatManager) handleClaudePayload(state *chatSessionState, payload map[s
 		if sessionID := asString(payload["session_id"]); sessionID != "" {
 			m.updateProviderSessionID(state, sessionID)
 		}
+		// The turn is over; closing its input lets the process exit.
+		m.closeStdin(state)
+		if usage, _ := payload["usage"].(map[string]any); usage != nil {
+			input := asInt64(usage["input_tokens"]) + asInt64(usage["cache_creation_input_tokens"]) + asInt64(usage["cache_read_input_tokens"])
+			m.reportUsage(state, "claude", input, asInt64(usage["output_tokens"]), asFloat64(payload["total_cost_usd"]))
+		}
 		isErr := asBool(payload["is_error"])
 		// Claude result envelopes commonly repeat the assistant text on success.
 		// Keep them only for explicit errors.
@@ -515,6 +779,12 @@ func (m *ChatManager) handleClaudePayload(state *chatSessionState, payload map[s
 		resetClaudeTurnTracking(state)
 
 	case "control_request":
+		request, _ := payload["request"].(map[string]any)
+		requestID := asString(payload["request_id"])
+		if requestID != "" && asString(request["subtype"]) == "can_use_tool" {
+			m.appendPermissionRequest(state, requestID, request)
+			return
+		}
 		m.appendMessage(state, ChatMessage{
 			Kind:      ChatMessageKindSystem,
 			Provider:  "claude",
@@ -522,6 +792,12 @@ func (m *ChatManager) handleClaudePayload(state *chatSessionState, payload map[s
 			Data:      cloneMap(payload),
 			CreatedAt: time.Now().UTC(),
 		})
+		if requestID != "" {
+			m.rejectControlRequest(state, requestID, "unsupported control request")
+		}
+
+	case "control_cancel_request":
+		m.resolvePermission(state, asString(payload["request_id"]), ChatPermissionExpired, "")
 	}
 }
 
@@ -569,6 +845,9 @@ func (m *ChatManager) handleCodexPayload(state *chatSessionState, payload map[st
 		return
 
 	case "turn.completed":
+		if usage, _ := payload["usage"].(map[string]any); usage != nil {
+			m.reportUsage(state, "codex", asInt64(usage["input_tokens"]), asInt64(usage["output_tokens"]), 0)
+		}
 		return
 
 	case "item.started":
@@ -780,6 +1059,10 @@ func (m *ChatManager) handleCodexPayload(state *chatSessionState, payload map[st
 		})
 
 	case "token_count":
+		info, _ := payload["info"].(map[string]any)
+		if usage, _ := info["last_token_usage"].(map[string]any); usage != nil {
+			m.reportUsage(state, "codex", asInt64(usage["input_tokens"]), asInt64(usage["output_tokens"]), 0)
+		}
 		return
 
 	default:
@@ -869,6 +1152,15 @@ func (m *ChatManager) finishToolCall(state *chatSessionState, provider, callID s
 	msg.Tool.Result = result
 	msg.Tool.IsError = isErr
 	state.messages[idx] = msg
+	state.mu.Unlock()
+
+	m.publishUpdate(state, msg)
+}
+
+// publishUpdate persists a message that changed in place and sends it to
+// subscribers as final.
+func (m *ChatManager) publishUpdate(state *chatSessionState, msg ChatMessage) {
+	state.mu.Lock()
 	subs := make([]chan ChatMessage, 0, len(state.subs))
 	for _, ch := range state.subs {
 		subs = append(subs, ch)
@@ -877,7 +1169,7 @@ func (m *ChatManager) finishToolCall(state *chatSessionState, provider, callID s
 
 	if payload, err := json.Marshal(msg); err == nil {
 		if err := m.db.UpdateAgentMessagePayload(msg.ID, string(msg.Kind), string(payload)); err != nil && !errors.Is(err, db.ErrNotFound) {
-			slog.Warn("failed to persist tool call update", "session_id", state.id, "message_id", msg.ID, "error", err)
+			slog.Warn("failed to persist chat message update", "session_id", state.id, "message_id", msg.ID, "error", err)
 		}
 	}
 
@@ -887,6 +1179,9 @@ func (m *ChatManager) finishToolCall(state *chatSessionState, provider, callID s
 		default:
 		}
 	}
+	if m.onFinalized != nil {
+		m.onFinalized(msg)
+	}
 }
 
 func resetClaudeTurnTrackingLocked(state *chatSessionState) {
@@ -894,6 +1189,7 @@ func resetClaudeTurnTrackingLocked(state *chatSessionState) {
 	state.claudePromptToProviderSubagents = make(map[string][]string)
 	state.claudeProviderToSessionSubagent = make(map[string]string)
 	state.claudeSessionSubagentTitles = make(map[string]string)
+	state.claudeSubagentParents = make(map[string]string)
 	state.claudeBufferedSubagentPayloads = make(map[string][]map[string]any)
 	state.claudeHiddenParentTaskToolCallIDs = make(map[string]bool)
 	state.claudeStartedSubagents = make(map[string]bool)
@@ -906,6 +1202,9 @@ func resetClaudeTurnTracking(state *chatSessionState) {
 	state.mu.Unlock()
 }
 
+// ensureClaudeSessionSubagent registers the subagent a Task tool call
+// starts. Its ID is the call's ID, so it is the same when the transcript is
+// replayed.
 func ensureClaudeSessionSubagent(state *chatSessionState, providerSubagentID string) string {
 	if providerSubagentID == "" {
 		return ""
@@ -916,9 +1215,8 @@ func ensureClaudeSessionSubagent(state *chatSessionState, providerSubagentID str
 	if existing := state.claudeProviderToSessionSubagent[providerSubagentID]; existing != "" {
 		return existing
 	}
-	created := db.NewID()
-	state.claudeProviderToSessionSubagent[providerSubagentID] = created
-	return created
+	state.claudeProviderToSessionSubagent[providerSubagentID] = providerSubagentID
+	return providerSubagentID
 }
 
 func normalizeClaudePrompt(prompt string) string {
@@ -1067,6 +1365,8 @@ func (m *ChatManager) markClaudeSubagentStarted(state *chatSessionState, session
 	if sessionSubagentID == "" {
 		return
 	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
 	if state.claudeStartedSubagents == nil {
 		state.claudeStartedSubagents = make(map[string]bool)
 	}
@@ -1081,9 +1381,8 @@ func (m *ChatManager) markClaudeSubagentStopped(state *chatSessionState, session
 	if sessionSubagentID == "" {
 		return
 	}
-	if state.claudeActiveSubagents == nil {
-		return
-	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
 	delete(state.claudeActiveSubagents, sessionSubagentID)
 }
 
@@ -1097,6 +1396,9 @@ func claudeSubagentData(state *chatSessionState, sessionSubagentID string) map[s
 	if title := state.claudeSessionSubagentTitles[sessionSubagentID]; title != "" {
 		data["subagentTitle"] = title
 	}
+	if parent := state.claudeSubagentParents[sessionSubagentID]; parent != "" {
+		data["parentSubagentId"] = parent
+	}
 	return data
 }
 
@@ -1143,6 +1445,9 @@ func (m *ChatManager) appendMessage(state *chatSessionState, msg ChatMessage) (C
 	if msg.Tool != nil && msg.Tool.CallID != "" {
 		state.toolByID[msg.Tool.CallID] = idx
 	}
+	if msg.Permission != nil {
+		state.permissionByID[msg.Permission.RequestID] = idx
+	}
 	state.mu.Unlock()
 
 	for _, ch := range subs {
@@ -1151,6 +1456,11 @@ func (m *ChatManager) appendMessage(state *chatSessionState, msg ChatMessage) (C
 		default:
 		}
 	}
+	pending := msg.Tool != nil && msg.Tool.State == ChatToolStateRunning ||
+		msg.Permission != nil && msg.Permission.Status == ChatPermissionPending
+	if m.onFinalized != nil && !pending {
+		m.onFinalized(msg)
+	}
 	return msg, nil
 }
 
@@ -1207,13 +1517,16 @@ func (m *ChatManager) ensureSession(sessionID, provider, model string) (*chatSes
 		id:                                sessionID,
 		provider:                          firstNonEmpty(provider, dbSession.Provider),
 		model:                             model,
+		readOnly:                          dbSession.ReadOnly,
 		toolByID:                          make(map[string]int),
+		permissionByID:                    make(map[string]int),
 		subs:                              make(map[uint64]chan ChatMessage),
 		providerSessionID:                 firstNonEmpty(stringPtrValue(dbSession.ProviderSessionID), ""),
 		claudeUUIDToProviderSubagent:      make(map[string]string),
 		claudePromptToProviderSubagents:   make(map[string][]string),
 		claudeProviderToSessionSubagent:   make(map[string]string),
 		claudeSessionSubagentTitles:       make(map[string]string),
+		claudeSubagentParents:             make(map[string]string),
 		claudeBufferedSubagentPayloads:    make(map[string][]map[string]any),
 		claudeHiddenParentTaskToolCallIDs: make(map[string]bool),
 		claudeStartedSubagents:            make(map[string]bool),
@@ -1221,27 +1534,21 @@ func (m *ChatManager) ensureSession(sessionID, provider, model string) (*chatSes
 	}
 
 	for _, row := range messages {
-		var msg ChatMessage
-		if err := json.Unmarshal([]byte(row.PayloadJSON), &msg); err != nil {
+		msg, ok := chatMessageFromRow(row, state.provider)
+		if !ok {
 			continue
 		}
-		if msg.ID == "" {
-			msg.ID = row.ID
-		}
-		if msg.Seq == 0 {
-			msg.Seq = row.Seq
-		}
-		msg.SessionID = sessionID
-		if msg.Provider == "" {
-			msg.Provider = state.provider
-		}
-		if msg.CreatedAt.IsZero() {
-			msg.CreatedAt = row.CreatedAt
-		}
 		state.messages = append(state.messages, msg)
 		if msg.Tool != nil && msg.Tool.CallID != "" {
 			state.toolByID[msg.Tool.CallID] = len(state.messages) - 1
 		}
+		if msg.Permission != nil {
+			// Whatever process asked is gone, so nobody can answer it.
+			if msg.Permission.Status == ChatPermissionPending {
+				msg.Permission.Status = ChatPermissionExpired
+			}
+			state.permissionByID[msg.Permission.RequestID] = len(state.messages) - 1
+		}
 		if msg.Seq > state.seq {
 			state.seq = msg.Seq
 		}
@@ -1257,6 +1564,29 @@ func (m *ChatManager) ensureSession(sessionID, provider, model string) (*chatSes
 	return state, nil
 }
 
+// chatMessageFromRow decodes a stored chat message, filling in fields older
+// payloads lack from the row. It reports false for unreadable payloads.
+func chatMessageFromRow(row *db.AgentMessage, provider string) (ChatMessage, bool) {
+	var msg ChatMessage
+	if err := json.Unmarshal([]byte(row.PayloadJSON), &msg); err != nil {
+		return ChatMessage{}, false
+	}
+	if msg.ID == "" {
+		msg.ID = row.ID
+	}
+	if msg.Seq == 0 {
+		msg.Seq = row.Seq
+	}
+	msg.SessionID = row.SessionID
+	if msg.Provider == "" {
+		msg.Provider = provider
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = row.CreatedAt
+	}
+	return msg, true
+}
+
 func codexCommandSummary(raw any) string {
 	switch v := raw.(type) {
 	case string:
@@ -1328,6 +1658,16 @@ func asBool(v any) bool {
 	return b
 }
 
+func asInt64(v any) int64 {
+	f, _ := v.(float64)
+	return int64(f)
+}
+
+func asFloat64(v any) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
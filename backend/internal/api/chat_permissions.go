@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPermissionRequestNotFound = errors.New("permission request not found")
+	ErrPermissionRequestResolved = errors.New("permission request already answered or expired")
+)
+
+// defaultDenyMessage is what the agent is told when a request is denied
+// without a reason.
+const defaultDenyMessage = "The user denied this action."
+
+// claudeUserInput is a user turn in Claude's stream-json input format.
+func claudeUserInput(prompt string) map[string]any {
+	return map[string]any{
+		"type": "user",
+		"message": map[string]any{
+			"role":    "user",
+			"content": []map[string]any{{"type": "text", "text": prompt}},
+		},
+	}
+}
+
+// writeStdin sends one stream-json line to the running turn.
+func (m *ChatManager) writeStdin(state *chatSessionState, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	state.stdinMu.Lock()
+	defer state.stdinMu.Unlock()
+	state.mu.Lock()
+	stdin := state.stdin
+	state.mu.Unlock()
+	if stdin == nil {
+		return ErrPermissionRequestResolved
+	}
+	_, err = stdin.Write(append(data, '\n'))
+	return err
+}
+
+// closeStdin ends the running turn's input, if it has any.
+func (m *ChatManager) closeStdin(state *chatSessionState) {
+	state.stdinMu.Lock()
+	defer state.stdinMu.Unlock()
+	state.mu.Lock()
+	stdin := state.stdin
+	state.stdin = nil
+	state.mu.Unlock()
+	if stdin != nil {
+		stdin.Close()
+	}
+}
+
+func (m *ChatManager) appendPermissionRequest(state *chatSessionState, requestID string, request map[string]any) {
+	toolName := firstNonEmpty(asString(request["tool_name"]), "tool")
+	var denial string
+	if m.permissionPolicy != nil {
+		denial = m.permissionPolicy(state.id, toolName, request["input"])
+	}
+	status := ChatPermissionPending
+	if denial != "" {
+		status = ChatPermissionDenied
+	}
+	msg, err := m.appendMessage(state, ChatMessage{
+		Kind:     ChatMessageKindPermission,
+		Provider: "claude",
+		Text:     "Allow " + toolName + "?",
+		Permission: &ChatPermissionRequest{
+			RequestID: requestID,
+			ToolName:  toolName,
+			ToolUseID: asString(request["tool_use_id"]),
+			Input:     request["input"],
+			Status:    status,
+			Message:   denial,
+		},
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	if denial != "" {
+		m.denyByPolicy(state, requestID, denial)
+		return
+	}
+	if m.onPermission != nil {
+		m.onPermission(msg)
+	}
+}
+
+// denyByPolicy answers a permission request the policy denied.
+func (m *ChatManager) denyByPolicy(state *chatSessionState, requestID, reason string) {
+	err := m.writeStdin(state, map[string]any{
+		"type": "control_response",
+		"response": map[string]any{
+			"subtype":    "success",
+			"request_id": requestID,
+			"response":   map[string]any{"behavior": "deny", "message": reason},
+		},
+	})
+	if err != nil && !errors.Is(err, ErrPermissionRequestResolved) {
+		slog.Warn("failed to deny permission request", "session_id", state.id, "request_id", requestID, "error", err)
+	}
+}
+
+// rejectControlRequest answers a control request Codeburg does not handle,
+// so the provider does not wait on it.
+func (m *ChatManager) rejectControlRequest(state *chatSessionState, requestID, reason string) {
+	err := m.writeStdin(state, map[string]any{
+		"type": "control_response",
+		"response": map[string]any{
+			"subtype":    "error",
+			"request_id": requestID,
+			"error":      reason,
+		},
+	})
+	if err != nil && !errors.Is(err, ErrPermissionRequestResolved) {
+		slog.Warn("failed to reject control request", "session_id", state.id, "request_id", requestID, "error", err)
+	}
+}
+
+// RespondPermission answers a pending permission request of a session's
+// running turn and returns the updated message. message is passed to the
+// agent as the reason when denying.
+func (m *ChatManager) RespondPermission(sessionID, requestID string, allow bool, message string) (ChatMessage, error) {
+	state, err := m.ensureSession(sessionID, "", "")
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	state.mu.Lock()
+	idx, ok := state.permissionByID[requestID]
+	if !ok || idx >= len(state.messages) {
+		state.mu.Unlock()
+		return ChatMessage{}, ErrPermissionRequestNotFound
+	}
+	perm := state.messages[idx].Permission
+	if perm == nil || perm.Status != ChatPermissionPending {
+		state.mu.Unlock()
+		return ChatMessage{}, ErrPermissionRequestResolved
+	}
+	input := perm.Input
+	state.mu.Unlock()
+
+	decision := map[string]any{"behavior": "allow", "updatedInput": input}
+	status := ChatPermissionAllowed
+	message = strings.TrimSpace(message)
+	if !allow {
+		status = ChatPermissionDenied
+		decision = map[string]any{"behavior": "deny", "message": firstNonEmpty(message, defaultDenyMessage)}
+	}
+	err = m.writeStdin(state, map[string]any{
+		"type": "control_response",
+		"response": map[string]any{
+			"subtype":    "success",
+			"request_id": requestID,
+			"response":   decision,
+		},
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPermissionRequestResolved) {
+			slog.Warn("failed to send permission response", "session_id", sessionID, "request_id", requestID, "error", err)
+		}
+		m.resolvePermission(state, requestID, ChatPermissionExpired, "")
+		return ChatMessage{}, ErrPermissionRequestResolved
+	}
+
+	if !allow {
+		message = firstNonEmpty(message, defaultDenyMessage)
+	}
+	msg, ok := m.resolvePermission(state, requestID, status, message)
+	if !ok {
+		return ChatMessage{}, ErrPermissionRequestResolved
+	}
+	return msg, nil
+}
+
+// resolvePermission moves a pending request to status. It reports false when
+// the request is unknown or no longer pending.
+func (m *ChatManager) resolvePermission(state *chatSessionState, requestID string, status ChatPermissionStatus, message string) (ChatMessage, bool) {
+	state.mu.Lock()
+	idx, ok := state.permissionByID[requestID]
+	if !ok || idx >= len(state.messages) {
+		state.mu.Unlock()
+		return ChatMessage{}, false
+	}
+	msg := state.messages[idx]
+	if msg.Permission == nil || msg.Permission.Status != ChatPermissionPending {
+		state.mu.Unlock()
+		return ChatMessage{}, false
+	}
+	updated := *msg.Permission
+	updated.Status = status
+	updated.Message = message
+	msg.Permission = &updated
+	state.messages[idx] = msg
+	state.mu.Unlock()
+
+	m.publishUpdate(state, msg)
+	m.touchTurn(state)
+	return msg, true
+}
+
+// expirePendingPermissions marks the requests the ending turn left
+// unanswered.
+func (m *ChatManager) expirePendingPermissions(state *chatSessionState) {
+	state.mu.Lock()
+	var pending []string
+	for requestID, idx := range state.permissionByID {
+		if idx < len(state.messages) {
+			if perm := state.messages[idx].Permission; perm != nil && perm.Status == ChatPermissionPending {
+				pending = append(pending, requestID)
+			}
+		}
+	}
+	state.mu.Unlock()
+
+	for _, requestID := range pending {
+		m.resolvePermission(state, requestID, ChatPermissionExpired, "")
+	}
+}
+
+type permissionDecisionRequest struct {
+	Decision string `json:"decision"` // "allow" or "deny"
+	Message  string `json:"message,omitempty"`
+}
+
+// handleRespondPermission answers a chat session's pending permission
+// request.
+func (s *Server) handleRespondPermission(w http.ResponseWriter, r *http.Request) {
+	sessionID := urlParam(r, "id")
+	var req permissionDecisionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Decision != "allow" && req.Decision != "deny" {
+		writeError(w, http.StatusBadRequest, "decision must be allow or deny")
+		return
+	}
+
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	if session.SessionType != "chat" {
+		writeError(w, http.StatusBadRequest, "session is not a chat session")
+		return
+	}
+
+	msg, err := s.chat.RespondPermission(sessionID, urlParam(r, "requestId"), req.Decision == "allow", req.Message)
+	switch {
+	case errors.Is(err, ErrPermissionRequestNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrPermissionRequestResolved):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "failed to answer permission request")
+	default:
+		writeJSON(w, http.StatusOK, msg)
+	}
+}
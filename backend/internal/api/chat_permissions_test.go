@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+type recordingStdin struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (r *recordingStdin) Close() error {
+	r.closed = true
+	return nil
+}
+
+// lines decodes the stream-json lines written so far.
+func (r *recordingStdin) lines(t *testing.T) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(r.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var v map[string]any
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("invalid stdin line %q: %v", line, err)
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func claudePermissionRequest(requestID, command string) map[string]any {
+	return map[string]any{
+		"type":       "control_request",
+		"request_id": requestID,
+		"request": map[string]any{
+			"subtype":     "can_use_tool",
+			"tool_name":   "Bash",
+			"tool_use_id": "toolu_1",
+			"input":       map[string]any{"command": command},
+		},
+	}
+}
+
+func TestChatManager_PermissionRequestAllow(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	stdin := &recordingStdin{}
+	state.stdin = stdin
+	var notified []ChatMessage
+	manager.SetPermissionHook(func(msg ChatMessage) { notified = append(notified, msg) })
+
+	manager.handleClaudePayload(state, claudePermissionRequest("req-1", "rm -rf build"))
+
+	if len(state.messages) != 1 || state.messages[0].Kind != ChatMessageKindPermission {
+		t.Fatalf("expected a permission request message, got %+v", state.messages)
+	}
+	perm := state.messages[0].Permission
+	if perm == nil || perm.RequestID != "req-1" || perm.ToolName != "Bash" || perm.Status != ChatPermissionPending {
+		t.Fatalf("unexpected permission: %+v", perm)
+	}
+	if len(notified) != 1 || notified[0].Permission.RequestID != "req-1" {
+		t.Fatalf("expected the permission hook to be called, got %+v", notified)
+	}
+
+	msg, err := manager.RespondPermission(state.id, "req-1", true, "")
+	if err != nil {
+		t.Fatalf("respond: %v", err)
+	}
+	if msg.Permission.Status != ChatPermissionAllowed {
+		t.Errorf("expected allowed, got %q", msg.Permission.Status)
+	}
+
+	lines := stdin.lines(t)
+	if len(lines) != 1 || lines[0]["type"] != "control_response" {
+		t.Fatalf("expected one control response, got %+v", lines)
+	}
+	response := lines[0]["response"].(map[string]any)
+	decision := response["response"].(map[string]any)
+	if response["request_id"] != "req-1" || decision["behavior"] != "allow" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if input := decision["updatedInput"].(map[string]any); input["command"] != "rm -rf build" {
+		t.Errorf("expected the original input passed back, got %+v", input)
+	}
+
+	if _, err := manager.RespondPermission(state.id, "req-1", false, ""); !errors.Is(err, ErrPermissionRequestResolved) {
+		t.Errorf("expected ErrPermissionRequestResolved answering twice, got %v", err)
+	}
+	if _, err := manager.RespondPermission(state.id, "nope", true, ""); !errors.Is(err, ErrPermissionRequestNotFound) {
+		t.Errorf("expected ErrPermissionRequestNotFound, got %v", err)
+	}
+
+	// The stored message reflects the answer.
+	rows, _ := manager.db.ListAgentMessagesBySession(state.id)
+	stored, _ := chatMessageFromRow(rows[0], "claude")
+	if stored.Permission == nil || stored.Permission.Status != ChatPermissionAllowed {
+		t.Errorf("expected the stored message updated, got %+v", stored.Permission)
+	}
+}
+
+func TestChatManager_PermissionRequestDenyAndExpire(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	stdin := &recordingStdin{}
+	state.stdin = stdin
+
+	manager.handleClaudePayload(state, claudePermissionRequest("req-1", "git push"))
+	manager.handleClaudePayload(state, claudePermissionRequest("req-2", "curl example.com"))
+	manager.handleClaudePayload(state, claudePermissionRequest("req-3", "make"))
+
+	msg, err := manager.RespondPermission(state.id, "req-1", false, "")
+	if err != nil {
+		t.Fatalf("respond: %v", err)
+	}
+	if msg.Permission.Status != ChatPermissionDenied || msg.Permission.Message != defaultDenyMessage {
+		t.Errorf("unexpected denied permission: %+v", msg.Permission)
+	}
+	decision := stdin.lines(t)[0]["response"].(map[string]any)["response"].(map[string]any)
+	if decision["behavior"] != "deny" || decision["message"] != defaultDenyMessage {
+		t.Errorf("unexpected deny decision: %+v", decision)
+	}
+
+	manager.handleClaudePayload(state, map[string]any{"type": "control_cancel_request", "request_id": "req-2"})
+	if got := state.messages[1].Permission.Status; got != ChatPermissionExpired {
+		t.Errorf("expected a canceled request to expire, got %q", got)
+	}
+
+	// The result ends the turn's input; what is still pending expires.
+	manager.handleClaudePayload(state, map[string]any{"type": "result", "subtype": "success"})
+	if !stdin.closed {
+		t.Error("expected stdin closed on result")
+	}
+	if _, err := manager.RespondPermission(state.id, "req-3", true, ""); !errors.Is(err, ErrPermissionRequestResolved) {
+		t.Errorf("expected answering after the turn to fail, got %v", err)
+	}
+	if got := state.messages[2].Permission.Status; got != ChatPermissionExpired {
+		t.Errorf("expected an unanswerable request to expire, got %q", got)
+	}
+}
+
+func TestChatManager_UnsupportedControlRequestRejected(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	stdin := &recordingStdin{}
+	state.stdin = stdin
+
+	manager.handleClaudePayload(state, map[string]any{
+		"type":       "control_request",
+		"request_id": "req-1",
+		"request":    map[string]any{"subtype": "hook_callback"},
+	})
+
+	if len(state.messages) != 1 || state.messages[0].Kind != ChatMessageKindSystem {
+		t.Fatalf("expected a system message, got %+v", state.messages)
+	}
+	lines := stdin.lines(t)
+	if len(lines) != 1 || lines[0]["response"].(map[string]any)["subtype"] != "error" {
+		t.Fatalf("expected an error control response, got %+v", lines)
+	}
+}
+
+func TestChatManager_PendingPermissionExpiresOnReload(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	state.stdin = &recordingStdin{}
+	manager.handleClaudePayload(state, claudePermissionRequest("req-1", "ls"))
+
+	reloaded := NewChatManager(manager.db)
+	reloadedState, err := reloaded.ensureSession(state.id, "claude", "")
+	if err != nil {
+		t.Fatalf("ensure session: %v", err)
+	}
+	if got := reloadedState.messages[0].Permission.Status; got != ChatPermissionExpired {
+		t.Errorf("expected a pending request from a previous run to be expired, got %q", got)
+	}
+}
+
+func TestRespondPermission_API(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "perm", Path: t.TempDir()})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	state, err := env.server.chat.ensureSession(session.ID, "claude", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.stdin = &recordingStdin{}
+	env.server.chat.handleClaudePayload(state, claudePermissionRequest("req-1", "ls"))
+
+	path := "/api/sessions/" + session.ID + "/permissions/req-1"
+	if resp := env.post(path, map[string]string{"decision": "maybe"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad decision, got %d", resp.Code)
+	}
+	if resp := env.post("/api/sessions/"+session.ID+"/permissions/other", map[string]string{"decision": "allow"}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown request, got %d", resp.Code)
+	}
+
+	resp := env.post(path, map[string]string{"decision": "deny", "message": "not now"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var msg ChatMessage
+	decodeResponse(t, resp, &msg)
+	if msg.Permission == nil || msg.Permission.Status != ChatPermissionDenied || msg.Permission.Message != "not now" {
+		t.Fatalf("unexpected response: %+v", msg.Permission)
+	}
+	if resp := env.post(path, map[string]string{"decision": "allow"}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 answering twice, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// Subagent statuses.
+const (
+	subagentRunning   = "running"
+	subagentCompleted = "completed"
+)
+
+// chatSubagent is a Claude subagent started with the Task tool, with what
+// it did. Its ID is the subagentId in the data of its messages.
+type chatSubagent struct {
+	ID           string          `json:"id"`
+	ParentID     string          `json:"parentId,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Status       string          `json:"status"`
+	MessageCount int             `json:"messageCount"`
+	StartedAt    time.Time       `json:"startedAt"`
+	LastActiveAt time.Time       `json:"lastActiveAt"`
+	DurationMs   int64           `json:"durationMs"`
+	Children     []*chatSubagent `json:"children"`
+}
+
+// activeSubagents returns the IDs of the subagents working in a session's
+// running turn.
+func (m *ChatManager) activeSubagents(sessionID string) map[string]bool {
+	m.mu.RLock()
+	state := m.sessions[sessionID]
+	m.mu.RUnlock()
+	active := make(map[string]bool)
+	if state == nil {
+		return active
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if !state.running {
+		return active
+	}
+	for id := range state.claudeActiveSubagents {
+		active[id] = true
+	}
+	return active
+}
+
+// subagentTree groups a transcript's subagent messages into the subagents
+// that sent them, nested under the subagent that started each, in the
+// order they started. A running subagent's duration runs to now.
+func subagentTree(messages []ChatMessage, active map[string]bool, now time.Time) []*chatSubagent {
+	byID := make(map[string]*chatSubagent)
+	var order []*chatSubagent
+	for _, msg := range messages {
+		id, _ := msg.Data["subagentId"].(string)
+		if id == "" {
+			continue
+		}
+		sub := byID[id]
+		if sub == nil {
+			sub = &chatSubagent{ID: id, Status: subagentCompleted, StartedAt: msg.CreatedAt, Children: []*chatSubagent{}}
+			byID[id] = sub
+			order = append(order, sub)
+		}
+		if title, _ := msg.Data["subagentTitle"].(string); title != "" {
+			sub.Title = title
+		}
+		if parent, _ := msg.Data["parentSubagentId"].(string); parent != "" {
+			sub.ParentID = parent
+		}
+		sub.MessageCount++
+		if msg.CreatedAt.After(sub.LastActiveAt) {
+			sub.LastActiveAt = msg.CreatedAt
+		}
+	}
+
+	roots := make([]*chatSubagent, 0)
+	for _, sub := range order {
+		end := sub.LastActiveAt
+		if active[sub.ID] {
+			sub.Status = subagentRunning
+			end = now
+		}
+		sub.DurationMs = end.Sub(sub.StartedAt).Milliseconds()
+		if parent := byID[sub.ParentID]; parent != nil && parent != sub {
+			parent.Children = append(parent.Children, sub)
+		} else {
+			roots = append(roots, sub)
+		}
+	}
+	return roots
+}
+
+// handleListSessionSubagents returns the tree of a chat session's
+// subagents, so clients can collapse each one's messages.
+func (s *Server) handleListSessionSubagents(w http.ResponseWriter, r *http.Request) {
+	session, err := s.db.GetSession(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	rows, err := s.db.ListAgentMessagesBySession(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list messages")
+		return
+	}
+	messages := make([]ChatMessage, 0, len(rows))
+	for _, row := range rows {
+		if msg, ok := chatMessageFromRow(row, session.Provider); ok {
+			messages = append(messages, msg)
+		}
+	}
+	writeJSON(w, http.StatusOK, subagentTree(messages, s.chat.activeSubagents(session.ID), time.Now()))
+}
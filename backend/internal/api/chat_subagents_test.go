@@ -0,0 +1,77 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubagentTree(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	state.running = true
+
+	taskCall := func(parent, id, description string) map[string]any {
+		return map[string]any{
+			"type":               "assistant",
+			"parent_tool_use_id": parent,
+			"message": map[string]any{
+				"role": "assistant",
+				"content": []any{map[string]any{
+					"type":  "tool_use",
+					"id":    id,
+					"name":  "Task",
+					"input": map[string]any{"prompt": description + " now", "description": description},
+				}},
+			},
+		}
+	}
+	text := func(parent, text string) map[string]any {
+		return map[string]any{
+			"type":               "assistant",
+			"parent_tool_use_id": parent,
+			"message": map[string]any{
+				"role":    "assistant",
+				"content": []any{map[string]any{"type": "text", "text": text}},
+			},
+		}
+	}
+
+	manager.handleClaudePayload(state, taskCall("", "task-1", "Explore the repo"))
+	manager.handleClaudePayload(state, text("task-1", "Looking around"))
+	manager.handleClaudePayload(state, taskCall("task-1", "task-2", "Read the tests"))
+	manager.handleClaudePayload(state, text("task-2", "Found them"))
+	manager.handleClaudePayload(state, text("task-2", "All pass"))
+	manager.handleClaudePayload(state, map[string]any{
+		"type":               "user",
+		"parent_tool_use_id": "task-1",
+		"message": map[string]any{
+			"role":    "user",
+			"content": []any{map[string]any{"type": "tool_result", "tool_use_id": "task-2", "content": "done"}},
+		},
+	})
+	manager.handleClaudePayload(state, text("", "Both done"))
+
+	if id := state.messages[0].Data["subagentId"]; id != "task-1" {
+		t.Fatalf("expected the Task call ID as the subagent ID, got %v", id)
+	}
+
+	tree := subagentTree(state.messages, manager.activeSubagents(state.id), time.Now())
+	if len(tree) != 1 {
+		t.Fatalf("expected one top-level subagent, got %d", len(tree))
+	}
+	root := tree[0]
+	if root.ID != "task-1" || root.Title != "Explore the repo" || root.Status != subagentRunning || root.MessageCount != 1 {
+		t.Errorf("unexpected top-level subagent %+v", root)
+	}
+	if len(root.Children) != 1 {
+		t.Fatalf("expected one nested subagent, got %d", len(root.Children))
+	}
+	child := root.Children[0]
+	if child.ID != "task-2" || child.ParentID != "task-1" || child.Title != "Read the tests" || child.Status != subagentCompleted || child.MessageCount != 2 {
+		t.Errorf("unexpected nested subagent %+v", child)
+	}
+
+	state.running = false
+	if tree := subagentTree(state.messages, manager.activeSubagents(state.id), time.Now()); tree[0].Status != subagentCompleted {
+		t.Errorf("expected subagents of a finished turn completed, got %s", tree[0].Status)
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Chat turn timeout preference:
+//
+//	chat_turn_timeout  {"providers": {"claude": 90, "codex": 60}, "default": 60}
+//
+// A chat turn whose provider prints nothing for its timeout, in minutes,
+// has its process killed; the session fails through the session lifecycle
+// with a "turn timed out" message in its conversation, so a wedged
+// provider no longer keeps the session busy. Each line of output starts
+// the wait over, and it is paused while a permission request waits on the
+// user, so a long turn that keeps working is never cut short. Providers
+// not listed use default, 60 minutes when unset, and 0 lets turns run for
+// ever. Timed out turns are not retried under the project's retry policy.
+const chatTurnTimeoutPreference = "chat_turn_timeout"
+
+const (
+	defaultChatTurnTimeoutMinutes = 60
+	// chatTurnKillGrace is how long a killed provider has to close its
+	// output before the turn stops reading it, for children that outlive
+	// the process and keep it open.
+	chatTurnKillGrace = 5 * time.Second
+)
+
+// chatTurnTimeoutUnit scales the chat_turn_timeout minutes; tests shrink it.
+var chatTurnTimeoutUnit = time.Minute
+
+type chatTurnTimeoutSettings struct {
+	Providers map[string]int `json:"providers"`
+	Default   *int           `json:"default,omitempty"`
+}
+
+// timeout is how long a provider's chat turns may go without output, or 0
+// for ever.
+func (c chatTurnTimeoutSettings) timeout(provider string) time.Duration {
+	minutes, ok := c.Providers[provider]
+	if !ok {
+		minutes = defaultChatTurnTimeoutMinutes
+		if c.Default != nil {
+			minutes = *c.Default
+		}
+	}
+	return time.Duration(max(minutes, 0)) * chatTurnTimeoutUnit
+}
+
+// chatTurnTimeout reads the chat_turn_timeout preference for a provider.
+func (s *Server) chatTurnTimeout(provider string) time.Duration {
+	var settings chatTurnTimeoutSettings
+	if pref, err := s.db.GetPreference(db.DefaultUserID, chatTurnTimeoutPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+			slog.Warn("invalid chat_turn_timeout preference", "error", err)
+			settings = chatTurnTimeoutSettings{}
+		}
+	}
+	return settings.timeout(provider)
+}
+
+// timedOutText is the system message a timed out turn leaves.
+func timedOutText(timeout time.Duration) string {
+	return fmt.Sprintf("Turn timed out after %s without output; the provider process was stopped.", formatTurnTimeout(timeout))
+}
+
+// formatTurnTimeout writes a timeout without the zero units
+// time.Duration.String keeps ("1h" rather than "1h0m0s").
+func formatTurnTimeout(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// errChatTurnTimedOut is the cause a turn's context is cancelled with when
+// its watchdog fires.
+var errChatTurnTimedOut = errors.New("chat turn timed out")
+
+// turnWatchdog stops a turn that has gone quiet for its timeout.
+type turnWatchdog struct {
+	timeout time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func newTurnWatchdog(timeout time.Duration, expire func()) *turnWatchdog {
+	return &turnWatchdog{timeout: timeout, timer: time.AfterFunc(timeout, expire)}
+}
+
+// touch starts the wait over, or pauses it while paused is set. A nil
+// watchdog does nothing.
+func (w *turnWatchdog) touch(paused bool) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer.Stop()
+	if !paused {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+// stop disarms the watchdog for good.
+func (w *turnWatchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer.Stop()
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/fakeprovider"
+)
+
+func TestChatTurnTimeoutSettings(t *testing.T) {
+	zero := 0
+	cases := []struct {
+		settings chatTurnTimeoutSettings
+		provider string
+		want     time.Duration
+	}{
+		{chatTurnTimeoutSettings{}, "claude", time.Hour},
+		{chatTurnTimeoutSettings{Default: &zero}, "claude", 0},
+		{chatTurnTimeoutSettings{Providers: map[string]int{"codex": 90}, Default: &zero}, "codex", 90 * time.Minute},
+		{chatTurnTimeoutSettings{Providers: map[string]int{"codex": 90}}, "claude", time.Hour},
+	}
+	for _, c := range cases {
+		if got := c.settings.timeout(c.provider); got != c.want {
+			t.Errorf("%+v %s: expected %v, got %v", c.settings, c.provider, c.want, got)
+		}
+	}
+
+	if got := timedOutText(time.Hour); got != "Turn timed out after 1h without output; the provider process was stopped." {
+		t.Errorf("unexpected message: %q", got)
+	}
+	if got := formatTurnTimeout(90 * time.Minute); got != "1h30m" {
+		t.Errorf("expected 1h30m, got %q", got)
+	}
+}
+
+func TestChatTurnTimeout(t *testing.T) {
+	chatTurnTimeoutUnit = 10 * time.Millisecond
+	t.Cleanup(func() { chatTurnTimeoutUnit = time.Minute })
+
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	installFakeProviders(t, fakeprovider.Script{{Text: "Working on it"}, {Sleep: "30s"}, {Text: "done"}})
+	taskID, _ := createTaskWithWorktree(t, env)
+	task, _ := env.server.db.GetTask(taskID)
+	env.server.db.SetPreference(db.DefaultUserID, chatTurnTimeoutPreference, `{"providers": {"claude": 50}}`)
+	// Timed out turns aren't retried.
+	env.server.db.UpdateProject(task.ProjectID, db.UpdateProjectInput{RetryPolicy: &db.RetryPolicy{MaxRetries: 2}})
+
+	resp := env.post("/api/tasks/"+taskID+"/sessions", map[string]string{
+		"provider": "claude", "sessionType": "chat", "prompt": "hello",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("start session: %d %s", resp.Code, resp.Body.String())
+	}
+	var session db.AgentSession
+	decodeResponse(t, resp, &session)
+
+	started := time.Now()
+	failed := env.waitForSessionStatus(t, session.ID, db.SessionStatusError)
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("expected the turn stopped near its timeout, took %v", elapsed)
+	}
+	if len(failed.RetryHistory) != 0 {
+		t.Errorf("expected no retries, got %+v", failed.RetryHistory)
+	}
+
+	var timeouts []string
+	for _, msg := range env.chatMessages(t, session.ID) {
+		if msg.Kind == ChatMessageKindSystem && msg.Data["type"] == "timeout" {
+			timeouts = append(timeouts, msg.Text)
+		}
+		if msg.Text == "done" {
+			t.Error("expected the turn stopped before it finished")
+		}
+	}
+	if len(timeouts) != 1 || timeouts[0] != "Turn timed out after 500ms without output; the provider process was stopped." {
+		t.Fatalf("expected one timeout message, got %q", timeouts)
+	}
+
+	// The session no longer counts as busy.
+	resultCh, err := env.server.chat.StartTurn(StartChatTurnInput{
+		SessionID: session.ID, Provider: "claude", Prompt: "again", Timeout: 50 * time.Millisecond,
+	})
+	if errors.Is(err, ErrChatTurnBusy) {
+		t.Fatal("expected a new turn after the timeout, got busy")
+	}
+	if err != nil {
+		t.Fatalf("start turn: %v", err)
+	}
+	if result := <-resultCh; !result.TimedOut || result.Err == nil {
+		t.Errorf("expected the turn to time out, got %+v", result)
+	}
+}
+
+func TestChatTurnTimeoutWaitsOnActivity(t *testing.T) {
+	chatTurnTimeoutUnit = 10 * time.Millisecond
+	t.Cleanup(func() { chatTurnTimeoutUnit = time.Minute })
+
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	// The turn runs well past its timeout, but never goes quiet for that
+	// long outside the permission request.
+	installFakeProviders(t, fakeprovider.Script{
+		{Text: "one"}, {Sleep: "300ms"}, {Text: "two"}, {Sleep: "300ms"}, {Text: "three"},
+		{Tool: "Bash", Input: map[string]any{"command": "rm -rf build"}, Output: "removed", Ask: true},
+		{Text: "done"},
+	})
+	env.server.db.SetPreference(db.DefaultUserID, chatTurnTimeoutPreference, `{"default": 50}`)
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	var session db.AgentSession
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/sessions", map[string]any{
+		"provider": "claude", "sessionType": "chat", "prompt": "clean the build", "autoApprove": false,
+	}), &session)
+
+	var requestID string
+	waitForCondition(t, 10*time.Second, func() bool {
+		for _, msg := range env.chatMessages(t, session.ID) {
+			if msg.Permission != nil && msg.Permission.Status == ChatPermissionPending {
+				requestID = msg.Permission.RequestID
+				return true
+			}
+		}
+		return false
+	}, "permission request")
+	time.Sleep(time.Second)
+
+	resp := env.post("/api/sessions/"+session.ID+"/permissions/"+requestID, map[string]string{"decision": "allow"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("answer the permission request: %d %s", resp.Code, resp.Body.String())
+	}
+	env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+
+	var finished bool
+	for _, msg := range env.chatMessages(t, session.ID) {
+		if msg.Kind == ChatMessageKindSystem && msg.Data["type"] == "timeout" {
+			t.Errorf("expected the turn not to time out, got %q", msg.Text)
+		}
+		finished = finished || msg.Text == "done"
+	}
+	if !finished {
+		t.Error("expected the turn to finish")
+	}
+}
@@ -10,6 +10,8 @@ const (
 	ChatMessageKindToolCall  ChatMessageKind = "tool-call"
 	ChatMessageKindSystem    ChatMessageKind = "system"
 	ChatMessageKindResult    ChatMessageKind = "result"
+	// ChatMessageKindPermission asks the user to allow or deny a tool call.
+	ChatMessageKindPermission ChatMessageKind = "permission-request"
 )
 
 type ChatToolState string
@@ -31,16 +33,37 @@ type ChatToolCall struct {
 	IsError     bool          `json:"isError,omitempty"`
 }
 
+type ChatPermissionStatus string
+
+const (
+	ChatPermissionPending ChatPermissionStatus = "pending"
+	ChatPermissionAllowed ChatPermissionStatus = "allowed"
+	ChatPermissionDenied  ChatPermissionStatus = "denied"
+	// ChatPermissionExpired marks requests whose turn ended before they were
+	// answered.
+	ChatPermissionExpired ChatPermissionStatus = "expired"
+)
+
+type ChatPermissionRequest struct {
+	RequestID string               `json:"requestId"`
+	ToolName  string               `json:"toolName"`
+	ToolUseID string               `json:"toolUseId,omitempty"`
+	Input     any                  `json:"input,omitempty"`
+	Status    ChatPermissionStatus `json:"status"`
+	Message   string               `json:"message,omitempty"` // reason given when denied
+}
+
 type ChatMessage struct {
-	ID         string          `json:"id"`
-	SessionID  string          `json:"sessionId,omitempty"`
-	Seq        int64           `json:"seq,omitempty"`
-	Kind       ChatMessageKind `json:"kind"`
-	Provider   string          `json:"provider"`
-	Role       string          `json:"role,omitempty"`
-	Text       string          `json:"text,omitempty"`
-	IsThinking bool            `json:"isThinking,omitempty"`
-	Tool       *ChatToolCall   `json:"tool,omitempty"`
-	Data       map[string]any  `json:"data,omitempty"`
-	CreatedAt  time.Time       `json:"createdAt"`
+	ID         string                 `json:"id"`
+	SessionID  string                 `json:"sessionId,omitempty"`
+	Seq        int64                  `json:"seq,omitempty"`
+	Kind       ChatMessageKind        `json:"kind"`
+	Provider   string                 `json:"provider"`
+	Role       string                 `json:"role,omitempty"`
+	Text       string                 `json:"text,omitempty"`
+	IsThinking bool                   `json:"isThinking,omitempty"`
+	Tool       *ChatToolCall          `json:"tool,omitempty"`
+	Permission *ChatPermissionRequest `json:"permission,omitempty"`
+	Data       map[string]any         `json:"data,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
 }
@@ -33,7 +33,7 @@ const (
 
 func (s *Server) handleChatWS(w http.ResponseWriter, r *http.Request) {
 	token := authTokenFromWSRequest(r)
-	if token == "" || !s.auth.ValidateToken(token) {
+	if !s.validLoginToken(token) && !s.ambientLogin(r) {
 		writeError(w, http.StatusUnauthorized, "invalid token")
 		return
 	}
@@ -136,7 +136,11 @@ func (ws *chatWSConn) readLoop() {
 			if strings.TrimSpace(in.Content) == "" {
 				continue
 			}
-			if err := ws.server.startChatTurn(ws.sessionID, in.Content, "chat_ws"); err != nil {
+			err := ws.server.throttleSessionMessage(ws.sessionID)
+			if err == nil {
+				err = ws.server.startChatTurn(ws.sessionID, in.Content, "chat_ws")
+			}
+			if err != nil {
 				_ = ws.writeJSON(map[string]any{
 					"type":  "error",
 					"error": err.Error(),
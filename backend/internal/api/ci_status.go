@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/gitclone"
+	"github.com/miguel-bm/codeburg/internal/github"
+	"github.com/miguel-bm/codeburg/internal/notify"
+)
+
+// CI status of task branches, from GitHub's check runs and commit statuses.
+// Lookups are cached for ciStatusCacheTTL, since every board refresh may ask.
+// After a push of a task's branch from Codeburg, the pushed commit is
+// watched until its checks finish, and a failure is notified.
+const ciStatusCacheTTL = 30 * time.Second
+
+var (
+	// fetchCIStatus reads a commit's checks; tests replace it.
+	fetchCIStatus = github.CommitCIStatus
+
+	ciPollInterval = 30 * time.Second
+	ciWatchTimeout = time.Hour
+	// ciNoChecksGrace is how long a pushed commit may go without checks
+	// before the repository is taken not to have CI.
+	ciNoChecksGrace = 5 * time.Minute
+)
+
+var errNoGitHubRemote = errors.New("CI status needs a project on GitHub")
+
+// ciStatusCache holds recent lookups by repository and ref. The zero value
+// is ready to use.
+type ciStatusCache struct {
+	mu      sync.Mutex
+	entries map[string]ciStatusCacheEntry
+}
+
+type ciStatusCacheEntry struct {
+	status  *github.CIStatus
+	fetched time.Time
+}
+
+func (c *ciStatusCache) lookup(key string) (*github.CIStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.fetched) > ciStatusCacheTTL {
+		return nil, false
+	}
+	return entry.status, true
+}
+
+func (c *ciStatusCache) store(key string, status *github.CIStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]ciStatusCacheEntry)
+	}
+	for k, entry := range c.entries {
+		if time.Since(entry.fetched) > ciStatusCacheTTL {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = ciStatusCacheEntry{status: status, fetched: time.Now()}
+}
+
+// ciWatches holds the watch of each task's last push, so that a newer push
+// replaces it. The zero value is ready to use.
+type ciWatches struct {
+	mu      sync.Mutex
+	watches map[string]*ciWatch // task ID -> watch
+}
+
+type ciWatch struct {
+	cancel context.CancelFunc
+}
+
+// start cancels the task's previous watch and returns a context for the
+// new one, and a func that ends it.
+func (w *ciWatches) start(parent context.Context, taskID string) (context.Context, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watches == nil {
+		w.watches = make(map[string]*ciWatch)
+	}
+	if previous, ok := w.watches[taskID]; ok {
+		previous.cancel()
+	}
+	ctx, cancel := context.WithTimeout(parent, ciWatchTimeout)
+	watch := &ciWatch{cancel: cancel}
+	w.watches[taskID] = watch
+	return ctx, func() {
+		cancel()
+		w.mu.Lock()
+		if w.watches[taskID] == watch {
+			delete(w.watches, taskID)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// githubRepo returns a project's "owner/repo" on GitHub.
+func (s *Server) githubRepo(project *db.Project) (string, error) {
+	remote, ok := s.projectRemote(project)
+	if !ok || remote.Provider != gitclone.ProviderGitHub {
+		return "", errNoGitHubRemote
+	}
+	return remote.Path(), nil
+}
+
+// ciStatus returns the checks of ref in repo, from the cache unless fresh
+// is set.
+func (s *Server) ciStatus(ctx context.Context, repo, ref string, fresh bool) (*github.CIStatus, error) {
+	key := repo + "@" + ref
+	if !fresh {
+		if status, ok := s.ciStatuses.lookup(key); ok {
+			return status, nil
+		}
+	}
+	status, err := fetchCIStatus(ctx, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	s.ciStatuses.store(key, status)
+	return status, nil
+}
+
+// handleTaskCIStatus returns the checks of the head of a task's branch on
+// GitHub. refresh=true skips the cache.
+func (s *Server) handleTaskCIStatus(w http.ResponseWriter, r *http.Request) {
+	task, err := s.db.GetTask(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	branch := ptrToString(task.Branch)
+	if branch == "" {
+		writeError(w, http.StatusBadRequest, "task has no branch")
+		return
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	repo, err := s.githubRepo(project)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status, err := s.ciStatus(r.Context(), repo, branch, r.URL.Query().Get("refresh") == "true")
+	if err != nil {
+		slog.Warn("failed to get CI status", "task_id", task.ID, "error", err)
+		writeError(w, http.StatusBadGateway, "failed to get CI status: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"branch": branch, "status": status})
+}
+
+// watchCIAfterPush watches the checks of a commit pushed for a task, when
+// its project is on GitHub.
+func (s *Server) watchCIAfterPush(projectID, taskID, branch, commit string) {
+	if taskID == "" || commit == "" {
+		return
+	}
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		return
+	}
+	repo, err := s.githubRepo(project)
+	if err != nil {
+		return
+	}
+
+	ctx, done := s.ciWatches.start(s.bgCtx, taskID)
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		defer done()
+		s.watchCI(ctx, repo, projectID, taskID, branch, commit)
+	}()
+}
+
+// watchCI polls a commit's checks until they finish, the commit turns out
+// to have none, or ctx ends. Three failed lookups in a row end it too.
+func (s *Server) watchCI(ctx context.Context, repo, projectID, taskID, branch, commit string) {
+	started := time.Now()
+	ticker := time.NewTicker(ciPollInterval)
+	defer ticker.Stop()
+	lookupErrors := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status, err := s.ciStatus(ctx, repo, commit, true)
+		if err != nil {
+			if lookupErrors++; lookupErrors >= 3 {
+				slog.Warn("stopped watching CI", "task_id", taskID, "commit", commit, "error", err)
+				return
+			}
+			continue
+		}
+		lookupErrors = 0
+
+		switch status.State {
+		case github.CIPending:
+			continue
+		case github.CINone:
+			if time.Since(started) < ciNoChecksGrace {
+				continue
+			}
+			return
+		}
+		s.wsHub.BroadcastToProject(projectID, "ci_status", map[string]any{
+			"taskId": taskID,
+			"branch": branch,
+			"status": status,
+		})
+		if status.State == github.CIFailure {
+			s.notifyCIFailed(taskID, branch, status)
+		}
+		return
+	}
+}
+
+// notifyCIFailed tells the user, on the channels that want it, that the
+// checks of a commit pushed for a task failed.
+func (s *Server) notifyCIFailed(taskID, branch string, status *github.CIStatus) {
+	sinks := s.notificationSinks(eventCIFailed)
+	if len(sinks) == 0 {
+		return
+	}
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		slog.Warn("failed to load task for CI notification", "task_id", taskID, "error", err)
+		return
+	}
+
+	failed := status.Failed()
+	names := make([]string, len(failed))
+	var links []notify.Link
+	for i, check := range failed {
+		names[i] = check.Name
+		if check.URL != "" && len(links) < 3 {
+			links = append(links, notify.Link{Title: check.Name, URL: check.URL})
+		}
+	}
+	url := s.deepLink(taskPath(task.ID))
+
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		return notify.Message{
+			Title: localize(lang, msgCIFailedTitle, task.Title),
+			Body:  localize(lang, msgCIFailedBody, len(failed), len(status.Checks), branch, strings.Join(names, ", ")),
+			URL:   url,
+			Links: links,
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/github"
+)
+
+func TestTaskCIStatus(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var fetches atomic.Int32
+	state := github.CIPending
+	fetchCIStatus = func(ctx context.Context, repo, ref string) (*github.CIStatus, error) {
+		fetches.Add(1)
+		if repo != "acme/webapp" {
+			t.Errorf("unexpected repo %q", repo)
+		}
+		return &github.CIStatus{SHA: "abc123", State: state, Checks: []github.CICheck{
+			{Name: "build", State: github.CISuccess},
+			{Name: "test", State: state, URL: "https://github.com/acme/webapp/runs/2"},
+		}}, nil
+	}
+	t.Cleanup(func() { fetchCIStatus = github.CommitCIStatus })
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "webapp", "path": repoPath}), &project)
+	branch := "fix-login"
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Fix login", Branch: &branch})
+	bare, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "No branch"})
+
+	if resp := env.get("/api/tasks/" + bare.ID + "/ci-status"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a branch, got %d", resp.Code)
+	}
+	if resp := env.get("/api/tasks/" + task.ID + "/ci-status"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a GitHub remote, got %d", resp.Code)
+	}
+
+	if out, err := exec.Command("git", "-C", repoPath, "remote", "add", "origin", "https://github.com/acme/webapp.git").CombinedOutput(); err != nil {
+		t.Fatalf("add remote: %v %s", err, out)
+	}
+	resp := env.get("/api/tasks/" + task.ID + "/ci-status")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("ci-status: %d %s", resp.Code, resp.Body.String())
+	}
+	var body struct {
+		Branch string          `json:"branch"`
+		Status github.CIStatus `json:"status"`
+	}
+	decodeResponse(t, resp, &body)
+	if body.Branch != branch || body.Status.State != github.CIPending || len(body.Status.Checks) != 2 {
+		t.Fatalf("unexpected CI status: %+v", body)
+	}
+	env.get("/api/tasks/" + task.ID + "/ci-status")
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected the second lookup cached, got %d fetches", n)
+	}
+	env.get("/api/tasks/" + task.ID + "/ci-status?refresh=true")
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("expected refresh to skip the cache, got %d fetches", n)
+	}
+
+	// A push from Codeburg is watched until its checks finish.
+	received := make(chan map[string]any, 1)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+
+	ciPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { ciPollInterval = 30 * time.Second })
+	state = github.CIFailure
+	env.server.watchCIAfterPush(project.ID, task.ID, branch, "abc123")
+
+	select {
+	case body := <-received:
+		if body["title"] != "❌ Checks failed for Fix login" || body["message"] != "1 of 2 checks failed on fix-login: test" {
+			t.Errorf("unexpected ntfy payload: %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no CI failure notification")
+	}
+}
@@ -0,0 +1,556 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+// Comparisons run one prompt against several providers at once. Each gets a
+// chat session in its own throwaway worktree, branched from the task's
+// current commit; the user reviews the results side by side and picks one,
+// whose changes land in the task's worktree, or discards them all.
+
+const (
+	minComparisonVariants = 2
+	maxComparisonVariants = 4
+
+	// comparisonBranchPrefix starts the branch names of comparison worktrees.
+	comparisonBranchPrefix = "codeburg-compare/"
+)
+
+var (
+	errComparisonClosed  = errors.New("comparison is no longer running")
+	errComparisonRunning = errors.New("the picked session is still running; stop it or wait for its turn to finish")
+	errComparisonApply   = errors.New("the picked changes do not apply to the task's worktree")
+)
+
+type comparisonVariant struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+}
+
+type StartComparisonRequest struct {
+	Prompt      string              `json:"prompt"`
+	Variants    []comparisonVariant `json:"variants,omitempty"` // default: claude and codex
+	AutoApprove *bool               `json:"autoApprove,omitempty"`
+}
+
+type PickComparisonRequest struct {
+	SessionID string `json:"sessionId"`
+}
+
+// comparisonEntryView is an entry with its session's progress and the size of
+// its changes, and with the changes themselves in the diff view.
+type comparisonEntryView struct {
+	db.ComparisonEntry
+	Status    db.SessionStatus    `json:"status"`
+	Result    string              `json:"result,omitempty"` // the session's last reply
+	Additions int                 `json:"additions"`
+	Deletions int                 `json:"deletions"`
+	Files     []GitStructuredFile `json:"files,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+type comparisonView struct {
+	*db.SessionComparison
+	Entries []comparisonEntryView `json:"entries"`
+}
+
+func validateComparisonRequest(req *StartComparisonRequest) error {
+	req.Prompt = strings.TrimSpace(req.Prompt)
+	if req.Prompt == "" {
+		return fmt.Errorf("prompt is required")
+	}
+	if len(req.Variants) == 0 {
+		req.Variants = []comparisonVariant{{Provider: "claude"}, {Provider: "codex"}}
+	}
+	if len(req.Variants) < minComparisonVariants || len(req.Variants) > maxComparisonVariants {
+		return fmt.Errorf("a comparison takes %d to %d variants", minComparisonVariants, maxComparisonVariants)
+	}
+	for _, v := range req.Variants {
+		if v.Provider != "claude" && v.Provider != "codex" {
+			return fmt.Errorf("invalid provider: %s", v.Provider)
+		}
+		if v.Model != "" && !isValidModelName(v.Model) {
+			return fmt.Errorf("invalid model name: %s", v.Model)
+		}
+	}
+	return nil
+}
+
+// comparisonBaseCommit is the commit comparison worktrees branch from: the
+// HEAD of the task's worktree, or the project's default branch without one.
+// Uncommitted changes in the task's worktree are not included.
+func (s *Server) comparisonBaseCommit(ctx context.Context, task *db.Task, project *db.Project) (string, error) {
+	dir, ref := project.Path, project.DefaultBranch
+	if path := ptrToString(task.WorktreePath); path != "" && s.worktree.Exists(path) {
+		dir, ref = path, "HEAD"
+	}
+	out, err := runGitContext(ctx, dir, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("resolve base commit: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (s *Server) handleStartComparison(w http.ResponseWriter, r *http.Request) {
+	task, err := s.db.GetTask(urlParam(r, "taskId"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	var req StartComparisonRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := validateComparisonRequest(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	baseCommit, err := s.comparisonBaseCommit(r.Context(), task, project)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comparison, err := s.db.CreateComparison(db.CreateComparisonInput{TaskID: task.ID, Prompt: req.Prompt, BaseCommit: baseCommit})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create comparison")
+		return
+	}
+	if err := s.startComparisonVariants(r.Context(), comparison, task, project, req); err != nil {
+		s.closeComparison(comparison.ID, project, db.ComparisonStatusDiscarded, "", "")
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	comparison, err = s.db.GetComparison(comparison.ID)
+	if err != nil {
+		writeDBError(w, err, "comparison")
+		return
+	}
+	s.wsHub.BroadcastToTask(task.ID, "comparison_updated", comparison)
+	writeJSON(w, http.StatusCreated, comparison)
+}
+
+// startComparisonVariants creates a worktree and chat session per variant
+// and sends each the prompt.
+func (s *Server) startComparisonVariants(ctx context.Context, comparison *db.SessionComparison, task *db.Task, project *db.Project, req StartComparisonRequest) error {
+	for i, v := range req.Variants {
+		branch := fmt.Sprintf("%s%s-%d-%s", comparisonBranchPrefix, comparison.ID[:8], i+1, v.Provider)
+		result, err := s.worktree.Create(worktree.CreateOptions{
+			ProjectPath:  project.Path,
+			ProjectID:    project.ID,
+			ProjectName:  project.Name,
+			TaskID:       task.ID,
+			BranchName:   branch,
+			BaseBranch:   comparison.BaseCommit,
+			LocalBase:    true,
+			SymlinkPaths: project.SymlinkPaths,
+			ClonePaths:   project.ClonePaths,
+			SecretFiles:  mapSecretFiles(project.SecretFiles),
+			SetupScript:  ptrToString(project.SetupScript),
+		})
+		if err != nil {
+			return fmt.Errorf("create worktree for %s: %w", v.Provider, err)
+		}
+
+		session, err := s.startSessionInternal(ctx, startSessionParams{
+			ProjectID: project.ID,
+			TaskID:    task.ID,
+			WorkDir:   result.WorktreePath,
+		}, StartSessionRequest{
+			Provider:    v.Provider,
+			Model:       v.Model,
+			SessionType: "chat",
+			AutoApprove: req.AutoApprove,
+		})
+		if err == nil {
+			err = s.db.AddComparisonEntry(db.ComparisonEntry{
+				ComparisonID: comparison.ID,
+				SessionID:    session.ID,
+				Provider:     v.Provider,
+				Model:        v.Model,
+				WorktreePath: result.WorktreePath,
+				Branch:       result.BranchName,
+			})
+			if err != nil {
+				s.stopSession(session)
+				s.chat.RemoveSession(session.ID)
+				_ = s.db.DeleteSession(session.ID)
+			}
+		}
+		if err != nil {
+			s.removeComparisonWorktree(project, db.ComparisonEntry{WorktreePath: result.WorktreePath, Branch: result.BranchName})
+			return fmt.Errorf("start %s session: %w", v.Provider, err)
+		}
+		// The entry has to exist first: chat turns run in the session's work
+		// directory, which the entry provides.
+		if err := s.startChatTurn(session.ID, req.Prompt, "comparison"); err != nil {
+			return fmt.Errorf("start %s turn: %w", v.Provider, err)
+		}
+	}
+	return nil
+}
+
+// comparisonWorkDir returns the work directory of a session that belongs to
+// a comparison. ok is false for other sessions. A picked session continues
+// in the task's worktree.
+func (s *Server) comparisonWorkDir(sessionID string) (dir string, ok bool, err error) {
+	comparison, entry, err := s.db.GetComparisonBySession(sessionID)
+	if errors.Is(err, db.ErrNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	switch {
+	case comparison.Status == db.ComparisonStatusRunning:
+		return entry.WorktreePath, true, nil
+	case ptrToString(comparison.PickedSessionID) == sessionID:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("this session's comparison was closed and its worktree removed")
+	}
+}
+
+func (s *Server) handleListComparisons(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "taskId")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	comparisons, err := s.db.ListComparisons(taskID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list comparisons")
+		return
+	}
+	writeJSON(w, http.StatusOK, comparisons)
+}
+
+// handleGetComparison returns a comparison with each entry's status, last
+// reply and change size.
+func (s *Server) handleGetComparison(w http.ResponseWriter, r *http.Request) {
+	s.writeComparisonView(w, r, false, false)
+}
+
+// handleComparisonDiff returns a comparison like handleGetComparison, with
+// each entry's changes as a structured diff. view=split lays hunks out side
+// by side.
+func (s *Server) handleComparisonDiff(w http.ResponseWriter, r *http.Request) {
+	view := r.URL.Query().Get("view")
+	if view != "" && view != "unified" && view != "split" {
+		writeError(w, http.StatusBadRequest, "view must be unified or split")
+		return
+	}
+	s.writeComparisonView(w, r, true, view == "split")
+}
+
+func (s *Server) writeComparisonView(w http.ResponseWriter, r *http.Request, withFiles, split bool) {
+	comparison, err := s.db.GetComparison(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "comparison")
+		return
+	}
+
+	view := comparisonView{SessionComparison: comparison, Entries: make([]comparisonEntryView, 0, len(comparison.Entries))}
+	for _, entry := range comparison.Entries {
+		ev := comparisonEntryView{ComparisonEntry: entry, Result: s.lastAgentReply(entry.SessionID)}
+		if session, err := s.db.GetSession(entry.SessionID); err == nil {
+			ev.Status = session.Status
+		}
+		if comparison.Status == db.ComparisonStatusRunning {
+			out, err := comparisonDiff(r.Context(), entry.WorktreePath, comparison.BaseCommit, false)
+			if err != nil {
+				ev.Error = err.Error()
+			} else {
+				files := structuredDiff(out, split)
+				for _, f := range files {
+					ev.Additions += f.Additions
+					ev.Deletions += f.Deletions
+				}
+				if withFiles {
+					ev.Files = files
+				}
+			}
+		}
+		view.Entries = append(view.Entries, ev)
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// lastAgentReply returns the text of a chat session's last reply.
+func (s *Server) lastAgentReply(sessionID string) string {
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return ""
+	}
+	rows, err := s.db.ListAgentMessagesBySession(sessionID)
+	if err != nil {
+		return ""
+	}
+	for i := len(rows) - 1; i >= 0; i-- {
+		msg, ok := chatMessageFromRow(rows[i], session.Provider)
+		if ok && msg.Kind == ChatMessageKindAgentText && !msg.IsThinking && strings.TrimSpace(msg.Text) != "" {
+			return msg.Text
+		}
+	}
+	return ""
+}
+
+// comparisonDiff returns the changes of a comparison worktree since
+// baseCommit, committed or not, new files included. It stages into a
+// temporary index so the agent's own index is left alone.
+func comparisonDiff(ctx context.Context, workDir, baseCommit string, binary bool) (string, error) {
+	index, err := os.CreateTemp("", "codeburg-compare-index-*")
+	if err != nil {
+		return "", err
+	}
+	index.Close()
+	defer os.Remove(index.Name())
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = workDir
+		cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+index.Name())
+		out, err := cmd.Output()
+		if err != nil {
+			var stderr string
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				stderr = strings.TrimSpace(string(exitErr.Stderr))
+			}
+			return "", fmt.Errorf("git %s: %s: %w", args[0], stderr, err)
+		}
+		return string(out), nil
+	}
+
+	if _, err := git("read-tree", "HEAD"); err != nil {
+		return "", err
+	}
+	if _, err := git("add", "-A"); err != nil {
+		return "", err
+	}
+	args := []string{"diff", "--cached", "--no-color", "--no-ext-diff", "--find-renames"}
+	if binary {
+		args = append(args, "--binary")
+	}
+	return git(append(args, baseCommit)...)
+}
+
+func (s *Server) handlePickComparison(w http.ResponseWriter, r *http.Request) {
+	comparison, err := s.db.GetComparison(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "comparison")
+		return
+	}
+	var req PickComparisonRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	resp, err := s.pickComparison(r.Context(), comparison, req.SessionID)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, http.StatusBadRequest, "sessionId is not part of this comparison")
+	case errors.Is(err, errComparisonClosed), errors.Is(err, errComparisonRunning), errors.Is(err, errComparisonApply):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// pickComparison brings the picked session's changes into the task. A task
+// without a worktree adopts the picked worktree; otherwise the changes are
+// applied to the task's worktree as a patch. The other worktrees are removed.
+func (s *Server) pickComparison(ctx context.Context, comparison *db.SessionComparison, sessionID string) (*WorktreeResponse, error) {
+	s.comparisonMu.Lock()
+	defer s.comparisonMu.Unlock()
+
+	// Re-read under the lock so two picks cannot both win.
+	comparison, err := s.db.GetComparison(comparison.ID)
+	if err != nil {
+		return nil, err
+	}
+	if comparison.Status != db.ComparisonStatusRunning {
+		return nil, errComparisonClosed
+	}
+	var picked *db.ComparisonEntry
+	for i := range comparison.Entries {
+		if comparison.Entries[i].SessionID == sessionID {
+			picked = &comparison.Entries[i]
+		}
+	}
+	if picked == nil {
+		return nil, db.ErrNotFound
+	}
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == db.SessionStatusRunning {
+		return nil, errComparisonRunning
+	}
+	task, err := s.db.GetTask(comparison.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	taskWorktree := ptrToString(task.WorktreePath)
+	adopt := taskWorktree == "" || !s.worktree.Exists(taskWorktree)
+	resp := &WorktreeResponse{WorktreePath: taskWorktree, BranchName: ptrToString(task.Branch)}
+	if adopt {
+		branch := s.renameComparisonBranch(task, project, picked.Branch)
+		if _, err := s.db.UpdateTask(task.ID, db.UpdateTaskInput{WorktreePath: &picked.WorktreePath, Branch: &branch}); err != nil {
+			return nil, fmt.Errorf("update task: %w", err)
+		}
+		resp.WorktreePath, resp.BranchName = picked.WorktreePath, branch
+	} else {
+		patch, err := comparisonDiff(ctx, picked.WorktreePath, comparison.BaseCommit, true)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(patch) != "" {
+			if err := runGitStdin(taskWorktree, patch, "apply", "--whitespace=nowarn"); err != nil {
+				slog.Warn("failed to apply comparison changes", "comparison_id", comparison.ID, "error", err)
+				return nil, errComparisonApply
+			}
+		}
+	}
+
+	keep := ""
+	if adopt {
+		keep = sessionID
+	}
+	s.closeComparison(comparison.ID, project, db.ComparisonStatusPicked, keep, sessionID)
+	s.wakeDiffStats()
+	return resp, nil
+}
+
+// renameComparisonBranch gives an adopted comparison worktree's branch the
+// name a task worktree would get. It keeps the comparison branch if the
+// rename fails.
+func (s *Server) renameComparisonBranch(task *db.Task, project *db.Project, branch string) string {
+	name := strings.TrimSpace(ptrToString(task.Branch))
+	if name == "" {
+		name = worktree.Slugify(task.Title)
+	}
+	if gitRefExists(project.Path, name) {
+		name += "-" + task.ID[:6]
+	}
+	if _, err := runGit(project.Path, "branch", "-m", branch, name); err != nil {
+		slog.Warn("failed to rename comparison branch", "branch", branch, "error", err)
+		return branch
+	}
+	return name
+}
+
+func (s *Server) handleDiscardComparison(w http.ResponseWriter, r *http.Request) {
+	comparison, err := s.db.GetComparison(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "comparison")
+		return
+	}
+	task, err := s.db.GetTask(comparison.TaskID)
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	s.comparisonMu.Lock()
+	defer s.comparisonMu.Unlock()
+	if current, err := s.db.GetComparison(comparison.ID); err != nil || current.Status != db.ComparisonStatusRunning {
+		writeError(w, http.StatusConflict, errComparisonClosed.Error())
+		return
+	}
+	s.closeComparison(comparison.ID, project, db.ComparisonStatusDiscarded, "", "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// closeComparison stops a comparison's sessions and removes their worktrees,
+// except the session keep (whose worktree the task adopted), and records the
+// outcome with the picked session, if any.
+func (s *Server) closeComparison(comparisonID string, project *db.Project, status, keep, picked string) {
+	comparison, err := s.db.GetComparison(comparisonID)
+	if err != nil {
+		return
+	}
+	for _, entry := range comparison.Entries {
+		if entry.SessionID == keep {
+			continue
+		}
+		if session, err := s.db.GetSession(entry.SessionID); err == nil {
+			s.stopSession(session)
+		}
+		s.removeComparisonWorktree(project, entry)
+	}
+
+	var pickedID *string
+	if picked != "" {
+		pickedID = &picked
+	}
+	if err := s.db.SetComparisonStatus(comparisonID, status, pickedID); err != nil {
+		slog.Warn("failed to update comparison status", "comparison_id", comparisonID, "error", err)
+		return
+	}
+	if updated, err := s.db.GetComparison(comparisonID); err == nil {
+		s.wsHub.BroadcastToTask(updated.TaskID, "comparison_updated", updated)
+	}
+}
+
+func (s *Server) removeComparisonWorktree(project *db.Project, entry db.ComparisonEntry) {
+	// Only ever remove what a comparison created.
+	if !strings.HasPrefix(entry.Branch, comparisonBranchPrefix) {
+		return
+	}
+	if err := s.worktree.RemovePooled(project.Path, entry.WorktreePath, entry.Branch, ptrToString(project.TeardownScript)); err != nil {
+		slog.Warn("failed to remove comparison worktree", "path", entry.WorktreePath, "error", err)
+	}
+}
+
+// removeTaskComparisons removes the worktrees of a task's running
+// comparisons, before the task is deleted.
+func (s *Server) removeTaskComparisons(taskID string, project *db.Project) {
+	comparisons, err := s.db.ListComparisons(taskID)
+	if err != nil {
+		slog.Warn("failed to list task comparisons", "task_id", taskID, "error", err)
+		return
+	}
+	for _, c := range comparisons {
+		if c.Status != db.ComparisonStatusRunning {
+			continue
+		}
+		for _, entry := range c.Entries {
+			s.removeComparisonWorktree(project, entry)
+		}
+	}
+}
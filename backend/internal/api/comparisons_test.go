@@ -0,0 +1,181 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// installFakeClaude puts a claude on PATH that writes its worktree's name to
+// impl.txt and replies "done".
+func installFakeClaude(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	script := `#!/bin/sh
+basename "$PWD" > impl.txt
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"done"}]}}'
+echo '{"type":"result","subtype":"success","result":"done"}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// startTestComparison starts a two-variant comparison and waits for both
+// turns to finish.
+func startTestComparison(t *testing.T, env *testEnv, taskID string) comparisonView {
+	t.Helper()
+	resp := env.post("/api/tasks/"+taskID+"/sessions/compare", StartComparisonRequest{
+		Prompt:   "implement it",
+		Variants: []comparisonVariant{{Provider: "claude"}, {Provider: "claude", Model: "sonnet"}},
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var comparison db.SessionComparison
+	decodeResponse(t, resp, &comparison)
+	if len(comparison.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", comparison.Entries)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var view comparisonView
+		decodeResponse(t, env.get("/api/comparisons/"+comparison.ID), &view)
+		done := true
+		for _, e := range view.Entries {
+			if e.Status != db.SessionStatusWaitingInput {
+				done = false
+			}
+		}
+		if done {
+			return view
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("comparison sessions did not finish: %+v", view.Entries)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestComparisons_PickAdoptsWorktree(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	installFakeClaude(t)
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, _ := createTaskWithWorktree(t, env)
+	env.server.db.UpdateTask(taskID, db.UpdateTaskInput{WorktreePath: new(string)})
+
+	view := startTestComparison(t, env, taskID)
+	first, second := view.Entries[0], view.Entries[1]
+	if first.Result != "done" || first.Additions != 1 || first.WorktreePath == second.WorktreePath {
+		t.Fatalf("unexpected entry: %+v", first)
+	}
+
+	var diff comparisonView
+	decodeResponse(t, env.get("/api/comparisons/"+view.ID+"/diff?view=split"), &diff)
+	if files := diff.Entries[1].Files; len(files) != 1 || files[0].Path != "impl.txt" || files[0].Status != "A" || len(files[0].Hunks[0].Rows) != 1 {
+		t.Fatalf("unexpected diff: %+v", files)
+	}
+
+	if resp := env.post("/api/comparisons/"+view.ID+"/pick", PickComparisonRequest{SessionID: "nope"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a session outside the comparison, got %d", resp.Code)
+	}
+	resp := env.post("/api/comparisons/"+view.ID+"/pick", PickComparisonRequest{SessionID: first.SessionID})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var picked WorktreeResponse
+	decodeResponse(t, resp, &picked)
+	if picked.WorktreePath != first.WorktreePath || strings.HasPrefix(picked.BranchName, comparisonBranchPrefix) {
+		t.Fatalf("expected the task to adopt the picked worktree on a task branch, got %+v", picked)
+	}
+
+	task, _ := env.server.db.GetTask(taskID)
+	if ptrToString(task.WorktreePath) != first.WorktreePath {
+		t.Errorf("expected the task worktree updated, got %q", ptrToString(task.WorktreePath))
+	}
+	if _, err := os.Stat(second.WorktreePath); !os.IsNotExist(err) {
+		t.Errorf("expected the other worktree removed, got %v", err)
+	}
+	comparison, _ := env.server.db.GetComparison(view.ID)
+	if comparison.Status != db.ComparisonStatusPicked || ptrToString(comparison.PickedSessionID) != first.SessionID {
+		t.Errorf("unexpected comparison: %+v", comparison)
+	}
+	if resp := env.post("/api/comparisons/"+view.ID+"/pick", PickComparisonRequest{SessionID: second.SessionID}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 picking twice, got %d", resp.Code)
+	}
+
+	// The picked session keeps working in what is now the task's worktree.
+	session, _ := env.server.db.GetSession(first.SessionID)
+	if dir, err := env.server.resolveSessionWorkDir(session); err != nil || dir != first.WorktreePath {
+		t.Errorf("unexpected work dir %q (%v)", dir, err)
+	}
+	session, _ = env.server.db.GetSession(second.SessionID)
+	if _, err := env.server.resolveSessionWorkDir(session); err == nil {
+		t.Error("expected a discarded session to have no work dir")
+	}
+}
+
+func TestComparisons_PickAppliesToTaskWorktree(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	installFakeClaude(t)
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	view := startTestComparison(t, env, taskID)
+	picked := view.Entries[1]
+	if resp := env.post("/api/comparisons/"+view.ID+"/pick", PickComparisonRequest{SessionID: picked.SessionID}); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(repoPath, "impl.txt"))
+	if err != nil || strings.TrimSpace(string(data)) != filepath.Base(picked.WorktreePath) {
+		t.Fatalf("expected the picked change applied to the task worktree, got %q (%v)", data, err)
+	}
+	for _, e := range view.Entries {
+		if _, err := os.Stat(e.WorktreePath); !os.IsNotExist(err) {
+			t.Errorf("expected comparison worktree %s removed, got %v", e.WorktreePath, err)
+		}
+	}
+
+	// A change that no longer applies leaves the comparison running.
+	view = startTestComparison(t, env, taskID)
+	if resp := env.post("/api/comparisons/"+view.ID+"/pick", PickComparisonRequest{SessionID: view.Entries[0].SessionID}); resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a conflicting change, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := env.post("/api/comparisons/"+view.ID+"/discard", nil); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	comparison, _ := env.server.db.GetComparison(view.ID)
+	if comparison.Status != db.ComparisonStatusDiscarded {
+		t.Errorf("expected discarded, got %q", comparison.Status)
+	}
+	if _, err := os.Stat(view.Entries[0].WorktreePath); !os.IsNotExist(err) {
+		t.Errorf("expected discarded worktree removed, got %v", err)
+	}
+}
+
+func TestComparisons_Validation(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, _ := createTaskWithWorktree(t, env)
+
+	cases := []StartComparisonRequest{
+		{Prompt: " "},
+		{Prompt: "x", Variants: []comparisonVariant{{Provider: "claude"}}},
+		{Prompt: "x", Variants: []comparisonVariant{{Provider: "claude"}, {Provider: "terminal"}}},
+		{Prompt: "x", Variants: []comparisonVariant{{Provider: "claude"}, {Provider: "codex", Model: "bad model"}}},
+	}
+	for _, req := range cases {
+		if resp := env.post("/api/tasks/"+taskID+"/sessions/compare", req); resp.Code != http.StatusBadRequest {
+			t.Errorf("%+v: expected 400, got %d", req, resp.Code)
+		}
+	}
+}
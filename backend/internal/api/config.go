@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/miguel-bm/codeburg/internal/tunnel"
+	"gopkg.in/yaml.v3"
+)
+
+// Server configuration file, ~/.codeburg/config.yaml unless serve -config
+// names another:
+//
+//	auth:
+//	  origin: https://codeburg.example.com  # public origin, for passkeys, links and CORS
+//	  trusted_proxies: [10.0.0.0/8]          # whose X-Forwarded-* headers to believe
+//	  cookies: true                          # logins also set a session cookie
+//	  forward_auth: {header: Remote-User}    # the proxy authenticates instead
+//	server:
+//	  allowed_origins: [https://dash.example.com]  # more origins allowed to call the API
+//	  data_dir: /srv/codeburg                      # where the database lives
+//	tunnels:
+//	  provider: tailscale  # default tunnel provider
+//	notifications:
+//	  ntfy: {server: https://ntfy.sh, topic: codeburg}
+//	  email: {host: smtp.example.com, from: codeburg@example.com, to: [me@example.com]}
+//
+// See proxy_auth.go for trusted_proxies, cookies and forward_auth. auth
+// also holds the password hash Codeburg writes. The tunnel_providers,
+// ntfy and email preferences take precedence over tunnels and
+// notifications. After editing the file, send the server SIGHUP or call
+// POST /api/config/reload; a file that doesn't validate is rejected and the
+// settings in use stay. data_dir is only read at startup.
+
+// ServerConfig is the server section of the configuration file.
+type ServerConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	DataDir        string   `yaml:"data_dir,omitempty"`
+}
+
+// TunnelsConfig is the tunnels section of the configuration file.
+type TunnelsConfig struct {
+	Provider string `yaml:"provider,omitempty"`
+}
+
+// NotificationsConfig is the notifications section of the configuration
+// file, in the format of the ntfy and email preferences.
+type NotificationsConfig struct {
+	Ntfy  *ntfyConfig  `yaml:"ntfy,omitempty"`
+	Email *emailConfig `yaml:"email,omitempty"`
+}
+
+// DefaultConfigPath returns the default configuration file path
+// (~/.codeburg/config.yaml).
+func DefaultConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "config.yaml")
+}
+
+// LoadConfig reads and validates the configuration file at path. A missing
+// file is an empty configuration.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// DatabasePath returns where the configured data directory keeps the
+// database, or "" when the configuration doesn't set one.
+func (c *Config) DatabasePath() string {
+	if c.Server.DataDir == "" {
+		return ""
+	}
+	return filepath.Join(expandHome(c.Server.DataDir), "codeburg.db")
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	if c.Auth.Origin != "" {
+		if err := validateOrigin(c.Auth.Origin, false); err != nil {
+			errs = append(errs, fmt.Errorf("auth.origin: %w", err))
+		}
+	}
+	if _, err := parseTrustedProxies(c.Auth.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("auth.trusted_proxies: %w", err))
+	}
+	if f := c.Auth.ForwardAuth; f != nil {
+		if strings.TrimSpace(f.Header) == "" {
+			errs = append(errs, errors.New("auth.forward_auth: header is required"))
+		}
+		// Anyone could send the header otherwise.
+		if len(c.Auth.TrustedProxies) == 0 {
+			errs = append(errs, errors.New("auth.forward_auth: needs auth.trusted_proxies"))
+		}
+	}
+	for _, origin := range c.Server.AllowedOrigins {
+		if err := validateOrigin(origin, true); err != nil {
+			errs = append(errs, fmt.Errorf("server.allowed_origins: %w", err))
+		}
+	}
+	if dir := c.Server.DataDir; dir != "" && !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "~/") {
+		errs = append(errs, fmt.Errorf("server.data_dir: %q is not an absolute path", dir))
+	}
+	if p := c.Tunnels.Provider; p != "" && !slices.Contains([]string{tunnel.ProviderCloudflared, tunnel.ProviderTailscale, tunnel.ProviderNgrok}, p) {
+		errs = append(errs, fmt.Errorf("tunnels.provider: unknown provider %q", p))
+	}
+	if n := c.Notifications.Ntfy; n != nil && n.Topic == "" {
+		errs = append(errs, errors.New("notifications.ntfy: topic is required"))
+	}
+	if e := c.Notifications.Email; e != nil {
+		if e.Host == "" || e.From == "" || len(e.To) == 0 {
+			errs = append(errs, errors.New("notifications.email: host, from and to are required"))
+		}
+		if !slices.Contains([]string{"", "starttls", "tls", "none"}, e.Security) {
+			errs = append(errs, fmt.Errorf("notifications.email.security: unknown value %q", e.Security))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateOrigin checks that origin is a scheme, host and optional port.
+// With wildcardPort, "http://localhost:*" style origins are accepted too.
+func validateOrigin(origin string, wildcardPort bool) error {
+	if wildcardPort {
+		origin = strings.TrimSuffix(origin, ":*")
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("%q is not an origin like https://example.com", origin)
+	}
+	return nil
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, rest)
+	}
+	return path
+}
+
+// currentConfig returns the configuration in use.
+func (s *Server) currentConfig() *Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	if s.config == nil {
+		return &Config{}
+	}
+	return s.config
+}
+
+// ReloadConfig reads the configuration file again and applies it. When the
+// file doesn't validate it returns the error and changes nothing. The
+// warnings name settings that only apply on restart.
+func (s *Server) ReloadConfig() (warnings []string, err error) {
+	config, err := LoadConfig(s.auth.configPath)
+	if err != nil {
+		return nil, err
+	}
+	previous := s.currentConfig()
+	if config.Server.DataDir != previous.Server.DataDir {
+		warnings = append(warnings, "server.data_dir changes apply when the server restarts")
+	}
+	s.applyConfig(config)
+	if config.Auth.Origin != previous.Auth.Origin {
+		// The bot links to the origin.
+		s.startTelegramBot()
+	}
+	slog.Info("configuration reloaded", "path", s.auth.configPath)
+	return warnings, nil
+}
+
+// applyConfig puts config in use: the origins allowed to call the API, and
+// passkeys for the public origin.
+func (s *Server) applyConfig(config *Config) {
+	origins := []string{"http://localhost:*"}
+	if config.Auth.Origin != "" {
+		origins = append(origins, config.Auth.Origin)
+	}
+	origins = append(origins, config.Server.AllowedOrigins...)
+
+	var wa *webauthn.WebAuthn
+	if parsed, err := url.Parse(config.Auth.Origin); err == nil && config.Auth.Origin != "" {
+		rpID := parsed.Hostname()
+		wa, err = webauthn.New(&webauthn.Config{
+			RPDisplayName: "Codeburg",
+			RPID:          rpID,
+			RPOrigins:     []string{config.Auth.Origin},
+			AuthenticatorSelection: protocol.AuthenticatorSelection{
+				ResidentKey:      protocol.ResidentKeyRequirementRequired,
+				UserVerification: protocol.VerificationPreferred,
+			},
+		})
+		if err != nil {
+			slog.Error("failed to initialize WebAuthn", "error", err)
+		} else {
+			slog.Info("webauthn initialized", "rpID", rpID, "origin", config.Auth.Origin)
+		}
+	}
+
+	// validate has checked them.
+	proxies, _ := parseTrustedProxies(config.Auth.TrustedProxies)
+
+	s.configMu.Lock()
+	s.config = config
+	s.allowedOrigins = origins
+	s.trustedProxies = proxies
+	s.webauthn = wa
+	s.configMu.Unlock()
+}
+
+// originAllowed reports whether origin may call the API.
+func (s *Server) originAllowed(origin string) bool {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return isAllowedOrigin(s.allowedOrigins, origin)
+}
+
+// passkeys returns the WebAuthn relying party, or nil without a public
+// origin.
+func (s *Server) passkeys() *webauthn.WebAuthn {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.webauthn
+}
+
+func (s *Server) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	warnings, err := s.ReloadConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "reloaded", "warnings": warnings})
+}
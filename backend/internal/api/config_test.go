@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "config.yaml")
+		os.WriteFile(path, []byte(content), 0600)
+		return path
+	}
+
+	config, err := LoadConfig(filepath.Join(dir, "missing.yaml"))
+	if err != nil || config.DatabasePath() != "" {
+		t.Fatalf("expected an empty configuration for a missing file, got %+v, %v", config, err)
+	}
+
+	config, err = LoadConfig(write(`
+auth:
+  origin: https://codeburg.example.com
+server:
+  allowed_origins: [https://dash.example.com, "http://127.0.0.1:*"]
+  data_dir: /srv/codeburg
+tunnels:
+  provider: ngrok
+notifications:
+  ntfy: {topic: builds}
+`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if config.DatabasePath() != "/srv/codeburg/codeburg.db" || config.Tunnels.Provider != "ngrok" || config.Notifications.Ntfy.Topic != "builds" {
+		t.Errorf("unexpected configuration %+v", config)
+	}
+
+	for content, want := range map[string]string{
+		"server:\n  alowed_origins: [https://x.example]\n":    "field alowed_origins not found",
+		"auth:\n  origin: codeburg.example.com\n":             "auth.origin",
+		"server:\n  allowed_origins: [https://x.example/app]": "server.allowed_origins",
+		"server:\n  data_dir: data\n":                         "server.data_dir",
+		"tunnels:\n  provider: frp\n":                         "tunnels.provider",
+		"notifications:\n  ntfy: {server: https://ntfy.sh}":   "notifications.ntfy",
+		"notifications:\n  email: {host: smtp.example.com}":   "notifications.email",
+	} {
+		if _, err := LoadConfig(write(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error about %s for %q, got %v", want, content, err)
+		}
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	env := setupTestEnv(t)
+	path := env.server.auth.configPath
+	os.WriteFile(path, []byte("tunnels:\n  provider: tailscale\n"), 0600)
+	env.setup("testpass123")
+
+	// Setting the password keeps the rest of the file.
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "provider: tailscale") {
+		t.Fatalf("expected the tunnels section kept, got:\n%s", data)
+	}
+	if env.server.tunnelProvidersConfig().Provider != "" {
+		t.Error("expected the file unapplied before a reload")
+	}
+
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append(data, []byte("server:\n  allowed_origins: [https://dash.example.com]\nnotifications:\n  ntfy: {topic: builds}\n")...), 0600)
+	resp := env.post("/api/config/reload", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if !env.server.originAllowed("https://dash.example.com") || !env.server.originAllowed("http://localhost:5173") {
+		t.Error("expected the configured and localhost origins allowed")
+	}
+	if env.server.tunnelProvidersConfig().Provider != "tailscale" {
+		t.Error("expected the configured tunnel provider as the default")
+	}
+	sinks := env.server.notificationSinks(eventAttention)
+	if len(sinks) != 1 || sinks[0].Name() != "ntfy" {
+		t.Errorf("expected the configured ntfy sink, got %v", sinks)
+	}
+	if !env.server.auth.ValidatePassword("testpass123") {
+		t.Error("expected the password kept")
+	}
+
+	// An invalid file is rejected and the settings in use stay.
+	os.WriteFile(path, []byte("tunnels:\n  provider: frp\n"), 0600)
+	resp = env.post("/api/config/reload", nil)
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), "tunnels.provider") {
+		t.Errorf("expected 400 naming the invalid setting, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if !env.server.originAllowed("https://dash.example.com") || env.server.tunnelProvidersConfig().Provider != "tailscale" {
+		t.Error("expected the previous configuration kept")
+	}
+}
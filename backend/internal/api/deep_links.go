@@ -0,0 +1,344 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/qrcode"
+)
+
+// Deep links preference:
+//
+//	deep_links  {"loginTokens": false, "ttlMinutes": 15, "qr": false}
+//
+// Notifications link to the task or session screen they are about. With
+// loginTokens, each link also carries a one-time token that logs in a
+// device that isn't logged in yet; it works once, within ttlMinutes. With
+// qr, ntfy and Web Push notifications carry a QR code of the link, to open
+// it on a phone.
+const deepLinksPreference = "deep_links"
+
+const (
+	defaultLoginLinkTTLMinutes = 15
+	maxLoginLinkTTLMinutes     = 24 * 60
+
+	// loginTokenParam is the query parameter the web UI exchanges for a
+	// login before opening the screen.
+	loginTokenParam = "login_token"
+
+	deepLinkQRScale = 6
+	// deepLinkQRTTL is how long the QR code of a link without a login
+	// token can be fetched; one with a token lasts as long as the token.
+	deepLinkQRTTL = 24 * time.Hour
+)
+
+type deepLinkSettings struct {
+	LoginTokens bool `json:"loginTokens"`
+	TTLMinutes  int  `json:"ttlMinutes"`
+	QR          bool `json:"qr"`
+}
+
+func (d deepLinkSettings) ttl() time.Duration {
+	return time.Duration(d.TTLMinutes) * time.Minute
+}
+
+// deepLinkSettings reads the deep_links preference over the defaults.
+func (s *Server) deepLinkSettings() deepLinkSettings {
+	settings := deepLinkSettings{TTLMinutes: defaultLoginLinkTTLMinutes}
+	pref, err := s.db.GetPreference(db.DefaultUserID, deepLinksPreference)
+	if err != nil {
+		return settings
+	}
+	if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+		slog.Warn("invalid deep_links preference", "error", err)
+	}
+	if settings.TTLMinutes <= 0 {
+		settings.TTLMinutes = defaultLoginLinkTTLMinutes
+	}
+	settings.TTLMinutes = min(settings.TTLMinutes, maxLoginLinkTTLMinutes)
+	return settings
+}
+
+// taskPath is the web UI path of a task's screen.
+func taskPath(taskID string) string {
+	return "/tasks/" + url.PathEscape(taskID)
+}
+
+// sessionPath is the web UI path of a task's screen with one of its
+// sessions open.
+func sessionPath(taskID, sessionID string) string {
+	return taskPath(taskID) + "?session=" + url.QueryEscape(sessionID)
+}
+
+// validAppPath reports whether path is a path of the web UI itself, so a
+// link can't send the browser to another site.
+func validAppPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.Contains(path, `\`)
+}
+
+func withQueryParam(path, key, value string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + key + "=" + url.QueryEscape(value)
+}
+
+func generateLoginToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// deepLink returns the web UI URL of path, carrying a one-time login token
+// when the deep_links preference asks for them. It returns "" when no
+// public origin is configured.
+func (s *Server) deepLink(path string) string {
+	origin := s.webOrigin()
+	if origin == "" {
+		return ""
+	}
+	settings := s.deepLinkSettings()
+	if !settings.LoginTokens {
+		return origin + path
+	}
+	link, _, err := s.createLoginLink(origin, path, settings.ttl())
+	if err != nil {
+		slog.Warn("failed to create login link", "path", path, "error", err)
+		return origin + path
+	}
+	return link
+}
+
+// createLoginLink mints a one-time login token for path and returns the
+// link carrying it.
+func (s *Server) createLoginLink(origin, path string, ttl time.Duration) (string, time.Time, error) {
+	raw, err := generateLoginToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	if _, err := s.db.CreateLoginLink(hashAPIToken(raw), path, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+	return origin + withQueryParam(path, loginTokenParam, raw), expiresAt, nil
+}
+
+// qrLinkStore keeps the links notification QR codes encode under random
+// IDs, so the public URL of a QR code doesn't carry a login token into
+// access logs.
+type qrLinkStore struct {
+	mu    sync.Mutex
+	links map[string]qrLink
+}
+
+type qrLink struct {
+	link    string
+	expires time.Time
+}
+
+func (q *qrLinkStore) add(link string, ttl time.Duration) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(b)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	for k, entry := range q.links {
+		if now.After(entry.expires) {
+			delete(q.links, k)
+		}
+	}
+	if q.links == nil {
+		q.links = make(map[string]qrLink)
+	}
+	q.links[id] = qrLink{link: link, expires: now.Add(ttl)}
+	return id, nil
+}
+
+func (q *qrLinkStore) get(id string) (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.links[id]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.link, true
+}
+
+// deepLinkQR returns the URL of a QR code image of link, when the
+// deep_links preference asks for them.
+func (s *Server) deepLinkQR(link string) string {
+	settings := s.deepLinkSettings()
+	if link == "" || !settings.QR {
+		return ""
+	}
+	ttl := deepLinkQRTTL
+	if settings.LoginTokens {
+		ttl = settings.ttl()
+	}
+	id, err := s.qrLinks.add(link, ttl)
+	if err != nil {
+		slog.Warn("failed to keep QR code link", "error", err)
+		return ""
+	}
+	return s.webOrigin() + "/api/deep-links/qr/" + id
+}
+
+// handleCreateDeepLink serves POST /api/deep-links: a link to a task, a
+// session or another screen of the web UI, to continue on another device.
+// With login it carries a one-time login token, and with qr the response
+// includes a QR code of the link as a PNG data URL.
+func (s *Server) handleCreateDeepLink(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TaskID    string `json:"taskId"`
+		SessionID string `json:"sessionId"`
+		Path      string `json:"path"`
+		Login     bool   `json:"login"`
+		QR        bool   `json:"qr"`
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	origin := s.webOrigin()
+	if origin == "" {
+		writeError(w, http.StatusConflict, "no public origin is configured")
+		return
+	}
+
+	var path string
+	switch {
+	case input.SessionID != "":
+		session, err := s.db.GetSession(input.SessionID)
+		if err != nil {
+			writeDBError(w, err, "session")
+			return
+		}
+		if session.TaskID == "" {
+			writeError(w, http.StatusBadRequest, "session has no task")
+			return
+		}
+		path = sessionPath(session.TaskID, session.ID)
+	case input.TaskID != "":
+		task, err := s.db.GetTask(input.TaskID)
+		if err != nil {
+			writeDBError(w, err, "task")
+			return
+		}
+		path = taskPath(task.ID)
+	case input.Path != "":
+		if !validAppPath(input.Path) {
+			writeError(w, http.StatusBadRequest, "path must be a path of the web UI")
+			return
+		}
+		path = input.Path
+	default:
+		path = "/"
+	}
+
+	resp := map[string]any{"url": origin + path}
+	if input.Login {
+		link, expiresAt, err := s.createLoginLink(origin, path, s.deepLinkSettings().ttl())
+		if err != nil {
+			slog.Warn("failed to create login link", "path", path, "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to create login link")
+			return
+		}
+		resp["url"] = link
+		resp["expiresAt"] = expiresAt
+	}
+	if input.QR {
+		png, err := qrPNG(resp["url"].(string))
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		resp["qr"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func qrPNG(link string) ([]byte, error) {
+	code, err := qrcode.Encode(link)
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG(deepLinkQRScale)
+}
+
+// handleDeepLinkQR serves GET /api/deep-links/qr/{id}, the QR code image
+// a notification attaches. It's public, so notification services can fetch
+// it, and only encodes links deepLinkQR kept, until they expire.
+func (s *Server) handleDeepLinkQR(w http.ResponseWriter, r *http.Request) {
+	link, ok := s.qrLinks.get(urlParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "QR code not found or expired")
+		return
+	}
+	png, err := qrPNG(link)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(png)
+}
+
+// handleLoginLink serves POST /api/auth/login-link, exchanging a link's
+// one-time token for a login token and the path the link opens.
+func (s *Server) handleLoginLink(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	if !s.authLimiter.allow(ip) {
+		writeError(w, http.StatusTooManyRequests, "too many attempts, try again later")
+		return
+	}
+
+	var input struct {
+		Token string `json:"token"`
+	}
+	if err := decodeJSON(r, &input); err != nil || input.Token == "" {
+		s.authLimiter.record(ip)
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	link, err := s.db.UseLoginLink(hashAPIToken(input.Token))
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			slog.Warn("login link lookup failed", "error", err)
+		}
+		s.authLimiter.record(ip)
+		writeError(w, http.StatusUnauthorized, "invalid or expired link")
+		return
+	}
+	s.authLimiter.reset(ip)
+
+	token, err := s.auth.GenerateToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	s.setSessionCookie(w, token)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"token": token,
+		"path":  link.Path,
+	})
+}
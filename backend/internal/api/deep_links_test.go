@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+const testOrigin = "https://codeburg.example.com"
+
+func setTestOrigin(t *testing.T, env *testEnv) {
+	t.Helper()
+	config, err := env.server.auth.loadConfig()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	config.Auth.Origin = testOrigin
+	if err := env.server.auth.saveConfig(config); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+}
+
+func createDeepLinkSession(t *testing.T, env *testEnv) (*db.Task, *db.AgentSession) {
+	t.Helper()
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix login"}), &task)
+	session, err := env.server.db.CreateSession(db.CreateSessionInput{
+		TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "terminal",
+	})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return &task, session
+}
+
+func TestNotificationDeepLinkLogsInOnce(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	setTestOrigin(t, env)
+
+	received := make(chan map[string]any, 1)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+	env.server.db.SetPreference(db.DefaultUserID, deepLinksPreference, `{"loginTokens": true, "qr": true}`)
+
+	task, session := createDeepLinkSession(t, env)
+	env.server.notifySessionNeedsAttention(task.ID, session.ID)
+	body := <-received
+
+	click, _ := body["click"].(string)
+	link, err := url.Parse(click)
+	if err != nil || !strings.HasPrefix(click, testOrigin+"/tasks/"+task.ID+"?") {
+		t.Fatalf("unexpected click URL %q", click)
+	}
+	if link.Query().Get("session") != session.ID {
+		t.Errorf("expected the link to open session %s, got %q", session.ID, click)
+	}
+	token := link.Query().Get(loginTokenParam)
+	attach, _ := body["attach"].(string)
+	if !strings.HasPrefix(attach, testOrigin+"/api/deep-links/qr/") || strings.Contains(attach, token) {
+		t.Errorf("expected a QR attachment without the login token, got %q", attach)
+	}
+
+	resp := env.requestWithToken("POST", "/api/auth/login-link", map[string]string{"token": token}, "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var login map[string]string
+	decodeResponse(t, resp, &login)
+	if !env.server.auth.ValidateToken(login["token"]) {
+		t.Error("expected a valid login token")
+	}
+	if login["path"] != sessionPath(task.ID, session.ID) {
+		t.Errorf("unexpected path %q", login["path"])
+	}
+
+	resp = env.requestWithToken("POST", "/api/auth/login-link", map[string]string{"token": token}, "")
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected a reused link to get 401, got %d", resp.Code)
+	}
+}
+
+func TestNotificationDeepLinkWithoutLoginTokens(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	setTestOrigin(t, env)
+
+	task, session := createDeepLinkSession(t, env)
+	link := env.server.deepLink(sessionPath(task.ID, session.ID))
+	if link != testOrigin+"/tasks/"+task.ID+"?session="+session.ID {
+		t.Errorf("unexpected link %q", link)
+	}
+	if qr := env.server.deepLinkQR(link); qr != "" {
+		t.Errorf("expected no QR code by default, got %q", qr)
+	}
+}
+
+func TestCreateDeepLink(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, session := createDeepLinkSession(t, env)
+
+	if resp := env.post("/api/deep-links", map[string]string{"taskId": task.ID}); resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 without an origin, got %d", resp.Code)
+	}
+	setTestOrigin(t, env)
+
+	var plain map[string]any
+	decodeResponse(t, env.post("/api/deep-links", map[string]string{"taskId": task.ID}), &plain)
+	if plain["url"] != testOrigin+"/tasks/"+task.ID || plain["expiresAt"] != nil {
+		t.Errorf("unexpected link %v", plain)
+	}
+
+	resp := env.post("/api/deep-links", map[string]any{"sessionId": session.ID, "login": true, "qr": true})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var created map[string]any
+	decodeResponse(t, resp, &created)
+	if link, _ := created["url"].(string); !strings.Contains(link, "?session="+session.ID+"&"+loginTokenParam+"=") {
+		t.Errorf("unexpected link %q", link)
+	}
+	if created["expiresAt"] == nil {
+		t.Error("expected a login link to expire")
+	}
+	qr, _ := created["qr"].(string)
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(qr, "data:image/png;base64,"))
+	if err != nil {
+		t.Fatalf("decode qr: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("expected a PNG QR code: %v", err)
+	}
+
+	for _, path := range []string{"//evil.example.com", "https://evil.example.com", `/\evil.example.com`} {
+		if resp := env.post("/api/deep-links", map[string]string{"path": path}); resp.Code != http.StatusBadRequest {
+			t.Errorf("path %q: expected 400, got %d", path, resp.Code)
+		}
+	}
+	if resp := env.post("/api/deep-links", map[string]string{"taskId": "nope"}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown task, got %d", resp.Code)
+	}
+}
+
+func TestDeepLinkQR(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	setTestOrigin(t, env)
+	env.server.db.SetPreference(db.DefaultUserID, deepLinksPreference, `{"qr": true}`)
+
+	qr := env.server.deepLinkQR(testOrigin + "/tasks/abc?" + loginTokenParam + "=secret")
+	if !strings.HasPrefix(qr, testOrigin+"/api/deep-links/qr/") || strings.Contains(qr, "secret") {
+		t.Fatalf("expected a QR URL without the link in it, got %q", qr)
+	}
+	resp := env.requestWithToken("GET", strings.TrimPrefix(qr, testOrigin), nil, "")
+	if resp.Code != http.StatusOK || resp.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a PNG, got %d %q", resp.Code, resp.Header().Get("Content-Type"))
+	}
+
+	if resp := env.requestWithToken("GET", "/api/deep-links/qr/unknown", nil, ""); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown QR code, got %d", resp.Code)
+	}
+	id, _ := env.server.qrLinks.add(testOrigin+"/tasks/abc", -time.Second)
+	if resp := env.requestWithToken("GET", "/api/deep-links/qr/"+id, nil, ""); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an expired QR code, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/demo"
+	"github.com/miguel-bm/codeburg/internal/fakeprovider"
+)
+
+// SeedDemo fills an empty server with the sample content of package demo:
+// the sample repository, written under dir and added as a project, its
+// tasks, and their sessions' transcripts, replayed through the chat manager
+// so they read as if the agents had run. A server that has projects is left
+// as it is.
+func (s *Server) SeedDemo(dir string) error {
+	projects, err := s.db.ListProjects()
+	if err != nil {
+		return fmt.Errorf("list projects: %w", err)
+	}
+	if len(projects) > 0 {
+		return nil
+	}
+
+	path := filepath.Join(dir, demo.ProjectName)
+	if err := demo.WriteRepo(path); err != nil {
+		return err
+	}
+	mainBranch := "main"
+	project, err := s.db.CreateProject(db.CreateProjectInput{Name: demo.ProjectName, Path: path, DefaultBranch: &mainBranch})
+	if err != nil {
+		return fmt.Errorf("create project: %w", err)
+	}
+
+	for _, sample := range demo.Tasks {
+		description, priority := sample.Description, sample.Priority
+		task, err := s.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: sample.Title, Description: &description, Priority: &priority})
+		if err != nil {
+			return fmt.Errorf("create task: %w", err)
+		}
+		if sample.Status != db.TaskStatusBacklog {
+			status := sample.Status
+			if _, err := s.db.UpdateTask(task.ID, db.UpdateTaskInput{Status: &status}); err != nil {
+				return fmt.Errorf("update task: %w", err)
+			}
+		}
+		for _, run := range sample.Sessions {
+			if err := s.seedDemoSession(task, run); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) seedDemoSession(task *db.Task, run demo.Session) error {
+	session, err := s.db.CreateSession(db.CreateSessionInput{
+		TaskID:      task.ID,
+		ProjectID:   task.ProjectID,
+		Provider:    run.Provider,
+		SessionType: "chat",
+	})
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	s.nameSession(session, run.Prompt)
+	if err := s.chat.Replay(session.ID, run.Prompt, fakeprovider.Transcript(run.Provider, run.Prompt, run.Script)); err != nil {
+		return fmt.Errorf("replay session: %w", err)
+	}
+	completed := db.SessionStatusCompleted
+	if _, err := s.db.UpdateSession(session.ID, db.UpdateSessionInput{Status: &completed}); err != nil {
+		return fmt.Errorf("update session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/demo"
+)
+
+func TestSeedDemo(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	dir := t.TempDir()
+
+	if err := env.server.SeedDemo(dir); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	var projects []db.Project
+	decodeResponse(t, env.get("/api/projects"), &projects)
+	if len(projects) != 1 || projects[0].Name != demo.ProjectName {
+		t.Fatalf("expected the sample project, got %+v", projects)
+	}
+	if _, err := os.Stat(filepath.Join(dir, demo.ProjectName, "todo", "store.py")); err != nil {
+		t.Errorf("expected the sample repository written: %v", err)
+	}
+	resp := env.get("/api/projects/" + projects[0].ID + "/git/log")
+	if resp.Code != http.StatusOK {
+		t.Errorf("expected a git log for the sample repository, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	var tasks []db.Task
+	decodeResponse(t, env.get("/api/tasks?project="+projects[0].ID), &tasks)
+	statuses := map[db.TaskStatus]int{}
+	for _, task := range tasks {
+		statuses[task.Status]++
+	}
+	for _, status := range db.BuiltinTaskStatuses {
+		if statuses[status] == 0 {
+			t.Errorf("expected a task in %s, got %v", status, statuses)
+		}
+	}
+
+	var sessions []db.AgentSession
+	decodeResponse(t, env.get("/api/sessions?projectId="+projects[0].ID), &sessions)
+	if len(sessions) == 0 {
+		t.Fatal("expected sessions")
+	}
+	for _, session := range sessions {
+		if session.Status != db.SessionStatusCompleted || session.Title == "" {
+			t.Errorf("expected a completed, titled session, got %+v", session)
+		}
+		kinds := map[ChatMessageKind]int{}
+		for _, msg := range env.chatMessages(t, session.ID) {
+			kinds[msg.Kind]++
+		}
+		if kinds[ChatMessageKindUserText] != 1 || kinds[ChatMessageKindAgentText] == 0 || kinds[ChatMessageKindToolCall] == 0 {
+			t.Errorf("expected a replayed transcript for %s session, got %v", session.Provider, kinds)
+		}
+	}
+
+	// Seeding again leaves the server as it is.
+	if err := env.server.SeedDemo(dir); err != nil {
+		t.Fatalf("seed again: %v", err)
+	}
+	decodeResponse(t, env.get("/api/projects"), &projects)
+	if len(projects) != 1 {
+		t.Errorf("expected one project after seeding twice, got %d", len(projects))
+	}
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/devcontainer"
+)
+
+const (
+	// devcontainerUpTimeout bounds pulling or building the image and starting
+	// the container when a session or recipe needs it.
+	devcontainerUpTimeout = 30 * time.Minute
+
+	containerProgressInterval = 500 * time.Millisecond
+)
+
+// devcontainerLocks serializes bringing up each task's container.
+var devcontainerLocks sync.Map
+
+func devcontainerEnabled(project *db.Project) bool {
+	return project.Devcontainer != nil && project.Devcontainer.Enabled
+}
+
+func validateDevcontainer(cfg *db.ProjectDevcontainer) error {
+	if cfg.Config == "" {
+		return nil
+	}
+	clean := filepath.Clean(cfg.Config)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("devcontainer config must be a path inside the project")
+	}
+	return nil
+}
+
+// taskContainerName names the container for a task; tasks have one each, as
+// their worktrees differ.
+func taskContainerName(taskID string) string {
+	return "codeburg-" + strings.ToLower(taskID)
+}
+
+// loadDevcontainer reads the project's devcontainer.json from dir, the
+// project or one of its worktrees.
+func loadDevcontainer(project *db.Project, dir string) (*devcontainer.Config, error) {
+	if project.Devcontainer != nil && project.Devcontainer.Config != "" {
+		return devcontainer.Load(filepath.Join(dir, project.Devcontainer.Config))
+	}
+	path, err := devcontainer.Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	return devcontainer.Load(path)
+}
+
+// taskContainer returns the running devcontainer for a task, starting it
+// first if needed, or nil if the task's project does not use one.
+func (s *Server) taskContainer(ctx context.Context, taskID string, progress func(string)) (*devcontainer.Container, error) {
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !devcontainerEnabled(project) {
+		return nil, nil
+	}
+
+	workDir := project.Path
+	var mounts []string
+	if wt := ptrToString(task.WorktreePath); wt != "" {
+		// The worktree's .git file points into the project's git directory.
+		workDir = wt
+		mounts = append(mounts, filepath.Join(project.Path, ".git"))
+	}
+	cfg, err := loadDevcontainer(project, workDir)
+	if err != nil {
+		return nil, fmt.Errorf("devcontainer: %w", err)
+	}
+
+	lock, _ := devcontainerLocks.LoadOrStore(taskID, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, devcontainerUpTimeout)
+	defer cancel()
+	container, err := s.containers.Up(ctx, cfg, devcontainer.UpOptions{
+		Name:         taskContainerName(taskID),
+		Image:        "codeburg-dev-" + strings.ToLower(project.ID),
+		WorkspaceDir: workDir,
+		Mounts:       mounts,
+		Labels:       map[string]string{"codeburg.project": project.ID, "codeburg.task": taskID},
+		Progress:     progress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("devcontainer: %w", err)
+	}
+	return container, nil
+}
+
+// removeTaskContainer removes a task's devcontainer, when its project uses
+// one, along with the task's worktree or the task itself.
+func (s *Server) removeTaskContainer(task *db.Task, project *db.Project) {
+	if !devcontainerEnabled(project) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := s.containers.Remove(ctx, taskContainerName(task.ID)); err != nil {
+		slog.Warn("failed to remove task devcontainer", "task_id", task.ID, "error", err)
+	}
+}
+
+// containerShellArgs starts a login shell in a container, after running
+// prompt if set. Not every image has bash.
+func containerShellArgs(prompt string) []string {
+	shell := "if command -v bash >/dev/null 2>&1; then exec bash -l; else exec sh -l; fi"
+	if prompt != "" {
+		shell = prompt + "; " + shell
+	}
+	return []string{"sh", "-c", shell}
+}
+
+// throttledProgress passes on at most one line every
+// containerProgressInterval, as image builds print a lot.
+func throttledProgress(progress func(string)) func(string) {
+	var reported time.Time
+	return func(line string) {
+		if time.Since(reported) < containerProgressInterval {
+			return
+		}
+		reported = time.Now()
+		progress(line)
+	}
+}
+
+type projectDevcontainerResponse struct {
+	Found   bool                 `json:"found"`
+	Enabled bool                 `json:"enabled"`
+	Config  *devcontainer.Config `json:"config,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// handleGetProjectDevcontainer reports the devcontainer.json found in the
+// project, so that clients can offer to use it.
+func (s *Server) handleGetProjectDevcontainer(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	resp := projectDevcontainerResponse{Enabled: devcontainerEnabled(project)}
+	cfg, err := loadDevcontainer(project, project.Path)
+	switch {
+	case errors.Is(err, devcontainer.ErrNotFound), errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		resp.Found = true
+		resp.Error = err.Error()
+	default:
+		resp.Found = true
+		resp.Config = cfg
+		cfg.Path, _ = filepath.Rel(project.Path, cfg.Path)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type taskDevcontainerResponse struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`
+	State   string `json:"state,omitempty"` // e.g. "running"; empty if not created
+}
+
+func (s *Server) handleGetTaskDevcontainer(w http.ResponseWriter, r *http.Request) {
+	task, project, ok := s.devcontainerTask(w, r)
+	if !ok {
+		return
+	}
+	resp := taskDevcontainerResponse{Enabled: devcontainerEnabled(project)}
+	if resp.Enabled {
+		resp.Name = taskContainerName(task.ID)
+		resp.State = s.containers.State(r.Context(), resp.Name)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleStartTaskDevcontainer brings up a task's container in a job, ahead
+// of the first session or recipe that needs it.
+func (s *Server) handleStartTaskDevcontainer(w http.ResponseWriter, r *http.Request) {
+	task, project, ok := s.devcontainerTask(w, r)
+	if !ok {
+		return
+	}
+	if !devcontainerEnabled(project) {
+		writeError(w, http.StatusBadRequest, "project does not use a devcontainer")
+		return
+	}
+	job, err := s.enqueueJob(jobKindDevcontainer, project.ID, devcontainerJobPayload{TaskID: task.ID}, 1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue devcontainer")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleRemoveTaskDevcontainer removes a task's container, for instance to
+// pick up changes to devcontainer.json. The next session or recipe starts
+// a new one.
+func (s *Server) handleRemoveTaskDevcontainer(w http.ResponseWriter, r *http.Request) {
+	task, _, ok := s.devcontainerTask(w, r)
+	if !ok {
+		return
+	}
+	if err := s.containers.Remove(r.Context(), taskContainerName(task.ID)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) devcontainerTask(w http.ResponseWriter, r *http.Request) (*db.Task, *db.Project, bool) {
+	task, err := s.db.GetTask(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return nil, nil, false
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return nil, nil, false
+	}
+	return task, project, true
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/devcontainer"
+)
+
+func TestTaskDevcontainer(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	// A container engine stand-in that logs its calls.
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls.log")
+	state := filepath.Join(bin, "running")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\ncase \"$1\" in\n" +
+		"inspect) [ -f " + state + " ] && echo running || exit 1 ;;\n" +
+		"run) touch " + state + " ;;\n" +
+		"rm) rm -f " + state + " ;;\n" +
+		"esac\n"
+	os.WriteFile(filepath.Join(bin, "engine"), []byte(script), 0o755)
+	env.server.containers = devcontainer.Runtime{CLI: filepath.Join(bin, "engine")}
+
+	projectDir := t.TempDir()
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "devc", Path: projectDir})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "in a container"})
+
+	resp := env.get("/api/projects/" + project.ID + "/devcontainer")
+	var detected projectDevcontainerResponse
+	decodeResponse(t, resp, &detected)
+	if detected.Found {
+		t.Fatalf("expected no devcontainer, got %+v", detected)
+	}
+
+	os.MkdirAll(filepath.Join(projectDir, ".devcontainer"), 0o755)
+	os.WriteFile(filepath.Join(projectDir, ".devcontainer", "devcontainer.json"), []byte(`{
+		// Go lives here
+		"image": "golang:1.24",
+	}`), 0o644)
+	resp = env.get("/api/projects/" + project.ID + "/devcontainer")
+	decodeResponse(t, resp, &detected)
+	if !detected.Found || detected.Enabled || detected.Config == nil || detected.Config.Image != "golang:1.24" {
+		t.Fatalf("expected the devcontainer detected, got %+v", detected)
+	}
+
+	if resp := env.post("/api/tasks/"+task.ID+"/devcontainer", nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 before enabling, got %d", resp.Code)
+	}
+	if resp := env.patch("/api/projects/"+project.ID, map[string]any{"devcontainer": map[string]any{"enabled": true, "config": "../elsewhere.json"}}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a config outside the project, got %d", resp.Code)
+	}
+	if resp := env.patch("/api/projects/"+project.ID, map[string]any{"devcontainer": map[string]any{"enabled": true}}); resp.Code != http.StatusOK {
+		t.Fatalf("enable: %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp = env.post("/api/tasks/"+task.ID+"/devcontainer", nil)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("start: %d %s", resp.Code, resp.Body.String())
+	}
+	var job db.Job
+	decodeResponse(t, resp, &job)
+	runDueJobs(env)
+	if done, _ := env.server.db.GetJob(job.ID); done.Status != db.JobSucceeded {
+		t.Fatalf("expected the job to succeed, got %+v", done)
+	}
+
+	resp = env.get("/api/tasks/" + task.ID + "/devcontainer")
+	var status taskDevcontainerResponse
+	decodeResponse(t, resp, &status)
+	if !status.Enabled || status.State != "running" || status.Name != taskContainerName(task.ID) {
+		t.Fatalf("expected the container running, got %+v", status)
+	}
+
+	// Recipes and pipeline steps run through the engine.
+	if code, err := env.server.runPipelineStep(t.Context(), "run", 0, task.ID, projectDir, "make test"); code != 0 || err != nil {
+		t.Fatalf("pipeline step: %d %v", code, err)
+	}
+
+	if resp := env.delete("/api/tasks/" + task.ID + "/devcontainer"); resp.Code != http.StatusNoContent {
+		t.Fatalf("remove: %d", resp.Code)
+	}
+	data, _ := os.ReadFile(log)
+	calls := string(data)
+	for _, want := range []string{
+		"image inspect golang:1.24",
+		"run --detach --name " + taskContainerName(task.ID),
+		"exec --interactive --workdir /workspaces/" + filepath.Base(projectDir) + " " + taskContainerName(task.ID) + " sh -c make test",
+		"rm --force " + taskContainerName(task.ID),
+	} {
+		if !strings.Contains(calls, want) {
+			t.Errorf("expected %q in calls:\n%s", want, calls)
+		}
+	}
+}
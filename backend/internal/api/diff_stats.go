@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// diffStatsRefreshInterval is how often the worktrees of tasks in progress
+// or in review are checked for changed diff stats.
+const diffStatsRefreshInterval = 15 * time.Second
+
+// diffStatsCache holds the last diff stats computed per task, with the
+// worktree fingerprint they were computed at (see worktree.DiffStatsKey).
+// Entries are valid while the fingerprint matches, so they need no
+// invalidating: a commit, pull or edit, by the API or by an agent, changes
+// it. The zero value is ready to use.
+type diffStatsCache struct {
+	mu      sync.Mutex
+	entries map[string]diffStatsCacheEntry // task ID -> entry
+}
+
+type diffStatsCacheEntry struct {
+	key   string
+	stats *DiffStats
+}
+
+func (c *diffStatsCache) lookup(taskID, key string) (*DiffStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[taskID]
+	if !ok || entry.key != key {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+// store caches stats at key and reports whether they differ from the
+// stats cached before, if any.
+func (c *diffStatsCache) store(taskID, key string, stats *DiffStats) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]diffStatsCacheEntry)
+	}
+	previous, ok := c.entries[taskID]
+	c.entries[taskID] = diffStatsCacheEntry{key: key, stats: stats}
+	return ok && *previous.stats != *stats
+}
+
+// forget drops a task's entry, once its worktree is gone.
+func (c *diffStatsCache) forget(taskID string) {
+	c.mu.Lock()
+	delete(c.entries, taskID)
+	c.mu.Unlock()
+}
+
+// taskDiffStats returns a task's diff stats against baseBranch, from the
+// cache while its worktree is unchanged, and whether they differ from the
+// ones computed before. Returns nil on error (non-fatal).
+func (s *Server) taskDiffStats(task *db.Task, baseBranch string) (stats *DiffStats, changed bool) {
+	if task.WorktreePath == nil || *task.WorktreePath == "" {
+		return nil, false
+	}
+	key, err := s.worktree.DiffStatsKey(*task.WorktreePath, baseBranch)
+	if err != nil {
+		slog.Debug("diff stats key failed", "task_id", task.ID, "error", err)
+		return nil, false
+	}
+	if stats, ok := s.diffStats.lookup(task.ID, key); ok {
+		return stats, false
+	}
+
+	additions, deletions, err := s.worktree.DiffStats(*task.WorktreePath, baseBranch)
+	if err != nil {
+		slog.Debug("diff stats failed", "task_id", task.ID, "error", err)
+		return nil, false
+	}
+	stats = &DiffStats{Additions: additions, Deletions: deletions}
+	return stats, s.diffStats.store(task.ID, key, stats)
+}
+
+// getCachedDiffStats returns a task's diff stats against its base branch. Returns nil on error (non-fatal).
+func (s *Server) getCachedDiffStats(task *db.Task) *DiffStats {
+	if task.WorktreePath == nil || *task.WorktreePath == "" {
+		return nil
+	}
+	proj, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil
+	}
+	stats, changed := s.taskDiffStats(task, baseBranchFor(task, proj))
+	if changed {
+		s.broadcastDiffStats(task, stats)
+	}
+	return stats
+}
+
+// wakeDiffStats asks the refresher for a pass now, after something that
+// likely changed a worktree.
+func (s *Server) wakeDiffStats() {
+	select {
+	case s.diffStatsWake <- struct{}{}:
+	default:
+	}
+}
+
+// runDiffStatsRefresh keeps the diff stats of tasks in progress or in
+// review current until ctx is done, and pushes the ones that change.
+func (s *Server) runDiffStatsRefresh(ctx context.Context) {
+	ticker := time.NewTicker(diffStatsRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.diffStatsWake:
+		}
+		s.refreshDiffStats()
+	}
+}
+
+// refreshDiffStats runs one refresher pass.
+func (s *Server) refreshDiffStats() {
+	tasks, err := s.db.ListTasks(db.TaskFilter{
+		Categories: []db.TaskStatus{db.TaskStatusInProgress, db.TaskStatusInReview},
+	})
+	if err != nil {
+		slog.Warn("failed to list tasks for diff stats", "error", err)
+		return
+	}
+	projects := make(map[string]*db.Project)
+	for _, task := range tasks {
+		if task.WorktreePath == nil || *task.WorktreePath == "" {
+			continue
+		}
+		proj, ok := projects[task.ProjectID]
+		if !ok {
+			var err error
+			if proj, err = s.db.GetProject(task.ProjectID); err != nil {
+				continue
+			}
+			projects[task.ProjectID] = proj
+		}
+		if stats, changed := s.taskDiffStats(task, baseBranchFor(task, proj)); changed {
+			s.broadcastDiffStats(task, stats)
+		}
+	}
+}
+
+// broadcastDiffStats tells the task's and its project's subscribers about
+// its new diff stats.
+func (s *Server) broadcastDiffStats(task *db.Task, stats *DiffStats) {
+	payload := map[string]any{"taskId": task.ID, "diffStats": stats}
+	s.wsHub.BroadcastToTask(task.ID, "diff_stats", payload)
+	s.wsHub.BroadcastToProject(task.ProjectID, "diff_stats", payload)
+}
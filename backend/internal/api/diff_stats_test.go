@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestDiffStatsFollowWorktreeChanges(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	srv := httptest.NewServer(env.server.router)
+	defer srv.Close()
+
+	repo := createTestGitRepoWithMain(t)
+	gitExecHelper(t, repo, "checkout", "-b", "feature")
+	main := "main"
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "p", Path: repo, DefaultBranch: &main})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Edit the readme"})
+	inProgress := db.TaskStatusInProgress
+	task, _ = env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{Status: &inProgress, WorktreePath: &repo})
+
+	if stats := env.server.getCachedDiffStats(task); stats == nil || *stats != (DiffStats{}) {
+		t.Fatalf("expected no changes yet, got %+v", stats)
+	}
+
+	conn := dialWSWithToken(t, srv, env.token)
+	if got := subscribeWS(t, conn, "task", task.ID, ""); got != "subscribed" {
+		t.Fatalf("expected the task topic subscribed, got %s", got)
+	}
+
+	// An edit made outside the API, like an agent's, is picked up and pushed.
+	readme := filepath.Join(repo, "README.md")
+	os.WriteFile(readme, []byte("# Test\nOne\nTwo\n"), 0o644)
+	env.server.refreshDiffStats()
+	msg := readWS(t, conn)
+	data, _ := msg["data"].(map[string]any)
+	stats, _ := data["diffStats"].(map[string]any)
+	if msg["type"] != "diff_stats" || data["taskId"] != task.ID || stats["additions"] != float64(2) {
+		t.Fatalf("expected the new stats pushed, got %#v", msg)
+	}
+
+	// So is a commit made outside the API, followed by another edit.
+	gitExecHelper(t, repo, "commit", "-am", "edit")
+	os.WriteFile(readme, []byte("# Test\nOne\nTwo\nThree\n"), 0o644)
+	if stats := env.server.getCachedDiffStats(task); stats == nil || stats.Additions != 3 {
+		t.Fatalf("expected the committed and uncommitted changes counted, got %+v", stats)
+	}
+	msg = readWS(t, conn)
+	data, _ = msg["data"].(map[string]any)
+	if stats, _ := data["diffStats"].(map[string]any); msg["type"] != "diff_stats" || stats["additions"] != float64(3) {
+		t.Fatalf("expected the change pushed, got %#v", msg)
+	}
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+)
+
+// Digest preference:
+//
+//	digest  {"schedule": "weekly", "hour": 8, "weekday": 1}
+//
+// The digest sums up the period since the previous one: the tasks finished,
+// the sessions started, the commits agents made, and what is waiting now —
+// tasks in review and sessions waiting for input. schedule is daily (the
+// default), weekly or off. It goes out at hour, in the server's local time,
+// on weekday for weekly digests (0 is Sunday; Monday by default), to the
+// channels that want it (see notificationSinks). When it was last sent is
+// kept in a preference, so a restart doesn't send it twice.
+const (
+	digestPreference     = "digest"
+	digestSentPreference = "notification_digest_sent"
+	digestCheckInterval  = time.Minute
+	digestMaxItems       = 10
+
+	digestDaily  = "daily"
+	digestWeekly = "weekly"
+	digestOff    = "off"
+
+	defaultDigestHour = 8
+)
+
+type digestSettings struct {
+	Schedule string `json:"schedule,omitempty"`
+	Hour     *int   `json:"hour,omitempty"`
+	Weekday  *int   `json:"weekday,omitempty"`
+}
+
+// digestSettings returns the digest preference with defaults filled in.
+func (s *Server) digestSettings() digestSettings {
+	var settings digestSettings
+	if pref, err := s.db.GetPreference(db.DefaultUserID, digestPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+			slog.Warn("invalid digest preference", "error", err)
+		}
+	}
+	switch settings.Schedule {
+	case digestWeekly, digestOff:
+	default:
+		settings.Schedule = digestDaily
+	}
+	if settings.Hour == nil || *settings.Hour < 0 || *settings.Hour > 23 {
+		hour := defaultDigestHour
+		settings.Hour = &hour
+	}
+	if settings.Weekday == nil || *settings.Weekday < 0 || *settings.Weekday > 6 {
+		monday := int(time.Monday)
+		settings.Weekday = &monday
+	}
+	return settings
+}
+
+// period is how far back a digest on this schedule looks.
+func (d digestSettings) period() time.Duration {
+	if d.Schedule == digestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// due reports whether a digest should go out at now, given when the last
+// one was sent.
+func (d digestSettings) due(now, last time.Time) bool {
+	if d.Schedule == digestOff || now.Hour() < *d.Hour {
+		return false
+	}
+	if d.Schedule == digestWeekly && now.Weekday() != time.Weekday(*d.Weekday) {
+		return false
+	}
+	if last.IsZero() {
+		return true
+	}
+	y, m, day := last.In(now.Location()).Date()
+	ny, nm, nd := now.Date()
+	return y != ny || m != nm || day != nd
+}
+
+// since is where a digest sent at now starts: one period back, or the last
+// digest when that was more recent.
+func (d digestSettings) since(now, last time.Time) time.Time {
+	since := now.Add(-d.period())
+	if last.After(since) && last.Before(now) {
+		return last
+	}
+	return since
+}
+
+type digestItem struct {
+	TaskID    string `json:"taskId,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
+	Title     string `json:"title"`
+	Project   string `json:"project,omitempty"`
+}
+
+type digestReport struct {
+	Schedule string         `json:"schedule"`
+	Since    time.Time      `json:"since"`
+	Until    time.Time      `json:"until"`
+	Done     []digestItem   `json:"done"`
+	InReview []digestItem   `json:"inReview"`
+	Waiting  []digestItem   `json:"waiting"`
+	Sessions map[string]int `json:"sessions"` // sessions started, per provider
+	Commits  int            `json:"commits"`
+	URL      string         `json:"-"`
+}
+
+func (d digestReport) sessionCount() int {
+	total := 0
+	for _, n := range d.Sessions {
+		total += n
+	}
+	return total
+}
+
+func (d digestReport) empty() bool {
+	return len(d.Done) == 0 && len(d.InReview) == 0 && len(d.Waiting) == 0 &&
+		d.sessionCount() == 0 && d.Commits == 0
+}
+
+// message writes the digest in lang, listing up to digestMaxItems of each
+// section.
+func (d digestReport) message(lang string) notify.Message {
+	var body strings.Builder
+	if total := d.sessionCount(); total > 0 || d.Commits > 0 {
+		sessions := fmt.Sprint(total)
+		if total > 0 {
+			providers := make([]string, 0, len(d.Sessions))
+			for provider := range d.Sessions {
+				providers = append(providers, provider)
+			}
+			sort.Strings(providers)
+			for i, provider := range providers {
+				providers[i] = fmt.Sprintf("%s %d", provider, d.Sessions[provider])
+			}
+			sessions += " (" + strings.Join(providers, ", ") + ")"
+		}
+		body.WriteString(localize(lang, msgDigestActivity, sessions, d.Commits) + "\n")
+	}
+	section := func(heading string, items []digestItem) {
+		if len(items) == 0 {
+			return
+		}
+		if body.Len() > 0 {
+			body.WriteString("\n")
+		}
+		body.WriteString(localize(lang, heading, len(items)) + "\n")
+		for i, item := range items {
+			if i == digestMaxItems {
+				body.WriteString(localize(lang, msgDigestMore, len(items)-i) + "\n")
+				break
+			}
+			body.WriteString("• " + item.Title)
+			if item.Project != "" {
+				body.WriteString(" (" + item.Project + ")")
+			}
+			body.WriteString("\n")
+		}
+	}
+	section(msgDigestDone, d.Done)
+	section(msgDigestInReview, d.InReview)
+	section(msgDigestWaiting, d.Waiting)
+
+	title := msgDigestTitle
+	if d.Schedule == digestWeekly {
+		title = msgWeeklyDigestTitle
+	}
+	return notify.Message{
+		Title: localize(lang, title),
+		Body:  strings.TrimSuffix(body.String(), "\n"),
+		URL:   d.URL,
+	}
+}
+
+func (s *Server) runDigest(ctx context.Context) {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.queueDigestIfDue(now)
+		}
+	}
+}
+
+// queueDigestIfDue queues the digest job when the digest is due and no
+// digest job is already waiting or running.
+func (s *Server) queueDigestIfDue(now time.Time) {
+	if !s.digestSettings().due(now, s.digestSentAt()) {
+		return
+	}
+	if active, err := s.db.HasActiveJob(jobKindDigest); err != nil || active {
+		return
+	}
+	if _, err := s.enqueueJob(jobKindDigest, "", nil, 1); err != nil {
+		slog.Warn("failed to queue digest", "error", err)
+	}
+}
+
+// sendDigestIfDue sends the digest when the schedule says so, it hasn't been
+// sent yet today and some channel wants it. It reports whether a digest was
+// sent; an empty one is skipped but counts as sent.
+func (s *Server) sendDigestIfDue(now time.Time) bool {
+	settings := s.digestSettings()
+	last := s.digestSentAt()
+	if !settings.due(now, last) {
+		return false
+	}
+	sinks := s.notificationSinks(eventDigest)
+	if len(sinks) == 0 {
+		return false
+	}
+
+	report, err := s.buildDigest(settings.Schedule, settings.since(now, last), now)
+	if err != nil {
+		slog.Warn("failed to build digest", "error", err)
+		return false
+	}
+	if _, err := s.db.SetPreference(db.DefaultUserID, digestSentPreference, `"`+now.UTC().Format(time.RFC3339)+`"`); err != nil {
+		slog.Warn("failed to record digest", "error", err)
+		return false
+	}
+	if report.empty() {
+		return false
+	}
+	s.deliverLocalized(sinks, report.message)
+	return true
+}
+
+func (s *Server) digestSentAt() time.Time {
+	pref, err := s.db.GetPreference(db.DefaultUserID, digestSentPreference)
+	if err != nil {
+		return time.Time{}
+	}
+	sent, err := time.Parse(time.RFC3339, unquotePreference(pref.Value))
+	if err != nil {
+		return time.Time{}
+	}
+	return sent
+}
+
+// buildDigest collects what a digest of [since, until) lists, across all
+// projects.
+func (s *Server) buildDigest(schedule string, since, until time.Time) (digestReport, error) {
+	report := digestReport{Schedule: schedule, Since: since, Until: until, URL: s.deepLink("/")}
+	projects, err := s.db.ListProjects()
+	if err != nil {
+		return report, err
+	}
+	projectNames := make(map[string]string, len(projects))
+	for _, p := range projects {
+		projectNames[p.ID] = p.Name
+	}
+
+	done, err := s.db.ListTasks(db.TaskFilter{Categories: []db.TaskStatus{db.TaskStatusDone}})
+	if err != nil {
+		return report, err
+	}
+	report.Done = []digestItem{}
+	for _, task := range done {
+		if task.CompletedAt != nil && !task.CompletedAt.Before(since) && task.CompletedAt.Before(until) {
+			report.Done = append(report.Done, digestItem{TaskID: task.ID, Title: task.Title, Project: projectNames[task.ProjectID]})
+		}
+	}
+
+	inReview, err := s.db.ListTasks(db.TaskFilter{Categories: []db.TaskStatus{db.TaskStatusInReview}})
+	if err != nil {
+		return report, err
+	}
+	report.InReview = []digestItem{}
+	for _, task := range inReview {
+		report.InReview = append(report.InReview, digestItem{TaskID: task.ID, Title: task.Title, Project: projectNames[task.ProjectID]})
+	}
+
+	sessions, err := s.db.ListActiveSessions()
+	if err != nil {
+		return report, err
+	}
+	report.Waiting = []digestItem{}
+	for _, session := range sessions {
+		if session.Status != db.SessionStatusWaitingInput {
+			continue
+		}
+		title := sessionLabel(session)
+		if session.TaskID != "" {
+			if task, err := s.db.GetTask(session.TaskID); err == nil {
+				title = task.Title + " · " + title
+			}
+		}
+		report.Waiting = append(report.Waiting, digestItem{
+			TaskID: session.TaskID, SessionID: session.ID, Title: title, Project: projectNames[session.ProjectID],
+		})
+	}
+
+	if report.Sessions, err = s.db.CountSessionsByProvider(since, until); err != nil {
+		return report, err
+	}
+	activity, err := s.db.ListActivity(db.ActivityFilter{Since: since, Until: until})
+	if err != nil {
+		return report, err
+	}
+	for _, hour := range activity {
+		report.Commits += hour.Commits
+	}
+	return report, nil
+}
+
+// handleDigestPreview serves GET /api/digest/preview: the digest that would
+// go out now, with its message in the user's language. ?schedule=daily or
+// weekly previews that schedule instead of the configured one.
+func (s *Server) handleDigestPreview(w http.ResponseWriter, r *http.Request) {
+	settings := s.digestSettings()
+	switch schedule := r.URL.Query().Get("schedule"); schedule {
+	case "":
+	case digestDaily, digestWeekly:
+		settings.Schedule = schedule
+	default:
+		writeError(w, http.StatusBadRequest, "schedule must be daily or weekly")
+		return
+	}
+	if settings.Schedule == digestOff {
+		settings.Schedule = digestDaily
+	}
+
+	now := time.Now()
+	report, err := s.buildDigest(settings.Schedule, settings.since(now, s.digestSentAt()), now)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to build digest")
+		return
+	}
+	msg := report.message(s.userLanguage())
+	writeJSON(w, http.StatusOK, struct {
+		digestReport
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}{report, msg.Title, msg.Body})
+}
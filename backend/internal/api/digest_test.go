@@ -0,0 +1,134 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestDailyDigest(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "webapp", Path: t.TempDir()})
+	done, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Ship export"})
+	review, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Fix login"})
+	env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Untouched"})
+	doneStatus, reviewStatus := db.TaskStatusDone, db.TaskStatusInReview
+	env.server.db.UpdateTask(done.ID, db.UpdateTaskInput{Status: &doneStatus})
+	env.server.db.UpdateTask(review.ID, db.UpdateTaskInput{Status: &reviewStatus})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{
+		TaskID: review.ID, ProjectID: project.ID, Provider: "claude", SessionType: "terminal",
+	})
+	waiting := db.SessionStatusWaitingInput
+	env.server.db.UpdateSession(session.ID, db.UpdateSessionInput{Status: &waiting})
+
+	env.server.db.RecordActivityCommits(project.ID, "claude", []db.ActivityCommit{{Hash: "abc123", At: time.Now()}})
+
+	now := time.Now()
+	digest, err := env.server.buildDigest(digestDaily, now.Add(-time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("build digest: %v", err)
+	}
+	msg := digest.message("en")
+	want := "Sessions started: 1 (claude 1) · Commits: 1\n\n" +
+		"Done since the last digest (1):\n• Ship export (webapp)\n\n" +
+		"Waiting for review (1):\n• Fix login (webapp)\n\n" +
+		"Sessions waiting for input (1):\n• Fix login · claude " + shortID(session.ID) + " (webapp)"
+	if msg.Title != "Codeburg daily digest" || msg.Body != want {
+		t.Errorf("unexpected digest %q:\n%s", msg.Title, msg.Body)
+	}
+	if digest, _ := env.server.buildDigest(digestDaily, now.Add(time.Hour), now.Add(2*time.Hour)); len(digest.Done) != 0 || digest.sessionCount() != 0 {
+		t.Errorf("expected what happened before since to be left out, got %+v", digest)
+	}
+
+	// Nothing wants the digest until email is set up. The server doesn't
+	// exist, so sending fails, but the digest still counts as sent.
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	if env.server.sendDigestIfDue(day.Add(10 * time.Hour)) {
+		t.Fatal("sent a digest with no channel for it")
+	}
+	env.server.db.SetPreference(db.DefaultUserID, emailPreference,
+		`{"host": "127.0.0.1", "port": 1, "security": "none", "from": "cb@example.com", "to": ["me@example.com"]}`)
+	env.server.db.SetPreference(db.DefaultUserID, digestPreference, `{"hour": 9}`)
+	for _, step := range []struct {
+		at   time.Duration
+		sent bool
+	}{
+		{8 * time.Hour, false},  // before the digest hour
+		{10 * time.Hour, true},  // due
+		{23 * time.Hour, false}, // already sent today
+		{24*time.Hour + time.Minute, false},
+		{33 * time.Hour, true}, // the next day
+	} {
+		if sent := env.server.sendDigestIfDue(day.Add(step.at)); sent != step.sent {
+			t.Errorf("at %v: sent = %v, want %v", step.at, sent, step.sent)
+		}
+	}
+	if sent := env.server.digestSentAt(); !sent.Equal(day.Add(33 * time.Hour).Truncate(time.Second)) {
+		t.Errorf("digest sent at %v", sent)
+	}
+}
+
+func TestDigestSchedule(t *testing.T) {
+	hour, monday := 9, int(time.Monday)
+	weekly := digestSettings{Schedule: digestWeekly, Hour: &hour, Weekday: &monday}
+	sunday := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	nextMonday := sunday.Add(24 * time.Hour)
+	lastMonday := nextMonday.Add(-7 * 24 * time.Hour)
+
+	if weekly.due(sunday, time.Time{}) {
+		t.Error("weekly digest due on the wrong weekday")
+	}
+	if !weekly.due(nextMonday, lastMonday) {
+		t.Error("weekly digest not due on its weekday")
+	}
+	if weekly.due(nextMonday, nextMonday.Add(-time.Minute)) {
+		t.Error("weekly digest due twice on the same day")
+	}
+	if since := weekly.since(nextMonday, lastMonday); !since.Equal(lastMonday) {
+		t.Errorf("weekly digest since %v, want the last one", since)
+	}
+	if since := weekly.since(nextMonday, time.Time{}); !since.Equal(nextMonday.Add(-7 * 24 * time.Hour)) {
+		t.Errorf("first weekly digest since %v, want a week back", since)
+	}
+
+	off := digestSettings{Schedule: digestOff, Hour: &hour, Weekday: &monday}
+	if off.due(nextMonday, time.Time{}) {
+		t.Error("digest due with the schedule off")
+	}
+}
+
+func TestDigestPreview(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "webapp", Path: t.TempDir()})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Ship export"})
+	doneStatus := db.TaskStatusDone
+	env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{Status: &doneStatus})
+	env.server.db.SetPreference(db.DefaultUserID, "language", `"es"`)
+
+	resp := env.get("/api/digest/preview?schedule=weekly")
+	if resp.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var preview struct {
+		Schedule string       `json:"schedule"`
+		Done     []digestItem `json:"done"`
+		Title    string       `json:"title"`
+		Body     string       `json:"body"`
+	}
+	decodeResponse(t, resp, &preview)
+	if preview.Schedule != digestWeekly || len(preview.Done) != 1 || preview.Done[0].TaskID != task.ID {
+		t.Errorf("unexpected preview %+v", preview)
+	}
+	if preview.Title != "Resumen semanal de Codeburg" {
+		t.Errorf("expected the title in the user's language, got %q", preview.Title)
+	}
+
+	if resp := env.get("/api/digest/preview?schedule=hourly"); resp.Code != 400 {
+		t.Errorf("expected 400 for an unknown schedule, got %d", resp.Code)
+	}
+}
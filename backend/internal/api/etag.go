@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tasks and projects carry a version that every change bumps. GET and PATCH
+// send it as the ETag, and writes honor If-Match with it (or "version" in
+// the body), so that an edit from the web app, Telegram or an LLM tool based
+// on an old read fails instead of overwriting the one in between. A stale
+// write answers 412 for If-Match and 409 for a body version, with the record
+// as it is now.
+//
+// A task's board position is outside its version: moving or reordering a
+// task bumps that task, but not the tasks around it that are renumbered to
+// make room, so an edit to one of them isn't refused because a neighbor
+// moved.
+
+func setVersionETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// ifMatchVersion returns the version the If-Match header requires, or nil
+// without one or for "*". A tag that is not one of ours, weak ones included,
+// can never match and reads as -1.
+func ifMatchVersion(r *http.Request) *int {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+	version := -1
+	if tag, ok := strings.CutPrefix(header, `"`); ok {
+		if tag, ok := strings.CutSuffix(tag, `"`); ok {
+			if n, err := strconv.Atoi(tag); err == nil && n >= 0 {
+				version = n
+			}
+		}
+	}
+	return &version
+}
+
+// writeStale answers a write based on a stale version with the current
+// record under key, e.g. "task".
+func writeStale(w http.ResponseWriter, ifMatch *int, msg, key string, current any, version int) {
+	status := http.StatusConflict
+	if ifMatch != nil {
+		status = http.StatusPreconditionFailed
+	}
+	setVersionETag(w, version)
+	writeJSON(w, status, map[string]any{"error": msg, key: current})
+}
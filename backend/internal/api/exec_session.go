@@ -17,6 +17,7 @@ type Session struct {
 	WorkDir           string
 	LastActivityAt    time.Time
 	FallbackStarted   bool
+	StartRequest      *StartSessionRequest // nil for sessions restored from the DB
 	mu                sync.Mutex
 }
 
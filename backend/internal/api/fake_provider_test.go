@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/fakeprovider"
+)
+
+// installFakeProviders puts the fake claude and codex first on PATH, plays
+// script in their turns (the default script when nil) and returns a
+// function that reads how they were run.
+func installFakeProviders(t *testing.T, script fakeprovider.Script) func() []fakeprovider.Invocation {
+	t.Helper()
+	bin := t.TempDir()
+	if err := fakeprovider.Install(bin); err != nil {
+		t.Fatalf("install fake providers: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	if script != nil {
+		data, _ := json.Marshal(script)
+		path := filepath.Join(dir, "script.json")
+		os.WriteFile(path, data, 0o644)
+		t.Setenv(fakeprovider.ScriptEnv, path)
+	}
+	logPath := filepath.Join(dir, "invocations.jsonl")
+	t.Setenv(fakeprovider.LogEnv, logPath)
+
+	return func() []fakeprovider.Invocation {
+		f, err := os.Open(logPath)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		var invocations []fakeprovider.Invocation
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var inv fakeprovider.Invocation
+			if json.Unmarshal(scanner.Bytes(), &inv) == nil {
+				invocations = append(invocations, inv)
+			}
+		}
+		return invocations
+	}
+}
+
+func (e *testEnv) waitForSessionStatus(t *testing.T, id string, want db.SessionStatus) *db.AgentSession {
+	t.Helper()
+	var session *db.AgentSession
+	waitForCondition(t, 10*time.Second, func() bool {
+		session, _ = e.server.db.GetSession(id)
+		return session != nil && session.Status == want
+	}, "session "+string(want))
+	return session
+}
+
+func (e *testEnv) chatMessages(t *testing.T, id string) []ChatMessage {
+	t.Helper()
+	var messages []ChatMessage
+	decodeResponse(t, e.get("/api/sessions/"+id+"/messages"), &messages)
+	return messages
+}
+
+func TestFakeProviderChatSessions(t *testing.T) {
+	for _, provider := range []string{"claude", "codex"} {
+		t.Run(provider, func(t *testing.T) {
+			env := setupTestEnv(t)
+			env.setup("testpass123")
+			invocations := installFakeProviders(t, nil)
+
+			var project db.Project
+			decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+			var task db.Task
+			decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix login"}), &task)
+
+			resp := env.post("/api/tasks/"+task.ID+"/sessions", map[string]string{
+				"provider": provider, "sessionType": "chat", "prompt": "hello",
+			})
+			if resp.Code != 201 {
+				t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+			}
+			var session db.AgentSession
+			decodeResponse(t, resp, &session)
+
+			started := env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+			if started.ProviderSessionID == nil || !strings.HasPrefix(*started.ProviderSessionID, "fake-") {
+				t.Fatalf("expected the provider session ID to be captured, got %v", started.ProviderSessionID)
+			}
+
+			var text, thinking []string
+			tools := 0
+			for _, msg := range env.chatMessages(t, session.ID) {
+				switch {
+				case msg.Kind == ChatMessageKindToolCall && msg.Tool.State == ChatToolStateCompleted:
+					tools++
+				case msg.Kind == ChatMessageKindAgentText && msg.IsThinking:
+					thinking = append(thinking, msg.Text)
+				case msg.Kind == ChatMessageKindAgentText:
+					text = append(text, msg.Text)
+				}
+			}
+			want := "Fake " + provider + " reply to: hello"
+			if !slices.Contains(text, want) || len(thinking) != 1 || tools != 1 {
+				t.Errorf("unexpected transcript: text %q, thinking %q, %d tools", text, thinking, tools)
+			}
+
+			// The next turn resumes the provider's session.
+			if resp := env.post("/api/sessions/"+session.ID+"/message", map[string]string{"content": "and now?"}); resp.Code != 200 {
+				t.Fatalf("send message: %d %s", resp.Code, resp.Body.String())
+			}
+			waitForCondition(t, 10*time.Second, func() bool { return len(invocations()) == 2 }, "second turn")
+			env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+			second := invocations()[1]
+			if !slices.Contains(second.Args, *started.ProviderSessionID) || !slices.Contains(second.Prompts, "and now?") {
+				t.Errorf("expected the second turn to resume %s, got %+v", *started.ProviderSessionID, second)
+			}
+		})
+	}
+}
+
+func TestFakeProviderPermissionRequest(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	installFakeProviders(t, fakeprovider.Script{
+		{Tool: "Bash", Input: map[string]any{"command": "rm -rf build"}, Output: "removed", Ask: true},
+		{Text: "Cleaned up."},
+	})
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	var session db.AgentSession
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/sessions", map[string]any{
+		"provider": "claude", "sessionType": "chat", "prompt": "clean the build", "autoApprove": false,
+	}), &session)
+
+	var requestID string
+	waitForCondition(t, 10*time.Second, func() bool {
+		for _, msg := range env.chatMessages(t, session.ID) {
+			if msg.Permission != nil && msg.Permission.Status == ChatPermissionPending {
+				requestID = msg.Permission.RequestID
+				return true
+			}
+		}
+		return false
+	}, "permission request")
+
+	resp := env.post("/api/sessions/"+session.ID+"/permissions/"+requestID, map[string]string{"decision": "allow"})
+	if resp.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+
+	var result any
+	for _, msg := range env.chatMessages(t, session.ID) {
+		if msg.Kind == ChatMessageKindToolCall && msg.Tool.CallID != "" {
+			result = msg.Tool.Result
+		}
+	}
+	if result != "removed" {
+		t.Errorf("expected the allowed command to run, got result %v", result)
+	}
+}
+
+func TestFakeProviderTerminalHooks(t *testing.T) {
+	for _, provider := range []string{"claude", "codex"} {
+		t.Run(provider, func(t *testing.T) {
+			env := setupTestEnv(t)
+			env.setup("testpass123")
+			installFakeProviders(t, nil)
+
+			// Hooks call back over HTTP with a token kept under $HOME.
+			srv := httptest.NewServer(env.server.Handler())
+			t.Cleanup(srv.Close)
+			t.Setenv("CODEBURG_URL", srv.URL)
+			t.Setenv("HOME", t.TempDir())
+
+			var project db.Project
+			decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+			var session db.AgentSession
+			resp := env.post("/api/projects/"+project.ID+"/sessions", map[string]string{
+				"provider": provider, "sessionType": "terminal", "prompt": "hello",
+			})
+			if resp.Code != 201 {
+				t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+			}
+			decodeResponse(t, resp, &session)
+			t.Cleanup(func() { env.server.sessions.runtime.Stop(session.ID) })
+
+			// The turn's Stop hook (claude) or notify program (codex) reports
+			// the session waiting for input.
+			env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+
+			if resp := env.post("/api/sessions/"+session.ID+"/message", map[string]string{"content": "and now?"}); resp.Code != 200 {
+				t.Fatalf("send message: %d %s", resp.Code, resp.Body.String())
+			}
+			env.waitForSessionStatus(t, session.ID, db.SessionStatusRunning)
+			// The prompt typed into the terminal runs another turn.
+			env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+		})
+	}
+}
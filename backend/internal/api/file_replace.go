@@ -0,0 +1,280 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- Search and replace ---
+
+const (
+	// maxReplaceFiles and maxReplaceMatches cap how much one request may
+	// change; larger replacements are refused rather than applied partially.
+	maxReplaceFiles   = 200
+	maxReplaceMatches = 5000
+	// maxReplacePreviewLines bounds the changed lines listed per file.
+	maxReplacePreviewLines = 20
+)
+
+var errReplaceTooLarge = fmt.Errorf("replacement affects more than %d files or %d matches; narrow it with paths", maxReplaceFiles, maxReplaceMatches)
+
+type fileReplaceRequest struct {
+	Search        string   `json:"search"`
+	Replace       string   `json:"replace"`
+	Regex         bool     `json:"regex,omitempty"` // Replace may then use $1 and ${name}
+	CaseSensitive bool     `json:"caseSensitive,omitempty"`
+	Paths         []string `json:"paths,omitempty"` // files or directories to limit to
+	DryRun        bool     `json:"dryRun,omitempty"`
+}
+
+type fileReplaceLine struct {
+	Line   int    `json:"line"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type fileReplaceResult struct {
+	File         string            `json:"file"`
+	Replacements int               `json:"replacements"`
+	Lines        []fileReplaceLine `json:"lines"`
+}
+
+type fileReplaceResponse struct {
+	DryRun       bool                `json:"dryRun"`
+	Files        []fileReplaceResult `json:"files"`
+	TotalFiles   int                 `json:"totalFiles"`
+	Replacements int                 `json:"replacements"`
+}
+
+// fileReplacer replaces matches of a pattern line by line, like searchFiles
+// matches them.
+type fileReplacer struct {
+	re      *regexp.Regexp
+	replace string
+	literal bool
+}
+
+func newFileReplacer(req fileReplaceRequest) (*fileReplacer, error) {
+	if req.Search == "" {
+		return nil, errors.New("search is required")
+	}
+	pattern := req.Search
+	if !req.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !req.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	if re.MatchString("") {
+		return nil, errors.New("search must not match empty text")
+	}
+	return &fileReplacer{re: re, replace: req.Replace, literal: !req.Regex}, nil
+}
+
+// apply returns content with all matches replaced, the number of matches and
+// a preview of the first changed lines.
+func (f *fileReplacer) apply(content string) (string, int, []fileReplaceLine) {
+	lines := strings.Split(content, "\n")
+	count := 0
+	var preview []fileReplaceLine
+	for i, line := range lines {
+		n := len(f.re.FindAllStringIndex(line, -1))
+		if n == 0 {
+			continue
+		}
+		var replaced string
+		if f.literal {
+			replaced = f.re.ReplaceAllLiteralString(line, f.replace)
+		} else {
+			replaced = f.re.ReplaceAllString(line, f.replace)
+		}
+		count += n
+		if replaced == line {
+			continue
+		}
+		if len(preview) < maxReplacePreviewLines {
+			preview = append(preview, fileReplaceLine{
+				Line:   i + 1,
+				Before: truncateLine(line, 200),
+				After:  truncateLine(replaced, 200),
+			})
+		}
+		lines[i] = replaced
+	}
+	return strings.Join(lines, "\n"), count, preview
+}
+
+// resolveReplacePaths resolves the paths a replacement is limited to
+// against root: the whole tree when there are none.
+func resolveReplacePaths(root string, paths []string, guard pathGuard) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	out := make([]string, 0, len(paths))
+	for _, raw := range paths {
+		relPath, err := normalizeRelativePath(raw, true)
+		if err != nil {
+			return nil, err
+		}
+		if guard.protects(relPath) {
+			return nil, errors.New("path is protected")
+		}
+		absPath, err := safeJoin(root, relPath)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, absPath)
+	}
+	return out, nil
+}
+
+// replaceFiles replaces across the files under targets, skipping the
+// directories searchFiles skips outside a git work tree and the files guard
+// protects. It plans every change before writing any, so a replacement over
+// the caps leaves the tree untouched.
+func replaceFiles(root string, targets []string, replacer *fileReplacer, dryRun bool, guard pathGuard) (fileReplaceResponse, error) {
+	resp := fileReplaceResponse{DryRun: dryRun, Files: []fileReplaceResult{}}
+	seen := make(map[string]bool)
+	var changed []string
+
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "node_modules", ".next", "vendor":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks could point outside the worktree.
+		if !d.Type().IsRegular() || seen[path] {
+			return nil
+		}
+		seen[path] = true
+		relPath, _ := filepath.Rel(root, path)
+		if guard.protects(relPath) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxProjectFileWriteBytes {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || !utf8.Valid(data) {
+			return nil
+		}
+		updated, count, preview := replacer.apply(string(data))
+		if updated == string(data) {
+			return nil
+		}
+		resp.TotalFiles++
+		resp.Replacements += count
+		if resp.TotalFiles > maxReplaceFiles || resp.Replacements > maxReplaceMatches {
+			return errReplaceTooLarge
+		}
+		resp.Files = append(resp.Files, fileReplaceResult{
+			File:         filepath.ToSlash(relPath),
+			Replacements: count,
+			Lines:        preview,
+		})
+		changed = append(changed, path)
+		return nil
+	}
+	for _, target := range targets {
+		if err := filepath.WalkDir(target, walk); err != nil {
+			return resp, err
+		}
+	}
+
+	if dryRun {
+		return resp, nil
+	}
+	for _, path := range changed {
+		info, err := os.Stat(path)
+		if err != nil {
+			return resp, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return resp, err
+		}
+		updated, _, _ := replacer.apply(string(data))
+		if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) handleReplaceProjectFiles(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	s.replaceInRoot(w, r, project.Path, "", newPathGuard(project))
+}
+
+// handleReplaceTaskFiles replaces text across a task's worktree.
+func (s *Server) handleReplaceTaskFiles(w http.ResponseWriter, r *http.Request) {
+	root, ok := s.resolveTaskFileRoot(w, r)
+	if !ok {
+		return
+	}
+	s.replaceInRoot(w, r, root, urlParam(r, "id"), s.taskPathGuard(urlParam(r, "id")))
+}
+
+// replaceInRoot serves a replacement across root. With dryRun it only
+// reports the files and lines that would change.
+func (s *Server) replaceInRoot(w http.ResponseWriter, r *http.Request, root, taskID string, guard pathGuard) {
+	var req fileReplaceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	replacer, err := newFileReplacer(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Resolve symlinks in the root too, so reported paths stay relative.
+	root, err = safeJoin(root, "")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	targets, err := resolveReplacePaths(root, req.Paths, guard)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := replaceFiles(root, targets, replacer, req.DryRun, guard)
+	if errors.Is(err, errReplaceTooLarge) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Error("file replace failed", "root", root, "error", err)
+		writeError(w, http.StatusInternalServerError, "replace failed")
+		return
+	}
+	if taskID != "" && !req.DryRun && resp.TotalFiles > 0 {
+		s.wakeDiffStats()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
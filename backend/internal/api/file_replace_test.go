@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestFileReplacer(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     fileReplaceRequest
+		content string
+		want    string
+		count   int
+	}{
+		{"literal is case-insensitive by default", fileReplaceRequest{Search: "foo.Bar", Replace: "$x"}, "a FOO.bar\nfooXbar foo.bar\n", "a $x\nfooXbar $x\n", 2},
+		{"case-sensitive", fileReplaceRequest{Search: "Foo", Replace: "Baz", CaseSensitive: true}, "Foo foo", "Baz foo", 1},
+		{"regex groups", fileReplaceRequest{Search: `oldName\((\w+)\)`, Replace: "newName($1, nil)", Regex: true, CaseSensitive: true}, "x := oldName(y)\n", "x := newName(y, nil)\n", 1},
+		{"anchors apply per line", fileReplaceRequest{Search: "^// ", Replace: "# ", Regex: true}, "// a\nb // c\n// d", "# a\nb // c\n# d", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replacer, err := newFileReplacer(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, count, preview := replacer.apply(tt.content)
+			if got != tt.want || count != tt.count {
+				t.Errorf("got %q (%d), want %q (%d)", got, count, tt.want, tt.count)
+			}
+			if len(preview) == 0 || preview[0].Before == preview[0].After {
+				t.Errorf("unexpected preview: %+v", preview)
+			}
+		})
+	}
+
+	for _, req := range []fileReplaceRequest{{}, {Search: "(", Regex: true}, {Search: "x*", Regex: true}} {
+		if _, err := newFileReplacer(req); err == nil {
+			t.Errorf("%+v: expected an error", req)
+		}
+	}
+}
+
+func TestReplaceTaskFiles(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	project := createWorkspaceProject(t, env)
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "replace"})
+
+	files := map[string]string{
+		"src/a.go":            "package a\n\nfunc oldName() {}\n",
+		"src/b.go":            "package b\n\nvar x = oldName\n",
+		"docs/notes.md":       "oldName is documented\n",
+		"node_modules/x/y.js": "oldName()\n",
+		"src/untouched.go":    "package c\n",
+	}
+	for path, content := range files {
+		abs := filepath.Join(project.Path, path)
+		os.MkdirAll(filepath.Dir(abs), 0o755)
+		if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, _ := os.ReadFile(filepath.Join(project.Path, path))
+		return string(data)
+	}
+	url := "/api/tasks/" + task.ID + "/files/replace"
+
+	resp := env.post(url, fileReplaceRequest{Search: "oldName", Replace: "newName", CaseSensitive: true, Paths: []string{"src"}, DryRun: true})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var preview fileReplaceResponse
+	decodeResponse(t, resp, &preview)
+	if !preview.DryRun || preview.TotalFiles != 2 || preview.Replacements != 2 {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if l := preview.Files[0].Lines[0]; preview.Files[0].File != "src/a.go" || l.Line != 3 || l.After != "func newName() {}" {
+		t.Errorf("unexpected preview file: %+v", preview.Files[0])
+	}
+	if read("src/a.go") != files["src/a.go"] {
+		t.Fatal("expected a dry run to leave files untouched")
+	}
+
+	resp = env.post(url, fileReplaceRequest{Search: "oldName", Replace: "newName", CaseSensitive: true})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var applied fileReplaceResponse
+	decodeResponse(t, resp, &applied)
+	if applied.DryRun || applied.TotalFiles != 3 {
+		t.Fatalf("unexpected result: %+v", applied)
+	}
+	if got := read("src/b.go"); !strings.Contains(got, "var x = newName") {
+		t.Errorf("expected src/b.go replaced, got %q", got)
+	}
+	if got := read("node_modules/x/y.js"); got != files["node_modules/x/y.js"] {
+		t.Errorf("expected node_modules skipped, got %q", got)
+	}
+
+	for _, req := range []fileReplaceRequest{
+		{Search: ""},
+		{Search: "x", Paths: []string{"../outside"}},
+		{Search: "x", Paths: []string{".git"}},
+	} {
+		if resp := env.post(url, req); resp.Code != http.StatusBadRequest {
+			t.Errorf("%+v: expected 400, got %d", req, resp.Code)
+		}
+	}
+}
+
+func TestReplaceFilesCap(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i <= maxReplaceFiles; i++ {
+		if err := os.WriteFile(filepath.Join(root, fmt.Sprintf("f%03d.txt", i)), []byte("needle\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replacer, _ := newFileReplacer(fileReplaceRequest{Search: "needle", Replace: "pin"})
+	if _, err := replaceFiles(root, []string{root}, replacer, false, pathGuard{}); !errors.Is(err, errReplaceTooLarge) {
+		t.Fatalf("expected errReplaceTooLarge, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "f000.txt")); string(data) != "needle\n" {
+		t.Errorf("expected nothing written over the cap, got %q", data)
+	}
+}
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// --- File search ---
+
+const (
+	defaultSearchResults = 200
+	maxSearchResults     = 5000
+	// maxSearchFileBytes skips larger files, which are rarely source.
+	maxSearchFileBytes = 512 * 1024
+	// binarySniffBytes is how much of a file is checked for NUL bytes, as
+	// ripgrep and git do, to tell binary files apart.
+	binarySniffBytes = 8000
+)
+
+type fileSearchRequest struct {
+	Query         string   `json:"query"`
+	Regex         bool     `json:"regex,omitempty"`
+	CaseSensitive bool     `json:"caseSensitive,omitempty"`
+	MaxResults    int      `json:"maxResults,omitempty"`
+	MaxPerFile    int      `json:"maxPerFile,omitempty"` // matches listed per file; 0 for no limit
+	Include       []string `json:"include,omitempty"`    // globs a file must match one of
+	Exclude       []string `json:"exclude,omitempty"`    // globs a file must match none of
+}
+
+type fileSearchMatch struct {
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+}
+
+type fileSearchResult struct {
+	File      string            `json:"file"`
+	Matches   []fileSearchMatch `json:"matches"`
+	Truncated bool              `json:"truncated,omitempty"` // more matches than maxPerFile
+}
+
+type fileSearchResponse struct {
+	Results   []fileSearchResult `json:"results"`
+	Truncated bool               `json:"truncated,omitempty"` // more matches than maxResults
+}
+
+// fileSearcher matches a query against files.
+type fileSearcher struct {
+	re         *regexp.Regexp
+	include    []string
+	exclude    []string
+	maxResults int
+	maxPerFile int
+}
+
+func newFileSearcher(req fileSearchRequest) (*fileSearcher, error) {
+	if req.Query == "" {
+		return nil, errors.New("query is required")
+	}
+	pattern := req.Query
+	if !req.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	// Multi-line mode lets a whole file be checked at once before its lines
+	// are, without ^ and $ missing matches.
+	flags := "(?m)"
+	if !req.CaseSensitive {
+		flags = "(?mi)"
+	}
+	re, err := regexp.Compile(flags + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	for _, glob := range append(append([]string{}, req.Include...), req.Exclude...) {
+		if err := validateGlob(glob); err != nil {
+			return nil, err
+		}
+	}
+
+	maxResults := req.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultSearchResults
+	}
+	return &fileSearcher{
+		re:         re,
+		include:    req.Include,
+		exclude:    req.Exclude,
+		maxResults: min(maxResults, maxSearchResults),
+		maxPerFile: max(req.MaxPerFile, 0),
+	}, nil
+}
+
+// wants reports whether the include and exclude globs let a file through.
+func (f *fileSearcher) wants(rel string) bool {
+	for _, glob := range f.exclude {
+		if matchGlob(glob, rel) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, glob := range f.include {
+		if matchGlob(glob, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchFile returns a file's matching lines, or nil when it has none or is
+// too large or binary.
+func (f *fileSearcher) searchFile(absPath, rel string) *fileSearchResult {
+	info, err := os.Lstat(absPath)
+	// Symlinks could point outside the tree.
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > maxSearchFileBytes {
+		return nil
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) >= 0 {
+		return nil
+	}
+	if !f.re.Match(data) {
+		return nil
+	}
+
+	result := &fileSearchResult{File: rel}
+	line := 0
+	for rest := data; len(rest) > 0; {
+		var text []byte
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			text, rest = rest[:i], rest[i+1:]
+		} else {
+			text, rest = rest, nil
+		}
+		line++
+		if !f.re.Match(text) {
+			continue
+		}
+		if f.maxPerFile > 0 && len(result.Matches) == f.maxPerFile {
+			result.Truncated = true
+			break
+		}
+		result.Matches = append(result.Matches, fileSearchMatch{
+			Line:    line,
+			Content: truncateLine(string(text), 200),
+		})
+	}
+	return result
+}
+
+// searchFiles searches the files under root in parallel. Results are sorted
+// by file; once maxResults matches are found, files not yet searched are
+// skipped.
+func searchFiles(ctx context.Context, root string, searcher *fileSearcher) (fileSearchResponse, error) {
+	files, err := searchableFiles(ctx, root)
+	if err != nil {
+		return fileSearchResponse{}, err
+	}
+
+	paths := make(chan string)
+	var (
+		mu      sync.Mutex
+		results []fileSearchResult
+		found   atomic.Int64
+		wg      sync.WaitGroup
+	)
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range paths {
+				if found.Load() >= int64(searcher.maxResults) {
+					continue
+				}
+				result := searcher.searchFile(filepath.Join(root, filepath.FromSlash(rel)), rel)
+				if result == nil || len(result.Matches) == 0 {
+					continue
+				}
+				found.Add(int64(len(result.Matches)))
+				mu.Lock()
+				results = append(results, *result)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, rel := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		if searcher.wants(rel) {
+			paths <- rel
+		}
+	}
+	close(paths)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return fileSearchResponse{}, err
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].File < results[j].File })
+	resp := fileSearchResponse{Results: make([]fileSearchResult, 0, len(results))}
+	remaining := searcher.maxResults
+	for _, result := range results {
+		if remaining == 0 {
+			resp.Truncated = true
+			break
+		}
+		if len(result.Matches) > remaining {
+			result.Matches = result.Matches[:remaining]
+			result.Truncated = true
+			resp.Truncated = true
+		}
+		remaining -= len(result.Matches)
+		resp.Results = append(resp.Results, result)
+	}
+	if found.Load() > int64(searcher.maxResults) {
+		resp.Truncated = true
+	}
+	return resp, nil
+}
+
+// searchableFiles lists the files under root, relative and slash-separated.
+// In a git work tree those are the tracked files and the untracked ones
+// .gitignore doesn't exclude; elsewhere, every file outside .git,
+// node_modules, .next and vendor.
+func searchableFiles(ctx context.Context, root string) ([]string, error) {
+	if out, err := runGitContext(ctx, root, "ls-files", "-z", "--cached", "--others", "--exclude-standard"); err == nil {
+		seen := make(map[string]bool)
+		files := make([]string, 0)
+		for _, rel := range strings.Split(out, "\x00") {
+			if rel != "" && !seen[rel] {
+				seen[rel] = true
+				files = append(files, rel)
+			}
+		}
+		return files, nil
+	}
+
+	files := make([]string, 0)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip errors
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case ".git", "node_modules", ".next", "vendor":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// validateGlob checks a glob's syntax.
+func validateGlob(glob string) error {
+	if strings.Trim(glob, "/") == "" {
+		return errors.New("empty glob")
+	}
+	for _, part := range strings.Split(strings.Trim(glob, "/"), "/") {
+		if _, err := path.Match(part, ""); err != nil {
+			return fmt.Errorf("invalid glob %q", glob)
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether rel matches glob. As in .gitignore, a glob
+// without a slash matches any file or directory name along the path, "**"
+// matches any number of directories, and a glob matching a directory
+// matches the files under it.
+func matchGlob(glob, rel string) bool {
+	glob = strings.Trim(glob, "/")
+	segments := strings.Split(rel, "/")
+	if !strings.Contains(glob, "/") {
+		for _, name := range segments {
+			if ok, _ := path.Match(glob, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchGlobSegments(strings.Split(glob, "/"), segments)
+}
+
+func matchGlobSegments(glob, segments []string) bool {
+	if len(glob) == 0 {
+		return true
+	}
+	if glob[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(glob[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(glob[0], segments[0]); !ok {
+		return false
+	}
+	return matchGlobSegments(glob[1:], segments[1:])
+}
+
+func truncateLine(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+func (s *Server) handleSearchProjectFiles(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	s.searchInRoot(w, r, project.Path)
+}
+
+func (s *Server) handleSearchTaskFiles(w http.ResponseWriter, r *http.Request) {
+	root, ok := s.resolveTaskFileRoot(w, r)
+	if !ok {
+		return
+	}
+	s.searchInRoot(w, r, root)
+}
+
+// searchInRoot serves a search across root.
+func (s *Server) searchInRoot(w http.ResponseWriter, r *http.Request, root string) {
+	var req fileSearchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	searcher, err := newFileSearcher(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := searchFiles(r.Context(), root, searcher)
+	if err != nil {
+		slog.Error("file search failed", "root", root, "error", err)
+		writeError(w, http.StatusInternalServerError, "search failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, rel string
+		want      bool
+	}{
+		{"*.go", "internal/api/server.go", true},
+		{"*.go", "internal/api/server.ts", false},
+		{"testdata", "internal/testdata/a.txt", true},
+		{"internal/api", "internal/api/server.go", true},
+		{"internal/*.go", "internal/api/server.go", false},
+		{"internal/**/*.go", "internal/api/server.go", true},
+		{"**/*_test.go", "server_test.go", true},
+		{"/docs/", "docs/readme.md", true},
+		{"docs", "src/docs.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.glob, tt.rel); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.glob, tt.rel, got, tt.want)
+		}
+	}
+	if err := validateGlob("[a-"); err == nil {
+		t.Error("expected an invalid glob to be rejected")
+	}
+}
+
+func TestSearchFiles(t *testing.T) {
+	root := createTestGitRepo(t)
+	files := map[string]string{
+		".gitignore":        "build/\n*.log\n",
+		"src/a.go":          "package a\n\n// TODO one\n// todo two\n",
+		"src/b.go":          "package b // TODO\n",
+		"docs/notes.md":     "TODO docs\n",
+		"build/out.js":      "TODO ignored\n",
+		"debug.log":         "TODO ignored\n",
+		"untracked/new.txt": "TODO untracked\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(root, "src", "blob.bin"), []byte("TODO\x00binary"), 0644)
+
+	search := func(req fileSearchRequest) fileSearchResponse {
+		t.Helper()
+		searcher, err := newFileSearcher(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := searchFiles(context.Background(), root, searcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	matchedFiles := func(resp fileSearchResponse) []string {
+		out := make([]string, 0, len(resp.Results))
+		for _, r := range resp.Results {
+			out = append(out, r.File)
+		}
+		return out
+	}
+
+	resp := search(fileSearchRequest{Query: "todo"})
+	if got := matchedFiles(resp); !slices.Equal(got, []string{"docs/notes.md", "src/a.go", "src/b.go", "untracked/new.txt"}) {
+		t.Fatalf("expected ignored and binary files skipped, got %v", got)
+	}
+	if len(resp.Results[1].Matches) != 2 || resp.Results[1].Matches[0].Line != 3 {
+		t.Errorf("unexpected matches: %+v", resp.Results[1])
+	}
+
+	if got := matchedFiles(search(fileSearchRequest{Query: "TODO", CaseSensitive: true, Include: []string{"*.go"}})); !slices.Equal(got, []string{"src/a.go", "src/b.go"}) {
+		t.Errorf("expected include to limit to Go files, got %v", got)
+	}
+	if got := matchedFiles(search(fileSearchRequest{Query: "todo", Exclude: []string{"src", "untracked/**"}})); !slices.Equal(got, []string{"docs/notes.md"}) {
+		t.Errorf("expected exclude to drop directories, got %v", got)
+	}
+	if got := matchedFiles(search(fileSearchRequest{Query: `^todo`, Regex: true})); !slices.Equal(got, []string{"docs/notes.md", "untracked/new.txt"}) {
+		t.Errorf("expected anchors to apply per line and regexes to be case-insensitive, got %v", got)
+	}
+
+	resp = search(fileSearchRequest{Query: "todo", MaxPerFile: 1})
+	if a := resp.Results[1]; len(a.Matches) != 1 || !a.Truncated {
+		t.Errorf("expected src/a.go cut at one match, got %+v", a)
+	}
+	resp = search(fileSearchRequest{Query: "todo", MaxResults: 2})
+	total := 0
+	for _, r := range resp.Results {
+		total += len(r.Matches)
+	}
+	if total != 2 || !resp.Truncated {
+		t.Errorf("expected two matches and truncated, got %d (%v)", total, resp.Truncated)
+	}
+}
+
+func TestSearchProjectFiles_API(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	root := t.TempDir() // not a git repository
+	os.MkdirAll(filepath.Join(root, "node_modules", "x"), 0755)
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("needle\n"), 0644)
+	os.WriteFile(filepath.Join(root, "node_modules", "x", "index.js"), []byte("needle\n"), 0644)
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "search", Path: root})
+
+	path := "/api/projects/" + project.ID + "/files/search"
+	resp := env.post(path, map[string]any{"query": "needle"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var got fileSearchResponse
+	decodeResponse(t, resp, &got)
+	if len(got.Results) != 1 || got.Results[0].File != "main.go" {
+		t.Fatalf("expected only main.go, got %+v", got.Results)
+	}
+
+	for _, body := range []map[string]any{{}, {"query": "(", "regex": true}, {"query": "x", "include": []string{"[a-"}}} {
+		if resp := env.post(path, body); resp.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", body, resp.Code)
+		}
+	}
+}
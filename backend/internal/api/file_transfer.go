@@ -0,0 +1,381 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// --- Binary file transfer ---
+//
+// The JSON file endpoints carry UTF-8 text up to 1 MiB. These stream raw
+// bytes instead, so images, fonts and archives can be managed too:
+//
+//	GET  .../file/raw?path=       download a file (also GET .../file with Accept: application/octet-stream)
+//	PUT  .../file?path=           write the request body, when it is not JSON
+//	POST .../files/upload?dir=    multipart upload of one or more "file" parts
+
+// defaultUploadLimitMB caps uploads unless CODEBURG_MAX_UPLOAD_MB says
+// otherwise.
+const defaultUploadLimitMB = 100
+
+var errUploadTooLarge = errors.New("file exceeds the upload size limit")
+
+// uploadLimitFromEnv reads the upload cap in MiB from CODEBURG_MAX_UPLOAD_MB.
+func uploadLimitFromEnv() int64 {
+	raw := strings.TrimSpace(os.Getenv("CODEBURG_MAX_UPLOAD_MB"))
+	if raw == "" {
+		return defaultUploadLimitMB << 20
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		slog.Warn("ignoring invalid upload limit", "env", "CODEBURG_MAX_UPLOAD_MB", "value", raw)
+		return defaultUploadLimitMB << 20
+	}
+	return n << 20
+}
+
+// wantsRawFile reports whether a GET .../file request asks for the bytes
+// rather than the JSON preview.
+func wantsRawFile(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		if mediaType == "application/octet-stream" {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONRequest reports whether a request body is JSON. Requests without a
+// Content-Type are taken as JSON, as the file endpoints always were.
+func isJSONRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// fileContentType guesses a file's type from its name, falling back to
+// sniffing its first bytes.
+func fileContentType(f *os.File) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(f.Name())); contentType != "" {
+		return contentType
+	}
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(f, buf)
+	_, _ = f.Seek(0, io.SeekStart)
+	return http.DetectContentType(buf[:n])
+}
+
+// serveRawFile streams a file under root, with range support. Types that
+// are safe to render are served inline; everything else, or any file with
+// download=true, as an attachment.
+func serveRawFile(w http.ResponseWriter, r *http.Request, root string) {
+	relPath, err := normalizeRelativePath(r.URL.Query().Get("path"), false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	absPath, err := safeJoin(root, relPath)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, http.StatusNotFound, "file not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to open file")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to stat file")
+		return
+	}
+	if info.IsDir() {
+		writeError(w, http.StatusBadRequest, "path is a directory")
+		return
+	}
+
+	contentType := fileContentType(f)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	disposition := "attachment"
+	if inlineAttachmentTypes[mediaType] && r.URL.Query().Get("download") != "true" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filepath.Base(relPath)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// writeFileFromReader streams src into absPath through a temporary file, so
+// a failed or oversized write leaves any existing file untouched. It keeps
+// an existing file's mode.
+func writeFileFromReader(absPath string, src io.Reader, limit int64) (os.FileInfo, error) {
+	staged, err := stageFile(absPath, src, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer staged.discard()
+	return staged.commit()
+}
+
+// stagedFile is an upload written to a temporary file next to its
+// destination, waiting to be renamed over it.
+type stagedFile struct {
+	tmpPath string
+	absPath string
+	mode    os.FileMode
+}
+
+// stageFile streams src into a temporary file for absPath, without
+// touching absPath itself.
+func stageFile(absPath string, src io.Reader, limit int64) (*stagedFile, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(absPath); err == nil {
+		if info.IsDir() {
+			return nil, errors.New("path is a directory")
+		}
+		mode = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(absPath), "."+filepath.Base(absPath)+".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	staged := &stagedFile{tmpPath: tmp.Name(), absPath: absPath, mode: mode}
+
+	n, err := io.Copy(tmp, io.LimitReader(src, limit+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > limit {
+		err = errUploadTooLarge
+	}
+	if err != nil {
+		staged.discard()
+		return nil, err
+	}
+	return staged, nil
+}
+
+// commit renames the staged file over its destination.
+func (f *stagedFile) commit() (os.FileInfo, error) {
+	if err := os.Chmod(f.tmpPath, f.mode); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.tmpPath, f.absPath); err != nil {
+		return nil, err
+	}
+	return os.Stat(f.absPath)
+}
+
+// discard removes the staged file; after commit it's a no-op.
+func (f *stagedFile) discard() {
+	os.Remove(f.tmpPath)
+}
+
+// uploadPath resolves a path an upload writes to.
+func uploadPath(root, rawPath string, guard pathGuard) (string, string, error) {
+	relPath, err := normalizeRelativePath(rawPath, false)
+	if err != nil {
+		return "", "", err
+	}
+	if guard.protects(relPath) {
+		return "", "", errors.New("path is protected")
+	}
+	absPath, err := safeJoin(root, relPath)
+	if err != nil {
+		return "", "", err
+	}
+	return relPath, absPath, nil
+}
+
+func (s *Server) writeUploadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, errUploadTooLarge), errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("%s of %d MiB", errUploadTooLarge, s.uploadLimit>>20))
+	default:
+		slog.Warn("file upload failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to write file")
+	}
+}
+
+// writeRawFile serves PUT .../file?path= with a non-JSON body, writing the
+// body as the file's content.
+func (s *Server) writeRawFile(w http.ResponseWriter, r *http.Request, root string, guard pathGuard) {
+	relPath, absPath, err := uploadPath(root, r.URL.Query().Get("path"), guard)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.ContentLength > s.uploadLimit {
+		s.writeUploadError(w, errUploadTooLarge)
+		return
+	}
+
+	info, err := writeFileFromReader(absPath, r.Body, s.uploadLimit)
+	if err != nil {
+		s.writeUploadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"name":    filepath.Base(relPath),
+		"path":    filepath.ToSlash(relPath),
+		"type":    "file",
+		"size":    info.Size(),
+		"modTime": info.ModTime(),
+	})
+}
+
+// uploadFiles serves a multipart upload into the directory dir= (the root
+// by default). Every "file" part is streamed to disk under its file name;
+// existing files are replaced only with overwrite=true.
+func (s *Server) uploadFiles(w http.ResponseWriter, r *http.Request, root string, guard pathGuard) {
+	dir, err := normalizeRelativePath(r.URL.Query().Get("dir"), true)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+
+	// The cap applies per file; the whole request may carry several.
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid multipart upload")
+		return
+	}
+
+	type uploadedFile struct {
+		Name    string    `json:"name"`
+		Path    string    `json:"path"`
+		Type    string    `json:"type"`
+		Size    int64     `json:"size"`
+		ModTime time.Time `json:"modTime"`
+	}
+	// Every file is staged before any is written, so a failed part leaves
+	// the directory as it was.
+	var staged []*stagedFile
+	defer func() {
+		for _, f := range staged {
+			f.discard()
+		}
+	}()
+	var relPaths []string
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid multipart upload")
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		relPath, absPath, err := uploadPath(root, filepath.Join(dir, filepath.Base(part.FileName())), guard)
+		if err != nil {
+			part.Close()
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := os.Stat(absPath); err == nil && !overwrite {
+			part.Close()
+			writeError(w, http.StatusConflict, filepath.ToSlash(relPath)+" already exists")
+			return
+		}
+		file, err := stageFile(absPath, part, s.uploadLimit)
+		part.Close()
+		if err != nil {
+			s.writeUploadError(w, err)
+			return
+		}
+		staged = append(staged, file)
+		relPaths = append(relPaths, relPath)
+	}
+
+	if len(staged) == 0 {
+		writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	uploaded := make([]uploadedFile, 0, len(staged))
+	for i, file := range staged {
+		info, err := file.commit()
+		if err != nil {
+			// Renames next to the destination hardly fail; say what was
+			// written when one does.
+			slog.Warn("file upload failed", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to write file", "files": uploaded})
+			return
+		}
+		uploaded = append(uploaded, uploadedFile{
+			Name:    filepath.Base(relPaths[i]),
+			Path:    filepath.ToSlash(relPaths[i]),
+			Type:    "file",
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"files": uploaded})
+}
+
+func (s *Server) handleDownloadProjectFile(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	serveRawFile(w, r, project.Path)
+}
+
+func (s *Server) handleUploadProjectFiles(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	s.uploadFiles(w, r, project.Path, newPathGuard(project))
+}
+
+func (s *Server) handleDownloadTaskFile(w http.ResponseWriter, r *http.Request) {
+	root, ok := s.resolveTaskFileRoot(w, r)
+	if !ok {
+		return
+	}
+	serveRawFile(w, r, root)
+}
+
+func (s *Server) handleUploadTaskFiles(w http.ResponseWriter, r *http.Request) {
+	root, ok := s.resolveTaskFileRoot(w, r)
+	if !ok {
+		return
+	}
+	s.uploadFiles(w, r, root, s.taskPathGuard(urlParam(r, "id")))
+	s.wakeDiffStats()
+}
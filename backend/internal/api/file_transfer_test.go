@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// rawRequest sends body as is, with the given headers.
+func (e *testEnv) rawRequest(method, path string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	e.t.Helper()
+	req := httptest.NewRequest(method, path, body)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Authorization", "Bearer "+e.token)
+	w := httptest.NewRecorder()
+	e.server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWorkspaceFileTransfer(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	root := t.TempDir()
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "files", Path: root})
+	base := "/api/projects/" + project.ID
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0, 1, 2}, 100)...)
+	font := []byte("wOFF\x00\x01binary")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, data := range map[string][]byte{"logo.png": png, "../font.woff": font} {
+		part, _ := mw.CreateFormFile("file", name)
+		part.Write(data)
+	}
+	mw.Close()
+	resp := env.rawRequest("POST", base+"/files/upload?dir=assets", &body, map[string]string{"Content-Type": mw.FormDataContentType()})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("upload: expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var uploaded struct {
+		Files []struct {
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+		} `json:"files"`
+	}
+	decodeResponse(t, resp, &uploaded)
+	if len(uploaded.Files) != 2 {
+		t.Fatalf("expected two files, got %+v", uploaded.Files)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "assets", "font.woff")); !bytes.Equal(got, font) {
+		t.Errorf("expected the file name's directories dropped, got %q", got)
+	}
+
+	// Uploading over an existing file needs overwrite=true.
+	body.Reset()
+	mw = multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "logo.png")
+	part.Write([]byte("new"))
+	mw.Close()
+	if resp := env.rawRequest("POST", base+"/files/upload?dir=assets", bytes.NewReader(body.Bytes()), map[string]string{"Content-Type": mw.FormDataContentType()}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an existing file, got %d", resp.Code)
+	}
+
+	resp = env.rawRequest("GET", base+"/file/raw?path=assets/logo.png", nil, nil)
+	if resp.Code != http.StatusOK || !bytes.Equal(resp.Body.Bytes(), png) {
+		t.Fatalf("download: got %d with %d bytes", resp.Code, resp.Body.Len())
+	}
+	if got := resp.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("expected image/png, got %q", got)
+	}
+	if got := resp.Header().Get("Content-Disposition"); got != `inline; filename=logo.png` {
+		t.Errorf("expected an inline image, got %q", got)
+	}
+
+	resp = env.rawRequest("GET", base+"/file?path=assets/font.woff", nil, map[string]string{"Accept": "application/octet-stream"})
+	if !bytes.Equal(resp.Body.Bytes(), font) {
+		t.Errorf("expected raw bytes for Accept: application/octet-stream, got %q", resp.Body.String())
+	}
+	if got := resp.Header().Get("Content-Disposition"); got != `attachment; filename=font.woff` {
+		t.Errorf("expected a font downloaded as an attachment, got %q", got)
+	}
+
+	resp = env.rawRequest("GET", base+"/file/raw?path=assets/logo.png", nil, map[string]string{"Range": "bytes=0-3"})
+	if resp.Code != http.StatusPartialContent || resp.Body.String() != "\x89PNG" {
+		t.Errorf("expected a partial response, got %d %q", resp.Code, resp.Body.String())
+	}
+
+	// A non-JSON PUT writes the body as the file.
+	resp = env.rawRequest("PUT", base+"/file?path=assets/logo.png", bytes.NewReader([]byte{0xff, 0x00}), map[string]string{"Content-Type": "application/octet-stream"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("raw put: expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "assets", "logo.png")); !bytes.Equal(got, []byte{0xff, 0x00}) {
+		t.Errorf("expected the raw body written, got %q", got)
+	}
+	if resp := env.rawRequest("PUT", base+"/file?path=.git/config", bytes.NewReader([]byte("x")), map[string]string{"Content-Type": "application/octet-stream"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected protected paths refused, got %d", resp.Code)
+	}
+}
+
+func TestWorkspaceFileTransfer_SizeLimit(t *testing.T) {
+	t.Setenv("CODEBURG_MAX_UPLOAD_MB", "1")
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "big.bin"), []byte("original"), 0644)
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "files", Path: root})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "t"})
+
+	tooBig := bytes.Repeat([]byte("x"), 1<<20+1)
+	resp := env.rawRequest("PUT", "/api/tasks/"+task.ID+"/file?path=big.bin", bytes.NewReader(tooBig), map[string]string{"Content-Type": "application/octet-stream"})
+	if resp.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "big.bin")
+	part.Write(tooBig)
+	mw.Close()
+	resp = env.rawRequest("POST", "/api/tasks/"+task.ID+"/files/upload?overwrite=true", &body, map[string]string{"Content-Type": mw.FormDataContentType()})
+	if resp.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for a multipart upload, got %d", resp.Code)
+	}
+	if got, _ := os.ReadFile(filepath.Join(root, "big.bin")); string(got) != "original" {
+		t.Errorf("expected a refused upload to leave the file alone, got %d bytes", len(got))
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestWorkspaceFileTransfer_UploadIsAllOrNothing(t *testing.T) {
+	t.Setenv("CODEBURG_MAX_UPLOAD_MB", "1")
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	root := t.TempDir()
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "files", Path: root})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "t"})
+
+	// The first file is fine, the second too large.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("file", "small.txt")
+	part.Write([]byte("hello"))
+	part, _ = mw.CreateFormFile("file", "big.bin")
+	part.Write(bytes.Repeat([]byte("x"), 1<<20+1))
+	mw.Close()
+	resp := env.rawRequest("POST", "/api/tasks/"+task.ID+"/files/upload", &body, map[string]string{"Content-Type": mw.FormDataContentType()})
+	if resp.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Errorf("expected no file written by a failed upload, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/gitclone"
+	"github.com/miguel-bm/codeburg/internal/gitea"
+	"github.com/miguel-bm/codeburg/internal/github"
+	"github.com/miguel-bm/codeburg/internal/gitlab"
+)
+
+// prForge creates and merges pull requests (merge requests on GitLab) on the
+// host behind a project's origin remote.
+type prForge interface {
+	createPR(workDir, title, body, baseBranch, headBranch string) (string, error)
+	mergePR(workDir, prURL, strategy string, deleteBranch bool) error
+}
+
+// forgeTokenPreference names the preference (and fallback env var) holding
+// the API token for each non-GitHub provider. GitHub uses the gh CLI login.
+var forgeTokenPreference = map[gitclone.Provider]struct{ pref, env string }{
+	gitclone.ProviderGitLab: {"gitlab_token", "GITLAB_TOKEN"},
+	gitclone.ProviderGitea:  {"gitea_token", "GITEA_TOKEN"},
+}
+
+// forgeToken returns the configured API token for a provider, or "".
+func (s *Server) forgeToken(provider gitclone.Provider) string {
+	names, ok := forgeTokenPreference[provider]
+	if !ok {
+		return ""
+	}
+	if pref, err := s.db.GetPreference(db.DefaultUserID, names.pref); err == nil {
+		if token := unquotePreference(pref.Value); token != "" {
+			return token
+		}
+	}
+	return os.Getenv(names.env)
+}
+
+// projectRemote parses the project's origin, from the stored git origin or
+// the repository itself.
+func (s *Server) projectRemote(project *db.Project) (gitclone.Remote, bool) {
+	origin := ptrToString(project.GitOrigin)
+	if origin == "" {
+		out, err := runGit(project.Path, "remote", "get-url", "origin")
+		if err != nil {
+			return gitclone.Remote{}, false
+		}
+		origin = strings.TrimSpace(out)
+	}
+	return s.gitclone.ParseRemote(origin)
+}
+
+// forgeForProject returns the PR forge for a project. Remotes on unknown
+// hosts fall back to the gh CLI, which may be configured for them.
+func (s *Server) forgeForProject(project *db.Project) (prForge, error) {
+	remote, ok := s.projectRemote(project)
+	if !ok || remote.Provider == gitclone.ProviderGitHub {
+		if !github.Available() {
+			return nil, errors.New("gh CLI not available")
+		}
+		return githubForge{}, nil
+	}
+
+	token := s.forgeToken(remote.Provider)
+	if token == "" {
+		names := forgeTokenPreference[remote.Provider]
+		return nil, errors.New(string(remote.Provider) + " token not configured (set the " + names.pref + " preference)")
+	}
+
+	switch remote.Provider {
+	case gitclone.ProviderGitLab:
+		return gitlabForge{remote: remote, client: gitlab.NewClient(remote.BaseURL(), token)}, nil
+	default:
+		return giteaForge{remote: remote, client: gitea.NewClient(remote.BaseURL(), token)}, nil
+	}
+}
+
+type githubForge struct{}
+
+func (githubForge) createPR(workDir, title, body, baseBranch, headBranch string) (string, error) {
+	return github.CreatePR(workDir, title, body, baseBranch, headBranch)
+}
+
+func (githubForge) mergePR(workDir, prURL, strategy string, deleteBranch bool) error {
+	return github.MergePR(workDir, prURL, strategy, deleteBranch)
+}
+
+type gitlabForge struct {
+	remote gitclone.Remote
+	client *gitlab.Client
+}
+
+func (f gitlabForge) createPR(_, title, body, baseBranch, headBranch string) (string, error) {
+	return f.client.CreateMergeRequest(f.remote.Path(), title, body, baseBranch, headBranch)
+}
+
+func (f gitlabForge) mergePR(_, prURL, strategy string, deleteBranch bool) error {
+	return f.client.MergeMergeRequest(prURL, strategy, deleteBranch)
+}
+
+type giteaForge struct {
+	remote gitclone.Remote
+	client *gitea.Client
+}
+
+func (f giteaForge) createPR(_, title, body, baseBranch, headBranch string) (string, error) {
+	return f.client.CreatePullRequest(f.remote.Owner, f.remote.Repo, title, body, baseBranch, headBranch)
+}
+
+func (f giteaForge) mergePR(_, prURL, strategy string, deleteBranch bool) error {
+	return f.client.MergePullRequest(prURL, strategy, deleteBranch)
+}
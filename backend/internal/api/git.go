@@ -12,28 +12,19 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+	"github.com/miguel-bm/codeburg/service"
 )
 
 
 // Git operation response types
 
-type GitFileEntry struct {
-	Path      string `json:"path"`
-	Status    string `json:"status"` // M, A, D, R, C, etc.
-	Additions int    `json:"additions,omitempty"`
-	Deletions int    `json:"deletions,omitempty"`
-}
+type GitFileEntry = service.GitFileEntry
 
-type GitStatusResponse struct {
-	Branch    string         `json:"branch"`
-	Upstream  string         `json:"upstream,omitempty"`
-	HasUpstream bool         `json:"hasUpstream"`
-	Ahead     int            `json:"ahead"`
-	Behind    int            `json:"behind"`
-	Staged    []GitFileEntry `json:"staged"`
-	Unstaged  []GitFileEntry `json:"unstaged"`
-	Untracked []string       `json:"untracked"`
-}
+type GitStatusResponse = service.GitStatus
 
 type GitDiffResponse struct {
 	Diff string `json:"diff"`
@@ -43,20 +34,14 @@ type GitStageRequest struct {
 	Files []string `json:"files"`
 }
 
-type GitCommitRequest struct {
-	Message string `json:"message"`
-	Amend   bool   `json:"amend,omitempty"`
-}
+type GitCommitRequest = service.CommitOptions
 
 type GitRevertRequest struct {
 	Tracked   []string `json:"tracked,omitempty"`
 	Untracked []string `json:"untracked,omitempty"`
 }
 
-type GitCommitResponse struct {
-	Hash    string `json:"hash"`
-	Message string `json:"message"`
-}
+type GitCommitResponse = service.GitCommit
 
 type GitPushRequest struct {
 	Force bool `json:"force,omitempty"`
@@ -95,32 +80,51 @@ type GitStashResponse struct {
 // resolveTaskWorkDir resolves a task's working directory (worktree or project path).
 // Returns the workDir, the task, or writes an error response and returns empty string.
 func (s *Server) resolveTaskWorkDir(w http.ResponseWriter, r *http.Request) (string, bool) {
-	taskID := urlParam(r, "id")
-
-	task, err := s.db.GetTask(taskID)
+	workDir, err := s.taskWorkDir(urlParam(r, "id"))
 	if err != nil {
-		writeDBError(w, err, "task")
+		writeServiceError(w, err, "failed to get task")
 		return "", false
 	}
+	return workDir, true
+}
 
+// taskWorkDir returns the path of a task's worktree.
+func (s *Server) taskWorkDir(taskID string) (string, error) {
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return "", notFound(err, "task")
+	}
 	if task.WorktreePath == nil || *task.WorktreePath == "" {
-		writeError(w, http.StatusBadRequest, "task has no worktree")
-		return "", false
+		return "", service.Invalid("task has no worktree")
 	}
-
-	return *task.WorktreePath, true
+	return *task.WorktreePath, nil
 }
 
 // runGit executes a git command in the given directory with a 5s timeout.
 func runGit(dir string, args ...string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return runGitContext(context.Background(), dir, args...)
+}
+
+// runGitContext is runGit recorded as a span when ctx carries a trace.
+func runGitContext(ctx context.Context, dir string, args ...string) (string, error) {
+	var span *telemetry.Span
+	if telemetry.SpanFromContext(ctx) != nil {
+		ctx, span = telemetry.StartKind(ctx, telemetry.KindClient, "git "+args[0],
+			telemetry.String("git.args", strings.Join(args, " ")),
+		)
+		defer span.End()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", fmt.Errorf("git %s: %s: %w", args[0], strings.TrimSpace(string(out)), err)
+		err = fmt.Errorf("git %s: %s: %w", args[0], strings.TrimSpace(string(out)), err)
+		span.RecordError(err)
+		return "", err
 	}
 	return string(out), nil
 }
@@ -192,7 +196,7 @@ func (s *Server) handleListBranches(w http.ResponseWriter, r *http.Request) {
 	fetchCmd.Run() // ignore errors
 
 	// List all branches (local + remote)
-	out, err := runGit(project.Path, "branch", "-a", "--format=%(refname:short)")
+	out, err := runGitContext(r.Context(), project.Path, "branch", "-a", "--format=%(refname:short)")
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -211,8 +215,8 @@ func (s *Server) handleListBranches(w http.ResponseWriter, r *http.Request) {
 		if strings.HasPrefix(name, "origin/") {
 			name = strings.TrimPrefix(name, "origin/")
 		}
-		// Skip HEAD pointer and default branch
-		if name == "HEAD" || name == defaultBranch {
+		// Skip HEAD pointer, default branch and pooled worktree placeholders
+		if name == "HEAD" || name == defaultBranch || strings.HasPrefix(name, worktree.PoolBranchPrefix) {
 			continue
 		}
 		seen[name] = true
@@ -255,20 +259,23 @@ func gitStatus(workDir string) (*GitStatusResponse, error) {
 }
 
 func (s *Server) handleGitStatus(w http.ResponseWriter, r *http.Request) {
-	workDir, ok := s.resolveTaskWorkDir(w, r)
-	if !ok {
-		return
-	}
-
-	resp, err := gitStatus(workDir)
+	resp, err := s.git().Status(r.Context(), urlParam(r, "id"))
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err, err.Error())
 		return
 	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
 
+func (g *gitService) Status(ctx context.Context, taskID string) (*GitStatusResponse, error) {
+	workDir, err := g.s.taskWorkDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return gitStatus(workDir)
+}
+
 func (s *Server) handleProjectGitStatus(w http.ResponseWriter, r *http.Request) {
 	workDir, ok := s.resolveProjectWorkDir(w, r)
 	if !ok {
@@ -441,62 +448,84 @@ func mergeNumstat(workDir string, resp *GitStatusResponse) {
 }
 
 func (s *Server) handleGitDiff(w http.ResponseWriter, r *http.Request) {
-	workDir, ok := s.resolveTaskWorkDir(w, r)
-	if !ok {
+	query := r.URL.Query()
+	out, err := s.git().Diff(r.Context(), urlParam(r, "id"), service.DiffOptions{
+		Commit: query.Get("commit"),
+		Base:   query.Get("base") == "true",
+		Staged: query.Get("staged") == "true",
+		File:   query.Get("file"),
+	})
+	if err != nil {
+		writeServiceError(w, err, err.Error())
 		return
 	}
 
-	file := r.URL.Query().Get("file")
-	staged := r.URL.Query().Get("staged") == "true"
-	base := r.URL.Query().Get("base") == "true"
-	commitHash := r.URL.Query().Get("commit")
+	writeJSON(w, http.StatusOK, GitDiffResponse{Diff: out})
+}
 
-	var args []string
-	if commitHash != "" {
+func (g *gitService) Diff(ctx context.Context, taskID string, opts service.DiffOptions) (string, error) {
+	workDir, err := g.s.taskWorkDir(taskID)
+	if err != nil {
+		return "", err
+	}
+
+	var baseBranch string
+	if opts.Base {
+		baseBranch = g.s.taskBaseBranch(taskID)
+	}
+	args := gitDiffArgs(ctx, workDir, opts.Staged, opts.Commit, baseBranch)
+	if opts.File != "" {
+		args = append(args, "--", opts.File)
+	}
+	return runGitContext(ctx, workDir, args...)
+}
+
+// gitDiffArgs returns the git arguments for the diff of a commit, of HEAD
+// against its merge-base with baseBranch (when set), of the index or of the
+// worktree, in that order of precedence.
+func gitDiffArgs(ctx context.Context, workDir string, staged bool, commitHash, baseBranch string) []string {
+	switch {
+	case commitHash != "":
 		// Diff for a specific commit — use diff-tree for root commit safety
-		_, err := runGit(workDir, "rev-parse", "--verify", commitHash+"^")
-		if err != nil {
+		if _, err := runGitContext(ctx, workDir, "rev-parse", "--verify", commitHash+"^"); err != nil {
 			// Root commit: show entire tree as additions
-			args = []string{"diff-tree", "--patch", "--no-commit-id", "-r", commitHash}
-		} else {
-			args = []string{"diff", commitHash + "^", commitHash}
-		}
-	} else if base {
-		// Diff against merge-base with default branch
-		taskID := urlParam(r, "id")
-		task, _ := s.db.GetTask(taskID)
-		project, _ := s.db.GetProject(task.ProjectID)
-		baseBranch := "main"
-		if project != nil {
-			baseBranch = project.DefaultBranch
+			return []string{"diff-tree", "--patch", "--no-commit-id", "-r", commitHash}
 		}
-
-		// Get merge-base
-		mbOut, err := runGit(workDir, "merge-base", baseBranch, "HEAD")
+		return []string{"diff", commitHash + "^", commitHash}
+	case baseBranch != "":
+		mbOut, err := runGitContext(ctx, workDir, "merge-base", baseBranch, "HEAD")
 		if err != nil {
 			// Fallback: diff against the base branch directly
-			args = []string{"diff", baseBranch + "...HEAD"}
-		} else {
-			mergeBase := strings.TrimSpace(mbOut)
-			args = []string{"diff", mergeBase, "HEAD"}
+			return []string{"diff", baseBranch + "...HEAD"}
 		}
-	} else if staged {
-		args = []string{"diff", "--cached"}
-	} else {
-		args = []string{"diff"}
+		return []string{"diff", strings.TrimSpace(mbOut), "HEAD"}
+	case staged:
+		return []string{"diff", "--cached"}
+	default:
+		return []string{"diff"}
 	}
+}
 
-	if file != "" {
-		args = append(args, "--", file)
+// taskBaseBranch returns the branch a task's worktree is based on, which
+// base diffs compare against.
+func (s *Server) taskBaseBranch(taskID string) string {
+	if task, err := s.db.GetTask(taskID); err == nil {
+		if project, err := s.db.GetProject(task.ProjectID); err == nil {
+			if branch := baseBranchFor(task, project); branch != "" {
+				return branch
+			}
+		}
 	}
+	return "main"
+}
 
-	out, err := runGit(workDir, args...)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+// baseBranchFor returns the branch a task's worktree is based on: the one
+// it was retargeted onto, or else its project's default branch.
+func baseBranchFor(task *db.Task, project *db.Project) string {
+	if branch := ptrToString(task.BaseBranch); branch != "" {
+		return branch
 	}
-
-	writeJSON(w, http.StatusOK, GitDiffResponse{Diff: out})
+	return project.DefaultBranch
 }
 
 func (s *Server) handleGitStage(w http.ResponseWriter, r *http.Request) {
@@ -517,7 +546,7 @@ func (s *Server) handleGitStage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	args := append([]string{"add", "--"}, req.Files...)
-	if _, err := runGit(workDir, args...); err != nil {
+	if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -543,7 +572,7 @@ func (s *Server) handleGitUnstage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	args := append([]string{"reset", "HEAD", "--"}, req.Files...)
-	if _, err := runGit(workDir, args...); err != nil {
+	if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -567,10 +596,14 @@ func (s *Server) handleGitRevert(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "tracked or untracked files are required")
 		return
 	}
+	if s.taskPathGuard(urlParam(r, "id")).protectsAny(append(req.Tracked, req.Untracked...)) {
+		writeError(w, http.StatusBadRequest, "path is protected")
+		return
+	}
 
 	if len(req.Tracked) > 0 {
 		args := append([]string{"restore", "--staged", "--worktree", "--"}, req.Tracked...)
-		if _, err := runGit(workDir, args...); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -578,7 +611,7 @@ func (s *Server) handleGitRevert(w http.ResponseWriter, r *http.Request) {
 
 	if len(req.Untracked) > 0 {
 		args := append([]string{"clean", "-f", "-d", "--"}, req.Untracked...)
-		if _, err := runGit(workDir, args...); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -588,22 +621,31 @@ func (s *Server) handleGitRevert(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGitCommit(w http.ResponseWriter, r *http.Request) {
-	workDir, ok := s.resolveTaskWorkDir(w, r)
-	if !ok {
-		return
-	}
-
 	var req GitCommitRequest
 	if err := decodeJSON(r, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	if req.Message == "" && !req.Amend {
-		writeError(w, http.StatusBadRequest, "message is required")
+	commit, err := s.git().Commit(r.Context(), urlParam(r, "id"), req)
+	if err != nil {
+		writeServiceError(w, err, err.Error())
 		return
 	}
 
+	writeJSON(w, http.StatusOK, commit)
+}
+
+func (g *gitService) Commit(ctx context.Context, taskID string, req service.CommitOptions) (*GitCommitResponse, error) {
+	workDir, err := g.s.taskWorkDir(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Message == "" && !req.Amend {
+		return nil, service.Invalid("message is required")
+	}
+
 	args := []string{"commit"}
 	if req.Amend {
 		args = append(args, "--amend")
@@ -614,72 +656,89 @@ func (s *Server) handleGitCommit(w http.ResponseWriter, r *http.Request) {
 	if req.Message != "" {
 		args = append(args, "-m", req.Message)
 	}
+	authorArgs, err := g.s.commitAuthorArgs(req.SessionID)
+	if err != nil {
+		return nil, notFound(err, "session")
+	}
+	args = append(args, authorArgs...)
 
-	if _, err := runGit(workDir, args...); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+	if _, err := runGitContext(ctx, workDir, args...); err != nil {
+		return nil, err
 	}
 
-	// Invalidate diff stats cache for this task
-	taskID := urlParam(r, "id")
-	s.diffStatsCache.Delete(taskID)
+	g.s.wakeDiffStats()
 
 	// Get the commit hash
-	hashOut, err := runGit(workDir, "rev-parse", "--short", "HEAD")
+	hashOut, err := runGitContext(ctx, workDir, "rev-parse", "--short", "HEAD")
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, err
 	}
 
 	// Get commit message
-	msgOut, err := runGit(workDir, "log", "-1", "--format=%s")
+	msgOut, err := runGitContext(ctx, workDir, "log", "-1", "--format=%s")
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, err
 	}
 
-	writeJSON(w, http.StatusOK, GitCommitResponse{
+	return &GitCommitResponse{
 		Hash:    strings.TrimSpace(hashOut),
 		Message: strings.TrimSpace(msgOut),
-	})
+	}, nil
 }
 
 func (s *Server) handleGitPull(w http.ResponseWriter, r *http.Request) {
-	workDir, ok := s.resolveTaskWorkDir(w, r)
-	if !ok {
+	if err := s.git().Pull(r.Context(), urlParam(r, "id")); err != nil {
+		writeServiceError(w, err, err.Error())
 		return
 	}
 
-	if _, err := runGit(workDir, "pull", "--ff-only"); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// Invalidate diff stats cache for this task
-	taskID := urlParam(r, "id")
-	s.diffStatsCache.Delete(taskID)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *Server) handleGitPush(w http.ResponseWriter, r *http.Request) {
-	workDir, ok := s.resolveTaskWorkDir(w, r)
-	if !ok {
-		return
+func (g *gitService) Pull(ctx context.Context, taskID string) error {
+	workDir, err := g.s.taskWorkDir(taskID)
+	if err != nil {
+		return err
+	}
+	if _, err := runGitContext(ctx, workDir, "pull", "--ff-only"); err != nil {
+		return err
 	}
 
+	g.s.wakeDiffStats()
+	return nil
+}
+
+func (s *Server) handleGitPush(w http.ResponseWriter, r *http.Request) {
 	var req GitPushRequest
 	// Body is optional — ignore decode errors for backwards compat
 	_ = decodeJSON(r, &req)
 
-	if err := gitPushCurrentBranch(workDir, req.Force); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+	if err := s.git().Push(r.Context(), urlParam(r, "id"), req.Force); err != nil {
+		writeServiceError(w, err, err.Error())
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (g *gitService) Push(ctx context.Context, taskID string, force bool) error {
+	task, project, err := g.s.taskWorktree(taskID)
+	if err != nil {
+		return err
+	}
+	workDir := *task.WorktreePath
+	if force {
+		if err := checkForcePush(project, workDir); err != nil {
+			return err
+		}
+	}
+	if err := gitPushCurrentBranch(workDir, force); err != nil {
+		return err
+	}
+	g.s.emitGitPushed(task.ProjectID, task.ID, workDir, "")
+	return nil
+}
+
 func (s *Server) handleGitStash(w http.ResponseWriter, r *http.Request) {
 	workDir, ok := s.resolveTaskWorkDir(w, r)
 	if !ok {
@@ -694,21 +753,21 @@ func (s *Server) handleGitStash(w http.ResponseWriter, r *http.Request) {
 
 	switch req.Action {
 	case "push":
-		if _, err := runGit(workDir, "stash", "push"); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, "stash", "push"); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 
 	case "pop":
-		if _, err := runGit(workDir, "stash", "pop"); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, "stash", "pop"); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 
 	case "list":
-		out, err := runGit(workDir, "stash", "list")
+		out, err := runGitContext(r.Context(), workDir, "stash", "list")
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -843,29 +902,12 @@ func (s *Server) handleProjectGitDiff(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file := r.URL.Query().Get("file")
-	staged := r.URL.Query().Get("staged") == "true"
-	commitHash := r.URL.Query().Get("commit")
-
-	var args []string
-	if commitHash != "" {
-		_, err := runGit(workDir, "rev-parse", "--verify", commitHash+"^")
-		if err != nil {
-			args = []string{"diff-tree", "--patch", "--no-commit-id", "-r", commitHash}
-		} else {
-			args = []string{"diff", commitHash + "^", commitHash}
-		}
-	} else if staged {
-		args = []string{"diff", "--cached"}
-	} else {
-		args = []string{"diff"}
-	}
-
-	if file != "" {
+	args := gitDiffArgs(r.Context(), workDir, r.URL.Query().Get("staged") == "true", r.URL.Query().Get("commit"), "")
+	if file := r.URL.Query().Get("file"); file != "" {
 		args = append(args, "--", file)
 	}
 
-	out, err := runGit(workDir, args...)
+	out, err := runGitContext(r.Context(), workDir, args...)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -891,7 +933,7 @@ func (s *Server) handleProjectGitStage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	args := append([]string{"add", "--"}, req.Files...)
-	if _, err := runGit(workDir, args...); err != nil {
+	if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -916,7 +958,7 @@ func (s *Server) handleProjectGitUnstage(w http.ResponseWriter, r *http.Request)
 	}
 
 	args := append([]string{"reset", "HEAD", "--"}, req.Files...)
-	if _, err := runGit(workDir, args...); err != nil {
+	if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -939,17 +981,21 @@ func (s *Server) handleProjectGitRevert(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusBadRequest, "tracked or untracked files are required")
 		return
 	}
+	if s.projectPathGuard(urlParam(r, "id")).protectsAny(append(req.Tracked, req.Untracked...)) {
+		writeError(w, http.StatusBadRequest, "path is protected")
+		return
+	}
 
 	if len(req.Tracked) > 0 {
 		args := append([]string{"restore", "--staged", "--worktree", "--"}, req.Tracked...)
-		if _, err := runGit(workDir, args...); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 	if len(req.Untracked) > 0 {
 		args := append([]string{"clean", "-f", "-d", "--"}, req.Untracked...)
-		if _, err := runGit(workDir, args...); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -984,19 +1030,25 @@ func (s *Server) handleProjectGitCommit(w http.ResponseWriter, r *http.Request)
 	if req.Message != "" {
 		args = append(args, "-m", req.Message)
 	}
+	authorArgs, err := s.commitAuthorArgs(req.SessionID)
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	args = append(args, authorArgs...)
 
-	if _, err := runGit(workDir, args...); err != nil {
+	if _, err := runGitContext(r.Context(), workDir, args...); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	hashOut, err := runGit(workDir, "rev-parse", "--short", "HEAD")
+	hashOut, err := runGitContext(r.Context(), workDir, "rev-parse", "--short", "HEAD")
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	msgOut, err := runGit(workDir, "log", "-1", "--format=%s")
+	msgOut, err := runGitContext(r.Context(), workDir, "log", "-1", "--format=%s")
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1014,7 +1066,7 @@ func (s *Server) handleProjectGitPull(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := runGit(workDir, "pull", "--ff-only"); err != nil {
+	if _, err := runGitContext(r.Context(), workDir, "pull", "--ff-only"); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -1023,18 +1075,27 @@ func (s *Server) handleProjectGitPull(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleProjectGitPush(w http.ResponseWriter, r *http.Request) {
-	workDir, ok := s.resolveProjectWorkDir(w, r)
-	if !ok {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
 		return
 	}
+	workDir := project.Path
 
 	var req GitPushRequest
 	_ = decodeJSON(r, &req)
 
+	if req.Force {
+		if err := checkForcePush(project, workDir); err != nil {
+			writeServiceError(w, err, err.Error())
+			return
+		}
+	}
 	if err := gitPushCurrentBranch(workDir, req.Force); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	s.emitGitPushed(urlParam(r, "id"), "", workDir, "")
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -1053,19 +1114,19 @@ func (s *Server) handleProjectGitStash(w http.ResponseWriter, r *http.Request) {
 
 	switch req.Action {
 	case "push":
-		if _, err := runGit(workDir, "stash", "push"); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, "stash", "push"); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 	case "pop":
-		if _, err := runGit(workDir, "stash", "pop"); err != nil {
+		if _, err := runGitContext(r.Context(), workDir, "stash", "pop"); err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 	case "list":
-		out, err := runGit(workDir, "stash", "list")
+		out, err := runGitContext(r.Context(), workDir, "stash", "list")
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -1207,14 +1268,7 @@ func (s *Server) handleGitDiffContent(w http.ResponseWriter, r *http.Request) {
 
 	baseBranch := "main"
 	if base {
-		taskID := urlParam(r, "id")
-		task, _ := s.db.GetTask(taskID)
-		if task != nil {
-			project, _ := s.db.GetProject(task.ProjectID)
-			if project != nil {
-				baseBranch = project.DefaultBranch
-			}
-		}
+		baseBranch = s.taskBaseBranch(urlParam(r, "id"))
 	}
 
 	resp, err := gitDiffContent(workDir, file, staged, base, baseBranch, commitHash)
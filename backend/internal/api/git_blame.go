@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Blame and file history: who, or which session's commit, last touched each
+// line of a file, and the commits that changed it.
+
+type GitBlameLine struct {
+	Line     int    `json:"line"`
+	OrigLine int    `json:"origLine"` // the line's number in the commit that last changed it
+	Hash     string `json:"hash"`
+	Content  string `json:"content"`
+}
+
+type GitBlameCommit struct {
+	Hash        string `json:"hash"`
+	ShortHash   string `json:"shortHash"`
+	Author      string `json:"author"`
+	AuthorEmail string `json:"authorEmail"`
+	Date        string `json:"date"`
+	Summary     string `json:"summary"`
+	Path        string `json:"path"`                  // the file's path in that commit
+	Uncommitted bool   `json:"uncommitted,omitempty"` // lines changed in the working tree
+}
+
+type GitBlameResponse struct {
+	File    string                    `json:"file"`
+	Lines   []GitBlameLine            `json:"lines"`
+	Commits map[string]GitBlameCommit `json:"commits"` // keyed by hash
+}
+
+type GitFileLogEntry struct {
+	GitLogEntry
+	Path string `json:"path"` // the file's path after the commit, which differs across renames
+}
+
+type GitFileLogResponse struct {
+	File    string            `json:"file"`
+	Commits []GitFileLogEntry `json:"commits"`
+}
+
+// uncommittedHash is what git blame reports for working tree changes.
+const uncommittedHash = "0000000000000000000000000000000000000000"
+
+// parseBlamePorcelain parses `git blame --porcelain` output. Commit details
+// are given once, with the first line a commit touched.
+func parseBlamePorcelain(out string) ([]GitBlameLine, map[string]GitBlameCommit) {
+	lines := make([]GitBlameLine, 0)
+	commits := make(map[string]GitBlameCommit)
+	var current *GitBlameLine
+	var commit GitBlameCommit
+	var authorTime int64
+	var authorTZ string
+
+	for _, raw := range strings.Split(out, "\n") {
+		if current == nil {
+			fields := strings.Fields(raw)
+			if len(fields) < 3 || len(fields[0]) != 40 {
+				continue
+			}
+			orig, _ := strconv.Atoi(fields[1])
+			final, _ := strconv.Atoi(fields[2])
+			current = &GitBlameLine{Hash: fields[0], OrigLine: orig, Line: final}
+			commit = commits[current.Hash]
+			commit.Hash = current.Hash
+			continue
+		}
+		if content, ok := strings.CutPrefix(raw, "\t"); ok {
+			current.Content = content
+			lines = append(lines, *current)
+			if _, seen := commits[commit.Hash]; !seen {
+				commit.ShortHash = commit.Hash[:7]
+				commit.Uncommitted = commit.Hash == uncommittedHash
+				if authorTime > 0 {
+					commit.Date = blameDate(authorTime, authorTZ)
+				}
+				commits[commit.Hash] = commit
+			}
+			current, authorTime, authorTZ = nil, 0, ""
+			continue
+		}
+		key, value, _ := strings.Cut(raw, " ")
+		switch key {
+		case "author":
+			commit.Author = value
+		case "author-mail":
+			commit.AuthorEmail = strings.Trim(value, "<>")
+		case "author-time":
+			authorTime, _ = strconv.ParseInt(value, 10, 64)
+		case "author-tz":
+			authorTZ = value
+		case "summary":
+			commit.Summary = value
+		case "filename":
+			commit.Path = value
+		}
+	}
+	return lines, commits
+}
+
+// blameDate formats an author time like %aI does, in the author's zone.
+func blameDate(unix int64, tz string) string {
+	t := time.Unix(unix, 0).UTC()
+	if len(tz) == 5 {
+		hours, _ := strconv.Atoi(tz[1:3])
+		minutes, _ := strconv.Atoi(tz[3:])
+		offset := hours*3600 + minutes*60
+		if tz[0] == '-' {
+			offset = -offset
+		}
+		t = t.In(time.FixedZone(tz, offset))
+	}
+	return t.Format(time.RFC3339)
+}
+
+// gitFileQuery reads and validates the file, and the optional rev, of a
+// blame or file history request.
+func gitFileQuery(r *http.Request) (string, string, error) {
+	file, err := normalizeRelativePath(r.URL.Query().Get("file"), false)
+	if err != nil {
+		return "", "", fmt.Errorf("file: %w", err)
+	}
+	rev := r.URL.Query().Get("rev")
+	if strings.HasPrefix(rev, "-") {
+		return "", "", fmt.Errorf("invalid rev")
+	}
+	return file, rev, nil
+}
+
+// handleGitBlame serves GET .../git/blame?file=, optionally at rev= and for
+// the lines from start= to end=. Without rev, working tree changes are
+// blamed as uncommitted.
+func (s *Server) handleGitBlame(resolve func(http.ResponseWriter, *http.Request) (string, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workDir, ok := resolve(w, r)
+		if !ok {
+			return
+		}
+		file, rev, err := gitFileQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		args := []string{"blame", "--porcelain"}
+		query := r.URL.Query()
+		if query.Get("start") != "" || query.Get("end") != "" {
+			start, startErr := strconv.Atoi(firstNonEmpty(query.Get("start"), "1"))
+			end, endErr := strconv.Atoi(firstNonEmpty(query.Get("end"), query.Get("start")))
+			if startErr != nil || endErr != nil || start < 1 || end < start {
+				writeError(w, http.StatusBadRequest, "start and end must be line numbers with start <= end")
+				return
+			}
+			args = append(args, "-L", fmt.Sprintf("%d,%d", start, end))
+		}
+		if rev != "" {
+			args = append(args, rev)
+		}
+		args = append(args, "--", file)
+
+		out, err := runGitContext(r.Context(), workDir, args...)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		lines, commits := parseBlamePorcelain(out)
+		writeJSON(w, http.StatusOK, GitBlameResponse{File: file, Lines: lines, Commits: commits})
+	}
+}
+
+// gitFileLog returns the commits that changed a file, newest first,
+// following it across renames.
+func gitFileLog(workDir, file, rev string, limit int) ([]GitFileLogEntry, error) {
+	const recordSep, fieldSep = "\x1e", "\x1f"
+	format := recordSep + strings.Join([]string{"%H", "%h", "%s", "%an", "%ae", "%aI", "%b"}, fieldSep) + fieldSep
+	args := []string{"log", "--follow", "--numstat", fmt.Sprintf("-%d", limit), "--format=" + format}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := runGit(workDir, append(args, "--", file)...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]GitFileLogEntry, 0)
+	for _, record := range strings.Split(out, recordSep) {
+		parts := strings.Split(record, fieldSep)
+		if len(parts) < 8 {
+			continue
+		}
+		entry := GitFileLogEntry{
+			GitLogEntry: GitLogEntry{
+				Hash:        parts[0],
+				ShortHash:   parts[1],
+				Message:     parts[2],
+				Author:      parts[3],
+				AuthorEmail: parts[4],
+				Date:        parts[5],
+				Body:        strings.TrimSpace(parts[6]),
+			},
+			Path: file,
+		}
+		for _, line := range strings.Split(strings.TrimSpace(parts[7]), "\n") {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) < 3 {
+				continue
+			}
+			add, _ := strconv.Atoi(fields[0])
+			del, _ := strconv.Atoi(fields[1])
+			entry.Additions += add
+			entry.Deletions += del
+			entry.FilesChanged++
+			entry.Path = numstatNewPath(fields[2])
+		}
+		commits = append(commits, entry)
+	}
+	return commits, nil
+}
+
+// numstatNewPath returns the new path of a numstat path, which for renames
+// reads "old => new" or "dir/{old => new}/rest".
+func numstatNewPath(path string) string {
+	if open := strings.Index(path, "{"); open >= 0 {
+		if end := strings.Index(path[open:], "}"); end >= 0 {
+			inner := path[open+1 : open+end]
+			if _, newPart, ok := strings.Cut(inner, " => "); ok {
+				joined := path[:open] + newPart + path[open+end+1:]
+				return strings.ReplaceAll(joined, "//", "/")
+			}
+		}
+	}
+	if _, newPath, ok := strings.Cut(path, " => "); ok {
+		return newPath
+	}
+	return path
+}
+
+// handleGitFileLog serves GET .../git/file-log?file=, with optional rev= and
+// limit= (default 50, at most 200).
+func (s *Server) handleGitFileLog(resolve func(http.ResponseWriter, *http.Request) (string, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workDir, ok := resolve(w, r)
+		if !ok {
+			return
+		}
+		file, rev, err := gitFileQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit := 50
+		if q := r.URL.Query().Get("limit"); q != "" {
+			if n, err := strconv.Atoi(q); err == nil && n > 0 && n <= 200 {
+				limit = n
+			}
+		}
+
+		commits, err := gitFileLog(workDir, file, rev, limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, GitFileLogResponse{File: file, Commits: commits})
+	}
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// A task's branch can be renamed, and its worktree moved onto another base
+// branch mid-flight, e.g. from main to a release branch. Moving replays the
+// task's commits onto the new base and carries uncommitted changes over in
+// a stash. The base is stored on the task, so diffs and PRs follow it.
+
+type GitRenameBranchRequest struct {
+	Name string `json:"name"`
+}
+
+type GitRenameBranchResponse struct {
+	Branch string `json:"branch"`
+}
+
+type GitRetargetRequest struct {
+	BaseBranch string `json:"baseBranch"`
+}
+
+type GitRetargetResponse struct {
+	BaseBranch string   `json:"baseBranch"`
+	Onto       string   `json:"onto"` // the ref the commits were replayed onto
+	Head       string   `json:"head"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// taskWorktree returns a task with a worktree, and its project.
+func (s *Server) taskWorktree(taskID string) (*db.Task, *db.Project, error) {
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return nil, nil, notFound(err, "task")
+	}
+	if task.WorktreePath == nil || *task.WorktreePath == "" {
+		return nil, nil, service.Invalid("task has no worktree")
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil, nil, notFound(err, "project")
+	}
+	return task, project, nil
+}
+
+func (s *Server) handleGitRenameBranch(w http.ResponseWriter, r *http.Request) {
+	var req GitRenameBranchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	branch, err := s.git().RenameBranch(r.Context(), urlParam(r, "id"), req.Name)
+	if err != nil {
+		writeServiceError(w, err, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, GitRenameBranchResponse{Branch: branch})
+}
+
+// RenameBranch renames a task's branch. The old upstream is dropped, so
+// the next push publishes the branch under its new name. Tasks with a PR
+// are refused, since the PR would be left pointing at the old name.
+func (g *gitService) RenameBranch(ctx context.Context, taskID, name string) (string, error) {
+	task, _, err := g.s.taskWorktree(taskID)
+	if err != nil {
+		return "", err
+	}
+	workDir := *task.WorktreePath
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", service.Invalid("name is required")
+	}
+	if _, err := runGitContext(ctx, workDir, "check-ref-format", "--branch", name); err != nil {
+		return "", service.Invalid("invalid branch name %q", name)
+	}
+	if ptrToString(task.PRURL) != "" {
+		return "", service.Conflict("task has a pull request; rename the branch on the forge instead")
+	}
+
+	current, err := runGitContext(ctx, workDir, "branch", "--show-current")
+	if err != nil {
+		return "", err
+	}
+	current = strings.TrimSpace(current)
+	if current == "" {
+		return "", service.Invalid("worktree is not on a branch")
+	}
+	if current != name {
+		if gitRefExists(workDir, "refs/heads/"+name) {
+			return "", service.Conflict("branch %q already exists", name)
+		}
+		if _, err := runGitContext(ctx, workDir, "branch", "-m", current, name); err != nil {
+			return "", err
+		}
+		// Fails when there was no upstream, which is fine.
+		runGitContext(ctx, workDir, "branch", "--unset-upstream")
+	}
+
+	if _, err := g.s.db.UpdateTask(task.ID, db.UpdateTaskInput{Branch: &name}); err != nil {
+		return "", fmt.Errorf("update task: %w", err)
+	}
+	return name, nil
+}
+
+func (s *Server) handleGitRetarget(w http.ResponseWriter, r *http.Request) {
+	var req GitRetargetRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	resp, err := s.git().Retarget(r.Context(), urlParam(r, "id"), req.BaseBranch)
+	if err != nil {
+		writeServiceError(w, err, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Retarget moves a task's worktree onto baseBranch: the commits made since
+// the old base are replayed onto origin/<baseBranch> (or the local branch
+// when there is no remote one), with uncommitted changes stashed around
+// the rebase. A conflicting rebase is aborted and the worktree left as it
+// was. A stash that no longer applies cleanly is left conflicted and
+// kept, with a warning.
+func (g *gitService) Retarget(ctx context.Context, taskID, baseBranch string) (*GitRetargetResponse, error) {
+	task, project, err := g.s.taskWorktree(taskID)
+	if err != nil {
+		return nil, err
+	}
+	workDir := *task.WorktreePath
+
+	baseBranch = strings.TrimSpace(baseBranch)
+	if baseBranch == "" {
+		return nil, service.Invalid("baseBranch is required")
+	}
+	if _, err := runGitContext(ctx, workDir, "check-ref-format", "--branch", baseBranch); err != nil {
+		return nil, service.Invalid("invalid branch name %q", baseBranch)
+	}
+
+	// Fails without a remote; the local branch is used then.
+	runGitContext(ctx, workDir, "fetch", "origin", baseBranch)
+	onto := "origin/" + baseBranch
+	if !gitRefExists(workDir, onto) {
+		onto = baseBranch
+		if !gitRefExists(workDir, "refs/heads/"+baseBranch) {
+			return nil, service.Invalid("branch %q not found", baseBranch)
+		}
+	}
+
+	oldBase := baseBranchFor(task, project)
+	forkPoint, err := runGitContext(ctx, workDir, "merge-base", "HEAD", oldBase)
+	if err != nil {
+		return nil, service.Invalid("cannot find where the task forked from %s", oldBase)
+	}
+	forkPoint = strings.TrimSpace(forkPoint)
+
+	status, err := runGitContext(ctx, workDir, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	stashed := strings.TrimSpace(status) != ""
+	if stashed {
+		if _, err := runGitContext(ctx, workDir, "stash", "push", "--include-untracked", "-m", "codeburg: retarget onto "+baseBranch); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := runGitContext(ctx, workDir, "rebase", "--onto", onto, forkPoint); err != nil {
+		runGitContext(ctx, workDir, "rebase", "--abort")
+		if stashed {
+			runGitContext(ctx, workDir, "stash", "pop")
+		}
+		return nil, service.Conflict("the task's commits conflict with %s; resolve them by hand or retarget elsewhere", onto)
+	}
+
+	resp := &GitRetargetResponse{BaseBranch: baseBranch, Onto: onto}
+	if stashed {
+		if _, err := runGitContext(ctx, workDir, "stash", "pop"); err != nil {
+			resp.Warnings = append(resp.Warnings, "uncommitted changes conflict with "+baseBranch+"; resolve the conflicts, then drop the stash that kept them")
+		}
+	}
+
+	// The project's default branch is stored as no base at all, so the
+	// task follows the project if its default changes.
+	stored := baseBranch
+	if baseBranch == project.DefaultBranch {
+		stored = ""
+	}
+	if _, err := g.s.db.UpdateTask(task.ID, db.UpdateTaskInput{BaseBranch: &stored}); err != nil {
+		return nil, fmt.Errorf("update task: %w", err)
+	}
+
+	if head, err := runGitContext(ctx, workDir, "rev-parse", "HEAD"); err == nil {
+		resp.Head = strings.TrimSpace(head)
+	}
+	g.s.wakeDiffStats()
+	return resp, nil
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Structured diffs: the same diffs as .../git/diff, parsed into files, hunks
+// and typed lines with line numbers, renames and binary markers, and with
+// word-level change ranges between the removed and added lines of each change
+// block, so clients can render unified or side-by-side views directly.
+
+type GitDiffRange struct {
+	Start int `json:"start"` // UTF-16 offsets into the line text, as JS strings index
+	End   int `json:"end"`
+}
+
+type GitDiffLine struct {
+	Type    string `json:"type"` // context, add or delete
+	OldLine int    `json:"oldLine,omitempty"`
+	NewLine int    `json:"newLine,omitempty"`
+	Text    string `json:"text"`
+	// Changes marks the changed words of a line paired with one on the other
+	// side. Without it, an add or delete line changed as a whole.
+	Changes   []GitDiffRange `json:"changes,omitempty"`
+	NoNewline bool           `json:"noNewline,omitempty"` // "\ No newline at end of file"
+}
+
+// GitDiffRow is one row of a side-by-side view. Context rows carry the same
+// line on both sides; unpaired adds and deletes leave the other side empty.
+type GitDiffRow struct {
+	Old *GitDiffLine `json:"old,omitempty"`
+	New *GitDiffLine `json:"new,omitempty"`
+}
+
+type GitStructuredHunk struct {
+	Header   string        `json:"header"`
+	OldStart int           `json:"oldStart"`
+	OldLines int           `json:"oldLines"`
+	NewStart int           `json:"newStart"`
+	NewLines int           `json:"newLines"`
+	Section  string        `json:"section,omitempty"` // the function context after the @@ range
+	Lines    []GitDiffLine `json:"lines,omitempty"`
+	Rows     []GitDiffRow  `json:"rows,omitempty"` // instead of lines with view=split
+}
+
+type GitStructuredFile struct {
+	Path       string              `json:"path"`
+	OldPath    string              `json:"oldPath,omitempty"`
+	Status     string              `json:"status"` // M, A, D or R
+	Similarity int                 `json:"similarity,omitempty"`
+	Binary     bool                `json:"binary,omitempty"`
+	OldMode    string              `json:"oldMode,omitempty"`
+	NewMode    string              `json:"newMode,omitempty"`
+	Additions  int                 `json:"additions"`
+	Deletions  int                 `json:"deletions"`
+	Hunks      []GitStructuredHunk `json:"hunks"`
+}
+
+type GitStructuredDiffResponse struct {
+	Files []GitStructuredFile `json:"files"`
+}
+
+const (
+	// maxWordDiffTokens bounds the token product compared per line pair.
+	maxWordDiffTokens = 250_000
+	// maxWordDiffLines skips word ranges for files with more changed lines.
+	maxWordDiffLines = 5000
+)
+
+// structuredDiff parses `git diff` output into structured files. With split,
+// hunks carry side-by-side rows instead of lines.
+func structuredDiff(out string, split bool) []GitStructuredFile {
+	files := make([]GitStructuredFile, 0)
+	for _, f := range parseUnifiedDiff(out) {
+		sf := GitStructuredFile{
+			Path:    f.Path,
+			OldPath: f.OldPath,
+			Status:  f.Status,
+			Binary:  f.Binary,
+			Hunks:   make([]GitStructuredHunk, 0, len(f.Hunks)),
+		}
+		for _, line := range f.header {
+			switch {
+			case strings.HasPrefix(line, "similarity index "):
+				sf.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+			case strings.HasPrefix(line, "old mode "):
+				sf.OldMode = strings.TrimPrefix(line, "old mode ")
+			case strings.HasPrefix(line, "new mode "):
+				sf.NewMode = strings.TrimPrefix(line, "new mode ")
+			case strings.HasPrefix(line, "new file mode "):
+				sf.NewMode = strings.TrimPrefix(line, "new file mode ")
+			case strings.HasPrefix(line, "deleted file mode "):
+				sf.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+			}
+		}
+
+		changed := 0
+		for _, h := range f.Hunks {
+			changed += len(h.Lines)
+		}
+		for _, h := range f.Hunks {
+			hunk := GitStructuredHunk{
+				Header:   h.Header,
+				OldStart: h.OldStart,
+				OldLines: h.OldLines,
+				NewStart: h.NewStart,
+				NewLines: h.NewLines,
+			}
+			if i := strings.Index(h.Header[2:], "@@"); i >= 0 {
+				hunk.Section = strings.TrimSpace(h.Header[i+4:])
+			}
+			lines := diffLines(h)
+			for _, l := range lines {
+				switch l.Type {
+				case "add":
+					sf.Additions++
+				case "delete":
+					sf.Deletions++
+				}
+			}
+			pairs := pairChangedLines(lines)
+			if changed <= maxWordDiffLines {
+				for _, p := range pairs {
+					lines[p[0]].Changes, lines[p[1]].Changes = wordRanges(lines[p[0]].Text, lines[p[1]].Text)
+				}
+			}
+			if split {
+				hunk.Rows = splitRows(lines, pairs)
+			} else {
+				hunk.Lines = lines
+			}
+			sf.Hunks = append(sf.Hunks, hunk)
+		}
+		files = append(files, sf)
+	}
+	return files
+}
+
+// diffLines types a hunk's body lines and numbers them.
+func diffLines(h GitDiffHunk) []GitDiffLine {
+	lines := make([]GitDiffLine, 0, len(h.Lines))
+	oldLine, newLine := h.OldStart, h.NewStart
+	for _, raw := range h.Lines {
+		if raw == "" {
+			continue
+		}
+		text := raw[1:]
+		switch raw[0] {
+		case '+':
+			lines = append(lines, GitDiffLine{Type: "add", NewLine: newLine, Text: text})
+			newLine++
+		case '-':
+			lines = append(lines, GitDiffLine{Type: "delete", OldLine: oldLine, Text: text})
+			oldLine++
+		case '\\':
+			if n := len(lines); n > 0 {
+				lines[n-1].NoNewline = true
+			}
+		default:
+			lines = append(lines, GitDiffLine{Type: "context", OldLine: oldLine, NewLine: newLine, Text: text})
+			oldLine++
+			newLine++
+		}
+	}
+	return lines
+}
+
+// pairChangedLines pairs, within each block of deletes followed by adds, the
+// n-th deleted line with the n-th added one. It returns index pairs into
+// lines.
+func pairChangedLines(lines []GitDiffLine) [][2]int {
+	var pairs [][2]int
+	for i := 0; i < len(lines); {
+		if lines[i].Type != "delete" {
+			i++
+			continue
+		}
+		delStart := i
+		for i < len(lines) && lines[i].Type == "delete" {
+			i++
+		}
+		addStart := i
+		for i < len(lines) && lines[i].Type == "add" {
+			i++
+		}
+		for k := 0; delStart+k < addStart && addStart+k < i; k++ {
+			pairs = append(pairs, [2]int{delStart + k, addStart + k})
+		}
+	}
+	return pairs
+}
+
+// splitRows lays lines out side by side, putting paired lines on one row.
+// Deletes come before adds in a change block, so unpaired adds follow.
+func splitRows(lines []GitDiffLine, pairs [][2]int) []GitDiffRow {
+	partner := make(map[int]int, len(pairs))
+	paired := make(map[int]bool, len(pairs))
+	for _, p := range pairs {
+		partner[p[0]] = p[1]
+		paired[p[1]] = true
+	}
+
+	rows := make([]GitDiffRow, 0, len(lines))
+	for i := range lines {
+		l := &lines[i]
+		switch {
+		case l.Type == "context":
+			rows = append(rows, GitDiffRow{Old: l, New: l})
+		case l.Type == "delete":
+			row := GitDiffRow{Old: l}
+			if k, ok := partner[i]; ok {
+				row.New = &lines[k]
+			}
+			rows = append(rows, row)
+		case !paired[i]:
+			rows = append(rows, GitDiffRow{New: l})
+		}
+	}
+	return rows
+}
+
+// wordRanges returns the changed ranges of a removed line and the line that
+// replaced it, comparing them word by word. Lines with little in common get
+// no ranges: they changed as a whole.
+func wordRanges(oldText, newText string) ([]GitDiffRange, []GitDiffRange) {
+	a, b := diffTokens(oldText), diffTokens(newText)
+	if len(a)*len(b) > maxWordDiffTokens {
+		return nil, nil
+	}
+
+	// Longest common subsequence of tokens.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	keepA, keepB := make([]bool, len(a)), make([]bool, len(b))
+	common := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			keepA[i], keepB[j] = true, true
+			if strings.TrimSpace(a[i]) != "" {
+				common += utf16Len(a[i])
+			}
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	longest := max(utf16Len(strings.TrimSpace(oldText)), utf16Len(strings.TrimSpace(newText)))
+	if longest == 0 || common*3 < longest {
+		return nil, nil
+	}
+	return changedRanges(a, keepA), changedRanges(b, keepB)
+}
+
+// diffTokens splits text into words, runs of whitespace and single other
+// characters.
+func diffTokens(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i + 1
+		switch {
+		case isWordRune(runes[i]):
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+		case unicode.IsSpace(runes[i]):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// changedRanges merges the tokens not kept into ranges.
+func changedRanges(tokens []string, keep []bool) []GitDiffRange {
+	var ranges []GitDiffRange
+	offset := 0
+	for i, tok := range tokens {
+		n := utf16Len(tok)
+		if !keep[i] {
+			if last := len(ranges) - 1; last >= 0 && ranges[last].End == offset {
+				ranges[last].End += n
+			} else {
+				ranges = append(ranges, GitDiffRange{Start: offset, End: offset + n})
+			}
+		}
+		offset += n
+	}
+	return ranges
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// handleGitStructuredDiff serves GET .../git/diff/structured, which takes the
+// same file, staged, commit and base parameters as .../git/diff plus
+// view=split for side-by-side rows.
+func (s *Server) handleGitStructuredDiff(resolve func(http.ResponseWriter, *http.Request) (string, bool), allowBase bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workDir, ok := resolve(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		view := query.Get("view")
+		if view != "" && view != "unified" && view != "split" {
+			writeError(w, http.StatusBadRequest, "view must be unified or split")
+			return
+		}
+
+		var baseBranch string
+		if allowBase && query.Get("base") == "true" {
+			baseBranch = s.taskBaseBranch(urlParam(r, "id"))
+		}
+		args := gitDiffArgs(r.Context(), workDir, query.Get("staged") == "true", query.Get("commit"), baseBranch)
+		args = append(args[:1], append([]string{"--no-color", "--no-ext-diff", "--find-renames"}, args[1:]...)...)
+		if file := query.Get("file"); file != "" {
+			args = append(args, "--", file)
+		}
+
+		out, err := runGitContext(r.Context(), workDir, args...)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, GitStructuredDiffResponse{Files: structuredDiff(out, view == "split")})
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hunk-level review: the diff is returned split into files and hunks, and
+// individual hunks can be staged, unstaged or reverted by ID. Hunk IDs are
+// derived from the hunk content, so an action against a diff that has since
+// changed fails with 409 instead of touching the wrong lines.
+
+type GitDiffHunk struct {
+	ID       string   `json:"id"`
+	Header   string   `json:"header"` // the "@@ -a,b +c,d @@ ..." line
+	OldStart int      `json:"oldStart"`
+	OldLines int      `json:"oldLines"`
+	NewStart int      `json:"newStart"`
+	NewLines int      `json:"newLines"`
+	Lines    []string `json:"lines"` // body lines, each prefixed with ' ', '+', '-' or '\'
+}
+
+type GitDiffFile struct {
+	Path    string        `json:"path"`
+	OldPath string        `json:"oldPath,omitempty"` // set for renames and copies
+	Status  string        `json:"status"`            // M, A, D or R
+	Binary  bool          `json:"binary,omitempty"`
+	Hunks   []GitDiffHunk `json:"hunks"`
+
+	header []string // raw header lines, replayed when building patches
+}
+
+type GitHunksResponse struct {
+	Staged bool          `json:"staged"`
+	Files  []GitDiffFile `json:"files"`
+}
+
+type GitHunksRequest struct {
+	Hunks []string `json:"hunks"`
+}
+
+type hunkAction string
+
+const (
+	hunkStage   hunkAction = "stage"
+	hunkUnstage hunkAction = "unstage"
+	hunkRevert  hunkAction = "revert"
+)
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnifiedDiff splits `git diff` output into files and hunks.
+func parseUnifiedDiff(out string) []GitDiffFile {
+	var files []GitDiffFile
+	var file *GitDiffFile
+	var hunk *GitDiffHunk
+
+	flush := func() {
+		if file == nil {
+			return
+		}
+		if hunk != nil {
+			file.Hunks = append(file.Hunks, *hunk)
+			hunk = nil
+		}
+		for i := range file.Hunks {
+			file.Hunks[i].ID = hunkID(file.Path, &file.Hunks[i])
+		}
+		files = append(files, *file)
+		file = nil
+	}
+
+	for _, line := range strings.SplitAfter(out, "\n") {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			file = &GitDiffFile{Status: "M", Hunks: []GitDiffHunk{}, header: []string{line}}
+			if a, b, ok := splitDiffGitPaths(strings.TrimPrefix(line, "diff --git ")); ok {
+				file.Path = b
+				if a != b {
+					file.OldPath = a
+				}
+			}
+		case file == nil || line == "":
+			// Context lines always carry a prefix, so an empty line is only the
+			// end of the output.
+			continue
+		case hunk == nil && !strings.HasPrefix(line, "@@"):
+			file.header = append(file.header, line)
+			switch {
+			case strings.HasPrefix(line, "new file mode"):
+				file.Status = "A"
+			case strings.HasPrefix(line, "deleted file mode"):
+				file.Status = "D"
+			case strings.HasPrefix(line, "rename from "):
+				file.Status = "R"
+				file.OldPath = strings.TrimPrefix(line, "rename from ")
+			case strings.HasPrefix(line, "rename to "):
+				file.Path = strings.TrimPrefix(line, "rename to ")
+			case strings.HasPrefix(line, "+++ b/"):
+				file.Path = strings.TrimPrefix(line, "+++ b/")
+			case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+				file.Binary = true
+			}
+		case strings.HasPrefix(line, "@@"):
+			if hunk != nil {
+				file.Hunks = append(file.Hunks, *hunk)
+			}
+			hunk = &GitDiffHunk{Header: line, Lines: []string{}}
+			if m := hunkHeaderRe.FindStringSubmatch(line); m != nil {
+				hunk.OldStart, _ = strconv.Atoi(m[1])
+				hunk.OldLines = hunkCount(m[2])
+				hunk.NewStart, _ = strconv.Atoi(m[3])
+				hunk.NewLines = hunkCount(m[4])
+			}
+		default:
+			hunk.Lines = append(hunk.Lines, line)
+		}
+	}
+	flush()
+	return files
+}
+
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// splitDiffGitPaths parses the "a/x b/y" part of a diff --git line. It only
+// handles unquoted paths; quoted ones are fixed up from the ---/+++ lines.
+func splitDiffGitPaths(s string) (a, b string, ok bool) {
+	if !strings.HasPrefix(s, "a/") {
+		return "", "", false
+	}
+	// With identical paths the line is "a/P b/P"; find the split that matches.
+	half := (len(s) - 1) / 2
+	if len(s)%2 == 1 && s[half] == ' ' && s[2:half] == s[half+3:] {
+		return s[2:half], s[half+3:], true
+	}
+	i := strings.Index(s, " b/")
+	if i < 0 {
+		return "", "", false
+	}
+	return s[2:i], s[i+3:], true
+}
+
+func hunkID(path string, h *GitDiffHunk) string {
+	sum := sha1.New()
+	sum.Write([]byte(path + "\x00" + h.Header + "\x00" + strings.Join(h.Lines, "\n")))
+	return hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+// gitDiffHunks returns the unstaged (or staged) diff of workDir as hunks.
+func gitDiffHunks(workDir string, staged bool, file string) ([]GitDiffFile, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if file != "" {
+		args = append(args, "--", file)
+	}
+	out, err := runGit(workDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseUnifiedDiff(out), nil
+}
+
+// buildHunkPatch returns a patch containing only the selected hunks. Every ID
+// must match a hunk in files.
+func buildHunkPatch(files []GitDiffFile, ids []string) (string, error) {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+
+	var patch strings.Builder
+	for _, f := range files {
+		var selected []GitDiffHunk
+		for _, h := range f.Hunks {
+			if want[h.ID] {
+				selected = append(selected, h)
+				delete(want, h.ID)
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+		for _, line := range f.header {
+			patch.WriteString(line + "\n")
+		}
+		for _, h := range selected {
+			patch.WriteString(h.Header + "\n")
+			for _, line := range h.Lines {
+				patch.WriteString(line + "\n")
+			}
+		}
+	}
+	if len(want) > 0 {
+		return "", errStaleHunks
+	}
+	return patch.String(), nil
+}
+
+var errStaleHunks = errors.New("some hunks no longer match the diff; reload and try again")
+
+// applyHunks stages, unstages or reverts the hunks with the given IDs.
+func applyHunks(workDir string, action hunkAction, ids []string) error {
+	staged := action == hunkUnstage
+	files, err := gitDiffHunks(workDir, staged, "")
+	if err != nil {
+		return err
+	}
+	patch, err := buildHunkPatch(files, ids)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"apply", "--whitespace=nowarn"}
+	switch action {
+	case hunkStage:
+		args = append(args, "--cached")
+	case hunkUnstage:
+		args = append(args, "--cached", "--reverse")
+	case hunkRevert:
+		args = append(args, "--reverse")
+	}
+	return runGitStdin(workDir, patch, args...)
+}
+
+// runGitStdin runs a git command that reads its input from stdin.
+func runGitStdin(dir, stdin string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %s: %w", args[0], strings.TrimSpace(out.String()), err)
+	}
+	return nil
+}
+
+// handleGitHunks serves GET .../git/hunks?staged=true&file=path.
+func (s *Server) handleGitHunks(resolve func(http.ResponseWriter, *http.Request) (string, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workDir, ok := resolve(w, r)
+		if !ok {
+			return
+		}
+		staged := r.URL.Query().Get("staged") == "true"
+		files, err := gitDiffHunks(workDir, staged, r.URL.Query().Get("file"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if files == nil {
+			files = []GitDiffFile{}
+		}
+		writeJSON(w, http.StatusOK, GitHunksResponse{Staged: staged, Files: files})
+	}
+}
+
+// handleGitHunkAction serves POST .../git/hunks/{stage,unstage,revert}.
+// Stage and revert take IDs from the unstaged diff, unstage from the staged one.
+func (s *Server) handleGitHunkAction(resolve func(http.ResponseWriter, *http.Request) (string, bool), action hunkAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workDir, ok := resolve(w, r)
+		if !ok {
+			return
+		}
+		var req GitHunksRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if len(req.Hunks) == 0 {
+			writeError(w, http.StatusBadRequest, "hunks is required")
+			return
+		}
+
+		if err := applyHunks(workDir, action, req.Hunks); err != nil {
+			if errors.Is(err, errStaleHunks) {
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/miguel-bm/codeburg/internal/db"
@@ -649,3 +651,495 @@ func TestGitStash_InvalidAction(t *testing.T) {
 		t.Errorf("unexpected error: %q", body["error"])
 	}
 }
+
+func TestParseUnifiedDiff(t *testing.T) {
+	out := `diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -1,3 +1,3 @@ package main
+ a
+-b
++B
+ c
+@@ -10 +10,2 @@ func x() {
+ j
++k
+diff --git a/old name.txt b/new name.txt
+similarity 90%
+rename from old name.txt
+rename to new name.txt
+diff --git a/img.png b/img.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/img.png differ
+`
+	files := parseUnifiedDiff(out)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+
+	app := files[0]
+	if app.Path != "app.go" || app.Status != "M" || len(app.Hunks) != 2 {
+		t.Fatalf("unexpected app.go entry: %+v", app)
+	}
+	h := app.Hunks[1]
+	if h.OldStart != 10 || h.OldLines != 1 || h.NewStart != 10 || h.NewLines != 2 || len(h.Lines) != 2 {
+		t.Errorf("unexpected second hunk: %+v", h)
+	}
+	if app.Hunks[0].ID == "" || app.Hunks[0].ID == h.ID {
+		t.Errorf("expected distinct hunk ids, got %q and %q", app.Hunks[0].ID, h.ID)
+	}
+
+	if files[1].Status != "R" || files[1].Path != "new name.txt" || files[1].OldPath != "old name.txt" {
+		t.Errorf("unexpected rename entry: %+v", files[1])
+	}
+	if files[2].Status != "A" || !files[2].Binary || len(files[2].Hunks) != 0 {
+		t.Errorf("unexpected binary entry: %+v", files[2])
+	}
+}
+
+func TestGitHunks_StageUnstageRevert(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	write := func() {
+		os.WriteFile(filepath.Join(repoPath, "file.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	}
+	write()
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "add file")
+
+	// Two changes far enough apart to form separate hunks.
+	lines[1] = "line 2 changed"
+	lines[17] = "line 18 changed"
+	write()
+
+	hunks := func(staged bool) GitHunksResponse {
+		t.Helper()
+		resp := env.get(fmt.Sprintf("/api/tasks/%s/git/hunks?staged=%t", taskID, staged))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("list hunks: %d %s", resp.Code, resp.Body.String())
+		}
+		var out GitHunksResponse
+		decodeResponse(t, resp, &out)
+		return out
+	}
+
+	unstaged := hunks(false)
+	if len(unstaged.Files) != 1 || len(unstaged.Files[0].Hunks) != 2 {
+		t.Fatalf("expected 1 file with 2 hunks, got %+v", unstaged.Files)
+	}
+	first, second := unstaged.Files[0].Hunks[0], unstaged.Files[0].Hunks[1]
+
+	// Stage only the second hunk.
+	resp := env.post("/api/tasks/"+taskID+"/git/hunks/stage", GitHunksRequest{Hunks: []string{second.ID}})
+	if resp.Code != http.StatusNoContent {
+		t.Fatalf("stage hunk: %d %s", resp.Code, resp.Body.String())
+	}
+	staged := hunks(true)
+	if len(staged.Files) != 1 || len(staged.Files[0].Hunks) != 1 || !strings.Contains(strings.Join(staged.Files[0].Hunks[0].Lines, "\n"), "line 18 changed") {
+		t.Fatalf("expected only the second hunk staged, got %+v", staged.Files)
+	}
+	if remaining := hunks(false); len(remaining.Files[0].Hunks) != 1 || remaining.Files[0].Hunks[0].ID != first.ID {
+		t.Fatalf("expected the first hunk to remain unstaged, got %+v", remaining.Files)
+	}
+
+	// A stale ID is rejected rather than applied.
+	resp = env.post("/api/tasks/"+taskID+"/git/hunks/stage", GitHunksRequest{Hunks: []string{second.ID}})
+	if resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for stale hunk, got %d", resp.Code)
+	}
+
+	// Unstage it again.
+	resp = env.post("/api/tasks/"+taskID+"/git/hunks/unstage", GitHunksRequest{Hunks: []string{staged.Files[0].Hunks[0].ID}})
+	if resp.Code != http.StatusNoContent {
+		t.Fatalf("unstage hunk: %d %s", resp.Code, resp.Body.String())
+	}
+	if len(hunks(true).Files) != 0 {
+		t.Fatal("expected nothing staged after unstage")
+	}
+
+	// Revert the first hunk in the working tree.
+	resp = env.post("/api/tasks/"+taskID+"/git/hunks/revert", GitHunksRequest{Hunks: []string{first.ID}})
+	if resp.Code != http.StatusNoContent {
+		t.Fatalf("revert hunk: %d %s", resp.Code, resp.Body.String())
+	}
+	content, _ := os.ReadFile(filepath.Join(repoPath, "file.txt"))
+	if strings.Contains(string(content), "line 2 changed") || !strings.Contains(string(content), "line 18 changed") {
+		t.Errorf("expected only the first hunk reverted, got:\n%s", content)
+	}
+}
+
+func TestGitCommit_AgentIdentity(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	task, _ := env.server.db.GetTask(taskID)
+	session, err := env.server.db.CreateSession(db.CreateSessionInput{
+		TaskID: taskID, ProjectID: task.ProjectID, Provider: "claude", SessionType: "chat",
+	})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	commit := func(name string) string {
+		t.Helper()
+		os.WriteFile(filepath.Join(repoPath, name), []byte("x"), 0644)
+		gitExecHelper(t, repoPath, "add", name)
+		resp := env.post("/api/tasks/"+taskID+"/git/commit", GitCommitRequest{Message: name, SessionID: session.ID})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("commit: %d %s", resp.Code, resp.Body.String())
+		}
+		out, err := runGit(repoPath, "log", "-1", "--format=%an <%ae>|%cn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(out)
+	}
+
+	// Without an agent identity the user's git config is used.
+	if got := commit("a.txt"); got != "Test <test@test.com>|Test" {
+		t.Errorf("unexpected author without agent identity: %q", got)
+	}
+
+	resp := env.patch("/api/projects/"+task.ProjectID, map[string]any{
+		"agentIdentity": map[string]any{"enabled": true},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update project: %d %s", resp.Code, resp.Body.String())
+	}
+	if got := commit("b.txt"); got != "Claude via Codeburg <agent@codeburg>|Test" {
+		t.Errorf("unexpected author with agent identity: %q", got)
+	}
+
+	project, err := env.server.db.GetProject(task.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env := agentGitEnv(project, "terminal"); env != nil {
+		t.Errorf("expected no agent env for plain terminals, got %v", env)
+	}
+	if env := agentGitEnv(project, "codex"); len(env) != 2 || env[0] != "GIT_AUTHOR_NAME=Codex via Codeburg" {
+		t.Errorf("unexpected agent env: %v", env)
+	}
+}
+
+func TestStructuredDiff(t *testing.T) {
+	out := `diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -1,4 +1,4 @@ package main
+ a
+-func old(x int) error {
+-removed line
++func renamed(x int) error {
+ c
+\ No newline at end of file
+diff --git a/old.txt b/new.txt
+similarity index 90%
+rename from old.txt
+rename to new.txt
+diff --git a/img.png b/img.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/img.png differ
+`
+	files := structuredDiff(out, false)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+
+	app := files[0]
+	if app.Additions != 1 || app.Deletions != 2 || len(app.Hunks) != 1 {
+		t.Fatalf("unexpected app.go entry: %+v", app)
+	}
+	h := app.Hunks[0]
+	if h.Section != "package main" || len(h.Lines) != 5 {
+		t.Fatalf("unexpected hunk: %+v", h)
+	}
+	del, add, last := h.Lines[1], h.Lines[3], h.Lines[4]
+	if del.Type != "delete" || del.OldLine != 2 || add.Type != "add" || add.NewLine != 2 {
+		t.Errorf("unexpected line numbers: %+v %+v", del, add)
+	}
+	if len(del.Changes) != 1 || del.Text[del.Changes[0].Start:del.Changes[0].End] != "old" {
+		t.Errorf("expected the deleted word marked, got %+v", del.Changes)
+	}
+	if len(add.Changes) != 1 || add.Text[add.Changes[0].Start:add.Changes[0].End] != "renamed" {
+		t.Errorf("expected the added word marked, got %+v", add.Changes)
+	}
+	if h.Lines[2].Changes != nil {
+		t.Errorf("expected an unpaired delete without ranges, got %+v", h.Lines[2].Changes)
+	}
+	if last.Type != "context" || last.OldLine != 4 || last.NewLine != 3 || !last.NoNewline {
+		t.Errorf("unexpected last line: %+v", last)
+	}
+
+	if files[1].Status != "R" || files[1].OldPath != "old.txt" || files[1].Path != "new.txt" || files[1].Similarity != 90 {
+		t.Errorf("unexpected rename entry: %+v", files[1])
+	}
+	if !files[2].Binary || files[2].Status != "A" || files[2].NewMode != "100644" {
+		t.Errorf("unexpected binary entry: %+v", files[2])
+	}
+
+	split := structuredDiff(out, true)[0].Hunks[0]
+	if split.Lines != nil || len(split.Rows) != 4 {
+		t.Fatalf("expected 4 side-by-side rows, got %+v", split.Rows)
+	}
+	if row := split.Rows[1]; row.Old == nil || row.New == nil || row.New.Text != "func renamed(x int) error {" {
+		t.Errorf("expected the paired lines on one row, got %+v", row)
+	}
+	if row := split.Rows[2]; row.Old == nil || row.New != nil {
+		t.Errorf("expected the unpaired delete alone, got %+v", row)
+	}
+}
+
+func TestWordRanges(t *testing.T) {
+	oldRanges, newRanges := wordRanges("héllo wörld", "héllo there")
+	if len(oldRanges) != 1 || oldRanges[0] != (GitDiffRange{Start: 6, End: 11}) {
+		t.Errorf("unexpected old ranges (UTF-16 offsets): %+v", oldRanges)
+	}
+	if len(newRanges) != 1 || newRanges[0] != (GitDiffRange{Start: 6, End: 11}) {
+		t.Errorf("unexpected new ranges: %+v", newRanges)
+	}
+
+	// Lines with little in common changed as a whole.
+	if o, n := wordRanges("completely different", "nothing alike here"); o != nil || n != nil {
+		t.Errorf("expected no ranges, got %+v %+v", o, n)
+	}
+}
+
+func TestGitStructuredDiff(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("# Test project\n"), 0644)
+	os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("one\ntwo\nthree\nfour\nfive\n"), 0644)
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "notes")
+	gitExecHelper(t, repoPath, "mv", "notes.txt", "moved.txt")
+	os.WriteFile(filepath.Join(repoPath, "logo.bin"), []byte{0, 1, 2, 0, 3}, 0644)
+	gitExecHelper(t, repoPath, "add", ".")
+
+	resp := env.get("/api/tasks/" + taskID + "/git/diff/structured?staged=true")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var diff GitStructuredDiffResponse
+	decodeResponse(t, resp, &diff)
+	byPath := map[string]GitStructuredFile{}
+	for _, f := range diff.Files {
+		byPath[f.Path] = f
+	}
+	if f := byPath["moved.txt"]; f.Status != "R" || f.OldPath != "notes.txt" || f.Similarity != 100 {
+		t.Errorf("expected a detected rename, got %+v", f)
+	}
+	if f := byPath["logo.bin"]; !f.Binary {
+		t.Errorf("expected a binary marker, got %+v", f)
+	}
+
+	resp = env.get("/api/tasks/" + taskID + "/git/diff/structured?commit=HEAD&view=split")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, resp, &diff)
+	var readme *GitStructuredFile
+	for i := range diff.Files {
+		if diff.Files[i].Path == "README.md" {
+			readme = &diff.Files[i]
+		}
+	}
+	if readme == nil || len(readme.Hunks) != 1 || len(readme.Hunks[0].Rows) != 1 {
+		t.Fatalf("expected one side-by-side row for README.md, got %+v", readme)
+	}
+	if row := readme.Hunks[0].Rows[0]; row.New == nil || len(row.New.Changes) != 1 {
+		t.Errorf("expected the added word marked, got %+v", row)
+	}
+
+	if resp := env.get("/api/tasks/" + taskID + "/git/diff/structured?view=stacked"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown view, got %d", resp.Code)
+	}
+}
+
+func TestGitBlameAndFileLog(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("one\ntwo\n"), 0644)
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "add notes")
+	gitExecHelper(t, repoPath, "mv", "notes.txt", "docs.txt")
+	os.WriteFile(filepath.Join(repoPath, "docs.txt"), []byte("one\nTWO\n"), 0644)
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "rename notes", "-m", "and shout")
+	os.WriteFile(filepath.Join(repoPath, "docs.txt"), []byte("one\nTWO\nthree\n"), 0644)
+
+	resp := env.get("/api/tasks/" + taskID + "/git/blame?file=docs.txt")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var blame GitBlameResponse
+	decodeResponse(t, resp, &blame)
+	if len(blame.Lines) != 3 || blame.Lines[0].Content != "one" || blame.Lines[2].Line != 3 {
+		t.Fatalf("unexpected lines: %+v", blame.Lines)
+	}
+	first, second, third := blame.Commits[blame.Lines[0].Hash], blame.Commits[blame.Lines[1].Hash], blame.Commits[blame.Lines[2].Hash]
+	if first.Summary != "add notes" || first.Path != "notes.txt" || first.Author == "" || first.Date == "" {
+		t.Errorf("expected the first line from the original commit, got %+v", first)
+	}
+	if second.Summary != "rename notes" || second.Path != "docs.txt" {
+		t.Errorf("expected the second line from the rename commit, got %+v", second)
+	}
+	if !third.Uncommitted {
+		t.Errorf("expected the working tree line uncommitted, got %+v", third)
+	}
+
+	var ranged GitBlameResponse
+	decodeResponse(t, env.get("/api/tasks/"+taskID+"/git/blame?file=docs.txt&rev=HEAD&start=2&end=2"), &ranged)
+	if len(ranged.Lines) != 1 || ranged.Lines[0].Content != "TWO" || len(ranged.Commits) != 1 {
+		t.Fatalf("unexpected ranged blame: %+v", ranged)
+	}
+
+	resp = env.get("/api/tasks/" + taskID + "/git/file-log?file=docs.txt")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var history GitFileLogResponse
+	decodeResponse(t, resp, &history)
+	if len(history.Commits) != 2 {
+		t.Fatalf("expected 2 commits across the rename, got %+v", history.Commits)
+	}
+	if c := history.Commits[0]; c.Message != "rename notes" || c.Body != "and shout" || c.Path != "docs.txt" || c.Additions != 1 || c.Deletions != 1 {
+		t.Errorf("unexpected newest commit: %+v", c)
+	}
+	if c := history.Commits[1]; c.Message != "add notes" || c.Path != "notes.txt" || c.Additions != 2 {
+		t.Errorf("unexpected oldest commit: %+v", c)
+	}
+
+	for _, query := range []string{"", "file=../x", "file=docs.txt&rev=--output=x", "file=docs.txt&start=3&end=1", "file=missing.txt"} {
+		if resp := env.get("/api/tasks/" + taskID + "/git/blame?" + query); resp.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, resp.Code)
+		}
+	}
+}
+
+func TestNumstatNewPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"a.go":                   "a.go",
+		"old.go => new.go":       "new.go",
+		"src/{old.go => new.go}": "src/new.go",
+		"src/{a => b}/main.go":   "src/b/main.go",
+		"src/{ => lib}/main.go":  "src/lib/main.go",
+		"src/{lib => }/main.go":  "src/main.go",
+	} {
+		if got := numstatNewPath(in); got != want {
+			t.Errorf("numstatNewPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGitRenameBranch(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+	gitExecHelper(t, repoPath, "checkout", "-b", "feature")
+
+	resp := env.post("/api/tasks/"+taskID+"/git/branch/rename", GitRenameBranchRequest{Name: "bad..name"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid name, got %d", resp.Code)
+	}
+	resp = env.post("/api/tasks/"+taskID+"/git/branch/rename", GitRenameBranchRequest{Name: "main"})
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for an existing branch, got %d", resp.Code)
+	}
+
+	resp = env.post("/api/tasks/"+taskID+"/git/branch/rename", GitRenameBranchRequest{Name: "fix/login"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("rename: expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if current := strings.TrimSpace(runGitCmd(t, repoPath, "branch", "--show-current")); current != "fix/login" {
+		t.Errorf("expected the worktree on fix/login, got %q", current)
+	}
+	task, _ := env.server.db.GetTask(taskID)
+	if ptrToString(task.Branch) != "fix/login" {
+		t.Errorf("expected the task's branch updated, got %v", task.Branch)
+	}
+
+	prURL := "https://github.com/o/r/pull/1"
+	env.server.db.UpdateTask(taskID, db.UpdateTaskInput{PRURL: &prURL})
+	resp = env.post("/api/tasks/"+taskID+"/git/branch/rename", GitRenameBranchRequest{Name: "fix/other"})
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a task with a PR, got %d", resp.Code)
+	}
+}
+
+func TestGitRetarget(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	gitExecHelper(t, repoPath, "checkout", "-b", "release")
+	os.WriteFile(filepath.Join(repoPath, "RELEASE.md"), []byte("v1\n"), 0644)
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "release notes")
+	gitExecHelper(t, repoPath, "checkout", "-b", "feature", "main")
+	os.WriteFile(filepath.Join(repoPath, "feature.txt"), []byte("feature\n"), 0644)
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "feature")
+	os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("# Work in progress\n"), 0644)
+
+	resp := env.post("/api/tasks/"+taskID+"/git/retarget", GitRetargetRequest{BaseBranch: "missing"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a missing branch, got %d", resp.Code)
+	}
+
+	resp = env.post("/api/tasks/"+taskID+"/git/retarget", GitRetargetRequest{BaseBranch: "release"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("retarget: expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var result GitRetargetResponse
+	decodeResponse(t, resp, &result)
+	if result.Onto != "release" || len(result.Warnings) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if log := strings.TrimSpace(runGitCmd(t, repoPath, "log", "--format=%s", "main..HEAD")); log != "feature\nrelease notes" {
+		t.Errorf("expected the task's commit replayed onto release, got %q", log)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repoPath, "README.md")); string(data) != "# Work in progress\n" {
+		t.Errorf("expected uncommitted changes kept, got %q", data)
+	}
+	task, _ := env.server.db.GetTask(taskID)
+	if ptrToString(task.BaseBranch) != "release" || env.server.taskBaseBranch(taskID) != "release" {
+		t.Errorf("expected release stored as the base, got %v", task.BaseBranch)
+	}
+
+	// Diffs against the base now leave release's own commits out.
+	diff := env.get("/api/tasks/" + taskID + "/git/diff?base=true")
+	var diffResp GitDiffResponse
+	decodeResponse(t, diff, &diffResp)
+	if strings.Contains(diffResp.Diff, "RELEASE.md") || !strings.Contains(diffResp.Diff, "feature.txt") {
+		t.Errorf("expected the base diff against release, got %q", diffResp.Diff)
+	}
+
+	// Back onto the default branch, which is stored as no base.
+	resp = env.post("/api/tasks/"+taskID+"/git/retarget", GitRetargetRequest{BaseBranch: "main"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("retarget to main: expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if log := strings.TrimSpace(runGitCmd(t, repoPath, "log", "--format=%s", "main..HEAD")); log != "feature" {
+		t.Errorf("expected only the task's commit on top of main, got %q", log)
+	}
+	if task, _ := env.server.db.GetTask(taskID); task.BaseBranch != nil {
+		t.Errorf("expected the base cleared, got %q", *task.BaseBranch)
+	}
+}
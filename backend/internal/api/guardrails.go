@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+)
+
+// Projects can set guardrails on how much agents change a task's branch
+// (see db.AgentGuardrails). After each chat turn the worktree is measured
+// against the merge base with the task's base branch, uncommitted and
+// untracked files included. A session past a guardrail is held in
+// waiting_input with a warning in its conversation, and the user is
+// notified, so the agent only goes on once someone has looked.
+
+// maxGuardrailCountBytes bounds the untracked files whose lines are
+// counted; bigger ones count as changed files only.
+const maxGuardrailCountBytes = 1 << 20
+
+// guardrailReport is how a worktree measures against its project's
+// guardrails.
+type guardrailReport struct {
+	FilesChanged   int      `json:"filesChanged"`
+	LinesChanged   int      `json:"linesChanged"`
+	MaxFiles       int      `json:"maxFilesChanged,omitempty"`
+	MaxLines       int      `json:"maxLinesChanged,omitempty"`
+	ForbiddenPaths []string `json:"forbiddenPaths,omitempty"` // changed paths the guardrails forbid
+}
+
+func (r *guardrailReport) filesExceeded() bool {
+	return r.MaxFiles > 0 && r.FilesChanged > r.MaxFiles
+}
+
+func (r *guardrailReport) linesExceeded() bool {
+	return r.MaxLines > 0 && r.LinesChanged > r.MaxLines
+}
+
+func (r *guardrailReport) violated() bool {
+	return r.filesExceeded() || r.linesExceeded() || len(r.ForbiddenPaths) > 0
+}
+
+// reasons lists what went past the guardrails, in lang.
+func (r *guardrailReport) reasons(lang string) []string {
+	var out []string
+	if r.filesExceeded() {
+		out = append(out, localize(lang, msgGuardrailFiles, r.FilesChanged, r.MaxFiles))
+	}
+	if r.linesExceeded() {
+		out = append(out, localize(lang, msgGuardrailLines, r.LinesChanged, r.MaxLines))
+	}
+	if len(r.ForbiddenPaths) > 0 {
+		paths := r.ForbiddenPaths
+		if len(paths) > 5 {
+			paths = append(paths[:5:5], fmt.Sprintf("+%d", len(r.ForbiddenPaths)-5))
+		}
+		out = append(out, localize(lang, msgGuardrailPaths, strings.Join(paths, ", ")))
+	}
+	return out
+}
+
+// worktreeChanges returns the paths changed in workDir since its merge base
+// with baseBranch, and the lines added plus deleted. Binary files count as
+// changed files without lines.
+func worktreeChanges(workDir, baseBranch string) ([]string, int, error) {
+	mergeBase, err := runGit(workDir, "merge-base", baseBranch, "HEAD")
+	if err != nil {
+		return nil, 0, err
+	}
+	out, err := runGit(workDir, "diff", "--numstat", "-z", "--no-renames", strings.TrimSpace(mergeBase))
+	if err != nil {
+		return nil, 0, err
+	}
+	var paths []string
+	lines := 0
+	for _, entry := range strings.Split(out, "\x00") {
+		fields := strings.SplitN(entry, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0]) // "-" for binary files
+		deleted, _ := strconv.Atoi(fields[1])
+		lines += added + deleted
+		paths = append(paths, fields[2])
+	}
+
+	untracked, err := runGit(workDir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if path == "" {
+			continue
+		}
+		paths = append(paths, path)
+		lines += countFileLines(filepath.Join(workDir, path))
+	}
+	return paths, lines, nil
+}
+
+// countFileLines counts the lines of a text file, as git diff would for a
+// new file; binary and very large files count none.
+func countFileLines(path string) int {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxGuardrailCountBytes {
+		return 0
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 || bytes.IndexByte(data, 0) >= 0 {
+		return 0
+	}
+	n := bytes.Count(data, []byte("\n"))
+	if data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// checkGuardrails measures a chat session's worktree against its project's
+// guardrails. It returns nil when the session has nothing to check (no
+// task, no guardrails, a read-only session) or the check fails.
+func (s *Server) checkGuardrails(session *db.AgentSession) *guardrailReport {
+	if session.TaskID == "" || session.ReadOnly {
+		return nil
+	}
+	project, err := s.db.GetProject(session.ProjectID)
+	if err != nil || project.Protection == nil || project.Protection.Guardrails == nil {
+		return nil
+	}
+	guardrails := project.Protection.Guardrails
+	if guardrails.MaxFilesChanged == 0 && guardrails.MaxLinesChanged == 0 && len(guardrails.ForbiddenPaths) == 0 {
+		return nil
+	}
+	task, err := s.db.GetTask(session.TaskID)
+	if err != nil {
+		return nil
+	}
+	workDir, err := s.resolveSessionWorkDir(session)
+	if err != nil {
+		return nil
+	}
+
+	paths, lines, err := worktreeChanges(workDir, baseBranchFor(task, project))
+	if err != nil {
+		slog.Warn("failed to check guardrails", "session_id", session.ID, "error", err)
+		return nil
+	}
+	report := &guardrailReport{
+		FilesChanged: len(paths),
+		LinesChanged: lines,
+		MaxFiles:     guardrails.MaxFilesChanged,
+		MaxLines:     guardrails.MaxLinesChanged,
+	}
+	for _, path := range paths {
+		if matchesAnyGlob(guardrails.ForbiddenPaths, filepath.ToSlash(path)) {
+			report.ForbiddenPaths = append(report.ForbiddenPaths, path)
+		}
+	}
+	return report
+}
+
+// warnGuardrails tells a session's conversation and the user's
+// notification channels that the session went past its guardrails.
+func (s *Server) warnGuardrails(session *db.AgentSession, report *guardrailReport) {
+	slog.Info("session went past its guardrails", "session_id", session.ID, "task_id", session.TaskID,
+		"files", report.FilesChanged, "lines", report.LinesChanged, "forbidden", len(report.ForbiddenPaths))
+
+	text := "Guardrails reached: " + strings.Join(report.reasons(""), "; ") + ". Review the changes, then send a message to let the agent go on."
+	data := map[string]any{"type": "guardrail", "guardrails": report}
+	if err := s.chat.AppendSystemMessage(session.ID, text, data); err != nil {
+		slog.Warn("failed to add guardrail warning", "session_id", session.ID, "error", err)
+	}
+
+	sinks := s.notificationSinks(eventAttention)
+	if len(sinks) == 0 {
+		return
+	}
+	// Name the session as the attention notification does.
+	var names []string
+	if task, err := s.db.GetTask(session.TaskID); err == nil {
+		names = append(names, task.Title)
+	}
+	if session.Title != "" {
+		names = append(names, session.Title)
+	}
+	name := strings.Join(names, " · ")
+	url := s.deepLink(sessionPath(session.TaskID, session.ID))
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		return notify.Message{
+			Title:     localize(lang, msgGuardrailTitle, name),
+			Body:      strings.Join(report.reasons(lang), "\n") + "\n" + localize(lang, msgGuardrailBody),
+			URL:       url,
+			SessionID: session.ID,
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestGuardrails(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	installFakeProviders(t, nil)
+	taskID, repoPath := createTaskWithWorktree(t, env)
+	task, _ := env.server.db.GetTask(taskID)
+
+	resp := env.patch("/api/projects/"+task.ProjectID, db.UpdateProjectInput{
+		Protection: &db.ProtectionPolicy{Guardrails: &db.AgentGuardrails{MaxFilesChanged: -1}},
+	})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected a negative limit rejected, got %d", resp.Code)
+	}
+	resp = env.patch("/api/projects/"+task.ProjectID, db.UpdateProjectInput{
+		Protection: &db.ProtectionPolicy{Guardrails: &db.AgentGuardrails{MaxLinesChanged: 5, ForbiddenPaths: []string{"deploy/"}}},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update project: %d %s", resp.Code, resp.Body.String())
+	}
+
+	received := make(chan map[string]any, 4)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+
+	guardrailWarnings := func(sessionID string) []string {
+		var out []string
+		for _, msg := range env.chatMessages(t, sessionID) {
+			if msg.Kind == ChatMessageKindSystem && msg.Data["type"] == "guardrail" {
+				out = append(out, msg.Text)
+			}
+		}
+		return out
+	}
+
+	// Within the guardrails, a turn ends as usual.
+	resp = env.post("/api/tasks/"+taskID+"/sessions", map[string]string{
+		"provider": "claude", "sessionType": "chat", "prompt": "hello",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("start session: %d %s", resp.Code, resp.Body.String())
+	}
+	var session db.AgentSession
+	decodeResponse(t, resp, &session)
+	env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+	if warnings := guardrailWarnings(session.ID); len(warnings) != 0 {
+		t.Fatalf("expected no guardrail warning, got %q", warnings)
+	}
+	select {
+	case body := <-received:
+		if body["title"] != "Git Test Task · hello needs attention" {
+			t.Errorf("unexpected notification: %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no attention notification")
+	}
+
+	// The agent writes 8 lines, 2 of them under a forbidden path.
+	os.WriteFile(filepath.Join(repoPath, "notes.txt"), []byte("1\n2\n3\n4\n5\n6\n"), 0o644)
+	os.MkdirAll(filepath.Join(repoPath, "deploy"), 0o755)
+	os.WriteFile(filepath.Join(repoPath, "deploy", "prod.yaml"), []byte("replicas: 3\nimage: app"), 0o644)
+	if resp := env.post("/api/sessions/"+session.ID+"/message", map[string]string{"content": "go on"}); resp.Code != http.StatusOK {
+		t.Fatalf("send message: %d %s", resp.Code, resp.Body.String())
+	}
+
+	var warnings []string
+	waitForCondition(t, 10*time.Second, func() bool {
+		warnings = guardrailWarnings(session.ID)
+		return len(warnings) > 0
+	}, "guardrail warning")
+	env.waitForSessionStatus(t, session.ID, db.SessionStatusWaitingInput)
+	want := "Guardrails reached: 8 lines changed (limit 5); Forbidden paths changed: deploy/prod.yaml."
+	if !strings.HasPrefix(warnings[0], want) {
+		t.Errorf("expected the warning to start with %q, got %q", want, warnings[0])
+	}
+
+	// The guardrail notification replaces the usual one.
+	var titles []string
+	timeout := time.After(2 * time.Second)
+collect:
+	for {
+		select {
+		case body := <-received:
+			titles = append(titles, body["title"].(string))
+			if body["title"] == "🚧 Git Test Task · hello went past its guardrails" &&
+				!strings.Contains(body["message"].(string), "8 lines changed (limit 5)") {
+				t.Errorf("unexpected guardrail notification: %v", body)
+			}
+		case <-timeout:
+			break collect
+		}
+	}
+	if !slices.Equal(titles, []string{"🚧 Git Test Task · hello went past its guardrails"}) {
+		t.Errorf("expected one guardrail notification, got %q", titles)
+	}
+}
@@ -54,7 +54,7 @@ func (s *Server) handleSessionHook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	token := strings.TrimPrefix(auth, "Bearer ")
-	if !s.auth.ValidateHookToken(token, sessionID) && !s.auth.ValidateToken(token) {
+	if !s.auth.ValidateHookToken(token, sessionID) && !s.validLoginToken(token) {
 		writeError(w, http.StatusUnauthorized, "invalid token")
 		return
 	}
@@ -136,6 +136,9 @@ func (s *Server) handleSessionHook(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
+	if transitionEvent == sessionlifecycle.EventStopHookWaiting || transitionEvent == sessionlifecycle.EventAgentTurnComplete {
+		s.activity.recordTurn(session)
+	}
 
 	// Capture provider session ID if present
 	if payload.SessionID != "" {
@@ -171,9 +174,9 @@ func (s *Server) handleSessionHook(w http.ResponseWriter, r *http.Request) {
 	// Broadcast status transition
 	s.broadcastSessionStatus(session.TaskID, sessionID, newStatus)
 
-	// Invalidate diff stats cache when agent finishes work
+	// Push the agent's changes when it finishes work
 	if newStatus == db.SessionStatusWaitingInput || newStatus == db.SessionStatusCompleted {
-		s.diffStatsCache.Delete(session.TaskID)
+		s.wakeDiffStats()
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,565 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/gitclone"
+)
+
+// Background jobs run the operations that take too long for a request or
+// must survive a restart: clones and unshallowing them, creating task
+// worktrees and pull requests, removing a deleted project's pooled
+// worktrees, bringing up devcontainers, tearing down task provisions,
+// sending the digest and pruning chat transcripts. Jobs are
+// queued in the database and run by jobWorkers workers; a job that fails is
+// retried on jobRetryDelays until it has used its attempts, and jobs a
+// restart interrupted are queued again. Pending and running jobs can be
+// cancelled. Every change, including progress, is broadcast as job_updated.
+// Finished jobs are kept for jobRetention.
+const (
+	jobKindClone           = "project.clone"
+	jobKindPoolRemoval     = "worktree_pool.remove"
+	jobKindTeardown        = "provision.teardown"
+	jobKindDigest          = "digest"
+	jobKindUnshallow       = "project.unshallow"
+	jobKindDevcontainer    = "devcontainer.up"
+	jobKindTranscriptPrune = "transcripts.prune"
+	jobKindWorktreeCreate  = "worktree.create"
+	jobKindCreatePR        = "pull_request.create"
+
+	jobWorkers      = 2
+	jobPollInterval = 5 * time.Second
+	jobBatchSize    = 20
+	jobRetention    = 7 * 24 * time.Hour
+
+	gitProgressInterval = 500 * time.Millisecond
+)
+
+// jobRetryDelays are the waits before each retry of a failed job.
+var jobRetryDelays = []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute, 30 * time.Minute}
+
+// jobHandler runs one attempt of a job, reporting what it is doing through
+// progress. What it returns is stored as the job's result.
+type jobHandler func(ctx context.Context, job *db.Job, progress func(string)) (any, error)
+
+func (s *Server) jobHandler(kind string) jobHandler {
+	switch kind {
+	case jobKindClone:
+		return s.runCloneJob
+	case jobKindPoolRemoval:
+		return s.runPoolRemovalJob
+	case jobKindTeardown:
+		return s.runTeardownJob
+	case jobKindDigest:
+		return s.runDigestJob
+	case jobKindUnshallow:
+		return s.runUnshallowJob
+	case jobKindDevcontainer:
+		return s.runDevcontainerJob
+	case jobKindTranscriptPrune:
+		return s.runTranscriptPruneJob
+	case jobKindWorktreeCreate:
+		return s.runWorktreeJob
+	case jobKindCreatePR:
+		return s.runCreatePRJob
+	}
+	return nil
+}
+
+// enqueueJob queues a job of kind with payload encoded as JSON, trying it
+// up to maxAttempts times.
+func (s *Server) enqueueJob(kind, projectID string, payload any, maxAttempts int) (*db.Job, error) {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("encode job payload: %w", err)
+		}
+	}
+	input := db.CreateJobInput{Kind: kind, Payload: data, MaxAttempts: maxAttempts}
+	if projectID != "" {
+		input.ProjectID = &projectID
+	}
+	job, err := s.db.CreateJob(input)
+	if err != nil {
+		return nil, err
+	}
+	s.wsHub.BroadcastGlobal("job_updated", job)
+	s.wakeJobs()
+	return job, nil
+}
+
+// wakeJobs has the job loop look for due jobs now.
+func (s *Server) wakeJobs() {
+	select {
+	case s.jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// runJobs hands due jobs to the workers until ctx is cancelled.
+func (s *Server) runJobs(ctx context.Context) {
+	if n, err := s.db.RequeueRunningJobs(); err != nil {
+		slog.Warn("failed to requeue interrupted jobs", "error", err)
+	} else if n > 0 {
+		slog.Info("requeued interrupted jobs", "count", n)
+	}
+
+	workers := make(chan struct{}, jobWorkers)
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		s.startDueJobs(ctx, workers)
+		if time.Since(pruned) >= time.Hour {
+			if _, err := s.db.PruneJobs(time.Now().Add(-jobRetention)); err != nil {
+				slog.Warn("failed to prune jobs", "error", err)
+			}
+			pruned = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.jobWake:
+		}
+	}
+}
+
+func (s *Server) startDueJobs(ctx context.Context, workers chan struct{}) {
+	due, err := s.db.ListDueJobs(time.Now(), jobBatchSize)
+	if err != nil {
+		slog.Warn("failed to list due jobs", "error", err)
+		return
+	}
+	for _, job := range due {
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		job, err := s.db.StartJob(job.ID)
+		if err != nil {
+			<-workers
+			continue
+		}
+		s.wsHub.BroadcastGlobal("job_updated", job)
+		s.bgWG.Add(1)
+		go func() {
+			defer s.bgWG.Done()
+			defer func() { <-workers }()
+			s.runJob(ctx, job)
+		}()
+	}
+}
+
+// runJobNow starts a pending job outside the workers. It does nothing when
+// a worker got to it first.
+func (s *Server) runJobNow(job *db.Job) {
+	started, err := s.db.StartJob(job.ID)
+	if err != nil {
+		return
+	}
+	s.wsHub.BroadcastGlobal("job_updated", started)
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runJob(s.bgCtx, started)
+	}()
+}
+
+// runJob runs an attempt of a started job and records the outcome,
+// scheduling the next retry when it fails.
+func (s *Server) runJob(ctx context.Context, job *db.Job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.jobs.started(job.ID, cancel)
+
+	var result any
+	var err error
+	if handler := s.jobHandler(job.Kind); handler == nil {
+		err = fmt.Errorf("unknown job kind %q", job.Kind)
+	} else {
+		progress := func(text string) {
+			if err := s.db.SetJobProgress(job.ID, text); err != nil {
+				return
+			}
+			if updated, err := s.db.GetJob(job.ID); err == nil {
+				s.wsHub.BroadcastGlobal("job_updated", updated)
+			}
+		}
+		result, err = handler(jobCtx, job, progress)
+	}
+	if ctx.Err() != nil {
+		s.jobs.forget(job.ID)
+		return // stopped by shutdown; requeued on the next start
+	}
+
+	var data []byte
+	var retryAt *time.Time
+	if err == nil && result != nil {
+		data, _ = json.Marshal(result)
+	}
+	if err != nil && job.Attempts < job.MaxAttempts && jobCtx.Err() == nil {
+		next := time.Now().Add(jobRetryDelays[min(job.Attempts-1, len(jobRetryDelays)-1)])
+		retryAt = &next
+	}
+	if err != nil && jobCtx.Err() == nil {
+		slog.Warn("job failed", "job_id", job.ID, "kind", job.Kind, "attempt", job.Attempts, "retry", retryAt != nil, "error", err)
+	}
+	finished, ferr := s.db.FinishJob(job.ID, data, err, retryAt)
+	if ferr != nil {
+		slog.Warn("failed to record job outcome", "job_id", job.ID, "error", ferr)
+		s.jobs.forget(job.ID)
+		return
+	}
+	s.jobs.finished(finished)
+	s.wsHub.BroadcastGlobal("job_updated", finished)
+}
+
+// jobDone reports whether a job has finished for good.
+func jobDone(job *db.Job) bool {
+	return job.Status == db.JobSucceeded || job.Status == db.JobFailed || job.Status == db.JobCancelled
+}
+
+// jobRegistry tracks the jobs this process is running, to cancel them, and
+// the requests waiting for jobs to finish. The zero value is ready to use.
+type jobRegistry struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	waiters map[string][]chan *db.Job
+}
+
+func (r *jobRegistry) started(id string, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancels == nil {
+		r.cancels = make(map[string]context.CancelFunc)
+	}
+	r.cancels[id] = cancel
+}
+
+// cancel stops a job if this process is running it.
+func (r *jobRegistry) cancel(id string) {
+	r.mu.Lock()
+	cancel := r.cancels[id]
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (r *jobRegistry) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, id)
+}
+
+// finished forgets a job that stopped running and, once it is done for
+// good, hands it to whoever waits for it.
+func (r *jobRegistry) finished(job *db.Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, job.ID)
+	if !jobDone(job) {
+		return
+	}
+	for _, ch := range r.waiters[job.ID] {
+		ch <- job
+	}
+	delete(r.waiters, job.ID)
+}
+
+func (r *jobRegistry) wait(id string) <-chan *db.Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.waiters == nil {
+		r.waiters = make(map[string][]chan *db.Job)
+	}
+	ch := make(chan *db.Job, 1)
+	r.waiters[id] = append(r.waiters[id], ch)
+	return ch
+}
+
+// waitForJob returns a job once it is done for good, or ctx's error.
+func (s *Server) waitForJob(ctx context.Context, id string) (*db.Job, error) {
+	ch := s.jobs.wait(id)
+	// It may have finished before the wait began.
+	job, err := s.db.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if jobDone(job) {
+		return job, nil
+	}
+	select {
+	case job := <-ch:
+		return job, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// respondWithJob queues a job for a request. With ?wait=false the reply is
+// the job (202), for a worker to run; otherwise the job starts right away,
+// since someone waits on it, and the reply is its result with status once
+// it has finished, or its error. A client that goes away leaves the job to
+// finish on its own. These jobs are tried once: the user is there to try
+// again.
+func (s *Server) respondWithJob(w http.ResponseWriter, r *http.Request, kind, projectID string, payload any, status int) {
+	job, err := s.enqueueJob(kind, projectID, payload, 1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue job")
+		return
+	}
+	if r.URL.Query().Get("wait") == "false" {
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+	s.runJobNow(job)
+	if job, err = s.waitForJob(r.Context(), job.ID); err != nil {
+		if r.Context().Err() == nil {
+			writeError(w, http.StatusInternalServerError, "failed to wait for job")
+		}
+		return
+	}
+	switch job.Status {
+	case db.JobSucceeded:
+		writeJSON(w, status, job.Result)
+	case db.JobCancelled:
+		writeError(w, http.StatusConflict, "job was cancelled")
+	default:
+		writeError(w, http.StatusInternalServerError, "failed to "+job.LastError)
+	}
+}
+
+// taskJobPayload names the task a job works on.
+type taskJobPayload struct {
+	TaskID string `json:"taskId"`
+}
+
+// cloneJobPayload is a create-project request cloned in a job.
+type cloneJobPayload struct {
+	Request createProjectRequest `json:"request"`
+	Name    string               `json:"name"`
+}
+
+// runCloneJob clones a remote and creates its project, which is the job's
+// result.
+func (s *Server) runCloneJob(ctx context.Context, job *db.Job, progress func(string)) (any, error) {
+	var payload cloneJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	remote, ok := s.gitclone.ParseRemote(payload.Request.GitHubURL)
+	if !ok {
+		return nil, errors.New("unsupported git URL")
+	}
+	progress("Cloning " + payload.Request.GitHubURL)
+	input, err := s.cloneProject(ctx, payload.Request, remote, payload.Name, gitProgress(progress))
+	if err != nil {
+		return nil, fmt.Errorf("clone failed: %w", err)
+	}
+	project, err := s.db.CreateProject(input)
+	if err != nil {
+		return nil, fmt.Errorf("create project: %w", err)
+	}
+	s.wsHub.BroadcastToProject(project.ID, "project_created", project)
+	return project, nil
+}
+
+// runUnshallowJob fetches what a project's partial clone left out. Its
+// result is the clone's shape afterwards.
+func (s *Server) runUnshallowJob(ctx context.Context, job *db.Job, progress func(string)) (any, error) {
+	project, err := s.db.GetProject(ptrToString(job.ProjectID))
+	if err != nil {
+		return nil, err
+	}
+	var opts gitclone.CloneOptions
+	if remote, ok := s.projectRemote(project); ok {
+		opts.Provider, opts.Token = remote.Provider, s.forgeToken(remote.Provider)
+	}
+	opts.Progress = gitProgress(progress)
+	if err := gitclone.Unshallow(ctx, project.Path, opts); err != nil {
+		return nil, err
+	}
+	return gitclone.Inspect(project.Path)
+}
+
+// gitProgress turns git's progress into a job's, at most every
+// gitProgressInterval unless the phase changes or completes.
+func gitProgress(progress func(string)) func(gitclone.Progress) {
+	var last gitclone.Progress
+	var reported time.Time
+	return func(p gitclone.Progress) {
+		if p.Phase == last.Phase && p.Percent < 100 && time.Since(reported) < gitProgressInterval {
+			return
+		}
+		last, reported = p, time.Now()
+		progress(p.String())
+	}
+}
+
+// devcontainerJobPayload names the task whose devcontainer to bring up.
+type devcontainerJobPayload struct {
+	TaskID string `json:"taskId"`
+}
+
+// runDevcontainerJob pulls or builds a task's devcontainer image and starts
+// the container.
+func (s *Server) runDevcontainerJob(ctx context.Context, job *db.Job, progress func(string)) (any, error) {
+	var payload devcontainerJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	container, err := s.taskContainer(ctx, payload.TaskID, throttledProgress(progress))
+	if err != nil {
+		return nil, err
+	}
+	if container == nil {
+		return nil, errors.New("project does not use a devcontainer")
+	}
+	return container, nil
+}
+
+type pooledWorktreeRef struct {
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+}
+
+// poolRemovalJobPayload lists the pooled worktrees of a deleted project.
+type poolRemovalJobPayload struct {
+	ProjectPath string              `json:"projectPath"`
+	Worktrees   []pooledWorktreeRef `json:"worktrees"`
+}
+
+// runPoolRemovalJob removes a deleted project's pooled worktrees. Ones
+// already gone are skipped, so a retry picks up where an attempt failed.
+func (s *Server) runPoolRemovalJob(_ context.Context, job *db.Job, progress func(string)) (any, error) {
+	var payload poolRemovalJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	var errs []error
+	for i, entry := range payload.Worktrees {
+		if err := s.worktree.RemovePooled(payload.ProjectPath, entry.Path, entry.Branch, ""); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Path, err))
+		}
+		progress(fmt.Sprintf("Removed %d of %d worktrees", i+1, len(payload.Worktrees)))
+	}
+	return nil, errors.Join(errs...)
+}
+
+type teardownJobPayload struct {
+	TaskID string `json:"taskId"`
+}
+
+// runTeardownJob tears down what the provisioner started for a task, and
+// its devcontainer.
+func (s *Server) runTeardownJob(_ context.Context, job *db.Job, _ func(string)) (any, error) {
+	var payload teardownJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	task, err := s.db.GetTask(payload.TaskID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil // deleted, and torn down with it
+	}
+	if err != nil {
+		return nil, err
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	s.removeTaskContainer(task, project)
+	return nil, s.teardownTaskProvision(task, project)
+}
+
+// runDigestJob sends the digest if it is still due.
+func (s *Server) runDigestJob(_ context.Context, _ *db.Job, _ func(string)) (any, error) {
+	return map[string]bool{"sent": s.sendDigestIfDue(time.Now())}, nil
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := db.JobFilter{Kind: q.Get("kind"), Status: q.Get("status"), ProjectID: q.Get("projectId"), Limit: 50}
+	switch filter.Status {
+	case "", db.JobPending, db.JobRunning, db.JobSucceeded, db.JobFailed, db.JobCancelled:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > db.MaxPageLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", db.MaxPageLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+	jobs, err := s.db.ListJobs(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list jobs")
+		return
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.db.GetJob(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "job")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleRetryJob queues a failed or cancelled job again with a fresh
+// series of attempts.
+func (s *Server) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.db.RetryJob(urlParam(r, "id"))
+	if errors.Is(err, db.ErrJobNotFailed) {
+		writeError(w, http.StatusConflict, "only failed or cancelled jobs can be retried")
+		return
+	}
+	if err != nil {
+		writeDBError(w, err, "job")
+		return
+	}
+	s.wsHub.BroadcastGlobal("job_updated", job)
+	s.wakeJobs()
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleCancelJob cancels a pending job, or stops a running one. A
+// cancelled clone leaves nothing behind.
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	id := urlParam(r, "id")
+	job, err := s.db.CancelJob(id)
+	if errors.Is(err, db.ErrNotFound) {
+		if _, err := s.db.GetJob(id); err != nil {
+			writeDBError(w, err, "job")
+			return
+		}
+		writeError(w, http.StatusConflict, "job has already finished")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to cancel job")
+		return
+	}
+	s.jobs.cancel(id)
+	s.jobs.finished(job)
+	s.wsHub.BroadcastGlobal("job_updated", job)
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/gitclone"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+// runDueJobs runs the jobs due now to completion.
+func runDueJobs(env *testEnv) {
+	env.server.startDueJobs(context.Background(), make(chan struct{}, jobWorkers))
+	env.server.bgWG.Wait()
+}
+
+func TestJobs_TeardownWhenTaskDone(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	workDir := t.TempDir()
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "provisioned", Path: workDir})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "needs a database"})
+	env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{WorktreePath: &workDir})
+	env.patch("/api/projects/"+project.ID, map[string]any{
+		"provisioner": map[string]any{"up": "touch up.txt", "down": "rm up.txt"},
+	})
+	if resp := env.post("/api/tasks/"+task.ID+"/provision", nil); resp.Code != http.StatusOK {
+		t.Fatalf("provision: %d %s", resp.Code, resp.Body.String())
+	}
+
+	if resp := env.patch("/api/tasks/"+task.ID, map[string]string{"status": "done"}); resp.Code != http.StatusOK {
+		t.Fatalf("move to done: %d %s", resp.Code, resp.Body.String())
+	}
+	resp := env.get("/api/jobs?kind=" + jobKindTeardown + "&projectId=" + project.ID)
+	var jobs []db.Job
+	decodeResponse(t, resp, &jobs)
+	if len(jobs) != 1 || jobs[0].Status != db.JobPending {
+		t.Fatalf("expected a pending teardown job, got %+v", jobs)
+	}
+
+	runDueJobs(env)
+	resp = env.get("/api/jobs/" + jobs[0].ID)
+	var job db.Job
+	decodeResponse(t, resp, &job)
+	if job.Status != db.JobSucceeded || job.Attempts != 1 || job.FinishedAt == nil {
+		t.Fatalf("expected the job to succeed, got %+v", job)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "up.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the down command to run, got %v", err)
+	}
+	if stored, _ := env.server.db.GetTaskProvision(task.ID); stored.Status != db.ProvisionStatusTornDown {
+		t.Errorf("expected torn down status, got %s", stored.Status)
+	}
+}
+
+func TestJobs_RetryAndFail(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	retried, _ := env.server.enqueueJob("bogus", "", nil, 2)
+	job, _ := env.server.enqueueJob("bogus", "", nil, 1)
+	runDueJobs(env)
+
+	retried, _ = env.server.db.GetJob(retried.ID)
+	if retried.Status != db.JobPending || retried.Attempts != 1 || retried.LastError == "" || !retried.RunAt.After(time.Now()) {
+		t.Fatalf("expected a retry to be scheduled, got %+v", retried)
+	}
+	job, _ = env.server.db.GetJob(job.ID)
+	if job.Status != db.JobFailed || job.Attempts != 1 {
+		t.Fatalf("expected the job to fail, got %+v", job)
+	}
+
+	resp := env.get("/api/jobs?status=failed")
+	var failed []db.Job
+	decodeResponse(t, resp, &failed)
+	if len(failed) != 1 || failed[0].ID != job.ID {
+		t.Fatalf("expected the failed job listed, got %+v", failed)
+	}
+	if resp := env.get("/api/jobs?status=bogus"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid status, got %d", resp.Code)
+	}
+
+	resp = env.post("/api/jobs/"+job.ID+"/retry", nil)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("retry: %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := env.post("/api/jobs/"+job.ID+"/retry", nil); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 retrying a pending job, got %d", resp.Code)
+	}
+	if resp := env.get("/api/jobs/missing"); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.Code)
+	}
+}
+
+func TestJobs_Cancel(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	job, _ := env.server.enqueueJob("bogus", "", nil, 1)
+	waited := make(chan *db.Job, 1)
+	go func() {
+		done, _ := env.server.waitForJob(context.Background(), job.ID)
+		waited <- done
+	}()
+
+	resp := env.post("/api/jobs/"+job.ID+"/cancel", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("cancel: %d %s", resp.Code, resp.Body.String())
+	}
+	select {
+	case done := <-waited:
+		if done == nil || done.Status != db.JobCancelled {
+			t.Errorf("expected the waiter to see the job cancelled, got %+v", done)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter not released")
+	}
+	if resp := env.post("/api/jobs/"+job.ID+"/cancel", nil); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 cancelling a cancelled job, got %d", resp.Code)
+	}
+	if resp := env.post("/api/jobs/"+job.ID+"/retry", nil); resp.Code != http.StatusAccepted {
+		t.Errorf("expected a cancelled job to be retried, got %d", resp.Code)
+	}
+}
+
+func TestCreateProject_CloneQueuesJob(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	resp := env.post("/api/projects", map[string]string{"githubUrl": "https://github.com/user/repo"})
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var job db.Job
+	decodeResponse(t, resp, &job)
+	if job.Kind != jobKindClone || job.Status != db.JobPending {
+		t.Fatalf("expected a pending clone job, got %+v", job)
+	}
+
+	os.MkdirAll(filepath.Join(env.server.gitclone.BaseDir, "taken"), 0o755)
+	resp = env.post("/api/projects", map[string]string{"githubUrl": "https://github.com/user/taken"})
+	if resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an existing destination, got %d", resp.Code)
+	}
+}
+
+func TestCreateWorktree_RunsAsJob(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.worktree = worktree.NewManager(worktree.Config{BaseDir: t.TempDir()})
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": createTestGitRepoWithMain(t)}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Queued"}), &task)
+
+	// Without waiting, the reply is the job, for a worker to run.
+	resp := env.post("/api/tasks/"+task.ID+"/worktree?wait=false", nil)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var job db.Job
+	decodeResponse(t, resp, &job)
+	if job.Kind != jobKindWorktreeCreate || job.Status != db.JobPending || ptrToString(job.ProjectID) != project.ID {
+		t.Fatalf("expected a pending worktree job, got %+v", job)
+	}
+	runDueJobs(env)
+	done, _ := env.server.db.GetJob(job.ID)
+	var wt WorktreeResponse
+	json.Unmarshal(done.Result, &wt)
+	if done.Status != db.JobSucceeded || wt.WorktreePath == "" {
+		t.Fatalf("expected the job to create the worktree, got %+v", done)
+	}
+	if updated, _ := env.server.db.GetTask(task.ID); ptrToString(updated.WorktreePath) != wt.WorktreePath {
+		t.Errorf("expected the task on %s, got %v", wt.WorktreePath, updated.WorktreePath)
+	}
+
+	// A request that waits gets the worktree, and the job is listed.
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Waited"}), &task)
+	resp = env.post("/api/tasks/"+task.ID+"/worktree", nil)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create worktree: %d %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, resp, &wt)
+	var jobs []db.Job
+	decodeResponse(t, env.get("/api/jobs?kind="+jobKindWorktreeCreate), &jobs)
+	if len(jobs) != 2 || jobs[0].Status != db.JobSucceeded {
+		t.Errorf("expected both worktree jobs succeeded, got %+v", jobs)
+	}
+}
+
+func TestProjectUnshallow(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	resp := env.post("/api/projects", map[string]any{
+		"githubUrl": "https://github.com/user/repo",
+		"clone":     map[string]any{"partial": "everything"},
+	})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown partial filter, got %d", resp.Code)
+	}
+
+	origin := createTestGitRepo(t)
+	gitExecHelper(t, origin, "commit", "--allow-empty", "-m", "second")
+	cfg := gitclone.Config{BaseDir: t.TempDir()}
+	result, err := gitclone.Clone(context.Background(), cfg, "file://"+origin+"/.git", "shallow", gitclone.CloneOptions{
+		Shape: &gitclone.Shape{Depth: 1},
+	})
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	gitExecHelper(t, result.Path, "config", "gc.auto", "0")
+	gitExecHelper(t, result.Path, "config", "maintenance.auto", "false")
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "shallow", Path: result.Path})
+
+	resp = env.get("/api/projects/" + project.ID + "/clone")
+	var shape gitclone.Shape
+	decodeResponse(t, resp, &shape)
+	if shape.Depth != 1 {
+		t.Fatalf("expected a shallow clone, got %+v", shape)
+	}
+
+	resp = env.post("/api/projects/"+project.ID+"/unshallow", nil)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("unshallow: %d %s", resp.Code, resp.Body.String())
+	}
+	var job db.Job
+	decodeResponse(t, resp, &job)
+	runDueJobs(env)
+	done, _ := env.server.db.GetJob(job.ID)
+	if done.Status != db.JobSucceeded {
+		t.Fatalf("expected the unshallow job to succeed, got %+v", done)
+	}
+	if resp := env.post("/api/projects/"+project.ID+"/unshallow", nil); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a full clone, got %d", resp.Code)
+	}
+}
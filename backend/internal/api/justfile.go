@@ -3,6 +3,8 @@ package api
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os/exec"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/miguel-bm/codeburg/internal/justfile"
+	"github.com/miguel-bm/codeburg/internal/recipes"
 )
 
 var justMgr = justfile.NewManager()
@@ -53,13 +56,19 @@ func (s *Server) handleRunJustRecipe(w http.ResponseWriter, r *http.Request) {
 
 	// Parse optional args from body
 	var input struct {
-		Args []string `json:"args"`
+		Args   []string          `json:"args"`
+		Params map[string]string `json:"params"`
 	}
 	if r.Body != nil {
 		json.NewDecoder(r.Body).Decode(&input)
 	}
+	args, err := resolveJustArgs(project.Path, recipe, input.Args, input.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	result, err := justMgr.Run(project.Path, recipe, input.Args...)
+	result, err := justMgr.Run(project.Path, recipe, args...)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -95,13 +104,19 @@ func (s *Server) handleRunJustRecipeInTask(w http.ResponseWriter, r *http.Reques
 
 	// Parse optional args from body
 	var input struct {
-		Args []string `json:"args"`
+		Args   []string          `json:"args"`
+		Params map[string]string `json:"params"`
 	}
 	if r.Body != nil {
 		json.NewDecoder(r.Body).Decode(&input)
 	}
+	args, err := resolveJustArgs(workDir, recipe, input.Args, input.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	result, err := justMgr.Run(workDir, recipe, input.Args...)
+	result, err := justMgr.Run(workDir, recipe, args...)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -134,14 +149,37 @@ func (s *Server) handleStreamJustRecipe(w http.ResponseWriter, r *http.Request)
 		workDir = project.Path
 	}
 
-	// Parse optional args
-	args := r.URL.Query()["arg"]
+	// Parse optional args, either positional (arg=value) or named
+	// (param=name=value)
+	var params map[string]string
+	for _, param := range r.URL.Query()["param"] {
+		name, value, _ := strings.Cut(param, "=")
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[name] = value
+	}
+	args, err := resolveJustArgs(workDir, recipe, r.URL.Query()["arg"], params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	cmd, err := justMgr.StartRecipe(workDir, recipe, args...)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	taskEnv := s.taskEnv(taskID)
+	cmd.Env = append(cmd.Env, taskEnv...)
+	container, err := s.taskContainer(r.Context(), taskID, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if container != nil {
+		s.containers.Wrap(cmd, container, taskEnv)
+	}
 
 	// Get output pipes
 	stdout, err := cmd.StdoutPipe()
@@ -283,3 +321,30 @@ func (s *Server) handleListTaskJustRecipes(w http.ResponseWriter, r *http.Reques
 		"recipes":     recipes,
 	})
 }
+
+// resolveJustArgs returns the positional arguments for a recipe run. Named
+// params are mapped onto the recipe's declared parameters; they cannot be
+// combined with positional args.
+func resolveJustArgs(dir, recipe string, args []string, params map[string]string) ([]string, error) {
+	if len(params) == 0 {
+		return args, nil
+	}
+	if len(args) > 0 {
+		return nil, errors.New("use either args or params, not both")
+	}
+
+	listed, err := justMgr.ListRecipes(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range listed {
+		if candidate.Name == recipe {
+			return recipes.Recipe{
+				Name:   candidate.Name,
+				Source: "justfile",
+				Params: recipes.ParseJustParams(candidate.Args),
+			}.JustArgs(params)
+		}
+	}
+	return nil, fmt.Errorf("recipe %q not found", recipe)
+}
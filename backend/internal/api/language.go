@@ -0,0 +1,265 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Language preferences:
+//
+//	language            the user's language (e.g. "es"), used for agent replies and notifications
+//	telegram_language   overrides language for messages sent to the Telegram chat
+const (
+	languagePreference         = "language"
+	telegramLanguagePreference = "telegram_language"
+)
+
+// Notification templates are keyed by their English text, which is also
+// what untranslated languages get.
+const (
+	msgSessionNeedsAttention = "Session needs attention"
+	msgTaskNeedsAttention    = "%s needs attention"
+	msgSessionWaiting        = "The %s session is waiting for input."
+	msgReactHint             = "React 👍 to continue or 👎 to stop and explain."
+	msgTestTitle             = "Codeburg test notification"
+	msgTestBody              = "Notifications are working."
+	msgTunnelOpen            = "🌐 Tunnel open: %s (port %d)\n%s"
+	msgTunnelExpires         = "Expires at %s."
+	msgTunnelClosed          = "🔌 Tunnel closed: %s (port %d)\n%s\nReason: %s"
+	msgTunnelReconnected     = "🔁 Tunnel re-established: %s (port %d)\n%s"
+	msgTunnelLongOpen        = "⏳ Tunnel open for %s: %s (port %d)\n%s\nStop it if it's no longer needed."
+	msgBudgetAlert           = "💸 %s budget at %d%%"
+	msgBudgetUsed            = "%s used this month."
+	msgBudgetBlocked         = "New sessions are blocked until the budget is overridden or the month ends."
+	msgVoiceHint             = "Reply to a session message with a voice note to send it to the agent."
+	msgTaskInReview          = "%s is ready for review"
+	msgTaskInReviewBody      = "Moved to review in %s."
+	msgPullRequest           = "Pull request"
+	msgDigestTitle           = "Codeburg daily digest"
+	msgWeeklyDigestTitle     = "Codeburg weekly digest"
+	msgDigestActivity        = "Sessions started: %s · Commits: %d"
+	msgDigestDone            = "Done since the last digest (%d):"
+	msgDigestInReview        = "Waiting for review (%d):"
+	msgDigestWaiting         = "Sessions waiting for input (%d):"
+	msgDigestMore            = "…and %d more"
+	msgUpdateTitle           = "Codeburg %s is available"
+	msgUpdateBody            = "You are running %s. Update with codeburg self-update or from the release page."
+	msgSessionIdleTitle      = "%s will be stopped for inactivity"
+	msgSessionIdleBody       = "Idle for %s. It stops in %s unless there is activity."
+	msgCIFailedTitle         = "❌ Checks failed for %s"
+	msgCIFailedBody          = "%d of %d checks failed on %s: %s"
+	msgGuardrailTitle        = "🚧 %s went past its guardrails"
+	msgGuardrailFiles        = "%d files changed (limit %d)"
+	msgGuardrailLines        = "%d lines changed (limit %d)"
+	msgGuardrailPaths        = "Forbidden paths changed: %s"
+	msgGuardrailBody         = "The agent is waiting for you to review before it goes on."
+)
+
+var messageCatalog = map[string]map[string]string{
+	"es": {
+		msgSessionNeedsAttention: "La sesión necesita atención",
+		msgTaskNeedsAttention:    "%s necesita atención",
+		msgSessionWaiting:        "La sesión de %s está esperando una respuesta.",
+		msgReactHint:             "Reacciona 👍 para continuar o 👎 para detenerla y explicar por qué.",
+		msgTestTitle:             "Notificación de prueba de Codeburg",
+		msgTestBody:              "Las notificaciones funcionan.",
+		msgTunnelOpen:            "🌐 Túnel abierto: %s (puerto %d)\n%s",
+		msgTunnelExpires:         "Caduca a las %s.",
+		msgTunnelClosed:          "🔌 Túnel cerrado: %s (puerto %d)\n%s\nMotivo: %s",
+		msgTunnelReconnected:     "🔁 Túnel restablecido: %s (puerto %d)\n%s",
+		msgTunnelLongOpen:        "⏳ Túnel abierto desde hace %s: %s (puerto %d)\n%s\nDetenlo si ya no lo necesitas.",
+		msgBudgetAlert:           "💸 Presupuesto de %s al %d%%",
+		msgBudgetUsed:            "%s usado este mes.",
+		msgBudgetBlocked:         "Las nuevas sesiones están bloqueadas hasta que se anule el presupuesto o acabe el mes.",
+		msgVoiceHint:             "Responde a un mensaje de una sesión con una nota de voz para enviársela al agente.",
+		msgTaskInReview:          "%s está lista para revisión",
+		msgTaskInReviewBody:      "Pasó a revisión en %s.",
+		msgPullRequest:           "Pull request",
+		msgDigestTitle:           "Resumen diario de Codeburg",
+		msgWeeklyDigestTitle:     "Resumen semanal de Codeburg",
+		msgDigestActivity:        "Sesiones iniciadas: %s · Commits: %d",
+		msgDigestDone:            "Terminadas desde el último resumen (%d):",
+		msgDigestInReview:        "Esperando revisión (%d):",
+		msgDigestWaiting:         "Sesiones esperando una respuesta (%d):",
+		msgDigestMore:            "…y %d más",
+		msgUpdateTitle:           "Codeburg %s está disponible",
+		msgUpdateBody:            "Estás usando %s. Actualiza con codeburg self-update o desde la página de la versión.",
+		msgSessionIdleTitle:      "%s se detendrá por inactividad",
+		msgSessionIdleBody:       "Inactiva desde hace %s. Se detiene en %s si no hay actividad.",
+		msgCIFailedTitle:         "❌ Fallaron las comprobaciones de %s",
+		msgCIFailedBody:          "%d de %d comprobaciones fallaron en %s: %s",
+		msgGuardrailTitle:        "🚧 %s superó sus límites de seguridad",
+		msgGuardrailFiles:        "%d archivos cambiados (límite %d)",
+		msgGuardrailLines:        "%d líneas cambiadas (límite %d)",
+		msgGuardrailPaths:        "Rutas prohibidas cambiadas: %s",
+		msgGuardrailBody:         "El agente espera a que lo revises antes de seguir.",
+	},
+	"fr": {
+		msgSessionNeedsAttention: "La session requiert votre attention",
+		msgTaskNeedsAttention:    "%s requiert votre attention",
+		msgSessionWaiting:        "La session %s attend une réponse.",
+		msgReactHint:             "Réagissez 👍 pour continuer ou 👎 pour l'arrêter et expliquer pourquoi.",
+		msgTestTitle:             "Notification de test Codeburg",
+		msgTestBody:              "Les notifications fonctionnent.",
+		msgTunnelOpen:            "🌐 Tunnel ouvert : %s (port %d)\n%s",
+		msgTunnelExpires:         "Expire à %s.",
+		msgTunnelClosed:          "🔌 Tunnel fermé : %s (port %d)\n%s\nRaison : %s",
+		msgTunnelReconnected:     "🔁 Tunnel rétabli : %s (port %d)\n%s",
+		msgTunnelLongOpen:        "⏳ Tunnel ouvert depuis %s : %s (port %d)\n%s\nArrêtez-le s'il n'est plus utile.",
+		msgBudgetAlert:           "💸 Budget %s à %d %%",
+		msgBudgetUsed:            "%s utilisé ce mois-ci.",
+		msgBudgetBlocked:         "Les nouvelles sessions sont bloquées jusqu'à ce que le budget soit levé ou que le mois se termine.",
+		msgVoiceHint:             "Répondez au message d'une session avec une note vocale pour l'envoyer à l'agent.",
+		msgTaskInReview:          "%s est prête pour la revue",
+		msgTaskInReviewBody:      "Passée en revue dans %s.",
+		msgPullRequest:           "Pull request",
+		msgDigestTitle:           "Résumé quotidien Codeburg",
+		msgWeeklyDigestTitle:     "Résumé hebdomadaire Codeburg",
+		msgDigestActivity:        "Sessions lancées : %s · Commits : %d",
+		msgDigestDone:            "Terminées depuis le dernier résumé (%d) :",
+		msgDigestInReview:        "En attente de revue (%d) :",
+		msgDigestWaiting:         "Sessions en attente d'une réponse (%d) :",
+		msgDigestMore:            "…et %d de plus",
+		msgUpdateTitle:           "Codeburg %s est disponible",
+		msgUpdateBody:            "Vous utilisez %s. Mettez à jour avec codeburg self-update ou depuis la page de la version.",
+		msgSessionIdleTitle:      "%s sera arrêtée pour inactivité",
+		msgSessionIdleBody:       "Inactive depuis %s. Elle s'arrête dans %s sans activité.",
+		msgCIFailedTitle:         "❌ Échec des vérifications pour %s",
+		msgCIFailedBody:          "%d vérifications sur %d ont échoué sur %s : %s",
+		msgGuardrailTitle:        "🚧 %s a dépassé ses garde-fous",
+		msgGuardrailFiles:        "%d fichiers modifiés (limite %d)",
+		msgGuardrailLines:        "%d lignes modifiées (limite %d)",
+		msgGuardrailPaths:        "Chemins interdits modifiés : %s",
+		msgGuardrailBody:         "L'agent attend votre relecture avant de continuer.",
+	},
+	"de": {
+		msgSessionNeedsAttention: "Sitzung braucht Aufmerksamkeit",
+		msgTaskNeedsAttention:    "%s braucht Aufmerksamkeit",
+		msgSessionWaiting:        "Die %s-Sitzung wartet auf eine Eingabe.",
+		msgReactHint:             "Reagiere mit 👍 zum Fortfahren oder mit 👎 zum Anhalten und Erklären.",
+		msgTestTitle:             "Codeburg-Testbenachrichtigung",
+		msgTestBody:              "Benachrichtigungen funktionieren.",
+		msgTunnelOpen:            "🌐 Tunnel geöffnet: %s (Port %d)\n%s",
+		msgTunnelExpires:         "Läuft um %s ab.",
+		msgTunnelClosed:          "🔌 Tunnel geschlossen: %s (Port %d)\n%s\nGrund: %s",
+		msgTunnelReconnected:     "🔁 Tunnel wiederhergestellt: %s (Port %d)\n%s",
+		msgTunnelLongOpen:        "⏳ Tunnel seit %s geöffnet: %s (Port %d)\n%s\nBeende ihn, wenn er nicht mehr gebraucht wird.",
+		msgBudgetAlert:           "💸 Budget %s bei %d %%",
+		msgBudgetUsed:            "%s in diesem Monat verbraucht.",
+		msgBudgetBlocked:         "Neue Sitzungen sind gesperrt, bis das Budget aufgehoben wird oder der Monat endet.",
+		msgVoiceHint:             "Antworte mit einer Sprachnachricht auf eine Sitzungsnachricht, um sie an den Agenten zu senden.",
+		msgTaskInReview:          "%s ist bereit zur Prüfung",
+		msgTaskInReviewBody:      "In %s zur Prüfung verschoben.",
+		msgPullRequest:           "Pull-Request",
+		msgDigestTitle:           "Codeburg-Tageszusammenfassung",
+		msgWeeklyDigestTitle:     "Codeburg-Wochenzusammenfassung",
+		msgDigestActivity:        "Gestartete Sitzungen: %s · Commits: %d",
+		msgDigestDone:            "Erledigt seit der letzten Zusammenfassung (%d):",
+		msgDigestInReview:        "Wartet auf Prüfung (%d):",
+		msgDigestWaiting:         "Sitzungen, die auf eine Eingabe warten (%d):",
+		msgDigestMore:            "…und %d weitere",
+		msgUpdateTitle:           "Codeburg %s ist verfügbar",
+		msgUpdateBody:            "Du verwendest %s. Aktualisiere mit codeburg self-update oder über die Release-Seite.",
+		msgSessionIdleTitle:      "%s wird wegen Inaktivität beendet",
+		msgSessionIdleBody:       "Seit %s inaktiv. Sie wird in %s beendet, wenn nichts passiert.",
+		msgCIFailedTitle:         "❌ Checks für %s fehlgeschlagen",
+		msgCIFailedBody:          "%d von %d Checks auf %s fehlgeschlagen: %s",
+		msgGuardrailTitle:        "🚧 %s hat seine Leitplanken überschritten",
+		msgGuardrailFiles:        "%d Dateien geändert (Grenze %d)",
+		msgGuardrailLines:        "%d Zeilen geändert (Grenze %d)",
+		msgGuardrailPaths:        "Verbotene Pfade geändert: %s",
+		msgGuardrailBody:         "Der Agent wartet auf deine Prüfung, bevor er weitermacht.",
+	},
+}
+
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"ca": "Catalan",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// normalizeLanguage reduces a language tag such as "es-MX" to its base
+// language, "es".
+func normalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return base
+}
+
+// localize formats an English template in lang.
+func localize(lang, template string, args ...any) string {
+	if translated, ok := messageCatalog[lang][template]; ok {
+		template = translated
+	}
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// languageInstruction is the system prompt line asking an agent to reply in
+// lang, or "" for English.
+func languageInstruction(lang string) string {
+	if lang == "" || lang == "en" {
+		return ""
+	}
+	name := languageNames[lang]
+	if name == "" {
+		name = "the language with code " + lang
+	}
+	return "Reply to the user in " + name + ". Keep code, commands and identifiers as they are."
+}
+
+func (s *Server) languagePref(key string) string {
+	pref, err := s.db.GetPreference(db.DefaultUserID, key)
+	if err != nil {
+		return ""
+	}
+	return normalizeLanguage(unquotePreference(pref.Value))
+}
+
+// userLanguage is the user's language, or "" when unset.
+func (s *Server) userLanguage() string {
+	return s.languagePref(languagePreference)
+}
+
+// telegramLanguage is the language of messages sent to the Telegram chat.
+func (s *Server) telegramLanguage() string {
+	if lang := s.languagePref(telegramLanguagePreference); lang != "" {
+		return lang
+	}
+	return s.userLanguage()
+}
+
+// sessionPrelude is the system prompt prelude for an agent session: the
+// project's agent instructions and the user's language.
+func (s *Server) sessionPrelude(project *db.Project, provider string) string {
+	if provider == "terminal" {
+		return ""
+	}
+	parts := make([]string, 0, 2)
+	if prelude := agentPrelude(project, provider); prelude != "" {
+		parts = append(parts, prelude)
+	}
+	if instruction := languageInstruction(s.userLanguage()); instruction != "" {
+		parts = append(parts, instruction)
+	}
+	return strings.Join(parts, "\n\n")
+}
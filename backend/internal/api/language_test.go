@@ -0,0 +1,51 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestLocalize(t *testing.T) {
+	if got := localize("es", msgTunnelOpen, "web", 3000, "https://x"); got != "🌐 Túnel abierto: web (puerto 3000)\nhttps://x" {
+		t.Errorf("unexpected Spanish text %q", got)
+	}
+	if got := localize("ja", msgTaskNeedsAttention, "Fix login"); got != "Fix login needs attention" {
+		t.Errorf("expected untranslated languages to get English, got %q", got)
+	}
+	if got := localize("", msgReactHint); got != msgReactHint {
+		t.Errorf("expected English without a language, got %q", got)
+	}
+	for tag, want := range map[string]string{"es-MX": "es", " PT_br ": "pt", "de": "de", "": ""} {
+		if got := normalizeLanguage(tag); got != want {
+			t.Errorf("normalizeLanguage(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestSessionPreludeLanguage(t *testing.T) {
+	env := setupTestEnv(t)
+	project := &db.Project{AgentInstructions: &db.AgentInstructions{Prelude: "Use tabs."}}
+
+	if got := env.server.sessionPrelude(project, "claude"); got != "Use tabs." {
+		t.Errorf("expected only the project prelude without a language, got %q", got)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, languagePreference, `"es"`)
+	got := env.server.sessionPrelude(project, "claude")
+	if !strings.HasPrefix(got, "Use tabs.\n\n") || !strings.Contains(got, "Reply to the user in Spanish.") {
+		t.Errorf("expected the language instruction after the prelude, got %q", got)
+	}
+	if got := env.server.sessionPrelude(nil, "codex"); !strings.HasPrefix(got, "Reply to the user in Spanish.") {
+		t.Errorf("expected the language instruction without a prelude, got %q", got)
+	}
+	if got := env.server.sessionPrelude(project, "terminal"); got != "" {
+		t.Errorf("expected no prelude for terminal sessions, got %q", got)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, telegramLanguagePreference, `"fr"`)
+	if got := env.server.telegramLanguage(); got != "fr" {
+		t.Errorf("expected telegram_language to override language, got %q", got)
+	}
+}
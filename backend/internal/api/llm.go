@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/llm"
+)
+
+// LLM preference:
+//
+//	llm  {"url": "http://127.0.0.1:11434/v1", "apiKey": "...",
+//	      "model": "llama3.1", "embeddingModel": "nomic-embed-text",
+//	      "features": {"semantic_search": {"model": "..."},
+//	                   "session_titles": {"url": "...", "apiKey": "...", "model": "..."}}}
+//
+// Every built-in language model feature goes through one OpenAI-compatible
+// client. A feature's own settings override the shared ones, and model and
+// embeddingModel are the shared choices for chat and embedding features.
+// Without a url, OpenAI is used with apiKey, openai_api_key or
+// OPENAI_API_KEY.
+const llmPreference = "llm"
+
+const (
+	llmDefaultChatModel      = "gpt-4o-mini"
+	llmDefaultEmbeddingModel = "text-embedding-3-small"
+)
+
+// llmFeature is a feature that uses a language model, by the name its
+// settings go under in the llm preference.
+type llmFeature struct {
+	name       string
+	embeddings bool // uses an embedding model rather than a chat one
+}
+
+var (
+	llmFeatureSemanticSearch = llmFeature{name: "semantic_search", embeddings: true}
+	llmFeatureSessionTitles  = llmFeature{name: "session_titles"}
+)
+
+// llmFeatures lists the features for GET /api/llm/features.
+var llmFeatures = []llmFeature{llmFeatureSemanticSearch, llmFeatureSessionTitles}
+
+type llmEndpointConfig struct {
+	URL    string `json:"url,omitempty"`
+	APIKey string `json:"apiKey,omitempty"`
+	Model  string `json:"model,omitempty"`
+}
+
+type llmConfig struct {
+	llmEndpointConfig
+	EmbeddingModel string                       `json:"embeddingModel,omitempty"`
+	Features       map[string]llmEndpointConfig `json:"features,omitempty"`
+}
+
+var errLLMNotConfigured = errors.New("no language model is configured")
+
+func (s *Server) llmConfig() (llmConfig, error) {
+	var cfg llmConfig
+	if pref, err := s.db.GetPreference(db.DefaultUserID, llmPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			return cfg, fmt.Errorf("invalid llm preference: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// resolve returns the endpoint and model a feature uses.
+func (cfg llmConfig) resolve(feature llmFeature) llmEndpointConfig {
+	own := cfg.Features[feature.name]
+	shared := cfg.Model
+	fallback := llmDefaultChatModel
+	if feature.embeddings {
+		shared, fallback = cfg.EmbeddingModel, llmDefaultEmbeddingModel
+	}
+	resolved := llmEndpointConfig{
+		URL:    firstNonEmpty(own.URL, cfg.URL),
+		APIKey: firstNonEmpty(own.APIKey, cfg.APIKey),
+		Model:  firstNonEmpty(own.Model, shared),
+	}
+	// A feature with its own server doesn't share its key.
+	if own.URL != "" && own.URL != cfg.URL {
+		resolved.APIKey = own.APIKey
+	}
+	if resolved.URL == "" && resolved.Model == "" {
+		resolved.Model = fallback
+	}
+	return resolved
+}
+
+// llmClient returns the client and model for a feature.
+func (s *Server) llmClient(feature llmFeature) (*llm.Client, string, error) {
+	cfg, err := s.llmConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint := cfg.resolve(feature)
+	if endpoint.URL == "" {
+		endpoint.APIKey = firstNonEmpty(endpoint.APIKey, s.openAIKey())
+		if endpoint.APIKey == "" {
+			return nil, "", fmt.Errorf("%w for %s: set an api key or a url", errLLMNotConfigured, feature.name)
+		}
+	}
+	if endpoint.Model == "" {
+		return nil, "", fmt.Errorf("%w for %s: set a model", errLLMNotConfigured, feature.name)
+	}
+	return &llm.Client{BaseURL: endpoint.URL, APIKey: endpoint.APIKey}, endpoint.Model, nil
+}
+
+type llmFeatureStatus struct {
+	Feature    string `json:"feature"`
+	Embeddings bool   `json:"embeddings,omitempty"`
+	URL        string `json:"url"`
+	Model      string `json:"model,omitempty"`
+	Configured bool   `json:"configured"`
+	Error      string `json:"error,omitempty"`
+}
+
+// handleListLLMFeatures shows the endpoint and model each feature resolves
+// to, without keys.
+func (s *Server) handleListLLMFeatures(w http.ResponseWriter, r *http.Request) {
+	cfg, err := s.llmConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	statuses := make([]llmFeatureStatus, 0, len(llmFeatures))
+	for _, feature := range llmFeatures {
+		endpoint := cfg.resolve(feature)
+		status := llmFeatureStatus{
+			Feature:    feature.name,
+			Embeddings: feature.embeddings,
+			URL:        firstNonEmpty(endpoint.URL, llm.DefaultBaseURL),
+			Model:      endpoint.Model,
+			Configured: true,
+		}
+		if _, _, err := s.llmClient(feature); err != nil {
+			status.Configured, status.Error = false, err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestLLMConfigResolve(t *testing.T) {
+	cfg := llmConfig{
+		llmEndpointConfig: llmEndpointConfig{URL: "http://ollama:11434/v1", APIKey: "shared", Model: "llama3.1"},
+		EmbeddingModel:    "nomic-embed-text",
+		Features: map[string]llmEndpointConfig{
+			"titles": {URL: "https://api.openai.com/v1", Model: "gpt-4o-mini"},
+		},
+	}
+	if got := cfg.resolve(llmFeatureSemanticSearch); got != (llmEndpointConfig{URL: "http://ollama:11434/v1", APIKey: "shared", Model: "nomic-embed-text"}) {
+		t.Errorf("embeddings: %+v", got)
+	}
+	if got := cfg.resolve(llmFeature{name: "summaries"}); got.Model != "llama3.1" {
+		t.Errorf("chat: %+v", got)
+	}
+	// Its own server doesn't get the shared key.
+	if got := cfg.resolve(llmFeature{name: "titles"}); got != (llmEndpointConfig{URL: "https://api.openai.com/v1", Model: "gpt-4o-mini"}) {
+		t.Errorf("own endpoint: %+v", got)
+	}
+	if got := (llmConfig{}).resolve(llmFeatureSemanticSearch); got.URL != "" || got.Model != llmDefaultEmbeddingModel {
+		t.Errorf("defaults: %+v", got)
+	}
+}
+
+func TestListLLMFeatures(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	var statuses []llmFeatureStatus
+	decodeResponse(t, env.get("/api/llm/features"), &statuses)
+	if len(statuses) != len(llmFeatures) || statuses[0].Configured || statuses[0].Error == "" {
+		t.Fatalf("expected unconfigured features, got %+v", statuses)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, llmPreference, `{"url": "http://127.0.0.1:11434/v1", "embeddingModel": "nomic-embed-text"}`)
+	decodeResponse(t, env.get("/api/llm/features"), &statuses)
+	if !statuses[0].Configured || statuses[0].Model != "nomic-embed-text" || statuses[0].URL != "http://127.0.0.1:11434/v1" {
+		t.Fatalf("unexpected status %+v", statuses[0])
+	}
+}
@@ -0,0 +1,15 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/fakeprovider"
+)
+
+// TestMain lets the test binary stand in for the claude and codex CLIs, for
+// tests that install it with installFakeProviders.
+func TestMain(m *testing.M) {
+	fakeprovider.RunIfInvoked()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/gitclone"
+	"github.com/miguel-bm/codeburg/internal/markdown"
+)
+
+const (
+	maxMarkdownBytes = 1 << 20
+	// maxInlineImageBytes caps repo images embedded as data URIs.
+	maxInlineImageBytes = 512 << 10
+)
+
+type renderMarkdownRequest struct {
+	Markdown string          `json:"markdown"`
+	TaskID   string          `json:"taskId,omitempty"`
+	Flavor   markdown.Flavor `json:"flavor,omitempty"` // "html" (default) or "telegram"
+}
+
+func (s *Server) handleRenderMarkdown(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMarkdownBytes+4096)
+	var req renderMarkdownRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Markdown) > maxMarkdownBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "markdown too large")
+		return
+	}
+	switch req.Flavor {
+	case "", markdown.FlavorHTML, markdown.FlavorTelegram:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid flavor: "+string(req.Flavor))
+		return
+	}
+
+	opts := markdown.Options{Flavor: req.Flavor}
+	if req.TaskID != "" {
+		task, err := s.db.GetTask(req.TaskID)
+		if err != nil {
+			writeDBError(w, err, "task")
+			return
+		}
+		project, err := s.db.GetProject(task.ProjectID)
+		if err != nil {
+			writeDBError(w, err, "project")
+			return
+		}
+		opts = s.taskMarkdownOptions(task, project, req.Flavor)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"html": markdown.Render(req.Markdown, opts)})
+}
+
+// taskMarkdownOptions resolves repo-relative links against the task's remote
+// (at the task branch) and, for the web, inlines small images from the task's
+// working tree.
+func (s *Server) taskMarkdownOptions(task *db.Task, project *db.Project, flavor markdown.Flavor) markdown.Options {
+	remote, hasRemote := s.projectRemote(project)
+	ref := ptrToString(task.Branch)
+	if ref == "" {
+		ref = project.DefaultBranch
+	}
+	root := ptrToString(task.WorktreePath)
+	if root == "" {
+		root = project.Path
+	}
+
+	return markdown.Options{
+		Flavor: flavor,
+		ResolveLink: func(dest string) string {
+			p, suffix, ok := repoRelativePath(dest)
+			if !ok || !hasRemote {
+				return ""
+			}
+			return remoteFileURL(remote, ref, p, false) + suffix
+		},
+		ResolveImage: func(dest string) string {
+			p, _, ok := repoRelativePath(dest)
+			if !ok {
+				return ""
+			}
+			if flavor != markdown.FlavorTelegram {
+				if data := inlineImage(root, p); data != "" {
+					return data
+				}
+			}
+			if !hasRemote {
+				return ""
+			}
+			return remoteFileURL(remote, ref, p, flavor != markdown.FlavorTelegram)
+		},
+	}
+}
+
+// repoRelativePath cleans a relative link destination into a repo path,
+// splitting off any query or fragment. Paths escaping the repo are rejected.
+func repoRelativePath(dest string) (p, suffix string, ok bool) {
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		dest, suffix = dest[:i], dest[i:]
+	}
+	if unescaped, err := url.PathUnescape(dest); err == nil {
+		dest = unescaped
+	}
+	p = path.Clean("/" + dest)[1:]
+	if p == "" || strings.HasPrefix(path.Clean(dest), "..") {
+		return "", "", false
+	}
+	return p, suffix, true
+}
+
+// remoteFileURL returns the web URL of a file on the remote at ref; raw
+// returns the URL of the file contents instead of the file page.
+func remoteFileURL(remote gitclone.Remote, ref, filePath string, raw bool) string {
+	base := remote.BaseURL() + "/" + remote.Path()
+	escaped := (&url.URL{Path: filePath}).EscapedPath()
+	ref = (&url.URL{Path: ref}).EscapedPath()
+	switch remote.Provider {
+	case gitclone.ProviderGitLab:
+		kind := "blob"
+		if raw {
+			kind = "raw"
+		}
+		return base + "/-/" + kind + "/" + ref + "/" + escaped
+	case gitclone.ProviderGitea:
+		kind := "src"
+		if raw {
+			kind = "raw"
+		}
+		return base + "/" + kind + "/branch/" + ref + "/" + escaped
+	default:
+		u := base + "/blob/" + ref + "/" + escaped
+		if raw {
+			u += "?raw=true"
+		}
+		return u
+	}
+}
+
+// inlineImage returns a data URI for a small image file under root, or "".
+func inlineImage(root, relPath string) string {
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(relPath)))
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+	default:
+		return "" // SVG can carry script; link to it instead
+	}
+	absPath, err := safeJoin(root, filepath.FromSlash(relPath))
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(absPath)
+	if err != nil || info.IsDir() || info.Size() > maxInlineImageBytes {
+		return ""
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return ""
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
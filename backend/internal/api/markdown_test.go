@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	resp := env.post("/api/render/markdown", map[string]string{
+		"markdown": "**hi** <script>x</script>",
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var out map[string]string
+	decodeResponse(t, resp, &out)
+	if out["html"] != "<p><strong>hi</strong> &lt;script&gt;x&lt;/script&gt;</p>" {
+		t.Errorf("unexpected html %q", out["html"])
+	}
+
+	resp = env.post("/api/render/markdown", map[string]string{"markdown": "x", "flavor": "pdf"})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid flavor, got %d", resp.Code)
+	}
+}
+
+func TestRenderMarkdown_ResolvesTaskLinks(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	pixel := []byte("\x89PNG\r\n\x1a\n")
+	if err := os.WriteFile(filepath.Join(repoPath, "shot.png"), pixel, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	origin := "git@github.com:acme/widgets.git"
+	projResp := env.post("/api/projects", map[string]any{
+		"name": "md-" + db.NewID(), "path": repoPath, "gitOrigin": origin,
+	})
+	var project db.Project
+	decodeResponse(t, projResp, &project)
+
+	taskResp := env.post("/api/projects/"+project.ID+"/tasks", map[string]string{
+		"title": "Markdown Task", "branch": "feature/md",
+	})
+	var task db.Task
+	decodeResponse(t, taskResp, &task)
+
+	resp := env.post("/api/render/markdown", map[string]string{
+		"markdown": "[guide](docs/guide.md#setup) [up](../../etc/passwd) ![shot](shot.png) ![logo](logo.svg)",
+		"taskId":   task.ID,
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var out map[string]string
+	decodeResponse(t, resp, &out)
+	html := out["html"]
+
+	for _, want := range []string{
+		`<a href="https://github.com/acme/widgets/blob/feature/md/docs/guide.md#setup">guide</a>`,
+		` up `,
+		`<img src="data:image/png;base64,`,
+		`<img src="https://github.com/acme/widgets/blob/feature/md/logo.svg?raw=true" alt="logo">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected html to contain %q, got %q", want, html)
+		}
+	}
+
+	resp = env.post("/api/render/markdown", map[string]string{
+		"markdown": "![shot](shot.png)", "taskId": task.ID, "flavor": "telegram",
+	})
+	decodeResponse(t, resp, &out)
+	if out["html"] != `<a href="https://github.com/acme/widgets/blob/feature/md/shot.png">shot</a>` {
+		t.Errorf("unexpected telegram html %q", out["html"])
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Model catalog preference:
+//
+//	provider_models  {"claude": ["claude-opus-4-1-20250805"], "codex": ["o3"]}
+//
+// GET /api/providers/models lists the models each agent provider takes,
+// so clients can offer a choice instead of a free-text field. The catalog
+// is the built-in registry, plus the default model configured in the CLI's
+// own settings (~/.claude/settings.json, ~/.codex/config.toml) and the
+// models in the provider_models preference. Names isValidModelName
+// rejects are left out.
+const providerModelsPreference = "provider_models"
+
+// Where a catalog entry comes from.
+const (
+	modelSourceRegistry   = "registry"
+	modelSourceCLIConfig  = "cli_config"
+	modelSourcePreference = "preference"
+)
+
+type catalogModel struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source"`
+}
+
+type providerModels struct {
+	Provider string `json:"provider"`
+	// Default is the model the CLI uses when none is given, when its
+	// settings name one.
+	Default string         `json:"default,omitempty"`
+	Models  []catalogModel `json:"models"`
+}
+
+// modelRegistry is the built-in list: the aliases each CLI resolves to its
+// latest models first, then pinned versions.
+var modelRegistry = map[string][]catalogModel{
+	"claude": {
+		{ID: "sonnet", Name: "Claude Sonnet (latest)"},
+		{ID: "opus", Name: "Claude Opus (latest)"},
+		{ID: "haiku", Name: "Claude Haiku (latest)"},
+		{ID: "claude-sonnet-4-5-20250929", Name: "Claude Sonnet 4.5"},
+		{ID: "claude-opus-4-1-20250805", Name: "Claude Opus 4.1"},
+		{ID: "claude-haiku-4-5-20251001", Name: "Claude Haiku 4.5"},
+	},
+	"codex": {
+		{ID: "gpt-5-codex", Name: "GPT-5 Codex"},
+		{ID: "gpt-5", Name: "GPT-5"},
+		{ID: "gpt-5-mini", Name: "GPT-5 mini"},
+	},
+}
+
+// modelProviders are the providers with a catalog, in listing order.
+var modelProviders = []string{"claude", "codex"}
+
+func (s *Server) handleListProviderModels(w http.ResponseWriter, r *http.Request) {
+	providers := modelProviders
+	if provider := r.URL.Query().Get("provider"); provider != "" {
+		if _, ok := modelRegistry[provider]; !ok {
+			writeError(w, http.StatusBadRequest, "unknown provider: "+provider)
+			return
+		}
+		providers = []string{provider}
+	}
+
+	catalog := make([]providerModels, 0, len(providers))
+	for _, provider := range providers {
+		catalog = append(catalog, s.modelCatalog(provider))
+	}
+	writeJSON(w, http.StatusOK, catalog)
+}
+
+// modelCatalog returns the models of one provider.
+func (s *Server) modelCatalog(provider string) providerModels {
+	catalog := providerModels{Provider: provider, Models: []catalogModel{}}
+	seen := map[string]bool{}
+	add := func(m catalogModel) {
+		if seen[m.ID] || !isValidModelName(m.ID) {
+			return
+		}
+		seen[m.ID] = true
+		catalog.Models = append(catalog.Models, m)
+	}
+
+	if model := cliDefaultModel(provider); model != "" && isValidModelName(model) {
+		catalog.Default = model
+		add(catalogModel{ID: model, Source: modelSourceCLIConfig})
+	}
+	for _, model := range s.preferredModels()[provider] {
+		add(catalogModel{ID: model, Source: modelSourcePreference})
+	}
+	for _, model := range modelRegistry[provider] {
+		model.Source = modelSourceRegistry
+		add(model)
+	}
+	return catalog
+}
+
+// preferredModels returns the provider_models preference.
+func (s *Server) preferredModels() map[string][]string {
+	models := map[string][]string{}
+	pref, err := s.db.GetPreference(db.DefaultUserID, providerModelsPreference)
+	if err != nil {
+		return models
+	}
+	if err := json.Unmarshal([]byte(pref.Value), &models); err != nil {
+		slog.Warn("invalid provider_models preference", "error", err)
+	}
+	return models
+}
+
+// cliDefaultModel returns the model the provider's CLI settings choose, or
+// "" when they don't.
+func cliDefaultModel(provider string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	switch provider {
+	case "claude":
+		data, err := os.ReadFile(filepath.Join(home, ".claude", "settings.json"))
+		if err != nil {
+			return ""
+		}
+		var settings struct {
+			Model string `json:"model"`
+		}
+		if json.Unmarshal(data, &settings) != nil {
+			return ""
+		}
+		return settings.Model
+	case "codex":
+		return codexConfigModel(filepath.Join(home, ".codex", "config.toml"))
+	}
+	return ""
+}
+
+// codexConfigModel reads the top-level model key of a codex config.toml,
+// ignoring the tables after it such as profiles.
+func codexConfigModel(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			return ""
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "model" {
+			continue
+		}
+		value, _, _ = strings.Cut(strings.TrimSpace(value), "#")
+		return strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return ""
+}
+
+// telegramListModels answers /models: the catalog of every provider, or of
+// the one named.
+func (s *Server) telegramListModels(args string) string {
+	providers := modelProviders
+	if provider := strings.TrimSpace(args); provider != "" {
+		if _, ok := modelRegistry[provider]; !ok {
+			return "Unknown provider: " + provider
+		}
+		providers = []string{provider}
+	}
+
+	var b strings.Builder
+	for i, provider := range providers {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		catalog := s.modelCatalog(provider)
+		fmt.Fprintf(&b, "%s models:\n", provider)
+		for _, model := range catalog.Models {
+			b.WriteString("• " + model.ID)
+			if model.ID == catalog.Default {
+				b.WriteString(" (default)")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
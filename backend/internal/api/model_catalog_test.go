@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestProviderModels(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".codex"), 0o755)
+	os.WriteFile(filepath.Join(home, ".codex", "config.toml"), []byte("# codex\nmodel = \"o4-mini\" # fast\n\n[profiles.deep]\nmodel = \"o3\"\n"), 0o644)
+
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.db.SetPreference(db.DefaultUserID, providerModelsPreference, `{"codex": ["o3", "bad model", "gpt-5"]}`)
+
+	resp := env.get("/api/providers/models")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var catalog []providerModels
+	decodeResponse(t, resp, &catalog)
+	if len(catalog) != 2 || catalog[0].Provider != "claude" || catalog[1].Provider != "codex" {
+		t.Fatalf("unexpected providers: %+v", catalog)
+	}
+	if catalog[0].Default != "" || len(catalog[0].Models) != len(modelRegistry["claude"]) {
+		t.Errorf("expected the claude registry only, got %+v", catalog[0])
+	}
+
+	codex := catalog[1]
+	if codex.Default != "o4-mini" {
+		t.Errorf("expected the config.toml default, got %q", codex.Default)
+	}
+	var ids, sources []string
+	for _, m := range codex.Models {
+		ids = append(ids, m.ID)
+		sources = append(sources, m.Source)
+	}
+	if got := strings.Join(ids, ","); got != "o4-mini,o3,gpt-5,gpt-5-codex,gpt-5-mini" {
+		t.Errorf("unexpected codex models %s", got)
+	}
+	if sources[0] != modelSourceCLIConfig || sources[1] != modelSourcePreference || sources[3] != modelSourceRegistry {
+		t.Errorf("unexpected sources %v", sources)
+	}
+
+	resp = env.get("/api/providers/models?provider=claude")
+	decodeResponse(t, resp, &catalog)
+	if len(catalog) != 1 || catalog[0].Provider != "claude" {
+		t.Errorf("expected claude only, got %+v", catalog)
+	}
+	if resp := env.get("/api/providers/models?provider=gemini"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown provider, got %d", resp.Code)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"424242"`)
+	reply := env.server.handleTelegramCommand(context.Background(), telegram.Command{ChatID: 424242, UserID: 424242, Name: "models", Args: "codex"})
+	if !strings.HasPrefix(reply, "codex models:\n• o4-mini (default)\n• o3\n") || strings.Contains(reply, "claude") {
+		t.Errorf("unexpected /models reply %q", reply)
+	}
+}
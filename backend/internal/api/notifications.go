@@ -0,0 +1,492 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+// Notification preferences:
+//
+//	telegram_attention_notifications  false disables Telegram attention messages
+//	telegram_review_notifications     false disables Telegram messages about tasks moved to review
+//	telegram_digest_notifications     true sends the daily digest to Telegram too
+//	telegram_tunnel_notifications     false disables Telegram tunnel open/close messages
+//	telegram_ci_notifications         false disables Telegram messages about failed CI checks
+//	ntfy                              {"server": "https://ntfy.sh", "topic": "...", "token": "..."}
+//	webpush_subscriptions             browser PushSubscriptions (managed via the API)
+//	email                             SMTP settings and which emails to send (see emailConfig)
+//
+// Without the ntfy or email preference, the same settings under
+// notifications in the configuration file are used (see config.go). Messages
+// are written in the language and telegram_language preferences (see
+// language.go).
+const (
+	ntfyPreference          = "ntfy"
+	webPushPreference       = "webpush_subscriptions"
+	emailPreference         = "email"
+	notificationSendTimeout = 20 * time.Second
+)
+
+// notificationEvent is what a notification is about. Each channel's
+// preferences say which events it gets.
+type notificationEvent string
+
+const (
+	eventAttention notificationEvent = "attention" // sessions waiting for input or about to time out, budget alerts
+	eventInReview  notificationEvent = "in_review" // tasks moved into review
+	eventDigest    notificationEvent = "digest"    // the daily digest
+	eventTest      notificationEvent = "test"      // test messages, sent to every configured channel
+	eventUpdate    notificationEvent = "update"    // a new Codeburg release
+	eventCIFailed  notificationEvent = "ci_failed" // checks failed on a commit pushed for a task
+)
+
+type ntfyConfig struct {
+	Server string `json:"server,omitempty"`
+	Topic  string `json:"topic"`
+	Token  string `json:"token,omitempty"`
+}
+
+// emailConfig is the email preference, e.g.
+//
+//	{"host": "smtp.example.com", "username": "me", "password": "...",
+//	 "from": "Codeburg <codeburg@example.com>", "to": ["me@example.com"],
+//	 "review": false}
+//
+// Attention, review, digest and CI emails are all sent unless turned off.
+type emailConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port,omitempty"`
+	Security string   `json:"security,omitempty"` // starttls (default), tls or none
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+
+	Attention *bool `json:"attention,omitempty"`
+	Review    *bool `json:"review,omitempty"`
+	Digest    *bool `json:"digest,omitempty"`
+	CI        *bool `json:"ci,omitempty"`
+}
+
+// wants reports whether the user asked for emails about event.
+func (c emailConfig) wants(event notificationEvent) bool {
+	enabled := func(b *bool) bool { return b == nil || *b }
+	switch event {
+	case eventAttention:
+		return enabled(c.Attention)
+	case eventInReview:
+		return enabled(c.Review)
+	case eventDigest:
+		return enabled(c.Digest)
+	case eventCIFailed:
+		return enabled(c.CI)
+	}
+	return true
+}
+
+// emailSettings returns the email preference, or the configuration file's
+// email settings, if email is set up.
+func (s *Server) emailSettings() (emailConfig, bool) {
+	var cfg emailConfig
+	pref, err := s.db.GetPreference(db.DefaultUserID, emailPreference)
+	if err != nil {
+		if file := s.currentConfig().Notifications.Email; file != nil {
+			return *file, file.Host != "" && len(file.To) > 0
+		}
+		return cfg, false
+	}
+	if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+		slog.Warn("invalid email preference", "error", err)
+		return cfg, false
+	}
+	return cfg, cfg.Host != "" && len(cfg.To) > 0
+}
+
+// telegramNotifier sends notifications to the user's Telegram chat and
+// remembers which session each message is about, so reactions route back.
+type telegramNotifier struct {
+	s      *Server
+	bot    *telegram.Bot
+	chatID int64
+	lang   string
+}
+
+func (n *telegramNotifier) Name() string { return "telegram" }
+
+func (n *telegramNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	text := "🔔 " + msg.Title
+	if msg.Body != "" {
+		text += "\n" + msg.Body
+	}
+	if msg.URL != "" {
+		text += "\n🔗 " + msg.URL
+	}
+	for _, link := range msg.Links {
+		text += "\n📦 " + link.Title + ": " + link.URL
+	}
+	if msg.SessionID != "" {
+		text += "\n" + localize(n.lang, msgReactHint)
+	}
+	messageID, err := n.bot.Send(n.chatID, text)
+	if err != nil {
+		return err
+	}
+	if msg.SessionID != "" {
+		if err := n.s.db.RecordTelegramMessage(n.chatID, messageID, msg.SessionID); err != nil {
+			slog.Warn("failed to record telegram message", "session_id", msg.SessionID, "error", err)
+		}
+	}
+	return nil
+}
+
+// notificationSinks returns every configured notification channel that
+// wants event. The daily digest is too long for push notifications, so only
+// email and Telegram get it.
+func (s *Server) notificationSinks(event notificationEvent) []notify.Notifier {
+	var sinks []notify.Notifier
+
+	if bot := s.currentTelegramBot(); bot != nil && s.telegramWants(event) {
+		if chatID, ok := s.telegramChatID(); ok {
+			sinks = append(sinks, &telegramNotifier{s: s, bot: bot, chatID: chatID, lang: s.telegramLanguage()})
+		}
+	}
+
+	if cfg, ok := s.emailSettings(); ok && cfg.wants(event) {
+		sinks = append(sinks, &notify.Email{
+			Host: cfg.Host, Port: cfg.Port, Security: cfg.Security,
+			Username: cfg.Username, Password: cfg.Password,
+			From: cfg.From, To: cfg.To,
+		})
+	}
+
+	if event == eventDigest {
+		return sinks
+	}
+
+	if pref, err := s.db.GetPreference(db.DefaultUserID, ntfyPreference); err == nil {
+		var cfg ntfyConfig
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			slog.Warn("invalid ntfy preference", "error", err)
+		} else if cfg.Topic != "" {
+			sinks = append(sinks, &notify.Ntfy{Server: cfg.Server, Topic: cfg.Topic, Token: cfg.Token})
+		}
+	} else if cfg := s.currentConfig().Notifications.Ntfy; cfg != nil {
+		sinks = append(sinks, &notify.Ntfy{Server: cfg.Server, Topic: cfg.Topic, Token: cfg.Token})
+	}
+
+	if subs := s.webPushSubscriptions(); len(subs) > 0 {
+		key, err := s.vapidKey()
+		if err != nil {
+			slog.Warn("web push disabled: no vapid key", "error", err)
+		} else {
+			subject := s.webOrigin()
+			if !strings.HasPrefix(subject, "https://") {
+				subject = ""
+			}
+			for _, sub := range subs {
+				sinks = append(sinks, &notify.WebPush{Key: key, Subscription: sub, Subject: subject})
+			}
+		}
+	}
+
+	return sinks
+}
+
+// telegramWants reports whether the Telegram preferences ask for messages
+// about event. The digest is opt-in, since /report covers the same ground.
+func (s *Server) telegramWants(event notificationEvent) bool {
+	pref := func(key string) string {
+		if p, err := s.db.GetPreference(db.DefaultUserID, key); err == nil {
+			return p.Value
+		}
+		return ""
+	}
+	switch event {
+	case eventAttention:
+		return pref("telegram_attention_notifications") != "false"
+	case eventInReview:
+		return pref("telegram_review_notifications") != "false"
+	case eventDigest:
+		return pref("telegram_digest_notifications") == "true"
+	case eventCIFailed:
+		return pref("telegram_ci_notifications") != "false"
+	}
+	return true
+}
+
+// notifySessionNeedsAttention tells the user, on every configured channel,
+// that a session is waiting for input.
+func (s *Server) notifySessionNeedsAttention(taskID, sessionID string) {
+	sinks := s.notificationSinks(eventAttention)
+	if len(sinks) == 0 {
+		return
+	}
+
+	var provider, taskTitle, sessionTitle string
+	if session, err := s.db.GetSession(sessionID); err == nil {
+		provider, sessionTitle = session.Provider, session.Title
+	}
+	msg := notify.Message{SessionID: sessionID}
+	if taskID != "" {
+		if task, err := s.db.GetTask(taskID); err == nil {
+			taskTitle = task.Title
+		}
+		msg.Links = s.sessionArtifactLinks(taskID, sessionID)
+		msg.URL = s.deepLink(sessionPath(taskID, sessionID))
+		msg.Image = s.deepLinkQR(msg.URL)
+	}
+
+	// Name the session by its task and its own title, where it has them.
+	name := taskTitle
+	if sessionTitle != "" {
+		if name != "" {
+			name += " · "
+		}
+		name += sessionTitle
+	}
+
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		localized := msg
+		localized.Title = localize(lang, msgSessionNeedsAttention)
+		if name != "" {
+			localized.Title = localize(lang, msgTaskNeedsAttention, name)
+		}
+		if provider != "" {
+			localized.Body = localize(lang, msgSessionWaiting, provider)
+		}
+		return localized
+	})
+}
+
+// notifyTaskInReview tells the user, on the channels that want it, that a
+// task moved into review. The task is read again so a pull request opened
+// by the workflow is linked.
+func (s *Server) notifyTaskInReview(taskID string) {
+	sinks := s.notificationSinks(eventInReview)
+	if len(sinks) == 0 {
+		return
+	}
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		slog.Warn("failed to load task for review notification", "task_id", taskID, "error", err)
+		return
+	}
+	projectName := task.ProjectID
+	if project, err := s.db.GetProject(task.ProjectID); err == nil {
+		projectName = project.Name
+	}
+	url := s.deepLink(taskPath(task.ID))
+
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		msg := notify.Message{
+			Title: localize(lang, msgTaskInReview, task.Title),
+			Body:  localize(lang, msgTaskInReviewBody, projectName),
+			URL:   url,
+		}
+		if task.PRURL != nil && *task.PRURL != "" {
+			msg.Links = []notify.Link{{Title: localize(lang, msgPullRequest), URL: *task.PRURL}}
+		}
+		return msg
+	})
+}
+
+// sinkLanguage is the language a sink's messages are written in.
+func (s *Server) sinkLanguage(sink notify.Notifier) string {
+	if tg, ok := sink.(*telegramNotifier); ok {
+		return tg.lang
+	}
+	return s.userLanguage()
+}
+
+// deliverLocalized sends each sink the message build returns for its
+// language, and returns per-sink errors like deliverNotification.
+func (s *Server) deliverLocalized(sinks []notify.Notifier, build func(lang string) notify.Message) []error {
+	errs := make([]error, len(sinks))
+	for i, sink := range sinks {
+		errs[i] = s.deliverNotification(build(s.sinkLanguage(sink)), []notify.Notifier{sink})[0]
+	}
+	return errs
+}
+
+// deliverNotification sends msg to each sink and returns per-sink errors.
+// Expired Web Push subscriptions are dropped.
+func (s *Server) deliverNotification(msg notify.Message, sinks []notify.Notifier) []error {
+	errs := make([]error, len(sinks))
+	for i, sink := range sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationSendTimeout)
+		err := sink.Notify(ctx, msg)
+		cancel()
+		if err == nil {
+			continue
+		}
+		errs[i] = err
+		if wp, ok := sink.(*notify.WebPush); ok && errors.Is(err, notify.ErrGone) {
+			s.removeWebPushSubscription(wp.Subscription.Endpoint)
+			continue
+		}
+		slog.Warn("notification failed", "sink", sink.Name(), "session_id", msg.SessionID, "error", err)
+	}
+	return errs
+}
+
+// webOrigin returns the configured public origin, or "".
+func (s *Server) webOrigin() string {
+	config, err := s.auth.loadConfig()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(config.Auth.Origin, "/")
+}
+
+// vapidKey loads the Web Push application server key, generating it on first
+// use. It lives next to the JWT secret, outside the preferences API.
+func (s *Server) vapidKey() (*notify.VAPIDKey, error) {
+	s.webPushMu.Lock()
+	defer s.webPushMu.Unlock()
+	if s.webPushKey != nil {
+		return s.webPushKey, nil
+	}
+
+	keyPath := filepath.Join(filepath.Dir(s.auth.configPath), ".vapid_key")
+	if data, err := os.ReadFile(keyPath); err == nil {
+		key, err := notify.ParseVAPIDKey(string(data))
+		if err == nil {
+			s.webPushKey = key
+			return key, nil
+		}
+		slog.Warn("corrupt vapid key file, regenerating (existing subscriptions must resubscribe)", "error", err)
+	}
+
+	key, err := notify.GenerateVAPIDKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, []byte(key.Encode()), 0600); err != nil {
+		return nil, err
+	}
+	s.webPushKey = key
+	return key, nil
+}
+
+func (s *Server) webPushSubscriptions() []notify.Subscription {
+	pref, err := s.db.GetPreference(db.DefaultUserID, webPushPreference)
+	if err != nil {
+		return nil
+	}
+	var subs []notify.Subscription
+	if err := json.Unmarshal([]byte(pref.Value), &subs); err != nil {
+		slog.Warn("invalid webpush_subscriptions preference", "error", err)
+		return nil
+	}
+	return subs
+}
+
+// updateWebPushSubscriptions applies fn to the stored subscriptions.
+func (s *Server) updateWebPushSubscriptions(fn func([]notify.Subscription) []notify.Subscription) error {
+	s.webPushMu.Lock()
+	defer s.webPushMu.Unlock()
+	data, err := json.Marshal(fn(s.webPushSubscriptions()))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.SetPreference(db.DefaultUserID, webPushPreference, string(data))
+	return err
+}
+
+func (s *Server) removeWebPushSubscription(endpoint string) {
+	err := s.updateWebPushSubscriptions(func(subs []notify.Subscription) []notify.Subscription {
+		kept := subs[:0]
+		for _, sub := range subs {
+			if sub.Endpoint != endpoint {
+				kept = append(kept, sub)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		slog.Warn("failed to remove web push subscription", "error", err)
+	}
+}
+
+func (s *Server) handleGetWebPushKey(w http.ResponseWriter, r *http.Request) {
+	key, err := s.vapidKey()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load web push key: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"publicKey": key.PublicKey()})
+}
+
+func (s *Server) handleAddWebPushSubscription(w http.ResponseWriter, r *http.Request) {
+	var sub notify.Subscription
+	if err := decodeJSON(r, &sub); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !strings.HasPrefix(sub.Endpoint, "https://") || sub.Keys.P256dh == "" || sub.Keys.Auth == "" {
+		writeError(w, http.StatusBadRequest, "endpoint (https) and keys.p256dh/keys.auth are required")
+		return
+	}
+
+	err := s.updateWebPushSubscriptions(func(subs []notify.Subscription) []notify.Subscription {
+		for i := range subs {
+			if subs[i].Endpoint == sub.Endpoint {
+				subs[i] = sub
+				return subs
+			}
+		}
+		return append(subs, sub)
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save subscription")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteWebPushSubscription(w http.ResponseWriter, r *http.Request) {
+	endpoint := r.URL.Query().Get("endpoint")
+	if endpoint == "" {
+		writeError(w, http.StatusBadRequest, "endpoint is required")
+		return
+	}
+	s.removeWebPushSubscription(endpoint)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type notificationTestResult struct {
+	Sink  string `json:"sink"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleTestNotification sends a test message to every configured sink.
+func (s *Server) handleTestNotification(w http.ResponseWriter, r *http.Request) {
+	sinks := s.notificationSinks(eventTest)
+	errs := s.deliverLocalized(sinks, func(lang string) notify.Message {
+		return notify.Message{Title: localize(lang, msgTestTitle), Body: localize(lang, msgTestBody), URL: s.webOrigin()}
+	})
+
+	results := make([]notificationTestResult, len(sinks))
+	for i, sink := range sinks {
+		results[i] = notificationTestResult{Sink: sink.Name()}
+		if errs[i] != nil {
+			results[i].Error = fmt.Sprint(errs[i])
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
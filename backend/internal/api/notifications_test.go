@@ -0,0 +1,228 @@
+package api
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+)
+
+func TestNotifySessionNeedsAttention_Ntfy(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	received := make(chan map[string]any, 1)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix login"}), &task)
+	session, err := env.server.db.CreateSession(db.CreateSessionInput{
+		TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "terminal",
+	})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	env.server.notifySessionNeedsAttention(task.ID, session.ID)
+
+	body := <-received
+	if body["topic"] != "cb-alerts" || body["title"] != "Fix login needs attention" {
+		t.Errorf("unexpected ntfy payload: %v", body)
+	}
+}
+
+func TestWebPushSubscriptions(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	resp := env.get("/api/notifications/webpush/key")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var key map[string]string
+	decodeResponse(t, resp, &key)
+	if key["publicKey"] == "" {
+		t.Fatal("expected a public key")
+	}
+	// The key is persisted, not regenerated on restart.
+	env.server.webPushKey = nil
+	var again map[string]string
+	decodeResponse(t, env.get("/api/notifications/webpush/key"), &again)
+	if again["publicKey"] != key["publicKey"] {
+		t.Error("expected the vapid key to be reloaded from disk")
+	}
+
+	if resp := env.post("/api/notifications/webpush/subscriptions", map[string]any{"endpoint": "http://insecure"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid subscription, got %d", resp.Code)
+	}
+
+	uaKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	sub := map[string]any{
+		"endpoint": "https://push.example.com/send/abc",
+		"keys": map[string]string{
+			"p256dh": base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes()),
+			"auth":   base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef")),
+		},
+	}
+	for range 2 {
+		if resp := env.post("/api/notifications/webpush/subscriptions", sub); resp.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", resp.Code, resp.Body.String())
+		}
+	}
+	if subs := env.server.webPushSubscriptions(); len(subs) != 1 {
+		t.Fatalf("expected 1 deduplicated subscription, got %d", len(subs))
+	}
+
+	resp = env.delete("/api/notifications/webpush/subscriptions?endpoint=" + url.QueryEscape("https://push.example.com/send/abc"))
+	if resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if subs := env.server.webPushSubscriptions(); len(subs) != 0 {
+		t.Errorf("expected subscription to be removed, got %d", len(subs))
+	}
+}
+
+func TestDeliverNotification_DropsGoneSubscription(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	push := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer push.Close()
+
+	uaKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	var sub notify.Subscription
+	sub.Endpoint = push.URL + "/send/gone"
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes())
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString([]byte("0123456789abcdef"))
+	env.server.updateWebPushSubscriptions(func([]notify.Subscription) []notify.Subscription {
+		return []notify.Subscription{sub}
+	})
+
+	key, err := env.server.vapidKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &notify.WebPush{Key: key, Subscription: sub, Client: push.Client()}
+	env.server.deliverNotification(notify.Message{Title: "x"}, []notify.Notifier{sink})
+
+	if subs := env.server.webPushSubscriptions(); len(subs) != 0 {
+		t.Errorf("expected gone subscription to be dropped, got %d", len(subs))
+	}
+}
+
+func TestNotifySessionNeedsAttention_Language(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	received := make(chan map[string]any, 1)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+	env.server.db.SetPreference(db.DefaultUserID, languagePreference, `"es-ES"`)
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "p", Path: t.TempDir()})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Arreglar login"})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{
+		TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "terminal",
+	})
+
+	env.server.notifySessionNeedsAttention(task.ID, session.ID)
+
+	body := <-received
+	if body["title"] != "Arreglar login necesita atención" || body["message"] != "La sesión de claude está esperando una respuesta." {
+		t.Errorf("expected a Spanish notification, got %v", body)
+	}
+}
+
+func TestNotificationSinksByEvent(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	cfg, _ := json.Marshal(ntfyConfig{Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+	env.server.db.SetPreference(db.DefaultUserID, emailPreference, `{"host": "smtp.example.com", "from": "cb@example.com", "to": ["me@example.com"], "review": false}`)
+
+	names := func(event notificationEvent) string {
+		var names []string
+		for _, sink := range env.server.notificationSinks(event) {
+			names = append(names, sink.Name())
+		}
+		return strings.Join(names, ",")
+	}
+	for event, want := range map[notificationEvent]string{
+		eventAttention: "email,ntfy",
+		eventInReview:  "ntfy",
+		eventDigest:    "email",
+		eventTest:      "email,ntfy",
+	} {
+		if got := names(event); got != want {
+			t.Errorf("%s: got sinks %q, want %q", event, got, want)
+		}
+	}
+
+	// Without recipients email isn't set up at all.
+	env.server.db.SetPreference(db.DefaultUserID, emailPreference, `{"host": "smtp.example.com", "from": "cb@example.com"}`)
+	if got := names(eventDigest); got != "" {
+		t.Errorf("expected no digest sinks, got %q", got)
+	}
+}
+
+func TestNotifyTaskInReview(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	received := make(chan map[string]any, 1)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "webapp", "path": createTestGitRepo(t)}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix login"}), &task)
+	if resp := env.patch("/api/tasks/"+task.ID, map[string]string{"status": "in_review"}); resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	select {
+	case body := <-received:
+		if body["title"] != "Fix login is ready for review" || body["message"] != "Moved to review in webapp." {
+			t.Errorf("unexpected ntfy payload: %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no review notification")
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// nextCursorHeader carries the cursor for the next page of a paged list.
+// Paged responses keep the plain JSON array body, so clients that do not
+// page see no difference.
+const nextCursorHeader = "X-Next-Cursor"
+
+// parsePage reads the limit and cursor query parameters. Without them the
+// whole list is returned.
+func parsePage(r *http.Request) (db.Page, error) {
+	q := r.URL.Query()
+	page := db.Page{Cursor: q.Get("cursor")}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > db.MaxPageLimit {
+			return db.Page{}, fmt.Errorf("limit must be between 1 and %d", db.MaxPageLimit)
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// writePage writes one page of a list.
+func writePage(w http.ResponseWriter, items any, next string) {
+	if next != "" {
+		w.Header().Set(nextCursorHeader, next)
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func writeListError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, db.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "failed to list "+what)
+}
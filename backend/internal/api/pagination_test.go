@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestListTasks_Pagination(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	projResp := env.post("/api/projects", map[string]string{"name": "paged", "path": createTestGitRepo(t)})
+	var project db.Project
+	decodeResponse(t, projResp, &project)
+	for _, title := range []string{"one", "two", "three"} {
+		env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": title})
+	}
+
+	resp := env.get("/api/tasks?project=" + project.ID + "&limit=2")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("first page: %d %s", resp.Code, resp.Body.String())
+	}
+	var first []db.Task
+	decodeResponse(t, resp, &first)
+	next := resp.Header().Get(nextCursorHeader)
+	if len(first) != 2 || next == "" {
+		t.Fatalf("first page: %d tasks, cursor %q", len(first), next)
+	}
+	if first[0].Title != "three" || first[1].Title != "two" {
+		t.Errorf("expected newest first, got %q, %q", first[0].Title, first[1].Title)
+	}
+
+	resp = env.get("/api/tasks?project=" + project.ID + "&limit=2&cursor=" + next)
+	var second []db.Task
+	decodeResponse(t, resp, &second)
+	if len(second) != 1 || second[0].Title != "one" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+	if resp.Header().Get(nextCursorHeader) != "" {
+		t.Error("last page should not have a next cursor")
+	}
+
+	// Unpaged requests still get everything.
+	resp = env.get("/api/tasks?project=" + project.ID)
+	var all []db.Task
+	decodeResponse(t, resp, &all)
+	if len(all) != 3 {
+		t.Errorf("expected 3 tasks, got %d", len(all))
+	}
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=2&sort=title", "cursor=bogus!"} {
+		if resp := env.get("/api/tasks?" + query); resp.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, resp.Code)
+		}
+	}
+}
+
+func TestListSessionMessages_Pagination(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "chat", Path: t.TempDir()})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	for seq := int64(1); seq <= 3; seq++ {
+		env.server.db.CreateAgentMessage(db.CreateAgentMessageInput{
+			SessionID:   session.ID,
+			Seq:         seq,
+			Kind:        "user-text",
+			PayloadJSON: `{"kind":"user-text","text":"hi"}`,
+		})
+	}
+
+	resp := env.get("/api/sessions/" + session.ID + "/messages?limit=2")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var messages []ChatMessage
+	decodeResponse(t, resp, &messages)
+	if len(messages) != 2 || messages[0].Seq != 1 || messages[0].SessionID != session.ID {
+		t.Fatalf("unexpected first page: %+v", messages)
+	}
+
+	resp = env.get("/api/sessions/" + session.ID + "/messages?cursor=" + resp.Header().Get(nextCursorHeader))
+	decodeResponse(t, resp, &messages)
+	if len(messages) != 1 || messages[0].Seq != 3 {
+		t.Fatalf("unexpected second page: %+v", messages)
+	}
+}
@@ -64,7 +64,7 @@ func dbPasskeyToCredential(p db.Passkey) webauthn.Credential {
 // challengeStore holds WebAuthn session data in memory with a TTL.
 type challengeStore struct {
 	mu       sync.Mutex
-	sessions map[string]challengeEntry // key: "register" or "login"
+	sessions map[string]challengeEntry // key: "register", or "login:" and the challenge
 }
 
 type challengeEntry struct {
@@ -81,6 +81,13 @@ func newChallengeStore() *challengeStore {
 func (cs *challengeStore) Save(key string, data *webauthn.SessionData) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	// Logins that were never finished leave their challenges behind.
+	now := time.Now()
+	for k, entry := range cs.sessions {
+		if now.After(entry.expires) {
+			delete(cs.sessions, k)
+		}
+	}
 	cs.sessions[key] = challengeEntry{
 		data:    data,
 		expires: time.Now().Add(5 * time.Minute),
@@ -99,17 +106,23 @@ func (cs *challengeStore) Get(key string) *webauthn.SessionData {
 	return entry.data
 }
 
+// loginChallengeKey is the challenge store key of a login.
+func loginChallengeKey(challenge string) string {
+	return "login:" + challenge
+}
+
 // Handlers
 
 func (s *Server) handlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
-	if s.webauthn == nil {
+	wa := s.passkeys()
+	if wa == nil {
 		writeError(w, http.StatusNotFound, "passkeys not configured (set origin in config)")
 		return
 	}
 
 	user := &codeburgUser{db: s.db}
 
-	creation, session, err := s.webauthn.BeginRegistration(user,
+	creation, session, err := wa.BeginRegistration(user,
 		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
 	)
 	if err != nil {
@@ -123,7 +136,8 @@ func (s *Server) handlePasskeyRegisterBegin(w http.ResponseWriter, r *http.Reque
 }
 
 func (s *Server) handlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
-	if s.webauthn == nil {
+	wa := s.passkeys()
+	if wa == nil {
 		writeError(w, http.StatusNotFound, "passkeys not configured")
 		return
 	}
@@ -135,7 +149,7 @@ func (s *Server) handlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Requ
 	}
 
 	user := &codeburgUser{db: s.db}
-	cred, err := s.webauthn.FinishRegistration(user, *session, r)
+	cred, err := wa.FinishRegistration(user, *session, r)
 	if err != nil {
 		slog.Error("passkey register finish failed", "error", err)
 		writeError(w, http.StatusBadRequest, "registration verification failed")
@@ -173,7 +187,8 @@ func (s *Server) handlePasskeyRegisterFinish(w http.ResponseWriter, r *http.Requ
 }
 
 func (s *Server) handlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
-	if s.webauthn == nil {
+	wa := s.passkeys()
+	if wa == nil {
 		writeError(w, http.StatusNotFound, "passkeys not configured")
 		return
 	}
@@ -184,19 +199,22 @@ func (s *Server) handlePasskeyLoginBegin(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	assertion, session, err := s.webauthn.BeginDiscoverableLogin()
+	assertion, session, err := wa.BeginDiscoverableLogin()
 	if err != nil {
 		slog.Error("passkey login begin failed", "error", err)
 		writeError(w, http.StatusInternalServerError, "failed to begin login")
 		return
 	}
 
-	s.challenges.Save("login", session)
+	// Each login keeps its own challenge, so that logins from several
+	// devices at once don't replace each other's.
+	s.challenges.Save(loginChallengeKey(session.Challenge), session)
 	writeJSON(w, http.StatusOK, assertion)
 }
 
 func (s *Server) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
-	if s.webauthn == nil {
+	wa := s.passkeys()
+	if wa == nil {
 		writeError(w, http.StatusNotFound, "passkeys not configured")
 		return
 	}
@@ -207,7 +225,13 @@ func (s *Server) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	session := s.challenges.Get("login")
+	parsed, err := protocol.ParseCredentialRequestResponse(r)
+	if err != nil {
+		s.authLimiter.record(ip)
+		writeError(w, http.StatusBadRequest, "invalid passkey response")
+		return
+	}
+	session := s.challenges.Get(loginChallengeKey(parsed.Response.CollectedClientData.Challenge))
 	if session == nil {
 		s.authLimiter.record(ip)
 		writeError(w, http.StatusBadRequest, "no login in progress or challenge expired")
@@ -218,7 +242,7 @@ func (s *Server) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request
 		return &codeburgUser{db: s.db}, nil
 	}
 
-	_, cred, err := s.webauthn.FinishPasskeyLogin(handler, *session, r)
+	_, cred, err := wa.ValidatePasskeyLogin(handler, *session, parsed)
 	if err != nil {
 		s.authLimiter.record(ip)
 		slog.Error("passkey login finish failed", "error", err)
@@ -240,6 +264,7 @@ func (s *Server) handlePasskeyLoginFinish(w http.ResponseWriter, r *http.Request
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
+	s.setSessionCookie(w, token)
 
 	writeJSON(w, http.StatusOK, map[string]string{
 		"token": token,
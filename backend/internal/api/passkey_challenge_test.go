@@ -1,9 +1,14 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 )
 
@@ -37,3 +42,55 @@ func TestChallengeStoreExpiresEntries(t *testing.T) {
 		t.Fatalf("expected expired challenge to be nil, got %v", got)
 	}
 }
+
+func TestPasskeyLoginChallengePerLogin(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.applyConfig(&Config{Auth: AuthConfig{Origin: "https://codeburg.example.com"}})
+
+	begin := func() string {
+		t.Helper()
+		resp := env.post("/api/auth/passkey/login/begin", nil)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("begin login: %d %s", resp.Code, resp.Body.String())
+		}
+		var assertion protocol.CredentialAssertion
+		decodeResponse(t, resp, &assertion)
+		return assertion.Response.Challenge.String()
+	}
+	finish := func(challenge string) int {
+		t.Helper()
+		clientData, _ := json.Marshal(map[string]string{
+			"type": "webauthn.get", "challenge": challenge, "origin": "https://codeburg.example.com",
+		})
+		rpIDHash := sha256.Sum256([]byte("codeburg.example.com"))
+		authData := append(rpIDHash[:], 0x05, 0, 0, 0, 1) // user present and verified
+		enc := base64.RawURLEncoding.EncodeToString
+		return env.post("/api/auth/passkey/login/finish", map[string]any{
+			"id": enc([]byte("unknown")), "rawId": enc([]byte("unknown")), "type": "public-key",
+			"response": map[string]string{
+				"clientDataJSON":    enc(clientData),
+				"authenticatorData": enc(authData),
+				"signature":         enc([]byte("sig")),
+				"userHandle":        enc([]byte("default")),
+			},
+		}).Code
+	}
+
+	// Two devices start logging in; the first one's challenge is still
+	// there when it answers.
+	first := begin()
+	second := begin()
+	if first == second {
+		t.Fatal("expected a challenge per login")
+	}
+	if code := finish(first); code != http.StatusUnauthorized {
+		t.Errorf("expected the first login checked against its challenge and refused for the unknown passkey, got %d", code)
+	}
+	if code := finish(first); code != http.StatusBadRequest {
+		t.Errorf("expected a used challenge refused, got %d", code)
+	}
+	if code := finish(second); code != http.StatusUnauthorized {
+		t.Errorf("expected the second login checked against its challenge, got %d", code)
+	}
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/justfile"
+	"github.com/miguel-bm/codeburg/internal/recipes"
+)
+
+const (
+	// pipelineRunHistory is how many finished runs are kept per task.
+	pipelineRunHistory = 10
+	// maxPipelineStepOutput caps the output kept per step; older output is
+	// dropped from the front.
+	maxPipelineStepOutput = 64 << 10
+)
+
+var errPipelineRunning = errors.New("pipeline is already running for this task")
+
+const (
+	PipelineStatusPending   = "pending"
+	PipelineStatusRunning   = "running"
+	PipelineStatusSucceeded = "succeeded"
+	PipelineStatusFailed    = "failed"
+	PipelineStatusSkipped   = "skipped"
+	PipelineStatusCanceled  = "canceled"
+)
+
+// PipelineRun is one execution of a pipeline in a task's worktree. Runs
+// live in memory only.
+type PipelineRun struct {
+	ID           string            `json:"id"`
+	PipelineID   string            `json:"pipelineId"`
+	PipelineName string            `json:"pipelineName"`
+	TaskID       string            `json:"taskId"`
+	Status       string            `json:"status"`
+	Steps        []PipelineRunStep `json:"steps"`
+	StartedAt    time.Time         `json:"startedAt"`
+	FinishedAt   *time.Time        `json:"finishedAt,omitempty"`
+}
+
+type PipelineRunStep struct {
+	Source     string     `json:"source"`
+	Recipe     string     `json:"recipe"`
+	Command    string     `json:"command,omitempty"`
+	Status     string     `json:"status"`
+	ExitCode   *int       `json:"exitCode,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+func (r *PipelineRun) clone() PipelineRun {
+	c := *r
+	c.Steps = append([]PipelineRunStep(nil), r.Steps...)
+	return c
+}
+
+type pipelineRunStore struct {
+	mu      sync.Mutex
+	runs    map[string]*PipelineRun
+	cancels map[string]context.CancelFunc // running runs only
+}
+
+func newPipelineRunStore() *pipelineRunStore {
+	return &pipelineRunStore{
+		runs:    make(map[string]*PipelineRun),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// add registers a new run unless the same pipeline is already running for
+// the task, and drops the task's oldest finished runs.
+func (ps *pipelineRunStore) add(run *PipelineRun, cancel context.CancelFunc) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var finished []*PipelineRun
+	for id, existing := range ps.runs {
+		if existing.TaskID != run.TaskID {
+			continue
+		}
+		if _, running := ps.cancels[id]; running {
+			if existing.PipelineID == run.PipelineID {
+				return errPipelineRunning
+			}
+			continue
+		}
+		finished = append(finished, existing)
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].StartedAt.After(finished[j].StartedAt) })
+	for i := pipelineRunHistory - 1; i < len(finished); i++ {
+		delete(ps.runs, finished[i].ID)
+	}
+
+	ps.runs[run.ID] = run
+	ps.cancels[run.ID] = cancel
+	return nil
+}
+
+// update applies fn to a run and returns a copy of the result. It reports
+// false if the run was dropped in the meantime.
+func (ps *pipelineRunStore) update(id string, fn func(*PipelineRun)) (PipelineRun, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	run, ok := ps.runs[id]
+	if !ok {
+		return PipelineRun{}, false
+	}
+	fn(run)
+	return run.clone(), true
+}
+
+// finish marks a run done and releases its context.
+func (ps *pipelineRunStore) finish(id string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if cancel, ok := ps.cancels[id]; ok {
+		cancel()
+		delete(ps.cancels, id)
+	}
+}
+
+func (ps *pipelineRunStore) get(id string) (PipelineRun, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	run, ok := ps.runs[id]
+	if !ok {
+		return PipelineRun{}, false
+	}
+	return run.clone(), true
+}
+
+// list returns a task's runs, newest first.
+func (ps *pipelineRunStore) list(taskID string) []PipelineRun {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	runs := make([]PipelineRun, 0)
+	for _, run := range ps.runs {
+		if run.TaskID == taskID {
+			runs = append(runs, run.clone())
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs
+}
+
+// cancel stops a running run, reporting whether one was found.
+func (ps *pipelineRunStore) cancel(id string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	cancel, ok := ps.cancels[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// forgetTask cancels and drops a deleted task's runs.
+func (ps *pipelineRunStore) forgetTask(taskID string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for id, run := range ps.runs {
+		if run.TaskID != taskID {
+			continue
+		}
+		if cancel, ok := ps.cancels[id]; ok {
+			cancel()
+		}
+		delete(ps.runs, id)
+	}
+}
+
+// startPipelineRun registers a run and executes it in the background.
+func (s *Server) startPipelineRun(pipeline *db.Pipeline, taskID, workDir string) (PipelineRun, error) {
+	run := &PipelineRun{
+		ID:           db.NewID(),
+		PipelineID:   pipeline.ID,
+		PipelineName: pipeline.Name,
+		TaskID:       taskID,
+		Status:       PipelineStatusRunning,
+		StartedAt:    time.Now().UTC(),
+	}
+	for _, step := range pipeline.Steps {
+		run.Steps = append(run.Steps, PipelineRunStep{
+			Source: step.Source,
+			Recipe: step.Recipe,
+			Status: PipelineStatusPending,
+		})
+	}
+
+	ctx, cancel := context.WithCancel(s.bgCtx)
+	if err := s.pipelineRuns.add(run, cancel); err != nil {
+		cancel()
+		return PipelineRun{}, err
+	}
+	snapshot := run.clone()
+	s.wsHub.BroadcastToTask(taskID, "pipeline_run", snapshot)
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.executePipelineRun(ctx, run.ID, pipeline, taskID, workDir)
+	}()
+	return snapshot, nil
+}
+
+func (s *Server) executePipelineRun(ctx context.Context, runID string, pipeline *db.Pipeline, taskID, workDir string) {
+	defer s.pipelineRuns.finish(runID)
+
+	publish := func(fn func(*PipelineRun)) {
+		if run, ok := s.pipelineRuns.update(runID, fn); ok {
+			s.wsHub.BroadcastToTask(taskID, "pipeline_run", run)
+		}
+	}
+
+	discovered, listErr := recipesMgr.List(workDir)
+	failed := false
+	for i, step := range pipeline.Steps {
+		if ctx.Err() != nil {
+			publish(func(run *PipelineRun) { run.Steps[i].Status = PipelineStatusCanceled })
+			continue
+		}
+		if failed && !pipeline.ContinueOnError {
+			publish(func(run *PipelineRun) { run.Steps[i].Status = PipelineStatusSkipped })
+			continue
+		}
+
+		command, err := pipelineStepCommand(discovered, listErr, step)
+		if err != nil {
+			failed = true
+			publish(func(run *PipelineRun) {
+				run.Steps[i].Status = PipelineStatusFailed
+				run.Steps[i].Error = err.Error()
+			})
+			continue
+		}
+
+		started := time.Now().UTC()
+		publish(func(run *PipelineRun) {
+			run.Steps[i].Command = command
+			run.Steps[i].Status = PipelineStatusRunning
+			run.Steps[i].StartedAt = &started
+		})
+
+		exitCode, err := s.runPipelineStep(ctx, runID, i, taskID, workDir, command)
+		if err == nil && ctx.Err() == nil && len(step.Artifacts) > 0 {
+			if _, pubErr := s.publishArtifactGlobs(workDir, step.Artifacts, publishArtifactInput{
+				TaskID: taskID,
+				Source: db.ArtifactSourcePipeline,
+			}); pubErr != nil {
+				err = fmt.Errorf("publish artifacts: %w", pubErr)
+			}
+		}
+
+		finished := time.Now().UTC()
+		publish(func(run *PipelineRun) {
+			st := &run.Steps[i]
+			st.FinishedAt = &finished
+			if exitCode >= 0 {
+				st.ExitCode = &exitCode
+			}
+			switch {
+			case ctx.Err() != nil:
+				st.Status = PipelineStatusCanceled
+			case err != nil:
+				st.Status = PipelineStatusFailed
+				st.Error = err.Error()
+			default:
+				st.Status = PipelineStatusSucceeded
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			failed = true
+		}
+	}
+
+	finished := time.Now().UTC()
+	publish(func(run *PipelineRun) {
+		run.FinishedAt = &finished
+		switch {
+		case ctx.Err() != nil:
+			run.Status = PipelineStatusCanceled
+		case failed:
+			run.Status = PipelineStatusFailed
+		default:
+			run.Status = PipelineStatusSucceeded
+		}
+	})
+}
+
+// pipelineStepCommand resolves a step against the recipes discovered in the
+// worktree.
+func pipelineStepCommand(discovered []recipes.Recipe, listErr error, step db.PipelineStep) (string, error) {
+	if listErr != nil {
+		return "", fmt.Errorf("list recipes: %w", listErr)
+	}
+	for _, recipe := range discovered {
+		if recipe.Source == step.Source && recipe.Name == step.Recipe {
+			return recipe.CommandWithArgs(step.Args)
+		}
+	}
+	return "", fmt.Errorf("%s recipe %q not found", step.Source, step.Recipe)
+}
+
+// runPipelineStep runs one command and streams its output. It returns the
+// exit code, or -1 if the command did not run to completion.
+func (s *Server) runPipelineStep(ctx context.Context, runID string, step int, taskID, workDir, command string) (int, error) {
+	out := &pipelineOutput{s: s, runID: runID, taskID: taskID, step: step}
+	defer out.flush()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = workDir
+	taskEnv := s.taskEnv(taskID)
+	cmd.Env = append(justfile.RuntimeEnv(), taskEnv...)
+	if taskID != "" {
+		container, err := s.taskContainer(ctx, taskID, nil)
+		if err != nil {
+			return -1, err
+		}
+		if container != nil {
+			s.containers.Wrap(cmd, container, taskEnv)
+		}
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.WaitDelay = 5 * time.Second
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		return exitErr.ExitCode(), fmt.Errorf("exited with status %d", exitErr.ExitCode())
+	default:
+		return -1, err
+	}
+}
+
+// pipelineOutput forwards a step's output line by line to the task's
+// WebSocket channel and keeps its tail on the run.
+type pipelineOutput struct {
+	s      *Server
+	runID  string
+	taskID string
+	step   int
+	buf    []byte
+}
+
+func (o *pipelineOutput) Write(p []byte) (int, error) {
+	o.buf = append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			break
+		}
+		o.emit(string(o.buf[:i+1]))
+		o.buf = o.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (o *pipelineOutput) flush() {
+	if len(o.buf) > 0 {
+		o.emit(string(o.buf))
+		o.buf = nil
+	}
+}
+
+func (o *pipelineOutput) emit(chunk string) {
+	o.s.pipelineRuns.update(o.runID, func(run *PipelineRun) {
+		output := run.Steps[o.step].Output + chunk
+		if len(output) > maxPipelineStepOutput {
+			output = output[len(output)-maxPipelineStepOutput:]
+		}
+		run.Steps[o.step].Output = output
+	})
+	o.s.wsHub.BroadcastToTask(o.taskID, "pipeline_output", map[string]any{
+		"runId": o.runID,
+		"step":  o.step,
+		"data":  chunk,
+	})
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+const maxPipelineSteps = 50
+
+func validatePipelineSteps(steps []db.PipelineStep) string {
+	if len(steps) == 0 {
+		return "a pipeline needs at least one step"
+	}
+	if len(steps) > maxPipelineSteps {
+		return fmt.Sprintf("a pipeline can have at most %d steps", maxPipelineSteps)
+	}
+	for i, step := range steps {
+		if strings.TrimSpace(step.Source) == "" || strings.TrimSpace(step.Recipe) == "" {
+			return fmt.Sprintf("step %d: source and recipe are required", i+1)
+		}
+		for _, pattern := range step.Artifacts {
+			if _, err := normalizeRelativePath(pattern, false); err != nil {
+				return fmt.Sprintf("step %d: artifact %q: %v", i+1, pattern, err)
+			}
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Sprintf("step %d: artifact %q: invalid pattern", i+1, pattern)
+			}
+		}
+	}
+	return ""
+}
+
+func (s *Server) handleListPipelines(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	if _, err := s.db.GetProject(projectID); err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	pipelines, err := s.db.ListPipelines(projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list pipelines")
+		return
+	}
+	writeJSON(w, http.StatusOK, pipelines)
+}
+
+func (s *Server) handleCreatePipeline(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	if _, err := s.db.GetProject(projectID); err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	var input db.CreatePipelineInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	input.ProjectID = projectID
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if msg := validatePipelineSteps(input.Steps); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	pipeline, err := s.db.CreatePipeline(input)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create pipeline")
+		return
+	}
+	writeJSON(w, http.StatusCreated, pipeline)
+}
+
+func (s *Server) handleUpdatePipeline(w http.ResponseWriter, r *http.Request) {
+	var input db.UpdatePipelineInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			writeError(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		input.Name = &name
+	}
+	if input.Steps != nil {
+		if msg := validatePipelineSteps(*input.Steps); msg != "" {
+			writeError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	pipeline, err := s.db.UpdatePipeline(urlParam(r, "id"), input)
+	if err != nil {
+		writeDBError(w, err, "pipeline")
+		return
+	}
+	writeJSON(w, http.StatusOK, pipeline)
+}
+
+func (s *Server) handleDeletePipeline(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.DeletePipeline(urlParam(r, "id")); err != nil {
+		writeDBError(w, err, "pipeline")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRunPipeline starts a pipeline in a task's worktree. Progress is
+// reported over the task's WebSocket channel as pipeline_run and
+// pipeline_output events.
+func (s *Server) handleRunPipeline(w http.ResponseWriter, r *http.Request) {
+	workDir, ok := s.resolveTaskWorkDir(w, r)
+	if !ok {
+		return
+	}
+	taskID := urlParam(r, "id")
+
+	pipeline, err := s.db.GetPipeline(urlParam(r, "pipelineId"))
+	if err != nil {
+		writeDBError(w, err, "pipeline")
+		return
+	}
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	if task.ProjectID != pipeline.ProjectID {
+		writeError(w, http.StatusBadRequest, "pipeline belongs to another project")
+		return
+	}
+
+	run, err := s.startPipelineRun(pipeline, taskID, workDir)
+	if errors.Is(err, errPipelineRunning) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleListPipelineRuns(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.pipelineRuns.list(taskID))
+}
+
+func (s *Server) handleGetPipelineRun(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.pipelineRuns.get(urlParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "pipeline run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (s *Server) handleCancelPipelineRun(w http.ResponseWriter, r *http.Request) {
+	if !s.pipelineRuns.cancel(urlParam(r, "id")) {
+		writeError(w, http.StatusNotFound, "no running pipeline with this ID")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestPipelines_RunFailFast(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	workDir := t.TempDir()
+	makefile := "lint:\n\t@echo linting\n\ntest:\n\t@echo failing >&2; exit 3\n\nbuild:\n\t@echo building\n"
+	if err := os.WriteFile(filepath.Join(workDir, "Makefile"), []byte(makefile), 0644); err != nil {
+		t.Fatalf("write Makefile: %v", err)
+	}
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "pipeline-project", Path: workDir})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "pipeline task"})
+	env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{WorktreePath: &workDir})
+
+	resp := env.post("/api/projects/"+project.ID+"/pipelines", map[string]any{"name": "check"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a pipeline without steps, got %d", resp.Code)
+	}
+	resp = env.post("/api/projects/"+project.ID+"/pipelines", map[string]any{
+		"name": "check",
+		"steps": []map[string]string{
+			{"source": "makefile", "recipe": "lint"},
+			{"source": "makefile", "recipe": "test"},
+			{"source": "makefile", "recipe": "build"},
+		},
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var pipeline db.Pipeline
+	decodeResponse(t, resp, &pipeline)
+
+	resp = env.post("/api/tasks/"+task.ID+"/pipelines/"+pipeline.ID+"/run", nil)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var run PipelineRun
+	decodeResponse(t, resp, &run)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for run.Status == PipelineStatusRunning && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		decodeResponse(t, env.get("/api/pipeline-runs/"+run.ID), &run)
+	}
+	if run.Status != PipelineStatusFailed {
+		t.Fatalf("expected failed run, got %+v", run)
+	}
+	want := []string{PipelineStatusSucceeded, PipelineStatusFailed, PipelineStatusSkipped}
+	for i, status := range want {
+		if run.Steps[i].Status != status {
+			t.Errorf("step %d: expected %s, got %s", i, status, run.Steps[i].Status)
+		}
+	}
+	if run.Steps[0].Output != "linting\n" {
+		t.Errorf("unexpected lint output %q", run.Steps[0].Output)
+	}
+	if code := run.Steps[1].ExitCode; code == nil || *code == 0 {
+		t.Errorf("expected a non-zero exit code, got %v", code)
+	}
+
+	var runs []PipelineRun
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/pipeline-runs"), &runs)
+	if len(runs) != 1 || runs[0].ID != run.ID {
+		t.Errorf("expected the run to be listed, got %+v", runs)
+	}
+}
@@ -3,10 +3,13 @@ package api
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/miguel-bm/codeburg/internal/db"
 	"github.com/miguel-bm/codeburg/internal/portsuggest"
 	"github.com/miguel-bm/codeburg/internal/tunnel"
 )
@@ -28,12 +31,15 @@ type tunnelRef struct {
 }
 
 type taskPortSuggestion struct {
-	Port           int                  `json:"port"`
-	Sources        []string             `json:"sources"`
-	FirstSeenAt    time.Time            `json:"firstSeenAt"`
-	LastSeenAt     time.Time            `json:"lastSeenAt"`
-	Status         portSuggestionStatus `json:"status"`
-	ExistingTunnel *tunnelRef           `json:"existingTunnel,omitempty"`
+	Port           int                     `json:"port"`
+	Sources        []string                `json:"sources"`
+	Services       []string                `json:"services,omitempty"` // compose services or containers behind the port
+	FirstSeenAt    time.Time               `json:"firstSeenAt"`
+	LastSeenAt     time.Time               `json:"lastSeenAt"`
+	Status         portSuggestionStatus    `json:"status"`
+	ExistingTunnel *tunnelRef              `json:"existingTunnel,omitempty"`
+	ClaimedBy      []string                `json:"claimedBy,omitempty"`  // other task IDs that want this port
+	Assignment     *portsuggest.Assignment `json:"assignment,omitempty"` // where this task runs it instead
 }
 
 // handleListTaskPortSuggestions lists detected/suggested ports for a task.
@@ -47,16 +53,25 @@ func (s *Server) handleListTaskPortSuggestions(w http.ResponseWriter, r *http.Re
 	}
 
 	raw := s.portSuggest.ListTask(taskID)
+	assignments := s.portSuggest.Assignments(taskID)
 	out := make([]taskPortSuggestion, 0, len(raw))
 	for _, suggestion := range raw {
 		row := taskPortSuggestion{
 			Port:        suggestion.Port,
 			Sources:     suggestion.Sources,
+			Services:    suggestion.Services,
 			FirstSeenAt: suggestion.FirstSeenAt,
 			LastSeenAt:  suggestion.LastSeenAt,
 			Status:      portSuggestionStatusSuggested,
 		}
 
+		row.ClaimedBy = s.portSuggest.ClaimedBy(suggestion.Port, taskID)
+		for _, a := range assignments {
+			if a.Port == suggestion.Port {
+				row.Assignment = &a
+			}
+		}
+
 		if existing := s.tunnels.FindByPort(suggestion.Port); existing != nil {
 			row.ExistingTunnel = mapTunnelRef(*existing, s)
 			if existing.TaskID == taskID {
@@ -74,11 +89,13 @@ func (s *Server) handleListTaskPortSuggestions(w http.ResponseWriter, r *http.Re
 	})
 }
 
-// handleScanTaskPorts triggers an on-demand listener scan.
+// handleScanTaskPorts triggers an on-demand listener scan. It also looks
+// for the ports of the task's containers, so dev servers running in Docker
+// are suggested under their service names.
 func (s *Server) handleScanTaskPorts(w http.ResponseWriter, r *http.Request) {
 	taskID := chi.URLParam(r, "id")
 
-	_, err := s.db.GetTask(taskID)
+	task, err := s.db.GetTask(taskID)
 	if err != nil {
 		writeDBError(w, err, "task")
 		return
@@ -97,9 +114,100 @@ func (s *Server) handleScanTaskPorts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if dir := s.taskPortScanDir(task); dir != "" {
+		docker, err := s.portSuggest.ScanDocker(ctx, taskID, dir)
+		if err != nil {
+			slog.Debug("docker port scan failed", "task_id", taskID, "error", err)
+		}
+		result.Docker = docker
+	}
+
 	writeJSON(w, http.StatusOK, result)
 }
 
+// taskPortScanDir is where a task's compose file and containers are looked
+// for: its worktree, else its project's checkout.
+func (s *Server) taskPortScanDir(task *db.Task) string {
+	if task.WorktreePath != nil && *task.WorktreePath != "" {
+		return *task.WorktreePath
+	}
+	if project, err := s.db.GetProject(task.ProjectID); err == nil {
+		return project.Path
+	}
+	return ""
+}
+
+// handleListTaskPortAssignments lists the task's alternate port assignments.
+func (s *Server) handleListTaskPortAssignments(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.portSuggest.Assignments(taskID))
+}
+
+// handleAssignTaskPort moves a task's process off a contested port. Without
+// an explicit port in the body, a free alternate is picked. The assignment
+// applies to sessions and recipes started afterwards.
+func (s *Server) handleAssignTaskPort(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	port, err := strconv.Atoi(chi.URLParam(r, "port"))
+	if err != nil || port <= 0 || port > 65535 {
+		writeError(w, http.StatusBadRequest, "invalid port")
+		return
+	}
+
+	var input struct {
+		AssignedPort int    `json:"assignedPort,omitempty"`
+		EnvVar       string `json:"envVar,omitempty"` // defaults to PORT
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	if input.AssignedPort == 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		input.AssignedPort, err = s.portSuggest.AlternatePort(ctx, port)
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+	}
+
+	assignment, err := s.portSuggest.Assign(taskID, port, input.AssignedPort, input.EnvVar)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, assignment)
+}
+
+// handleUnassignTaskPort removes an alternate port assignment.
+func (s *Server) handleUnassignTaskPort(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+	port, err := strconv.Atoi(chi.URLParam(r, "port"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid port")
+		return
+	}
+
+	if !s.portSuggest.Unassign(taskID, port) {
+		writeError(w, http.StatusNotFound, "port assignment not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func mapTunnelRef(info tunnel.TunnelInfo, s *Server) *tunnelRef {
 	ref := &tunnelRef{
 		ID:     info.ID,
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/portsuggest"
+)
+
+func TestTaskPortAssignments(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _ := createRunningTaskSession(t, env, "claude")
+	path := "/api/tasks/" + task.ID + "/port-assignments"
+
+	resp := env.request("PUT", path+"/5173", map[string]any{"assignedPort": 51731, "envVar": "bad-name"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid env var, got %d", resp.Code)
+	}
+
+	resp = env.request("PUT", path+"/5173", map[string]any{"assignedPort": 51731})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var assignment portsuggest.Assignment
+	decodeResponse(t, resp, &assignment)
+	if assignment.Port != 5173 || assignment.AssignedPort != 51731 || assignment.EnvVar != "PORT" {
+		t.Fatalf("unexpected assignment: %+v", assignment)
+	}
+
+	var list []portsuggest.Assignment
+	decodeResponse(t, env.get(path), &list)
+	if len(list) != 1 {
+		t.Fatalf("expected one assignment, got %+v", list)
+	}
+
+	// New processes for the task get the alternate port.
+	if opts := env.server.runtimeCallbacks(task.ID, "s1"); !slices.Contains(opts.Env, "PORT=51731") {
+		t.Errorf("expected PORT in runtime env, got %v", opts.Env)
+	}
+	if opts := env.server.runtimeCallbacks("other-task", "s2"); len(opts.Env) != 0 {
+		t.Errorf("expected no port env for other tasks, got %v", opts.Env)
+	}
+
+	if resp := env.delete(path + "/5173"); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if resp := env.delete(path + "/5173"); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a removed assignment, got %d", resp.Code)
+	}
+}
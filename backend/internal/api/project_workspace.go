@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -115,6 +114,10 @@ func (s *Server) handleReadProjectFile(w http.ResponseWriter, r *http.Request) {
 		writeDBError(w, err, "project")
 		return
 	}
+	if wantsRawFile(r) {
+		serveRawFile(w, r, project.Path)
+		return
+	}
 
 	relPath, err := normalizeRelativePath(r.URL.Query().Get("path"), false)
 	if err != nil {
@@ -194,7 +197,7 @@ func (s *Server) handleCreateProjectFileEntry(w http.ResponseWriter, r *http.Req
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if isProtectedProjectPath(relPath) {
+	if newPathGuard(project).protects(relPath) {
 		writeError(w, http.StatusBadRequest, "path is protected")
 		return
 	}
@@ -266,6 +269,10 @@ func (s *Server) handlePutProjectFile(w http.ResponseWriter, r *http.Request) {
 		writeDBError(w, err, "project")
 		return
 	}
+	if !isJSONRequest(r) {
+		s.writeRawFile(w, r, project.Path, newPathGuard(project))
+		return
+	}
 
 	var req writeProjectFileRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -278,7 +285,7 @@ func (s *Server) handlePutProjectFile(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if isProtectedProjectPath(relPath) {
+	if newPathGuard(project).protects(relPath) {
 		writeError(w, http.StatusBadRequest, "path is protected")
 		return
 	}
@@ -344,7 +351,7 @@ func (s *Server) handleDeleteProjectFile(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if isProtectedProjectPath(relPath) {
+	if newPathGuard(project).protectsTree(project.Path, relPath) {
 		writeError(w, http.StatusBadRequest, "path is protected")
 		return
 	}
@@ -380,7 +387,7 @@ func (s *Server) handleDeleteProjectFile(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func renameFileInRoot(root string, req renameFileRequest) (int, string) {
+func renameFileInRoot(root string, req renameFileRequest, guard pathGuard) (int, string) {
 	fromRel, err := normalizeRelativePath(req.From, false)
 	if err != nil {
 		return http.StatusBadRequest, err.Error()
@@ -389,7 +396,7 @@ func renameFileInRoot(root string, req renameFileRequest) (int, string) {
 	if err != nil {
 		return http.StatusBadRequest, err.Error()
 	}
-	if isProtectedProjectPath(fromRel) || isProtectedProjectPath(toRel) {
+	if guard.protectsTree(root, fromRel) || guard.protects(toRel) {
 		return http.StatusBadRequest, "path is protected"
 	}
 
@@ -438,7 +445,7 @@ func (s *Server) handleRenameProjectFile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if status, msg := renameFileInRoot(project.Path, req); status != 0 {
+	if status, msg := renameFileInRoot(project.Path, req, newPathGuard(project)); status != 0 {
 		writeError(w, status, msg)
 		return
 	}
@@ -465,12 +472,12 @@ func (s *Server) handleRenameProjectFile(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-func duplicateFileInRoot(root string, path string) (string, int, string) {
+func duplicateFileInRoot(root string, path string, guard pathGuard) (string, int, string) {
 	relPath, err := normalizeRelativePath(path, false)
 	if err != nil {
 		return "", http.StatusBadRequest, err.Error()
 	}
-	if isProtectedProjectPath(relPath) {
+	if guard.protects(relPath) {
 		return "", http.StatusBadRequest, "path is protected"
 	}
 
@@ -557,7 +564,7 @@ func (s *Server) handleDuplicateProjectFile(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	copyRel, status, msg := duplicateFileInRoot(project.Path, req.Path)
+	copyRel, status, msg := duplicateFileInRoot(project.Path, req.Path, newPathGuard(project))
 	if status != 0 {
 		writeError(w, status, msg)
 		return
@@ -1001,6 +1008,10 @@ func (s *Server) handleReadTaskFile(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	if wantsRawFile(r) {
+		serveRawFile(w, r, root)
+		return
+	}
 
 	relPath, err := normalizeRelativePath(r.URL.Query().Get("path"), false)
 	if err != nil {
@@ -1066,6 +1077,10 @@ func (s *Server) handlePutTaskFile(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	if !isJSONRequest(r) {
+		s.writeRawFile(w, r, root, s.taskPathGuard(urlParam(r, "id")))
+		return
+	}
 
 	var req writeProjectFileRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -1078,7 +1093,7 @@ func (s *Server) handlePutTaskFile(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if isProtectedProjectPath(relPath) {
+	if s.taskPathGuard(urlParam(r, "id")).protects(relPath) {
 		writeError(w, http.StatusBadRequest, "path is protected")
 		return
 	}
@@ -1148,7 +1163,7 @@ func (s *Server) handleCreateTaskFileEntry(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if isProtectedProjectPath(relPath) {
+	if s.taskPathGuard(urlParam(r, "id")).protects(relPath) {
 		writeError(w, http.StatusBadRequest, "path is protected")
 		return
 	}
@@ -1224,7 +1239,7 @@ func (s *Server) handleDeleteTaskFile(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if isProtectedProjectPath(relPath) {
+	if s.taskPathGuard(urlParam(r, "id")).protectsTree(root, relPath) {
 		writeError(w, http.StatusBadRequest, "path is protected")
 		return
 	}
@@ -1272,7 +1287,7 @@ func (s *Server) handleRenameTaskFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if status, msg := renameFileInRoot(root, req); status != 0 {
+	if status, msg := renameFileInRoot(root, req, s.taskPathGuard(urlParam(r, "id"))); status != 0 {
 		writeError(w, status, msg)
 		return
 	}
@@ -1311,7 +1326,7 @@ func (s *Server) handleDuplicateTaskFile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	copyRel, status, msg := duplicateFileInRoot(root, req.Path)
+	copyRel, status, msg := duplicateFileInRoot(root, req.Path, s.taskPathGuard(urlParam(r, "id")))
 	if status != 0 {
 		writeError(w, status, msg)
 		return
@@ -1333,166 +1348,6 @@ func (s *Server) handleDuplicateTaskFile(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// --- File search ---
-
-type fileSearchRequest struct {
-	Query         string `json:"query"`
-	Regex         bool   `json:"regex,omitempty"`
-	CaseSensitive bool   `json:"caseSensitive,omitempty"`
-	MaxResults    int    `json:"maxResults,omitempty"`
-}
-
-type fileSearchMatch struct {
-	Line    int    `json:"line"`
-	Content string `json:"content"`
-}
-
-type fileSearchResult struct {
-	File    string            `json:"file"`
-	Matches []fileSearchMatch `json:"matches"`
-}
-
-func searchFiles(rootDir, query string, regex, caseSensitive bool, maxResults int) ([]fileSearchResult, error) {
-	if maxResults <= 0 {
-		maxResults = 200
-	}
-
-	var results []fileSearchResult
-	totalMatches := 0
-
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // skip errors
-		}
-		if totalMatches >= maxResults {
-			return filepath.SkipAll
-		}
-
-		name := info.Name()
-		// Skip hidden dirs and .git
-		if info.IsDir() && (name == ".git" || name == "node_modules" || name == ".next" || name == "vendor") {
-			return filepath.SkipDir
-		}
-		if info.IsDir() {
-			return nil
-		}
-		// Skip large and binary files
-		if info.Size() > 512*1024 || info.Size() == 0 {
-			return nil
-		}
-
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-		if !utf8.Valid(data) {
-			return nil
-		}
-
-		relPath, _ := filepath.Rel(rootDir, path)
-		content := string(data)
-		lines := strings.Split(content, "\n")
-
-		var matches []fileSearchMatch
-		for i, line := range lines {
-			if totalMatches >= maxResults {
-				break
-			}
-			var found bool
-			if regex {
-				// Simple regex match
-				if re, err := regexp.Compile(query); err == nil {
-					found = re.MatchString(line)
-				}
-			} else if caseSensitive {
-				found = strings.Contains(line, query)
-			} else {
-				found = strings.Contains(strings.ToLower(line), strings.ToLower(query))
-			}
-			if found {
-				matches = append(matches, fileSearchMatch{
-					Line:    i + 1,
-					Content: truncateLine(line, 200),
-				})
-				totalMatches++
-			}
-		}
-
-		if len(matches) > 0 {
-			results = append(results, fileSearchResult{
-				File:    filepath.ToSlash(relPath),
-				Matches: matches,
-			})
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-	return results, nil
-}
-
-func truncateLine(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
-}
-
-func (s *Server) handleSearchProjectFiles(w http.ResponseWriter, r *http.Request) {
-	projectID := urlParam(r, "id")
-	project, err := s.db.GetProject(projectID)
-	if err != nil {
-		writeDBError(w, err, "project")
-		return
-	}
-
-	var req fileSearchRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-	if req.Query == "" {
-		writeError(w, http.StatusBadRequest, "query is required")
-		return
-	}
-
-	results, err := searchFiles(project.Path, req.Query, req.Regex, req.CaseSensitive, req.MaxResults)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "search failed")
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]any{"results": results})
-}
-
-func (s *Server) handleSearchTaskFiles(w http.ResponseWriter, r *http.Request) {
-	root, ok := s.resolveTaskFileRoot(w, r)
-	if !ok {
-		return
-	}
-
-	var req fileSearchRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-	if req.Query == "" {
-		writeError(w, http.StatusBadRequest, "query is required")
-		return
-	}
-
-	results, err := searchFiles(root, req.Query, req.Regex, req.CaseSensitive, req.MaxResults)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "search failed")
-		return
-	}
-
-	writeJSON(w, http.StatusOK, map[string]any{"results": results})
-}
-
 func mapSecretFiles(configs []db.SecretFileConfig) []worktree.SecretFile {
 	if len(configs) == 0 {
 		return nil
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -13,6 +16,8 @@ import (
 	"github.com/miguel-bm/codeburg/internal/github"
 )
 
+// handleListProjects lists the active projects, or with archived=true only
+// the archived ones.
 func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
 	projects, err := s.db.ListProjects()
 	if err != nil {
@@ -20,20 +25,30 @@ func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, projects)
+	archived := r.URL.Query().Get("archived") == "true"
+	filtered := make([]*db.Project, 0, len(projects))
+	for _, p := range projects {
+		if (p.ArchivedAt != nil) == archived {
+			filtered = append(filtered, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, filtered)
 }
 
 // createProjectRequest extends db.CreateProjectInput with an optional GitHub URL.
 type createProjectRequest struct {
 	Name           string                `json:"name"`
 	Path           string                `json:"path"`
-	GitHubURL      string                `json:"githubUrl"`
+	GitHubURL      string                `json:"githubUrl"` // any supported remote (GitHub, GitLab, Gitea)
 	CreateRepo     bool                  `json:"createRepo"`
+	Wait           bool                  `json:"wait"`            // reply with the cloned project rather than the clone job
+	Clone          *gitclone.Shape       `json:"clone,omitempty"` // depth, single branch and partial filter; the server's default when nil
 	Description    string                `json:"description"`
 	Private        bool                  `json:"private"`
 	GitOrigin      *string               `json:"gitOrigin,omitempty"`
 	DefaultBranch  *string               `json:"defaultBranch,omitempty"`
 	SymlinkPaths   []string              `json:"symlinkPaths,omitempty"`
+	ClonePaths     []string              `json:"clonePaths,omitempty"`
 	SecretFiles    []db.SecretFileConfig `json:"secretFiles,omitempty"`
 	SetupScript    *string               `json:"setupScript,omitempty"`
 	TeardownScript *string               `json:"teardownScript,omitempty"`
@@ -94,17 +109,25 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 			GitOrigin:      &result.HTTPSURL,
 			DefaultBranch:  &result.DefaultBranch,
 			SymlinkPaths:   req.SymlinkPaths,
+			ClonePaths:     req.ClonePaths,
 			SecretFiles:    req.SecretFiles,
 			SetupScript:    req.SetupScript,
 			TeardownScript: req.TeardownScript,
 		}
 	} else if req.GitHubURL != "" {
-		// Clone from GitHub URL
-		if !gitclone.IsGitHubURL(req.GitHubURL) {
-			writeError(w, http.StatusBadRequest, "invalid GitHub URL")
+		// Clone from a remote URL
+		if _, ok := s.gitclone.ParseRemote(req.GitHubURL); !ok {
+			writeError(w, http.StatusBadRequest, "unsupported git URL (expected GitHub, GitLab or Gitea)")
 			return
 		}
 
+		if req.Clone != nil {
+			if err := req.Clone.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
 		name := req.Name
 		if name == "" {
 			name = gitclone.ParseRepoName(req.GitHubURL)
@@ -114,32 +137,39 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		result, err := gitclone.Clone(s.gitclone, req.GitHubURL, name)
+		// Clones run as jobs; the reply is the job unless the request waits
+		// for the project.
+		dest := filepath.Join(s.gitclone.BaseDir, name)
+		if _, err := os.Stat(dest); err == nil {
+			writeError(w, http.StatusConflict, "destination already exists: "+dest)
+			return
+		}
+		job, err := s.enqueueJob(jobKindClone, "", cloneJobPayload{Request: req, Name: name}, 1)
 		if err != nil {
-			if strings.Contains(err.Error(), "destination already exists") {
-				writeError(w, http.StatusConflict, err.Error())
-				return
-			}
-			writeError(w, http.StatusInternalServerError, "clone failed: "+err.Error())
+			writeError(w, http.StatusInternalServerError, "failed to queue clone")
 			return
 		}
-
-		normalized := gitclone.NormalizeGitHubURL(req.GitHubURL)
-		input = db.CreateProjectInput{
-			Name:           name,
-			Path:           result.Path,
-			GitOrigin:      &normalized,
-			DefaultBranch:  &result.DefaultBranch,
-			SymlinkPaths:   req.SymlinkPaths,
-			SecretFiles:    req.SecretFiles,
-			SetupScript:    req.SetupScript,
-			TeardownScript: req.TeardownScript,
+		if !req.Wait {
+			writeJSON(w, http.StatusAccepted, job)
+			return
 		}
-
-		// Auto-detect branch protection and configure workflow
-		if wf := detectBranchProtection(req.GitHubURL, result.DefaultBranch); wf != nil {
-			input.Workflow = wf
+		if job, err = s.waitForJob(r.Context(), job.ID); err != nil {
+			if r.Context().Err() == nil {
+				writeError(w, http.StatusInternalServerError, "failed to wait for clone")
+			}
+			return // otherwise the client went away; the clone goes on
 		}
+		switch {
+		case job.Status == db.JobSucceeded:
+			writeJSON(w, http.StatusCreated, job.Result)
+		case strings.Contains(job.LastError, "destination already exists"):
+			writeError(w, http.StatusConflict, job.LastError)
+		case job.Status == db.JobCancelled:
+			writeError(w, http.StatusConflict, "clone was cancelled")
+		default:
+			writeError(w, http.StatusInternalServerError, job.LastError)
+		}
+		return
 	} else {
 		// Local path flow (existing behavior)
 		if req.Name == "" {
@@ -177,6 +207,7 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 			GitOrigin:      req.GitOrigin,
 			DefaultBranch:  req.DefaultBranch,
 			SymlinkPaths:   req.SymlinkPaths,
+			ClonePaths:     req.ClonePaths,
 			SecretFiles:    req.SecretFiles,
 			SetupScript:    req.SetupScript,
 			TeardownScript: req.TeardownScript,
@@ -192,6 +223,82 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, project)
 }
 
+// cloneProject clones req's remote into the clone directory as name and
+// returns the input creating its project.
+func (s *Server) cloneProject(ctx context.Context, req createProjectRequest, remote gitclone.Remote, name string, progress func(gitclone.Progress)) (db.CreateProjectInput, error) {
+	result, err := gitclone.Clone(ctx, s.gitclone, req.GitHubURL, name, gitclone.CloneOptions{
+		Provider: remote.Provider,
+		Token:    s.forgeToken(remote.Provider),
+		Shape:    req.Clone,
+		Progress: progress,
+	})
+	if err != nil {
+		return db.CreateProjectInput{}, err
+	}
+
+	normalized := gitclone.NormalizeGitHubURL(req.GitHubURL)
+	input := db.CreateProjectInput{
+		Name:           name,
+		Path:           result.Path,
+		GitOrigin:      &normalized,
+		DefaultBranch:  &result.DefaultBranch,
+		SymlinkPaths:   req.SymlinkPaths,
+		ClonePaths:     req.ClonePaths,
+		SecretFiles:    req.SecretFiles,
+		SetupScript:    req.SetupScript,
+		TeardownScript: req.TeardownScript,
+	}
+
+	// Auto-detect branch protection and configure workflow
+	if remote.Provider == gitclone.ProviderGitHub {
+		if wf := detectBranchProtection(req.GitHubURL, result.DefaultBranch); wf != nil {
+			input.Workflow = wf
+		}
+	}
+	return input, nil
+}
+
+// handleGetProjectClone reports how much of its repository a project's
+// clone has: its depth, whether it is single-branch and its partial filter.
+func (s *Server) handleGetProjectClone(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	shape, err := gitclone.Inspect(project.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to inspect clone: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, shape)
+}
+
+// handleUnshallowProject queues a job fetching what a shallow, single-branch
+// or partial clone left out.
+func (s *Server) handleUnshallowProject(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	shape, err := gitclone.Inspect(project.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to inspect clone: "+err.Error())
+		return
+	}
+	if shape == (gitclone.Shape{}) {
+		writeError(w, http.StatusConflict, "project is already a full clone")
+		return
+	}
+	job, err := s.enqueueJob(jobKindUnshallow, project.ID, nil, 3)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue unshallow")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
 func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
 	id := urlParam(r, "id")
 
@@ -201,6 +308,7 @@ func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setVersionETag(w, project.Version)
 	writeJSON(w, http.StatusOK, project)
 }
 
@@ -212,6 +320,10 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	ifMatch := ifMatchVersion(r)
+	if ifMatch != nil {
+		input.Version = ifMatch
+	}
 
 	// Validate path if provided
 	if input.Path != nil {
@@ -230,12 +342,102 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if input.AgentInstructions != nil {
+		if model := input.AgentInstructions.DefaultModel; model != "" && !isValidModelName(model) {
+			writeError(w, http.StatusBadRequest, "invalid default model name")
+			return
+		}
+	}
+
+	if input.TunnelAuth != nil {
+		if err := validateTunnelAuth(input.TunnelAuth); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if input.Provisioner != nil {
+		if err := validateProvisioner(input.Provisioner); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if input.Protection != nil {
+		if err := validateProtectionPolicy(input.Protection); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if input.Devcontainer != nil {
+		if err := validateDevcontainer(input.Devcontainer); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if pool := input.WorktreePool; pool != nil {
+		if pool.Size < 0 || pool.Size > maxWorktreePoolSize {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("worktree pool size must be between 0 and %d", maxWorktreePoolSize))
+			return
+		}
+		if pool.MaxDiskMB < 0 {
+			writeError(w, http.StatusBadRequest, "maxDiskMb must not be negative")
+			return
+		}
+	}
+
+	if policy := input.RetryPolicy; policy != nil {
+		if policy.MaxRetries < 0 || policy.MaxRetries > maxSessionRetries {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("maxRetries must be between 0 and %d", maxSessionRetries))
+			return
+		}
+		if policy.BackoffSeconds < 0 {
+			writeError(w, http.StatusBadRequest, "backoffSeconds must not be negative")
+			return
+		}
+	}
+
+	if input.BoardColumns != nil {
+		if err := validateBoardColumns(input.BoardColumns); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		current, err := s.db.GetProject(id)
+		if err != nil {
+			writeDBError(w, err, "project")
+			return
+		}
+		inUse, err := s.removedColumnInUse(current, input.BoardColumns)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to check board columns")
+			return
+		}
+		if inUse != "" {
+			writeError(w, http.StatusConflict, fmt.Sprintf("column %q still has tasks; move them first", inUse))
+			return
+		}
+	}
+
 	project, err := s.db.UpdateProject(id, input)
+	if errors.Is(err, db.ErrConflict) {
+		if current, getErr := s.db.GetProject(id); getErr == nil {
+			writeStale(w, ifMatch, fmt.Sprintf("project changed since version %d", *input.Version), "project", current, current.Version)
+			return
+		}
+	}
 	if err != nil {
 		writeDBError(w, err, "project")
 		return
 	}
 
+	// Pooled worktrees follow the pool size and the default branch.
+	if input.WorktreePool != nil || input.DefaultBranch != nil {
+		s.syncWorktreePool(id)
+	}
+
+	setVersionETag(w, project.Version)
 	writeJSON(w, http.StatusOK, project)
 }
 
@@ -338,6 +540,7 @@ func (s *Server) handlePushProjectDefaultBranch(w http.ResponseWriter, r *http.R
 		writeError(w, http.StatusConflict, fmt.Sprintf("failed to push %s: %v", branch, err))
 		return
 	}
+	s.emitGitPushed(project.ID, "", project.Path, branch)
 
 	afterHash := ""
 	if out, err := runGit(project.Path, "rev-parse", "--verify", remoteRef); err == nil {
@@ -364,11 +567,29 @@ func checkedOutPathFromFetchError(message string) string {
 func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
 	id := urlParam(r, "id")
 
+	project, err := s.db.GetProject(id)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	pooled, _ := s.db.ListPooledWorktrees(id)
+
 	if err := s.db.DeleteProject(id); err != nil {
 		writeDBError(w, err, "project")
 		return
 	}
 
+	// Pool entries go with the project; their worktrees are removed in a job.
+	if len(pooled) > 0 {
+		payload := poolRemovalJobPayload{ProjectPath: project.Path}
+		for _, entry := range pooled {
+			payload.Worktrees = append(payload.Worktrees, pooledWorktreeRef{Path: entry.Path, Branch: entry.Branch})
+		}
+		if _, err := s.enqueueJob(jobKindPoolRemoval, project.ID, payload, 3); err != nil {
+			slog.Warn("failed to queue pooled worktree removal", "project_id", project.ID, "error", err)
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
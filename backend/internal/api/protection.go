@@ -0,0 +1,204 @@
+package api
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// Projects can protect paths and branches beyond the .git directory (see
+// db.ProtectionPolicy). The file and git endpoints refuse to change a
+// protected path or to force-push a protected branch, and a Claude session
+// asking to edit a path agents may not touch is denied without asking the
+// user.
+
+// pathGuard tells which paths of a worktree may not be changed: .git, and
+// the paths of the project's policy. The zero value protects .git only.
+type pathGuard struct {
+	patterns []string
+}
+
+func newPathGuard(project *db.Project) pathGuard {
+	if project == nil || project.Protection == nil {
+		return pathGuard{}
+	}
+	return pathGuard{patterns: project.Protection.Paths}
+}
+
+// taskPathGuard returns the guard of a task's project.
+func (s *Server) taskPathGuard(taskID string) pathGuard {
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return pathGuard{}
+	}
+	return s.projectPathGuard(task.ProjectID)
+}
+
+// projectPathGuard returns the guard of a project.
+func (s *Server) projectPathGuard(projectID string) pathGuard {
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		return pathGuard{}
+	}
+	return newPathGuard(project)
+}
+
+// protects reports whether relPath may not be changed.
+func (g pathGuard) protects(relPath string) bool {
+	if isProtectedProjectPath(relPath) {
+		return true
+	}
+	return matchesAnyGlob(g.patterns, filepath.ToSlash(relPath))
+}
+
+// protectsTree reports whether relPath, or anything under it when it is a
+// directory of root, may not be changed, for deletes and renames.
+func (g pathGuard) protectsTree(root, relPath string) bool {
+	if g.protects(relPath) {
+		return true
+	}
+	if len(g.patterns) == 0 {
+		return false
+	}
+	absPath, err := safeJoin(root, relPath)
+	if err != nil {
+		return false
+	}
+	found := false
+	filepath.WalkDir(absPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(absPath, p)
+		if err != nil {
+			return nil
+		}
+		if g.protects(filepath.Join(relPath, rel)) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// protectsAny reports whether any of relPaths may not be changed.
+func (g pathGuard) protectsAny(relPaths []string) bool {
+	for _, relPath := range relPaths {
+		if g.protects(filepath.Clean(relPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyGlob(globs []string, rel string) bool {
+	for _, glob := range globs {
+		if matchGlob(glob, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProtectionPolicy checks a policy's globs, branch patterns and
+// guardrail limits.
+func validateProtectionPolicy(policy *db.ProtectionPolicy) error {
+	for _, glob := range append(append([]string{}, policy.Paths...), policy.AgentPaths...) {
+		if err := validateGlob(glob); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range policy.NoForcePush {
+		if _, err := path.Match(pattern, ""); err != nil || strings.TrimSpace(pattern) == "" {
+			return fmt.Errorf("invalid branch pattern %q", pattern)
+		}
+	}
+	if g := policy.Guardrails; g != nil {
+		if g.MaxFilesChanged < 0 || g.MaxLinesChanged < 0 {
+			return fmt.Errorf("guardrail limits can't be negative")
+		}
+		for _, glob := range g.ForbiddenPaths {
+			if err := validateGlob(glob); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkForcePush refuses to force-push the branch checked out in workDir
+// when the project protects it.
+func checkForcePush(project *db.Project, workDir string) error {
+	if project.Protection == nil || len(project.Protection.NoForcePush) == 0 {
+		return nil
+	}
+	out, err := runGit(workDir, "branch", "--show-current")
+	if err != nil {
+		return err
+	}
+	branch := strings.TrimSpace(out)
+	for _, pattern := range project.Protection.NoForcePush {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return service.Forbidden("force-pushing %s is not allowed in this project", branch)
+		}
+	}
+	return nil
+}
+
+// agentEditTools maps the Claude tools that edit files to the input field
+// naming the file.
+var agentEditTools = map[string]string{
+	"Edit":         "file_path",
+	"MultiEdit":    "file_path",
+	"Write":        "file_path",
+	"NotebookEdit": "notebook_path",
+}
+
+// agentPermissionDenial returns why a session's agent may not use a tool
+// with input, or "" when the policy doesn't stop it. Agents may edit
+// neither the paths nobody may change nor the paths agents may not.
+func (s *Server) agentPermissionDenial(sessionID, toolName string, input any) string {
+	field, ok := agentEditTools[toolName]
+	if !ok {
+		return ""
+	}
+	fields, _ := input.(map[string]any)
+	target, _ := fields[field].(string)
+	if target == "" {
+		return ""
+	}
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return ""
+	}
+	project, err := s.db.GetProject(session.ProjectID)
+	if err != nil {
+		return ""
+	}
+	workDir, err := s.resolveSessionWorkDir(session)
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(workDir, target)
+	}
+	rel, err := filepath.Rel(workDir, filepath.Clean(target))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+
+	protected := newPathGuard(project).protects(rel)
+	if !protected && project.Protection != nil {
+		protected = matchesAnyGlob(project.Protection.AgentPaths, filepath.ToSlash(rel))
+	}
+	if !protected {
+		return ""
+	}
+	return fmt.Sprintf("%s is protected in this project; agents may not edit it.", filepath.ToSlash(rel))
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestProtectedPaths(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+	task, _ := env.server.db.GetTask(taskID)
+
+	resp := env.patch("/api/projects/"+task.ProjectID, db.UpdateProjectInput{
+		Protection: &db.ProtectionPolicy{Paths: []string{"[bad"}},
+	})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid glob rejected, got %d", resp.Code)
+	}
+	resp = env.patch("/api/projects/"+task.ProjectID, db.UpdateProjectInput{
+		Protection: &db.ProtectionPolicy{Paths: []string{"migrations/*.sql"}},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update project: %d %s", resp.Code, resp.Body.String())
+	}
+
+	os.MkdirAll(filepath.Join(repoPath, "migrations"), 0o755)
+	migration := filepath.Join(repoPath, "migrations", "001_init.sql")
+	os.WriteFile(migration, []byte("CREATE TABLE old;\n"), 0o644)
+	gitExecHelper(t, repoPath, "add", ".")
+	gitExecHelper(t, repoPath, "commit", "-m", "migration")
+
+	files := "/api/tasks/" + taskID + "/files"
+	if resp := env.request(http.MethodPut, "/api/tasks/"+taskID+"/file", map[string]string{"path": "migrations/001_init.sql", "content": "x"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected a write to a protected file refused, got %d", resp.Code)
+	}
+	if resp := env.request(http.MethodPut, "/api/tasks/"+taskID+"/file", map[string]string{"path": "migrations/notes.md", "content": "x"}); resp.Code != http.StatusOK {
+		t.Errorf("expected an unprotected file written, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := env.delete("/api/tasks/" + taskID + "/file?path=migrations"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected a directory holding protected files kept, got %d", resp.Code)
+	}
+	if resp := env.post(files+"/replace", map[string]any{"search": "old", "replace": "new"}); resp.Code != http.StatusOK {
+		t.Fatalf("replace: %d %s", resp.Code, resp.Body.String())
+	}
+	if data, _ := os.ReadFile(migration); string(data) != "CREATE TABLE old;\n" {
+		t.Errorf("expected replace to skip protected files, got %q", data)
+	}
+
+	os.WriteFile(migration, []byte("CREATE TABLE changed;\n"), 0o644)
+	resp = env.post("/api/tasks/"+taskID+"/git/revert", GitRevertRequest{Tracked: []string{"migrations/001_init.sql"}})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected a revert of a protected file refused, got %d", resp.Code)
+	}
+}
+
+func TestProtectedBranchForcePush(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+	task, _ := env.server.db.GetTask(taskID)
+	env.server.db.UpdateProject(task.ProjectID, db.UpdateProjectInput{
+		Protection: &db.ProtectionPolicy{NoForcePush: []string{"release/*"}},
+	})
+	gitExecHelper(t, repoPath, "checkout", "-b", "release/1.0")
+
+	resp := env.post("/api/tasks/"+taskID+"/git/push", GitPushRequest{Force: true})
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("expected a force push to a protected branch refused, got %d: %s", resp.Code, resp.Body.String())
+	}
+	resp = env.post("/api/projects/"+task.ProjectID+"/git/push", GitPushRequest{Force: true})
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("expected a project force push refused, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestAgentProtectedPaths(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+	task, _ := env.server.db.GetTask(taskID)
+	env.server.db.UpdateProject(task.ProjectID, db.UpdateProjectInput{
+		Protection: &db.ProtectionPolicy{AgentPaths: []string{"migrations/"}},
+	})
+	session, err := env.server.db.CreateSession(db.CreateSessionInput{TaskID: taskID, ProjectID: task.ProjectID, Provider: "claude", SessionType: "chat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := env.server.chat
+	manager.SetPermissionPolicy(env.server.agentPermissionDenial)
+	state, err := manager.ensureSession(session.ID, "claude", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := &recordingStdin{}
+	state.stdin = stdin
+	var notified []ChatMessage
+	manager.SetPermissionHook(func(msg ChatMessage) { notified = append(notified, msg) })
+
+	request := func(requestID, path string) map[string]any {
+		return map[string]any{
+			"type":       "control_request",
+			"request_id": requestID,
+			"request": map[string]any{
+				"subtype":   "can_use_tool",
+				"tool_name": "Write",
+				"input":     map[string]any{"file_path": path, "content": "x"},
+			},
+		}
+	}
+	manager.handleClaudePayload(state, request("req-1", filepath.Join(repoPath, "migrations", "002.sql")))
+	manager.handleClaudePayload(state, request("req-2", filepath.Join(repoPath, "src", "main.go")))
+
+	if perm := state.messages[0].Permission; perm.Status != ChatPermissionDenied || perm.Message == "" {
+		t.Errorf("expected the protected edit denied with a reason, got %+v", perm)
+	}
+	lines := stdin.lines(t)
+	if len(lines) != 1 {
+		t.Fatalf("expected one answer written, got %d", len(lines))
+	}
+	response, _ := lines[0]["response"].(map[string]any)
+	decision, _ := response["response"].(map[string]any)
+	if response["request_id"] != "req-1" || decision["behavior"] != "deny" {
+		t.Errorf("expected req-1 denied, got %+v", lines[0])
+	}
+	if perm := state.messages[1].Permission; perm.Status != ChatPermissionPending {
+		t.Errorf("expected the other edit left to the user, got %+v", perm)
+	}
+	if len(notified) != 1 || notified[0].Permission.RequestID != "req-2" {
+		t.Errorf("expected only the undecided request notified, got %+v", notified)
+	}
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/justfile"
+)
+
+const (
+	provisionTimeout   = 10 * time.Minute
+	maxProvisionOutput = 16 << 10
+)
+
+var (
+	provisionEnvNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	errNoProvisioner = errors.New("project has no provisioner")
+)
+
+func validateProvisioner(cfg *db.ProjectProvisioner) error {
+	for _, name := range cfg.Ports {
+		if !provisionEnvNameRe.MatchString(name) {
+			return fmt.Errorf("invalid port variable name %q", name)
+		}
+	}
+	for name := range cfg.Env {
+		if !provisionEnvNameRe.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+func provisionerEnabled(project *db.Project) bool {
+	return project.Provisioner != nil && strings.TrimSpace(project.Provisioner.Up) != ""
+}
+
+// freeLocalPort asks the kernel for a port nothing is listening on.
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// provisionEnv builds the environment recorded for a task: a compose project
+// name unique to the task, a free port per name in Ports, and the Env
+// templates expanded against those.
+func provisionEnv(taskID string, cfg *db.ProjectProvisioner) (map[string]string, error) {
+	env := map[string]string{
+		"COMPOSE_PROJECT_NAME": "codeburg-" + strings.ToLower(taskID),
+	}
+	for _, name := range cfg.Ports {
+		port, err := freeLocalPort()
+		if err != nil {
+			return nil, fmt.Errorf("allocate port for %s: %w", name, err)
+		}
+		env[name] = strconv.Itoa(port)
+	}
+	base := make(map[string]string, len(env))
+	for k, v := range env {
+		base[k] = v
+	}
+	for name, tmpl := range cfg.Env {
+		env[name] = os.Expand(tmpl, func(key string) string { return base[key] })
+	}
+	return env, nil
+}
+
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// runProvisionCommand runs a provisioner command in the worktree and returns
+// the tail of its combined output.
+func runProvisionCommand(workDir, command string, env map[string]string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = workDir
+	cmd.Env = append(justfile.RuntimeEnv(), envList(env)...)
+	out, err := cmd.CombinedOutput()
+	if len(out) > maxProvisionOutput {
+		out = out[len(out)-maxProvisionOutput:]
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", provisionTimeout)
+	}
+	return string(out), err
+}
+
+// provisionTask runs the project's provisioner for a task and records the
+// resulting connection details, which taskEnv then hands to the task's
+// processes.
+func (s *Server) provisionTask(taskID string, project *db.Project, workDir string) (*db.TaskProvision, error) {
+	if !provisionerEnabled(project) {
+		return nil, errNoProvisioner
+	}
+
+	env, err := provisionEnv(taskID, project.Provisioner)
+	if err != nil {
+		return nil, err
+	}
+	output, runErr := runProvisionCommand(workDir, project.Provisioner.Up, env)
+
+	provision := &db.TaskProvision{TaskID: taskID, Status: db.ProvisionStatusReady, Env: env, Output: output}
+	if runErr != nil {
+		msg := runErr.Error()
+		provision.Status = db.ProvisionStatusFailed
+		provision.Error = &msg
+	}
+	if err := s.db.SaveTaskProvision(provision); err != nil {
+		return nil, err
+	}
+	s.wsHub.BroadcastToTask(taskID, "task_provision", provision)
+	if runErr != nil {
+		return provision, fmt.Errorf("provisioner failed: %w", runErr)
+	}
+	return provision, nil
+}
+
+// teardownTaskProvision runs the project's teardown command for a task that
+// was provisioned. It does nothing if the task was never provisioned or was
+// already torn down.
+func (s *Server) teardownTaskProvision(task *db.Task, project *db.Project) error {
+	provision, err := s.db.GetTaskProvision(task.ID)
+	if errors.Is(err, db.ErrNotFound) || (err == nil && provision.Status == db.ProvisionStatusTornDown) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A failed provision may still have started some services, so it is torn
+	// down too.
+	workDir := ptrToString(task.WorktreePath)
+	if project.Provisioner != nil && project.Provisioner.Down != "" && workDir != "" {
+		output, runErr := runProvisionCommand(workDir, project.Provisioner.Down, provision.Env)
+		provision.Output = output
+		if runErr != nil {
+			msg := "teardown failed: " + runErr.Error()
+			provision.Error = &msg
+			if err := s.db.SaveTaskProvision(provision); err != nil {
+				return err
+			}
+			s.wsHub.BroadcastToTask(task.ID, "task_provision", provision)
+			return runErr
+		}
+	}
+
+	provision.Status = db.ProvisionStatusTornDown
+	provision.Error = nil
+	if err := s.db.SaveTaskProvision(provision); err != nil {
+		return err
+	}
+	s.wsHub.BroadcastToTask(task.ID, "task_provision", provision)
+	return nil
+}
+
+// teardownTaskProvisionAsync tears down in a job, for status changes that
+// should not wait on the provisioner.
+func (s *Server) teardownTaskProvisionAsync(task *db.Task) {
+	if _, err := s.enqueueJob(jobKindTeardown, task.ProjectID, teardownJobPayload{TaskID: task.ID}, 3); err != nil {
+		slog.Warn("failed to queue task provision teardown", "task_id", task.ID, "error", err)
+	}
+}
+
+// taskEnv returns the environment entries for processes started for a task:
+// its port assignments, the connection details of provisioned services and,
+// last, the task's own variables.
+func (s *Server) taskEnv(taskID string) []string {
+	env := s.portSuggest.Env(taskID)
+	if provision, err := s.db.GetTaskProvision(taskID); err == nil && provision.Status == db.ProvisionStatusReady {
+		env = append(env, envList(provision.Env)...)
+	}
+	return s.withTaskOverrides(taskID, env)
+}
+
+func (s *Server) handleGetTaskProvision(w http.ResponseWriter, r *http.Request) {
+	provision, err := s.db.GetTaskProvision(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "provision")
+		return
+	}
+	writeJSON(w, http.StatusOK, provision)
+}
+
+// handleProvisionTask (re)runs the provisioner for a task, tearing down what
+// an earlier run started first.
+func (s *Server) handleProvisionTask(w http.ResponseWriter, r *http.Request) {
+	workDir, ok := s.resolveTaskWorkDir(w, r)
+	if !ok {
+		return
+	}
+	task, err := s.db.GetTask(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	if !provisionerEnabled(project) {
+		writeError(w, http.StatusBadRequest, errNoProvisioner.Error())
+		return
+	}
+
+	if err := s.teardownTaskProvision(task, project); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to tear down previous provision: "+err.Error())
+		return
+	}
+	provision, err := s.provisionTask(task.ID, project, workDir)
+	if provision == nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// A failed provisioner run is reported in the record itself.
+	writeJSON(w, http.StatusOK, provision)
+}
+
+func (s *Server) handleTeardownTaskProvision(w http.ResponseWriter, r *http.Request) {
+	task, err := s.db.GetTask(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	if err := s.teardownTaskProvision(task, project); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestTaskProvision_Lifecycle(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	workDir := t.TempDir()
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "provisioned", Path: workDir})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "needs a database"})
+	env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{WorktreePath: &workDir})
+
+	resp := env.patch("/api/projects/"+project.ID, map[string]any{
+		"provisioner": map[string]any{"up": "true", "ports": []string{"DB-PORT"}},
+	})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid port variable, got %d", resp.Code)
+	}
+	resp = env.patch("/api/projects/"+project.ID, map[string]any{
+		"provisioner": map[string]any{
+			"up":    `echo "$DB_PORT" > db.txt`,
+			"down":  "rm db.txt",
+			"ports": []string{"DB_PORT"},
+			"env":   map[string]string{"DATABASE_URL": "postgres://localhost:${DB_PORT}/app"},
+		},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("update project: %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp = env.post("/api/tasks/"+task.ID+"/provision", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("provision: %d %s", resp.Code, resp.Body.String())
+	}
+	var provision db.TaskProvision
+	decodeResponse(t, resp, &provision)
+	port := provision.Env["DB_PORT"]
+	if provision.Status != db.ProvisionStatusReady || port == "" {
+		t.Fatalf("unexpected provision: %+v", provision)
+	}
+	if got := provision.Env["DATABASE_URL"]; got != "postgres://localhost:"+port+"/app" {
+		t.Errorf("unexpected DATABASE_URL %q", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workDir, "db.txt")); strings.TrimSpace(string(data)) != port {
+		t.Errorf("expected the up command to see DB_PORT=%s, got %q", port, data)
+	}
+	if !slices.Contains(env.server.taskEnv(task.ID), "DB_PORT="+port) {
+		t.Errorf("expected task env to carry the provisioned port, got %v", env.server.taskEnv(task.ID))
+	}
+
+	if resp := env.delete("/api/tasks/" + task.ID + "/provision"); resp.Code != http.StatusNoContent {
+		t.Fatalf("teardown: %d %s", resp.Code, resp.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "db.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the down command to run, got %v", err)
+	}
+	stored, _ := env.server.db.GetTaskProvision(task.ID)
+	if stored.Status != db.ProvisionStatusTornDown {
+		t.Errorf("expected torn down status, got %s", stored.Status)
+	}
+	if len(env.server.taskEnv(task.ID)) != 0 {
+		t.Errorf("expected no task env after teardown, got %v", env.server.taskEnv(task.ID))
+	}
+}
+
+func TestTaskProvision_FailedRun(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	workDir := t.TempDir()
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{
+		Name:        "broken",
+		Path:        workDir,
+		Provisioner: &db.ProjectProvisioner{Up: "echo starting; exit 1"},
+	})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "broken provisioner"})
+
+	provision, err := env.server.provisionTask(task.ID, project, workDir)
+	if err == nil || provision == nil || provision.Status != db.ProvisionStatusFailed {
+		t.Fatalf("expected a failed provision, got %+v (%v)", provision, err)
+	}
+	if provision.Output != "starting\n" || provision.Error == nil {
+		t.Errorf("expected output and error recorded, got %+v", provision)
+	}
+	if len(env.server.taskEnv(task.ID)) != 0 {
+		t.Errorf("expected a failed provision to add no env, got %v", env.server.taskEnv(task.ID))
+	}
+}
+
+func TestTaskEnv_Overrides(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "env", Path: t.TempDir()})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "with env"})
+	env.server.db.SaveTaskProvision(&db.TaskProvision{
+		TaskID: task.ID,
+		Status: db.ProvisionStatusReady,
+		Env:    map[string]string{"DB_PORT": "5433", "LOG_LEVEL": "info"},
+	})
+
+	resp := env.request("PUT", "/api/tasks/"+task.ID+"/env", []map[string]any{{"name": "BAD-NAME", "value": "x"}})
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid name, got %d", resp.Code)
+	}
+	resp = env.request("PUT", "/api/tasks/"+task.ID+"/env", []map[string]any{
+		{"name": "LOG_LEVEL", "value": "debug"},
+		{"name": "DATABASE_URL", "value": "postgres://localhost:${DB_PORT}/app"},
+		{"name": "API_TOKEN", "value": "s3cret", "secret": true},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("set env: %d %s", resp.Code, resp.Body.String())
+	}
+	if strings.Contains(resp.Body.String(), "s3cret") {
+		t.Errorf("expected the secret value masked, got %s", resp.Body.String())
+	}
+
+	// A secret sent back without its value keeps it.
+	resp = env.request("PUT", "/api/tasks/"+task.ID+"/env", []map[string]any{
+		{"name": "LOG_LEVEL", "value": "debug"},
+		{"name": "DATABASE_URL", "value": "postgres://localhost:${DB_PORT}/app"},
+		{"name": "API_TOKEN", "secret": true},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("resave env: %d %s", resp.Code, resp.Body.String())
+	}
+	resp = env.get("/api/tasks/" + task.ID + "/env")
+	var vars []taskEnvVarResponse
+	decodeResponse(t, resp, &vars)
+	if len(vars) != 3 || vars[0].Name != "API_TOKEN" || vars[0].Value != nil {
+		t.Fatalf("unexpected env listing: %+v", vars)
+	}
+
+	taskEnv := env.server.taskEnv(task.ID)
+	for _, want := range []string{"API_TOKEN=s3cret", "DATABASE_URL=postgres://localhost:5433/app"} {
+		if !slices.Contains(taskEnv, want) {
+			t.Errorf("expected %s in task env, got %v", want, taskEnv)
+		}
+	}
+	// Later entries win when the environment is built.
+	if i, j := slices.Index(taskEnv, "LOG_LEVEL=info"), slices.Index(taskEnv, "LOG_LEVEL=debug"); j < i {
+		t.Errorf("expected the task override after the project value, got %v", taskEnv)
+	}
+}
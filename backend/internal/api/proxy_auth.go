@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Running behind a reverse proxy, in the auth section of the configuration
+// file:
+//
+//	auth:
+//	  trusted_proxies: [10.0.0.0/8, 127.0.0.1]  # addresses or CIDRs
+//	  cookies: true
+//	  forward_auth:
+//	    header: Remote-User   # e.g. Tailscale-User-Login
+//	    users: [alice]        # optional; any user the proxy lets through otherwise
+//
+// X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host (and
+// CF-Connecting-IP) are believed only from trusted_proxies; without it, from
+// loopback and private addresses. With cookies, logins also set an HttpOnly,
+// Secure, SameSite=Strict session cookie, which authenticates requests that
+// have no Authorization header, websockets included. With forward_auth, a
+// request from a trusted proxy carrying the header is authenticated as that
+// user, for proxies such as Authelia or Tailscale Serve that authenticate
+// themselves. Requests authenticated by cookie or forward-auth header must
+// come from an allowed origin, or the request's own, to change anything.
+
+// ForwardAuthConfig is the auth.forward_auth section of the configuration
+// file.
+type ForwardAuthConfig struct {
+	Header string   `yaml:"header"`
+	Users  []string `yaml:"users,omitempty"`
+}
+
+const (
+	sessionCookieName = "codeburg_session"
+	// sessionCookieMaxAge matches the lifetime of login tokens.
+	sessionCookieMaxAge = 7 * 24 * time.Hour
+)
+
+// clientIPContextKey holds the client address clientIPMiddleware resolved.
+const clientIPContextKey contextKey = "client_ip"
+
+// parseTrustedProxies parses addresses and CIDRs; an address stands for
+// itself.
+func parseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an address or CIDR", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// proxyTrusted reports whether the headers a peer at ip forwards are
+// believed.
+func (s *Server) proxyTrusted(ip string) bool {
+	s.configMu.RLock()
+	prefixes := s.trustedProxies
+	s.configMu.RUnlock()
+	if len(prefixes) == 0 {
+		return isTrustedProxy(ip)
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// clientIPMiddleware resolves the client address once, against the
+// configured proxies, for clientIP.
+func (s *Server) clientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := resolveClientIP(r, s.proxyTrusted)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey, ip)))
+	})
+}
+
+// forwardedHeader returns the first value of a forwarding header, when the
+// peer is a trusted proxy.
+func (s *Server) forwardedHeader(r *http.Request, name string) string {
+	if !s.proxyTrusted(parseRemoteIP(r.RemoteAddr)) {
+		return ""
+	}
+	first, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.TrimSpace(first)
+}
+
+// requestOrigin is the origin the client sent r to, which a proxy may
+// have changed.
+func (s *Server) requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := s.forwardedHeader(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	host := r.Host
+	if forwarded := s.forwardedHeader(r, "X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
+
+// sameOriginRequest reports whether a browser sent r from an allowed
+// origin or the request's own. Requests without Origin or Referer don't
+// come from a page and pass.
+func (s *Server) sameOriginRequest(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		if referer := r.Header.Get("Referer"); referer != "" {
+			origin = referer
+			if scheme, rest, ok := strings.Cut(referer, "://"); ok {
+				host, _, _ := strings.Cut(rest, "/")
+				origin = scheme + "://" + host
+			}
+		}
+	}
+	return origin == "" || s.originAllowed(origin) || origin == s.requestOrigin(r)
+}
+
+// forwardAuthUser returns the user a trusted proxy authenticated r as,
+// when forward auth is configured.
+func (s *Server) forwardAuthUser(r *http.Request) (string, bool) {
+	config := s.currentConfig().Auth
+	if config.ForwardAuth == nil || len(config.TrustedProxies) == 0 {
+		return "", false
+	}
+	user := s.forwardedHeader(r, config.ForwardAuth.Header)
+	if user == "" {
+		return "", false
+	}
+	if len(config.ForwardAuth.Users) > 0 && !slices.ContainsFunc(config.ForwardAuth.Users, func(u string) bool { return strings.EqualFold(u, user) }) {
+		slog.Warn("forward auth user not allowed", "user", user, "ip", clientIP(r))
+		return "", false
+	}
+	return user, true
+}
+
+// ambientLogin reports whether r carries the user's login without an
+// Authorization header or token: a session cookie or a forward-auth header.
+func (s *Server) ambientLogin(r *http.Request) bool {
+	if _, ok := s.forwardAuthUser(r); ok {
+		return true
+	}
+	if !s.currentConfig().Auth.Cookies {
+		return false
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	return err == nil && s.validLoginToken(cookie.Value)
+}
+
+// validLoginToken reports whether token is a login token that is valid and
+// hasn't been revoked by logging out.
+func (s *Server) validLoginToken(token string) bool {
+	if !s.auth.ValidateToken(token) {
+		return false
+	}
+	revoked, err := s.db.TokenRevoked(hashAPIToken(token))
+	if err != nil {
+		slog.Warn("failed to check revoked token", "error", err)
+		return false
+	}
+	return !revoked
+}
+
+// revokeLoginToken makes a valid login token unusable from now on.
+func (s *Server) revokeLoginToken(token string) {
+	if !s.auth.ValidateToken(token) {
+		return
+	}
+	// No login token outlives the cookie.
+	if err := s.db.RevokeToken(hashAPIToken(token), time.Now().Add(sessionCookieMaxAge)); err != nil {
+		slog.Warn("failed to revoke token", "error", err)
+	}
+}
+
+// setSessionCookie sets the session cookie for a login token, when
+// cookies are enabled.
+func (s *Server) setSessionCookie(w http.ResponseWriter, token string) {
+	if !s.currentConfig().Auth.Cookies {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sessionCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// handleLogout revokes the login token of the session cookie, and the one
+// in the Authorization header, and clears the cookie.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.revokeLoginToken(cookie.Value)
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		s.revokeLoginToken(token)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProxyAuthConfigValidation(t *testing.T) {
+	dir := t.TempDir()
+	load := func(content string) error {
+		path := filepath.Join(dir, "config.yaml")
+		os.WriteFile(path, []byte(content), 0600)
+		_, err := LoadConfig(path)
+		return err
+	}
+
+	if err := load("auth:\n  trusted_proxies: [10.0.0.0/8, 127.0.0.1, \"::1\"]\n  cookies: true\n  forward_auth: {header: Remote-User}\n"); err != nil {
+		t.Fatalf("expected a valid configuration, got %v", err)
+	}
+	if err := load("auth:\n  trusted_proxies: [10.0.0.0/33]\n"); err == nil || !strings.Contains(err.Error(), "auth.trusted_proxies") {
+		t.Errorf("expected an invalid CIDR rejected, got %v", err)
+	}
+	if err := load("auth:\n  forward_auth: {header: Remote-User}\n"); err == nil || !strings.Contains(err.Error(), "needs auth.trusted_proxies") {
+		t.Errorf("expected forward auth without trusted proxies rejected, got %v", err)
+	}
+	if err := load("auth:\n  trusted_proxies: [10.0.0.1]\n  forward_auth: {users: [alice]}\n"); err == nil || !strings.Contains(err.Error(), "header is required") {
+		t.Errorf("expected forward auth without a header rejected, got %v", err)
+	}
+}
+
+func TestClientIP_ConfiguredTrustedProxies(t *testing.T) {
+	env := setupTestEnv(t)
+	env.server.applyConfig(&Config{Auth: AuthConfig{TrustedProxies: []string{"192.168.1.0/24"}}})
+
+	var got string
+	handler := env.server.clientIPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	}))
+	resolve := func(remoteAddr string) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", "198.51.100.7")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return got
+	}
+
+	if ip := resolve("192.168.1.5:8080"); ip != "198.51.100.7" {
+		t.Errorf("expected the forwarded address from a configured proxy, got %q", ip)
+	}
+	// Only the configured proxies are trusted, private addresses or not.
+	if ip := resolve("10.0.0.2:8080"); ip != "10.0.0.2" {
+		t.Errorf("expected the peer address from an unlisted proxy, got %q", ip)
+	}
+}
+
+func TestSessionCookies(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.applyConfig(&Config{Auth: AuthConfig{Origin: "https://codeburg.example.com", Cookies: true}})
+	env.token = ""
+
+	send := func(method, path string, cookie *http.Cookie, origin string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"password": "testpass123"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		env.server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	resp := send("POST", "/api/auth/login", nil, "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", resp.Code, resp.Body.String())
+	}
+	cookies := resp.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookieName || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteStrictMode {
+		t.Fatalf("expected a secure session cookie, got %+v", cookies)
+	}
+	cookie := cookies[0]
+
+	if resp := send("GET", "/api/auth/me", nil, ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", resp.Code)
+	}
+	if resp := send("GET", "/api/auth/me", cookie, ""); resp.Code != http.StatusOK {
+		t.Errorf("expected the cookie to authenticate, got %d", resp.Code)
+	}
+	if resp := send("GET", "/api/auth/me", &http.Cookie{Name: sessionCookieName, Value: "forged"}, ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected a forged cookie refused, got %d", resp.Code)
+	}
+
+	// Changes need the page's origin.
+	if resp := send("POST", "/api/projects", cookie, "https://evil.example.com"); resp.Code != http.StatusForbidden {
+		t.Errorf("expected a cross-origin change refused, got %d", resp.Code)
+	}
+	if resp := send("POST", "/api/auth/password", cookie, "https://codeburg.example.com"); resp.Code == http.StatusForbidden || resp.Code == http.StatusUnauthorized {
+		t.Errorf("expected a same-origin change through, got %d", resp.Code)
+	}
+
+	// Another login's cookie outlives this one's logout.
+	other := send("POST", "/api/auth/login", nil, "").Result().Cookies()[0]
+
+	resp = send("POST", "/api/auth/logout", cookie, "")
+	if cleared := resp.Result().Cookies(); resp.Code != http.StatusNoContent || len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("expected logout to clear the cookie, got %d %+v", resp.Code, cleared)
+	}
+	// A copy of the cookie kept past logout no longer works.
+	if resp := send("GET", "/api/auth/me", cookie, ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected the logged out cookie refused, got %d", resp.Code)
+	}
+	env.token = cookie.Value
+	if resp := env.get("/api/auth/me"); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected the logged out token refused as a bearer token, got %d", resp.Code)
+	}
+	env.token = ""
+	if resp := send("GET", "/api/auth/me", other, ""); resp.Code != http.StatusOK {
+		t.Errorf("expected the other login's cookie to still work, got %d", resp.Code)
+	}
+	cookie = other
+
+	// With cookies turned off, the cookie no longer authenticates.
+	env.server.applyConfig(&Config{})
+	if resp := send("GET", "/api/auth/me", cookie, ""); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected the cookie ignored with cookies off, got %d", resp.Code)
+	}
+}
+
+func TestForwardAuth(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.applyConfig(&Config{Auth: AuthConfig{
+		TrustedProxies: []string{"10.0.0.1"},
+		ForwardAuth:    &ForwardAuthConfig{Header: "Remote-User", Users: []string{"alice"}},
+	}})
+	env.token = ""
+
+	send := func(path, remoteAddr, user string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if user != "" {
+			req.Header.Set("Remote-User", user)
+		}
+		w := httptest.NewRecorder()
+		env.server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	if resp := send("/api/auth/me", "10.0.0.1:4000", "alice"); resp.Code != http.StatusOK {
+		t.Errorf("expected the proxy's user authenticated, got %d", resp.Code)
+	}
+	if resp := send("/api/auth/me", "10.0.0.1:4000", "mallory"); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected a user not listed refused, got %d", resp.Code)
+	}
+	if resp := send("/api/auth/me", "10.0.0.9:4000", "alice"); resp.Code != http.StatusUnauthorized {
+		t.Errorf("expected the header ignored from another peer, got %d", resp.Code)
+	}
+
+	var status map[string]any
+	decodeResponse(t, send("/api/auth/status", "10.0.0.1:4000", "alice"), &status)
+	if status["forwardAuth"] != true {
+		t.Errorf("expected status to report forward auth, got %v", status)
+	}
+}
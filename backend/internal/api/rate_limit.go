@@ -0,0 +1,179 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+const (
+	// defaultRequestRateLimit is how many API requests a single token may make
+	// per minute. Override with CODEBURG_RATE_LIMIT; 0 disables the limit.
+	defaultRequestRateLimit = 600
+	// defaultMessageRateLimit is how many messages may be sent to a single
+	// session per minute. Override with CODEBURG_MESSAGE_RATE_LIMIT; 0
+	// disables the limit.
+	defaultMessageRateLimit = 20
+
+	// rateLimitPruneInterval is how often idle buckets are dropped.
+	rateLimitPruneInterval = 5 * time.Minute
+)
+
+// rateLimitedError reports that a caller exceeded a limit and when it may try
+// again.
+type rateLimitedError struct {
+	what       string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("too many %s, retry in %ss", e.what, retryAfterSeconds(e.retryAfter))
+}
+
+// rateLimiter is a token bucket per key: a key may use up to perMinute
+// requests at once, and its allowance refills evenly over the minute. A nil
+// limiter allows everything.
+type rateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	buckets   map[string]*rateBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing perMinute requests per key, or
+// nil when perMinute is not positive.
+func newRateLimiter(perMinute int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		perMinute: perMinute,
+		buckets:   make(map[string]*rateBucket),
+		now:       time.Now,
+	}
+}
+
+// take spends one request for key. When the key is out of requests it
+// returns false and how long until the next one is available.
+func (rl *rateLimiter) take(key string) (bool, time.Duration) {
+	if rl == nil {
+		return true, 0
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	capacity := float64(rl.perMinute)
+	perSecond := capacity / 60
+
+	if now.Sub(rl.lastPrune) > rateLimitPruneInterval {
+		rl.prune(now, capacity, perSecond)
+		rl.lastPrune = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: capacity, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops buckets that have refilled completely, since a fresh bucket
+// behaves the same.
+func (rl *rateLimiter) prune(now time.Time, capacity, perSecond float64) {
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*perSecond >= capacity {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// rateLimitFromEnv reads a per-minute limit from the environment, falling
+// back to def when the variable is unset or invalid.
+func rateLimitFromEnv(name string, def int) int {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		slog.Warn("ignoring invalid rate limit", "env", name, "value", raw)
+		return def
+	}
+	return n
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	secs := int(math.Ceil(d.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.Itoa(secs)
+}
+
+// writeRateLimited responds 429 with a Retry-After header.
+func writeRateLimited(w http.ResponseWriter, err *rateLimitedError) {
+	w.Header().Set("Retry-After", retryAfterSeconds(err.retryAfter))
+	writeError(w, http.StatusTooManyRequests, err.Error())
+}
+
+// rateLimitKey identifies the credential behind an authenticated request:
+// the API token's ID, or a digest of the session JWT.
+func rateLimitKey(r *http.Request) string {
+	if token, ok := r.Context().Value(apiTokenContextKey).(*db.APIToken); ok && token != nil {
+		return "token:" + token.ID
+	}
+	bearer := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer == "" {
+		return "ip:" + clientIP(r)
+	}
+	sum := sha256.Sum256([]byte(bearer))
+	return "jwt:" + hex.EncodeToString(sum[:8])
+}
+
+// rateLimitMiddleware limits authenticated requests per token. It runs after
+// authMiddleware so that invalid credentials are rejected before they can
+// use up anyone's allowance.
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := s.requestLimiter.take(rateLimitKey(r)); !ok {
+			writeRateLimited(w, &rateLimitedError{what: "requests", retryAfter: wait})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// throttleSessionMessage spends one of a session's message sends, returning
+// a *rateLimitedError when it has sent too many recently.
+func (s *Server) throttleSessionMessage(sessionID string) error {
+	if ok, wait := s.messageLimiter.take(sessionID); !ok {
+		return &rateLimitedError{what: "messages to this session", retryAfter: wait}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_RefillsOverTime(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	rl := newRateLimiter(2)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := rl.take("a"); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	ok, wait := rl.take("a")
+	if ok {
+		t.Fatal("third request should be limited")
+	}
+	if wait <= 0 || wait > 30*time.Second {
+		t.Fatalf("unexpected wait %s", wait)
+	}
+	if ok, _ := rl.take("b"); !ok {
+		t.Fatal("other keys should have their own allowance")
+	}
+
+	now = now.Add(wait)
+	if ok, _ := rl.take("a"); !ok {
+		t.Fatal("request should be allowed after waiting")
+	}
+}
+
+func TestRateLimiter_NilAllowsEverything(t *testing.T) {
+	rl := newRateLimiter(0)
+	for i := 0; i < 100; i++ {
+		if ok, _ := rl.take("a"); !ok {
+			t.Fatal("disabled limiter should allow all requests")
+		}
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.requestLimiter = newRateLimiter(2)
+
+	for i := 0; i < 2; i++ {
+		if resp := env.get("/api/projects"); resp.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, resp.Code)
+		}
+	}
+	resp := env.get("/api/projects")
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	// Unauthenticated requests are rejected before they use the allowance.
+	env.token = ""
+	if resp := env.get("/api/projects"); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.Code)
+	}
+}
+
+func TestSendMessage_ThrottledPerSession(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.messageLimiter = newRateLimiter(1)
+
+	_, session := createRunningTaskSession(t, env, "claude")
+	path := "/api/sessions/" + session.ID + "/message"
+
+	if resp := env.post(path, map[string]string{"content": "hello"}); resp.Code == http.StatusTooManyRequests {
+		t.Fatal("first message should not be throttled")
+	}
+	resp := env.post(path, map[string]string{"content": "again"})
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+}
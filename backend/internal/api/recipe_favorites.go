@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/recipes"
+)
+
+const recipeFavoritesPreference = "recipe_favorites"
+
+// RecipeFavorite is a recipe saved with its arguments so it can be rerun in
+// one click.
+type RecipeFavorite struct {
+	ID        string            `json:"id"`
+	ProjectID string            `json:"projectId"`
+	Source    string            `json:"source"`
+	Name      string            `json:"name"`
+	Args      map[string]string `json:"args,omitempty"`
+	Command   string            `json:"command"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+func (s *Server) recipeFavorites() []RecipeFavorite {
+	pref, err := s.db.GetPreference(db.DefaultUserID, recipeFavoritesPreference)
+	if err != nil {
+		return nil
+	}
+	var favorites []RecipeFavorite
+	if err := json.Unmarshal([]byte(pref.Value), &favorites); err != nil {
+		slog.Warn("invalid recipe_favorites preference", "error", err)
+		return nil
+	}
+	return favorites
+}
+
+// updateRecipeFavorites applies fn to the stored favorites.
+func (s *Server) updateRecipeFavorites(fn func([]RecipeFavorite) []RecipeFavorite) error {
+	s.recipeFavoritesMu.Lock()
+	defer s.recipeFavoritesMu.Unlock()
+	data, err := json.Marshal(fn(s.recipeFavorites()))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.SetPreference(db.DefaultUserID, recipeFavoritesPreference, string(data))
+	return err
+}
+
+func (s *Server) handleListRecipeFavorites(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	if _, err := s.db.GetProject(projectID); err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	favorites := []RecipeFavorite{}
+	for _, favorite := range s.recipeFavorites() {
+		if favorite.ProjectID == projectID {
+			favorites = append(favorites, favorite)
+		}
+	}
+	writeJSON(w, http.StatusOK, favorites)
+}
+
+// handleCreateRecipeFavorite saves a project recipe with arguments. The
+// arguments are checked against the recipe's parameters and the resulting
+// command is stored alongside them.
+func (s *Server) handleCreateRecipeFavorite(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	var input struct {
+		Source string            `json:"source"`
+		Name   string            `json:"name"`
+		Args   map[string]string `json:"args"`
+	}
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.Source == "" || input.Name == "" {
+		writeError(w, http.StatusBadRequest, "source and name are required")
+		return
+	}
+
+	discovered, err := recipesMgr.List(project.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var recipe *recipes.Recipe
+	for i := range discovered {
+		if discovered[i].Source == input.Source && discovered[i].Name == input.Name {
+			recipe = &discovered[i]
+			break
+		}
+	}
+	if recipe == nil {
+		writeError(w, http.StatusNotFound, "recipe not found")
+		return
+	}
+	command, err := recipe.CommandWithArgs(input.Args)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	favorite := RecipeFavorite{
+		ID:        db.NewID(),
+		ProjectID: project.ID,
+		Source:    recipe.Source,
+		Name:      recipe.Name,
+		Args:      input.Args,
+		Command:   command,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = s.updateRecipeFavorites(func(favorites []RecipeFavorite) []RecipeFavorite {
+		return append(favorites, favorite)
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save favorite")
+		return
+	}
+	writeJSON(w, http.StatusCreated, favorite)
+}
+
+func (s *Server) handleDeleteRecipeFavorite(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	favoriteID := urlParam(r, "favoriteId")
+
+	found := false
+	err := s.updateRecipeFavorites(func(favorites []RecipeFavorite) []RecipeFavorite {
+		kept := favorites[:0]
+		for _, favorite := range favorites {
+			if favorite.ID == favoriteID && favorite.ProjectID == projectID {
+				found = true
+				continue
+			}
+			kept = append(kept, favorite)
+		}
+		return kept
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete favorite")
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "favorite not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
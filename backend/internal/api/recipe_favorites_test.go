@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestRecipeFavorites(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	if err := os.WriteFile(filepath.Join(repoPath, "justfile"), []byte("deploy env=\"staging\" +targets:\n\t./deploy.sh {{env}} {{targets}}\n"), 0644); err != nil {
+		t.Fatalf("write justfile: %v", err)
+	}
+	resp := env.post("/api/projects", map[string]string{"name": "fav-project", "path": repoPath})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create project: %d %s", resp.Code, resp.Body.String())
+	}
+	var project db.Project
+	decodeResponse(t, resp, &project)
+	base := "/api/projects/" + project.ID + "/recipe-favorites"
+
+	resp = env.post(base, map[string]any{"source": "justfile", "name": "deploy", "args": map[string]string{"env": "prod"}})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for missing required parameter, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := env.post(base, map[string]any{"source": "justfile", "name": "missing"}); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown recipe, got %d", resp.Code)
+	}
+
+	resp = env.post(base, map[string]any{"source": "justfile", "name": "deploy", "args": map[string]string{"env": "prod", "targets": "web"}})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var favorite RecipeFavorite
+	decodeResponse(t, resp, &favorite)
+	if favorite.Command != "just deploy prod web" {
+		t.Errorf("unexpected command %q", favorite.Command)
+	}
+
+	var list []RecipeFavorite
+	decodeResponse(t, env.get(base), &list)
+	if len(list) != 1 || list[0].ID != favorite.ID {
+		t.Fatalf("expected the favorite to be listed, got %+v", list)
+	}
+
+	if resp := env.delete(base + "/" + favorite.ID); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.Code)
+	}
+	if resp := env.delete(base + "/" + favorite.ID); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted favorite, got %d", resp.Code)
+	}
+}
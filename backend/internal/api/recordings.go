@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/ptyruntime"
+)
+
+// Terminal session recordings preference:
+//
+//	session_recordings  {"enabled": true, "retentionDays": 14, "maxMB": 50}
+//
+// Terminal sessions record their PTY output in asciicast v2 format, so what
+// an agent did can be replayed after its process exits. retentionDays 0
+// keeps recordings until their session is deleted; maxMB 0 lifts the size
+// cap.
+const sessionRecordingsPreference = "session_recordings"
+
+const (
+	defaultRecordingRetentionDays = 14
+	defaultRecordingMaxMB         = 50
+	recordingSweepInterval        = time.Hour
+)
+
+type recordingSettings struct {
+	Enabled       bool `json:"enabled"`
+	RetentionDays int  `json:"retentionDays"`
+	MaxMB         int  `json:"maxMB"`
+}
+
+func recordingsRoot() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "logs", "recordings")
+}
+
+func sessionRecordingPath(sessionID string) string {
+	return filepath.Join(recordingsRoot(), sessionID+".cast")
+}
+
+// recordingSettings reads the session_recordings preference over the
+// defaults.
+func (s *Server) recordingSettings() recordingSettings {
+	settings := recordingSettings{
+		Enabled:       true,
+		RetentionDays: defaultRecordingRetentionDays,
+		MaxMB:         defaultRecordingMaxMB,
+	}
+	pref, err := s.db.GetPreference(db.DefaultUserID, sessionRecordingsPreference)
+	if err != nil {
+		return settings
+	}
+	if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+		slog.Warn("invalid session_recordings preference", "error", err)
+	}
+	settings.RetentionDays = max(settings.RetentionDays, 0)
+	settings.MaxMB = max(settings.MaxMB, 0)
+	return settings
+}
+
+// sessionRecording returns the recording options for a terminal session,
+// or nil when recording is turned off.
+func (s *Server) sessionRecording(sessionID string) *ptyruntime.RecordingOptions {
+	settings := s.recordingSettings()
+	if !settings.Enabled {
+		return nil
+	}
+	return &ptyruntime.RecordingOptions{
+		Path:     sessionRecordingPath(sessionID),
+		MaxBytes: int64(settings.MaxMB) << 20,
+	}
+}
+
+// sweepRecordings deletes expired recordings every recordingSweepInterval.
+func (s *Server) sweepRecordings(ctx context.Context) {
+	ticker := time.NewTicker(recordingSweepInterval)
+	defer ticker.Stop()
+
+	s.removeExpiredRecordings()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.removeExpiredRecordings()
+		}
+	}
+}
+
+// removeExpiredRecordings runs one sweep. A recording expires once it has
+// gone unwritten for the retention period and its session isn't running.
+func (s *Server) removeExpiredRecordings() {
+	retentionDays := s.recordingSettings().RetentionDays
+	if retentionDays == 0 {
+		return
+	}
+	entries, err := os.ReadDir(recordingsRoot())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to list session recordings", "error", err)
+		}
+		return
+	}
+	cutoff := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour)
+	for _, entry := range entries {
+		sessionID, ok := strings.CutSuffix(entry.Name(), ".cast")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) || s.sessions.runtime.Exists(sessionID) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(recordingsRoot(), entry.Name())); err != nil {
+			slog.Warn("failed to remove session recording", "session_id", sessionID, "error", err)
+		}
+	}
+}
+
+// handleGetSessionRecording serves GET /api/sessions/{id}/recording, the
+// session's asciicast recording. download=true serves it as an attachment.
+func (s *Server) handleGetSessionRecording(w http.ResponseWriter, r *http.Request) {
+	session, err := s.db.GetSession(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+
+	f, err := os.Open(sessionRecordingPath(session.ID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, http.StatusNotFound, "session has no recording")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to open recording")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to stat recording")
+		return
+	}
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "true" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": session.ID + ".cast"}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestSessionRecording_Serve(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	_, session := createRunningTaskSession(t, env, "terminal")
+
+	path := "/api/sessions/" + session.ID + "/recording"
+	if resp := env.get(path); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a recording, got %d", resp.Code)
+	}
+
+	cast := "{\"version\":2,\"width\":80,\"height\":24,\"timestamp\":1}\n[0.5,\"o\",\"hi\"]\n"
+	if err := os.MkdirAll(recordingsRoot(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sessionRecordingPath(session.ID), []byte(cast), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	resp := env.get(path + "?download=true")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if got := resp.Header().Get("Content-Type"); got != "application/x-asciicast" {
+		t.Errorf("content type = %q", got)
+	}
+	if got := resp.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+		t.Errorf("content disposition = %q", got)
+	}
+	if resp.Body.String() != cast {
+		t.Errorf("body = %q", resp.Body.String())
+	}
+
+	if resp := env.get("/api/sessions/missing/recording"); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown session, got %d", resp.Code)
+	}
+}
+
+func TestSessionRecording_Settings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+
+	opts := env.server.sessionRecording("s1")
+	if opts == nil || opts.Path != sessionRecordingPath("s1") || opts.MaxBytes != defaultRecordingMaxMB<<20 {
+		t.Fatalf("unexpected default recording options %+v", opts)
+	}
+	env.server.db.SetPreference(db.DefaultUserID, sessionRecordingsPreference, `{"enabled": false}`)
+	if opts := env.server.sessionRecording("s1"); opts != nil {
+		t.Errorf("expected no recording when disabled, got %+v", opts)
+	}
+}
+
+func TestSessionRecording_Retention(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.server.db.SetPreference(db.DefaultUserID, sessionRecordingsPreference, `{"enabled": true, "retentionDays": 7}`)
+
+	if err := os.MkdirAll(recordingsRoot(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	old, fresh := sessionRecordingPath("old"), sessionRecordingPath("fresh")
+	for _, p := range []string{old, fresh} {
+		if err := os.WriteFile(p, []byte("{}\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := time.Now().Add(-8 * 24 * time.Hour)
+	if err := os.Chtimes(old, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	env.server.removeExpiredRecordings()
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expected the expired recording removed, got %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("expected the recent recording kept: %v", err)
+	}
+
+	// Deleting a session deletes its recording.
+	removeSessionLog("fresh")
+	if _, err := os.Stat(filepath.Join(recordingsRoot(), "fresh.cast")); !os.IsNotExist(err) {
+		t.Errorf("expected the recording removed with its session, got %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/github"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// GitHub webhook preference:
+//
+//	github_webhook_secret  the secret of a GitHub webhook posting to /api/github/webhook
+//
+// With it (or GITHUB_WEBHOOK_SECRET), GitHub can post pull request reviews,
+// and the comments of each review submitted on a task's pull request are
+// sent to the task's active chat session to be addressed.
+const githubWebhookSecretPreference = "github_webhook_secret"
+
+// GitHub review webhook preference:
+//
+//	github_review_webhook  {"reviewers": ["alice"], "startSessions": true}
+//
+// Only reviews by the repository's owner, members and collaborators, or by
+// the GitHub users listed in reviewers, are sent on: anyone can review a
+// public repository's pull requests, and their comments become an agent's
+// prompt. startSessions starts a chat session for a review when the task
+// has no active one; by default such reviews are left for the user.
+const githubReviewWebhookPreference = "github_review_webhook"
+
+type githubReviewWebhookSettings struct {
+	Reviewers     []string `json:"reviewers"`
+	StartSessions bool     `json:"startSessions"`
+}
+
+// trustedReviewAssociations are the author_association values of reviewers
+// with write access to the repository.
+var trustedReviewAssociations = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+// trusts reports whether a review by login, with the given
+// author_association, may be sent to an agent.
+func (c githubReviewWebhookSettings) trusts(login, association string) bool {
+	if slices.Contains(trustedReviewAssociations, strings.ToUpper(association)) {
+		return true
+	}
+	return login != "" && slices.ContainsFunc(c.Reviewers, func(r string) bool {
+		return strings.EqualFold(strings.TrimPrefix(r, "@"), login)
+	})
+}
+
+func (s *Server) githubReviewWebhookSettings() githubReviewWebhookSettings {
+	var settings githubReviewWebhookSettings
+	if pref, err := s.db.GetPreference(db.DefaultUserID, githubReviewWebhookPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+			slog.Warn("invalid github_review_webhook preference", "error", err)
+			settings = githubReviewWebhookSettings{}
+		}
+	}
+	return settings
+}
+
+// maxGitHubWebhookBytes bounds a webhook payload; review events are a few
+// kilobytes.
+const maxGitHubWebhookBytes = 5 << 20
+
+// fetchReviewComments reads a pull request's review comments; tests
+// replace it.
+var fetchReviewComments = github.ReviewComments
+
+type reviewCommentsRequest struct {
+	SessionID string `json:"sessionId,omitempty"` // an active chat session of the task; empty starts one
+	Provider  string `json:"provider,omitempty"`  // of a new session (default: claude)
+	Model     string `json:"model,omitempty"`     // of a new session
+	ReviewID  int64  `json:"reviewId,omitempty"`  // one review's comments; 0 for every review's
+}
+
+type reviewCommentsResponse struct {
+	SessionID string `json:"sessionId"`
+	Started   bool   `json:"started"` // whether a new session was started
+	Comments  int    `json:"comments"`
+}
+
+// handleTaskReviewComments pulls the review comments of a task's pull
+// request and sends them to a chat session as a prompt to address them.
+func (s *Server) handleTaskReviewComments(w http.ResponseWriter, r *http.Request) {
+	var req reviewCommentsRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	resp, err := s.ingestReviewComments(r.Context(), urlParam(r, "id"), req)
+	if err != nil {
+		writeServiceError(w, err, "failed to send review comments")
+		return
+	}
+	status := http.StatusOK
+	if resp.Started {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *Server) ingestReviewComments(ctx context.Context, taskID string, req reviewCommentsRequest) (*reviewCommentsResponse, error) {
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return nil, notFound(err, "task")
+	}
+	prURL := ptrToString(task.PRURL)
+	if prURL == "" {
+		return nil, service.Invalid("task has no pull request")
+	}
+	if _, _, err := github.ParsePRURL(prURL); err != nil {
+		return nil, service.Invalid("review comments need a GitHub pull request")
+	}
+	if req.Provider == "terminal" {
+		return nil, service.Invalid("review comments go to a chat session")
+	}
+
+	var session *db.AgentSession
+	if req.SessionID != "" {
+		if session, err = s.db.GetSession(req.SessionID); err != nil {
+			return nil, notFound(err, "session")
+		}
+		if session.TaskID != task.ID || session.SessionType != "chat" {
+			return nil, service.Invalid("session is not a chat session of the task")
+		}
+	}
+
+	comments, err := fetchReviewComments(ctx, prURL, req.ReviewID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch review comments: %w", err)
+	}
+	if len(comments) == 0 {
+		return nil, service.Invalid("the pull request has no review comments")
+	}
+	prompt := reviewCommentsPrompt(prURL, comments)
+
+	if session != nil {
+		if err := s.sessionService().Send(ctx, session.ID, prompt); err != nil {
+			return nil, err
+		}
+		return &reviewCommentsResponse{SessionID: session.ID, Comments: len(comments)}, nil
+	}
+	provider := req.Provider
+	if provider == "" {
+		provider = "claude"
+	}
+	started, err := s.sessionService().StartInTask(ctx, task.ID, StartSessionRequest{
+		Provider: provider, SessionType: "chat", Prompt: prompt, Model: req.Model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reviewCommentsResponse{SessionID: started.ID, Started: true, Comments: len(comments)}, nil
+}
+
+// reviewCommentsPrompt asks the agent to address comments, each with where
+// it was left, who left it and what it says.
+func reviewCommentsPrompt(prURL string, comments []github.ReviewComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Address these review comments on %s. Make the changes they ask for, or explain why a comment should not be followed.\n", prURL)
+	for i, c := range comments {
+		where := "Review"
+		if c.Path != "" {
+			where = c.Path
+			if c.Line > 0 {
+				where += fmt.Sprintf(":%d", c.Line)
+			}
+		}
+		fmt.Fprintf(&b, "\n%d. %s (@%s)\n", i+1, where, c.Author)
+		for _, line := range strings.Split(strings.TrimSpace(c.Body), "\n") {
+			b.WriteString("   > " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+func (s *Server) githubWebhookSecret() string {
+	if pref, err := s.db.GetPreference(db.DefaultUserID, githubWebhookSecretPreference); err == nil {
+		if secret := unquotePreference(pref.Value); secret != "" {
+			return secret
+		}
+	}
+	return os.Getenv("GITHUB_WEBHOOK_SECRET")
+}
+
+// validGitHubSignature checks the X-Hub-Signature-256 header of a payload.
+func validGitHubSignature(secret string, payload []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// handleGitHubWebhook takes pull_request_review events from GitHub and
+// sends the comments of each submitted review by a trusted reviewer to the
+// task with that pull request. Other events are acknowledged and ignored.
+func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := s.githubWebhookSecret()
+	if secret == "" {
+		writeError(w, http.StatusNotFound, "GitHub webhook not configured")
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxGitHubWebhookBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read payload")
+		return
+	}
+	if !validGitHubSignature(secret, payload, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	if r.Header.Get("X-GitHub-Event") != "pull_request_review" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var event struct {
+		Action string `json:"action"`
+		Review struct {
+			ID                int64  `json:"id"`
+			State             string `json:"state"`
+			Body              string `json:"body"`
+			AuthorAssociation string `json:"author_association"`
+			User              struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		} `json:"review"`
+		PullRequest struct {
+			HTMLURL string `json:"html_url"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	// An approval without a word leaves nothing to address.
+	if event.Action != "submitted" || (strings.EqualFold(event.Review.State, "approved") && strings.TrimSpace(event.Review.Body) == "") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	task, err := s.db.GetTaskByPRURL(event.PullRequest.HTMLURL)
+	if err != nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	settings := s.githubReviewWebhookSettings()
+	if !settings.trusts(event.Review.User.Login, event.Review.AuthorAssociation) {
+		slog.Info("ignored a review from an untrusted reviewer", "task_id", task.ID, "review_id", event.Review.ID,
+			"reviewer", event.Review.User.Login, "association", event.Review.AuthorAssociation)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	req := reviewCommentsRequest{ReviewID: event.Review.ID}
+	session := s.latestChatSession(task.ID)
+	switch {
+	case session != nil && (session.Status == db.SessionStatusRunning || session.Status == db.SessionStatusWaitingInput):
+		req.SessionID = session.ID
+	case !settings.StartSessions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case session != nil:
+		req.Provider = session.Provider
+	}
+	// GitHub gives up on slow deliveries, and reading the comments takes a
+	// few calls, so answer first.
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		resp, err := s.ingestReviewComments(s.bgCtx, task.ID, req)
+		if err != nil {
+			slog.Warn("failed to send review comments", "task_id", task.ID, "review_id", event.Review.ID, "error", err)
+			return
+		}
+		slog.Info("sent review comments", "task_id", task.ID, "session_id", resp.SessionID, "comments", resp.Comments)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// latestChatSession returns a task's newest chat session, or nil.
+func (s *Server) latestChatSession(taskID string) *db.AgentSession {
+	sessions, err := s.db.ListSessionsByTask(taskID)
+	if err != nil {
+		return nil
+	}
+	for _, session := range sessions {
+		if session.SessionType == "chat" {
+			return session
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/github"
+)
+
+func TestReviewComments(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	invocations := installFakeProviders(t, nil)
+
+	const prURL = "https://github.com/acme/shop/pull/7"
+	var mu sync.Mutex
+	var reviewIDs []int64
+	fetchReviewComments = func(ctx context.Context, url string, reviewID int64) ([]github.ReviewComment, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reviewIDs = append(reviewIDs, reviewID)
+		return []github.ReviewComment{
+			{Author: "alice", Body: "Handle nil here.", Path: "main.go", Line: 12},
+			{Author: "alice", Body: "Needs tests."},
+		}, nil
+	}
+	t.Cleanup(func() { fetchReviewComments = github.ReviewComments })
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix login"}), &task)
+
+	if resp := env.post("/api/tasks/"+task.ID+"/review-comments", nil); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a pull request, got %d", resp.Code)
+	}
+	pr := prURL
+	env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{PRURL: &pr})
+
+	resp := env.post("/api/tasks/"+task.ID+"/review-comments", nil)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("review comments: %d %s", resp.Code, resp.Body.String())
+	}
+	var result reviewCommentsResponse
+	decodeResponse(t, resp, &result)
+	if !result.Started || result.Comments != 2 {
+		t.Fatalf("expected a new session with two comments, got %+v", result)
+	}
+	env.waitForSessionStatus(t, result.SessionID, db.SessionStatusWaitingInput)
+	prompt := strings.Join(invocations()[0].Prompts, "\n")
+	if !strings.Contains(prompt, "Address these review comments on "+prURL) || !strings.Contains(prompt, "1. main.go:12 (@alice)\n   > Handle nil here.") {
+		t.Errorf("unexpected prompt: %q", prompt)
+	}
+
+	// A submitted review goes to the task's active chat session.
+	env.server.db.SetPreference(db.DefaultUserID, githubWebhookSecretPreference, `"s3cret"`)
+	post := func(reviewID int, association, signature string) *httptest.ResponseRecorder {
+		payload := fmt.Sprintf(`{"action": "submitted", "review": {"id": %d, "state": "changes_requested", "body": "", "author_association": %q, "user": {"login": "mallory"}}, "pull_request": {"html_url": %q}}`,
+			reviewID, association, prURL)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte("s3cret"))
+			mac.Write([]byte(payload))
+			signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		req := httptest.NewRequest("POST", "/api/github/webhook", strings.NewReader(payload))
+		req.Header.Set("X-GitHub-Event", "pull_request_review")
+		req.Header.Set("X-Hub-Signature-256", signature)
+		w := httptest.NewRecorder()
+		env.server.router.ServeHTTP(w, req)
+		return w
+	}
+	if resp := post(42, "COLLABORATOR", "sha256="+strings.Repeat("0", 64)); resp.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad signature, got %d", resp.Code)
+	}
+	// Anyone may review a public repository; their comments are not an
+	// agent's prompt.
+	if resp := post(41, "NONE", ""); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for an untrusted reviewer, got %d", resp.Code)
+	}
+	if resp := post(42, "COLLABORATOR", ""); resp.Code != http.StatusAccepted {
+		t.Fatalf("webhook: %d %s", resp.Code, resp.Body.String())
+	}
+	waitForCondition(t, 10*time.Second, func() bool { return len(invocations()) == 2 }, "second turn")
+	env.waitForSessionStatus(t, result.SessionID, db.SessionStatusWaitingInput)
+
+	// A reviewer listed in the preference is trusted too. Without an active
+	// session, the review waits for the user unless startSessions is set.
+	env.server.db.SetPreference(db.DefaultUserID, githubReviewWebhookPreference, `{"reviewers": ["@Mallory"]}`)
+	completed := db.SessionStatusCompleted
+	env.server.db.UpdateSession(result.SessionID, db.UpdateSessionInput{Status: &completed})
+	if resp := post(43, "CONTRIBUTOR", ""); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 without an active session, got %d", resp.Code)
+	}
+	env.server.db.SetPreference(db.DefaultUserID, githubReviewWebhookPreference, `{"reviewers": ["@Mallory"], "startSessions": true}`)
+	if resp := post(44, "CONTRIBUTOR", ""); resp.Code != http.StatusAccepted {
+		t.Fatalf("webhook with startSessions: %d %s", resp.Code, resp.Body.String())
+	}
+	waitForCondition(t, 10*time.Second, func() bool { return len(invocations()) == 3 }, "new session")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(reviewIDs, []int64{0, 42, 44}) {
+		t.Errorf("expected the webhook to fetch reviews 42 and 44, got %v", reviewIDs)
+	}
+	if sessions, _ := env.server.db.ListSessionsByTask(task.ID); len(sessions) != 2 {
+		t.Errorf("expected review 42 in the existing session and a new one for 44, got %d sessions", len(sessions))
+	}
+}
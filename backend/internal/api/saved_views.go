@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// defaultViewRef is the reserved view reference that resolves to the user's default view.
+const defaultViewRef = "default"
+
+func validateViewFilters(f db.ViewFilters) string {
+	// Views span projects, so any status a board column could have is fine.
+	for _, st := range f.Statuses {
+		if !db.IsBuiltinTaskStatus(st) && !boardColumnIDRe.MatchString(string(st)) {
+			return "invalid status: " + string(st)
+		}
+	}
+	if !db.ValidTaskSort(f.Sort) {
+		return "invalid sort: " + f.Sort
+	}
+	return ""
+}
+
+// resolveSavedView looks up a view by reference: "default", an ID, or a name.
+func (s *Server) resolveSavedView(ref string) (*db.SavedView, error) {
+	if ref == defaultViewRef {
+		return s.db.GetDefaultSavedView(db.DefaultUserID)
+	}
+	view, err := s.db.GetSavedView(db.DefaultUserID, ref)
+	if errors.Is(err, db.ErrNotFound) {
+		return s.db.GetSavedViewByName(db.DefaultUserID, ref)
+	}
+	return view, err
+}
+
+func (s *Server) handleListSavedViews(w http.ResponseWriter, r *http.Request) {
+	views, err := s.db.ListSavedViews(db.DefaultUserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list views")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleGetSavedView(w http.ResponseWriter, r *http.Request) {
+	view, err := s.resolveSavedView(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "view")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) handleCreateSavedView(w http.ResponseWriter, r *http.Request) {
+	var input db.CreateSavedViewInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if strings.EqualFold(input.Name, defaultViewRef) {
+		writeError(w, http.StatusBadRequest, "view name \"default\" is reserved")
+		return
+	}
+	if msg := validateViewFilters(input.Filters); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if _, err := s.db.GetSavedViewByName(db.DefaultUserID, input.Name); err == nil {
+		writeError(w, http.StatusConflict, "a view with this name already exists")
+		return
+	}
+
+	view, err := s.db.CreateSavedView(db.DefaultUserID, input)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create view")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, view)
+}
+
+func (s *Server) handleUpdateSavedView(w http.ResponseWriter, r *http.Request) {
+	id := urlParam(r, "id")
+
+	var input db.UpdateSavedViewInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			writeError(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		if strings.EqualFold(name, defaultViewRef) {
+			writeError(w, http.StatusBadRequest, "view name \"default\" is reserved")
+			return
+		}
+		if existing, err := s.db.GetSavedViewByName(db.DefaultUserID, name); err == nil && existing.ID != id {
+			writeError(w, http.StatusConflict, "a view with this name already exists")
+			return
+		}
+		input.Name = &name
+	}
+	if input.Filters != nil {
+		if msg := validateViewFilters(*input.Filters); msg != "" {
+			writeError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	view, err := s.db.UpdateSavedView(db.DefaultUserID, id, input)
+	if err != nil {
+		writeDBError(w, err, "view")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) handleDeleteSavedView(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.DeleteSavedView(db.DefaultUserID, urlParam(r, "id")); err != nil {
+		writeDBError(w, err, "view")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestSavedViews_ListTasksWithView(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+
+	env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix outage", "priority": "urgent"})
+	env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Tidy docs", "priority": "low"})
+
+	resp := env.post("/api/views", map[string]any{
+		"name":      "urgent",
+		"filters":   map[string]any{"priorities": []string{"urgent"}},
+		"isDefault": true,
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var view db.SavedView
+	decodeResponse(t, resp, &view)
+
+	if resp := env.post("/api/views", map[string]any{"name": "Urgent"}); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate name, got %d", resp.Code)
+	}
+	if resp := env.post("/api/views", map[string]any{"name": "bad", "filters": map[string]any{"sort": "random"}}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid sort, got %d", resp.Code)
+	}
+
+	for _, ref := range []string{view.ID, "urgent", "default"} {
+		resp := env.get("/api/tasks?view=" + ref)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("view=%s: expected 200, got %d", ref, resp.Code)
+		}
+		var tasks []db.Task
+		decodeResponse(t, resp, &tasks)
+		if len(tasks) != 1 || tasks[0].Title != "Fix outage" {
+			t.Errorf("view=%s: expected only the urgent task, got %d tasks", ref, len(tasks))
+		}
+	}
+
+	if resp := env.get("/api/tasks?view=missing"); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown view, got %d", resp.Code)
+	}
+
+	// Unfiltered board is unaffected by the default view.
+	var all []db.Task
+	decodeResponse(t, env.get("/api/tasks"), &all)
+	if len(all) != 2 {
+		t.Errorf("expected 2 tasks without a view, got %d", len(all))
+	}
+}
+
+func TestTelegramTasksCommand(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"`+strconv.FormatInt(tgUserID, 10)+`"`)
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Fix outage", "priority": "urgent"})
+	env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Tidy docs"})
+	env.post("/api/views", map[string]any{
+		"name":    "urgent",
+		"filters": map[string]any{"priorities": []string{"urgent"}},
+	})
+
+	reply := env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: "tasks", Args: "view:urgent"})
+	if !strings.Contains(reply, "Fix outage") || strings.Contains(reply, "Tidy docs") {
+		t.Errorf("unexpected /tasks view:urgent reply: %q", reply)
+	}
+
+	reply = env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: "tasks", Args: "view:nope"})
+	if !strings.Contains(reply, "View not found") {
+		t.Errorf("expected view-not-found reply, got %q", reply)
+	}
+
+	if reply := env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: tgUserID, UserID: 1, Name: "tasks"}); reply != "" {
+		t.Errorf("expected no reply for unauthorized user, got %q", reply)
+	}
+}
@@ -0,0 +1,420 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/embedding"
+	"github.com/miguel-bm/codeburg/internal/llm"
+)
+
+// Semantic search preference:
+//
+//	semantic_search  {"enabled": true}
+//
+// Embeddings come from the semantic_search feature of the llm preference.
+// A project's index is
+// built on its first search and then kept up to date: only files whose
+// size or modification time changed are embedded again, after file and git
+// writes through the API and before each search.
+const (
+	semanticSearchPreference = "semantic_search"
+	semanticMaxFiles         = 5000
+	semanticBatchSize        = 64
+	semanticDefaultResults   = 10
+	semanticMaxResults       = 50
+	semanticPreviewBytes     = 400
+	// semanticRefreshDelay lets a burst of file changes settle before the
+	// index catches up with them.
+	semanticRefreshDelay = 2 * time.Second
+)
+
+type semanticSearchConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+var errSemanticSearchDisabled = errors.New("semantic search is not enabled")
+
+// embedder returns the configured embedding provider.
+func (s *Server) embedder() (embedding.Embedder, error) {
+	var cfg semanticSearchConfig
+	if pref, err := s.db.GetPreference(db.DefaultUserID, semanticSearchPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			return nil, fmt.Errorf("invalid semantic_search preference: %w", err)
+		}
+	}
+	if !cfg.Enabled {
+		return nil, errSemanticSearchDisabled
+	}
+	client, model, err := s.llmClient(llmFeatureSemanticSearch)
+	if err != nil {
+		return nil, err
+	}
+	return &llm.Embedder{Client: client, Name: model}, nil
+}
+
+// semanticIndexer refreshes the indexes of projects whose files change,
+// once the changes settle, and keeps refreshes of a project from
+// overlapping.
+type semanticIndexer struct {
+	changes chan string
+	locks   sync.Map // projectID -> *sync.Mutex
+}
+
+func newSemanticIndexer() *semanticIndexer {
+	return &semanticIndexer{changes: make(chan string, 64)}
+}
+
+// changed notes that files of a project changed. It never blocks.
+func (x *semanticIndexer) changed(projectID string) {
+	select {
+	case x.changes <- projectID:
+	default:
+	}
+}
+
+func (x *semanticIndexer) lock(projectID string) func() {
+	mu, _ := x.locks.LoadOrStore(projectID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func (x *semanticIndexer) run(ctx context.Context, refresh func(ctx context.Context, projectID string)) {
+	pending := make(map[string]bool)
+	timer := time.NewTimer(semanticRefreshDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case projectID := <-x.changes:
+			pending[projectID] = true
+			timer.Reset(semanticRefreshDelay)
+		case <-timer.C:
+			for projectID := range pending {
+				refresh(ctx, projectID)
+			}
+			clear(pending)
+		}
+	}
+}
+
+// refreshChangedProject brings a project's index up to date after its
+// files changed, if it has one.
+func (s *Server) refreshChangedProject(ctx context.Context, projectID string) {
+	files, err := s.db.ListSemanticFiles(projectID)
+	if err != nil || len(files) == 0 {
+		return
+	}
+	embedder, err := s.embedder()
+	if err != nil {
+		return
+	}
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		return
+	}
+	if _, err := s.refreshSemanticIndex(ctx, project, embedder); err != nil {
+		slog.Warn("failed to refresh semantic index", "project_id", projectID, "error", err)
+	}
+}
+
+// semanticIndexWatcher tells the indexer about writes to a project's files,
+// direct or through git.
+func (s *Server) semanticIndexWatcher(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			return
+		}
+		if projectID := projectFilesChanged(r.URL.Path); projectID != "" {
+			s.semantic.changed(projectID)
+		}
+	})
+}
+
+// projectFilesChanged returns the project whose files a write request to
+// path may change, or "".
+func projectFilesChanged(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "api" || parts[1] != "projects" {
+		return ""
+	}
+	switch parts[3] {
+	case "file", "git", "sync-default-branch":
+		return parts[2]
+	case "files":
+		if len(parts) == 5 && parts[4] == "search" {
+			return ""
+		}
+		return parts[2]
+	}
+	return ""
+}
+
+type semanticIndexStats struct {
+	Files    int  `json:"files"`    // files in the index
+	Embedded int  `json:"embedded"` // files embedded by this refresh
+	Removed  int  `json:"removed"`  // files dropped by this refresh
+	Capped   bool `json:"capped,omitempty"`
+}
+
+// semanticPendingFile is a changed file waiting for its chunks' vectors.
+type semanticPendingFile struct {
+	file   db.SemanticFile
+	chunks []embedding.Chunk
+}
+
+// refreshSemanticIndex embeds the project's files that changed since they
+// were indexed, and drops those that are gone. Files too large or binary
+// are recorded without chunks so they aren't read again until they change.
+func (s *Server) refreshSemanticIndex(ctx context.Context, project *db.Project, embedder embedding.Embedder) (semanticIndexStats, error) {
+	defer s.semantic.lock(project.ID)()
+
+	var stats semanticIndexStats
+	indexed, err := s.db.ListSemanticFiles(project.ID)
+	if err != nil {
+		return stats, err
+	}
+	files, err := searchableFiles(ctx, project.Path)
+	if err != nil {
+		return stats, err
+	}
+	if len(files) > semanticMaxFiles {
+		files, stats.Capped = files[:semanticMaxFiles], true
+	}
+
+	var pending []semanticPendingFile
+	texts := 0
+	flush := func() error {
+		if err := s.embedSemanticFiles(ctx, project.ID, embedder, pending); err != nil {
+			return err
+		}
+		stats.Embedded += len(pending)
+		pending, texts = pending[:0], 0
+		return nil
+	}
+
+	seen := make(map[string]bool, len(files))
+	for _, rel := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		info, err := os.Lstat(filepath.Join(project.Path, filepath.FromSlash(rel)))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		seen[rel] = true
+		file := db.SemanticFile{Path: rel, Size: info.Size(), ModTime: info.ModTime().UTC(), Model: embedder.Model(), IndexedAt: time.Now()}
+		if prev, ok := indexed[rel]; ok && prev.Size == file.Size && prev.ModTime.Equal(file.ModTime) && prev.Model == file.Model {
+			continue
+		}
+
+		var chunks []embedding.Chunk
+		if info.Size() > 0 && info.Size() <= maxSearchFileBytes {
+			data, err := os.ReadFile(filepath.Join(project.Path, filepath.FromSlash(rel)))
+			if err != nil {
+				continue
+			}
+			if bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) < 0 {
+				chunks = embedding.Split(string(data))
+			}
+		}
+		pending = append(pending, semanticPendingFile{file: file, chunks: chunks})
+		if texts += len(chunks); texts >= semanticBatchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+
+	for rel := range indexed {
+		if !seen[rel] {
+			if err := s.db.DeleteSemanticFile(project.ID, rel); err != nil {
+				return stats, err
+			}
+			stats.Removed++
+		}
+	}
+	stats.Files = len(seen)
+	return stats, nil
+}
+
+// embedSemanticFiles embeds the chunks of files, in batches, and stores
+// them.
+func (s *Server) embedSemanticFiles(ctx context.Context, projectID string, embedder embedding.Embedder, files []semanticPendingFile) error {
+	var texts []string
+	for _, f := range files {
+		for _, c := range f.chunks {
+			// The path tells the model what the code is as much as the code.
+			texts = append(texts, f.file.Path+"\n\n"+c.Text)
+		}
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += semanticBatchSize {
+		batch, err := embedder.Embed(ctx, texts[start:min(start+semanticBatchSize, len(texts))])
+		if err != nil {
+			return err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	next := 0
+	for _, f := range files {
+		chunks := make([]db.SemanticChunk, 0, len(f.chunks))
+		for _, c := range f.chunks {
+			chunks = append(chunks, db.SemanticChunk{
+				Path:      f.file.Path,
+				StartLine: c.StartLine,
+				EndLine:   c.EndLine,
+				Preview:   truncatePreview(c.Text, semanticPreviewBytes),
+				Vector:    vectors[next],
+			})
+			next++
+		}
+		if err := s.db.PutSemanticFile(projectID, f.file, chunks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncatePreview(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	return strings.ToValidUTF8(text[:maxBytes], "")
+}
+
+type semanticSearchRequest struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+type semanticSearchResult struct {
+	Path      string  `json:"path"`
+	StartLine int     `json:"startLine"`
+	EndLine   int     `json:"endLine"`
+	Score     float64 `json:"score"`
+	Preview   string  `json:"preview"`
+}
+
+type semanticSearchResponse struct {
+	Results []semanticSearchResult `json:"results"`
+	Index   semanticIndexStats     `json:"index"`
+}
+
+// semanticSearch returns the chunks of the project's files closest in
+// meaning to query, best first, after bringing its index up to date.
+func (s *Server) semanticSearch(ctx context.Context, project *db.Project, embedder embedding.Embedder, query string, limit int) (semanticSearchResponse, error) {
+	stats, err := s.refreshSemanticIndex(ctx, project, embedder)
+	if err != nil {
+		return semanticSearchResponse{}, fmt.Errorf("index files: %w", err)
+	}
+	vectors, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return semanticSearchResponse{}, fmt.Errorf("embed query: %w", err)
+	}
+	chunks, err := s.db.ListSemanticChunks(project.ID)
+	if err != nil {
+		return semanticSearchResponse{}, err
+	}
+
+	results := make([]semanticSearchResult, 0, len(chunks))
+	for _, c := range chunks {
+		results = append(results, semanticSearchResult{
+			Path:      c.Path,
+			StartLine: c.StartLine,
+			EndLine:   c.EndLine,
+			Score:     embedding.Cosine(vectors[0], c.Vector),
+			Preview:   c.Preview,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return semanticSearchResponse{Results: results[:min(limit, len(results))], Index: stats}, nil
+}
+
+func (s *Server) handleSemanticSearch(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	var req semanticSearchRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "query is required")
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = semanticDefaultResults
+	}
+
+	embedder, err := s.embedder()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := s.semanticSearch(r.Context(), project, embedder, req.Query, min(req.Limit, semanticMaxResults))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "semantic search failed: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleRefreshSemanticIndex builds or updates a project's index ahead of
+// its first search.
+func (s *Server) handleRefreshSemanticIndex(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	embedder, err := s.embedder()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	stats, err := s.refreshSemanticIndex(r.Context(), project, embedder)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "failed to index files: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleDeleteSemanticIndex(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	defer s.semantic.lock(project.ID)()
+	if err := s.db.DeleteSemanticIndex(project.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to delete semantic index")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
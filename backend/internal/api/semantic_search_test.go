@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// fakeEmbeddings serves /embeddings with bag-of-words vectors, so texts
+// sharing words are close, and counts the inputs it embeds.
+func fakeEmbeddings(t *testing.T, inputs *atomic.Int64) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		inputs.Add(int64(len(req.Input)))
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		data := make([]item, 0, len(req.Input))
+		for i, text := range req.Input {
+			vector := make([]float32, 64)
+			for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return r < 'a' || r > 'z' }) {
+				h := fnv.New32a()
+				h.Write([]byte(word))
+				vector[h.Sum32()%64]++
+			}
+			data = append(data, item{Index: i, Embedding: vector})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSemanticSearch(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	repo := createTestGitRepo(t)
+	os.WriteFile(filepath.Join(repo, "db.go"), []byte("package store\n\n// open the database connection pool\nfunc Connect() {}\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "http.go"), []byte("package web\n\n// render the login page template\nfunc Login() {}\n"), 0644)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "semantic", "path": repo}), &project)
+
+	search := map[string]any{"query": "where is the database connection opened?", "limit": 2}
+	if resp := env.post("/api/projects/"+project.ID+"/semantic-search", search); resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 while disabled, got %d", resp.Code)
+	}
+
+	var inputs atomic.Int64
+	srv := fakeEmbeddings(t, &inputs)
+	env.server.db.SetPreference(db.DefaultUserID, semanticSearchPreference, `{"enabled": true}`)
+	env.server.db.SetPreference(db.DefaultUserID, llmPreference, `{"url": "`+srv.URL+`", "embeddingModel": "bag-of-words"}`)
+
+	var resp semanticSearchResponse
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/semantic-search", search), &resp)
+	if len(resp.Results) != 2 || resp.Results[0].Path != "db.go" || resp.Results[0].StartLine != 1 {
+		t.Fatalf("expected db.go first, got %+v", resp.Results)
+	}
+	if resp.Index.Embedded != resp.Index.Files || resp.Index.Files < 3 {
+		t.Errorf("expected every file embedded on the first search, got %+v", resp.Index)
+	}
+
+	// Only a file written since is embedded again.
+	if r := env.request(http.MethodPut, "/api/projects/"+project.ID+"/file", map[string]string{"path": "http.go", "content": "package web\n\n// close idle database connections\n"}); r.Code != http.StatusOK {
+		t.Fatalf("write file: %d", r.Code)
+	}
+	os.Remove(filepath.Join(repo, "db.go"))
+	inputs.Store(0)
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/semantic-search", search), &resp)
+	if resp.Index.Embedded != 1 || resp.Index.Removed != 1 || inputs.Load() != 2 {
+		t.Errorf("expected one file embedded and one removed, got %+v after %d inputs", resp.Index, inputs.Load())
+	}
+	if resp.Results[0].Path != "http.go" || !strings.Contains(resp.Results[0].Preview, "idle database") {
+		t.Errorf("expected the rewritten file first, got %+v", resp.Results)
+	}
+
+	if r := env.post("/api/projects/"+project.ID+"/semantic-search", map[string]string{"query": " "}); r.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty query, got %d", r.Code)
+	}
+	if r := env.delete("/api/projects/" + project.ID + "/semantic-index"); r.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", r.Code)
+	}
+	if files, _ := env.server.db.ListSemanticFiles(project.ID); len(files) != 0 {
+		t.Errorf("expected the index gone, got %d files", len(files))
+	}
+}
+
+func TestProjectFilesChanged(t *testing.T) {
+	for path, want := range map[string]string{
+		"/api/projects/p1/file":                "p1",
+		"/api/projects/p1/files/upload":        "p1",
+		"/api/projects/p1/git/commit":          "p1",
+		"/api/projects/p1/sync-default-branch": "p1",
+		"/api/projects/p1/files/search":        "",
+		"/api/projects/p1/tasks":               "",
+		"/api/tasks/t1/file":                   "",
+	} {
+		if got := projectFilesChanged(path); got != want {
+			t.Errorf("projectFilesChanged(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
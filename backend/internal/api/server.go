@@ -9,6 +9,7 @@ import (
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -19,13 +20,16 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/devcontainer"
+	"github.com/miguel-bm/codeburg/internal/eventbus"
 	"github.com/miguel-bm/codeburg/internal/gitclone"
+	"github.com/miguel-bm/codeburg/internal/notify"
 	"github.com/miguel-bm/codeburg/internal/portsuggest"
 	"github.com/miguel-bm/codeburg/internal/telegram"
 	"github.com/miguel-bm/codeburg/internal/tunnel"
+	"github.com/miguel-bm/codeburg/internal/version"
 	"github.com/miguel-bm/codeburg/internal/worktree"
 )
 
@@ -70,22 +74,68 @@ type Server struct {
 	tunnels           *tunnel.Manager
 	portSuggest       *portsuggest.Manager
 	gitclone          gitclone.Config
+	containers        devcontainer.Runtime
 	authLimiter       *loginRateLimiter
-	diffStatsCache    sync.Map // taskID -> diffStatsCacheEntry
+	requestLimiter    *rateLimiter
+	messageLimiter    *rateLimiter
+	uploadLimit       int64 // bytes per uploaded file
+	diffStats         diffStatsCache
+	diffStatsWake     chan struct{} // wakes the diff stats refresher
+	ciStatuses        ciStatusCache
+	ciWatches         ciWatches
 	webauthn          *webauthn.WebAuthn
 	challenges        *challengeStore
+	qrLinks           qrLinkStore
+	taskUndo          *taskUndoStore
+	pipelineRuns      *pipelineRunStore
+	poolSyncs         worktreePoolSyncs
+	comparisonMu      sync.Mutex
+	transcripts       *transcriptStreamer
+	activity          *activityTracker
+	semantic          *semanticIndexer
+	configMu          sync.RWMutex // guards config, allowedOrigins, trustedProxies and webauthn
+	config            *Config
 	allowedOrigins    []string
+	trustedProxies    []netip.Prefix // empty: loopback and private addresses
+	telegramBot       *telegram.Bot
 	telegramBotCancel context.CancelFunc
 	telegramBotMu     sync.Mutex
+	voiceRepliesTo    sync.Map // session ID → Telegram chat awaiting a voice reply
+	webPushKey        *notify.VAPIDKey
+	webPushMu         sync.Mutex
+	recipeFavoritesMu sync.Mutex
+	releases          *version.Releases
+	update            updateStatus // guarded by updateMu
+	updateMu          sync.Mutex
+	webhookWake       chan struct{} // wakes the webhook delivery loop
+	jobWake           chan struct{} // wakes the job loop
+	jobs              jobRegistry
 	httpServer        *http.Server
 	httpServerMu      sync.Mutex
 }
 
 func NewServer(database *db.DB) *Server {
+	return NewServerWithConfig(database, DefaultConfigPath())
+}
+
+// NewServerWithConfig is NewServer with the configuration file at
+// configPath.
+func NewServerWithConfig(database *db.DB, configPath string) *Server {
 	wsHub := NewWSHub()
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 
-	authSvc := NewAuthService()
+	// Relay realtime events between replicas when an event bus is configured,
+	// e.g. CODEBURG_EVENT_BUS=redis://host:6379 or nats://host:4222.
+	if busURL := os.Getenv("CODEBURG_EVENT_BUS"); busURL != "" {
+		bus, err := eventbus.Open(busURL)
+		if err != nil {
+			slog.Error("failed to configure event bus; events stay local to this instance", "error", err)
+		} else {
+			wsHub.SetEventBus(bus)
+		}
+	}
+
+	authSvc := NewAuthService(configPath)
 
 	s := &Server{
 		db:             database,
@@ -100,35 +150,46 @@ func NewServer(database *db.DB) *Server {
 		portSuggest:    portsuggest.NewManager(nil),
 		gitclone:       gitclone.DefaultConfig(),
 		authLimiter:    newLoginRateLimiter(5, 1*time.Minute),
+		requestLimiter: newRateLimiter(rateLimitFromEnv("CODEBURG_RATE_LIMIT", defaultRequestRateLimit)),
+		messageLimiter: newRateLimiter(rateLimitFromEnv("CODEBURG_MESSAGE_RATE_LIMIT", defaultMessageRateLimit)),
+		uploadLimit:    uploadLimitFromEnv(),
 		challenges:     newChallengeStore(),
-		allowedOrigins: []string{"http://localhost:*"},
+		taskUndo:       newTaskUndoStore(),
+		pipelineRuns:   newPipelineRunStore(),
+		transcripts:    newTranscriptStreamer(database),
+		semantic:       newSemanticIndexer(),
+		releases:       &version.Releases{},
+		webhookWake:    make(chan struct{}, 1),
+		jobWake:        make(chan struct{}, 1),
+		diffStatsWake:  make(chan struct{}, 1),
 	}
+	s.activity = newActivityTracker(database, s.resolveSessionWorkDir)
+	s.chat.SetFinalizedHook(func(msg ChatMessage) {
+		s.transcripts.enqueue(msg)
+		s.activity.recordChatMessage(msg)
+	})
+	s.chat.SetPermissionHook(func(msg ChatMessage) {
+		go s.notifyPermissionRequest(msg)
+	})
+	s.chat.SetPermissionPolicy(s.agentPermissionDenial)
+	s.chat.SetUsageHook(func(usage ChatUsage) {
+		go s.recordUsage(usage)
+	})
+	s.tunnels.SetExpireHandler(func(info tunnel.TunnelInfo) {
+		s.tunnelClosed(info, "its time limit ran out")
+	})
+	s.tunnels.SetReconnectHandler(s.tunnelReconnected)
+	s.tunnels.SetFailHandler(func(info tunnel.TunnelInfo, err error) {
+		s.tunnelClosed(info, "it dropped and could not be re-established: "+err.Error())
+	})
 
-	// Initialize WebAuthn + CORS if origin is configured
-	if config, err := authSvc.loadConfig(); err == nil && config.Auth.Origin != "" {
-		// Add configured origin to allowed CORS origins
-		s.allowedOrigins = append(s.allowedOrigins, config.Auth.Origin)
-
-		parsed, err := url.Parse(config.Auth.Origin)
-		if err == nil {
-			rpID := parsed.Hostname()
-			wa, err := webauthn.New(&webauthn.Config{
-				RPDisplayName: "Codeburg",
-				RPID:          rpID,
-				RPOrigins:     []string{config.Auth.Origin},
-				AuthenticatorSelection: protocol.AuthenticatorSelection{
-					ResidentKey:      protocol.ResidentKeyRequirementRequired,
-					UserVerification: protocol.VerificationPreferred,
-				},
-			})
-			if err != nil {
-				slog.Error("failed to initialize WebAuthn", "error", err)
-			} else {
-				s.webauthn = wa
-				slog.Info("webauthn initialized", "rpID", rpID, "origin", config.Auth.Origin)
-			}
-		}
+	// Allowed origins and WebAuthn come from the configuration file
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		slog.Error("failed to load configuration; using defaults until it is fixed and reloaded", "error", err)
+		config = &Config{}
 	}
+	s.applyConfig(config)
 
 	// Start Telegram bot if token preference is configured
 	s.startTelegramBot()
@@ -139,6 +200,84 @@ func NewServer(database *db.DB) *Server {
 		wsHub.Run(s.bgCtx)
 	}()
 
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.transcripts.run(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.activity.run(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.watchTunnels(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.semantic.run(s.bgCtx, s.refreshChangedProject)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.restoreWorktreePools()
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.sweepArtifacts(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.sweepRecordings(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runDigest(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runTranscriptRetention(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runUpdateCheck(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runWebhookDeliveries(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runJobs(s.bgCtx)
+	}()
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runDiffStatsRefresh(s.bgCtx)
+	}()
+
 	// Restore sessions that survived a server restart
 	s.sessions.Reconcile(s)
 
@@ -149,6 +288,12 @@ func NewServer(database *db.DB) *Server {
 		s.sessions.StartCleanupLoop(s.bgCtx, s)
 	}()
 
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		s.runIdleTimeouts(s.bgCtx)
+	}()
+
 	s.setupRoutes()
 	return s
 }
@@ -157,14 +302,16 @@ func (s *Server) setupRoutes() {
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(traceRequests)
+	r.Use(s.clientIPMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   s.allowedOrigins,
+		AllowOriginFunc:  func(r *http.Request, origin string) bool { return s.originAllowed(origin) },
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", nextCursorHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -173,6 +320,7 @@ func (s *Server) setupRoutes() {
 	r.Post("/api/auth/login", s.handleLogin)
 	r.Post("/api/auth/setup", s.handleSetup)
 	r.Get("/api/auth/status", s.handleAuthStatus)
+	r.Post("/api/auth/logout", s.handleLogout)
 
 	// Passkey public routes (rate-limited internally)
 	r.Post("/api/auth/passkey/login/begin", s.handlePasskeyLoginBegin)
@@ -180,6 +328,14 @@ func (s *Server) setupRoutes() {
 
 	// Telegram public route (rate-limited internally)
 	r.Post("/api/auth/telegram", s.handleTelegramAuth)
+	// Telegram webhook updates (auth via the webhook's secret token)
+	r.Post("/api/telegram/webhook", s.handleTelegramWebhook)
+	// GitHub webhook events (auth via the webhook's signature)
+	r.Post("/api/github/webhook", s.handleGitHubWebhook)
+
+	// One-time login links (rate-limited internally) and their QR codes
+	r.Post("/api/auth/login-link", s.handleLoginLink)
+	r.Get("/api/deep-links/qr/{id}", s.handleDeepLinkQR)
 
 	// WebSocket (public route; JWT required via query/header token or auth message)
 	r.Get("/ws", s.handleWebSocket)
@@ -188,10 +344,17 @@ func (s *Server) setupRoutes() {
 
 	// Hook endpoint (auth handled inline — accepts scoped hook tokens or full JWTs)
 	r.Post("/api/sessions/{id}/hook", s.handleSessionHook)
+	r.Post("/api/sessions/{id}/artifacts", s.handlePublishSessionArtifact)
+
+	// Artifact downloads (auth via the signed link's token)
+	r.Get("/api/artifacts/{id}/download", s.handleDownloadArtifact)
 
 	// Protected routes
 	r.Group(func(r chi.Router) {
 		r.Use(s.authMiddleware)
+		r.Use(s.rateLimitMiddleware)
+		r.Use(s.archivedProjectGuard)
+		r.Use(s.semanticIndexWatcher)
 
 		// Auth
 		r.Get("/api/auth/me", s.handleMe)
@@ -204,21 +367,83 @@ func (s *Server) setupRoutes() {
 		r.Patch("/api/auth/passkeys/{id}", s.handleRenamePasskey)
 		r.Delete("/api/auth/passkeys/{id}", s.handleDeletePasskey)
 
+		// Personal access tokens (login JWT only)
+		r.Get("/api/auth/tokens", s.handleListAPITokens)
+		r.Post("/api/auth/tokens", s.handleCreateAPIToken)
+		r.Delete("/api/auth/tokens/{id}", s.handleRevokeAPIToken)
+
+		// Deep links to continue on another device (login JWT only)
+		r.Post("/api/deep-links", s.handleCreateDeepLink)
+
 		// Sidebar (aggregated)
 		r.Get("/api/sidebar", s.handleSidebar)
 
+		// Agent activity heatmaps
+		r.Get("/api/activity", s.handleGetActivity)
+		r.Get("/api/projects/{id}/activity", s.handleGetProjectActivity)
+
+		// Models each agent provider accepts
+		r.Get("/api/providers/models", s.handleListProviderModels)
+
+		// Build info and the latest release
+		r.Get("/api/system/version", s.handleGetVersion)
+
+		// Outbound webhooks (login JWT only)
+		r.Get("/api/webhooks", s.handleListWebhooks)
+		r.Post("/api/webhooks", s.handleCreateWebhook)
+		r.Patch("/api/webhooks/{id}", s.handleUpdateWebhook)
+		r.Delete("/api/webhooks/{id}", s.handleDeleteWebhook)
+		r.Get("/api/webhooks/{id}/deliveries", s.handleListWebhookDeliveries)
+		r.Post("/api/webhooks/{id}/deliveries/{deliveryId}/redeliver", s.handleRedeliverWebhook)
+		r.Post("/api/webhooks/{id}/test", s.handleTestWebhook)
+
+		// Background jobs
+		r.Get("/api/jobs", s.handleListJobs)
+		r.Get("/api/jobs/{id}", s.handleGetJob)
+		r.Post("/api/jobs/{id}/retry", s.handleRetryJob)
+		r.Post("/api/jobs/{id}/cancel", s.handleCancelJob)
+		r.Post("/api/transcripts/prune", s.handlePruneTranscripts)
+
+		// Configuration file (login JWT only)
+		r.Post("/api/config/reload", s.handleReloadConfig)
+
+		// Notification digest
+		r.Get("/api/digest/preview", s.handleDigestPreview)
+
+		// Usage budgets (overrides are login JWT only)
+		r.Get("/api/budgets", s.handleGetBudgets)
+		r.Post("/api/budgets/override", s.handleOverrideBudget)
+		r.Delete("/api/budgets/override", s.handleDeleteBudgetOverride)
+
+		// Time tracking
+		r.Get("/api/time/weekly", s.handleGetWeeklyTime)
+		r.Delete("/api/time/{id}", s.handleDeleteTimeEntry)
+		r.Get("/api/projects/{id}/time", s.handleGetProjectTime)
+		r.Get("/api/tasks/{id}/time", s.handleGetTaskTime)
+		r.Post("/api/tasks/{id}/time", s.handleCreateTimeEntry)
+
+		// Rendering
+		r.Post("/api/render/markdown", s.handleRenderMarkdown)
+
 		// Projects
 		r.Get("/api/projects", s.handleListProjects)
 		r.Post("/api/projects", s.handleCreateProject)
 		r.Get("/api/projects/{id}", s.handleGetProject)
 		r.Patch("/api/projects/{id}", s.handleUpdateProject)
 		r.Delete("/api/projects/{id}", s.handleDeleteProject)
+		r.Get("/api/projects/{id}/board", s.handleGetProjectBoard)
+		r.Get("/api/projects/{id}/board/export", s.handleExportBoard)
+		r.Post("/api/projects/{id}/import", s.handleImportIssues)
 		r.Post("/api/projects/{id}/sync-default-branch", s.handleSyncProjectDefaultBranch)
 		r.Post("/api/projects/{id}/push-default-branch", s.handlePushProjectDefaultBranch)
+		r.Get("/api/projects/{id}/clone", s.handleGetProjectClone)
+		r.Get("/api/projects/{id}/devcontainer", s.handleGetProjectDevcontainer)
+		r.Post("/api/projects/{id}/unshallow", s.handleUnshallowProject)
 		r.Get("/api/projects/{id}/files", s.handleListProjectFiles)
 		r.Post("/api/projects/{id}/files", s.handleCreateProjectFileEntry)
 		r.Get("/api/projects/{id}/file", s.handleReadProjectFile)
 		r.Put("/api/projects/{id}/file", s.handlePutProjectFile)
+		r.Get("/api/projects/{id}/file/raw", s.handleDownloadProjectFile)
 		r.Delete("/api/projects/{id}/file", s.handleDeleteProjectFile)
 		r.Post("/api/projects/{id}/file/rename", s.handleRenameProjectFile)
 		r.Post("/api/projects/{id}/file/duplicate", s.handleDuplicateProjectFile)
@@ -227,7 +452,12 @@ func (s *Server) setupRoutes() {
 		r.Get("/api/projects/{id}/secrets/content", s.handleGetProjectSecretContent)
 		r.Put("/api/projects/{id}/secrets/content", s.handlePutProjectSecretContent)
 		r.Post("/api/projects/{id}/secrets/resolve", s.handleResolveProjectSecrets)
+		r.Post("/api/projects/{id}/files/upload", s.handleUploadProjectFiles)
 		r.Post("/api/projects/{id}/files/search", s.handleSearchProjectFiles)
+		r.Post("/api/projects/{id}/files/replace", s.handleReplaceProjectFiles)
+		r.Post("/api/projects/{id}/semantic-search", s.handleSemanticSearch)
+		r.Post("/api/projects/{id}/semantic-index", s.handleRefreshSemanticIndex)
+		r.Delete("/api/projects/{id}/semantic-index", s.handleDeleteSemanticIndex)
 
 		// Project sessions
 		r.Get("/api/projects/{id}/sessions", s.handleListProjectSessions)
@@ -237,6 +467,7 @@ func (s *Server) setupRoutes() {
 		r.Get("/api/projects/{id}/git/status", s.handleProjectGitStatus)
 		r.Get("/api/projects/{id}/git/diff", s.handleProjectGitDiff)
 		r.Get("/api/projects/{id}/git/diff-content", s.handleProjectGitDiffContent)
+		r.Get("/api/projects/{id}/git/diff/structured", s.handleGitStructuredDiff(s.resolveProjectWorkDir, false))
 		r.Post("/api/projects/{id}/git/stage", s.handleProjectGitStage)
 		r.Post("/api/projects/{id}/git/unstage", s.handleProjectGitUnstage)
 		r.Post("/api/projects/{id}/git/revert", s.handleProjectGitRevert)
@@ -245,6 +476,12 @@ func (s *Server) setupRoutes() {
 		r.Post("/api/projects/{id}/git/push", s.handleProjectGitPush)
 		r.Post("/api/projects/{id}/git/stash", s.handleProjectGitStash)
 		r.Get("/api/projects/{id}/git/log", s.handleProjectGitLog)
+		r.Get("/api/projects/{id}/git/file-log", s.handleGitFileLog(s.resolveProjectWorkDir))
+		r.Get("/api/projects/{id}/git/blame", s.handleGitBlame(s.resolveProjectWorkDir))
+		r.Get("/api/projects/{id}/git/hunks", s.handleGitHunks(s.resolveProjectWorkDir))
+		r.Post("/api/projects/{id}/git/hunks/stage", s.handleGitHunkAction(s.resolveProjectWorkDir, hunkStage))
+		r.Post("/api/projects/{id}/git/hunks/unstage", s.handleGitHunkAction(s.resolveProjectWorkDir, hunkUnstage))
+		r.Post("/api/projects/{id}/git/hunks/revert", s.handleGitHunkAction(s.resolveProjectWorkDir, hunkRevert))
 
 		// Project tunnels
 		r.Get("/api/projects/{id}/tunnels", s.handleListProjectTunnels)
@@ -252,40 +489,105 @@ func (s *Server) setupRoutes() {
 
 		// Branches
 		r.Get("/api/projects/{id}/branches", s.handleListBranches)
+		r.Get("/api/projects/{id}/worktree-pool", s.handleGetWorktreePool)
+		r.Post("/api/projects/{id}/worktree-pool/refill", s.handleRefillWorktreePool)
+		r.Delete("/api/projects/{id}/worktree-pool", s.handleDrainWorktreePool)
 
 		// Tasks
 		r.Get("/api/tasks", s.handleListTasks)
 		r.Post("/api/projects/{projectId}/tasks", s.handleCreateTask)
 		r.Get("/api/tasks/{id}", s.handleGetTask)
 		r.Patch("/api/tasks/{id}", s.handleUpdateTask)
+		r.Post("/api/tasks/{id}/reorder", s.handleReorderTask)
 		r.Delete("/api/tasks/{id}", s.handleDeleteTask)
 		r.Post("/api/tasks/{id}/create-pr", s.handleCreatePR)
+		r.Post("/api/tasks/{id}/review-comments", s.handleTaskReviewComments)
+		r.Post("/api/tasks/{id}/snapshot", s.handleCreateWorktreeSnapshot)
+		r.Get("/api/tasks/{id}/snapshots", s.handleListWorktreeSnapshots)
+		r.Delete("/api/tasks/{id}/snapshots/{snapshotId}", s.handleDeleteWorktreeSnapshot)
+		r.Post("/api/tasks/{id}/rollback", s.handleRollbackWorktree)
+		r.Post("/api/tasks/{id}/undo-status", s.handleUndoTaskStatus)
+
+		// Saved board views
+		r.Get("/api/views", s.handleListSavedViews)
+		r.Post("/api/views", s.handleCreateSavedView)
+		r.Get("/api/views/{id}", s.handleGetSavedView)
+		r.Patch("/api/views/{id}", s.handleUpdateSavedView)
+		r.Delete("/api/views/{id}", s.handleDeleteSavedView)
 
 		// Worktrees
 		r.Post("/api/tasks/{id}/worktree", s.handleCreateWorktree)
 		r.Delete("/api/tasks/{id}/worktree", s.handleDeleteWorktree)
+		r.Get("/api/tasks/{id}/provision", s.handleGetTaskProvision)
+		r.Post("/api/tasks/{id}/provision", s.handleProvisionTask)
+		r.Delete("/api/tasks/{id}/provision", s.handleTeardownTaskProvision)
+		r.Get("/api/tasks/{id}/devcontainer", s.handleGetTaskDevcontainer)
+		r.Post("/api/tasks/{id}/devcontainer", s.handleStartTaskDevcontainer)
+		r.Delete("/api/tasks/{id}/devcontainer", s.handleRemoveTaskDevcontainer)
+		r.Get("/api/tasks/{id}/env", s.handleGetTaskEnv)
+		r.Put("/api/tasks/{id}/env", s.handleSetTaskEnv)
 
 		// Task files
 		r.Get("/api/tasks/{id}/files", s.handleListTaskFiles)
 		r.Post("/api/tasks/{id}/files", s.handleCreateTaskFileEntry)
 		r.Get("/api/tasks/{id}/file", s.handleReadTaskFile)
 		r.Put("/api/tasks/{id}/file", s.handlePutTaskFile)
+		r.Get("/api/tasks/{id}/file/raw", s.handleDownloadTaskFile)
 		r.Delete("/api/tasks/{id}/file", s.handleDeleteTaskFile)
 		r.Post("/api/tasks/{id}/file/rename", s.handleRenameTaskFile)
 		r.Post("/api/tasks/{id}/file/duplicate", s.handleDuplicateTaskFile)
+		r.Post("/api/tasks/{id}/files/upload", s.handleUploadTaskFiles)
 		r.Post("/api/tasks/{id}/files/search", s.handleSearchTaskFiles)
+		r.Post("/api/tasks/{id}/files/replace", s.handleReplaceTaskFiles)
 
 		// Sessions
 		r.Get("/api/tasks/{taskId}/sessions", s.handleListSessions)
 		r.Post("/api/tasks/{taskId}/sessions", s.handleStartSession)
+		r.Post("/api/tasks/{taskId}/sessions/compare", s.handleStartComparison)
+		r.Post("/api/tasks/{taskId}/sessions/from-template", s.handleStartSessionFromTemplate)
+		r.Get("/api/tasks/{taskId}/comparisons", s.handleListComparisons)
+		r.Get("/api/comparisons/{id}", s.handleGetComparison)
+		r.Get("/api/comparisons/{id}/diff", s.handleComparisonDiff)
+		r.Post("/api/comparisons/{id}/pick", s.handlePickComparison)
+		r.Post("/api/comparisons/{id}/discard", s.handleDiscardComparison)
+		r.Get("/api/sessions", s.handleSearchSessions)
 		r.Get("/api/sessions/{id}", s.handleGetSession)
+		r.Patch("/api/sessions/{id}", s.handleUpdateSession)
+		r.Get("/api/sessions/{id}/messages", s.handleListSessionMessages)
+		r.Get("/api/sessions/{id}/subagents", s.handleListSessionSubagents)
+		r.Get("/api/sessions/{id}/recording", s.handleGetSessionRecording)
 		r.Post("/api/sessions/{id}/message", s.handleSendMessage)
+		r.Post("/api/sessions/{id}/permissions/{requestId}", s.handleRespondPermission)
 		r.Post("/api/sessions/{id}/stop", s.handleStopSession)
+		r.Post("/api/sessions/{id}/resize", s.handleResizeSession)
 		r.Delete("/api/sessions/{id}", s.handleDeleteSession)
 
+		// Session bookmarks
+		r.Get("/api/sessions/{id}/bookmarks", s.handleListBookmarks)
+		r.Post("/api/sessions/{id}/bookmarks", s.handleCreateBookmark)
+		r.Patch("/api/bookmarks/{id}", s.handleRenameBookmark)
+		r.Delete("/api/bookmarks/{id}", s.handleDeleteBookmark)
+
 		// Recipes / Justfile
 		r.Get("/api/tasks/{id}/recipes", s.handleListTaskRecipes)
 		r.Get("/api/projects/{id}/recipes", s.handleListProjectRecipes)
+		r.Get("/api/projects/{id}/recipe-favorites", s.handleListRecipeFavorites)
+		r.Post("/api/projects/{id}/recipe-favorites", s.handleCreateRecipeFavorite)
+		r.Delete("/api/projects/{id}/recipe-favorites/{favoriteId}", s.handleDeleteRecipeFavorite)
+
+		// Pipelines
+		r.Get("/api/projects/{id}/pipelines", s.handleListPipelines)
+		r.Post("/api/projects/{id}/pipelines", s.handleCreatePipeline)
+		r.Patch("/api/pipelines/{id}", s.handleUpdatePipeline)
+		r.Delete("/api/pipelines/{id}", s.handleDeletePipeline)
+		r.Get("/api/projects/{id}/session-templates", s.handleListSessionTemplates)
+		r.Post("/api/projects/{id}/session-templates", s.handleCreateSessionTemplate)
+		r.Patch("/api/session-templates/{id}", s.handleUpdateSessionTemplate)
+		r.Delete("/api/session-templates/{id}", s.handleDeleteSessionTemplate)
+		r.Post("/api/tasks/{id}/pipelines/{pipelineId}/run", s.handleRunPipeline)
+		r.Get("/api/tasks/{id}/pipeline-runs", s.handleListPipelineRuns)
+		r.Get("/api/pipeline-runs/{id}", s.handleGetPipelineRun)
+		r.Post("/api/pipeline-runs/{id}/cancel", s.handleCancelPipelineRun)
 		r.Get("/api/projects/{id}/justfile", s.handleListJustRecipes)
 		r.Post("/api/projects/{id}/just/{recipe}", s.handleRunJustRecipe)
 		r.Get("/api/tasks/{id}/justfile", s.handleListTaskJustRecipes)
@@ -296,14 +598,24 @@ func (s *Server) setupRoutes() {
 		r.Get("/api/tasks/{id}/git/status", s.handleGitStatus)
 		r.Get("/api/tasks/{id}/git/diff", s.handleGitDiff)
 		r.Get("/api/tasks/{id}/git/diff-content", s.handleGitDiffContent)
+		r.Get("/api/tasks/{id}/git/diff/structured", s.handleGitStructuredDiff(s.resolveTaskWorkDir, true))
 		r.Post("/api/tasks/{id}/git/stage", s.handleGitStage)
 		r.Post("/api/tasks/{id}/git/unstage", s.handleGitUnstage)
 		r.Post("/api/tasks/{id}/git/revert", s.handleGitRevert)
 		r.Post("/api/tasks/{id}/git/commit", s.handleGitCommit)
 		r.Post("/api/tasks/{id}/git/pull", s.handleGitPull)
 		r.Post("/api/tasks/{id}/git/push", s.handleGitPush)
+		r.Get("/api/tasks/{id}/ci-status", s.handleTaskCIStatus)
 		r.Post("/api/tasks/{id}/git/stash", s.handleGitStash)
+		r.Post("/api/tasks/{id}/git/branch/rename", s.handleGitRenameBranch)
+		r.Post("/api/tasks/{id}/git/retarget", s.handleGitRetarget)
 		r.Get("/api/tasks/{id}/git/log", s.handleGitLog)
+		r.Get("/api/tasks/{id}/git/file-log", s.handleGitFileLog(s.resolveTaskWorkDir))
+		r.Get("/api/tasks/{id}/git/blame", s.handleGitBlame(s.resolveTaskWorkDir))
+		r.Get("/api/tasks/{id}/git/hunks", s.handleGitHunks(s.resolveTaskWorkDir))
+		r.Post("/api/tasks/{id}/git/hunks/stage", s.handleGitHunkAction(s.resolveTaskWorkDir, hunkStage))
+		r.Post("/api/tasks/{id}/git/hunks/unstage", s.handleGitHunkAction(s.resolveTaskWorkDir, hunkUnstage))
+		r.Post("/api/tasks/{id}/git/hunks/revert", s.handleGitHunkAction(s.resolveTaskWorkDir, hunkRevert))
 
 		// Labels
 		r.Get("/api/projects/{id}/labels", s.handleListLabels)
@@ -312,19 +624,47 @@ func (s *Server) setupRoutes() {
 		r.Post("/api/tasks/{id}/labels", s.handleAssignLabel)
 		r.Delete("/api/tasks/{id}/labels/{labelId}", s.handleUnassignLabel)
 
+		// Attachments
+		r.Get("/api/tasks/{id}/attachments", s.handleListAttachments)
+		r.Post("/api/tasks/{id}/attachments", s.handleUploadAttachment)
+		r.Get("/api/attachments/{id}", s.handleDownloadAttachment)
+		r.Delete("/api/attachments/{id}", s.handleDeleteAttachment)
+
+		// Artifacts
+		r.Get("/api/tasks/{id}/artifacts", s.handleListArtifacts)
+		r.Post("/api/tasks/{id}/artifacts", s.handlePublishArtifact)
+		r.Delete("/api/artifacts/{id}", s.handleDeleteArtifact)
+
+		// Snippet inbox
+		r.Get("/api/tasks/{id}/snippets", s.handleListSnippets)
+		r.Post("/api/tasks/{id}/snippets", s.handleCreateSnippet)
+		r.Delete("/api/snippets/{id}", s.handleDeleteSnippet)
+
 		// Tunnels
 		r.Get("/api/tasks/{id}/tunnels", s.handleListTunnels)
 		r.Post("/api/tasks/{id}/tunnels", s.handleCreateTunnel)
 		r.Get("/api/tasks/{id}/port-suggestions", s.handleListTaskPortSuggestions)
 		r.Post("/api/tasks/{id}/ports/scan", s.handleScanTaskPorts)
+		r.Get("/api/tasks/{id}/port-assignments", s.handleListTaskPortAssignments)
+		r.Put("/api/tasks/{id}/port-assignments/{port}", s.handleAssignTaskPort)
+		r.Delete("/api/tasks/{id}/port-assignments/{port}", s.handleUnassignTaskPort)
+		r.Get("/api/tunnels/providers", s.handleListTunnelProviders)
 		r.Delete("/api/tunnels/{id}", s.handleStopTunnel)
 
 		// Archives
 		r.Post("/api/projects/{id}/archive", s.handleArchiveProject)
+		r.Post("/api/projects/{id}/unarchive", s.handleUnarchiveProject)
+		r.Post("/api/projects/{id}/export", s.handleExportProject)
 		r.Get("/api/archives", s.handleListArchives)
-		r.Post("/api/archives/{filename}/unarchive", s.handleUnarchiveProject)
+		r.Post("/api/archives/{filename}/unarchive", s.handleRestoreArchive)
 		r.Delete("/api/archives/{filename}", s.handleDeleteArchive)
 
+		// Notifications
+		r.Get("/api/notifications/webpush/key", s.handleGetWebPushKey)
+		r.Post("/api/notifications/webpush/subscriptions", s.handleAddWebPushSubscription)
+		r.Delete("/api/notifications/webpush/subscriptions", s.handleDeleteWebPushSubscription)
+		r.Post("/api/notifications/test", s.handleTestNotification)
+
 		// Telegram bot management
 		r.Post("/api/telegram/bot/restart", s.handleRestartTelegramBot)
 
@@ -332,6 +672,12 @@ func (s *Server) setupRoutes() {
 		r.Get("/api/preferences/{key}", s.handleGetPreference)
 		r.Put("/api/preferences/{key}", s.handleSetPreference)
 		r.Delete("/api/preferences/{key}", s.handleDeletePreference)
+		r.Get("/api/llm/features", s.handleListLLMFeatures)
+
+		// Settings export/import and first-run setup
+		r.Get("/api/settings/export", s.handleExportSettings)
+		r.Post("/api/settings/import", s.handleImportSettings)
+		r.Get("/api/setup/status", s.handleSetupStatus)
 	})
 
 	// Serve frontend static files (SPA with index.html fallback)
@@ -410,6 +756,12 @@ func writeAPIAwareNotFound(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+// Handler returns the server's HTTP API, for serving it from another
+// http.Server.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
 func (s *Server) ListenAndServe(addr string) error {
 	httpServer := &http.Server{
 		Addr:              addr,
@@ -448,6 +800,7 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.telegramBotCancel != nil {
 		s.telegramBotCancel()
 		s.telegramBotCancel = nil
+		s.telegramBot = nil
 	}
 	s.telegramBotMu.Unlock()
 
@@ -478,6 +831,7 @@ func (s *Server) startTelegramBot() {
 	if s.telegramBotCancel != nil {
 		s.telegramBotCancel()
 		s.telegramBotCancel = nil
+		s.telegramBot = nil
 	}
 
 	// Read bot token from preferences
@@ -502,6 +856,14 @@ func (s *Server) startTelegramBot() {
 	s.telegramBotCancel = cancel
 
 	bot := telegram.NewBot(token, config.Auth.Origin)
+	bot.SetCommandHandler(s.handleTelegramCommand)
+	bot.SetReactionHandler(s.handleTelegramReaction)
+	bot.SetFileHandler(s.handleTelegramFile)
+	bot.SetCallbackHandler(s.handleTelegramCallback)
+	if webhookURL, secret, ok := s.telegramWebhook(); ok {
+		bot.SetWebhook(webhookURL, secret)
+	}
+	s.telegramBot = bot
 	go bot.Run(ctx)
 }
 
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// The services hold the business logic behind the task, git and session
+// handlers, which only decode requests and write responses. Each service's
+// methods live next to its handlers.
+type (
+	taskService    struct{ s *Server }
+	gitService     struct{ s *Server }
+	sessionService struct{ s *Server }
+)
+
+var (
+	_ service.TaskService    = (*taskService)(nil)
+	_ service.GitService     = (*gitService)(nil)
+	_ service.SessionService = (*sessionService)(nil)
+)
+
+// Services returns the server's services, for callers that don't go
+// through HTTP.
+func (s *Server) Services() service.Services {
+	return service.Services{Tasks: s.tasks(), Git: s.git(), Sessions: s.sessionService()}
+}
+
+func (s *Server) tasks() *taskService             { return &taskService{s: s} }
+func (s *Server) git() *gitService                { return &gitService{s: s} }
+func (s *Server) sessionService() *sessionService { return &sessionService{s: s} }
+
+// notFound names what wasn't found when err is db.ErrNotFound, and adds
+// context to other errors.
+func notFound(err error, what string) error {
+	if errors.Is(err, db.ErrNotFound) {
+		return service.NotFound(what)
+	}
+	return fmt.Errorf("get %s: %w", what, err)
+}
+
+// writeServiceError writes an error returned by a service: the status its
+// kind calls for with its message, or 500 with msg for unexpected errors.
+func writeServiceError(w http.ResponseWriter, err error, msg string) {
+	var svcErr *service.Error
+	var limited *rateLimitedError
+	var budgetErr *budgetExceededError
+	switch {
+	case errors.As(err, &svcErr):
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(svcErr.Kind, service.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(svcErr.Kind, service.ErrInvalid):
+			status = http.StatusBadRequest
+		case errors.Is(svcErr.Kind, service.ErrConflict):
+			status = http.StatusConflict
+		case errors.Is(svcErr.Kind, service.ErrForbidden):
+			status = http.StatusForbidden
+		}
+		writeError(w, status, svcErr.Message)
+	case errors.Is(err, db.ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &limited):
+		writeRateLimited(w, limited)
+	case errors.As(err, &budgetErr):
+		writeError(w, http.StatusForbidden, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, msg)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+func TestServices(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	ctx := t.Context()
+	svc := env.server.Services()
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": createTestGitRepo(t)}), &project)
+
+	if _, err := svc.Tasks.Create(ctx, db.CreateTaskInput{ProjectID: project.ID}); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("expected an invalid argument without a title, got %v", err)
+	}
+	if _, err := svc.Tasks.Create(ctx, db.CreateTaskInput{ProjectID: "missing", Title: "x"}); !errors.Is(err, service.ErrNotFound) || err.Error() != "project not found" {
+		t.Errorf("expected project not found, got %v", err)
+	}
+	task, err := svc.Tasks.Create(ctx, db.CreateTaskInput{ProjectID: project.ID, Title: "Fix login"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	status := db.TaskStatus("nowhere")
+	if _, err := svc.Tasks.Update(ctx, task.ID, db.UpdateTaskInput{Status: &status}); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("expected an invalid status, got %v", err)
+	}
+	title := "Fix the login"
+	updated, err := svc.Tasks.Update(ctx, task.ID, db.UpdateTaskInput{Title: &title})
+	if err != nil || updated.Title != title {
+		t.Fatalf("update: %+v, %v", updated, err)
+	}
+	tasks, _, err := svc.Tasks.List(ctx, db.TaskFilter{ProjectID: &project.ID}, db.Page{})
+	if err != nil || len(tasks) != 1 || tasks[0].Title != title {
+		t.Errorf("list: %+v, %v", tasks, err)
+	}
+
+	if _, err := svc.Git.Status(ctx, task.ID); !errors.Is(err, service.ErrInvalid) || err.Error() != "task has no worktree" {
+		t.Errorf("expected an error without a worktree, got %v", err)
+	}
+	if err := svc.Sessions.Send(ctx, "missing", "hi"); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("expected session not found, got %v", err)
+	}
+	if _, err := svc.Sessions.StartInTask(ctx, task.ID, service.StartSessionOptions{Provider: "cobol"}); !errors.Is(err, service.ErrInvalid) {
+		t.Errorf("expected an invalid provider, got %v", err)
+	}
+
+	if err := svc.Tasks.Delete(ctx, task.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.Tasks.Get(ctx, task.ID); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("expected the task to be gone, got %v", err)
+	}
+}
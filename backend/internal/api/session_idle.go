@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+	"github.com/miguel-bm/codeburg/internal/sessionlifecycle"
+)
+
+// Session idle timeout preference:
+//
+//	session_idle_timeout  {"providers": {"claude": 120, "codex": 120, "terminal": 480}, "default": 0, "warningMinutes": 10}
+//
+// Terminal sessions idle for longer than their provider's timeout, in
+// minutes, are stopped through the session lifecycle, after an attention
+// notification warningMinutes before. Agent sessions are idle while they
+// wait for input; terminal shells, which never do, while they produce no
+// output. Providers not listed use default, and 0 never stops them.
+// Sessions marked idleExempt are never stopped.
+const sessionIdleTimeoutPreference = "session_idle_timeout"
+
+const (
+	defaultIdleWarningMinutes = 10
+	idleCheckInterval         = time.Minute
+)
+
+type idleTimeoutSettings struct {
+	Providers      map[string]int `json:"providers"`
+	Default        int            `json:"default"`
+	WarningMinutes *int           `json:"warningMinutes,omitempty"`
+}
+
+// timeout is how long a provider's sessions may stay idle, or 0 for ever.
+func (c idleTimeoutSettings) timeout(provider string) time.Duration {
+	minutes, ok := c.Providers[provider]
+	if !ok {
+		minutes = c.Default
+	}
+	return time.Duration(max(minutes, 0)) * time.Minute
+}
+
+// warning is how long before a session is stopped the user is warned.
+func (c idleTimeoutSettings) warning() time.Duration {
+	minutes := defaultIdleWarningMinutes
+	if c.WarningMinutes != nil {
+		minutes = max(*c.WarningMinutes, 0)
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// idleTimeoutSettings reads the session_idle_timeout preference. Without
+// it no session times out.
+func (s *Server) idleTimeoutSettings() idleTimeoutSettings {
+	var settings idleTimeoutSettings
+	pref, err := s.db.GetPreference(db.DefaultUserID, sessionIdleTimeoutPreference)
+	if err != nil {
+		return settings
+	}
+	if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+		slog.Warn("invalid session_idle_timeout preference", "error", err)
+		return idleTimeoutSettings{}
+	}
+	return settings
+}
+
+// idleWarning is a warning sent about a session, and the idle period it
+// was about. Activity starts a new period, which needs its own warning.
+type idleWarning struct {
+	idleSince time.Time
+	sentAt    time.Time
+}
+
+// runIdleTimeouts warns about and stops idle sessions until ctx is done.
+func (s *Server) runIdleTimeouts(ctx context.Context) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	warned := make(map[string]idleWarning)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkIdleSessions(time.Now(), warned)
+		}
+	}
+}
+
+// checkIdleSessions runs one idle timeout pass. warned holds the warnings
+// sent, per session ID, and is carried between passes. A session is only
+// stopped once it was warned the full warning period before, so one found
+// past its timeout after a restart is warned first.
+func (s *Server) checkIdleSessions(now time.Time, warned map[string]idleWarning) {
+	settings := s.idleTimeoutSettings()
+	sessions, err := s.db.ListActiveSessions()
+	if err != nil {
+		slog.Warn("failed to list sessions for idle timeout", "error", err)
+		return
+	}
+
+	idle := make(map[string]bool)
+	for _, session := range sessions {
+		timeout := settings.timeout(session.Provider)
+		if session.SessionType == "chat" || session.IdleExempt || timeout <= 0 {
+			continue
+		}
+		idleSince, ok := s.sessionIdleSince(session)
+		if !ok {
+			continue
+		}
+		idle[session.ID] = true
+
+		w, ok := warned[session.ID]
+		if ok && !w.idleSince.Equal(idleSince) {
+			delete(warned, session.ID)
+			ok = false
+		}
+		idleFor := now.Sub(idleSince)
+		warning := min(settings.warning(), timeout)
+		switch {
+		case idleFor >= timeout && (warning == 0 || ok && now.Sub(w.sentAt) >= warning):
+			delete(warned, session.ID)
+			slog.Info("stopping idle session", "session_id", session.ID, "provider", session.Provider, "idle_for", idleFor.Round(time.Second))
+			s.stopSessionFor(session, sessionlifecycle.EventIdleTimeout, "idle_timeout")
+		case idleFor >= timeout-warning && warning > 0 && !ok:
+			warned[session.ID] = idleWarning{idleSince: idleSince, sentAt: now}
+			s.warnSessionIdle(session, idleFor, warning)
+		}
+	}
+
+	for id := range warned {
+		if !idle[id] {
+			delete(warned, id)
+		}
+	}
+}
+
+// sessionIdleSince returns when a session last showed activity, or false
+// when it is busy: an agent working on a turn, or a session that ended.
+func (s *Server) sessionIdleSince(session *db.AgentSession) (time.Time, bool) {
+	switch session.Status {
+	case db.SessionStatusIdle, db.SessionStatusWaitingInput:
+	case db.SessionStatusRunning:
+		if session.Provider != "terminal" {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	since := session.UpdatedAt
+	if session.LastActivityAt != nil && session.LastActivityAt.After(since) {
+		since = *session.LastActivityAt
+	}
+	if output, ok := s.sessions.runtime.LastOutput(session.ID); ok && output.After(since) {
+		since = output
+	}
+	return since, true
+}
+
+// warnSessionIdle tells clients and the user, on the channels that want
+// attention messages, that a session is stopped in stopIn unless there is
+// activity.
+func (s *Server) warnSessionIdle(session *db.AgentSession, idleFor, stopIn time.Duration) {
+	slog.Info("session idle", "session_id", session.ID, "idle_for", idleFor.Round(time.Second), "stop_in", stopIn)
+	payload := map[string]any{
+		"sessionId": session.ID,
+		"stopsAt":   time.Now().Add(stopIn).UTC(),
+	}
+	s.wsHub.BroadcastToSession(session.ID, "session_idle_warning", payload)
+	if session.TaskID != "" {
+		s.wsHub.BroadcastToTask(session.TaskID, "session_idle_warning", payload)
+	}
+
+	sinks := s.notificationSinks(eventAttention)
+	if len(sinks) == 0 {
+		return
+	}
+	name := sessionLabel(session)
+	msg := notify.Message{SessionID: session.ID}
+	if session.TaskID != "" {
+		if task, err := s.db.GetTask(session.TaskID); err == nil {
+			name = task.Title + " · " + name
+		}
+		msg.URL = s.deepLink(sessionPath(session.TaskID, session.ID))
+	}
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		localized := msg
+		localized.Title = localize(lang, msgSessionIdleTitle, name)
+		localized.Body = localize(lang, msgSessionIdleBody, formatTunnelAge(idleFor), formatTunnelAge(stopIn))
+		return localized
+	})
+}
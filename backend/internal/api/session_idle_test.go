@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	received := make(chan map[string]any, 4)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+	env.server.db.SetPreference(db.DefaultUserID, sessionIdleTimeoutPreference, `{"providers": {"claude": 60}, "warningMinutes": 10}`)
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": createTestGitRepo(t)}), &project)
+	waiting := db.SessionStatusWaitingInput
+	newSession := func(provider string) *db.AgentSession {
+		session, err := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: provider, SessionType: "terminal"})
+		if err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		session, _ = env.server.db.UpdateSession(session.ID, db.UpdateSessionInput{Status: &waiting})
+		return session
+	}
+	idle := newSession("claude")
+	untimed := newSession("codex")
+	exempt := newSession("claude")
+
+	var updated db.AgentSession
+	decodeResponse(t, env.patch("/api/sessions/"+exempt.ID, map[string]any{"idleExempt": true}), &updated)
+	if !updated.IdleExempt || updated.Title != "" {
+		t.Fatalf("expected the session exempted and its title untouched, got %+v", updated)
+	}
+	if resp := env.patch("/api/sessions/"+exempt.ID, map[string]any{}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty update, got %d", resp.Code)
+	}
+
+	start := time.Now()
+	warned := make(map[string]idleWarning)
+	status := func(id string) db.SessionStatus {
+		session, _ := env.server.db.GetSession(id)
+		return session.Status
+	}
+
+	env.server.checkIdleSessions(start.Add(45*time.Minute), warned)
+	if len(warned) != 0 {
+		t.Fatalf("expected no warning before the warning period, got %v", warned)
+	}
+
+	env.server.checkIdleSessions(start.Add(51*time.Minute), warned)
+	select {
+	case body := <-received:
+		if body["title"] != "claude "+shortID(idle.ID)+" will be stopped for inactivity" {
+			t.Errorf("unexpected ntfy payload: %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an idle warning")
+	}
+	if _, ok := warned[idle.ID]; !ok || len(warned) != 1 {
+		t.Fatalf("expected only the claude session warned, got %v", warned)
+	}
+
+	// Past the timeout, but warned less than the warning period ago.
+	env.server.checkIdleSessions(start.Add(60*time.Minute), warned)
+	if got := status(idle.ID); got != db.SessionStatusWaitingInput {
+		t.Fatalf("expected the session kept until the warning period ends, got %s", got)
+	}
+
+	env.server.checkIdleSessions(start.Add(62*time.Minute), warned)
+	if got := status(idle.ID); got != db.SessionStatusCompleted {
+		t.Errorf("expected the idle session stopped, got %s", got)
+	}
+	if got := status(untimed.ID); got != db.SessionStatusWaitingInput {
+		t.Errorf("expected a provider without a timeout kept, got %s", got)
+	}
+	if got := status(exempt.ID); got != db.SessionStatusWaitingInput {
+		t.Errorf("expected the exempt session kept, got %s", got)
+	}
+	if len(warned) != 0 {
+		t.Errorf("expected the warning forgotten, got %v", warned)
+	}
+	select {
+	case body := <-received:
+		t.Errorf("expected a single notification, got %v", body)
+	default:
+	}
+}
+
+func TestIdleTimeoutActivityResetsWarning(t *testing.T) {
+	env := setupTestEnv(t)
+	env.server.db.SetPreference(db.DefaultUserID, sessionIdleTimeoutPreference, `{"default": 30, "warningMinutes": 5}`)
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "p", Path: t.TempDir()})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "terminal", SessionType: "terminal"})
+	running := db.SessionStatusRunning
+	env.server.db.UpdateSession(session.ID, db.UpdateSessionInput{Status: &running})
+
+	start := time.Now()
+	warned := make(map[string]idleWarning)
+	env.server.checkIdleSessions(start.Add(26*time.Minute), warned)
+	if _, ok := warned[session.ID]; !ok {
+		t.Fatalf("expected a quiet terminal shell warned, got %v", warned)
+	}
+
+	// Activity after the warning starts a new idle period.
+	later := start.Add(27 * time.Minute)
+	env.server.db.UpdateSession(session.ID, db.UpdateSessionInput{LastActivityAt: &later})
+	env.server.checkIdleSessions(start.Add(35*time.Minute), warned)
+	if got, _ := env.server.db.GetSession(session.ID); got.Status != db.SessionStatusRunning {
+		t.Errorf("expected the session kept after activity, got %s", got.Status)
+	}
+	if len(warned) != 0 {
+		t.Errorf("expected the old warning dropped, got %v", warned)
+	}
+}
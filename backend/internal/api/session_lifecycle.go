@@ -47,6 +47,7 @@ func (s *Server) applySessionTransition(sessionID string, current db.SessionStat
 		"to_status", tr.To,
 		"changed", true,
 	)
+	s.trackSessionTime(sessionID, tr.From, tr.To, source)
 
 	return tr.To, true, nil
 }
@@ -85,6 +86,16 @@ func (s *Server) applySessionTransitionWithFallback(sessionID string, fallbackCu
 }
 
 func (s *Server) broadcastSessionStatus(taskID, sessionID string, status db.SessionStatus) {
+	s.publishSessionStatus(taskID, sessionID, status)
+	if status == db.SessionStatusWaitingInput {
+		go s.notifySessionNeedsAttention(taskID, sessionID)
+	}
+}
+
+// publishSessionStatus tells clients and webhooks about a session's status,
+// like broadcastSessionStatus without the notification of a session
+// waiting for input.
+func (s *Server) publishSessionStatus(taskID, sessionID string, status db.SessionStatus) {
 	s.wsHub.BroadcastToSession(sessionID, "status_changed", map[string]string{
 		"status": string(status),
 	})
@@ -94,11 +105,16 @@ func (s *Server) broadcastSessionStatus(taskID, sessionID string, status db.Sess
 			"status":    string(status),
 		})
 	}
-	s.wsHub.BroadcastGlobal("sidebar_update", map[string]string{
+	projectID := ""
+	if session, err := s.db.GetSession(sessionID); err == nil {
+		projectID = session.ProjectID
+	}
+	s.wsHub.BroadcastToProject(projectID, "sidebar_update", map[string]string{
 		"taskId":    taskID,
 		"sessionId": sessionID,
 		"status":    string(status),
 	})
+	s.emitSessionStatus(sessionID, status)
 }
 
 func logInvalidSessionTransition(sessionID string, current db.SessionStatus, event sessionlifecycle.Event, source string, err error) {
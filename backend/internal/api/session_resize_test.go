@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestResizeSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	resp := env.post("/api/projects/"+project.ID+"/sessions", map[string]any{
+		"provider": "terminal", "cols": 100, "rows": 30,
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("start session: %d %s", resp.Code, resp.Body.String())
+	}
+	var session db.AgentSession
+	decodeResponse(t, resp, &session)
+	t.Cleanup(func() { env.server.sessions.runtime.Stop(session.ID) })
+
+	path := "/api/sessions/" + session.ID + "/resize"
+	if resp := env.post(path, map[string]int{"cols": 0, "rows": 40}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without cols, got %d", resp.Code)
+	}
+	if resp := env.post(path, map[string]int{"cols": 132, "rows": 43}); resp.Code != http.StatusNoContent {
+		t.Fatalf("resize: %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := env.post("/api/sessions/missing/resize", map[string]int{"cols": 80, "rows": 24}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown session, got %d", resp.Code)
+	}
+
+	// The recording starts at the requested size, then has the resize.
+	var header struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	}
+	var events []string
+	waitForCondition(t, 5*time.Second, func() bool {
+		f, err := os.Open(sessionRecordingPath(session.ID))
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		events = nil
+		scanner := bufio.NewScanner(f)
+		if scanner.Scan() {
+			json.Unmarshal(scanner.Bytes(), &header)
+		}
+		for scanner.Scan() {
+			events = append(events, scanner.Text())
+		}
+		return len(events) > 0
+	}, "recording")
+	if header.Width != 100 || header.Height != 30 {
+		t.Errorf("expected a 100x30 terminal, got %dx%d", header.Width, header.Height)
+	}
+	if !strings.Contains(strings.Join(events, "\n"), `"r","132x43"`) {
+		t.Errorf("expected the resize recorded, got %q", events)
+	}
+
+	env.server.sessions.runtime.Stop(session.ID)
+	waitForCondition(t, 5*time.Second, func() bool {
+		return env.post(path, map[string]int{"cols": 80, "rows": 24}).Code == http.StatusConflict
+	}, "409 once stopped")
+
+	chat, err := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := env.post("/api/sessions/"+chat.ID+"/resize", map[string]int{"cols": 80, "rows": 24}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a chat session, got %d", resp.Code)
+	}
+}
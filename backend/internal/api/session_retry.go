@@ -0,0 +1,226 @@
+package api
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/ptyruntime"
+)
+
+const (
+	defaultRetryBackoffSeconds = 10
+	maxRetryBackoff            = 10 * time.Minute
+	maxRetryErrorBytes         = 2000
+	maxSessionRetries          = 10
+
+	defaultRetryPromptTemplate = "The previous attempt failed with:\n\n{error}\n\nPlease try again. The original request was:\n\n{prompt}"
+)
+
+// retryBackoffUnit scales RetryPolicy.BackoffSeconds; tests shrink it.
+var retryBackoffUnit = time.Second
+
+var terminalEscapeRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()][0-9A-Za-z]|\x1b[=>78]`)
+
+// chatTurnRetry tracks the user's original prompt across retried turns.
+type chatTurnRetry struct {
+	Prompt  string
+	Attempt int // retries made so far
+}
+
+// retryDelay returns how long to wait before the given retry (1-based),
+// doubling the base backoff after each attempt.
+func retryDelay(policy *db.RetryPolicy, attempt int) time.Duration {
+	seconds := policy.BackoffSeconds
+	if seconds <= 0 {
+		seconds = defaultRetryBackoffSeconds
+	}
+	delay := time.Duration(seconds) * retryBackoffUnit
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+func buildRetryPrompt(policy *db.RetryPolicy, errText, prompt string) string {
+	template := policy.PromptTemplate
+	if strings.TrimSpace(template) == "" {
+		template = defaultRetryPromptTemplate
+	}
+	if strings.TrimSpace(prompt) == "" {
+		prompt = "(none; continue the previous task)"
+	}
+	return strings.NewReplacer("{error}", errText, "{prompt}", prompt).Replace(template)
+}
+
+// retryErrorText cleans terminal escapes from error output and keeps its tail,
+// which is where the error usually is.
+func retryErrorText(text string) string {
+	text = strings.TrimSpace(terminalEscapeRe.ReplaceAllString(text, ""))
+	if len(text) > maxRetryErrorBytes {
+		text = "…" + strings.ToValidUTF8(text[len(text)-maxRetryErrorBytes:], "")
+	}
+	if text == "" {
+		text = "unknown error"
+	}
+	return text
+}
+
+// sessionRetryPolicy returns the project's retry policy if it allows another
+// retry after the given number of attempts, or nil.
+func (s *Server) sessionRetryPolicy(session *db.AgentSession, attempt int) *db.RetryPolicy {
+	project, err := s.db.GetProject(session.ProjectID)
+	if err != nil || project.RetryPolicy == nil {
+		return nil
+	}
+	if attempt >= project.RetryPolicy.MaxRetries {
+		return nil
+	}
+	return project.RetryPolicy
+}
+
+// recordSessionRetry appends a retry to the session's history and notifies
+// clients watching the session.
+func (s *Server) recordSessionRetry(session *db.AgentSession, entry db.SessionRetry) {
+	history := append(session.RetryHistory, entry)
+	if err := s.db.SetSessionRetryHistory(session.ID, history); err != nil {
+		slog.Warn("failed to record session retry", "session_id", session.ID, "error", err)
+	}
+	s.wsHub.BroadcastToSession(session.ID, "session_retry", entry)
+}
+
+// sessionStillActive reports whether a session that is waiting out a retry
+// backoff has not been stopped in the meantime.
+func (s *Server) sessionStillActive(sessionID string) bool {
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return false
+	}
+	return session.Status == db.SessionStatusRunning || session.Status == db.SessionStatusWaitingInput
+}
+
+// retryChatTurn schedules another attempt of a failed chat turn when the
+// project's retry policy allows it. It returns false if no retry was made and
+// the failure should be reported as usual.
+func (s *Server) retryChatTurn(session *db.AgentSession, source string, retry chatTurnRetry, errText string) bool {
+	policy := s.sessionRetryPolicy(session, retry.Attempt)
+	if policy == nil {
+		return false
+	}
+
+	retry.Attempt++
+	errText = retryErrorText(errText)
+	s.recordSessionRetry(session, db.SessionRetry{
+		Attempt:   retry.Attempt,
+		Error:     errText,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	delay := retryDelay(policy, retry.Attempt)
+	slog.Info("retrying failed chat turn", "session_id", session.ID, "attempt", retry.Attempt, "delay", delay)
+	go func() {
+		time.Sleep(delay)
+		if !s.sessionStillActive(session.ID) {
+			return
+		}
+		prompt := buildRetryPrompt(policy, errText, retry.Prompt)
+		if err := s.startChatTurnAttempt(session.ID, prompt, strings.TrimSuffix(source, "_retry")+"_retry", retry); err != nil {
+			slog.Warn("failed to start chat retry", "session_id", session.ID, "error", err)
+		}
+	}()
+	return true
+}
+
+// retryTerminalSession restarts a claude terminal session that exited with a
+// failure, resuming its conversation with a prompt describing the error. Only
+// claude can pick up where the failed run left off, so other providers keep
+// the interactive shell fallback. It returns false if no retry was made.
+func (s *Server) retryTerminalSession(taskID string, result ptyruntime.ExitResult) bool {
+	if result.ExitCode <= 0 {
+		// Zero is success; negative codes mean the process was killed.
+		return false
+	}
+	execSession := s.sessions.getOrRestore(result.SessionID, s.db)
+	if execSession == nil || execSession.StartRequest == nil || execSession.Provider != "claude" {
+		return false
+	}
+	dbSession, err := s.db.GetSession(result.SessionID)
+	if err != nil || dbSession.SessionType != "terminal" {
+		return false
+	}
+	policy := s.sessionRetryPolicy(dbSession, len(dbSession.RetryHistory))
+	if policy == nil {
+		return false
+	}
+
+	attempt := len(dbSession.RetryHistory) + 1
+	errText := retryErrorText(string(result.Output))
+	s.recordSessionRetry(dbSession, db.SessionRetry{
+		Attempt:   attempt,
+		Error:     errText,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	delay := retryDelay(policy, attempt)
+	slog.Info("retrying failed terminal session", "session_id", dbSession.ID, "attempt", attempt, "delay", delay)
+	go func() {
+		time.Sleep(delay)
+		if !s.sessionStillActive(dbSession.ID) {
+			return
+		}
+		if err := s.restartTerminalSession(taskID, execSession, buildRetryPrompt(policy, errText, execSession.StartRequest.Prompt)); err != nil {
+			slog.Warn("failed to start terminal retry", "session_id", dbSession.ID, "error", err)
+			s.finishRuntimeExit(taskID, result)
+		}
+	}()
+	return true
+}
+
+// restartTerminalSession starts the agent again in an existing terminal
+// session, resuming its provider conversation with the given prompt.
+func (s *Server) restartTerminalSession(taskID string, execSession *Session, prompt string) error {
+	dbSession, err := s.db.GetSession(execSession.ID)
+	if err != nil {
+		return err
+	}
+	project, err := s.db.GetProject(dbSession.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	req := *execSession.StartRequest
+	req.Prompt = prompt
+	req.ResumeSessionID = dbSession.ID
+	var resumeProviderSessionID string
+	if dbSession.ProviderSessionID != nil {
+		resumeProviderSessionID = *dbSession.ProviderSessionID
+	}
+
+	command, args := buildSessionCommand(req, "", resumeProviderSessionID, resolveAutoApprove(req), s.sessionPrelude(project, req.Provider))
+	command, args = withShellFallback(command, args)
+	opts := s.runtimeCallbacks(taskID, dbSession.ID)
+	opts.WorkDir = execSession.WorkDir
+	opts.Command = command
+	opts.Args = args
+	opts.Cols, opts.Rows = req.Cols, req.Rows
+	opts.Env = append(opts.Env, agentGitEnv(project, req.Provider)...)
+
+	return withClaudeSessionStartLock(execSession.WorkDir, func() error {
+		// Another session may have rewritten the hooks config since this one started.
+		hookToken, err := s.auth.GenerateHookToken(dbSession.ID)
+		if err != nil {
+			return err
+		}
+		tokenPath, err := writeHookToken(dbSession.ID, hookToken)
+		if err != nil {
+			slog.Warn("failed to write hook token file", "session_id", dbSession.ID, "error", err)
+		}
+		if err := writeClaudeHooks(execSession.WorkDir, dbSession.ID, tokenPath, hookAPIURL()); err != nil {
+			slog.Warn("failed to write Claude hooks", "session_id", dbSession.ID, "error", err)
+		}
+		opts.Env = append(opts.Env, sessionArtifactEnv(dbSession.ID, tokenPath, hookAPIURL())...)
+		return s.sessions.runtime.Start(dbSession.ID, opts)
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/ptyruntime"
+)
+
+func TestRetryDelay(t *testing.T) {
+	policy := &db.RetryPolicy{MaxRetries: 3}
+	if got := retryDelay(policy, 1); got != 10*time.Second {
+		t.Errorf("expected default 10s for first retry, got %v", got)
+	}
+	policy.BackoffSeconds = 30
+	if got := retryDelay(policy, 3); got != 2*time.Minute {
+		t.Errorf("expected 2m for third retry, got %v", got)
+	}
+	if got := retryDelay(policy, 20); got != maxRetryBackoff {
+		t.Errorf("expected backoff capped at %v, got %v", maxRetryBackoff, got)
+	}
+}
+
+func TestBuildRetryPrompt(t *testing.T) {
+	prompt := buildRetryPrompt(&db.RetryPolicy{}, "rate limited", "fix the tests")
+	if !strings.Contains(prompt, "rate limited") || !strings.Contains(prompt, "fix the tests") {
+		t.Errorf("expected error and prompt in default template, got %q", prompt)
+	}
+
+	policy := &db.RetryPolicy{PromptTemplate: "Failed: {error}. Retry: {prompt}"}
+	if got := buildRetryPrompt(policy, "boom", "go"); got != "Failed: boom. Retry: go" {
+		t.Errorf("unexpected custom prompt: %q", got)
+	}
+}
+
+func TestRetryErrorText(t *testing.T) {
+	if got := retryErrorText("\x1b[31mError:\x1b[0m API overloaded\r\n"); got != "Error: API overloaded" {
+		t.Errorf("expected escapes stripped, got %q", got)
+	}
+	long := strings.Repeat("x", 3*maxRetryErrorBytes) + "the real error"
+	if got := retryErrorText(long); !strings.HasSuffix(got, "the real error") || len(got) > maxRetryErrorBytes+len("…") {
+		t.Errorf("expected the tail of long output, got %d bytes", len(got))
+	}
+}
+
+func TestRetryChatTurn_FollowsProjectPolicy(t *testing.T) {
+	retryBackoffUnit = time.Hour // keep the scheduled retry from running
+	t.Cleanup(func() { retryBackoffUnit = time.Second })
+
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	_, session := createRunningTaskSession(t, env, "claude")
+	retry := chatTurnRetry{Prompt: "fix the tests"}
+	if env.server.retryChatTurn(session, "chat_ws", retry, "boom") {
+		t.Fatal("expected no retry without a policy")
+	}
+
+	if _, err := env.server.db.UpdateProject(session.ProjectID, db.UpdateProjectInput{
+		RetryPolicy: &db.RetryPolicy{MaxRetries: 1},
+	}); err != nil {
+		t.Fatalf("update project: %v", err)
+	}
+	if !env.server.retryChatTurn(session, "chat_ws", retry, "boom") {
+		t.Fatal("expected a retry under the policy")
+	}
+	updated, err := env.server.db.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if len(updated.RetryHistory) != 1 || updated.RetryHistory[0].Attempt != 1 || updated.RetryHistory[0].Error != "boom" {
+		t.Fatalf("unexpected retry history: %+v", updated.RetryHistory)
+	}
+
+	retry.Attempt = 1
+	if env.server.retryChatTurn(updated, "chat_ws", retry, "boom") {
+		t.Error("expected no retry once maxRetries is reached")
+	}
+}
+
+func TestRetryTerminalSession_SkipsWithoutStartRequest(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	task, session := createRunningTaskSession(t, env, "claude")
+	if _, err := env.server.db.UpdateProject(session.ProjectID, db.UpdateProjectInput{
+		RetryPolicy: &db.RetryPolicy{MaxRetries: 3},
+	}); err != nil {
+		t.Fatalf("update project: %v", err)
+	}
+	env.server.sessions.mu.Lock()
+	env.server.sessions.sessions[session.ID] = &Session{ID: session.ID, TaskID: task.ID, Provider: "claude"}
+	env.server.sessions.mu.Unlock()
+
+	// Sessions restored from the DB have lost their start request and
+	// cannot be relaunched with the same arguments.
+	if env.server.retryTerminalSession(task.ID, ptyruntime.ExitResult{SessionID: session.ID, ExitCode: 1}) {
+		t.Error("expected no retry without the original start request")
+	}
+}
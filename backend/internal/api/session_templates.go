@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// Session templates start sessions in a project's tasks with saved
+// settings and a prompt. The prompt's placeholders are filled in from the
+// task:
+//
+//	{{task.title}} {{task.description}} {{task.id}} {{task.branch}} {{project.name}}
+//
+// Unknown placeholders are left as they are.
+
+// validateSessionTemplate checks a template's provider and model the way a
+// session request is checked.
+func validateSessionTemplate(provider, model string) error {
+	req := StartSessionRequest{Provider: provider, Model: model}
+	return validateSessionRequest(&req)
+}
+
+// renderTemplatePrompt fills in a template prompt's placeholders.
+func renderTemplatePrompt(prompt string, task *db.Task, project *db.Project) string {
+	return strings.NewReplacer(
+		"{{task.title}}", task.Title,
+		"{{task.description}}", ptrToString(task.Description),
+		"{{task.id}}", task.ID,
+		"{{task.branch}}", ptrToString(task.Branch),
+		"{{project.name}}", project.Name,
+	).Replace(prompt)
+}
+
+func (s *Server) handleListSessionTemplates(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	if _, err := s.db.GetProject(projectID); err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	templates, err := s.db.ListSessionTemplates(projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list session templates")
+		return
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+func (s *Server) handleCreateSessionTemplate(w http.ResponseWriter, r *http.Request) {
+	projectID := urlParam(r, "id")
+	if _, err := s.db.GetProject(projectID); err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	var input db.CreateSessionTemplateInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	input.ProjectID = projectID
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	input.Provider = firstNonEmpty(input.Provider, "claude")
+	if err := validateSessionTemplate(input.Provider, input.Model); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tmpl, err := s.db.CreateSessionTemplate(input)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create session template")
+		return
+	}
+	writeJSON(w, http.StatusCreated, tmpl)
+}
+
+func (s *Server) handleUpdateSessionTemplate(w http.ResponseWriter, r *http.Request) {
+	id := urlParam(r, "id")
+	existing, err := s.db.GetSessionTemplate(id)
+	if err != nil {
+		writeDBError(w, err, "session template")
+		return
+	}
+
+	var input db.UpdateSessionTemplateInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			writeError(w, http.StatusBadRequest, "name cannot be empty")
+			return
+		}
+		input.Name = &name
+	}
+	provider, model := existing.Provider, existing.Model
+	if input.Provider != nil {
+		provider = *input.Provider
+	}
+	if input.Model != nil {
+		model = *input.Model
+	}
+	if err := validateSessionTemplate(provider, model); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tmpl, err := s.db.UpdateSessionTemplate(id, input)
+	if err != nil {
+		writeDBError(w, err, "session template")
+		return
+	}
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+func (s *Server) handleDeleteSessionTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.DeleteSessionTemplate(urlParam(r, "id")); err != nil {
+		writeDBError(w, err, "session template")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type startFromTemplateRequest struct {
+	TemplateID string `json:"templateId"`
+}
+
+func (s *Server) handleStartSessionFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req startFromTemplateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	session, err := s.sessionService().StartFromTemplate(r.Context(), urlParam(r, "taskId"), req.TemplateID)
+	if err != nil {
+		writeServiceError(w, err, fmt.Sprint(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// StartFromTemplate starts a session in a task with one of its project's
+// templates.
+func (ss *sessionService) StartFromTemplate(ctx context.Context, taskID, templateID string) (*db.AgentSession, error) {
+	task, err := ss.s.db.GetTask(taskID)
+	if err != nil {
+		return nil, notFound(err, "task")
+	}
+	if templateID == "" {
+		return nil, service.Invalid("templateId is required")
+	}
+	tmpl, err := ss.s.db.GetSessionTemplate(templateID)
+	if err != nil {
+		return nil, notFound(err, "session template")
+	}
+	if tmpl.ProjectID != task.ProjectID {
+		return nil, service.Invalid("session template belongs to another project")
+	}
+	project, err := ss.s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+
+	autoApprove := tmpl.AutoApprove
+	return ss.StartInTask(ctx, taskID, StartSessionRequest{
+		Provider:    tmpl.Provider,
+		Model:       tmpl.Model,
+		AutoApprove: &autoApprove,
+		Prompt:      renderTemplatePrompt(tmpl.Prompt, task, project),
+	})
+}
+
+// telegramStartTemplate replies to /template <task-id> [name], which lists
+// the task's project's templates, or starts a session with the one named.
+func (s *Server) telegramStartTemplate(ctx context.Context, args string) string {
+	const usage = "Usage: /template <task-id> [template name]"
+	taskRef, name, _ := strings.Cut(strings.TrimSpace(args), " ")
+	task, reply := s.telegramTaskArg(taskRef, usage)
+	if reply != "" {
+		return reply
+	}
+	templates, err := s.db.ListSessionTemplates(task.ProjectID)
+	if err != nil {
+		return "Failed to list templates."
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		if len(templates) == 0 {
+			return "No session templates in this task's project."
+		}
+		names := make([]string, 0, len(templates))
+		for _, tmpl := range templates {
+			names = append(names, "• "+tmpl.Name+" ("+tmpl.Provider+")")
+		}
+		sort.Strings(names)
+		return "Templates:\n" + strings.Join(names, "\n")
+	}
+
+	for _, tmpl := range templates {
+		if !strings.EqualFold(tmpl.Name, name) {
+			continue
+		}
+		session, err := s.sessionService().StartFromTemplate(ctx, task.ID, tmpl.ID)
+		if err != nil {
+			return "Failed to start session: " + err.Error()
+		}
+		return fmt.Sprintf("Started %s for %s (%s).", sessionLabel(session), task.Title, shortID(session.ID))
+	}
+	return "Template not found: " + name
+}
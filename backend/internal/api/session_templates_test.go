@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestSessionTemplates(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	invocations := installFakeProviders(t, nil)
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	description := "Users land on /404 after signing in."
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Fix login redirect", Description: &description})
+	templates := "/api/projects/" + project.ID + "/session-templates"
+
+	if resp := env.post(templates, map[string]any{"name": "review", "provider": "gemini"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected an unknown provider rejected, got %d", resp.Code)
+	}
+	resp := env.post(templates, map[string]any{
+		"name":   "review",
+		"prompt": "Review {{task.title}} in {{project.name}}: {{task.description}} {{unknown}}",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create template: %d %s", resp.Code, resp.Body.String())
+	}
+	var tmpl db.SessionTemplate
+	decodeResponse(t, resp, &tmpl)
+	if tmpl.Provider != "claude" || !tmpl.AutoApprove {
+		t.Errorf("expected claude and auto-approve by default, got %+v", tmpl)
+	}
+	if resp := env.patch("/api/session-templates/"+tmpl.ID, map[string]any{"model": "no spaces"}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid model rejected, got %d", resp.Code)
+	}
+
+	start := "/api/tasks/" + task.ID + "/sessions/from-template"
+	if resp := env.post(start, map[string]string{"templateId": "missing"}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing template, got %d", resp.Code)
+	}
+	resp = env.post(start, map[string]string{"templateId": tmpl.ID})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("start from template: %d %s", resp.Code, resp.Body.String())
+	}
+	want := "Review Fix login redirect in shop: Users land on /404 after signing in. {{unknown}}"
+	waitForCondition(t, 10*time.Second, func() bool {
+		runs := invocations()
+		return len(runs) == 1 && slices.Contains(runs[0].Prompts, want)
+	}, "templated prompt")
+
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"424242"`)
+	env.server.db.SetPreference(db.DefaultUserID, telegramChatsPreference, `{"-1001": "read-only"}`)
+	run := func(chatID int64, args string) string {
+		return env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: chatID, UserID: 424242, Name: "template", Args: args})
+	}
+	if got := run(424242, task.ID); got != "Templates:\n• review (claude)" {
+		t.Errorf("unexpected template list %q", got)
+	}
+	if got := run(-1001, task.ID+" review"); got != "/template needs operator access in this chat." {
+		t.Errorf("expected a read-only chat refused, got %q", got)
+	}
+	if got := run(424242, task.ID+" nope"); got != "Template not found: nope" {
+		t.Errorf("unexpected reply %q", got)
+	}
+	if got := run(424242, task.ID+" Review"); !strings.HasPrefix(got, "Started ") {
+		t.Errorf("expected a session started, got %q", got)
+	}
+}
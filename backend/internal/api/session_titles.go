@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/llm"
+)
+
+// Session naming preference:
+//
+//	session_naming  {"mode": "truncate", "maxLength": 60}
+//
+// A session is titled from its first prompt. "truncate" (the default) keeps
+// the start of the prompt; "llm" also asks the session_titles feature of the
+// llm preference for a summary, which replaces the truncated title unless
+// the session was renamed meanwhile; "off" leaves sessions untitled until
+// renamed.
+const (
+	sessionNamingPreference = "session_naming"
+	sessionNamingTruncate   = "truncate"
+	sessionNamingLLM        = "llm"
+	sessionNamingOff        = "off"
+
+	sessionTitleDefaultLength = 60
+	sessionTitleMaxLength     = 200
+	sessionTitleTimeout       = 30 * time.Second
+	// sessionTitlePromptBytes caps how much of a prompt is sent to be
+	// summarized.
+	sessionTitlePromptBytes = 4000
+)
+
+type sessionNamingConfig struct {
+	Mode      string `json:"mode"`
+	MaxLength int    `json:"maxLength"`
+}
+
+func (s *Server) sessionNaming() sessionNamingConfig {
+	var cfg sessionNamingConfig
+	if pref, err := s.db.GetPreference(db.DefaultUserID, sessionNamingPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			slog.Warn("invalid session_naming preference", "error", err)
+		}
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = sessionNamingTruncate
+	}
+	if cfg.MaxLength <= 0 || cfg.MaxLength > sessionTitleMaxLength {
+		cfg.MaxLength = sessionTitleDefaultLength
+	}
+	return cfg
+}
+
+// truncateTitle makes a title of at most maxLen characters from the first
+// non-blank line of text, cut at a word boundary where it can be.
+func truncateTitle(text string, maxLen int) string {
+	var line string
+	for _, l := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(l), " "); line != "" {
+			break
+		}
+	}
+	if utf8.RuneCountInString(line) <= maxLen {
+		return line
+	}
+	runes := []rune(line)[:maxLen-1]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:-") + "…"
+}
+
+// nameSession titles an untitled session from its first prompt, as the
+// session_naming preference says. A summary from a language model is asked
+// for in the background.
+func (s *Server) nameSession(session *db.AgentSession, prompt string) {
+	cfg := s.sessionNaming()
+	if session.Title != "" || cfg.Mode == sessionNamingOff {
+		return
+	}
+	title := truncateTitle(prompt, cfg.MaxLength)
+	if title == "" {
+		return
+	}
+	ok, err := s.db.ReplaceSessionTitle(session.ID, "", title)
+	if err != nil {
+		slog.Warn("failed to title session", "session_id", session.ID, "error", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	session.Title = title
+	s.broadcastSessionUpdated(session)
+
+	if cfg.Mode != sessionNamingLLM {
+		return
+	}
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		ctx, cancel := context.WithTimeout(s.bgCtx, sessionTitleTimeout)
+		defer cancel()
+		summary, err := s.summarizeSessionTitle(ctx, prompt, cfg.MaxLength)
+		if err != nil {
+			slog.Warn("failed to generate session title", "session_id", session.ID, "error", err)
+			return
+		}
+		if summary == "" || summary == title {
+			return
+		}
+		if ok, err := s.db.ReplaceSessionTitle(session.ID, title, summary); err != nil || !ok {
+			return
+		}
+		if updated, err := s.db.GetSession(session.ID); err == nil {
+			s.broadcastSessionUpdated(updated)
+		}
+	}()
+}
+
+// summarizeSessionTitle asks the session_titles model for a short title for
+// a prompt.
+func (s *Server) summarizeSessionTitle(ctx context.Context, prompt string, maxLen int) (string, error) {
+	client, model, err := s.llmClient(llmFeatureSessionTitles)
+	if err != nil {
+		return "", err
+	}
+	if len(prompt) > sessionTitlePromptBytes {
+		prompt = strings.ToValidUTF8(prompt[:sessionTitlePromptBytes], "")
+	}
+	temperature := 0.2
+	reply, err := client.Complete(ctx, llm.CompletionRequest{
+		Model: model,
+		Messages: []llm.Message{
+			{Role: "system", Content: "Write a title of at most six words for the coding session that starts with the user's request. Reply with the title alone, without quotes or a final period."},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens:   24,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	return truncateTitle(strings.Trim(reply, "\"'` "), maxLen), nil
+}
+
+// broadcastSessionUpdated tells the session's and its task's subscribers
+// that its details, such as its title, changed.
+func (s *Server) broadcastSessionUpdated(session *db.AgentSession) {
+	s.wsHub.BroadcastToSession(session.ID, "session_updated", session)
+	if session.TaskID != "" {
+		s.wsHub.BroadcastToTask(session.TaskID, "session_updated", session)
+	}
+	s.wsHub.BroadcastToProject(session.ProjectID, "sidebar_update", map[string]string{
+		"taskId":    session.TaskID,
+		"sessionId": session.ID,
+	})
+}
+
+type updateSessionRequest struct {
+	Title      *string `json:"title"`
+	IdleExempt *bool   `json:"idleExempt"`
+}
+
+// handleUpdateSession renames a session, where an empty title clears it,
+// or exempts it from the idle timeout.
+func (s *Server) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	id := urlParam(r, "id")
+	if _, err := s.db.GetSession(id); err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+
+	var req updateSessionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Title == nil && req.IdleExempt == nil {
+		writeError(w, http.StatusBadRequest, "title or idleExempt is required")
+		return
+	}
+	input := db.UpdateSessionInput{IdleExempt: req.IdleExempt}
+	if req.Title != nil {
+		title := strings.Join(strings.Fields(*req.Title), " ")
+		if utf8.RuneCountInString(title) > sessionTitleMaxLength {
+			writeError(w, http.StatusBadRequest, "title is too long")
+			return
+		}
+		input.Title = &title
+	}
+
+	session, err := s.db.UpdateSession(id, input)
+	if err != nil {
+		writeDBError(w, err, "session")
+		return
+	}
+	s.broadcastSessionUpdated(session)
+	writeJSON(w, http.StatusOK, session)
+}
+
+// handleSearchSessions lists sessions across projects, newest first,
+// filtered by ?q= (titles, and IDs by prefix) and ?projectId=.
+func (s *Server) handleSearchSessions(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sessions, next, err := s.db.ListSessionsPage(db.SessionFilter{
+		ProjectID: r.URL.Query().Get("projectId"),
+		Query:     r.URL.Query().Get("q"),
+	}, page)
+	if err != nil {
+		writeListError(w, err, "sessions")
+		return
+	}
+	writePage(w, sessions, next)
+}
+
+// sessionLabel names a session in text: its title, or its provider and
+// short ID when it has none.
+func sessionLabel(session *db.AgentSession) string {
+	if session.Title != "" {
+		return session.Title
+	}
+	return session.Provider + " " + shortID(session.ID)
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// telegramListSessions lists the active sessions, or with arguments the
+// sessions whose titles match them.
+func (s *Server) telegramListSessions(args string) string {
+	query := strings.TrimSpace(args)
+	var sessions []*db.AgentSession
+	var err error
+	title := "Active sessions"
+	if query == "" {
+		sessions, err = s.db.ListActiveSessions()
+	} else {
+		title = "Sessions matching \"" + query + "\""
+		sessions, _, err = s.db.ListSessionsPage(db.SessionFilter{Query: query}, db.Page{Limit: telegramTaskListLimit + 1})
+	}
+	if err != nil {
+		return "Failed to list sessions."
+	}
+	if len(sessions) == 0 {
+		return title + ": none"
+	}
+
+	var b strings.Builder
+	b.WriteString(title + ":\n")
+	for i, session := range sessions {
+		if i == telegramTaskListLimit {
+			b.WriteString("… and more")
+			break
+		}
+		fmt.Fprintf(&b, "• [%s] %s (%s, %s)\n", session.Status, sessionLabel(session), session.Provider, shortID(session.ID))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTruncateTitle(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Fix the login redirect", "Fix the login redirect"},
+		{"\n\n  Fix   the\tlogin  \nand more", "Fix the login"},
+		{"Refactor the session manager so that restarts keep running sessions", "Refactor the session manager…"},
+		{strings.Repeat("x", 40), strings.Repeat("x", 29) + "…"},
+		{"  \n ", ""},
+	}
+	for _, tt := range tests {
+		if got := truncateTitle(tt.text, 30); got != tt.want {
+			t.Errorf("truncateTitle(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSessionTitles(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "p", Path: t.TempDir()})
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Login"})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude"})
+
+	env.server.nameSession(session, "Fix the login redirect\n\nIt loops after OAuth.")
+	// Only the first prompt names a session.
+	env.server.nameSession(&db.AgentSession{ID: session.ID}, "Now add tests")
+	var got db.AgentSession
+	decodeResponse(t, env.get("/api/sessions/"+session.ID), &got)
+	if got.Title != "Fix the login redirect" {
+		t.Fatalf("title = %q", got.Title)
+	}
+
+	resp := env.patch("/api/sessions/"+session.ID, map[string]string{"title": "  OAuth   loop "})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("rename: %d %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, resp, &got)
+	if got.Title != "OAuth loop" {
+		t.Fatalf("renamed title = %q", got.Title)
+	}
+	if resp := env.patch("/api/sessions/"+session.ID, map[string]string{"title": strings.Repeat("x", 201)}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a long title, got %d", resp.Code)
+	}
+
+	var found []db.AgentSession
+	decodeResponse(t, env.get("/api/sessions?q=oauth"), &found)
+	if len(found) != 1 || found[0].ID != session.ID {
+		t.Fatalf("search: %+v", found)
+	}
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/sessions?q=redirect"), &found)
+	if len(found) != 0 {
+		t.Fatalf("expected no match for the old title, got %+v", found)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", "424242")
+	reply := env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: 424242, UserID: 424242, Name: "sessions", Args: "oauth"})
+	if !strings.Contains(reply, "OAuth loop (claude, "+session.ID[:8]+")") {
+		t.Errorf("unexpected /sessions reply %q", reply)
+	}
+}
+
+func TestSessionTitlesFromLLM(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"\"Fix OAuth redirect loop\""}}]}`)
+	}))
+	defer srv.Close()
+	env.server.db.SetPreference(db.DefaultUserID, llmPreference, `{"url": "`+srv.URL+`", "model": "small"}`)
+	env.server.db.SetPreference(db.DefaultUserID, sessionNamingPreference, `{"mode": "llm"}`)
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "p", Path: t.TempDir()})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "codex"})
+	env.server.nameSession(session, "After signing in with Google the app keeps redirecting back to /login")
+	if !strings.HasPrefix(session.Title, "After signing in") {
+		t.Fatalf("expected a truncated title first, got %q", session.Title)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := env.server.db.GetSession(session.ID)
+		if got.Title == "Fix OAuth redirect loop" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("title = %q", got.Title)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
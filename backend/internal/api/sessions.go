@@ -15,8 +15,11 @@ import (
 	"time"
 
 	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/devcontainer"
 	"github.com/miguel-bm/codeburg/internal/ptyruntime"
 	"github.com/miguel-bm/codeburg/internal/sessionlifecycle"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
+	"github.com/miguel-bm/codeburg/service"
 )
 
 // Guards Claude startup sequence per worktree so hook file write + process start
@@ -33,11 +36,29 @@ type SessionManager struct {
 // NewSessionManager creates a new session manager
 func NewSessionManager() *SessionManager {
 	return &SessionManager{
-		runtime:  ptyruntime.NewManager(),
+		runtime:  newRuntimeManager(),
 		sessions: make(map[string]*Session),
 	}
 }
 
+// newRuntimeManager picks the PTY runtime. Setting CODEBURG_PTY_RUNTIME=tmux
+// runs terminal sessions inside tmux so they survive server restarts.
+func newRuntimeManager() *ptyruntime.Manager {
+	if os.Getenv("CODEBURG_PTY_RUNTIME") != "tmux" {
+		return ptyruntime.NewManager()
+	}
+	if !ptyruntime.TmuxAvailable() {
+		slog.Warn("CODEBURG_PTY_RUNTIME=tmux but tmux is not installed; using in-process PTYs")
+		return ptyruntime.NewManager()
+	}
+	cfg := ptyruntime.TmuxConfig{}
+	if home, err := os.UserHomeDir(); err == nil {
+		cfg.StateDir = filepath.Join(home, ".codeburg", "runtime")
+	}
+	slog.Info("using tmux-backed PTY runtime")
+	return ptyruntime.NewTmuxManager(cfg)
+}
+
 // getOrRestore looks up a session in memory, falling back to DB if runtime is alive.
 func (sm *SessionManager) getOrRestore(sessionID string, database *db.DB) *Session {
 	// Fast path: check in-memory map
@@ -74,7 +95,8 @@ func (sm *SessionManager) getOrRestore(sessionID string, database *db.DB) *Sessi
 }
 
 // Reconcile restores in-memory session state from the database on startup.
-// PTY runtimes are in-process and don't survive restart, so active sessions are marked completed.
+// Terminal sessions still running under the tmux runtime are re-attached;
+// everything else is orphaned by the restart and marked completed.
 func (sm *SessionManager) Reconcile(server *Server) {
 	sessions, err := server.db.ListActiveSessions()
 	if err != nil {
@@ -82,8 +104,12 @@ func (sm *SessionManager) Reconcile(server *Server) {
 		return
 	}
 
-	var cleaned int
+	var restored, cleaned int
 	for _, s := range sessions {
+		if s.SessionType != "chat" && sm.reattach(server, s) {
+			restored++
+			continue
+		}
 		if _, _, err := server.applySessionTransition(s.ID, s.Status, sessionlifecycle.EventReconcileOrphan, s.TaskID, "reconcile"); err != nil {
 			if errors.Is(err, sessionlifecycle.ErrInvalidTransition) {
 				logInvalidSessionTransition(s.ID, s.Status, sessionlifecycle.EventReconcileOrphan, "reconcile", err)
@@ -96,19 +122,53 @@ func (sm *SessionManager) Reconcile(server *Server) {
 		cleaned++
 	}
 
-	slog.Info("session reconciliation complete", "restored", 0, "cleaned", cleaned)
+	slog.Info("session reconciliation complete", "restored", restored, "cleaned", cleaned)
 }
 
-// StartSessionRequest contains the request body for starting a session
-type StartSessionRequest struct {
-	Provider        string `json:"provider"`        // "claude", "codex", "terminal" (default: "claude")
-	SessionType     string `json:"sessionType"`     // "chat" or "terminal" (default: chat for claude/codex, terminal for terminal provider)
-	Prompt          string `json:"prompt"`          // Initial prompt (claude/codex sessions)
-	Model           string `json:"model"`           // Optional model override
-	ResumeSessionID string `json:"resumeSessionId"` // Codeburg session ID to resume
-	AutoApprove     *bool  `json:"autoApprove"`     // Skip permission prompts (nil = true)
+// reattach re-attaches a runtime that survived a restart and restores its
+// in-memory session. Returns false if there is nothing to re-attach.
+func (sm *SessionManager) reattach(server *Server, s *db.AgentSession) bool {
+	if err := sm.runtime.Reattach(s.ID, server.runtimeCallbacks(s.TaskID, s.ID)); err != nil {
+		if !errors.Is(err, ptyruntime.ErrSessionNotFound) {
+			slog.Warn("failed to re-attach session runtime", "session_id", s.ID, "error", err)
+		}
+		return false
+	}
+
+	sm.mu.Lock()
+	sm.sessions[s.ID] = &Session{
+		ID:       s.ID,
+		TaskID:   s.TaskID,
+		Provider: s.Provider,
+		Status:   s.Status,
+	}
+	sm.mu.Unlock()
+
+	slog.Info("session runtime re-attached", "session_id", s.ID, "provider", s.Provider)
+	return true
 }
 
+// runtimeCallbacks returns runtime start options carrying the output and exit
+// hooks shared by all terminal sessions of a task, and the session's
+// recording.
+func (s *Server) runtimeCallbacks(taskID, sessionID string) ptyruntime.StartOptions {
+	return ptyruntime.StartOptions{
+		Env:       s.taskEnv(taskID),
+		Recording: s.sessionRecording(sessionID),
+		OnOutput: func(sessionID string, chunk []byte) {
+			if taskID != "" {
+				s.portSuggest.IngestOutput(taskID, sessionID, chunk)
+			}
+		},
+		OnExit: func(result ptyruntime.ExitResult) {
+			s.handleRuntimeExit(taskID, result)
+		},
+	}
+}
+
+// StartSessionRequest contains the request body for starting a session
+type StartSessionRequest = service.StartSessionOptions
+
 func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
 	taskID := urlParam(r, "taskId")
 
@@ -119,25 +179,25 @@ func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessions, err := s.db.ListSessionsByTask(taskID)
+	page, err := parsePage(r)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	writeJSON(w, http.StatusOK, sessions)
-}
-
-func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
-	taskID := urlParam(r, "taskId")
-
-	// Verify task exists and get it
-	task, err := s.db.GetTask(taskID)
+	sessions, next, err := s.sessionService().List(r.Context(), db.SessionFilter{TaskID: taskID, Query: r.URL.Query().Get("q")}, page)
 	if err != nil {
-		writeDBError(w, err, "task")
+		writeListError(w, err, "sessions")
 		return
 	}
 
+	writePage(w, sessions, next)
+}
+
+func (ss *sessionService) List(ctx context.Context, filter db.SessionFilter, page db.Page) ([]*db.AgentSession, string, error) {
+	return ss.s.db.ListSessionsPage(filter, page)
+}
+
+func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req StartSessionRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -145,16 +205,33 @@ func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := validateSessionRequest(&req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+	session, err := s.sessionService().StartInTask(r.Context(), urlParam(r, "taskId"), req)
+	if err != nil {
+		writeServiceError(w, err, fmt.Sprint(err))
 		return
 	}
 
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// StartInTask starts a session in the task's worktree, if it has one.
+func (ss *sessionService) StartInTask(ctx context.Context, taskID string, req StartSessionRequest) (*db.AgentSession, error) {
+	s := ss.s
+
+	// Verify task exists and get it
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		return nil, notFound(err, "task")
+	}
+
+	if err := validateSessionRequest(&req); err != nil {
+		return nil, service.Invalid("%s", err.Error())
+	}
+
 	// Get project for worktree path
 	project, err := s.db.GetProject(task.ProjectID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "get project: "+err.Error())
-		return
+		return nil, fmt.Errorf("get project: %w", err)
 	}
 
 	// Determine working directory (worktree if available, else project path)
@@ -163,17 +240,11 @@ func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
 		workDir = *task.WorktreePath
 	}
 
-	session, err := s.startSessionInternal(startSessionParams{
+	return s.startSessionInternal(ctx, startSessionParams{
 		ProjectID: task.ProjectID,
 		TaskID:    task.ID,
 		WorkDir:   workDir,
 	}, req)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, session)
 }
 
 func (s *Server) handleListProjectSessions(w http.ResponseWriter, r *http.Request) {
@@ -186,45 +257,51 @@ func (s *Server) handleListProjectSessions(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	sessions, err := s.db.ListSessionsByProject(projectID)
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sessions, next, err := s.sessionService().List(r.Context(), db.SessionFilter{ProjectID: projectID, ProjectOnly: true, Query: r.URL.Query().Get("q")}, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list sessions")
+		writeListError(w, err, "sessions")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, sessions)
+	writePage(w, sessions, next)
 }
 
 func (s *Server) handleStartProjectSession(w http.ResponseWriter, r *http.Request) {
-	projectID := urlParam(r, "id")
+	var req StartSessionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
 
-	project, err := s.db.GetProject(projectID)
+	session, err := s.sessionService().StartInProject(r.Context(), urlParam(r, "id"), req)
 	if err != nil {
-		writeDBError(w, err, "project")
+		writeServiceError(w, err, fmt.Sprint(err))
 		return
 	}
 
-	var req StartSessionRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
-		return
+	writeJSON(w, http.StatusCreated, session)
+}
+
+// StartInProject starts a session in the project's directory.
+func (ss *sessionService) StartInProject(ctx context.Context, projectID string, req StartSessionRequest) (*db.AgentSession, error) {
+	project, err := ss.s.db.GetProject(projectID)
+	if err != nil {
+		return nil, notFound(err, "project")
 	}
 
 	if err := validateSessionRequest(&req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, service.Invalid("%s", err.Error())
 	}
 
-	session, err := s.startSessionInternal(startSessionParams{
+	return ss.s.startSessionInternal(ctx, startSessionParams{
 		ProjectID: project.ID,
 		WorkDir:   project.Path,
 	}, req)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	writeJSON(w, http.StatusCreated, session)
 }
 
 func validateSessionRequest(req *StartSessionRequest) error {
@@ -243,6 +320,15 @@ func validateSessionRequest(req *StartSessionRequest) error {
 	if req.Provider == "terminal" && req.SessionType == "chat" {
 		return fmt.Errorf("terminal provider only supports terminal session type")
 	}
+	switch req.Mode {
+	case "":
+	case sessionModeAsk:
+		if req.Provider == "terminal" || req.SessionType == "terminal" {
+			return fmt.Errorf("ask sessions are chat sessions with claude or codex")
+		}
+	default:
+		return fmt.Errorf("invalid mode: %s", req.Mode)
+	}
 	return nil
 }
 
@@ -272,22 +358,55 @@ type startSessionParams struct {
 
 // startSessionInternal creates and starts a session.
 // It handles hook setup and process launch in the PTY runtime.
-func (s *Server) startSessionInternal(params startSessionParams, req StartSessionRequest) (*db.AgentSession, error) {
+func (s *Server) startSessionInternal(ctx context.Context, params startSessionParams, req StartSessionRequest) (_ *db.AgentSession, err error) {
 	provider := req.Provider
 	sessionType := resolveSessionType(req)
 	workDir := params.WorkDir
 	taskID := params.TaskID
 
+	ctx, span := telemetry.Start(ctx, "session.start",
+		telemetry.String("session.provider", provider),
+		telemetry.String("session.type", sessionType),
+		telemetry.String("project.id", params.ProjectID),
+		telemetry.String("task.id", taskID),
+	)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if err := s.checkBudget(params.ProjectID, provider); err != nil {
+		return nil, err
+	}
+
+	// Terminal sessions of tasks in projects with a devcontainer run in it;
+	// agents stay on the host, with their hooks and credentials.
+	var container *devcontainer.Container
+	if provider == "terminal" && taskID != "" {
+		if container, err = s.taskContainer(ctx, taskID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// The DB writes before launch are timed as one span; deferred End covers
+	// the early returns.
+	_, dbSpan := telemetry.Start(ctx, "session.db_setup")
+	defer dbSpan.End()
+
 	// Create database session.
 	dbSession, err := s.db.CreateSession(db.CreateSessionInput{
 		TaskID:      params.TaskID,
 		ProjectID:   params.ProjectID,
 		Provider:    provider,
 		SessionType: sessionType,
+		ReadOnly:    req.Mode == sessionModeAsk,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session record: %w", err)
 	}
+	if strings.TrimSpace(req.Prompt) != "" {
+		s.nameSession(dbSession, req.Prompt)
+	}
 
 	var resumeSource *db.AgentSession
 	if req.ResumeSessionID != "" {
@@ -332,8 +451,39 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 		}
 	}
 
+	project, err := s.db.GetProject(params.ProjectID)
+	if err != nil {
+		_ = s.db.DeleteSession(dbSession.ID)
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+	if project.ArchivedAt != nil {
+		_ = s.db.DeleteSession(dbSession.ID)
+		return nil, errProjectArchived
+	}
+	dbSpan.End()
+	applyAgentInstructionDefaults(&req, project)
 	autoApprove := resolveAutoApprove(req)
 
+	// A new session with a prompt takes the task's inbox snippets; they count
+	// as consumed once it has started.
+	if taskID != "" && provider != "terminal" && req.Mode != sessionModeAsk && strings.TrimSpace(req.Prompt) != "" {
+		if snippets := s.inboxSnippets(taskID); len(snippets) > 0 {
+			req.Prompt = withInboxSnippets(strings.TrimSpace(req.Prompt), snippets)
+			defer func() {
+				if err != nil {
+					return
+				}
+				ids := make([]string, len(snippets))
+				for i, snippet := range snippets {
+					ids[i] = snippet.ID
+				}
+				if consumeErr := s.db.ConsumeSnippets(ids, dbSession.ID); consumeErr != nil {
+					slog.Warn("failed to mark snippets consumed", "session_id", dbSession.ID, "error", consumeErr)
+				}
+			}()
+		}
+	}
+
 	if sessionType == "chat" {
 		if err := s.chat.RegisterSession(dbSession.ID, provider, req.Model, autoApprove); err != nil {
 			_ = s.db.DeleteSession(dbSession.ID)
@@ -374,6 +524,9 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 		return updatedSession, nil
 	}
 
+	_, hookSpan := telemetry.Start(ctx, "session.hooks_write")
+	defer hookSpan.End()
+
 	// Generate a scoped JWT token for hook callbacks
 	hookToken, err := s.auth.GenerateHookToken(dbSession.ID)
 	if err != nil {
@@ -388,11 +541,7 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 		// Fall through — scripts will fail but session still works
 	}
 
-	// Get API base URL
-	apiURL := os.Getenv("CODEBURG_URL")
-	if apiURL == "" {
-		apiURL = "http://localhost:8080"
-	}
+	apiURL := hookAPIURL()
 
 	var notifyScript string
 	switch provider {
@@ -404,6 +553,8 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 		}
 	}
 
+	hookSpan.End()
+
 	var resumeProviderSessionID string
 	if req.Provider == "claude" && resumeSource != nil {
 		if resumeSource.ProviderSessionID != nil && *resumeSource.ProviderSessionID != "" {
@@ -414,7 +565,7 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 		}
 	}
 
-	command, args := buildSessionCommand(req, notifyScript, resumeProviderSessionID, autoApprove)
+	command, args := buildSessionCommand(req, notifyScript, resumeProviderSessionID, autoApprove, s.sessionPrelude(project, provider))
 	originalCommand := command
 	command, args = withShellFallback(command, args)
 	if originalCommand != command {
@@ -422,19 +573,23 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 	}
 
 	startRuntime := func() error {
-		return s.sessions.runtime.Start(dbSession.ID, ptyruntime.StartOptions{
-			WorkDir: workDir,
-			Command: command,
-			Args:    args,
-			OnOutput: func(sessionID string, chunk []byte) {
-				if taskID != "" {
-					s.portSuggest.IngestOutput(taskID, sessionID, chunk)
-				}
-			},
-			OnExit: func(result ptyruntime.ExitResult) {
-				s.handleRuntimeExit(taskID, result)
-			},
-		})
+		spanCtx, span := telemetry.Start(ctx, "session.runtime_start", telemetry.String("process.command", command))
+		defer span.End()
+
+		opts := s.runtimeCallbacks(taskID, dbSession.ID)
+		opts.TraceContext = spanCtx
+		opts.WorkDir = workDir
+		opts.Command = command
+		opts.Args = args
+		opts.Cols, opts.Rows = req.Cols, req.Rows
+		opts.Env = append(opts.Env, agentGitEnv(project, provider)...)
+		opts.Env = append(opts.Env, sessionArtifactEnv(dbSession.ID, tokenPath, apiURL)...)
+		if container != nil {
+			opts.Command, opts.Args = s.containers.ExecArgs(container, workDir, opts.Env, true, containerShellArgs(req.Prompt)...)
+		}
+		err := s.sessions.runtime.Start(dbSession.ID, opts)
+		span.RecordError(err)
+		return err
 	}
 
 	var startErr error
@@ -442,9 +597,12 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 		startErr = withClaudeSessionStartLock(workDir, func() error {
 			// Write Claude Code hooks config immediately before start.
 			// Claude snapshots hooks at startup, so this must be serialized per worktree.
+			_, span := telemetry.Start(ctx, "session.claude_hooks_write")
 			if err := writeClaudeHooks(workDir, dbSession.ID, tokenPath, apiURL); err != nil {
+				span.RecordError(err)
 				slog.Warn("failed to write Claude hooks", "session_id", dbSession.ID, "error", err)
 			}
+			span.End()
 			return startRuntime()
 		})
 	} else {
@@ -460,7 +618,10 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 	}
 
 	// Transition idle -> running after successful runtime start.
+	_, statusSpan := telemetry.Start(ctx, "session.db_status")
 	runningStatus, changed, err := s.applySessionTransition(dbSession.ID, dbSession.Status, sessionlifecycle.EventSessionStarted, taskID, "session_start")
+	statusSpan.RecordError(err)
+	statusSpan.End()
 	if err != nil {
 		if stopErr := s.sessions.runtime.Stop(dbSession.ID); stopErr != nil {
 			slog.Warn("failed to stop session runtime after status update failure", "session_id", dbSession.ID, "error", stopErr)
@@ -486,11 +647,12 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 
 	// Store in-memory session
 	execSession := &Session{
-		ID:       dbSession.ID,
-		TaskID:   taskID,
-		Provider: provider,
-		Status:   runningStatus,
-		WorkDir:  workDir,
+		ID:           dbSession.ID,
+		TaskID:       taskID,
+		Provider:     provider,
+		Status:       runningStatus,
+		WorkDir:      workDir,
+		StartRequest: &req,
 	}
 	s.sessions.mu.Lock()
 	s.sessions.sessions[dbSession.ID] = execSession
@@ -509,38 +671,57 @@ func (s *Server) startSessionInternal(params startSessionParams, req StartSessio
 }
 
 func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
-	id := urlParam(r, "id")
-
-	session, err := s.db.GetSession(id)
+	session, err := s.sessionService().Get(r.Context(), urlParam(r, "id"))
 	if err != nil {
-		writeDBError(w, err, "session")
+		writeServiceError(w, err, "failed to get session")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, session)
 }
 
-// SendMessageRequest contains the request body for sending a message
-type SendMessageRequest struct {
-	Content string `json:"content"`
+func (ss *sessionService) Get(ctx context.Context, id string) (*db.AgentSession, error) {
+	session, err := ss.s.db.GetSession(id)
+	if err != nil {
+		return nil, notFound(err, "session")
+	}
+	return session, nil
 }
 
-func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
-	id := urlParam(r, "id")
-
-	// Get session from database
-	session, err := s.db.GetSession(id)
+// handleListSessionMessages returns a chat session's stored messages in
+// order, a page at a time when limit or cursor is given.
+func (s *Server) handleListSessionMessages(w http.ResponseWriter, r *http.Request) {
+	session, err := s.db.GetSession(urlParam(r, "id"))
 	if err != nil {
 		writeDBError(w, err, "session")
 		return
 	}
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// Check if session is active
-	if session.Status != db.SessionStatusRunning && session.Status != db.SessionStatusWaitingInput {
-		writeError(w, http.StatusBadRequest, "session is not active")
+	rows, next, err := s.db.ListAgentMessagesPage(session.ID, page)
+	if err != nil {
+		writeListError(w, err, "messages")
 		return
 	}
+	messages := make([]ChatMessage, 0, len(rows))
+	for _, row := range rows {
+		if msg, ok := chatMessageFromRow(row, session.Provider); ok {
+			messages = append(messages, msg)
+		}
+	}
+	writePage(w, messages, next)
+}
 
+// SendMessageRequest contains the request body for sending a message
+type SendMessageRequest struct {
+	Content string `json:"content"`
+}
+
+func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req SendMessageRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -548,43 +729,71 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Content == "" {
-		writeError(w, http.StatusBadRequest, "content is required")
+	if err := s.sessionService().Send(r.Context(), urlParam(r, "id"), req.Content); err != nil {
+		writeServiceError(w, err, "failed to send message: "+err.Error())
 		return
 	}
 
-	if session.SessionType == "chat" {
-		if err := s.startChatTurn(session.ID, strings.TrimSpace(req.Content), "send_message"); err != nil {
-			status := http.StatusInternalServerError
-			if errors.Is(err, ErrChatTurnBusy) {
-				status = http.StatusConflict
-			}
-			writeError(w, status, "failed to send message: "+err.Error())
-			return
-		}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "sent"})
+}
 
-		writeJSON(w, http.StatusOK, map[string]string{"status": "sent"})
-		return
+// Send delivers content to an active session.
+func (ss *sessionService) Send(ctx context.Context, id, content string) error {
+	session, err := ss.s.db.GetSession(id)
+	if err != nil {
+		return notFound(err, "session")
+	}
+
+	// Check if session is active
+	if session.Status != db.SessionStatusRunning && session.Status != db.SessionStatusWaitingInput {
+		return service.Invalid("session is not active")
+	}
+	if content == "" {
+		return service.Invalid("content is required")
+	}
+
+	err = ss.s.sendSessionMessage(session, content, "send_message")
+	switch {
+	case errors.Is(err, errSessionNotRunning):
+		return service.Invalid("%s", err.Error())
+	case errors.Is(err, ErrChatTurnBusy):
+		return service.Conflict("failed to send message: %s", err.Error())
+	}
+	return err
+}
+
+// errSessionNotRunning is returned when a terminal session has no runtime on
+// this server.
+var errSessionNotRunning = errors.New("session not running on this server")
+
+// sendSessionMessage delivers user input to an active session: a chat turn
+// for chat sessions, or a line written to the terminal runtime otherwise.
+func (s *Server) sendSessionMessage(session *db.AgentSession, content, source string) error {
+	id := session.ID
+	if err := s.throttleSessionMessage(id); err != nil {
+		return err
+	}
+	s.nameSession(session, content)
+	if session.SessionType == "chat" {
+		return s.startChatTurn(id, strings.TrimSpace(content), source)
 	}
 
 	// Get the running session (with DB fallback)
 	execSession := s.sessions.getOrRestore(id, s.db)
 	if execSession == nil {
-		writeError(w, http.StatusBadRequest, "session not running on this server")
-		return
+		return errSessionNotRunning
 	}
 
 	// Deliver message to runtime process
-	if err := s.sessions.runtime.Write(id, []byte(req.Content+"\n")); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to send message: "+err.Error())
-		return
+	if err := s.sessions.runtime.Write(id, []byte(content+"\n")); err != nil {
+		return err
 	}
 
 	// Transition waiting_input -> running when user sends a message.
-	runningStatus, changed, err := s.applySessionTransition(id, session.Status, sessionlifecycle.EventUserMessage, session.TaskID, "send_message")
+	runningStatus, changed, err := s.applySessionTransition(id, session.Status, sessionlifecycle.EventUserMessage, session.TaskID, source)
 	if err != nil {
 		if errors.Is(err, sessionlifecycle.ErrInvalidTransition) {
-			logInvalidSessionTransition(id, session.Status, sessionlifecycle.EventUserMessage, "send_message", err)
+			logInvalidSessionTransition(id, session.Status, sessionlifecycle.EventUserMessage, source, err)
 		} else {
 			slog.Warn("failed to update session status after send message", "session_id", id, "error", err)
 		}
@@ -595,13 +804,15 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 
 	// Broadcast to WebSocket subscribers
 	s.wsHub.BroadcastToSession(id, "message_sent", map[string]string{
-		"content": req.Content,
+		"content": content,
 	})
-
-	writeJSON(w, http.StatusOK, map[string]string{"status": "sent"})
+	return nil
 }
 
 func (s *Server) resolveSessionWorkDir(session *db.AgentSession) (string, error) {
+	if dir, ok, err := s.comparisonWorkDir(session.ID); err != nil || ok {
+		return dir, err
+	}
 	if session.TaskID != "" {
 		task, err := s.db.GetTask(session.TaskID)
 		if err != nil {
@@ -630,6 +841,12 @@ func (s *Server) startChatTurn(sessionID, content, source string) error {
 	if content == "" {
 		return fmt.Errorf("content is required")
 	}
+	return s.startChatTurnAttempt(sessionID, content, source, chatTurnRetry{Prompt: content})
+}
+
+// startChatTurnAttempt starts a chat turn. retry carries the prompt the user
+// originally sent and how many times it has been retried.
+func (s *Server) startChatTurnAttempt(sessionID, content, source string, retry chatTurnRetry) error {
 
 	session, err := s.db.GetSession(sessionID)
 	if err != nil {
@@ -639,6 +856,14 @@ func (s *Server) startChatTurn(sessionID, content, source string) error {
 		return fmt.Errorf("session is not a chat session")
 	}
 
+	project, err := s.db.GetProject(session.ProjectID)
+	if err != nil {
+		return err
+	}
+	if project.ArchivedAt != nil {
+		return errProjectArchived
+	}
+
 	workDir, err := s.resolveSessionWorkDir(session)
 	if err != nil {
 		return err
@@ -655,18 +880,30 @@ func (s *Server) startChatTurn(sessionID, content, source string) error {
 		s.broadcastSessionStatus(session.TaskID, sessionID, runningStatus)
 	}
 
-	resultCh, err := s.chat.StartTurn(StartChatTurnInput{
-		SessionID: sessionID,
-		Provider:  session.Provider,
-		WorkDir:   workDir,
-		Prompt:    content,
-		Model:     "",
-	})
+	input := StartChatTurnInput{
+		SessionID:    sessionID,
+		Provider:     session.Provider,
+		WorkDir:      workDir,
+		Prompt:       content,
+		Model:        "",
+		Env:          append(agentGitEnv(project, session.Provider), s.taskEnv(session.TaskID)...),
+		SystemPrompt: s.sessionPrelude(project, session.Provider),
+		Timeout:      s.chatTurnTimeout(session.Provider),
+	}
+	if session.ReadOnly {
+		input.SystemPrompt = strings.TrimSpace(askReadOnlyPrelude + "\n\n" + input.SystemPrompt)
+		found, err := askContext(context.Background(), workDir, content)
+		if err != nil {
+			slog.Warn("failed to search code for ask session", "session_id", sessionID, "error", err)
+		}
+		input.Context = found
+	}
+	resultCh, err := s.chat.StartTurn(input)
 	if err != nil {
 		return err
 	}
 
-	go s.awaitChatTurnResult(sessionID, source, resultCh)
+	go s.awaitChatTurnResult(sessionID, source, retry, resultCh)
 
 	s.wsHub.BroadcastToSession(sessionID, "message_sent", map[string]string{
 		"content": content,
@@ -674,7 +911,7 @@ func (s *Server) startChatTurn(sessionID, content, source string) error {
 	return nil
 }
 
-func (s *Server) awaitChatTurnResult(sessionID, source string, resultCh <-chan ChatTurnResult) {
+func (s *Server) awaitChatTurnResult(sessionID, source string, retry chatTurnRetry, resultCh <-chan ChatTurnResult) {
 	result, ok := <-resultCh
 	if !ok {
 		return
@@ -702,10 +939,18 @@ func (s *Server) awaitChatTurnResult(sessionID, source string, resultCh <-chan C
 	}
 
 	if result.Err != nil {
-		errorStatus, changed, applyErr := s.applySessionTransition(sessionID, session.Status, sessionlifecycle.EventRuntimeExitFailure, session.TaskID, source+"_error")
+		// A wedged provider would likely wedge again: timeouts aren't retried.
+		if !result.TimedOut && s.retryChatTurn(session, source, retry, result.ErrorText) {
+			return
+		}
+		errorSource := source + "_error"
+		if result.TimedOut {
+			errorSource = source + "_timeout"
+		}
+		errorStatus, changed, applyErr := s.applySessionTransition(sessionID, session.Status, sessionlifecycle.EventRuntimeExitFailure, session.TaskID, errorSource)
 		if applyErr != nil {
 			if errors.Is(applyErr, sessionlifecycle.ErrInvalidTransition) {
-				logInvalidSessionTransition(sessionID, session.Status, sessionlifecycle.EventRuntimeExitFailure, source+"_error", applyErr)
+				logInvalidSessionTransition(sessionID, session.Status, sessionlifecycle.EventRuntimeExitFailure, errorSource, applyErr)
 			} else {
 				slog.Warn("failed to update chat session status on error", "session_id", sessionID, "error", applyErr)
 			}
@@ -717,6 +962,13 @@ func (s *Server) awaitChatTurnResult(sessionID, source string, resultCh <-chan C
 		return
 	}
 
+	// A session past its project's guardrails waits like any finished
+	// turn, but with a warning that replaces the usual notification.
+	guardrails := s.checkGuardrails(session)
+	if guardrails != nil && !guardrails.violated() {
+		guardrails = nil
+	}
+
 	waitingStatus, changed, waitErr := s.applySessionTransition(sessionID, session.Status, sessionlifecycle.EventAgentTurnComplete, session.TaskID, source+"_complete")
 	if waitErr != nil {
 		if errors.Is(waitErr, sessionlifecycle.ErrInvalidTransition) {
@@ -726,26 +978,90 @@ func (s *Server) awaitChatTurnResult(sessionID, source string, resultCh <-chan C
 		}
 		return
 	}
-	if changed {
+	if guardrails != nil {
+		s.warnGuardrails(session, guardrails)
+		if changed {
+			s.publishSessionStatus(session.TaskID, sessionID, waitingStatus)
+		}
+	} else if changed {
 		s.broadcastSessionStatus(session.TaskID, sessionID, waitingStatus)
 	}
+	if source == telegramVoiceSource {
+		s.sendVoiceReply(session)
+	}
 }
 
 func (s *Server) handleStopSession(w http.ResponseWriter, r *http.Request) {
-	id := urlParam(r, "id")
+	if err := s.sessionService().Stop(r.Context(), urlParam(r, "id")); err != nil {
+		writeServiceError(w, err, "failed to get session")
+		return
+	}
 
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ss *sessionService) Stop(ctx context.Context, id string) error {
 	// Get session from database
-	dbSession, err := s.db.GetSession(id)
+	dbSession, err := ss.s.db.GetSession(id)
 	if err != nil {
-		writeDBError(w, err, "session")
+		return notFound(err, "session")
+	}
+	ss.s.stopSession(dbSession)
+	return nil
+}
+
+func (s *Server) handleResizeSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Cols uint16 `json:"cols"`
+		Rows uint16 `json:"rows"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := s.sessionService().Resize(r.Context(), urlParam(r, "id"), req.Cols, req.Rows); err != nil {
+		writeServiceError(w, err, "failed to resize session")
 		return
 	}
 
-	// Transition session to completed on explicit stop.
-	completedStatus, changed, err := s.applySessionTransition(id, dbSession.Status, sessionlifecycle.EventStopRequested, dbSession.TaskID, "stop_session")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ss *sessionService) Resize(ctx context.Context, id string, cols, rows uint16) error {
+	if cols == 0 || rows == 0 {
+		return service.Invalid("cols and rows are required")
+	}
+	dbSession, err := ss.s.db.GetSession(id)
+	if err != nil {
+		return notFound(err, "session")
+	}
+	if dbSession.SessionType == "chat" {
+		return service.Invalid("chat sessions have no terminal")
+	}
+	if err := ss.s.sessions.runtime.Resize(id, cols, rows); err != nil {
+		if errors.Is(err, ptyruntime.ErrSessionNotFound) {
+			return service.Conflict("session is not running")
+		}
+		return err
+	}
+	return nil
+}
+
+// stopSession stops a session's runtime (or chat turn), marks it completed
+// and notifies clients.
+func (s *Server) stopSession(dbSession *db.AgentSession) {
+	s.stopSessionFor(dbSession, sessionlifecycle.EventStopRequested, "stop_session")
+}
+
+// stopSessionFor stops a session like stopSession, recording event as the
+// reason for the transition.
+func (s *Server) stopSessionFor(dbSession *db.AgentSession, event sessionlifecycle.Event, source string) {
+	id := dbSession.ID
+
+	completedStatus, changed, err := s.applySessionTransition(id, dbSession.Status, event, dbSession.TaskID, source)
 	if err != nil {
 		if errors.Is(err, sessionlifecycle.ErrInvalidTransition) {
-			logInvalidSessionTransition(id, dbSession.Status, sessionlifecycle.EventStopRequested, "stop_session", err)
+			logInvalidSessionTransition(id, dbSession.Status, event, source, err)
 		} else {
 			slog.Warn("failed to update session status on stop", "session_id", id, "error", err)
 		}
@@ -777,8 +1093,6 @@ func (s *Server) handleStopSession(w http.ResponseWriter, r *http.Request) {
 		s.broadcastSessionStatus(dbSession.TaskID, id, completedStatus)
 	}
 	s.wsHub.BroadcastToSession(id, "session_stopped", nil)
-
-	w.WriteHeader(http.StatusNoContent)
 }
 
 func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
@@ -821,8 +1135,9 @@ func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 	removeNotifyScript(id)
 	s.portSuggest.ForgetSession(id)
 
-	// Remove session log file
+	// Remove session log file and uploads
 	removeSessionLog(id)
+	removeSessionUploads(id)
 
 	// Delete from database
 	if err := s.db.DeleteSession(id); err != nil {
@@ -837,7 +1152,7 @@ func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 			"sessionId": id,
 		})
 	}
-	s.wsHub.BroadcastGlobal("sidebar_update", map[string]string{
+	s.wsHub.BroadcastToProject(dbSession.ProjectID, "sidebar_update", map[string]string{
 		"taskId":    dbSession.TaskID,
 		"sessionId": id,
 	})
@@ -988,6 +1303,7 @@ func (s *Server) tryStartTerminalFallback(taskID string, result ptyruntime.ExitR
 		WorkDir: workDir,
 		Command: command,
 		Args:    args,
+		Env:     s.taskEnv(taskID),
 		OnOutput: func(sessionID string, chunk []byte) {
 			if taskID != "" {
 				s.portSuggest.IngestOutput(taskID, sessionID, chunk)
@@ -996,6 +1312,7 @@ func (s *Server) tryStartTerminalFallback(taskID string, result ptyruntime.ExitR
 		OnExit: func(exitResult ptyruntime.ExitResult) {
 			s.handleRuntimeExit(taskID, exitResult)
 		},
+		Recording: s.sessionRecording(result.SessionID),
 	})
 	if startErr != nil {
 		slog.Warn("failed to start terminal fallback runtime", "session_id", result.SessionID, "error", startErr)
@@ -1038,6 +1355,15 @@ func (s *Server) tryStartTerminalFallback(taskID string, result ptyruntime.ExitR
 }
 
 func (s *Server) handleRuntimeExit(taskID string, result ptyruntime.ExitResult) {
+	if s.retryTerminalSession(taskID, result) {
+		return
+	}
+	s.finishRuntimeExit(taskID, result)
+}
+
+// finishRuntimeExit falls back to an interactive shell or records the final
+// session status once a runtime has exited for good.
+func (s *Server) finishRuntimeExit(taskID string, result ptyruntime.ExitResult) {
 	if s.tryStartTerminalFallback(taskID, result) {
 		return
 	}
@@ -1079,6 +1405,14 @@ func (s *Server) handleRuntimeExit(taskID string, result ptyruntime.ExitResult)
 	}
 }
 
+// hookAPIURL is the base URL hook scripts use to reach this server.
+func hookAPIURL() string {
+	if apiURL := os.Getenv("CODEBURG_URL"); apiURL != "" {
+		return apiURL
+	}
+	return "http://localhost:8080"
+}
+
 // writeHookToken writes a scoped token to ~/.codeburg/tokens/{sessionID} and returns the path.
 func writeHookToken(sessionID, token string) (string, error) {
 	home, err := os.UserHomeDir()
@@ -1105,13 +1439,14 @@ func removeHookToken(sessionID string) {
 	os.Remove(filepath.Join(home, ".codeburg", "tokens", sessionID))
 }
 
-// removeSessionLog deletes the log file for a session.
+// removeSessionLog deletes the log file and terminal recording for a session.
 func removeSessionLog(sessionID string) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return
 	}
 	os.Remove(filepath.Join(home, ".codeburg", "logs", "sessions", sessionID+".jsonl"))
+	os.Remove(sessionRecordingPath(sessionID))
 }
 
 func withClaudeSessionStartLock(workDir string, fn func() error) error {
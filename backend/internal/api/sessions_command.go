@@ -36,7 +36,10 @@ func shellQuote(s string) string {
 	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
 }
 
-func buildSessionCommand(req StartSessionRequest, notifyScript, resumeProviderSessionID string, autoApprove bool) (string, []string) {
+// buildSessionCommand returns the command line for a terminal session.
+// systemPrompt is the project's agent prelude: claude takes it as an appended
+// system prompt, codex has no such flag so it is prepended to the prompt.
+func buildSessionCommand(req StartSessionRequest, notifyScript, resumeProviderSessionID string, autoApprove bool, systemPrompt string) (string, []string) {
 	switch req.Provider {
 	case "claude":
 		args := []string{}
@@ -46,6 +49,9 @@ func buildSessionCommand(req StartSessionRequest, notifyScript, resumeProviderSe
 		if req.Model != "" {
 			args = append(args, "--model", req.Model)
 		}
+		if systemPrompt != "" {
+			args = append(args, "--append-system-prompt", systemPrompt)
+		}
 		if req.ResumeSessionID != "" {
 			if resumeProviderSessionID != "" {
 				args = append(args, "--resume", resumeProviderSessionID)
@@ -69,8 +75,8 @@ func buildSessionCommand(req StartSessionRequest, notifyScript, resumeProviderSe
 		if notifyScript != "" {
 			args = append(args, "-c", fmt.Sprintf(`notify=["%s"]`, notifyScript))
 		}
-		if req.Prompt != "" {
-			args = append(args, req.Prompt)
+		if prompt := withPrelude(systemPrompt, req.Prompt); prompt != "" {
+			args = append(args, prompt)
 		}
 		return "codex", args
 
@@ -89,28 +95,41 @@ func buildSessionCommand(req StartSessionRequest, notifyScript, resumeProviderSe
 	}
 }
 
-func buildChatTurnCommand(provider, prompt, model, providerSessionID string, autoApprove bool) (string, []string, error) {
+// buildChatTurnCommand returns the command line for one chat turn. The
+// systemPrompt prelude is passed to codex only on the first turn of a thread.
+// A readOnly turn may read and search files but not edit them or run
+// commands, whatever autoApprove says.
+func buildChatTurnCommand(provider, prompt, model, providerSessionID string, autoApprove, readOnly bool, systemPrompt string) (string, []string, error) {
 	switch provider {
 	case "claude":
 		args := []string{"--print", "--output-format", "stream-json", "--verbose"}
-		if autoApprove {
+		if readOnly {
+			args = append(args,
+				"--allowedTools", strings.Join(claudeReadOnlyTools, ","),
+				"--disallowedTools", strings.Join(claudeWriteTools, ","),
+			)
+		} else if autoApprove {
 			args = append(args, "--dangerously-skip-permissions")
 		}
 		if model != "" {
 			args = append(args, "--model", model)
 		}
+		if systemPrompt != "" {
+			args = append(args, "--append-system-prompt", systemPrompt)
+		}
 		if providerSessionID != "" {
 			args = append(args, "--resume", providerSessionID)
 		}
-		args = append(args, prompt)
+		if chatPromptOnStdin(provider, autoApprove) {
+			args = append(args, "--input-format", "stream-json", "--permission-prompt-tool", "stdio")
+		} else {
+			args = append(args, prompt)
+		}
 		return "claude", args, nil
 
 	case "codex":
 		if providerSessionID != "" {
-			args := []string{"exec", "resume", "--json"}
-			if autoApprove {
-				args = append(args, "--full-auto")
-			}
+			args := append([]string{"exec", "resume", "--json"}, codexApprovalArgs(autoApprove, readOnly)...)
 			if model != "" {
 				args = append(args, "--model", model)
 			}
@@ -118,14 +137,11 @@ func buildChatTurnCommand(provider, prompt, model, providerSessionID string, aut
 			return "codex", args, nil
 		}
 
-		args := []string{"exec", "--json"}
-		if autoApprove {
-			args = append(args, "--full-auto")
-		}
+		args := append([]string{"exec", "--json"}, codexApprovalArgs(autoApprove, readOnly)...)
 		if model != "" {
 			args = append(args, "--model", model)
 		}
-		args = append(args, prompt)
+		args = append(args, withPrelude(systemPrompt, prompt))
 		return "codex", args, nil
 
 	default:
@@ -133,6 +149,41 @@ func buildChatTurnCommand(provider, prompt, model, providerSessionID string, aut
 	}
 }
 
+var (
+	// claudeReadOnlyTools are the Claude tools a read-only turn may use.
+	claudeReadOnlyTools = []string{"Read", "Grep", "Glob", "LS"}
+	// claudeWriteTools are denied outright in a read-only turn, so a print
+	// run doesn't stall asking for them.
+	claudeWriteTools = []string{"Bash", "Edit", "MultiEdit", "Write", "NotebookEdit"}
+)
+
+func codexApprovalArgs(autoApprove, readOnly bool) []string {
+	switch {
+	case readOnly:
+		return []string{"--sandbox", "read-only"}
+	case autoApprove:
+		return []string{"--full-auto"}
+	}
+	return nil
+}
+
+// chatPromptOnStdin reports whether a chat turn sends its prompt over stdin
+// as stream-json rather than as an argument. Claude turns without
+// auto-approval do, so that their permission requests can be answered on
+// the same pipe.
+func chatPromptOnStdin(provider string, autoApprove bool) bool {
+	return provider == "claude" && !autoApprove
+}
+
+// withPrelude prepends the agent prelude to a prompt. An empty prompt stays
+// empty so an interactive session does not start working on its own.
+func withPrelude(prelude, prompt string) string {
+	if prelude == "" || prompt == "" {
+		return prompt
+	}
+	return prelude + "\n\n" + prompt
+}
+
 func buildInteractiveShellCommand() (string, []string) {
 	shell := os.Getenv("SHELL")
 	if shell == "" {
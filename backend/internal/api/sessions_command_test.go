@@ -3,6 +3,8 @@ package api
 import (
 	"strings"
 	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
 )
 
 func containsArg(args []string, want string) bool {
@@ -15,31 +17,31 @@ func containsArg(args []string, want string) bool {
 }
 
 func TestBuildSessionCommand_AutoApproveOff(t *testing.T) {
-	_, claudeArgs := buildSessionCommand(StartSessionRequest{Provider: "claude"}, "", "", false)
+	_, claudeArgs := buildSessionCommand(StartSessionRequest{Provider: "claude"}, "", "", false, "")
 	if containsArg(claudeArgs, "--dangerously-skip-permissions") {
 		t.Fatalf("expected claude without auto-approve flag, got args %v", claudeArgs)
 	}
 
-	_, codexArgs := buildSessionCommand(StartSessionRequest{Provider: "codex"}, "", "", false)
+	_, codexArgs := buildSessionCommand(StartSessionRequest{Provider: "codex"}, "", "", false, "")
 	if containsArg(codexArgs, "--dangerously-bypass-approvals-and-sandbox") {
 		t.Fatalf("expected codex without auto-approve flag, got args %v", codexArgs)
 	}
 }
 
 func TestBuildSessionCommand_AutoApproveOn(t *testing.T) {
-	_, claudeArgs := buildSessionCommand(StartSessionRequest{Provider: "claude"}, "", "", true)
+	_, claudeArgs := buildSessionCommand(StartSessionRequest{Provider: "claude"}, "", "", true, "")
 	if !containsArg(claudeArgs, "--dangerously-skip-permissions") {
 		t.Fatalf("expected claude auto-approve flag, got args %v", claudeArgs)
 	}
 
-	_, codexArgs := buildSessionCommand(StartSessionRequest{Provider: "codex"}, "", "", true)
+	_, codexArgs := buildSessionCommand(StartSessionRequest{Provider: "codex"}, "", "", true, "")
 	if !containsArg(codexArgs, "--dangerously-bypass-approvals-and-sandbox") {
 		t.Fatalf("expected codex auto-approve flag, got args %v", codexArgs)
 	}
 }
 
 func TestBuildChatTurnCommand_Claude(t *testing.T) {
-	command, args, err := buildChatTurnCommand("claude", "fix tests", "claude-sonnet", "provider-session-1", true)
+	command, args, err := buildChatTurnCommand("claude", "fix tests", "claude-sonnet", "provider-session-1", true, false, "")
 	if err != nil {
 		t.Fatalf("buildChatTurnCommand: %v", err)
 	}
@@ -61,7 +63,7 @@ func TestBuildChatTurnCommand_Claude(t *testing.T) {
 }
 
 func TestBuildChatTurnCommand_CodexResume(t *testing.T) {
-	command, args, err := buildChatTurnCommand("codex", "continue", "gpt-5-codex", "session-123", true)
+	command, args, err := buildChatTurnCommand("codex", "continue", "gpt-5-codex", "session-123", true, false, "")
 	if err != nil {
 		t.Fatalf("buildChatTurnCommand: %v", err)
 	}
@@ -80,15 +82,18 @@ func TestBuildChatTurnCommand_CodexResume(t *testing.T) {
 }
 
 func TestBuildChatTurnCommand_AutoApproveOff(t *testing.T) {
-	_, claudeArgs, err := buildChatTurnCommand("claude", "hi", "", "", false)
+	_, claudeArgs, err := buildChatTurnCommand("claude", "hi", "", "", false, false, "")
 	if err != nil {
 		t.Fatalf("buildChatTurnCommand(claude): %v", err)
 	}
 	if containsArg(claudeArgs, "--dangerously-skip-permissions") {
 		t.Fatalf("expected claude chat auto-approval disabled, got args %v", claudeArgs)
 	}
+	if !containsArg(claudeArgs, "--permission-prompt-tool") || !containsArg(claudeArgs, "--input-format") || containsArg(claudeArgs, "hi") {
+		t.Fatalf("expected claude to take its prompt and permission answers on stdin, got args %v", claudeArgs)
+	}
 
-	_, codexArgs, err := buildChatTurnCommand("codex", "hi", "", "", false)
+	_, codexArgs, err := buildChatTurnCommand("codex", "hi", "", "", false, false, "")
 	if err != nil {
 		t.Fatalf("buildChatTurnCommand(codex): %v", err)
 	}
@@ -97,6 +102,29 @@ func TestBuildChatTurnCommand_AutoApproveOff(t *testing.T) {
 	}
 }
 
+func TestBuildChatTurnCommand_ReadOnly(t *testing.T) {
+	_, claudeArgs, err := buildChatTurnCommand("claude", "hi", "", "", true, true, "")
+	if err != nil {
+		t.Fatalf("buildChatTurnCommand(claude): %v", err)
+	}
+	if containsArg(claudeArgs, "--dangerously-skip-permissions") || !containsArg(claudeArgs, "--disallowedTools") {
+		t.Fatalf("expected claude limited to read-only tools, got args %v", claudeArgs)
+	}
+	if !containsArg(claudeArgs, "Read,Grep,Glob,LS") || !containsArg(claudeArgs, "hi") {
+		t.Fatalf("expected read tools allowed and the prompt as an argument, got args %v", claudeArgs)
+	}
+
+	for _, resume := range []string{"", "thread-1"} {
+		_, codexArgs, err := buildChatTurnCommand("codex", "hi", "", resume, true, true, "")
+		if err != nil {
+			t.Fatalf("buildChatTurnCommand(codex): %v", err)
+		}
+		if containsArg(codexArgs, "--full-auto") || !containsArg(codexArgs, "read-only") {
+			t.Fatalf("expected codex in a read-only sandbox, got args %v", codexArgs)
+		}
+	}
+}
+
 func TestResolveSessionType_Defaults(t *testing.T) {
 	if got := resolveSessionType(StartSessionRequest{Provider: "claude"}); got != "chat" {
 		t.Fatalf("expected claude default chat, got %q", got)
@@ -118,7 +146,7 @@ func TestBuildSessionCommand_TerminalPromptKeepsShellOpen(t *testing.T) {
 	command, args := buildSessionCommand(StartSessionRequest{
 		Provider: "terminal",
 		Prompt:   "just test",
-	}, "", "", false)
+	}, "", "", false, "")
 
 	if command != "/bin/zsh" {
 		t.Fatalf("expected shell command /bin/zsh, got %q", command)
@@ -133,3 +161,55 @@ func TestBuildSessionCommand_TerminalPromptKeepsShellOpen(t *testing.T) {
 		t.Fatalf("expected interactive shell handoff after prompt, got %q", args[1])
 	}
 }
+
+func TestBuildCommands_AgentPrelude(t *testing.T) {
+	prelude := "Follow the repo's CONTRIBUTING.md."
+
+	_, claudeArgs := buildSessionCommand(StartSessionRequest{Provider: "claude", Prompt: "fix it"}, "", "", false, prelude)
+	if !containsArg(claudeArgs, "--append-system-prompt") || !containsArg(claudeArgs, prelude) {
+		t.Fatalf("expected claude to get the prelude as system prompt, got %v", claudeArgs)
+	}
+	if claudeArgs[len(claudeArgs)-1] != "fix it" {
+		t.Fatalf("expected the prompt to stay unchanged, got %v", claudeArgs)
+	}
+
+	_, codexArgs := buildSessionCommand(StartSessionRequest{Provider: "codex", Prompt: "fix it"}, "", "", false, prelude)
+	if got := codexArgs[len(codexArgs)-1]; got != prelude+"\n\nfix it" {
+		t.Fatalf("expected codex prompt to start with the prelude, got %q", got)
+	}
+	_, codexArgs = buildSessionCommand(StartSessionRequest{Provider: "codex"}, "", "", false, prelude)
+	if len(codexArgs) != 0 {
+		t.Fatalf("expected no prompt for an interactive codex session, got %v", codexArgs)
+	}
+
+	_, chatArgs, _ := buildChatTurnCommand("codex", "next", "", "", false, false, prelude)
+	if got := chatArgs[len(chatArgs)-1]; got != prelude+"\n\nnext" {
+		t.Fatalf("expected first codex turn to carry the prelude, got %q", got)
+	}
+	_, chatArgs, _ = buildChatTurnCommand("codex", "next", "", "thread-1", false, false, prelude)
+	if got := chatArgs[len(chatArgs)-1]; got != "next" {
+		t.Fatalf("expected resumed codex turn without prelude, got %q", got)
+	}
+	_, chatArgs, _ = buildChatTurnCommand("claude", "next", "", "thread-1", false, false, prelude)
+	if !containsArg(chatArgs, prelude) {
+		t.Fatalf("expected every claude turn to carry the prelude, got %v", chatArgs)
+	}
+}
+
+func TestApplyAgentInstructionDefaults(t *testing.T) {
+	off := false
+	project := &db.Project{AgentInstructions: &db.AgentInstructions{DefaultModel: "gpt-5-codex", DefaultAutoApprove: &off}}
+
+	req := StartSessionRequest{Provider: "codex"}
+	applyAgentInstructionDefaults(&req, project)
+	if req.Model != "gpt-5-codex" || resolveAutoApprove(req) {
+		t.Fatalf("expected project defaults, got model %q autoApprove %v", req.Model, resolveAutoApprove(req))
+	}
+
+	on := true
+	req = StartSessionRequest{Provider: "codex", Model: "o3", AutoApprove: &on}
+	applyAgentInstructionDefaults(&req, project)
+	if req.Model != "o3" || !resolveAutoApprove(req) {
+		t.Fatalf("expected explicit request values to win, got model %q autoApprove %v", req.Model, resolveAutoApprove(req))
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// settingsBundleVersion is bumped when the bundle format changes
+// incompatibly.
+const settingsBundleVersion = 1
+
+// nonPortablePreferences are tied to this instance and are left out of
+// exports: Web Push subscriptions are bound to the instance's VAPID key.
+var nonPortablePreferences = []string{webPushPreference}
+
+// SettingsBundle is a portable export of the user's preferences. It contains
+// secrets such as bot and forge tokens and should be stored accordingly.
+type SettingsBundle struct {
+	Version     int                        `json:"version"`
+	ExportedAt  time.Time                  `json:"exportedAt"`
+	Preferences map[string]json.RawMessage `json:"preferences"`
+}
+
+func (s *Server) handleExportSettings(w http.ResponseWriter, r *http.Request) {
+	prefs, err := s.db.ListPreferences(db.DefaultUserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list preferences")
+		return
+	}
+
+	bundle := SettingsBundle{
+		Version:     settingsBundleVersion,
+		ExportedAt:  time.Now().UTC(),
+		Preferences: make(map[string]json.RawMessage, len(prefs)),
+	}
+	for _, pref := range prefs {
+		if slices.Contains(nonPortablePreferences, pref.Key) || !json.Valid([]byte(pref.Value)) {
+			continue
+		}
+		bundle.Preferences[pref.Key] = json.RawMessage(pref.Value)
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="codeburg-settings.json"`)
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+func (s *Server) handleImportSettings(w http.ResponseWriter, r *http.Request) {
+	var bundle SettingsBundle
+	if err := decodeJSON(r, &bundle); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid settings bundle")
+		return
+	}
+	if bundle.Version != settingsBundleVersion {
+		writeError(w, http.StatusBadRequest, "unsupported settings bundle version")
+		return
+	}
+
+	values := make(map[string]string, len(bundle.Preferences))
+	for key, value := range bundle.Preferences {
+		if key == "" {
+			writeError(w, http.StatusBadRequest, "preference keys must not be empty")
+			return
+		}
+		if slices.Contains(nonPortablePreferences, key) {
+			continue
+		}
+		values[key] = string(value)
+	}
+
+	if err := s.db.SetPreferences(db.DefaultUserID, values); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to import preferences")
+		return
+	}
+
+	// The bot only reads its token at startup.
+	if _, ok := values["telegram_bot_token"]; ok {
+		s.startTelegramBot()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"imported": len(values)})
+}
+
+// SetupStep is one item of the first-run checklist.
+type SetupStep struct {
+	ID         string `json:"id"`
+	Configured bool   `json:"configured"`
+	Required   bool   `json:"required"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+type SetupStatusResponse struct {
+	// Complete is true once every required step is configured.
+	Complete bool        `json:"complete"`
+	Steps    []SetupStep `json:"steps"`
+}
+
+func (s *Server) handleSetupStatus(w http.ResponseWriter, r *http.Request) {
+	steps := []SetupStep{
+		s.setupStepAuth(),
+		s.setupStepOrigin(),
+		s.setupStepTelegram(),
+		s.setupStepLLM(),
+		s.setupStepTranscription(),
+		s.setupStepTunnel(),
+	}
+
+	complete := true
+	for _, step := range steps {
+		if step.Required && !step.Configured {
+			complete = false
+		}
+	}
+	writeJSON(w, http.StatusOK, SetupStatusResponse{Complete: complete, Steps: steps})
+}
+
+func (s *Server) setupStepAuth() SetupStep {
+	step := SetupStep{ID: "auth", Configured: s.auth.IsSetup(), Required: true}
+	if passkeys, _ := s.db.ListPasskeys(); len(passkeys) > 0 {
+		step.Detail = "password and passkeys"
+	} else if step.Configured {
+		step.Detail = "password only; add a passkey for faster login"
+	}
+	return step
+}
+
+// setupStepOrigin reports the public origin, which passkeys and Telegram
+// login links depend on.
+func (s *Server) setupStepOrigin() SetupStep {
+	step := SetupStep{ID: "origin"}
+	if config, err := s.auth.loadConfig(); err == nil && config.Auth.Origin != "" {
+		step.Configured = true
+		step.Detail = config.Auth.Origin
+	}
+	return step
+}
+
+func (s *Server) setupStepTelegram() SetupStep {
+	step := SetupStep{ID: "telegram"}
+	hasToken := false
+	if pref, err := s.db.GetPreference(db.DefaultUserID, "telegram_bot_token"); err == nil {
+		hasToken = unquotePreference(pref.Value) != ""
+	}
+	_, hasUser := s.telegramChatID()
+
+	switch {
+	case !hasToken:
+		step.Detail = "set telegram_bot_token"
+	case !hasUser:
+		step.Detail = "set telegram_user_id"
+	case s.currentTelegramBot() == nil:
+		step.Detail = "bot is not running; check the token and restart it"
+	default:
+		step.Configured = true
+	}
+	return step
+}
+
+// setupStepLLM reports whether the built-in language model features have a
+// server: one set in the llm preference, or OpenAI with a key.
+func (s *Server) setupStepLLM() SetupStep {
+	step := SetupStep{ID: "llm"}
+	if cfg, err := s.llmConfig(); err != nil {
+		step.Detail = err.Error()
+	} else if cfg.URL != "" {
+		step.Configured = true
+		step.Detail = "llm preference: " + cfg.URL
+	} else if pref, err := s.db.GetPreference(db.DefaultUserID, "openai_api_key"); err == nil && unquotePreference(pref.Value) != "" {
+		step.Configured = true
+		step.Detail = "openai_api_key preference"
+	} else if os.Getenv("OPENAI_API_KEY") != "" {
+		step.Configured = true
+		step.Detail = "OPENAI_API_KEY environment variable"
+	}
+	return step
+}
+
+// setupStepTranscription reports whether Telegram voice notes can be
+// transcribed.
+func (s *Server) setupStepTranscription() SetupStep {
+	step := SetupStep{ID: "transcription"}
+	transcriber, _, err := s.transcriber()
+	if err != nil {
+		step.Detail = err.Error()
+		return step
+	}
+	step.Configured = true
+	step.Detail = transcriber.Name()
+	return step
+}
+
+func (s *Server) setupStepTunnel() SetupStep {
+	step := SetupStep{ID: "tunnel", Configured: s.tunnels.Available()}
+	if !step.Configured {
+		step.Detail = "install cloudflared, tailscale or ngrok to share ports"
+	}
+	return step
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestSettingsExportImport(t *testing.T) {
+	src := setupTestEnv(t)
+	src.setup("testpass123")
+	src.server.db.SetPreference(db.DefaultUserID, "pinned_projects", `["p1"]`)
+	src.server.db.SetPreference(db.DefaultUserID, "ntfy", `{"topic":"alerts"}`)
+	src.server.db.SetPreference(db.DefaultUserID, webPushPreference, `[]`)
+
+	resp := src.get("/api/settings/export")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var bundle SettingsBundle
+	decodeResponse(t, resp, &bundle)
+	if bundle.Version != settingsBundleVersion || len(bundle.Preferences) != 2 {
+		t.Fatalf("unexpected bundle: %+v", bundle)
+	}
+	if _, ok := bundle.Preferences[webPushPreference]; ok {
+		t.Error("expected web push subscriptions to be left out")
+	}
+
+	dst := setupTestEnv(t)
+	dst.setup("testpass123")
+	resp = dst.post("/api/settings/import", bundle)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	pref, err := dst.server.db.GetPreference(db.DefaultUserID, "ntfy")
+	if err != nil || pref.Value != `{"topic":"alerts"}` {
+		t.Errorf("expected imported ntfy preference, got %+v (%v)", pref, err)
+	}
+
+	bundle.Version = 99
+	if resp := dst.post("/api/settings/import", bundle); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown bundle version, got %d", resp.Code)
+	}
+}
+
+func TestSetupStatus(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var status SetupStatusResponse
+	decodeResponse(t, env.get("/api/setup/status"), &status)
+	if !status.Complete {
+		t.Error("expected setup to be complete once auth is configured")
+	}
+	steps := make(map[string]SetupStep)
+	for _, step := range status.Steps {
+		steps[step.ID] = step
+	}
+	if !steps["auth"].Configured || steps["telegram"].Configured || steps["llm"].Configured {
+		t.Errorf("unexpected steps: %+v", status.Steps)
+	}
+
+	value, _ := json.Marshal("sk-test")
+	env.server.db.SetPreference(db.DefaultUserID, "openai_api_key", string(value))
+	decodeResponse(t, env.get("/api/setup/status"), &status)
+	for _, step := range status.Steps {
+		if step.ID == "llm" && !step.Configured {
+			t.Error("expected llm step to be configured")
+		}
+	}
+}
@@ -2,11 +2,10 @@ package api
 
 import (
 	"encoding/json"
-	"log/slog"
 	"net/http"
+	"slices"
 	"sort"
 	"sync"
-	"time"
 
 	"github.com/miguel-bm/codeburg/internal/db"
 )
@@ -48,48 +47,6 @@ type SidebarSession struct {
 	Number   int              `json:"number"`
 }
 
-// diffStatsCache is an in-memory cache for diff stats with TTL
-type diffStatsCacheEntry struct {
-	stats     *DiffStats
-	expiresAt time.Time
-}
-
-// getCachedDiffStats returns cached diff stats for a task, computing if expired/missing.
-// Returns nil on error (non-fatal).
-func (s *Server) getCachedDiffStats(task *db.Task) *DiffStats {
-	if task.WorktreePath == nil || *task.WorktreePath == "" {
-		return nil
-	}
-
-	// Check cache
-	if cached, ok := s.diffStatsCache.Load(task.ID); ok {
-		if entry, ok := cached.(diffStatsCacheEntry); ok {
-			if time.Now().Before(entry.expiresAt) {
-				return entry.stats
-			}
-		}
-	}
-
-	// Compute
-	proj, err := s.db.GetProject(task.ProjectID)
-	if err != nil {
-		return nil
-	}
-
-	additions, deletions, err := s.worktree.DiffStats(*task.WorktreePath, proj.DefaultBranch)
-	if err != nil {
-		slog.Debug("diff stats failed", "task_id", task.ID, "error", err)
-		return nil
-	}
-
-	stats := &DiffStats{Additions: additions, Deletions: deletions}
-	s.diffStatsCache.Store(task.ID, diffStatsCacheEntry{
-		stats:     stats,
-		expiresAt: time.Now().Add(30 * time.Second),
-	})
-	return stats
-}
-
 // handleSidebar returns aggregated sidebar data
 func (s *Server) handleSidebar(w http.ResponseWriter, r *http.Request) {
 	// 1. Load all projects
@@ -98,6 +55,7 @@ func (s *Server) handleSidebar(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to list projects")
 		return
 	}
+	projects = slices.DeleteFunc(projects, func(p *db.Project) bool { return p.ArchivedAt != nil })
 
 	// Load pinned projects preference
 	pinnedSet := make(map[string]bool)
@@ -110,9 +68,9 @@ func (s *Server) handleSidebar(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// 2. Load tasks with status in (in_progress, in_review)
+	// 2. Load tasks in in_progress or in_review columns
 	tasks, err := s.db.ListTasks(db.TaskFilter{
-		Statuses: []db.TaskStatus{db.TaskStatusInProgress, db.TaskStatusInReview},
+		Categories: []db.TaskStatus{db.TaskStatusInProgress, db.TaskStatusInReview},
 	})
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to list tasks")
@@ -166,15 +124,9 @@ func (s *Server) handleSidebar(w http.ResponseWriter, r *http.Request) {
 		if t.WorktreePath == nil {
 			continue
 		}
-
-		// Check cache first
-		if cached, ok := s.diffStatsCache.Load(t.ID); ok {
-			if entry, ok := cached.(diffStatsCacheEntry); ok {
-				if time.Now().Before(entry.expiresAt) {
-					diffCh <- diffResult{taskID: t.ID, stats: entry.stats}
-					continue
-				}
-			}
+		proj := projectMap[t.ProjectID]
+		if proj == nil {
+			continue
 		}
 
 		diffWg.Add(1)
@@ -183,23 +135,13 @@ func (s *Server) handleSidebar(w http.ResponseWriter, r *http.Request) {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			proj := projectMap[task.ProjectID]
-			if proj == nil {
+			stats, changed := s.taskDiffStats(task, baseBranchFor(task, proj))
+			if stats == nil {
 				return
 			}
-
-			additions, deletions, err := s.worktree.DiffStats(*task.WorktreePath, proj.DefaultBranch)
-			if err != nil {
-				slog.Debug("diff stats failed", "task_id", task.ID, "error", err)
-				return
+			if changed {
+				s.broadcastDiffStats(task, stats)
 			}
-
-			stats := &DiffStats{Additions: additions, Deletions: deletions}
-			// Cache for 30 seconds
-			s.diffStatsCache.Store(task.ID, diffStatsCacheEntry{
-				stats:     stats,
-				expiresAt: time.Now().Add(30 * time.Second),
-			})
 			diffCh <- diffResult{taskID: task.ID, stats: stats}
 		}(t)
 	}
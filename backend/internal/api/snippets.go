@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+const (
+	// maxSnippetBytes caps one pasted snippet.
+	maxSnippetBytes = 64 * 1024
+	// New sessions take at most snippetPromptLimit unconsumed snippets from
+	// the last snippetPromptMaxAge, up to maxSnippetPromptBytes in total.
+	snippetPromptLimit    = 10
+	snippetPromptMaxAge   = 7 * 24 * time.Hour
+	maxSnippetPromptBytes = 128 * 1024
+)
+
+type createSnippetRequest struct {
+	Content string `json:"content"`
+}
+
+func (s *Server) handleListSnippets(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	snippets, err := s.db.ListSnippets(taskID, r.URL.Query().Get("unconsumed") == "true")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list snippets")
+		return
+	}
+	writeJSON(w, http.StatusOK, snippets)
+}
+
+// handleCreateSnippet pastes text into a task's inbox.
+func (s *Server) handleCreateSnippet(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	var req createSnippetRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		writeError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+	if len(content) > maxSnippetBytes {
+		writeError(w, http.StatusBadRequest, "content exceeds 64 KiB limit")
+		return
+	}
+
+	snippet, err := s.db.CreateSnippet(taskID, content)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create snippet")
+		return
+	}
+	s.wsHub.BroadcastToTask(taskID, "snippet_added", snippet)
+	writeJSON(w, http.StatusCreated, snippet)
+}
+
+func (s *Server) handleDeleteSnippet(w http.ResponseWriter, r *http.Request) {
+	snippet, err := s.db.GetSnippet(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "snippet")
+		return
+	}
+	if err := s.db.DeleteSnippet(snippet.ID); err != nil {
+		writeDBError(w, err, "snippet")
+		return
+	}
+	s.wsHub.BroadcastToTask(snippet.TaskID, "snippet_deleted", map[string]string{"id": snippet.ID})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// inboxSnippets returns a task's recent unconsumed snippets, oldest first,
+// as they should go into a new session's prompt.
+func (s *Server) inboxSnippets(taskID string) []*db.Snippet {
+	snippets, err := s.db.ListSnippets(taskID, true)
+	if err != nil {
+		slog.Warn("failed to list task snippets", "task_id", taskID, "error", err)
+		return nil
+	}
+	cutoff := time.Now().Add(-snippetPromptMaxAge)
+	var picked []*db.Snippet
+	size := 0
+	for _, snippet := range snippets {
+		if len(picked) == snippetPromptLimit || snippet.CreatedAt.Before(cutoff) {
+			break
+		}
+		if size += len(snippet.Content); size > maxSnippetPromptBytes {
+			break
+		}
+		picked = append(picked, snippet)
+	}
+	// Listed newest first; paste them in the order they arrived.
+	for i, j := 0, len(picked)-1; i < j; i, j = i+1, j-1 {
+		picked[i], picked[j] = picked[j], picked[i]
+	}
+	return picked
+}
+
+// withInboxSnippets appends snippets to a session's initial prompt.
+func withInboxSnippets(prompt string, snippets []*db.Snippet) string {
+	if len(snippets) == 0 {
+		return prompt
+	}
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\n---\nThe user pasted the following into this task's inbox:")
+	for i, snippet := range snippets {
+		fmt.Fprintf(&b, "\n\n[Snippet %d]\n%s", i+1, snippet.Content)
+	}
+	return b.String()
+}
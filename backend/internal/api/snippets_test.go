@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestSnippetInbox(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	installFakeClaude(t)
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	project := createWorkspaceProject(t, env)
+	task, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "inbox"})
+	url := "/api/tasks/" + task.ID + "/snippets"
+
+	if resp := env.post(url, createSnippetRequest{Content: "  "}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty snippet, got %d", resp.Code)
+	}
+	if resp := env.post(url, createSnippetRequest{Content: strings.Repeat("x", maxSnippetBytes+1)}); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an oversized snippet, got %d", resp.Code)
+	}
+	for _, content := range []string{"panic: nil map\n  at main.go:12", "https://example.com/issue/1"} {
+		if resp := env.post(url, createSnippetRequest{Content: content}); resp.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	// A session started without a prompt leaves the inbox alone.
+	if resp := env.post("/api/tasks/"+task.ID+"/sessions", StartSessionRequest{Provider: "claude"}); resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var pending []db.Snippet
+	decodeResponse(t, env.get(url+"?unconsumed=true"), &pending)
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 unconsumed snippets, got %d", len(pending))
+	}
+
+	resp := env.post("/api/tasks/"+task.ID+"/sessions", StartSessionRequest{Provider: "claude", Prompt: "fix the crash"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var session db.AgentSession
+	decodeResponse(t, resp, &session)
+
+	var prompt string
+	deadline := time.Now().Add(5 * time.Second)
+	for prompt == "" && time.Now().Before(deadline) {
+		rows, _ := env.server.db.ListAgentMessagesBySession(session.ID)
+		for _, row := range rows {
+			if msg, ok := chatMessageFromRow(row, "claude"); ok && msg.Kind == ChatMessageKindUserText {
+				prompt = msg.Text
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	first, second := strings.Index(prompt, "panic: nil map"), strings.Index(prompt, "https://example.com/issue/1")
+	if !strings.HasPrefix(prompt, "fix the crash\n") || first < 0 || second < first {
+		t.Fatalf("expected the snippets appended oldest first, got %q", prompt)
+	}
+
+	decodeResponse(t, env.get(url+"?unconsumed=true"), &pending)
+	if len(pending) != 0 {
+		t.Fatalf("expected the snippets consumed, got %+v", pending)
+	}
+	var all []db.Snippet
+	decodeResponse(t, env.get(url), &all)
+	if len(all) != 2 || all[0].ConsumedSessionID == nil || *all[0].ConsumedSessionID != session.ID {
+		t.Fatalf("expected consumed snippets listed with their session, got %+v", all)
+	}
+
+	if resp := env.delete("/api/snippets/" + all[0].ID); resp.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.Code)
+	}
+	if resp := env.delete("/api/snippets/" + all[0].ID); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/notify"
+	"github.com/miguel-bm/codeburg/internal/version"
+)
+
+// Update check preference:
+//
+//	update_check  {"enabled": true}
+//
+// When enabled, the server asks GitHub for the latest Codeburg release at
+// start and then daily, and tells every notification channel once about
+// each release newer than the running one. Off by default. Builds from
+// source ("dev") see the latest release but are not told about it. The
+// release last notified is kept in a preference, so a restart doesn't repeat
+// it.
+const (
+	updateCheckPreference    = "update_check"
+	updateNotifiedPreference = "update_notified"
+	updateCheckInterval      = 24 * time.Hour
+	updateCheckTimeout       = 30 * time.Second
+)
+
+type updateCheckSettings struct {
+	Enabled bool `json:"enabled"`
+}
+
+// updateStatus is what the server knows about newer releases.
+type updateStatus struct {
+	Enabled   bool             `json:"enabled"`
+	CheckedAt *time.Time       `json:"checkedAt,omitempty"`
+	Latest    *version.Release `json:"latest,omitempty"`
+	Available bool             `json:"available"` // Latest is newer than the running version
+	Error     string           `json:"error,omitempty"`
+}
+
+func (s *Server) updateCheckEnabled() bool {
+	var settings updateCheckSettings
+	if pref, err := s.db.GetPreference(db.DefaultUserID, updateCheckPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+			slog.Warn("invalid update_check preference", "error", err)
+		}
+	}
+	return settings.Enabled
+}
+
+// handleGetVersion returns the running build and, once checked, the latest
+// release. check=true checks now, whether or not daily checks are enabled.
+func (s *Server) handleGetVersion(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("check") == "true" {
+		ctx, cancel := context.WithTimeout(r.Context(), updateCheckTimeout)
+		defer cancel()
+		s.checkForUpdate(ctx)
+	}
+	status := s.updateStatus()
+	writeJSON(w, http.StatusOK, struct {
+		version.Info
+		Update updateStatus `json:"update"`
+	}{version.Get(), status})
+}
+
+func (s *Server) updateStatus() updateStatus {
+	s.updateMu.Lock()
+	status := s.update
+	s.updateMu.Unlock()
+	status.Enabled = s.updateCheckEnabled()
+	return status
+}
+
+// runUpdateCheck checks for a new release at start and every
+// updateCheckInterval while the update_check preference enables it.
+func (s *Server) runUpdateCheck(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if s.updateCheckEnabled() {
+			s.updateMu.Lock()
+			due := s.update.CheckedAt == nil || time.Since(*s.update.CheckedAt) >= updateCheckInterval
+			s.updateMu.Unlock()
+			if due {
+				checkCtx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+				s.checkForUpdate(checkCtx)
+				cancel()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkForUpdate asks for the latest release, records it, and notifies
+// about it when it is newer than the running version and hasn't been
+// notified yet.
+func (s *Server) checkForUpdate(ctx context.Context) {
+	release, err := s.releases.Latest(ctx)
+	now := time.Now().UTC()
+
+	s.updateMu.Lock()
+	s.update.CheckedAt = &now
+	if err != nil {
+		s.update.Error = err.Error()
+	} else {
+		s.update.Error = ""
+		s.update.Latest = release
+		s.update.Available = version.Newer(release.Version, version.Version)
+	}
+	available := s.update.Available
+	s.updateMu.Unlock()
+
+	if err != nil {
+		slog.Warn("failed to check for a new release", "error", err)
+		return
+	}
+	if !available {
+		return
+	}
+	if pref, err := s.db.GetPreference(db.DefaultUserID, updateNotifiedPreference); err == nil && unquotePreference(pref.Value) == release.Version {
+		return
+	}
+	sinks := s.notificationSinks(eventUpdate)
+	if len(sinks) == 0 {
+		return
+	}
+	notified, _ := json.Marshal(release.Version)
+	if _, err := s.db.SetPreference(db.DefaultUserID, updateNotifiedPreference, string(notified)); err != nil {
+		slog.Warn("failed to record update notification", "error", err)
+		return
+	}
+	s.deliverLocalized(sinks, func(lang string) notify.Message {
+		return notify.Message{
+			Title: localize(lang, msgUpdateTitle, release.Version),
+			Body:  localize(lang, msgUpdateBody, version.Version),
+			URL:   release.URL,
+		}
+	})
+}
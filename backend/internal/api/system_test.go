@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/version"
+)
+
+func TestVersionAndUpdateCheck(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"tag_name": "v0.2.0",
+			"html_url": "https://github.com/" + version.Repository + "/releases/tag/v0.2.0",
+		})
+	}))
+	defer github.Close()
+	env.server.releases = &version.Releases{BaseURL: github.URL}
+
+	received := make(chan map[string]any, 2)
+	ntfy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ntfy.Close()
+	cfg, _ := json.Marshal(ntfyConfig{Server: ntfy.URL, Topic: "cb-alerts"})
+	env.server.db.SetPreference(db.DefaultUserID, ntfyPreference, string(cfg))
+
+	var info struct {
+		version.Info
+		Update updateStatus `json:"update"`
+	}
+	decodeResponse(t, env.get("/api/system/version"), &info)
+	if info.Version != version.Version || info.GoVersion == "" || info.Update.CheckedAt != nil || info.Update.Enabled {
+		t.Errorf("expected the build info and no check yet, got %+v", info)
+	}
+
+	previous := version.Version
+	version.Version = "v0.1.0"
+	defer func() { version.Version = previous }()
+
+	for range 2 {
+		decodeResponse(t, env.get("/api/system/version?check=true"), &info)
+		if !info.Update.Available || info.Update.Latest == nil || info.Update.Latest.Version != "v0.2.0" {
+			t.Fatalf("expected v0.2.0 available, got %+v", info.Update)
+		}
+	}
+
+	// Each release is notified once.
+	select {
+	case body := <-received:
+		if body["title"] != "Codeburg v0.2.0 is available" {
+			t.Errorf("unexpected ntfy payload: %v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an update notification")
+	}
+	select {
+	case body := <-received:
+		t.Errorf("expected a single notification, got another: %v", body)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// The running release is not an update.
+	version.Version = "v0.2.0"
+	decodeResponse(t, env.get("/api/system/version?check=true"), &info)
+	if info.Update.Available {
+		t.Errorf("expected no update for the latest release, got %+v", info.Update)
+	}
+}
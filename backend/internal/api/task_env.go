@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// taskEnvVarResponse is a task environment variable as shown to clients:
+// the values of secrets are left out.
+type taskEnvVarResponse struct {
+	Name   string  `json:"name"`
+	Value  *string `json:"value"`
+	Secret bool    `json:"secret"`
+}
+
+type taskEnvVarRequest struct {
+	Name  string  `json:"name"`
+	Value *string `json:"value"`
+	// Secret values are write-only; a secret sent without a value keeps the
+	// one stored.
+	Secret bool `json:"secret"`
+}
+
+func taskEnvResponse(vars []db.TaskEnvVar) []taskEnvVarResponse {
+	resp := make([]taskEnvVarResponse, len(vars))
+	for i, v := range vars {
+		resp[i] = taskEnvVarResponse{Name: v.Name, Secret: v.Secret}
+		if !v.Secret {
+			value := v.Value
+			resp[i].Value = &value
+		}
+	}
+	return resp
+}
+
+// withTaskOverrides appends a task's own variables to env, the entries for
+// its project, so that they win. Values may refer to the project's entries
+// as ${NAME}.
+func (s *Server) withTaskOverrides(taskID string, env []string) []string {
+	vars, err := s.db.ListTaskEnv(taskID)
+	if err != nil {
+		slog.Warn("failed to load task environment", "task_id", taskID, "error", err)
+		return env
+	}
+	if len(vars) == 0 {
+		return env
+	}
+	base := make(map[string]string, len(env))
+	for _, entry := range env {
+		if k, v, ok := strings.Cut(entry, "="); ok {
+			base[k] = v
+		}
+	}
+	for _, v := range vars {
+		env = append(env, v.Name+"="+os.Expand(v.Value, func(key string) string { return base[key] }))
+	}
+	return env
+}
+
+func (s *Server) handleGetTaskEnv(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	vars, err := s.db.ListTaskEnv(taskID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load task environment")
+		return
+	}
+	writeJSON(w, http.StatusOK, taskEnvResponse(vars))
+}
+
+// handleSetTaskEnv replaces a task's environment variables. New sessions,
+// chat turns and recipe runs pick them up; running terminals keep the
+// environment they started with.
+func (s *Server) handleSetTaskEnv(w http.ResponseWriter, r *http.Request) {
+	taskID := urlParam(r, "id")
+	if _, err := s.db.GetTask(taskID); err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	var req []taskEnvVarRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	current, err := s.db.ListTaskEnv(taskID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to load task environment")
+		return
+	}
+	stored := make(map[string]db.TaskEnvVar, len(current))
+	for _, v := range current {
+		stored[v.Name] = v
+	}
+
+	vars := make([]db.TaskEnvVar, 0, len(req))
+	seen := make(map[string]bool, len(req))
+	for _, v := range req {
+		name := strings.TrimSpace(v.Name)
+		if !provisionEnvNameRe.MatchString(name) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment variable name %q", v.Name))
+			return
+		}
+		if seen[name] {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("duplicate environment variable %q", name))
+			return
+		}
+		seen[name] = true
+
+		value := ptrToString(v.Value)
+		if v.Value == nil {
+			prev, ok := stored[name]
+			if !v.Secret || !ok || !prev.Secret {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("environment variable %q needs a value", name))
+				return
+			}
+			value = prev.Value
+		}
+		vars = append(vars, db.TaskEnvVar{Name: name, Value: value, Secret: v.Secret})
+	}
+
+	if err := s.db.SetTaskEnv(taskID, vars); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to save task environment")
+		return
+	}
+	writeJSON(w, http.StatusOK, taskEnvResponse(vars))
+}
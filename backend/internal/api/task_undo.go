@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+// taskUndoWindow is how long a status change can be undone.
+const taskUndoWindow = 30 * time.Second
+
+// taskStatusUndo records a status change and the automation it triggered.
+type taskStatusUndo struct {
+	FromStatus      db.TaskStatus
+	ToStatus        db.TaskStatus
+	FromPosition    int
+	WorktreeCreated bool   // worktree was auto-created by the change
+	BranchAdopted   bool   // worktree checked out an existing branch
+	SessionStarted  string // session ID auto-started by the workflow
+	PRCreated       string // PR URL auto-created by the workflow
+	Irreversible    string // reason the change cannot be undone, if any
+	expires         time.Time
+}
+
+// taskUndoStore holds the last status change per task in memory with a TTL.
+type taskUndoStore struct {
+	mu      sync.Mutex
+	entries map[string]taskStatusUndo // key: task ID
+}
+
+func newTaskUndoStore() *taskUndoStore {
+	return &taskUndoStore{
+		entries: make(map[string]taskStatusUndo),
+	}
+}
+
+func (us *taskUndoStore) record(taskID string, entry taskStatusUndo) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	now := time.Now()
+	for id, e := range us.entries {
+		if now.After(e.expires) {
+			delete(us.entries, id)
+		}
+	}
+	entry.expires = now.Add(taskUndoWindow)
+	us.entries[taskID] = entry
+}
+
+// take returns and removes the pending undo for a task, if it has not expired.
+func (us *taskUndoStore) take(taskID string) (taskStatusUndo, bool) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	entry, ok := us.entries[taskID]
+	delete(us.entries, taskID) // one-time use
+	if !ok || time.Now().After(entry.expires) {
+		return taskStatusUndo{}, false
+	}
+	return entry, true
+}
+
+type undoTaskStatusResponse struct {
+	Task       *db.Task `json:"task"`
+	RolledBack []string `json:"rolledBack"`
+	Skipped    []string `json:"skipped"`
+}
+
+// handleUndoTaskStatus reverts the last status change of a task within the
+// undo window. Automated side effects are rolled back only when that loses
+// no work: a started session is stopped, and an auto-created worktree is
+// removed only if it is clean and (for new branches) has no commits.
+func (s *Server) handleUndoTaskStatus(w http.ResponseWriter, r *http.Request) {
+	id := urlParam(r, "id")
+
+	task, err := s.db.GetTask(id)
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+
+	entry, ok := s.taskUndo.take(id)
+	if !ok {
+		writeError(w, http.StatusConflict, "nothing to undo")
+		return
+	}
+	if task.Status != entry.ToStatus {
+		writeError(w, http.StatusConflict, "task status changed since")
+		return
+	}
+	if entry.Irreversible != "" {
+		writeError(w, http.StatusConflict, "cannot undo: "+entry.Irreversible)
+		return
+	}
+
+	resp := undoTaskStatusResponse{RolledBack: []string{}, Skipped: []string{}}
+
+	if entry.SessionStarted != "" {
+		session, err := s.db.GetSession(entry.SessionStarted)
+		if err == nil && session.Status != db.SessionStatusCompleted && session.Status != db.SessionStatusError {
+			s.stopSession(session)
+			resp.RolledBack = append(resp.RolledBack, "session stopped")
+		}
+	}
+
+	if entry.PRCreated != "" {
+		resp.Skipped = append(resp.Skipped, "pull request left open: "+entry.PRCreated)
+	}
+
+	input := db.UpdateTaskInput{
+		Status:   &entry.FromStatus,
+		Position: &entry.FromPosition,
+	}
+
+	if entry.WorktreeCreated && task.WorktreePath != nil && *task.WorktreePath != "" {
+		project, err := s.db.GetProject(task.ProjectID)
+		if err != nil {
+			writeDBError(w, err, "project")
+			return
+		}
+		if reason := worktreeUndoBlocker(*task.WorktreePath, project.DefaultBranch, entry.BranchAdopted); reason != "" {
+			resp.Skipped = append(resp.Skipped, "worktree kept: "+reason)
+		} else if err := s.worktree.Delete(worktree.DeleteOptions{
+			ProjectPath:    project.Path,
+			WorktreePath:   *task.WorktreePath,
+			DeleteBranch:   !entry.BranchAdopted,
+			TeardownScript: ptrToString(project.TeardownScript),
+		}); err != nil {
+			resp.Skipped = append(resp.Skipped, "worktree kept: "+err.Error())
+		} else {
+			emptyStr := ""
+			input.WorktreePath = &emptyStr
+			if !entry.BranchAdopted {
+				input.Branch = &emptyStr
+			}
+			resp.RolledBack = append(resp.RolledBack, "worktree removed")
+		}
+	}
+
+	task, err = s.db.UpdateTask(id, input)
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	if labels, err := s.db.GetTaskLabels(id); err == nil {
+		task.Labels = labels
+	}
+	resp.Task = task
+	s.emitTaskStatusChanged(task, entry.ToStatus)
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// worktreeUndoBlocker returns why an auto-created worktree must be kept, or
+// "" if removing it loses nothing.
+func worktreeUndoBlocker(worktreePath, baseBranch string, branchAdopted bool) string {
+	status, err := runGit(worktreePath, "status", "--porcelain")
+	if err != nil {
+		return "could not check worktree status"
+	}
+	if strings.TrimSpace(status) != "" {
+		return "uncommitted changes"
+	}
+	if branchAdopted || baseBranch == "" {
+		return ""
+	}
+	count, err := runGit(worktreePath, "rev-list", "--count", baseBranch+"..HEAD")
+	if err != nil {
+		return "could not compare against " + baseBranch
+	}
+	if strings.TrimSpace(count) != "0" {
+		return "branch has new commits"
+	}
+	return ""
+}
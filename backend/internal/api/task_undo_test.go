@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+func setupUndoTask(t *testing.T, env *testEnv) (repoPath string, task db.Task) {
+	t.Helper()
+	env.server.worktree = worktree.NewManager(worktree.Config{BaseDir: t.TempDir()})
+
+	repoPath = createTestGitRepo(t)
+	if out, err := exec.Command("git", "-C", repoPath, "branch", "-M", "main").CombinedOutput(); err != nil {
+		t.Fatalf("set default branch to main: %v (%s)", err, string(out))
+	}
+
+	projResp := env.post("/api/projects", map[string]string{
+		"name": "undo-" + db.NewID(), "path": repoPath,
+	})
+	var project db.Project
+	decodeResponse(t, projResp, &project)
+
+	taskResp := env.post("/api/projects/"+project.ID+"/tasks", map[string]string{
+		"title": "Undo Task",
+	})
+	decodeResponse(t, taskResp, &task)
+	return repoPath, task
+}
+
+func TestUndoTaskStatus_RemovesCleanWorktree(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	repoPath, task := setupUndoTask(t, env)
+
+	resp := env.patch("/api/tasks/"+task.ID, map[string]string{"status": "in_progress"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var moved db.Task
+	decodeResponse(t, resp, &moved)
+	if moved.WorktreePath == nil || *moved.WorktreePath == "" {
+		t.Fatal("expected worktree to be created")
+	}
+	branch := *moved.Branch
+
+	resp = env.post("/api/tasks/"+task.ID+"/undo-status", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var undone undoTaskStatusResponse
+	decodeResponse(t, resp, &undone)
+
+	if undone.Task.Status != db.TaskStatusBacklog {
+		t.Errorf("expected status backlog, got %q", undone.Task.Status)
+	}
+	if undone.Task.WorktreePath != nil && *undone.Task.WorktreePath != "" {
+		t.Errorf("expected worktree path cleared, got %q", *undone.Task.WorktreePath)
+	}
+	if len(undone.RolledBack) != 1 || undone.RolledBack[0] != "worktree removed" {
+		t.Errorf("expected worktree rollback, got %v", undone.RolledBack)
+	}
+	if _, err := os.Stat(*moved.WorktreePath); !os.IsNotExist(err) {
+		t.Errorf("expected worktree directory removed, stat err: %v", err)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", branch).Run(); err == nil {
+		t.Errorf("expected branch %q deleted", branch)
+	}
+
+	// A second undo has nothing to revert.
+	resp = env.post("/api/tasks/"+task.ID+"/undo-status", nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on second undo, got %d", resp.Code)
+	}
+}
+
+func TestUndoTaskStatus_KeepsDirtyWorktree(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	_, task := setupUndoTask(t, env)
+
+	resp := env.patch("/api/tasks/"+task.ID, map[string]string{"status": "in_progress"})
+	var moved db.Task
+	decodeResponse(t, resp, &moved)
+	if moved.WorktreePath == nil || *moved.WorktreePath == "" {
+		t.Fatal("expected worktree to be created")
+	}
+	if err := os.WriteFile(filepath.Join(*moved.WorktreePath, "work.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = env.post("/api/tasks/"+task.ID+"/undo-status", nil)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var undone undoTaskStatusResponse
+	decodeResponse(t, resp, &undone)
+
+	if undone.Task.Status != db.TaskStatusBacklog {
+		t.Errorf("expected status backlog, got %q", undone.Task.Status)
+	}
+	if undone.Task.WorktreePath == nil || *undone.Task.WorktreePath != *moved.WorktreePath {
+		t.Errorf("expected worktree kept, got %v", undone.Task.WorktreePath)
+	}
+	if len(undone.Skipped) != 1 || undone.Skipped[0] != "worktree kept: uncommitted changes" {
+		t.Errorf("expected worktree skip, got %v", undone.Skipped)
+	}
+}
+
+func TestUndoTaskStatus_RejectsStaleChange(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	_, task := setupUndoTask(t, env)
+
+	env.patch("/api/tasks/"+task.ID, map[string]string{"status": "in_progress"})
+	inReview := db.TaskStatusInReview
+	if _, err := env.server.db.UpdateTask(task.ID, db.UpdateTaskInput{Status: &inReview}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := env.post("/api/tasks/"+task.ID+"/undo-status", nil)
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	resp = env.post("/api/tasks/nonexistent/undo-status", nil)
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.Code)
+	}
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -11,6 +13,7 @@ import (
 	"github.com/miguel-bm/codeburg/internal/db"
 	"github.com/miguel-bm/codeburg/internal/github"
 	"github.com/miguel-bm/codeburg/internal/worktree"
+	"github.com/miguel-bm/codeburg/service"
 )
 
 // taskWithDiffStats extends a Task with optional diff stats for the response.
@@ -22,6 +25,16 @@ type taskWithDiffStats struct {
 func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
 	filter := db.TaskFilter{}
 
+	// A saved view supplies the base filter; explicit query parameters override it.
+	if ref := r.URL.Query().Get("view"); ref != "" {
+		view, err := s.resolveSavedView(ref)
+		if err != nil {
+			writeDBError(w, err, "view")
+			return
+		}
+		filter = view.Filters.TaskFilter()
+	}
+
 	// Parse query parameters
 	if projectID := r.URL.Query().Get("project"); projectID != "" {
 		filter.ProjectID = &projectID
@@ -29,31 +42,53 @@ func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
 	if status := r.URL.Query().Get("status"); status != "" {
 		taskStatus := db.TaskStatus(status)
 		filter.Status = &taskStatus
+		filter.Statuses = nil
+	}
+	if category := r.URL.Query().Get("category"); category != "" {
+		filter.Categories = []db.TaskStatus{db.TaskStatus(category)}
+	}
+	if priorities := r.URL.Query().Get("priority"); priorities != "" {
+		filter.Priorities = nil
+		for _, p := range strings.Split(priorities, ",") {
+			priority, ok := db.NormalizePriority(p)
+			if !ok || priority == "" {
+				writeError(w, http.StatusBadRequest, "invalid priority")
+				return
+			}
+			filter.Priorities = append(filter.Priorities, priority)
+		}
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		if !db.ValidTaskSort(sort) {
+			writeError(w, http.StatusBadRequest, "invalid sort")
+			return
+		}
+		// Pages always follow creation order, newest first.
+		if (page.Limit > 0 || page.Cursor != "") && sort != db.TaskSortCreated {
+			writeError(w, http.StatusBadRequest, "paged task lists only support sort=created")
+			return
+		}
+		filter.Sort = sort
 	}
 	if archived := r.URL.Query().Get("archived"); archived == "true" {
 		t := true
 		filter.Archived = &t
 	}
 
-	tasks, err := s.db.ListTasks(filter)
+	tasks, next, err := s.tasks().List(r.Context(), filter, page)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list tasks")
+		writeListError(w, err, "tasks")
 		return
 	}
 
-	// Batch-load labels for all tasks
-	taskIDs := make([]string, len(tasks))
-	for i, t := range tasks {
-		taskIDs[i] = t.ID
-	}
-	labelsMap, _ := s.db.GetTasksLabels(taskIDs)
-
 	// Enrich tasks that have worktrees with diff stats
 	result := make([]taskWithDiffStats, len(tasks))
 	for i, t := range tasks {
-		if labels, ok := labelsMap[t.ID]; ok {
-			t.Labels = labels
-		}
 		result[i] = taskWithDiffStats{Task: t}
 		if t.WorktreePath == nil || *t.WorktreePath == "" {
 			continue
@@ -63,56 +98,96 @@ func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writePage(w, result, next)
 }
 
-func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
-	projectID := urlParam(r, "projectId")
-
-	// Verify project exists
-	_, err := s.db.GetProject(projectID)
+// List returns a page of tasks with their labels.
+func (t *taskService) List(ctx context.Context, filter db.TaskFilter, page db.Page) ([]*db.Task, string, error) {
+	tasks, next, err := t.s.db.ListTasksPage(filter, page)
 	if err != nil {
-		writeDBError(w, err, "project")
-		return
+		return nil, "", err
 	}
 
+	// Batch-load labels for all tasks
+	taskIDs := make([]string, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+	labelsMap, _ := t.s.db.GetTasksLabels(taskIDs)
+	for _, task := range tasks {
+		if labels, ok := labelsMap[task.ID]; ok {
+			task.Labels = labels
+		}
+	}
+	return tasks, next, nil
+}
+
+func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	var input db.CreateTaskInput
 	if err := decodeJSON(r, &input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	input.ProjectID = urlParam(r, "projectId")
+
+	task, err := s.tasks().Create(r.Context(), input)
+	if err != nil {
+		writeServiceError(w, err, "failed to create task")
+		return
+	}
 
-	input.ProjectID = projectID
+	writeJSON(w, http.StatusCreated, task)
+}
+
+// Create adds a task to its project's backlog.
+func (t *taskService) Create(ctx context.Context, input db.CreateTaskInput) (*db.Task, error) {
+	// Verify project exists
+	project, err := t.s.db.GetProject(input.ProjectID)
+	if err != nil {
+		return nil, notFound(err, "project")
+	}
+	if project.ArchivedAt != nil {
+		return nil, service.Conflict("%s", errProjectArchived)
+	}
 
 	// Validate required fields
 	if input.Title == "" {
-		writeError(w, http.StatusBadRequest, "title is required")
-		return
+		return nil, service.Invalid("title is required")
+	}
+	if err := validateTaskPlanning(input.Priority, input.Estimate); err != nil {
+		return nil, err
 	}
 
-	task, err := s.db.CreateTask(input)
+	task, err := t.s.db.CreateTask(input)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create task")
-		return
+		return nil, fmt.Errorf("create task: %w", err)
 	}
+	return task, nil
+}
 
-	writeJSON(w, http.StatusCreated, task)
+// validateTaskPlanning checks a task's priority and estimate, spelling the
+// priority as one of db.TaskPriorities.
+func validateTaskPlanning(priority *string, estimate *int) error {
+	if priority != nil {
+		p, ok := db.NormalizePriority(*priority)
+		if !ok {
+			return service.Invalid("priority must be one of urgent, high, medium, low or p0 to p3")
+		}
+		*priority = p
+	}
+	if estimate != nil && *estimate < 0 {
+		return service.Invalid("estimateMinutes must not be negative")
+	}
+	return nil
 }
 
 func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
-	id := urlParam(r, "id")
-
-	task, err := s.db.GetTask(id)
+	task, err := s.tasks().Get(r.Context(), urlParam(r, "id"))
 	if err != nil {
-		writeDBError(w, err, "task")
+		writeServiceError(w, err, "failed to get task")
 		return
 	}
 
-	// Load labels for this task
-	if labels, err := s.db.GetTaskLabels(id); err == nil {
-		task.Labels = labels
-	}
-
 	result := taskWithDiffStats{Task: task}
 	if task.WorktreePath != nil && *task.WorktreePath != "" {
 		if stats := s.getCachedDiffStats(task); stats != nil {
@@ -120,89 +195,162 @@ func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	setVersionETag(w, task.Version)
 	writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
-	id := urlParam(r, "id")
+// Get returns a task with its labels.
+func (t *taskService) Get(ctx context.Context, id string) (*db.Task, error) {
+	task, err := t.s.db.GetTask(id)
+	if err != nil {
+		return nil, notFound(err, "task")
+	}
 
+	// Load labels for this task
+	if labels, err := t.s.db.GetTaskLabels(id); err == nil {
+		task.Labels = labels
+	}
+	return task, nil
+}
+
+func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	var input db.UpdateTaskInput
 	if err := decodeJSON(r, &input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	// Validate status if provided
-	if input.Status != nil {
-		validStatuses := map[db.TaskStatus]bool{
-			db.TaskStatusBacklog:    true,
-			db.TaskStatusInProgress: true,
-			db.TaskStatusInReview:   true,
-			db.TaskStatusDone:       true,
-		}
-		if !validStatuses[*input.Status] {
-			writeError(w, http.StatusBadRequest, "invalid status")
-			return
-		}
+	ifMatch := ifMatchVersion(r)
+	if ifMatch != nil {
+		input.Version = ifMatch
 	}
 
+	id := urlParam(r, "id")
+	resp, err := s.tasks().Update(r.Context(), id, input)
+	if s.writeStaleTask(w, r, ifMatch, id, err) {
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "failed to update task")
+		return
+	}
+
+	setVersionETag(w, resp.Version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeStaleTask answers an update or move based on a stale version with the
+// task as it is now, for the client to redo the change on. It reports
+// whether it did.
+func (s *Server) writeStaleTask(w http.ResponseWriter, r *http.Request, ifMatch *int, id string, err error) bool {
+	var svcErr *service.Error
+	if !errors.As(err, &svcErr) || !errors.Is(svcErr.Kind, service.ErrStale) {
+		return false
+	}
+	task, getErr := s.tasks().Get(r.Context(), id)
+	if getErr != nil {
+		return false
+	}
+	writeStale(w, ifMatch, svcErr.Message, "task", task, task.Version)
+	return true
+}
+
+// Update changes a task and, when it moves to another column, runs the
+// project's workflow for the move.
+func (t *taskService) Update(ctx context.Context, id string, input db.UpdateTaskInput) (*updateTaskResponse, error) {
+	s := t.s
+
 	// Get current task to check status transition
 	currentTask, err := s.db.GetTask(id)
 	if err != nil {
-		writeDBError(w, err, "task")
-		return
+		return nil, notFound(err, "task")
+	}
+	if err := s.checkProjectWritable(currentTask.ProjectID); errors.Is(err, errProjectArchived) {
+		return nil, service.Conflict("%s", err)
+	}
+	// Fail a stale edit before the workflow acts on it. UpdateTask checks
+	// again, for a change in between.
+	if input.Version != nil && *input.Version != currentTask.Version {
+		return nil, service.Stale("task changed since version %d", *input.Version)
 	}
 
-	// Validate archive: only done tasks can be archived
-	if input.SetArchived != nil && *input.SetArchived {
-		effectiveStatus := currentTask.Status
-		if input.Status != nil {
-			effectiveStatus = *input.Status
+	// Validate status if provided: one of the project's board columns. The
+	// lifecycle below goes by the column's category.
+	category := currentTask.Category
+	if input.Status != nil {
+		project, err := s.db.GetProject(currentTask.ProjectID)
+		if err != nil {
+			return nil, notFound(err, "project")
 		}
-		if effectiveStatus != db.TaskStatusDone {
-			writeError(w, http.StatusBadRequest, "only done tasks can be archived")
-			return
+		category = project.StatusCategory(*input.Status)
+		if category == "" {
+			return nil, service.Invalid("invalid status")
 		}
+		if *input.Status != currentTask.Status {
+			if err := s.checkWIPLimit(project, *input.Status, currentTask.ID); errors.Is(err, errWIPLimit) {
+				return nil, service.Conflict("%s", err.Error())
+			} else if err != nil {
+				return nil, fmt.Errorf("check WIP limit: %w", err)
+			}
+		}
+	}
+
+	if err := validateTaskPlanning(input.Priority, input.Estimate); err != nil {
+		return nil, err
+	}
+
+	// Validate archive: only done tasks can be archived
+	if input.SetArchived != nil && *input.SetArchived && category != db.TaskStatusDone {
+		return nil, service.Invalid("only done tasks can be archived")
 	}
 
 	// Auto-create worktree when moving to in_progress
 	var worktreeWarnings []string
-	if input.Status != nil && *input.Status == db.TaskStatusInProgress {
+	undo := taskStatusUndo{FromPosition: currentTask.Position}
+	if input.Status != nil && category == db.TaskStatusInProgress {
 		// Only create if no worktree exists yet
 		if currentTask.WorktreePath == nil || *currentTask.WorktreePath == "" {
-			warnings, err := s.autoCreateWorktree(currentTask, &input)
+			warnings, adopted, err := s.autoCreateWorktree(currentTask, &input)
 			if err != nil {
 				slog.Warn("failed to auto-create worktree", "task_id", id, "error", err)
 				worktreeWarnings = append(worktreeWarnings, "failed to create worktree: "+err.Error())
 			} else {
 				worktreeWarnings = warnings
+				undo.WorktreeCreated = true
+				undo.BranchAdopted = adopted
 			}
 		}
 	}
 
 	// Run in_review -> done workflow before status update so failures can block completion.
 	handledReviewToDone := false
-	if input.Status != nil && *input.Status == db.TaskStatusDone && currentTask.Status == db.TaskStatusInReview {
+	if input.Status != nil && category == db.TaskStatusDone && currentTask.Category == db.TaskStatusInReview {
 		project, err := s.db.GetProject(currentTask.ProjectID)
 		if err != nil {
-			writeDBError(w, err, "project")
-			return
+			return nil, notFound(err, "project")
 		}
 		wfResp := updateTaskResponse{Task: currentTask}
 		if project.Workflow != nil {
 			s.handleReviewToDone(currentTask, project, project.Workflow.ReviewToDone, &wfResp)
 		}
 		if wfResp.WorkflowError != nil {
-			writeError(w, http.StatusConflict, *wfResp.WorkflowError)
-			return
+			return nil, service.Conflict("%s", *wfResp.WorkflowError)
 		}
 		handledReviewToDone = true
+		if project.Workflow != nil && project.Workflow.ReviewToDone != nil {
+			switch project.Workflow.ReviewToDone.Action {
+			case "merge_pr", "merge_branch":
+				undo.Irreversible = "changes were merged"
+			}
+		}
 	}
 
 	task, err := s.db.UpdateTask(id, input)
+	if errors.Is(err, db.ErrConflict) {
+		return nil, service.Stale("task changed since version %d", *input.Version)
+	}
 	if err != nil {
-		writeDBError(w, err, "task")
-		return
+		return nil, notFound(err, "task")
 	}
 
 	// Load labels
@@ -211,28 +359,110 @@ func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check for workflow automation on status transitions
-	resp := updateTaskResponse{Task: task, WorktreeWarning: worktreeWarnings}
+	resp := &updateTaskResponse{Task: task, WorktreeWarning: worktreeWarnings}
 	if input.Status != nil && *input.Status != currentTask.Status {
 		if !handledReviewToDone {
-			s.dispatchWorkflow(currentTask, task, &resp)
+			s.dispatchWorkflow(ctx, currentTask, task, resp)
 		}
+		undo.FromStatus = currentTask.Status
+		undo.ToStatus = task.Status
+		undo.SessionStarted = ptrToString(resp.SessionStarted)
+		undo.PRCreated = ptrToString(resp.PRCreated)
+		s.taskUndo.record(id, undo)
+		s.emitTaskStatusChanged(task, currentTask.Status)
+	}
+	if input.Status != nil && task.Category == db.TaskStatusInReview && currentTask.Category != db.TaskStatusInReview {
+		go s.notifyTaskInReview(id)
+	}
+	if input.Status != nil && task.Category == db.TaskStatusDone && currentTask.Category != db.TaskStatusDone {
+		s.stopTaskTunnels(id, "the task moved to done")
+		s.teardownTaskProvisionAsync(task)
+	}
+
+	return resp, nil
+}
+
+func (s *Server) handleReorderTask(w http.ResponseWriter, r *http.Request) {
+	var input service.ReorderTaskInput
+	if err := decodeJSON(r, &input); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
 	}
 
+	ifMatch := ifMatchVersion(r)
+	if ifMatch != nil {
+		input.Version = ifMatch
+	}
+
+	id := urlParam(r, "id")
+	resp, err := s.tasks().Reorder(r.Context(), id, input)
+	if s.writeStaleTask(w, r, ifMatch, id, err) {
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "failed to reorder task")
+		return
+	}
+
+	s.wsHub.BroadcastToProject(resp.ProjectID, "task_reordered", map[string]any{
+		"taskId":  id,
+		"status":  resp.Status,
+		"version": resp.Version,
+	})
+	setVersionETag(w, resp.Version)
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// updateTaskResponse wraps a Task with optional workflow automation hints.
-type updateTaskResponse struct {
-	*db.Task
-	WorkflowAction  *string  `json:"workflowAction,omitempty"`  // "ask" when user should pick provider
-	SessionStarted  *string  `json:"sessionStarted,omitempty"`  // session ID if auto-started
-	PRCreated       *string  `json:"prCreated,omitempty"`       // PR URL if auto-created
-	WorkflowError   *string  `json:"workflowError,omitempty"`   // non-fatal workflow error message
-	WorktreeWarning []string `json:"worktreeWarning,omitempty"` // non-fatal worktree creation warnings
+// Reorder moves a task to an index in a column. A move to another column
+// goes through Update first, for its WIP limit and workflow.
+func (t *taskService) Reorder(ctx context.Context, id string, input service.ReorderTaskInput) (*updateTaskResponse, error) {
+	if input.Index < 0 {
+		return nil, service.Invalid("index must not be negative")
+	}
+	current, err := t.s.db.GetTask(id)
+	if err != nil {
+		return nil, notFound(err, "task")
+	}
+	if input.Version != nil && *input.Version != current.Version {
+		return nil, service.Stale("task changed since version %d", *input.Version)
+	}
+
+	status := current.Status
+	if input.Status != "" {
+		status = input.Status
+	}
+	resp := &updateTaskResponse{Task: current}
+	version := input.Version
+	if status != current.Status {
+		resp, err = t.Update(ctx, id, db.UpdateTaskInput{Status: &status, Version: input.Version})
+		if err != nil {
+			return nil, err
+		}
+		// The workflow may have changed the task again; the move is still
+		// this request's as long as the task stays in the column.
+		version = nil
+	}
+
+	task, err := t.s.db.ReorderTask(id, status, input.Index, input.AllProjects, version)
+	if errors.Is(err, db.ErrConflict) {
+		return nil, service.Stale("task changed while being reordered")
+	}
+	if err != nil {
+		return nil, notFound(err, "task")
+	}
+	if labels, err := t.s.db.GetTaskLabels(id); err == nil {
+		task.Labels = labels
+	}
+	resp.Task = task
+	return resp, nil
 }
 
-// dispatchWorkflow checks the project's workflow config and acts on status transitions.
-func (s *Server) dispatchWorkflow(oldTask, newTask *db.Task, resp *updateTaskResponse) {
+// updateTaskResponse wraps a Task with optional workflow automation hints.
+type updateTaskResponse = service.TaskUpdate
+
+// dispatchWorkflow checks the project's workflow config and acts on status
+// transitions, between the categories of the tasks' columns.
+func (s *Server) dispatchWorkflow(ctx context.Context, oldTask, newTask *db.Task, resp *updateTaskResponse) {
 	project, err := s.db.GetProject(newTask.ProjectID)
 	if err != nil || project.Workflow == nil {
 		return
@@ -240,7 +470,7 @@ func (s *Server) dispatchWorkflow(oldTask, newTask *db.Task, resp *updateTaskRes
 	wf := project.Workflow
 
 	// backlog → in_progress
-	if oldTask.Status == db.TaskStatusBacklog && newTask.Status == db.TaskStatusInProgress {
+	if oldTask.Category == db.TaskStatusBacklog && newTask.Category == db.TaskStatusInProgress {
 		if wf.BacklogToProgress == nil {
 			return
 		}
@@ -256,7 +486,7 @@ func (s *Server) dispatchWorkflow(oldTask, newTask *db.Task, resp *updateTaskRes
 			if newTask.WorktreePath != nil && *newTask.WorktreePath != "" {
 				workDir = *newTask.WorktreePath
 			}
-			session, err := s.startSessionInternal(startSessionParams{
+			session, err := s.startSessionInternal(ctx, startSessionParams{
 				ProjectID: newTask.ProjectID,
 				TaskID:    newTask.ID,
 				WorkDir:   workDir,
@@ -277,12 +507,12 @@ func (s *Server) dispatchWorkflow(oldTask, newTask *db.Task, resp *updateTaskRes
 	}
 
 	// in_progress → in_review
-	if oldTask.Status == db.TaskStatusInProgress && newTask.Status == db.TaskStatusInReview {
+	if oldTask.Category == db.TaskStatusInProgress && newTask.Category == db.TaskStatusInReview {
 		s.handleProgressToReview(newTask, project, wf.ProgressToReview, resp)
 	}
 
 	// in_review → done
-	if oldTask.Status == db.TaskStatusInReview && newTask.Status == db.TaskStatusDone {
+	if oldTask.Category == db.TaskStatusInReview && newTask.Category == db.TaskStatusDone {
 		s.handleReviewToDone(newTask, project, wf.ReviewToDone, resp)
 	}
 }
@@ -300,11 +530,12 @@ func buildPromptFromTemplate(tmpl, title, description string) string {
 }
 
 // autoCreateWorktree creates a worktree for a task and updates the input with worktree info.
+// adopted reports whether an existing branch was checked out rather than a new one created.
 // Returns non-fatal warnings (e.g. stale base branch) and an error if creation failed entirely.
-func (s *Server) autoCreateWorktree(task *db.Task, input *db.UpdateTaskInput) (warnings []string, err error) {
+func (s *Server) autoCreateWorktree(task *db.Task, input *db.UpdateTaskInput) (warnings []string, adopted bool, err error) {
 	project, err := s.db.GetProject(task.ProjectID)
 	if err != nil {
-		return nil, fmt.Errorf("get project: %w", err)
+		return nil, false, fmt.Errorf("get project: %w", err)
 	}
 
 	branchName := strings.TrimSpace(ptrToString(task.Branch))
@@ -312,7 +543,7 @@ func (s *Server) autoCreateWorktree(task *db.Task, input *db.UpdateTaskInput) (w
 	// Treat it as adopt mode only when the ref actually exists locally or remotely.
 	adoptBranch := branchName != "" && (gitRefExists(project.Path, branchName) || gitRefExists(project.Path, "origin/"+branchName))
 
-	result, err := s.worktree.Create(worktree.CreateOptions{
+	result, err := s.createTaskWorktree(project, worktree.CreateOptions{
 		ProjectPath:  project.Path,
 		ProjectID:    project.ID,
 		ProjectName:  project.Name,
@@ -322,18 +553,25 @@ func (s *Server) autoCreateWorktree(task *db.Task, input *db.UpdateTaskInput) (w
 		BaseBranch:   project.DefaultBranch,
 		AdoptBranch:  adoptBranch,
 		SymlinkPaths: project.SymlinkPaths,
+		ClonePaths:   project.ClonePaths,
 		SecretFiles:  mapSecretFiles(project.SecretFiles),
 		SetupScript:  ptrToString(project.SetupScript),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("create worktree: %w", err)
+		return nil, false, fmt.Errorf("create worktree: %w", err)
 	}
 
 	// Add worktree info to the update input
 	input.WorktreePath = &result.WorktreePath
 	input.Branch = &result.BranchName
 
-	return result.Warnings, nil
+	warnings = result.Warnings
+	if provisionerEnabled(project) {
+		if _, err := s.provisionTask(task.ID, project, result.WorktreePath); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return warnings, adoptBranch, nil
 }
 
 func gitRefExists(repoPath, ref string) bool {
@@ -362,17 +600,19 @@ func (s *Server) handleProgressToReview(task *db.Task, project *db.Project, cfg
 	}
 
 	// Guard: skip PR workflow if there are no changes on this branch
-	logOut, err := runGit(workDir, "log", "--oneline", project.DefaultBranch+"..HEAD")
+	taskBase := baseBranchFor(task, project)
+	logOut, err := runGit(workDir, "log", "--oneline", taskBase+"..HEAD")
 	if err == nil && strings.TrimSpace(logOut) == "" {
-		wfErr := "no changes on branch " + branch + " compared to " + project.DefaultBranch
+		wfErr := "no changes on branch " + branch + " compared to " + taskBase
 		resp.WorkflowError = &wfErr
 		return
 	}
 
 	switch cfg.Action {
 	case "pr_auto":
-		if !github.Available() {
-			wfErr := "gh CLI not available, skipping PR creation"
+		forge, err := s.forgeForProject(project)
+		if err != nil {
+			wfErr := err.Error() + ", skipping PR creation"
 			resp.WorkflowError = &wfErr
 			slog.Warn("workflow: pr_auto skipped", "task_id", task.ID, "reason", wfErr)
 			return
@@ -384,13 +624,18 @@ func (s *Server) handleProgressToReview(task *db.Task, project *db.Project, cfg
 			slog.Error("workflow: push branch failed", "task_id", task.ID, "error", err)
 			return
 		}
+		s.emitGitPushed(project.ID, task.ID, workDir, branch)
 		// Create the PR
-		baseBranch := cfg.PRBaseBranch
+		// A task retargeted onto another branch opens its PR against it.
+		baseBranch := ptrToString(task.BaseBranch)
+		if baseBranch == "" {
+			baseBranch = cfg.PRBaseBranch
+		}
 		if baseBranch == "" {
 			baseBranch = project.DefaultBranch
 		}
 		body := ptrToString(task.Description)
-		prURL, err := github.CreatePR(workDir, task.Title, body, baseBranch, branch)
+		prURL, err := forge.createPR(workDir, task.Title, body, baseBranch, branch)
 		if err != nil {
 			wfErr := fmt.Sprintf("failed to create PR: %v", err)
 			resp.WorkflowError = &wfErr
@@ -404,8 +649,8 @@ func (s *Server) handleProgressToReview(task *db.Task, project *db.Project, cfg
 		slog.Info("workflow: PR created", "task_id", task.ID, "pr_url", prURL)
 
 	case "pr_manual":
-		if !github.Available() {
-			wfErr := "gh CLI not available, skipping branch push"
+		if _, err := s.forgeForProject(project); err != nil {
+			wfErr := err.Error() + ", skipping branch push"
 			resp.WorkflowError = &wfErr
 			return
 		}
@@ -415,6 +660,7 @@ func (s *Server) handleProgressToReview(task *db.Task, project *db.Project, cfg
 			slog.Error("workflow: push branch failed", "task_id", task.ID, "error", err)
 			return
 		}
+		s.emitGitPushed(project.ID, task.ID, workDir, branch)
 		action := "branch_pushed"
 		resp.WorkflowAction = &action
 		slog.Info("workflow: branch pushed (manual PR)", "task_id", task.ID, "branch", branch)
@@ -439,8 +685,9 @@ func (s *Server) handleReviewToDone(task *db.Task, project *db.Project, cfg *db.
 			resp.WorkflowError = &wfErr
 			return
 		}
-		if !github.Available() {
-			wfErr := "gh CLI not available, skipping PR merge"
+		forge, err := s.forgeForProject(project)
+		if err != nil {
+			wfErr := err.Error() + ", skipping PR merge"
 			resp.WorkflowError = &wfErr
 			return
 		}
@@ -449,7 +696,7 @@ func (s *Server) handleReviewToDone(task *db.Task, project *db.Project, cfg *db.
 			strategy = "squash"
 		}
 		// Merge first, then clean up worktree, then delete branch explicitly.
-		if err := github.MergePR(project.Path, prURL, strategy, false); err != nil {
+		if err := forge.mergePR(project.Path, prURL, strategy, false); err != nil {
 			wfErr := fmt.Sprintf("failed to merge PR: %v", err)
 			resp.WorkflowError = &wfErr
 			slog.Error("workflow: merge PR failed", "task_id", task.ID, "error", err)
@@ -464,7 +711,7 @@ func (s *Server) handleReviewToDone(task *db.Task, project *db.Project, cfg *db.
 			resp.WorkflowError = &wfErr
 			return
 		}
-		baseBranch := project.DefaultBranch
+		baseBranch := baseBranchFor(task, project)
 		if err := directMergeBranch(project.Path, baseBranch, branch); err != nil {
 			wfErr := fmt.Sprintf("failed to merge branch: %v", err)
 			resp.WorkflowError = &wfErr
@@ -479,6 +726,7 @@ func (s *Server) handleReviewToDone(task *db.Task, project *db.Project, cfg *db.
 				slog.Error("workflow: push base branch failed", "task_id", task.ID, "branch", baseBranch, "error", err)
 				return
 			}
+			s.emitGitPushed(project.ID, task.ID, project.Path, baseBranch)
 		}
 		slog.Info("workflow: branch merged directly", "task_id", task.ID, "branch", branch)
 
@@ -488,6 +736,7 @@ func (s *Server) handleReviewToDone(task *db.Task, project *db.Project, cfg *db.
 
 	// Cleanup worktree if configured
 	if cleanupWorktree && task.WorktreePath != nil && *task.WorktreePath != "" {
+		s.removeTaskContainer(task, project)
 		err := s.worktree.Delete(worktree.DeleteOptions{
 			ProjectPath:    project.Path,
 			WorktreePath:   *task.WorktreePath,
@@ -569,7 +818,8 @@ func directMergeBranch(repoPath, baseBranch, featureBranch string) error {
 	return nil
 }
 
-// handleCreatePR manually pushes the branch and creates a PR for a task.
+// handleCreatePR manually pushes the branch and creates a PR for a task,
+// in a job (see respondWithJob).
 func (s *Server) handleCreatePR(w http.ResponseWriter, r *http.Request) {
 	id := urlParam(r, "id")
 
@@ -591,67 +841,108 @@ func (s *Server) handleCreatePR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !github.Available() {
-		writeError(w, http.StatusServiceUnavailable, "gh CLI not available")
+	if _, err := s.forgeForProject(project); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
 		return
 	}
 
-	workDir := ptrToString(task.WorktreePath)
-	if workDir == "" {
-		workDir = project.Path
-	}
-
 	// Check for changes
-	logOut, err := runGit(workDir, "log", "--oneline", project.DefaultBranch+"..HEAD")
+	baseBranch := baseBranchFor(task, project)
+	logOut, err := runGit(taskWorkDir(task, project), "log", "--oneline", baseBranch+"..HEAD")
 	if err == nil && strings.TrimSpace(logOut) == "" {
-		writeError(w, http.StatusBadRequest, "no changes on branch "+branch+" compared to "+project.DefaultBranch)
+		writeError(w, http.StatusBadRequest, "no changes on branch "+branch+" compared to "+baseBranch)
 		return
 	}
 
+	s.respondWithJob(w, r, jobKindCreatePR, project.ID, taskJobPayload{TaskID: task.ID}, http.StatusOK)
+}
+
+// taskWorkDir is where a task's git commands run: its worktree, or the
+// project without one.
+func taskWorkDir(task *db.Task, project *db.Project) string {
+	if workDir := ptrToString(task.WorktreePath); workDir != "" {
+		return workDir
+	}
+	return project.Path
+}
+
+// runCreatePRJob pushes a task's branch and opens its pull request; the
+// result is {"prUrl": ...}.
+func (s *Server) runCreatePRJob(_ context.Context, job *db.Job, progress func(string)) (any, error) {
+	var payload taskJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	task, err := s.db.GetTask(payload.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("find task: %w", err)
+	}
+	project, err := s.db.GetProject(task.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("find project: %w", err)
+	}
+	forge, err := s.forgeForProject(project)
+	if err != nil {
+		return nil, fmt.Errorf("create PR: %w", err)
+	}
+	branch := ptrToString(task.Branch)
+	workDir := taskWorkDir(task, project)
+
 	// Push branch
+	progress("Pushing " + branch)
 	if err := github.PushBranch(workDir, branch); err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to push branch: %v", err))
-		return
+		return nil, fmt.Errorf("push branch: %w", err)
 	}
+	s.emitGitPushed(project.ID, task.ID, workDir, branch)
 
 	// Create PR
+	progress("Creating pull request")
 	body := ptrToString(task.Description)
-	prURL, err := github.CreatePR(workDir, task.Title, body, project.DefaultBranch, branch)
+	if artifacts := s.artifactsMarkdown(task.ID); artifacts != "" {
+		body = strings.TrimSpace(body + "\n\n" + artifacts)
+	}
+	prURL, err := forge.createPR(workDir, task.Title, body, baseBranchFor(task, project), branch)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to create PR: %v", err))
-		return
+		return nil, fmt.Errorf("create PR: %w", err)
 	}
 
 	// Store PR URL
 	if _, err := s.db.UpdateTask(task.ID, db.UpdateTaskInput{PRURL: &prURL}); err != nil {
 		slog.Warn("failed to store PR URL on task", "task_id", task.ID, "error", err)
 	}
-
-	writeJSON(w, http.StatusOK, map[string]string{"prUrl": prURL})
+	return map[string]string{"prUrl": prURL}, nil
 }
 
 func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
-	id := urlParam(r, "id")
+	if err := s.tasks().Delete(r.Context(), urlParam(r, "id")); err != nil {
+		writeServiceError(w, err, "failed to delete task")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete stops everything running for a task, removes its worktree and
+// deletes it.
+func (t *taskService) Delete(ctx context.Context, id string) error {
+	s := t.s
 
 	// 1. Get task (need worktree_path, project_id)
 	task, err := s.db.GetTask(id)
 	if err != nil {
-		writeDBError(w, err, "task")
-		return
+		return notFound(err, "task")
 	}
 
 	// 2. Get project (need path, teardown_script)
 	project, err := s.db.GetProject(task.ProjectID)
 	if err != nil {
-		writeDBError(w, err, "project")
-		return
+		return notFound(err, "project")
 	}
 
 	// 3. Clean up all sessions for the task
 	sessions, err := s.db.ListSessionsByTask(id)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list task sessions")
-		return
+		return fmt.Errorf("list task sessions: %w", err)
 	}
 	for _, sess := range sessions {
 		if sess.SessionType == "chat" {
@@ -668,6 +959,7 @@ func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		removeHookToken(sess.ID)
 		removeNotifyScript(sess.ID)
 		removeSessionLog(sess.ID)
+		removeSessionUploads(sess.ID)
 		s.portSuggest.ForgetSession(sess.ID)
 	}
 
@@ -677,8 +969,16 @@ func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 			slog.Warn("failed to stop tunnel during task deletion", "task_id", id, "tunnel_id", t.ID, "error", err)
 		}
 	}
+	s.portSuggest.ForgetTask(id)
+	s.pipelineRuns.forgetTask(id)
+	if err := s.teardownTaskProvision(task, project); err != nil {
+		slog.Warn("failed to tear down task provision during task deletion", "task_id", id, "error", err)
+	}
+	s.removeTaskContainer(task, project)
 
-	// 5. Delete worktree if present
+	// 5. Delete worktrees if present
+	s.removeTaskComparisons(id, project)
+	s.removeWorktreeSnapshots(id, project)
 	if task.WorktreePath != nil && *task.WorktreePath != "" {
 		if err := s.worktree.Delete(worktree.DeleteOptions{
 			ProjectPath:    project.Path,
@@ -690,16 +990,16 @@ func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// 6. Delete task from DB (cascades handle session/label/dep records)
+	// 6. Delete task from DB (cascades handle session/label/dep/attachment records)
 	if err := s.db.DeleteTask(id); err != nil {
-		writeDBError(w, err, "task")
-		return
+		return notFound(err, "task")
 	}
+	removeTaskAttachments(id)
+	removeTaskArtifacts(id)
 
 	// 7. Broadcast deletion via WebSocket
-	s.wsHub.BroadcastGlobal("task_deleted", map[string]string{"taskId": id})
-
-	w.WriteHeader(http.StatusNoContent)
+	s.wsHub.BroadcastToProject(task.ProjectID, "task_deleted", map[string]string{"taskId": id})
+	return nil
 }
 
 // ptrToString safely dereferences a string pointer
@@ -173,6 +173,7 @@ func (s *Server) handleTelegramAuth(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
+	s.setSessionCookie(w, token)
 
 	writeJSON(w, http.StatusOK, map[string]string{
 		"token": token,
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Telegram chat access preference:
+//
+//	telegram_chats  {"-1001234567890": "read-only", "555123": "operator"}
+//
+// Chats listed may use the bot at their level: read-only chats can look at
+// tasks, sessions and diffs; operator chats can also act on sessions, by
+// reacting, answering permission requests, sending files and starting them
+// from templates; admin chats can also change this list with /chats. The
+// telegram_user_id is always admin in its private chat with the bot. Any
+// other chat is ignored.
+const telegramChatsPreference = "telegram_chats"
+
+type telegramAccess int
+
+const (
+	telegramNoAccess telegramAccess = iota
+	telegramReadOnly
+	telegramOperator
+	telegramAdmin
+)
+
+var telegramAccessNames = map[telegramAccess]string{
+	telegramReadOnly: "read-only",
+	telegramOperator: "operator",
+	telegramAdmin:    "admin",
+}
+
+func (a telegramAccess) String() string {
+	return telegramAccessNames[a]
+}
+
+func parseTelegramAccess(name string) (telegramAccess, bool) {
+	for access, n := range telegramAccessNames {
+		if n == name {
+			return access, true
+		}
+	}
+	return telegramNoAccess, false
+}
+
+// telegramCommandAccess is the access the commands needing more than
+// read-only require.
+var telegramCommandAccess = map[string]telegramAccess{
+	"template": telegramOperator,
+	"chats":    telegramAdmin,
+}
+
+// telegramChats returns the configured chat levels, skipping invalid
+// entries.
+func (s *Server) telegramChats() map[int64]telegramAccess {
+	pref, err := s.db.GetPreference(db.DefaultUserID, telegramChatsPreference)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(pref.Value), &raw); err != nil {
+		slog.Warn("invalid telegram_chats preference", "error", err)
+		return nil
+	}
+	chats := make(map[int64]telegramAccess, len(raw))
+	for key, level := range raw {
+		chatID, err := strconv.ParseInt(strings.TrimSpace(key), 10, 64)
+		access, ok := parseTelegramAccess(level)
+		if err != nil || !ok {
+			slog.Warn("ignoring invalid telegram chat", "chat_id", key, "level", level)
+			continue
+		}
+		chats[chatID] = access
+	}
+	return chats
+}
+
+// telegramChatAccess returns what a user may do with the bot in a chat.
+// Only the owner counts in the owner's chat; in other chats the chat's
+// level applies to everyone in it.
+func (s *Server) telegramChatAccess(chatID, userID int64) telegramAccess {
+	if chatID == 0 {
+		return telegramNoAccess
+	}
+	if ownerID, ok := s.telegramChatID(); ok && chatID == ownerID {
+		if userID != ownerID {
+			return telegramNoAccess
+		}
+		return telegramAdmin
+	}
+	return s.telegramChats()[chatID]
+}
+
+// telegramAllowed reports whether a chat has at least the access needed,
+// logging chats that are turned away.
+func (s *Server) telegramAllowed(chatID, userID int64, need telegramAccess, what string) bool {
+	access := s.telegramChatAccess(chatID, userID)
+	if access < need {
+		slog.Warn("telegram "+what+" refused", "chat_id", chatID, "user_id", userID, "access", access.String(), "needs", need.String())
+		return false
+	}
+	return true
+}
+
+// telegramEditChats replies to /chats, which lists the chats allowed to use
+// the bot, or with a chat ID and level sets that chat's level ("off"
+// removes it).
+func (s *Server) telegramEditChats(args string) string {
+	const usage = "Usage: /chats [<chat-id> read-only|operator|admin|off]"
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return s.telegramListChats()
+	}
+	if len(fields) != 2 {
+		return usage
+	}
+	chatID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || chatID == 0 {
+		return usage
+	}
+	if ownerID, ok := s.telegramChatID(); ok && chatID == ownerID {
+		return "The telegram_user_id's chat is always admin."
+	}
+	access, ok := parseTelegramAccess(fields[1])
+	if !ok && fields[1] != "off" {
+		return usage
+	}
+
+	chats := s.telegramChats()
+	if chats == nil {
+		chats = make(map[int64]telegramAccess)
+	}
+	if ok {
+		chats[chatID] = access
+	} else {
+		delete(chats, chatID)
+	}
+	raw := make(map[string]string, len(chats))
+	for id, a := range chats {
+		raw[strconv.FormatInt(id, 10)] = a.String()
+	}
+	value, _ := json.Marshal(raw)
+	if _, err := s.db.SetPreference(db.DefaultUserID, telegramChatsPreference, string(value)); err != nil {
+		return "Failed to save chats."
+	}
+	if !ok {
+		return fmt.Sprintf("Removed chat %d.", chatID)
+	}
+	return fmt.Sprintf("Chat %d is now %s.", chatID, access)
+}
+
+// telegramListChats renders the chats allowed to use the bot.
+func (s *Server) telegramListChats() string {
+	chats := s.telegramChats()
+	if len(chats) == 0 {
+		return "Only the telegram_user_id's chat may use the bot."
+	}
+	ids := make([]int64, 0, len(chats))
+	for id := range chats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var b strings.Builder
+	b.WriteString("Chats:")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "\n• %d %s", id, chats[id])
+	}
+	return b.String()
+}
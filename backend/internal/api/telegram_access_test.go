@@ -0,0 +1,59 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTelegramChatAccess(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	const tgUserID, groupID int64 = 424242, -1001
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"424242"`)
+	run := func(chatID, userID int64, name, args string) string {
+		return env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: chatID, UserID: userID, Name: name, Args: args})
+	}
+
+	if got := run(groupID, 7, "tasks", ""); got != "" {
+		t.Errorf("expected an unknown chat ignored, got %q", got)
+	}
+	if got := run(tgUserID, tgUserID, "chats", "-1001 owner"); !strings.HasPrefix(got, "Usage") {
+		t.Errorf("expected usage for an unknown level, got %q", got)
+	}
+	if got := run(tgUserID, tgUserID, "chats", "-1001 read-only"); got != "Chat -1001 is now read-only." {
+		t.Fatalf("unexpected /chats reply %q", got)
+	}
+	if got := run(groupID, 7, "tasks", ""); !strings.HasPrefix(got, "Tasks") {
+		t.Errorf("expected a read-only chat to list tasks, got %q", got)
+	}
+	if got := run(groupID, 7, "chats", "-1001 admin"); got != "/chats needs admin access in this chat." {
+		t.Errorf("expected /chats refused in a read-only chat, got %q", got)
+	}
+
+	project := createWorkspaceProject(t, env)
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "terminal"})
+	env.server.db.RecordTelegramMessage(groupID, 7, session.ID)
+	react := func() string {
+		return env.server.handleTelegramReaction(t.Context(), telegram.Reaction{ChatID: groupID, MessageID: 7, UserID: 7, Emoji: []string{"👍"}})
+	}
+	if got := react(); got != "" {
+		t.Errorf("expected a reaction refused in a read-only chat, got %q", got)
+	}
+	run(tgUserID, tgUserID, "chats", "-1001 operator")
+	if got := react(); got == "" {
+		t.Error("expected a reaction handled in an operator chat")
+	}
+
+	if got := run(tgUserID, tgUserID, "chats", ""); got != "Chats:\n• -1001 operator" {
+		t.Errorf("unexpected chat list %q", got)
+	}
+	if got := run(tgUserID, tgUserID, "chats", "-1001 off"); got != "Removed chat -1001." {
+		t.Errorf("unexpected removal reply %q", got)
+	}
+	if got := run(groupID, 7, "tasks", ""); got != "" {
+		t.Errorf("expected a removed chat ignored, got %q", got)
+	}
+}
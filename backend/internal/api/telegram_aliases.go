@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+// Telegram command aliases preference:
+//
+//	telegram_aliases  {"t": "/tasks view:in-progress", "go": "/session $1 claude \"continue\""}
+//
+// An alias expands into another command before it is dispatched. $1 to $9
+// stand for the alias's arguments and $* for all of them; a template with
+// neither gets the arguments appended. Aliases expand once and cannot
+// shadow built-in commands.
+const telegramAliasesPreference = "telegram_aliases"
+
+// telegramBuiltinCommands are the commands aliases cannot replace.
+var telegramBuiltinCommands = map[string]bool{"start": true, "tasks": true, "board": true, "aliases": true, "report": true, "sessions": true, "models": true, "status": true, "diff": true, "messages": true, "chats": true, "template": true}
+
+var (
+	telegramAliasName   = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+	telegramAliasArgRef = regexp.MustCompile(`\$([1-9*])`)
+)
+
+// telegramAliases returns the configured aliases, skipping invalid ones.
+func (s *Server) telegramAliases() map[string]string {
+	pref, err := s.db.GetPreference(db.DefaultUserID, telegramAliasesPreference)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(pref.Value), &raw); err != nil {
+		slog.Warn("invalid telegram_aliases preference", "error", err)
+		return nil
+	}
+	aliases := make(map[string]string, len(raw))
+	for name, template := range raw {
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "/"))
+		template = strings.TrimSpace(template)
+		if !telegramAliasName.MatchString(name) || telegramBuiltinCommands[name] || !strings.HasPrefix(template, "/") {
+			slog.Warn("ignoring invalid telegram alias", "alias", name)
+			continue
+		}
+		aliases[name] = template
+	}
+	return aliases
+}
+
+// expandTelegramAlias rewrites a command that names an alias into the
+// command the alias stands for. Other commands are returned as they are.
+// When the alias can't be expanded, it returns the reply explaining why.
+func (s *Server) expandTelegramAlias(cmd telegram.Command) (telegram.Command, string) {
+	if telegramBuiltinCommands[cmd.Name] {
+		return cmd, ""
+	}
+	template, ok := s.telegramAliases()[cmd.Name]
+	if !ok {
+		return cmd, ""
+	}
+
+	args := strings.Fields(cmd.Args)
+	var missing int
+	expanded := telegramAliasArgRef.ReplaceAllStringFunc(template, func(ref string) string {
+		if ref == "$*" {
+			return cmd.Args
+		}
+		n, _ := strconv.Atoi(ref[1:])
+		if n > len(args) {
+			missing = max(missing, n)
+			return ""
+		}
+		return args[n-1]
+	})
+	if missing > 0 {
+		return cmd, fmt.Sprintf("Usage: /%s needs %d argument(s): %s", cmd.Name, missing, template)
+	}
+	if !telegramAliasArgRef.MatchString(template) && cmd.Args != "" {
+		expanded += " " + cmd.Args
+	}
+
+	name, rest, ok := telegram.ParseCommand(expanded)
+	if !ok {
+		return cmd, "Alias /" + cmd.Name + " does not expand to a command"
+	}
+	cmd.Name, cmd.Args = name, rest
+	return cmd, ""
+}
+
+// telegramListAliases renders the configured aliases.
+func (s *Server) telegramListAliases() string {
+	aliases := s.telegramAliases()
+	if len(aliases) == 0 {
+		return "No aliases. Set them in the telegram_aliases preference."
+	}
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Aliases:")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n/%s → %s", name, aliases[name])
+	}
+	return b.String()
+}
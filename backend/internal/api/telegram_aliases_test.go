@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTelegramAliases(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"424242"`)
+	env.server.db.SetPreference(db.DefaultUserID, telegramAliasesPreference, `{
+		"t": "/tasks",
+		"v": "/tasks view:$1",
+		"all": "/tasks $*",
+		"go": "/session $1 claude \"continue\"",
+		"tasks": "/aliases",
+		"bad name": "/tasks",
+		"x": "tasks"
+	}`)
+
+	run := func(name, args string) string {
+		return env.server.handleTelegramCommand(context.Background(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: name, Args: args})
+	}
+
+	if got := run("t", ""); got != "Tasks: none" {
+		t.Errorf("/t: got %q", got)
+	}
+	if got := run("t", "view:nope"); got != "View not found: nope" {
+		t.Errorf("expected arguments appended, got %q", got)
+	}
+	if got := run("v", "nope"); got != "View not found: nope" {
+		t.Errorf("expected $1 substituted, got %q", got)
+	}
+	if got := run("v", ""); got != `Usage: /v needs 1 argument(s): /tasks view:$1` {
+		t.Errorf("expected a usage reply for missing arguments, got %q", got)
+	}
+	if got := run("all", "view:a sort:b"); got != "Unknown option: sort" {
+		t.Errorf("expected $* to pass every argument, got %q", got)
+	}
+	if got := run("go", "abc"); got != "Unknown command: /session" {
+		t.Errorf("expected the alias expanded to its command, got %q", got)
+	}
+	if got := run("tasks", ""); got != "Tasks: none" {
+		t.Errorf("expected built-in commands not to be shadowed, got %q", got)
+	}
+	if got := run("x", ""); got != "Unknown command: /x" {
+		t.Errorf("expected an alias not starting with / ignored, got %q", got)
+	}
+
+	want := "Aliases:\n/all → /tasks $*\n/go → /session $1 claude \"continue\"\n/t → /tasks\n/v → /tasks view:$1"
+	if got := run("aliases", ""); got != want {
+		t.Errorf("/aliases: got %q", got)
+	}
+
+	cmd, _ := env.server.expandTelegramAlias(telegram.Command{Name: "go", Args: "abc"})
+	if cmd.Name != "session" || cmd.Args != `abc claude "continue"` {
+		t.Errorf("unexpected expansion %+v", cmd)
+	}
+	if got := env.server.handleTelegramCommand(context.Background(), telegram.Command{ChatID: tgUserID, UserID: 1, Name: "t"}); got != "" {
+		t.Errorf("expected other users ignored, got %q", got)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/boardimage"
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+// telegramBoardTasksPerColumn caps the cards drawn in each /board column.
+const telegramBoardTasksPerColumn = 5
+
+// boardColumns are the kanban columns, in board order. The board spans
+// projects, so tasks in custom columns go under their category.
+var boardColumns = []struct {
+	status db.TaskStatus
+	title  string
+}{
+	{db.TaskStatusBacklog, "Backlog"},
+	{db.TaskStatusInProgress, "In Progress"},
+	{db.TaskStatusInReview, "In Review"},
+	{db.TaskStatusDone, "Done"},
+}
+
+// telegramBoard builds the /board snapshot. Arguments are those of /tasks;
+// a view that filters by status keeps only the columns its tasks are in. On bad arguments or
+// a failed lookup it returns the reply explaining why.
+func (s *Server) telegramBoard(args string) (boardimage.Board, string) {
+	viewRef, reply := parseTelegramViewArgs(args, "Usage: /board [view:<name>]")
+	if reply != "" {
+		return boardimage.Board{}, reply
+	}
+	filter, title, reply := s.telegramTaskFilter(viewRef)
+	if reply != "" {
+		return boardimage.Board{}, reply
+	}
+	tasks, err := s.db.ListTasks(filter)
+	if err != nil {
+		return boardimage.Board{}, "Failed to list tasks."
+	}
+
+	board := boardimage.Board{Title: title}
+	for _, column := range boardColumns {
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, column.status) &&
+			!slices.ContainsFunc(tasks, func(t *db.Task) bool { return t.Category == column.status }) {
+			continue
+		}
+		col := boardimage.Column{Title: column.title}
+		for _, t := range tasks {
+			if t.Category != column.status {
+				continue
+			}
+			col.Count++
+			if len(col.Tasks) < telegramBoardTasksPerColumn {
+				card := boardimage.Task{Title: t.Title}
+				if t.Priority != nil {
+					card.Priority = *t.Priority
+				}
+				col.Tasks = append(col.Tasks, card)
+			}
+		}
+		board.Columns = append(board.Columns, col)
+	}
+	return board, ""
+}
+
+// telegramSendBoard answers /board with a PNG snapshot of the board. If the
+// image can't be made or sent, it falls back to the /tasks text.
+func (s *Server) telegramSendBoard(ctx context.Context, cmd telegram.Command) string {
+	board, reply := s.telegramBoard(cmd.Args)
+	if reply != "" {
+		return reply
+	}
+	bot := s.currentTelegramBot()
+	if bot == nil {
+		return s.telegramListTasks(cmd.Args)
+	}
+
+	png, err := boardimage.Render(board)
+	if err == nil {
+		_, err = bot.SendPhoto(ctx, cmd.ChatID, png, "board.png", boardCaption(board))
+	}
+	if err != nil {
+		slog.Warn("failed to send board snapshot", "error", err)
+		return s.telegramListTasks(cmd.Args)
+	}
+	return ""
+}
+
+// boardCaption summarizes the board's counts, e.g. "Tasks: 3 backlog · 1 in progress".
+func boardCaption(board boardimage.Board) string {
+	counts := make([]string, len(board.Columns))
+	for i, col := range board.Columns {
+		counts[i] = fmt.Sprintf("%d %s", col.Count, strings.ToLower(col.Title))
+	}
+	return board.Title + ": " + strings.Join(counts, " · ")
+}
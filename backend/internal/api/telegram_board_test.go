@@ -0,0 +1,71 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestTelegramBoard(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, err := env.server.db.CreateProject(db.CreateProjectInput{Name: "board", Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	urgent := "urgent"
+	for i := range telegramBoardTasksPerColumn + 2 {
+		input := db.CreateTaskInput{ProjectID: project.ID, Title: "Backlog task"}
+		if i == 0 {
+			input.Priority = &urgent
+		}
+		if _, err := env.server.db.CreateTask(input); err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+	}
+	review, _ := env.server.db.CreateTask(db.CreateTaskInput{ProjectID: project.ID, Title: "Needs review"})
+	status := db.TaskStatusInReview
+	if _, err := env.server.db.UpdateTask(review.ID, db.UpdateTaskInput{Status: &status}); err != nil {
+		t.Fatalf("update task: %v", err)
+	}
+
+	board, reply := env.server.telegramBoard("")
+	if reply != "" {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+	if len(board.Columns) != 4 || board.Title != "Tasks" {
+		t.Fatalf("expected the four columns, got %+v", board)
+	}
+	backlog := board.Columns[0]
+	if backlog.Count != telegramBoardTasksPerColumn+2 || len(backlog.Tasks) != telegramBoardTasksPerColumn {
+		t.Errorf("backlog: count %d with %d cards", backlog.Count, len(backlog.Tasks))
+	}
+	if backlog.Tasks[0].Priority != "urgent" {
+		t.Errorf("expected the first card's priority, got %q", backlog.Tasks[0].Priority)
+	}
+	if got := board.Columns[2]; got.Count != 1 || got.Tasks[0].Title != "Needs review" {
+		t.Errorf("in review column: %+v", got)
+	}
+	if got := boardCaption(board); got != "Tasks: 7 backlog · 0 in progress · 1 in review · 0 done" {
+		t.Errorf("caption = %q", got)
+	}
+
+	if _, err := env.server.db.CreateSavedView(db.DefaultUserID, db.CreateSavedViewInput{
+		Name:    "review",
+		Filters: db.ViewFilters{Statuses: []db.TaskStatus{db.TaskStatusInReview}},
+	}); err != nil {
+		t.Fatalf("create view: %v", err)
+	}
+	board, _ = env.server.telegramBoard("view:review")
+	if len(board.Columns) != 1 || board.Columns[0].Title != "In Review" || board.Title != "Tasks (review)" {
+		t.Errorf("expected only the view's column, got %+v", board)
+	}
+
+	if _, reply := env.server.telegramBoard("view:nope"); reply != "View not found: nope" {
+		t.Errorf("unknown view: got %q", reply)
+	}
+	if _, reply := env.server.telegramBoard("oops"); reply != "Usage: /board [view:<name>]" {
+		t.Errorf("bad args: got %q", reply)
+	}
+}
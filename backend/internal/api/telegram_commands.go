@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+// telegramTaskListLimit caps how many tasks a /tasks reply lists.
+const telegramTaskListLimit = 20
+
+// handleTelegramCommand dispatches slash commands from the Telegram bot,
+// after expanding aliases. Commands from chats without access are ignored,
+// see telegramChatsPreference.
+func (s *Server) handleTelegramCommand(ctx context.Context, cmd telegram.Command) string {
+	if !s.telegramAllowed(cmd.ChatID, cmd.UserID, telegramReadOnly, "command /"+cmd.Name) {
+		return ""
+	}
+
+	cmd, reply := s.expandTelegramAlias(cmd)
+	if reply != "" {
+		return reply
+	}
+	if need, ok := telegramCommandAccess[cmd.Name]; ok && s.telegramChatAccess(cmd.ChatID, cmd.UserID) < need {
+		return "/" + cmd.Name + " needs " + need.String() + " access in this chat."
+	}
+
+	switch cmd.Name {
+	case "tasks":
+		return s.telegramListTasks(cmd.Args)
+	case "board":
+		return s.telegramSendBoard(ctx, cmd)
+	case "aliases":
+		return s.telegramListAliases()
+	case "report":
+		return s.telegramReport(cmd.Args)
+	case "sessions":
+		return s.telegramListSessions(cmd.Args)
+	case "models":
+		return s.telegramListModels(cmd.Args)
+	case "status":
+		return s.telegramGitStatus(ctx, cmd.Args)
+	case "diff":
+		return s.telegramTaskDiff(ctx, cmd.Args)
+	case "messages":
+		return s.telegramSessionMessages(cmd.Args)
+	case "chats":
+		return s.telegramEditChats(cmd.Args)
+	case "template":
+		return s.telegramStartTemplate(ctx, cmd.Args)
+	default:
+		return "Unknown command: /" + cmd.Name
+	}
+}
+
+// telegramListTasks renders the task board as plain text. Arguments:
+//
+//	view:<name>   apply a saved view (defaults to the user's default view, if any)
+func (s *Server) telegramListTasks(args string) string {
+	viewRef, reply := parseTelegramViewArgs(args, "Usage: /tasks [view:<name>]")
+	if reply != "" {
+		return reply
+	}
+	filter, title, reply := s.telegramTaskFilter(viewRef)
+	if reply != "" {
+		return reply
+	}
+
+	// Grouped by priority, so listed in its order.
+	filter.Sort = db.TaskSortPriority
+	tasks, err := s.db.ListTasks(filter)
+	if err != nil {
+		return "Failed to list tasks."
+	}
+	if len(tasks) == 0 {
+		return title + ": none"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d\n", title, len(tasks))
+	group := "-"
+	for i, t := range tasks {
+		if i == telegramTaskListLimit {
+			fmt.Fprintf(&b, "… and %d more", len(tasks)-telegramTaskListLimit)
+			break
+		}
+		if priority := ptrToString(t.Priority); priority != group {
+			group = priority
+			b.WriteString(priorityHeading(priority) + "\n")
+		}
+		fmt.Fprintf(&b, "• [%s] %s", t.Status, t.Title)
+		if t.Estimate != nil {
+			fmt.Fprintf(&b, " (~%s)", formatEstimate(*t.Estimate))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// priorityHeading titles a group of tasks of a priority, e.g. "P0 · urgent".
+func priorityHeading(priority string) string {
+	if i := slices.Index(db.TaskPriorities, priority); i >= 0 {
+		return fmt.Sprintf("P%d · %s", i, priority)
+	}
+	return "No priority"
+}
+
+// formatEstimate spells an estimate in minutes as e.g. "45m", "2h" or
+// "1h30m".
+func formatEstimate(minutes int) string {
+	h, m := minutes/60, minutes%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	default:
+		return fmt.Sprintf("%dh%dm", h, m)
+	}
+}
+
+// parseTelegramViewArgs reads the view:<name> argument of a task command.
+// On bad arguments it returns the reply explaining them.
+func parseTelegramViewArgs(args, usage string) (string, string) {
+	viewRef := ""
+	for _, field := range strings.Fields(args) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			return "", usage
+		}
+		switch strings.ToLower(key) {
+		case "view":
+			viewRef = value
+		default:
+			return "", "Unknown option: " + key
+		}
+	}
+	return viewRef, ""
+}
+
+// telegramTaskFilter resolves a saved view, or the default one, into a task
+// filter and a title for the reply.
+func (s *Server) telegramTaskFilter(viewRef string) (db.TaskFilter, string, string) {
+	view, err := s.resolveSavedView(viewRefOrDefault(viewRef))
+	switch {
+	case err == nil:
+		return view.Filters.TaskFilter(), "Tasks (" + view.Name + ")", ""
+	case errors.Is(err, db.ErrNotFound) && viewRef != "":
+		return db.TaskFilter{}, "", "View not found: " + viewRef
+	case !errors.Is(err, db.ErrNotFound):
+		return db.TaskFilter{}, "", "Failed to load view."
+	}
+	return db.TaskFilter{}, "Tasks", ""
+}
+
+func viewRefOrDefault(ref string) string {
+	if ref == "" {
+		return defaultViewRef
+	}
+	return ref
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+const telegramFileUsage = "Reply to a session message to send this to the agent, or caption it with /attach <task-id> to store it on a task."
+
+// uploadsDir holds files sent to sessions from outside the UI, one
+// subdirectory per session, so they stay out of the worktree's git status.
+func uploadsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "uploads")
+}
+
+// removeSessionUploads deletes files uploaded to a session.
+func removeSessionUploads(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	os.RemoveAll(filepath.Join(uploadsDir(), sessionID))
+}
+
+// handleTelegramFile handles photos, documents and voice notes sent to the
+// bot. A file replying to a message about a session is saved and passed to
+// the agent by path, which both claude and codex read as image input. A file
+// captioned "/attach <task-id>" is stored on the task. Voice notes are
+// transcribed instead, see telegramVoice.
+func (s *Server) handleTelegramFile(ctx context.Context, f telegram.File) string {
+	if !s.telegramAllowed(f.ChatID, f.UserID, telegramOperator, "file") {
+		return ""
+	}
+	if f.Size > telegram.MaxDownloadBytes {
+		return "File is too large; Telegram bots can only download files up to 20 MB."
+	}
+	if f.Voice {
+		return s.telegramVoice(ctx, f)
+	}
+
+	if name, args, ok := telegram.ParseCommand(f.Caption); ok && name == "attach" {
+		return s.telegramAttachFile(ctx, f, args)
+	}
+	if f.ReplyTo != 0 {
+		if sessionID, err := s.db.GetTelegramMessageSession(f.ChatID, f.ReplyTo); err == nil {
+			return s.telegramSendFileToSession(ctx, f, sessionID)
+		}
+	}
+	return telegramFileUsage
+}
+
+func (s *Server) telegramAttachFile(ctx context.Context, f telegram.File, args string) string {
+	taskID := strings.TrimSpace(args)
+	if taskID == "" {
+		return "Usage: /attach <task-id>"
+	}
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return "Task not found: " + taskID
+		}
+		return "Failed to load task."
+	}
+	if err := s.checkProjectWritable(task.ProjectID); errors.Is(err, errProjectArchived) {
+		return "Task's project is archived; its tasks are read-only."
+	}
+
+	data, err := f.Download(ctx)
+	if err != nil {
+		slog.Warn("telegram file download failed", "error", err)
+		return "Failed to download file: " + err.Error()
+	}
+	attachment, err := s.storeAttachment(task.ID, telegramFileName(f), f.MimeType, bytes.NewReader(data))
+	switch {
+	case errors.Is(err, errAttachmentTooLarge), errors.Is(err, errAttachmentQuota), errors.Is(err, errProjectArchived):
+		return "Failed to store attachment: " + err.Error()
+	case err != nil:
+		slog.Warn("failed to store telegram attachment", "task_id", task.ID, "error", err)
+		return "Failed to store attachment."
+	}
+	s.wsHub.BroadcastToTask(task.ID, "attachment_added", attachment)
+	return fmt.Sprintf("Attached %s to %s.", attachment.Filename, task.Title)
+}
+
+func (s *Server) telegramSendFileToSession(ctx context.Context, f telegram.File, sessionID string) string {
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return "Session no longer exists."
+	}
+	if session.Status != db.SessionStatusRunning && session.Status != db.SessionStatusWaitingInput {
+		return "Session is no longer active."
+	}
+
+	data, err := f.Download(ctx)
+	if err != nil {
+		slog.Warn("telegram file download failed", "error", err)
+		return "Failed to download file: " + err.Error()
+	}
+	path, err := writeUploadedFile(filepath.Join(uploadsDir(), session.ID), telegramFileName(f), data)
+	if err != nil {
+		slog.Warn("failed to store telegram upload", "session_id", session.ID, "error", err)
+		return "Failed to store file."
+	}
+
+	prompt := f.Caption
+	if prompt == "" {
+		prompt = "See the attached file."
+	}
+	prompt += "\n\nAttached file: " + path
+	if err := s.sendSessionMessage(session, prompt, "telegram_file"); err != nil {
+		if errors.Is(err, ErrChatTurnBusy) {
+			return "Session is busy; file not sent."
+		}
+		slog.Warn("telegram file send failed", "session_id", session.ID, "error", err)
+		return "Failed to send file: " + err.Error()
+	}
+	return "Sent to session."
+}
+
+// telegramFileName names a received file after its original name, or after
+// the message for photos, which arrive without one.
+func telegramFileName(f telegram.File) string {
+	if name := filepath.Base(f.FileName); f.FileName != "" && name != "." && name != "/" {
+		return name
+	}
+	ext := ".bin"
+	if strings.HasPrefix(f.MimeType, "image/") {
+		ext = "." + strings.TrimPrefix(f.MimeType, "image/")
+		if ext == ".jpeg" {
+			ext = ".jpg"
+		}
+	}
+	return fmt.Sprintf("telegram-%d%s", f.MessageID, ext)
+}
+
+// writeUploadedFile writes data to dir/name, adding a numeric suffix instead
+// of overwriting an existing file, and returns the path written.
+func writeUploadedFile(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for i := 2; ; i++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return "", err
+		}
+		return path, file.Close()
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+	"github.com/miguel-bm/codeburg/internal/transcribe"
+)
+
+func TestTelegramFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"`+strconv.FormatInt(tgUserID, 10)+`"`)
+
+	task, session := createRunningTaskSession(t, env, "claude")
+	if err := env.server.db.RecordTelegramMessage(tgUserID, 7, session.ID); err != nil {
+		t.Fatalf("record message: %v", err)
+	}
+
+	downloads := 0
+	photo := func(userID, replyTo int64, caption string) telegram.File {
+		return telegram.File{
+			ChatID: tgUserID, UserID: userID, MessageID: 99, ReplyTo: replyTo,
+			Caption: caption, MimeType: "image/jpeg",
+			Download: func(context.Context) ([]byte, error) {
+				downloads++
+				return []byte("jpeg"), nil
+			},
+		}
+	}
+
+	if reply := env.server.handleTelegramFile(t.Context(), photo(1, 0, "/attach "+task.ID)); reply != "" || downloads != 0 {
+		t.Fatalf("expected unauthorized file to be ignored, got %q", reply)
+	}
+	if reply := env.server.handleTelegramFile(t.Context(), photo(tgUserID, 0, "look")); reply != telegramFileUsage {
+		t.Errorf("expected usage hint, got %q", reply)
+	}
+	if reply := env.server.handleTelegramFile(t.Context(), photo(tgUserID, 0, "/attach nope")); reply != "Task not found: nope" {
+		t.Errorf("unexpected reply for unknown task: %q", reply)
+	}
+
+	reply := env.server.handleTelegramFile(t.Context(), photo(tgUserID, 0, "/attach "+task.ID))
+	if !strings.HasPrefix(reply, "Attached telegram-99.jpg") {
+		t.Fatalf("unexpected attach reply: %q", reply)
+	}
+	attachments, err := env.server.db.ListAttachments(task.ID)
+	if err != nil || len(attachments) != 1 || attachments[0].ContentType != "image/jpeg" {
+		t.Fatalf("expected one stored attachment, got %+v (%v)", attachments, err)
+	}
+	data, err := os.ReadFile(attachmentPath(attachments[0]))
+	if err != nil || string(data) != "jpeg" {
+		t.Fatalf("expected stored attachment, got %q (%v)", data, err)
+	}
+
+	// The session has no live runtime here, so the file is stored but the
+	// prompt fails to reach it.
+	reply = env.server.handleTelegramFile(t.Context(), photo(tgUserID, 7, "what is wrong here?"))
+	if !strings.HasPrefix(reply, "Failed to send file") {
+		t.Errorf("unexpected reply for session file: %q", reply)
+	}
+	if _, err := os.Stat(filepath.Join(uploadsDir(), session.ID, "telegram-99.jpg")); err != nil {
+		t.Errorf("expected upload to be stored: %v", err)
+	}
+
+	// Archived projects' tasks take no attachments.
+	if _, err := env.server.db.SetProjectArchived(task.ProjectID, true); err != nil {
+		t.Fatalf("archive project: %v", err)
+	}
+	downloads = 0
+	reply = env.server.handleTelegramFile(t.Context(), photo(tgUserID, 0, "/attach "+task.ID))
+	if reply != "Task's project is archived; its tasks are read-only." || downloads != 0 {
+		t.Errorf("unexpected attach reply for an archived project: %q", reply)
+	}
+	if attachments, _ := env.server.db.ListAttachments(task.ID); len(attachments) != 1 {
+		t.Errorf("expected no new attachment, got %d", len(attachments))
+	}
+}
+
+func TestTelegramVoice(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OPENAI_API_KEY", "")
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"`+strconv.FormatInt(tgUserID, 10)+`"`)
+	_, session := createRunningTaskSession(t, env, "claude")
+	env.server.db.RecordTelegramMessage(tgUserID, 7, session.ID)
+
+	voice := func(replyTo int64) telegram.File {
+		return telegram.File{
+			ChatID: tgUserID, UserID: tgUserID, MessageID: 99, ReplyTo: replyTo,
+			MimeType: "audio/ogg", Voice: true, Duration: 3,
+			Download: func(context.Context) ([]byte, error) { return []byte("ogg"), nil },
+		}
+	}
+
+	// OpenAI is the default provider, and there is no key.
+	if reply := env.server.handleTelegramFile(t.Context(), voice(0)); !strings.Contains(reply, "set an api key for openai") {
+		t.Errorf("expected a configuration hint, got %q", reply)
+	}
+
+	var language string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language = r.FormValue("language")
+		io.WriteString(w, `{"text":" run the tests "}`)
+	}))
+	defer srv.Close()
+	env.server.db.SetPreference(db.DefaultUserID, transcriptionPreference,
+		`{"provider":"whisper_cpp","language":"en","providers":{"whisper_cpp":{"url":"`+srv.URL+`"}}}`)
+
+	reply := env.server.handleTelegramFile(t.Context(), voice(0))
+	if !strings.HasPrefix(reply, "🎙 run the tests\n") || !strings.Contains(reply, "Reply to a session message") {
+		t.Errorf("expected the transcript echoed with a hint, got %q", reply)
+	}
+	if language != "en" {
+		t.Errorf("expected the language hint passed, got %q", language)
+	}
+
+	// The session has no live runtime here, so the transcript fails to reach it.
+	reply = env.server.handleTelegramFile(t.Context(), voice(7))
+	if !strings.HasPrefix(reply, "🎙 run the tests\n") || !strings.Contains(reply, "Failed to send") {
+		t.Errorf("unexpected reply for a session voice note: %q", reply)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, transcriptionPreference, `{"provider":"nope"}`)
+	if reply := env.server.handleTelegramFile(t.Context(), voice(0)); !strings.Contains(reply, `unknown transcription provider "nope"`) {
+		t.Errorf("unexpected reply for an unknown provider: %q", reply)
+	}
+}
+
+func TestTranscriberKeys(t *testing.T) {
+	t.Setenv("GROQ_API_KEY", "gsk-env")
+	t.Setenv("DEEPGRAM_API_KEY", "")
+	env := setupTestEnv(t)
+
+	env.server.db.SetPreference(db.DefaultUserID, transcriptionPreference, `{"provider":"groq"}`)
+	transcriber, _, err := env.server.transcriber()
+	if err != nil {
+		t.Fatalf("groq: %v", err)
+	}
+	if groq, ok := transcriber.(*transcribe.OpenAI); !ok || groq.APIKey != "gsk-env" || groq.Name() != "groq" {
+		t.Errorf("expected groq with the environment key, got %+v", transcriber)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, transcriptionPreference, `{"provider":"deepgram"}`)
+	if _, _, err := env.server.transcriber(); !errors.Is(err, errTranscriptionNotConfigured) {
+		t.Errorf("expected deepgram without a key to be unconfigured, got %v", err)
+	}
+	env.server.db.SetPreference(db.DefaultUserID, transcriptionPreference, `{"provider":"deepgram","providers":{"deepgram":{"apiKey":"dg"}}}`)
+	if transcriber, _, err := env.server.transcriber(); err != nil || transcriber.Name() != "deepgram" {
+		t.Errorf("expected deepgram, got %v, %v", transcriber, err)
+	}
+}
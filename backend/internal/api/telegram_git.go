@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/service"
+)
+
+// The bot has no LLM assistant with tools to call, so what changed in a
+// task is shown with commands instead: /status lists the changed files and
+// /diff the changes themselves, cut to fit a message.
+
+// telegramDiffLimit caps the diff a /diff reply shows, well within
+// Telegram's 4096-character message limit.
+const telegramDiffLimit = 3000
+
+// telegramGitStatus replies to /status <task-id> with the task's branch and
+// changed files.
+func (s *Server) telegramGitStatus(ctx context.Context, args string) string {
+	task, reply := s.telegramTaskArg(args, "Usage: /status <task-id>")
+	if reply != "" {
+		return reply
+	}
+	status, err := s.git().Status(ctx, task.ID)
+	if err != nil {
+		return telegramGitError(err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\nBranch: %s", task.Title, status.Branch)
+	if status.Ahead > 0 || status.Behind > 0 {
+		fmt.Fprintf(&b, " (ahead %d, behind %d)", status.Ahead, status.Behind)
+	}
+	b.WriteString("\n")
+	if len(status.Staged)+len(status.Unstaged)+len(status.Untracked) == 0 {
+		b.WriteString("No uncommitted changes.")
+		return b.String()
+	}
+	writeFiles := func(title string, files []service.GitFileEntry) {
+		if len(files) == 0 {
+			return
+		}
+		b.WriteString(title + ":\n")
+		for _, f := range files {
+			fmt.Fprintf(&b, "• %s %s (+%d -%d)\n", f.Status, f.Path, f.Additions, f.Deletions)
+		}
+	}
+	writeFiles("Staged", status.Staged)
+	writeFiles("Unstaged", status.Unstaged)
+	if len(status.Untracked) > 0 {
+		b.WriteString("Untracked:\n")
+		for _, path := range status.Untracked {
+			b.WriteString("• " + path + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// telegramTaskDiff replies to /diff <task-id> [base] [file] with the task's
+// uncommitted changes, or with base its branch's changes against its base
+// branch.
+func (s *Server) telegramTaskDiff(ctx context.Context, args string) string {
+	const usage = "Usage: /diff <task-id> [base] [file]"
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return usage
+	}
+	task, reply := s.telegramTaskArg(fields[0], usage)
+	if reply != "" {
+		return reply
+	}
+	var opts service.DiffOptions
+	for _, field := range fields[1:] {
+		switch {
+		case field == "base":
+			opts.Base = true
+		case opts.File == "":
+			opts.File = field
+		default:
+			return usage
+		}
+	}
+
+	var diff string
+	if opts.Base {
+		out, err := s.git().Diff(ctx, task.ID, opts)
+		if err != nil {
+			return telegramGitError(err)
+		}
+		diff = out
+	} else {
+		// Staged and unstaged changes together.
+		for _, staged := range []bool{true, false} {
+			opts.Staged = staged
+			out, err := s.git().Diff(ctx, task.ID, opts)
+			if err != nil {
+				return telegramGitError(err)
+			}
+			diff += out
+		}
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "No changes."
+	}
+	return truncateDiff(diff, telegramDiffLimit)
+}
+
+// truncateDiff cuts a diff to at most limit bytes at a line boundary,
+// saying how many lines were left out.
+func truncateDiff(diff string, limit int) string {
+	diff = strings.TrimRight(diff, "\n")
+	if len(diff) <= limit {
+		return diff
+	}
+	cut := strings.LastIndexByte(diff[:limit], '\n')
+	if cut < 0 {
+		cut = limit
+	}
+	omitted := strings.Count(diff[cut:], "\n")
+	return fmt.Sprintf("%s\n… %d more lines", diff[:cut], omitted)
+}
+
+// telegramTaskArg loads the task a command names by ID. On a bad argument
+// it returns the reply explaining it.
+func (s *Server) telegramTaskArg(args, usage string) (*db.Task, string) {
+	taskID := strings.TrimSpace(args)
+	if taskID == "" || strings.ContainsAny(taskID, " \t\n") {
+		return nil, usage
+	}
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, "Task not found: " + taskID
+		}
+		return nil, "Failed to load task."
+	}
+	return task, ""
+}
+
+// telegramGitError is the reply for a failed git service call.
+func telegramGitError(err error) string {
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		return svcErr.Message
+	}
+	return "Git failed: " + err.Error()
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTelegramGitCommands(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	taskID, repoPath := createTaskWithWorktree(t, env)
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"424242"`)
+	run := func(name, args string) string {
+		return env.server.handleTelegramCommand(context.Background(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: name, Args: args})
+	}
+
+	if got := run("status", ""); got != "Usage: /status <task-id>" {
+		t.Errorf("expected usage, got %q", got)
+	}
+	if got := run("status", "nope"); got != "Task not found: nope" {
+		t.Errorf("expected not found, got %q", got)
+	}
+	if got := run("status", taskID); !strings.Contains(got, "Branch: main") || !strings.Contains(got, "No uncommitted changes.") {
+		t.Errorf("unexpected clean status %q", got)
+	}
+
+	os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("# Test\nChanged\n"), 0o644)
+	os.WriteFile(filepath.Join(repoPath, "staged.txt"), []byte("new\n"), 0o644)
+	gitExecHelper(t, repoPath, "add", "staged.txt")
+	got := run("status", taskID)
+	if !strings.Contains(got, "Staged:\n• A staged.txt (+1 -0)") || !strings.Contains(got, "Unstaged:\n• M README.md (+1 -0)") {
+		t.Errorf("unexpected status %q", got)
+	}
+
+	got = run("diff", taskID)
+	if !strings.Contains(got, "+new") || !strings.Contains(got, "+Changed") {
+		t.Errorf("expected staged and unstaged changes in the diff, got %q", got)
+	}
+	if got := run("diff", taskID+" README.md"); strings.Contains(got, "staged.txt") {
+		t.Errorf("expected the diff limited to the file, got %q", got)
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	diff := strings.Repeat("+line\n", 100)
+	got := truncateDiff(diff, 60)
+	if !strings.HasSuffix(got, "… 90 more lines") || strings.Count(got, "+line") != 10 {
+		t.Errorf("unexpected truncation %q", got)
+	}
+	if got := truncateDiff("+a\n", 60); got != "+a" {
+		t.Errorf("expected a short diff kept, got %q", got)
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// /messages <session> [n] shows what an agent has been doing lately without
+// opening the web UI. The session is named by ID or ID prefix, as /sessions
+// lists them.
+const (
+	telegramMessagesDefault = 10
+	telegramMessagesMax     = 50
+	// telegramMessageTextLimit caps the characters shown of one message.
+	telegramMessageTextLimit = 300
+)
+
+// telegramSessionMessages replies to /messages with a session's last
+// messages.
+func (s *Server) telegramSessionMessages(args string) string {
+	const usage = "Usage: /messages <session-id> [count]"
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return usage
+	}
+	limit := telegramMessagesDefault
+	if len(fields) == 2 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return usage
+		}
+		limit = min(n, telegramMessagesMax)
+	}
+
+	session, reply := s.telegramSessionArg(fields[0])
+	if reply != "" {
+		return reply
+	}
+	if session.SessionType != "chat" {
+		return "Session " + shortID(session.ID) + " is a terminal session; it has no messages."
+	}
+	messages, err := s.chat.RecentMessages(session.ID, limit)
+	if err != nil {
+		return "Failed to load messages."
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s]\n", sessionLabel(session), session.Status)
+	if len(messages) == 0 {
+		b.WriteString("No messages yet.")
+		return b.String()
+	}
+	for _, msg := range messages {
+		if line := telegramMessageLine(msg); line != "" {
+			b.WriteString("• " + line + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// telegramSessionArg finds the session named by an ID or a unique ID
+// prefix. On a bad argument it returns the reply explaining it.
+func (s *Server) telegramSessionArg(ref string) (*db.AgentSession, string) {
+	session, err := s.db.GetSession(ref)
+	if err == nil {
+		return session, ""
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, "Failed to load session."
+	}
+	sessions, _, err := s.db.ListSessionsPage(db.SessionFilter{Query: ref}, db.Page{Limit: telegramTaskListLimit})
+	if err != nil {
+		return nil, "Failed to load session."
+	}
+	var match *db.AgentSession
+	for _, candidate := range sessions {
+		if !strings.HasPrefix(candidate.ID, ref) {
+			continue
+		}
+		if match != nil {
+			return nil, "Several sessions start with " + ref + "; give more of the ID."
+		}
+		match = candidate
+	}
+	if match == nil {
+		return nil, "Session not found: " + ref
+	}
+	return match, ""
+}
+
+// telegramMessageLine renders a chat message as one line of text, or ""
+// for messages not worth showing.
+func telegramMessageLine(msg ChatMessage) string {
+	switch msg.Kind {
+	case ChatMessageKindUserText:
+		return "You: " + telegramMessageText(msg.Text)
+	case ChatMessageKindAgentText:
+		if msg.IsThinking {
+			return ""
+		}
+		return "Agent: " + telegramMessageText(msg.Text)
+	case ChatMessageKindToolCall:
+		if msg.Tool == nil {
+			return ""
+		}
+		name := msg.Tool.Name
+		if msg.Tool.Title != "" {
+			name += " " + telegramMessageText(msg.Tool.Title)
+		}
+		return fmt.Sprintf("Tool: %s (%s)", name, msg.Tool.State)
+	case ChatMessageKindPermission:
+		if msg.Permission == nil {
+			return ""
+		}
+		return fmt.Sprintf("Permission: %s (%s)", msg.Permission.ToolName, msg.Permission.Status)
+	case ChatMessageKindResult:
+		if msg.Text == "" {
+			return "Turn finished"
+		}
+		return "Result: " + telegramMessageText(msg.Text)
+	default:
+		return ""
+	}
+}
+
+// telegramMessageText folds text onto one line, cut short when long.
+func telegramMessageText(text string) string {
+	return truncateTitle(strings.Join(strings.Fields(text), " "), telegramMessageTextLimit)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTelegramSessionMessages(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	project := createWorkspaceProject(t, env)
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	payloads := []string{
+		`{"kind":"user-text","text":"fix the\nlogin bug"}`,
+		`{"kind":"agent-text","text":"pondering","isThinking":true}`,
+		`{"kind":"tool-call","tool":{"callId":"c1","name":"Edit","title":"auth.go","state":"completed"}}`,
+		`{"kind":"agent-text","text":"Fixed it."}`,
+	}
+	for i, payload := range payloads {
+		env.server.db.CreateAgentMessage(db.CreateAgentMessageInput{SessionID: session.ID, Seq: int64(i + 1), Kind: "chat", PayloadJSON: payload})
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"424242"`)
+	run := func(args string) string {
+		return env.server.handleTelegramCommand(context.Background(), telegram.Command{ChatID: 424242, UserID: 424242, Name: "messages", Args: args})
+	}
+
+	got := run(session.ID[:10])
+	want := "• You: fix the login bug\n• Tool: Edit auth.go (completed)\n• Agent: Fixed it."
+	if !strings.HasSuffix(got, want) || strings.Contains(got, "pondering") {
+		t.Errorf("unexpected reply %q", got)
+	}
+	if got := run(session.ID + " 1"); !strings.HasSuffix(got, "\n• Agent: Fixed it.") || strings.Contains(got, "Edit") {
+		t.Errorf("expected only the last message, got %q", got)
+	}
+	if got := run("zzz"); got != "Session not found: zzz" {
+		t.Errorf("expected not found, got %q", got)
+	}
+	if got := run(session.ID + " many"); got != "Usage: /messages <session-id> [count]" {
+		t.Errorf("expected usage, got %q", got)
+	}
+}
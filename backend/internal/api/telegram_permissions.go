@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+// Callback data of the permission buttons is the prefix followed by the
+// provider's request ID. The session comes from the recorded message.
+const (
+	telegramAllowPrefix = "perm:allow:"
+	telegramDenyPrefix  = "perm:deny:"
+)
+
+// maxPermissionInputPreview caps how much of a tool's input is quoted in a
+// Telegram permission message.
+const maxPermissionInputPreview = 500
+
+// notifyPermissionRequest asks the user on Telegram to allow or deny a chat
+// session's tool call, with buttons that answer it. It respects the
+// telegram_attention_notifications preference.
+func (s *Server) notifyPermissionRequest(msg ChatMessage) {
+	bot := s.currentTelegramBot()
+	if bot == nil || msg.Permission == nil {
+		return
+	}
+	if pref, err := s.db.GetPreference(db.DefaultUserID, "telegram_attention_notifications"); err == nil && pref.Value == "false" {
+		return
+	}
+	chatID, ok := s.telegramChatID()
+	if !ok {
+		return
+	}
+	// Request IDs are UUIDs, but anything longer would not fit in the data.
+	if len(telegramAllowPrefix+msg.Permission.RequestID) > 64 {
+		return
+	}
+
+	title := "A session"
+	if session, err := s.db.GetSession(msg.SessionID); err == nil && session.TaskID != "" {
+		if task, err := s.db.GetTask(session.TaskID); err == nil {
+			title = task.Title
+		}
+	}
+	text := "🔐 " + title + " wants to use " + msg.Permission.ToolName
+	if preview := permissionInputPreview(msg.Permission.Input); preview != "" {
+		text += "\n" + preview
+	}
+
+	messageID, err := bot.SendWithButtons(chatID, text, [][]telegram.Button{{
+		{Text: "✅ Allow", Data: telegramAllowPrefix + msg.Permission.RequestID},
+		{Text: "❌ Deny", Data: telegramDenyPrefix + msg.Permission.RequestID},
+	}})
+	if err != nil {
+		slog.Warn("failed to send permission request to telegram", "session_id", msg.SessionID, "error", err)
+		return
+	}
+	if err := s.db.RecordTelegramMessage(chatID, messageID, msg.SessionID); err != nil {
+		slog.Warn("failed to record telegram message", "session_id", msg.SessionID, "error", err)
+	}
+}
+
+// permissionInputPreview shows a tool's input compactly: the command for
+// shell tools, else the JSON input, truncated.
+func permissionInputPreview(input any) string {
+	var preview string
+	if fields, ok := input.(map[string]any); ok {
+		preview = firstNonEmpty(asString(fields["command"]), asString(fields["file_path"]))
+	}
+	if preview == "" && input != nil {
+		if data, err := json.Marshal(input); err == nil {
+			preview = string(data)
+		}
+	}
+	preview = strings.TrimSpace(preview)
+	if len(preview) > maxPermissionInputPreview {
+		preview = preview[:maxPermissionInputPreview] + "…"
+	}
+	return preview
+}
+
+// handleTelegramCallback answers a permission request from its Telegram
+// buttons.
+func (s *Server) handleTelegramCallback(ctx context.Context, c telegram.Callback) string {
+	if !s.telegramAllowed(c.ChatID, c.UserID, telegramOperator, "permission answer") {
+		return ""
+	}
+	var allow bool
+	var requestID string
+	switch {
+	case strings.HasPrefix(c.Data, telegramAllowPrefix):
+		allow, requestID = true, strings.TrimPrefix(c.Data, telegramAllowPrefix)
+	case strings.HasPrefix(c.Data, telegramDenyPrefix):
+		requestID = strings.TrimPrefix(c.Data, telegramDenyPrefix)
+	default:
+		return ""
+	}
+	sessionID, err := s.db.GetTelegramMessageSession(c.ChatID, c.MessageID)
+	if err != nil {
+		return "Unknown request."
+	}
+
+	_, err = s.chat.RespondPermission(sessionID, requestID, allow, "")
+	switch {
+	case errors.Is(err, ErrPermissionRequestNotFound), errors.Is(err, ErrChatSessionNotFound):
+		return "Unknown request."
+	case errors.Is(err, ErrPermissionRequestResolved):
+		return "Already answered or expired."
+	case err != nil:
+		slog.Warn("telegram permission response failed", "session_id", sessionID, "error", err)
+		return "Failed: " + err.Error()
+	case allow:
+		return "Allowed."
+	default:
+		return "Denied."
+	}
+}
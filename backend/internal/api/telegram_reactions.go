@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+// defaultTelegramReactions maps reaction emoji to the reply sent to the
+// session. Override per user with the telegram_reactions preference, a JSON
+// object of emoji to reply text (an empty text disables that emoji).
+var defaultTelegramReactions = map[string]string{
+	"👍": "Looks good, continue.",
+	"👎": "Stop and explain what you are doing and why.",
+}
+
+// currentTelegramBot returns the running bot, or nil.
+func (s *Server) currentTelegramBot() *telegram.Bot {
+	s.telegramBotMu.Lock()
+	defer s.telegramBotMu.Unlock()
+	return s.telegramBot
+}
+
+// telegramChatID returns the configured telegram_user_id, which is also the
+// chat ID of the user's private chat with the bot.
+func (s *Server) telegramChatID() (int64, bool) {
+	pref, err := s.db.GetPreference(db.DefaultUserID, "telegram_user_id")
+	if err != nil {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(unquotePreference(pref.Value), 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// telegramReactionMap returns the user's reaction mapping.
+func (s *Server) telegramReactionMap() map[string]string {
+	pref, err := s.db.GetPreference(db.DefaultUserID, "telegram_reactions")
+	if err != nil {
+		return defaultTelegramReactions
+	}
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(pref.Value), &mapping); err != nil {
+		slog.Warn("invalid telegram_reactions preference", "error", err)
+		return defaultTelegramReactions
+	}
+	return mapping
+}
+
+// handleTelegramReaction sends the reply mapped to a reaction emoji to the
+// session the reacted-to message was about.
+func (s *Server) handleTelegramReaction(ctx context.Context, r telegram.Reaction) string {
+	if !s.telegramAllowed(r.ChatID, r.UserID, telegramOperator, "reaction") {
+		return ""
+	}
+	sessionID, err := s.db.GetTelegramMessageSession(r.ChatID, r.MessageID)
+	if err != nil {
+		return "" // not a message about a session
+	}
+
+	mapping := s.telegramReactionMap()
+	reply := ""
+	for _, emoji := range r.Emoji {
+		if reply = mapping[emoji]; reply != "" {
+			break
+		}
+	}
+	if reply == "" {
+		return ""
+	}
+
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return "Session no longer exists."
+	}
+	if session.Status != db.SessionStatusRunning && session.Status != db.SessionStatusWaitingInput {
+		return "Session is no longer active."
+	}
+	if err := s.sendSessionMessage(session, reply, "telegram_reaction"); err != nil {
+		if errors.Is(err, ErrChatTurnBusy) {
+			return "Session is busy; reply not sent."
+		}
+		slog.Warn("telegram reaction reply failed", "session_id", sessionID, "error", err)
+		return "Failed to send reply: " + err.Error()
+	}
+	return "Sent: " + reply
+}
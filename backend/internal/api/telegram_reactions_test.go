@@ -0,0 +1,71 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTelegramReaction(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"`+strconv.FormatInt(tgUserID, 10)+`"`)
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "React"}), &task)
+
+	session, err := env.server.db.CreateSession(db.CreateSessionInput{
+		TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "terminal",
+	})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := env.server.db.RecordTelegramMessage(tgUserID, 7, session.ID); err != nil {
+		t.Fatalf("record message: %v", err)
+	}
+	react := func(userID, messageID int64, emoji ...string) string {
+		return env.server.handleTelegramReaction(t.Context(), telegram.Reaction{
+			ChatID: tgUserID, MessageID: messageID, UserID: userID, Emoji: emoji,
+		})
+	}
+
+	if reply := react(1, 7, "👍"); reply != "" {
+		t.Errorf("expected no reply for unauthorized user, got %q", reply)
+	}
+	if reply := react(tgUserID, 8, "👍"); reply != "" {
+		t.Errorf("expected no reply for unmapped message, got %q", reply)
+	}
+	if reply := react(tgUserID, 7, "🎉"); reply != "" {
+		t.Errorf("expected no reply for unmapped emoji, got %q", reply)
+	}
+
+	completed := db.SessionStatusCompleted
+	env.server.db.UpdateSession(session.ID, db.UpdateSessionInput{Status: &completed})
+	if reply := react(tgUserID, 7, "👍"); reply != "Session is no longer active." {
+		t.Errorf("unexpected reply for inactive session: %q", reply)
+	}
+
+	// The session is marked running but has no live runtime here, so the
+	// reply reaches sendSessionMessage and fails there.
+	running := db.SessionStatusRunning
+	env.server.db.UpdateSession(session.ID, db.UpdateSessionInput{Status: &running})
+	if reply := react(tgUserID, 7, "👎"); !strings.HasPrefix(reply, "Failed to send reply") {
+		t.Errorf("expected send failure, got %q", reply)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_reactions", `{"👍": "", "🚀": "Ship it."}`)
+	if reply := react(tgUserID, 7, "👍"); reply != "" {
+		t.Errorf("expected disabled emoji to be ignored, got %q", reply)
+	}
+	if reply := react(tgUserID, 7, "🚀"); !strings.HasPrefix(reply, "Failed to send reply") {
+		t.Errorf("expected custom emoji to be mapped, got %q", reply)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Telegram webhook preference:
+//
+//	telegram_webhook_url  "https://codeburg.example.com"
+//
+// With an https auth.origin, the bot asks Telegram to post updates to
+// <origin>/api/telegram/webhook instead of long-polling for them, which
+// cuts latency and keeps no connection open while idle. The preference
+// overrides the origin, for a public URL other than the one users open,
+// and set to "" keeps the bot polling. The URL must be public HTTPS
+// reachable by Telegram. Each start registers a new secret token, which
+// updates must carry; a start Telegram refuses the webhook on polls until
+// the bot next starts, as it does when the origin changes.
+const telegramWebhookPreference = "telegram_webhook_url"
+
+// telegramWebhookPath is where Telegram posts updates in webhook mode.
+const telegramWebhookPath = "/api/telegram/webhook"
+
+// telegramWebhook returns the webhook URL to register and a fresh secret,
+// or false to long-poll.
+func (s *Server) telegramWebhook() (string, string, bool) {
+	origin := s.webOrigin()
+	if pref, err := s.db.GetPreference(db.DefaultUserID, telegramWebhookPreference); err == nil {
+		origin = unquotePreference(pref.Value)
+	}
+	origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+	if origin == "" {
+		return "", "", false
+	}
+	if u, err := url.Parse(origin); err != nil || u.Scheme != "https" || u.Host == "" {
+		slog.Info("telegram bot polling: webhook URL is not https", "url", origin)
+		return "", "", false
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", false
+	}
+	return origin + telegramWebhookPath, hex.EncodeToString(b), true
+}
+
+// handleTelegramWebhook passes an update Telegram posted to the bot, which
+// checks its secret token.
+func (s *Server) handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
+	bot := s.currentTelegramBot()
+	if bot == nil {
+		writeError(w, http.StatusNotFound, "telegram bot not running")
+		return
+	}
+	bot.ServeHTTP(w, r)
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestTelegramWebhook(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	if resp := env.post("/api/telegram/webhook", map[string]any{"update_id": 1}); resp.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a running bot, got %d", resp.Code)
+	}
+
+	if _, _, ok := env.server.telegramWebhook(); ok {
+		t.Error("expected polling without an origin")
+	}
+	setTestOrigin(t, env)
+	webhookURL, secret, ok := env.server.telegramWebhook()
+	if !ok || webhookURL != testOrigin+telegramWebhookPath || len(secret) != 64 {
+		t.Errorf("expected the webhook at the origin, got %q %q %v", webhookURL, secret, ok)
+	}
+	if _, again, _ := env.server.telegramWebhook(); again == secret {
+		t.Error("expected a fresh secret per start")
+	}
+
+	// The preference overrides the origin.
+	env.server.db.SetPreference(db.DefaultUserID, telegramWebhookPreference, `"https://bot.example.com/"`)
+	if webhookURL, _, ok := env.server.telegramWebhook(); !ok || webhookURL != "https://bot.example.com/api/telegram/webhook" {
+		t.Errorf("expected the preference's webhook, got %q %v", webhookURL, ok)
+	}
+	env.server.db.SetPreference(db.DefaultUserID, telegramWebhookPreference, `"http://bot.example.com"`)
+	if _, _, ok := env.server.telegramWebhook(); ok {
+		t.Error("expected a plain http URL ignored")
+	}
+	env.server.db.SetPreference(db.DefaultUserID, telegramWebhookPreference, `""`)
+	if _, _, ok := env.server.telegramWebhook(); ok {
+		t.Error("expected an empty preference to keep polling")
+	}
+}
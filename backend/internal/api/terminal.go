@@ -33,7 +33,7 @@ const (
 //   - session: codeburg session ID (required)
 func (s *Server) handleTerminalWS(w http.ResponseWriter, r *http.Request) {
 	token := authTokenFromWSRequest(r)
-	if token == "" || !s.auth.ValidateToken(token) {
+	if !s.validLoginToken(token) && !s.ambientLogin(r) {
 		writeError(w, http.StatusUnauthorized, "invalid token")
 		return
 	}
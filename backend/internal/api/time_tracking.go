@@ -0,0 +1,381 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Time spent on tasks is tracked from sessions: an entry opens when a
+// session starts running and closes when it stops, whatever the reason.
+// Time entered by hand adds to it. Totals are split by provider, with
+// manual entries under "manual".
+const (
+	manualTimeProvider = "manual"
+	maxManualTimeEntry = 24 * time.Hour
+	maxTimeEntryNote   = 500
+)
+
+// timeTotals sums time entries, in seconds.
+type timeTotals struct {
+	TotalSeconds int64            `json:"totalSeconds"`
+	ByProvider   map[string]int64 `json:"byProvider"`
+}
+
+func (t *timeTotals) add(e *db.TimeEntry, d time.Duration) {
+	if t.ByProvider == nil {
+		t.ByProvider = make(map[string]int64)
+	}
+	seconds := int64(d / time.Second)
+	t.TotalSeconds += seconds
+	provider := e.Provider
+	if e.Manual() || provider == "" {
+		provider = manualTimeProvider
+	}
+	t.ByProvider[provider] += seconds
+}
+
+func sumTimeEntries(entries []*db.TimeEntry, since, until, now time.Time) timeTotals {
+	totals := timeTotals{ByProvider: make(map[string]int64)}
+	for _, e := range entries {
+		totals.add(e, e.DurationWithin(since, until, now))
+	}
+	return totals
+}
+
+// trackSessionTime opens or closes a session's time entry as it enters or
+// leaves the running state. Sessions found orphaned after a restart stopped
+// at their last activity, not when they were found.
+func (s *Server) trackSessionTime(sessionID string, from, to db.SessionStatus, source string) {
+	now := time.Now()
+	switch {
+	case to == db.SessionStatusRunning:
+		session, err := s.db.GetSession(sessionID)
+		if err != nil {
+			return
+		}
+		if err := s.db.StartSessionTime(session.ID, session.ProjectID, session.TaskID, session.Provider, now); err != nil {
+			slog.Warn("failed to start session time", "session_id", sessionID, "error", err)
+		}
+	case from == db.SessionStatusRunning:
+		if source == "reconcile" {
+			if session, err := s.db.GetSession(sessionID); err == nil && session.LastActivityAt != nil {
+				now = *session.LastActivityAt
+			}
+		}
+		if err := s.db.StopSessionTime(sessionID, now); err != nil {
+			slog.Warn("failed to stop session time", "session_id", sessionID, "error", err)
+		}
+	}
+}
+
+type createTimeEntryRequest struct {
+	Minutes   int        `json:"minutes"`
+	Note      string     `json:"note"`
+	StartedAt *time.Time `json:"startedAt,omitempty"` // defaults to minutes ago
+}
+
+type taskTimeResponse struct {
+	timeTotals
+	Entries []*db.TimeEntry `json:"entries"`
+}
+
+func (s *Server) handleGetTaskTime(w http.ResponseWriter, r *http.Request) {
+	task, err := s.db.GetTask(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	entries, err := s.db.ListTimeEntries(db.TimeEntryFilter{TaskID: task.ID})
+	if err != nil {
+		writeListError(w, err, "time entries")
+		return
+	}
+	writeJSON(w, http.StatusOK, taskTimeResponse{
+		timeTotals: sumTimeEntries(entries, time.Time{}, time.Time{}, time.Now()),
+		Entries:    entries,
+	})
+}
+
+func (s *Server) handleCreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	task, err := s.db.GetTask(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "task")
+		return
+	}
+	var req createTimeEntryRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	duration := time.Duration(req.Minutes) * time.Minute
+	if duration <= 0 || duration > maxManualTimeEntry {
+		writeError(w, http.StatusBadRequest, "minutes must be between 1 and 1440")
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+	if len(note) > maxTimeEntryNote {
+		writeError(w, http.StatusBadRequest, "note must be at most 500 bytes")
+		return
+	}
+	startedAt := time.Now().Add(-duration)
+	if req.StartedAt != nil {
+		startedAt = *req.StartedAt
+	}
+
+	entry, err := s.db.CreateTimeEntry(db.CreateTimeEntryInput{
+		ProjectID: task.ProjectID,
+		TaskID:    task.ID,
+		StartedAt: startedAt,
+		Duration:  duration,
+		Note:      note,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create time entry")
+		return
+	}
+	writeJSON(w, http.StatusCreated, entry)
+}
+
+// handleDeleteTimeEntry deletes a manual time entry. Session time can't be
+// deleted.
+func (s *Server) handleDeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.db.GetTimeEntry(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "time entry")
+		return
+	}
+	if !entry.Manual() {
+		writeError(w, http.StatusConflict, "only manual time entries can be deleted")
+		return
+	}
+	if err := s.db.DeleteTimeEntry(entry.ID); err != nil {
+		writeDBError(w, err, "time entry")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type taskTimeSummary struct {
+	TaskID       string `json:"taskId,omitempty"` // empty for project sessions
+	Title        string `json:"title,omitempty"`
+	TotalSeconds int64  `json:"totalSeconds"`
+}
+
+type projectTimeSummary struct {
+	ProjectID string `json:"projectId"`
+	Name      string `json:"name"`
+	timeTotals
+	Tasks []taskTimeSummary `json:"tasks"`
+}
+
+func (s *Server) handleGetProjectTime(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	entries, err := s.db.ListTimeEntries(db.TimeEntryFilter{ProjectID: project.ID})
+	if err != nil {
+		writeListError(w, err, "time entries")
+		return
+	}
+	summaries := s.summarizeTime(entries, time.Time{}, time.Time{}, time.Now())
+	if len(summaries) == 0 {
+		writeJSON(w, http.StatusOK, projectTimeSummary{
+			ProjectID:  project.ID,
+			Name:       project.Name,
+			timeTotals: timeTotals{ByProvider: map[string]int64{}},
+			Tasks:      []taskTimeSummary{},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, summaries[0])
+}
+
+type weeklyTimeReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	timeTotals
+	Projects []projectTimeSummary `json:"projects"`
+}
+
+// handleGetWeeklyTime summarizes the time spent in the week (from Monday)
+// holding date, this week by default, in the tz time zone (UTC by default).
+func (s *Server) handleGetWeeklyTime(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	loc := time.UTC
+	if tz := q.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			writeError(w, http.StatusBadRequest, "unknown time zone")
+			return
+		}
+	}
+	day := time.Now().In(loc)
+	if raw := q.Get("date"); raw != "" {
+		var err error
+		if day, err = time.ParseInLocation("2006-01-02", raw, loc); err != nil {
+			writeError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	report, err := s.weeklyTimeReport(day, time.Now())
+	if err != nil {
+		writeListError(w, err, "time entries")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// weekStart returns midnight of the Monday of t's week, in t's location.
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	day := t.AddDate(0, 0, -offset)
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func (s *Server) weeklyTimeReport(day, now time.Time) (weeklyTimeReport, error) {
+	start := weekStart(day)
+	end := start.AddDate(0, 0, 7)
+	entries, err := s.db.ListTimeEntries(db.TimeEntryFilter{Since: start, Until: end})
+	if err != nil {
+		return weeklyTimeReport{}, err
+	}
+	report := weeklyTimeReport{
+		Start:      start,
+		End:        end,
+		timeTotals: sumTimeEntries(entries, start, end, now),
+		Projects:   s.summarizeTime(entries, start, end, now),
+	}
+	return report, nil
+}
+
+// summarizeTime totals entries per project and task, the most time first.
+func (s *Server) summarizeTime(entries []*db.TimeEntry, since, until, now time.Time) []projectTimeSummary {
+	byProject := make(map[string]*projectTimeSummary)
+	taskSeconds := make(map[string]map[string]int64)
+	for _, e := range entries {
+		d := e.DurationWithin(since, until, now)
+		if d <= 0 {
+			continue
+		}
+		summary := byProject[e.ProjectID]
+		if summary == nil {
+			summary = &projectTimeSummary{ProjectID: e.ProjectID, timeTotals: timeTotals{ByProvider: map[string]int64{}}}
+			byProject[e.ProjectID] = summary
+			taskSeconds[e.ProjectID] = make(map[string]int64)
+		}
+		summary.add(e, d)
+		taskSeconds[e.ProjectID][e.TaskID] += int64(d / time.Second)
+	}
+
+	summaries := make([]projectTimeSummary, 0, len(byProject))
+	for projectID, summary := range byProject {
+		if project, err := s.db.GetProject(projectID); err == nil {
+			summary.Name = project.Name
+		}
+		summary.Tasks = make([]taskTimeSummary, 0, len(taskSeconds[projectID]))
+		for taskID, seconds := range taskSeconds[projectID] {
+			task := taskTimeSummary{TaskID: taskID, TotalSeconds: seconds}
+			if taskID != "" {
+				if t, err := s.db.GetTask(taskID); err == nil {
+					task.Title = t.Title
+				}
+			}
+			summary.Tasks = append(summary.Tasks, task)
+		}
+		sort.Slice(summary.Tasks, func(i, j int) bool {
+			if summary.Tasks[i].TotalSeconds != summary.Tasks[j].TotalSeconds {
+				return summary.Tasks[i].TotalSeconds > summary.Tasks[j].TotalSeconds
+			}
+			return summary.Tasks[i].TaskID < summary.Tasks[j].TaskID
+		})
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].TotalSeconds != summaries[j].TotalSeconds {
+			return summaries[i].TotalSeconds > summaries[j].TotalSeconds
+		}
+		return summaries[i].ProjectID < summaries[j].ProjectID
+	})
+	return summaries
+}
+
+// telegramReportTasks caps how many tasks a /report reply lists per project.
+const telegramReportTasks = 5
+
+// telegramReport renders the weekly time report. Arguments: "last" for last
+// week instead of this one.
+func (s *Server) telegramReport(args string) string {
+	now := time.Now()
+	day := now
+	switch strings.TrimSpace(strings.ToLower(args)) {
+	case "":
+	case "last":
+		day = now.AddDate(0, 0, -7)
+	default:
+		return "Usage: /report [last]"
+	}
+	report, err := s.weeklyTimeReport(day, now)
+	if err != nil {
+		return "Failed to load time entries."
+	}
+
+	title := "Week of " + report.Start.Format("Jan 2")
+	if report.TotalSeconds == 0 {
+		return title + ": no time tracked"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", title, formatTrackedTime(report.TotalSeconds))
+	if providers := formatProviderTime(report.ByProvider); providers != "" {
+		b.WriteString(providers + "\n")
+	}
+	for _, project := range report.Projects {
+		fmt.Fprintf(&b, "\n%s: %s\n", firstNonEmpty(project.Name, project.ProjectID), formatTrackedTime(project.TotalSeconds))
+		for i, task := range project.Tasks {
+			if i == telegramReportTasks {
+				fmt.Fprintf(&b, "… and %d more\n", len(project.Tasks)-telegramReportTasks)
+				break
+			}
+			fmt.Fprintf(&b, "• %s: %s\n", firstNonEmpty(task.Title, "(project sessions)"), formatTrackedTime(task.TotalSeconds))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatProviderTime lists the time per provider, the most first.
+func formatProviderTime(byProvider map[string]int64) string {
+	providers := make([]string, 0, len(byProvider))
+	for provider, seconds := range byProvider {
+		if seconds > 0 {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if byProvider[providers[i]] != byProvider[providers[j]] {
+			return byProvider[providers[i]] > byProvider[providers[j]]
+		}
+		return providers[i] < providers[j]
+	})
+	parts := make([]string, len(providers))
+	for i, provider := range providers {
+		parts[i] = provider + " " + formatTrackedTime(byProvider[provider])
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatTrackedTime renders seconds as "45m" or "3h05m".
+func formatTrackedTime(seconds int64) string {
+	minutes := seconds / 60
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/sessionlifecycle"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+)
+
+func TestTaskTimeTracking(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "shop", "path": createTestGitRepo(t)}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Checkout flow"}), &task)
+
+	// A session's time runs from running until it waits for input.
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	status, _, err := env.server.applySessionTransition(session.ID, session.Status, sessionlifecycle.EventSessionStarted, task.ID, "test")
+	if err != nil || status != db.SessionStatusRunning {
+		t.Fatalf("expected running, got %s, %v", status, err)
+	}
+	entries, _ := env.server.db.ListTimeEntries(db.TimeEntryFilter{TaskID: task.ID})
+	if len(entries) != 1 || entries[0].Provider != "claude" || entries[0].EndedAt != nil {
+		t.Fatalf("expected an open claude entry, got %+v", entries)
+	}
+	if _, _, err := env.server.applySessionTransition(session.ID, status, sessionlifecycle.EventNotificationWaiting, task.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = env.server.db.ListTimeEntries(db.TimeEntryFilter{TaskID: task.ID})
+	if len(entries) != 1 || entries[0].EndedAt == nil {
+		t.Fatalf("expected the entry closed, got %+v", entries)
+	}
+
+	for _, body := range []map[string]any{{"minutes": 0}, {"minutes": 2000}, {"minutes": 5, "note": strings.Repeat("x", 501)}} {
+		if resp := env.post("/api/tasks/"+task.ID+"/time", body); resp.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", body, resp.Code)
+		}
+	}
+	// Started this week, so the weekly report counts all of it.
+	resp := env.post("/api/tasks/"+task.ID+"/time", map[string]any{"minutes": 45, "note": "pairing", "startedAt": weekStart(time.Now())})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", resp.Code, resp.Body.String())
+	}
+	var manual db.TimeEntry
+	decodeResponse(t, resp, &manual)
+
+	var taskTime struct {
+		TotalSeconds int64            `json:"totalSeconds"`
+		ByProvider   map[string]int64 `json:"byProvider"`
+		Entries      []db.TimeEntry   `json:"entries"`
+	}
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/time"), &taskTime)
+	if len(taskTime.Entries) != 2 || taskTime.ByProvider["manual"] != 45*60 || taskTime.TotalSeconds < 45*60 {
+		t.Fatalf("unexpected task time %+v", taskTime)
+	}
+
+	var projectTime projectTimeSummary
+	decodeResponse(t, env.get("/api/projects/"+project.ID+"/time"), &projectTime)
+	if projectTime.Name != "shop" || len(projectTime.Tasks) != 1 || projectTime.Tasks[0].Title != "Checkout flow" {
+		t.Fatalf("unexpected project time %+v", projectTime)
+	}
+
+	var weekly weeklyTimeReport
+	decodeResponse(t, env.get("/api/time/weekly?tz=Europe/Madrid"), &weekly)
+	if weekly.Start.Weekday() != time.Monday || weekly.End.Sub(weekly.Start) < 167*time.Hour || len(weekly.Projects) != 1 {
+		t.Fatalf("unexpected weekly report %+v", weekly)
+	}
+	if resp := env.get("/api/time/weekly?date=last-week"); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad date, got %d", resp.Code)
+	}
+	decodeResponse(t, env.get("/api/time/weekly?date=2020-01-01"), &weekly)
+	if weekly.TotalSeconds != 0 || len(weekly.Projects) != 0 || weekly.Start.Format("2006-01-02") != "2019-12-30" {
+		t.Errorf("expected an empty week from Monday 2019-12-30, got %+v", weekly)
+	}
+
+	const tgUserID int64 = 424242
+	env.server.db.SetPreference(db.DefaultUserID, "telegram_user_id", `"`+strconv.FormatInt(tgUserID, 10)+`"`)
+	reply := env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: "report"})
+	if !strings.Contains(reply, "shop: 45m") || !strings.Contains(reply, "• Checkout flow: 45m") || !strings.Contains(reply, "manual 45m") {
+		t.Errorf("unexpected report:\n%s", reply)
+	}
+	if reply := env.server.handleTelegramCommand(t.Context(), telegram.Command{ChatID: tgUserID, UserID: tgUserID, Name: "report", Args: "next"}); !strings.HasPrefix(reply, "Usage") {
+		t.Errorf("expected usage, got %q", reply)
+	}
+
+	if resp := env.delete("/api/time/" + entries[0].ID); resp.Code != http.StatusConflict {
+		t.Errorf("expected session time to be kept, got %d", resp.Code)
+	}
+	if resp := env.delete("/api/time/" + manual.ID); resp.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.Code)
+	}
+}
+
+func TestFormatTrackedTime(t *testing.T) {
+	for seconds, want := range map[int64]string{59: "0m", 45 * 60: "45m", 3600: "1h00m", 3*3600 + 5*60 + 30: "3h05m"} {
+		if got := formatTrackedTime(seconds); got != want {
+			t.Errorf("formatTrackedTime(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
+)
+
+// traceRequests wraps each HTTP request in a server span named after its
+// route, continuing the caller's trace when a traceparent header is present.
+// WebSocket upgrades are skipped: their span would last the whole connection.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !telemetry.Enabled() || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := telemetry.ContextWithTraceParent(r.Context(), r.Header.Get("traceparent"))
+		ctx, span := telemetry.StartKind(ctx, telemetry.KindServer, r.Method,
+			telemetry.String("http.request.method", r.Method),
+			telemetry.String("url.path", r.URL.Path),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(telemetry.Int("http.response.status_code", status))
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(telemetry.String("http.route", rctx.RoutePattern()))
+		}
+		if status >= http.StatusInternalServerError {
+			span.RecordError(errHTTPStatus(status))
+		}
+	})
+}
+
+type errHTTPStatus int
+
+func (e errHTTPStatus) Error() string { return http.StatusText(int(e)) }
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/telemetry"
+)
+
+func TestTraceRequests_NamesSpanAfterRoute(t *testing.T) {
+	var mu sync.Mutex
+	var exported []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		exported = append(exported, string(body))
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	shutdown := telemetry.Setup(telemetry.Config{Endpoint: collector.URL, ServiceName: "codeburg-test"})
+	resp := env.get("/api/projects")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(exported) == 0 {
+		t.Fatal("expected spans to be exported on shutdown")
+	}
+	all := strings.Join(exported, "\n")
+	if !json.Valid([]byte(exported[0])) || !strings.Contains(all, `"name":"GET /api/projects"`) {
+		t.Errorf("expected a span named after the route, got %s", all)
+	}
+	if !strings.Contains(all, `"http.response.status_code"`) {
+		t.Errorf("expected the status code attribute, got %s", all)
+	}
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Chat transcript retention preference:
+//
+//	transcript_retention  {"maxAgeDays": 90, "maxMessagesPerSession": 5000, "archive": true, "vacuumIntervalDays": 7}
+//
+// Chat messages older than maxAgeDays, and those before the latest
+// maxMessagesPerSession of a session, are pruned once a day by a job; 0
+// keeps them. With archive, pruned messages are first appended to a JSONL
+// file per session under ~/.codeburg/archive/transcripts. The job also
+// VACUUMs the database every vacuumIntervalDays (0 never), so that the
+// space of pruned and deleted rows is given back. When each last ran is
+// kept in a preference, so restarts don't repeat them.
+const (
+	transcriptRetentionPreference = "transcript_retention"
+	transcriptsPrunedPreference   = "transcripts_pruned_at"
+	databaseVacuumedPreference    = "database_vacuumed_at"
+
+	defaultVacuumIntervalDays = 7
+	transcriptPruneInterval   = 24 * time.Hour
+	transcriptCheckInterval   = time.Hour
+)
+
+type transcriptRetention struct {
+	MaxAgeDays            int  `json:"maxAgeDays"`
+	MaxMessagesPerSession int  `json:"maxMessagesPerSession"`
+	Archive               bool `json:"archive"`
+	VacuumIntervalDays    int  `json:"vacuumIntervalDays"`
+}
+
+// transcriptPruneJobPayload asks a prune to vacuum whether or not it is due.
+type transcriptPruneJobPayload struct {
+	Vacuum bool `json:"vacuum,omitempty"`
+}
+
+// transcriptPruneResult is what a prune job did.
+type transcriptPruneResult struct {
+	Sessions   int   `json:"sessions"`
+	Deleted    int64 `json:"deleted"`
+	Archived   int   `json:"archived"`
+	Vacuumed   bool  `json:"vacuumed"`
+	SizeBefore int64 `json:"sizeBefore"`
+	SizeAfter  int64 `json:"sizeAfter"`
+}
+
+func transcriptArchiveRoot() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codeburg", "archive", "transcripts")
+}
+
+// transcriptRetention reads the transcript_retention preference over the
+// defaults, which keep every message.
+func (s *Server) transcriptRetention() transcriptRetention {
+	settings := transcriptRetention{VacuumIntervalDays: defaultVacuumIntervalDays}
+	if pref, err := s.db.GetPreference(db.DefaultUserID, transcriptRetentionPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &settings); err != nil {
+			slog.Warn("invalid transcript_retention preference", "error", err)
+		}
+	}
+	settings.MaxAgeDays = max(settings.MaxAgeDays, 0)
+	settings.MaxMessagesPerSession = max(settings.MaxMessagesPerSession, 0)
+	settings.VacuumIntervalDays = max(settings.VacuumIntervalDays, 0)
+	return settings
+}
+
+func (r transcriptRetention) cutoff(now time.Time) time.Time {
+	if r.MaxAgeDays == 0 {
+		return time.Time{}
+	}
+	return now.Add(-time.Duration(r.MaxAgeDays) * 24 * time.Hour)
+}
+
+func (r transcriptRetention) vacuumDue(now, last time.Time) bool {
+	return r.VacuumIntervalDays > 0 && now.Sub(last) >= time.Duration(r.VacuumIntervalDays)*24*time.Hour
+}
+
+// runTranscriptRetention queues the prune job when it is due.
+func (s *Server) runTranscriptRetention(ctx context.Context) {
+	ticker := time.NewTicker(transcriptCheckInterval)
+	defer ticker.Stop()
+
+	s.queueTranscriptPruneIfDue(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.queueTranscriptPruneIfDue(now)
+		}
+	}
+}
+
+// queueTranscriptPruneIfDue queues the prune job when a day has passed since
+// the last prune or the database is due a vacuum, and no prune job is
+// already waiting or running.
+func (s *Server) queueTranscriptPruneIfDue(now time.Time) {
+	settings := s.transcriptRetention()
+	pruneDue := (settings.MaxAgeDays > 0 || settings.MaxMessagesPerSession > 0) &&
+		now.Sub(s.preferenceTime(transcriptsPrunedPreference)) >= transcriptPruneInterval
+	if !pruneDue && !settings.vacuumDue(now, s.preferenceTime(databaseVacuumedPreference)) {
+		return
+	}
+	if active, err := s.db.HasActiveJob(jobKindTranscriptPrune); err != nil || active {
+		return
+	}
+	if _, err := s.enqueueJob(jobKindTranscriptPrune, "", nil, 3); err != nil {
+		slog.Warn("failed to queue transcript pruning", "error", err)
+	}
+}
+
+// runTranscriptPruneJob prunes chat messages past the retention policy,
+// archiving them first if asked, and vacuums the database when due.
+func (s *Server) runTranscriptPruneJob(ctx context.Context, job *db.Job, progress func(string)) (any, error) {
+	var payload transcriptPruneJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	now := time.Now()
+	settings := s.transcriptRetention()
+	var result transcriptPruneResult
+	result.SizeBefore, _ = s.db.SizeBytes()
+
+	sessions, err := s.db.SessionsWithExpiredMessages(settings.cutoff(now), settings.MaxMessagesPerSession)
+	if err != nil {
+		return nil, err
+	}
+	for i, sessionID := range sessions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress(fmt.Sprintf("pruning session %d of %d", i+1, len(sessions)))
+		messages, err := s.db.ExpiredAgentMessages(sessionID, settings.cutoff(now), settings.MaxMessagesPerSession)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		if settings.Archive {
+			if err := archiveTranscript(sessionID, messages); err != nil {
+				return nil, fmt.Errorf("archive session %s: %w", sessionID, err)
+			}
+			result.Archived += len(messages)
+		}
+		ids := make([]string, len(messages))
+		for i, msg := range messages {
+			ids[i] = msg.ID
+		}
+		deleted, err := s.db.DeleteAgentMessages(ids)
+		if err != nil {
+			return nil, err
+		}
+		result.Sessions++
+		result.Deleted += deleted
+	}
+	s.setPreferenceTime(transcriptsPrunedPreference, now)
+
+	if payload.Vacuum || settings.vacuumDue(now, s.preferenceTime(databaseVacuumedPreference)) {
+		progress("vacuuming the database")
+		if err := s.db.Vacuum(); err != nil {
+			return nil, err
+		}
+		result.Vacuumed = true
+		s.setPreferenceTime(databaseVacuumedPreference, now)
+	}
+	result.SizeAfter, _ = s.db.SizeBytes()
+	return result, nil
+}
+
+// archiveTranscript appends messages to the session's archive file, one
+// JSON object per line.
+func archiveTranscript(sessionID string, messages []*db.AgentMessage) error {
+	if err := os.MkdirAll(transcriptArchiveRoot(), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(transcriptArchiveRoot(), sessionID+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	// The messages are deleted next, so the archive must be on disk first.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Server) preferenceTime(key string) time.Time {
+	pref, err := s.db.GetPreference(db.DefaultUserID, key)
+	if err != nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, unquotePreference(pref.Value))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (s *Server) setPreferenceTime(key string, t time.Time) {
+	if _, err := s.db.SetPreference(db.DefaultUserID, key, `"`+t.UTC().Format(time.RFC3339)+`"`); err != nil {
+		slog.Warn("failed to record preference time", "key", key, "error", err)
+	}
+}
+
+// handlePruneTranscripts queues a prune now. vacuum=true vacuums the
+// database whether or not it is due.
+func (s *Server) handlePruneTranscripts(w http.ResponseWriter, r *http.Request) {
+	if active, err := s.db.HasActiveJob(jobKindTranscriptPrune); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to check jobs")
+		return
+	} else if active {
+		writeError(w, http.StatusConflict, "transcript pruning is already queued")
+		return
+	}
+	payload := transcriptPruneJobPayload{Vacuum: r.URL.Query().Get("vacuum") == "true"}
+	job, err := s.enqueueJob(jobKindTranscriptPrune, "", payload, 1)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue transcript pruning")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestTranscriptPruning(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	project, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "chats", Path: t.TempDir()})
+	session, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	for seq := int64(1); seq <= 5; seq++ {
+		env.server.db.CreateAgentMessage(db.CreateAgentMessageInput{SessionID: session.ID, Seq: seq, Kind: "agent-text", PayloadJSON: "{}"})
+	}
+
+	// Without a policy, nothing is due until the first vacuum.
+	env.server.db.SetPreference(db.DefaultUserID, transcriptRetentionPreference, `{"vacuumIntervalDays":0}`)
+	env.server.queueTranscriptPruneIfDue(time.Now())
+	if active, _ := env.server.db.HasActiveJob(jobKindTranscriptPrune); active {
+		t.Fatal("expected no prune without a retention policy")
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, transcriptRetentionPreference, `{"maxMessagesPerSession":2,"archive":true,"vacuumIntervalDays":0}`)
+	env.server.queueTranscriptPruneIfDue(time.Now())
+	runDueJobs(env)
+
+	messages, _ := env.server.db.ListAgentMessagesBySession(session.ID)
+	if len(messages) != 2 || messages[0].Seq != 4 {
+		t.Fatalf("expected the latest two messages kept, got %d", len(messages))
+	}
+	f, err := os.Open(filepath.Join(transcriptArchiveRoot(), session.ID+".jsonl"))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer f.Close()
+	var archived []db.AgentMessage
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var msg db.AgentMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("archive line: %v", err)
+		}
+		archived = append(archived, msg)
+	}
+	if len(archived) != 3 || archived[0].Seq != 1 || archived[2].Seq != 3 {
+		t.Fatalf("expected the three pruned messages archived, got %+v", archived)
+	}
+
+	// Pruned today, so not due again.
+	env.server.queueTranscriptPruneIfDue(time.Now())
+	if active, _ := env.server.db.HasActiveJob(jobKindTranscriptPrune); active {
+		t.Error("expected no second prune on the same day")
+	}
+
+	resp := env.post("/api/transcripts/prune?vacuum=true", nil)
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("prune: %d %s", resp.Code, resp.Body.String())
+	}
+	var job db.Job
+	decodeResponse(t, resp, &job)
+	if resp := env.post("/api/transcripts/prune", nil); resp.Code != http.StatusConflict {
+		t.Errorf("expected 409 while a prune is queued, got %d", resp.Code)
+	}
+	runDueJobs(env)
+
+	done, _ := env.server.db.GetJob(job.ID)
+	var result transcriptPruneResult
+	if done.Status != db.JobSucceeded || json.Unmarshal(done.Result, &result) != nil {
+		t.Fatalf("expected the job to succeed, got %+v", done)
+	}
+	if !result.Vacuumed || result.Deleted != 0 || result.SizeAfter <= 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if env.server.preferenceTime(databaseVacuumedPreference).IsZero() {
+		t.Error("expected the vacuum time recorded")
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Transcript streaming posts finalized chat messages of selected sessions to
+// an external webhook, batched in near real-time. Configure it with the
+// transcript_webhook preference:
+//
+//	{"url": "https://...", "secret": "...", "all": false,
+//	 "sessions": ["<session id>"], "projects": ["<project id>"]}
+//
+// When a secret is set each request carries X-Codeburg-Signature:
+// sha256=<hex HMAC-SHA256 of the body>.
+const (
+	transcriptWebhookPreference = "transcript_webhook"
+	transcriptQueueSize         = 1024
+	transcriptBatchSize         = 50
+	transcriptFlushInterval     = time.Second
+	transcriptMaxAttempts       = 3
+)
+
+type transcriptWebhookConfig struct {
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret,omitempty"`
+	All      bool     `json:"all,omitempty"`
+	Sessions []string `json:"sessions,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+}
+
+func (c *transcriptWebhookConfig) selects(session *db.AgentSession) bool {
+	return c.All || slices.Contains(c.Sessions, session.ID) || slices.Contains(c.Projects, session.ProjectID)
+}
+
+// transcriptEvent is one streamed message with its session context.
+type transcriptEvent struct {
+	ProjectID string      `json:"projectId"`
+	TaskID    string      `json:"taskId,omitempty"`
+	Message   ChatMessage `json:"message"`
+}
+
+type transcriptStreamer struct {
+	db     *db.DB
+	queue  chan ChatMessage
+	client *http.Client
+	retry  time.Duration // base backoff between delivery attempts
+}
+
+func newTranscriptStreamer(database *db.DB) *transcriptStreamer {
+	return &transcriptStreamer{
+		db:     database,
+		queue:  make(chan ChatMessage, transcriptQueueSize),
+		client: &http.Client{Timeout: 10 * time.Second},
+		retry:  time.Second,
+	}
+}
+
+// enqueue hands a finalized message to the streamer without blocking the
+// chat turn; messages are dropped if the endpoint cannot keep up.
+func (t *transcriptStreamer) enqueue(msg ChatMessage) {
+	select {
+	case t.queue <- msg:
+	default:
+		slog.Warn("transcript stream queue full, dropping message", "session_id", msg.SessionID, "seq", msg.Seq)
+	}
+}
+
+// run batches queued messages until ctx is cancelled.
+func (t *transcriptStreamer) run(ctx context.Context) {
+	ticker := time.NewTicker(transcriptFlushInterval)
+	defer ticker.Stop()
+
+	var batch []ChatMessage
+	flush := func() {
+		if len(batch) > 0 {
+			t.flush(batch)
+			batch = nil
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case msg := <-t.queue:
+			batch = append(batch, msg)
+			if len(batch) >= transcriptBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (t *transcriptStreamer) config() (*transcriptWebhookConfig, bool) {
+	pref, err := t.db.GetPreference(db.DefaultUserID, transcriptWebhookPreference)
+	if err != nil {
+		return nil, false
+	}
+	var cfg transcriptWebhookConfig
+	if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+		slog.Warn("invalid transcript_webhook preference", "error", err)
+		return nil, false
+	}
+	return &cfg, cfg.URL != ""
+}
+
+// flush delivers the messages of selected sessions, if a webhook is configured.
+func (t *transcriptStreamer) flush(batch []ChatMessage) {
+	cfg, ok := t.config()
+	if !ok {
+		return
+	}
+
+	sessions := make(map[string]*db.AgentSession)
+	events := make([]transcriptEvent, 0, len(batch))
+	for _, msg := range batch {
+		session, seen := sessions[msg.SessionID]
+		if !seen {
+			session, _ = t.db.GetSession(msg.SessionID)
+			sessions[msg.SessionID] = session
+		}
+		if session == nil || !cfg.selects(session) {
+			continue
+		}
+		events = append(events, transcriptEvent{ProjectID: session.ProjectID, TaskID: session.TaskID, Message: msg})
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(map[string]any{"event": "chat.messages", "messages": events})
+	if err != nil {
+		slog.Warn("failed to encode transcript batch", "error", err)
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		err = t.post(cfg, body)
+		if err == nil {
+			return
+		}
+		if attempt == transcriptMaxAttempts {
+			slog.Warn("transcript webhook delivery failed, dropping batch", "messages", len(events), "error", err)
+			return
+		}
+		time.Sleep(t.retry * time.Duration(attempt))
+	}
+}
+
+func (t *transcriptStreamer) post(cfg *transcriptWebhookConfig, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Codeburg-Transcripts")
+	if cfg.Secret != "" {
+		req.Header.Set("X-Codeburg-Signature", "sha256="+hex.EncodeToString(hmacSHA256([]byte(cfg.Secret), body)))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
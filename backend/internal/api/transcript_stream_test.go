@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestChatManager_FinalizedHookSkipsRunningToolCalls(t *testing.T) {
+	manager, state := setupChatManagerState(t, "claude")
+	var finalized []ChatMessage
+	manager.SetFinalizedHook(func(msg ChatMessage) { finalized = append(finalized, msg) })
+
+	manager.appendMessage(state, ChatMessage{Kind: ChatMessageKindAgentText, Text: "hello"})
+	manager.startToolCall(state, "claude", "call-1", "Bash", "Run", "", nil, nil)
+	if len(finalized) != 1 {
+		t.Fatalf("expected only the text message to be final, got %d", len(finalized))
+	}
+
+	manager.finishToolCall(state, "claude", "call-1", "ok", false)
+	if len(finalized) != 2 || finalized[1].Tool == nil || finalized[1].Tool.State != ChatToolStateCompleted {
+		t.Fatalf("expected the completed tool call to be final, got %+v", finalized)
+	}
+}
+
+func TestTranscriptStreamer_Flush(t *testing.T) {
+	env := setupTestEnv(t)
+	database := env.server.db
+
+	var received atomic.Value
+	var attempts atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // retried
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		want := "sha256=" + hex.EncodeToString(hmacSHA256([]byte("s3cret"), body))
+		if r.Header.Get("X-Codeburg-Signature") != want {
+			t.Errorf("bad signature %q", r.Header.Get("X-Codeburg-Signature"))
+		}
+		received.Store(body)
+	}))
+	defer hook.Close()
+
+	project, _ := database.CreateProject(db.CreateProjectInput{Name: "p", Path: t.TempDir()})
+	selected, _ := database.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	other, _ := database.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "codex", SessionType: "chat"})
+
+	streamer := newTranscriptStreamer(database)
+	streamer.retry = 0
+	batch := []ChatMessage{
+		{SessionID: selected.ID, Seq: 1, Kind: ChatMessageKindAgentText, Text: "one"},
+		{SessionID: other.ID, Seq: 1, Kind: ChatMessageKindAgentText, Text: "skipped"},
+	}
+
+	// Without configuration nothing is sent.
+	streamer.flush(batch)
+	if attempts.Load() != 0 {
+		t.Fatal("expected no delivery without a configured webhook")
+	}
+
+	cfg, _ := json.Marshal(transcriptWebhookConfig{URL: hook.URL, Secret: "s3cret", Sessions: []string{selected.ID}})
+	database.SetPreference(db.DefaultUserID, transcriptWebhookPreference, string(cfg))
+	streamer.flush(batch)
+
+	body, _ := received.Load().([]byte)
+	var payload struct {
+		Event    string            `json:"event"`
+		Messages []transcriptEvent `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("decode payload: %v (attempts %d)", err, attempts.Load())
+	}
+	if payload.Event != "chat.messages" || len(payload.Messages) != 1 {
+		t.Fatalf("unexpected payload: %s", body)
+	}
+	if m := payload.Messages[0]; m.ProjectID != project.ID || m.Message.Text != "one" {
+		t.Errorf("unexpected event: %+v", m)
+	}
+}
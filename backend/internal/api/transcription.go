@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telegram"
+	"github.com/miguel-bm/codeburg/internal/transcribe"
+)
+
+// Transcription preference:
+//
+//	transcription  {"provider": "openai" | "groq" | "deepgram" | "whisper_cpp",
+//	                "language": "en",
+//	                "providers": {"groq": {"apiKey": "...", "model": "..."},
+//	                              "whisper_cpp": {"url": "http://127.0.0.1:8080"}}}
+//
+// Without it, voice notes go to OpenAI. API keys left out of the preference
+// fall back to openai_api_key or OPENAI_API_KEY, GROQ_API_KEY and
+// DEEPGRAM_API_KEY.
+const (
+	transcriptionPreference = "transcription"
+	transcriptionTimeout    = 2 * time.Minute
+)
+
+const (
+	transcriptionOpenAI     = "openai"
+	transcriptionGroq       = "groq"
+	transcriptionDeepgram   = "deepgram"
+	transcriptionWhisperCpp = "whisper_cpp"
+)
+
+type transcriptionConfig struct {
+	Provider  string                                 `json:"provider,omitempty"`
+	Language  string                                 `json:"language,omitempty"`
+	Providers map[string]transcriptionProviderConfig `json:"providers,omitempty"`
+}
+
+type transcriptionProviderConfig struct {
+	APIKey string `json:"apiKey,omitempty"`
+	Model  string `json:"model,omitempty"`
+	// URL is the server of whisper_cpp, or another base URL for openai.
+	URL string `json:"url,omitempty"`
+}
+
+var errTranscriptionNotConfigured = errors.New("transcription is not configured")
+
+// transcriber returns the configured speech-to-text provider and the
+// language hint to pass it.
+func (s *Server) transcriber() (transcribe.Transcriber, string, error) {
+	var cfg transcriptionConfig
+	if pref, err := s.db.GetPreference(db.DefaultUserID, transcriptionPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			return nil, "", fmt.Errorf("invalid transcription preference: %w", err)
+		}
+	}
+	provider := firstNonEmpty(cfg.Provider, transcriptionOpenAI)
+	settings := cfg.Providers[provider]
+
+	var t transcribe.Transcriber
+	apiKey := settings.APIKey
+	switch provider {
+	case transcriptionOpenAI:
+		if apiKey == "" {
+			apiKey = s.openAIKey()
+		}
+		t = &transcribe.OpenAI{BaseURL: settings.URL, APIKey: apiKey, Model: settings.Model}
+	case transcriptionGroq:
+		apiKey = firstNonEmpty(apiKey, os.Getenv("GROQ_API_KEY"))
+		t = transcribe.NewGroq(apiKey, settings.Model)
+	case transcriptionDeepgram:
+		apiKey = firstNonEmpty(apiKey, os.Getenv("DEEPGRAM_API_KEY"))
+		t = &transcribe.Deepgram{APIKey: apiKey, Model: settings.Model}
+	case transcriptionWhisperCpp:
+		return &transcribe.WhisperCpp{URL: settings.URL}, cfg.Language, nil
+	default:
+		return nil, "", fmt.Errorf("unknown transcription provider %q", provider)
+	}
+	if apiKey == "" {
+		return nil, "", fmt.Errorf("%w: set an api key for %s", errTranscriptionNotConfigured, provider)
+	}
+	return t, cfg.Language, nil
+}
+
+// openAIKey returns the openai_api_key preference, or OPENAI_API_KEY.
+func (s *Server) openAIKey() string {
+	if pref, err := s.db.GetPreference(db.DefaultUserID, "openai_api_key"); err == nil {
+		if key := unquotePreference(pref.Value); key != "" {
+			return key
+		}
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// telegramVoice transcribes a voice note. Replying to a message about a
+// session sends the transcript to the agent; otherwise it is only echoed.
+func (s *Server) telegramVoice(ctx context.Context, f telegram.File) string {
+	transcriber, language, err := s.transcriber()
+	if err != nil {
+		return "Voice notes are unavailable: " + err.Error()
+	}
+	// Without a language of its own, transcribe in the chat's language.
+	language = firstNonEmpty(language, s.telegramLanguage())
+
+	var session *db.AgentSession
+	if f.ReplyTo != 0 {
+		if sessionID, err := s.db.GetTelegramMessageSession(f.ChatID, f.ReplyTo); err == nil {
+			if session, err = s.db.GetSession(sessionID); err != nil {
+				return "Session no longer exists."
+			}
+			if session.Status != db.SessionStatusRunning && session.Status != db.SessionStatusWaitingInput {
+				return "Session is no longer active."
+			}
+		}
+	}
+
+	data, err := f.Download(ctx)
+	if err != nil {
+		slog.Warn("telegram voice download failed", "error", err)
+		return "Failed to download voice note: " + err.Error()
+	}
+	ctx, cancel := context.WithTimeout(ctx, transcriptionTimeout)
+	defer cancel()
+	text, err := transcriber.Transcribe(ctx, transcribe.Audio{
+		Data:     data,
+		FileName: fmt.Sprintf("voice-%d.ogg", f.MessageID),
+		MimeType: f.MimeType,
+		Language: language,
+	})
+	if err != nil {
+		slog.Warn("voice transcription failed", "provider", transcriber.Name(), "error", err)
+		return "Failed to transcribe voice note: " + err.Error()
+	}
+	if text == "" {
+		return "Couldn't make out any speech."
+	}
+
+	if session == nil {
+		return "🎙 " + text + "\n\n" + localize(s.telegramLanguage(), msgVoiceHint)
+	}
+	s.awaitVoiceReply(session, f.ChatID)
+	if err := s.sendSessionMessage(session, text, telegramVoiceSource); err != nil {
+		s.voiceRepliesTo.Delete(session.ID)
+		if errors.Is(err, ErrChatTurnBusy) {
+			return "🎙 " + text + "\n\nSession is busy; not sent."
+		}
+		slog.Warn("telegram voice send failed", "session_id", session.ID, "error", err)
+		return "🎙 " + text + "\n\nFailed to send: " + err.Error()
+	}
+	return "🎙 " + text + "\n\nSent to session."
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/tunnel"
+)
+
+const (
+	defaultTunnelTTL    = 4 * time.Hour
+	maxTunnelTTL        = 24 * time.Hour
+	tunnelWatchInterval = 30 * time.Second
+	// tunnelIdleChecks is how many consecutive checks a tunneled port may
+	// fail before its tunnel is torn down, so dev server restarts survive.
+	tunnelIdleChecks = 4
+
+	// tunnelLongOpenPreference is how many minutes a tunnel may stay open
+	// before clients are warned about it; 0 turns the warning off.
+	tunnelLongOpenPreference     = "tunnel_long_open_minutes"
+	defaultTunnelLongOpenMinutes = 120
+)
+
+// tunnelPortListening reports whether something accepts connections on a
+// local port. Replaced in tests.
+var tunnelPortListening = func(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// tunnelTTL converts a requested lifetime in minutes, defaulting when unset.
+func tunnelTTL(minutes *int) (time.Duration, error) {
+	if minutes == nil {
+		return defaultTunnelTTL, nil
+	}
+	ttl := time.Duration(*minutes) * time.Minute
+	if ttl <= 0 || ttl > maxTunnelTTL {
+		return 0, fmt.Errorf("ttlMinutes must be between 1 and %d", int(maxTunnelTTL/time.Minute))
+	}
+	return ttl, nil
+}
+
+// watchTunnels tears down tunnels whose port has stopped listening, and
+// health checks the rest.
+func (s *Server) watchTunnels(ctx context.Context) {
+	ticker := time.NewTicker(tunnelWatchInterval)
+	defer ticker.Stop()
+
+	misses := make(map[string]int)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkTunnelPorts(misses)
+			s.warnLongOpenTunnels(time.Now())
+			s.tunnels.CheckHealth(ctx)
+		}
+	}
+}
+
+// checkTunnelPorts runs one watch pass. misses counts consecutive failed
+// checks per tunnel ID and is carried between passes.
+func (s *Server) checkTunnelPorts(misses map[string]int) {
+	for _, info := range idleTunnels(s.tunnels.List(), misses) {
+		if err := s.tunnels.Stop(info.ID); err != nil {
+			slog.Warn("failed to stop idle tunnel", "tunnel_id", info.ID, "error", err)
+			continue
+		}
+		s.tunnelClosed(info, fmt.Sprintf("nothing is listening on port %d anymore", info.Port))
+	}
+}
+
+// tunnelLongOpenAfter reads how long a tunnel may stay open before it is
+// flagged, or 0 when the warning is off.
+func (s *Server) tunnelLongOpenAfter() time.Duration {
+	minutes := defaultTunnelLongOpenMinutes
+	if pref, err := s.db.GetPreference(db.DefaultUserID, tunnelLongOpenPreference); err == nil {
+		n, err := strconv.Atoi(unquotePreference(pref.Value))
+		if err != nil || n < 0 {
+			slog.Warn("invalid tunnel_long_open_minutes preference", "value", pref.Value)
+		} else {
+			minutes = n
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// warnLongOpenTunnels flags tunnels that have been open for longer than
+// the tunnel_long_open_minutes preference, once each, and tells clients
+// and the Telegram chat, since a forgotten public URL is an exposure.
+func (s *Server) warnLongOpenTunnels(now time.Time) {
+	after := s.tunnelLongOpenAfter()
+	if after <= 0 {
+		return
+	}
+	for _, t := range s.tunnels.List() {
+		if !t.WarnIfOpenSince(now.Add(-after)) {
+			continue
+		}
+		info := t.Info()
+		openFor := now.Sub(info.CreatedAt).Round(time.Minute)
+		slog.Info("tunnel open for a long time", "tunnel_id", info.ID, "port", info.Port, "open_for", openFor)
+		payload := map[string]any{"tunnel": info, "openFor": int(openFor / time.Minute)}
+		if info.TaskID != "" {
+			s.wsHub.BroadcastToTask(info.TaskID, "tunnel_warning", payload)
+		} else {
+			s.wsHub.BroadcastGlobal("tunnel_warning", payload)
+		}
+		url := firstNonEmpty(info.ShareURL, info.URL)
+		go s.sendTunnelTelegram(localize(s.telegramLanguage(), msgTunnelLongOpen,
+			formatTunnelAge(openFor), s.tunnelLabel(info), info.Port, url))
+	}
+}
+
+// formatTunnelAge renders how long a tunnel has been open, e.g. "2h" or
+// "1h30m".
+func formatTunnelAge(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	hours, minutes := minutes/60, minutes%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
+
+// idleTunnels updates misses from the current port state and returns the
+// tunnels that reached tunnelIdleChecks.
+func idleTunnels(tunnels []*tunnel.Tunnel, misses map[string]int) []tunnel.TunnelInfo {
+	var idle []tunnel.TunnelInfo
+	active := make(map[string]bool, len(tunnels))
+	for _, t := range tunnels {
+		active[t.ID] = true
+		if tunnelPortListening(t.Port) {
+			delete(misses, t.ID)
+			continue
+		}
+		misses[t.ID]++
+		if misses[t.ID] >= tunnelIdleChecks {
+			delete(misses, t.ID)
+			idle = append(idle, t.Info())
+		}
+	}
+	for id := range misses {
+		if !active[id] {
+			delete(misses, id)
+		}
+	}
+	return idle
+}
+
+// stopTaskTunnels tears down a task's tunnels and reports why.
+func (s *Server) stopTaskTunnels(taskID, reason string) {
+	for _, t := range s.tunnels.ListForTask(taskID) {
+		info := t.Info()
+		if err := s.tunnels.Stop(t.ID); err != nil {
+			slog.Warn("failed to stop tunnel", "task_id", taskID, "tunnel_id", t.ID, "error", err)
+			continue
+		}
+		s.tunnelClosed(info, reason)
+	}
+}
+
+// tunnelClosed tells clients and the Telegram chat that a tunnel went away
+// without the user stopping it.
+func (s *Server) tunnelClosed(info tunnel.TunnelInfo, reason string) {
+	slog.Info("tunnel closed", "tunnel_id", info.ID, "port", info.Port, "reason", reason)
+	payload := map[string]any{"tunnel": info, "reason": reason}
+	if info.TaskID != "" {
+		s.wsHub.BroadcastToTask(info.TaskID, "tunnel_closed", payload)
+	} else {
+		s.wsHub.BroadcastGlobal("tunnel_closed", payload)
+	}
+	go s.sendTunnelTelegram(localize(s.telegramLanguage(), msgTunnelClosed,
+		s.tunnelLabel(info), info.Port, info.URL, reason))
+}
+
+// tunnelReconnected tells clients and the Telegram chat that a dropped
+// tunnel is back, since quick tunnels come back on a new URL.
+func (s *Server) tunnelReconnected(info tunnel.TunnelInfo) {
+	payload := map[string]any{"tunnel": info}
+	if info.TaskID != "" {
+		s.wsHub.BroadcastToTask(info.TaskID, "tunnel_reconnected", payload)
+	} else {
+		s.wsHub.BroadcastGlobal("tunnel_reconnected", payload)
+	}
+	url := firstNonEmpty(info.ShareURL, info.URL)
+	go s.sendTunnelTelegram(localize(s.telegramLanguage(), msgTunnelReconnected, s.tunnelLabel(info), info.Port, url))
+}
+
+func (s *Server) notifyTunnelOpened(info tunnel.TunnelInfo) {
+	url := info.URL
+	if info.ShareURL != "" {
+		url = info.ShareURL
+	}
+	lang := s.telegramLanguage()
+	text := localize(lang, msgTunnelOpen, s.tunnelLabel(info), info.Port, url)
+	if info.ExpiresAt != nil {
+		text += "\n" + localize(lang, msgTunnelExpires, info.ExpiresAt.Format("15:04 MST"))
+	}
+	s.sendTunnelTelegram(text)
+}
+
+// tunnelLabel names what a tunnel belongs to: its task, else its project.
+func (s *Server) tunnelLabel(info tunnel.TunnelInfo) string {
+	if info.TaskID != "" {
+		if task, err := s.db.GetTask(info.TaskID); err == nil {
+			return task.Title
+		}
+	}
+	if info.ProjectID != "" {
+		if project, err := s.db.GetProject(info.ProjectID); err == nil {
+			return project.Name
+		}
+	}
+	return "tunnel " + info.ID
+}
+
+func (s *Server) sendTunnelTelegram(text string) {
+	bot := s.currentTelegramBot()
+	if bot == nil {
+		return
+	}
+	if pref, err := s.db.GetPreference(db.DefaultUserID, "telegram_tunnel_notifications"); err == nil && pref.Value == "false" {
+		return
+	}
+	chatID, ok := s.telegramChatID()
+	if !ok {
+		return
+	}
+	if _, err := bot.Send(chatID, text); err != nil {
+		slog.Warn("failed to send tunnel notification", "error", err)
+	}
+}
@@ -1,14 +1,77 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/miguel-bm/codeburg/internal/db"
 	"github.com/miguel-bm/codeburg/internal/tunnel"
 	"github.com/oklog/ulid/v2"
 )
 
+// Tunnel providers preference:
+//
+//	tunnel_providers  {"provider": "cloudflared" | "tailscale" | "ngrok",
+//	                   "providers": {"ngrok": {"authToken": "..."}}}
+//
+// provider is the default for new tunnels, which a create request may
+// override; without it, tunnels.provider in the configuration file is. Without
+// an ngrok token, ngrok falls back to NGROK_AUTHTOKEN and its own config file.
+const tunnelProvidersPreference = "tunnel_providers"
+
+type tunnelProvidersConfig struct {
+	Provider  string                          `json:"provider,omitempty"`
+	Providers map[string]tunnelProviderConfig `json:"providers,omitempty"`
+}
+
+type tunnelProviderConfig struct {
+	AuthToken string `json:"authToken,omitempty"`
+}
+
+func (s *Server) tunnelProvidersConfig() tunnelProvidersConfig {
+	var cfg tunnelProvidersConfig
+	if pref, err := s.db.GetPreference(db.DefaultUserID, tunnelProvidersPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			slog.Warn("invalid tunnel_providers preference", "error", err)
+		}
+	}
+	cfg.Provider = firstNonEmpty(cfg.Provider, s.currentConfig().Tunnels.Provider)
+	return cfg
+}
+
+// tunnelProviderOptions fills in the provider for a new tunnel, the
+// requested one or the configured default, and its credentials.
+func (s *Server) tunnelProviderOptions(requested string, opts *tunnel.Options) {
+	cfg := s.tunnelProvidersConfig()
+	opts.Provider = firstNonEmpty(requested, cfg.Provider, tunnel.DefaultProvider)
+	opts.AuthToken = cfg.Providers[opts.Provider].AuthToken
+}
+
+// handleListTunnelProviders lists the tunnel providers, whether each is
+// installed and has credentials stored, and which is the default.
+func (s *Server) handleListTunnelProviders(w http.ResponseWriter, r *http.Request) {
+	type providerResponse struct {
+		tunnel.ProviderStatus
+		Configured bool `json:"configured"` // has an auth token stored
+		Default    bool `json:"default"`
+	}
+	cfg := s.tunnelProvidersConfig()
+	defaultProvider := firstNonEmpty(cfg.Provider, tunnel.DefaultProvider)
+	providers := make([]providerResponse, 0)
+	for _, status := range s.tunnels.Providers() {
+		providers = append(providers, providerResponse{
+			ProviderStatus: status,
+			Configured:     cfg.Providers[status.Name].AuthToken != "",
+			Default:        status.Name == defaultProvider,
+		})
+	}
+	writeJSON(w, http.StatusOK, providers)
+}
+
 // handleListTunnels lists all tunnels for a task
 func (s *Server) handleListTunnels(w http.ResponseWriter, r *http.Request) {
 	taskID := chi.URLParam(r, "id")
@@ -22,41 +85,36 @@ func (s *Server) handleListTunnels(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, infos)
 }
 
+// createTunnelRequest is the body accepted by the tunnel create endpoints.
+type createTunnelRequest struct {
+	Port       int            `json:"port"`
+	Provider   string         `json:"provider,omitempty"`   // overrides the tunnel_providers default
+	Auth       *db.TunnelAuth `json:"auth,omitempty"`       // overrides the project default
+	TTLMinutes *int           `json:"ttlMinutes,omitempty"` // defaults to defaultTunnelTTL
+}
+
 // handleCreateTunnel creates a new tunnel
 func (s *Server) handleCreateTunnel(w http.ResponseWriter, r *http.Request) {
 	taskID := chi.URLParam(r, "id")
 
-	var input struct {
-		Port int `json:"port"`
-	}
+	var input createTunnelRequest
 	if err := decodeJSON(r, &input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	if input.Port <= 0 || input.Port > 65535 {
-		writeError(w, http.StatusBadRequest, "invalid port")
+	task, err := s.db.GetTask(taskID)
+	if err != nil {
+		writeDBError(w, err, "task")
 		return
 	}
-
-	// Generate tunnel ID
-	id := ulid.Make().String()
-
-	t, err := s.tunnels.Create(id, taskID, input.Port)
+	project, err := s.db.GetProject(task.ProjectID)
 	if err != nil {
-		var conflict *tunnel.PortConflictError
-		if errors.As(err, &conflict) {
-			writeJSON(w, http.StatusConflict, map[string]interface{}{
-				"error":          conflict.Error(),
-				"existingTunnel": conflict.Existing,
-			})
-			return
-		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDBError(w, err, "project")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, t.Info())
+	s.openTunnel(w, taskID, project, input)
 }
 
 // handleListProjectTunnels lists all tunnels for a project
@@ -76,22 +134,52 @@ func (s *Server) handleListProjectTunnels(w http.ResponseWriter, r *http.Request
 func (s *Server) handleCreateProjectTunnel(w http.ResponseWriter, r *http.Request) {
 	projectID := chi.URLParam(r, "id")
 
-	var input struct {
-		Port int `json:"port"`
-	}
+	var input createTunnelRequest
 	if err := decodeJSON(r, &input); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
+	project, err := s.db.GetProject(projectID)
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+
+	s.openTunnel(w, "", project, input)
+}
+
+// openTunnel validates a create request, starts the tunnel and writes the
+// response. taskID is empty for project tunnels.
+func (s *Server) openTunnel(w http.ResponseWriter, taskID string, project *db.Project, input createTunnelRequest) {
 	if input.Port <= 0 || input.Port > 65535 {
 		writeError(w, http.StatusBadRequest, "invalid port")
 		return
 	}
+	auth, err := resolveTunnelAuth(input.Auth, project)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ttl, err := tunnelTTL(input.TTLMinutes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	// Generate tunnel ID
 	id := ulid.Make().String()
 
-	t, err := s.tunnels.Create(id, "", input.Port, projectID)
+	// A task whose dev server was moved off a contested port is served
+	// from its assigned port.
+	port := input.Port
+	if taskID != "" {
+		port = s.portSuggest.ResolvePort(taskID, port)
+	}
+
+	opts := tunnel.Options{Auth: auth, TTL: ttl}
+	s.tunnelProviderOptions(input.Provider, &opts)
+	t, err := s.tunnels.CreateWithOptions(id, taskID, port, project.ID, opts)
 	if err != nil {
 		var conflict *tunnel.PortConflictError
 		if errors.As(err, &conflict) {
@@ -101,11 +189,18 @@ func (s *Server) handleCreateProjectTunnel(w http.ResponseWriter, r *http.Reques
 			})
 			return
 		}
+		var providerErr *tunnel.ProviderError
+		if errors.As(err, &providerErr) {
+			writeError(w, http.StatusBadRequest, providerErr.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, t.Info())
+	info := t.Info()
+	go s.notifyTunnelOpened(info)
+	writeJSON(w, http.StatusCreated, info)
 }
 
 // handleStopTunnel stops a tunnel
@@ -119,3 +214,38 @@ func (s *Server) handleStopTunnel(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
 }
+
+// resolveTunnelAuth picks the auth for a new tunnel: the request's override
+// if given, otherwise the project default. A nil result leaves it public.
+func resolveTunnelAuth(override *db.TunnelAuth, project *db.Project) (*tunnel.Auth, error) {
+	cfg := project.TunnelAuth
+	if override != nil {
+		cfg = override
+	}
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := validateTunnelAuth(cfg); err != nil {
+		return nil, err
+	}
+	switch cfg.Mode {
+	case "basic":
+		return &tunnel.Auth{Mode: tunnel.AuthBasic, Username: cfg.Username, Password: cfg.Password}, nil
+	case "token":
+		return &tunnel.Auth{Mode: tunnel.AuthToken}, nil
+	}
+	return nil, nil
+}
+
+func validateTunnelAuth(cfg *db.TunnelAuth) error {
+	switch cfg.Mode {
+	case "", "none", "token":
+		return nil
+	case "basic":
+		if cfg.Username == "" || cfg.Password == "" {
+			return errors.New("basic tunnel auth requires a username and password")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown tunnel auth mode %q", cfg.Mode)
+}
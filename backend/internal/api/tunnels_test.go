@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/tunnel"
+)
+
+func TestResolveTunnelAuth(t *testing.T) {
+	project := &db.Project{TunnelAuth: &db.TunnelAuth{Mode: "basic", Username: "demo", Password: "s3cret"}}
+
+	auth, err := resolveTunnelAuth(nil, project)
+	if err != nil || auth == nil || auth.Mode != tunnel.AuthBasic || auth.Username != "demo" {
+		t.Fatalf("expected project default basic auth, got %+v (%v)", auth, err)
+	}
+
+	auth, err = resolveTunnelAuth(&db.TunnelAuth{Mode: "token"}, project)
+	if err != nil || auth == nil || auth.Mode != tunnel.AuthToken {
+		t.Fatalf("expected token override, got %+v (%v)", auth, err)
+	}
+
+	auth, err = resolveTunnelAuth(&db.TunnelAuth{Mode: "none"}, project)
+	if err != nil || auth != nil {
+		t.Fatalf("expected public tunnel for mode none, got %+v (%v)", auth, err)
+	}
+
+	if _, err := resolveTunnelAuth(&db.TunnelAuth{Mode: "basic"}, project); err == nil {
+		t.Error("expected error for basic auth without credentials")
+	}
+	if _, err := resolveTunnelAuth(&db.TunnelAuth{Mode: "oauth"}, project); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestUpdateProject_TunnelAuth(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{
+		"name": "tunnel-auth", "path": createTestGitRepo(t),
+	}), &project)
+
+	resp := env.patch("/api/projects/"+project.ID, map[string]any{
+		"tunnelAuth": map[string]string{"mode": "basic", "username": "demo"},
+	})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for basic auth without password, got %d", resp.Code)
+	}
+
+	resp = env.patch("/api/projects/"+project.ID, map[string]any{
+		"tunnelAuth": map[string]string{"mode": "token"},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, resp, &project)
+	if project.TunnelAuth == nil || project.TunnelAuth.Mode != "token" {
+		t.Errorf("expected token tunnel auth, got %+v", project.TunnelAuth)
+	}
+}
+
+func TestTunnelTTL(t *testing.T) {
+	if ttl, err := tunnelTTL(nil); err != nil || ttl != defaultTunnelTTL {
+		t.Errorf("expected default TTL, got %v (%v)", ttl, err)
+	}
+	minutes := 30
+	if ttl, err := tunnelTTL(&minutes); err != nil || ttl != 30*time.Minute {
+		t.Errorf("expected 30m, got %v (%v)", ttl, err)
+	}
+	for _, bad := range []int{0, -5, 24*60 + 1} {
+		if _, err := tunnelTTL(&bad); err == nil {
+			t.Errorf("expected error for ttlMinutes %d", bad)
+		}
+	}
+}
+
+func TestCreateTunnel_InvalidTTL(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	task, _ := createRunningTaskSession(t, env, "claude")
+
+	resp := env.post("/api/tasks/"+task.ID+"/tunnels", map[string]any{"port": 3000, "ttlMinutes": 0})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for zero TTL, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestIdleTunnels(t *testing.T) {
+	listening := map[int]bool{3000: true, 4000: true}
+	original := tunnelPortListening
+	tunnelPortListening = func(port int) bool { return listening[port] }
+	t.Cleanup(func() { tunnelPortListening = original })
+
+	tunnels := []*tunnel.Tunnel{{ID: "t-1", Port: 3000}, {ID: "t-2", Port: 4000}}
+	misses := map[string]int{"gone": 2}
+	if idle := idleTunnels(tunnels, misses); len(idle) != 0 || len(misses) != 0 {
+		t.Fatalf("expected no idle tunnels and stale misses dropped, got %v %v", idle, misses)
+	}
+
+	listening[3000] = false
+	for i := 1; i < tunnelIdleChecks; i++ {
+		if idle := idleTunnels(tunnels, misses); len(idle) != 0 {
+			t.Fatalf("expected tunnel to survive check %d", i)
+		}
+	}
+	idle := idleTunnels(tunnels, misses)
+	if len(idle) != 1 || idle[0].ID != "t-1" {
+		t.Fatalf("expected t-1 to be idle, got %+v", idle)
+	}
+
+	// A port that comes back resets its count.
+	listening[4000] = false
+	idleTunnels(tunnels, misses)
+	listening[4000] = true
+	idleTunnels(tunnels, misses)
+	if _, ok := misses["t-2"]; ok {
+		t.Errorf("expected misses reset once the port listens again, got %v", misses)
+	}
+}
+
+func TestTunnelProviders(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var opts tunnel.Options
+	env.server.tunnelProviderOptions("", &opts)
+	if opts.Provider != tunnel.ProviderCloudflared || opts.AuthToken != "" {
+		t.Fatalf("expected cloudflared by default, got %+v", opts)
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, tunnelProvidersPreference,
+		`{"provider": "ngrok", "providers": {"ngrok": {"authToken": "tok"}}}`)
+	opts = tunnel.Options{}
+	env.server.tunnelProviderOptions("", &opts)
+	if opts.Provider != tunnel.ProviderNgrok || opts.AuthToken != "tok" {
+		t.Fatalf("expected the configured ngrok default, got %+v", opts)
+	}
+	opts = tunnel.Options{}
+	env.server.tunnelProviderOptions(tunnel.ProviderTailscale, &opts)
+	if opts.Provider != tunnel.ProviderTailscale || opts.AuthToken != "" {
+		t.Fatalf("expected the requested provider, got %+v", opts)
+	}
+
+	var providers []struct {
+		Name       string `json:"name"`
+		Configured bool   `json:"configured"`
+		Default    bool   `json:"default"`
+	}
+	decodeResponse(t, env.get("/api/tunnels/providers"), &providers)
+	if len(providers) != 3 || providers[2].Name != "ngrok" || !providers[2].Configured || !providers[2].Default || providers[0].Default {
+		t.Fatalf("unexpected providers %+v", providers)
+	}
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{
+		"name": "tunnel-provider", "path": createTestGitRepo(t),
+	}), &project)
+	resp := env.post("/api/projects/"+project.ID+"/tunnels", map[string]any{"port": 3000, "provider": "carrier-pigeon"})
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown provider, got %d: %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestTunnelLongOpenAfter(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	if after := env.server.tunnelLongOpenAfter(); after != defaultTunnelLongOpenMinutes*time.Minute {
+		t.Errorf("expected the default, got %v", after)
+	}
+	env.server.db.SetPreference(db.DefaultUserID, tunnelLongOpenPreference, "45")
+	if after := env.server.tunnelLongOpenAfter(); after != 45*time.Minute {
+		t.Errorf("expected 45m, got %v", after)
+	}
+	env.server.db.SetPreference(db.DefaultUserID, tunnelLongOpenPreference, "0")
+	if after := env.server.tunnelLongOpenAfter(); after != 0 {
+		t.Errorf("expected the warning off, got %v", after)
+	}
+	// With nothing open, a pass is a no-op.
+	env.server.warnLongOpenTunnels(time.Now())
+
+	for d, want := range map[time.Duration]string{
+		45 * time.Minute:  "45m",
+		2 * time.Hour:     "2h",
+		150 * time.Minute: "2h30m",
+	} {
+		if got := formatTunnelAge(d); got != want {
+			t.Errorf("formatTunnelAge(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
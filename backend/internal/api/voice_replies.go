@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/llm"
+)
+
+// Voice replies preference:
+//
+//	voice_replies  {"enabled": true, "voice": "alloy", "model": "gpt-4o-mini-tts",
+//	                "chats": {"-1001234567890": false}}
+//
+// When a chat session's turn was started by a Telegram voice note, the
+// agent's final reply is read aloud with OpenAI's speech API and sent back
+// to the chat as a voice message. Off unless enabled; chats turns voice
+// replies on or off for single chats. apiKey and url default to
+// openai_api_key and OpenAI's API.
+const (
+	voiceRepliesPreference = "voice_replies"
+	voiceReplyTimeout      = 2 * time.Minute
+	// voiceReplyMaxChars is the most text OpenAI reads aloud at once.
+	voiceReplyMaxChars = 4096
+	// telegramVoiceSource is the source of turns started by voice notes.
+	telegramVoiceSource = "telegram_voice"
+
+	defaultSpeechModel = "gpt-4o-mini-tts"
+	defaultSpeechVoice = "alloy"
+)
+
+type voiceRepliesConfig struct {
+	Enabled bool            `json:"enabled"`
+	Model   string          `json:"model,omitempty"`
+	Voice   string          `json:"voice,omitempty"`
+	APIKey  string          `json:"apiKey,omitempty"`
+	URL     string          `json:"url,omitempty"`
+	Chats   map[string]bool `json:"chats,omitempty"`
+}
+
+func (s *Server) voiceReplies() voiceRepliesConfig {
+	var cfg voiceRepliesConfig
+	if pref, err := s.db.GetPreference(db.DefaultUserID, voiceRepliesPreference); err == nil {
+		if err := json.Unmarshal([]byte(pref.Value), &cfg); err != nil {
+			slog.Warn("invalid voice_replies preference", "error", err)
+			return voiceRepliesConfig{}
+		}
+	}
+	return cfg
+}
+
+// enabledFor reports whether chatID gets voice replies.
+func (c voiceRepliesConfig) enabledFor(chatID int64) bool {
+	if on, ok := c.Chats[strconv.FormatInt(chatID, 10)]; ok {
+		return on
+	}
+	return c.Enabled
+}
+
+// awaitVoiceReply remembers to answer the turn a voice note is about to
+// start in session by voice, when the chat gets voice replies.
+func (s *Server) awaitVoiceReply(session *db.AgentSession, chatID int64) {
+	if session.SessionType != "chat" || !s.voiceReplies().enabledFor(chatID) {
+		return
+	}
+	s.voiceRepliesTo.Store(session.ID, chatID)
+}
+
+// sendVoiceReply reads the agent's last reply in session aloud to the chat
+// whose voice note started the turn, in the background.
+func (s *Server) sendVoiceReply(session *db.AgentSession) {
+	value, ok := s.voiceRepliesTo.LoadAndDelete(session.ID)
+	if !ok {
+		return
+	}
+	chatID := value.(int64)
+	messages, err := s.chat.RecentMessages(session.ID, 50)
+	if err != nil {
+		return
+	}
+	text := lastAgentReply(messages)
+	if text == "" {
+		return
+	}
+
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		ctx, cancel := context.WithTimeout(s.bgCtx, voiceReplyTimeout)
+		defer cancel()
+		if err := s.speakToTelegram(ctx, session, chatID, text); err != nil {
+			slog.Warn("voice reply failed", "session_id", session.ID, "error", err)
+		}
+	}()
+}
+
+// speakToTelegram sends text to a chat as a voice message about session.
+func (s *Server) speakToTelegram(ctx context.Context, session *db.AgentSession, chatID int64, text string) error {
+	bot := s.currentTelegramBot()
+	if bot == nil {
+		return fmt.Errorf("telegram bot not running")
+	}
+	cfg := s.voiceReplies()
+	client := &llm.Client{BaseURL: cfg.URL, APIKey: firstNonEmpty(cfg.APIKey, s.openAIKey())}
+	if client.APIKey == "" && client.BaseURL == "" {
+		return fmt.Errorf("set an openai api key for voice replies")
+	}
+	if runes := []rune(text); len(runes) > voiceReplyMaxChars {
+		text = string(runes[:voiceReplyMaxChars])
+	}
+	audio, err := client.Speech(ctx, llm.SpeechRequest{
+		Model:  firstNonEmpty(cfg.Model, defaultSpeechModel),
+		Voice:  firstNonEmpty(cfg.Voice, defaultSpeechVoice),
+		Input:  text,
+		Format: "opus",
+	})
+	if err != nil {
+		return err
+	}
+	messageID, err := bot.SendVoice(ctx, chatID, audio, "reply.ogg", "🔊 "+sessionLabel(session))
+	if err != nil {
+		return err
+	}
+	// Like other messages about a session, replying to it reaches the agent.
+	return s.db.RecordTelegramMessage(chatID, messageID, session.ID)
+}
+
+// lastAgentReply returns the text of the agent's last reply in the latest
+// turn of messages, or "" when it has none.
+func lastAgentReply(messages []ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		switch {
+		case msg.Kind == ChatMessageKindUserText:
+			return ""
+		case msg.Kind == ChatMessageKindAgentText && !msg.IsThinking && strings.TrimSpace(msg.Text) != "":
+			return strings.TrimSpace(msg.Text)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestVoiceReplies(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	project := createWorkspaceProject(t, env)
+	chat, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	terminal, _ := env.server.db.CreateSession(db.CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "terminal"})
+
+	env.server.awaitVoiceReply(chat, 42)
+	if _, ok := env.server.voiceRepliesTo.Load(chat.ID); ok {
+		t.Error("expected no voice reply while voice_replies is off")
+	}
+
+	env.server.db.SetPreference(db.DefaultUserID, voiceRepliesPreference, `{"enabled": true, "chats": {"7": false}}`)
+	env.server.awaitVoiceReply(chat, 7)
+	env.server.awaitVoiceReply(terminal, 42)
+	if _, ok := env.server.voiceRepliesTo.Load(chat.ID); ok {
+		t.Error("expected a chat turned off to get no voice reply")
+	}
+	if _, ok := env.server.voiceRepliesTo.Load(terminal.ID); ok {
+		t.Error("expected terminal sessions to get no voice reply")
+	}
+	env.server.awaitVoiceReply(chat, 42)
+	if chatID, ok := env.server.voiceRepliesTo.Load(chat.ID); !ok || chatID != int64(42) {
+		t.Errorf("expected a voice reply awaited for chat 42, got %v", chatID)
+	}
+
+	// Nothing to read aloud: the entry is used up without sending.
+	env.server.sendVoiceReply(chat)
+	if _, ok := env.server.voiceRepliesTo.Load(chat.ID); ok {
+		t.Error("expected the awaited reply consumed")
+	}
+}
+
+func TestLastAgentReply(t *testing.T) {
+	messages := []ChatMessage{
+		{Kind: ChatMessageKindUserText, Text: "first"},
+		{Kind: ChatMessageKindAgentText, Text: "old answer"},
+		{Kind: ChatMessageKindUserText, Text: "fix it"},
+		{Kind: ChatMessageKindAgentText, Text: "Looking."},
+		{Kind: ChatMessageKindToolCall},
+		{Kind: ChatMessageKindAgentText, Text: " Fixed the redirect. "},
+		{Kind: ChatMessageKindAgentText, Text: "hmm", IsThinking: true},
+		{Kind: ChatMessageKindResult},
+	}
+	if got := lastAgentReply(messages); got != "Fixed the redirect." {
+		t.Errorf("lastAgentReply = %q", got)
+	}
+	if got := lastAgentReply(messages[:3]); got != "" {
+		t.Errorf("expected no reply in a turn without one, got %q", got)
+	}
+}
@@ -0,0 +1,475 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Outbound webhooks post Codeburg events to external systems (CI, chat-ops,
+// dashboards). Each webhook has a URL, a secret and optionally the events
+// and the project it wants. Every event is queued in the database as a
+// delivery per matching webhook and posted as
+//
+//	{"id": "<delivery id>", "event": "task.status_changed",
+//	 "createdAt": "...", "data": {...}}
+//
+// with X-Codeburg-Event, X-Codeburg-Delivery and X-Codeburg-Signature:
+// sha256=<hex HMAC-SHA256 of the body with the secret>. A delivery that
+// fails (an error or a non-2xx response) is retried on webhookRetryDelays,
+// surviving restarts, and then marked failed. Finished deliveries are kept
+// for webhookDeliveryRetention. Up to webhookWorkers webhooks are posted to
+// at once, each one's deliveries in order.
+const (
+	webhookEventTaskStatus     = "task.status_changed"
+	webhookEventSessionWaiting = "session.waiting_input" // the agent finished a turn
+	webhookEventSessionEnded   = "session.completed"     // the session completed or failed
+	webhookEventGitPushed      = "git.pushed"
+	webhookEventPing           = "ping" // sent by POST /api/webhooks/{id}/test
+
+	webhookTimeout           = 10 * time.Second
+	webhookPollInterval      = 5 * time.Second
+	webhookBatchSize         = 50
+	webhookWorkers           = 4
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+	webhookSecretPrefix      = "whsec_"
+)
+
+var webhookEvents = []string{webhookEventTaskStatus, webhookEventSessionWaiting, webhookEventSessionEnded, webhookEventGitPushed}
+
+// webhookRetryDelays are the waits before each retry of a failed delivery.
+var webhookRetryDelays = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour}
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookWants reports whether w takes event from projectID. Pings go to
+// the webhook tested only.
+func webhookWants(w *db.Webhook, event, projectID string) bool {
+	if !w.Enabled || event == webhookEventPing {
+		return false
+	}
+	if w.ProjectID != nil && *w.ProjectID != projectID {
+		return false
+	}
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// emitWebhook queues event for every webhook that wants it.
+func (s *Server) emitWebhook(event, projectID string, data any) {
+	webhooks, err := s.db.ListWebhooks()
+	if err != nil {
+		slog.Warn("failed to list webhooks", "event", event, "error", err)
+		return
+	}
+	var payload []byte
+	for _, w := range webhooks {
+		if !webhookWants(w, event, projectID) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(data); err != nil {
+				slog.Warn("failed to encode webhook event", "event", event, "error", err)
+				return
+			}
+		}
+		if _, err := s.db.CreateWebhookDelivery(w.ID, event, payload); err != nil {
+			slog.Warn("failed to queue webhook delivery", "webhook_id", w.ID, "event", event, "error", err)
+		}
+	}
+	if payload != nil {
+		s.wakeWebhooks()
+	}
+}
+
+// wakeWebhooks has the delivery loop look for due deliveries now.
+func (s *Server) wakeWebhooks() {
+	select {
+	case s.webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) emitTaskStatusChanged(task *db.Task, from db.TaskStatus) {
+	s.emitWebhook(webhookEventTaskStatus, task.ProjectID, map[string]any{
+		"task": task,
+		"from": from,
+		"to":   task.Status,
+	})
+}
+
+// emitSessionStatus sends the session events for a status a session just
+// reached.
+func (s *Server) emitSessionStatus(sessionID string, status db.SessionStatus) {
+	event := webhookEventSessionEnded
+	switch status {
+	case db.SessionStatusWaitingInput:
+		event = webhookEventSessionWaiting
+	case db.SessionStatusCompleted, db.SessionStatusError:
+	default:
+		return
+	}
+	session, err := s.db.GetSession(sessionID)
+	if err != nil {
+		return
+	}
+	s.emitWebhook(event, session.ProjectID, map[string]any{"session": session})
+}
+
+// emitGitPushed sends git.pushed for a branch pushed from dir. An empty
+// branch is the one checked out.
+func (s *Server) emitGitPushed(projectID, taskID, dir, branch string) {
+	ref := "HEAD"
+	if branch != "" {
+		ref = branch
+	} else if out, err := runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		branch = strings.TrimSpace(out)
+	}
+	commit := ""
+	if out, err := runGit(dir, "rev-parse", ref); err == nil {
+		commit = strings.TrimSpace(out)
+	}
+	data := map[string]any{"projectId": projectID, "branch": branch, "commit": commit}
+	if taskID != "" {
+		data["taskId"] = taskID
+	}
+	s.emitWebhook(webhookEventGitPushed, projectID, data)
+	s.watchCIAfterPush(projectID, taskID, branch, commit)
+}
+
+// runWebhookDeliveries posts due deliveries until ctx is cancelled.
+func (s *Server) runWebhookDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	var pruned time.Time
+	for {
+		s.deliverDueWebhooks(ctx)
+		if time.Since(pruned) >= time.Hour {
+			if _, err := s.db.PruneWebhookDeliveries(time.Now().Add(-webhookDeliveryRetention)); err != nil {
+				slog.Warn("failed to prune webhook deliveries", "error", err)
+			}
+			pruned = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.webhookWake:
+		}
+	}
+}
+
+// deliverDueWebhooks posts the due deliveries, to up to webhookWorkers
+// webhooks at a time. Once a webhook's delivery fails, the rest of its
+// deliveries wait for the next pass, so an endpoint that is down or slow
+// costs a pass one timeout rather than one per delivery, and holds up no
+// other webhook.
+func (s *Server) deliverDueWebhooks(ctx context.Context) {
+	var mu sync.Mutex
+	var failed []string
+	for ctx.Err() == nil {
+		mu.Lock()
+		skip := slices.Clone(failed)
+		mu.Unlock()
+		due, err := s.db.ListDueWebhookDeliveries(time.Now(), webhookBatchSize, skip...)
+		if err != nil {
+			slog.Warn("failed to list due webhook deliveries", "error", err)
+			return
+		}
+
+		byWebhook := make(map[string][]*db.WebhookDelivery)
+		var order []string
+		for _, delivery := range due {
+			if _, ok := byWebhook[delivery.WebhookID]; !ok {
+				order = append(order, delivery.WebhookID)
+			}
+			byWebhook[delivery.WebhookID] = append(byWebhook[delivery.WebhookID], delivery)
+		}
+		var wg sync.WaitGroup
+		workers := make(chan struct{}, webhookWorkers)
+		for _, webhookID := range order {
+			workers <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-workers
+					wg.Done()
+				}()
+				for _, delivery := range byWebhook[webhookID] {
+					if !s.attemptWebhookDelivery(ctx, delivery) {
+						mu.Lock()
+						failed = append(failed, webhookID)
+						mu.Unlock()
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if len(due) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attemptWebhookDelivery posts a delivery and records the outcome,
+// scheduling the next retry when it fails. It reports false when the
+// webhook's endpoint failed, so that its other deliveries can wait.
+func (s *Server) attemptWebhookDelivery(ctx context.Context, delivery *db.WebhookDelivery) bool {
+	webhook, err := s.db.GetWebhook(delivery.WebhookID)
+	if err != nil {
+		return true // deleted, with its deliveries
+	}
+	status := 0
+	if !webhook.Enabled {
+		err = fmt.Errorf("webhook is disabled")
+	} else {
+		status, err = postWebhook(ctx, webhook, delivery)
+	}
+
+	var retryAt *time.Time
+	if err != nil && webhook.Enabled && delivery.Attempts < len(webhookRetryDelays) {
+		next := time.Now().Add(webhookRetryDelays[delivery.Attempts])
+		retryAt = &next
+	}
+	if err != nil {
+		slog.Warn("webhook delivery failed", "webhook_id", webhook.ID, "delivery_id", delivery.ID, "event", delivery.Event, "attempt", delivery.Attempts+1, "retry", retryAt != nil, "error", err)
+	}
+	if err := s.db.RecordWebhookAttempt(delivery.ID, status, err, retryAt); err != nil {
+		slog.Warn("failed to record webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+	// A disabled webhook's deliveries all fail at once, without a post.
+	return err == nil || !webhook.Enabled
+}
+
+// postWebhook sends a delivery and returns the response status.
+func postWebhook(ctx context.Context, webhook *db.Webhook, delivery *db.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(map[string]any{
+		"id":        delivery.ID,
+		"event":     delivery.Event,
+		"createdAt": delivery.CreatedAt.UTC(),
+		"data":      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Codeburg-Webhooks")
+	req.Header.Set("X-Codeburg-Event", delivery.Event)
+	req.Header.Set("X-Codeburg-Delivery", delivery.ID)
+	req.Header.Set("X-Codeburg-Signature", "sha256="+hex.EncodeToString(hmacSHA256([]byte(webhook.Secret), body)))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(text)))
+	}
+	return resp.StatusCode, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(b), nil
+}
+
+// webhookRequest is the body of POST and PATCH /api/webhooks. PATCH changes
+// the fields given; an empty projectId removes the project filter.
+type webhookRequest struct {
+	Name      *string   `json:"name"`
+	URL       *string   `json:"url"`
+	Secret    *string   `json:"secret"`
+	Events    *[]string `json:"events"`
+	ProjectID *string   `json:"projectId"`
+	Enabled   *bool     `json:"enabled"`
+}
+
+func (s *Server) validateWebhookRequest(req webhookRequest) error {
+	if req.URL != nil {
+		u, err := url.Parse(*req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+	}
+	if req.Events != nil {
+		for _, event := range *req.Events {
+			if !slices.Contains(webhookEvents, event) {
+				return fmt.Errorf("unknown event %q (want one of %s)", event, strings.Join(webhookEvents, ", "))
+			}
+		}
+	}
+	if req.ProjectID != nil && *req.ProjectID != "" {
+		if _, err := s.db.GetProject(*req.ProjectID); err != nil {
+			return fmt.Errorf("project not found")
+		}
+	}
+	return nil
+}
+
+// webhookWithSecret shows the secret, on creation only.
+type webhookWithSecret struct {
+	*db.Webhook
+	Secret string `json:"secret"`
+}
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.db.ListWebhooks()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+	writeJSON(w, http.StatusOK, webhooks)
+}
+
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.URL == nil {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	if err := s.validateWebhookRequest(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input := db.CreateWebhookInput{URL: *req.URL, Name: ptrToString(req.Name), Secret: ptrToString(req.Secret)}
+	if req.Events != nil {
+		input.Events = *req.Events
+	}
+	if req.ProjectID != nil && *req.ProjectID != "" {
+		input.ProjectID = req.ProjectID
+	}
+	if input.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to generate secret")
+			return
+		}
+		input.Secret = secret
+	}
+	webhook, err := s.db.CreateWebhook(input)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+	writeJSON(w, http.StatusCreated, webhookWithSecret{webhook, webhook.Secret})
+}
+
+func (s *Server) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Secret != nil && *req.Secret == "" {
+		writeError(w, http.StatusBadRequest, "secret cannot be empty")
+		return
+	}
+	if err := s.validateWebhookRequest(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhook, err := s.db.UpdateWebhook(urlParam(r, "id"), db.UpdateWebhookInput{
+		Name:      req.Name,
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    req.Events,
+		ProjectID: req.ProjectID,
+		Enabled:   req.Enabled,
+	})
+	if err != nil {
+		writeDBError(w, err, "webhook")
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := s.db.DeleteWebhook(urlParam(r, "id")); err != nil {
+		writeDBError(w, err, "webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListWebhookDeliveries returns a webhook's latest deliveries, up to
+// limit (50 by default).
+func (s *Server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, err := s.db.GetWebhook(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "webhook")
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > db.MaxPageLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", db.MaxPageLimit))
+			return
+		}
+	}
+	deliveries, err := s.db.ListWebhookDeliveries(webhook.ID, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// handleTestWebhook queues a ping for the webhook, whatever events it takes.
+func (s *Server) handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, err := s.db.GetWebhook(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "webhook")
+		return
+	}
+	payload, _ := json.Marshal(map[string]string{"webhookId": webhook.ID})
+	delivery, err := s.db.CreateWebhookDelivery(webhook.ID, webhookEventPing, payload)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to queue ping")
+		return
+	}
+	s.wakeWebhooks()
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// handleRedeliverWebhook queues a past delivery again.
+func (s *Server) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, err := s.db.GetWebhookDelivery(urlParam(r, "deliveryId"))
+	if err != nil || delivery.WebhookID != urlParam(r, "id") {
+		writeError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	if delivery, err = s.db.RetryWebhookDelivery(delivery.ID); err != nil {
+		writeDBError(w, err, "delivery")
+		return
+	}
+	s.wakeWebhooks()
+	writeJSON(w, http.StatusAccepted, delivery)
+}
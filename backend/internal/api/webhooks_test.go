@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+func TestWebhooks(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	var mu sync.Mutex
+	var received []request
+	failNext := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, request{r.Header.Clone(), body})
+		if failNext {
+			failNext = false
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer target.Close()
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received)
+	}
+	last := func() request {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(received) == 0 {
+			t.Fatal("expected a delivery")
+		}
+		return received[len(received)-1]
+	}
+
+	repoPath := createTestGitRepo(t)
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": repoPath}), &project)
+
+	resp := env.post("/api/webhooks", map[string]any{"url": target.URL, "events": []string{"task.merged"}})
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), "unknown event") {
+		t.Errorf("expected 400 for an unknown event, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	var created struct {
+		db.Webhook
+		Secret string `json:"secret"`
+	}
+	resp = env.post("/api/webhooks", map[string]any{"name": "ci", "url": target.URL, "events": []string{webhookEventTaskStatus}, "projectId": project.ID})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create webhook: %d %s", resp.Code, resp.Body.String())
+	}
+	decodeResponse(t, resp, &created)
+	if !strings.HasPrefix(created.Secret, webhookSecretPrefix) {
+		t.Fatalf("expected a generated secret, got %q", created.Secret)
+	}
+	if list := env.get("/api/webhooks").Body.String(); strings.Contains(list, created.Secret) {
+		t.Error("expected the secret hidden when listing")
+	}
+	// Another project's webhook and a disabled one get nothing.
+	other, _ := env.server.db.CreateProject(db.CreateProjectInput{Name: "other", Path: t.TempDir()})
+	env.server.db.CreateWebhook(db.CreateWebhookInput{URL: target.URL, Secret: "x", ProjectID: &other.ID})
+	disabled, _ := env.server.db.CreateWebhook(db.CreateWebhookInput{URL: target.URL, Secret: "x"})
+	env.server.db.UpdateWebhook(disabled.ID, db.UpdateWebhookInput{Enabled: new(bool)})
+
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Ship it"}), &task)
+	env.patch("/api/tasks/"+task.ID, map[string]string{"status": "done"})
+	env.server.deliverDueWebhooks(context.Background())
+
+	got := last()
+	if count() != 1 {
+		t.Fatalf("expected one delivery, got %d", count())
+	}
+	want := "sha256=" + hex.EncodeToString(hmacSHA256([]byte(created.Secret), got.body))
+	if got.header.Get("X-Codeburg-Signature") != want || got.header.Get("X-Codeburg-Event") != webhookEventTaskStatus {
+		t.Errorf("unexpected headers %v", got.header)
+	}
+	var payload struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			Task db.Task `json:"task"`
+			From string  `json:"from"`
+			To   string  `json:"to"`
+		} `json:"data"`
+	}
+	json.Unmarshal(got.body, &payload)
+	if payload.Data.Task.ID != task.ID || payload.Data.From != "backlog" || payload.Data.To != "done" || payload.ID != got.header.Get("X-Codeburg-Delivery") {
+		t.Errorf("unexpected payload %s", got.body)
+	}
+
+	// A failed delivery is retried later; redelivering makes it due now.
+	mu.Lock()
+	failNext = true
+	mu.Unlock()
+	var ping db.WebhookDelivery
+	decodeResponse(t, env.post("/api/webhooks/"+created.ID+"/test", nil), &ping)
+	env.server.deliverDueWebhooks(context.Background())
+	delivery, _ := env.server.db.GetWebhookDelivery(ping.ID)
+	if delivery.Status != db.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusServiceUnavailable || delivery.NextAttemptAt == nil {
+		t.Fatalf("expected a scheduled retry, got %+v", delivery)
+	}
+	env.server.deliverDueWebhooks(context.Background())
+	if count() != 2 {
+		t.Fatalf("expected the retry to wait, got %d deliveries", count())
+	}
+	env.post("/api/webhooks/"+created.ID+"/deliveries/"+ping.ID+"/redeliver", nil)
+	env.server.deliverDueWebhooks(context.Background())
+	if got := last(); got.header.Get("X-Codeburg-Event") != webhookEventPing {
+		t.Errorf("expected the ping redelivered, got %v", got.header)
+	}
+
+	var deliveries []db.WebhookDelivery
+	decodeResponse(t, env.get("/api/webhooks/"+created.ID+"/deliveries"), &deliveries)
+	if len(deliveries) != 2 || deliveries[0].Status != db.WebhookDeliveryDelivered || deliveries[1].Event != webhookEventTaskStatus {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestWebhookDeliveryFailureHoldsOnlyItsWebhook(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	var mu sync.Mutex
+	hits := map[string]int{}
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/down" {
+			http.Error(w, "down", http.StatusBadGateway)
+		}
+	}))
+	defer target.Close()
+
+	down, _ := env.server.db.CreateWebhook(db.CreateWebhookInput{URL: target.URL + "/down", Secret: "x"})
+	up, _ := env.server.db.CreateWebhook(db.CreateWebhookInput{URL: target.URL + "/up", Secret: "x"})
+	var queued []*db.WebhookDelivery
+	for range 3 {
+		for _, webhook := range []*db.Webhook{down, up} {
+			delivery, err := env.server.db.CreateWebhookDelivery(webhook.ID, webhookEventPing, []byte(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			queued = append(queued, delivery)
+		}
+	}
+	env.server.deliverDueWebhooks(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["/down"] != 1 || hits["/up"] != 3 {
+		t.Fatalf("expected one attempt at the failing webhook and every delivery to the other, got %v", hits)
+	}
+	var attempted int
+	for _, d := range queued {
+		delivery, _ := env.server.db.GetWebhookDelivery(d.ID)
+		if delivery.WebhookID == down.ID {
+			attempted += delivery.Attempts
+			if delivery.Status != db.WebhookDeliveryPending {
+				t.Errorf("expected the failing webhook's deliveries kept pending, got %+v", delivery)
+			}
+		} else if delivery.Status != db.WebhookDeliveryDelivered {
+			t.Errorf("expected delivered, got %+v", delivery)
+		}
+	}
+	if attempted != 1 {
+		t.Errorf("expected the failing webhook's other deliveries left for the next pass, got %d attempts", attempted)
+	}
+}
@@ -1,15 +1,19 @@
 package api
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/eventbus"
 )
 
 const (
@@ -24,7 +28,7 @@ func (s *Server) wsUpgrader() websocket.Upgrader {
 		ReadBufferSize:  wsReadBufferSize,
 		WriteBufferSize: wsWriteBufferSize,
 		CheckOrigin: func(r *http.Request) bool {
-			return isAllowedOrigin(s.allowedOrigins, r.Header.Get("Origin"))
+			return s.originAllowed(r.Header.Get("Origin"))
 		},
 	}
 }
@@ -37,38 +41,160 @@ type WSMessage struct {
 
 // WSClient represents a WebSocket client connection
 type WSClient struct {
-	hub  *WSHub
-	conn *websocket.Conn
-	send chan []byte
-	subs map[string]bool // Subscribed channels (e.g., "session:123")
-	mu   sync.Mutex
-	auth bool
+	hub    *WSHub
+	conn   *websocket.Conn
+	send   chan []byte
+	subs   map[string]bool // Subscribed topics (e.g., "session:123", "global")
+	mu     sync.Mutex
+	auth   bool
+	access wsAccess // what the connection's token may listen to
+}
+
+// Topics clients subscribe to. Topic names are "<channel>:<id>", except
+// for the global topic, which carries board-wide events such as
+// sidebar_update.
+const (
+	wsChannelGlobal  = "global"
+	wsChannelProject = "project"
+	wsChannelTask    = "task"
+	wsChannelSession = "session"
+)
+
+// wsTopic returns the topic of a subscribe or unsubscribe message.
+func wsTopic(channel, id string) (string, bool) {
+	switch channel {
+	case wsChannelGlobal:
+		return wsChannelGlobal, true
+	case wsChannelProject, wsChannelTask, wsChannelSession:
+		return channel + ":" + id, id != ""
+	}
+	return "", false
+}
+
+// wsAccess is what a token may listen to on the websocket.
+type wsAccess struct {
+	full      bool     // a login token: every topic
+	sessionID string   // a hook token: only its session
+	scopes    []string // an API token: the topics its read scopes cover
+}
+
+func (a wsAccess) allows(topic string) bool {
+	if a.full {
+		return true
+	}
+	channel, id, _ := strings.Cut(topic, ":")
+	if a.sessionID != "" {
+		return channel == wsChannelSession && id == a.sessionID
+	}
+	switch channel {
+	case wsChannelSession:
+		return tokenHasScope(a.scopes, ScopeSessionsRead)
+	case wsChannelTask:
+		return tokenHasScope(a.scopes, ScopeTasksRead)
+	case wsChannelProject:
+		return tokenHasScope(a.scopes, ScopeProjectsRead)
+	}
+	// Global events span every project, so only login tokens get them.
+	return false
+}
+
+// canSendInput reports whether the token may type into sessions.
+func (a wsAccess) canSendInput() bool {
+	return a.full || tokenHasScope(a.scopes, ScopeSessionsWrite)
+}
+
+// wsAccessForToken validates a login, API or hook token.
+func (s *Server) wsAccessForToken(token string) (wsAccess, bool) {
+	token = strings.TrimSpace(token)
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		apiToken, err := s.db.GetAPITokenByHash(hashAPIToken(token))
+		if err != nil || (apiToken.ExpiresAt != nil && time.Now().After(*apiToken.ExpiresAt)) {
+			return wsAccess{}, false
+		}
+		return wsAccess{scopes: apiToken.Scopes}, true
+	}
+	if s.validLoginToken(token) {
+		return wsAccess{full: true}, true
+	}
+	if sessionID, ok := s.auth.HookTokenSession(token); ok {
+		return wsAccess{sessionID: sessionID}, true
+	}
+	return wsAccess{}, false
+}
+
+// hubMessage is a message for the clients subscribed to any of its topics.
+type hubMessage struct {
+	topics []string
+	data   []byte
 }
 
 // WSHub manages all WebSocket connections
 type WSHub struct {
 	clients    map[*WSClient]bool
-	broadcast  chan []byte
+	broadcast  chan hubMessage
 	register   chan *WSClient
 	unregister chan *WSClient
 	done       chan struct{}
 	stopOnce   sync.Once
 	mu         sync.RWMutex
+
+	// Optional event bus shared with other instances; nil when running alone.
+	bus        eventbus.Bus
+	instanceID string
+	outbound   chan []byte
+}
+
+// busEvent is a hub broadcast as relayed over the event bus. Topics are the
+// subscription topics ("session:<id>", "global"). Channel is the first
+// non-global topic, or empty for global messages, for instances that
+// predate topics.
+type busEvent struct {
+	Origin  string          `json:"origin"`
+	Channel string          `json:"channel,omitempty"`
+	Topics  []string        `json:"topics,omitempty"`
+	Message json.RawMessage `json:"message"`
 }
 
 // NewWSHub creates a new WebSocket hub
 func NewWSHub() *WSHub {
 	return &WSHub{
 		clients:    make(map[*WSClient]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan hubMessage, 256),
 		register:   make(chan *WSClient),
 		unregister: make(chan *WSClient),
 		done:       make(chan struct{}),
+		instanceID: db.NewID(),
 	}
 }
 
+// SetEventBus relays broadcasts through bus so clients connected to other
+// instances receive them too. Must be called before Run.
+func (h *WSHub) SetEventBus(bus eventbus.Bus) {
+	h.bus = bus
+	h.outbound = make(chan []byte, 256)
+}
+
 // Run starts the hub's event loop
 func (h *WSHub) Run(ctx context.Context) {
+	if h.bus != nil {
+		busCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.bus.Subscribe(busCtx, h.receiveBusEvent)
+		}()
+		go func() {
+			defer wg.Done()
+			h.publishLoop(busCtx)
+		}()
+		defer func() {
+			cancel()
+			wg.Wait()
+			h.bus.Close()
+		}()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -93,15 +219,7 @@ func (h *WSHub) Run(ctx context.Context) {
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Skip slow client — its own goroutine will handle cleanup via unregister
-				}
-			}
-			h.mu.RUnlock()
+			h.deliver(message)
 		}
 	}
 }
@@ -154,102 +272,141 @@ func (h *WSHub) Unregister(client *WSClient) {
 
 // BroadcastToSession sends a message to all clients subscribed to a session
 func (h *WSHub) BroadcastToSession(sessionID string, msgType string, data interface{}) {
-	if h.isStopped() {
-		return
-	}
-	channel := "session:" + sessionID
-
-	payload := map[string]interface{}{
+	h.broadcastTopics([]string{wsChannelSession + ":" + sessionID}, map[string]interface{}{
 		"type":      msgType,
 		"sessionId": sessionID,
 		"data":      data,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	}
+	})
+}
 
+// BroadcastToTask sends a message to all clients subscribed to a task
+func (h *WSHub) BroadcastToTask(taskID string, msgType string, data interface{}) {
+	h.broadcastTopics([]string{wsChannelTask + ":" + taskID}, map[string]interface{}{
+		"type":   msgType,
+		"taskId": taskID,
+		"data":   data,
+	})
+}
+
+// BroadcastToProject sends a board-wide message about one project to the
+// clients subscribed to that project and to global subscribers.
+func (h *WSHub) BroadcastToProject(projectID string, msgType string, data interface{}) {
+	h.broadcastTopics([]string{wsChannelGlobal, wsChannelProject + ":" + projectID}, map[string]interface{}{
+		"type":      msgType,
+		"projectId": projectID,
+		"data":      data,
+	})
+}
+
+// BroadcastGlobal sends a message to all clients subscribed to the global
+// topic, which login tokens are by default
+func (h *WSHub) BroadcastGlobal(msgType string, data interface{}) {
+	h.broadcastTopics([]string{wsChannelGlobal}, map[string]interface{}{
+		"type": msgType,
+		"data": data,
+	})
+}
+
+func (h *WSHub) broadcastTopics(topics []string, payload map[string]interface{}) {
+	if h.isStopped() {
+		return
+	}
+	payload["timestamp"] = time.Now().UTC().Format(time.RFC3339)
 	message, err := json.Marshal(payload)
 	if err != nil {
 		slog.Error("failed to marshal websocket message", "error", err)
 		return
 	}
 
+	h.enqueue(hubMessage{topics: topics, data: message})
+	h.publish(topics, message)
+}
+
+// enqueue hands a message to the hub loop for delivery to this instance's
+// clients.
+func (h *WSHub) enqueue(message hubMessage) {
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+	default:
+		slog.Warn("broadcast channel full, dropping message", "topics", message.topics)
+	}
+}
+
+// deliver sends a message once to each of this instance's clients that is
+// subscribed to any of its topics.
+func (h *WSHub) deliver(message hubMessage) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	for client := range h.clients {
 		client.mu.Lock()
-		authed := client.auth
-		subscribed := client.subs[channel]
+		wanted := client.auth && slices.ContainsFunc(message.topics, func(topic string) bool { return client.subs[topic] })
 		client.mu.Unlock()
 
-		if authed && subscribed {
+		if wanted {
 			select {
-			case client.send <- message:
+			case client.send <- message.data:
 			default:
-				// Client buffer full, skip
+				// Skip slow client — its own goroutine will handle cleanup via unregister
 			}
 		}
 	}
 }
 
-// BroadcastToTask sends a message to all clients subscribed to a task
-func (h *WSHub) BroadcastToTask(taskID string, msgType string, data interface{}) {
-	if h.isStopped() {
+// publish queues a locally delivered message for the other instances. It
+// never blocks the caller; messages are dropped if the bus cannot keep up.
+func (h *WSHub) publish(topics []string, message []byte) {
+	if h.bus == nil {
 		return
 	}
-	channel := "task:" + taskID
-
-	payload := map[string]interface{}{
-		"type":      msgType,
-		"taskId":    taskID,
-		"data":      data,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	channel := ""
+	for _, topic := range topics {
+		if topic != wsChannelGlobal {
+			channel = topic
+			break
+		}
 	}
-
-	message, err := json.Marshal(payload)
+	event, err := json.Marshal(busEvent{Origin: h.instanceID, Channel: channel, Topics: topics, Message: message})
 	if err != nil {
-		slog.Error("failed to marshal websocket message", "error", err)
+		slog.Error("failed to marshal event bus message", "error", err)
 		return
 	}
+	select {
+	case h.outbound <- event:
+	default:
+		slog.Warn("event bus queue full, dropping message", "topics", topics)
+	}
+}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	for client := range h.clients {
-		client.mu.Lock()
-		authed := client.auth
-		subscribed := client.subs[channel]
-		client.mu.Unlock()
-
-		if authed && subscribed {
-			select {
-			case client.send <- message:
-			default:
+func (h *WSHub) publishLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-h.outbound:
+			if err := h.bus.Publish(ctx, event); err != nil && ctx.Err() == nil {
+				slog.Warn("failed to publish to event bus", "error", err)
 			}
 		}
 	}
 }
 
-// BroadcastGlobal sends a message to all connected clients (no subscription required)
-func (h *WSHub) BroadcastGlobal(msgType string, data interface{}) {
-	payload := map[string]interface{}{
-		"type":      msgType,
-		"data":      data,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	}
-
-	message, err := json.Marshal(payload)
-	if err != nil {
-		slog.Error("failed to marshal global websocket message", "error", err)
+// receiveBusEvent delivers a message published by another instance.
+func (h *WSHub) receiveBusEvent(data []byte) {
+	var event busEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		slog.Warn("invalid event bus message", "error", err)
 		return
 	}
-
-	select {
-	case h.broadcast <- message:
-	case <-h.done:
+	if event.Origin == h.instanceID || h.isStopped() {
 		return
-	default:
-		slog.Warn("broadcast channel full, dropping global message", "type", msgType)
 	}
+	topics := event.Topics
+	if len(topics) == 0 {
+		topics = []string{cmp.Or(event.Channel, wsChannelGlobal)}
+	}
+	h.enqueue(hubMessage{topics: topics, data: event.Message})
 }
 
 func authTokenFromWSRequest(r *http.Request) string {
@@ -267,12 +424,16 @@ func authTokenFromWSRequest(r *http.Request) string {
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	token := authTokenFromWSRequest(r)
 	preAuthed := false
+	var access wsAccess
 	if token != "" {
-		if !s.auth.ValidateToken(token) {
+		var ok bool
+		if access, ok = s.wsAccessForToken(token); !ok {
 			writeError(w, http.StatusUnauthorized, "invalid token")
 			return
 		}
 		preAuthed = true
+	} else if s.ambientLogin(r) {
+		access, preAuthed = wsAccess{full: true}, true
 	}
 
 	upgrader := s.wsUpgrader()
@@ -287,10 +448,10 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		conn: conn,
 		send: make(chan []byte, 256),
 		subs: make(map[string]bool),
-		auth: preAuthed,
 	}
 
 	if preAuthed {
+		client.setAuthenticated(access)
 		if !s.wsHub.Register(client) {
 			_ = conn.Close()
 			return
@@ -393,6 +554,8 @@ func (c *WSClient) handleMessage(s *Server, message []byte) {
 		SessionID string `json:"sessionId"`
 		Content   string `json:"content"`
 		Token     string `json:"token"`
+		Cols      uint16 `json:"cols"`
+		Rows      uint16 `json:"rows"`
 	}
 
 	if err := json.Unmarshal(message, &msg); err != nil {
@@ -402,11 +565,12 @@ func (c *WSClient) handleMessage(s *Server, message []byte) {
 
 	switch msg.Type {
 	case "auth":
-		if !s.auth.ValidateToken(strings.TrimSpace(msg.Token)) {
+		access, ok := s.wsAccessForToken(msg.Token)
+		if !ok {
 			c.closeUnauthorized("invalid token")
 			return
 		}
-		wasAuthed := c.setAuthenticated()
+		wasAuthed := c.setAuthenticated(access)
 		if !wasAuthed {
 			if !c.hub.Register(c) {
 				c.closeUnauthorized("server unavailable")
@@ -420,10 +584,25 @@ func (c *WSClient) handleMessage(s *Server, message []byte) {
 			c.closeUnauthorized("authentication required")
 			return
 		}
-		// Subscribe to a channel (e.g., "session" or "task")
-		channel := msg.Channel + ":" + msg.ID
+		// Subscribe to a topic (e.g., "session" and its ID). A token given
+		// with the subscription authorizes just that topic.
+		topic, ok := wsTopic(msg.Channel, msg.ID)
+		if !ok {
+			c.sendSubscribeError(msg.Channel, msg.ID, "unknown channel or missing id")
+			return
+		}
+		if msg.Token != "" {
+			access, valid := s.wsAccessForToken(msg.Token)
+			if !valid || !access.allows(topic) {
+				c.sendSubscribeError(msg.Channel, msg.ID, "token does not grant this topic")
+				return
+			}
+		} else if !c.allows(topic) {
+			c.sendSubscribeError(msg.Channel, msg.ID, "not authorized for this topic")
+			return
+		}
 		c.mu.Lock()
-		c.subs[channel] = true
+		c.subs[topic] = true
 		c.mu.Unlock()
 
 		// Send confirmation
@@ -438,9 +617,9 @@ func (c *WSClient) handleMessage(s *Server, message []byte) {
 			c.closeUnauthorized("authentication required")
 			return
 		}
-		channel := msg.Channel + ":" + msg.ID
+		topic, _ := wsTopic(msg.Channel, msg.ID)
 		c.mu.Lock()
-		delete(c.subs, channel)
+		delete(c.subs, topic)
 		c.mu.Unlock()
 
 		c.sendJSON(map[string]interface{}{
@@ -454,11 +633,35 @@ func (c *WSClient) handleMessage(s *Server, message []byte) {
 			c.closeUnauthorized("authentication required")
 			return
 		}
+		c.mu.Lock()
+		canSend := c.access.canSendInput()
+		c.mu.Unlock()
+		if !canSend {
+			c.sendJSON(map[string]string{"type": "error", "error": "token may not send messages"})
+			return
+		}
 		// Send message to agent session
 		if msg.SessionID != "" && msg.Content != "" {
 			s.handleWSMessage(msg.SessionID, msg.Content)
 		}
 
+	case "resize":
+		if !c.isAuthenticated() {
+			c.closeUnauthorized("authentication required")
+			return
+		}
+		c.mu.Lock()
+		canSend := c.access.canSendInput()
+		c.mu.Unlock()
+		if !canSend {
+			c.sendJSON(map[string]string{"type": "error", "error": "token may not resize sessions"})
+			return
+		}
+		// Resize a terminal session's PTY, as the terminal socket does
+		if msg.SessionID != "" && msg.Cols > 0 && msg.Rows > 0 {
+			_ = s.sessions.runtime.Resize(msg.SessionID, msg.Cols, msg.Rows)
+		}
+
 	case "ping":
 		c.sendJSON(map[string]string{"type": "pong"})
 	}
@@ -470,15 +673,37 @@ func (c *WSClient) isAuthenticated() bool {
 	return c.auth
 }
 
-// setAuthenticated marks a client as authenticated and returns whether it was already authenticated.
-func (c *WSClient) setAuthenticated() bool {
+// setAuthenticated marks a client as authenticated with access and returns
+// whether it was already authenticated. Clients that may see global events
+// are subscribed to them.
+func (c *WSClient) setAuthenticated(access wsAccess) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	already := c.auth
 	c.auth = true
+	c.access = access
+	if access.allows(wsChannelGlobal) {
+		c.subs[wsChannelGlobal] = true
+	}
 	return already
 }
 
+// allows reports whether the client's own token grants a topic.
+func (c *WSClient) allows(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.access.allows(topic)
+}
+
+func (c *WSClient) sendSubscribeError(channel, id, reason string) {
+	c.sendJSON(map[string]string{
+		"type":    "subscribe_error",
+		"channel": channel,
+		"id":      id,
+		"error":   reason,
+	})
+}
+
 func (c *WSClient) closeUnauthorized(reason string) {
 	_ = c.conn.WriteControl(
 		websocket.CloseMessage,
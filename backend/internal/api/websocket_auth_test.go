@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -179,3 +180,162 @@ func TestChatWSRejectsNonChatSession(t *testing.T) {
 		t.Fatalf("expected 400, got %d", resp.Code)
 	}
 }
+
+// dialWSWithToken connects to the hub with a handshake token.
+func dialWSWithToken(t *testing.T, srv *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(wsBaseURL(srv.URL)+"/ws?token="+url.QueryEscape(token), wsDialHeaders())
+	if err != nil {
+		t.Fatalf("dial websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if msg := readWS(t, conn); msg["type"] != "authenticated" {
+		t.Fatalf("expected authenticated, got %#v", msg)
+	}
+	return conn
+}
+
+// readWSMessages reads frames until it has n messages. The hub batches
+// queued messages into one frame, separated by newlines.
+func readWSMessages(t *testing.T, conn *websocket.Conn, n int) []map[string]any {
+	t.Helper()
+	var msgs []map[string]any
+	for len(msgs) < n {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read websocket: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			var msg map[string]any
+			if err := json.Unmarshal([]byte(line), &msg); err != nil {
+				t.Fatalf("invalid websocket message %q: %v", line, err)
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) map[string]any {
+	t.Helper()
+	return readWSMessages(t, conn, 1)[0]
+}
+
+// subscribeWS subscribes and returns the reply's type.
+func subscribeWS(t *testing.T, conn *websocket.Conn, channel, id, token string) string {
+	t.Helper()
+	msg := map[string]string{"type": "subscribe", "channel": channel, "id": id}
+	if token != "" {
+		msg["token"] = token
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("write subscribe: %v", err)
+	}
+	reply := readWS(t, conn)
+	if reply["channel"] != channel {
+		t.Fatalf("unexpected reply %#v", reply)
+	}
+	return reply["type"].(string)
+}
+
+func TestWebSocketHookTokenLimitedToItsSession(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	srv := httptest.NewServer(env.server.router)
+	defer srv.Close()
+
+	hookToken, err := env.server.auth.GenerateHookToken("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dialWSWithToken(t, srv, hookToken)
+
+	for _, sub := range [][2]string{{"session", "s2"}, {"task", "t1"}, {"project", "p1"}, {"global", ""}} {
+		if got := subscribeWS(t, conn, sub[0], sub[1], ""); got != "subscribe_error" {
+			t.Errorf("subscribe %s %s: expected subscribe_error, got %s", sub[0], sub[1], got)
+		}
+	}
+	if got := subscribeWS(t, conn, "session", "s1", ""); got != "subscribed" {
+		t.Fatalf("expected its own session subscribed, got %s", got)
+	}
+
+	env.server.wsHub.BroadcastGlobal("sidebar_update", nil)
+	env.server.wsHub.BroadcastToProject("p1", "project_updated", nil)
+	env.server.wsHub.BroadcastToSession("s2", "status_changed", nil)
+	env.server.wsHub.BroadcastToSession("s1", "status_changed", nil)
+	if msg := readWS(t, conn); msg["type"] != "status_changed" || msg["sessionId"] != "s1" {
+		t.Fatalf("expected only its session's message, got %#v", msg)
+	}
+
+	if err := conn.WriteJSON(map[string]string{"type": "message", "sessionId": "s1", "content": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readWS(t, conn); msg["type"] != "error" {
+		t.Fatalf("expected a hook token to be refused input, got %#v", msg)
+	}
+}
+
+func TestWebSocketTopicsFollowAPITokenScopes(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	srv := httptest.NewServer(env.server.router)
+	defer srv.Close()
+
+	var created struct {
+		Token string `json:"token"`
+	}
+	decodeResponse(t, env.post("/api/auth/tokens", map[string]any{"name": "watcher", "scopes": []string{ScopeSessionsRead}}), &created)
+	conn := dialWSWithToken(t, srv, created.Token)
+
+	if got := subscribeWS(t, conn, "session", "s1", ""); got != "subscribed" {
+		t.Errorf("expected sessions:read to allow session topics, got %s", got)
+	}
+	if got := subscribeWS(t, conn, "task", "t1", ""); got != "subscribe_error" {
+		t.Errorf("expected task topics refused without tasks:read, got %s", got)
+	}
+	if got := subscribeWS(t, conn, "global", "", ""); got != "subscribe_error" {
+		t.Errorf("expected the global topic refused to an API token, got %s", got)
+	}
+	if got := subscribeWS(t, conn, "bogus", "x", ""); got != "subscribe_error" {
+		t.Errorf("expected an unknown channel refused, got %s", got)
+	}
+
+	// A token sent with the subscription authorizes that topic alone.
+	hookToken, _ := env.server.auth.GenerateHookToken("s9")
+	if got := subscribeWS(t, conn, "task", "t1", hookToken); got != "subscribe_error" {
+		t.Errorf("expected a hook token refused for a task topic, got %s", got)
+	}
+	if got := subscribeWS(t, conn, "task", "t1", env.token); got != "subscribed" {
+		t.Errorf("expected a login token to authorize the task topic, got %s", got)
+	}
+
+	env.server.wsHub.BroadcastGlobal("sidebar_update", nil)
+	env.server.wsHub.BroadcastToTask("t1", "task_updated", nil)
+	if msg := readWS(t, conn); msg["type"] != "task_updated" {
+		t.Fatalf("expected the task message and no global one, got %#v", msg)
+	}
+}
+
+func TestWebSocketLoginTokenReceivesProjectEvents(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	srv := httptest.NewServer(env.server.router)
+	defer srv.Close()
+
+	conn := dialWSWithToken(t, srv, env.token)
+	if got := subscribeWS(t, conn, "project", "p1", ""); got != "subscribed" {
+		t.Fatalf("expected subscribed, got %s", got)
+	}
+
+	// Subscribed both globally and to the project, the client gets it once.
+	env.server.wsHub.BroadcastToProject("p1", "project_updated", nil)
+	env.server.wsHub.BroadcastGlobal("sidebar_update", nil)
+	msgs := readWSMessages(t, conn, 2)
+	if msgs[0]["type"] != "project_updated" || msgs[0]["projectId"] != "p1" {
+		t.Fatalf("expected the project message, got %#v", msgs[0])
+	}
+	if msgs[1]["type"] != "sidebar_update" {
+		t.Fatalf("expected the global message next, got %#v", msgs[1])
+	}
+}
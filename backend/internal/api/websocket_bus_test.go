@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBus is an in-process eventbus.Bus shared by several hubs.
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers []func([]byte)
+}
+
+func (b *memoryBus) Publish(_ context.Context, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, handler := range b.handlers {
+		handler(data)
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, handler func([]byte)) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func (b *memoryBus) Close() error { return nil }
+
+func TestWSHub_EventBusRelaysBetweenInstances(t *testing.T) {
+	bus := &memoryBus{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hubA, hubB := NewWSHub(), NewWSHub()
+	for _, hub := range []*WSHub{hubA, hubB} {
+		hub.SetEventBus(bus)
+		go hub.Run(ctx)
+	}
+
+	// A client connected to instance B, subscribed to a session.
+	client := &WSClient{send: make(chan []byte, 8), subs: map[string]bool{"session:s1": true}, auth: true}
+	hubB.mu.Lock()
+	hubB.clients[client] = true
+	hubB.mu.Unlock()
+	defer func() {
+		hubB.mu.Lock()
+		delete(hubB.clients, client)
+		hubB.mu.Unlock()
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		bus.mu.Lock()
+		ready := len(bus.handlers) == 2
+		bus.mu.Unlock()
+		if ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hubs never subscribed to the bus")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The request was handled by instance A.
+	hubA.BroadcastToSession("s1", "session_status", map[string]string{"status": "waiting_input"})
+	hubA.BroadcastToSession("other", "session_status", nil)
+
+	select {
+	case raw := <-client.send:
+		var msg map[string]any
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg["type"] != "session_status" || msg["sessionId"] != "s1" {
+			t.Errorf("unexpected message: %s", raw)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the broadcast to reach the client on the other instance")
+	}
+
+	select {
+	case raw := <-client.send:
+		t.Errorf("unexpected extra message: %s", raw)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+const maxWorktreePoolSize = 10
+
+func worktreePoolEnabled(project *db.Project) bool {
+	return project.WorktreePool != nil && project.WorktreePool.Size > 0 && project.ArchivedAt == nil
+}
+
+// worktreePoolSyncs runs at most one pool sync per project. A sync requested
+// while one is running makes that one go around again.
+type worktreePoolSyncs struct {
+	mu      sync.Mutex
+	running map[string]bool // project ID → another pass requested
+}
+
+// begin reports whether the caller should start a sync for the project.
+func (ps *worktreePoolSyncs) begin(projectID string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.running == nil {
+		ps.running = make(map[string]bool)
+	}
+	if _, ok := ps.running[projectID]; ok {
+		ps.running[projectID] = true
+		return false
+	}
+	ps.running[projectID] = false
+	return true
+}
+
+// end reports whether the sync is done, or must run again because another
+// was requested meanwhile.
+func (ps *worktreePoolSyncs) end(projectID string) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.running[projectID] {
+		ps.running[projectID] = false
+		return false
+	}
+	delete(ps.running, projectID)
+	return true
+}
+
+// syncWorktreePool brings a project's pool to its configured size in the
+// background: it creates worktrees until the pool is full or at its disk
+// quota, and removes entries that are surplus or were created from another
+// base branch.
+func (s *Server) syncWorktreePool(projectID string) {
+	if !s.poolSyncs.begin(projectID) {
+		return
+	}
+	s.bgWG.Add(1)
+	go func() {
+		defer s.bgWG.Done()
+		for {
+			if err := s.fillWorktreePool(projectID); err != nil {
+				slog.Warn("failed to fill worktree pool", "project_id", projectID, "error", err)
+			}
+			if s.poolSyncs.end(projectID) {
+				return
+			}
+		}
+	}()
+}
+
+func (s *Server) fillWorktreePool(projectID string) error {
+	for s.bgCtx.Err() == nil {
+		project, err := s.db.GetProject(projectID)
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entries, err := s.db.ListPooledWorktrees(projectID)
+		if err != nil {
+			return err
+		}
+
+		size := 0
+		var quota int64
+		if worktreePoolEnabled(project) {
+			size = project.WorktreePool.Size
+			quota = int64(project.WorktreePool.MaxDiskMB) << 20
+		}
+
+		count := 0
+		var used, largest int64
+		for _, entry := range entries {
+			if entry.BaseBranch != project.DefaultBranch || count >= size {
+				s.removePooledWorktree(project, entry)
+				continue
+			}
+			count++
+			used += entry.SizeBytes
+			largest = max(largest, entry.SizeBytes)
+		}
+		if count >= size {
+			return nil
+		}
+		if quota > 0 && used+largest > quota {
+			slog.Info("worktree pool is at its disk quota", "project_id", projectID, "worktrees", count)
+			return nil
+		}
+
+		entry, err := s.createPooledWorktree(project)
+		if err != nil {
+			return err
+		}
+		if quota > 0 && used+entry.SizeBytes > quota {
+			s.removePooledWorktree(project, entry)
+			slog.Info("worktree pool is at its disk quota", "project_id", projectID, "worktrees", count)
+			return nil
+		}
+	}
+	return nil
+}
+
+// createPooledWorktree creates a worktree on a placeholder branch with the
+// project's symlinks, clones, secrets and setup script applied.
+func (s *Server) createPooledWorktree(project *db.Project) (*db.PooledWorktree, error) {
+	id := db.NewID()
+	entry := &db.PooledWorktree{
+		ID:         id,
+		ProjectID:  project.ID,
+		Path:       s.worktree.PoolPath(project.Name, id),
+		Branch:     worktree.PoolBranchPrefix + id,
+		BaseBranch: project.DefaultBranch,
+	}
+	// Recorded before creation so that a restart mid-way can clean up.
+	if err := s.db.CreatePooledWorktree(entry); err != nil {
+		return nil, err
+	}
+
+	result, err := s.worktree.Create(worktree.CreateOptions{
+		ProjectPath:  project.Path,
+		ProjectID:    project.ID,
+		ProjectName:  project.Name,
+		TaskID:       id,
+		BranchName:   entry.Branch,
+		BaseBranch:   project.DefaultBranch,
+		SymlinkPaths: project.SymlinkPaths,
+		ClonePaths:   project.ClonePaths,
+		SecretFiles:  mapSecretFiles(project.SecretFiles),
+		SetupScript:  ptrToString(project.SetupScript),
+	})
+	if err != nil {
+		s.db.DeletePooledWorktree(id)
+		return nil, fmt.Errorf("create pooled worktree: %w", err)
+	}
+	for _, warning := range result.Warnings {
+		slog.Warn("pooled worktree created with warning", "project_id", project.ID, "warning", warning)
+	}
+
+	entry.SizeBytes, _ = worktree.DirSize(result.WorktreePath)
+	if err := s.db.MarkPooledWorktreeReady(id, entry.SizeBytes); err != nil {
+		// The project was deleted meanwhile.
+		s.worktree.RemovePooled(project.Path, entry.Path, entry.Branch, "")
+		return nil, err
+	}
+	entry.Status = db.PooledWorktreeReady
+	return entry, nil
+}
+
+// removePooledWorktree deletes a pool entry unless a task claimed it first.
+func (s *Server) removePooledWorktree(project *db.Project, entry *db.PooledWorktree) {
+	if err := s.db.DeletePooledWorktree(entry.ID); err != nil {
+		return
+	}
+	if err := s.worktree.RemovePooled(project.Path, entry.Path, entry.Branch, ptrToString(project.TeardownScript)); err != nil {
+		slog.Warn("failed to remove pooled worktree", "path", entry.Path, "error", err)
+	}
+}
+
+// createTaskWorktree creates a task's worktree, taking one from the project's
+// pool when possible. Tasks adopting an existing branch always get a fresh
+// worktree.
+func (s *Server) createTaskWorktree(project *db.Project, opts worktree.CreateOptions) (*worktree.CreateResult, error) {
+	if worktreePoolEnabled(project) && !opts.AdoptBranch {
+		if result := s.claimPooledWorktree(project, opts); result != nil {
+			return result, nil
+		}
+	}
+	return s.worktree.Create(opts)
+}
+
+func (s *Server) claimPooledWorktree(project *db.Project, opts worktree.CreateOptions) *worktree.CreateResult {
+	defer s.syncWorktreePool(project.ID)
+
+	entry, err := s.db.ClaimPooledWorktree(project.ID, project.DefaultBranch)
+	if err != nil {
+		return nil
+	}
+	result, err := s.worktree.Claim(worktree.ClaimOptions{
+		ProjectPath: project.Path,
+		ProjectName: project.Name,
+		TaskID:      opts.TaskID,
+		BranchName:  opts.BranchName,
+		TaskTitle:   opts.TaskTitle,
+		BaseBranch:  project.DefaultBranch,
+		PoolPath:    entry.Path,
+		PoolBranch:  entry.Branch,
+	})
+	if err != nil {
+		slog.Warn("failed to claim pooled worktree, creating a new one", "task_id", opts.TaskID, "error", err)
+		if err := s.worktree.RemovePooled(project.Path, entry.Path, entry.Branch, ""); err != nil {
+			slog.Warn("failed to remove pooled worktree", "path", entry.Path, "error", err)
+		}
+		return nil
+	}
+	return result
+}
+
+// restoreWorktreePools cleans up pool entries a previous run left half
+// created and tops up every project's pool.
+func (s *Server) restoreWorktreePools() {
+	entries, err := s.db.ListPooledWorktrees("")
+	if err != nil {
+		slog.Warn("failed to list pooled worktrees", "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.Status != db.PooledWorktreeCreating {
+			continue
+		}
+		if project, err := s.db.GetProject(entry.ProjectID); err == nil {
+			s.removePooledWorktree(project, entry)
+		}
+	}
+
+	projects, err := s.db.ListProjects()
+	if err != nil {
+		slog.Warn("failed to list projects", "error", err)
+		return
+	}
+	for _, project := range projects {
+		if worktreePoolEnabled(project) {
+			s.syncWorktreePool(project.ID)
+		}
+	}
+}
+
+type worktreePoolResponse struct {
+	Config    *db.WorktreePoolConfig `json:"config,omitempty"`
+	Worktrees []*db.PooledWorktree   `json:"worktrees"`
+}
+
+func (s *Server) handleGetWorktreePool(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	entries, err := s.db.ListPooledWorktrees(project.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list pooled worktrees")
+		return
+	}
+	writeJSON(w, http.StatusOK, worktreePoolResponse{Config: project.WorktreePool, Worktrees: entries})
+}
+
+// handleRefillWorktreePool starts a pool sync, e.g. after the pool was
+// drained.
+func (s *Server) handleRefillWorktreePool(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	s.syncWorktreePool(project.ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleDrainWorktreePool removes the project's ready pooled worktrees, for
+// instance after its setup script changed. The pool then refills if it is
+// still enabled.
+func (s *Server) handleDrainWorktreePool(w http.ResponseWriter, r *http.Request) {
+	project, err := s.db.GetProject(urlParam(r, "id"))
+	if err != nil {
+		writeDBError(w, err, "project")
+		return
+	}
+	entries, err := s.db.ListPooledWorktrees(project.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list pooled worktrees")
+		return
+	}
+	for _, entry := range entries {
+		if entry.Status == db.PooledWorktreeReady {
+			s.removePooledWorktree(project, entry)
+		}
+	}
+	s.syncWorktreePool(project.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
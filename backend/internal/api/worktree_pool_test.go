@@ -0,0 +1,148 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+// waitForPool waits until a project's pool holds exactly want ready
+// worktrees and nothing else.
+func waitForPool(t *testing.T, env *testEnv, projectID string, want int) []*db.PooledWorktree {
+	t.Helper()
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		entries, err := env.server.db.ListPooledWorktrees(projectID)
+		if err != nil {
+			t.Fatalf("list pooled worktrees: %v", err)
+		}
+		ready := 0
+		for _, e := range entries {
+			if e.Status == db.PooledWorktreeReady {
+				ready++
+			}
+		}
+		if ready == want && len(entries) == want {
+			return entries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pool has %d entries (%d ready), want %d ready", len(entries), ready, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWorktreePool_ClaimAndRefill(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.worktree = worktree.NewManager(worktree.Config{BaseDir: t.TempDir()})
+	repoPath := createTestGitRepoWithMain(t)
+	t.Cleanup(env.server.bgWG.Wait)
+
+	projResp := env.post("/api/projects", map[string]string{
+		"name": "pool-project", "path": repoPath,
+	})
+	var project db.Project
+	decodeResponse(t, projResp, &project)
+
+	resp := env.patch("/api/projects/"+project.ID, map[string]any{
+		"worktreePool": map[string]any{"size": 1},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("enable pool: %d %s", resp.Code, resp.Body.String())
+	}
+	pooled := waitForPool(t, env, project.ID, 1)[0]
+
+	taskResp := env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Pooled Task"})
+	var task db.Task
+	decodeResponse(t, taskResp, &task)
+
+	resp = env.post("/api/tasks/"+task.ID+"/worktree", nil)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create worktree: %d %s", resp.Code, resp.Body.String())
+	}
+	var wt WorktreeResponse
+	decodeResponse(t, resp, &wt)
+	if wt.BranchName == "" || strings.HasPrefix(wt.BranchName, worktree.PoolBranchPrefix) {
+		t.Errorf("branch = %q, want the task's own branch", wt.BranchName)
+	}
+	if !env.server.worktree.Exists(wt.WorktreePath) {
+		t.Fatalf("worktree missing at %s", wt.WorktreePath)
+	}
+	if _, err := os.Stat(pooled.Path); !os.IsNotExist(err) {
+		t.Errorf("pooled worktree should have been moved to the task, stat err = %v", err)
+	}
+
+	// The pool refills with a new worktree.
+	refilled := waitForPool(t, env, project.ID, 1)[0]
+	if refilled.ID == pooled.ID {
+		t.Error("expected a new pooled worktree")
+	}
+
+	// Disabling the pool removes what is left.
+	resp = env.patch("/api/projects/"+project.ID, map[string]any{
+		"worktreePool": map[string]any{"size": 0},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("disable pool: %d %s", resp.Code, resp.Body.String())
+	}
+	waitForPool(t, env, project.ID, 0)
+	env.server.bgWG.Wait()
+	if _, err := os.Stat(refilled.Path); !os.IsNotExist(err) {
+		t.Errorf("pooled worktree should be removed, stat err = %v", err)
+	}
+}
+
+func TestWorktreePool_RespectsDiskQuota(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.worktree = worktree.NewManager(worktree.Config{BaseDir: t.TempDir()})
+	repoPath := createTestGitRepoWithMain(t)
+	t.Cleanup(env.server.bgWG.Wait)
+
+	projResp := env.post("/api/projects", map[string]string{
+		"name": "pool-quota", "path": repoPath,
+	})
+	var project db.Project
+	decodeResponse(t, projResp, &project)
+
+	// Each worktree is well over 1 MB once the setup script has run.
+	setup := "head -c 1500000 /dev/zero > deps.bin"
+	resp := env.patch("/api/projects/"+project.ID, map[string]any{
+		"setupScript":  setup,
+		"worktreePool": map[string]any{"size": 3, "maxDiskMb": 2},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("enable pool: %d %s", resp.Code, resp.Body.String())
+	}
+
+	env.server.bgWG.Wait()
+	waitForPool(t, env, project.ID, 1)
+}
+
+func TestWorktreePool_Validation(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+
+	projResp := env.post("/api/projects", map[string]string{
+		"name": "pool-invalid", "path": createTestGitRepoWithMain(t),
+	})
+	var project db.Project
+	decodeResponse(t, projResp, &project)
+
+	for _, pool := range []map[string]any{
+		{"size": maxWorktreePoolSize + 1},
+		{"size": -1},
+		{"size": 1, "maxDiskMb": -5},
+	} {
+		resp := env.patch("/api/projects/"+project.ID, map[string]any{"worktreePool": pool})
+		if resp.Code != http.StatusBadRequest {
+			t.Errorf("pool %v: expected 400, got %d", pool, resp.Code)
+		}
+	}
+}
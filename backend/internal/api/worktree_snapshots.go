@@ -0,0 +1,306 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// A task's worktree can be snapshotted before something risky, such as an
+// agent with auto-approve, and rolled back to later. A snapshot is the
+// commit the branch was on plus a commit on top of it holding every file as
+// it was, uncommitted and untracked changes included (ignored files are
+// not). It is built in a throwaway index, so the worktree and its staging
+// area are left alone, and kept under refs/codeburg/snapshots so git
+// doesn't collect it.
+
+const (
+	snapshotRefPrefix = "refs/codeburg/snapshots/"
+	// snapshotGitTimeout bounds the git commands of a snapshot, which add
+	// every file of the worktree.
+	snapshotGitTimeout = time.Minute
+)
+
+type worktreeSnapshotRequest struct {
+	Label string `json:"label"`
+}
+
+type worktreeRollbackRequest struct {
+	SnapshotID string `json:"snapshotId"` // default: the latest snapshot
+}
+
+type worktreeRollbackResponse struct {
+	Snapshot *db.WorktreeSnapshot `json:"snapshot"`
+	// Backup is the snapshot taken of the worktree just before, to undo
+	// the rollback with.
+	Backup *db.WorktreeSnapshot `json:"backup"`
+}
+
+// snapshotGit runs git in dir with extra environment, for the throwaway
+// index and the snapshot commit's identity.
+func snapshotGit(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, snapshotGitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s: %w", args[0], strings.TrimSpace(string(out)), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// snapshotWorktree records the state of a task's worktree.
+func (s *Server) snapshotWorktree(ctx context.Context, task *db.Task, label string) (*db.WorktreeSnapshot, error) {
+	workDir := *task.WorktreePath
+	head, err := snapshotGit(ctx, workDir, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	branch, _ := snapshotGit(ctx, workDir, nil, "rev-parse", "--abbrev-ref", "HEAD")
+	headTree, err := snapshotGit(ctx, workDir, nil, "rev-parse", "HEAD^{tree}")
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := os.CreateTemp("", "codeburg-snapshot-index-*")
+	if err != nil {
+		return nil, err
+	}
+	index.Close()
+	// git wants to create the index itself.
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+	env := []string{
+		"GIT_INDEX_FILE=" + index.Name(),
+		"GIT_AUTHOR_NAME=Codeburg", "GIT_AUTHOR_EMAIL=codeburg@localhost",
+		"GIT_COMMITTER_NAME=Codeburg", "GIT_COMMITTER_EMAIL=codeburg@localhost",
+	}
+	if _, err := snapshotGit(ctx, workDir, env, "read-tree", "HEAD"); err != nil {
+		return nil, err
+	}
+	if _, err := snapshotGit(ctx, workDir, env, "add", "-A"); err != nil {
+		return nil, err
+	}
+	tree, err := snapshotGit(ctx, workDir, env, "write-tree")
+	if err != nil {
+		return nil, err
+	}
+	message := "codeburg snapshot"
+	if label != "" {
+		message += ": " + label
+	}
+	state, err := snapshotGit(ctx, workDir, env, "commit-tree", tree, "-p", head, "-m", message)
+	if err != nil {
+		return nil, err
+	}
+
+	id := db.NewID()
+	if _, err := snapshotGit(ctx, workDir, nil, "update-ref", snapshotRefPrefix+id, state); err != nil {
+		return nil, err
+	}
+	snapshot, err := s.db.CreateWorktreeSnapshot(db.CreateWorktreeSnapshotInput{
+		ID: id, TaskID: task.ID, Label: label, Branch: branch,
+		HeadCommit: head, StateCommit: state, Dirty: tree != headTree,
+	})
+	if err != nil {
+		snapshotGit(ctx, workDir, nil, "update-ref", "-d", snapshotRefPrefix+id)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// rollbackWorktree puts a task's worktree back to a snapshot: the branch
+// on the snapshot's commit and the files as they were. Changes made since
+// are dropped, untracked files included; ignored files stay. Changes that
+// were staged come back unstaged. The worktree must be on the snapshot's
+// branch (see snapshotBranchMismatch), or the reset would move another
+// branch onto the snapshot's commit.
+func (s *Server) rollbackWorktree(ctx context.Context, task *db.Task, snapshot *db.WorktreeSnapshot) error {
+	workDir := *task.WorktreePath
+	steps := [][]string{
+		{"reset", "--hard", snapshot.HeadCommit},
+		{"clean", "-fd"},
+		{"read-tree", "-u", "--reset", snapshot.StateCommit},
+		{"reset", "-q"},
+	}
+	for _, args := range steps {
+		if _, err := snapshotGit(ctx, workDir, nil, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotBranchMismatch returns the branch a task's worktree is on when it
+// is not the one the snapshot was taken on, or "".
+func snapshotBranchMismatch(ctx context.Context, task *db.Task, snapshot *db.WorktreeSnapshot) (string, error) {
+	branch, err := snapshotGit(ctx, *task.WorktreePath, nil, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if snapshot.Branch == "" || branch == snapshot.Branch {
+		return "", nil
+	}
+	return branch, nil
+}
+
+// removeWorktreeSnapshots drops a task's snapshot refs, when it is deleted.
+// The rows go with the task.
+func (s *Server) removeWorktreeSnapshots(taskID string, project *db.Project) {
+	snapshots, err := s.db.ListWorktreeSnapshots(taskID)
+	if err != nil {
+		return
+	}
+	for _, snapshot := range snapshots {
+		if _, err := runGit(project.Path, "update-ref", "-d", snapshotRefPrefix+snapshot.ID); err != nil {
+			slog.Warn("failed to remove snapshot ref", "task_id", taskID, "snapshot_id", snapshot.ID, "error", err)
+		}
+	}
+}
+
+// taskSessionRunning reports whether an agent may be changing the task's
+// worktree right now.
+func (s *Server) taskSessionRunning(taskID string) bool {
+	sessions, err := s.db.ListSessionsByTask(taskID)
+	if err != nil {
+		return false
+	}
+	for _, session := range sessions {
+		if session.Status == db.SessionStatusRunning {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleCreateWorktreeSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req worktreeSnapshotRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	task, _, err := s.taskWorktree(urlParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, err, "failed to snapshot worktree")
+		return
+	}
+
+	snapshot, err := s.snapshotWorktree(r.Context(), task, strings.TrimSpace(req.Label))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to snapshot worktree: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, snapshot)
+}
+
+func (s *Server) handleListWorktreeSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := s.db.ListWorktreeSnapshots(urlParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list snapshots")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// handleRollbackWorktree rolls a task's worktree back to a snapshot, after
+// snapshotting it as it is now. It is refused while a session of the task
+// is running, since the agent would keep writing.
+func (s *Server) handleRollbackWorktree(w http.ResponseWriter, r *http.Request) {
+	var req worktreeRollbackRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	task, _, err := s.taskWorktree(urlParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, err, "failed to roll back worktree")
+		return
+	}
+
+	var snapshot *db.WorktreeSnapshot
+	if req.SnapshotID == "" {
+		snapshots, err := s.db.ListWorktreeSnapshots(task.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to list snapshots")
+			return
+		}
+		if len(snapshots) == 0 {
+			writeError(w, http.StatusNotFound, "task has no snapshots")
+			return
+		}
+		snapshot = snapshots[0]
+	} else {
+		snapshot, err = s.db.GetWorktreeSnapshot(req.SnapshotID)
+		if err != nil || snapshot.TaskID != task.ID {
+			writeError(w, http.StatusNotFound, "snapshot not found")
+			return
+		}
+	}
+	if s.taskSessionRunning(task.ID) {
+		writeError(w, http.StatusConflict, "a session of the task is running; stop it or wait for it first")
+		return
+	}
+	branch, err := snapshotBranchMismatch(r.Context(), task, snapshot)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to roll back worktree: "+err.Error())
+		return
+	}
+	if branch != "" {
+		writeError(w, http.StatusConflict, fmt.Sprintf("the worktree is on %s, but the snapshot was taken on %s; check out %s first", branch, snapshot.Branch, snapshot.Branch))
+		return
+	}
+
+	label := "before rollback"
+	if snapshot.Label != "" {
+		label += " to " + snapshot.Label
+	}
+	backup, err := s.snapshotWorktree(r.Context(), task, label)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to snapshot worktree before rollback: "+err.Error())
+		return
+	}
+	if err := s.rollbackWorktree(r.Context(), task, snapshot); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to roll back worktree: "+err.Error())
+		return
+	}
+
+	s.wakeDiffStats()
+	s.wsHub.BroadcastToProject(task.ProjectID, "worktree_rolled_back", map[string]string{
+		"taskId":     task.ID,
+		"snapshotId": snapshot.ID,
+	})
+	writeJSON(w, http.StatusOK, worktreeRollbackResponse{Snapshot: snapshot, Backup: backup})
+}
+
+func (s *Server) handleDeleteWorktreeSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.db.GetWorktreeSnapshot(urlParam(r, "snapshotId"))
+	if err != nil || snapshot.TaskID != urlParam(r, "id") {
+		writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	// Worktrees share their repository's refs, so the project's will do,
+	// even once the worktree is gone.
+	if task, err := s.db.GetTask(snapshot.TaskID); err == nil {
+		if project, err := s.db.GetProject(task.ProjectID); err == nil {
+			if _, err := runGit(project.Path, "update-ref", "-d", snapshotRefPrefix+snapshot.ID); err != nil {
+				slog.Warn("failed to remove snapshot ref", "snapshot_id", snapshot.ID, "error", err)
+			}
+		}
+	}
+	if err := s.db.DeleteWorktreeSnapshot(snapshot.ID); err != nil {
+		writeDBError(w, err, "snapshot")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/worktree"
+)
+
+func TestWorktreeSnapshotRollback(t *testing.T) {
+	env := setupTestEnv(t)
+	env.setup("testpass123")
+	env.server.worktree = worktree.NewManager(worktree.Config{BaseDir: t.TempDir()})
+
+	var project db.Project
+	decodeResponse(t, env.post("/api/projects", map[string]string{"name": "p", "path": createTestGitRepoWithMain(t)}), &project)
+	var task db.Task
+	decodeResponse(t, env.post("/api/projects/"+project.ID+"/tasks", map[string]string{"title": "Risky"}), &task)
+	if resp := env.post("/api/tasks/"+task.ID+"/snapshot", nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a worktree, got %d", resp.Code)
+	}
+	var wt WorktreeResponse
+	decodeResponse(t, env.post("/api/tasks/"+task.ID+"/worktree", nil), &wt)
+
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", wt.WorktreePath}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(wt.WorktreePath, name), []byte(content), 0o644)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(wt.WorktreePath, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	write("README.md", "# Edited")
+	write("notes.txt", "draft")
+	head := git("rev-parse", "HEAD")
+	status := git("status", "--porcelain")
+
+	resp := env.post("/api/tasks/"+task.ID+"/snapshot", map[string]string{"label": "before agent"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("snapshot: %d %s", resp.Code, resp.Body.String())
+	}
+	var snapshot db.WorktreeSnapshot
+	decodeResponse(t, resp, &snapshot)
+	if snapshot.HeadCommit != head || !snapshot.Dirty || snapshot.Label != "before agent" {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+	if got := git("status", "--porcelain"); got != status {
+		t.Errorf("expected the snapshot to leave the worktree alone, status %q became %q", status, got)
+	}
+
+	// The agent commits, edits, deletes and adds files.
+	write("README.md", "# Agent")
+	git("commit", "-am", "agent work")
+	os.Remove(filepath.Join(wt.WorktreePath, "notes.txt"))
+	write("junk.txt", "junk")
+
+	resp = env.post("/api/tasks/"+task.ID+"/rollback", map[string]string{"snapshotId": snapshot.ID})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", resp.Code, resp.Body.String())
+	}
+	var result worktreeRollbackResponse
+	decodeResponse(t, resp, &result)
+	if git("rev-parse", "HEAD") != head {
+		t.Error("expected the branch back on the snapshot's commit")
+	}
+	if read("README.md") != "# Edited" || read("notes.txt") != "draft" || read("junk.txt") != "<missing>" {
+		t.Errorf("expected the files as snapshotted, got README %q, notes %q, junk %q", read("README.md"), read("notes.txt"), read("junk.txt"))
+	}
+	if got := git("status", "--porcelain"); got != status {
+		t.Errorf("expected status %q after the rollback, got %q", status, got)
+	}
+
+	// The backup undoes the rollback.
+	if result.Backup == nil || result.Backup.HeadCommit == head {
+		t.Fatalf("expected a backup on the agent's commit, got %+v", result.Backup)
+	}
+	var snapshots []db.WorktreeSnapshot
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/snapshots"), &snapshots)
+	if len(snapshots) != 2 || snapshots[0].ID != result.Backup.ID {
+		t.Fatalf("expected the backup listed first, got %+v", snapshots)
+	}
+	if resp := env.post("/api/tasks/"+task.ID+"/rollback", nil); resp.Code != http.StatusOK {
+		t.Fatalf("undo rollback: %d %s", resp.Code, resp.Body.String())
+	}
+	if read("junk.txt") != "junk" || read("notes.txt") != "<missing>" || read("README.md") != "# Agent" {
+		t.Error("expected the latest snapshot, the backup, to bring the agent's work back")
+	}
+
+	// A snapshot is only rolled back onto the branch it was taken on.
+	branch := git("rev-parse", "--abbrev-ref", "HEAD")
+	git("checkout", "-q", "-b", "experiment")
+	experiment := git("rev-parse", "HEAD")
+	resp = env.post("/api/tasks/"+task.ID+"/rollback", map[string]string{"snapshotId": snapshot.ID})
+	if resp.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on another branch, got %d %s", resp.Code, resp.Body.String())
+	}
+	if git("rev-parse", "experiment") != experiment || read("junk.txt") != "junk" {
+		t.Error("expected the other branch and its files left alone")
+	}
+	decodeResponse(t, env.get("/api/tasks/"+task.ID+"/snapshots"), &snapshots)
+	if len(snapshots) != 3 {
+		t.Errorf("expected no backup taken for a refused rollback, got %d snapshots", len(snapshots))
+	}
+	git("checkout", "-q", branch)
+
+	if resp := env.delete("/api/tasks/" + task.ID + "/snapshots/" + snapshot.ID); resp.Code != http.StatusNoContent {
+		t.Fatalf("delete snapshot: %d", resp.Code)
+	}
+	if out, err := exec.Command("git", "-C", wt.WorktreePath, "rev-parse", "--verify", "-q", snapshotRefPrefix+snapshot.ID).CombinedOutput(); err == nil {
+		t.Errorf("expected the snapshot ref deleted, got %s", out)
+	}
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
 	"github.com/miguel-bm/codeburg/internal/worktree"
 )
 
@@ -14,6 +19,8 @@ type WorktreeResponse struct {
 	Warnings     []string `json:"warnings,omitempty"`
 }
 
+// handleCreateWorktree creates a task's worktree in a job (see
+// respondWithJob).
 func (s *Server) handleCreateWorktree(w http.ResponseWriter, r *http.Request) {
 	taskID := urlParam(r, "id")
 
@@ -35,44 +42,67 @@ func (s *Server) handleCreateWorktree(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get project
+	s.respondWithJob(w, r, jobKindWorktreeCreate, task.ProjectID, taskJobPayload{TaskID: task.ID}, http.StatusCreated)
+}
+
+// runWorktreeJob creates a task's worktree and provisions it; the result is
+// a WorktreeResponse.
+func (s *Server) runWorktreeJob(ctx context.Context, job *db.Job, progress func(string)) (any, error) {
+	var payload taskJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	task, err := s.db.GetTask(payload.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("find task: %w", err)
+	}
 	project, err := s.db.GetProject(task.ProjectID)
 	if err != nil {
-		writeDBError(w, err, "project")
-		return
+		return nil, fmt.Errorf("find project: %w", err)
 	}
 
 	// Create worktree
-	result, err := s.worktree.Create(worktree.CreateOptions{
+	progress("Creating worktree")
+	_, span := telemetry.Start(ctx, "worktree.create", telemetry.String("task.id", task.ID))
+	result, err := s.createTaskWorktree(project, worktree.CreateOptions{
 		ProjectPath:  project.Path,
 		ProjectID:    project.ID,
 		ProjectName:  project.Name,
 		TaskID:       task.ID,
 		BaseBranch:   project.DefaultBranch,
 		SymlinkPaths: project.SymlinkPaths,
+		ClonePaths:   project.ClonePaths,
 		SecretFiles:  mapSecretFiles(project.SecretFiles),
 		SetupScript:  ptrToString(project.SetupScript),
 	})
+	span.RecordError(err)
+	span.End()
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create worktree: "+err.Error())
-		return
+		return nil, fmt.Errorf("create worktree: %w", err)
 	}
 
 	// Update task with worktree info
-	_, err = s.db.UpdateTask(taskID, db.UpdateTaskInput{
+	_, err = s.db.UpdateTask(task.ID, db.UpdateTaskInput{
 		WorktreePath: &result.WorktreePath,
 		Branch:       &result.BranchName,
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update task with worktree info")
-		return
+		return nil, fmt.Errorf("update task with worktree info: %w", err)
+	}
+
+	warnings := result.Warnings
+	if provisionerEnabled(project) {
+		progress("Provisioning")
+		if _, err := s.provisionTask(task.ID, project, result.WorktreePath); err != nil {
+			warnings = append(warnings, err.Error())
+		}
 	}
 
-	writeJSON(w, http.StatusCreated, WorktreeResponse{
+	return WorktreeResponse{
 		WorktreePath: result.WorktreePath,
 		BranchName:   result.BranchName,
-		Warnings:     result.Warnings,
-	})
+		Warnings:     warnings,
+	}, nil
 }
 
 func (s *Server) handleDeleteWorktree(w http.ResponseWriter, r *http.Request) {
@@ -98,6 +128,12 @@ func (s *Server) handleDeleteWorktree(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The provisioner's teardown runs in the worktree, so it goes first
+	if err := s.teardownTaskProvision(task, project); err != nil {
+		slog.Warn("failed to tear down task provision", "task_id", taskID, "error", err)
+	}
+	s.removeTaskContainer(task, project)
+
 	// Delete worktree
 	err = s.worktree.Delete(worktree.DeleteOptions{
 		ProjectPath:    project.Path,
@@ -0,0 +1,218 @@
+// Package boardimage renders a compact PNG snapshot of a kanban board, for
+// chats that show images better than long text.
+package boardimage
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// Board is the content of a snapshot.
+type Board struct {
+	Title   string
+	Columns []Column
+}
+
+// Column is a board column. Count is how many tasks it holds, which may be
+// more than the Tasks shown.
+type Column struct {
+	Title string
+	Count int
+	Tasks []Task
+}
+
+// Task is a card on the board. Priority is one of urgent, high, medium or
+// low, or empty.
+type Task struct {
+	Title    string
+	Priority string
+}
+
+// Layout, in pixels. Text is the 5x7 font drawn at scale.
+const (
+	scale        = 2
+	charWidth    = glyphAdvance * scale
+	lineHeight   = (glyphHeight + 4) * scale
+	margin       = 16
+	gap          = 12
+	columnWidth  = 232
+	columnPad    = 10
+	cardPad      = 8
+	cardBar      = 4
+	cardGap      = 8
+	cardLines    = 2
+	headerHeight = lineHeight + 2*columnPad
+	titleHeight  = lineHeight + margin
+)
+
+var (
+	colorBackground = color.RGBA{0x17, 0x19, 0x1d, 0xff}
+	colorColumn     = color.RGBA{0x22, 0x25, 0x2b, 0xff}
+	colorCard       = color.RGBA{0x2e, 0x32, 0x3a, 0xff}
+	colorText       = color.RGBA{0xe6, 0xe8, 0xeb, 0xff}
+	colorMuted      = color.RGBA{0x8b, 0x91, 0x9b, 0xff}
+
+	priorityColors = map[string]color.RGBA{
+		"urgent": {0xe5, 0x48, 0x4d, 0xff},
+		"high":   {0xf0, 0x8c, 0x2e, 0xff},
+		"medium": {0xe5, 0xc0, 0x3a, 0xff},
+		"low":    {0x4a, 0x90, 0xd9, 0xff},
+	}
+)
+
+// Render draws the board as a PNG: its columns side by side, each headed by
+// its title and task count, with a card per task colored by priority.
+func Render(b Board) ([]byte, error) {
+	if len(b.Columns) == 0 {
+		return nil, fmt.Errorf("board has no columns")
+	}
+
+	textWidth := (columnWidth - 2*columnPad - cardBar - 2*cardPad) / charWidth
+	type card struct {
+		lines    [][]rune
+		priority string
+	}
+	cards := make([][]card, len(b.Columns))
+	tallest := 0
+	for i, col := range b.Columns {
+		height := headerHeight
+		for _, t := range col.Tasks {
+			c := card{lines: wrap(printable(t.Title), textWidth, cardLines), priority: t.Priority}
+			cards[i] = append(cards[i], c)
+			height += len(c.lines)*lineHeight + 2*cardPad + cardGap
+		}
+		if col.Count > len(col.Tasks) {
+			height += lineHeight
+		}
+		tallest = max(tallest, height+columnPad)
+	}
+
+	width := 2*margin + len(b.Columns)*columnWidth + (len(b.Columns)-1)*gap
+	height := titleHeight + tallest + margin
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill(img, img.Bounds(), colorBackground)
+	drawText(img, margin, margin, truncate(printable(b.Title), (width-2*margin)/charWidth), colorText)
+
+	for i, col := range b.Columns {
+		x := margin + i*(columnWidth+gap)
+		y := titleHeight
+		fill(img, image.Rect(x, y, x+columnWidth, y+tallest), colorColumn)
+
+		count := []rune(fmt.Sprintf(" %d", col.Count))
+		title := truncate(printable(col.Title), (columnWidth-2*columnPad)/charWidth-len(count))
+		drawText(img, x+columnPad, y+columnPad, title, colorText)
+		drawText(img, x+columnPad+len(title)*charWidth, y+columnPad, count, colorMuted)
+		y += headerHeight
+
+		for _, c := range cards[i] {
+			cardHeight := len(c.lines)*lineHeight + 2*cardPad
+			fill(img, image.Rect(x+columnPad, y, x+columnWidth-columnPad, y+cardHeight), colorCard)
+			bar, ok := priorityColors[c.priority]
+			if !ok {
+				bar = colorMuted
+			}
+			fill(img, image.Rect(x+columnPad, y, x+columnPad+cardBar, y+cardHeight), bar)
+			for j, line := range c.lines {
+				drawText(img, x+columnPad+cardBar+cardPad, y+cardPad+j*lineHeight, line, colorText)
+			}
+			y += cardHeight + cardGap
+		}
+		if more := col.Count - len(col.Tasks); more > 0 {
+			drawText(img, x+columnPad, y, []rune(fmt.Sprintf("+%d more", more)), colorMuted)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+// drawText draws text with its top left corner at x, y.
+func drawText(img *image.RGBA, x, y int, text []rune, c color.RGBA) {
+	for _, r := range text {
+		glyph := glyphs['?'-0x20]
+		if r >= 0x20 && r <= 0x7E {
+			glyph = glyphs[r-0x20]
+		}
+		for col, bits := range glyph {
+			for row := range glyphHeight {
+				if bits&(1<<row) == 0 {
+					continue
+				}
+				px, py := x+col*scale, y+row*scale
+				fill(img, image.Rect(px, py, px+scale, py+scale), c)
+			}
+		}
+		x += charWidth
+	}
+}
+
+// truncate shortens text to width characters, ending it with "..." when
+// cut.
+func truncate(text []rune, width int) []rune {
+	if len(text) <= width {
+		return text
+	}
+	if width <= 3 {
+		return text[:max(width, 0)]
+	}
+	return append(text[:width-3:width-3], '.', '.', '.')
+}
+
+// wrap breaks text into at most maxLines lines of width characters, at
+// spaces where it can. The last line is truncated if text doesn't fit.
+func wrap(text []rune, width, maxLines int) [][]rune {
+	words := make([][]rune, 0)
+	for start := 0; start < len(text); {
+		for start < len(text) && text[start] == ' ' {
+			start++
+		}
+		end := start
+		for end < len(text) && text[end] != ' ' {
+			end++
+		}
+		if end > start {
+			words = append(words, text[start:end])
+		}
+		start = end
+	}
+	if len(words) == 0 {
+		return [][]rune{nil}
+	}
+
+	lines := make([][]rune, 0, maxLines)
+	var line []rune
+	for i, word := range words {
+		switch {
+		case len(line) == 0:
+			line = append(line, word...)
+		case len(line)+1+len(word) <= width:
+			line = append(append(line, ' '), word...)
+		default:
+			if len(lines) == maxLines-1 {
+				// Out of lines: the rest goes on this one, truncated.
+				for _, rest := range words[i:] {
+					line = append(append(line, ' '), rest...)
+				}
+				return append(lines, truncate(line, width))
+			}
+			lines = append(lines, line)
+			line = append([]rune(nil), word...)
+		}
+		for len(line) > width && len(lines) < maxLines-1 {
+			lines = append(lines, line[:width:width])
+			line = line[width:]
+		}
+	}
+	return append(lines, truncate(line, width))
+}
@@ -0,0 +1,73 @@
+package boardimage
+
+import (
+	"bytes"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data, err := Render(Board{
+		Title: "Tasks",
+		Columns: []Column{
+			{Title: "Backlog", Count: 3, Tasks: []Task{
+				{Title: "Write the release notes for the next version", Priority: "high"},
+				{Title: "Café menu"},
+			}},
+			{Title: "In Progress", Count: 0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got, want := img.Bounds().Dx(), 2*margin+2*columnWidth+gap; got != want {
+		t.Errorf("width = %d, want %d", got, want)
+	}
+	// Header, a two-line and a one-line card, and the "+1 more" line.
+	wantHeight := titleHeight + headerHeight + (3*lineHeight + 4*cardPad + 2*cardGap) + lineHeight + columnPad + margin
+	if got := img.Bounds().Dy(); got != wantHeight {
+		t.Errorf("height = %d, want %d", got, wantHeight)
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	if _, err := Render(Board{Title: "Tasks"}); err == nil {
+		t.Fatal("expected an error for a board without columns")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := func(ls [][]rune) []string {
+		out := make([]string, len(ls))
+		for i, l := range ls {
+			out[i] = string(l)
+		}
+		return out
+	}
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"short", []string{"short"}},
+		{"fix the login page", []string{"fix the", "login page"}},
+		{"one two three four five", []string{"one two", "three f..."}},
+		{"abcdefghijklmnop", []string{"abcdefghij", "klmnop"}},
+		{"", []string{""}},
+	}
+	for _, tc := range cases {
+		if got := lines(wrap([]rune(tc.text), 10, 2)); !slices.Equal(got, tc.want) {
+			t.Errorf("wrap(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	if got := string(printable("Café “naïve” — ok 🚀")); got != `Cafe "naive" - ok ?` {
+		t.Errorf("printable = %q", got)
+	}
+}
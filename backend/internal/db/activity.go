@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActivityCounts is what agents did in some period.
+type ActivityCounts struct {
+	Messages  int `json:"messages"`
+	ToolCalls int `json:"toolCalls"`
+	Commits   int `json:"commits"`
+}
+
+// Add returns the sum of two counts.
+func (c ActivityCounts) Add(o ActivityCounts) ActivityCounts {
+	return ActivityCounts{
+		Messages:  c.Messages + o.Messages,
+		ToolCalls: c.ToolCalls + o.ToolCalls,
+		Commits:   c.Commits + o.Commits,
+	}
+}
+
+// Total is the number of events counted.
+func (c ActivityCounts) Total() int {
+	return c.Messages + c.ToolCalls + c.Commits
+}
+
+// ActivityHour is the activity of one provider in one project during one
+// hour, in UTC.
+type ActivityHour struct {
+	ProjectID string    `json:"projectId"`
+	Provider  string    `json:"provider"`
+	Hour      time.Time `json:"hour"`
+	ActivityCounts
+}
+
+// ActivityCommit is a commit seen in a session's work directory.
+type ActivityCommit struct {
+	Hash string
+	At   time.Time
+}
+
+// ActivityFilter selects activity. Empty fields match everything; Until is
+// exclusive.
+type ActivityFilter struct {
+	ProjectID string
+	Provider  string
+	Since     time.Time
+	Until     time.Time
+}
+
+// activityHour is the bucket t falls in.
+func activityHour(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+// AddActivity adds counts to the hour at falls in.
+func (db *DB) AddActivity(projectID, provider string, at time.Time, counts ActivityCounts) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO activity_hours (project_id, provider, hour, messages, tool_calls, commits)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_id, provider, hour) DO UPDATE SET
+			messages = messages + excluded.messages,
+			tool_calls = tool_calls + excluded.tool_calls,
+			commits = commits + excluded.commits
+	`, projectID, provider, activityHour(at), counts.Messages, counts.ToolCalls, counts.Commits)
+	if err != nil {
+		return fmt.Errorf("add activity: %w", err)
+	}
+	return nil
+}
+
+// RecordActivityCommits counts the commits not counted before for the
+// project, each in the hour it was made, and returns how many were new.
+func (db *DB) RecordActivityCommits(projectID, provider string, commits []ActivityCommit) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, c := range commits {
+		result, err := tx.Exec(`INSERT OR IGNORE INTO activity_commits (project_id, hash) VALUES (?, ?)`, projectID, c.Hash)
+		if err != nil {
+			return 0, fmt.Errorf("insert activity commit: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO activity_hours (project_id, provider, hour, commits) VALUES (?, ?, ?, 1)
+			ON CONFLICT (project_id, provider, hour) DO UPDATE SET commits = commits + 1
+		`, projectID, provider, activityHour(c.At))
+		if err != nil {
+			return 0, fmt.Errorf("count activity commit: %w", err)
+		}
+		added++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit activity commits: %w", err)
+	}
+	return added, nil
+}
+
+// ListActivity returns the hours with activity matching filter, oldest
+// first.
+func (db *DB) ListActivity(filter ActivityFilter) ([]ActivityHour, error) {
+	query := `SELECT project_id, provider, hour, messages, tool_calls, commits FROM activity_hours WHERE 1 = 1`
+	var args []any
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.Provider != "" {
+		query += ` AND provider = ?`
+		args = append(args, filter.Provider)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND hour >= ?`
+		args = append(args, activityHour(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND hour < ?`
+		args = append(args, filter.Until.UTC())
+	}
+	query += ` ORDER BY hour, project_id, provider`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query activity: %w", err)
+	}
+	defer rows.Close()
+
+	hours := make([]ActivityHour, 0)
+	for rows.Next() {
+		var h ActivityHour
+		if err := rows.Scan(&h.ProjectID, &h.Provider, &h.Hour, &h.Messages, &h.ToolCalls, &h.Commits); err != nil {
+			return nil, err
+		}
+		h.Hour = h.Hour.UTC()
+		hours = append(hours, h)
+	}
+	return hours, rows.Err()
+}
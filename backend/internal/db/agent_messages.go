@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -51,26 +52,52 @@ func (db *DB) CreateAgentMessage(input CreateAgentMessageInput) (*AgentMessage,
 
 // ListAgentMessagesBySession returns all chat messages for a session ordered by sequence.
 func (db *DB) ListAgentMessagesBySession(sessionID string) ([]*AgentMessage, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, session_id, seq, kind, payload_json, created_at
+	out, _, err := db.ListAgentMessagesPage(sessionID, Page{})
+	return out, err
+}
+
+// agentMessagePageOrder pages a session's chat messages in sequence order.
+var agentMessagePageOrder = pageOrder{table: "agent_messages", cols: []string{"seq", "created_at", "id"}}
+
+// ListAgentMessagesPage returns one page of a session's chat messages in
+// sequence order and the cursor for the next.
+func (db *DB) ListAgentMessagesPage(sessionID string, page Page) ([]*AgentMessage, string, error) {
+	query := `
+		SELECT id, session_id, seq, kind, payload_json, created_at` + agentMessagePageOrder.selectKeys() + `
 		FROM agent_messages
-		WHERE session_id = ?
-		ORDER BY seq ASC, created_at ASC
-	`, sessionID)
+		WHERE session_id = ?`
+	args := []any{sessionID}
+	if page.Cursor != "" {
+		cond, cursorArgs, err := db.keyset(agentMessagePageOrder, page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += " AND " + cond
+		args = append(args, cursorArgs...)
+	}
+	query += " ORDER BY seq ASC, created_at ASC, id ASC" + pageLimit(page)
+
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query agent messages: %w", err)
+		return nil, "", fmt.Errorf("query agent messages: %w", err)
 	}
 	defer rows.Close()
 
 	out := make([]*AgentMessage, 0)
+	var cursors []string
 	for rows.Next() {
-		msg, err := scanAgentMessage(rows.Scan)
+		msg, err := scanAgentMessage(agentMessagePageOrder.scanKeys(rows.Scan, &cursors))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		out = append(out, msg)
 	}
-	return out, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	out, next := trimPage(out, cursors, page)
+	return out, next, nil
 }
 
 // UpdateAgentMessagePayload replaces a message payload and optional kind.
@@ -184,3 +211,110 @@ func scanAgentMessage(scan scanFunc) (*AgentMessage, error) {
 	}
 	return &msg, nil
 }
+
+// expiredMessageConds returns the conditions matching a session's messages
+// created before cutoff, when not zero, or beyond its latest keep, when
+// positive.
+func expiredMessageConds(sessionID string, cutoff time.Time, keep int) ([]string, []any) {
+	var conds []string
+	var args []any
+	if !cutoff.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, cutoff)
+	}
+	if keep > 0 {
+		conds = append(conds, `id NOT IN (
+			SELECT id FROM agent_messages WHERE session_id = ?
+			ORDER BY seq DESC, created_at DESC, id DESC LIMIT ?)`)
+		args = append(args, sessionID, keep)
+	}
+	return conds, args
+}
+
+// SessionsWithExpiredMessages returns the sessions holding messages created
+// before cutoff, when not zero, or more than keep messages, when positive.
+func (db *DB) SessionsWithExpiredMessages(cutoff time.Time, keep int) ([]string, error) {
+	var having []string
+	var args []any
+	if !cutoff.IsZero() {
+		having = append(having, "MIN(created_at) < ?")
+		args = append(args, cutoff)
+	}
+	if keep > 0 {
+		having = append(having, "COUNT(*) > ?")
+		args = append(args, keep)
+	}
+	if len(having) == 0 {
+		return nil, nil
+	}
+	rows, err := db.conn.Query(`SELECT session_id FROM agent_messages GROUP BY session_id HAVING `+strings.Join(having, " OR "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("query sessions with expired messages: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ExpiredAgentMessages returns a session's messages created before cutoff,
+// when not zero, or beyond its latest keep, when positive, in sequence
+// order.
+func (db *DB) ExpiredAgentMessages(sessionID string, cutoff time.Time, keep int) ([]*AgentMessage, error) {
+	conds, condArgs := expiredMessageConds(sessionID, cutoff, keep)
+	if len(conds) == 0 {
+		return []*AgentMessage{}, nil
+	}
+	rows, err := db.conn.Query(`
+		SELECT id, session_id, seq, kind, payload_json, created_at
+		FROM agent_messages
+		WHERE session_id = ? AND (`+strings.Join(conds, " OR ")+`)
+		ORDER BY seq ASC, created_at ASC, id ASC`,
+		append([]any{sessionID}, condArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query expired agent messages: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]*AgentMessage, 0)
+	for rows.Next() {
+		msg, err := scanAgentMessage(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msg)
+	}
+	return out, rows.Err()
+}
+
+// DeleteAgentMessages deletes the messages with the given IDs.
+func (db *DB) DeleteAgentMessages(ids []string) (int64, error) {
+	const batch = 500
+	var deleted int64
+	for len(ids) > 0 {
+		n := min(len(ids), batch)
+		args := make([]any, n)
+		for i, id := range ids[:n] {
+			args[i] = id
+		}
+		result, err := db.conn.Exec(`DELETE FROM agent_messages WHERE id IN (?`+strings.Repeat(", ?", n-1)+`)`, args...)
+		if err != nil {
+			return deleted, fmt.Errorf("delete agent messages: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += rows
+		ids = ids[n:]
+	}
+	return deleted, nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// APIToken is a personal access token used by scripts and CI. Only a hash of
+// the secret is stored; TokenPrefix keeps the first characters for display.
+type APIToken struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"tokenPrefix"`
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type CreateAPITokenInput struct {
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	Scopes      []string
+	ExpiresAt   *time.Time
+}
+
+const apiTokenColumns = `id, name, token_prefix, scopes, created_at, expires_at, last_used_at`
+
+// CreateAPIToken stores a new token hash.
+func (db *DB) CreateAPIToken(input CreateAPITokenInput) (*APIToken, error) {
+	scopes, err := json.Marshal(input.Scopes)
+	if err != nil {
+		return nil, fmt.Errorf("marshal scopes: %w", err)
+	}
+
+	id := NewID()
+	var expiresAt sql.NullTime
+	if input.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *input.ExpiresAt, Valid: true}
+	}
+	_, err = db.conn.Exec(
+		`INSERT INTO api_tokens (id, name, token_hash, token_prefix, scopes, created_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, input.Name, input.TokenHash, input.TokenPrefix, string(scopes), time.Now(), expiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert api token: %w", err)
+	}
+
+	row := db.conn.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE id = ?`, id)
+	return scanAPIToken(row.Scan)
+}
+
+// GetAPITokenByHash looks up a token by the hash of its secret.
+func (db *DB) GetAPITokenByHash(hash string) (*APIToken, error) {
+	row := db.conn.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, hash)
+	t, err := scanAPIToken(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// ListAPITokens returns all tokens, newest first.
+func (db *DB) ListAPITokens() ([]*APIToken, error) {
+	rows, err := db.conn.Query(`SELECT ` + apiTokenColumns + ` FROM api_tokens ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("query api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*APIToken, 0)
+	for rows.Next() {
+		t, err := scanAPIToken(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// TouchAPIToken records that a token was just used.
+func (db *DB) TouchAPIToken(id string) error {
+	_, err := db.conn.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, time.Now(), id)
+	return err
+}
+
+// DeleteAPIToken revokes a token.
+func (db *DB) DeleteAPIToken(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete api token: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanAPIToken(scan scanFunc) (*APIToken, error) {
+	var t APIToken
+	var scopesJSON string
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := scan(&t.ID, &t.Name, &t.TokenPrefix, &scopesJSON, &t.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopesJSON), &t.Scopes); err != nil {
+		return nil, fmt.Errorf("unmarshal scopes: %w", err)
+	}
+	if t.Scopes == nil {
+		t.Scopes = []string{}
+	}
+	t.ExpiresAt = TimePtr(expiresAt)
+	t.LastUsedAt = TimePtr(lastUsedAt)
+	return &t, nil
+}
@@ -28,6 +28,7 @@ type ArchiveTask struct {
 	Status       TaskStatus `json:"status"`
 	TaskType     string     `json:"taskType"`
 	Priority     *string    `json:"priority,omitempty"`
+	Estimate     *int       `json:"estimateMinutes,omitempty"`
 	Branch       *string    `json:"branch,omitempty"`
 	WorktreePath *string    `json:"worktreePath,omitempty"`
 	PRURL        *string    `json:"prUrl,omitempty"`
@@ -47,6 +48,7 @@ type ArchiveSession struct {
 	ProviderSessionID *string       `json:"providerSessionId,omitempty"`
 	Status            SessionStatus `json:"status"`
 	LogFile           *string       `json:"logFile,omitempty"`
+	Title             string        `json:"title,omitempty"`
 	CreatedAt         time.Time     `json:"createdAt"`
 	UpdatedAt         time.Time     `json:"updatedAt"`
 }
@@ -131,16 +133,26 @@ func (db *DB) ImportProjectArchive(archive *ProjectArchive) error {
 
 	// Serialize JSON fields
 	symlinkJSON := marshalJSONOrNull(p.SymlinkPaths)
+	cloneJSON := marshalJSONOrNull(p.ClonePaths)
 	secretJSON := marshalJSONOrNull(p.SecretFiles)
 	workflowJSON := marshalJSONOrNull(p.Workflow)
+	agentIdentityJSON := marshalJSONOrNull(p.AgentIdentity)
+	agentInstructionsJSON := marshalJSONOrNull(p.AgentInstructions)
+	retryPolicyJSON := marshalJSONOrNull(p.RetryPolicy)
+	tunnelAuthJSON := marshalJSONOrNull(p.TunnelAuth)
+	provisionerJSON := marshalJSONOrNull(p.Provisioner)
+	worktreePoolJSON := marshalJSONOrNull(p.WorktreePool)
+	protectionJSON := marshalJSONOrNull(p.Protection)
+	devcontainerJSON := marshalJSONOrNull(p.Devcontainer)
+	boardColumnsJSON := marshalJSONOrNull(p.BoardColumns)
 
 	// Insert project
 	_, err = tx.Exec(`
-		INSERT INTO projects (id, name, path, git_origin, default_branch, symlink_paths, secret_files, setup_script, teardown_script, workflow, hidden, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO projects (id, name, path, git_origin, default_branch, symlink_paths, clone_paths, secret_files, setup_script, teardown_script, workflow, agent_identity, agent_instructions, retry_policy, tunnel_auth, provisioner, worktree_pool, protection, devcontainer, board_columns, hidden, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, p.ID, p.Name, p.Path, NullString(p.GitOrigin), p.DefaultBranch,
-		symlinkJSON, secretJSON, NullString(p.SetupScript), NullString(p.TeardownScript),
-		workflowJSON, p.Hidden, p.CreatedAt, p.UpdatedAt)
+		symlinkJSON, cloneJSON, secretJSON, NullString(p.SetupScript), NullString(p.TeardownScript),
+		workflowJSON, agentIdentityJSON, agentInstructionsJSON, retryPolicyJSON, tunnelAuthJSON, provisionerJSON, worktreePoolJSON, protectionJSON, devcontainerJSON, boardColumnsJSON, p.Hidden, p.CreatedAt, p.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("insert project: %w", err)
 	}
@@ -148,10 +160,10 @@ func (db *DB) ImportProjectArchive(archive *ProjectArchive) error {
 	// Insert tasks
 	for _, t := range archive.Tasks {
 		_, err = tx.Exec(`
-			INSERT INTO tasks (id, project_id, title, description, status, task_type, priority, branch, worktree_path, pr_url, pinned, position, created_at, started_at, completed_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO tasks (id, project_id, title, description, status, task_type, priority, estimate_minutes, branch, worktree_path, pr_url, pinned, position, created_at, started_at, completed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`, t.ID, t.ProjectID, t.Title, NullString(t.Description), t.Status, t.TaskType,
-			NullString(t.Priority), NullString(t.Branch), NullString(t.WorktreePath),
+			NullString(t.Priority), nullPositive(t.Estimate), NullString(t.Branch), NullString(t.WorktreePath),
 			NullString(t.PRURL), t.Pinned, t.Position, t.CreatedAt,
 			NullTime(t.StartedAt), NullTime(t.CompletedAt))
 		if err != nil {
@@ -192,11 +204,11 @@ func (db *DB) ImportProjectArchive(archive *ProjectArchive) error {
 	// Insert sessions
 	for _, s := range archive.Sessions {
 		_, err = tx.Exec(`
-			INSERT INTO agent_sessions (id, task_id, provider, session_type, provider_session_id, status, log_file, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO agent_sessions (id, task_id, provider, session_type, provider_session_id, status, log_file, title, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`, s.ID, s.TaskID, s.Provider, s.SessionType,
 			NullString(s.ProviderSessionID), s.Status, NullString(s.LogFile),
-			s.CreatedAt, s.UpdatedAt)
+			s.Title, s.CreatedAt, s.UpdatedAt)
 		if err != nil {
 			return fmt.Errorf("insert session %s: %w", s.ID, err)
 		}
@@ -209,7 +221,7 @@ func (db *DB) ImportProjectArchive(archive *ProjectArchive) error {
 
 func (db *DB) listAllTasksForProject(projectID string) ([]*ArchiveTask, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, project_id, title, description, status, COALESCE(task_type, 'task'), priority,
+		SELECT id, project_id, title, description, status, COALESCE(task_type, 'task'), priority, estimate_minutes,
 		       branch, worktree_path, pr_url, pinned, COALESCE(position, 0), created_at, started_at, completed_at
 		FROM tasks WHERE project_id = ? ORDER BY created_at
 	`, projectID)
@@ -222,13 +234,18 @@ func (db *DB) listAllTasksForProject(projectID string) ([]*ArchiveTask, error) {
 	for rows.Next() {
 		var t ArchiveTask
 		var desc, priority, branch, wt, prURL sql.NullString
+		var estimate sql.NullInt64
 		var startedAt, completedAt sql.NullTime
-		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &desc, &t.Status, &t.TaskType, &priority,
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &desc, &t.Status, &t.TaskType, &priority, &estimate,
 			&branch, &wt, &prURL, &t.Pinned, &t.Position, &t.CreatedAt, &startedAt, &completedAt); err != nil {
 			return nil, err
 		}
 		t.Description = StringPtr(desc)
 		t.Priority = StringPtr(priority)
+		if estimate.Valid {
+			e := int(estimate.Int64)
+			t.Estimate = &e
+		}
 		t.Branch = StringPtr(branch)
 		t.WorktreePath = StringPtr(wt)
 		t.PRURL = StringPtr(prURL)
@@ -289,7 +306,7 @@ func (db *DB) listAllSessionsForTasks(taskIDs []string) ([]*ArchiveSession, erro
 	if len(taskIDs) == 0 {
 		return nil, nil
 	}
-	query := `SELECT id, task_id, provider, session_type, provider_session_id, status, log_file, created_at, updated_at
+	query := `SELECT id, task_id, provider, session_type, provider_session_id, status, log_file, title, created_at, updated_at
 		FROM agent_sessions WHERE task_id IN (?` + repeatPlaceholders(len(taskIDs)-1) + `) ORDER BY created_at`
 	rows, err := db.conn.Query(query, toAnySlice(taskIDs)...)
 	if err != nil {
@@ -301,7 +318,7 @@ func (db *DB) listAllSessionsForTasks(taskIDs []string) ([]*ArchiveSession, erro
 	for rows.Next() {
 		var s ArchiveSession
 		var providerSessID, logFile sql.NullString
-		if err := rows.Scan(&s.ID, &s.TaskID, &s.Provider, &s.SessionType, &providerSessID, &s.Status, &logFile, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.TaskID, &s.Provider, &s.SessionType, &providerSessID, &s.Status, &logFile, &s.Title, &s.CreatedAt, &s.UpdatedAt); err != nil {
 			return nil, err
 		}
 		s.ProviderSessionID = StringPtr(providerSessID)
@@ -341,10 +358,38 @@ func marshalJSONOrNull(v any) sql.NullString {
 		if len(val) == 0 {
 			return sql.NullString{}
 		}
+	case []BoardColumn:
+		if len(val) == 0 {
+			return sql.NullString{}
+		}
 	case *ProjectWorkflow:
 		if val == nil {
 			return sql.NullString{}
 		}
+	case *AgentGitIdentity:
+		if val == nil {
+			return sql.NullString{}
+		}
+	case *AgentInstructions:
+		if val == nil {
+			return sql.NullString{}
+		}
+	case *RetryPolicy:
+		if val == nil {
+			return sql.NullString{}
+		}
+	case *TunnelAuth:
+		if val == nil {
+			return sql.NullString{}
+		}
+	case *ProjectProvisioner:
+		if val == nil {
+			return sql.NullString{}
+		}
+	case *WorktreePoolConfig:
+		if val == nil {
+			return sql.NullString{}
+		}
 	}
 	data, _ := json.Marshal(v)
 	return sql.NullString{String: string(data), Valid: true}
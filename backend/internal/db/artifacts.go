@@ -0,0 +1,152 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Where an artifact was published from.
+const (
+	ArtifactSourceAPI      = "api"
+	ArtifactSourceSession  = "session"
+	ArtifactSourcePipeline = "pipeline"
+)
+
+// Artifact is a build output copied out of a task's worktree so it can be
+// downloaded after the worktree changes or goes away. The contents live on
+// disk; this is its metadata.
+type Artifact struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"taskId"`
+	SessionID   *string   `json:"sessionId,omitempty"`
+	Name        string    `json:"name"`
+	Path        string    `json:"path"` // relative to the worktree it was published from
+	Source      string    `json:"source"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the artifact's retention has run out.
+func (a *Artifact) Expired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
+
+type CreateArtifactInput struct {
+	TaskID      string
+	SessionID   string
+	Name        string
+	Path        string
+	Source      string
+	ContentType string
+	Size        int64
+	ExpiresAt   time.Time
+}
+
+const artifactColumns = `id, task_id, session_id, name, path, source, content_type, size, created_at, expires_at`
+
+// CreateArtifact records a new artifact. The caller stores the contents
+// under the returned ID.
+func (db *DB) CreateArtifact(input CreateArtifactInput) (*Artifact, error) {
+	a := &Artifact{
+		ID:          NewID(),
+		TaskID:      input.TaskID,
+		Name:        input.Name,
+		Path:        input.Path,
+		Source:      input.Source,
+		ContentType: input.ContentType,
+		Size:        input.Size,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   input.ExpiresAt,
+	}
+	if input.SessionID != "" {
+		a.SessionID = &input.SessionID
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO task_artifacts (`+artifactColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, a.TaskID, NullString(a.SessionID), a.Name, a.Path, a.Source, a.ContentType, a.Size, a.CreatedAt, a.ExpiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert artifact: %w", err)
+	}
+	return a, nil
+}
+
+// GetArtifact retrieves an artifact by ID.
+func (db *DB) GetArtifact(id string) (*Artifact, error) {
+	row := db.conn.QueryRow(`SELECT `+artifactColumns+` FROM task_artifacts WHERE id = ?`, id)
+	a, err := scanArtifact(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return a, err
+}
+
+// ListArtifacts returns a task's artifacts, newest first. An empty taskID
+// lists every task's artifacts.
+func (db *DB) ListArtifacts(taskID string) ([]*Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM task_artifacts`
+	var args []any
+	if taskID != "" {
+		query += ` WHERE task_id = ?`
+		args = append(args, taskID)
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	artifacts := make([]*Artifact, 0)
+	for rows.Next() {
+		a, err := scanArtifact(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+// TaskArtifactsSize returns the total size of a task's artifacts.
+func (db *DB) TaskArtifactsSize(taskID string) (int64, error) {
+	var total int64
+	err := db.conn.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM task_artifacts WHERE task_id = ?`, taskID).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("sum artifact sizes: %w", err)
+	}
+	return total, nil
+}
+
+// DeleteArtifact deletes an artifact record.
+func (db *DB) DeleteArtifact(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM task_artifacts WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete artifact: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanArtifact(scan scanFunc) (*Artifact, error) {
+	var a Artifact
+	var sessionID sql.NullString
+	if err := scan(&a.ID, &a.TaskID, &sessionID, &a.Name, &a.Path, &a.Source, &a.ContentType, &a.Size, &a.CreatedAt, &a.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if sessionID.Valid {
+		a.SessionID = &sessionID.String
+	}
+	return &a, nil
+}
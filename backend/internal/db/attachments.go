@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Attachment is a file stored with a task. The contents live on disk; this
+// is its metadata.
+type Attachment struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"taskId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type CreateAttachmentInput struct {
+	TaskID      string
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+const attachmentColumns = `id, task_id, filename, content_type, size, created_at`
+
+// CreateAttachment records a new attachment. The caller stores the contents
+// under the returned ID.
+func (db *DB) CreateAttachment(input CreateAttachmentInput) (*Attachment, error) {
+	a := &Attachment{
+		ID:          NewID(),
+		TaskID:      input.TaskID,
+		Filename:    input.Filename,
+		ContentType: input.ContentType,
+		Size:        input.Size,
+		CreatedAt:   time.Now(),
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO task_attachments (`+attachmentColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		a.ID, a.TaskID, a.Filename, a.ContentType, a.Size, a.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert attachment: %w", err)
+	}
+	return a, nil
+}
+
+// GetAttachment retrieves an attachment by ID.
+func (db *DB) GetAttachment(id string) (*Attachment, error) {
+	row := db.conn.QueryRow(`SELECT `+attachmentColumns+` FROM task_attachments WHERE id = ?`, id)
+	a, err := scanAttachment(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return a, err
+}
+
+// ListAttachments returns a task's attachments, oldest first.
+func (db *DB) ListAttachments(taskID string) ([]*Attachment, error) {
+	rows, err := db.conn.Query(`SELECT `+attachmentColumns+` FROM task_attachments WHERE task_id = ? ORDER BY created_at`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make([]*Attachment, 0)
+	for rows.Next() {
+		a, err := scanAttachment(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// TaskAttachmentsSize returns the total size of a task's attachments.
+func (db *DB) TaskAttachmentsSize(taskID string) (int64, error) {
+	var total int64
+	err := db.conn.QueryRow(`SELECT COALESCE(SUM(size), 0) FROM task_attachments WHERE task_id = ?`, taskID).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("sum attachment sizes: %w", err)
+	}
+	return total, nil
+}
+
+// DeleteAttachment deletes an attachment record.
+func (db *DB) DeleteAttachment(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM task_attachments WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete attachment: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanAttachment(scan scanFunc) (*Attachment, error) {
+	var a Attachment
+	if err := scan(&a.ID, &a.TaskID, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,101 @@
+package db
+
+// BuiltinTaskStatuses are the columns every board has, in order. They are
+// also the categories other columns count as.
+var BuiltinTaskStatuses = []TaskStatus{TaskStatusBacklog, TaskStatusInProgress, TaskStatusInReview, TaskStatusDone}
+
+var builtinColumnNames = map[TaskStatus]string{
+	TaskStatusBacklog:    "Backlog",
+	TaskStatusInProgress: "In Progress",
+	TaskStatusInReview:   "In Review",
+	TaskStatusDone:       "Done",
+}
+
+// BoardColumn is a column a project adds to its board, such as "blocked" or
+// "qa", or a built-in one it renames or limits. Tasks in a column have its
+// ID as their status. Category is the built-in status the column counts as:
+// workflow automation, start and completion times, and views that only know
+// the built-in columns go by it.
+type BoardColumn struct {
+	ID       TaskStatus `json:"id"`
+	Name     string     `json:"name,omitempty"`
+	Category TaskStatus `json:"category,omitempty"` // a built-in column's is itself
+	WIPLimit int        `json:"wipLimit,omitempty"` // most unarchived tasks the column holds; 0 for no limit
+}
+
+// IsBuiltinTaskStatus reports whether status is one of the built-in columns.
+func IsBuiltinTaskStatus(status TaskStatus) bool {
+	_, ok := builtinColumnNames[status]
+	return ok
+}
+
+func categoryRank(status TaskStatus) int {
+	for i, s := range BuiltinTaskStatuses {
+		if s == status {
+			return i
+		}
+	}
+	return len(BuiltinTaskStatuses)
+}
+
+// Board returns the project's columns in board order, with names and
+// categories filled in. Columns appear in the order the project lists them;
+// a built-in column it leaves out goes before the first column of its
+// category or a later one.
+func (p *Project) Board() []BoardColumn {
+	columns := make([]BoardColumn, 0, len(p.BoardColumns)+len(BuiltinTaskStatuses))
+	listed := make(map[TaskStatus]bool)
+	for _, c := range p.BoardColumns {
+		if IsBuiltinTaskStatus(c.ID) {
+			c.Category = c.ID
+		}
+		if c.Name == "" {
+			c.Name = builtinColumnNames[c.ID]
+		}
+		if c.Name == "" {
+			c.Name = string(c.ID)
+		}
+		listed[c.ID] = true
+		columns = append(columns, c)
+	}
+	for _, status := range BuiltinTaskStatuses {
+		if listed[status] {
+			continue
+		}
+		at := len(columns)
+		for i, c := range columns {
+			if categoryRank(c.Category) >= categoryRank(status) {
+				at = i
+				break
+			}
+		}
+		column := BoardColumn{ID: status, Name: builtinColumnNames[status], Category: status}
+		columns = append(columns[:at], append([]BoardColumn{column}, columns[at:]...)...)
+	}
+	return columns
+}
+
+// Column returns the project's column for status, and whether there is one.
+func (p *Project) Column(status TaskStatus) (BoardColumn, bool) {
+	for _, c := range p.Board() {
+		if c.ID == status {
+			return c, true
+		}
+	}
+	return BoardColumn{}, false
+}
+
+// StatusCategory returns the built-in status that status counts as in the
+// project: itself for a built-in one, the column's category for a custom
+// one, and "" for a status the project has no column for.
+func (p *Project) StatusCategory(status TaskStatus) TaskStatus {
+	if IsBuiltinTaskStatus(status) {
+		return status
+	}
+	for _, c := range p.BoardColumns {
+		if c.ID == status {
+			return c.Category
+		}
+	}
+	return ""
+}
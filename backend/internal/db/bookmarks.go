@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Bookmark marks a point in a session, such as "before the refactor", so
+// it can be found again. Seq is the session's latest message when it was
+// marked and GitHead the commit its work directory was on.
+type Bookmark struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	Label     string    `json:"label"`
+	Seq       int64     `json:"seq"`
+	GitHead   *string   `json:"gitHead,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateBookmarkInput struct {
+	SessionID string
+	Label     string
+	Seq       int64
+	GitHead   string
+}
+
+const bookmarkColumns = `id, session_id, label, seq, git_head, created_at`
+
+// CreateBookmark adds a bookmark to a session.
+func (db *DB) CreateBookmark(input CreateBookmarkInput) (*Bookmark, error) {
+	b := &Bookmark{
+		ID:        NewID(),
+		SessionID: input.SessionID,
+		Label:     input.Label,
+		Seq:       input.Seq,
+		CreatedAt: time.Now(),
+	}
+	if input.GitHead != "" {
+		b.GitHead = &input.GitHead
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO session_bookmarks (id, session_id, label, seq, git_head, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		b.ID, b.SessionID, b.Label, b.Seq, b.GitHead, b.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert bookmark: %w", err)
+	}
+	return b, nil
+}
+
+// GetBookmark retrieves a bookmark by ID.
+func (db *DB) GetBookmark(id string) (*Bookmark, error) {
+	row := db.conn.QueryRow(`SELECT `+bookmarkColumns+` FROM session_bookmarks WHERE id = ?`, id)
+	b, err := scanBookmark(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// ListBookmarks returns a session's bookmarks in the order of the points
+// they mark.
+func (db *DB) ListBookmarks(sessionID string) ([]*Bookmark, error) {
+	rows, err := db.conn.Query(
+		`SELECT `+bookmarkColumns+` FROM session_bookmarks WHERE session_id = ? ORDER BY seq, created_at, id`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	bookmarks := make([]*Bookmark, 0)
+	for rows.Next() {
+		b, err := scanBookmark(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// RenameBookmark changes a bookmark's label.
+func (db *DB) RenameBookmark(id, label string) (*Bookmark, error) {
+	result, err := db.conn.Exec(`UPDATE session_bookmarks SET label = ? WHERE id = ?`, label, id)
+	if err != nil {
+		return nil, fmt.Errorf("rename bookmark: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetBookmark(id)
+}
+
+// DeleteBookmark deletes a bookmark.
+func (db *DB) DeleteBookmark(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM session_bookmarks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete bookmark: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanBookmark(scan scanFunc) (*Bookmark, error) {
+	var b Bookmark
+	var gitHead sql.NullString
+	if err := scan(&b.ID, &b.SessionID, &b.Label, &b.Seq, &gitHead, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	b.GitHead = StringPtr(gitHead)
+	return &b, nil
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Comparison statuses. A comparison runs until one implementation is picked
+// or all of them are discarded.
+const (
+	ComparisonStatusRunning   = "running"
+	ComparisonStatusPicked    = "picked"
+	ComparisonStatusDiscarded = "discarded"
+)
+
+// SessionComparison is a group of chat sessions given the same prompt, each
+// in its own throwaway worktree branched from BaseCommit.
+type SessionComparison struct {
+	ID              string            `json:"id"`
+	TaskID          string            `json:"taskId"`
+	Prompt          string            `json:"prompt"`
+	BaseCommit      string            `json:"baseCommit"`
+	Status          string            `json:"status"`
+	PickedSessionID *string           `json:"pickedSessionId,omitempty"`
+	Entries         []ComparisonEntry `json:"entries"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+}
+
+// ComparisonEntry is one session of a comparison and the worktree it works in.
+type ComparisonEntry struct {
+	ComparisonID string `json:"comparisonId"`
+	SessionID    string `json:"sessionId"`
+	Provider     string `json:"provider"`
+	Model        string `json:"model,omitempty"`
+	WorktreePath string `json:"worktreePath"`
+	Branch       string `json:"branch"`
+}
+
+type CreateComparisonInput struct {
+	TaskID     string
+	Prompt     string
+	BaseCommit string
+}
+
+// CreateComparison records a new running comparison without entries.
+func (db *DB) CreateComparison(input CreateComparisonInput) (*SessionComparison, error) {
+	now := time.Now()
+	c := &SessionComparison{
+		ID:         NewID(),
+		TaskID:     input.TaskID,
+		Prompt:     input.Prompt,
+		BaseCommit: input.BaseCommit,
+		Status:     ComparisonStatusRunning,
+		Entries:    []ComparisonEntry{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO session_comparisons (id, task_id, prompt, base_commit, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.TaskID, c.Prompt, c.BaseCommit, c.Status, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("insert comparison: %w", err)
+	}
+	return c, nil
+}
+
+// AddComparisonEntry adds a session to a comparison, after its existing ones.
+func (db *DB) AddComparisonEntry(entry ComparisonEntry) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO session_comparison_entries (comparison_id, session_id, provider, model, worktree_path, branch, position)
+		VALUES (?, ?, ?, ?, ?, ?, (SELECT COUNT(*) FROM session_comparison_entries WHERE comparison_id = ?))
+	`, entry.ComparisonID, entry.SessionID, entry.Provider, entry.Model, entry.WorktreePath, entry.Branch, entry.ComparisonID)
+	if err != nil {
+		return fmt.Errorf("insert comparison entry: %w", err)
+	}
+	return nil
+}
+
+const comparisonColumns = `id, task_id, prompt, base_commit, status, picked_session_id, created_at, updated_at`
+
+// GetComparison retrieves a comparison with its entries.
+func (db *DB) GetComparison(id string) (*SessionComparison, error) {
+	row := db.conn.QueryRow(`SELECT `+comparisonColumns+` FROM session_comparisons WHERE id = ?`, id)
+	c, err := scanComparison(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Entries, err = db.listComparisonEntries(c.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListComparisons returns a task's comparisons with their entries, newest
+// first.
+func (db *DB) ListComparisons(taskID string) ([]*SessionComparison, error) {
+	rows, err := db.conn.Query(`SELECT `+comparisonColumns+` FROM session_comparisons WHERE task_id = ? ORDER BY created_at DESC, id DESC`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("query comparisons: %w", err)
+	}
+	comparisons := make([]*SessionComparison, 0)
+	for rows.Next() {
+		c, err := scanComparison(rows.Scan)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		comparisons = append(comparisons, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, c := range comparisons {
+		if c.Entries, err = db.listComparisonEntries(c.ID); err != nil {
+			return nil, err
+		}
+	}
+	return comparisons, nil
+}
+
+// GetComparisonBySession returns the comparison a session belongs to and the
+// session's entry in it.
+func (db *DB) GetComparisonBySession(sessionID string) (*SessionComparison, *ComparisonEntry, error) {
+	var comparisonID string
+	err := db.conn.QueryRow(`SELECT comparison_id FROM session_comparison_entries WHERE session_id = ?`, sessionID).Scan(&comparisonID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("query comparison entry: %w", err)
+	}
+	c, err := db.GetComparison(comparisonID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range c.Entries {
+		if c.Entries[i].SessionID == sessionID {
+			return c, &c.Entries[i], nil
+		}
+	}
+	return nil, nil, ErrNotFound
+}
+
+// SetComparisonStatus closes a comparison as picked (with the picked session)
+// or discarded.
+func (db *DB) SetComparisonStatus(id, status string, pickedSessionID *string) error {
+	result, err := db.conn.Exec(`
+		UPDATE session_comparisons SET status = ?, picked_session_id = ?, updated_at = ? WHERE id = ?
+	`, status, NullString(pickedSessionID), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("update comparison: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (db *DB) listComparisonEntries(comparisonID string) ([]ComparisonEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT comparison_id, session_id, provider, model, worktree_path, branch
+		FROM session_comparison_entries WHERE comparison_id = ? ORDER BY position
+	`, comparisonID)
+	if err != nil {
+		return nil, fmt.Errorf("query comparison entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]ComparisonEntry, 0)
+	for rows.Next() {
+		var e ComparisonEntry
+		if err := rows.Scan(&e.ComparisonID, &e.SessionID, &e.Provider, &e.Model, &e.WorktreePath, &e.Branch); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func scanComparison(scan scanFunc) (*SessionComparison, error) {
+	var c SessionComparison
+	var picked sql.NullString
+	if err := scan(&c.ID, &c.TaskID, &c.Prompt, &c.BaseCommit, &c.Status, &picked, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.PickedSessionID = StringPtr(picked)
+	return &c, nil
+}
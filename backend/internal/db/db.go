@@ -15,8 +15,13 @@ import (
 // ErrNotFound is returned when a requested entity does not exist.
 var ErrNotFound = errors.New("not found")
 
+// ErrConflict is returned when a write expects a version of a row that has
+// changed since.
+var ErrConflict = errors.New("changed since it was read")
+
 type DB struct {
 	conn *sql.DB
+	enc  *encryptedFile // set when the database is encrypted at rest
 }
 
 // DefaultPath returns the default database path (~/.codeburg/codeburg.db)
@@ -28,7 +33,8 @@ func DefaultPath() string {
 	return filepath.Join(home, ".codeburg", "codeburg.db")
 }
 
-// Open opens or creates the database at the given path
+// Open opens or creates the database at the given path, encrypted at rest
+// when KeyFromEnv returns a key (see encrypt.go).
 func Open(path string) (*DB, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(path)
@@ -36,8 +42,41 @@ func Open(path string) (*DB, error) {
 		return nil, fmt.Errorf("create database directory: %w", err)
 	}
 
-	// Open database with WAL mode for better concurrency
-	conn, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)")
+	key, err := KeyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" || path == ":memory:" {
+		conn, err := openConn(path)
+		if err != nil {
+			return nil, err
+		}
+		if path == ":memory:" {
+			// Each connection to :memory: is a database of its own.
+			conn.SetMaxOpenConns(1)
+		}
+		return &DB{conn: conn}, nil
+	}
+
+	enc, err := openEncrypted(path, key)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := openConn(enc.workPath)
+	if err != nil {
+		enc.discard()
+		return nil, err
+	}
+	db := &DB{conn: conn, enc: enc}
+	enc.stop, enc.done = make(chan struct{}), make(chan struct{})
+	go enc.run(db)
+	return db, nil
+}
+
+func openConn(path string) (*sql.DB, error) {
+	// Open database with WAL mode for better concurrency, waiting out other
+	// writers instead of failing with SQLITE_BUSY
+	conn, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
@@ -47,15 +86,57 @@ func Open(path string) (*DB, error) {
 		conn.Close()
 		return nil, fmt.Errorf("ping database: %w", err)
 	}
-
-	return &DB{conn: conn}, nil
+	return conn, nil
 }
 
-// Close closes the database connection
+// Close closes the database connection, first writing out the encrypted
+// file when there is one.
 func (db *DB) Close() error {
+	if db.enc != nil {
+		return db.enc.close(db)
+	}
 	return db.conn.Close()
 }
 
+// Vacuum rebuilds the database file to give back the space of deleted rows,
+// then truncates the write-ahead log.
+func (db *DB) Vacuum() error {
+	if _, err := db.conn.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := db.conn.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// SizeBytes returns the size of the database, free pages included.
+func (db *DB) SizeBytes() (int64, error) {
+	var size int64
+	if err := db.conn.QueryRow(`SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`).Scan(&size); err != nil {
+		return 0, fmt.Errorf("database size: %w", err)
+	}
+	return size, nil
+}
+
+// rowQuerier is a *sql.DB or *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// missingOrChanged tells why a conditional update of the row id of table
+// matched none: it is gone, or it has changed.
+func missingOrChanged(q rowQuerier, table, id string) error {
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
 // NewID generates a new ULID
 func NewID() string {
 	return ulid.Make().String()
@@ -1,7 +1,14 @@
 package db
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 )
 
 // openTestDB creates an in-memory database for testing
@@ -546,6 +553,65 @@ func TestUpdateSession(t *testing.T) {
 	}
 }
 
+func TestSessionTitles(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "p", Path: "/tmp/p"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "T"})
+	login, _ := db.CreateSession(CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude"})
+	other, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "codex"})
+
+	if ok, err := db.ReplaceSessionTitle(login.ID, "", "Fix the login redirect"); err != nil || !ok {
+		t.Fatalf("title session: %v, %v", ok, err)
+	}
+	// A generated title doesn't replace one set since.
+	if ok, _ := db.ReplaceSessionTitle(login.ID, "", "Something else"); ok {
+		t.Error("expected the title to be kept")
+	}
+	title := "100% coverage_push"
+	if _, err := db.UpdateSession(other.ID, UpdateSessionInput{Title: &title}); err != nil {
+		t.Fatalf("rename session: %v", err)
+	}
+
+	tests := []struct {
+		filter SessionFilter
+		want   []string
+	}{
+		{SessionFilter{Query: "LOGIN"}, []string{login.ID}},
+		{SessionFilter{Query: "100%"}, []string{other.ID}},
+		{SessionFilter{Query: "x_t"}, nil},
+		{SessionFilter{Query: other.ID}, []string{other.ID}},
+		{SessionFilter{ProjectID: project.ID, ProjectOnly: true, Query: "login"}, nil},
+		{SessionFilter{TaskID: task.ID}, []string{login.ID}},
+	}
+	for _, tt := range tests {
+		sessions, _, err := db.ListSessionsPage(tt.filter, Page{})
+		if err != nil {
+			t.Fatalf("list %+v: %v", tt.filter, err)
+		}
+		var got []string
+		for _, s := range sessions {
+			got = append(got, s.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	counts, err := db.CountSessionsByProvider(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil || counts["claude"] != 1 || counts["codex"] != 1 {
+		t.Errorf("counts = %v, %v", counts, err)
+	}
+	if counts, _ := db.CountSessionsByProvider(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)); len(counts) != 0 {
+		t.Errorf("expected no sessions in the future, got %v", counts)
+	}
+
+	fetched, _ := db.GetSession(login.ID)
+	if fetched.Title != "Fix the login redirect" {
+		t.Errorf("title = %q", fetched.Title)
+	}
+}
+
 func TestListActiveSessions(t *testing.T) {
 	db := openTestDB(t)
 
@@ -659,6 +725,49 @@ func TestAgentMessages_CreateListAndUpdate(t *testing.T) {
 	}
 }
 
+func TestAgentMessages_Expired(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "p", Path: "/tmp/p"})
+	old, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	busy, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	quiet, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	for seq := int64(1); seq <= 2; seq++ {
+		db.CreateAgentMessage(CreateAgentMessageInput{SessionID: old.ID, Seq: seq, Kind: "agent-text", PayloadJSON: "{}"})
+	}
+	for seq := int64(1); seq <= 5; seq++ {
+		db.CreateAgentMessage(CreateAgentMessageInput{SessionID: busy.ID, Seq: seq, Kind: "agent-text", PayloadJSON: "{}"})
+	}
+	db.CreateAgentMessage(CreateAgentMessageInput{SessionID: quiet.ID, Seq: 1, Kind: "agent-text", PayloadJSON: "{}"})
+	db.conn.Exec(`UPDATE agent_messages SET created_at = ? WHERE session_id = ?`, time.Now().Add(-48*time.Hour), old.ID)
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	sessions, err := db.SessionsWithExpiredMessages(cutoff, 3)
+	if err != nil || len(sessions) != 2 || !slices.Contains(sessions, old.ID) || !slices.Contains(sessions, busy.ID) {
+		t.Fatalf("unexpected sessions: %v (%v)", sessions, err)
+	}
+	expired, err := db.ExpiredAgentMessages(busy.ID, cutoff, 3)
+	if err != nil || len(expired) != 2 || expired[0].Seq != 1 || expired[1].Seq != 2 {
+		t.Fatalf("expected the two oldest messages, got %+v (%v)", expired, err)
+	}
+	if deleted, err := db.DeleteAgentMessages([]string{expired[0].ID, expired[1].ID}); err != nil || deleted != 2 {
+		t.Fatalf("delete: %d (%v)", deleted, err)
+	}
+	if expired, _ := db.ExpiredAgentMessages(old.ID, cutoff, 0); len(expired) != 2 {
+		t.Errorf("expected the old session's messages expired by age, got %d", len(expired))
+	}
+	if sessions, _ := db.SessionsWithExpiredMessages(time.Time{}, 0); len(sessions) != 0 {
+		t.Errorf("expected no sessions without a policy, got %v", sessions)
+	}
+
+	if err := db.Vacuum(); err != nil {
+		t.Fatalf("vacuum: %v", err)
+	}
+	if size, err := db.SizeBytes(); err != nil || size <= 0 {
+		t.Errorf("expected a database size, got %d (%v)", size, err)
+	}
+}
+
 func TestAgentMessages_CascadeDeleteOnSessionDelete(t *testing.T) {
 	db := openTestDB(t)
 
@@ -823,3 +932,1249 @@ func TestPreference_Delete(t *testing.T) {
 		t.Errorf("expected ErrNotFound on second delete, got %v", err)
 	}
 }
+
+func TestPreference_SetManyAndList(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.SetPreference("default", "b", `1`); err != nil {
+		t.Fatalf("set preference: %v", err)
+	}
+	if err := db.SetPreferences("default", map[string]string{"a": `"x"`, "b": `2`}); err != nil {
+		t.Fatalf("set preferences: %v", err)
+	}
+	if _, err := db.SetPreference("other", "c", `3`); err != nil {
+		t.Fatalf("set preference: %v", err)
+	}
+
+	prefs, err := db.ListPreferences("default")
+	if err != nil {
+		t.Fatalf("list preferences: %v", err)
+	}
+	if len(prefs) != 2 || prefs[0].Key != "a" || prefs[1].Key != "b" || prefs[1].Value != `2` {
+		t.Errorf("unexpected preferences: %+v", prefs)
+	}
+}
+
+// --- Saved View Tests ---
+
+func TestSavedView_DefaultIsExclusive(t *testing.T) {
+	db := openTestDB(t)
+
+	first, err := db.CreateSavedView(DefaultUserID, CreateSavedViewInput{Name: "urgent", IsDefault: true})
+	if err != nil {
+		t.Fatalf("create view: %v", err)
+	}
+	second, err := db.CreateSavedView(DefaultUserID, CreateSavedViewInput{
+		Name:      "review",
+		Filters:   ViewFilters{Statuses: []TaskStatus{TaskStatusInReview}, Sort: TaskSortPriority},
+		IsDefault: true,
+	})
+	if err != nil {
+		t.Fatalf("create view: %v", err)
+	}
+
+	def, err := db.GetDefaultSavedView(DefaultUserID)
+	if err != nil {
+		t.Fatalf("get default view: %v", err)
+	}
+	if def.ID != second.ID {
+		t.Errorf("expected default %s, got %s", second.ID, def.ID)
+	}
+	if len(def.Filters.Statuses) != 1 || def.Filters.Sort != TaskSortPriority {
+		t.Errorf("filters not round-tripped: %+v", def.Filters)
+	}
+
+	isDefault := true
+	if _, err := db.UpdateSavedView(DefaultUserID, first.ID, UpdateSavedViewInput{IsDefault: &isDefault}); err != nil {
+		t.Fatalf("update view: %v", err)
+	}
+	def, _ = db.GetDefaultSavedView(DefaultUserID)
+	if def.ID != first.ID {
+		t.Errorf("expected default %s after update, got %s", first.ID, def.ID)
+	}
+
+	byName, err := db.GetSavedViewByName(DefaultUserID, "URGENT")
+	if err != nil || byName.ID != first.ID {
+		t.Errorf("expected case-insensitive lookup to find %s, got %v %v", first.ID, byName, err)
+	}
+
+	if err := db.DeleteSavedView(DefaultUserID, first.ID); err != nil {
+		t.Fatalf("delete view: %v", err)
+	}
+	if _, err := db.GetDefaultSavedView(DefaultUserID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after deleting default, got %v", err)
+	}
+}
+
+func TestListTasks_FilterByLabelAndPriority(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "p", Path: "/tmp/p"})
+	urgent := "urgent"
+	low := "low"
+	t1, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "Low", Priority: &low})
+	t2, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "Urgent", Priority: &urgent})
+	db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "None"})
+
+	bug, _ := db.CreateLabel(CreateLabelInput{ProjectID: project.ID, Name: "Bug"})
+	db.AssignLabel(t1.ID, bug.ID)
+	db.AssignLabel(t2.ID, bug.ID)
+
+	tasks, err := db.ListTasks(TaskFilter{Labels: []string{"bug"}, Sort: TaskSortPriority})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 labelled tasks, got %d", len(tasks))
+	}
+	if tasks[0].ID != t2.ID {
+		t.Errorf("expected urgent task first, got %q", tasks[0].Title)
+	}
+
+	tasks, err = db.ListTasks(TaskFilter{Priorities: []string{"urgent"}})
+	if err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != t2.ID {
+		t.Errorf("expected only the urgent task, got %d tasks", len(tasks))
+	}
+}
+
+func TestTaskPositions_RespectPriority(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "p", Path: "/tmp/p"})
+	high, low, urgent := "high", "low", "urgent"
+	plain, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "None"})
+	lowTask, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "Low", Priority: &low})
+	estimate := 90
+	highTask, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "High", Priority: &high, Estimate: &estimate})
+
+	order := func() []string {
+		tasks, _ := db.ListTasks(TaskFilter{})
+		titles := make([]string, len(tasks))
+		for i, task := range tasks {
+			titles[i] = task.Title
+		}
+		return titles
+	}
+	if got := order(); !slices.Equal(got, []string{"High", "Low", "None"}) {
+		t.Fatalf("expected new tasks placed by priority, got %v", got)
+	}
+	if task, _ := db.GetTask(highTask.ID); task.Estimate == nil || *task.Estimate != 90 {
+		t.Errorf("expected the estimate stored, got %v", task.Estimate)
+	}
+
+	// Reprioritizing moves the task; a position given wins.
+	db.UpdateTask(plain.ID, UpdateTaskInput{Priority: &urgent})
+	if got := order(); !slices.Equal(got, []string{"None", "High", "Low"}) {
+		t.Fatalf("expected the urgent task first, got %v", got)
+	}
+	last := 2
+	db.UpdateTask(plain.ID, UpdateTaskInput{Position: &last})
+	if got := order(); !slices.Equal(got, []string{"High", "Low", "None"}) {
+		t.Fatalf("expected the manual position kept, got %v", got)
+	}
+
+	// Moving to another column places the task by priority there too.
+	inProgress := TaskStatusInProgress
+	db.UpdateTask(lowTask.ID, UpdateTaskInput{Status: &inProgress})
+	db.UpdateTask(highTask.ID, UpdateTaskInput{Status: &inProgress})
+	tasks, _ := db.ListTasks(TaskFilter{Status: &inProgress})
+	if len(tasks) != 2 || tasks[0].ID != highTask.ID || tasks[0].Position != 0 || tasks[1].Position != 1 {
+		t.Fatalf("expected the high task above the low one, got %+v", tasks)
+	}
+
+	none, cleared := "", 0
+	task, _ := db.UpdateTask(highTask.ID, UpdateTaskInput{Priority: &none, Estimate: &cleared})
+	if task.Priority != nil || task.Estimate != nil || task.Position != 1 {
+		t.Errorf("expected priority and estimate cleared and the task last, got %+v", task)
+	}
+	tasks, _ = db.ListTasks(TaskFilter{Sort: TaskSortEstimate})
+	if len(tasks) != 3 {
+		t.Errorf("expected 3 tasks, got %d", len(tasks))
+	}
+
+	if p, ok := NormalizePriority(" P1 "); !ok || p != "high" {
+		t.Errorf("expected p1 to be high, got %q", p)
+	}
+	if _, ok := NormalizePriority("p4"); ok {
+		t.Error("expected p4 to be refused")
+	}
+}
+
+func TestReorderTask(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "p", Path: "/tmp/p"})
+	other, _ := db.CreateProject(CreateProjectInput{Name: "o", Path: "/tmp/o"})
+	a, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "A"})
+	db.CreateTask(CreateTaskInput{ProjectID: other.ID, Title: "X"})
+	b, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "B"})
+	c, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "C"})
+	// Concurrent moves used to leave duplicates behind.
+	db.conn.Exec(`UPDATE tasks SET position = 1 WHERE id = ?`, c.ID)
+
+	order := func() []string {
+		tasks, _ := db.ListTasks(TaskFilter{})
+		titles := make([]string, len(tasks))
+		for i, task := range tasks {
+			titles[i] = task.Title + strconv.Itoa(task.Position)
+		}
+		return titles
+	}
+
+	// Index 1 counts the project's tasks only: before B.
+	task, err := db.ReorderTask(c.ID, TaskStatusBacklog, 1, false, &c.Version)
+	if err != nil {
+		t.Fatalf("reorder: %v", err)
+	}
+	if got := order(); !slices.Equal(got, []string{"A0", "X1", "C2", "B3"}) {
+		t.Fatalf("unexpected order %v", got)
+	}
+	if task.Version != c.Version+1 {
+		t.Errorf("expected the version bumped, got %d", task.Version)
+	}
+	if moved, _ := db.GetTask(b.ID); moved.Version != b.Version {
+		t.Errorf("expected a renumbered task to keep its version, got %d", moved.Version)
+	}
+	if _, err := db.ReorderTask(c.ID, TaskStatusBacklog, 0, false, &c.Version); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict for a stale version, got %v", err)
+	}
+	if _, err := db.ReorderTask(c.ID, TaskStatusDone, 0, false, nil); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict for a task in another column, got %v", err)
+	}
+	if _, err := db.ReorderTask("missing", TaskStatusBacklog, 0, false, nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Counting every project, and past the end.
+	db.ReorderTask(a.ID, TaskStatusBacklog, 2, true, nil)
+	db.ReorderTask(b.ID, TaskStatusBacklog, 10, false, nil)
+	if got := order(); !slices.Equal(got, []string{"X0", "C1", "A2", "B3"}) {
+		t.Fatalf("unexpected order %v", got)
+	}
+
+	stale := b.Version
+	title := "B2"
+	if _, err := db.UpdateTask(b.ID, UpdateTaskInput{Title: &title, Version: &stale}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a stale update refused, got %v", err)
+	}
+}
+
+func TestTelegramMessageSession(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "p", Path: "/tmp/p"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "T"})
+	session, _ := db.CreateSession(CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude"})
+
+	if err := db.RecordTelegramMessage(42, 7, session.ID); err != nil {
+		t.Fatalf("record telegram message: %v", err)
+	}
+
+	got, err := db.GetTelegramMessageSession(42, 7)
+	if err != nil {
+		t.Fatalf("get telegram message session: %v", err)
+	}
+	if got != session.ID {
+		t.Errorf("expected session %q, got %q", session.ID, got)
+	}
+
+	if _, err := db.GetTelegramMessageSession(42, 8); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for unknown message, got %v", err)
+	}
+}
+
+func TestUpdateProject_AgentIdentity(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "agent", Path: "/tmp/agent"})
+	if project.AgentIdentity != nil {
+		t.Error("expected nil agent identity initially")
+	}
+
+	updated, err := db.UpdateProject(project.ID, UpdateProjectInput{
+		AgentIdentity: &AgentGitIdentity{Enabled: true, Email: "bots@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("update project agent identity: %v", err)
+	}
+	if updated.AgentIdentity == nil || !updated.AgentIdentity.Enabled || updated.AgentIdentity.Email != "bots@example.com" {
+		t.Errorf("unexpected agent identity: %+v", updated.AgentIdentity)
+	}
+}
+
+func TestUpdateProject_AgentInstructions(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "instr", Path: "/tmp/instr"})
+	if project.AgentInstructions != nil {
+		t.Error("expected nil agent instructions initially")
+	}
+
+	autoApprove := false
+	updated, err := db.UpdateProject(project.ID, UpdateProjectInput{
+		AgentInstructions: &AgentInstructions{Prelude: "Run just test before committing.", DefaultModel: "o3", DefaultAutoApprove: &autoApprove},
+	})
+	if err != nil {
+		t.Fatalf("update project agent instructions: %v", err)
+	}
+	got := updated.AgentInstructions
+	if got == nil || got.Prelude != "Run just test before committing." || got.DefaultModel != "o3" || got.DefaultAutoApprove == nil || *got.DefaultAutoApprove {
+		t.Errorf("unexpected agent instructions: %+v", got)
+	}
+}
+
+func TestSessionRetryPolicyAndHistory(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{
+		Name:        "retry",
+		Path:        "/tmp/retry",
+		RetryPolicy: &RetryPolicy{MaxRetries: 2, BackoffSeconds: 5},
+	})
+	if project.RetryPolicy == nil || project.RetryPolicy.MaxRetries != 2 || project.RetryPolicy.BackoffSeconds != 5 {
+		t.Fatalf("unexpected retry policy: %+v", project.RetryPolicy)
+	}
+
+	session, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	if len(session.RetryHistory) != 0 {
+		t.Fatalf("expected empty retry history, got %+v", session.RetryHistory)
+	}
+
+	history := []SessionRetry{
+		{Attempt: 1, Error: "rate limited", CreatedAt: time.Now().UTC()},
+		{Attempt: 2, Error: "overloaded", CreatedAt: time.Now().UTC()},
+	}
+	if err := db.SetSessionRetryHistory(session.ID, history); err != nil {
+		t.Fatalf("set retry history: %v", err)
+	}
+	got, _ := db.GetSession(session.ID)
+	if len(got.RetryHistory) != 2 || got.RetryHistory[1].Error != "overloaded" {
+		t.Errorf("unexpected retry history: %+v", got.RetryHistory)
+	}
+
+	if err := db.SetSessionRetryHistory("missing", history); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "attach", Path: "/tmp/attach"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "with files"})
+
+	first, err := db.CreateAttachment(CreateAttachmentInput{TaskID: task.ID, Filename: "a.png", ContentType: "image/png", Size: 100})
+	if err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+	if _, err := db.CreateAttachment(CreateAttachmentInput{TaskID: task.ID, Filename: "b.txt", ContentType: "text/plain", Size: 20}); err != nil {
+		t.Fatalf("create attachment: %v", err)
+	}
+
+	list, _ := db.ListAttachments(task.ID)
+	if len(list) != 2 || list[0].ID != first.ID {
+		t.Fatalf("expected two attachments oldest first, got %+v", list)
+	}
+	if total, _ := db.TaskAttachmentsSize(task.ID); total != 120 {
+		t.Errorf("expected total size 120, got %d", total)
+	}
+
+	if err := db.DeleteAttachment(first.ID); err != nil {
+		t.Fatalf("delete attachment: %v", err)
+	}
+	if _, err := db.GetAttachment(first.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.DeleteTask(task.ID)
+	if list, _ := db.ListAttachments(task.ID); len(list) != 0 {
+		t.Errorf("expected attachments deleted with task, got %d", len(list))
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "ci", Path: "/tmp/ci"})
+	pipeline, err := db.CreatePipeline(CreatePipelineInput{
+		ProjectID: project.ID,
+		Name:      "check",
+		Steps: []PipelineStep{
+			{Source: "justfile", Recipe: "lint"},
+			{Source: "justfile", Recipe: "test", Args: map[string]string{"pkg": "./..."}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create pipeline: %v", err)
+	}
+	if len(pipeline.Steps) != 2 || pipeline.Steps[1].Args["pkg"] != "./..." || pipeline.ContinueOnError {
+		t.Fatalf("unexpected pipeline: %+v", pipeline)
+	}
+
+	name := "verify"
+	keepGoing := true
+	updated, err := db.UpdatePipeline(pipeline.ID, UpdatePipelineInput{Name: &name, ContinueOnError: &keepGoing})
+	if err != nil {
+		t.Fatalf("update pipeline: %v", err)
+	}
+	if updated.Name != "verify" || !updated.ContinueOnError || len(updated.Steps) != 2 {
+		t.Errorf("unexpected updated pipeline: %+v", updated)
+	}
+	if _, err := db.UpdatePipeline("missing", UpdatePipelineInput{Name: &name}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.DeleteProject(project.ID)
+	if list, _ := db.ListPipelines(project.ID); len(list) != 0 {
+		t.Errorf("expected pipelines deleted with project, got %d", len(list))
+	}
+}
+
+func TestSessionTemplates(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "tmpl", Path: "/tmp/tmpl"})
+	tmpl, err := db.CreateSessionTemplate(CreateSessionTemplateInput{
+		ProjectID: project.ID,
+		Name:      "review",
+		Provider:  "claude",
+		Prompt:    "Review {{task.title}}",
+	})
+	if err != nil {
+		t.Fatalf("create session template: %v", err)
+	}
+	if !tmpl.AutoApprove || tmpl.Model != "" || tmpl.Prompt != "Review {{task.title}}" {
+		t.Fatalf("unexpected template: %+v", tmpl)
+	}
+
+	model := "opus"
+	ask := false
+	updated, err := db.UpdateSessionTemplate(tmpl.ID, UpdateSessionTemplateInput{Model: &model, AutoApprove: &ask})
+	if err != nil {
+		t.Fatalf("update session template: %v", err)
+	}
+	if updated.Model != "opus" || updated.AutoApprove || updated.Name != "review" {
+		t.Errorf("unexpected updated template: %+v", updated)
+	}
+	if _, err := db.UpdateSessionTemplate("missing", UpdateSessionTemplateInput{Model: &model}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.DeleteProject(project.ID)
+	if list, _ := db.ListSessionTemplates(project.ID); len(list) != 0 {
+		t.Errorf("expected templates deleted with project, got %d", len(list))
+	}
+}
+
+func TestTaskProvisions(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{
+		Name:        "prov",
+		Path:        "/tmp/prov",
+		Provisioner: &ProjectProvisioner{Up: "docker compose up -d", Ports: []string{"PG_PORT"}},
+	})
+	if got, _ := db.GetProject(project.ID); got.Provisioner == nil || got.Provisioner.Ports[0] != "PG_PORT" {
+		t.Fatalf("expected provisioner stored, got %+v", got.Provisioner)
+	}
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "with services"})
+
+	if _, err := db.GetTaskProvision(task.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	p := &TaskProvision{TaskID: task.ID, Status: ProvisionStatusReady, Env: map[string]string{"PG_PORT": "54321"}}
+	if err := db.SaveTaskProvision(p); err != nil {
+		t.Fatalf("save provision: %v", err)
+	}
+	p.Status = ProvisionStatusTornDown
+	if err := db.SaveTaskProvision(p); err != nil {
+		t.Fatalf("update provision: %v", err)
+	}
+	got, err := db.GetTaskProvision(task.ID)
+	if err != nil || got.Status != ProvisionStatusTornDown || got.Env["PG_PORT"] != "54321" {
+		t.Fatalf("unexpected provision: %+v (%v)", got, err)
+	}
+
+	db.DeleteTask(task.ID)
+	if _, err := db.GetTaskProvision(task.ID); err != ErrNotFound {
+		t.Errorf("expected provision deleted with task, got %v", err)
+	}
+}
+
+func TestTaskEnv(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "env", Path: "/tmp/env"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "with env"})
+
+	if err := db.SetTaskEnv(task.ID, []TaskEnvVar{{Name: "TOKEN", Value: "s3cret", Secret: true}, {Name: "DEBUG", Value: "1"}}); err != nil {
+		t.Fatalf("set task env: %v", err)
+	}
+	if err := db.SetTaskEnv(task.ID, []TaskEnvVar{{Name: "TOKEN", Value: "s3cret", Secret: true}, {Name: "LOG_LEVEL", Value: "debug"}}); err != nil {
+		t.Fatalf("replace task env: %v", err)
+	}
+	vars, err := db.ListTaskEnv(task.ID)
+	if err != nil || len(vars) != 2 || vars[0].Name != "LOG_LEVEL" || vars[1].Name != "TOKEN" || !vars[1].Secret {
+		t.Fatalf("unexpected task env: %+v (%v)", vars, err)
+	}
+
+	db.DeleteTask(task.ID)
+	if vars, _ := db.ListTaskEnv(task.ID); len(vars) != 0 {
+		t.Errorf("expected task env deleted with task, got %+v", vars)
+	}
+}
+
+func TestPooledWorktrees(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{
+		Name:         "pool",
+		Path:         "/tmp/pool",
+		WorktreePool: &WorktreePoolConfig{Size: 2, MaxDiskMB: 500},
+	})
+	if got, _ := db.GetProject(project.ID); got.WorktreePool == nil || got.WorktreePool.Size != 2 {
+		t.Fatalf("expected worktree pool stored, got %+v", got.WorktreePool)
+	}
+
+	entry := &PooledWorktree{ProjectID: project.ID, Path: "/wt/a", Branch: "codeburg-pool/a", BaseBranch: "main"}
+	if err := db.CreatePooledWorktree(entry); err != nil {
+		t.Fatalf("create pooled worktree: %v", err)
+	}
+	if _, err := db.ClaimPooledWorktree(project.ID, "main"); err != ErrNotFound {
+		t.Fatalf("entries still being created must not be claimed, got %v", err)
+	}
+	if err := db.MarkPooledWorktreeReady(entry.ID, 1234); err != nil {
+		t.Fatalf("mark ready: %v", err)
+	}
+	if _, err := db.ClaimPooledWorktree(project.ID, "develop"); err != ErrNotFound {
+		t.Fatalf("expected no entry for another base branch, got %v", err)
+	}
+
+	claimed, err := db.ClaimPooledWorktree(project.ID, "main")
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if claimed.ID != entry.ID || claimed.SizeBytes != 1234 || claimed.Status != PooledWorktreeReady {
+		t.Errorf("unexpected claimed entry: %+v", claimed)
+	}
+	if list, _ := db.ListPooledWorktrees(project.ID); len(list) != 0 {
+		t.Errorf("claimed entry should leave the pool, got %d", len(list))
+	}
+	if err := db.DeletePooledWorktree(entry.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.CreatePooledWorktree(&PooledWorktree{ProjectID: project.ID, Path: "/wt/b", Branch: "codeburg-pool/b", BaseBranch: "main"})
+	db.DeleteProject(project.ID)
+	if list, _ := db.ListPooledWorktrees(""); len(list) != 0 {
+		t.Errorf("expected pool entries deleted with project, got %d", len(list))
+	}
+}
+
+func TestListTasksPage(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "paged", Path: "/tmp/paged"})
+	var created []string
+	for i := 0; i < 5; i++ {
+		task, err := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "task " + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+		created = append(created, task.ID)
+	}
+
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		tasks, next, err := db.ListTasksPage(TaskFilter{ProjectID: &project.ID}, Page{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("list page: %v", err)
+		}
+		for _, task := range tasks {
+			seen = append(seen, task.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(seen) != len(created) {
+		t.Fatalf("saw %d tasks, want %d", len(seen), len(created))
+	}
+	for i, id := range seen {
+		if want := created[len(created)-1-i]; id != want {
+			t.Errorf("position %d: got %s, want %s (newest first)", i, id, want)
+		}
+	}
+
+	// Deleting the row a cursor follows doesn't break paging.
+	first, cursor, err := db.ListTasksPage(TaskFilter{ProjectID: &project.ID}, Page{Limit: 2})
+	if err != nil || len(first) != 2 {
+		t.Fatalf("first page: %v %d", err, len(first))
+	}
+	if err := db.DeleteTask(first[1].ID); err != nil {
+		t.Fatalf("delete task: %v", err)
+	}
+	rest, _, err := db.ListTasksPage(TaskFilter{ProjectID: &project.ID}, Page{Limit: 10, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("page after deleting the cursor row: %v", err)
+	}
+	if len(rest) != 3 || rest[0].ID != created[2] {
+		t.Errorf("expected the 3 older tasks, got %d starting %v", len(rest), rest)
+	}
+
+	if _, _, err := db.ListTasksPage(TaskFilter{}, Page{Limit: 2, Cursor: "not a cursor!"}); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+	if _, _, err := db.ListTasksPage(TaskFilter{}, Page{Limit: 2, Cursor: EncodeCursor("missing")}); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor for a deleted row, got %v", err)
+	}
+}
+
+func TestListAgentMessagesPage(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "chat", Path: "/tmp/chat"})
+	session, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	for seq := int64(1); seq <= 3; seq++ {
+		if _, err := db.CreateAgentMessage(CreateAgentMessageInput{SessionID: session.ID, Seq: seq, Kind: "text", PayloadJSON: "{}"}); err != nil {
+			t.Fatalf("create message: %v", err)
+		}
+	}
+
+	first, next, err := db.ListAgentMessagesPage(session.ID, Page{Limit: 2})
+	if err != nil || len(first) != 2 || next == "" {
+		t.Fatalf("first page: %d messages, next %q, err %v", len(first), next, err)
+	}
+	rest, next, err := db.ListAgentMessagesPage(session.ID, Page{Limit: 2, Cursor: next})
+	if err != nil || len(rest) != 1 || next != "" {
+		t.Fatalf("second page: %d messages, next %q, err %v", len(rest), next, err)
+	}
+	if first[0].Seq != 1 || first[1].Seq != 2 || rest[0].Seq != 3 {
+		t.Errorf("unexpected order: %d %d %d", first[0].Seq, first[1].Seq, rest[0].Seq)
+	}
+}
+
+func TestArtifacts(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "artifacts", Path: "/tmp/artifacts"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "build"})
+	session, _ := db.CreateSession(CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+
+	expires := time.Now().Add(time.Hour)
+	first, err := db.CreateArtifact(CreateArtifactInput{TaskID: task.ID, Name: "app.zip", Path: "dist/app.zip", Source: ArtifactSourcePipeline, ContentType: "application/zip", Size: 100, ExpiresAt: expires})
+	if err != nil {
+		t.Fatalf("create artifact: %v", err)
+	}
+	second, err := db.CreateArtifact(CreateArtifactInput{TaskID: task.ID, SessionID: session.ID, Name: "log.txt", Path: "log.txt", Source: ArtifactSourceSession, ContentType: "text/plain", Size: 20, ExpiresAt: expires})
+	if err != nil {
+		t.Fatalf("create artifact: %v", err)
+	}
+
+	list, _ := db.ListArtifacts(task.ID)
+	if len(list) != 2 || list[0].ID != second.ID || list[1].ID != first.ID {
+		t.Fatalf("expected two artifacts newest first, got %+v", list)
+	}
+	if list[0].SessionID == nil || *list[0].SessionID != session.ID || list[1].SessionID != nil {
+		t.Errorf("unexpected session IDs: %v, %v", list[0].SessionID, list[1].SessionID)
+	}
+	if !list[0].ExpiresAt.Equal(expires) || list[0].Expired(time.Now()) {
+		t.Errorf("unexpected expiry %v", list[0].ExpiresAt)
+	}
+	if total, _ := db.TaskArtifactsSize(task.ID); total != 120 {
+		t.Errorf("expected total size 120, got %d", total)
+	}
+
+	// Deleting the session keeps its artifacts.
+	db.DeleteSession(session.ID)
+	if got, err := db.GetArtifact(second.ID); err != nil || got.SessionID != nil {
+		t.Fatalf("expected artifact kept without session, got %+v (%v)", got, err)
+	}
+
+	if err := db.DeleteArtifact(first.ID); err != nil {
+		t.Fatalf("delete artifact: %v", err)
+	}
+	if _, err := db.GetArtifact(first.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.DeleteTask(task.ID)
+	if list, _ := db.ListArtifacts(""); len(list) != 0 {
+		t.Errorf("expected artifacts removed with the task, got %d", len(list))
+	}
+}
+
+func TestSessionComparisons(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "compare", Path: "/tmp/compare"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "compare"})
+	claude, _ := db.CreateSession(CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+	codex, _ := db.CreateSession(CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "codex", SessionType: "chat"})
+
+	c, err := db.CreateComparison(CreateComparisonInput{TaskID: task.ID, Prompt: "fix it", BaseCommit: "abc123"})
+	if err != nil {
+		t.Fatalf("create comparison: %v", err)
+	}
+	for _, s := range []*AgentSession{claude, codex} {
+		entry := ComparisonEntry{ComparisonID: c.ID, SessionID: s.ID, Provider: s.Provider, WorktreePath: "/tmp/wt-" + s.Provider, Branch: "cmp-" + s.Provider}
+		if err := db.AddComparisonEntry(entry); err != nil {
+			t.Fatalf("add entry: %v", err)
+		}
+	}
+
+	got, err := db.GetComparison(c.ID)
+	if err != nil {
+		t.Fatalf("get comparison: %v", err)
+	}
+	if got.Status != ComparisonStatusRunning || len(got.Entries) != 2 || got.Entries[0].Provider != "claude" || got.Entries[1].Provider != "codex" {
+		t.Fatalf("unexpected comparison: %+v", got)
+	}
+
+	byCodex, entry, err := db.GetComparisonBySession(codex.ID)
+	if err != nil || byCodex.ID != c.ID || entry.WorktreePath != "/tmp/wt-codex" {
+		t.Fatalf("unexpected lookup by session: %+v %+v (%v)", byCodex, entry, err)
+	}
+	if _, _, err := db.GetComparisonBySession("nope"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := db.SetComparisonStatus(c.ID, ComparisonStatusPicked, &codex.ID); err != nil {
+		t.Fatalf("set status: %v", err)
+	}
+	list, _ := db.ListComparisons(task.ID)
+	if len(list) != 1 || list[0].Status != ComparisonStatusPicked || list[0].PickedSessionID == nil || *list[0].PickedSessionID != codex.ID || len(list[0].Entries) != 2 {
+		t.Fatalf("unexpected comparisons: %+v", list)
+	}
+
+	// Deleting a session drops its entry.
+	db.DeleteSession(claude.ID)
+	if got, _ := db.GetComparison(c.ID); len(got.Entries) != 1 {
+		t.Errorf("expected one entry left, got %d", len(got.Entries))
+	}
+
+	db.DeleteTask(task.ID)
+	if _, err := db.GetComparison(c.ID); err != ErrNotFound {
+		t.Errorf("expected comparison removed with the task, got %v", err)
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "snippets", Path: "/tmp/snippets"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "inbox"})
+	session, _ := db.CreateSession(CreateSessionInput{TaskID: task.ID, ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+
+	first, err := db.CreateSnippet(task.ID, "panic: nil map")
+	if err != nil {
+		t.Fatalf("create snippet: %v", err)
+	}
+	second, _ := db.CreateSnippet(task.ID, "https://example.com/issue/1")
+
+	list, _ := db.ListSnippets(task.ID, true)
+	if len(list) != 2 || list[0].ID != second.ID || list[1].ID != first.ID {
+		t.Fatalf("expected two snippets newest first, got %+v", list)
+	}
+
+	if err := db.ConsumeSnippets([]string{first.ID}, session.ID); err != nil {
+		t.Fatalf("consume: %v", err)
+	}
+	if list, _ := db.ListSnippets(task.ID, true); len(list) != 1 || list[0].ID != second.ID {
+		t.Fatalf("expected only the unconsumed snippet, got %+v", list)
+	}
+	got, _ := db.GetSnippet(first.ID)
+	if got.ConsumedAt == nil || got.ConsumedSessionID == nil || *got.ConsumedSessionID != session.ID {
+		t.Fatalf("expected the snippet consumed by the session, got %+v", got)
+	}
+
+	// Deleting the session keeps the snippet consumed.
+	db.DeleteSession(session.ID)
+	if got, _ := db.GetSnippet(first.ID); got.ConsumedSessionID != nil || got.ConsumedAt == nil {
+		t.Errorf("expected the snippet to stay consumed without a session, got %+v", got)
+	}
+
+	if err := db.DeleteSnippet(second.ID); err != nil {
+		t.Fatalf("delete snippet: %v", err)
+	}
+	if err := db.DeleteSnippet(second.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.DeleteTask(task.ID)
+	if _, err := db.GetSnippet(first.ID); err != ErrNotFound {
+		t.Errorf("expected snippets removed with the task, got %v", err)
+	}
+}
+
+func TestProjectArchived(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "archived", Path: "/tmp/archived"})
+	if project.ArchivedAt != nil {
+		t.Fatal("expected a new project not to be archived")
+	}
+
+	archived, err := db.SetProjectArchived(project.ID, true)
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if archived.ArchivedAt == nil {
+		t.Fatal("expected archivedAt to be set")
+	}
+	again, _ := db.SetProjectArchived(project.ID, true)
+	if again.ArchivedAt == nil || !again.ArchivedAt.Equal(*archived.ArchivedAt) {
+		t.Errorf("expected archiving again to keep the time, got %v want %v", again.ArchivedAt, archived.ArchivedAt)
+	}
+
+	restored, err := db.SetProjectArchived(project.ID, false)
+	if err != nil {
+		t.Fatalf("unarchive: %v", err)
+	}
+	if restored.ArchivedAt != nil {
+		t.Errorf("expected archivedAt cleared, got %v", restored.ArchivedAt)
+	}
+
+	if _, err := db.SetProjectArchived("nope", true); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	db := openTestDB(t)
+
+	project, _ := db.CreateProject(CreateProjectInput{Name: "bookmarks", Path: "/tmp/bookmarks"})
+	session, _ := db.CreateSession(CreateSessionInput{ProjectID: project.ID, Provider: "claude", SessionType: "chat"})
+
+	later, err := db.CreateBookmark(CreateBookmarkInput{SessionID: session.ID, Label: "tests pass", Seq: 9, GitHead: "abc123"})
+	if err != nil {
+		t.Fatalf("create bookmark: %v", err)
+	}
+	earlier, _ := db.CreateBookmark(CreateBookmarkInput{SessionID: session.ID, Label: "start", Seq: 2})
+
+	list, _ := db.ListBookmarks(session.ID)
+	if len(list) != 2 || list[0].ID != earlier.ID || list[1].ID != later.ID {
+		t.Fatalf("expected bookmarks ordered by seq, got %+v", list)
+	}
+	if list[0].GitHead != nil || list[1].GitHead == nil || *list[1].GitHead != "abc123" {
+		t.Errorf("unexpected git heads: %v, %v", list[0].GitHead, list[1].GitHead)
+	}
+
+	renamed, err := db.RenameBookmark(earlier.ID, "the start")
+	if err != nil || renamed.Label != "the start" {
+		t.Fatalf("rename: %+v, %v", renamed, err)
+	}
+	if _, err := db.RenameBookmark("nope", "x"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	db.DeleteSession(session.ID)
+	if _, err := db.GetBookmark(later.ID); err != ErrNotFound {
+		t.Errorf("expected bookmarks removed with the session, got %v", err)
+	}
+}
+
+func TestLoginLinks(t *testing.T) {
+	db := openTestDB(t)
+
+	link, err := db.CreateLoginLink("hash-1", "/tasks/abc", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("create login link: %v", err)
+	}
+	db.CreateLoginLink("hash-expired", "/", time.Now().Add(-time.Second))
+
+	used, err := db.UseLoginLink("hash-1")
+	if err != nil {
+		t.Fatalf("use login link: %v", err)
+	}
+	if used.ID != link.ID || used.Path != "/tasks/abc" || used.UsedAt == nil {
+		t.Errorf("unexpected link %+v", used)
+	}
+	if _, err := db.UseLoginLink("hash-1"); err != ErrNotFound {
+		t.Errorf("expected a used link to be refused, got %v", err)
+	}
+	if _, err := db.UseLoginLink("hash-expired"); err != ErrNotFound {
+		t.Errorf("expected an expired link to be refused, got %v", err)
+	}
+	if _, err := db.UseLoginLink("nope"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	db := openTestDB(t)
+	project, _ := db.CreateProject(CreateProjectInput{Name: "activity", Path: "/tmp/activity"})
+	other, _ := db.CreateProject(CreateProjectInput{Name: "other", Path: "/tmp/other"})
+
+	at := time.Date(2026, 10, 1, 14, 20, 0, 0, time.UTC)
+	db.AddActivity(project.ID, "claude", at, ActivityCounts{Messages: 2, ToolCalls: 5})
+	db.AddActivity(project.ID, "claude", at.Add(30*time.Minute), ActivityCounts{Messages: 1})
+	db.AddActivity(project.ID, "codex", at, ActivityCounts{Messages: 1})
+	db.AddActivity(other.ID, "claude", at.Add(-48*time.Hour), ActivityCounts{ToolCalls: 1})
+
+	added, err := db.RecordActivityCommits(project.ID, "claude", []ActivityCommit{
+		{Hash: "aaa", At: at.Add(10 * time.Minute)},
+		{Hash: "bbb", At: at.Add(2 * time.Hour)},
+	})
+	if err != nil || added != 2 {
+		t.Fatalf("record commits: %d, %v", added, err)
+	}
+	if added, _ := db.RecordActivityCommits(project.ID, "claude", []ActivityCommit{{Hash: "aaa", At: at}}); added != 0 {
+		t.Errorf("expected a counted commit to be skipped, got %d", added)
+	}
+
+	hours, err := db.ListActivity(ActivityFilter{ProjectID: project.ID, Provider: "claude"})
+	if err != nil {
+		t.Fatalf("list activity: %v", err)
+	}
+	if len(hours) != 2 {
+		t.Fatalf("expected 2 hours, got %+v", hours)
+	}
+	want := ActivityCounts{Messages: 3, ToolCalls: 5, Commits: 1}
+	if !hours[0].Hour.Equal(at.Truncate(time.Hour)) || hours[0].ActivityCounts != want {
+		t.Errorf("unexpected first hour %+v", hours[0])
+	}
+	if hours[1].Commits != 1 || hours[1].Hour.Hour() != 16 {
+		t.Errorf("unexpected second hour %+v", hours[1])
+	}
+
+	recent, _ := db.ListActivity(ActivityFilter{Since: at.Add(-time.Hour), Until: at.Add(time.Hour)})
+	if len(recent) != 2 {
+		t.Errorf("expected claude and codex in the window, got %+v", recent)
+	}
+
+	db.DeleteProject(other.ID)
+	if all, _ := db.ListActivity(ActivityFilter{}); len(all) != 3 {
+		t.Errorf("expected the deleted project's activity removed, got %+v", all)
+	}
+}
+
+func TestUsageAndBudgets(t *testing.T) {
+	db := openTestDB(t)
+	project, _ := db.CreateProject(CreateProjectInput{Name: "usage", Path: "/tmp/usage"})
+	other, _ := db.CreateProject(CreateProjectInput{Name: "other", Path: "/tmp/other"})
+
+	month := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for _, r := range []UsageRecord{
+		{SessionID: "s1", ProjectID: project.ID, Provider: "claude", InputTokens: 1000, OutputTokens: 200, CostUSD: 0.5, CreatedAt: month.Add(time.Hour)},
+		{SessionID: "s1", ProjectID: project.ID, Provider: "claude", InputTokens: 500, OutputTokens: 100, CostUSD: 0.25, CreatedAt: month.Add(48 * time.Hour)},
+		{SessionID: "s2", ProjectID: project.ID, Provider: "codex", InputTokens: 300, OutputTokens: 30, CreatedAt: month.Add(time.Hour)},
+		{SessionID: "s3", ProjectID: other.ID, Provider: "claude", InputTokens: 9000, CreatedAt: month.Add(-time.Hour)},
+	} {
+		if err := db.RecordUsage(r); err != nil {
+			t.Fatalf("record usage: %v", err)
+		}
+	}
+
+	totals, err := db.SumUsage(UsageFilter{Provider: "claude", Since: month, Until: month.AddDate(0, 1, 0)})
+	if err != nil {
+		t.Fatalf("sum usage: %v", err)
+	}
+	if totals.InputTokens != 1500 || totals.OutputTokens != 300 || totals.Tokens() != 1800 || totals.CostUSD != 0.75 {
+		t.Errorf("unexpected claude totals %+v", totals)
+	}
+	if totals, _ := db.SumUsage(UsageFilter{ProjectID: project.ID}); totals.Tokens() != 2130 {
+		t.Errorf("unexpected project totals %+v", totals)
+	}
+
+	if fresh, err := db.MarkBudgetAlert("claude/*", "2026-10", 50); err != nil || !fresh {
+		t.Fatalf("expected a new alert, got %v %v", fresh, err)
+	}
+	if fresh, _ := db.MarkBudgetAlert("claude/*", "2026-10", 50); fresh {
+		t.Error("expected an alert to be marked once")
+	}
+	if fresh, _ := db.MarkBudgetAlert("claude/*", "2026-11", 50); !fresh {
+		t.Error("expected alerts to start over each month")
+	}
+
+	if _, err := db.GetBudgetOverride("claude/*", "2026-10"); err != ErrNotFound {
+		t.Errorf("expected no override, got %v", err)
+	}
+	until := month.Add(72 * time.Hour)
+	db.SetBudgetOverride("claude/*", "2026-10", month)
+	if err := db.SetBudgetOverride("claude/*", "2026-10", until); err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+	if got, err := db.GetBudgetOverride("claude/*", "2026-10"); err != nil || !got.Equal(until) {
+		t.Errorf("expected the override until %v, got %v %v", until, got, err)
+	}
+	if err := db.DeleteBudgetOverride("claude/*", "2026-10"); err != nil {
+		t.Fatalf("delete override: %v", err)
+	}
+	if err := db.DeleteBudgetOverride("claude/*", "2026-10"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestTimeEntries(t *testing.T) {
+	db := openTestDB(t)
+	project, _ := db.CreateProject(CreateProjectInput{Name: "time", Path: "/tmp/time"})
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "Track me"})
+
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	if err := db.StartSessionTime("sess-1", project.ID, task.ID, "claude", start); err != nil {
+		t.Fatalf("StartSessionTime: %v", err)
+	}
+	// Already open: no second entry.
+	if err := db.StartSessionTime("sess-1", project.ID, task.ID, "claude", start.Add(time.Minute)); err != nil {
+		t.Fatalf("StartSessionTime: %v", err)
+	}
+	entries, err := db.ListTimeEntries(TimeEntryFilter{TaskID: task.ID})
+	if err != nil || len(entries) != 1 || entries[0].EndedAt != nil || entries[0].Manual() {
+		t.Fatalf("expected one open session entry, got %+v, %v", entries, err)
+	}
+	if d := entries[0].DurationWithin(time.Time{}, time.Time{}, start.Add(10*time.Minute)); d != 10*time.Minute {
+		t.Errorf("expected an open entry to run until now, got %v", d)
+	}
+
+	if err := db.StopSessionTime("sess-1", start.Add(30*time.Minute)); err != nil {
+		t.Fatalf("StopSessionTime: %v", err)
+	}
+	manual, err := db.CreateTimeEntry(CreateTimeEntryInput{
+		ProjectID: project.ID,
+		TaskID:    task.ID,
+		StartedAt: start.Add(-2 * time.Hour),
+		Duration:  90 * time.Minute,
+		Note:      "design review",
+	})
+	if err != nil || !manual.Manual() || manual.Note != "design review" {
+		t.Fatalf("CreateTimeEntry: %+v, %v", manual, err)
+	}
+
+	entries, _ = db.ListTimeEntries(TimeEntryFilter{ProjectID: project.ID})
+	if len(entries) != 2 || entries[0].ID != manual.ID || entries[1].EndedAt == nil {
+		t.Fatalf("expected the manual entry then the closed one, got %+v", entries)
+	}
+	if d := entries[1].DurationWithin(time.Time{}, time.Time{}, time.Now()); d != 30*time.Minute {
+		t.Errorf("expected 30m, got %v", d)
+	}
+	// The manual entry ends at 8:30, so a window from 8:45 leaves it out and
+	// clips nothing else.
+	since := start.Add(-15 * time.Minute)
+	entries, _ = db.ListTimeEntries(TimeEntryFilter{ProjectID: project.ID, Since: since, Until: start.Add(15 * time.Minute)})
+	if len(entries) != 1 || entries[0].DurationWithin(since, start.Add(15*time.Minute), time.Now()) != 15*time.Minute {
+		t.Fatalf("expected the session entry clipped to 15m, got %+v", entries)
+	}
+
+	if err := db.DeleteTimeEntry(manual.ID); err != nil {
+		t.Fatalf("DeleteTimeEntry: %v", err)
+	}
+	if _, err := db.GetTimeEntry(manual.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestBoardColumns(t *testing.T) {
+	db := openTestDB(t)
+	project, _ := db.CreateProject(CreateProjectInput{Name: "board", Path: "/tmp/board"})
+
+	columns := []BoardColumn{
+		{ID: "blocked", Name: "Blocked", Category: TaskStatusInProgress, WIPLimit: 2},
+		{ID: TaskStatusInProgress, Name: "Doing"},
+		{ID: "qa", Category: TaskStatusInReview},
+	}
+	project, err := db.UpdateProject(project.ID, UpdateProjectInput{BoardColumns: columns})
+	if err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	var order []TaskStatus
+	for _, c := range project.Board() {
+		order = append(order, c.ID)
+	}
+	want := []TaskStatus{TaskStatusBacklog, "blocked", TaskStatusInProgress, TaskStatusInReview, "qa", TaskStatusDone}
+	if !slices.Equal(order, want) {
+		t.Fatalf("expected board %v, got %v", want, order)
+	}
+	if c, ok := project.Column(TaskStatusInProgress); !ok || c.Name != "Doing" || c.Category != TaskStatusInProgress {
+		t.Errorf("unexpected in_progress column %+v", c)
+	}
+	if project.StatusCategory("qa") != TaskStatusInReview || project.StatusCategory("nope") != "" {
+		t.Errorf("unexpected categories")
+	}
+
+	task, _ := db.CreateTask(CreateTaskInput{ProjectID: project.ID, Title: "Stuck"})
+	blocked := TaskStatus("blocked")
+	task, err = db.UpdateTask(task.ID, UpdateTaskInput{Status: &blocked})
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if task.Status != blocked || task.Category != TaskStatusInProgress || task.StartedAt == nil {
+		t.Fatalf("expected a started task in blocked, got %+v", task)
+	}
+	tasks, _ := db.ListTasks(TaskFilter{Categories: []TaskStatus{TaskStatusInProgress}})
+	if len(tasks) != 1 || tasks[0].ID != task.ID || tasks[0].Category != TaskStatusInProgress {
+		t.Fatalf("expected the blocked task by category, got %+v", tasks)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	db := openTestDB(t)
+
+	job, err := db.CreateJob(CreateJobInput{Kind: "clone", Payload: []byte(`{"url":"x"}`), MaxAttempts: 3})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if job.Status != JobPending || job.MaxAttempts != 3 || string(job.Payload) != `{"url":"x"}` {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if active, _ := db.HasActiveJob("clone"); !active {
+		t.Error("expected an active clone job")
+	}
+
+	due, _ := db.ListDueJobs(time.Now(), 10)
+	if len(due) != 1 {
+		t.Fatalf("expected 1 due job, got %d", len(due))
+	}
+	started, err := db.StartJob(job.ID)
+	if err != nil || started.Status != JobRunning || started.Attempts != 1 {
+		t.Fatalf("unexpected started job: %+v, %v", started, err)
+	}
+	if _, err := db.StartJob(job.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound starting a running job, got %v", err)
+	}
+
+	// A retry goes back in the queue, due later.
+	retryAt := time.Now().Add(time.Hour)
+	retried, err := db.FinishJob(job.ID, nil, errors.New("boom"), &retryAt)
+	if err != nil || retried.Status != JobPending || retried.LastError != "boom" {
+		t.Fatalf("unexpected retried job: %+v, %v", retried, err)
+	}
+	if due, _ := db.ListDueJobs(time.Now(), 10); len(due) != 0 {
+		t.Errorf("expected no due jobs before the retry, got %d", len(due))
+	}
+
+	// Jobs left running by a crash are queued again.
+	db.StartJob(job.ID)
+	if n, _ := db.RequeueRunningJobs(); n != 1 {
+		t.Errorf("expected 1 requeued job, got %d", n)
+	}
+	db.StartJob(job.ID)
+	failed, _ := db.FinishJob(job.ID, nil, errors.New("boom"), nil)
+	if failed.Status != JobFailed || failed.FinishedAt == nil {
+		t.Fatalf("unexpected failed job: %+v", failed)
+	}
+	if _, err := db.RetryJob("missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	again, err := db.RetryJob(job.ID)
+	if err != nil || again.Status != JobPending || again.Attempts != 0 {
+		t.Fatalf("unexpected retried job: %+v, %v", again, err)
+	}
+	if _, err := db.RetryJob(job.ID); err != ErrJobNotFailed {
+		t.Errorf("expected ErrJobNotFailed, got %v", err)
+	}
+
+	db.CreateJob(CreateJobInput{Kind: "digest"})
+	if list, _ := db.ListJobs(JobFilter{Kind: "digest"}); len(list) != 1 || list[0].MaxAttempts != 1 {
+		t.Errorf("expected 1 digest job, got %+v", list)
+	}
+	if list, _ := db.ListJobs(JobFilter{Status: JobPending}); len(list) != 2 {
+		t.Errorf("expected 2 pending jobs, got %d", len(list))
+	}
+	db.StartJob(job.ID)
+	if cancelled, err := db.CancelJob(job.ID); err != nil || cancelled.Status != JobCancelled {
+		t.Fatalf("unexpected cancelled job: %+v, %v", cancelled, err)
+	}
+	if finished, _ := db.FinishJob(job.ID, nil, nil, nil); finished.Status != JobCancelled {
+		t.Errorf("expected a cancelled job to stay cancelled, got %s", finished.Status)
+	}
+	if _, err := db.CancelJob(job.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound cancelling a finished job, got %v", err)
+	}
+	db.RetryJob(job.ID)
+	db.StartJob(job.ID)
+	db.FinishJob(job.ID, []byte(`{"ok":true}`), nil, nil)
+	if n, _ := db.PruneJobs(time.Now().Add(time.Minute)); n != 1 {
+		t.Errorf("expected 1 pruned job, got %d", n)
+	}
+}
+
+func TestEncryptedDB(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "codeburg.db")
+
+	// A plain database is encrypted when first opened with a key.
+	plain, err := Open(path)
+	if err != nil {
+		t.Fatalf("open plain: %v", err)
+	}
+	plain.Migrate()
+	plain.CreateProject(CreateProjectInput{Name: "before-encryption", Path: "/tmp/a"})
+	plain.Close()
+
+	t.Setenv("CODEBURG_DB_KEY", "correct horse")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("open with key: %v", err)
+	}
+	db.Migrate()
+	db.CreateProject(CreateProjectInput{Name: "after-encryption", Path: "/tmp/b"})
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(data, encryptedMagic) || bytes.Contains(data, []byte("encryption")) {
+		t.Fatal("expected the file on disk encrypted")
+	}
+	if _, err := os.Stat(path + "-wal"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the plain journal removed, got %v", err)
+	}
+	if entries, _ := os.ReadDir(os.Getenv("XDG_RUNTIME_DIR")); len(entries) != 0 {
+		t.Errorf("expected the working copy removed, got %v", entries)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	projects, _ := db.ListProjects()
+	if len(projects) != 2 {
+		t.Errorf("expected both projects after reopening, got %d", len(projects))
+	}
+
+	// A second open is refused while the first holds the database, and
+	// leaves its working copy alone.
+	if _, err := Open(path); !errors.Is(err, ErrDatabaseLocked) {
+		t.Errorf("expected ErrDatabaseLocked, got %v", err)
+	}
+	if _, err := db.CreateProject(CreateProjectInput{Name: "still-open", Path: "/tmp/c"}); err != nil {
+		t.Errorf("expected the first open still usable, got %v", err)
+	}
+	workDir := db.enc.workDir
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// Once the lock is free, a working copy left by a crash is replaced.
+	os.MkdirAll(workDir, 0o700)
+	os.WriteFile(filepath.Join(workDir, "codeburg.db"), []byte("stale"), 0o600)
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("open over a stale working copy: %v", err)
+	}
+	if projects, _ := db.ListProjects(); len(projects) != 3 {
+		t.Errorf("expected 3 projects, got %d", len(projects))
+	}
+	db.Close()
+
+	t.Setenv("CODEBURG_DB_KEY", "")
+	t.Setenv("CODEBURG_DB_KEY_COMMAND", "echo wrong horse")
+	if _, err := Open(path); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("expected ErrWrongKey, got %v", err)
+	}
+	os.WriteFile(path, data[:len(data)-10], 0o600)
+	t.Setenv("CODEBURG_DB_KEY_COMMAND", "printf 'correct horse'")
+	if _, err := Open(path); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("expected a truncated file refused, got %v", err)
+	}
+}
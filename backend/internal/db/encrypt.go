@@ -0,0 +1,428 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encryption at rest
+//
+// With CODEBURG_DB_KEY set to a passphrase, or CODEBURG_DB_KEY_COMMAND to a
+// command that prints one (such as `security find-generic-password -s
+// codeburg -w` or `secret-tool lookup service codeburg`), the database file
+// is kept encrypted with AES-256-GCM under a key derived by scrypt. SQLite
+// itself works on a decrypted copy in a private directory on a RAM-backed
+// file system where there is one ($XDG_RUNTIME_DIR or /dev/shm), which is
+// encrypted back to the database path every encryptedFlushInterval and on
+// Close. A crash loses at most that interval of writes. An existing plain
+// database is encrypted the first time it is opened with a key. The
+// working copy belongs to whoever holds the lock on <path>.lock, so that a
+// second server started on the same database is refused instead of
+// removing the first one's working copy from under it.
+const (
+	dbKeyEnv        = "CODEBURG_DB_KEY"
+	dbKeyCommandEnv = "CODEBURG_DB_KEY_COMMAND"
+
+	encryptedFlushInterval = 30 * time.Second
+	encryptedChunkSize     = 64 << 10
+	encryptedSaltSize      = 16
+)
+
+// encryptedMagic starts every encrypted database file.
+var encryptedMagic = []byte("CODEBURG-ENCRYPTED-DB-1\n")
+
+// sqliteMagic starts every plain SQLite database file.
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// ErrWrongKey is returned when an encrypted database does not decrypt with
+// the key given.
+var ErrWrongKey = errors.New("database key is wrong or the file is corrupt")
+
+// ErrDatabaseLocked is returned when another process has the encrypted
+// database open.
+var ErrDatabaseLocked = errors.New("database is open in another process")
+
+// KeyFromEnv returns the database passphrase from CODEBURG_DB_KEY, or the
+// output of CODEBURG_DB_KEY_COMMAND, or "" when neither is set.
+func KeyFromEnv() (string, error) {
+	if key := os.Getenv(dbKeyEnv); key != "" {
+		return key, nil
+	}
+	command := strings.TrimSpace(os.Getenv(dbKeyCommandEnv))
+	if command == "" {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", dbKeyCommandEnv, err, strings.TrimSpace(stderr.String()))
+	}
+	key := strings.TrimRight(string(out), "\r\n")
+	if key == "" {
+		return "", fmt.Errorf("%s printed no key", dbKeyCommandEnv)
+	}
+	return key, nil
+}
+
+// encryptedFile keeps the database at path encrypted, while SQLite works on
+// the plain copy at workPath.
+type encryptedFile struct {
+	path     string
+	workDir  string
+	workPath string
+	lock     *os.File // holds the flock on path+".lock" until close
+	salt     []byte
+	aead     cipher.AEAD
+
+	mu      sync.Mutex
+	flushed string // stamp of the working copy when it was last flushed
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// openEncrypted decrypts the database at path, or copies a plain one, into
+// a private working directory and returns it ready to be opened there.
+func openEncrypted(path, passphrase string) (*encryptedFile, error) {
+	lock, err := lockDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(path))
+	workDir := filepath.Join(encryptedWorkRoot(), "codeburg-db-"+hex.EncodeToString(sum[:8]))
+	e := &encryptedFile{path: path, workDir: workDir, workPath: filepath.Join(workDir, "codeburg.db"), lock: lock}
+	// With the lock held, a working copy left behind is from a crash; it
+	// is not to be trusted over the encrypted file, and must not linger in
+	// the clear.
+	if err := os.RemoveAll(workDir); err != nil {
+		e.discard()
+		return nil, fmt.Errorf("remove stale working copy: %w", err)
+	}
+	if err := os.MkdirAll(workDir, 0o700); err != nil {
+		e.discard()
+		return nil, fmt.Errorf("create working directory: %w", err)
+	}
+	if err := e.load(passphrase); err != nil {
+		e.discard()
+		return nil, err
+	}
+	return e, nil
+}
+
+// lockDatabase takes an exclusive lock on path+".lock", failing with
+// ErrDatabaseLocked rather than waiting when another process holds it. The
+// lock goes with the process, so a crash does not leave it held.
+func lockDatabase(path string) (*os.File, error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open database lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrDatabaseLocked
+		}
+		return nil, fmt.Errorf("lock database: %w", err)
+	}
+	return f, nil
+}
+
+// discard removes the working copy and releases the lock, for an open that
+// failed.
+func (e *encryptedFile) discard() {
+	os.RemoveAll(e.workDir)
+	e.lock.Close()
+}
+
+// encryptedWorkRoot picks a RAM-backed directory for the working copy, so
+// that it does not reach the disk.
+func encryptedWorkRoot() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		return "/dev/shm"
+	}
+	return os.TempDir()
+}
+
+func (e *encryptedFile) load(passphrase string) error {
+	f, err := os.Open(e.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return e.setKey(passphrase, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, len(encryptedMagic))
+	n, _ := io.ReadFull(f, header)
+	switch {
+	case n == 0:
+		return e.setKey(passphrase, nil)
+	case bytes.Equal(header, encryptedMagic):
+		salt := make([]byte, encryptedSaltSize)
+		if _, err := io.ReadFull(f, salt); err != nil {
+			return ErrWrongKey
+		}
+		if err := e.setKey(passphrase, salt); err != nil {
+			return err
+		}
+		return e.decrypt(f)
+	case bytes.HasPrefix(header, sqliteMagic):
+		f.Close()
+		if err := e.setKey(passphrase, nil); err != nil {
+			return err
+		}
+		return e.adoptPlain()
+	default:
+		return fmt.Errorf("%s is not a database", e.path)
+	}
+}
+
+// setKey derives the key from the passphrase and salt, or a new salt.
+func (e *encryptedFile) setKey(passphrase string, salt []byte) error {
+	if salt == nil {
+		salt = make([]byte, encryptedSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return fmt.Errorf("derive database key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	e.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	e.salt = salt
+	return nil
+}
+
+// adoptPlain encrypts a plain database the first time it is opened with a
+// key, removing the plain file and its journal.
+func (e *encryptedFile) adoptPlain() error {
+	conn, err := openConn(e.path)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(`VACUUM INTO ?`, e.workPath)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("copy plain database: %w", err)
+	}
+	if err := e.flush(); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(e.path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	slog.Info("encrypted the database", "path", e.path)
+	return nil
+}
+
+// chunkAD binds each chunk to its place, so that chunks cannot be
+// reordered, and marks the last one, so that the file cannot be truncated.
+func chunkAD(index uint64, last bool) []byte {
+	ad := make([]byte, 9)
+	binary.BigEndian.PutUint64(ad, index)
+	if last {
+		ad[8] = 1
+	}
+	return ad
+}
+
+// decrypt writes the chunks that follow the header to the working copy.
+// Every chunk but the last holds encryptedChunkSize bytes.
+func (e *encryptedFile) decrypt(r io.Reader) error {
+	out, err := os.OpenFile(e.workPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	nonceSize := e.aead.NonceSize()
+	buf := make([]byte, nonceSize+encryptedChunkSize+e.aead.Overhead())
+	br := bufio.NewReader(r)
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			// io.EOF: the last chunk never came.
+			return ErrWrongKey
+		}
+		last := n < len(buf)
+		if n < nonceSize {
+			return ErrWrongKey
+		}
+		plain, err := e.aead.Open(buf[nonceSize:nonceSize], buf[:nonceSize], buf[nonceSize:n], chunkAD(index, last))
+		if err != nil {
+			return ErrWrongKey
+		}
+		if _, err := out.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return out.Close()
+		}
+	}
+}
+
+// flush encrypts a snapshot of the working copy to the database path,
+// replacing it atomically.
+func (e *encryptedFile) flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.writeEncrypted(e.workPath)
+}
+
+// snapshotAndFlush takes a consistent snapshot of the open database with
+// VACUUM INTO and flushes it, unless nothing changed since the last flush.
+func (e *encryptedFile) snapshotAndFlush(db *DB) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stamp := e.stamp()
+	if stamp == e.flushed {
+		return nil
+	}
+	snapshot := filepath.Join(e.workDir, "snapshot.db")
+	os.Remove(snapshot)
+	defer os.Remove(snapshot)
+	if _, err := db.conn.Exec(`VACUUM INTO ?`, snapshot); err != nil {
+		return fmt.Errorf("snapshot database: %w", err)
+	}
+	if err := e.writeEncrypted(snapshot); err != nil {
+		return err
+	}
+	e.flushed = stamp
+	return nil
+}
+
+// stamp identifies the state of the working copy and its write-ahead log.
+func (e *encryptedFile) stamp() string {
+	var parts []string
+	for _, p := range []string{e.workPath, e.workPath + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			parts = append(parts, fmt.Sprintf("%d/%d", info.Size(), info.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (e *encryptedFile) writeEncrypted(plainPath string) error {
+	in, err := os.Open(plainPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), filepath.Base(e.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write encrypted database: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := e.encrypt(in, tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write encrypted database: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), e.path)
+}
+
+func (e *encryptedFile) encrypt(r io.Reader, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.Write(encryptedMagic)
+	bw.Write(e.salt)
+
+	nonceSize := e.aead.NonceSize()
+	plain := make([]byte, encryptedChunkSize)
+	sealed := make([]byte, nonceSize, nonceSize+encryptedChunkSize+e.aead.Overhead())
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(r, plain)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		last := n < encryptedChunkSize
+		if _, err := rand.Read(sealed[:nonceSize]); err != nil {
+			return err
+		}
+		out := e.aead.Seal(sealed[:nonceSize], sealed[:nonceSize], plain[:n], chunkAD(index, last))
+		if _, err := bw.Write(out); err != nil {
+			return err
+		}
+		if last {
+			return bw.Flush()
+		}
+	}
+}
+
+// run flushes the working copy until close is called.
+func (e *encryptedFile) run(db *DB) {
+	defer close(e.done)
+	ticker := time.NewTicker(encryptedFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			if err := e.snapshotAndFlush(db); err != nil {
+				slog.Error("failed to write the encrypted database", "error", err)
+			}
+		}
+	}
+}
+
+// close writes the last changes, removes the working copy and releases the
+// lock.
+func (e *encryptedFile) close(db *DB) error {
+	defer e.lock.Close()
+	close(e.stop)
+	<-e.done
+	err := e.snapshotAndFlush(db)
+	if cerr := db.conn.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Keep the working copy, so that it can be recovered by hand
+		// before the next Open replaces it.
+		return fmt.Errorf("%w (the working copy is kept in %s)", err, e.workDir)
+	}
+	return os.RemoveAll(e.workDir)
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Job statuses.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed" // gave up after the last attempt
+	JobCancelled = "cancelled"
+)
+
+// Job is a long-running operation queued for, or run by, the background
+// workers. ProjectID, when set, is the project it works on; it outlives the
+// project so that cleanup after a deletion can still be followed.
+type Job struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	ProjectID   *string         `json:"projectId,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Progress    string          `json:"progress,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"maxAttempts"`
+	LastError   string          `json:"lastError,omitempty"`
+	RunAt       *time.Time      `json:"runAt,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+type CreateJobInput struct {
+	Kind        string
+	ProjectID   *string
+	Payload     []byte // JSON; {} when empty
+	MaxAttempts int    // 1 when not positive
+}
+
+// JobFilter narrows ListJobs; empty fields match every job.
+type JobFilter struct {
+	Kind      string
+	Status    string
+	ProjectID string
+	Limit     int // 100 when not positive
+}
+
+const jobColumns = `id, kind, project_id, payload, status, progress, result, attempts, max_attempts, last_error, run_at, created_at, updated_at, finished_at`
+
+// CreateJob queues a job, due now.
+func (db *DB) CreateJob(input CreateJobInput) (*Job, error) {
+	id := NewID()
+	now := time.Now()
+	payload := string(input.Payload)
+	if payload == "" {
+		payload = "{}"
+	}
+	maxAttempts := max(input.MaxAttempts, 1)
+	_, err := db.conn.Exec(
+		`INSERT INTO jobs (id, kind, project_id, payload, status, max_attempts, run_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, input.Kind, NullString(input.ProjectID), payload, JobPending, maxAttempts, now, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert job: %w", err)
+	}
+	return db.GetJob(id)
+}
+
+func (db *DB) GetJob(id string) (*Job, error) {
+	row := db.conn.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id)
+	j, err := scanJob(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return j, err
+}
+
+// ListJobs returns the jobs matching filter, newest first.
+func (db *DB) ListJobs(filter JobFilter) ([]*Job, error) {
+	var where []string
+	var args []any
+	if filter.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, filter.Kind)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.ProjectID != "" {
+		where = append(where, "project_id = ?")
+		args = append(args, filter.ProjectID)
+	}
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+	return db.queryJobs(query, args...)
+}
+
+// ListDueJobs returns up to limit pending jobs due by now, oldest first.
+func (db *DB) ListDueJobs(now time.Time, limit int) ([]*Job, error) {
+	return db.queryJobs(
+		`SELECT `+jobColumns+` FROM jobs
+		 WHERE status = ? AND run_at <= ? ORDER BY run_at, id LIMIT ?`,
+		JobPending, now, limit,
+	)
+}
+
+// HasActiveJob reports whether a job of kind is pending or running.
+func (db *DB) HasActiveJob(kind string) (bool, error) {
+	var n int
+	err := db.conn.QueryRow(
+		`SELECT COUNT(*) FROM jobs WHERE kind = ? AND status IN (?, ?)`,
+		kind, JobPending, JobRunning,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("count active jobs: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (db *DB) queryJobs(query string, args ...any) ([]*Job, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*Job, 0)
+	for rows.Next() {
+		j, err := scanJob(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// StartJob marks a pending job running and counts the attempt. It returns
+// ErrNotFound when the job is no longer pending, e.g. another worker took
+// it.
+func (db *DB) StartJob(id string) (*Job, error) {
+	result, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, attempts = attempts + 1, progress = '', updated_at = ?
+		 WHERE id = ? AND status = ?`,
+		JobRunning, time.Now(), id, JobPending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("start job: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetJob(id)
+}
+
+// SetJobProgress records what a running job is doing.
+func (db *DB) SetJobProgress(id, progress string) error {
+	_, err := db.conn.Exec(`UPDATE jobs SET progress = ?, updated_at = ? WHERE id = ?`, progress, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("set job progress: %w", err)
+	}
+	return nil
+}
+
+// FinishJob stores the outcome of a running job's attempt: its result
+// (JSON, may be nil) when it succeeded, or its error. A nil retryAt with an
+// error gives up. A job cancelled meanwhile stays cancelled.
+func (db *DB) FinishJob(id string, result []byte, attemptErr error, retryAt *time.Time) (*Job, error) {
+	now := time.Now()
+	status, lastError := JobSucceeded, ""
+	var finishedAt, runAt any
+	if attemptErr != nil {
+		lastError = attemptErr.Error()
+		status, finishedAt = JobFailed, now
+		if retryAt != nil {
+			status, runAt, finishedAt = JobPending, *retryAt, nil
+		}
+	} else {
+		finishedAt = now
+	}
+	_, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, result = ?, last_error = ?, run_at = ?, updated_at = ?, finished_at = ?
+		 WHERE id = ? AND status = ?`,
+		status, string(result), lastError, runAt, now, finishedAt, id, JobRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("finish job: %w", err)
+	}
+	return db.GetJob(id)
+}
+
+// CancelJob marks a pending or running job cancelled. It returns
+// ErrNotFound when the job does not exist or has already finished.
+func (db *DB) CancelJob(id string) (*Job, error) {
+	now := time.Now()
+	result, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, updated_at = ?, finished_at = ? WHERE id = ? AND status IN (?, ?)`,
+		JobCancelled, now, now, id, JobPending, JobRunning,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cancel job: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetJob(id)
+}
+
+// RetryJob queues a failed or cancelled job again, due now, with a fresh
+// series of attempts.
+func (db *DB) RetryJob(id string) (*Job, error) {
+	result, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, attempts = 0, run_at = ?, updated_at = ?, finished_at = NULL
+		 WHERE id = ? AND status IN (?, ?)`,
+		JobPending, time.Now(), time.Now(), id, JobFailed, JobCancelled,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("retry job: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		if _, err := db.GetJob(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrJobNotFailed
+	}
+	return db.GetJob(id)
+}
+
+// ErrJobNotFailed is returned by RetryJob for jobs that have neither failed
+// nor been cancelled.
+var ErrJobNotFailed = errors.New("job has not failed")
+
+// RequeueRunningJobs puts jobs left running by a previous process back in
+// the queue, due now. Their interrupted attempt still counts.
+func (db *DB) RequeueRunningJobs() (int64, error) {
+	now := time.Now()
+	result, err := db.conn.Exec(
+		`UPDATE jobs SET status = ?, run_at = ?, updated_at = ? WHERE status = ?`,
+		JobPending, now, now, JobRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("requeue running jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// PruneJobs deletes finished jobs created before cutoff.
+func (db *DB) PruneJobs(cutoff time.Time) (int64, error) {
+	result, err := db.conn.Exec(
+		`DELETE FROM jobs WHERE status IN (?, ?, ?) AND created_at < ?`,
+		JobSucceeded, JobFailed, JobCancelled, cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("prune jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func scanJob(scan scanFunc) (*Job, error) {
+	var j Job
+	var payload, result string
+	var projectID sql.NullString
+	var runAt, finishedAt sql.NullTime
+	if err := scan(&j.ID, &j.Kind, &projectID, &payload, &j.Status, &j.Progress, &result, &j.Attempts, &j.MaxAttempts, &j.LastError, &runAt, &j.CreatedAt, &j.UpdatedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	j.ProjectID = StringPtr(projectID)
+	j.Payload = json.RawMessage(payload)
+	if result != "" {
+		j.Result = json.RawMessage(result)
+	}
+	j.RunAt = TimePtr(runAt)
+	j.FinishedAt = TimePtr(finishedAt)
+	return &j, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LoginLink is a one-time link that logs a device in and opens Path. Only a
+// hash of the link's token is stored.
+type LoginLink struct {
+	ID        string     `json:"id"`
+	Path      string     `json:"path"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+}
+
+const loginLinkColumns = `id, path, created_at, expires_at, used_at`
+
+// CreateLoginLink stores a new link's token hash, and drops links that
+// expired a day or more ago.
+func (db *DB) CreateLoginLink(tokenHash, path string, expiresAt time.Time) (*LoginLink, error) {
+	now := time.Now()
+	if _, err := db.conn.Exec(`DELETE FROM login_links WHERE expires_at < ?`, now.Add(-24*time.Hour)); err != nil {
+		return nil, fmt.Errorf("delete expired login links: %w", err)
+	}
+
+	link := &LoginLink{ID: NewID(), Path: path, CreatedAt: now, ExpiresAt: expiresAt}
+	_, err := db.conn.Exec(
+		`INSERT INTO login_links (id, token_hash, path, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+		link.ID, tokenHash, link.Path, link.ExpiresAt, link.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert login link: %w", err)
+	}
+	return link, nil
+}
+
+// UseLoginLink marks the link with the given token hash as used and
+// returns it. Links that are unknown, expired or already used return
+// ErrNotFound, so each link works once.
+func (db *DB) UseLoginLink(tokenHash string) (*LoginLink, error) {
+	now := time.Now()
+	result, err := db.conn.Exec(
+		`UPDATE login_links SET used_at = ? WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`,
+		now, tokenHash, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("use login link: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	row := db.conn.QueryRow(`SELECT `+loginLinkColumns+` FROM login_links WHERE token_hash = ?`, tokenHash)
+	return scanLoginLink(row.Scan)
+}
+
+func scanLoginLink(scan scanFunc) (*LoginLink, error) {
+	var l LoginLink
+	var usedAt sql.NullTime
+	if err := scan(&l.ID, &l.Path, &l.CreatedAt, &l.ExpiresAt, &usedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	l.UsedAt = TimePtr(usedAt)
+	return &l, nil
+}
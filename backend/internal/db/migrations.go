@@ -314,4 +314,575 @@ var migrations = []migration{
 			CREATE INDEX idx_agent_messages_session_seq ON agent_messages(session_id, seq);
 		`,
 	},
+	{
+		version: 17,
+		sql: `
+			-- Named saved board views (filter + sort presets) per user
+			CREATE TABLE saved_views (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				filters TEXT NOT NULL DEFAULT '{}',
+				is_default BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (user_id, name)
+			);
+		`,
+	},
+	{
+		version: 18,
+		sql: `
+			-- Personal access tokens for automation (stored as SHA-256 hashes)
+			CREATE TABLE api_tokens (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				token_prefix TEXT NOT NULL,
+				scopes TEXT NOT NULL DEFAULT '[]',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				expires_at DATETIME,
+				last_used_at DATETIME
+			);
+		`,
+	},
+	{
+		version: 19,
+		sql: `
+			-- Telegram messages sent about a session, so replies and reactions
+			-- can be routed back to it
+			CREATE TABLE telegram_messages (
+				chat_id INTEGER NOT NULL,
+				message_id INTEGER NOT NULL,
+				session_id TEXT NOT NULL REFERENCES agent_sessions(id) ON DELETE CASCADE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (chat_id, message_id)
+			);
+		`,
+	},
+	{
+		version: 20,
+		sql: `
+			-- Per-project author identity for commits made by agent sessions
+			ALTER TABLE projects ADD COLUMN agent_identity TEXT;
+		`,
+	},
+	{
+		version: 21,
+		sql: `
+			-- Per-project agent instructions (system prompt prelude and defaults)
+			ALTER TABLE projects ADD COLUMN agent_instructions TEXT;
+		`,
+	},
+	{
+		version: 22,
+		sql: `
+			-- Per-project retry policy for failed agent sessions, and the
+			-- retry attempts made for each session
+			ALTER TABLE projects ADD COLUMN retry_policy TEXT;
+			ALTER TABLE agent_sessions ADD COLUMN retry_history TEXT;
+		`,
+	},
+	{
+		version: 23,
+		sql: `
+			-- Per-project default auth for tunneled ports
+			ALTER TABLE projects ADD COLUMN tunnel_auth TEXT;
+		`,
+	},
+	{
+		version: 24,
+		sql: `
+			-- Files attached to tasks; contents live under ~/.codeburg/attachments
+			CREATE TABLE task_attachments (
+				id TEXT PRIMARY KEY,
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				filename TEXT NOT NULL,
+				content_type TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_task_attachments_task ON task_attachments(task_id);
+		`,
+	},
+	{
+		version: 25,
+		sql: `
+			-- Ordered recipe pipelines per project
+			CREATE TABLE pipelines (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				steps TEXT NOT NULL DEFAULT '[]',
+				continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_pipelines_project ON pipelines(project_id);
+		`,
+	},
+	{
+		version: 26,
+		sql: `
+			ALTER TABLE projects ADD COLUMN provisioner TEXT;
+
+			-- Services started for a task by its project's provisioner
+			CREATE TABLE task_provisions (
+				task_id TEXT PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+				status TEXT NOT NULL,
+				env TEXT NOT NULL DEFAULT '{}',
+				output TEXT NOT NULL DEFAULT '',
+				error TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+	},
+	{
+		version: 27,
+		sql: `
+			ALTER TABLE projects ADD COLUMN clone_paths TEXT;
+		`,
+	},
+	{
+		version: 28,
+		sql: `
+			ALTER TABLE projects ADD COLUMN worktree_pool TEXT;
+
+			-- Worktrees created ahead of time, waiting to be claimed by a task
+			CREATE TABLE pooled_worktrees (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				path TEXT NOT NULL,
+				branch TEXT NOT NULL,
+				base_branch TEXT NOT NULL,
+				status TEXT NOT NULL,
+				size_bytes INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_pooled_worktrees_project ON pooled_worktrees(project_id);
+		`,
+	},
+	{
+		version: 29,
+		sql: `
+			-- Keyset pagination orders by (created_at, id)
+			CREATE INDEX idx_tasks_created ON tasks(created_at, id);
+			CREATE INDEX idx_sessions_task_created ON agent_sessions(task_id, created_at, id);
+		`,
+	},
+	{
+		version: 30,
+		sql: `
+			-- Build outputs published from a task's worktree; contents live under
+			-- ~/.codeburg/artifacts until they expire
+			CREATE TABLE task_artifacts (
+				id TEXT PRIMARY KEY,
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				session_id TEXT REFERENCES agent_sessions(id) ON DELETE SET NULL,
+				name TEXT NOT NULL,
+				path TEXT NOT NULL,
+				source TEXT NOT NULL,
+				content_type TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				expires_at DATETIME NOT NULL
+			);
+			CREATE INDEX idx_task_artifacts_task ON task_artifacts(task_id);
+		`,
+	},
+	{
+		version: 31,
+		sql: `
+			-- One prompt run by several providers side by side, each in its own
+			-- throwaway worktree, until one implementation is picked
+			CREATE TABLE session_comparisons (
+				id TEXT PRIMARY KEY,
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				prompt TEXT NOT NULL,
+				base_commit TEXT NOT NULL,
+				status TEXT NOT NULL,
+				picked_session_id TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_session_comparisons_task ON session_comparisons(task_id);
+
+			CREATE TABLE session_comparison_entries (
+				comparison_id TEXT NOT NULL REFERENCES session_comparisons(id) ON DELETE CASCADE,
+				session_id TEXT NOT NULL REFERENCES agent_sessions(id) ON DELETE CASCADE,
+				provider TEXT NOT NULL,
+				model TEXT NOT NULL DEFAULT '',
+				worktree_path TEXT NOT NULL,
+				branch TEXT NOT NULL,
+				position INTEGER NOT NULL,
+				PRIMARY KEY (comparison_id, session_id)
+			);
+			CREATE UNIQUE INDEX idx_session_comparison_entries_session ON session_comparison_entries(session_id);
+		`,
+	},
+	{
+		version: 32,
+		sql: `
+			-- Text pasted into a task's inbox (logs, stack traces, links) until
+			-- a new session takes it into its initial prompt
+			CREATE TABLE task_snippets (
+				id TEXT PRIMARY KEY,
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				content TEXT NOT NULL,
+				consumed_session_id TEXT REFERENCES agent_sessions(id) ON DELETE SET NULL,
+				consumed_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_task_snippets_task ON task_snippets(task_id);
+		`,
+	},
+	{
+		version: 33,
+		sql: `
+			-- Archived projects are hidden and their tasks read-only
+			ALTER TABLE projects ADD COLUMN archived_at DATETIME;
+		`,
+	},
+	{
+		version: 34,
+		sql: `
+			-- Labeled points in a session: the message seq and the git HEAD of
+			-- the session's work directory when it was marked
+			CREATE TABLE session_bookmarks (
+				id TEXT PRIMARY KEY,
+				session_id TEXT NOT NULL REFERENCES agent_sessions(id) ON DELETE CASCADE,
+				label TEXT NOT NULL,
+				seq INTEGER NOT NULL DEFAULT 0,
+				git_head TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_session_bookmarks_session ON session_bookmarks(session_id);
+		`,
+	},
+	{
+		version: 35,
+		sql: `
+			-- One-time links that log a device in and open a screen. Only a
+			-- hash of the token is stored
+			CREATE TABLE login_links (
+				id TEXT PRIMARY KEY,
+				token_hash TEXT NOT NULL UNIQUE,
+				path TEXT NOT NULL,
+				expires_at DATETIME NOT NULL,
+				used_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+	},
+	{
+		version: 36,
+		sql: `
+			-- Agent activity per project, provider and hour (UTC), for
+			-- heatmaps. Counted commits are remembered so none counts twice
+			CREATE TABLE activity_hours (
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				provider TEXT NOT NULL,
+				hour DATETIME NOT NULL,
+				messages INTEGER NOT NULL DEFAULT 0,
+				tool_calls INTEGER NOT NULL DEFAULT 0,
+				commits INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (project_id, provider, hour)
+			);
+			CREATE INDEX idx_activity_hours_hour ON activity_hours(hour);
+
+			CREATE TABLE activity_commits (
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				hash TEXT NOT NULL,
+				PRIMARY KEY (project_id, hash)
+			);
+		`,
+	},
+	{
+		version: 37,
+		sql: `
+			-- Tokens and cost each chat turn consumed, as providers report
+			-- them. Kept when the session is deleted, so budgets still add up
+			CREATE TABLE usage_records (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				session_id TEXT NOT NULL,
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				provider TEXT NOT NULL,
+				model TEXT NOT NULL DEFAULT '',
+				input_tokens INTEGER NOT NULL DEFAULT 0,
+				output_tokens INTEGER NOT NULL DEFAULT 0,
+				cost_usd REAL NOT NULL DEFAULT 0,
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_usage_records_created ON usage_records(created_at);
+
+			-- Budget thresholds already alerted on, and enforcement lifted by
+			-- an override, per budget and month ("2026-10")
+			CREATE TABLE budget_alerts (
+				budget_key TEXT NOT NULL,
+				month TEXT NOT NULL,
+				threshold INTEGER NOT NULL,
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (budget_key, month, threshold)
+			);
+			CREATE TABLE budget_overrides (
+				budget_key TEXT NOT NULL,
+				month TEXT NOT NULL,
+				expires_at DATETIME NOT NULL,
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (budget_key, month)
+			);
+		`,
+	},
+	{
+		version: 38,
+		sql: `
+			-- "Ask" sessions answer questions about the code and edit nothing
+			ALTER TABLE agent_sessions ADD COLUMN read_only INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		version: 39,
+		sql: `
+			-- Time spent on tasks: the spans sessions run, per provider, and
+			-- manual entries (no session). A running session's entry has no
+			-- end yet. Entries outlive their session
+			CREATE TABLE time_entries (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				task_id TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+				session_id TEXT,
+				provider TEXT NOT NULL DEFAULT '',
+				started_at DATETIME NOT NULL,
+				ended_at DATETIME,
+				note TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_time_entries_task ON time_entries(task_id);
+			CREATE INDEX idx_time_entries_project ON time_entries(project_id, started_at);
+			CREATE INDEX idx_time_entries_session ON time_entries(session_id) WHERE ended_at IS NULL;
+		`,
+	},
+	{
+		version: 40,
+		sql: `
+			-- Columns a project adds to its board, with the built-in status
+			-- each counts as and WIP limits
+			ALTER TABLE projects ADD COLUMN board_columns TEXT;
+		`,
+	},
+	{
+		version: 41,
+		sql: `
+			-- Embedding index for semantic search: the files indexed, with the
+			-- size and modification time they had, and their chunks' vectors
+			CREATE TABLE semantic_files (
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				path TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				mod_time DATETIME NOT NULL,
+				model TEXT NOT NULL,
+				indexed_at DATETIME NOT NULL,
+				PRIMARY KEY (project_id, path)
+			);
+			CREATE TABLE semantic_chunks (
+				project_id TEXT NOT NULL,
+				path TEXT NOT NULL,
+				start_line INTEGER NOT NULL,
+				end_line INTEGER NOT NULL,
+				preview TEXT NOT NULL,
+				vector BLOB NOT NULL,
+				PRIMARY KEY (project_id, path, start_line),
+				FOREIGN KEY (project_id, path) REFERENCES semantic_files(project_id, path) ON DELETE CASCADE
+			);
+		`,
+	},
+	{
+		version: 42,
+		sql: `
+			-- Session titles, generated from the first prompt or set by hand
+			ALTER TABLE agent_sessions ADD COLUMN title TEXT NOT NULL DEFAULT '';
+		`,
+	},
+	{
+		version: 43,
+		sql: `
+			-- Tasks imported from other trackers, by the issue they came from
+			CREATE TABLE task_imports (
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				source TEXT NOT NULL,
+				external_key TEXT NOT NULL,
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				imported_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (project_id, source, external_key)
+			);
+			CREATE INDEX idx_task_imports_task ON task_imports(task_id);
+		`,
+	},
+	{
+		version: 44,
+		sql: `
+			-- Outbound webhooks: where to post which events, and the queue of
+			-- deliveries with their retry state
+			CREATE TABLE webhooks (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL DEFAULT '',
+				url TEXT NOT NULL,
+				secret TEXT NOT NULL,
+				events TEXT NOT NULL DEFAULT '[]',
+				project_id TEXT REFERENCES projects(id) ON DELETE CASCADE,
+				enabled BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL
+			);
+			CREATE TABLE webhook_deliveries (
+				id TEXT PRIMARY KEY,
+				webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+				event TEXT NOT NULL,
+				payload TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				next_attempt_at DATETIME,
+				response_status INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				delivered_at DATETIME
+			);
+			CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+			CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at);
+		`,
+	},
+	{
+		version: 45,
+		sql: `
+			-- Sessions the idle timeout never stops
+			ALTER TABLE agent_sessions ADD COLUMN idle_exempt BOOLEAN NOT NULL DEFAULT FALSE;
+		`,
+	},
+	{
+		version: 46,
+		sql: `
+			-- Branch a task's worktree is based on, when not the project's default
+			ALTER TABLE tasks ADD COLUMN base_branch TEXT;
+		`,
+	},
+	{
+		version: 47,
+		sql: `
+			-- Per-project protected paths and branches
+			ALTER TABLE projects ADD COLUMN protection TEXT;
+		`,
+	},
+	{
+		version: 48,
+		sql: `
+			-- Reusable session settings and prompts per project
+			CREATE TABLE session_templates (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				model TEXT NOT NULL DEFAULT '',
+				auto_approve BOOLEAN NOT NULL DEFAULT TRUE,
+				prompt TEXT NOT NULL DEFAULT '',
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_session_templates_project ON session_templates(project_id);
+		`,
+	},
+	{
+		version: 49,
+		sql: `
+			-- Background jobs: long-running operations and their retry state
+			CREATE TABLE jobs (
+				id TEXT PRIMARY KEY,
+				kind TEXT NOT NULL,
+				project_id TEXT,
+				payload TEXT NOT NULL DEFAULT '{}',
+				status TEXT NOT NULL DEFAULT 'pending',
+				progress TEXT NOT NULL DEFAULT '',
+				result TEXT NOT NULL DEFAULT '',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				max_attempts INTEGER NOT NULL DEFAULT 1,
+				last_error TEXT NOT NULL DEFAULT '',
+				run_at DATETIME,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				finished_at DATETIME
+			);
+			CREATE INDEX idx_jobs_due ON jobs(run_at) WHERE status = 'pending';
+			CREATE INDEX idx_jobs_created ON jobs(created_at);
+		`,
+	},
+	{
+		version: 50,
+		sql: `
+			-- Environment variables set for one task, over the project's
+			CREATE TABLE task_env_vars (
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				value TEXT NOT NULL,
+				secret BOOLEAN NOT NULL DEFAULT FALSE,
+				PRIMARY KEY (task_id, name)
+			);
+		`,
+	},
+	{
+		version: 51,
+		sql: `
+			-- Devcontainer settings: run sessions and recipes in the project's container
+			ALTER TABLE projects ADD COLUMN devcontainer TEXT;
+		`,
+	},
+	{
+		version: 52,
+		sql: `
+			-- Task estimates, in minutes
+			ALTER TABLE tasks ADD COLUMN estimate_minutes INTEGER;
+		`,
+	},
+	{
+		version: 53,
+		sql: `
+			-- Task versions, bumped on every change, for optimistic concurrency
+			ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		version: 54,
+		sql: `
+			-- Project versions, bumped on every change, for optimistic concurrency
+			ALTER TABLE projects ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+		`,
+	},
+	{
+		version: 55,
+		sql: `
+			-- Worktree snapshots: a task's worktree state, kept in a commit
+			-- under refs/codeburg/snapshots, to roll back to
+			CREATE TABLE worktree_snapshots (
+				id TEXT PRIMARY KEY,
+				task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+				label TEXT NOT NULL DEFAULT '',
+				branch TEXT NOT NULL DEFAULT '',
+				head_commit TEXT NOT NULL,
+				state_commit TEXT NOT NULL,
+				dirty BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX idx_worktree_snapshots_task ON worktree_snapshots(task_id, created_at);
+		`,
+	},
+	{
+		version: 56,
+		sql: `
+			-- Login tokens revoked by logging out, until they would expire
+			CREATE TABLE revoked_tokens (
+				token_hash TEXT PRIMARY KEY,
+				expires_at DATETIME NOT NULL,
+				created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+	},
 }
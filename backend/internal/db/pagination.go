@@ -0,0 +1,187 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxPageLimit caps how many rows a single page may hold.
+const MaxPageLimit = 500
+
+// ErrInvalidCursor is returned for a cursor that was not issued by a list
+// query, or that EncodeCursor made from the ID of a row since deleted.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects part of a list. The zero value selects every row.
+type Page struct {
+	// Limit is the most rows to return; 0 means no limit.
+	Limit int
+	// Cursor continues after the last row of a previous page.
+	Cursor string
+}
+
+func (p Page) active() bool {
+	return p.Limit > 0 || p.Cursor != ""
+}
+
+// pageOrder is the order a paged list is read in: its table's sort
+// columns, the last of them the table's unique id.
+type pageOrder struct {
+	table string
+	cols  []string
+	desc  bool
+}
+
+// The cursors that list queries issue hold the sort values of the row they
+// follow, as SQLite stores them, so paging goes on when that row is
+// deleted in between.
+
+// EncodeCursor makes an opaque cursor pointing after the row with this ID,
+// for a caller that knows only the ID. The row must still exist when the
+// cursor is used.
+func EncodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+// encodeKeyCursor makes a cursor pointing after the row with these sort
+// values.
+func encodeKeyCursor(values []any) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns a cursor's sort values, or, for a cursor made by
+// EncodeCursor, the ID of its row.
+func decodeCursor(cursor string, cols int) (values []any, id string, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) == 0 {
+		return nil, "", ErrInvalidCursor
+	}
+	if data[0] != '[' {
+		return nil, string(data), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil || len(values) != cols {
+		return nil, "", ErrInvalidCursor
+	}
+	for i, v := range values {
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, "", ErrInvalidCursor
+			}
+		case string:
+		default:
+			return nil, "", ErrInvalidCursor
+		}
+	}
+	return values, "", nil
+}
+
+// keyset builds the condition selecting rows that come after the cursor
+// when ordered by o. Rows are compared with the sort values the cursor
+// holds, as stored, so the result matches the ORDER BY on the same columns
+// exactly.
+func (db *DB) keyset(o pageOrder, cursor string) (string, []any, error) {
+	values, id, err := decodeCursor(cursor, len(o.cols))
+	if err != nil {
+		return "", nil, err
+	}
+
+	qualified := make([]string, len(o.cols))
+	for i, col := range o.cols {
+		qualified[i] = o.table + "." + col
+	}
+	op := ">"
+	if o.desc {
+		op = "<"
+	}
+	if values != nil {
+		cond := fmt.Sprintf("(%s) %s (?%s)", strings.Join(qualified, ", "), op, strings.Repeat(", ?", len(values)-1))
+		return cond, values, nil
+	}
+
+	var found int
+	err = db.conn.QueryRow(`SELECT 1 FROM `+o.table+` WHERE id = ?`, id).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrInvalidCursor
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("resolve cursor: %w", err)
+	}
+	cond := fmt.Sprintf("(%s) %s (SELECT %s FROM %s WHERE id = ?)",
+		strings.Join(qualified, ", "), op, strings.Join(o.cols, ", "), o.table)
+	return cond, []any{id}, nil
+}
+
+// selectKeys is added to the end of a paged query's SELECT list, to read
+// each row's sort values as stored: the type and text of each.
+func (o pageOrder) selectKeys() string {
+	var b strings.Builder
+	for _, col := range o.cols {
+		fmt.Fprintf(&b, ", typeof(%[1]s.%[2]s), CAST(%[1]s.%[2]s AS TEXT)", o.table, col)
+	}
+	return b.String()
+}
+
+// scanKeys wraps scan to also read the columns selectKeys adds, appending
+// the row's cursor to cursors.
+func (o pageOrder) scanKeys(scan scanFunc, cursors *[]string) scanFunc {
+	return func(dest ...any) error {
+		raw := make([]sql.NullString, 2*len(o.cols))
+		for i := range raw {
+			dest = append(dest, &raw[i])
+		}
+		if err := scan(dest...); err != nil {
+			return err
+		}
+		values := make([]any, len(o.cols))
+		for i := range values {
+			kind, text := raw[2*i].String, raw[2*i+1].String
+			values[i] = text
+			switch kind {
+			case "integer":
+				if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+					values[i] = n
+				}
+			case "real":
+				if f, err := strconv.ParseFloat(text, 64); err == nil {
+					values[i] = f
+				}
+			}
+		}
+		*cursors = append(*cursors, encodeKeyCursor(values))
+		return nil
+	}
+}
+
+// pageLimit returns the LIMIT clause for a page, fetching one extra row to
+// tell whether another page follows.
+func pageLimit(page Page) string {
+	if page.Limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d", min(page.Limit, MaxPageLimit)+1)
+}
+
+// trimPage drops the extra row fetched by pageLimit and returns the cursor
+// for the next page, or "" on the last page. cursors holds each row's, as
+// scanKeys read them.
+func trimPage[T any](rows []T, cursors []string, page Page) ([]T, string) {
+	limit := min(page.Limit, MaxPageLimit)
+	if page.Limit <= 0 || len(rows) <= limit {
+		return rows, ""
+	}
+	return rows[:limit], cursors[limit-1]
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PipelineStep runs one discovered recipe, optionally with named arguments.
+// Artifacts lists files, as paths or globs relative to the worktree, that
+// the step builds; they are published on the task when the step succeeds.
+type PipelineStep struct {
+	Source    string            `json:"source"`
+	Recipe    string            `json:"recipe"`
+	Args      map[string]string `json:"args,omitempty"`
+	Artifacts []string          `json:"artifacts,omitempty"`
+}
+
+// Pipeline is an ordered list of recipes run as one unit, e.g. install,
+// lint, test, build. It stops at the first failing step unless
+// ContinueOnError is set.
+type Pipeline struct {
+	ID              string         `json:"id"`
+	ProjectID       string         `json:"projectId"`
+	Name            string         `json:"name"`
+	Steps           []PipelineStep `json:"steps"`
+	ContinueOnError bool           `json:"continueOnError"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+}
+
+type CreatePipelineInput struct {
+	ProjectID       string         `json:"-"`
+	Name            string         `json:"name"`
+	Steps           []PipelineStep `json:"steps"`
+	ContinueOnError bool           `json:"continueOnError"`
+}
+
+type UpdatePipelineInput struct {
+	Name            *string         `json:"name,omitempty"`
+	Steps           *[]PipelineStep `json:"steps,omitempty"`
+	ContinueOnError *bool           `json:"continueOnError,omitempty"`
+}
+
+const pipelineColumns = `id, project_id, name, steps, continue_on_error, created_at, updated_at`
+
+func (db *DB) CreatePipeline(input CreatePipelineInput) (*Pipeline, error) {
+	steps := input.Steps
+	if steps == nil {
+		steps = []PipelineStep{}
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pipeline steps: %w", err)
+	}
+
+	id := NewID()
+	now := time.Now()
+	_, err = db.conn.Exec(
+		`INSERT INTO pipelines (`+pipelineColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, input.ProjectID, input.Name, string(data), input.ContinueOnError, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert pipeline: %w", err)
+	}
+	return db.GetPipeline(id)
+}
+
+func (db *DB) GetPipeline(id string) (*Pipeline, error) {
+	row := db.conn.QueryRow(`SELECT `+pipelineColumns+` FROM pipelines WHERE id = ?`, id)
+	p, err := scanPipeline(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
+
+// ListPipelines returns a project's pipelines ordered by name.
+func (db *DB) ListPipelines(projectID string) ([]*Pipeline, error) {
+	rows, err := db.conn.Query(
+		`SELECT `+pipelineColumns+` FROM pipelines WHERE project_id = ? ORDER BY name COLLATE NOCASE`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query pipelines: %w", err)
+	}
+	defer rows.Close()
+
+	pipelines := make([]*Pipeline, 0)
+	for rows.Next() {
+		p, err := scanPipeline(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, p)
+	}
+	return pipelines, rows.Err()
+}
+
+func (db *DB) UpdatePipeline(id string, input UpdatePipelineInput) (*Pipeline, error) {
+	query := "UPDATE pipelines SET updated_at = ?"
+	args := []any{time.Now()}
+
+	if input.Name != nil {
+		query += ", name = ?"
+		args = append(args, *input.Name)
+	}
+	if input.Steps != nil {
+		steps := *input.Steps
+		if steps == nil {
+			steps = []PipelineStep{}
+		}
+		data, err := json.Marshal(steps)
+		if err != nil {
+			return nil, fmt.Errorf("marshal pipeline steps: %w", err)
+		}
+		query += ", steps = ?"
+		args = append(args, string(data))
+	}
+	if input.ContinueOnError != nil {
+		query += ", continue_on_error = ?"
+		args = append(args, *input.ContinueOnError)
+	}
+
+	query += " WHERE id = ?"
+	args = append(args, id)
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update pipeline: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetPipeline(id)
+}
+
+func (db *DB) DeletePipeline(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM pipelines WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete pipeline: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanPipeline(scan scanFunc) (*Pipeline, error) {
+	var p Pipeline
+	var stepsJSON string
+	if err := scan(&p.ID, &p.ProjectID, &p.Name, &stepsJSON, &p.ContinueOnError, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(stepsJSON), &p.Steps); err != nil {
+		return nil, fmt.Errorf("unmarshal pipeline steps: %w", err)
+	}
+	return &p, nil
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -65,3 +66,51 @@ func (db *DB) DeletePreference(userID, key string) error {
 	}
 	return nil
 }
+
+// ListPreferences returns all preferences of a user ordered by key.
+func (db *DB) ListPreferences(userID string) ([]*UserPreference, error) {
+	rows, err := db.conn.Query(
+		`SELECT user_id, key, value, updated_at FROM user_preferences WHERE user_id = ? ORDER BY key`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []*UserPreference
+	for rows.Next() {
+		var p UserPreference
+		if err := rows.Scan(&p.UserID, &p.Key, &p.Value, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, &p)
+	}
+	return prefs, rows.Err()
+}
+
+// SetPreferences upserts several preference values in one transaction.
+func (db *DB) SetPreferences(userID string, values map[string]string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for key, value := range values {
+		_, err := tx.Exec(
+			`INSERT INTO user_preferences (user_id, key, value, updated_at)
+			 VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+			 ON CONFLICT (user_id, key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+			userID, key, value,
+		)
+		if err != nil {
+			return fmt.Errorf("set preference %q: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit preferences: %w", err)
+	}
+	return nil
+}
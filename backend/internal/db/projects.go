@@ -37,6 +37,88 @@ type ReviewToDoneConfig struct {
 	PushAfterMerge  *bool  `json:"pushAfterMerge,omitempty"`
 }
 
+// AgentGitIdentity attributes commits made by agent sessions to a distinct
+// author, leaving the committer as the user, so git blame tells human and
+// agent changes apart.
+type AgentGitIdentity struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`  // supports {provider}; default "{provider} via Codeburg"
+	Email   string `json:"email,omitempty"` // default "agent@codeburg"
+}
+
+// AgentInstructions are project-level defaults for agent sessions.
+type AgentInstructions struct {
+	// Prelude is appended to the system prompt of claude sessions and
+	// prepended to the first prompt of codex sessions.
+	Prelude            string `json:"prelude,omitempty"`
+	DefaultModel       string `json:"defaultModel,omitempty"`
+	DefaultAutoApprove *bool  `json:"defaultAutoApprove,omitempty"`
+}
+
+// RetryPolicy retries agent sessions that exit with a failure. The retry
+// prompt tells the agent what went wrong in the previous attempt.
+type RetryPolicy struct {
+	MaxRetries     int    `json:"maxRetries"`
+	BackoffSeconds int    `json:"backoffSeconds,omitempty"` // doubled after each attempt; default 10
+	PromptTemplate string `json:"promptTemplate,omitempty"` // supports {error} and {prompt}
+}
+
+// TunnelAuth protects ports tunneled for a project. Mode is "basic" (with
+// Username and Password) or "token" (a signed token in the share URL); empty
+// leaves tunnels public.
+type TunnelAuth struct {
+	Mode     string `json:"mode"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ProjectProvisioner starts per-task services, such as a database, when a
+// task's worktree is created and stops them when the task is done. Both
+// commands run in the worktree. Each name in Ports gets a free local port in
+// the environment; Env values may reference them as ${NAME}.
+type ProjectProvisioner struct {
+	Up    string            `json:"up"`             // e.g. "docker compose -f compose.dev.yml up -d --wait"
+	Down  string            `json:"down,omitempty"` // e.g. "docker compose -f compose.dev.yml down -v"
+	Ports []string          `json:"ports,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
+}
+
+// WorktreePoolConfig keeps Size worktrees ready per project, created from
+// the default branch with the project's setup already run, so starting a
+// task with a worktree does not wait on checkout and dependency installs.
+// MaxDiskMB caps the disk space the pool may use; 0 means no cap.
+type WorktreePoolConfig struct {
+	Size      int `json:"size"`
+	MaxDiskMB int `json:"maxDiskMb,omitempty"`
+}
+
+// ProjectDevcontainer runs the project's terminal sessions and recipes in
+// the container described by its devcontainer.json. Config is the file's
+// path relative to the project root; empty looks in the usual places.
+type ProjectDevcontainer struct {
+	Enabled bool   `json:"enabled"`
+	Config  string `json:"config,omitempty"`
+}
+
+// ProtectionPolicy guards a project's files and branches beyond the .git
+// directory, which is always protected. Paths are globs relative to the
+// worktree root, matched like file search's include globs.
+type ProtectionPolicy struct {
+	Paths       []string         `json:"paths,omitempty"`       // may not be changed through Codeburg
+	AgentPaths  []string         `json:"agentPaths,omitempty"`  // agent sessions may not edit
+	NoForcePush []string         `json:"noForcePush,omitempty"` // branch patterns, e.g. "release/*"
+	Guardrails  *AgentGuardrails `json:"guardrails,omitempty"`
+}
+
+// AgentGuardrails bound how much a task's branch may change, against its
+// base branch, before a chat session is held for review. They are checked
+// after every chat turn; zero limits don't apply.
+type AgentGuardrails struct {
+	MaxFilesChanged int      `json:"maxFilesChanged,omitempty"`
+	MaxLinesChanged int      `json:"maxLinesChanged,omitempty"` // added plus deleted
+	ForbiddenPaths  []string `json:"forbiddenPaths,omitempty"`  // globs no change may touch
+}
+
 // SecretFileConfig defines how a secret file should be materialized in task worktrees.
 type SecretFileConfig struct {
 	Path       string  `json:"path"`
@@ -46,44 +128,76 @@ type SecretFileConfig struct {
 }
 
 type Project struct {
-	ID             string             `json:"id"`
-	Name           string             `json:"name"`
-	Path           string             `json:"path"`
-	GitOrigin      *string            `json:"gitOrigin,omitempty"`
-	DefaultBranch  string             `json:"defaultBranch"`
-	SymlinkPaths   []string           `json:"symlinkPaths,omitempty"`
-	SecretFiles    []SecretFileConfig `json:"secretFiles,omitempty"`
-	SetupScript    *string            `json:"setupScript,omitempty"`
-	TeardownScript *string            `json:"teardownScript,omitempty"`
-	Workflow       *ProjectWorkflow   `json:"workflow,omitempty"`
-	Hidden         bool               `json:"hidden"`
-	CreatedAt      time.Time          `json:"createdAt"`
-	UpdatedAt      time.Time          `json:"updatedAt"`
+	ID                string               `json:"id"`
+	Name              string               `json:"name"`
+	Path              string               `json:"path"`
+	GitOrigin         *string              `json:"gitOrigin,omitempty"`
+	DefaultBranch     string               `json:"defaultBranch"`
+	SymlinkPaths      []string             `json:"symlinkPaths,omitempty"`
+	ClonePaths        []string             `json:"clonePaths,omitempty"` // cloned copy-on-write into new worktrees
+	SecretFiles       []SecretFileConfig   `json:"secretFiles,omitempty"`
+	SetupScript       *string              `json:"setupScript,omitempty"`
+	TeardownScript    *string              `json:"teardownScript,omitempty"`
+	Workflow          *ProjectWorkflow     `json:"workflow,omitempty"`
+	AgentIdentity     *AgentGitIdentity    `json:"agentIdentity,omitempty"`
+	AgentInstructions *AgentInstructions   `json:"agentInstructions,omitempty"`
+	RetryPolicy       *RetryPolicy         `json:"retryPolicy,omitempty"`
+	TunnelAuth        *TunnelAuth          `json:"tunnelAuth,omitempty"`
+	Provisioner       *ProjectProvisioner  `json:"provisioner,omitempty"`
+	WorktreePool      *WorktreePoolConfig  `json:"worktreePool,omitempty"`
+	Protection        *ProtectionPolicy    `json:"protection,omitempty"`
+	Devcontainer      *ProjectDevcontainer `json:"devcontainer,omitempty"`
+	BoardColumns      []BoardColumn        `json:"boardColumns,omitempty"` // see Board
+	Hidden            bool                 `json:"hidden"`
+	ArchivedAt        *time.Time           `json:"archivedAt,omitempty"` // archived projects are read-only
+	Version           int                  `json:"version"`              // bumped on every change
+	CreatedAt         time.Time            `json:"createdAt"`
+	UpdatedAt         time.Time            `json:"updatedAt"`
 }
 
 type CreateProjectInput struct {
-	Name           string             `json:"name"`
-	Path           string             `json:"path"`
-	GitOrigin      *string            `json:"gitOrigin,omitempty"`
-	DefaultBranch  *string            `json:"defaultBranch,omitempty"`
-	SymlinkPaths   []string           `json:"symlinkPaths,omitempty"`
-	SecretFiles    []SecretFileConfig `json:"secretFiles,omitempty"`
-	SetupScript    *string            `json:"setupScript,omitempty"`
-	TeardownScript *string            `json:"teardownScript,omitempty"`
-	Workflow       *ProjectWorkflow   `json:"workflow,omitempty"`
+	Name              string               `json:"name"`
+	Path              string               `json:"path"`
+	GitOrigin         *string              `json:"gitOrigin,omitempty"`
+	DefaultBranch     *string              `json:"defaultBranch,omitempty"`
+	SymlinkPaths      []string             `json:"symlinkPaths,omitempty"`
+	ClonePaths        []string             `json:"clonePaths,omitempty"` // cloned copy-on-write into new worktrees
+	SecretFiles       []SecretFileConfig   `json:"secretFiles,omitempty"`
+	SetupScript       *string              `json:"setupScript,omitempty"`
+	TeardownScript    *string              `json:"teardownScript,omitempty"`
+	Workflow          *ProjectWorkflow     `json:"workflow,omitempty"`
+	AgentIdentity     *AgentGitIdentity    `json:"agentIdentity,omitempty"`
+	AgentInstructions *AgentInstructions   `json:"agentInstructions,omitempty"`
+	RetryPolicy       *RetryPolicy         `json:"retryPolicy,omitempty"`
+	TunnelAuth        *TunnelAuth          `json:"tunnelAuth,omitempty"`
+	Provisioner       *ProjectProvisioner  `json:"provisioner,omitempty"`
+	WorktreePool      *WorktreePoolConfig  `json:"worktreePool,omitempty"`
+	Protection        *ProtectionPolicy    `json:"protection,omitempty"`
+	Devcontainer      *ProjectDevcontainer `json:"devcontainer,omitempty"`
 }
 
 type UpdateProjectInput struct {
-	Name           *string            `json:"name,omitempty"`
-	Path           *string            `json:"path,omitempty"`
-	GitOrigin      *string            `json:"gitOrigin,omitempty"`
-	DefaultBranch  *string            `json:"defaultBranch,omitempty"`
-	SymlinkPaths   []string           `json:"symlinkPaths,omitempty"`
-	SecretFiles    []SecretFileConfig `json:"secretFiles,omitempty"`
-	SetupScript    *string            `json:"setupScript,omitempty"`
-	TeardownScript *string            `json:"teardownScript,omitempty"`
-	Workflow       *ProjectWorkflow   `json:"workflow,omitempty"`
-	Hidden         *bool              `json:"hidden,omitempty"`
+	Name              *string              `json:"name,omitempty"`
+	Path              *string              `json:"path,omitempty"`
+	GitOrigin         *string              `json:"gitOrigin,omitempty"`
+	DefaultBranch     *string              `json:"defaultBranch,omitempty"`
+	SymlinkPaths      []string             `json:"symlinkPaths,omitempty"`
+	ClonePaths        []string             `json:"clonePaths,omitempty"` // cloned copy-on-write into new worktrees
+	SecretFiles       []SecretFileConfig   `json:"secretFiles,omitempty"`
+	SetupScript       *string              `json:"setupScript,omitempty"`
+	TeardownScript    *string              `json:"teardownScript,omitempty"`
+	Workflow          *ProjectWorkflow     `json:"workflow,omitempty"`
+	AgentIdentity     *AgentGitIdentity    `json:"agentIdentity,omitempty"`
+	AgentInstructions *AgentInstructions   `json:"agentInstructions,omitempty"`
+	RetryPolicy       *RetryPolicy         `json:"retryPolicy,omitempty"`
+	TunnelAuth        *TunnelAuth          `json:"tunnelAuth,omitempty"`
+	Provisioner       *ProjectProvisioner  `json:"provisioner,omitempty"`
+	WorktreePool      *WorktreePoolConfig  `json:"worktreePool,omitempty"`
+	Protection        *ProtectionPolicy    `json:"protection,omitempty"`
+	Devcontainer      *ProjectDevcontainer `json:"devcontainer,omitempty"`
+	BoardColumns      []BoardColumn        `json:"boardColumns,omitempty"`
+	Hidden            *bool                `json:"hidden,omitempty"`
+	Version           *int                 `json:"version,omitempty"` // the version last read; ErrConflict if it changed
 }
 
 // CreateProject creates a new project
@@ -105,6 +219,16 @@ func (db *DB) CreateProject(input CreateProjectInput) (*Project, error) {
 		symlinkPathsJSON = sql.NullString{String: string(data), Valid: true}
 	}
 
+	// Serialize clone paths as JSON
+	var clonePathsJSON sql.NullString
+	if len(input.ClonePaths) > 0 {
+		data, err := json.Marshal(input.ClonePaths)
+		if err != nil {
+			return nil, fmt.Errorf("marshal clone paths: %w", err)
+		}
+		clonePathsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
 	// Serialize secret files as JSON
 	var secretFilesJSON sql.NullString
 	if len(input.SecretFiles) > 0 {
@@ -125,10 +249,90 @@ func (db *DB) CreateProject(input CreateProjectInput) (*Project, error) {
 		workflowJSON = sql.NullString{String: string(data), Valid: true}
 	}
 
+	// Serialize agent identity as JSON
+	var agentIdentityJSON sql.NullString
+	if input.AgentIdentity != nil {
+		data, err := json.Marshal(input.AgentIdentity)
+		if err != nil {
+			return nil, fmt.Errorf("marshal agent identity: %w", err)
+		}
+		agentIdentityJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize agent instructions as JSON
+	var agentInstructionsJSON sql.NullString
+	if input.AgentInstructions != nil {
+		data, err := json.Marshal(input.AgentInstructions)
+		if err != nil {
+			return nil, fmt.Errorf("marshal agent instructions: %w", err)
+		}
+		agentInstructionsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize retry policy as JSON
+	var retryPolicyJSON sql.NullString
+	if input.RetryPolicy != nil {
+		data, err := json.Marshal(input.RetryPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("marshal retry policy: %w", err)
+		}
+		retryPolicyJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize tunnel auth as JSON
+	var tunnelAuthJSON sql.NullString
+	if input.TunnelAuth != nil {
+		data, err := json.Marshal(input.TunnelAuth)
+		if err != nil {
+			return nil, fmt.Errorf("marshal tunnel auth: %w", err)
+		}
+		tunnelAuthJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize provisioner as JSON
+	var provisionerJSON sql.NullString
+	if input.Provisioner != nil {
+		data, err := json.Marshal(input.Provisioner)
+		if err != nil {
+			return nil, fmt.Errorf("marshal provisioner: %w", err)
+		}
+		provisionerJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize worktree pool as JSON
+	var worktreePoolJSON sql.NullString
+	if input.WorktreePool != nil {
+		data, err := json.Marshal(input.WorktreePool)
+		if err != nil {
+			return nil, fmt.Errorf("marshal worktree pool: %w", err)
+		}
+		worktreePoolJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize protection policy as JSON
+	var protectionJSON sql.NullString
+	if input.Protection != nil {
+		data, err := json.Marshal(input.Protection)
+		if err != nil {
+			return nil, fmt.Errorf("marshal protection: %w", err)
+		}
+		protectionJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	// Serialize devcontainer settings as JSON
+	var devcontainerJSON sql.NullString
+	if input.Devcontainer != nil {
+		data, err := json.Marshal(input.Devcontainer)
+		if err != nil {
+			return nil, fmt.Errorf("marshal devcontainer: %w", err)
+		}
+		devcontainerJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
 	_, err := db.conn.Exec(`
-		INSERT INTO projects (id, name, path, git_origin, default_branch, symlink_paths, secret_files, setup_script, teardown_script, workflow, hidden, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, FALSE, ?, ?)
-	`, id, input.Name, input.Path, NullString(input.GitOrigin), defaultBranch, symlinkPathsJSON, secretFilesJSON, NullString(input.SetupScript), NullString(input.TeardownScript), workflowJSON, now, now)
+		INSERT INTO projects (id, name, path, git_origin, default_branch, symlink_paths, clone_paths, secret_files, setup_script, teardown_script, workflow, agent_identity, agent_instructions, retry_policy, tunnel_auth, provisioner, worktree_pool, protection, devcontainer, hidden, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, FALSE, ?, ?)
+	`, id, input.Name, input.Path, NullString(input.GitOrigin), defaultBranch, symlinkPathsJSON, clonePathsJSON, secretFilesJSON, NullString(input.SetupScript), NullString(input.TeardownScript), workflowJSON, agentIdentityJSON, agentInstructionsJSON, retryPolicyJSON, tunnelAuthJSON, provisionerJSON, worktreePoolJSON, protectionJSON, devcontainerJSON, now, now)
 	if err != nil {
 		return nil, fmt.Errorf("insert project: %w", err)
 	}
@@ -139,7 +343,7 @@ func (db *DB) CreateProject(input CreateProjectInput) (*Project, error) {
 // GetProject retrieves a project by ID
 func (db *DB) GetProject(id string) (*Project, error) {
 	row := db.conn.QueryRow(`
-		SELECT id, name, path, git_origin, default_branch, symlink_paths, secret_files, setup_script, teardown_script, workflow, hidden, created_at, updated_at
+		SELECT id, name, path, git_origin, default_branch, symlink_paths, clone_paths, secret_files, setup_script, teardown_script, workflow, agent_identity, agent_instructions, retry_policy, tunnel_auth, provisioner, worktree_pool, protection, devcontainer, board_columns, hidden, archived_at, version, created_at, updated_at
 		FROM projects WHERE id = ?
 	`, id)
 
@@ -153,7 +357,7 @@ func (db *DB) GetProject(id string) (*Project, error) {
 // ListProjects retrieves all projects
 func (db *DB) ListProjects() ([]*Project, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, name, path, git_origin, default_branch, symlink_paths, secret_files, setup_script, teardown_script, workflow, hidden, created_at, updated_at
+		SELECT id, name, path, git_origin, default_branch, symlink_paths, clone_paths, secret_files, setup_script, teardown_script, workflow, agent_identity, agent_instructions, retry_policy, tunnel_auth, provisioner, worktree_pool, protection, devcontainer, board_columns, hidden, archived_at, version, created_at, updated_at
 		FROM projects ORDER BY name
 	`)
 	if err != nil {
@@ -176,7 +380,7 @@ func (db *DB) ListProjects() ([]*Project, error) {
 // UpdateProject updates a project
 func (db *DB) UpdateProject(id string, input UpdateProjectInput) (*Project, error) {
 	// Build update query dynamically
-	query := "UPDATE projects SET updated_at = ?"
+	query := "UPDATE projects SET version = version + 1, updated_at = ?"
 	args := []any{time.Now()}
 
 	if input.Name != nil {
@@ -203,6 +407,14 @@ func (db *DB) UpdateProject(id string, input UpdateProjectInput) (*Project, erro
 		query += ", symlink_paths = ?"
 		args = append(args, string(data))
 	}
+	if input.ClonePaths != nil {
+		data, err := json.Marshal(input.ClonePaths)
+		if err != nil {
+			return nil, fmt.Errorf("marshal clone paths: %w", err)
+		}
+		query += ", clone_paths = ?"
+		args = append(args, string(data))
+	}
 	if input.SecretFiles != nil {
 		data, err := json.Marshal(input.SecretFiles)
 		if err != nil {
@@ -227,6 +439,78 @@ func (db *DB) UpdateProject(id string, input UpdateProjectInput) (*Project, erro
 		query += ", workflow = ?"
 		args = append(args, string(data))
 	}
+	if input.AgentIdentity != nil {
+		data, err := json.Marshal(input.AgentIdentity)
+		if err != nil {
+			return nil, fmt.Errorf("marshal agent identity: %w", err)
+		}
+		query += ", agent_identity = ?"
+		args = append(args, string(data))
+	}
+	if input.AgentInstructions != nil {
+		data, err := json.Marshal(input.AgentInstructions)
+		if err != nil {
+			return nil, fmt.Errorf("marshal agent instructions: %w", err)
+		}
+		query += ", agent_instructions = ?"
+		args = append(args, string(data))
+	}
+	if input.RetryPolicy != nil {
+		data, err := json.Marshal(input.RetryPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("marshal retry policy: %w", err)
+		}
+		query += ", retry_policy = ?"
+		args = append(args, string(data))
+	}
+	if input.TunnelAuth != nil {
+		data, err := json.Marshal(input.TunnelAuth)
+		if err != nil {
+			return nil, fmt.Errorf("marshal tunnel auth: %w", err)
+		}
+		query += ", tunnel_auth = ?"
+		args = append(args, string(data))
+	}
+	if input.Provisioner != nil {
+		data, err := json.Marshal(input.Provisioner)
+		if err != nil {
+			return nil, fmt.Errorf("marshal provisioner: %w", err)
+		}
+		query += ", provisioner = ?"
+		args = append(args, string(data))
+	}
+	if input.WorktreePool != nil {
+		data, err := json.Marshal(input.WorktreePool)
+		if err != nil {
+			return nil, fmt.Errorf("marshal worktree pool: %w", err)
+		}
+		query += ", worktree_pool = ?"
+		args = append(args, string(data))
+	}
+	if input.Protection != nil {
+		data, err := json.Marshal(input.Protection)
+		if err != nil {
+			return nil, fmt.Errorf("marshal protection: %w", err)
+		}
+		query += ", protection = ?"
+		args = append(args, string(data))
+	}
+	if input.Devcontainer != nil {
+		data, err := json.Marshal(input.Devcontainer)
+		if err != nil {
+			return nil, fmt.Errorf("marshal devcontainer: %w", err)
+		}
+		query += ", devcontainer = ?"
+		args = append(args, string(data))
+	}
+	if input.BoardColumns != nil {
+		data, err := json.Marshal(input.BoardColumns)
+		if err != nil {
+			return nil, fmt.Errorf("marshal board columns: %w", err)
+		}
+		query += ", board_columns = ?"
+		args = append(args, string(data))
+	}
 	if input.Hidden != nil {
 		query += ", hidden = ?"
 		args = append(args, *input.Hidden)
@@ -234,6 +518,10 @@ func (db *DB) UpdateProject(id string, input UpdateProjectInput) (*Project, erro
 
 	query += " WHERE id = ?"
 	args = append(args, id)
+	if input.Version != nil {
+		query += " AND version = ?"
+		args = append(args, *input.Version)
+	}
 
 	result, err := db.conn.Exec(query, args...)
 	if err != nil {
@@ -245,12 +533,35 @@ func (db *DB) UpdateProject(id string, input UpdateProjectInput) (*Project, erro
 		return nil, err
 	}
 	if rows == 0 {
-		return nil, ErrNotFound
+		return nil, missingOrChanged(db.conn, "projects", id)
 	}
 
 	return db.GetProject(id)
 }
 
+// SetProjectArchived archives a project, or restores an archived one.
+// Archiving an archived project keeps its original archive time.
+func (db *DB) SetProjectArchived(id string, archived bool) (*Project, error) {
+	query := `UPDATE projects SET archived_at = COALESCE(archived_at, ?), version = version + 1, updated_at = ? WHERE id = ?`
+	args := []any{time.Now(), time.Now(), id}
+	if !archived {
+		query = `UPDATE projects SET archived_at = NULL, version = version + 1, updated_at = ? WHERE id = ?`
+		args = []any{time.Now(), id}
+	}
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("set project archived: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetProject(id)
+}
+
 // DeleteProject deletes a project
 func (db *DB) DeleteProject(id string) error {
 	result, err := db.conn.Exec("DELETE FROM projects WHERE id = ?", id)
@@ -271,14 +582,16 @@ func (db *DB) DeleteProject(id string) error {
 
 func scanProject(scan scanFunc) (*Project, error) {
 	var p Project
-	var gitOrigin, symlinkPathsJSON, secretFilesJSON, setupScript, teardownScript, workflowJSON sql.NullString
+	var archivedAt sql.NullTime
+	var gitOrigin, symlinkPathsJSON, clonePathsJSON, secretFilesJSON, setupScript, teardownScript, workflowJSON, agentIdentityJSON, agentInstructionsJSON, retryPolicyJSON, tunnelAuthJSON, provisionerJSON, worktreePoolJSON, protectionJSON, devcontainerJSON, boardColumnsJSON sql.NullString
 
-	err := scan(&p.ID, &p.Name, &p.Path, &gitOrigin, &p.DefaultBranch, &symlinkPathsJSON, &secretFilesJSON, &setupScript, &teardownScript, &workflowJSON, &p.Hidden, &p.CreatedAt, &p.UpdatedAt)
+	err := scan(&p.ID, &p.Name, &p.Path, &gitOrigin, &p.DefaultBranch, &symlinkPathsJSON, &clonePathsJSON, &secretFilesJSON, &setupScript, &teardownScript, &workflowJSON, &agentIdentityJSON, &agentInstructionsJSON, &retryPolicyJSON, &tunnelAuthJSON, &provisionerJSON, &worktreePoolJSON, &protectionJSON, &devcontainerJSON, &boardColumnsJSON, &p.Hidden, &archivedAt, &p.Version, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 
 	p.GitOrigin = StringPtr(gitOrigin)
+	p.ArchivedAt = TimePtr(archivedAt)
 	p.SetupScript = StringPtr(setupScript)
 	p.TeardownScript = StringPtr(teardownScript)
 
@@ -289,6 +602,13 @@ func scanProject(scan scanFunc) (*Project, error) {
 		}
 	}
 
+	// Parse clone paths from JSON
+	if clonePathsJSON.Valid && clonePathsJSON.String != "" {
+		if err := json.Unmarshal([]byte(clonePathsJSON.String), &p.ClonePaths); err != nil {
+			return nil, fmt.Errorf("unmarshal clone paths: %w", err)
+		}
+	}
+
 	// Parse secret files from JSON
 	if secretFilesJSON.Valid && secretFilesJSON.String != "" {
 		if err := json.Unmarshal([]byte(secretFilesJSON.String), &p.SecretFiles); err != nil {
@@ -305,5 +625,84 @@ func scanProject(scan scanFunc) (*Project, error) {
 		p.Workflow = &wf
 	}
 
+	// Parse agent identity from JSON
+	if agentIdentityJSON.Valid && agentIdentityJSON.String != "" {
+		var identity AgentGitIdentity
+		if err := json.Unmarshal([]byte(agentIdentityJSON.String), &identity); err != nil {
+			return nil, fmt.Errorf("unmarshal agent identity: %w", err)
+		}
+		p.AgentIdentity = &identity
+	}
+
+	// Parse agent instructions from JSON
+	if agentInstructionsJSON.Valid && agentInstructionsJSON.String != "" {
+		var instructions AgentInstructions
+		if err := json.Unmarshal([]byte(agentInstructionsJSON.String), &instructions); err != nil {
+			return nil, fmt.Errorf("unmarshal agent instructions: %w", err)
+		}
+		p.AgentInstructions = &instructions
+	}
+
+	// Parse retry policy from JSON
+	if retryPolicyJSON.Valid && retryPolicyJSON.String != "" {
+		var policy RetryPolicy
+		if err := json.Unmarshal([]byte(retryPolicyJSON.String), &policy); err != nil {
+			return nil, fmt.Errorf("unmarshal retry policy: %w", err)
+		}
+		p.RetryPolicy = &policy
+	}
+
+	// Parse tunnel auth from JSON
+	if tunnelAuthJSON.Valid && tunnelAuthJSON.String != "" {
+		var auth TunnelAuth
+		if err := json.Unmarshal([]byte(tunnelAuthJSON.String), &auth); err != nil {
+			return nil, fmt.Errorf("unmarshal tunnel auth: %w", err)
+		}
+		p.TunnelAuth = &auth
+	}
+
+	// Parse provisioner from JSON
+	if provisionerJSON.Valid && provisionerJSON.String != "" {
+		var v ProjectProvisioner
+		if err := json.Unmarshal([]byte(provisionerJSON.String), &v); err != nil {
+			return nil, fmt.Errorf("unmarshal provisioner: %w", err)
+		}
+		p.Provisioner = &v
+	}
+
+	// Parse worktree pool from JSON
+	if worktreePoolJSON.Valid && worktreePoolJSON.String != "" {
+		var v WorktreePoolConfig
+		if err := json.Unmarshal([]byte(worktreePoolJSON.String), &v); err != nil {
+			return nil, fmt.Errorf("unmarshal worktree pool: %w", err)
+		}
+		p.WorktreePool = &v
+	}
+
+	// Parse protection policy from JSON
+	if protectionJSON.Valid && protectionJSON.String != "" {
+		var v ProtectionPolicy
+		if err := json.Unmarshal([]byte(protectionJSON.String), &v); err != nil {
+			return nil, fmt.Errorf("unmarshal protection: %w", err)
+		}
+		p.Protection = &v
+	}
+
+	// Parse devcontainer settings from JSON
+	if devcontainerJSON.Valid && devcontainerJSON.String != "" {
+		var v ProjectDevcontainer
+		if err := json.Unmarshal([]byte(devcontainerJSON.String), &v); err != nil {
+			return nil, fmt.Errorf("unmarshal devcontainer: %w", err)
+		}
+		p.Devcontainer = &v
+	}
+
+	// Parse board columns from JSON
+	if boardColumnsJSON.Valid && boardColumnsJSON.String != "" {
+		if err := json.Unmarshal([]byte(boardColumnsJSON.String), &p.BoardColumns); err != nil {
+			return nil, fmt.Errorf("unmarshal board columns: %w", err)
+		}
+	}
+
 	return &p, nil
 }
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ProvisionStatusReady    = "ready"
+	ProvisionStatusFailed   = "failed"
+	ProvisionStatusTornDown = "torn_down"
+)
+
+// TaskProvision records the services a project's provisioner started for a
+// task. Env holds the connection details handed to the task's processes.
+type TaskProvision struct {
+	TaskID    string            `json:"taskId"`
+	Status    string            `json:"status"`
+	Env       map[string]string `json:"env"`
+	Output    string            `json:"output,omitempty"`
+	Error     *string           `json:"error,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+const taskProvisionColumns = `task_id, status, env, output, error, created_at, updated_at`
+
+// SaveTaskProvision creates or replaces a task's provision record.
+func (db *DB) SaveTaskProvision(p *TaskProvision) error {
+	env := p.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal provision env: %w", err)
+	}
+
+	now := time.Now()
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+	_, err = db.conn.Exec(`
+		INSERT INTO task_provisions (`+taskProvisionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(task_id) DO UPDATE SET
+			status = excluded.status, env = excluded.env, output = excluded.output,
+			error = excluded.error, updated_at = excluded.updated_at
+	`, p.TaskID, p.Status, string(data), p.Output, NullString(p.Error), p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save task provision: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) GetTaskProvision(taskID string) (*TaskProvision, error) {
+	row := db.conn.QueryRow(`SELECT `+taskProvisionColumns+` FROM task_provisions WHERE task_id = ?`, taskID)
+	p, err := scanTaskProvision(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
+
+func scanTaskProvision(scan scanFunc) (*TaskProvision, error) {
+	var p TaskProvision
+	var envJSON string
+	var errMsg sql.NullString
+	if err := scan(&p.TaskID, &p.Status, &envJSON, &p.Output, &errMsg, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(envJSON), &p.Env); err != nil {
+		return nil, fmt.Errorf("unmarshal provision env: %w", err)
+	}
+	if errMsg.Valid {
+		p.Error = &errMsg.String
+	}
+	return &p, nil
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// RevokeToken records a login token's hash as revoked until expiresAt,
+// after which the token is refused anyway, and drops revocations that
+// have run out.
+func (db *DB) RevokeToken(tokenHash string, expiresAt time.Time) error {
+	if _, err := db.conn.Exec(`DELETE FROM revoked_tokens WHERE expires_at < ?`, time.Now()); err != nil {
+		return fmt.Errorf("delete expired revocations: %w", err)
+	}
+	_, err := db.conn.Exec(
+		`INSERT OR IGNORE INTO revoked_tokens (token_hash, expires_at, created_at) VALUES (?, ?, ?)`,
+		tokenHash, expiresAt, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	return nil
+}
+
+// TokenRevoked reports whether the login token with the given hash was
+// revoked.
+func (db *DB) TokenRevoked(tokenHash string) (bool, error) {
+	var n int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE token_hash = ?`, tokenHash).Scan(&n); err != nil {
+		return false, fmt.Errorf("check revoked token: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,238 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ViewFilters is the filter and sort preset stored with a saved view.
+type ViewFilters struct {
+	ProjectID  *string      `json:"projectId,omitempty"`
+	Statuses   []TaskStatus `json:"statuses,omitempty"`
+	Priorities []string     `json:"priorities,omitempty"`
+	Labels     []string     `json:"labels,omitempty"` // label names, matched case-insensitively
+	Sort       string       `json:"sort,omitempty"`   // see TaskSort* constants
+}
+
+// TaskFilter converts the view filters into a task list filter.
+func (f ViewFilters) TaskFilter() TaskFilter {
+	return TaskFilter{
+		ProjectID:  f.ProjectID,
+		Statuses:   f.Statuses,
+		Priorities: f.Priorities,
+		Labels:     f.Labels,
+		Sort:       f.Sort,
+	}
+}
+
+type SavedView struct {
+	ID        string      `json:"id"`
+	UserID    string      `json:"-"`
+	Name      string      `json:"name"`
+	Filters   ViewFilters `json:"filters"`
+	IsDefault bool        `json:"isDefault"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+type CreateSavedViewInput struct {
+	Name      string      `json:"name"`
+	Filters   ViewFilters `json:"filters"`
+	IsDefault bool        `json:"isDefault"`
+}
+
+type UpdateSavedViewInput struct {
+	Name      *string      `json:"name,omitempty"`
+	Filters   *ViewFilters `json:"filters,omitempty"`
+	IsDefault *bool        `json:"isDefault,omitempty"`
+}
+
+const savedViewColumns = `id, user_id, name, filters, is_default, created_at, updated_at`
+
+// CreateSavedView stores a new named view for a user. If the view is marked
+// as default, any previous default for that user is cleared.
+func (db *DB) CreateSavedView(userID string, input CreateSavedViewInput) (*SavedView, error) {
+	data, err := json.Marshal(input.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("marshal view filters: %w", err)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if input.IsDefault {
+		if _, err := tx.Exec(`UPDATE saved_views SET is_default = FALSE WHERE user_id = ?`, userID); err != nil {
+			return nil, fmt.Errorf("clear default view: %w", err)
+		}
+	}
+
+	id := NewID()
+	now := time.Now()
+	_, err = tx.Exec(
+		`INSERT INTO saved_views (id, user_id, name, filters, is_default, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, userID, input.Name, string(data), input.IsDefault, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert saved view: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit saved view: %w", err)
+	}
+
+	return db.GetSavedView(userID, id)
+}
+
+// GetSavedView returns a user's view by ID.
+func (db *DB) GetSavedView(userID, id string) (*SavedView, error) {
+	row := db.conn.QueryRow(
+		`SELECT `+savedViewColumns+` FROM saved_views WHERE user_id = ? AND id = ?`,
+		userID, id,
+	)
+	v, err := scanSavedView(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return v, err
+}
+
+// GetSavedViewByName returns a user's view by name (case-insensitive).
+func (db *DB) GetSavedViewByName(userID, name string) (*SavedView, error) {
+	row := db.conn.QueryRow(
+		`SELECT `+savedViewColumns+` FROM saved_views WHERE user_id = ? AND name = ? COLLATE NOCASE`,
+		userID, name,
+	)
+	v, err := scanSavedView(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return v, err
+}
+
+// GetDefaultSavedView returns the user's default view, or ErrNotFound if none is set.
+func (db *DB) GetDefaultSavedView(userID string) (*SavedView, error) {
+	row := db.conn.QueryRow(
+		`SELECT `+savedViewColumns+` FROM saved_views WHERE user_id = ? AND is_default = TRUE`,
+		userID,
+	)
+	v, err := scanSavedView(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return v, err
+}
+
+// ListSavedViews returns all views for a user ordered by name.
+func (db *DB) ListSavedViews(userID string) ([]*SavedView, error) {
+	rows, err := db.conn.Query(
+		`SELECT `+savedViewColumns+` FROM saved_views WHERE user_id = ? ORDER BY name COLLATE NOCASE`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query saved views: %w", err)
+	}
+	defer rows.Close()
+
+	views := make([]*SavedView, 0)
+	for rows.Next() {
+		v, err := scanSavedView(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// UpdateSavedView updates a user's view. Marking a view as default clears
+// the flag on every other view of that user.
+func (db *DB) UpdateSavedView(userID, id string, input UpdateSavedViewInput) (*SavedView, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE saved_views SET updated_at = ?"
+	args := []any{time.Now()}
+
+	if input.Name != nil {
+		query += ", name = ?"
+		args = append(args, *input.Name)
+	}
+	if input.Filters != nil {
+		data, err := json.Marshal(input.Filters)
+		if err != nil {
+			return nil, fmt.Errorf("marshal view filters: %w", err)
+		}
+		query += ", filters = ?"
+		args = append(args, string(data))
+	}
+	if input.IsDefault != nil {
+		if *input.IsDefault {
+			if _, err := tx.Exec(`UPDATE saved_views SET is_default = FALSE WHERE user_id = ? AND id != ?`, userID, id); err != nil {
+				return nil, fmt.Errorf("clear default view: %w", err)
+			}
+		}
+		query += ", is_default = ?"
+		args = append(args, *input.IsDefault)
+	}
+
+	query += " WHERE user_id = ? AND id = ?"
+	args = append(args, userID, id)
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update saved view: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit saved view: %w", err)
+	}
+
+	return db.GetSavedView(userID, id)
+}
+
+// DeleteSavedView removes a user's view.
+func (db *DB) DeleteSavedView(userID, id string) error {
+	result, err := db.conn.Exec(`DELETE FROM saved_views WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return fmt.Errorf("delete saved view: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanSavedView(scan scanFunc) (*SavedView, error) {
+	var v SavedView
+	var filtersJSON string
+	if err := scan(&v.ID, &v.UserID, &v.Name, &filtersJSON, &v.IsDefault, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if filtersJSON != "" {
+		if err := json.Unmarshal([]byte(filtersJSON), &v.Filters); err != nil {
+			return nil, fmt.Errorf("unmarshal view filters: %w", err)
+		}
+	}
+	return &v, nil
+}
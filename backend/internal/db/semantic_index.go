@@ -0,0 +1,141 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SemanticFile is a file in a project's semantic search index, with the
+// size and modification time it had when indexed and the embedding model
+// used, so that only files changed since are embedded again.
+type SemanticFile struct {
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	Model     string
+	IndexedAt time.Time
+}
+
+// SemanticChunk is a span of lines of an indexed file and its embedding.
+type SemanticChunk struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Preview   string
+	Vector    []float32
+}
+
+// ListSemanticFiles returns the files in a project's index, keyed by path.
+func (db *DB) ListSemanticFiles(projectID string) (map[string]SemanticFile, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, size, mod_time, model, indexed_at FROM semantic_files WHERE project_id = ?
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("query semantic files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]SemanticFile)
+	for rows.Next() {
+		var f SemanticFile
+		if err := rows.Scan(&f.Path, &f.Size, &f.ModTime, &f.Model, &f.IndexedAt); err != nil {
+			return nil, err
+		}
+		files[f.Path] = f
+	}
+	return files, rows.Err()
+}
+
+// PutSemanticFile replaces a file's entry in a project's index and its
+// chunks.
+func (db *DB) PutSemanticFile(projectID string, file SemanticFile, chunks []SemanticChunk) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM semantic_files WHERE project_id = ? AND path = ?`, projectID, file.Path); err != nil {
+		return fmt.Errorf("delete semantic file: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO semantic_files (project_id, path, size, mod_time, model, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, projectID, file.Path, file.Size, file.ModTime.UTC(), file.Model, file.IndexedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("insert semantic file: %w", err)
+	}
+	for _, c := range chunks {
+		_, err := tx.Exec(`
+			INSERT INTO semantic_chunks (project_id, path, start_line, end_line, preview, vector)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, projectID, file.Path, c.StartLine, c.EndLine, c.Preview, encodeVector(c.Vector))
+		if err != nil {
+			return fmt.Errorf("insert semantic chunk: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit semantic file: %w", err)
+	}
+	return nil
+}
+
+// DeleteSemanticFile removes a file and its chunks from a project's index.
+func (db *DB) DeleteSemanticFile(projectID, path string) error {
+	if _, err := db.conn.Exec(`DELETE FROM semantic_files WHERE project_id = ? AND path = ?`, projectID, path); err != nil {
+		return fmt.Errorf("delete semantic file: %w", err)
+	}
+	return nil
+}
+
+// DeleteSemanticIndex removes a project's whole index.
+func (db *DB) DeleteSemanticIndex(projectID string) error {
+	if _, err := db.conn.Exec(`DELETE FROM semantic_files WHERE project_id = ?`, projectID); err != nil {
+		return fmt.Errorf("delete semantic index: %w", err)
+	}
+	return nil
+}
+
+// ListSemanticChunks returns every chunk in a project's index.
+func (db *DB) ListSemanticChunks(projectID string) ([]SemanticChunk, error) {
+	rows, err := db.conn.Query(`
+		SELECT path, start_line, end_line, preview, vector FROM semantic_chunks
+		WHERE project_id = ? ORDER BY path, start_line
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("query semantic chunks: %w", err)
+	}
+	defer rows.Close()
+
+	chunks := make([]SemanticChunk, 0)
+	for rows.Next() {
+		var c SemanticChunk
+		var vector []byte
+		if err := rows.Scan(&c.Path, &c.StartLine, &c.EndLine, &c.Preview, &vector); err != nil {
+			return nil, err
+		}
+		c.Vector = decodeVector(vector)
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// encodeVector packs a vector as little-endian float32s.
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SessionTemplate is a reusable way to start a session in a project's
+// tasks: its provider, model and approval mode, and a prompt that may name
+// the task with placeholders such as {{task.title}}.
+type SessionTemplate struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"projectId"`
+	Name        string    `json:"name"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	AutoApprove bool      `json:"autoApprove"`
+	Prompt      string    `json:"prompt"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type CreateSessionTemplateInput struct {
+	ProjectID   string `json:"-"`
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+	AutoApprove *bool  `json:"autoApprove"` // nil = true, as for sessions
+	Prompt      string `json:"prompt"`
+}
+
+type UpdateSessionTemplateInput struct {
+	Name        *string `json:"name,omitempty"`
+	Provider    *string `json:"provider,omitempty"`
+	Model       *string `json:"model,omitempty"`
+	AutoApprove *bool   `json:"autoApprove,omitempty"`
+	Prompt      *string `json:"prompt,omitempty"`
+}
+
+const sessionTemplateColumns = `id, project_id, name, provider, model, auto_approve, prompt, created_at, updated_at`
+
+func (db *DB) CreateSessionTemplate(input CreateSessionTemplateInput) (*SessionTemplate, error) {
+	autoApprove := input.AutoApprove == nil || *input.AutoApprove
+	id := NewID()
+	now := time.Now()
+	_, err := db.conn.Exec(
+		`INSERT INTO session_templates (`+sessionTemplateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, input.ProjectID, input.Name, input.Provider, input.Model, autoApprove, input.Prompt, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert session template: %w", err)
+	}
+	return db.GetSessionTemplate(id)
+}
+
+func (db *DB) GetSessionTemplate(id string) (*SessionTemplate, error) {
+	row := db.conn.QueryRow(`SELECT `+sessionTemplateColumns+` FROM session_templates WHERE id = ?`, id)
+	t, err := scanSessionTemplate(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// ListSessionTemplates returns a project's session templates ordered by
+// name.
+func (db *DB) ListSessionTemplates(projectID string) ([]*SessionTemplate, error) {
+	rows, err := db.conn.Query(
+		`SELECT `+sessionTemplateColumns+` FROM session_templates WHERE project_id = ? ORDER BY name COLLATE NOCASE`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query session templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*SessionTemplate, 0)
+	for rows.Next() {
+		t, err := scanSessionTemplate(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (db *DB) UpdateSessionTemplate(id string, input UpdateSessionTemplateInput) (*SessionTemplate, error) {
+	query := "UPDATE session_templates SET updated_at = ?"
+	args := []any{time.Now()}
+
+	if input.Name != nil {
+		query += ", name = ?"
+		args = append(args, *input.Name)
+	}
+	if input.Provider != nil {
+		query += ", provider = ?"
+		args = append(args, *input.Provider)
+	}
+	if input.Model != nil {
+		query += ", model = ?"
+		args = append(args, *input.Model)
+	}
+	if input.AutoApprove != nil {
+		query += ", auto_approve = ?"
+		args = append(args, *input.AutoApprove)
+	}
+	if input.Prompt != nil {
+		query += ", prompt = ?"
+		args = append(args, *input.Prompt)
+	}
+
+	query += " WHERE id = ?"
+	args = append(args, id)
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update session template: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetSessionTemplate(id)
+}
+
+func (db *DB) DeleteSessionTemplate(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM session_templates WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete session template: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanSessionTemplate(scan scanFunc) (*SessionTemplate, error) {
+	var t SessionTemplate
+	if err := scan(&t.ID, &t.ProjectID, &t.Name, &t.Provider, &t.Model, &t.AutoApprove, &t.Prompt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
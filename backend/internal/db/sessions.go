@@ -2,8 +2,10 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -25,19 +27,31 @@ const (
 // - "chat": structured message stream rendered in the chat UI
 // Reserved for future modes like "headless" (background/no UI) or "api" (direct API).
 type AgentSession struct {
-	ID                string        `json:"id"`
-	TaskID            string        `json:"taskId,omitempty"`
-	ProjectID         string        `json:"projectId"`
-	Provider          string        `json:"provider"`
-	SessionType       string        `json:"sessionType"`
-	ProviderSessionID *string       `json:"providerSessionId,omitempty"`
-	Status            SessionStatus `json:"status"`
-	TmuxWindow        *string       `json:"tmuxWindow,omitempty"`
-	TmuxPane          *string       `json:"tmuxPane,omitempty"`
-	LogFile           *string       `json:"logFile,omitempty"`
-	LastActivityAt    *time.Time    `json:"lastActivityAt,omitempty"`
-	CreatedAt         time.Time     `json:"createdAt"`
-	UpdatedAt         time.Time     `json:"updatedAt"`
+	ID                string         `json:"id"`
+	TaskID            string         `json:"taskId,omitempty"`
+	ProjectID         string         `json:"projectId"`
+	Provider          string         `json:"provider"`
+	SessionType       string         `json:"sessionType"`
+	ProviderSessionID *string        `json:"providerSessionId,omitempty"`
+	Status            SessionStatus  `json:"status"`
+	TmuxWindow        *string        `json:"tmuxWindow,omitempty"`
+	TmuxPane          *string        `json:"tmuxPane,omitempty"`
+	LogFile           *string        `json:"logFile,omitempty"`
+	LastActivityAt    *time.Time     `json:"lastActivityAt,omitempty"`
+	RetryHistory      []SessionRetry `json:"retryHistory,omitempty"`
+	ReadOnly          bool           `json:"readOnly,omitempty"`   // an "ask" session: answers questions, edits nothing
+	Title             string         `json:"title,omitempty"`      // from the first prompt, or set by hand
+	IdleExempt        bool           `json:"idleExempt,omitempty"` // never stopped for inactivity
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+}
+
+// SessionRetry records an automatic retry after a failed turn or run.
+// Retries happen in place, in the same session.
+type SessionRetry struct {
+	Attempt   int       `json:"attempt"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // CreateSessionInput contains fields for creating a new session
@@ -49,6 +63,7 @@ type CreateSessionInput struct {
 	ProviderSessionID *string
 	TmuxWindow        *string
 	TmuxPane          *string
+	ReadOnly          bool
 }
 
 // UpdateSessionInput contains fields for updating a session
@@ -59,6 +74,16 @@ type UpdateSessionInput struct {
 	TmuxPane          *string        `json:"tmuxPane,omitempty"`
 	LogFile           *string        `json:"logFile,omitempty"`
 	LastActivityAt    *time.Time     `json:"lastActivityAt,omitempty"`
+	Title             *string        `json:"title,omitempty"`
+	IdleExempt        *bool          `json:"idleExempt,omitempty"`
+}
+
+// SessionFilter selects sessions. Empty fields match everything.
+type SessionFilter struct {
+	TaskID      string
+	ProjectID   string
+	ProjectOnly bool   // only the project's own sessions, not its tasks'
+	Query       string // matched against titles, and IDs by prefix
 }
 
 // CreateSession creates a new agent session
@@ -77,9 +102,9 @@ func (db *DB) CreateSession(input CreateSessionInput) (*AgentSession, error) {
 	}
 
 	_, err := db.conn.Exec(`
-		INSERT INTO agent_sessions (id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, id, taskID, input.ProjectID, input.Provider, sessionType, NullString(input.ProviderSessionID), SessionStatusIdle, NullString(input.TmuxWindow), NullString(input.TmuxPane), now, now)
+		INSERT INTO agent_sessions (id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, read_only, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, taskID, input.ProjectID, input.Provider, sessionType, NullString(input.ProviderSessionID), SessionStatusIdle, NullString(input.TmuxWindow), NullString(input.TmuxPane), input.ReadOnly, now, now)
 	if err != nil {
 		return nil, fmt.Errorf("insert session: %w", err)
 	}
@@ -90,7 +115,7 @@ func (db *DB) CreateSession(input CreateSessionInput) (*AgentSession, error) {
 // GetSession retrieves a session by ID
 func (db *DB) GetSession(id string) (*AgentSession, error) {
 	row := db.conn.QueryRow(`
-		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, created_at, updated_at
+		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, retry_history, read_only, title, idle_exempt, created_at, updated_at
 		FROM agent_sessions WHERE id = ?
 	`, id)
 
@@ -103,31 +128,26 @@ func (db *DB) GetSession(id string) (*AgentSession, error) {
 
 // ListSessionsByTask retrieves all sessions for a task
 func (db *DB) ListSessionsByTask(taskID string) ([]*AgentSession, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, created_at, updated_at
-		FROM agent_sessions WHERE task_id = ? ORDER BY created_at DESC
-	`, taskID)
-	if err != nil {
-		return nil, fmt.Errorf("query sessions: %w", err)
-	}
-	defer rows.Close()
+	sessions, _, err := db.ListSessionsByTaskPage(taskID, Page{})
+	return sessions, err
+}
 
-	sessions := make([]*AgentSession, 0)
-	for rows.Next() {
-		s, err := scanSession(rows.Scan)
-		if err != nil {
-			return nil, err
-		}
-		sessions = append(sessions, s)
-	}
+// ListSessionsByTaskPage retrieves one page of a task's sessions, newest
+// first, and the cursor for the next.
+func (db *DB) ListSessionsByTaskPage(taskID string, page Page) ([]*AgentSession, string, error) {
+	return db.ListSessionsPage(SessionFilter{TaskID: taskID}, page)
+}
 
-	return sessions, rows.Err()
+// escapeLike escapes the wildcards of a LIKE pattern, with \ as the escape
+// character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 // ListActiveSessions returns all sessions with active statuses (running, waiting_input, idle)
 func (db *DB) ListActiveSessions() ([]*AgentSession, error) {
 	rows, err := db.conn.Query(`
-		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, created_at, updated_at
+		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, retry_history, read_only, title, idle_exempt, created_at, updated_at
 		FROM agent_sessions WHERE status IN (?, ?, ?) ORDER BY created_at
 	`, SessionStatusRunning, SessionStatusWaitingInput, SessionStatusIdle)
 	if err != nil {
@@ -176,6 +196,14 @@ func (db *DB) UpdateSession(id string, input UpdateSessionInput) (*AgentSession,
 		query += ", last_activity_at = ?"
 		args = append(args, *input.LastActivityAt)
 	}
+	if input.Title != nil {
+		query += ", title = ?"
+		args = append(args, *input.Title)
+	}
+	if input.IdleExempt != nil {
+		query += ", idle_exempt = ?"
+		args = append(args, *input.IdleExempt)
+	}
 
 	query += " WHERE id = ?"
 	args = append(args, id)
@@ -196,6 +224,46 @@ func (db *DB) UpdateSession(id string, input UpdateSessionInput) (*AgentSession,
 	return db.GetSession(id)
 }
 
+// ReplaceSessionTitle sets a session's title to title if it is still from,
+// so a generated title doesn't overwrite one set meanwhile, and reports
+// whether it did.
+func (db *DB) ReplaceSessionTitle(id, from, title string) (bool, error) {
+	result, err := db.conn.Exec("UPDATE agent_sessions SET title = ?, updated_at = ? WHERE id = ? AND title = ?", title, time.Now(), id, from)
+	if err != nil {
+		return false, fmt.Errorf("update session title: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// SetSessionRetryHistory replaces the retry history of a session.
+func (db *DB) SetSessionRetryHistory(id string, history []SessionRetry) error {
+	var historyJSON sql.NullString
+	if len(history) > 0 {
+		data, err := json.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("marshal retry history: %w", err)
+		}
+		historyJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	result, err := db.conn.Exec("UPDATE agent_sessions SET retry_history = ?, updated_at = ? WHERE id = ?", historyJSON, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("update retry history: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // DeleteSession deletes a session
 func (db *DB) DeleteSession(id string) error {
 	result, err := db.conn.Exec("DELETE FROM agent_sessions WHERE id = ?", id)
@@ -217,7 +285,7 @@ func (db *DB) DeleteSession(id string) error {
 // GetActiveSessionForTask returns the most recent active session for a task
 func (db *DB) GetActiveSessionForTask(taskID string) (*AgentSession, error) {
 	row := db.conn.QueryRow(`
-		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, created_at, updated_at
+		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, retry_history, read_only, title, idle_exempt, created_at, updated_at
 		FROM agent_sessions
 		WHERE task_id = ? AND status IN (?, ?, ?)
 		ORDER BY created_at DESC LIMIT 1
@@ -232,37 +300,109 @@ func (db *DB) GetActiveSessionForTask(taskID string) (*AgentSession, error) {
 
 // ListSessionsByProject retrieves all sessions for a project (project-level only, no task)
 func (db *DB) ListSessionsByProject(projectID string) ([]*AgentSession, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, created_at, updated_at
-		FROM agent_sessions WHERE project_id = ? AND task_id IS NULL ORDER BY created_at DESC
-	`, projectID)
+	sessions, _, err := db.ListSessionsByProjectPage(projectID, Page{})
+	return sessions, err
+}
+
+// ListSessionsByProjectPage retrieves one page of a project's project-level
+// sessions, newest first, and the cursor for the next.
+func (db *DB) ListSessionsByProjectPage(projectID string, page Page) ([]*AgentSession, string, error) {
+	return db.ListSessionsPage(SessionFilter{ProjectID: projectID, ProjectOnly: true}, page)
+}
+
+// sessionPageOrder pages sessions newest first.
+var sessionPageOrder = pageOrder{table: "agent_sessions", cols: []string{"created_at", "id"}, desc: true}
+
+// ListSessionsPage retrieves one page of the sessions matching filter,
+// newest first, and the cursor for the next.
+func (db *DB) ListSessionsPage(filter SessionFilter, page Page) ([]*AgentSession, string, error) {
+	query := `
+		SELECT id, task_id, project_id, provider, session_type, provider_session_id, status, tmux_window, tmux_pane, log_file, last_activity_at, retry_history, read_only, title, idle_exempt, created_at, updated_at` + sessionPageOrder.selectKeys() + `
+		FROM agent_sessions WHERE 1=1`
+	var args []any
+	if filter.TaskID != "" {
+		query += " AND task_id = ?"
+		args = append(args, filter.TaskID)
+	}
+	if filter.ProjectID != "" {
+		query += " AND project_id = ?"
+		args = append(args, filter.ProjectID)
+	}
+	if filter.ProjectOnly {
+		query += " AND task_id IS NULL"
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		query += ` AND (title LIKE ? ESCAPE '\' OR id LIKE ? ESCAPE '\')`
+		args = append(args, "%"+escapeLike(q)+"%", escapeLike(q)+"%")
+	}
+	if page.Cursor != "" {
+		cond, cursorArgs, err := db.keyset(sessionPageOrder, page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += " AND " + cond
+		args = append(args, cursorArgs...)
+	}
+	query += " ORDER BY created_at DESC, id DESC" + pageLimit(page)
+
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query project sessions: %w", err)
+		return nil, "", fmt.Errorf("query sessions: %w", err)
 	}
 	defer rows.Close()
 
 	sessions := make([]*AgentSession, 0)
+	var cursors []string
 	for rows.Next() {
-		s, err := scanSession(rows.Scan)
+		s, err := scanSession(sessionPageOrder.scanKeys(rows.Scan, &cursors))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		sessions = append(sessions, s)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
 
-	return sessions, rows.Err()
+	sessions, next := trimPage(sessions, cursors, page)
+	return sessions, next, nil
+}
+
+// CountSessionsByProvider counts the sessions created in [since, until),
+// per provider.
+func (db *DB) CountSessionsByProvider(since, until time.Time) (map[string]int, error) {
+	rows, err := db.conn.Query(`
+		SELECT provider, COUNT(*) FROM agent_sessions
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY provider
+	`, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("count sessions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var provider string
+		var n int
+		if err := rows.Scan(&provider, &n); err != nil {
+			return nil, err
+		}
+		counts[provider] = n
+	}
+	return counts, rows.Err()
 }
 
 func scanSession(scan scanFunc) (*AgentSession, error) {
 	var s AgentSession
 	var taskID, projectID sql.NullString
 	var sessionType sql.NullString
-	var providerSessionID, tmuxWindow, tmuxPane, logFile sql.NullString
+	var providerSessionID, tmuxWindow, tmuxPane, logFile, retryHistoryJSON sql.NullString
 	var lastActivityAt sql.NullTime
 
 	err := scan(
 		&s.ID, &taskID, &projectID, &s.Provider, &sessionType, &providerSessionID, &s.Status,
-		&tmuxWindow, &tmuxPane, &logFile, &lastActivityAt, &s.CreatedAt, &s.UpdatedAt,
+		&tmuxWindow, &tmuxPane, &logFile, &lastActivityAt, &retryHistoryJSON, &s.ReadOnly, &s.Title, &s.IdleExempt, &s.CreatedAt, &s.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -285,6 +425,11 @@ func scanSession(scan scanFunc) (*AgentSession, error) {
 	if lastActivityAt.Valid {
 		s.LastActivityAt = &lastActivityAt.Time
 	}
+	if retryHistoryJSON.Valid && retryHistoryJSON.String != "" {
+		if err := json.Unmarshal([]byte(retryHistoryJSON.String), &s.RetryHistory); err != nil {
+			return nil, fmt.Errorf("unmarshal retry history: %w", err)
+		}
+	}
 
 	return &s, nil
 }
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Snippet is text pasted into a task's inbox, such as an error log, a stack
+// trace or a link. It is consumed by the first new session that includes it
+// in its initial prompt.
+type Snippet struct {
+	ID                string     `json:"id"`
+	TaskID            string     `json:"taskId"`
+	Content           string     `json:"content"`
+	ConsumedSessionID *string    `json:"consumedSessionId,omitempty"`
+	ConsumedAt        *time.Time `json:"consumedAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+}
+
+const snippetColumns = `id, task_id, content, consumed_session_id, consumed_at, created_at`
+
+// CreateSnippet adds a snippet to a task's inbox.
+func (db *DB) CreateSnippet(taskID, content string) (*Snippet, error) {
+	s := &Snippet{
+		ID:        NewID(),
+		TaskID:    taskID,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO task_snippets (id, task_id, content, created_at) VALUES (?, ?, ?, ?)`,
+		s.ID, s.TaskID, s.Content, s.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert snippet: %w", err)
+	}
+	return s, nil
+}
+
+// GetSnippet retrieves a snippet by ID.
+func (db *DB) GetSnippet(id string) (*Snippet, error) {
+	row := db.conn.QueryRow(`SELECT `+snippetColumns+` FROM task_snippets WHERE id = ?`, id)
+	s, err := scanSnippet(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return s, err
+}
+
+// ListSnippets returns a task's snippets, newest first. With unconsumedOnly,
+// snippets a session already took are left out.
+func (db *DB) ListSnippets(taskID string, unconsumedOnly bool) ([]*Snippet, error) {
+	query := `SELECT ` + snippetColumns + ` FROM task_snippets WHERE task_id = ?`
+	if unconsumedOnly {
+		query += ` AND consumed_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.conn.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("query snippets: %w", err)
+	}
+	defer rows.Close()
+
+	snippets := make([]*Snippet, 0)
+	for rows.Next() {
+		s, err := scanSnippet(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets, rows.Err()
+}
+
+// ConsumeSnippets marks snippets as taken by a session. Snippets already
+// consumed keep their first session.
+func (db *DB) ConsumeSnippets(ids []string, sessionID string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := []any{sessionID, time.Now()}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	_, err := db.conn.Exec(
+		`UPDATE task_snippets SET consumed_session_id = ?, consumed_at = ?
+		 WHERE consumed_at IS NULL AND id IN (`+placeholders+`)`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("consume snippets: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnippet deletes a snippet.
+func (db *DB) DeleteSnippet(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM task_snippets WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete snippet: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanSnippet(scan scanFunc) (*Snippet, error) {
+	var s Snippet
+	var consumedSessionID sql.NullString
+	var consumedAt sql.NullTime
+	if err := scan(&s.ID, &s.TaskID, &s.Content, &consumedSessionID, &consumedAt, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	if consumedSessionID.Valid {
+		s.ConsumedSessionID = &consumedSessionID.String
+	}
+	if consumedAt.Valid {
+		s.ConsumedAt = &consumedAt.Time
+	}
+	return &s, nil
+}
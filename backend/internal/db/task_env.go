@@ -0,0 +1,52 @@
+package db
+
+import "fmt"
+
+// TaskEnvVar is an environment variable set for a task's sessions, chat
+// turns and recipe runs. Secret values are never shown by the API.
+type TaskEnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+// ListTaskEnv returns a task's environment variables by name.
+func (db *DB) ListTaskEnv(taskID string) ([]TaskEnvVar, error) {
+	rows, err := db.conn.Query(`SELECT name, value, secret FROM task_env_vars WHERE task_id = ? ORDER BY name`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("query task env: %w", err)
+	}
+	defer rows.Close()
+
+	vars := make([]TaskEnvVar, 0)
+	for rows.Next() {
+		var v TaskEnvVar
+		if err := rows.Scan(&v.Name, &v.Value, &v.Secret); err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+	}
+	return vars, rows.Err()
+}
+
+// SetTaskEnv replaces a task's environment variables.
+func (db *DB) SetTaskEnv(taskID string, vars []TaskEnvVar) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM task_env_vars WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("clear task env: %w", err)
+	}
+	for _, v := range vars {
+		if _, err := tx.Exec(
+			`INSERT INTO task_env_vars (task_id, name, value, secret) VALUES (?, ?, ?, ?)`,
+			taskID, v.Name, v.Value, v.Secret,
+		); err != nil {
+			return fmt.Errorf("insert task env var: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,38 @@
+package db
+
+import "fmt"
+
+// ImportedTasks returns the tasks of a project imported from source, by the
+// key of the issue each came from.
+func (db *DB) ImportedTasks(projectID, source string) (map[string]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT external_key, task_id FROM task_imports WHERE project_id = ? AND source = ?
+	`, projectID, source)
+	if err != nil {
+		return nil, fmt.Errorf("query task imports: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make(map[string]string)
+	for rows.Next() {
+		var key, taskID string
+		if err := rows.Scan(&key, &taskID); err != nil {
+			return nil, err
+		}
+		tasks[key] = taskID
+	}
+	return tasks, rows.Err()
+}
+
+// RecordTaskImport notes that a task was made from the issue with key in
+// source, so that importing it again updates the task instead.
+func (db *DB) RecordTaskImport(projectID, source, key, taskID string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO task_imports (project_id, source, external_key, task_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT (project_id, source, external_key) DO UPDATE SET task_id = excluded.task_id, imported_at = CURRENT_TIMESTAMP
+	`, projectID, source, key, taskID)
+	if err != nil {
+		return fmt.Errorf("insert task import: %w", err)
+	}
+	return nil
+}
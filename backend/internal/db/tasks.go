@@ -23,13 +23,17 @@ type Task struct {
 	Title        string     `json:"title"`
 	Description  *string    `json:"description,omitempty"`
 	Status       TaskStatus `json:"status"`
+	Category     TaskStatus `json:"category"` // the built-in status Status counts as; see BoardColumn
 	TaskType     string     `json:"taskType"`
-	Priority     *string    `json:"priority,omitempty"`
+	Priority     *string    `json:"priority,omitempty"` // one of TaskPriorities
+	Estimate     *int       `json:"estimateMinutes,omitempty"`
 	Branch       *string    `json:"branch,omitempty"`
+	BaseBranch   *string    `json:"baseBranch,omitempty"` // branch the worktree is based on; nil means the project's default
 	WorktreePath *string    `json:"worktreePath,omitempty"`
 	PRURL        *string    `json:"prUrl,omitempty"`
 	Pinned       bool       `json:"pinned"`
 	Position     int        `json:"position"`
+	Version      int        `json:"version"` // bumped on every change
 	Labels       []*Label   `json:"labels"`
 	CreatedAt    time.Time  `json:"createdAt"`
 	StartedAt    *time.Time `json:"startedAt,omitempty"`
@@ -43,6 +47,7 @@ type CreateTaskInput struct {
 	Description *string `json:"description,omitempty"`
 	TaskType    *string `json:"taskType,omitempty"`
 	Priority    *string `json:"priority,omitempty"`
+	Estimate    *int    `json:"estimateMinutes,omitempty"`
 	Branch      *string `json:"branch,omitempty"`
 }
 
@@ -51,22 +56,95 @@ type UpdateTaskInput struct {
 	Description  *string     `json:"description,omitempty"`
 	Status       *TaskStatus `json:"status,omitempty"`
 	TaskType     *string     `json:"taskType,omitempty"`
-	Priority     *string     `json:"priority,omitempty"`
+	Priority     *string     `json:"priority,omitempty"`        // "" clears it
+	Estimate     *int        `json:"estimateMinutes,omitempty"` // 0 clears it
 	Branch       *string     `json:"branch,omitempty"`
+	BaseBranch   *string     `json:"baseBranch,omitempty"` // "" clears it
 	WorktreePath *string     `json:"worktreePath,omitempty"`
 	PRURL        *string     `json:"prUrl,omitempty"`
 	Pinned       *bool       `json:"pinned,omitempty"`
 	Position     *int        `json:"position,omitempty"`
-	SetArchived *bool `json:"archived,omitempty"` // true=archive now, false=unarchive; nil=unchanged
+	Version      *int        `json:"version,omitempty"`  // the version last read; ErrConflict if it changed
+	SetArchived  *bool       `json:"archived,omitempty"` // true=archive now, false=unarchive; nil=unchanged
 }
 
 type TaskFilter struct {
-	ProjectID *string
-	Status    *TaskStatus
-	Statuses  []TaskStatus
-	Archived  *bool // nil or false: exclude archived; true: only archived
+	ProjectID  *string
+	Status     *TaskStatus
+	Statuses   []TaskStatus
+	Categories []TaskStatus // statuses counting as any of these, custom columns included
+	Priorities []string
+	Labels     []string // label names; a task matches if it has any of them
+	Archived   *bool    // nil or false: exclude archived; true: only archived
+	Sort       string   // one of the TaskSort* constants; empty means TaskSortPosition
 }
 
+// TaskPriorities are the task priorities, most urgent first. They read as
+// P0 to P3.
+var TaskPriorities = []string{"urgent", "high", "medium", "low"}
+
+// NormalizePriority returns the priority p names, accepting "p0" to "p3"
+// for TaskPriorities, and whether it is one. "" is valid: no priority.
+func NormalizePriority(p string) (string, bool) {
+	p = strings.ToLower(strings.TrimSpace(p))
+	if p == "" {
+		return "", true
+	}
+	for i, name := range TaskPriorities {
+		if p == name || p == fmt.Sprintf("p%d", i) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// priorityRank orders the priority in column: 0 for urgent to 4 for none.
+func priorityRank(column string) string {
+	return `CASE ` + column + ` WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END`
+}
+
+// Task list sort orders.
+const (
+	TaskSortPosition = "position"
+	TaskSortPriority = "priority"
+	TaskSortCreated  = "created"
+	TaskSortTitle    = "title"
+	TaskSortEstimate = "estimate"
+)
+
+// ValidTaskSort reports whether sort is a known task sort order (or empty).
+func ValidTaskSort(sort string) bool {
+	switch sort {
+	case "", TaskSortPosition, TaskSortPriority, TaskSortCreated, TaskSortTitle, TaskSortEstimate:
+		return true
+	}
+	return false
+}
+
+func taskOrderBy(sort string) string {
+	switch sort {
+	case TaskSortPriority:
+		return " ORDER BY " + priorityRank("tasks.priority") + ", position ASC"
+	case TaskSortCreated:
+		return " ORDER BY tasks.created_at DESC"
+	case TaskSortTitle:
+		return " ORDER BY tasks.title COLLATE NOCASE ASC"
+	case TaskSortEstimate:
+		// Smallest first; tasks without an estimate last.
+		return " ORDER BY tasks.estimate_minutes IS NULL, tasks.estimate_minutes ASC, position ASC"
+	default:
+		return " ORDER BY position ASC"
+	}
+}
+
+// taskCategoryColumn selects the category of a task's status, given the
+// task's project joined as projects: the category of the project's column
+// for it, or the status itself.
+const taskCategoryColumn = `COALESCE((
+	SELECT json_extract(c.value, '$.category') FROM json_each(projects.board_columns) c
+	WHERE json_extract(c.value, '$.id') = tasks.status
+), tasks.status)`
+
 // CreateTask creates a new task
 func (db *DB) CreateTask(input CreateTaskInput) (*Task, error) {
 	id := NewID()
@@ -77,13 +155,26 @@ func (db *DB) CreateTask(input CreateTaskInput) (*Task, error) {
 		taskType = *input.TaskType
 	}
 
-	_, err := db.conn.Exec(`
-		INSERT INTO tasks (id, project_id, title, description, task_type, priority, branch, status, position, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, COALESCE((SELECT MAX(position) FROM tasks WHERE status = ?), -1) + 1, ?)
-	`, id, input.ProjectID, input.Title, NullString(input.Description), taskType, NullString(input.Priority), NullString(input.Branch), TaskStatusBacklog, TaskStatusBacklog, now)
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	position, err := makeRoomByPriority(tx, TaskStatusBacklog, NullString(input.Priority).String, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO tasks (id, project_id, title, description, task_type, priority, estimate_minutes, branch, status, position, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, input.ProjectID, input.Title, NullString(input.Description), taskType, NullString(input.Priority), nullPositive(input.Estimate), NullString(input.Branch), TaskStatusBacklog, position, now)
 	if err != nil {
 		return nil, fmt.Errorf("insert task: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
 
 	return db.GetTask(id)
 }
@@ -91,10 +182,12 @@ func (db *DB) CreateTask(input CreateTaskInput) (*Task, error) {
 // GetTask retrieves a task by ID
 func (db *DB) GetTask(id string) (*Task, error) {
 	row := db.conn.QueryRow(`
-		SELECT id, project_id, title, description, status, task_type, priority,
-		       branch, worktree_path, pr_url, pinned, position,
-		       created_at, started_at, completed_at, archived_at
-		FROM tasks WHERE id = ?
+		SELECT tasks.id, tasks.project_id, tasks.title, tasks.description, tasks.status, `+taskCategoryColumn+`, tasks.task_type, tasks.priority, tasks.estimate_minutes,
+		       tasks.branch, tasks.base_branch, tasks.worktree_path, tasks.pr_url, tasks.pinned, tasks.position, tasks.version,
+		       tasks.created_at, tasks.started_at, tasks.completed_at, tasks.archived_at
+		FROM tasks
+		LEFT JOIN projects ON projects.id = tasks.project_id
+		WHERE tasks.id = ?
 	`, id)
 
 	t, err := scanTask(row.Scan)
@@ -104,12 +197,44 @@ func (db *DB) GetTask(id string) (*Task, error) {
 	return t, err
 }
 
+// GetTaskByPRURL retrieves the unarchived task with a pull request URL, the
+// most recently created one if several share it.
+func (db *DB) GetTaskByPRURL(prURL string) (*Task, error) {
+	row := db.conn.QueryRow(`
+		SELECT tasks.id, tasks.project_id, tasks.title, tasks.description, tasks.status, `+taskCategoryColumn+`, tasks.task_type, tasks.priority, tasks.estimate_minutes,
+		       tasks.branch, tasks.base_branch, tasks.worktree_path, tasks.pr_url, tasks.pinned, tasks.position, tasks.version,
+		       tasks.created_at, tasks.started_at, tasks.completed_at, tasks.archived_at
+		FROM tasks
+		LEFT JOIN projects ON projects.id = tasks.project_id
+		WHERE tasks.pr_url = ? AND tasks.archived_at IS NULL
+		ORDER BY tasks.created_at DESC
+		LIMIT 1
+	`, prURL)
+
+	t, err := scanTask(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
 // ListTasks retrieves tasks with optional filtering
 func (db *DB) ListTasks(filter TaskFilter) ([]*Task, error) {
+	tasks, _, err := db.ListTasksPage(filter, Page{})
+	return tasks, err
+}
+
+// taskPageOrder pages tasks newest first.
+var taskPageOrder = pageOrder{table: "tasks", cols: []string{"created_at", "id"}, desc: true}
+
+// ListTasksPage retrieves one page of tasks and the cursor for the next.
+// Paged lists are ordered by creation, newest first, whatever filter.Sort
+// says, so that cursors stay valid while tasks move between columns.
+func (db *DB) ListTasksPage(filter TaskFilter, page Page) ([]*Task, string, error) {
 	query := `
-		SELECT tasks.id, tasks.project_id, tasks.title, tasks.description, tasks.status, tasks.task_type, tasks.priority,
-		       tasks.branch, tasks.worktree_path, tasks.pr_url, tasks.pinned, tasks.position,
-		       tasks.created_at, tasks.started_at, tasks.completed_at, tasks.archived_at
+		SELECT tasks.id, tasks.project_id, tasks.title, tasks.description, tasks.status, ` + taskCategoryColumn + `, tasks.task_type, tasks.priority, tasks.estimate_minutes,
+		       tasks.branch, tasks.base_branch, tasks.worktree_path, tasks.pr_url, tasks.pinned, tasks.position, tasks.version,
+		       tasks.created_at, tasks.started_at, tasks.completed_at, tasks.archived_at` + taskPageOrder.selectKeys() + `
 		FROM tasks
 		JOIN projects ON tasks.project_id = projects.id AND projects.hidden = FALSE
 		WHERE 1=1
@@ -138,25 +263,70 @@ func (db *DB) ListTasks(filter TaskFilter) ([]*Task, error) {
 		}
 		query += " AND status IN (" + strings.Join(placeholders, ", ") + ")"
 	}
+	if len(filter.Categories) > 0 {
+		placeholders := make([]string, len(filter.Categories))
+		for i, c := range filter.Categories {
+			placeholders[i] = "?"
+			args = append(args, c)
+		}
+		query += " AND " + taskCategoryColumn + " IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	if len(filter.Priorities) > 0 {
+		placeholders := make([]string, len(filter.Priorities))
+		for i, p := range filter.Priorities {
+			placeholders[i] = "?"
+			args = append(args, p)
+		}
+		query += " AND tasks.priority IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	if len(filter.Labels) > 0 {
+		placeholders := make([]string, len(filter.Labels))
+		for i, name := range filter.Labels {
+			placeholders[i] = "?"
+			args = append(args, strings.ToLower(name))
+		}
+		query += ` AND tasks.id IN (
+			SELECT tla.task_id FROM task_label_assignments tla
+			JOIN task_labels tl ON tl.id = tla.label_id
+			WHERE LOWER(tl.name) IN (` + strings.Join(placeholders, ", ") + `))`
+	}
 
-	query += " ORDER BY position ASC"
+	if page.Cursor != "" {
+		cond, cursorArgs, err := db.keyset(taskPageOrder, page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += " AND " + cond
+		args = append(args, cursorArgs...)
+	}
+
+	if page.active() {
+		query += " ORDER BY tasks.created_at DESC, tasks.id DESC" + pageLimit(page)
+	} else {
+		query += taskOrderBy(filter.Sort)
+	}
 
 	rows, err := db.conn.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query tasks: %w", err)
+		return nil, "", fmt.Errorf("query tasks: %w", err)
 	}
 	defer rows.Close()
 
 	tasks := make([]*Task, 0)
+	var cursors []string
 	for rows.Next() {
-		t, err := scanTask(rows.Scan)
+		t, err := scanTask(taskPageOrder.scanKeys(rows.Scan, &cursors))
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		tasks = append(tasks, t)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
 
-	return tasks, rows.Err()
+	tasks, next := trimPage(tasks, cursors, page)
+	return tasks, next, nil
 }
 
 // UpdateTask updates a task
@@ -167,6 +337,20 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 		return nil, err
 	}
 
+	// Status timestamps go by category, so that custom columns count as the
+	// built-in one they map to.
+	var category TaskStatus
+	if input.Status != nil {
+		category = *input.Status
+		project, err := db.GetProject(current.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if c := project.StatusCategory(category); c != "" {
+			category = c
+		}
+	}
+
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("begin transaction: %w", err)
@@ -175,6 +359,14 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 
 	statusChanging := input.Status != nil && *input.Status != current.Status
 	positionChanging := input.Position != nil && *input.Position != current.Position
+	priority := NullString(current.Priority).String
+	if input.Priority != nil {
+		priority = *input.Priority
+	}
+	priorityChanging := priority != NullString(current.Priority).String
+	// Without a position to go to, a task moved or reprioritized is placed
+	// after the tasks of its priority and above.
+	byPriority := input.Position == nil && (statusChanging || priorityChanging)
 
 	// Handle position shifting
 	if statusChanging {
@@ -215,9 +407,17 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 		if err != nil {
 			return nil, fmt.Errorf("shift positions: %w", err)
 		}
+	} else if byPriority {
+		_, err = tx.Exec(
+			"UPDATE tasks SET position = position - 1 WHERE status = ? AND position > ? AND id != ?",
+			current.Status, current.Position, id,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("close gap: %w", err)
+		}
 	}
 
-	query := "UPDATE tasks SET id = id" // No-op to start the SET clause
+	query := "UPDATE tasks SET version = version + 1"
 	args := []any{}
 
 	if input.Title != nil {
@@ -234,7 +434,15 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 	}
 	if input.Priority != nil {
 		query += ", priority = ?"
-		args = append(args, NullString(input.Priority))
+		if *input.Priority == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *input.Priority)
+		}
+	}
+	if input.Estimate != nil {
+		query += ", estimate_minutes = ?"
+		args = append(args, nullPositive(input.Estimate))
 	}
 	if input.Status != nil {
 		query += ", status = ?"
@@ -242,11 +450,11 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 
 		// Handle status transition timestamps
 		now := time.Now()
-		if *input.Status == TaskStatusInProgress && current.Status == TaskStatusBacklog {
+		if category == TaskStatusInProgress && current.Category == TaskStatusBacklog {
 			query += ", started_at = ?"
 			args = append(args, now)
 		}
-		if *input.Status == TaskStatusDone && current.Status != TaskStatusDone {
+		if category == TaskStatusDone && current.Category != TaskStatusDone {
 			query += ", completed_at = ?"
 			args = append(args, now)
 		}
@@ -255,6 +463,14 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 		query += ", branch = ?"
 		args = append(args, *input.Branch)
 	}
+	if input.BaseBranch != nil {
+		query += ", base_branch = ?"
+		if *input.BaseBranch == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *input.BaseBranch)
+		}
+	}
 	if input.WorktreePath != nil {
 		query += ", worktree_path = ?"
 		args = append(args, *input.WorktreePath)
@@ -280,14 +496,25 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 	if input.Position != nil {
 		query += ", position = ?"
 		args = append(args, *input.Position)
-	} else if statusChanging {
-		// Append to end of target column
-		query += ", position = COALESCE((SELECT MAX(position) FROM tasks WHERE status = ? AND id != ?), -1) + 1"
-		args = append(args, *input.Status, id)
+	} else if byPriority {
+		status := current.Status
+		if input.Status != nil {
+			status = *input.Status
+		}
+		position, err := makeRoomByPriority(tx, status, priority, id)
+		if err != nil {
+			return nil, err
+		}
+		query += ", position = ?"
+		args = append(args, position)
 	}
 
 	query += " WHERE id = ?"
 	args = append(args, id)
+	if input.Version != nil {
+		query += " AND version = ?"
+		args = append(args, *input.Version)
+	}
 
 	result, err := tx.Exec(query, args...)
 	if err != nil {
@@ -299,7 +526,7 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 		return nil, err
 	}
 	if rows == 0 {
-		return nil, ErrNotFound
+		return nil, missingOrChanged(tx, "tasks", id)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -309,6 +536,133 @@ func (db *DB) UpdateTask(id string, input UpdateTaskInput) (*Task, error) {
 	return db.GetTask(id)
 }
 
+// ReorderTask moves a task to index in its status column, counting the
+// column's unarchived tasks of the task's project, or of all visible
+// projects with allProjects, and renumbers the column's positions from 0 so
+// that gaps and duplicates left by concurrent moves are gone. The task must
+// already be in status and, when version is set, still at that version;
+// otherwise it returns ErrConflict. Only the task's version is bumped; the
+// tasks renumbered around it keep theirs.
+func (db *DB) ReorderTask(id string, status TaskStatus, index int, allProjects bool, version *int) (*Task, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Writing first takes the write lock before anything is read.
+	query := "UPDATE tasks SET version = version + 1 WHERE id = ? AND status = ?"
+	args := []any{id, status}
+	if version != nil {
+		query += " AND version = ?"
+		args = append(args, *version)
+	}
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update task: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, missingOrChanged(tx, "tasks", id)
+	}
+
+	var projectID string
+	if err := tx.QueryRow("SELECT project_id FROM tasks WHERE id = ?", id).Scan(&projectID); err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	rows, err := tx.Query(`
+		SELECT tasks.id, tasks.position, tasks.archived_at IS NULL AND COALESCE(projects.hidden, FALSE) = FALSE
+		       AND (? OR tasks.project_id = ?)
+		FROM tasks
+		LEFT JOIN projects ON projects.id = tasks.project_id
+		WHERE tasks.status = ? AND tasks.id != ?
+		ORDER BY tasks.position ASC, tasks.created_at ASC
+	`, allProjects, projectID, status, id)
+	if err != nil {
+		return nil, fmt.Errorf("list column: %w", err)
+	}
+	type slot struct {
+		id       string
+		position int
+	}
+	var column []slot
+	at, seen := -1, 0 // where the task goes in column, and visible tasks passed
+	for rows.Next() {
+		var s slot
+		var position sql.NullInt64
+		var visible bool
+		if err := rows.Scan(&s.id, &position, &visible); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.position = int(position.Int64)
+		if visible {
+			if seen == index {
+				at = len(column)
+			}
+			seen++
+		}
+		column = append(column, s)
+		if visible && seen <= index {
+			// Past index, the task goes after the last visible one.
+			at = len(column)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if at < 0 {
+		at = len(column)
+	}
+	column = append(column[:at], append([]slot{{id: id, position: -1}}, column[at:]...)...)
+
+	for i, s := range column {
+		if s.position == i {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE tasks SET position = ? WHERE id = ?", i, s.id); err != nil {
+			return nil, fmt.Errorf("renumber column: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return db.GetTask(id)
+}
+
+// makeRoomByPriority returns the position in the status column after the
+// tasks of priority and above, other than the task id, and shifts the tasks
+// below it down to make room.
+func makeRoomByPriority(tx *sql.Tx, status TaskStatus, priority, id string) (int, error) {
+	var position int
+	err := tx.QueryRow(`
+		SELECT COALESCE(MAX(position), -1) + 1 FROM tasks
+		WHERE status = ? AND id != ? AND `+priorityRank("priority")+` <= `+priorityRank("?"),
+		status, id, priority,
+	).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("find position: %w", err)
+	}
+	_, err = tx.Exec(
+		"UPDATE tasks SET position = position + 1 WHERE status = ? AND position >= ? AND id != ?",
+		status, position, id,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("make room: %w", err)
+	}
+	return position, nil
+}
+
+// nullPositive stores n, or NULL when it is nil or not positive.
+func nullPositive(n *int) sql.NullInt64 {
+	if n == nil || *n <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*n), Valid: true}
+}
+
 // DeleteTask deletes a task
 func (db *DB) DeleteTask(id string) error {
 	result, err := db.conn.Exec("DELETE FROM tasks WHERE id = ?", id)
@@ -329,13 +683,13 @@ func (db *DB) DeleteTask(id string) error {
 
 func scanTask(scan scanFunc) (*Task, error) {
 	var t Task
-	var description, taskType, priority, branch, worktreePath, prURL sql.NullString
-	var position sql.NullInt64
+	var description, taskType, priority, branch, baseBranch, worktreePath, prURL sql.NullString
+	var estimate, position sql.NullInt64
 	var startedAt, completedAt, archivedAt sql.NullTime
 
 	err := scan(
-		&t.ID, &t.ProjectID, &t.Title, &description, &t.Status, &taskType, &priority,
-		&branch, &worktreePath, &prURL, &t.Pinned, &position,
+		&t.ID, &t.ProjectID, &t.Title, &description, &t.Status, &t.Category, &taskType, &priority, &estimate,
+		&branch, &baseBranch, &worktreePath, &prURL, &t.Pinned, &position, &t.Version,
 		&t.CreatedAt, &startedAt, &completedAt, &archivedAt,
 	)
 	if err != nil {
@@ -348,7 +702,12 @@ func scanTask(scan scanFunc) (*Task, error) {
 		t.TaskType = taskType.String
 	}
 	t.Priority = StringPtr(priority)
+	if estimate.Valid {
+		e := int(estimate.Int64)
+		t.Estimate = &e
+	}
 	t.Branch = StringPtr(branch)
+	t.BaseBranch = StringPtr(baseBranch)
 	t.WorktreePath = StringPtr(worktreePath)
 	t.PRURL = StringPtr(prURL)
 	if position.Valid {
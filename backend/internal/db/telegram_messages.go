@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// telegramMessageRetention bounds how long message→session links are kept.
+const telegramMessageRetention = 7 * 24 * time.Hour
+
+// RecordTelegramMessage links a sent Telegram message to a session and prunes
+// links older than the retention period.
+func (db *DB) RecordTelegramMessage(chatID, messageID int64, sessionID string) error {
+	now := time.Now()
+	if _, err := db.conn.Exec(
+		`INSERT OR REPLACE INTO telegram_messages (chat_id, message_id, session_id, created_at) VALUES (?, ?, ?, ?)`,
+		chatID, messageID, sessionID, now,
+	); err != nil {
+		return fmt.Errorf("insert telegram message: %w", err)
+	}
+	if _, err := db.conn.Exec(
+		`DELETE FROM telegram_messages WHERE created_at < ?`, now.Add(-telegramMessageRetention),
+	); err != nil {
+		return fmt.Errorf("prune telegram messages: %w", err)
+	}
+	return nil
+}
+
+// GetTelegramMessageSession returns the session a Telegram message was sent about.
+func (db *DB) GetTelegramMessageSession(chatID, messageID int64) (string, error) {
+	var sessionID string
+	err := db.conn.QueryRow(
+		`SELECT session_id FROM telegram_messages WHERE chat_id = ? AND message_id = ?`,
+		chatID, messageID,
+	).Scan(&sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("get telegram message: %w", err)
+	}
+	return sessionID, nil
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeEntry is a span of time spent on a project, and on a task unless
+// TaskID is empty: a session running, or, with no SessionID, a manual
+// entry. EndedAt is nil while the session still runs.
+type TimeEntry struct {
+	ID        string     `json:"id"`
+	ProjectID string     `json:"projectId"`
+	TaskID    string     `json:"taskId,omitempty"`
+	SessionID *string    `json:"sessionId,omitempty"`
+	Provider  string     `json:"provider,omitempty"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Note      string     `json:"note,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Manual reports whether the entry was added by hand.
+func (e *TimeEntry) Manual() bool {
+	return e.SessionID == nil
+}
+
+// DurationWithin is how much of the entry falls in [since, until). An entry
+// still open runs until now; zero since or until leave that side open.
+func (e *TimeEntry) DurationWithin(since, until, now time.Time) time.Duration {
+	start, end := e.StartedAt, now
+	if e.EndedAt != nil {
+		end = *e.EndedAt
+	}
+	if !since.IsZero() && start.Before(since) {
+		start = since
+	}
+	if !until.IsZero() && end.After(until) {
+		end = until
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// CreateTimeEntryInput describes a manual time entry.
+type CreateTimeEntryInput struct {
+	ProjectID string
+	TaskID    string
+	StartedAt time.Time
+	Duration  time.Duration
+	Note      string
+}
+
+// TimeEntryFilter selects time entries overlapping [Since, Until). Empty
+// fields match everything.
+type TimeEntryFilter struct {
+	ProjectID string
+	TaskID    string
+	Since     time.Time
+	Until     time.Time
+}
+
+const timeEntryColumns = `id, project_id, task_id, session_id, provider, started_at, ended_at, note, created_at`
+
+// StartSessionTime opens a time entry for a session that started running,
+// unless one is open already.
+func (db *DB) StartSessionTime(sessionID, projectID, taskID, provider string, at time.Time) error {
+	var task any = taskID
+	if taskID == "" {
+		task = nil
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO time_entries (id, project_id, task_id, session_id, provider, started_at, created_at)
+		SELECT ?, ?, ?, ?, ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM time_entries WHERE session_id = ? AND ended_at IS NULL)
+	`, NewID(), projectID, task, sessionID, provider, at.UTC(), time.Now().UTC(), sessionID)
+	if err != nil {
+		return fmt.Errorf("start session time: %w", err)
+	}
+	return nil
+}
+
+// StopSessionTime closes a session's open time entry at at.
+func (db *DB) StopSessionTime(sessionID string, at time.Time) error {
+	_, err := db.conn.Exec(`
+		UPDATE time_entries SET ended_at = ? WHERE session_id = ? AND ended_at IS NULL
+	`, at.UTC(), sessionID)
+	if err != nil {
+		return fmt.Errorf("stop session time: %w", err)
+	}
+	return nil
+}
+
+// CreateTimeEntry adds a manual time entry.
+func (db *DB) CreateTimeEntry(input CreateTimeEntryInput) (*TimeEntry, error) {
+	id := NewID()
+	started := input.StartedAt.UTC()
+	ended := started.Add(input.Duration)
+	var task any = input.TaskID
+	if input.TaskID == "" {
+		task = nil
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO time_entries (id, project_id, task_id, started_at, ended_at, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, id, input.ProjectID, task, started, ended, input.Note, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("insert time entry: %w", err)
+	}
+	return db.GetTimeEntry(id)
+}
+
+// GetTimeEntry retrieves a time entry by ID.
+func (db *DB) GetTimeEntry(id string) (*TimeEntry, error) {
+	row := db.conn.QueryRow(`SELECT `+timeEntryColumns+` FROM time_entries WHERE id = ?`, id)
+	e, err := scanTimeEntry(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// ListTimeEntries returns the entries matching filter, oldest first.
+func (db *DB) ListTimeEntries(filter TimeEntryFilter) ([]*TimeEntry, error) {
+	query := `SELECT ` + timeEntryColumns + ` FROM time_entries WHERE 1 = 1`
+	var args []any
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.TaskID != "" {
+		query += ` AND task_id = ?`
+		args = append(args, filter.TaskID)
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND started_at < ?`
+		args = append(args, filter.Until.UTC())
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND (ended_at IS NULL OR ended_at > ?)`
+		args = append(args, filter.Since.UTC())
+	}
+	query += ` ORDER BY started_at, id`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query time entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*TimeEntry, 0)
+	for rows.Next() {
+		e, err := scanTimeEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteTimeEntry deletes a time entry.
+func (db *DB) DeleteTimeEntry(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM time_entries WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete time entry: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanTimeEntry(scan scanFunc) (*TimeEntry, error) {
+	var e TimeEntry
+	var taskID, sessionID sql.NullString
+	var endedAt sql.NullTime
+	if err := scan(&e.ID, &e.ProjectID, &taskID, &sessionID, &e.Provider, &e.StartedAt, &endedAt, &e.Note, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.TaskID = taskID.String
+	e.SessionID = StringPtr(sessionID)
+	e.StartedAt = e.StartedAt.UTC()
+	if endedAt.Valid {
+		ended := endedAt.Time.UTC()
+		e.EndedAt = &ended
+	}
+	return &e, nil
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UsageRecord is what one chat turn consumed.
+type UsageRecord struct {
+	SessionID    string    `json:"sessionId"`
+	ProjectID    string    `json:"projectId"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model,omitempty"`
+	InputTokens  int64     `json:"inputTokens"`
+	OutputTokens int64     `json:"outputTokens"`
+	CostUSD      float64   `json:"costUsd"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// UsageTotals sums usage records.
+type UsageTotals struct {
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	CostUSD      float64 `json:"costUsd"`
+}
+
+// Tokens is the number of tokens in and out.
+func (u UsageTotals) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens
+}
+
+// UsageFilter selects usage records. Empty fields match everything; Until
+// is exclusive.
+type UsageFilter struct {
+	ProjectID string
+	Provider  string
+	Since     time.Time
+	Until     time.Time
+}
+
+// RecordUsage stores a usage record, stamped now unless CreatedAt is set.
+func (db *DB) RecordUsage(r UsageRecord) error {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO usage_records (session_id, project_id, provider, model, input_tokens, output_tokens, cost_usd, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.SessionID, r.ProjectID, r.Provider, r.Model, r.InputTokens, r.OutputTokens, r.CostUSD, r.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("record usage: %w", err)
+	}
+	return nil
+}
+
+// SumUsage adds up the usage matching filter.
+func (db *DB) SumUsage(filter UsageFilter) (UsageTotals, error) {
+	query := `SELECT COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost_usd), 0)
+		FROM usage_records WHERE 1 = 1`
+	var args []any
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.Provider != "" {
+		query += ` AND provider = ?`
+		args = append(args, filter.Provider)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND created_at < ?`
+		args = append(args, filter.Until.UTC())
+	}
+
+	var totals UsageTotals
+	if err := db.conn.QueryRow(query, args...).Scan(&totals.InputTokens, &totals.OutputTokens, &totals.CostUSD); err != nil {
+		return UsageTotals{}, fmt.Errorf("sum usage: %w", err)
+	}
+	return totals, nil
+}
+
+// MarkBudgetAlert records that a budget crossed threshold percent in month,
+// and reports whether that is news.
+func (db *DB) MarkBudgetAlert(budgetKey, month string, threshold int) (bool, error) {
+	result, err := db.conn.Exec(`
+		INSERT OR IGNORE INTO budget_alerts (budget_key, month, threshold) VALUES (?, ?, ?)
+	`, budgetKey, month, threshold)
+	if err != nil {
+		return false, fmt.Errorf("mark budget alert: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// SetBudgetOverride lifts a budget's enforcement in month until expiresAt.
+func (db *DB) SetBudgetOverride(budgetKey, month string, expiresAt time.Time) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO budget_overrides (budget_key, month, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (budget_key, month) DO UPDATE SET expires_at = excluded.expires_at, created_at = CURRENT_TIMESTAMP
+	`, budgetKey, month, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("set budget override: %w", err)
+	}
+	return nil
+}
+
+// GetBudgetOverride returns when a budget's override in month expires. It
+// returns ErrNotFound when there is none.
+func (db *DB) GetBudgetOverride(budgetKey, month string) (time.Time, error) {
+	var expiresAt time.Time
+	err := db.conn.QueryRow(`
+		SELECT expires_at FROM budget_overrides WHERE budget_key = ? AND month = ?
+	`, budgetKey, month).Scan(&expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get budget override: %w", err)
+	}
+	return expiresAt.UTC(), nil
+}
+
+// DeleteBudgetOverride restores a budget's enforcement in month.
+func (db *DB) DeleteBudgetOverride(budgetKey, month string) error {
+	result, err := db.conn.Exec(`DELETE FROM budget_overrides WHERE budget_key = ? AND month = ?`, budgetKey, month)
+	if err != nil {
+		return fmt.Errorf("delete budget override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Webhook posts events to an external URL. Events empty means every event;
+// ProjectID, when set, limits it to that project's events. The secret signs
+// the deliveries and is never returned by the API.
+type Webhook struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	ProjectID *string   `json:"projectId,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type CreateWebhookInput struct {
+	Name      string
+	URL       string
+	Secret    string
+	Events    []string
+	ProjectID *string
+}
+
+type UpdateWebhookInput struct {
+	Name      *string
+	URL       *string
+	Secret    *string
+	Events    *[]string
+	ProjectID *string // "" clears it
+	Enabled   *bool
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // gave up after the last attempt
+)
+
+// WebhookDelivery is an event queued for, or posted to, a webhook.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+const webhookColumns = `id, name, url, secret, events, project_id, enabled, created_at, updated_at`
+
+func (db *DB) CreateWebhook(input CreateWebhookInput) (*Webhook, error) {
+	events, err := json.Marshal(nonNilStrings(input.Events))
+	if err != nil {
+		return nil, fmt.Errorf("marshal events: %w", err)
+	}
+	id := NewID()
+	now := time.Now()
+	_, err = db.conn.Exec(
+		`INSERT INTO webhooks (id, name, url, secret, events, project_id, enabled, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, TRUE, ?, ?)`,
+		id, input.Name, input.URL, input.Secret, string(events), NullString(input.ProjectID), now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook: %w", err)
+	}
+	return db.GetWebhook(id)
+}
+
+func (db *DB) GetWebhook(id string) (*Webhook, error) {
+	row := db.conn.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id)
+	w, err := scanWebhook(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return w, err
+}
+
+// ListWebhooks returns every webhook, oldest first.
+func (db *DB) ListWebhooks() ([]*Webhook, error) {
+	rows, err := db.conn.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *DB) UpdateWebhook(id string, input UpdateWebhookInput) (*Webhook, error) {
+	query := "UPDATE webhooks SET updated_at = ?"
+	args := []any{time.Now()}
+	if input.Name != nil {
+		query += ", name = ?"
+		args = append(args, *input.Name)
+	}
+	if input.URL != nil {
+		query += ", url = ?"
+		args = append(args, *input.URL)
+	}
+	if input.Secret != nil {
+		query += ", secret = ?"
+		args = append(args, *input.Secret)
+	}
+	if input.Events != nil {
+		events, err := json.Marshal(nonNilStrings(*input.Events))
+		if err != nil {
+			return nil, fmt.Errorf("marshal events: %w", err)
+		}
+		query += ", events = ?"
+		args = append(args, string(events))
+	}
+	if input.ProjectID != nil {
+		query += ", project_id = ?"
+		var projectID *string
+		if *input.ProjectID != "" {
+			projectID = input.ProjectID
+		}
+		args = append(args, NullString(projectID))
+	}
+	if input.Enabled != nil {
+		query += ", enabled = ?"
+		args = append(args, *input.Enabled)
+	}
+	query += " WHERE id = ?"
+	args = append(args, id)
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("update webhook: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetWebhook(id)
+}
+
+// DeleteWebhook deletes a webhook and its deliveries.
+func (db *DB) DeleteWebhook(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanWebhook(scan scanFunc) (*Webhook, error) {
+	var w Webhook
+	var events string
+	var projectID sql.NullString
+	if err := scan(&w.ID, &w.Name, &w.URL, &w.Secret, &events, &projectID, &w.Enabled, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+		return nil, fmt.Errorf("unmarshal webhook events: %w", err)
+	}
+	w.Events = nonNilStrings(w.Events)
+	w.ProjectID = StringPtr(projectID)
+	return &w, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, next_attempt_at, response_status, last_error, created_at, delivered_at`
+
+// CreateWebhookDelivery queues payload for the webhook, due now.
+func (db *DB) CreateWebhookDelivery(webhookID, event string, payload []byte) (*WebhookDelivery, error) {
+	id := NewID()
+	now := time.Now()
+	_, err := db.conn.Exec(
+		`INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, next_attempt_at, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, webhookID, event, string(payload), WebhookDeliveryPending, now, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return db.GetWebhookDelivery(id)
+}
+
+func (db *DB) GetWebhookDelivery(id string) (*WebhookDelivery, error) {
+	row := db.conn.QueryRow(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+	d, err := scanWebhookDelivery(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return d, err
+}
+
+// ListWebhookDeliveries returns a webhook's latest deliveries, newest first.
+func (db *DB) ListWebhookDeliveries(webhookID string, limit int) ([]*WebhookDelivery, error) {
+	return db.queryWebhookDeliveries(
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		 WHERE webhook_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`,
+		webhookID, limit,
+	)
+}
+
+// ListDueWebhookDeliveries returns up to limit pending deliveries due by
+// now, oldest first, leaving out those of the webhooks in skip.
+func (db *DB) ListDueWebhookDeliveries(now time.Time, limit int, skip ...string) ([]*WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ?`
+	args := []any{WebhookDeliveryPending, now}
+	if len(skip) > 0 {
+		query += ` AND webhook_id NOT IN (?` + strings.Repeat(", ?", len(skip)-1) + `)`
+		for _, id := range skip {
+			args = append(args, id)
+		}
+	}
+	return db.queryWebhookDeliveries(query+` ORDER BY next_attempt_at, id LIMIT ?`, append(args, limit)...)
+}
+
+func (db *DB) queryWebhookDeliveries(query string, args ...any) ([]*WebhookDelivery, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordWebhookAttempt stores the outcome of a delivery attempt. A nil
+// retryAt with an error gives up; a successful attempt marks it delivered.
+func (db *DB) RecordWebhookAttempt(id string, responseStatus int, attemptErr error, retryAt *time.Time) error {
+	now := time.Now()
+	status, lastError := WebhookDeliveryDelivered, ""
+	var deliveredAt, next any
+	if attemptErr != nil {
+		lastError = attemptErr.Error()
+		status = WebhookDeliveryFailed
+		if retryAt != nil {
+			status, next = WebhookDeliveryPending, *retryAt
+		}
+	} else {
+		deliveredAt = now
+	}
+	_, err := db.conn.Exec(
+		`UPDATE webhook_deliveries
+		 SET status = ?, attempts = attempts + 1, next_attempt_at = ?, response_status = ?, last_error = ?, delivered_at = ?
+		 WHERE id = ?`,
+		status, next, responseStatus, lastError, deliveredAt, id,
+	)
+	if err != nil {
+		return fmt.Errorf("record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// RetryWebhookDelivery queues a delivery again, due now, with a fresh
+// series of attempts.
+func (db *DB) RetryWebhookDelivery(id string) (*WebhookDelivery, error) {
+	result, err := db.conn.Exec(
+		`UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, delivered_at = NULL WHERE id = ?`,
+		WebhookDeliveryPending, time.Now(), id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("retry webhook delivery: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		return nil, ErrNotFound
+	}
+	return db.GetWebhookDelivery(id)
+}
+
+// PruneWebhookDeliveries deletes finished deliveries created before cutoff.
+func (db *DB) PruneWebhookDeliveries(cutoff time.Time) (int64, error) {
+	result, err := db.conn.Exec(
+		`DELETE FROM webhook_deliveries WHERE status != ? AND created_at < ?`,
+		WebhookDeliveryPending, cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("prune webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func scanWebhookDelivery(scan scanFunc) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	var payload string
+	var next, delivered sql.NullTime
+	if err := scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &next, &d.ResponseStatus, &d.LastError, &d.CreatedAt, &delivered); err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	d.NextAttemptAt = TimePtr(next)
+	d.DeliveredAt = TimePtr(delivered)
+	return &d, nil
+}
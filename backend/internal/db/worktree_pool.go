@@ -0,0 +1,144 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	PooledWorktreeCreating = "creating"
+	PooledWorktreeReady    = "ready"
+)
+
+// PooledWorktree is a worktree created ahead of time on a placeholder branch,
+// waiting for a task to claim it.
+type PooledWorktree struct {
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"projectId"`
+	Path       string    `json:"path"`
+	Branch     string    `json:"branch"`
+	BaseBranch string    `json:"baseBranch"`
+	Status     string    `json:"status"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+const pooledWorktreeColumns = `id, project_id, path, branch, base_branch, status, size_bytes, created_at`
+
+// CreatePooledWorktree records a pool entry. Entries start out as creating
+// until MarkPooledWorktreeReady is called.
+func (db *DB) CreatePooledWorktree(w *PooledWorktree) error {
+	if w.ID == "" {
+		w.ID = NewID()
+	}
+	if w.Status == "" {
+		w.Status = PooledWorktreeCreating
+	}
+	w.CreatedAt = time.Now()
+	_, err := db.conn.Exec(`
+		INSERT INTO pooled_worktrees (`+pooledWorktreeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, w.ID, w.ProjectID, w.Path, w.Branch, w.BaseBranch, w.Status, w.SizeBytes, w.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create pooled worktree: %w", err)
+	}
+	return nil
+}
+
+// MarkPooledWorktreeReady makes an entry available to ClaimPooledWorktree.
+func (db *DB) MarkPooledWorktreeReady(id string, sizeBytes int64) error {
+	result, err := db.conn.Exec(`
+		UPDATE pooled_worktrees SET status = ?, size_bytes = ? WHERE id = ?
+	`, PooledWorktreeReady, sizeBytes, id)
+	if err != nil {
+		return fmt.Errorf("mark pooled worktree ready: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListPooledWorktrees returns a project's pool entries, oldest first. An
+// empty projectID lists every project's entries.
+func (db *DB) ListPooledWorktrees(projectID string) ([]*PooledWorktree, error) {
+	query := `SELECT ` + pooledWorktreeColumns + ` FROM pooled_worktrees`
+	var args []any
+	if projectID != "" {
+		query += ` WHERE project_id = ?`
+		args = append(args, projectID)
+	}
+	query += ` ORDER BY created_at ASC`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list pooled worktrees: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*PooledWorktree, 0)
+	for rows.Next() {
+		w, err := scanPooledWorktree(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("scan pooled worktree: %w", err)
+		}
+		entries = append(entries, w)
+	}
+	return entries, rows.Err()
+}
+
+// ClaimPooledWorktree removes and returns the oldest ready entry created from
+// baseBranch. It returns ErrNotFound when the pool has none.
+func (db *DB) ClaimPooledWorktree(projectID, baseBranch string) (*PooledWorktree, error) {
+	for {
+		row := db.conn.QueryRow(`
+			SELECT `+pooledWorktreeColumns+` FROM pooled_worktrees
+			WHERE project_id = ? AND base_branch = ? AND status = ?
+			ORDER BY created_at ASC LIMIT 1
+		`, projectID, baseBranch, PooledWorktreeReady)
+		w, err := scanPooledWorktree(row.Scan)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Another claim may have taken the entry in the meantime.
+		result, err := db.conn.Exec(`DELETE FROM pooled_worktrees WHERE id = ? AND status = ?`, w.ID, PooledWorktreeReady)
+		if err != nil {
+			return nil, fmt.Errorf("claim pooled worktree: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows == 1 {
+			return w, nil
+		}
+	}
+}
+
+func (db *DB) DeletePooledWorktree(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM pooled_worktrees WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete pooled worktree: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanPooledWorktree(scan scanFunc) (*PooledWorktree, error) {
+	var w PooledWorktree
+	if err := scan(&w.ID, &w.ProjectID, &w.Path, &w.Branch, &w.BaseBranch, &w.Status, &w.SizeBytes, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
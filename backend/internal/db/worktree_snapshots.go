@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WorktreeSnapshot is the state of a task's worktree at a point, to roll
+// back to. HeadCommit is the commit the branch was on and StateCommit a
+// commit on top of it holding the files as they were, changes included.
+type WorktreeSnapshot struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"taskId"`
+	Label       string    `json:"label"`
+	Branch      string    `json:"branch"`
+	HeadCommit  string    `json:"headCommit"`
+	StateCommit string    `json:"stateCommit"`
+	Dirty       bool      `json:"dirty"` // whether there were uncommitted changes
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type CreateWorktreeSnapshotInput struct {
+	ID          string // optional; the caller may need it before the insert
+	TaskID      string
+	Label       string
+	Branch      string
+	HeadCommit  string
+	StateCommit string
+	Dirty       bool
+}
+
+const worktreeSnapshotColumns = `id, task_id, label, branch, head_commit, state_commit, dirty, created_at`
+
+// CreateWorktreeSnapshot records a worktree snapshot.
+func (db *DB) CreateWorktreeSnapshot(input CreateWorktreeSnapshotInput) (*WorktreeSnapshot, error) {
+	s := &WorktreeSnapshot{
+		ID:          input.ID,
+		TaskID:      input.TaskID,
+		Label:       input.Label,
+		Branch:      input.Branch,
+		HeadCommit:  input.HeadCommit,
+		StateCommit: input.StateCommit,
+		Dirty:       input.Dirty,
+		CreatedAt:   time.Now(),
+	}
+	if s.ID == "" {
+		s.ID = NewID()
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO worktree_snapshots (`+worktreeSnapshotColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.TaskID, s.Label, s.Branch, s.HeadCommit, s.StateCommit, s.Dirty, s.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert worktree snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// GetWorktreeSnapshot retrieves a worktree snapshot by ID.
+func (db *DB) GetWorktreeSnapshot(id string) (*WorktreeSnapshot, error) {
+	row := db.conn.QueryRow(`SELECT `+worktreeSnapshotColumns+` FROM worktree_snapshots WHERE id = ?`, id)
+	s, err := scanWorktreeSnapshot(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return s, err
+}
+
+// ListWorktreeSnapshots returns a task's worktree snapshots, newest first.
+func (db *DB) ListWorktreeSnapshots(taskID string) ([]*WorktreeSnapshot, error) {
+	rows, err := db.conn.Query(
+		`SELECT `+worktreeSnapshotColumns+` FROM worktree_snapshots WHERE task_id = ? ORDER BY created_at DESC, id DESC`,
+		taskID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query worktree snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*WorktreeSnapshot, 0)
+	for rows.Next() {
+		s, err := scanWorktreeSnapshot(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// DeleteWorktreeSnapshot deletes a worktree snapshot.
+func (db *DB) DeleteWorktreeSnapshot(id string) error {
+	result, err := db.conn.Exec(`DELETE FROM worktree_snapshots WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete worktree snapshot: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanWorktreeSnapshot(scan scanFunc) (*WorktreeSnapshot, error) {
+	var s WorktreeSnapshot
+	if err := scan(&s.ID, &s.TaskID, &s.Label, &s.Branch, &s.HeadCommit, &s.StateCommit, &s.Dirty, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,147 @@
+// Package demo is the sample content `codeburg serve --demo` starts with: a
+// small repository to register as a project, and tasks across the board
+// with the sessions an agent ran on them, scripted for the fake providers.
+package demo
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+	"github.com/miguel-bm/codeburg/internal/fakeprovider"
+)
+
+// ProjectName names the sample project and its directory.
+const ProjectName = "acme-todo"
+
+//go:embed all:repo
+var repoFS embed.FS
+
+// Task is a sample task and the sessions run on it, oldest first.
+type Task struct {
+	Title       string
+	Description string
+	Status      db.TaskStatus
+	Priority    string
+	Sessions    []Session
+}
+
+// Session is a finished chat session: one turn, replayed from a script.
+type Session struct {
+	Provider string
+	Prompt   string
+	Script   fakeprovider.Script
+}
+
+// Tasks are the sample tasks, at least one in every built-in status.
+var Tasks = []Task{
+	{
+		Title:       "Export todos as CSV",
+		Description: "Add `todo export --csv` so lists can be opened in a spreadsheet.",
+		Status:      db.TaskStatusBacklog,
+		Priority:    "low",
+	},
+	{
+		Title:       "Support recurring todos",
+		Description: "A todo added with `--every week` comes back a week after it is done.",
+		Status:      db.TaskStatusBacklog,
+		Priority:    "medium",
+	},
+	{
+		Title:       "Add due dates to todos",
+		Description: "`todo add --due 2025-06-01` sets a due date, and `todo list` shows overdue todos first.",
+		Status:      db.TaskStatusInProgress,
+		Priority:    "high",
+		Sessions: []Session{{
+			Provider: "claude",
+			Prompt:   "Add an optional due date to todos, settable with `todo add --due YYYY-MM-DD`. List overdue todos first.",
+			Script: fakeprovider.Script{
+				{Thinking: "The Todo dataclass needs a due field that old files without it still load."},
+				{Tool: "Read", Input: map[string]any{"file_path": "todo/store.py"}, Output: "@dataclass\nclass Todo:\n    id: int\n    title: str\n    done: bool = False"},
+				{Tool: "Edit", Input: map[string]any{"file_path": "todo/store.py", "old_string": "    done: bool = False", "new_string": "    done: bool = False\n    due: str | None = None"}, Output: "The file todo/store.py has been updated."},
+				{Tool: "Bash", Input: map[string]any{"command": "python -m pytest -q"}, Output: "..                                                   [100%]\n2 passed in 0.02s"},
+				{Text: "Todos now have an optional `due` field, and files written before it still load. Next I'll add the `--due` flag and sort overdue todos first in `todo list`."},
+			},
+		}},
+	},
+	{
+		Title:       "Fix crash on an empty todo file",
+		Description: "`todo list` fails with a JSONDecodeError when ~/.todo.json is empty.",
+		Status:      db.TaskStatusInReview,
+		Priority:    "urgent",
+		Sessions: []Session{
+			{
+				Provider: "codex",
+				Prompt:   "todo list crashes when the todo file exists but is empty. Reproduce it and fix it.",
+				Script: fakeprovider.Script{
+					{Thinking: "json.load raises on an empty file; treat it like a missing one."},
+					{Tool: "Bash", Input: map[string]any{"command": "touch /tmp/empty.json && TODO_FILE=/tmp/empty.json python -m todo list"}, Output: "json.decoder.JSONDecodeError: Expecting value: line 1 column 1 (char 0)", Error: true},
+					{Tool: "Bash", Input: map[string]any{"command": "python -m pytest -q"}, Output: "...                                                  [100%]\n3 passed in 0.03s"},
+					{Text: "`Store.load` now returns no todos for an empty file, as for a missing one, and `test_empty_file_is_empty` covers it."},
+				},
+			},
+			{
+				Provider: "claude",
+				Prompt:   "Review the fix for the empty file crash.",
+				Script: fakeprovider.Script{
+					{Tool: "Bash", Input: map[string]any{"command": "git diff main"}, Output: "-        with self.path.open() as f:\n-            return [Todo(**item) for item in json.load(f)]\n+        text = self.path.read_text()\n+        if not text.strip():\n+            return []\n+        return [Todo(**item) for item in json.loads(text)]"},
+					{Text: "The fix is right and the test covers it. A file holding only whitespace is handled too, which matches what editors leave behind."},
+				},
+			},
+		},
+	},
+	{
+		Title:       "Document the commands in the README",
+		Description: "The README should show add, list and done.",
+		Status:      db.TaskStatusDone,
+		Priority:    "low",
+		Sessions: []Session{{
+			Provider: "claude",
+			Prompt:   "Add a usage example for every command to the README.",
+			Script: fakeprovider.Script{
+				{Tool: "Read", Input: map[string]any{"file_path": "README.md"}, Output: "# acme-todo\n\nA small command-line todo list, kept in a JSON file."},
+				{Tool: "Edit", Input: map[string]any{"file_path": "README.md"}, Output: "The file README.md has been updated."},
+				{Text: "The README now shows `todo add`, `todo list` and `todo done`, and where todos are stored."},
+			},
+		}},
+	},
+}
+
+// WriteRepo writes the sample repository to dir and commits it on main. A
+// repository already there is left as it is.
+func WriteRepo(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return nil
+	}
+	root, err := fs.Sub(repoFS, "repo")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.CopyFS(dir, root); err != nil {
+		return fmt.Errorf("write sample repository: %w", err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "-A"},
+		{"commit", "-q", "-m", "Add the todo store and command"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Acme Developer", "GIT_AUTHOR_EMAIL=dev@acme.example",
+			"GIT_COMMITTER_NAME=Acme Developer", "GIT_COMMITTER_EMAIL=dev@acme.example",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w: %s", args[0], err, out)
+		}
+	}
+	return nil
+}
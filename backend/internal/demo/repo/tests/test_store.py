@@ -0,0 +1,16 @@
+from todo.store import Store
+
+
+def test_add_and_complete(tmp_path):
+    store = Store(tmp_path / "todo.json")
+    first = store.add("Water the plants")
+    store.add("Call the bank")
+    store.complete(first.id)
+
+    todos = store.load()
+    assert [t.title for t in todos] == ["Water the plants", "Call the bank"]
+    assert todos[0].done and not todos[1].done
+
+
+def test_missing_file_is_empty(tmp_path):
+    assert Store(tmp_path / "missing.json").load() == []
@@ -0,0 +1,3 @@
+"""A small command-line todo list."""
+
+__version__ = "0.3.0"
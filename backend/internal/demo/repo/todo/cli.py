@@ -0,0 +1,33 @@
+"""The todo command."""
+
+import argparse
+import sys
+
+from todo.store import Store, default_path
+
+
+def main(argv: list[str] | None = None) -> int:
+    parser = argparse.ArgumentParser(prog="todo")
+    commands = parser.add_subparsers(dest="command", required=True)
+    add = commands.add_parser("add", help="add a todo")
+    add.add_argument("title")
+    commands.add_parser("list", help="list todos")
+    done = commands.add_parser("done", help="mark a todo done")
+    done.add_argument("id", type=int)
+    args = parser.parse_args(argv)
+
+    store = Store(default_path())
+    if args.command == "add":
+        todo = store.add(args.title)
+        print(f"Added {todo.id}")
+    elif args.command == "list":
+        for todo in store.load():
+            mark = "x" if todo.done else " "
+            print(f"[{mark}] {todo.id}  {todo.title}")
+    elif args.command == "done":
+        try:
+            store.complete(args.id)
+        except KeyError:
+            print(f"No todo {args.id}", file=sys.stderr)
+            return 1
+    return 0
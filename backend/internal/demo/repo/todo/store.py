@@ -0,0 +1,47 @@
+"""Todos, kept in a JSON file."""
+
+import json
+import os
+from dataclasses import asdict, dataclass
+from pathlib import Path
+
+
+@dataclass
+class Todo:
+    id: int
+    title: str
+    done: bool = False
+
+
+def default_path() -> Path:
+    return Path(os.environ.get("TODO_FILE", Path.home() / ".todo.json"))
+
+
+class Store:
+    def __init__(self, path: Path):
+        self.path = path
+
+    def load(self) -> list[Todo]:
+        if not self.path.exists():
+            return []
+        with self.path.open() as f:
+            return [Todo(**item) for item in json.load(f)]
+
+    def save(self, todos: list[Todo]) -> None:
+        with self.path.open("w") as f:
+            json.dump([asdict(t) for t in todos], f, indent=2)
+
+    def add(self, title: str) -> Todo:
+        todos = self.load()
+        todo = Todo(id=max((t.id for t in todos), default=0) + 1, title=title)
+        self.save(todos + [todo])
+        return todo
+
+    def complete(self, todo_id: int) -> Todo:
+        todos = self.load()
+        for todo in todos:
+            if todo.id == todo_id:
+                todo.done = True
+                self.save(todos)
+                return todo
+        raise KeyError(todo_id)
@@ -0,0 +1,227 @@
+// Package devcontainer runs a project's development container, as described
+// by its devcontainer.json, so that terminal sessions and recipes can use a
+// toolchain that only exists in there.
+package devcontainer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ConfigPaths are where Find looks for a devcontainer.json, relative to the
+// project root, in order.
+var ConfigPaths = []string{
+	".devcontainer/devcontainer.json",
+	".devcontainer.json",
+}
+
+// ErrNotFound is returned by Find when a project has no devcontainer.json.
+var ErrNotFound = errors.New("no devcontainer.json found")
+
+// Build describes an image built from a Dockerfile. Paths are relative to
+// the devcontainer.json.
+type Build struct {
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	Context    string            `json:"context,omitempty"`
+	Args       map[string]string `json:"args,omitempty"`
+	Target     string            `json:"target,omitempty"`
+}
+
+// Config is the part of devcontainer.json Codeburg understands: an image to
+// pull or build, the environment, the user and extra run arguments. Features
+// and lifecycle commands are not run.
+type Config struct {
+	Name            string            `json:"name,omitempty"`
+	Image           string            `json:"image,omitempty"`
+	Build           *Build            `json:"build,omitempty"`
+	ContainerEnv    map[string]string `json:"containerEnv,omitempty"`
+	RemoteEnv       map[string]string `json:"remoteEnv,omitempty"`
+	WorkspaceFolder string            `json:"workspaceFolder,omitempty"`
+	RemoteUser      string            `json:"remoteUser,omitempty"`
+	ContainerUser   string            `json:"containerUser,omitempty"`
+	RunArgs         []string          `json:"runArgs,omitempty"`
+
+	// Path is the devcontainer.json the config was read from.
+	Path string `json:"path"`
+
+	// Older spellings of build.dockerfile and build.context, and the compose
+	// setup, which is not supported.
+	LegacyDockerfile  string          `json:"dockerFile,omitempty"`
+	LegacyContext     string          `json:"context,omitempty"`
+	DockerComposeFile json.RawMessage `json:"dockerComposeFile,omitempty"`
+}
+
+// Find returns the path of the devcontainer.json in projectPath, or
+// ErrNotFound.
+func Find(projectPath string) (string, error) {
+	for _, rel := range ConfigPaths {
+		p := filepath.Join(projectPath, rel)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Load reads and checks the devcontainer.json at file.
+func Load(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(standardJSON(data), &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(file), err)
+	}
+	cfg.Path = file
+	if cfg.Build == nil && cfg.LegacyDockerfile != "" {
+		cfg.Build = &Build{Dockerfile: cfg.LegacyDockerfile, Context: cfg.LegacyContext}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.DockerComposeFile) > 0 {
+		return errors.New("docker compose devcontainers are not supported")
+	}
+	if c.Image == "" && (c.Build == nil || c.Build.Dockerfile == "") {
+		return errors.New("devcontainer.json needs an image or a build.dockerfile")
+	}
+	if c.WorkspaceFolder != "" && !path.IsAbs(c.WorkspaceFolder) {
+		return fmt.Errorf("workspaceFolder %q must be absolute", c.WorkspaceFolder)
+	}
+	return nil
+}
+
+// workspaceFolder returns where the workspace is mounted in the container:
+// workspaceFolder, or /workspaces/<directory name> as the reference
+// implementation does.
+func (c *Config) workspaceFolder(workspaceDir string) string {
+	if c.WorkspaceFolder != "" {
+		return c.WorkspaceFolder
+	}
+	return "/workspaces/" + filepath.Base(workspaceDir)
+}
+
+// user returns the user commands run as in the container, if set.
+func (c *Config) user() string {
+	if c.RemoteUser != "" {
+		return c.RemoteUser
+	}
+	return c.ContainerUser
+}
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z]+)(?::([^}]*))?\}`)
+
+// substitute expands the ${...} variables devcontainer.json values may use:
+// localEnv and containerEnv lookups (with an optional default after a
+// second colon) and the workspace folders. Unknown variables are left as
+// they are.
+func substitute(s string, localDir, containerDir string, containerEnv func(string) string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := variablePattern.FindStringSubmatch(m)
+		name, arg := parts[1], parts[2]
+		key, fallback, _ := strings.Cut(arg, ":")
+		var value string
+		switch name {
+		case "localEnv":
+			value = os.Getenv(key)
+		case "containerEnv":
+			value = containerEnv(key)
+		case "localWorkspaceFolder":
+			return localDir
+		case "localWorkspaceFolderBasename":
+			return filepath.Base(localDir)
+		case "containerWorkspaceFolder":
+			return containerDir
+		case "containerWorkspaceFolderBasename":
+			return path.Base(containerDir)
+		default:
+			return m
+		}
+		if value == "" {
+			return fallback
+		}
+		return value
+	})
+}
+
+// standardJSON turns the JSON with comments and trailing commas that
+// devcontainer.json allows into plain JSON.
+func standardJSON(data []byte) []byte {
+	return dropTrailingCommas(stripComments(data))
+}
+
+func stripComments(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out.Bytes()
+			}
+			i += end + 3
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+func dropTrailingCommas(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == ',':
+			if rest := bytes.TrimLeft(data[i+1:], " \t\r\n"); len(rest) > 0 && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,169 @@
+package devcontainer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ".devcontainer", "devcontainer.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Find(dir); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	writeConfig(t, dir, `{
+		// The toolchain lives here.
+		"name": "app // not a comment",
+		"dockerFile": "Dockerfile", /* legacy spelling */
+		"containerEnv": {"GOFLAGS": "-mod=mod",},
+		"remoteEnv": {"PATH": "${containerEnv:PATH}:/opt/bin"},
+		"remoteUser": "dev",
+	}`)
+	path, err := Find(dir)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Name != "app // not a comment" || cfg.Build == nil || cfg.Build.Dockerfile != "Dockerfile" || cfg.ContainerEnv["GOFLAGS"] != "-mod=mod" || cfg.user() != "dev" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if got := cfg.workspaceFolder("/repos/app"); got != "/workspaces/app" {
+		t.Errorf("expected the default workspace folder, got %s", got)
+	}
+
+	writeConfig(t, dir, `{"dockerComposeFile": "compose.yml", "service": "app"}`)
+	if _, err := Load(path); err == nil {
+		t.Error("expected compose configs to be refused")
+	}
+	writeConfig(t, dir, `{"name": "nothing to run"}`)
+	if _, err := Load(path); err == nil {
+		t.Error("expected a config without an image to be refused")
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	t.Setenv("DEVCONTAINER_TEST_TOKEN", "abc")
+	env := map[string]string{"PATH": "/usr/bin"}
+	got := substitute("${localEnv:DEVCONTAINER_TEST_TOKEN}|${localEnv:UNSET_VAR:fallback}|${containerEnv:PATH}:/opt|${containerWorkspaceFolderBasename}|${unknown}",
+		"/repos/app", "/workspaces/app", func(k string) string { return env[k] })
+	if want := "abc|fallback|/usr/bin:/opt|app|${unknown}"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	c := &Container{
+		Name:            "codeburg-task",
+		WorkspaceDir:    "/repos/app",
+		WorkspaceFolder: "/workspaces/app",
+		User:            "dev",
+		Env:             []string{"PATH=/opt/bin:/usr/bin", "TOKEN=from-config"},
+	}
+	if got := c.Path("/repos/app/web"); got != "/workspaces/app/web" {
+		t.Errorf("expected a path in the workspace folder, got %s", got)
+	}
+	if got := c.Path("/elsewhere"); got != "/workspaces/app" {
+		t.Errorf("expected paths outside the workspace to map to its folder, got %s", got)
+	}
+
+	cli, args := Runtime{CLI: "podman"}.ExecArgs(c, "/repos/app/web", []string{"TOKEN=secret"}, true, "just", "test")
+	want := []string{"exec", "--interactive", "--tty", "--workdir", "/workspaces/app/web", "--user", "dev",
+		"--env", "PATH=/opt/bin:/usr/bin", "--env", "TOKEN", "codeburg-task", "just", "test"}
+	if cli != "podman" || !slices.Equal(args, want) {
+		t.Errorf("unexpected exec command: %s %v", cli, args)
+	}
+	if strings.Contains(strings.Join(args, " "), "secret") {
+		t.Error("expected forwarded values to stay off the command line")
+	}
+}
+
+// fakeCLI writes a container engine stand-in that logs its arguments and
+// reports the container as missing until it is run.
+func fakeCLI(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	state := filepath.Join(dir, "running")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+case "$1" in
+inspect) [ -f ` + state + ` ] && echo running || exit 1 ;;
+run) touch ` + state + ` ;;
+rm) rm -f ` + state + ` ;;
+exec) echo "PATH=/usr/bin" ;;
+esac
+`
+	cli := filepath.Join(dir, "engine")
+	if err := os.WriteFile(cli, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return cli, log
+}
+
+func TestUp(t *testing.T) {
+	cli, log := fakeCLI(t)
+	dir := t.TempDir()
+	cfg, err := Load(writeConfig(t, dir, `{
+		"build": {"dockerfile": "Dockerfile", "context": "..", "args": {"GO_VERSION": "1.24"}},
+		"remoteEnv": {"PATH": "${containerEnv:PATH}:/go/bin"}
+	}`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	rt := Runtime{CLI: cli}
+	var lines []string
+	opts := UpOptions{Name: "codeburg-t1", Image: "codeburg-dev-p1", WorkspaceDir: dir, Progress: func(s string) { lines = append(lines, s) }}
+	c, err := rt.Up(context.Background(), cfg, opts)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if !slices.Equal(c.Env, []string{"PATH=/usr/bin:/go/bin"}) || c.WorkspaceFolder != "/workspaces/"+filepath.Base(dir) {
+		t.Errorf("unexpected container: %+v", c)
+	}
+	if rt.State(context.Background(), "codeburg-t1") != "running" {
+		t.Error("expected the container running")
+	}
+
+	// A running container is reused.
+	if _, err := rt.Up(context.Background(), cfg, opts); err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	if err := rt.Remove(context.Background(), "codeburg-t1"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	data, _ := os.ReadFile(log)
+	calls := string(data)
+	for _, want := range []string{
+		"build --tag codeburg-dev-p1 --file " + filepath.Join(dir, ".devcontainer", "Dockerfile") + " --build-arg GO_VERSION=1.24 " + dir,
+		"run --detach --name codeburg-t1 --mount type=bind,source=" + dir + ",target=/workspaces/" + filepath.Base(dir),
+		"rm --force codeburg-t1",
+	} {
+		if !strings.Contains(calls, want) {
+			t.Errorf("expected %q in calls:\n%s", want, calls)
+		}
+	}
+	if n := strings.Count(calls, "\nrun "); n != 1 {
+		t.Errorf("expected one run, got %d:\n%s", n, calls)
+	}
+}
@@ -0,0 +1,311 @@
+package devcontainer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Runtime drives a container engine's CLI: docker, or podman, which takes
+// the same arguments.
+type Runtime struct {
+	// CLI is the engine's command; empty uses CODEBURG_CONTAINER_CLI, or
+	// docker.
+	CLI string
+}
+
+func (r Runtime) cli() string {
+	if r.CLI != "" {
+		return r.CLI
+	}
+	if cli := strings.TrimSpace(os.Getenv("CODEBURG_CONTAINER_CLI")); cli != "" {
+		return cli
+	}
+	return "docker"
+}
+
+// UpOptions name the container Up starts and say what it mounts.
+type UpOptions struct {
+	Name string // container name
+	// Image tags the image when the config builds one.
+	Image string
+	// WorkspaceDir is the host directory mounted as the workspace folder.
+	WorkspaceDir string
+	// Mounts are host paths mounted at the same path, such as the git
+	// directory a worktree points to.
+	Mounts []string
+	Labels map[string]string
+	// Progress, if set, is called with each line of pull and build output.
+	Progress func(string)
+}
+
+// Container is a running devcontainer commands can be executed in.
+type Container struct {
+	Name            string `json:"name"`
+	WorkspaceDir    string `json:"workspaceDir"`
+	WorkspaceFolder string `json:"workspaceFolder"`
+	User            string `json:"user,omitempty"`
+	// Env is the config's remoteEnv, resolved.
+	Env []string `json:"-"`
+}
+
+// State returns the state of the container called name, such as "running"
+// or "exited", or "" if there is no such container.
+func (r Runtime) State(ctx context.Context, name string) string {
+	out, err := exec.CommandContext(ctx, r.cli(), "inspect", "--format", "{{.State.Status}}", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Up starts the container for cfg, pulling or building its image first,
+// unless one called opts.Name exists, which is started if stopped and used
+// as is otherwise. Remove the container to apply changes to the config.
+func (r Runtime) Up(ctx context.Context, cfg *Config, opts UpOptions) (*Container, error) {
+	folder := cfg.workspaceFolder(opts.WorkspaceDir)
+	switch r.State(ctx, opts.Name) {
+	case "running":
+	case "":
+		image, err := r.image(ctx, cfg, opts)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.run(ctx, nil, r.runArgs(cfg, opts, folder, image)...); err != nil {
+			return nil, fmt.Errorf("start container: %w", err)
+		}
+	default:
+		if _, err := r.run(ctx, nil, "start", opts.Name); err != nil {
+			return nil, fmt.Errorf("start container: %w", err)
+		}
+	}
+
+	c := &Container{Name: opts.Name, WorkspaceDir: opts.WorkspaceDir, WorkspaceFolder: folder, User: cfg.user()}
+	if len(cfg.RemoteEnv) > 0 {
+		env, err := r.containerEnv(ctx, opts.Name)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range cfg.RemoteEnv {
+			c.Env = append(c.Env, k+"="+substitute(v, opts.WorkspaceDir, folder, func(key string) string { return env[key] }))
+		}
+		sort.Strings(c.Env)
+	}
+	return c, nil
+}
+
+// Remove stops and deletes the container called name, if there is one.
+func (r Runtime) Remove(ctx context.Context, name string) error {
+	if r.State(ctx, name) == "" {
+		return nil
+	}
+	if _, err := r.run(ctx, nil, "rm", "--force", name); err != nil {
+		return fmt.Errorf("remove container: %w", err)
+	}
+	return nil
+}
+
+// image pulls cfg's image if it is not there yet, or builds its Dockerfile
+// as opts.Image.
+func (r Runtime) image(ctx context.Context, cfg *Config, opts UpOptions) (string, error) {
+	if cfg.Image != "" {
+		if _, err := r.run(ctx, nil, "image", "inspect", cfg.Image); err == nil {
+			return cfg.Image, nil
+		}
+		if _, err := r.run(ctx, opts.Progress, "pull", cfg.Image); err != nil {
+			return "", fmt.Errorf("pull image: %w", err)
+		}
+		return cfg.Image, nil
+	}
+
+	dir := filepath.Dir(cfg.Path)
+	args := []string{"build", "--tag", opts.Image, "--file", filepath.Join(dir, cfg.Build.Dockerfile)}
+	for _, k := range sortedKeys(cfg.Build.Args) {
+		args = append(args, "--build-arg", k+"="+cfg.Build.Args[k])
+	}
+	if cfg.Build.Target != "" {
+		args = append(args, "--target", cfg.Build.Target)
+	}
+	args = append(args, filepath.Join(dir, cfg.Build.Context))
+	if _, err := r.run(ctx, opts.Progress, args...); err != nil {
+		return "", fmt.Errorf("build image: %w", err)
+	}
+	return opts.Image, nil
+}
+
+// runArgs starts the container idle, as the reference implementation does,
+// so that sessions and recipes can be executed in it.
+func (r Runtime) runArgs(cfg *Config, opts UpOptions, folder, image string) []string {
+	args := []string{"run", "--detach", "--name", opts.Name,
+		"--mount", "type=bind,source=" + opts.WorkspaceDir + ",target=" + folder,
+		"--workdir", folder,
+	}
+	for _, m := range opts.Mounts {
+		args = append(args, "--mount", "type=bind,source="+m+",target="+m)
+	}
+	for _, k := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", k+"="+opts.Labels[k])
+	}
+	for _, k := range sortedKeys(cfg.ContainerEnv) {
+		args = append(args, "--env", k+"="+substitute(cfg.ContainerEnv[k], opts.WorkspaceDir, folder, func(string) string { return "" }))
+	}
+	if cfg.ContainerUser != "" {
+		args = append(args, "--user", cfg.ContainerUser)
+	}
+	args = append(args, cfg.RunArgs...)
+	return append(args, "--entrypoint", "/bin/sh", image,
+		"-c", "trap 'exit 0' TERM; while sleep 1000 & wait $!; do :; done")
+}
+
+// containerEnv returns the environment of the container's main process, for
+// remoteEnv values referring to it.
+func (r Runtime) containerEnv(ctx context.Context, name string) (map[string]string, error) {
+	out, err := r.run(ctx, nil, "exec", name, "env")
+	if err != nil {
+		return nil, fmt.Errorf("read container environment: %w", err)
+	}
+	env := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			env[k] = v
+		}
+	}
+	return env, nil
+}
+
+// Path maps a host path in the workspace to the container. Paths outside
+// the workspace map to the workspace folder.
+func (c *Container) Path(hostPath string) string {
+	rel, err := filepath.Rel(c.WorkspaceDir, hostPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return c.WorkspaceFolder
+	}
+	return path.Join(c.WorkspaceFolder, filepath.ToSlash(rel))
+}
+
+// ExecArgs returns the engine command and arguments that run command in the
+// container, in the container path of dir. The variables named in env are
+// passed on from the engine's own environment, so that their values stay
+// out of the command line; the caller sets them there. tty allocates a
+// terminal, for interactive sessions.
+func (r Runtime) ExecArgs(c *Container, dir string, env []string, tty bool, command ...string) (string, []string) {
+	args := []string{"exec", "--interactive"}
+	if tty {
+		args = append(args, "--tty")
+	}
+	args = append(args, "--workdir", c.Path(dir))
+	if c.User != "" {
+		args = append(args, "--user", c.User)
+	}
+	names := make(map[string]bool, len(env))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		names[name] = true
+	}
+	for _, entry := range c.Env {
+		if name, _, _ := strings.Cut(entry, "="); !names[name] {
+			args = append(args, "--env", entry)
+		}
+	}
+	seen := make(map[string]bool, len(names))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if !seen[name] {
+			seen[name] = true
+			args = append(args, "--env", name)
+		}
+	}
+	args = append(args, c.Name)
+	return r.cli(), append(args, command...)
+}
+
+// Wrap rewrites cmd to run in the container instead, in the container path
+// of cmd.Dir. env are entries of cmd.Env to pass into the container.
+func (r Runtime) Wrap(cmd *exec.Cmd, c *Container, env []string) {
+	name, args := r.ExecArgs(c, cmd.Dir, env, false, cmd.Args...)
+	cmd.Path = name
+	if resolved, err := exec.LookPath(name); err == nil {
+		cmd.Path = resolved
+	} else {
+		cmd.Err = err
+	}
+	cmd.Args = append([]string{name}, args...)
+}
+
+// run runs the engine CLI and returns its output. With progress set, the
+// output is passed on line by line instead.
+func (r Runtime) run(ctx context.Context, progress func(string), args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, r.cli(), args...)
+	if progress == nil {
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("%s %s: %w: %s", r.cli(), args[0], err, strings.TrimSpace(lastLines(string(out), 5)))
+		}
+		return string(out), nil
+	}
+
+	out := &lineWriter{fn: progress}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err := cmd.Run()
+	out.flush()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", r.cli(), args[0], err, out.last)
+	}
+	return "", nil
+}
+
+// lineWriter calls fn with each complete line written to it.
+type lineWriter struct {
+	fn   func(string)
+	buf  []byte
+	last string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := strings.IndexAny(string(w.buf), "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		w.line(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+}
+
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.line(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) line(s string) {
+	if s = strings.TrimSpace(s); s != "" {
+		w.last = s
+		w.fn(s)
+	}
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+// Package embedding splits source files into the chunks semantic search
+// embeds, and compares the vectors an Embedder gives them.
+package embedding
+
+import (
+	"context"
+	"math"
+	"strings"
+)
+
+// Embedder turns texts into vectors, one per text and in order.
+type Embedder interface {
+	// Model names the embedding model; vectors from different models can't
+	// be compared.
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+const (
+	// ChunkLines is how many lines a chunk spans, and ChunkOverlap how many
+	// of them it shares with the next, so that code split across a
+	// boundary is whole in one of them.
+	ChunkLines   = 40
+	ChunkOverlap = 10
+	// MaxChunkBytes trims chunks of very long lines, such as minified code,
+	// to stay within embedding models' input limits.
+	MaxChunkBytes = 4000
+)
+
+// Chunk is a span of lines of a file, numbered from 1.
+type Chunk struct {
+	StartLine int
+	EndLine   int
+	Text      string
+}
+
+// Split cuts content into overlapping chunks of lines, skipping those with
+// nothing but whitespace.
+func Split(content string) []Chunk {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += ChunkLines - ChunkOverlap {
+		end := min(start+ChunkLines, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			if len(text) > MaxChunkBytes {
+				text = strings.ToValidUTF8(text[:MaxChunkBytes], "")
+			}
+			chunks = append(chunks, Chunk{StartLine: start + 1, EndLine: end, Text: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 when their
+// lengths differ or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,38 @@
+package embedding
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	lines := make([]string, 75)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	chunks := Split(strings.Join(lines, "\n") + "\n")
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %+v", chunks)
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 40 || chunks[1].StartLine != 31 || chunks[2].EndLine != 75 {
+		t.Errorf("unexpected spans %+v", chunks)
+	}
+	if len(Split("\n\n  \n")) != 0 {
+		t.Error("expected no chunks for blank content")
+	}
+	if long := Split(strings.Repeat("x", 2*MaxChunkBytes)); len(long) != 1 || len(long[0].Text) != MaxChunkBytes {
+		t.Errorf("expected one trimmed chunk, got %d", len(long))
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if got := Cosine([]float32{1, 0}, []float32{2, 0}); got != 1 {
+		t.Errorf("parallel vectors: %v", got)
+	}
+	if got := Cosine([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("orthogonal vectors: %v", got)
+	}
+	if got := Cosine([]float32{1}, []float32{1, 0}); got != 0 {
+		t.Errorf("mismatched lengths: %v", got)
+	}
+}
@@ -0,0 +1,209 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts connections and fans published payloads out to
+// subscribed connections, using a protocol-specific serve function.
+type fakeBroker struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	subs []net.Conn
+	auth []string
+}
+
+func newFakeBroker(t *testing.T, serve func(b *fakeBroker, conn net.Conn)) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(b, conn)
+			}()
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) addSub(conn net.Conn) {
+	b.mu.Lock()
+	b.subs = append(b.subs, conn)
+	b.mu.Unlock()
+}
+
+func (b *fakeBroker) fanout(frame func() string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.subs {
+		io.WriteString(conn, frame())
+	}
+	return len(b.subs)
+}
+
+func (b *fakeBroker) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func serveRedis(b *fakeBroker, conn net.Conn) {
+	br := bufio.NewReader(conn)
+	for {
+		reply, err := readRESP(br)
+		if err != nil {
+			return
+		}
+		args, _ := reply.([]any)
+		if len(args) == 0 {
+			return
+		}
+		switch args[0] {
+		case "AUTH":
+			b.mu.Lock()
+			b.auth = append(b.auth, fmt.Sprint(args[1:]...))
+			b.mu.Unlock()
+			io.WriteString(conn, "+OK\r\n")
+		case "SUBSCRIBE":
+			channel := args[1].(string)
+			fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(channel), channel)
+			b.addSub(conn)
+		case "PUBLISH":
+			channel, payload := args[1].(string), args[2].(string)
+			n := b.fanout(func() string {
+				return string(encodeRESPCommand([]string{"message", channel, payload}))
+			})
+			fmt.Fprintf(conn, ":%d\r\n", n)
+		default:
+			io.WriteString(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func serveNATS(b *fakeBroker, conn net.Conn) {
+	io.WriteString(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	br := bufio.NewReader(conn)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			b.mu.Lock()
+			b.auth = append(b.auth, strings.TrimSpace(strings.TrimPrefix(line, "CONNECT")))
+			b.mu.Unlock()
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "SUB":
+			b.addSub(conn)
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return
+			}
+			subject, payload := fields[1], string(buf[:size])
+			b.fanout(func() string {
+				return fmt.Sprintf("MSG %s 1 %d\r\n%s\r\n", subject, len(payload), payload)
+			})
+		}
+	}
+}
+
+func testBusRoundTrip(t *testing.T, rawURL string, broker *fakeBroker) {
+	t.Helper()
+	bus, err := Open(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan string, 4)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Subscribe(ctx, func(data []byte) { received <- string(data) })
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for broker.subscribers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber never connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	payload := `{"type":"session_status","data":"line1\r\nline2"}`
+	if err := bus.Publish(ctx, []byte(payload)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	select {
+	case got := <-received:
+		if got != payload {
+			t.Errorf("got %q, want %q", got, payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for published message")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscribe did not return after cancel")
+	}
+}
+
+func TestRedisBus(t *testing.T) {
+	broker := newFakeBroker(t, serveRedis)
+	testBusRoundTrip(t, "redis://:s3cret@"+broker.ln.Addr().String()+"?channel=test", broker)
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.auth) == 0 || broker.auth[0] != "s3cret" {
+		t.Errorf("expected AUTH with the url password, got %v", broker.auth)
+	}
+}
+
+func TestNATSBus(t *testing.T) {
+	broker := newFakeBroker(t, serveNATS)
+	testBusRoundTrip(t, "nats://tok@"+broker.ln.Addr().String(), broker)
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.auth) == 0 || !strings.Contains(broker.auth[0], `"auth_token":"tok"`) {
+		t.Errorf("expected CONNECT with the url token, got %v", broker.auth)
+	}
+}
+
+func TestOpen_UnsupportedScheme(t *testing.T) {
+	if _, err := Open("kafka://localhost:9092"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
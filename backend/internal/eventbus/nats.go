@@ -0,0 +1,232 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATS is a Bus over core NATS subjects, speaking the text protocol directly.
+type NATS struct {
+	addr     string
+	host     string
+	tls      bool
+	user     string
+	password string
+	token    string
+	subject  string
+
+	mu  sync.Mutex // guards the publishing connection and writes to it
+	pub net.Conn
+}
+
+func newNATS(u *url.URL, subject string) (*NATS, error) {
+	n := &NATS{addr: u.Host, host: u.Hostname(), tls: u.Scheme == "tls", subject: subject}
+	if u.Port() == "" {
+		n.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			n.user, n.password = u.User.Username(), password
+		} else {
+			n.token = u.User.Username()
+		}
+	}
+	return n, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, data []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.pub == nil {
+		conn, br, err := n.connect(ctx)
+		if err != nil {
+			return err
+		}
+		n.pub = conn
+		go n.servePublisher(conn, br)
+	}
+	n.pub.SetWriteDeadline(deadline(ctx, writeTimeout))
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\n", n.subject, len(data), data)
+	if _, err := io.WriteString(n.pub, msg); err != nil {
+		n.pub.Close()
+		n.pub = nil
+		return fmt.Errorf("nats publish: %w", err)
+	}
+	return nil
+}
+
+// servePublisher answers server pings on the publishing connection, which
+// would otherwise be dropped as stale, and forgets it once it fails.
+func (n *NATS) servePublisher(conn net.Conn, br *bufio.Reader) {
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			n.mu.Lock()
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_, err = io.WriteString(conn, "PONG\r\n")
+			n.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			slog.Warn("nats event bus error", "error", strings.TrimSpace(line[4:]))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	n.mu.Lock()
+	if n.pub == conn {
+		n.pub = nil
+	}
+	n.mu.Unlock()
+	conn.Close()
+}
+
+func (n *NATS) Subscribe(ctx context.Context, handler func(data []byte)) error {
+	delay := time.Second
+	for {
+		err := n.subscribeOnce(ctx, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("nats event bus subscription lost, reconnecting", "addr", n.addr, "error", err)
+		var ok bool
+		if delay, ok = sleepBackoff(ctx, delay); !ok {
+			return nil
+		}
+	}
+}
+
+func (n *NATS) subscribeOnce(ctx context.Context, handler func(data []byte)) error {
+	conn, br, err := n.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	conn.SetWriteDeadline(deadline(ctx, writeTimeout))
+	if _, err := fmt.Fprintf(conn, "SUB %s 1\r\n", n.subject); err != nil {
+		return err
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("malformed nats message header %q", strings.TrimSpace(line))
+			}
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(br, buf); err != nil {
+				return err
+			}
+			handler(buf[:size])
+		case strings.HasPrefix(line, "PING"):
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if _, err := io.WriteString(conn, "PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + strings.TrimSpace(line[4:]))
+		}
+	}
+}
+
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pub == nil {
+		return nil
+	}
+	err := n.pub.Close()
+	n.pub = nil
+	return err
+}
+
+// connect dials the server, upgrades to TLS when asked to, and completes the
+// CONNECT handshake (confirmed by a PING/PONG round trip).
+func (n *NATS) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	conn, err := (&net.Dialer{Timeout: dialTimeout}).DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial nats: %w", err)
+	}
+	conn.SetDeadline(deadline(ctx, writeTimeout))
+	br := bufio.NewReader(conn)
+
+	fail := func(err error) (net.Conn, *bufio.Reader, error) {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats handshake: %w", err)
+	}
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return fail(err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fail(fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line)))
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	_ = json.Unmarshal([]byte(line[5:]), &info)
+	if n.tls || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: n.host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fail(err)
+		}
+		conn = tlsConn
+		br = bufio.NewReader(conn)
+	}
+
+	connectOpts, _ := json.Marshal(map[string]any{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "codeburg",
+		"lang":       "go",
+		"version":    "1",
+		"protocol":   1,
+		"user":       n.user,
+		"pass":       n.password,
+		"auth_token": n.token,
+	})
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connectOpts); err != nil {
+		return fail(err)
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return fail(err)
+		}
+		switch {
+		case strings.HasPrefix(line, "PONG"):
+			conn.SetDeadline(time.Time{})
+			return conn, br, nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fail(errors.New(strings.TrimSpace(line[4:])))
+		}
+	}
+}
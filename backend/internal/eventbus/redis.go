@@ -0,0 +1,239 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redis is a Bus over Redis pub/sub, speaking RESP directly.
+type Redis struct {
+	addr     string
+	tls      bool
+	username string
+	password string
+	db       int
+	channel  string
+
+	mu  sync.Mutex // guards the publishing connection
+	pub net.Conn
+	br  *bufio.Reader
+}
+
+func newRedis(u *url.URL, channel string) (*Redis, error) {
+	r := &Redis{addr: u.Host, tls: u.Scheme == "rediss", channel: channel}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		db, err := strconv.Atoi(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", path)
+		}
+		r.db = db
+	}
+	return r, nil
+}
+
+func (r *Redis) Publish(ctx context.Context, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pub == nil {
+		conn, br, err := r.connect(ctx)
+		if err != nil {
+			return err
+		}
+		r.pub, r.br = conn, br
+	}
+	r.pub.SetDeadline(deadline(ctx, writeTimeout))
+	_, err := r.roundTrip(r.pub, r.br, "PUBLISH", r.channel, string(data))
+	if err != nil {
+		r.pub.Close()
+		r.pub, r.br = nil, nil
+		return fmt.Errorf("redis publish: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) Subscribe(ctx context.Context, handler func(data []byte)) error {
+	delay := time.Second
+	for {
+		err := r.subscribeOnce(ctx, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("redis event bus subscription lost, reconnecting", "addr", r.addr, "error", err)
+		var ok bool
+		if delay, ok = sleepBackoff(ctx, delay); !ok {
+			return nil
+		}
+	}
+}
+
+func (r *Redis) subscribeOnce(ctx context.Context, handler func(data []byte)) error {
+	conn, br, err := r.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	conn.SetDeadline(deadline(ctx, writeTimeout))
+	if _, err := r.roundTrip(conn, br, "SUBSCRIBE", r.channel); err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	for {
+		reply, err := readRESP(br)
+		if err != nil {
+			return err
+		}
+		// Pushed messages are ["message", channel, payload].
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 || parts[0] != "message" {
+			continue
+		}
+		if payload, ok := parts[2].(string); ok {
+			handler([]byte(payload))
+		}
+	}
+}
+
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pub == nil {
+		return nil
+	}
+	err := r.pub.Close()
+	r.pub, r.br = nil, nil
+	return err
+}
+
+// connect dials Redis and authenticates.
+func (r *Redis) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	var err error
+	if r.tls {
+		host, _, _ := net.SplitHostPort(r.addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", r.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial redis: %w", err)
+	}
+	br := bufio.NewReader(conn)
+
+	conn.SetDeadline(deadline(ctx, writeTimeout))
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.username != "" {
+			args = []string{"AUTH", r.username, r.password}
+		}
+		if _, err := r.roundTrip(conn, br, args...); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.roundTrip(conn, br, "SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis select: %w", err)
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, br, nil
+}
+
+func (r *Redis) roundTrip(conn net.Conn, br *bufio.Reader, args ...string) (any, error) {
+	if _, err := conn.Write(encodeRESPCommand(args)); err != nil {
+		return nil, err
+	}
+	return readRESP(br)
+}
+
+func encodeRESPCommand(args []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(b.String())
+}
+
+// redisError is an error reply ("-ERR ...") from the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// readRESP reads one RESP2 reply: simple and bulk strings become string,
+// integers int64, arrays []any, nil bulk strings nil and error replies an
+// error.
+func readRESP(br *bufio.Reader) (any, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readRESP(br); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
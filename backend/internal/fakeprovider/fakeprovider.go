@@ -0,0 +1,193 @@
+// Package fakeprovider is a stand-in for the claude and codex CLIs. It
+// answers the same command lines Codeburg runs with scripted output in each
+// CLI's format, so sessions can be exercised end to end in tests and in
+// frontend development without the real agents or their accounts.
+//
+// Install links claude and codex in a directory to the running executable;
+// with that directory first on PATH, sessions run them instead of the real
+// CLIs. The executable must call RunIfInvoked before anything else, so that
+// when it is started under one of those names it acts as the fake and exits.
+//
+// Chat turns (claude --print, codex exec) write the script as stream-json
+// or codex JSON events. Interactive runs, as in terminal sessions, write it
+// as text, run the hooks Codeburg configured (claude's Stop and SessionEnd
+// hooks in .claude/settings.local.json, codex's notify program) after each
+// turn, and take further prompts one per line on stdin.
+package fakeprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Environment variables the fake reads.
+const (
+	// ScriptEnv names a JSON file holding the Script every turn plays.
+	// DefaultScript is played when it is unset.
+	ScriptEnv = "CODEBURG_FAKE_SCRIPT"
+	// LogEnv names a file each run appends an Invocation line to when it
+	// exits.
+	LogEnv = "CODEBURG_FAKE_LOG"
+)
+
+// Providers are the CLIs the fake stands in for.
+var Providers = []string{"claude", "codex"}
+
+// Script is what the fake does in each turn, step by step.
+type Script []Step
+
+// Step is one thing the agent does. Set one of Text, Thinking, Tool, Sleep
+// or Fail. In Text and Thinking, {prompt} and {provider} are replaced by the
+// turn's prompt and the provider's name.
+type Step struct {
+	Text     string `json:"text,omitempty"`
+	Thinking string `json:"thinking,omitempty"`
+
+	// Tool is a tool call, e.g. "Bash", with its input and the output it
+	// returns. Error makes the call fail.
+	Tool   string         `json:"tool,omitempty"`
+	Input  map[string]any `json:"input,omitempty"`
+	Output string         `json:"output,omitempty"`
+	Error  bool           `json:"error,omitempty"`
+	// Ask asks permission for the tool call first when claude takes its
+	// input on stdin; a denied call fails with the reason given.
+	Ask bool `json:"ask,omitempty"`
+
+	// Sleep pauses the turn, e.g. "500ms".
+	Sleep string `json:"sleep,omitempty"`
+	// Fail ends the turn with this error, and the process with status 1.
+	Fail string `json:"fail,omitempty"`
+}
+
+// DefaultScript runs one command and echoes the prompt back.
+var DefaultScript = Script{
+	{Thinking: "Looking at the repository."},
+	{Tool: "Bash", Input: map[string]any{"command": "ls"}, Output: "README.md"},
+	{Text: "Fake {provider} reply to: {prompt}"},
+}
+
+// Invocation is a line of the LogEnv file: how the fake was run.
+type Invocation struct {
+	Provider string   `json:"provider"`
+	Args     []string `json:"args"`
+	Dir      string   `json:"dir"`
+	Prompts  []string `json:"prompts"`
+}
+
+// Install links the fake providers in dir to the running executable.
+func Install(dir string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, name := range Providers {
+		link := filepath.Join(dir, name)
+		os.Remove(link)
+		if err := os.Symlink(exe, link); err != nil {
+			return fmt.Errorf("link %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RunIfInvoked runs the fake and exits when the process was started as one
+// of the Providers, and returns otherwise.
+func RunIfInvoked() {
+	name := filepath.Base(os.Args[0])
+	for _, provider := range Providers {
+		if name == provider {
+			os.Exit(Run(provider, os.Args[1:]))
+		}
+	}
+}
+
+// Run runs the fake provider with the CLI arguments args, on the standard
+// streams, and returns the exit status.
+func Run(provider string, args []string) int {
+	script, err := loadScript()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fake "+provider+": "+err.Error())
+		return 2
+	}
+	r := &run{provider: provider, args: args, script: script, in: newLineReader(os.Stdin), out: os.Stdout}
+	code := r.run()
+	r.log()
+	return code
+}
+
+// Transcript returns the output lines of one chat turn playing script for
+// prompt, as claude stream-json or codex JSON events, without running a
+// process. Sleep steps are skipped.
+func Transcript(provider, prompt string, script Script) []string {
+	args := []string{"--print", "--output-format", "stream-json", prompt}
+	if provider == "codex" {
+		args = []string{"exec", "--json", prompt}
+	}
+	var steps Script
+	for _, step := range script {
+		if step.Sleep == "" {
+			steps = append(steps, step)
+		}
+	}
+	var out bytes.Buffer
+	r := &run{provider: provider, args: args, script: steps, in: newLineReader(strings.NewReader("")), out: &out}
+	r.run()
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func loadScript() (Script, error) {
+	path := os.Getenv(ScriptEnv)
+	if path == "" {
+		return DefaultScript, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read script: %w", err)
+	}
+	var script Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parse script %s: %w", path, err)
+	}
+	return script, nil
+}
+
+// log appends the invocation to the LogEnv file, if set.
+func (r *run) log() {
+	path := os.Getenv(LogEnv)
+	if path == "" {
+		return
+	}
+	dir, _ := os.Getwd()
+	line, _ := json.Marshal(Invocation{Provider: r.provider, Args: r.args, Dir: dir, Prompts: r.prompts})
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
+
+func (s Step) expand(provider, prompt string) Step {
+	replace := strings.NewReplacer("{prompt}", prompt, "{provider}", provider)
+	s.Text = replace.Replace(s.Text)
+	s.Thinking = replace.Replace(s.Thinking)
+	return s
+}
+
+func (s Step) pause() {
+	if d, err := time.ParseDuration(s.Sleep); err == nil {
+		time.Sleep(d)
+	}
+}
+
+func newID(prefix string) string {
+	return fmt.Sprintf("%s-%d-%d", prefix, os.Getpid(), time.Now().UnixNano())
+}
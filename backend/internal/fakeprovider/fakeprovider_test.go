@@ -0,0 +1,113 @@
+package fakeprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func play(t *testing.T, provider string, script Script, stdin string, args ...string) (int, []map[string]any) {
+	t.Helper()
+	var out bytes.Buffer
+	r := &run{provider: provider, args: args, script: script, in: newLineReader(strings.NewReader(stdin)), out: &out}
+	code := r.run()
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid output line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return code, events
+}
+
+func TestClaudeTurn(t *testing.T) {
+	code, events := play(t, "claude", Script{{Text: "Working on {prompt}"}, {Fail: "out of credits"}}, "",
+		"--print", "--output-format", "stream-json", "--resume", "abc", "--model", "opus", "fix it")
+	if code != 1 || len(events) != 3 {
+		t.Fatalf("exit %d, events %+v", code, events)
+	}
+	if events[0]["session_id"] != "abc" || events[0]["model"] != "opus" {
+		t.Errorf("expected the resumed session and model in init, got %+v", events[0])
+	}
+	text := events[1]["message"].(map[string]any)["content"].([]any)[0].(map[string]any)["text"]
+	if text != "Working on fix it" {
+		t.Errorf("unexpected text %v", text)
+	}
+	if last := events[2]; last["type"] != "result" || last["is_error"] != true || last["result"] != "out of credits" {
+		t.Errorf("expected an error result, got %+v", last)
+	}
+}
+
+func TestClaudePermissionDenied(t *testing.T) {
+	prompt := `{"type":"user","message":{"role":"user","content":[{"type":"text","text":"clean"}]}}`
+	script := Script{{Tool: "Bash", Input: map[string]any{"command": "rm -rf /"}, Output: "gone", Ask: true}}
+
+	// The answer has to echo the request ID, so answer from a reader that
+	// sees the request first.
+	var out bytes.Buffer
+	pr := &answeringReader{prompt: prompt + "\n", out: &out}
+	r := &run{provider: "claude", args: []string{"--print", "--input-format", "stream-json"}, script: script, in: newLineReader(pr), out: &out}
+	if code := r.run(); code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	if !strings.Contains(out.String(), `"content":"not that","is_error":true`) {
+		t.Errorf("expected the denied call to fail with the reason, got:\n%s", out.String())
+	}
+	if len(r.prompts) != 1 || r.prompts[0] != "clean" {
+		t.Errorf("unexpected prompts %q", r.prompts)
+	}
+}
+
+// answeringReader yields the prompt, then a denial of the control request
+// written to out.
+type answeringReader struct {
+	prompt string
+	out    *bytes.Buffer
+	sent   bool
+	buf    bytes.Buffer
+}
+
+func (a *answeringReader) Read(p []byte) (int, error) {
+	if a.buf.Len() == 0 {
+		switch {
+		case a.prompt != "":
+			a.buf.WriteString(a.prompt)
+			a.prompt = ""
+		case !a.sent:
+			var id string
+			for _, line := range strings.Split(a.out.String(), "\n") {
+				var event map[string]any
+				if json.Unmarshal([]byte(line), &event) == nil && event["type"] == "control_request" {
+					id, _ = event["request_id"].(string)
+				}
+			}
+			a.sent = true
+			answer, _ := json.Marshal(map[string]any{"type": "control_response", "response": map[string]any{
+				"subtype": "success", "request_id": id, "response": map[string]any{"behavior": "deny", "message": "not that"},
+			}})
+			a.buf.Write(append(answer, '\n'))
+		default:
+			return 0, io.EOF
+		}
+	}
+	return a.buf.Read(p)
+}
+
+func TestCodexTurn(t *testing.T) {
+	code, events := play(t, "codex", Script{{Tool: "Bash", Input: map[string]any{"command": "go test"}, Error: true}}, "",
+		"exec", "resume", "--json", "--sandbox", "read-only", "thread-1", "run the tests")
+	if code != 0 || len(events) != 5 {
+		t.Fatalf("exit %d, events %+v", code, events)
+	}
+	if events[0]["thread_id"] != "thread-1" {
+		t.Errorf("expected the resumed thread, got %+v", events[0])
+	}
+	item := events[3]["item"].(map[string]any)
+	if item["command"] != "go test" || item["status"] != "failed" || item["exit_code"] != float64(1) {
+		t.Errorf("expected a failed command, got %+v", item)
+	}
+}
@@ -0,0 +1,440 @@
+package fakeprovider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// valueFlags are the flags of each CLI that take a value, as Codeburg
+// passes them.
+var valueFlags = map[string][]string{
+	"claude": {"output-format", "input-format", "model", "append-system-prompt", "resume", "allowedTools", "disallowedTools", "permission-prompt-tool"},
+	"codex":  {"model", "sandbox", "c"},
+}
+
+type run struct {
+	provider string
+	args     []string
+	script   Script
+	in       *lineReader
+	out      io.Writer
+
+	flags      map[string]string
+	configs    []string // codex -c values
+	positional []string
+	prompts    []string
+	sessionID  string
+}
+
+func (r *run) run() int {
+	r.parseArgs()
+	switch {
+	case r.provider == "claude" && r.flags["print"] != "":
+		return r.claudeTurn()
+	case r.provider == "codex" && len(r.positional) > 0 && r.positional[0] == "exec":
+		return r.codexTurn()
+	}
+	return r.interactive()
+}
+
+func (r *run) parseArgs() {
+	r.flags = map[string]string{}
+	takesValue := func(name string) bool {
+		for _, f := range valueFlags[r.provider] {
+			if f == name {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < len(r.args); i++ {
+		arg := r.args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			r.positional = append(r.positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !hasValue {
+			value = "true"
+			if takesValue(name) && i+1 < len(r.args) {
+				i++
+				value = r.args[i]
+			}
+		}
+		if name == "c" {
+			r.configs = append(r.configs, value)
+		}
+		r.flags[name] = value
+	}
+}
+
+func (r *run) emit(v any) {
+	line, _ := json.Marshal(v)
+	r.out.Write(append(line, '\n'))
+}
+
+func (r *run) lastPositional() string {
+	if len(r.positional) == 0 {
+		return ""
+	}
+	return r.positional[len(r.positional)-1]
+}
+
+// claudeTurn plays the script as claude --print --output-format stream-json.
+func (r *run) claudeTurn() int {
+	onStdin := r.flags["input-format"] == "stream-json"
+	prompt := r.lastPositional()
+	if onStdin {
+		prompt = r.readClaudePrompt()
+	}
+	r.prompts = append(r.prompts, prompt)
+	r.sessionID = r.flags["resume"]
+	if r.sessionID == "" {
+		r.sessionID = newID("fake-claude")
+	}
+	cwd, _ := os.Getwd()
+	r.emit(map[string]any{"type": "system", "subtype": "init", "session_id": r.sessionID, "cwd": cwd, "model": r.flags["model"]})
+
+	assistant := func(block map[string]any) {
+		r.emit(map[string]any{
+			"type":       "assistant",
+			"session_id": r.sessionID,
+			"message":    map[string]any{"role": "assistant", "content": []any{block}},
+		})
+	}
+	toolResult := func(id, content string, isErr bool) {
+		r.emit(map[string]any{
+			"type":       "user",
+			"session_id": r.sessionID,
+			"message": map[string]any{"role": "user", "content": []any{map[string]any{
+				"type": "tool_result", "tool_use_id": id, "content": content, "is_error": isErr,
+			}}},
+		})
+	}
+
+	var lastText string
+	outputChars := 0
+	for _, step := range r.script {
+		step = step.expand(r.provider, prompt)
+		switch {
+		case step.Sleep != "":
+			step.pause()
+		case step.Thinking != "":
+			assistant(map[string]any{"type": "thinking", "thinking": step.Thinking})
+		case step.Text != "":
+			assistant(map[string]any{"type": "text", "text": step.Text})
+			lastText = step.Text
+			outputChars += len(step.Text)
+		case step.Tool != "":
+			id := newID("toolu")
+			assistant(map[string]any{"type": "tool_use", "id": id, "name": step.Tool, "input": step.Input})
+			if step.Ask && onStdin {
+				if allowed, reason := r.askPermission(step); !allowed {
+					toolResult(id, reason, true)
+					continue
+				}
+			}
+			toolResult(id, step.Output, step.Error)
+		case step.Fail != "":
+			r.emit(map[string]any{
+				"type": "result", "subtype": "error_during_execution", "is_error": true,
+				"result": step.Fail, "session_id": r.sessionID,
+			})
+			return 1
+		}
+	}
+	r.emit(map[string]any{
+		"type": "result", "subtype": "success", "is_error": false,
+		"result": lastText, "session_id": r.sessionID,
+		"usage":          map[string]any{"input_tokens": tokens(len(prompt)), "output_tokens": tokens(outputChars)},
+		"total_cost_usd": 0,
+	})
+	return 0
+}
+
+// readClaudePrompt reads stream-json input up to the first user message and
+// returns its text.
+func (r *run) readClaudePrompt() string {
+	for {
+		line, ok := r.in.next()
+		if !ok {
+			return ""
+		}
+		var msg struct {
+			Type    string `json:"type"`
+			Message struct {
+				Content []struct {
+					Type string `json:"type"`
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"message"`
+		}
+		if json.Unmarshal([]byte(line), &msg) != nil || msg.Type != "user" {
+			continue
+		}
+		var text []string
+		for _, block := range msg.Message.Content {
+			if block.Type == "text" {
+				text = append(text, block.Text)
+			}
+		}
+		return strings.Join(text, "\n")
+	}
+}
+
+// askPermission sends a can_use_tool request and waits for its answer. It
+// reports whether the call was allowed, and why not.
+func (r *run) askPermission(step Step) (bool, string) {
+	requestID := newID("req")
+	r.emit(map[string]any{
+		"type":       "control_request",
+		"request_id": requestID,
+		"request":    map[string]any{"subtype": "can_use_tool", "tool_name": step.Tool, "input": step.Input},
+	})
+	for {
+		line, ok := r.in.next()
+		if !ok {
+			return false, "no answer to the permission request"
+		}
+		var msg struct {
+			Type     string `json:"type"`
+			Response struct {
+				Subtype   string `json:"subtype"`
+				RequestID string `json:"request_id"`
+				Error     string `json:"error"`
+				Response  struct {
+					Behavior string `json:"behavior"`
+					Message  string `json:"message"`
+				} `json:"response"`
+			} `json:"response"`
+		}
+		if json.Unmarshal([]byte(line), &msg) != nil || msg.Type != "control_response" || msg.Response.RequestID != requestID {
+			continue
+		}
+		if msg.Response.Subtype == "error" {
+			return false, msg.Response.Error
+		}
+		if msg.Response.Response.Behavior != "allow" {
+			return false, msg.Response.Response.Message
+		}
+		return true, ""
+	}
+}
+
+// codexTurn plays the script as codex exec --json, or codex exec resume
+// --json when resuming a thread.
+func (r *run) codexTurn() int {
+	args := r.positional[1:]
+	if len(args) > 0 && args[0] == "resume" {
+		args = args[1:]
+		if len(args) > 1 {
+			r.sessionID = args[0]
+		}
+	}
+	prompt := ""
+	if len(args) > 0 {
+		prompt = args[len(args)-1]
+	}
+	r.prompts = append(r.prompts, prompt)
+	if r.sessionID == "" {
+		r.sessionID = newID("fake-thread")
+	}
+	r.emit(map[string]any{"type": "thread.started", "thread_id": r.sessionID})
+	r.emit(map[string]any{"type": "turn.started"})
+
+	outputChars := 0
+	for i, step := range r.script {
+		step = step.expand(r.provider, prompt)
+		itemID := fmt.Sprintf("item_%d", i)
+		switch {
+		case step.Sleep != "":
+			step.pause()
+		case step.Thinking != "":
+			r.emit(map[string]any{"type": "item.completed", "item": map[string]any{"id": itemID, "type": "reasoning", "text": step.Thinking}})
+		case step.Text != "":
+			r.emit(map[string]any{"type": "item.completed", "item": map[string]any{"id": itemID, "type": "agent_message", "text": step.Text}})
+			outputChars += len(step.Text)
+		case step.Tool != "":
+			command := toolSummary(step)
+			r.emit(map[string]any{"type": "item.started", "item": map[string]any{
+				"id": itemID, "type": "command_execution", "command": command, "aggregated_output": "", "status": "in_progress",
+			}})
+			exitCode, status := 0, "completed"
+			if step.Error {
+				exitCode, status = 1, "failed"
+			}
+			r.emit(map[string]any{"type": "item.completed", "item": map[string]any{
+				"id": itemID, "type": "command_execution", "command": command,
+				"aggregated_output": step.Output, "exit_code": exitCode, "status": status,
+			}})
+		case step.Fail != "":
+			r.emit(map[string]any{"type": "error", "message": step.Fail})
+			r.emit(map[string]any{"type": "turn.failed", "error": map[string]any{"message": step.Fail}})
+			return 1
+		}
+	}
+	r.emit(map[string]any{"type": "turn.completed", "usage": map[string]any{
+		"input_tokens": tokens(len(prompt)), "cached_input_tokens": 0, "output_tokens": tokens(outputChars),
+	}})
+	return 0
+}
+
+// interactive plays the script as text for the prompt given as an argument
+// and then for each line read, running the turn hooks after each.
+func (r *run) interactive() int {
+	r.sessionID = r.flags["resume"]
+	if r.sessionID == "" {
+		r.sessionID = newID("fake-" + r.provider)
+	}
+	fmt.Fprintf(r.out, "Fake %s. Type a prompt and press Enter; Ctrl-D quits.\r\n", r.provider)
+	if prompt := r.lastPositional(); prompt != "" {
+		r.textTurn(prompt)
+	}
+	for {
+		line, ok := r.in.next()
+		if !ok {
+			break
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			r.textTurn(line)
+		}
+	}
+	if r.provider == "claude" {
+		r.claudeHook("SessionEnd")
+	}
+	return 0
+}
+
+func (r *run) textTurn(prompt string) {
+	r.prompts = append(r.prompts, prompt)
+	fmt.Fprintf(r.out, "> %s\r\n", prompt)
+	var lastText string
+	for _, step := range r.script {
+		step = step.expand(r.provider, prompt)
+		switch {
+		case step.Sleep != "":
+			step.pause()
+		case step.Thinking != "":
+			fmt.Fprintf(r.out, "✻ %s\r\n", step.Thinking)
+		case step.Text != "":
+			fmt.Fprintf(r.out, "%s\r\n", step.Text)
+			lastText = step.Text
+		case step.Tool != "":
+			fmt.Fprintf(r.out, "● %s(%s)\r\n  ⎿ %s\r\n", step.Tool, toolSummary(step), step.Output)
+		case step.Fail != "":
+			fmt.Fprintf(r.out, "Error: %s\r\n", step.Fail)
+		}
+		if step.Fail != "" {
+			break
+		}
+	}
+
+	switch r.provider {
+	case "claude":
+		r.claudeHook("Stop")
+	case "codex":
+		r.codexNotify(prompt, lastText)
+	}
+}
+
+// claudeHook runs the commands .claude/settings.local.json has for event,
+// with the hook payload on stdin as claude does.
+func (r *run) claudeHook(event string) {
+	cwd, _ := os.Getwd()
+	data, err := os.ReadFile(filepath.Join(cwd, ".claude", "settings.local.json"))
+	if err != nil {
+		return
+	}
+	var settings struct {
+		Hooks map[string][]struct {
+			Hooks []struct {
+				Type    string `json:"type"`
+				Command string `json:"command"`
+			} `json:"hooks"`
+		} `json:"hooks"`
+	}
+	if json.Unmarshal(data, &settings) != nil {
+		return
+	}
+	payload := map[string]any{"hook_event_name": event, "session_id": r.sessionID, "cwd": cwd}
+	if event == "Stop" {
+		payload["stop_hook_active"] = false
+	}
+	input, _ := json.Marshal(payload)
+	for _, entry := range settings.Hooks[event] {
+		for _, hook := range entry.Hooks {
+			if hook.Type != "command" {
+				continue
+			}
+			cmd := exec.Command("sh", "-c", hook.Command)
+			cmd.Stdin = bytes.NewReader(input)
+			cmd.Run()
+		}
+	}
+}
+
+// codexNotify runs the notify program set with -c notify=[...], with the
+// turn's payload as its last argument as codex does.
+func (r *run) codexNotify(prompt, lastText string) {
+	for _, config := range r.configs {
+		value, ok := strings.CutPrefix(config, "notify=")
+		if !ok {
+			continue
+		}
+		var argv []string
+		if json.Unmarshal([]byte(value), &argv) != nil || len(argv) == 0 {
+			continue
+		}
+		cwd, _ := os.Getwd()
+		payload, _ := json.Marshal(map[string]any{
+			"type":                   "agent-turn-complete",
+			"thread-id":              r.sessionID,
+			"turn-id":                newID("turn"),
+			"cwd":                    cwd,
+			"input-messages":         []string{prompt},
+			"last-assistant-message": lastText,
+		})
+		exec.Command(argv[0], append(argv[1:], string(payload))...).Run()
+	}
+}
+
+// toolSummary is the one-line form of a tool call: its command, path or
+// pattern when it has one.
+func toolSummary(step Step) string {
+	for _, key := range []string{"command", "file_path", "path", "pattern"} {
+		if v, ok := step.Input[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return step.Tool
+}
+
+// tokens estimates the tokens in chars characters of text, at four a token.
+func tokens(chars int) int {
+	return (chars + 3) / 4
+}
+
+// lineReader reads stdin line by line.
+type lineReader struct {
+	scanner *bufio.Scanner
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	return &lineReader{scanner: scanner}
+}
+
+func (l *lineReader) next() (string, bool) {
+	if !l.scanner.Scan() {
+		return "", false
+	}
+	return strings.TrimRight(l.scanner.Text(), "\r"), true
+}
@@ -1,10 +1,16 @@
 package gitclone
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -12,6 +18,13 @@ import (
 type Config struct {
 	// BaseDir is the base directory for cloned repos (default: ~/.codeburg/repos)
 	BaseDir string
+	// Hosts maps self-hosted git hosts to their provider, in addition to the
+	// well-known public hosts (default: from CODEBURG_GIT_HOSTS)
+	Hosts map[string]Provider
+	// Shape is what clones fetch unless CloneOptions says otherwise
+	// (default: from CODEBURG_CLONE_DEPTH, CODEBURG_CLONE_SINGLE_BRANCH and
+	// CODEBURG_CLONE_FILTER)
+	Shape Shape
 }
 
 // DefaultConfig returns the default clone configuration.
@@ -22,6 +35,8 @@ func DefaultConfig() Config {
 	}
 	return Config{
 		BaseDir: filepath.Join(home, ".codeburg", "repos"),
+		Hosts:   hostsFromEnv(),
+		Shape:   shapeFromEnv(),
 	}
 }
 
@@ -58,7 +73,8 @@ func ParseRepoName(url string) string {
 	return parts[len(parts)-1]
 }
 
-// NormalizeGitHubURL ensures a GitHub HTTPS URL has the .git suffix.
+// NormalizeGitHubURL ensures a remote URL has the .git suffix. Despite the
+// name it is host-agnostic.
 func NormalizeGitHubURL(url string) string {
 	url = strings.TrimSpace(url)
 	url = strings.TrimSuffix(url, "/")
@@ -100,8 +116,51 @@ func ParseOwnerRepo(url string) (owner, repo string, ok bool) {
 	return "", "", false
 }
 
-// Clone clones a GitHub repository into cfg.BaseDir/name.
-func Clone(cfg Config, url, name string) (*CloneResult, error) {
+// CloneOptions holds optional settings for Clone.
+type CloneOptions struct {
+	// Provider and Token authenticate HTTPS clones of private repositories.
+	// The token is sent as an HTTP header and is not stored in the clone.
+	Provider Provider
+	Token    string
+	// Shape, when set, replaces the Config's.
+	Shape *Shape
+	// Progress, when set, is called with git's progress as the clone goes.
+	Progress func(Progress)
+}
+
+// Progress is a step of a clone as git reports it, e.g. "Receiving
+// objects" at 45% (450 of 1000).
+type Progress struct {
+	Phase   string `json:"phase"`
+	Percent int    `json:"percent"`
+	Current int    `json:"current"`
+	Total   int    `json:"total"`
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%s: %d%% (%d/%d)", p.Phase, p.Percent, p.Current, p.Total)
+}
+
+// progressPattern matches git's progress lines, such as
+// "remote: Counting objects:  10% (1/10)" and
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 600.00 KiB/s".
+var progressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z][A-Za-z ]*):\s+(\d+)% \((\d+)/(\d+)\)`)
+
+// parseProgress parses a line of git's progress output.
+func parseProgress(line string) (Progress, bool) {
+	m := progressPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Progress{}, false
+	}
+	percent, _ := strconv.Atoi(m[2])
+	current, _ := strconv.Atoi(m[3])
+	total, _ := strconv.Atoi(m[4])
+	return Progress{Phase: m[1], Percent: percent, Current: current, Total: total}, true
+}
+
+// Clone clones a repository into cfg.BaseDir/name. Cancelling ctx stops
+// git and removes what it had cloned.
+func Clone(ctx context.Context, cfg Config, url, name string, opts CloneOptions) (*CloneResult, error) {
 	dest := filepath.Join(cfg.BaseDir, name)
 
 	// Ensure base directory exists
@@ -116,10 +175,28 @@ func Clone(cfg Config, url, name string) (*CloneResult, error) {
 
 	normalized := NormalizeGitHubURL(url)
 
-	cmd := exec.Command("git", "clone", normalized, dest)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	shape := cfg.Shape
+	if opts.Shape != nil {
+		shape = *opts.Shape
+	}
+	if err := shape.Validate(); err != nil {
+		return nil, err
+	}
+
+	args := append([]string{"clone", "--progress"}, shape.args()...)
+	cmd := exec.CommandContext(ctx, "git", append(args, normalized, dest)...)
+	cmd.Env = os.Environ()
+	if opts.Token != "" && strings.HasPrefix(normalized, "https://") {
+		// Pass the header through the environment so the token stays out of
+		// the process list and .git/config.
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: "+authHeader(opts.Provider, opts.Token),
+		)
+	}
+	if err := runWithProgress(ctx, cmd, opts.Progress); err != nil {
+		os.RemoveAll(dest)
 		return nil, fmt.Errorf("git clone: %w", err)
 	}
 
@@ -131,6 +208,67 @@ func Clone(cfg Config, url, name string) (*CloneResult, error) {
 	}, nil
 }
 
+// runWithProgress runs a git command, passing the progress it reports on
+// stderr to progress (which may be nil). Its error carries git's last
+// message.
+func runWithProgress(ctx context.Context, cmd *exec.Cmd, progress func(Progress)) error {
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// Progress lines end in \r while they update and \n when done; other
+	// lines are kept for the error message.
+	var output []string
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanLinesOrReturns)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p, ok := parseProgress(line); ok {
+			if progress != nil {
+				progress(p)
+			}
+		} else if line = strings.TrimSpace(line); line != "" {
+			output = append(output, line)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(output) > 0 {
+			return fmt.Errorf("%w: %s", err, output[len(output)-1])
+		}
+		return err
+	}
+	return nil
+}
+
+// scanLinesOrReturns is bufio.ScanLines, also splitting at carriage
+// returns.
+func scanLinesOrReturns(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// authHeader returns the HTTP Authorization value for git over HTTPS.
+// GitLab expects the "oauth2" user with a token password; GitHub and Gitea
+// accept the token as the user name.
+func authHeader(provider Provider, token string) string {
+	creds := token + ":x-oauth-basic"
+	if provider == ProviderGitLab {
+		creds = "oauth2:" + token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+}
+
 // detectDefaultBranch figures out the default branch of a cloned repo.
 func detectDefaultBranch(repoPath string) string {
 	// Try symbolic-ref for origin HEAD
@@ -1,6 +1,12 @@
 package gitclone
 
-import "testing"
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
 
 func TestIsGitHubURL(t *testing.T) {
 	tests := []struct {
@@ -71,3 +77,147 @@ func TestNormalizeGitHubURL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRemote(t *testing.T) {
+	cfg := Config{Hosts: map[string]Provider{"git.example.com": ProviderGitea}}
+	tests := []struct {
+		input string
+		want  Remote
+		ok    bool
+	}{
+		{"https://github.com/user/repo.git", Remote{ProviderGitHub, "github.com", "user", "repo"}, true},
+		{"git@github.com:user/repo.git", Remote{ProviderGitHub, "github.com", "user", "repo"}, true},
+		{"https://gitlab.com/group/sub/repo", Remote{ProviderGitLab, "gitlab.com", "group/sub", "repo"}, true},
+		{"https://gitlab.com/group/repo/-/merge_requests/3", Remote{ProviderGitLab, "gitlab.com", "group", "repo"}, true},
+		{"git@gitlab.com:group/repo.git", Remote{ProviderGitLab, "gitlab.com", "group", "repo"}, true},
+		{"https://codeberg.org/user/repo/pulls/1", Remote{ProviderGitea, "codeberg.org", "user", "repo"}, true},
+		{"https://git.example.com/user/repo.git", Remote{ProviderGitea, "git.example.com", "user", "repo"}, true},
+		{"https://token@gitlab.com/group/repo.git", Remote{ProviderGitLab, "gitlab.com", "group", "repo"}, true},
+		{"https://example.com/user/repo", Remote{}, false},
+		{"https://github.com/user", Remote{}, false},
+		{"/home/user/projects/myrepo", Remote{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := cfg.ParseRemote(tt.input)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseRemote(%q) = %+v, %v, want %+v, %v", tt.input, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want Progress
+		ok   bool
+	}{
+		{"Receiving objects:  45% (450/1000), 1.20 MiB | 600.00 KiB/s", Progress{"Receiving objects", 45, 450, 1000}, true},
+		{"remote: Counting objects: 100% (10/10), done.", Progress{"Counting objects", 100, 10, 10}, true},
+		{"Resolving deltas:   0% (0/3)", Progress{"Resolving deltas", 0, 0, 3}, true},
+		{"Cloning into 'repo'...", Progress{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseProgress(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseProgress(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestClone_ProgressAndCancel(t *testing.T) {
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "origin.git")
+	work := filepath.Join(dir, "work")
+	for _, args := range [][]string{
+		{"init", "--bare", "-b", "main", bare},
+		{"init", "-b", "main", work},
+		{"-C", work, "-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "--allow-empty", "-m", "init"},
+		{"-C", work, "push", bare, "main"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	cfg := Config{BaseDir: filepath.Join(dir, "repos")}
+	var phases []string
+	result, err := Clone(context.Background(), cfg, "file://"+bare, "repo", CloneOptions{
+		Progress: func(p Progress) { phases = append(phases, p.Phase) },
+	})
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if result.DefaultBranch != "main" {
+		t.Errorf("default branch = %q, want main", result.DefaultBranch)
+	}
+	if len(phases) == 0 {
+		t.Error("expected progress to be reported")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Clone(ctx, cfg, "file://"+bare, "cancelled", CloneOptions{}); err == nil {
+		t.Fatal("expected a cancelled clone to fail")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.BaseDir, "cancelled")); !os.IsNotExist(err) {
+		t.Errorf("expected the cancelled clone removed, got %v", err)
+	}
+}
+
+func TestClone_ShapeAndUnshallow(t *testing.T) {
+	dir := t.TempDir()
+	bare := filepath.Join(dir, "origin.git")
+	work := filepath.Join(dir, "work")
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "--bare", "-b", "main", bare)
+	git("-C", bare, "config", "uploadpack.allowFilter", "true")
+	git("init", "-b", "main", work)
+	for _, msg := range []string{"one", "two", "three"} {
+		os.WriteFile(filepath.Join(work, "file.txt"), []byte(msg), 0o644)
+		git("-C", work, "add", ".")
+		git("-C", work, "-c", "user.email=t@example.com", "-c", "user.name=t", "commit", "-m", msg)
+	}
+	git("-C", work, "branch", "feature")
+	git("-C", work, "push", bare, "main", "feature")
+
+	cfg := Config{BaseDir: filepath.Join(dir, "repos")}
+	if _, err := Clone(context.Background(), cfg, "file://"+bare, "bad", CloneOptions{Shape: &Shape{Partial: "everything"}}); err == nil {
+		t.Error("expected an unknown partial filter to be rejected")
+	}
+
+	shape := Shape{Depth: 1, SingleBranch: true, Partial: PartialBlobless}
+	result, err := Clone(context.Background(), cfg, "file://"+bare, "repo", CloneOptions{Shape: &shape})
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if got, err := Inspect(result.Path); err != nil || got != shape {
+		t.Fatalf("Inspect = %+v, %v, want %+v", got, err, shape)
+	}
+	if count, _ := gitOutput(result.Path, "rev-list", "--count", "HEAD"); count != "1" {
+		t.Errorf("expected 1 commit in the shallow clone, got %s", count)
+	}
+
+	// No background maintenance to race the temp dir's removal.
+	git("-C", result.Path, "config", "gc.auto", "0")
+	git("-C", result.Path, "config", "maintenance.auto", "false")
+	if err := Unshallow(context.Background(), result.Path, CloneOptions{}); err != nil {
+		t.Fatalf("unshallow: %v", err)
+	}
+	if got, _ := Inspect(result.Path); got != (Shape{}) {
+		t.Errorf("expected a full clone after unshallowing, got %+v", got)
+	}
+	if count, _ := gitOutput(result.Path, "rev-list", "--count", "HEAD"); count != "3" {
+		t.Errorf("expected 3 commits after unshallowing, got %s", count)
+	}
+	if _, err := gitOutput(result.Path, "rev-parse", "--verify", "origin/feature"); err != nil {
+		t.Errorf("expected every branch after unshallowing: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package gitclone
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// Provider identifies the hosting service behind a git remote.
+type Provider string
+
+const (
+	ProviderGitHub Provider = "github"
+	ProviderGitLab Provider = "gitlab"
+	ProviderGitea  Provider = "gitea"
+)
+
+// defaultHosts maps well-known public hosts to their provider.
+var defaultHosts = map[string]Provider{
+	"github.com":   ProviderGitHub,
+	"gitlab.com":   ProviderGitLab,
+	"codeberg.org": ProviderGitea,
+	"gitea.com":    ProviderGitea,
+}
+
+// Remote is a parsed git remote URL.
+type Remote struct {
+	Provider Provider
+	Host     string
+	Owner    string // user, org, or (GitLab) nested group path
+	Repo     string
+}
+
+// Path returns "owner/repo".
+func (r Remote) Path() string {
+	return r.Owner + "/" + r.Repo
+}
+
+// BaseURL returns the HTTPS root of the host, e.g. "https://gitlab.com".
+func (r Remote) BaseURL() string {
+	return "https://" + r.Host
+}
+
+// hostsFromEnv parses CODEBURG_GIT_HOSTS, a comma-separated list of
+// host=provider pairs for self-hosted instances (e.g. "git.example.com=gitea").
+func hostsFromEnv() map[string]Provider {
+	raw := strings.TrimSpace(os.Getenv("CODEBURG_GIT_HOSTS"))
+	if raw == "" {
+		return nil
+	}
+	hosts := make(map[string]Provider)
+	for _, pair := range strings.Split(raw, ",") {
+		host, provider, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		switch p := Provider(strings.ToLower(strings.TrimSpace(provider))); p {
+		case ProviderGitHub, ProviderGitLab, ProviderGitea:
+			hosts[strings.ToLower(strings.TrimSpace(host))] = p
+		}
+	}
+	return hosts
+}
+
+// ProviderForHost returns the provider for a host, checking cfg.Hosts before
+// the well-known public hosts.
+func (cfg Config) ProviderForHost(host string) (Provider, bool) {
+	host = strings.ToLower(host)
+	if p, ok := cfg.Hosts[host]; ok {
+		return p, true
+	}
+	p, ok := defaultHosts[host]
+	return p, ok
+}
+
+// ParseRemote parses an HTTPS or SSH (git@host:path) remote URL on a
+// supported host. Nested group paths are only accepted for GitLab.
+func (cfg Config) ParseRemote(url string) (Remote, bool) {
+	url = strings.TrimSpace(url)
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+
+	var host, path string
+	switch {
+	case strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"):
+		rest := url[strings.Index(url, "://")+3:]
+		if at := strings.LastIndex(rest, "@"); at >= 0 && at < strings.Index(rest+"/", "/") {
+			rest = rest[at+1:] // drop credentials
+		}
+		host, path, _ = strings.Cut(rest, "/")
+	case strings.HasPrefix(url, "git@"):
+		var ok bool
+		host, path, ok = strings.Cut(strings.TrimPrefix(url, "git@"), ":")
+		if !ok {
+			return Remote{}, false
+		}
+	default:
+		return Remote{}, false
+	}
+
+	provider, ok := cfg.ProviderForHost(host)
+	if !ok {
+		return Remote{}, false
+	}
+
+	parts := strings.Split(path, "/")
+	if provider == ProviderGitLab {
+		// GitLab web URLs put extra routes after "/-/".
+		if i := slices.Index(parts, "-"); i >= 0 {
+			parts = parts[:i]
+		}
+	} else if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	if len(parts) < 2 {
+		return Remote{}, false
+	}
+	for _, p := range parts {
+		if p == "" {
+			return Remote{}, false
+		}
+	}
+
+	return Remote{
+		Provider: provider,
+		Host:     strings.ToLower(host),
+		Owner:    strings.Join(parts[:len(parts)-1], "/"),
+		Repo:     parts[len(parts)-1],
+	}, true
+}
+
+// IsSupportedURL returns true if s is a remote URL on a supported host.
+func (cfg Config) IsSupportedURL(s string) bool {
+	_, ok := cfg.ParseRemote(s)
+	return ok
+}
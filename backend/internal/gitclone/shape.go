@@ -0,0 +1,157 @@
+package gitclone
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Partial clone filters.
+const (
+	PartialBlobless = "blobless" // every commit and tree, blobs on demand
+	PartialTreeless = "treeless" // every commit, trees and blobs on demand
+)
+
+// Shape limits what a clone fetches, to onboard huge repositories on small
+// servers. The zero Shape is a full clone.
+type Shape struct {
+	// Depth keeps only the latest commits of history (0 for all of it).
+	Depth int `json:"depth,omitempty"`
+	// SingleBranch fetches only the default branch.
+	SingleBranch bool `json:"singleBranch,omitempty"`
+	// Partial is PartialBlobless or PartialTreeless for a partial clone.
+	Partial string `json:"partial,omitempty"`
+}
+
+// Validate reports a negative depth or an unknown partial filter.
+func (s Shape) Validate() error {
+	if s.Depth < 0 {
+		return fmt.Errorf("clone depth must not be negative")
+	}
+	switch s.Partial {
+	case "", PartialBlobless, PartialTreeless:
+		return nil
+	}
+	return fmt.Errorf("partial clone must be %q or %q", PartialBlobless, PartialTreeless)
+}
+
+func (s Shape) args() []string {
+	var args []string
+	if s.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(s.Depth))
+	}
+	if s.SingleBranch {
+		args = append(args, "--single-branch")
+	} else if s.Depth > 0 {
+		// --depth implies --single-branch otherwise.
+		args = append(args, "--no-single-branch")
+	}
+	switch s.Partial {
+	case PartialBlobless:
+		args = append(args, "--filter=blob:none")
+	case PartialTreeless:
+		args = append(args, "--filter=tree:0")
+	}
+	return args
+}
+
+// shapeFromEnv reads the default clone shape from CODEBURG_CLONE_DEPTH,
+// CODEBURG_CLONE_SINGLE_BRANCH ("true") and CODEBURG_CLONE_FILTER
+// ("blobless" or "treeless"). Invalid values are ignored.
+func shapeFromEnv() Shape {
+	var s Shape
+	if depth, err := strconv.Atoi(strings.TrimSpace(os.Getenv("CODEBURG_CLONE_DEPTH"))); err == nil && depth > 0 {
+		s.Depth = depth
+	}
+	s.SingleBranch, _ = strconv.ParseBool(strings.TrimSpace(os.Getenv("CODEBURG_CLONE_SINGLE_BRANCH")))
+	if filter := strings.ToLower(strings.TrimSpace(os.Getenv("CODEBURG_CLONE_FILTER"))); filter == PartialBlobless || filter == PartialTreeless {
+		s.Partial = filter
+	}
+	return s
+}
+
+// Inspect returns the shape of the clone at repoPath. Depth is reported as
+// 1 for any shallow clone, as git does not record the depth asked for.
+func Inspect(repoPath string) (Shape, error) {
+	var s Shape
+	out, err := gitOutput(repoPath, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return s, err
+	}
+	if out == "true" {
+		s.Depth = 1
+	}
+	// A single-branch clone fetches one branch instead of every one.
+	if refspecs, err := gitOutput(repoPath, "config", "--get-all", "remote.origin.fetch"); err == nil && !strings.Contains(refspecs, "refs/heads/*") {
+		s.SingleBranch = true
+	}
+	switch filter, _ := gitOutput(repoPath, "config", "--get", "remote.origin.partialclonefilter"); filter {
+	case "blob:none":
+		s.Partial = PartialBlobless
+	case "tree:0":
+		s.Partial = PartialTreeless
+	}
+	return s, nil
+}
+
+// Unshallow turns the clone at repoPath into a full one: it fetches the
+// whole history, every branch and, for partial clones, every object.
+// opts authenticates the fetches and reports their progress.
+func Unshallow(ctx context.Context, repoPath string, opts CloneOptions) error {
+	shape, err := Inspect(repoPath)
+	if err != nil {
+		return err
+	}
+	fetch := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath, "fetch", "--progress"}, args...)...)
+		cmd.Env = os.Environ()
+		if url, err := gitOutput(repoPath, "remote", "get-url", "origin"); err == nil && opts.Token != "" && strings.HasPrefix(url, "https://") {
+			cmd.Env = append(cmd.Env,
+				"GIT_CONFIG_COUNT=1",
+				"GIT_CONFIG_KEY_0=http.extraHeader",
+				"GIT_CONFIG_VALUE_0=Authorization: "+authHeader(opts.Provider, opts.Token),
+			)
+		}
+		if err := runWithProgress(ctx, cmd, opts.Progress); err != nil {
+			return fmt.Errorf("git fetch: %w", err)
+		}
+		return nil
+	}
+
+	if shape.SingleBranch {
+		if _, err := gitOutput(repoPath, "remote", "set-branches", "origin", "*"); err != nil {
+			return err
+		}
+	}
+	if shape.Partial != "" {
+		// Drop the filter so that the refetch, and later fetches, get
+		// everything.
+		if _, err := gitOutput(repoPath, "config", "--unset", "remote.origin.partialclonefilter"); err != nil {
+			return err
+		}
+		args := []string{"--refetch", "origin"}
+		if shape.Depth > 0 {
+			args = append([]string{"--unshallow"}, args...)
+		}
+		return fetch(args...)
+	}
+	if shape.Depth > 0 {
+		return fetch("--unshallow", "origin")
+	}
+	if shape.SingleBranch {
+		return fetch("origin")
+	}
+	return nil
+}
+
+// gitOutput runs git in repoPath and returns its trimmed output.
+func gitOutput(repoPath string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", repoPath}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,133 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to the Gitea (and Forgejo) REST API with an access token.
+type Client struct {
+	baseURL string // e.g. "https://codeberg.org"
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the Gitea instance at baseURL.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type pullRequest struct {
+	Number  int    `json:"number"`
+	Merged  bool   `json:"merged"`
+	HTMLURL string `json:"html_url"`
+}
+
+// CreatePullRequest opens a pull request and returns its web URL.
+func (c *Client) CreatePullRequest(owner, repo, title, body, baseBranch, headBranch string) (string, error) {
+	var pr pullRequest
+	err := c.do(http.MethodPost, fmt.Sprintf("/api/v1/repos/%s/%s/pulls", url.PathEscape(owner), url.PathEscape(repo)), map[string]any{
+		"head":  headBranch,
+		"base":  baseBranch,
+		"title": title,
+		"body":  body,
+	}, &pr)
+	if err != nil {
+		return "", fmt.Errorf("create pull request: %w", err)
+	}
+	return pr.HTMLURL, nil
+}
+
+// MergePullRequest merges the pull request at prURL.
+// strategy should be "squash", "merge", or "rebase". An already merged
+// pull request is not an error.
+func (c *Client) MergePullRequest(prURL, strategy string, deleteBranch bool) error {
+	owner, repo, number, ok := ParsePullRequestURL(prURL)
+	if !ok {
+		return fmt.Errorf("not a pull request URL: %s", prURL)
+	}
+	switch strategy {
+	case "merge", "rebase":
+	default:
+		strategy = "squash"
+	}
+	endpoint := fmt.Sprintf("/api/v1/repos/%s/%s/pulls/%d", url.PathEscape(owner), url.PathEscape(repo), number)
+
+	err := c.do(http.MethodPost, endpoint+"/merge", map[string]any{
+		"Do":                        strategy,
+		"delete_branch_after_merge": deleteBranch,
+	}, nil)
+	if err == nil {
+		return nil
+	}
+
+	var pr pullRequest
+	if getErr := c.do(http.MethodGet, endpoint, nil, &pr); getErr == nil && pr.Merged {
+		return nil
+	}
+	return fmt.Errorf("merge pull request: %w", err)
+}
+
+// ParsePullRequestURL extracts owner, repo and number from a pull request
+// web URL such as https://codeberg.org/owner/repo/pulls/12.
+func ParsePullRequestURL(prURL string) (owner, repo string, number int, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(prURL))
+	if err != nil {
+		return "", "", 0, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pulls" {
+		return "", "", 0, false
+	}
+	number, err = strconv.Atoi(parts[3])
+	if err != nil {
+		return "", "", 0, false
+	}
+	return parts[0], parts[1], number, true
+}
+
+func (c *Client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("gitea %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
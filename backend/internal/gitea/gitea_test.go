@@ -0,0 +1,75 @@
+package gitea
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePullRequestURL(t *testing.T) {
+	tests := []struct {
+		input  string
+		owner  string
+		repo   string
+		number int
+		ok     bool
+	}{
+		{"https://codeberg.org/owner/repo/pulls/12", "owner", "repo", 12, true},
+		{"https://codeberg.org/owner/repo/pulls/12/files", "owner", "repo", 12, true},
+		{"https://codeberg.org/owner/repo/issues/12", "", "", 0, false},
+		{"https://codeberg.org/owner/repo", "", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			owner, repo, number, ok := ParsePullRequestURL(tt.input)
+			if owner != tt.owner || repo != tt.repo || number != tt.number || ok != tt.ok {
+				t.Errorf("ParsePullRequestURL(%q) = %q, %q, %d, %v", tt.input, owner, repo, number, ok)
+			}
+		})
+	}
+}
+
+func TestCreateAndMergePullRequest(t *testing.T) {
+	var merged map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/owner/repo/pulls":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["head"] != "feature" || body["base"] != "main" {
+				t.Errorf("unexpected body: %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 4, "html_url": "https://codeberg.org/owner/repo/pulls/4"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/owner/repo/pulls/4/merge":
+			json.NewDecoder(r.Body).Decode(&merged)
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "secret")
+	url, err := c.CreatePullRequest("owner", "repo", "Title", "Body", "main", "feature")
+	if err != nil {
+		t.Fatalf("CreatePullRequest: %v", err)
+	}
+	if url != "https://codeberg.org/owner/repo/pulls/4" {
+		t.Fatalf("unexpected url %q", url)
+	}
+
+	if err := c.MergePullRequest(url, "rebase", true); err != nil {
+		t.Fatalf("MergePullRequest: %v", err)
+	}
+	if merged["Do"] != "rebase" || merged["delete_branch_after_merge"] != true {
+		t.Errorf("unexpected merge body: %v", merged)
+	}
+}
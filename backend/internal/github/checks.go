@@ -0,0 +1,148 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// CI states of a check and of a commit as a whole.
+const (
+	CIPending = "pending"
+	CISuccess = "success"
+	CIFailure = "failure"
+	CISkipped = "skipped" // a check that was skipped or neutral
+	CINone    = "none"    // a commit without checks
+)
+
+// CIStatus is what GitHub's check runs and commit statuses say about a
+// commit.
+type CIStatus struct {
+	SHA    string    `json:"sha"`
+	State  string    `json:"state"`
+	Checks []CICheck `json:"checks"`
+}
+
+// CICheck is one check run or commit status.
+type CICheck struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	URL   string `json:"url,omitempty"`
+}
+
+// Failed returns the checks that failed.
+func (s *CIStatus) Failed() []CICheck {
+	var failed []CICheck
+	for _, check := range s.Checks {
+		if check.State == CIFailure {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// CommitCIStatus reads the check runs and commit statuses of ref (a branch
+// or commit) in ownerRepo ("owner/repo") through the gh CLI.
+func CommitCIStatus(ctx context.Context, ownerRepo, ref string) (*CIStatus, error) {
+	base := fmt.Sprintf("repos/%s/commits/%s", ownerRepo, url.PathEscape(ref))
+	runs, err := ghAPI(ctx, base+"/check-runs?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := ghAPI(ctx, base+"/status?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+	return parseCIStatus(runs, statuses)
+}
+
+func ghAPI(ctx context.Context, path string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "gh", "api", path)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("gh api %s: %s: %w", path, strings.TrimSpace(string(exitErr.Stderr)), err)
+		}
+		return nil, fmt.Errorf("gh api %s: %w", path, err)
+	}
+	return output, nil
+}
+
+// parseCIStatus combines a check-runs response and a combined status
+// response. A commit fails when any check does, is pending while any check
+// still runs, and succeeds otherwise.
+func parseCIStatus(runsJSON, statusJSON []byte) (*CIStatus, error) {
+	var runs struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			HeadSHA    string `json:"head_sha"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := json.Unmarshal(runsJSON, &runs); err != nil {
+		return nil, fmt.Errorf("parse check runs: %w", err)
+	}
+	var combined struct {
+		SHA      string `json:"sha"`
+		Statuses []struct {
+			Context   string `json:"context"`
+			State     string `json:"state"`
+			TargetURL string `json:"target_url"`
+		} `json:"statuses"`
+	}
+	if err := json.Unmarshal(statusJSON, &combined); err != nil {
+		return nil, fmt.Errorf("parse commit status: %w", err)
+	}
+
+	status := &CIStatus{SHA: combined.SHA, Checks: []CICheck{}}
+	for _, run := range runs.CheckRuns {
+		if status.SHA == "" {
+			status.SHA = run.HeadSHA
+		}
+		status.Checks = append(status.Checks, CICheck{Name: run.Name, State: checkRunState(run.Status, run.Conclusion), URL: run.HTMLURL})
+	}
+	for _, st := range combined.Statuses {
+		state := CIPending
+		switch st.State {
+		case "success":
+			state = CISuccess
+		case "failure", "error":
+			state = CIFailure
+		}
+		status.Checks = append(status.Checks, CICheck{Name: st.Context, State: state, URL: st.TargetURL})
+	}
+
+	status.State = CINone
+	for _, check := range status.Checks {
+		switch {
+		case check.State == CIFailure:
+			status.State = CIFailure
+		case check.State == CIPending && status.State != CIFailure:
+			status.State = CIPending
+		case status.State == CINone && (check.State == CISuccess || check.State == CISkipped):
+			status.State = CISuccess
+		}
+	}
+	return status, nil
+}
+
+func checkRunState(status, conclusion string) string {
+	if status != "completed" {
+		return CIPending
+	}
+	switch conclusion {
+	case "success":
+		return CISuccess
+	case "neutral", "skipped":
+		return CISkipped
+	case "stale":
+		return CIPending
+	}
+	// failure, cancelled, timed_out, action_required, startup_failure
+	return CIFailure
+}
@@ -82,3 +82,71 @@ func TestIsAlreadyMergedPRFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCIStatus(t *testing.T) {
+	runs := `{"check_runs": [
+		{"name": "build", "head_sha": "abc", "status": "completed", "conclusion": "success", "html_url": "https://github.com/o/r/runs/1"},
+		{"name": "lint", "head_sha": "abc", "status": "completed", "conclusion": "skipped"},
+		{"name": "test", "head_sha": "abc", "status": "in_progress", "conclusion": null}
+	]}`
+	noStatuses := `{"sha": "abc", "statuses": []}`
+
+	status, err := parseCIStatus([]byte(runs), []byte(noStatuses))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.SHA != "abc" || status.State != CIPending || len(status.Checks) != 3 {
+		t.Fatalf("expected a pending commit with three checks, got %+v", status)
+	}
+
+	failing := `{"sha": "abc", "statuses": [{"context": "ci/deploy", "state": "error", "target_url": "https://ci.example.com/1"}]}`
+	status, err = parseCIStatus([]byte(runs), []byte(failing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != CIFailure {
+		t.Fatalf("expected a failure to win over pending checks, got %q", status.State)
+	}
+	if failed := status.Failed(); len(failed) != 1 || failed[0].Name != "ci/deploy" {
+		t.Errorf("expected ci/deploy to be the failed check, got %+v", failed)
+	}
+
+	status, err = parseCIStatus([]byte(`{"check_runs": []}`), []byte(`{"sha": "def", "statuses": []}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != CINone || status.SHA != "def" {
+		t.Errorf("expected no checks, got %+v", status)
+	}
+}
+
+func TestParseReviewComments(t *testing.T) {
+	reviews := `[
+		{"id": 1, "user": {"login": "alice"}, "body": "Needs tests.", "state": "CHANGES_REQUESTED", "html_url": "https://github.com/o/r/pull/7#pullrequestreview-1", "submitted_at": "2026-01-02T10:00:00Z"},
+		{"id": 2, "user": {"login": "bob"}, "body": "", "state": "APPROVED", "submitted_at": "2026-01-02T11:00:00Z"}
+	]`
+	comments := `[
+		{"pull_request_review_id": 1, "user": {"login": "alice"}, "body": "Handle nil here.", "path": "main.go", "line": 12, "html_url": "https://github.com/o/r/pull/7#discussion_r1", "created_at": "2026-01-02T09:59:00Z"},
+		{"pull_request_review_id": 1, "in_reply_to_id": 5, "user": {"login": "carol"}, "body": "Agreed.", "path": "main.go", "line": 12, "created_at": "2026-01-02T10:30:00Z"},
+		{"pull_request_review_id": 1, "user": {"login": "alice"}, "body": "Outdated.", "path": "util.go", "line": null, "original_line": 3, "created_at": "2026-01-02T09:58:00Z"}
+	]`
+
+	got, err := parseReviewComments([]byte(reviews), []byte(comments))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected the summary and two line comments, got %+v", got)
+	}
+	if got[0].Path != "util.go" || got[0].Line != 3 || got[1].Line != 12 || got[2].Body != "Needs tests." {
+		t.Errorf("unexpected comments, oldest first: %+v", got)
+	}
+
+	repo, number, err := ParsePRURL("https://github.com/o/r/pull/7")
+	if err != nil || repo != "o/r" || number != 7 {
+		t.Errorf("ParsePRURL() = %q, %d, %v", repo, number, err)
+	}
+	if _, _, err := ParsePRURL("https://github.com/o/r/issues/7"); err == nil {
+		t.Error("expected an issue URL to be refused")
+	}
+}
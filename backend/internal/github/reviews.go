@@ -0,0 +1,132 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReviewComment is a comment left in a pull request review: a line comment
+// when Path is set, or the summary of a review otherwise.
+type ReviewComment struct {
+	ReviewID  int64     `json:"reviewId"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	Path      string    `json:"path,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	State     string    `json:"state,omitempty"` // of the review, for summaries
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ParsePRURL splits a pull request URL such as
+// https://github.com/owner/repo/pull/12 into "owner/repo" and 12.
+func ParsePRURL(prURL string) (string, int, error) {
+	u, err := url.Parse(strings.TrimSpace(prURL))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid pull request URL: %w", err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" {
+		return "", 0, fmt.Errorf("not a pull request URL: %s", prURL)
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return "", 0, fmt.Errorf("not a pull request URL: %s", prURL)
+	}
+	return parts[0] + "/" + parts[1], number, nil
+}
+
+// ReviewComments reads the review comments of a pull request through the gh
+// CLI, oldest first: those of one review, or of every review when reviewID
+// is 0. Replies and empty review summaries are left out.
+func ReviewComments(ctx context.Context, prURL string, reviewID int64) ([]ReviewComment, error) {
+	repo, number, err := ParsePRURL(prURL)
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("repos/%s/pulls/%d", repo, number)
+
+	reviewsPath := base + "/reviews?per_page=100"
+	commentsPath := base + "/comments?per_page=100"
+	if reviewID != 0 {
+		reviewsPath = fmt.Sprintf("%s/reviews/%d", base, reviewID)
+		commentsPath = fmt.Sprintf("%s/reviews/%d/comments?per_page=100", base, reviewID)
+	}
+	reviews, err := ghAPI(ctx, reviewsPath)
+	if err != nil {
+		return nil, err
+	}
+	if reviewID != 0 {
+		reviews = append(append([]byte("["), reviews...), ']')
+	}
+	comments, err := ghAPI(ctx, commentsPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseReviewComments(reviews, comments)
+}
+
+func parseReviewComments(reviewsJSON, commentsJSON []byte) ([]ReviewComment, error) {
+	var reviews []struct {
+		ID   int64 `json:"id"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Body        string    `json:"body"`
+		State       string    `json:"state"`
+		HTMLURL     string    `json:"html_url"`
+		SubmittedAt time.Time `json:"submitted_at"`
+	}
+	if err := json.Unmarshal(reviewsJSON, &reviews); err != nil {
+		return nil, fmt.Errorf("parse reviews: %w", err)
+	}
+	var comments []struct {
+		PullRequestReviewID int64 `json:"pull_request_review_id"`
+		InReplyToID         int64 `json:"in_reply_to_id"`
+		User                struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Body         string    `json:"body"`
+		Path         string    `json:"path"`
+		Line         int       `json:"line"`
+		OriginalLine int       `json:"original_line"`
+		HTMLURL      string    `json:"html_url"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+	if err := json.Unmarshal(commentsJSON, &comments); err != nil {
+		return nil, fmt.Errorf("parse review comments: %w", err)
+	}
+
+	var out []ReviewComment
+	for _, review := range reviews {
+		if strings.TrimSpace(review.Body) == "" {
+			continue
+		}
+		out = append(out, ReviewComment{
+			ReviewID: review.ID, Author: review.User.Login, Body: review.Body,
+			State: review.State, URL: review.HTMLURL, CreatedAt: review.SubmittedAt,
+		})
+	}
+	for _, c := range comments {
+		if c.InReplyToID != 0 || strings.TrimSpace(c.Body) == "" {
+			continue
+		}
+		line := c.Line
+		if line == 0 {
+			// The line is gone from the latest diff; point at where it was.
+			line = c.OriginalLine
+		}
+		out = append(out, ReviewComment{
+			ReviewID: c.PullRequestReviewID, Author: c.User.Login, Body: c.Body,
+			Path: c.Path, Line: line, URL: c.HTMLURL, CreatedAt: c.CreatedAt,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
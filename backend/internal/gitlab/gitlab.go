@@ -0,0 +1,133 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client talks to the GitLab REST API (v4) with a personal access token.
+type Client struct {
+	baseURL string // e.g. "https://gitlab.com"
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the GitLab instance at baseURL.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type mergeRequest struct {
+	IID    int    `json:"iid"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
+}
+
+// CreateMergeRequest opens a merge request and returns its web URL.
+// projectPath is the full "group/project" path.
+func (c *Client) CreateMergeRequest(projectPath, title, body, targetBranch, sourceBranch string) (string, error) {
+	var mr mergeRequest
+	err := c.do(http.MethodPost, projectAPIPath(projectPath)+"/merge_requests", map[string]any{
+		"source_branch": sourceBranch,
+		"target_branch": targetBranch,
+		"title":         title,
+		"description":   body,
+	}, &mr)
+	if err != nil {
+		return "", fmt.Errorf("create merge request: %w", err)
+	}
+	return mr.WebURL, nil
+}
+
+// MergeMergeRequest merges the merge request at mrURL.
+// strategy "squash" squashes commits; "merge" and "rebase" use the project's
+// configured merge method. An already merged request is not an error.
+func (c *Client) MergeMergeRequest(mrURL, strategy string, deleteBranch bool) error {
+	projectPath, iid, ok := ParseMergeRequestURL(mrURL)
+	if !ok {
+		return fmt.Errorf("not a merge request URL: %s", mrURL)
+	}
+	endpoint := fmt.Sprintf("%s/merge_requests/%d", projectAPIPath(projectPath), iid)
+
+	err := c.do(http.MethodPut, endpoint+"/merge", map[string]any{
+		"squash":                      strategy == "squash",
+		"should_remove_source_branch": deleteBranch,
+	}, nil)
+	if err == nil {
+		return nil
+	}
+
+	var mr mergeRequest
+	if getErr := c.do(http.MethodGet, endpoint, nil, &mr); getErr == nil && mr.State == "merged" {
+		return nil
+	}
+	return fmt.Errorf("merge merge request: %w", err)
+}
+
+// ParseMergeRequestURL extracts the project path and IID from a merge
+// request web URL such as https://gitlab.com/group/project/-/merge_requests/12.
+func ParseMergeRequestURL(mrURL string) (projectPath string, iid int, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(mrURL))
+	if err != nil {
+		return "", 0, false
+	}
+	project, rest, found := strings.Cut(strings.Trim(u.Path, "/"), "/-/merge_requests/")
+	if !found || project == "" {
+		return "", 0, false
+	}
+	iid, err = strconv.Atoi(strings.SplitN(rest, "/", 2)[0])
+	if err != nil {
+		return "", 0, false
+	}
+	return project, iid, true
+}
+
+func projectAPIPath(projectPath string) string {
+	return "/api/v4/projects/" + url.PathEscape(projectPath)
+}
+
+func (c *Client) do(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("gitlab %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseMergeRequestURL(t *testing.T) {
+	tests := []struct {
+		input   string
+		project string
+		iid     int
+		ok      bool
+	}{
+		{"https://gitlab.com/group/project/-/merge_requests/12", "group/project", 12, true},
+		{"https://gitlab.com/group/sub/project/-/merge_requests/3/diffs", "group/sub/project", 3, true},
+		{"https://gitlab.com/group/project/-/issues/12", "", 0, false},
+		{"https://gitlab.com/group/project/-/merge_requests/abc", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			project, iid, ok := ParseMergeRequestURL(tt.input)
+			if project != tt.project || iid != tt.iid || ok != tt.ok {
+				t.Errorf("ParseMergeRequestURL(%q) = %q, %d, %v", tt.input, project, iid, ok)
+			}
+		})
+	}
+}
+
+func TestCreateAndMergeMergeRequest(t *testing.T) {
+	var merged map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.RawPath == "/api/v4/projects/group%2Fproject/merge_requests":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["source_branch"] != "feature" || body["target_branch"] != "main" {
+				t.Errorf("unexpected body: %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"iid": 7, "web_url": "https://gitlab.example/group/project/-/merge_requests/7"}`))
+		case r.Method == http.MethodPut && r.URL.RawPath == "/api/v4/projects/group%2Fproject/merge_requests/7/merge":
+			json.NewDecoder(r.Body).Decode(&merged)
+			w.Write([]byte(`{"iid": 7, "state": "merged"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "secret")
+	url, err := c.CreateMergeRequest("group/project", "Title", "Body", "main", "feature")
+	if err != nil {
+		t.Fatalf("CreateMergeRequest: %v", err)
+	}
+	if url != "https://gitlab.example/group/project/-/merge_requests/7" {
+		t.Fatalf("unexpected url %q", url)
+	}
+
+	if err := c.MergeMergeRequest(url, "squash", true); err != nil {
+		t.Fatalf("MergeMergeRequest: %v", err)
+	}
+	if merged["squash"] != true || merged["should_remove_source_branch"] != true {
+		t.Errorf("unexpected merge body: %v", merged)
+	}
+}
+
+func TestMergeMergeRequestAlreadyMerged(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte(`{"iid": 7, "state": "merged"}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, "secret")
+	if err := c.MergeMergeRequest(srv.URL+"/group/project/-/merge_requests/7", "merge", false); err != nil {
+		t.Fatalf("expected already merged request to succeed, got %v", err)
+	}
+}
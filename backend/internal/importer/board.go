@@ -0,0 +1,147 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BoardFormat and BoardVersion mark a JSON board dump.
+	BoardFormat  = "codeburg-board"
+	BoardVersion = 1
+)
+
+// Column is a board column in a dump.
+type Column struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Category string `json:"category,omitempty"`
+	WIPLimit int    `json:"wipLimit,omitempty"`
+}
+
+// Board is a dump of a project's board: its columns and its tasks in board
+// order, keyed by task ID.
+type Board struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	Project    string    `json:"project,omitempty"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Columns    []Column  `json:"columns,omitempty"`
+	Tasks      []Issue   `json:"tasks"`
+}
+
+// boardCSVHeader is the header of a CSV dump. The labels cell holds one
+// label per line.
+var boardCSVHeader = []string{"key", "title", "description", "status", "category", "priority", "type", "labels", "url", "pinned", "archived"}
+
+// WriteJSON writes the board as a JSON dump.
+func (b *Board) WriteJSON(w io.Writer) error {
+	b.Format, b.Version = BoardFormat, BoardVersion
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// WriteCSV writes the board's tasks as a CSV dump, a row each. Columns other
+// than the statuses tasks are in are not kept.
+func (b *Board) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(boardCSVHeader); err != nil {
+		return err
+	}
+	for _, t := range b.Tasks {
+		if err := cw.Write([]string{
+			t.Key, t.Title, t.Description, t.Status, t.Category, t.Priority, t.Type,
+			strings.Join(t.Labels, "\n"), t.URL, strconv.FormatBool(t.Pinned), strconv.FormatBool(t.Archived),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadBoard reads a JSON or CSV dump written by WriteJSON or WriteCSV.
+func ReadBoard(data []byte) (*Board, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\ufeff"))
+	if bytes.HasPrefix(data, []byte("{")) {
+		var board Board
+		if err := json.Unmarshal(data, &board); err != nil {
+			return nil, fmt.Errorf("invalid board dump: %w", err)
+		}
+		if board.Format != BoardFormat {
+			return nil, errors.New("invalid board dump: not a Codeburg board")
+		}
+		if board.Version > BoardVersion {
+			return nil, fmt.Errorf("board dump version %d is newer than this server reads", board.Version)
+		}
+		if len(board.Tasks) > MaxIssues {
+			return nil, fmt.Errorf("board dump has more than %d tasks", MaxIssues)
+		}
+		return &board, nil
+	}
+	return readBoardCSV(data)
+}
+
+func readBoardCSV(data []byte) (*Board, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read board csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("board csv has no title column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	board := &Board{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read board csv: %w", err)
+		}
+		if len(board.Tasks) == MaxIssues {
+			return nil, fmt.Errorf("board csv has more than %d tasks", MaxIssues)
+		}
+		task := Issue{
+			Key:         strings.TrimSpace(field(record, "key")),
+			Title:       strings.TrimSpace(field(record, "title")),
+			Description: field(record, "description"),
+			Status:      strings.TrimSpace(field(record, "status")),
+			Category:    strings.TrimSpace(field(record, "category")),
+			Priority:    normalizePriority(field(record, "priority")),
+			Type:        strings.TrimSpace(field(record, "type")),
+			URL:         strings.TrimSpace(field(record, "url")),
+		}
+		if task.Title == "" {
+			continue
+		}
+		for _, label := range strings.Split(field(record, "labels"), "\n") {
+			task.Labels = addLabel(task.Labels, label)
+		}
+		task.Pinned, _ = strconv.ParseBool(field(record, "pinned"))
+		task.Archived, _ = strconv.ParseBool(field(record, "archived"))
+		board.Tasks = append(board.Tasks, task)
+	}
+	return board, nil
+}
@@ -0,0 +1,116 @@
+// Package importer reads issues from other trackers, Jira and Linear, and
+// boards from Codeburg's own dumps into one shape that maps onto tasks, and
+// writes those dumps.
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Statuses are Codeburg's built-in board columns, which issues from other
+// trackers are sorted into.
+const (
+	StatusBacklog    = "backlog"
+	StatusInProgress = "in_progress"
+	StatusInReview   = "in_review"
+	StatusDone       = "done"
+)
+
+// Issue is an issue or task to import.
+type Issue struct {
+	// Key identifies the issue in its source, such as "ENG-12" or a task
+	// ID, so that importing it again finds the task made from it.
+	Key         string   `json:"key"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status"`             // a board column ID
+	Category    string   `json:"category,omitempty"` // the built-in status Status counts as, when it isn't one
+	Priority    string   `json:"priority,omitempty"` // urgent, high, medium or low
+	Type        string   `json:"type,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Pinned      bool     `json:"pinned,omitempty"`
+	Archived    bool     `json:"archived,omitempty"`
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// statusFromName sorts a workflow status by its name, for trackers whose
+// statuses are free-form.
+func statusFromName(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "review"), strings.Contains(name, "qa"), strings.Contains(name, "testing"):
+		return StatusInReview
+	case strings.Contains(name, "progress"), strings.Contains(name, "doing"), strings.Contains(name, "started"):
+		return StatusInProgress
+	case strings.Contains(name, "done"), strings.Contains(name, "closed"), strings.Contains(name, "resolved"),
+		strings.Contains(name, "complete"), strings.Contains(name, "cancel"), strings.Contains(name, "won't"):
+		return StatusDone
+	}
+	return StatusBacklog
+}
+
+// normalizePriority maps a tracker's priority name onto Codeburg's.
+func normalizePriority(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "urgent", "highest", "blocker", "critical", "p0":
+		return "urgent"
+	case "high", "major", "p1":
+		return "high"
+	case "medium", "normal", "p2":
+		return "medium"
+	case "low", "lowest", "minor", "trivial", "p3":
+		return "low"
+	}
+	return ""
+}
+
+// addLabel appends label to labels unless it is blank or already there.
+func addLabel(labels []string, label string) []string {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return labels
+	}
+	for _, l := range labels {
+		if strings.EqualFold(l, label) {
+			return labels
+		}
+	}
+	return append(labels, label)
+}
+
+// doJSON sends req and decodes the JSON response into out, or returns an
+// error with the server's message for non-2xx responses.
+func doJSON(client *http.Client, req *http.Request, service string, out any) error {
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s: %s", service, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", service, err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
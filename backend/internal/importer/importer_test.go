@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJiraCSV(t *testing.T) {
+	data := "\ufeffSummary,Issue key,Issue Type,Status,Status Category,Priority,Labels,Labels,Description\n" +
+		"Fix login,ABC-1,Bug,Code Review,In Progress,Highest,auth,web,\"Loops after\nOAuth\"\n" +
+		"Add export,ABC-2,Story,Selected,To Do,Medium,,,\n" +
+		",ABC-3,Task,Done,Done,,,,\n" +
+		"Old thing,ABC-4,Task,Closed,,Low,,,\n"
+	issues, err := ParseJiraCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []Issue{
+		{Key: "ABC-1", Title: "Fix login", Description: "Loops after\nOAuth", Status: StatusInReview, Priority: "urgent", Type: "bug", Labels: []string{"auth", "web"}},
+		{Key: "ABC-2", Title: "Add export", Status: StatusBacklog, Priority: "medium", Type: "story"},
+		{Key: "ABC-4", Title: "Old thing", Status: StatusDone, Priority: "low", Type: "task"},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("got %+v", issues)
+	}
+
+	if _, err := ParseJiraCSV(strings.NewReader("Key,Title\n")); err == nil {
+		t.Error("expected an error without a Summary column")
+	}
+}
+
+func TestJiraSearch(t *testing.T) {
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "me@example.com" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/rest/api/2/search/jql" || r.URL.Query().Get("jql") != "project = ABC" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		tokens = append(tokens, r.URL.Query().Get("nextPageToken"))
+		if r.URL.Query().Get("nextPageToken") == "" {
+			io.WriteString(w, `{"issues":[{"key":"ABC-1","fields":{"summary":"Fix login","labels":["auth"],
+				"status":{"name":"In Progress","statusCategory":{"key":"indeterminate"}},"priority":{"name":"High"},"issuetype":{"name":"Bug"}}}],
+				"nextPageToken":"p2"}`)
+			return
+		}
+		io.WriteString(w, `{"issues":[{"key":"ABC-2","fields":{"summary":"Ship it","status":{"name":"Done","statusCategory":{"key":"done"}},"issuetype":{"name":"Task"}}}],"isLast":true}`)
+	}))
+	defer srv.Close()
+
+	c := &JiraClient{BaseURL: srv.URL + "/", Email: "me@example.com", Token: "secret"}
+	issues, err := c.Search(context.Background(), "project = ABC")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(issues) != 2 || !reflect.DeepEqual(tokens, []string{"", "p2"}) {
+		t.Fatalf("got %+v after pages %v", issues, tokens)
+	}
+	want := Issue{Key: "ABC-1", Title: "Fix login", Status: StatusInProgress, Priority: "high", Type: "bug", Labels: []string{"auth"}, URL: srv.URL + "/browse/ABC-1"}
+	if !reflect.DeepEqual(issues[0], want) || issues[1].Status != StatusDone {
+		t.Errorf("got %+v", issues)
+	}
+
+	c.Token = "wrong"
+	if _, err := c.Search(context.Background(), "project = ABC"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+}
+
+const linearNode = `{"identifier":"ENG-7","title":"Cache diffs","description":"Slow","url":"https://linear.app/x/issue/ENG-7","priority":2,
+	"state":{"name":"In Review","type":"started"},"labels":{"nodes":[{"name":"perf"}]}}`
+
+func TestParseLinearExport(t *testing.T) {
+	want := []Issue{{Key: "ENG-7", Title: "Cache diffs", Description: "Slow", Status: StatusInReview, Priority: "high", Labels: []string{"perf"}, URL: "https://linear.app/x/issue/ENG-7"}}
+	for _, data := range []string{
+		`{"data":{"issues":{"nodes":[` + linearNode + `]}}}`,
+		`{"issues":{"nodes":[` + linearNode + `]}}`,
+		`[` + linearNode + `]`,
+	} {
+		issues, err := ParseLinearExport([]byte(data))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if !reflect.DeepEqual(issues, want) {
+			t.Errorf("got %+v", issues)
+		}
+	}
+	if _, err := ParseLinearExport([]byte(`{"data":{}}`)); err == nil {
+		t.Error("expected an error without issues")
+	}
+}
+
+func TestLinearIssues(t *testing.T) {
+	var requests []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_api_key" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Variables)
+		if body.Variables["after"] == nil {
+			io.WriteString(w, `{"data":{"issues":{"nodes":[`+linearNode+`],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"issues":{"nodes":[{"identifier":"ENG-8","title":"Later","priority":0,"state":{"name":"Todo","type":"unstarted"}}],"pageInfo":{"hasNextPage":false}}}}`)
+	}))
+	defer srv.Close()
+
+	c := &LinearClient{APIKey: "lin_api_key", URL: srv.URL}
+	issues, err := c.Issues(context.Background(), "ENG")
+	if err != nil {
+		t.Fatalf("issues: %v", err)
+	}
+	if len(issues) != 2 || issues[1].Status != StatusBacklog || issues[1].Priority != "" {
+		t.Fatalf("got %+v", issues)
+	}
+	if len(requests) != 2 || requests[1]["after"] != "c1" || requests[0]["filter"] == nil {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestBoardRoundTrip(t *testing.T) {
+	board := &Board{
+		Project: "p",
+		Columns: []Column{{ID: "backlog", Name: "Backlog", Category: "backlog"}, {ID: "blocked", Name: "Blocked", Category: "in_progress", WIPLimit: 2}},
+		Tasks: []Issue{
+			{Key: "t1", Title: "First, with \"quotes\"", Description: "line 1\nline 2", Status: "blocked", Category: "in_progress", Priority: "high", Type: "bug", Labels: []string{"a, b", "c"}, Pinned: true},
+			{Key: "t2", Title: "Second", Status: "done", Type: "task", URL: "https://example.com/pr/1", Archived: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := board.WriteJSON(&buf); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	read, err := ReadBoard(buf.Bytes())
+	if err != nil {
+		t.Fatalf("read json: %v", err)
+	}
+	if !reflect.DeepEqual(read, board) {
+		t.Errorf("json round trip: got %+v", read)
+	}
+
+	buf.Reset()
+	if err := board.WriteCSV(&buf); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	read, err = ReadBoard(buf.Bytes())
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if !reflect.DeepEqual(read.Tasks, board.Tasks) {
+		t.Errorf("csv round trip: got %+v", read.Tasks)
+	}
+
+	if _, err := ReadBoard([]byte(`{"tasks": []}`)); err == nil {
+		t.Error("expected an error for JSON that isn't a board dump")
+	}
+}
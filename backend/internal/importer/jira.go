@@ -0,0 +1,205 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MaxIssues caps how many issues one import reads.
+const MaxIssues = 5000
+
+// ParseJiraCSV reads the CSV that Jira's issue search exports ("Export CSV
+// (all fields)" or "(current fields)"). Summary is required; Issue key,
+// Issue Type, Status, Status Category, Priority, Description and the
+// repeated Labels columns are used when present.
+func ParseJiraCSV(r io.Reader) ([]Issue, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read jira csv header: %w", err)
+	}
+	columns := make(map[string][]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = append(columns[name], i)
+	}
+	if len(columns["summary"]) == 0 {
+		return nil, errors.New("jira csv has no Summary column")
+	}
+	field := func(record []string, name string) string {
+		for _, i := range columns[name] {
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var issues []Issue
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read jira csv: %w", err)
+		}
+		if len(issues) == MaxIssues {
+			return nil, fmt.Errorf("jira csv has more than %d issues", MaxIssues)
+		}
+		title := field(record, "summary")
+		if title == "" {
+			continue
+		}
+		issue := Issue{
+			Key:         firstNonEmpty(field(record, "issue key"), field(record, "issue id")),
+			Title:       title,
+			Description: field(record, "description"),
+			Status:      jiraStatus(field(record, "status"), jiraCSVCategory(field(record, "status category"))),
+			Priority:    normalizePriority(field(record, "priority")),
+			Type:        strings.ToLower(field(record, "issue type")),
+		}
+		for _, i := range columns["labels"] {
+			if i < len(record) {
+				issue.Labels = addLabel(issue.Labels, record[i])
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// jiraCSVCategory maps the Status Category column of a CSV export onto the
+// category keys of the REST API.
+func jiraCSVCategory(name string) string {
+	switch strings.ToLower(name) {
+	case "to do":
+		return "new"
+	case "in progress":
+		return "indeterminate"
+	case "done":
+		return "done"
+	}
+	return ""
+}
+
+// jiraStatus sorts a Jira status by its category, "new", "indeterminate" or
+// "done", telling review apart from work in progress by name.
+func jiraStatus(name, category string) string {
+	switch category {
+	case "new":
+		return StatusBacklog
+	case "done":
+		return StatusDone
+	case "indeterminate":
+		if statusFromName(name) == StatusInReview {
+			return StatusInReview
+		}
+		return StatusInProgress
+	}
+	return statusFromName(name)
+}
+
+// JiraClient searches issues with the Jira REST API.
+type JiraClient struct {
+	BaseURL string // e.g. "https://example.atlassian.net"
+	// Email and Token are an Atlassian account and its API token. Without
+	// an email, Token is sent as a personal access token, as Jira Data
+	// Center expects.
+	Email string
+	Token string
+	HTTP  *http.Client
+}
+
+type jiraSearchResponse struct {
+	Issues []struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string   `json:"summary"`
+			Description string   `json:"description"`
+			Labels      []string `json:"labels"`
+			Status      struct {
+				Name           string `json:"name"`
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"status"`
+			Priority *struct {
+				Name string `json:"name"`
+			} `json:"priority"`
+			IssueType struct {
+				Name string `json:"name"`
+			} `json:"issuetype"`
+		} `json:"fields"`
+	} `json:"issues"`
+	NextPageToken string `json:"nextPageToken"`
+	IsLast        bool   `json:"isLast"`
+}
+
+// Search returns the issues a JQL query finds, up to MaxIssues.
+func (c *JiraClient) Search(ctx context.Context, jql string) ([]Issue, error) {
+	baseURL := strings.TrimSuffix(c.BaseURL, "/")
+	var issues []Issue
+	pageToken := ""
+	for {
+		query := url.Values{
+			"jql":        {jql},
+			"fields":     {"summary,description,status,priority,labels,issuetype"},
+			"maxResults": {"100"},
+		}
+		if pageToken != "" {
+			query.Set("nextPageToken", pageToken)
+		}
+		var page jiraSearchResponse
+		if err := c.get(ctx, baseURL+"/rest/api/2/search/jql?"+query.Encode(), &page); err != nil {
+			return nil, err
+		}
+		for _, raw := range page.Issues {
+			f := raw.Fields
+			issue := Issue{
+				Key:         raw.Key,
+				Title:       strings.TrimSpace(f.Summary),
+				Description: strings.TrimSpace(f.Description),
+				Status:      jiraStatus(f.Status.Name, f.Status.StatusCategory.Key),
+				Type:        strings.ToLower(f.IssueType.Name),
+				URL:         baseURL + "/browse/" + raw.Key,
+			}
+			if f.Priority != nil {
+				issue.Priority = normalizePriority(f.Priority.Name)
+			}
+			for _, label := range f.Labels {
+				issue.Labels = addLabel(issue.Labels, label)
+			}
+			issues = append(issues, issue)
+			if len(issues) == MaxIssues {
+				return issues, nil
+			}
+		}
+		if page.IsLast || page.NextPageToken == "" || len(page.Issues) == 0 {
+			return issues, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (c *JiraClient) get(ctx context.Context, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Email != "" {
+		req.SetBasicAuth(c.Email, c.Token)
+	} else if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return doJSON(c.HTTP, req, "jira", out)
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const linearAPIURL = "https://api.linear.app/graphql"
+
+// LinearIssuesQuery is the GraphQL query the Linear client pages through.
+// Its response, saved from Linear's API explorer or a script, is what
+// ParseLinearExport reads.
+const LinearIssuesQuery = `query Issues($filter: IssueFilter, $after: String) {
+  issues(first: 100, after: $after, filter: $filter) {
+    nodes {
+      identifier
+      title
+      description
+      url
+      priority
+      state { name type }
+      labels { nodes { name } }
+    }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+type linearIssue struct {
+	Identifier  string `json:"identifier"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Priority    int    `json:"priority"`
+	State       struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"state"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+type linearIssueConnection struct {
+	Nodes    []linearIssue `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+func (li linearIssue) issue() Issue {
+	issue := Issue{
+		Key:         li.Identifier,
+		Title:       strings.TrimSpace(li.Title),
+		Description: strings.TrimSpace(li.Description),
+		Status:      linearStatus(li.State.Name, li.State.Type),
+		Priority:    linearPriority(li.Priority),
+		URL:         li.URL,
+	}
+	for _, label := range li.Labels.Nodes {
+		issue.Labels = addLabel(issue.Labels, label.Name)
+	}
+	return issue
+}
+
+// linearStatus sorts a Linear workflow state by its type, telling review
+// apart from work in progress by name.
+func linearStatus(name, stateType string) string {
+	switch stateType {
+	case "triage", "backlog", "unstarted":
+		return StatusBacklog
+	case "started":
+		if statusFromName(name) == StatusInReview {
+			return StatusInReview
+		}
+		return StatusInProgress
+	case "completed", "canceled":
+		return StatusDone
+	}
+	return statusFromName(name)
+}
+
+// linearPriority maps Linear's priorities, 1 (urgent) to 4 (low) with 0 for
+// none.
+func linearPriority(priority int) string {
+	switch priority {
+	case 1:
+		return "urgent"
+	case 2:
+		return "high"
+	case 3:
+		return "medium"
+	case 4:
+		return "low"
+	}
+	return ""
+}
+
+// ParseLinearExport reads issues exported from Linear's GraphQL API: the
+// response to LinearIssuesQuery ({"data": {"issues": {"nodes": [...]}}}),
+// its issues object, or the array of nodes.
+func ParseLinearExport(data []byte) ([]Issue, error) {
+	data = bytes.TrimSpace(data)
+	var nodes []linearIssue
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			return nil, fmt.Errorf("invalid linear export: %w", err)
+		}
+	} else {
+		var export struct {
+			Data struct {
+				Issues *linearIssueConnection `json:"issues"`
+			} `json:"data"`
+			Issues *linearIssueConnection `json:"issues"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("invalid linear export: %w", err)
+		}
+		connection := export.Data.Issues
+		if connection == nil {
+			connection = export.Issues
+		}
+		if connection == nil {
+			return nil, errors.New("invalid linear export: no issues")
+		}
+		nodes = connection.Nodes
+	}
+	if len(nodes) > MaxIssues {
+		return nil, fmt.Errorf("linear export has more than %d issues", MaxIssues)
+	}
+
+	issues := make([]Issue, 0, len(nodes))
+	for _, node := range nodes {
+		if issue := node.issue(); issue.Title != "" {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// LinearClient reads issues with Linear's GraphQL API.
+type LinearClient struct {
+	APIKey string // a personal API key
+	URL    string // the API endpoint; Linear's when empty
+	HTTP   *http.Client
+}
+
+// Issues returns a team's issues, by its key such as "ENG", or everyone's
+// the key can see when team is empty, up to MaxIssues.
+func (c *LinearClient) Issues(ctx context.Context, team string) ([]Issue, error) {
+	variables := map[string]any{}
+	if team != "" {
+		variables["filter"] = map[string]any{"team": map[string]any{"key": map[string]any{"eq": team}}}
+	}
+	var issues []Issue
+	for {
+		var result struct {
+			Data struct {
+				Issues linearIssueConnection `json:"issues"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := c.query(ctx, variables, &result); err != nil {
+			return nil, err
+		}
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("linear: %s", result.Errors[0].Message)
+		}
+		for _, node := range result.Data.Issues.Nodes {
+			issues = append(issues, node.issue())
+			if len(issues) == MaxIssues {
+				return issues, nil
+			}
+		}
+		page := result.Data.Issues.PageInfo
+		if !page.HasNextPage || page.EndCursor == "" {
+			return issues, nil
+		}
+		variables["after"] = page.EndCursor
+	}
+}
+
+func (c *LinearClient) query(ctx context.Context, variables map[string]any, out any) error {
+	body, err := json.Marshal(map[string]any{"query": LinearIssuesQuery, "variables": variables})
+	if err != nil {
+		return err
+	}
+	endpoint := c.URL
+	if endpoint == "" {
+		endpoint = linearAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.APIKey)
+	return doJSON(c.HTTP, req, "linear", out)
+}
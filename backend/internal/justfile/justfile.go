@@ -144,6 +144,12 @@ func (m *Manager) StartRecipe(dir string, recipe string, args ...string) (*exec.
 }
 
 func applyRuntimeEnv(cmd *exec.Cmd) {
+	cmd.Env = RuntimeEnv()
+}
+
+// RuntimeEnv returns the environment recipes run with: the server's own,
+// with the usual toolchain directories on PATH.
+func RuntimeEnv() []string {
 	home := os.Getenv("HOME")
 	pathEntries := []string{
 		"/usr/local/go/bin",
@@ -155,7 +161,7 @@ func applyRuntimeEnv(cmd *exec.Cmd) {
 	}
 
 	pathValue := prependMissingPathEntries(os.Getenv("PATH"), pathEntries)
-	cmd.Env = append(os.Environ(),
+	return append(os.Environ(),
 		"PATH="+pathValue,
 		"GOTOOLCHAIN=auto",
 		"COREPACK_ENABLE_DOWNLOAD_PROMPT=0",
@@ -0,0 +1,177 @@
+// Package llm is the client Codeburg's own language model features use,
+// such as semantic search and session titles. It speaks the OpenAI API,
+// which OpenAI, Ollama, LM Studio, vLLM, OpenRouter and most gateways
+// provide, so any of them can back every feature.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+var defaultHTTPClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Client calls an OpenAI-compatible API.
+type Client struct {
+	BaseURL string // DefaultBaseURL when empty
+	APIKey  string // optional for local servers
+	HTTP    *http.Client
+}
+
+// Message is a turn of a chat completion.
+type Message struct {
+	Role    string `json:"role"` // "system", "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// CompletionRequest asks for a chat completion.
+type CompletionRequest struct {
+	Model       string
+	Messages    []Message
+	MaxTokens   int      // 0 for the server's default
+	Temperature *float64 // nil for the server's default
+}
+
+// Complete returns the model's reply to req's messages.
+func (c *Client) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	body := map[string]any{"model": req.Model, "messages": req.Messages}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+	data, err := c.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return "", fmt.Errorf("chat completion: %w", err)
+	}
+	var result struct {
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("chat completion: invalid response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("chat completion: no choices in response")
+	}
+	return strings.TrimSpace(result.Choices[0].Message.Content), nil
+}
+
+// Embed returns a vector for each text, in order.
+func (c *Client) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	data, err := c.post(ctx, "/embeddings", map[string]any{"model": model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: %w", err)
+	}
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("embeddings: invalid response: %w", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings: invalid response: index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("embeddings: invalid response: no vector for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// SpeechRequest asks for text read aloud.
+type SpeechRequest struct {
+	Model  string
+	Voice  string
+	Input  string
+	Format string // "opus", "mp3", ...; the server's default when empty
+}
+
+// Speech returns the audio of req's input read aloud.
+func (c *Client) Speech(ctx context.Context, req SpeechRequest) ([]byte, error) {
+	body := map[string]any{"model": req.Model, "voice": req.Voice, "input": req.Input}
+	if req.Format != "" {
+		body["response_format"] = req.Format
+	}
+	data, err := c.post(ctx, "/audio/speech", body)
+	if err != nil {
+		return nil, fmt.Errorf("speech: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("speech: empty response")
+	}
+	return data, nil
+}
+
+// Embedder embeds with one model of a client. It satisfies
+// embedding.Embedder.
+type Embedder struct {
+	Client *Client
+	Name   string
+}
+
+func (e *Embedder) Model() string { return e.Name }
+
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.Client.Embed(ctx, e.Name, texts)
+}
+
+// post sends body as JSON to the API path and returns the response body,
+// or an error with the server's message for non-2xx responses.
+func (c *Client) post(ctx context.Context, path string, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(c.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data[:min(len(data), 1024)])))
+	}
+	return data, nil
+}
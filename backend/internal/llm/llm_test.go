@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	var auth string
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&req)
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":" Fix login redirect \n"}}]}`)
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL + "/", APIKey: "sk-key"}
+	temperature := 0.2
+	reply, err := c.Complete(context.Background(), CompletionRequest{
+		Model:       "small",
+		Messages:    []Message{{Role: "user", Content: "title?"}},
+		MaxTokens:   20,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if reply != "Fix login redirect" {
+		t.Errorf("reply = %q", reply)
+	}
+	if auth != "Bearer sk-key" || req["model"] != "small" || req["max_tokens"] != float64(20) || req["temperature"] != 0.2 {
+		t.Errorf("unexpected request: auth=%q %v", auth, req)
+	}
+
+	// Local servers need no key.
+	if _, err := (&Client{BaseURL: srv.URL}).Complete(context.Background(), CompletionRequest{Model: "small"}); err != nil || auth != "" {
+		t.Errorf("expected no authorization header, got %q, %v", auth, err)
+	}
+}
+
+func TestCompleteError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := (&Client{BaseURL: srv.URL}).Complete(context.Background(), CompletionRequest{Model: "missing"})
+	if err == nil || err.Error() != `chat completion: 404 Not Found: {"error":"model not found"}` {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	var req struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		// Out of order, as the API allows.
+		io.WriteString(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer srv.Close()
+
+	e := &Embedder{Client: &Client{BaseURL: srv.URL}, Name: "embed-small"}
+	vectors, err := e.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("embed: %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("unexpected vectors %v", vectors)
+	}
+	if req.Model != "embed-small" || len(req.Input) != 2 || e.Model() != "embed-small" {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	if _, err := e.Embed(context.Background(), []string{"a", "b", "c"}); err == nil {
+		t.Error("expected an error when a vector is missing")
+	}
+}
+
+func TestSpeech(t *testing.T) {
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		io.WriteString(w, "OggS")
+	}))
+	defer srv.Close()
+
+	audio, err := (&Client{BaseURL: srv.URL}).Speech(context.Background(), SpeechRequest{Model: "tts", Voice: "alloy", Input: "Done.", Format: "opus"})
+	if err != nil {
+		t.Fatalf("speech: %v", err)
+	}
+	if string(audio) != "OggS" {
+		t.Errorf("audio = %q", audio)
+	}
+	if req["model"] != "tts" || req["voice"] != "alloy" || req["input"] != "Done." || req["response_format"] != "opus" {
+		t.Errorf("unexpected request %v", req)
+	}
+}
@@ -0,0 +1,275 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	autolinkRe = regexp.MustCompile(`^<(https?://[^\s<>]+|mailto:[^\s<>]+)>`)
+	bareURLRe  = regexp.MustCompile(`^https?://[^\s<>]*[^\s<>.,:;"')\]!?]`)
+	dataImgRe  = regexp.MustCompile(`^data:image/(png|jpeg|gif|webp);base64,[A-Za-z0-9+/=]+$`)
+	schemeRe   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// inline renders inline markdown (emphasis, code, links) in text.
+func (r *renderer) inline(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isPunct(text[i+1]):
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			if n, ok := r.codeSpan(&b, text[i:]); ok {
+				i += n
+				continue
+			}
+
+		case c == '!' && strings.HasPrefix(text[i:], "!["):
+			if n, ok := r.link(&b, text[i+1:], true); ok {
+				i += 1 + n
+				continue
+			}
+
+		case c == '[':
+			if n, ok := r.link(&b, text[i:], false); ok {
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if m := autolinkRe.FindStringSubmatch(text[i:]); m != nil {
+				b.WriteString(anchor(m[1], html.EscapeString(strings.TrimPrefix(m[1], "mailto:"))))
+				i += len(m[0])
+				continue
+			}
+
+		case c == 'h' && (i == 0 || !isWordChar(text[i-1])):
+			if m := bareURLRe.FindString(text[i:]); m != "" {
+				b.WriteString(anchor(m, html.EscapeString(m)))
+				i += len(m)
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if n, ok := r.emphasis(&b, text, i); ok {
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// codeSpan renders a backtick code span at the start of s.
+func (r *renderer) codeSpan(b *strings.Builder, s string) (int, bool) {
+	ticks := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:ticks]
+	end := strings.Index(s[ticks:], fence)
+	if end < 0 {
+		return 0, false
+	}
+	code := s[ticks : ticks+end]
+	if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+		code = code[1 : len(code)-1]
+	}
+	b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+	return 2*ticks + end, true
+}
+
+// emphasis renders **strong**, *em*, __strong__, _em_ or ~~strike~~ at text[i].
+func (r *renderer) emphasis(b *strings.Builder, text string, i int) (int, bool) {
+	c := text[i]
+	run := 1
+	if i+1 < len(text) && text[i+1] == c {
+		run = 2
+	}
+	if c == '~' && run != 2 {
+		return 0, false
+	}
+	if c == '_' && i > 0 && isWordChar(text[i-1]) {
+		return 0, false // intra-word underscores are literal (snake_case)
+	}
+	delim := text[i : i+run]
+	rest := text[i+run:]
+	if rest == "" || rest[0] == ' ' {
+		return 0, false
+	}
+
+	end := findCloser(rest, delim)
+	if end <= 0 || rest[end-1] == ' ' {
+		return 0, false
+	}
+	if c == '_' && end+run < len(rest) && isWordChar(rest[end+run]) {
+		return 0, false
+	}
+
+	inner := r.inline(rest[:end])
+	switch {
+	case c == '~':
+		b.WriteString("<s>" + inner + "</s>")
+	case run == 2:
+		b.WriteString(r.tag("strong", "b", inner))
+	default:
+		b.WriteString(r.tag("em", "i", inner))
+	}
+	return run + end + run, true
+}
+
+// findCloser finds delim in s, skipping code spans and doubled delimiters
+// when looking for a single one.
+func findCloser(s, delim string) int {
+	for j := 0; j < len(s); j++ {
+		if s[j] == '`' {
+			if k := strings.IndexByte(s[j+1:], '`'); k >= 0 {
+				j += k + 1
+				continue
+			}
+		}
+		if strings.HasPrefix(s[j:], delim) {
+			if len(delim) == 1 && j+1 < len(s) && s[j+1] == delim[0] {
+				j++ // part of a strong delimiter
+				continue
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+func (r *renderer) tag(htmlTag, telegramTag, inner string) string {
+	t := htmlTag
+	if r.telegram() {
+		t = telegramTag
+	}
+	return "<" + t + ">" + inner + "</" + t + ">"
+}
+
+// link renders [text](dest) or, for images, [alt](src) at the start of s.
+func (r *renderer) link(b *strings.Builder, s string, image bool) (int, bool) {
+	closeText := matchBracket(s, '[', ']')
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return 0, false
+	}
+	closeDest := matchBracket(s[closeText+1:], '(', ')')
+	if closeDest < 0 {
+		return 0, false
+	}
+	label := s[1:closeText]
+	dest := strings.TrimSpace(s[closeText+2 : closeText+1+closeDest])
+	if sp := strings.IndexAny(dest, " \t"); sp >= 0 {
+		dest = dest[:sp] // drop optional title
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	n := closeText + 1 + closeDest + 1
+
+	if image {
+		src := r.imageSource(dest)
+		switch {
+		case src == "":
+			b.WriteString(html.EscapeString(label))
+		case r.telegram():
+			text := label
+			if text == "" {
+				text = "image"
+			}
+			b.WriteString(anchor(src, html.EscapeString(text)))
+		default:
+			b.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(label) + `">`)
+		}
+		return n, true
+	}
+
+	inner := r.inline(label)
+	if href := r.linkTarget(dest); href != "" {
+		b.WriteString(anchor(href, inner))
+	} else {
+		b.WriteString(inner)
+	}
+	return n, true
+}
+
+// linkTarget returns a safe href for dest, or "" to drop the link.
+func (r *renderer) linkTarget(dest string) string {
+	switch {
+	case dest == "":
+		return ""
+	case strings.HasPrefix(dest, "#"):
+		return dest
+	case schemeRe.MatchString(dest):
+		lower := strings.ToLower(dest)
+		if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "mailto:") {
+			return dest
+		}
+		return ""
+	case strings.HasPrefix(dest, "//"):
+		return "" // protocol-relative
+	case r.opts.ResolveLink != nil:
+		return r.opts.ResolveLink(dest)
+	default:
+		return dest
+	}
+}
+
+// imageSource returns a safe image src for dest, or "" to drop the image.
+func (r *renderer) imageSource(dest string) string {
+	switch {
+	case dest == "":
+		return ""
+	case dataImgRe.MatchString(dest):
+		if r.telegram() {
+			return ""
+		}
+		return dest
+	case schemeRe.MatchString(dest):
+		lower := strings.ToLower(dest)
+		if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+			return dest
+		}
+		return ""
+	case strings.HasPrefix(dest, "//"):
+		return ""
+	case r.opts.ResolveImage != nil:
+		return r.opts.ResolveImage(dest)
+	default:
+		return dest
+	}
+}
+
+func anchor(href, inner string) string {
+	return `<a href="` + html.EscapeString(href) + `">` + inner + `</a>`
+}
+
+// matchBracket returns the index of the bracket closing s[0], or -1.
+func matchBracket(s string, open, close byte) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
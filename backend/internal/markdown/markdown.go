@@ -0,0 +1,330 @@
+// Package markdown renders a safe subset of GitHub-flavoured markdown to HTML.
+//
+// Raw HTML in the source is never passed through: all text is escaped and
+// link destinations are restricted to safe schemes, so the output can be
+// inserted into a page (or sent to Telegram in HTML parse mode) as-is.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Flavor selects the output dialect.
+type Flavor string
+
+const (
+	// FlavorHTML produces regular HTML for the web UI.
+	FlavorHTML Flavor = "html"
+	// FlavorTelegram restricts output to the tags Telegram's HTML parse mode
+	// accepts (b, i, s, code, pre, a, blockquote); other blocks become text.
+	FlavorTelegram Flavor = "telegram"
+)
+
+// Options controls rendering.
+type Options struct {
+	Flavor Flavor
+
+	// ResolveLink rewrites relative link destinations (no scheme, not an
+	// anchor). Returning "" renders the link text without a link.
+	ResolveLink func(dest string) string
+	// ResolveImage rewrites relative image sources. Returning "" renders the
+	// alt text instead of the image.
+	ResolveImage func(dest string) string
+}
+
+// Render converts markdown to sanitized HTML.
+func Render(src string, opts Options) string {
+	if opts.Flavor == "" {
+		opts.Flavor = FlavorHTML
+	}
+	r := &renderer{opts: opts}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	r.blocks(strings.Split(src, "\n"))
+	return strings.TrimRight(r.out.String(), "\n")
+}
+
+type renderer struct {
+	opts Options
+	out  strings.Builder
+}
+
+func (r *renderer) telegram() bool {
+	return r.opts.Flavor == FlavorTelegram
+}
+
+var (
+	headingRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	hrRe          = regexp.MustCompile(`^ {0,3}(-( *-){2,}|\*( *\*){2,}|_( *_){2,}) *$`)
+	fenceRe       = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*([^`\\s]*)")
+	bulletRe      = regexp.MustCompile(`^( {0,3})([-*+])\s+(.*)$`)
+	orderedRe     = regexp.MustCompile(`^( {0,3})(\d{1,9})[.)]\s+(.*)$`)
+	tableSepRe    = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	blockquoteRe  = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	listIndentStr = "  "
+)
+
+// blocks renders a sequence of lines as block elements.
+func (r *renderer) blocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			i = r.fencedCode(lines, i)
+
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+			i++
+
+		case hrRe.MatchString(line):
+			if r.telegram() {
+				r.out.WriteString("——————\n\n")
+			} else {
+				r.out.WriteString("<hr>\n")
+			}
+			i++
+
+		case blockquoteRe.MatchString(line):
+			var inner []string
+			for i < len(lines) {
+				m := blockquoteRe.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				inner = append(inner, m[1])
+				i++
+			}
+			r.blockquote(inner)
+
+		case bulletRe.MatchString(line) || orderedRe.MatchString(line):
+			i = r.list(lines, i)
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableSepRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			i = r.table(lines, i)
+
+		default:
+			i = r.paragraph(lines, i)
+		}
+	}
+}
+
+func (r *renderer) fencedCode(lines []string, start int) int {
+	m := fenceRe.FindStringSubmatch(lines[start])
+	fence, lang := m[1], m[2]
+	var body []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence[:1]) && strings.Trim(trimmed, fence[:1]) == "" && len(trimmed) >= len(fence) {
+			i++
+			break
+		}
+		body = append(body, lines[i])
+	}
+	code := html.EscapeString(strings.Join(body, "\n"))
+	if lang != "" {
+		r.out.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">` + code + "</code></pre>\n")
+	} else {
+		r.out.WriteString("<pre><code>" + code + "</code></pre>\n")
+	}
+	if r.telegram() {
+		r.out.WriteString("\n")
+	}
+	return i
+}
+
+func (r *renderer) heading(level int, text string) {
+	if r.telegram() {
+		r.out.WriteString("<b>" + r.inline(text) + "</b>\n\n")
+		return
+	}
+	tag := "h" + strconv.Itoa(level)
+	r.out.WriteString("<" + tag + ">" + r.inline(text) + "</" + tag + ">\n")
+}
+
+func (r *renderer) blockquote(inner []string) {
+	sub := &renderer{opts: r.opts}
+	sub.blocks(inner)
+	body := strings.TrimRight(sub.out.String(), "\n")
+	if r.telegram() {
+		r.out.WriteString("<blockquote>" + body + "</blockquote>\n\n")
+		return
+	}
+	r.out.WriteString("<blockquote>\n" + body + "\n</blockquote>\n")
+}
+
+func (r *renderer) paragraph(lines []string, start int) int {
+	var text []string
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || (i > start && startsBlock(line)) {
+			break
+		}
+		text = append(text, strings.TrimSpace(line))
+	}
+	body := r.inline(strings.Join(text, "\n"))
+	if r.telegram() {
+		r.out.WriteString(body + "\n\n")
+	} else {
+		r.out.WriteString("<p>" + strings.ReplaceAll(body, "\n", "<br>\n") + "</p>\n")
+	}
+	return i
+}
+
+// startsBlock reports whether a line interrupts a paragraph.
+func startsBlock(line string) bool {
+	return fenceRe.MatchString(line) || headingRe.MatchString(line) || hrRe.MatchString(line) ||
+		blockquoteRe.MatchString(line) || bulletRe.MatchString(line) || orderedRe.MatchString(line)
+}
+
+type listItem struct {
+	lines []string
+}
+
+func (r *renderer) list(lines []string, start int) int {
+	ordered := orderedRe.MatchString(lines[start]) && !bulletRe.MatchString(lines[start])
+	marker := bulletRe
+	if ordered {
+		marker = orderedRe
+	}
+	first := marker.FindStringSubmatch(lines[start])
+	baseIndent := len(first[1])
+	startNum := 1
+	if ordered {
+		startNum, _ = strconv.Atoi(first[2])
+	}
+
+	var items []listItem
+	i := start
+	for i < len(lines) {
+		line := lines[i]
+		if m := marker.FindStringSubmatch(line); m != nil && len(m[1]) == baseIndent {
+			items = append(items, listItem{lines: []string{m[3]}})
+			i++
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			// A blank line continues the list only if more of it follows.
+			next := i + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && (strings.HasPrefix(lines[next], listIndentStr) || marker.MatchString(lines[next])) {
+				items[len(items)-1].lines = append(items[len(items)-1].lines, "")
+				i++
+				continue
+			}
+			break
+		}
+		if strings.HasPrefix(line, listIndentStr) {
+			items[len(items)-1].lines = append(items[len(items)-1].lines, dedent(line, baseIndent+2))
+			i++
+			continue
+		}
+		if startsBlock(line) {
+			break
+		}
+		// Lazy continuation of the item's paragraph.
+		items[len(items)-1].lines = append(items[len(items)-1].lines, strings.TrimSpace(line))
+		i++
+	}
+
+	if r.telegram() {
+		for n, item := range items {
+			prefix := "• "
+			if ordered {
+				prefix = strconv.Itoa(startNum+n) + ". "
+			}
+			sub := &renderer{opts: r.opts}
+			sub.blocks(item.lines)
+			body := strings.TrimRight(sub.out.String(), "\n")
+			body = strings.ReplaceAll(body, "\n\n", "\n")
+			r.out.WriteString(prefix + strings.ReplaceAll(body, "\n", "\n  ") + "\n")
+		}
+		r.out.WriteString("\n")
+		return i
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	if ordered && startNum != 1 {
+		r.out.WriteString("<ol start=\"" + strconv.Itoa(startNum) + "\">\n")
+	} else {
+		r.out.WriteString("<" + tag + ">\n")
+	}
+	for _, item := range items {
+		r.out.WriteString("<li>" + r.listItemBody(item.lines) + "</li>\n")
+	}
+	r.out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// listItemBody renders an item's content; a single paragraph is unwrapped.
+func (r *renderer) listItemBody(lines []string) string {
+	sub := &renderer{opts: r.opts}
+	sub.blocks(lines)
+	body := strings.TrimRight(sub.out.String(), "\n")
+	if strings.HasPrefix(body, "<p>") && strings.Count(body, "<p>") == 1 {
+		end := strings.Index(body, "</p>")
+		body = body[len("<p>"):end] + body[end+len("</p>"):]
+	}
+	return body
+}
+
+func dedent(line string, n int) string {
+	for j := 0; j < n && strings.HasPrefix(line, " "); j++ {
+		line = line[1:]
+	}
+	return line
+}
+
+func (r *renderer) table(lines []string, start int) int {
+	end := start + 2
+	for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+		end++
+	}
+
+	if r.telegram() {
+		r.out.WriteString("<pre>" + html.EscapeString(strings.Join(lines[start:end], "\n")) + "</pre>\n\n")
+		return end
+	}
+
+	r.out.WriteString("<table>\n<thead>\n<tr>")
+	for _, cell := range splitTableRow(lines[start]) {
+		r.out.WriteString("<th>" + r.inline(cell) + "</th>")
+	}
+	r.out.WriteString("</tr>\n</thead>\n")
+	if end > start+2 {
+		r.out.WriteString("<tbody>\n")
+		for _, row := range lines[start+2 : end] {
+			r.out.WriteString("<tr>")
+			for _, cell := range splitTableRow(row) {
+				r.out.WriteString("<td>" + r.inline(cell) + "</td>")
+			}
+			r.out.WriteString("</tr>\n")
+		}
+		r.out.WriteString("</tbody>\n")
+	}
+	r.out.WriteString("</table>\n")
+	return end
+}
+
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+	cells := strings.Split(row, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
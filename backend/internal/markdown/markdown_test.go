@@ -0,0 +1,87 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"paragraph", "hello world", "<p>hello world</p>"},
+		{"heading", "## Title", "<h2>Title</h2>"},
+		{"emphasis", "**bold** and *em* and ~~gone~~", "<p><strong>bold</strong> and <em>em</em> and <s>gone</s></p>"},
+		{"snake case", "use my_var_name here", "<p>use my_var_name here</p>"},
+		{"code span", "run `a <b>` now", "<p>run <code>a &lt;b&gt;</code> now</p>"},
+		{"fenced code", "```go\nfmt.Println(\"<x>\")\n```", "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;x&gt;&#34;)</code></pre>"},
+		{"link", "[docs](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2">docs</a></p>`},
+		{"bare url", "see https://example.com/x.", `<p>see <a href="https://example.com/x">https://example.com/x</a>.</p>`},
+		{"list", "- one\n- two", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>"},
+		{"ordered list", "3. c\n4. d", "<ol start=\"3\">\n<li>c</li>\n<li>d</li>\n</ol>"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n</ul>"},
+		{"blockquote", "> quoted", "<blockquote>\n<p>quoted</p>\n</blockquote>"},
+		{"table", "| a | b |\n|---|---|\n| 1 | 2 |", "<table>\n<thead>\n<tr><th>a</th><th>b</th></tr>\n</thead>\n<tbody>\n<tr><td>1</td><td>2</td></tr>\n</tbody>\n</table>"},
+		{"hr", "a\n\n---\n\nb", "<p>a</p>\n<hr>\n<p>b</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Render(tt.input, Options{})
+			if got != tt.want {
+				t.Errorf("Render(%q)\n got: %q\nwant: %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		input   string
+		mustNot string
+	}{
+		{"<script>alert(1)</script>", "<script>"},
+		{"[x](javascript:alert(1))", "javascript:"},
+		{"![x](javascript:alert(1))", "<img"},
+		{`[x](https://a.com" onmouseover="alert(1))`, `" onmouseover`},
+		{"<img src=x onerror=alert(1)>", "<img"},
+		{"![x](data:image/svg+xml;base64,PHN2Zz4=)", "<img"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Render(tt.input, Options{})
+			if strings.Contains(got, tt.mustNot) {
+				t.Errorf("Render(%q) = %q, must not contain %q", tt.input, got, tt.mustNot)
+			}
+		})
+	}
+}
+
+func TestRenderResolvesRelativeLinks(t *testing.T) {
+	opts := Options{
+		ResolveLink:  func(dest string) string { return "https://host/blob/main/" + dest },
+		ResolveImage: func(dest string) string { return "" },
+	}
+	got := Render("[readme](docs/README.md) [anchor](#top) ![diagram](img/a.png)", opts)
+	want := `<p><a href="https://host/blob/main/docs/README.md">readme</a> <a href="#top">anchor</a> diagram</p>`
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestRenderTelegram(t *testing.T) {
+	input := "# Done\n\n**Fixed** the `bug`:\n\n- one\n- two\n\n| a |\n|---|\n| 1 |"
+	got := Render(input, Options{Flavor: FlavorTelegram})
+	want := "<b>Done</b>\n\n<b>Fixed</b> the <code>bug</code>:\n\n• one\n• two\n\n<pre>| a |\n|---|\n| 1 |</pre>"
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+	for _, tag := range []string{"<p>", "<ul>", "<h1>", "<table>", "<strong>"} {
+		if strings.Contains(got, tag) {
+			t.Errorf("telegram output contains unsupported tag %s", tag)
+		}
+	}
+}
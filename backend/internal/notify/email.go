@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Email security modes.
+const (
+	EmailSTARTTLS = "starttls" // upgrade a plain connection; the default
+	EmailTLS      = "tls"      // implicit TLS, usually on port 465
+	EmailNone     = "none"     // no encryption, for local relays
+)
+
+// Email sends notifications over SMTP.
+type Email struct {
+	Host string
+	// Port defaults to 465 for EmailTLS and 587 otherwise.
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Security is one of EmailSTARTTLS (when empty), EmailTLS or EmailNone.
+	Security string
+	// TLSConfig overrides the TLS settings (optional).
+	TLSConfig *tls.Config
+}
+
+func (e *Email) Name() string { return "email" }
+
+// Notify sends msg as a plain-text email to every recipient.
+func (e *Email) Notify(ctx context.Context, msg Message) error {
+	if e.Host == "" || e.From == "" || len(e.To) == 0 {
+		return fmt.Errorf("email: host, from and to are required")
+	}
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return fmt.Errorf("email: invalid from address: %w", err)
+	}
+	to := make([]*mail.Address, len(e.To))
+	for i, addr := range e.To {
+		if to[i], err = mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("email: invalid to address %q: %w", addr, err)
+		}
+	}
+	data, err := e.message(from, to, msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+
+	client, err := e.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	defer client.Close()
+	if err := e.send(client, from, to, data); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+// dial connects to the server and, unless Security is EmailNone, makes sure
+// the connection is encrypted before anything is sent.
+func (e *Email) dial(ctx context.Context) (*smtp.Client, error) {
+	port := e.Port
+	if port == 0 {
+		port = 587
+		if e.Security == EmailTLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(port))
+	tlsConfig := e.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: e.Host}
+	}
+
+	var conn net.Conn
+	var err error
+	switch e.Security {
+	case EmailTLS:
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	case "", EmailSTARTTLS, EmailNone:
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown security mode %q", e.Security)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if e.Security == "" || e.Security == EmailSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (e *Email) send(client *smtp.Client, from *mail.Address, to []*mail.Address, data []byte) error {
+	if e.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted
+		// connection to anything but localhost.
+		if err := client.Auth(smtp.PlainAuth("", e.Username, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message formats msg as a MIME message. The body lists the URL and links
+// after the text, since plain-text mail can't make the title clickable.
+func (e *Email) message(from *mail.Address, to []*mail.Address, msg Message, now time.Time) ([]byte, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	var text strings.Builder
+	text.WriteString(msg.Body)
+	if msg.URL != "" {
+		text.WriteString("\n\n" + msg.URL)
+	}
+	if len(msg.Links) > 0 {
+		text.WriteString("\n")
+		for _, link := range msg.Links {
+			text.WriteString("\n" + link.Title + ": " + link.URL)
+		}
+	}
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(text.String(), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,54 @@
+// Package notify delivers "needs attention" style notifications to external
+// channels such as ntfy topics, browser Web Push subscriptions and email.
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Message is a notification to deliver.
+type Message struct {
+	Title string
+	Body  string
+	// URL is opened when the notification is clicked (optional).
+	URL string
+	// Image is the URL of a picture to show with the notification, such as
+	// a QR code of URL (optional).
+	Image string
+	// SessionID identifies the session the message is about. Sinks that can
+	// route replies back to a session use it; others may use it to collapse
+	// repeated notifications.
+	SessionID string
+	// Links are extra links, such as artifact downloads, for sinks with room
+	// to list them (optional).
+	Links []Link
+}
+
+// Link is a titled URL listed in a notification.
+type Link struct {
+	Title string
+	URL   string
+}
+
+// Notifier is a notification sink.
+type Notifier interface {
+	// Name identifies the sink in logs.
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// ErrGone is returned when the destination no longer exists (for example an
+// expired Web Push subscription) and should be removed.
+var ErrGone = errors.New("notification destination gone")
+
+var defaultClient = &http.Client{Timeout: 15 * time.Second}
+
+func clientOrDefault(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return defaultClient
+}
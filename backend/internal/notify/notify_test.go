@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestNtfyNotify(t *testing.T) {
+	var got map[string]any
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	n := &Ntfy{Server: srv.URL + "/", Topic: "codeburg-test", Token: "tk_1"}
+	err := n.Notify(t.Context(), Message{Title: "🔔 Fix bug", Body: "needs attention", URL: "https://cb.example/tasks/1"})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if got["topic"] != "codeburg-test" || got["title"] != "🔔 Fix bug" || got["message"] != "needs attention" || got["click"] != "https://cb.example/tasks/1" {
+		t.Errorf("unexpected payload: %v", got)
+	}
+	if auth != "Bearer tk_1" {
+		t.Errorf("expected bearer token, got %q", auth)
+	}
+
+	if err := (&Ntfy{Server: srv.URL}).Notify(t.Context(), Message{}); err == nil {
+		t.Error("expected error without topic")
+	}
+}
+
+func TestWebPushNotify(t *testing.T) {
+	vapid, err := GenerateVAPIDKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Round-trip the stored form.
+	if vapid, err = ParseVAPIDKey(vapid.Encode()); err != nil {
+		t.Fatalf("ParseVAPIDKey: %v", err)
+	}
+
+	// The browser side of the subscription.
+	uaPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+
+	var body []byte
+	var header http.Header
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	var sub Subscription
+	sub.Endpoint = srv.URL + "/push/abc"
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes())
+	sub.Keys.Auth = base64.URLEncoding.EncodeToString(authSecret) // padded form is accepted too
+
+	wp := &WebPush{Key: vapid, Subscription: sub, Subject: "mailto:me@example.com"}
+	if err := wp.Notify(t.Context(), Message{Title: "Task", Body: "needs attention", SessionID: "01HX-session"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	if header.Get("Content-Encoding") != "aes128gcm" || header.Get("Topic") != "01HX-session" {
+		t.Errorf("unexpected headers: %v", header)
+	}
+	auth := header.Get("Authorization")
+	if !strings.HasPrefix(auth, "vapid t=") || !strings.HasSuffix(auth, ", k="+vapid.PublicKey()) {
+		t.Fatalf("unexpected Authorization header %q", auth)
+	}
+	tokenStr := strings.TrimSuffix(strings.TrimPrefix(auth, "vapid t="), ", k="+vapid.PublicKey())
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(tokenStr, claims, func(*jwt.Token) (any, error) {
+		return &vapid.private.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"})); err != nil {
+		t.Fatalf("invalid VAPID token: %v", err)
+	}
+	if claims["aud"] != srv.URL {
+		t.Errorf("expected aud %q, got %v", srv.URL, claims["aud"])
+	}
+
+	plaintext := decryptForTest(t, body, uaPrivate, authSecret)
+	var payload map[string]string
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %q", plaintext)
+	}
+	if payload["title"] != "Task" || payload["body"] != "needs attention" || payload["sessionId"] != "01HX-session" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+
+	status = http.StatusGone
+	if err := wp.Notify(t.Context(), Message{Title: "x"}); !errors.Is(err, ErrGone) {
+		t.Errorf("expected ErrGone, got %v", err)
+	}
+}
+
+// decryptForTest performs the user agent's side of RFC 8291.
+func decryptForTest(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Fatalf("unexpected record size %d", rs)
+	}
+	idLen := int(body[20])
+	asPublicBytes := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatalf("bad keyid: %v", err)
+	}
+	shared, _ := uaPrivate.ECDH(asPublic)
+	cek, nonce, err := deriveContentKeys(shared, authSecret, salt, uaPrivate.PublicKey().Bytes(), asPublicBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if record[len(record)-1] != 0x02 {
+		t.Fatalf("missing last-record delimiter")
+	}
+	return record[:len(record)-1]
+}
+
+// fakeSMTP accepts one mail on a local port and sends what it received on
+// the returned channel: the envelope commands, then the message.
+func fakeSMTP(t *testing.T) (port int, received chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	received = make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		io.WriteString(conn, "220 localhost ESMTP\r\n")
+		var got []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO":
+				io.WriteString(conn, "250-localhost\r\n250 AUTH PLAIN\r\n")
+			case "AUTH":
+				got = append(got, line)
+				io.WriteString(conn, "235 ok\r\n")
+			case "DATA":
+				io.WriteString(conn, "354 go ahead\r\n")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				got = append(got, data.String())
+				io.WriteString(conn, "250 queued\r\n")
+			case "QUIT":
+				io.WriteString(conn, "221 bye\r\n")
+				received <- got
+				return
+			default:
+				got = append(got, line)
+				io.WriteString(conn, "250 ok\r\n")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestEmailNotify(t *testing.T) {
+	port, received := fakeSMTP(t)
+	e := &Email{
+		Host: "127.0.0.1", Port: port, Security: EmailNone,
+		Username: "me", Password: "secret",
+		From: "Codeburg <codeburg@example.com>", To: []string{"me@example.com", "you@example.com"},
+	}
+	err := e.Notify(t.Context(), Message{
+		Title: "Fix login needs attention ✋",
+		Body:  "The claude session is waiting for input.",
+		URL:   "https://cb.example/tasks/1",
+		Links: []Link{{Title: "report.html", URL: "https://cb.example/a/1"}},
+	})
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	got := <-received
+	if len(got) != 5 || !strings.HasPrefix(got[0], "AUTH PLAIN") || got[1] != "MAIL FROM:<codeburg@example.com>" ||
+		got[2] != "RCPT TO:<me@example.com>" || got[3] != "RCPT TO:<you@example.com>" {
+		t.Fatalf("unexpected commands %q", got)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(got[4]))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Fix login needs attention ✋" {
+		t.Errorf("subject = %q (%v)", subject, err)
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	want := "The claude session is waiting for input.\r\n\r\nhttps://cb.example/tasks/1\r\n\r\nreport.html: https://cb.example/a/1"
+	if strings.TrimSpace(string(body)) != want {
+		t.Errorf("body = %q", body)
+	}
+
+	if err := (&Email{Host: "127.0.0.1", From: "not an address", To: []string{"me@example.com"}}).Notify(t.Context(), Message{}); err == nil {
+		t.Error("expected an error for an invalid from address")
+	}
+}
+
+func TestEmailRequiresSTARTTLS(t *testing.T) {
+	port, _ := fakeSMTP(t)
+	e := &Email{Host: "127.0.0.1", Port: port, From: "codeburg@example.com", To: []string{"me@example.com"}}
+	if err := e.Notify(t.Context(), Message{Title: "hi"}); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected the plain connection to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultNtfyServer is used when Ntfy.Server is empty.
+const DefaultNtfyServer = "https://ntfy.sh"
+
+// Ntfy publishes to an ntfy topic (https://ntfy.sh or a self-hosted server).
+type Ntfy struct {
+	Server string
+	Topic  string
+	// Token is an optional access token for protected topics.
+	Token  string
+	Client *http.Client
+}
+
+func (n *Ntfy) Name() string { return "ntfy" }
+
+// Notify publishes msg as a high-priority message using ntfy's JSON API,
+// which keeps non-ASCII titles intact.
+func (n *Ntfy) Notify(ctx context.Context, msg Message) error {
+	if n.Topic == "" {
+		return fmt.Errorf("ntfy: topic is required")
+	}
+	server := strings.TrimSuffix(n.Server, "/")
+	if server == "" {
+		server = DefaultNtfyServer
+	}
+
+	payload := map[string]any{
+		"topic":    n.Topic,
+		"title":    msg.Title,
+		"message":  msg.Body,
+		"priority": 4,
+	}
+	if msg.URL != "" {
+		payload["click"] = msg.URL
+	}
+	if msg.Image != "" {
+		payload["attach"] = msg.Image
+		payload["filename"] = "qr.png"
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	resp, err := clientOrDefault(n.Client).Do(req)
+	if err != nil {
+		return fmt.Errorf("ntfy: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ntfy: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VAPIDKey is the application server key pair that identifies this server to
+// push services (RFC 8292).
+type VAPIDKey struct {
+	private *ecdsa.PrivateKey
+}
+
+// GenerateVAPIDKey creates a new P-256 key pair.
+func GenerateVAPIDKey() (*VAPIDKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKey{private: key}, nil
+}
+
+// ParseVAPIDKey decodes a key produced by VAPIDKey.Encode.
+func ParseVAPIDKey(encoded string) (*VAPIDKey, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode vapid key: %w", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse vapid key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok || key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("vapid key is not a P-256 ECDSA key")
+	}
+	return &VAPIDKey{private: key}, nil
+}
+
+// Encode returns the private key (base64 PKCS#8) for storage.
+func (k *VAPIDKey) Encode() string {
+	der, _ := x509.MarshalPKCS8PrivateKey(k.private)
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+// PublicKey returns the uncompressed public key, base64url-encoded, as
+// browsers expect for PushManager.subscribe's applicationServerKey.
+func (k *VAPIDKey) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.publicBytes())
+}
+
+func (k *VAPIDKey) publicBytes() []byte {
+	pub, _ := k.private.PublicKey.ECDH()
+	return pub.Bytes()
+}
+
+// Subscription is a browser PushSubscription as serialized by toJSON().
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// WebPush sends encrypted Web Push messages (RFC 8030, RFC 8291) to a single
+// browser subscription.
+type WebPush struct {
+	Key          *VAPIDKey
+	Subscription Subscription
+	// Subject is the contact for the push service: a mailto: or https: URL.
+	Subject string
+	Client  *http.Client
+}
+
+func (w *WebPush) Name() string { return "webpush" }
+
+// Notify encrypts msg as JSON ({title, body, url, image, sessionId}) for the
+// subscription's service worker. Returns ErrGone if the subscription has
+// expired or been revoked.
+func (w *WebPush) Notify(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"title":     msg.Title,
+		"body":      msg.Body,
+		"url":       msg.URL,
+		"image":     msg.Image,
+		"sessionId": msg.SessionID,
+	})
+	if err != nil {
+		return err
+	}
+	body, err := encryptPayload(w.Subscription, payload)
+	if err != nil {
+		return fmt.Errorf("webpush: %w", err)
+	}
+	auth, err := w.authorization()
+	if err != nil {
+		return fmt.Errorf("webpush: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webpush: %w", err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Urgency", "high")
+	if msg.SessionID != "" {
+		// Collapses repeated notifications for the same session.
+		req.Header.Set("Topic", pushTopic(msg.SessionID))
+	}
+
+	resp, err := clientOrDefault(w.Client).Do(req)
+	if err != nil {
+		return fmt.Errorf("webpush: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode >= 300:
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webpush: %s: %s", resp.Status, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
+
+// authorization builds the VAPID Authorization header for the endpoint's
+// push service.
+func (w *WebPush) authorization() (string, error) {
+	endpoint, err := url.Parse(w.Subscription.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q", w.Subscription.Endpoint)
+	}
+	subject := w.Subject
+	if subject == "" {
+		subject = "mailto:codeburg@localhost"
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	signed, err := token.SignedString(w.Key.private)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + w.Key.PublicKey(), nil
+}
+
+// pushTopic turns an ID into a Topic header value (at most 32 base64url
+// characters).
+func pushTopic(id string) string {
+	topic := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return -1
+	}, id)
+	if len(topic) > 32 {
+		topic = topic[len(topic)-32:]
+	}
+	return topic
+}
+
+const recordSize = 4096
+
+// encryptPayload encrypts plaintext for the subscription using the
+// aes128gcm content coding (RFC 8188) with Web Push key derivation (RFC 8291).
+func encryptPayload(sub Subscription, plaintext []byte) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64URL(sub.Keys.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64URL(sub.Keys.Auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, fmt.Errorf("invalid auth secret")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	if len(plaintext)+1+16 > recordSize-86 {
+		return nil, fmt.Errorf("payload too large")
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, nonce, err := deriveContentKeys(sharedSecret, authSecret, salt, uaPublicBytes, asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// A single record; the 0x02 delimiter marks it as the last one.
+	record := append(append([]byte{}, plaintext...), 0x02)
+
+	header := make([]byte, 0, 21+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// deriveContentKeys derives the content encryption key and nonce from the
+// ECDH shared secret (RFC 8291 section 3.4).
+func deriveContentKeys(sharedSecret, authSecret, salt, uaPublic, asPublic []byte) (cek, nonce []byte, err error) {
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16); err != nil {
+		return nil, nil, err
+	}
+	if nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12); err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, which is how
+// browsers and push libraries variously serialize subscription keys.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,194 @@
+package portsuggest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DefaultEnvVar is the variable most dev servers read their port from.
+const DefaultEnvVar = "PORT"
+
+var (
+	envVarRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	ErrNoAlternatePort = errors.New("no free alternate port")
+)
+
+// Assignment moves a task's dev server off a contested port. Processes
+// started for the task get EnvVar set to AssignedPort, and tunnels asked for
+// Port go to AssignedPort instead.
+type Assignment struct {
+	Port         int       `json:"port"`
+	AssignedPort int       `json:"assignedPort"`
+	EnvVar       string    `json:"envVar"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ClaimedBy returns the other tasks whose processes announced port in their
+// output or were assigned it. Scan results are ignored, since a scan
+// attributes every host listener to the task that ran it.
+func (m *Manager) ClaimedBy(port int, excludeTaskID string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tasks []string
+	for taskID, suggestions := range m.byTask {
+		if taskID == excludeTaskID {
+			continue
+		}
+		if state, ok := suggestions[port]; ok {
+			if _, fromOutput := state.Sources[sourceOutput]; fromOutput {
+				tasks = append(tasks, taskID)
+			}
+		}
+	}
+	for taskID, assignments := range m.assignments {
+		if taskID == excludeTaskID {
+			continue
+		}
+		for _, a := range assignments {
+			if a.AssignedPort == port {
+				tasks = append(tasks, taskID)
+			}
+		}
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+// AlternatePort picks a free port for a task that cannot use port. It tries
+// port*10+1 through port*10+9 first (5173 -> 51731), so the alternate stays
+// recognizable, then the ports right above port.
+func (m *Manager) AlternatePort(ctx context.Context, port int) (int, error) {
+	listening, err := m.currentListeningSet(ctx, true)
+	if err != nil {
+		return 0, fmt.Errorf("scan listening ports: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	taken := func(p int) bool {
+		if _, ok := listening[p]; ok {
+			return true
+		}
+		for _, assignments := range m.assignments {
+			for _, a := range assignments {
+				if a.AssignedPort == p {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var candidates []int
+	for i := 1; i <= 9; i++ {
+		candidates = append(candidates, port*10+i)
+	}
+	for i := 1; i <= 100; i++ {
+		candidates = append(candidates, port+i)
+	}
+	for _, p := range candidates {
+		if p >= m.minPort && p <= 65535 && !taken(p) {
+			return p, nil
+		}
+	}
+	return 0, ErrNoAlternatePort
+}
+
+// Assign records that the task should run what wants port on assigned,
+// passing it through envVar. It replaces an earlier assignment for the same
+// port.
+func (m *Manager) Assign(taskID string, port, assigned int, envVar string) (Assignment, error) {
+	if envVar == "" {
+		envVar = DefaultEnvVar
+	}
+	if !envVarRe.MatchString(envVar) {
+		return Assignment{}, fmt.Errorf("invalid environment variable name %q", envVar)
+	}
+	if assigned < m.minPort || assigned > 65535 || assigned == port {
+		return Assignment{}, fmt.Errorf("invalid assigned port %d", assigned)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	assignments := m.assignments[taskID]
+	for p, a := range assignments {
+		if p != port && a.EnvVar == envVar {
+			return Assignment{}, fmt.Errorf("%s already carries port %d for this task", envVar, a.AssignedPort)
+		}
+	}
+	if assignments == nil {
+		assignments = make(map[int]*Assignment)
+		m.assignments[taskID] = assignments
+	}
+	a := &Assignment{Port: port, AssignedPort: assigned, EnvVar: envVar, CreatedAt: time.Now()}
+	assignments[port] = a
+	return *a, nil
+}
+
+// Unassign drops the task's assignment for port, reporting whether one
+// existed.
+func (m *Manager) Unassign(taskID string, port int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	assignments := m.assignments[taskID]
+	if _, ok := assignments[port]; !ok {
+		return false
+	}
+	delete(assignments, port)
+	if len(assignments) == 0 {
+		delete(m.assignments, taskID)
+	}
+	return true
+}
+
+// Assignments returns a task's port assignments ordered by port.
+func (m *Manager) Assignments(taskID string) []Assignment {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Assignment, 0, len(m.assignments[taskID]))
+	for _, a := range m.assignments[taskID] {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Port < out[j].Port })
+	return out
+}
+
+// ResolvePort returns where the task actually serves port.
+func (m *Manager) ResolvePort(taskID string, port int) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a, ok := m.assignments[taskID][port]; ok {
+		return a.AssignedPort
+	}
+	return port
+}
+
+// Env returns the environment entries for a task's processes.
+func (m *Manager) Env(taskID string) []string {
+	var env []string
+	for _, a := range m.Assignments(taskID) {
+		env = append(env, fmt.Sprintf("%s=%d", a.EnvVar, a.AssignedPort))
+	}
+	return env
+}
+
+// ForgetTask drops all state for a deleted task.
+func (m *Manager) ForgetTask(taskID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.byTask, taskID)
+	delete(m.lastScan, taskID)
+	delete(m.assignments, taskID)
+}
@@ -0,0 +1,377 @@
+package portsuggest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	sourceCompose    = "compose"
+	sourceDockerfile = "dockerfile"
+	sourceContainer  = "container"
+
+	composeServiceLabel = "com.docker.compose.service"
+	composeWorkDirLabel = "com.docker.compose.project.working_dir"
+)
+
+var (
+	composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+	// composeVarRe matches ${VAR:-default} and ${VAR-default}, whose
+	// default is the best guess at what a port resolves to.
+	composeVarRe = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*:?-([^}]*)\}`)
+)
+
+// DockerPort is a host port a task's containers serve, or would serve once
+// started, and the compose service or container behind it.
+type DockerPort struct {
+	Port    int    `json:"port"`
+	Service string `json:"service,omitempty"`
+	Source  string `json:"source"` // "compose", "dockerfile" or "container"
+}
+
+// Container is a running container as the ContainerLister sees it.
+type Container struct {
+	Name    string
+	Labels  map[string]string
+	Mounts  []string // host paths bound into the container
+	Ports   []int    // host ports published
+	Service string   // compose service, if any
+}
+
+// ContainerLister lists running containers. The default asks the docker
+// CLI; when docker isn't installed or running there are none.
+type ContainerLister interface {
+	ListContainers(ctx context.Context) ([]Container, error)
+}
+
+// SetContainerLister replaces how running containers are found.
+func (m *Manager) SetContainerLister(lister ContainerLister) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.containers = lister
+}
+
+// ScanDocker looks for the ports of a task's containers: services declared
+// in a compose file or ports exposed by a Dockerfile at the root of dir,
+// and running containers started from dir or with part of it mounted.
+// Declared ports are only suggested once something listens on them;
+// running containers are suggested as they are. Each port found is stored
+// with its service name.
+func (m *Manager) ScanDocker(ctx context.Context, taskID, dir string) ([]DockerPort, error) {
+	var found []DockerPort
+	if dir != "" {
+		found = append(found, declaredDockerPorts(dir)...)
+	}
+
+	m.mu.Lock()
+	lister := m.containers
+	m.mu.Unlock()
+	if lister != nil && dir != "" {
+		containers, err := lister.ListContainers(ctx)
+		if err == nil {
+			found = append(found, containerPorts(containers, dir)...)
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	listening, err := m.currentListeningSet(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []DockerPort
+	seen := make(map[DockerPort]bool)
+	for _, p := range found {
+		if p.Port < m.minPort || p.Port > 65535 || seen[p] {
+			continue
+		}
+		seen[p] = true
+		if _, ok := listening[p.Port]; !ok && p.Source != sourceContainer {
+			continue
+		}
+		m.upsertService(taskID, p.Port, p.Source, p.Service)
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Source < out[j].Source
+	})
+	return out, nil
+}
+
+func (m *Manager) upsertService(taskID string, port int, source, service string) {
+	m.upsert(taskID, port, source)
+	if service == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state := m.byTask[taskID][port]; state != nil {
+		if state.Services == nil {
+			state.Services = make(map[string]struct{})
+		}
+		state.Services[service] = struct{}{}
+	}
+}
+
+// declaredDockerPorts reads the compose file and Dockerfile at the root of
+// dir.
+func declaredDockerPorts(dir string) []DockerPort {
+	var out []DockerPort
+	for _, name := range composeFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		out = append(out, ParseCompose(data)...)
+		break
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Dockerfile")); err == nil {
+		for _, port := range ParseDockerfile(data) {
+			out = append(out, DockerPort{Port: port, Service: filepath.Base(dir), Source: sourceDockerfile})
+		}
+	}
+	return out
+}
+
+// containerPorts returns the published ports of the containers that belong
+// to dir: started by compose from it, or with it or a path in it mounted.
+func containerPorts(containers []Container, dir string) []DockerPort {
+	dir = filepath.Clean(dir)
+	var out []DockerPort
+	for _, c := range containers {
+		if !containerInDir(c, dir) {
+			continue
+		}
+		service := firstNonEmpty(c.Service, c.Labels[composeServiceLabel], c.Name)
+		for _, port := range c.Ports {
+			out = append(out, DockerPort{Port: port, Service: service, Source: sourceContainer})
+		}
+	}
+	return out
+}
+
+func containerInDir(c Container, dir string) bool {
+	if wd := c.Labels[composeWorkDirLabel]; wd != "" && filepath.Clean(wd) == dir {
+		return true
+	}
+	for _, mount := range c.Mounts {
+		if mount = filepath.Clean(mount); mount == dir || strings.HasPrefix(mount, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCompose returns the host ports a compose file publishes, with their
+// service names. Ports left for Docker to pick are skipped.
+func ParseCompose(data []byte) []DockerPort {
+	var file struct {
+		Services map[string]struct {
+			Ports []composePort `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil
+	}
+
+	var out []DockerPort
+	for name, service := range file.Services {
+		for _, p := range service.Ports {
+			for _, port := range p.hostPorts() {
+				out = append(out, DockerPort{Port: port, Service: name, Source: sourceCompose})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Port != out[j].Port {
+			return out[i].Port < out[j].Port
+		}
+		return out[i].Service < out[j].Service
+	})
+	return out
+}
+
+// composePort is an entry of a service's ports, in the short syntax
+// ("8080:80", "127.0.0.1:8080:80/tcp", "3000-3001:3000-3001") or the long
+// one ({published: 8080, target: 80}).
+type composePort struct {
+	short     string
+	published string
+}
+
+func (p *composePort) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.short = value.Value
+		return nil
+	}
+	var long struct {
+		Published yaml.Node `yaml:"published"`
+	}
+	if err := value.Decode(&long); err != nil {
+		return err
+	}
+	p.published = long.Published.Value
+	return nil
+}
+
+func (p composePort) hostPorts() []int {
+	if p.published != "" {
+		return portRange(expandComposeVars(p.published))
+	}
+	spec := expandComposeVars(p.short)
+	spec, _, _ = strings.Cut(spec, "/")
+	// The host port is the second to last field; IPv6 addresses come in
+	// brackets.
+	if i := strings.LastIndex(spec, "]"); i >= 0 {
+		spec = strings.TrimPrefix(spec[i+1:], ":")
+	}
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		return nil
+	}
+	return portRange(parts[len(parts)-2])
+}
+
+func expandComposeVars(s string) string {
+	return composeVarRe.ReplaceAllString(s, "$1")
+}
+
+// portRange parses "3000" or "3000-3005".
+func portRange(s string) []int {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	start, err := strconv.Atoi(lo)
+	if err != nil {
+		return nil
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(hi); err != nil || end < start || end-start > 100 {
+			return nil
+		}
+	}
+	var ports []int
+	for p := start; p <= end; p++ {
+		ports = append(ports, p)
+	}
+	return ports
+}
+
+// ParseDockerfile returns the ports a Dockerfile EXPOSEs.
+func ParseDockerfile(data []byte) []int {
+	var ports []int
+	seen := make(map[int]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "EXPOSE") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			spec, _, _ := strings.Cut(field, "/")
+			for _, port := range portRange(spec) {
+				if !seen[port] {
+					seen[port] = true
+					ports = append(ports, port)
+				}
+			}
+		}
+	}
+	return ports
+}
+
+// dockerCLI lists containers with docker ps and docker inspect.
+type dockerCLI struct{}
+
+func (dockerCLI) ListContainers(ctx context.Context) ([]Container, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, nil
+	}
+	ids, err := exec.CommandContext(ctx, "docker", "ps", "-q").Output()
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"inspect"}, strings.Fields(string(ids))...)
+	if len(args) == 1 {
+		return nil, nil
+	}
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseDockerInspect(out)
+}
+
+func parseDockerInspect(data []byte) ([]Container, error) {
+	var inspected []struct {
+		Name   string
+		Config struct {
+			Labels map[string]string
+		}
+		Mounts []struct {
+			Type   string
+			Source string
+		}
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &inspected); err != nil {
+		return nil, err
+	}
+
+	containers := make([]Container, 0, len(inspected))
+	for _, in := range inspected {
+		c := Container{
+			Name:    strings.TrimPrefix(in.Name, "/"),
+			Labels:  in.Config.Labels,
+			Service: in.Config.Labels[composeServiceLabel],
+		}
+		for _, mount := range in.Mounts {
+			if mount.Type == "bind" {
+				c.Mounts = append(c.Mounts, mount.Source)
+			}
+		}
+		seen := make(map[int]bool)
+		for _, bindings := range in.NetworkSettings.Ports {
+			for _, b := range bindings {
+				if port, err := strconv.Atoi(b.HostPort); err == nil && !seen[port] {
+					seen[port] = true
+					c.Ports = append(c.Ports, port)
+				}
+			}
+		}
+		sort.Ints(c.Ports)
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package portsuggest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type fakeContainers []Container
+
+func (f fakeContainers) ListContainers(_ context.Context) ([]Container, error) {
+	return f, nil
+}
+
+func TestParseCompose(t *testing.T) {
+	compose := []byte(`
+services:
+  web:
+    build: .
+    ports:
+      - "5173:5173"
+      - "127.0.0.1:8080:80/tcp"
+      - "3000"
+  api:
+    ports:
+      - "${API_PORT:-4000}:4000"
+      - "[::1]:9229:9229"
+      - target: 5432
+        published: "15432"
+        protocol: tcp
+  worker:
+    image: busybox
+`)
+	got := ParseCompose(compose)
+	want := []DockerPort{
+		{Port: 4000, Service: "api", Source: sourceCompose},
+		{Port: 5173, Service: "web", Source: sourceCompose},
+		{Port: 8080, Service: "web", Source: sourceCompose},
+		{Port: 9229, Service: "api", Source: sourceCompose},
+		{Port: 15432, Service: "api", Source: sourceCompose},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCompose = %+v, want %+v", got, want)
+	}
+	if got := ParseCompose([]byte("services: [")); got != nil {
+		t.Errorf("expected nothing from an invalid file, got %+v", got)
+	}
+}
+
+func TestParseDockerfile(t *testing.T) {
+	dockerfile := []byte("FROM node:22\nexpose 3000\nEXPOSE 8080/tcp 9000-9002 3000\nRUN echo EXPOSE 1234\n")
+	want := []int{3000, 8080, 9000, 9001, 9002}
+	if got := ParseDockerfile(dockerfile); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDockerfile = %v, want %v", got, want)
+	}
+}
+
+func TestParseDockerInspect(t *testing.T) {
+	data := []byte(`[{
+		"Name": "/shop-db-1",
+		"Config": {"Labels": {"com.docker.compose.service": "db"}},
+		"Mounts": [{"Type": "bind", "Source": "/work/shop/data"}, {"Type": "volume", "Source": "/var/lib/docker/volumes/x"}],
+		"NetworkSettings": {"Ports": {"5432/tcp": [{"HostIp": "0.0.0.0", "HostPort": "15432"}, {"HostIp": "::", "HostPort": "15432"}], "9000/tcp": null}}
+	}]`)
+	containers, err := parseDockerInspect(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := Container{
+		Name:    "shop-db-1",
+		Labels:  map[string]string{composeServiceLabel: "db"},
+		Mounts:  []string{"/work/shop/data"},
+		Ports:   []int{15432},
+		Service: "db",
+	}
+	if len(containers) != 1 || !reflect.DeepEqual(containers[0], want) {
+		t.Errorf("parseDockerInspect = %+v, want %+v", containers, want)
+	}
+}
+
+func TestScanDocker(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services:\n  web:\n    ports: [\"5173:5173\", \"6006:6006\"]\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM node\nEXPOSE 5173\n"), 0o644)
+
+	m := NewManager(&fakeScanner{ports: []int{5173, 15432}})
+	m.SetContainerLister(fakeContainers{
+		{Name: "shop-db-1", Service: "db", Labels: map[string]string{composeWorkDirLabel: dir}, Ports: []int{15432}},
+		{Name: "adminer", Mounts: []string{filepath.Join(dir, "config")}, Ports: []int{8081}},
+		{Name: "elsewhere", Mounts: []string{dir + "-other"}, Ports: []int{9999}},
+	})
+
+	found, err := m.ScanDocker(context.Background(), "task-1", dir)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	want := []DockerPort{
+		{Port: 5173, Service: "web", Source: sourceCompose},
+		{Port: 5173, Service: filepath.Base(dir), Source: sourceDockerfile},
+		{Port: 8081, Service: "adminer", Source: sourceContainer},
+		{Port: 15432, Service: "db", Source: sourceContainer},
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("ScanDocker = %+v, want %+v", found, want)
+	}
+
+	suggestions := m.ListTask("task-1")
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %+v", suggestions)
+	}
+	web := suggestions[0]
+	if web.Port != 5173 || !reflect.DeepEqual(web.Sources, []string{sourceCompose, sourceDockerfile}) ||
+		!reflect.DeepEqual(web.Services, []string{filepath.Base(dir), "web"}) {
+		t.Errorf("unexpected suggestion %+v", web)
+	}
+	if db := suggestions[2]; db.Port != 15432 || !reflect.DeepEqual(db.Services, []string{"db"}) {
+		t.Errorf("unexpected suggestion %+v", db)
+	}
+}
@@ -36,20 +36,23 @@ type Scanner interface {
 type Suggestion struct {
 	Port        int       `json:"port"`
 	Sources     []string  `json:"sources"`
+	Services    []string  `json:"services,omitempty"` // compose services or containers serving the port
 	FirstSeenAt time.Time `json:"firstSeenAt"`
 	LastSeenAt  time.Time `json:"lastSeenAt"`
 }
 
 // ScanResult summarizes a scan run.
 type ScanResult struct {
-	ScannedAt          time.Time `json:"scannedAt"`
-	PortsFound         []int     `json:"portsFound"`
-	SuggestionsUpdated int       `json:"suggestionsUpdated"`
+	ScannedAt          time.Time    `json:"scannedAt"`
+	PortsFound         []int        `json:"portsFound"`
+	SuggestionsUpdated int          `json:"suggestionsUpdated"`
+	Docker             []DockerPort `json:"docker,omitempty"` // container ports, from ScanDocker
 }
 
 type suggestionState struct {
 	Port        int
 	Sources     map[string]struct{}
+	Services    map[string]struct{}
 	FirstSeenAt time.Time
 	LastSeenAt  time.Time
 }
@@ -64,11 +67,13 @@ type outputEvent struct {
 type Manager struct {
 	mu sync.Mutex
 
-	scanner Scanner
+	scanner    Scanner
+	containers ContainerLister
 
 	byTask      map[string]map[int]*suggestionState
 	sessionTail map[string]string
 	lastScan    map[string]time.Time
+	assignments map[string]map[int]*Assignment // task -> wanted port
 
 	listenCache   map[int]struct{}
 	listenCacheAt time.Time
@@ -90,9 +95,11 @@ func NewManager(scanner Scanner) *Manager {
 
 	m := &Manager{
 		scanner:        scanner,
+		containers:     dockerCLI{},
 		byTask:         make(map[string]map[int]*suggestionState),
 		sessionTail:    make(map[string]string),
 		lastScan:       make(map[string]time.Time),
+		assignments:    make(map[string]map[int]*Assignment),
 		listenCacheTTL: 3 * time.Second,
 		scanCooldown:   5 * time.Second,
 		suggestionTTL:  30 * time.Minute,
@@ -248,15 +255,20 @@ func (m *Manager) ListTask(taskID string) []Suggestion {
 			continue
 		}
 		sources := make([]string, 0, len(state.Sources))
-		if _, ok := state.Sources[sourceOutput]; ok {
-			sources = append(sources, sourceOutput)
+		for _, source := range []string{sourceOutput, sourceScan, sourceCompose, sourceDockerfile, sourceContainer} {
+			if _, ok := state.Sources[source]; ok {
+				sources = append(sources, source)
+			}
 		}
-		if _, ok := state.Sources[sourceScan]; ok {
-			sources = append(sources, sourceScan)
+		var services []string
+		for service := range state.Services {
+			services = append(services, service)
 		}
+		sort.Strings(services)
 		out = append(out, Suggestion{
 			Port:        state.Port,
 			Sources:     sources,
+			Services:    services,
 			FirstSeenAt: state.FirstSeenAt,
 			LastSeenAt:  state.LastSeenAt,
 		})
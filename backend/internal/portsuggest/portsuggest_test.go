@@ -96,3 +96,77 @@ func TestExtractPorts(t *testing.T) {
 		t.Fatalf("missing expected ports: %#v (got=%#v)", want, ports)
 	}
 }
+
+func TestClaimedBy_IgnoresScanResults(t *testing.T) {
+	m := NewManager(&fakeScanner{ports: []int{5173}})
+	if _, err := m.ScanTask(context.Background(), "task-scan"); err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	m.IngestOutput("task-1", "sess-1", []byte("Local: http://localhost:5173/\n"))
+	waitFor(t, func() bool { return len(m.ListTask("task-1")) == 1 })
+
+	claimed := m.ClaimedBy(5173, "task-2")
+	if len(claimed) != 1 || claimed[0] != "task-1" {
+		t.Fatalf("expected only task-1 to claim 5173, got %v", claimed)
+	}
+	if claimed := m.ClaimedBy(5173, "task-1"); len(claimed) != 0 {
+		t.Fatalf("expected the excluded task to be skipped, got %v", claimed)
+	}
+}
+
+func TestAlternatePort_SkipsTakenPorts(t *testing.T) {
+	m := NewManager(&fakeScanner{ports: []int{5173, 51731}})
+
+	port, err := m.AlternatePort(context.Background(), 5173)
+	if err != nil || port != 51732 {
+		t.Fatalf("expected 51732, got %d (%v)", port, err)
+	}
+	if _, err := m.Assign("task-2", 5173, port, ""); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if next, _ := m.AlternatePort(context.Background(), 5173); next != 51733 {
+		t.Fatalf("expected assigned port to be skipped, got %d", next)
+	}
+
+	// 8080*10 is out of range, so ports right above are used.
+	if port, _ := m.AlternatePort(context.Background(), 8080); port != 8081 {
+		t.Fatalf("expected 8081, got %d", port)
+	}
+}
+
+func TestAssign_EnvAndResolve(t *testing.T) {
+	m := NewManager(&fakeScanner{})
+
+	if _, err := m.Assign("task-1", 5173, 51731, "not-valid"); err == nil {
+		t.Fatal("expected invalid env var to be rejected")
+	}
+	a, err := m.Assign("task-1", 5173, 51731, "")
+	if err != nil || a.EnvVar != DefaultEnvVar {
+		t.Fatalf("unexpected assignment %+v (%v)", a, err)
+	}
+	if _, err := m.Assign("task-1", 3000, 30001, "PORT"); err == nil {
+		t.Fatal("expected a second assignment on the same env var to be rejected")
+	}
+	if _, err := m.Assign("task-1", 3000, 30001, "API_PORT"); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+
+	env := m.Env("task-1")
+	if len(env) != 2 || env[0] != "API_PORT=30001" || env[1] != "PORT=51731" {
+		t.Fatalf("unexpected env: %v", env)
+	}
+	if got := m.ResolvePort("task-1", 5173); got != 51731 {
+		t.Errorf("expected 5173 to resolve to 51731, got %d", got)
+	}
+	if got := m.ResolvePort("task-2", 5173); got != 5173 {
+		t.Errorf("expected other tasks to keep 5173, got %d", got)
+	}
+
+	if !m.Unassign("task-1", 5173) || m.Unassign("task-1", 5173) {
+		t.Error("expected Unassign to report the removal once")
+	}
+	m.ForgetTask("task-1")
+	if len(m.Assignments("task-1")) != 0 {
+		t.Error("expected assignments dropped with the task")
+	}
+}
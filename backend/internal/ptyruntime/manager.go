@@ -1,15 +1,18 @@
 package ptyruntime
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"sync"
 	"time"
 
 	"github.com/creack/pty"
+	"github.com/miguel-bm/codeburg/internal/telemetry"
 )
 
 var (
@@ -27,6 +30,12 @@ type StartOptions struct {
 	Rows     uint16
 	OnExit   func(ExitResult)
 	OnOutput func(sessionID string, chunk []byte)
+
+	// Recording saves the session's output to a file. Optional.
+	Recording *RecordingOptions
+
+	// TraceContext parents the process's trace spans. Optional.
+	TraceContext context.Context
 }
 
 // ExitResult describes process termination.
@@ -34,6 +43,7 @@ type ExitResult struct {
 	SessionID string
 	ExitCode  int
 	Err       error
+	Output    []byte // tail of the process output, for error reporting
 }
 
 // OutputEvent is a streamed chunk from the process PTY.
@@ -46,6 +56,7 @@ type OutputEvent struct {
 type Manager struct {
 	mu       sync.RWMutex
 	sessions map[string]*runtimeSession
+	tmux     *tmuxBackend // nil: processes run directly under an in-process PTY
 }
 
 // NewManager creates a runtime manager.
@@ -64,12 +75,17 @@ type runtimeSession struct {
 
 	mu         sync.Mutex
 	closed     bool
+	stopped    bool      // Stop was requested (tmux runtime only)
+	attachedAt time.Time // when the current tmux client was started
+	cols, rows uint16    // last applied size, reused when a tmux client is respawned
 	seq        uint64
 	ring       []OutputEvent
 	ringBytes  int
 	subs       map[uint64]chan OutputEvent
 	nextSubID  uint64
 	lastOutput time.Time
+	span       *telemetry.Span // covers the process lifetime; nil when not tracing
+	recorder   *recorder       // nil when not recording
 }
 
 const (
@@ -77,6 +93,9 @@ const (
 	defaultRows   = 40
 	maxRingBytes  = 2 * 1024 * 1024
 	subBufferSize = 256
+
+	exitOutputTailBytes = 4 * 1024
+	outputDrainTimeout  = time.Second
 )
 
 // Start creates and starts a runtime session process.
@@ -93,13 +112,102 @@ func (m *Manager) Start(sessionID string, opt StartOptions) error {
 		m.mu.Unlock()
 		return ErrSessionExists
 	}
+	if m.tmux != nil && m.tmux.alive(sessionID) {
+		m.mu.Unlock()
+		return ErrSessionExists
+	}
+
+	traceCtx := opt.TraceContext
+	if traceCtx == nil {
+		traceCtx = context.Background()
+	}
+	backend := "pty"
+	if m.tmux != nil {
+		backend = "tmux"
+	}
+	_, span := telemetry.Start(traceCtx, "pty.process",
+		telemetry.String("session.id", sessionID),
+		telemetry.String("process.command", opt.Command),
+		telemetry.String("pty.backend", backend),
+	)
+
+	cols := opt.Cols
+	rows := opt.Rows
+	if cols == 0 {
+		cols = defaultCols
+	}
+	if rows == 0 {
+		rows = defaultRows
+	}
+
+	var cmd *exec.Cmd
+	if m.tmux != nil {
+		if err := m.tmux.create(sessionID, opt, cols, rows); err != nil {
+			m.mu.Unlock()
+			span.RecordError(err)
+			span.End()
+			return err
+		}
+		cmd = m.tmux.attachCommand(sessionID)
+	} else {
+		cmd = exec.Command(opt.Command, opt.Args...)
+		if opt.WorkDir != "" {
+			cmd.Dir = opt.WorkDir
+		}
+		if len(opt.Env) > 0 {
+			cmd.Env = append(os.Environ(), opt.Env...)
+		}
+	}
+
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
+	if err != nil {
+		m.mu.Unlock()
+		if m.tmux != nil {
+			_ = m.tmux.kill(sessionID)
+		}
+		err = fmt.Errorf("start pty: %w", err)
+		span.RecordError(err)
+		span.End()
+		return err
+	}
+
+	rs := &runtimeSession{
+		id:         sessionID,
+		cmd:        cmd,
+		ptmx:       ptmx,
+		onExit:     opt.OnExit,
+		onOutput:   opt.OnOutput,
+		subs:       make(map[uint64]chan OutputEvent),
+		attachedAt: time.Now(),
+		cols:       cols,
+		rows:       rows,
+		span:       span,
+		recorder:   startRecording(sessionID, opt.Recording, cols, rows),
+	}
+	m.sessions[sessionID] = rs
+	m.mu.Unlock()
+
+	m.run(rs, cmd, ptmx)
+	return nil
+}
+
+// Reattach re-attaches to a session that is still running in tmux, typically
+// after a server restart. opt supplies the callbacks and terminal size; the
+// command fields are ignored. Returns ErrSessionNotFound when the runtime is
+// not tmux-backed or the tmux session no longer exists.
+func (m *Manager) Reattach(sessionID string, opt StartOptions) error {
+	if m.tmux == nil {
+		return ErrSessionNotFound
+	}
 
-	cmd := exec.Command(opt.Command, opt.Args...)
-	if opt.WorkDir != "" {
-		cmd.Dir = opt.WorkDir
+	m.mu.Lock()
+	if _, exists := m.sessions[sessionID]; exists {
+		m.mu.Unlock()
+		return ErrSessionExists
 	}
-	if len(opt.Env) > 0 {
-		cmd.Env = append(os.Environ(), opt.Env...)
+	if !m.tmux.alive(sessionID) {
+		m.mu.Unlock()
+		return ErrSessionNotFound
 	}
 
 	cols := opt.Cols
@@ -111,6 +219,7 @@ func (m *Manager) Start(sessionID string, opt StartOptions) error {
 		rows = defaultRows
 	}
 
+	cmd := m.tmux.attachCommand(sessionID)
 	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: cols, Rows: rows})
 	if err != nil {
 		m.mu.Unlock()
@@ -118,29 +227,43 @@ func (m *Manager) Start(sessionID string, opt StartOptions) error {
 	}
 
 	rs := &runtimeSession{
-		id:       sessionID,
-		cmd:      cmd,
-		ptmx:     ptmx,
-		onExit:   opt.OnExit,
-		onOutput: opt.OnOutput,
-		subs:     make(map[uint64]chan OutputEvent),
+		id:         sessionID,
+		cmd:        cmd,
+		ptmx:       ptmx,
+		onExit:     opt.OnExit,
+		onOutput:   opt.OnOutput,
+		subs:       make(map[uint64]chan OutputEvent),
+		attachedAt: time.Now(),
+		cols:       cols,
+		rows:       rows,
+		recorder:   startRecording(sessionID, opt.Recording, cols, rows),
 	}
 	m.sessions[sessionID] = rs
 	m.mu.Unlock()
 
-	go m.readLoop(rs)
-	go m.waitLoop(rs)
+	m.run(rs, cmd, ptmx)
 	return nil
 }
 
-func (m *Manager) readLoop(rs *runtimeSession) {
+// run starts reading a PTY client's output and waiting for it to exit.
+func (m *Manager) run(rs *runtimeSession, cmd *exec.Cmd, ptmx *os.File) {
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		m.readLoop(rs, ptmx)
+	}()
+	go m.waitLoop(rs, cmd, ptmx, readDone)
+}
+
+func (m *Manager) readLoop(rs *runtimeSession, ptmx *os.File) {
 	buf := make([]byte, 8192)
 	for {
-		n, err := rs.ptmx.Read(buf)
+		n, err := ptmx.Read(buf)
 		if n > 0 {
 			chunk := make([]byte, n)
 			copy(chunk, buf[:n])
 			rs.appendOutput(chunk)
+			rs.recorder.output(chunk)
 			if rs.onOutput != nil {
 				rs.onOutput(rs.id, chunk)
 			}
@@ -165,16 +288,45 @@ func exitCodeFromErr(err error) int {
 	return -1
 }
 
-func (m *Manager) waitLoop(rs *runtimeSession) {
-	err := rs.cmd.Wait()
+func (m *Manager) waitLoop(rs *runtimeSession, cmd *exec.Cmd, ptmx *os.File, readDone <-chan struct{}) {
+	err := cmd.Wait()
 	code := exitCodeFromErr(err)
 
+	if m.tmux != nil {
+		rs.mu.Lock()
+		stopped := rs.stopped
+		attachedAt := rs.attachedAt
+		rs.mu.Unlock()
+
+		if !stopped && m.tmux.alive(rs.id) {
+			// Only the tmux client went away; the process is still running.
+			_ = ptmx.Close()
+			if time.Since(attachedAt) >= minClientLifetime && m.respawnClient(rs) == nil {
+				return
+			}
+			m.detach(rs)
+			return
+		}
+		code, err = m.tmux.exitStatus(rs.id)
+		if stopped {
+			code, err = -1, errStopped
+		}
+	}
+
+	// Let the reader drain what the process wrote before it exited. A
+	// child left holding the terminal open would keep it going forever.
+	select {
+	case <-readDone:
+	case <-time.After(outputDrainTimeout):
+	}
+
 	rs.mu.Lock()
 	if rs.closed {
 		rs.mu.Unlock()
 		return
 	}
 	rs.closed = true
+	output := rs.outputTail(exitOutputTailBytes)
 	if rs.ptmx != nil {
 		_ = rs.ptmx.Close()
 	}
@@ -187,12 +339,37 @@ func (m *Manager) waitLoop(rs *runtimeSession) {
 	m.mu.Lock()
 	delete(m.sessions, rs.id)
 	m.mu.Unlock()
+	rs.recorder.close()
+
+	rs.span.SetAttributes(telemetry.Int("process.exit_code", code))
+	if !errors.Is(err, errStopped) {
+		rs.span.RecordError(err)
+	}
+	rs.span.End()
 
 	if rs.onExit != nil {
-		rs.onExit(ExitResult{SessionID: rs.id, ExitCode: code, Err: err})
+		rs.onExit(ExitResult{SessionID: rs.id, ExitCode: code, Err: err, Output: output})
 	}
 }
 
+// outputTail returns up to n trailing bytes of buffered output. The caller
+// must hold rs.mu.
+func (rs *runtimeSession) outputTail(n int) []byte {
+	start, size := len(rs.ring), 0
+	for start > 0 && size < n {
+		start--
+		size += len(rs.ring[start].Data)
+	}
+	tail := make([]byte, 0, size)
+	for _, ev := range rs.ring[start:] {
+		tail = append(tail, ev.Data...)
+	}
+	if len(tail) > n {
+		tail = tail[len(tail)-n:]
+	}
+	return tail
+}
+
 func (rs *runtimeSession) appendOutput(data []byte) {
 	if len(data) == 0 {
 		return
@@ -230,6 +407,20 @@ func (rs *runtimeSession) appendOutput(data []byte) {
 	rs.mu.Unlock()
 }
 
+// startRecording opens a session's recording, or returns nil when it isn't
+// recorded. A recording that can't be opened doesn't stop the session.
+func startRecording(sessionID string, opt *RecordingOptions, cols, rows uint16) *recorder {
+	if opt == nil || opt.Path == "" {
+		return nil
+	}
+	rec, err := openRecorder(*opt, cols, rows)
+	if err != nil {
+		slog.Warn("failed to start session recording", "session_id", sessionID, "error", err)
+		return nil
+	}
+	return rec
+}
+
 // Attach subscribes to runtime output and returns recent replay chunks.
 func (m *Manager) Attach(sessionID string) ([]OutputEvent, <-chan OutputEvent, func(), error) {
 	rs, err := m.get(sessionID)
@@ -268,9 +459,64 @@ func (m *Manager) Attach(sessionID string) ([]OutputEvent, <-chan OutputEvent, f
 	return snapshot, ch, cancel, nil
 }
 
+// respawnClient replaces a session's dead tmux client with a fresh one while
+// keeping its output ring and subscribers.
+func (m *Manager) respawnClient(rs *runtimeSession) error {
+	rs.mu.Lock()
+	size := &pty.Winsize{Cols: rs.cols, Rows: rs.rows}
+	rs.mu.Unlock()
+
+	cmd := m.tmux.attachCommand(rs.id)
+	ptmx, err := pty.StartWithSize(cmd, size)
+	if err != nil {
+		return err
+	}
+
+	rs.mu.Lock()
+	if rs.closed {
+		rs.mu.Unlock()
+		_ = ptmx.Close()
+		_ = cmd.Process.Kill()
+		return ErrSessionNotFound
+	}
+	rs.cmd = cmd
+	rs.ptmx = ptmx
+	rs.attachedAt = time.Now()
+	rs.mu.Unlock()
+
+	m.run(rs, cmd, ptmx)
+	return nil
+}
+
+// detach drops the in-process handle for a session whose tmux client cannot
+// be kept attached. The tmux session keeps running and can be re-attached.
+func (m *Manager) detach(rs *runtimeSession) {
+	rs.mu.Lock()
+	if rs.closed {
+		rs.mu.Unlock()
+		return
+	}
+	rs.closed = true
+	for id, ch := range rs.subs {
+		close(ch)
+		delete(rs.subs, id)
+	}
+	rs.mu.Unlock()
+
+	m.mu.Lock()
+	if m.sessions[rs.id] == rs {
+		delete(m.sessions, rs.id)
+	}
+	m.mu.Unlock()
+	rs.recorder.close()
+}
+
 // Write sends raw bytes into a session PTY.
 func (m *Manager) Write(sessionID string, data []byte) error {
 	rs, err := m.get(sessionID)
+	if errors.Is(err, ErrSessionNotFound) && m.tmux != nil && m.tmux.alive(sessionID) {
+		return m.tmux.sendBytes(sessionID, data)
+	}
 	if err != nil {
 		return err
 	}
@@ -288,6 +534,12 @@ func (m *Manager) Write(sessionID string, data []byte) error {
 // Resize changes the PTY size for a session.
 func (m *Manager) Resize(sessionID string, cols, rows uint16) error {
 	rs, err := m.get(sessionID)
+	if errors.Is(err, ErrSessionNotFound) && m.tmux != nil && m.tmux.alive(sessionID) {
+		if cols == 0 || rows == 0 {
+			return nil
+		}
+		return m.tmux.resize(sessionID, cols, rows)
+	}
 	if err != nil {
 		return err
 	}
@@ -301,12 +553,22 @@ func (m *Manager) Resize(sessionID string, cols, rows uint16) error {
 	if cols == 0 || rows == 0 {
 		return nil
 	}
+	rs.mu.Lock()
+	changed := rs.cols != cols || rs.rows != rows
+	rs.cols, rs.rows = cols, rows
+	rs.mu.Unlock()
+	if changed {
+		rs.recorder.resize(cols, rows)
+	}
 	return pty.Setsize(ptmx, &pty.Winsize{Cols: cols, Rows: rows})
 }
 
 // Stop terminates a runtime session.
 func (m *Manager) Stop(sessionID string) error {
 	rs, err := m.get(sessionID)
+	if errors.Is(err, ErrSessionNotFound) && m.tmux != nil && m.tmux.alive(sessionID) {
+		return m.tmux.kill(sessionID)
+	}
 	if err != nil {
 		return err
 	}
@@ -315,6 +577,12 @@ func (m *Manager) Stop(sessionID string) error {
 		rs.mu.Unlock()
 		return nil
 	}
+	if m.tmux != nil {
+		rs.stopped = true
+		rs.mu.Unlock()
+		// The attached client exits once the tmux session is gone.
+		return m.tmux.kill(sessionID)
+	}
 	proc := rs.cmd.Process
 	rs.mu.Unlock()
 	if proc == nil {
@@ -323,14 +591,30 @@ func (m *Manager) Stop(sessionID string) error {
 	return proc.Kill()
 }
 
-// Exists reports whether a session runtime is currently alive.
+// Exists reports whether a session runtime is currently alive. With the tmux
+// runtime this includes sessions that are running but not attached.
 func (m *Manager) Exists(sessionID string) bool {
 	m.mu.RLock()
 	_, ok := m.sessions[sessionID]
 	m.mu.RUnlock()
+	if !ok && m.tmux != nil {
+		return m.tmux.alive(sessionID)
+	}
 	return ok
 }
 
+// LastOutput returns when a session last produced output, or false when
+// it isn't attached here or hasn't produced any.
+func (m *Manager) LastOutput(sessionID string) (time.Time, bool) {
+	rs, err := m.get(sessionID)
+	if err != nil {
+		return time.Time{}, false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.lastOutput, !rs.lastOutput.IsZero()
+}
+
 func (m *Manager) get(sessionID string) (*runtimeSession, error) {
 	m.mu.RLock()
 	rs, ok := m.sessions[sessionID]
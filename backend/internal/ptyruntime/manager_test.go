@@ -60,3 +60,86 @@ func TestManagerStopUnknownSession(t *testing.T) {
 		t.Fatalf("expected ErrSessionNotFound, got %v", err)
 	}
 }
+
+func TestTmuxManagerSurvivesRestart(t *testing.T) {
+	if !TmuxAvailable() {
+		t.Skip("tmux not installed")
+	}
+	cfg := TmuxConfig{SocketName: "codeburg-test-" + strings.ReplaceAll(t.Name(), "/", "-"), StateDir: t.TempDir()}
+	t.Cleanup(func() {
+		(&tmuxBackend{socket: cfg.SocketName}).command("kill-server").Run()
+	})
+
+	first := NewTmuxManager(cfg)
+	err := first.Start("s1", StartOptions{
+		Command: "/bin/sh",
+		Args:    []string{"-c", "read line; echo \"got:$line\"; sleep 0.5; exit 3"},
+	})
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+
+	// Drop the first manager's client and use a fresh manager to stand in
+	// for a restarted server.
+	rs, err := first.get("s1")
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	first.detach(rs)
+	rs.cmd.Process.Kill()
+
+	second := NewTmuxManager(cfg)
+	if !second.Exists("s1") {
+		t.Fatal("expected tmux session to exist for a new manager")
+	}
+	if err := second.Resize("s1", 100, 30); err != nil {
+		t.Fatalf("resize detached session: %v", err)
+	}
+
+	var (
+		mu  sync.Mutex
+		out strings.Builder
+	)
+	exitCh := make(chan ExitResult, 1)
+	err = second.Reattach("s1", StartOptions{
+		OnOutput: func(_ string, chunk []byte) {
+			mu.Lock()
+			out.Write(chunk)
+			mu.Unlock()
+		},
+		OnExit: func(result ExitResult) { exitCh <- result },
+	})
+	if err != nil {
+		t.Fatalf("reattach: %v", err)
+	}
+
+	if err := second.Write("s1", []byte("hello\r")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	select {
+	case result := <-exitCh:
+		if result.ExitCode != 3 {
+			t.Fatalf("expected exit code 3, got %d (err=%v)", result.ExitCode, result.Err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for process exit")
+	}
+
+	mu.Lock()
+	got := out.String()
+	mu.Unlock()
+	if !strings.Contains(got, "got:hello") {
+		t.Fatalf("expected echoed input in output, got %q", got)
+	}
+	if second.Exists("s1") {
+		t.Fatal("expected session to be gone after exit")
+	}
+}
+
+func TestManagerReattachWithoutTmux(t *testing.T) {
+	m := NewManager()
+	if err := m.Reattach("missing", StartOptions{}); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+}
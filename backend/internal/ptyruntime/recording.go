@@ -0,0 +1,169 @@
+package ptyruntime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// RecordingOptions saves a session's PTY output as an asciicast v2 file,
+// which asciinema and its web player can replay.
+type RecordingOptions struct {
+	// Path is the .cast file. An existing recording, e.g. from before a
+	// restart, is appended to.
+	Path string
+	// MaxBytes stops recording once the file reaches it; 0 for no limit.
+	MaxBytes int64
+}
+
+// recorder writes asciicast v2 events. Its methods are safe on a nil
+// recorder, which records nothing.
+type recorder struct {
+	mu      sync.Mutex
+	f       *os.File
+	start   time.Time
+	size    int64
+	max     int64
+	full    bool
+	pending []byte // an incomplete UTF-8 sequence held back from the last chunk
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint16            `json:"width"`
+	Height    uint16            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+func openRecorder(opt RecordingOptions, cols, rows uint16) (*recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(opt.Path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(opt.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	rec := &recorder{f: f, size: info.Size(), max: opt.MaxBytes}
+	if info.Size() > 0 {
+		// Event times are relative to the header's timestamp.
+		var header castHeader
+		line, _ := bufio.NewReader(f).ReadBytes('\n')
+		if err := json.Unmarshal(line, &header); err != nil || header.Version != 2 {
+			f.Close()
+			return nil, fmt.Errorf("%s is not an asciicast v2 recording", opt.Path)
+		}
+		rec.start = time.Unix(header.Timestamp, 0)
+		rec.resize(cols, rows)
+		return rec, nil
+	}
+
+	rec.start = time.Now()
+	header, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: rec.start.Unix(),
+		Env:       map[string]string{"TERM": "xterm-256color", "SHELL": os.Getenv("SHELL")},
+	})
+	if err := rec.writeLine(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return rec, nil
+}
+
+// output records a chunk of terminal output.
+func (r *recorder) output(chunk []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.pending, chunk...)
+	data, r.pending = splitIncompleteUTF8(data)
+	if len(data) > 0 {
+		r.event("o", string(data))
+	}
+}
+
+// resize records a terminal size change.
+func (r *recorder) resize(cols, rows uint16) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// close flushes held back output and closes the file.
+func (r *recorder) close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return
+	}
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+		r.pending = nil
+	}
+	_ = r.f.Close()
+	r.f = nil
+}
+
+// event writes one event. The caller must hold r.mu.
+func (r *recorder) event(kind, data string) {
+	if r.f == nil || r.full {
+		return
+	}
+	elapsed := math.Round(time.Since(r.start).Seconds()*1e6) / 1e6
+	line, err := json.Marshal([]any{elapsed, kind, data})
+	if err != nil {
+		return
+	}
+	if r.max > 0 && r.size+int64(len(line))+1 > r.max {
+		// A marker tells players why the recording ends early.
+		r.full = true
+		marker, _ := json.Marshal([]any{elapsed, "m", "recording size limit reached"})
+		_ = r.writeLine(marker)
+		return
+	}
+	_ = r.writeLine(line)
+}
+
+func (r *recorder) writeLine(line []byte) error {
+	n, err := r.f.Write(append(line, '\n'))
+	r.size += int64(n)
+	return err
+}
+
+// splitIncompleteUTF8 splits off a trailing UTF-8 sequence that the next
+// chunk completes, so events never cut a character in two.
+func splitIncompleteUTF8(b []byte) ([]byte, []byte) {
+	for i := len(b) - 1; i >= 0 && i > len(b)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if !utf8.FullRune(b[i:]) {
+			return b[:i], append([]byte(nil), b[i:]...)
+		}
+		break
+	}
+	return b, nil
+}
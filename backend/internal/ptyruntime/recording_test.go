@@ -0,0 +1,120 @@
+package ptyruntime
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readCast(t *testing.T, path string) (castHeader, [][]any) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open recording: %v", err)
+	}
+	defer f.Close()
+
+	var header castHeader
+	var events [][]any
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		if i == 0 {
+			if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+				t.Fatalf("header: %v", err)
+			}
+			continue
+		}
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			t.Fatalf("event %d: %q", i, scanner.Text())
+		}
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestManagerRecordsOutput(t *testing.T) {
+	m := NewManager()
+	path := filepath.Join(t.TempDir(), "s1.cast")
+	exitCh := make(chan ExitResult, 1)
+
+	err := m.Start("s1", StartOptions{
+		Command:   "/bin/sh",
+		Args:      []string{"-c", "printf 'recorded-output\\n'"},
+		Cols:      80,
+		Rows:      24,
+		Recording: &RecordingOptions{Path: path},
+		OnExit:    func(result ExitResult) { exitCh <- result },
+	})
+	if err != nil {
+		t.Fatalf("start session: %v", err)
+	}
+	select {
+	case <-exitCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for process exit")
+	}
+
+	header, events := readCast(t, path)
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	var out strings.Builder
+	for _, event := range events {
+		if event[1] == "o" {
+			out.WriteString(event[2].(string))
+		}
+	}
+	if !strings.Contains(out.String(), "recorded-output") {
+		t.Fatalf("expected the output recorded, got %q", out.String())
+	}
+}
+
+func TestRecorderAppendsAndLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s.cast")
+	rec, err := openRecorder(RecordingOptions{Path: path}, 80, 24)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	// "é" split across two chunks stays one character.
+	rec.output([]byte("caf\xc3"))
+	rec.output([]byte("\xa9"))
+	rec.close()
+
+	rec, err = openRecorder(RecordingOptions{Path: path, MaxBytes: 200}, 100, 30)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	rec.output([]byte(strings.Repeat("x", 300)))
+	rec.output([]byte("dropped"))
+	rec.close()
+
+	_, events := readCast(t, path)
+	want := [][2]string{{"o", "caf"}, {"o", "é"}, {"r", "100x30"}, {"m", "recording size limit reached"}}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %v", len(want), events)
+	}
+	for i, w := range want {
+		if events[i][1] != w[0] || events[i][2] != w[1] {
+			t.Errorf("event %d = %v, want %v", i, events[i], w)
+		}
+	}
+}
+
+func TestSplitIncompleteUTF8(t *testing.T) {
+	for _, tc := range []struct{ in, head, tail string }{
+		{"abc", "abc", ""},
+		{"ab\xe2\x82", "ab", "\xe2\x82"},
+		{"ab\xe2\x82\xac", "ab\xe2\x82\xac", ""},
+		{"\xff", "\xff", ""},
+	} {
+		head, tail := splitIncompleteUTF8([]byte(tc.in))
+		if string(head) != tc.head || string(tail) != tc.tail {
+			t.Errorf("split(%q) = %q, %q", tc.in, head, tail)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package ptyruntime
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTmuxSocket = "codeburg"
+	tmuxSessionPrefix = "cb-"
+
+	// minClientLifetime guards against respawn loops when a tmux client
+	// cannot stay attached.
+	minClientLifetime = time.Second
+)
+
+var errStopped = errors.New("runtime session stopped")
+
+// TmuxConfig configures the tmux-backed runtime.
+type TmuxConfig struct {
+	SocketName string // tmux -L socket name (default "codeburg")
+	StateDir   string // where exit status files are written (default $TMPDIR/codeburg-tmux)
+}
+
+// NewTmuxManager creates a runtime manager whose sessions run inside detached
+// tmux sessions on a dedicated socket. The manager attaches a tmux client
+// through a local PTY, so Attach/Write/Resize behave as with NewManager, but
+// the session process lives in the tmux server and survives a restart of
+// this process. Use Reattach to pick such sessions up again.
+func NewTmuxManager(cfg TmuxConfig) *Manager {
+	if cfg.SocketName == "" {
+		cfg.SocketName = defaultTmuxSocket
+	}
+	if cfg.StateDir == "" {
+		cfg.StateDir = filepath.Join(os.TempDir(), "codeburg-tmux")
+	}
+	m := NewManager()
+	m.tmux = &tmuxBackend{socket: cfg.SocketName, stateDir: cfg.StateDir}
+	return m
+}
+
+// TmuxAvailable reports whether the tmux binary can be run.
+func TmuxAvailable() bool {
+	return exec.Command("tmux", "-V").Run() == nil
+}
+
+type tmuxBackend struct {
+	socket   string
+	stateDir string
+}
+
+func (t *tmuxBackend) sessionName(id string) string {
+	return tmuxSessionPrefix + id
+}
+
+// target addresses the session by exact name rather than tmux's prefix matching.
+func (t *tmuxBackend) target(id string) string {
+	return "=" + t.sessionName(id) + ":"
+}
+
+func (t *tmuxBackend) exitFile(id string) string {
+	return filepath.Join(t.stateDir, id+".exit")
+}
+
+func (t *tmuxBackend) command(args ...string) *exec.Cmd {
+	return exec.Command("tmux", append([]string{"-L", t.socket}, args...)...)
+}
+
+func (t *tmuxBackend) run(args ...string) error {
+	output, err := t.command(args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tmux %s: %s: %w", args[0], strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// create starts a detached tmux session running the command. The command is
+// wrapped in sh so its exit status is written to a file that outlives the
+// tmux session.
+func (t *tmuxBackend) create(id string, opt StartOptions, cols, rows uint16) error {
+	if err := os.MkdirAll(t.stateDir, 0700); err != nil {
+		return fmt.Errorf("create tmux state dir: %w", err)
+	}
+	exitFile := t.exitFile(id)
+	_ = os.Remove(exitFile)
+
+	name := t.sessionName(id)
+	args := []string{
+		"new-session", "-d", "-s", name,
+		"-x", strconv.Itoa(int(cols)), "-y", strconv.Itoa(int(rows)),
+	}
+	if opt.WorkDir != "" {
+		args = append(args, "-c", opt.WorkDir)
+	}
+	args = append(args, "env")
+	args = append(args, opt.Env...)
+	args = append(args, "sh", "-c", `f=$1; shift; "$@"; echo $? > "$f"`, "sh", exitFile, opt.Command)
+	args = append(args, opt.Args...)
+	args = append(args, ";", "set-option", "-t", t.target(id), "status", "off")
+	return t.run(args...)
+}
+
+// attachCommand returns a tmux client command attached to the session.
+func (t *tmuxBackend) attachCommand(id string) *exec.Cmd {
+	cmd := t.command("attach-session", "-t", "="+t.sessionName(id))
+	env := os.Environ()
+	if os.Getenv("TERM") == "" {
+		env = append(env, "TERM=xterm-256color")
+	}
+	cmd.Env = env
+	return cmd
+}
+
+func (t *tmuxBackend) alive(id string) bool {
+	return t.command("has-session", "-t", "="+t.sessionName(id)).Run() == nil
+}
+
+func (t *tmuxBackend) kill(id string) error {
+	err := t.run("kill-session", "-t", "="+t.sessionName(id))
+	if err != nil && !t.alive(id) {
+		return nil
+	}
+	return err
+}
+
+func (t *tmuxBackend) sendBytes(id string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	args := []string{"send-keys", "-t", t.target(id), "-H"}
+	for _, b := range data {
+		args = append(args, hex.EncodeToString([]byte{b}))
+	}
+	return t.run(args...)
+}
+
+func (t *tmuxBackend) resize(id string, cols, rows uint16) error {
+	return t.run("resize-window", "-t", t.target(id), "-x", strconv.Itoa(int(cols)), "-y", strconv.Itoa(int(rows)))
+}
+
+// exitStatus reads and removes the exit status recorded by the session wrapper.
+func (t *tmuxBackend) exitStatus(id string) (int, error) {
+	path := t.exitFile(id)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return -1, fmt.Errorf("tmux session ended without exit status")
+	}
+	_ = os.Remove(path)
+	code, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return -1, fmt.Errorf("parse exit status: %w", err)
+	}
+	if code != 0 {
+		return code, fmt.Errorf("exit status %d", code)
+	}
+	return 0, nil
+}
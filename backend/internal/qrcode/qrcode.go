@@ -0,0 +1,525 @@
+// Package qrcode encodes short texts, such as links, as QR codes (ISO/IEC
+// 18004) and renders them as PNG images. It writes byte mode at error
+// correction level M in versions 1 to 10, which holds up to 213 bytes:
+// enough for a URL, and small enough to scan off a phone screen.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// ErrTooLong is returned for texts that don't fit in the largest supported
+// version.
+var ErrTooLong = errors.New("text too long for a QR code")
+
+// quietZone is the light border, in modules, scanners need around a code.
+const quietZone = 4
+
+// levelM is error correction level M in the format bits.
+const levelM = 0b00
+
+// Code is an encoded QR symbol.
+type Code struct {
+	Version int
+	Size    int // modules per side
+
+	modules  [][]bool // true is dark
+	function [][]bool // finder, timing, alignment and format modules
+}
+
+// blockGroup describes a group of error correction blocks of one size.
+type blockGroup struct {
+	count int // blocks in the group
+	data  int // data codewords per block
+}
+
+type versionInfo struct {
+	ecPerBlock int
+	groups     []blockGroup
+	alignment  []int // alignment pattern centers
+}
+
+// versions holds the level M parameters, indexed by version.
+var versions = [...]versionInfo{
+	1:  {10, []blockGroup{{1, 16}}, nil},
+	2:  {16, []blockGroup{{1, 28}}, []int{6, 18}},
+	3:  {26, []blockGroup{{1, 44}}, []int{6, 22}},
+	4:  {18, []blockGroup{{2, 32}}, []int{6, 26}},
+	5:  {24, []blockGroup{{2, 43}}, []int{6, 30}},
+	6:  {16, []blockGroup{{4, 27}}, []int{6, 34}},
+	7:  {18, []blockGroup{{4, 31}}, []int{6, 22, 38}},
+	8:  {22, []blockGroup{{2, 38}, {2, 39}}, []int{6, 24, 42}},
+	9:  {22, []blockGroup{{3, 36}, {2, 37}}, []int{6, 26, 46}},
+	10: {26, []blockGroup{{4, 43}, {1, 44}}, []int{6, 28, 50}},
+}
+
+const maxVersion = len(versions) - 1
+
+// dataCodewords is how many data codewords a version holds.
+func dataCodewords(version int) int {
+	n := 0
+	for _, g := range versions[version].groups {
+		n += g.count * g.data
+	}
+	return n
+}
+
+// countBits is the width of the byte mode character count.
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// capacity is how many bytes a version holds in byte mode.
+func capacity(version int) int {
+	return (dataCodewords(version)*8 - 4 - countBits(version)) / 8
+}
+
+// Encode encodes text in the smallest version it fits, with the mask that
+// scores the lowest penalty.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if len(data) <= capacity(v) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	c := newCode(version)
+	c.drawFunctionPatterns()
+	c.drawCodewords(addErrorCorrection(version, encodeData(version, data)))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // masks are XORs, so this undoes it
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := &Code{Version: version, Size: size}
+	c.modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for y := range size {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+	return c
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// PNG renders the code with scale pixels per module and the standard quiet
+// zone around it.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.modules[y][x] {
+				continue
+			}
+			px, py := (x+quietZone)*scale, (y+quietZone)*scale
+			for dy := range scale {
+				for dx := range scale {
+					img.SetColorIndex(px+dx, py+dy, 1)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// --- Data encoding ---
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 == 1)
+	}
+}
+
+// encodeData lays data out as a byte mode segment, padded to the version's
+// data codewords.
+func encodeData(version int, data []byte) []byte {
+	total := dataCodewords(version) * 8
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, total-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < total; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	out := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// addErrorCorrection splits data into the version's blocks, appends each
+// block's error correction codewords and interleaves the result.
+func addErrorCorrection(version int, data []byte) []byte {
+	info := versions[version]
+	divisor := rsDivisor(info.ecPerBlock)
+
+	var blocks, ecs [][]byte
+	offset, longest := 0, 0
+	for _, g := range info.groups {
+		for range g.count {
+			block := data[offset : offset+g.data]
+			offset += g.data
+			blocks = append(blocks, block)
+			ecs = append(ecs, rsRemainder(block, divisor))
+			longest = max(longest, g.data)
+		}
+	}
+
+	out := make([]byte, 0, len(data)+len(blocks)*info.ecPerBlock)
+	for i := range longest {
+		for _, block := range blocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := range info.ecPerBlock {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given
+// degree, highest coefficient first and without the leading 1.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMul(coef, factor)
+		}
+	}
+	return result
+}
+
+// --- Module placement ---
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := range c.Size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	centers := versions[c.Version].alignment
+	for i, x := range centers {
+		for j, y := range centers {
+			last := len(centers) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // overlaps a finder
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas; Encode redraws them once the mask is known.
+	c.drawFormat(0)
+	c.drawVersion()
+}
+
+// drawFinder draws a finder pattern and its separator around (cx, cy).
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatBits returns the 15 format bits for level M and a mask.
+func formatBits(mask int) int {
+	data := levelM<<3 | mask
+	rem := data
+	for range 10 {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits returns the 18 version bits drawn from version 7 up.
+func versionBits(version int) int {
+	rem := version
+	for range 12 {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+func bit(value, i int) bool {
+	return value>>i&1 == 1
+}
+
+// drawFormat draws both copies of the format bits, and the dark module.
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.Size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionBits(c.Version)
+	for i := range 18 {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order of the standard:
+// two-module columns from the right, alternating up and down, skipping the
+// vertical timing pattern.
+func (c *Code) drawCodewords(codewords []byte) {
+	i, total := 0, len(codewords)*8
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y][x] || i >= total {
+					continue
+				}
+				c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// masked reports whether a mask flips the module at (x, y).
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.function[y][x] && masked(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// finderLike is the 1:1:3:1:1 pattern, with four light modules on one side,
+// that scanners could mistake for a finder.
+var finderLike = [...]bool{true, false, true, true, true, false, true, false, false, false, false}
+
+// penalty scores how hard the masked code is to scan; lower is better.
+func (c *Code) penalty() int {
+	score := 0
+	line := make([]bool, c.Size)
+	for _, vertical := range []bool{false, true} {
+		for i := range c.Size {
+			for j := range c.Size {
+				if vertical {
+					line[j] = c.modules[j][i]
+				} else {
+					line[j] = c.modules[i][j]
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				v := c.modules[y][x]
+				if c.modules[y][x+1] == v && c.modules[y+1][x] == v && c.modules[y+1][x+1] == v {
+					score += 3
+				}
+			}
+		}
+	}
+	percent := dark * 100 / (c.Size * c.Size)
+	score += abs(percent-50) / 5 * 10
+	return score
+}
+
+// linePenalty scores runs of five or more same-colored modules and
+// finder-like patterns in a row or column.
+func linePenalty(line []bool) int {
+	score, run := 0, 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += 3 + run - 5
+		}
+		run = 1
+	}
+
+	n := len(finderLike)
+	for i := 0; i+n <= len(line); i++ {
+		forward, backward := true, true
+		for k := range n {
+			forward = forward && line[i+k] == finderLike[k]
+			backward = backward && line[i+k] == finderLike[n-1-k]
+		}
+		if forward {
+			score += 40
+		}
+		if backward {
+			score += 40
+		}
+	}
+	return score
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
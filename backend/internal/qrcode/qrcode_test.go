@@ -0,0 +1,243 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncodePicksSmallestVersion(t *testing.T) {
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{1, 1},
+		{14, 1},
+		{15, 2},
+		{120, 7},
+		{213, 10},
+	}
+	for _, tt := range tests {
+		code, err := Encode(strings.Repeat("a", tt.length))
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", tt.length, err)
+		}
+		if code.Version != tt.version || code.Size != tt.version*4+17 {
+			t.Errorf("%d bytes: version %d size %d, want version %d", tt.length, code.Version, code.Size, tt.version)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("expected ErrTooLong, got %v", err)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	// Level M with mask 0 is the XOR pattern itself; version 7 is the first
+	// entry of the standard's version information table.
+	if got := formatBits(0); got != 0x5412 {
+		t.Errorf("format bits M/0 = %015b", got)
+	}
+	if got := versionBits(7); got != 0x07C94 {
+		t.Errorf("version bits 7 = %018b", got)
+	}
+}
+
+func TestDataModulesFitCodewords(t *testing.T) {
+	remainder := map[int]int{2: 7, 3: 7, 4: 7, 5: 7, 6: 7}
+	for version := 1; version <= maxVersion; version++ {
+		c := newCode(version)
+		c.drawFunctionPatterns()
+		free := 0
+		for y := range c.Size {
+			for x := range c.Size {
+				if !c.function[y][x] {
+					free++
+				}
+			}
+		}
+		total := dataCodewords(version) + len(blocksOf(version))*versions[version].ecPerBlock
+		if want := total*8 + remainder[version]; free != want {
+			t.Errorf("version %d: %d data modules, want %d", version, free, want)
+		}
+	}
+}
+
+func TestErrorCorrectionSyndromesAreZero(t *testing.T) {
+	data := []byte("https://codeburg.example.com/tasks/abc")
+	for _, degree := range []int{10, 16, 22, 26} {
+		codeword := append(append([]byte{}, data...), rsRemainder(data, rsDivisor(degree))...)
+		root := byte(1)
+		for i := range degree {
+			// Horner's rule evaluates the codeword polynomial at α^i.
+			var sum byte
+			for _, c := range codeword {
+				sum = gfMul(sum, root) ^ c
+			}
+			if sum != 0 {
+				t.Fatalf("degree %d: syndrome %d is %d", degree, i, sum)
+			}
+			root = gfMul(root, 2)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	texts := []string{
+		"hi",
+		"https://codeburg.example.com/tasks/01JABCDEF?session=01JXYZ&login_token=0123456789abcdef0123456789abcdef",
+		strings.Repeat("codeburg ", 23),
+	}
+	for _, text := range texts {
+		code, err := Encode(text)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		if got := decode(t, code); got != text {
+			t.Errorf("decoded %q, want %q", got, text)
+		}
+	}
+}
+
+func TestPNG(t *testing.T) {
+	code, err := Encode("https://codeburg.example.com")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data, err := code.PNG(3)
+	if err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	side := (code.Size + 2*quietZone) * 3
+	if b := img.Bounds(); b.Dx() != side || b.Dy() != side {
+		t.Fatalf("image is %v, want %dx%d", b, side, side)
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("quiet zone should be light")
+	}
+	// The top-left finder's corner module is dark.
+	if r, _, _, _ := img.At(quietZone*3, quietZone*3).RGBA(); r != 0 {
+		t.Error("finder corner should be dark")
+	}
+}
+
+// blocksOf lists each error correction block's data length.
+func blocksOf(version int) []int {
+	var lengths []int
+	for _, g := range versions[version].groups {
+		for range g.count {
+			lengths = append(lengths, g.data)
+		}
+	}
+	return lengths
+}
+
+// decode reads a code back the way a scanner would once it has located the
+// modules: format bits, unmasking, codeword order, blocks, then the segment.
+func decode(t *testing.T, code *Code) string {
+	t.Helper()
+
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(code.Dark(8, i)) << i
+	}
+	format |= b2i(code.Dark(8, 7)) << 6
+	format |= b2i(code.Dark(8, 8)) << 7
+	format |= b2i(code.Dark(7, 8)) << 8
+	for i := 9; i < 15; i++ {
+		format |= b2i(code.Dark(14-i, 8)) << i
+	}
+	mask := (format ^ 0x5412) >> 10 & 7
+	if formatBits(mask) != format {
+		t.Fatalf("format bits %015b are not a valid level M format", format)
+	}
+
+	layout := newCode(code.Version)
+	layout.drawFunctionPatterns()
+	var codewords []byte
+	var current byte
+	n := 0
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range code.Size {
+			y := vert
+			if upward {
+				y = code.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if layout.function[y][x] {
+					continue
+				}
+				dark := code.Dark(x, y) != masked(mask, x, y)
+				current = current<<1 | byte(b2i(dark))
+				if n++; n%8 == 0 {
+					codewords = append(codewords, current)
+					current = 0
+				}
+			}
+		}
+	}
+
+	lengths := blocksOf(code.Version)
+	ecPerBlock := versions[code.Version].ecPerBlock
+	blocks := make([][]byte, len(lengths))
+	pos := 0
+	for i := 0; i < lengths[len(lengths)-1]; i++ {
+		for b, length := range lengths {
+			if i < length {
+				blocks[b] = append(blocks[b], codewords[pos])
+				pos++
+			}
+		}
+	}
+	ecs := make([][]byte, len(lengths))
+	for range ecPerBlock {
+		for b := range lengths {
+			ecs[b] = append(ecs[b], codewords[pos])
+			pos++
+		}
+	}
+	var data []byte
+	divisor := rsDivisor(ecPerBlock)
+	for b, block := range blocks {
+		if !bytes.Equal(rsRemainder(block, divisor), ecs[b]) {
+			t.Fatalf("block %d error correction mismatch", b)
+		}
+		data = append(data, block...)
+	}
+
+	bits := func(start, n int) int {
+		v := 0
+		for i := start; i < start+n; i++ {
+			v = v<<1 | int(data[i/8]>>(7-i%8)&1)
+		}
+		return v
+	}
+	if mode := bits(0, 4); mode != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", mode)
+	}
+	count := bits(4, countBits(code.Version))
+	start := 4 + countBits(code.Version)
+	out := make([]byte, count)
+	for i := range out {
+		out[i] = byte(bits(start+8*i, 8))
+	}
+	return string(out)
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,245 @@
+package recipes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Param is a named argument a recipe accepts.
+type Param struct {
+	Name     string `json:"name"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required"`
+	Variadic bool   `json:"variadic,omitempty"` // takes several space-separated values
+
+	rawDefault string // just default as written, quotes included
+}
+
+// ParseJustParams parses the parameter list of a just recipe header, as in
+// `deploy env="staging" +targets:` or the same line in `just --list`.
+func ParseJustParams(spec string) []Param {
+	var params []Param
+	for _, token := range splitJustParams(spec) {
+		var p Param
+		switch token[0] {
+		case '+':
+			p.Variadic, p.Required = true, true
+			token = token[1:]
+		case '*':
+			p.Variadic = true
+			token = token[1:]
+		}
+		token = strings.TrimPrefix(token, "$") // exported to the recipe's environment
+		name, def, hasDefault := strings.Cut(token, "=")
+		if name == "" {
+			continue
+		}
+		p.Name = name
+		if hasDefault {
+			p.rawDefault = def
+			p.Default = unquoteJustString(def)
+			p.Required = false
+		} else if !p.Variadic {
+			p.Required = true
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// splitJustParams splits on spaces outside quotes, backticks and parens.
+func splitJustParams(spec string) []string {
+	var tokens []string
+	var current strings.Builder
+	var quote rune
+	depth := 0
+	for _, r := range spec {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case (r == ' ' || r == '\t') && depth == 0:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// unquoteJustString strips the quotes of a string literal default. Other
+// defaults, such as backtick or function expressions, are kept verbatim.
+func unquoteJustString(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// isJustLiteral reports whether a raw default is a quoted string literal,
+// which can be passed on the command line in place of the default. Anything
+// else is an expression only just can evaluate.
+func isJustLiteral(raw string) bool {
+	return unquoteJustString(raw) != raw
+}
+
+// taskfileParams reads the variables a Taskfile task declares: required ones
+// from requires.vars, optional ones with a literal default from vars.
+func taskfileParams(raw interface{}) []Param {
+	task, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var params []Param
+	seen := map[string]bool{}
+	if requires, ok := task["requires"].(map[string]interface{}); ok {
+		if vars, ok := requires["vars"].([]interface{}); ok {
+			for _, v := range vars {
+				if name, ok := v.(string); ok && !seen[name] {
+					seen[name] = true
+					params = append(params, Param{Name: name, Required: true})
+				}
+			}
+		}
+	}
+
+	if vars, ok := task["vars"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			// Dynamic vars ({sh: ...}) are computed by task and cannot be
+			// meaningfully overridden from here.
+			switch def := vars[name].(type) {
+			case string:
+				params = append(params, Param{Name: name, Default: def})
+			case int, float64, bool:
+				params = append(params, Param{Name: name, Default: fmt.Sprint(def)})
+			}
+		}
+	}
+	return params
+}
+
+// CommandWithArgs returns the recipe's command with named arguments applied.
+// Justfile arguments are positional (see JustArgs); Taskfile arguments are
+// passed as NAME=value.
+func (r Recipe) CommandWithArgs(args map[string]string) (string, error) {
+	if err := r.checkArgs(args); err != nil {
+		return "", err
+	}
+
+	var extra []string
+	switch r.Source {
+	case "justfile":
+		positional, err := r.JustArgs(args)
+		if err != nil {
+			return "", err
+		}
+		extra = positional
+	case "taskfile":
+		for _, p := range r.Params {
+			value, ok := args[p.Name]
+			if !ok {
+				if p.Required {
+					return "", fmt.Errorf("missing required parameter %q", p.Name)
+				}
+				continue
+			}
+			extra = append(extra, p.Name+"="+value)
+		}
+	default:
+		if len(args) > 0 {
+			return "", fmt.Errorf("%s recipes do not take named parameters", r.Source)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(r.Command)
+	for _, arg := range extra {
+		b.WriteString(" " + shellQuote(arg))
+	}
+	return b.String(), nil
+}
+
+// JustArgs orders named arguments into the positional list just expects.
+// Every parameter before the last one given must be set or have a literal
+// default; variadic values are split on whitespace.
+func (r Recipe) JustArgs(args map[string]string) ([]string, error) {
+	if err := r.checkArgs(args); err != nil {
+		return nil, err
+	}
+
+	last := -1
+	for i, p := range r.Params {
+		if _, ok := args[p.Name]; ok {
+			last = i
+		}
+	}
+
+	var out []string
+	for i, p := range r.Params {
+		value, ok := args[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("missing required parameter %q", p.Name)
+			}
+			if i > last {
+				break
+			}
+			if p.Variadic {
+				continue
+			}
+			if !isJustLiteral(p.rawDefault) {
+				return nil, fmt.Errorf("parameter %q must be set to pass later parameters", p.Name)
+			}
+			value = p.Default
+		}
+		if p.Variadic {
+			values := strings.Fields(value)
+			if p.Required && len(values) == 0 {
+				return nil, fmt.Errorf("missing required parameter %q", p.Name)
+			}
+			out = append(out, values...)
+			continue
+		}
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+func (r Recipe) checkArgs(args map[string]string) error {
+	for name := range args {
+		known := false
+		for _, p := range r.Params {
+			if p.Name == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%s recipe %q has no parameter %q", r.Source, r.Name, name)
+		}
+	}
+	return nil
+}
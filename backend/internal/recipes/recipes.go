@@ -19,10 +19,11 @@ var shellSafeArgRe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
 
 // Recipe is a runnable command discovered from a known recipe source.
 type Recipe struct {
-	Name        string `json:"name"`
-	Command     string `json:"command"`
-	Source      string `json:"source"`
-	Description string `json:"description,omitempty"`
+	Name        string  `json:"name"`
+	Command     string  `json:"command"`
+	Source      string  `json:"source"`
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params,omitempty"`
 }
 
 // Manager discovers recipes in a project or task directory.
@@ -89,6 +90,7 @@ func (m *Manager) listJustfileRecipes(dir string) ([]Recipe, error) {
 			Command:     "just " + shellQuote(recipe.Name),
 			Source:      "justfile",
 			Description: recipe.Description,
+			Params:      recipe.Params,
 		})
 	}
 
@@ -202,6 +204,7 @@ func (m *Manager) listTaskfileRecipes(dir string) ([]Recipe, error) {
 			Command:     "task " + shellQuote(name),
 			Source:      "taskfile",
 			Description: desc,
+			Params:      taskfileParams(root.Tasks[name]),
 		})
 	}
 	return recipes, nil
@@ -210,6 +213,7 @@ func (m *Manager) listTaskfileRecipes(dir string) ([]Recipe, error) {
 type parsedRecipe struct {
 	Name        string
 	Description string
+	Params      []Param
 }
 
 func parseJustList(output []byte) []parsedRecipe {
@@ -232,7 +236,11 @@ func parseJustList(output []byte) []parsedRecipe {
 			continue
 		}
 
-		recipes = append(recipes, parsedRecipe{Name: parts[0], Description: description})
+		recipes = append(recipes, parsedRecipe{
+			Name:        parts[0],
+			Description: description,
+			Params:      ParseJustParams(strings.TrimSpace(strings.TrimPrefix(line, parts[0]))),
+		})
 	}
 	return recipes
 }
@@ -261,7 +269,8 @@ func parseJustfileFallback(content []byte) []parsedRecipe {
 			continue
 		}
 
-		parts := strings.Fields(strings.TrimSpace(line[:colon]))
+		header := strings.TrimSpace(line[:colon])
+		parts := strings.Fields(header)
 		if len(parts) == 0 {
 			continue
 		}
@@ -271,7 +280,11 @@ func parseJustfileFallback(content []byte) []parsedRecipe {
 			continue
 		}
 
-		recipes = append(recipes, parsedRecipe{Name: name, Description: description})
+		recipes = append(recipes, parsedRecipe{
+			Name:        name,
+			Description: description,
+			Params:      ParseJustParams(strings.TrimSpace(strings.TrimPrefix(header, name))),
+		})
 	}
 	return recipes
 }
@@ -71,3 +71,84 @@ func TestDetectNodeScriptRunner(t *testing.T) {
 		t.Fatalf("expected pnpm run, got %q", got)
 	}
 }
+
+func TestParseJustParams(t *testing.T) {
+	params := ParseJustParams(`env="staging" $region +targets`)
+	if len(params) != 3 {
+		t.Fatalf("expected 3 params, got %+v", params)
+	}
+	if p := params[0]; p.Name != "env" || p.Default != "staging" || p.Required {
+		t.Errorf("unexpected env param: %+v", p)
+	}
+	if p := params[1]; p.Name != "region" || !p.Required || p.Variadic {
+		t.Errorf("unexpected region param: %+v", p)
+	}
+	if p := params[2]; p.Name != "targets" || !p.Required || !p.Variadic {
+		t.Errorf("unexpected targets param: %+v", p)
+	}
+
+	params = ParseJustParams(`tag=(arch() + "-x") *rest`)
+	if len(params) != 2 || params[0].Default != `(arch() + "-x")` || params[1].Required {
+		t.Errorf("unexpected params: %+v", params)
+	}
+}
+
+func TestCommandWithArgs(t *testing.T) {
+	deploy := Recipe{
+		Name:    "deploy",
+		Command: "just deploy",
+		Source:  "justfile",
+		Params:  ParseJustParams(`env="staging" +targets`),
+	}
+	got, err := deploy.CommandWithArgs(map[string]string{"targets": "web api"})
+	if err != nil || got != "just deploy staging web api" {
+		t.Errorf("CommandWithArgs = %q, %v", got, err)
+	}
+	if _, err := deploy.CommandWithArgs(map[string]string{"env": "prod"}); err == nil {
+		t.Error("expected an error for a missing required parameter")
+	}
+	if _, err := deploy.CommandWithArgs(map[string]string{"targets": "web", "force": "1"}); err == nil {
+		t.Error("expected an error for an unknown parameter")
+	}
+
+	build := Recipe{Name: "build", Command: "just build", Source: "justfile", Params: ParseJustParams("tag=`git rev-parse HEAD` arch")}
+	if _, err := build.CommandWithArgs(map[string]string{"arch": "arm64"}); err == nil {
+		t.Error("expected an error when an expression default precedes a given parameter")
+	}
+
+	if got, err := (Recipe{Name: "lint", Command: "make lint", Source: "makefile"}).CommandWithArgs(nil); err != nil || got != "make lint" {
+		t.Errorf("CommandWithArgs without args = %q, %v", got, err)
+	}
+}
+
+func TestTaskfileParams(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Taskfile.yml"), []byte(`version: "3"
+tasks:
+  deploy:
+    requires:
+      vars: [ENV]
+    vars:
+      REPLICAS: 2
+      REV:
+        sh: git rev-parse HEAD
+    cmds:
+      - ./deploy.sh`), 0644); err != nil {
+		t.Fatalf("write Taskfile.yml: %v", err)
+	}
+
+	recipes, err := NewManager().List(dir)
+	if err != nil || len(recipes) != 1 {
+		t.Fatalf("list recipes: %+v, %v", recipes, err)
+	}
+	deploy := recipes[0]
+	if len(deploy.Params) != 2 || deploy.Params[0].Name != "ENV" || !deploy.Params[0].Required ||
+		deploy.Params[1].Name != "REPLICAS" || deploy.Params[1].Default != "2" {
+		t.Fatalf("unexpected params: %+v", deploy.Params)
+	}
+
+	got, err := deploy.CommandWithArgs(map[string]string{"ENV": "prod"})
+	if err != nil || got != "task deploy ENV=prod" {
+		t.Errorf("CommandWithArgs = %q, %v", got, err)
+	}
+}
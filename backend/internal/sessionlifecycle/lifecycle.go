@@ -23,6 +23,7 @@ const (
 	EventDeleteRequested      Event = "delete_requested"
 	EventReconcileOrphan      Event = "reconcile_orphan"
 	EventZombieRuntimeMissing Event = "zombie_runtime_missing"
+	EventIdleTimeout          Event = "idle_timeout"
 	EventRuntimeExitSuccess   Event = "runtime_exit_success"
 	EventRuntimeExitFailure   Event = "runtime_exit_failure"
 )
@@ -73,6 +74,8 @@ func Apply(current db.SessionStatus, event Event) (Transition, error) {
 		)
 	case EventReconcileOrphan, EventZombieRuntimeMissing:
 		return transition(event, current, db.SessionStatusCompleted, db.SessionStatusIdle, db.SessionStatusRunning, db.SessionStatusWaitingInput)
+	case EventIdleTimeout:
+		return transition(event, current, db.SessionStatusCompleted, db.SessionStatusIdle, db.SessionStatusRunning, db.SessionStatusWaitingInput)
 	case EventRuntimeExitSuccess:
 		switch current {
 		case db.SessionStatusCompleted:
@@ -72,6 +72,19 @@ func TestApplyTransitions(t *testing.T) {
 			want:    db.SessionStatusError,
 			changed: false,
 		},
+		{
+			name:    "idle timeout waiting to completed",
+			current: db.SessionStatusWaitingInput,
+			event:   EventIdleTimeout,
+			want:    db.SessionStatusCompleted,
+			changed: true,
+		},
+		{
+			name:    "reject idle timeout from error",
+			current: db.SessionStatusError,
+			event:   EventIdleTimeout,
+			wantErr: true,
+		},
 		{
 			name:    "reject notification from completed",
 			current: db.SessionStatusCompleted,
@@ -3,21 +3,119 @@ package telegram
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-// Bot is a minimal Telegram bot that responds to /start with a Web App button.
+// Command is a slash command received from a Telegram chat.
+type Command struct {
+	ChatID int64
+	UserID int64
+	Name   string // command name without the leading slash or @botname suffix
+	Args   string // remainder of the message, trimmed
+}
+
+// CommandHandler handles a slash command and returns the text to reply with.
+// An empty reply sends nothing.
+type CommandHandler func(ctx context.Context, cmd Command) string
+
+// Reaction is a change to the emoji reactions on a message.
+type Reaction struct {
+	ChatID    int64
+	MessageID int64
+	UserID    int64
+	Emoji     []string // emoji added by this change
+}
+
+// ReactionHandler handles a reaction and returns the text to reply with.
+// An empty reply sends nothing.
+type ReactionHandler func(ctx context.Context, r Reaction) string
+
+// Button is an inline keyboard button. Pressing it sends Data back to the
+// bot as a Callback; Telegram limits Data to 64 bytes.
+type Button struct {
+	Text string
+	Data string
+}
+
+// Callback is a press on an inline keyboard button.
+type Callback struct {
+	ChatID    int64
+	MessageID int64 // message carrying the keyboard
+	UserID    int64
+	Data      string
+}
+
+// CallbackHandler handles a button press and returns a short notice to show
+// the user. The keyboard is removed from the message once handled.
+type CallbackHandler func(ctx context.Context, c Callback) string
+
+// MaxDownloadBytes is the largest file the Bot API lets bots download.
+const MaxDownloadBytes = 20 << 20
+
+// File is a photo, document or voice note sent to the bot.
+type File struct {
+	ChatID    int64
+	UserID    int64
+	MessageID int64
+	ReplyTo   int64  // ID of the message this one replies to, or 0
+	Caption   string // trimmed
+	FileName  string // empty for photos
+	MimeType  string
+	Size      int64
+	// Voice marks a voice note, which carries no caption; Duration is its
+	// length in seconds.
+	Voice    bool
+	Duration int
+	// Download fetches the file contents.
+	Download func(ctx context.Context) ([]byte, error)
+}
+
+// FileHandler handles a photo, document or voice note and returns the text
+// to reply with.
+// An empty reply sends nothing.
+type FileHandler func(ctx context.Context, f File) string
+
+// Bot is a minimal Telegram bot that responds to /start with a Web App button
+// and forwards other slash commands to an optional CommandHandler.
 type Bot struct {
-	token  string
-	webURL string // e.g. "https://codeburg.miscellanics.com"
-	client *http.Client
+	token     string
+	webURL    string // e.g. "https://codeburg.miscellanics.com"
+	client    *http.Client
+	commands  CommandHandler
+	reactions ReactionHandler
+	files     FileHandler
+	callbacks CallbackHandler
+
+	webhookURL    string
+	webhookSecret string
+
+	mu sync.Mutex
+	// webhookCtx is Run's context while updates arrive through the
+	// webhook, nil otherwise.
+	webhookCtx context.Context
 }
 
+// WebhookSecretHeader carries the secret Telegram posts webhook updates
+// with.
+const WebhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxUpdateBytes caps the size of an update posted to the webhook.
+const maxUpdateBytes = 1 << 20
+
+// allowedUpdates are the update types the bot asks Telegram for.
+const allowedUpdates = `["message","message_reaction","callback_query"]`
+
 // NewBot creates a bot that sends a Web App button linking to webURL.
 func NewBot(token, webURL string) *Bot {
 	return &Bot{
@@ -27,9 +125,72 @@ func NewBot(token, webURL string) *Bot {
 	}
 }
 
-// Run starts long-polling. Blocks until ctx is cancelled.
+// SetCommandHandler registers the handler for slash commands other than /start.
+// Must be called before Run.
+func (b *Bot) SetCommandHandler(h CommandHandler) {
+	b.commands = h
+}
+
+// SetReactionHandler registers the handler for message reactions.
+// Must be called before Run.
+func (b *Bot) SetReactionHandler(h ReactionHandler) {
+	b.reactions = h
+}
+
+// SetFileHandler registers the handler for photos and documents.
+// Must be called before Run.
+func (b *Bot) SetFileHandler(h FileHandler) {
+	b.files = h
+}
+
+// SetCallbackHandler registers the handler for inline keyboard presses.
+// Must be called before Run.
+func (b *Bot) SetCallbackHandler(h CallbackHandler) {
+	b.callbacks = h
+}
+
+// SetWebhook makes Run receive updates posted to url, which must be
+// served by ServeHTTP, instead of long-polling. Telegram sends secret in
+// the WebhookSecretHeader of each update. Must be called before Run.
+func (b *Bot) SetWebhook(url, secret string) {
+	b.webhookURL = url
+	b.webhookSecret = secret
+}
+
+// Run receives updates until ctx is cancelled: through the webhook when
+// one is set and Telegram accepts it, by long-polling otherwise. The
+// webhook is tried once, as Run starts; a refused one is not retried until
+// the bot is run again.
 func (b *Bot) Run(ctx context.Context) {
 	slog.Info("telegram bot started", "web_url", b.webURL)
+	if b.webhookURL != "" {
+		err := b.callAPI(ctx, "setWebhook", map[string]any{
+			"url":             b.webhookURL,
+			"secret_token":    b.webhookSecret,
+			"allowed_updates": json.RawMessage(allowedUpdates),
+		})
+		if err == nil {
+			slog.Info("telegram webhook set", "url", b.webhookURL)
+			b.mu.Lock()
+			b.webhookCtx = ctx
+			b.mu.Unlock()
+			<-ctx.Done()
+			b.mu.Lock()
+			b.webhookCtx = nil
+			b.mu.Unlock()
+			slog.Info("telegram bot stopped")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("telegram setWebhook failed, falling back to polling", "error", err)
+	}
+
+	// getUpdates is refused while a webhook is set, e.g. by an earlier run.
+	if err := b.callAPI(ctx, "deleteWebhook", map[string]any{}); err != nil && ctx.Err() == nil {
+		slog.Warn("telegram deleteWebhook failed", "error", err)
+	}
 	offset := 0
 	for {
 		select {
@@ -53,27 +214,139 @@ func (b *Bot) Run(ctx context.Context) {
 			if u.UpdateID >= offset {
 				offset = u.UpdateID + 1
 			}
-			b.handleUpdate(u)
+			b.handleUpdate(ctx, u)
 		}
 	}
 }
 
 type update struct {
-	UpdateID int      `json:"update_id"`
-	Message  *message `json:"message"`
+	UpdateID        int              `json:"update_id"`
+	Message         *message         `json:"message"`
+	MessageReaction *messageReaction `json:"message_reaction"`
+	CallbackQuery   *callbackQuery   `json:"callback_query"`
 }
 
 type message struct {
-	Chat chat   `json:"chat"`
-	Text string `json:"text"`
+	MessageID      int64       `json:"message_id"`
+	Chat           chat        `json:"chat"`
+	From           *user       `json:"from"`
+	Text           string      `json:"text"`
+	Caption        string      `json:"caption"`
+	Photo          []photoSize `json:"photo"`
+	Document       *document   `json:"document"`
+	Voice          *voice      `json:"voice"`
+	ReplyToMessage *message    `json:"reply_to_message"`
+}
+
+type photoSize struct {
+	FileID   string `json:"file_id"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileSize int64  `json:"file_size"`
+}
+
+type document struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	MimeType string `json:"mime_type"`
+	FileSize int64  `json:"file_size"`
+}
+
+type voice struct {
+	FileID   string `json:"file_id"`
+	Duration int    `json:"duration"`
+	MimeType string `json:"mime_type"`
+	FileSize int64  `json:"file_size"`
+}
+
+type messageReaction struct {
+	Chat        chat           `json:"chat"`
+	MessageID   int64          `json:"message_id"`
+	User        *user          `json:"user"`
+	OldReaction []reactionType `json:"old_reaction"`
+	NewReaction []reactionType `json:"new_reaction"`
+}
+
+type callbackQuery struct {
+	ID      string   `json:"id"`
+	From    user     `json:"from"`
+	Message *message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+type reactionType struct {
+	Type  string `json:"type"` // "emoji", "custom_emoji" or "paid"
+	Emoji string `json:"emoji"`
+}
+
+type user struct {
+	ID int64 `json:"id"`
 }
 
 type chat struct {
 	ID int64 `json:"id"`
 }
 
+// ServeHTTP handles an update posted to the webhook. Updates are refused
+// unless the webhook is in use and they carry its secret. The update is
+// handled after Telegram gets its answer, so slow handlers don't make it
+// retry.
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	ctx := b.webhookCtx
+	b.mu.Unlock()
+	if ctx == nil {
+		http.Error(w, "webhook not in use", http.StatusNotFound)
+		return
+	}
+	secret := r.Header.Get(WebhookSecretHeader)
+	if b.webhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(b.webhookSecret)) != 1 {
+		http.Error(w, "invalid secret token", http.StatusUnauthorized)
+		return
+	}
+
+	var u update
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateBytes)).Decode(&u); err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	go b.handleUpdate(ctx, u)
+}
+
+// callAPI calls a Bot API method that returns no result of interest.
+func (b *Bot) callAPI(ctx context.Context, method string, payload map[string]any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", b.token, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram %s: %s", method, result.Description)
+	}
+	return nil
+}
+
 func (b *Bot) getUpdates(ctx context.Context, offset int) ([]update, error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?offset=%d&timeout=30&allowed_updates=[\"message\"]", b.token, offset)
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?offset=%d&timeout=30&allowed_updates=%s", b.token, offset, allowedUpdates)
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
@@ -103,8 +376,42 @@ func (b *Bot) getUpdates(ctx context.Context, offset int) ([]update, error) {
 	return result.Result, nil
 }
 
-func (b *Bot) handleUpdate(u update) {
-	if u.Message == nil || u.Message.Text != "/start" {
+// ParseCommand splits "/name@bot args" into its name and arguments.
+func ParseCommand(text string) (name, args string, ok bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	head, rest, _ := strings.Cut(text[1:], " ")
+	head, _, _ = strings.Cut(head, "@")
+	if head == "" {
+		return "", "", false
+	}
+	return strings.ToLower(head), strings.TrimSpace(rest), true
+}
+
+func (b *Bot) handleUpdate(ctx context.Context, u update) {
+	if u.MessageReaction != nil {
+		b.handleReaction(ctx, u.MessageReaction)
+		return
+	}
+	if u.CallbackQuery != nil {
+		b.handleCallback(ctx, u.CallbackQuery)
+		return
+	}
+	if u.Message == nil {
+		return
+	}
+	if len(u.Message.Photo) > 0 || u.Message.Document != nil || u.Message.Voice != nil {
+		b.handleFile(ctx, u.Message)
+		return
+	}
+	name, args, ok := ParseCommand(u.Message.Text)
+	if !ok {
+		return
+	}
+	if name != "start" {
+		b.handleCommand(ctx, u.Message, name, args)
 		return
 	}
 
@@ -131,6 +438,299 @@ func (b *Bot) handleUpdate(u update) {
 	b.sendJSON("sendMessage", payload)
 }
 
+func (b *Bot) handleCommand(ctx context.Context, msg *message, name, args string) {
+	if b.commands == nil {
+		return
+	}
+	cmd := Command{ChatID: msg.Chat.ID, Name: name, Args: args}
+	if msg.From != nil {
+		cmd.UserID = msg.From.ID
+	}
+	reply := b.commands(ctx, cmd)
+	if reply == "" {
+		return
+	}
+	b.SendMessage(cmd.ChatID, reply)
+}
+
+func (b *Bot) handleReaction(ctx context.Context, mr *messageReaction) {
+	if b.reactions == nil {
+		return
+	}
+	r := Reaction{ChatID: mr.Chat.ID, MessageID: mr.MessageID, Emoji: addedEmoji(mr.OldReaction, mr.NewReaction)}
+	if mr.User != nil {
+		r.UserID = mr.User.ID
+	}
+	if len(r.Emoji) == 0 {
+		return // reaction removed
+	}
+	if reply := b.reactions(ctx, r); reply != "" {
+		b.SendMessage(r.ChatID, reply)
+	}
+}
+
+func (b *Bot) handleCallback(ctx context.Context, cq *callbackQuery) {
+	notice := ""
+	if b.callbacks != nil && cq.Message != nil {
+		notice = b.callbacks(ctx, Callback{
+			ChatID:    cq.Message.Chat.ID,
+			MessageID: cq.Message.MessageID,
+			UserID:    cq.From.ID,
+			Data:      cq.Data,
+		})
+	}
+	// Always answer, or the client keeps showing a spinner on the button.
+	b.sendJSON("answerCallbackQuery", map[string]any{
+		"callback_query_id": cq.ID,
+		"text":              notice,
+	})
+	if notice != "" && cq.Message != nil {
+		b.sendJSON("editMessageReplyMarkup", map[string]any{
+			"chat_id":      cq.Message.Chat.ID,
+			"message_id":   cq.Message.MessageID,
+			"reply_markup": map[string]any{"inline_keyboard": [][]map[string]any{}},
+		})
+	}
+}
+
+func (b *Bot) handleFile(ctx context.Context, msg *message) {
+	if b.files == nil {
+		return
+	}
+	f := File{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.MessageID,
+		Caption:   strings.TrimSpace(msg.Caption),
+	}
+	if msg.From != nil {
+		f.UserID = msg.From.ID
+	}
+	if msg.ReplyToMessage != nil {
+		f.ReplyTo = msg.ReplyToMessage.MessageID
+	}
+
+	var fileID string
+	switch {
+	case msg.Voice != nil:
+		fileID = msg.Voice.FileID
+		f.MimeType = msg.Voice.MimeType
+		if f.MimeType == "" {
+			f.MimeType = "audio/ogg"
+		}
+		f.Size = msg.Voice.FileSize
+		f.Voice = true
+		f.Duration = msg.Voice.Duration
+	case msg.Document != nil:
+		fileID = msg.Document.FileID
+		f.FileName = msg.Document.FileName
+		f.MimeType = msg.Document.MimeType
+		f.Size = msg.Document.FileSize
+	default:
+		// Photos arrive in several sizes, smallest first.
+		largest := msg.Photo[len(msg.Photo)-1]
+		fileID = largest.FileID
+		f.MimeType = "image/jpeg"
+		f.Size = largest.FileSize
+	}
+	f.Download = func(ctx context.Context) ([]byte, error) {
+		return b.DownloadFileByID(ctx, fileID)
+	}
+
+	if reply := b.files(ctx, f); reply != "" {
+		b.SendMessage(f.ChatID, reply)
+	}
+}
+
+// DownloadFileByID resolves a file ID with getFile and downloads the file.
+func (b *Bot) DownloadFileByID(ctx context.Context, fileID string) ([]byte, error) {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getFile?file_id=%s", b.token, url.QueryEscape(fileID))
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			FilePath string `json:"file_path"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.OK || result.Result.FilePath == "" {
+		return nil, fmt.Errorf("telegram getFile: %s", result.Description)
+	}
+
+	fileURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", b.token, result.Result.FilePath)
+	req, err = http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	fileResp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer fileResp.Body.Close()
+	if fileResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram file download: %s", fileResp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(fileResp.Body, MaxDownloadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDownloadBytes {
+		return nil, fmt.Errorf("file exceeds %d bytes", MaxDownloadBytes)
+	}
+	return data, nil
+}
+
+// addedEmoji returns the emoji present in next but not in prev.
+func addedEmoji(prev, next []reactionType) []string {
+	var added []string
+	for _, n := range next {
+		if n.Type != "emoji" {
+			continue
+		}
+		found := false
+		for _, p := range prev {
+			if p.Type == "emoji" && p.Emoji == n.Emoji {
+				found = true
+				break
+			}
+		}
+		if !found {
+			added = append(added, n.Emoji)
+		}
+	}
+	return added
+}
+
+// SendMessage sends a plain text message to a chat.
+func (b *Bot) SendMessage(chatID int64, text string) {
+	b.sendJSON("sendMessage", map[string]any{
+		"chat_id": chatID,
+		"text":    text,
+	})
+}
+
+// Send sends a plain text message and returns its message ID.
+func (b *Bot) Send(chatID int64, text string) (int64, error) {
+	return b.send(map[string]any{
+		"chat_id": chatID,
+		"text":    text,
+	})
+}
+
+// SendWithButtons sends a plain text message with an inline keyboard, one
+// slice of buttons per row, and returns its message ID.
+func (b *Bot) SendWithButtons(chatID int64, text string, rows [][]Button) (int64, error) {
+	keyboard := make([][]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		buttons := make([]map[string]any, 0, len(row))
+		for _, button := range row {
+			buttons = append(buttons, map[string]any{"text": button.Text, "callback_data": button.Data})
+		}
+		keyboard = append(keyboard, buttons)
+	}
+	return b.send(map[string]any{
+		"chat_id":      chatID,
+		"text":         text,
+		"reply_markup": map[string]any{"inline_keyboard": keyboard},
+	})
+}
+
+// SendPhoto sends a PNG or JPEG image with an optional caption and returns
+// the message ID.
+func (b *Bot) SendPhoto(ctx context.Context, chatID int64, photo []byte, filename, caption string) (int64, error) {
+	return b.sendUpload(ctx, "sendPhoto", "photo", chatID, photo, filename, caption)
+}
+
+// SendVoice sends OGG/Opus, MP3 or M4A audio as a voice message with an
+// optional caption and returns the message ID.
+func (b *Bot) SendVoice(ctx context.Context, chatID int64, audio []byte, filename, caption string) (int64, error) {
+	return b.sendUpload(ctx, "sendVoice", "voice", chatID, audio, filename, caption)
+}
+
+// sendUpload calls a method that sends a file, uploaded as field.
+func (b *Bot) sendUpload(ctx context.Context, method, field string, chatID int64, data []byte, filename, caption string) (int64, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	if caption != "" {
+		_ = form.WriteField("caption", caption)
+	}
+	part, err := form.CreateFormFile(field, filename)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return 0, err
+	}
+	if err := form.Close(); err != nil {
+		return 0, err
+	}
+
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", b.token, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, &body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool    `json:"ok"`
+		Description string  `json:"description"`
+		Result      message `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	if !result.OK {
+		return 0, fmt.Errorf("telegram %s: %s", method, result.Description)
+	}
+	return result.Result.MessageID, nil
+}
+
+func (b *Bot) send(payload map[string]any) (int64, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", b.token)
+	resp, err := b.client.Post(apiURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool    `json:"ok"`
+		Description string  `json:"description"`
+		Result      message `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	if !result.OK {
+		return 0, fmt.Errorf("telegram sendMessage: %s", result.Description)
+	}
+	return result.Result.MessageID, nil
+}
+
 func (b *Bot) sendJSON(method string, payload any) {
 	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", b.token, method)
 
@@ -0,0 +1,53 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	b := NewBot("token", "https://example.com")
+	b.SetWebhook("https://example.com/api/telegram/webhook", "s3cret")
+	got := make(chan Command, 1)
+	b.SetCommandHandler(func(_ context.Context, cmd Command) string {
+		got <- cmd
+		return ""
+	})
+	post := func(secret, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/telegram/webhook", strings.NewReader(body))
+		req.Header.Set(WebhookSecretHeader, secret)
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	update := `{"update_id":1,"message":{"message_id":5,"chat":{"id":42},"from":{"id":42},"text":"/tasks now"}}`
+
+	if code := post("s3cret", update); code != http.StatusNotFound {
+		t.Errorf("expected 404 before Run set the webhook, got %d", code)
+	}
+
+	b.mu.Lock()
+	b.webhookCtx = t.Context()
+	b.mu.Unlock()
+	if code := post("wrong", update); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong secret, got %d", code)
+	}
+	if code := post("s3cret", "{"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed update, got %d", code)
+	}
+	if code := post("s3cret", update); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	select {
+	case cmd := <-got:
+		if cmd.ChatID != 42 || cmd.UserID != 42 || cmd.Name != "tasks" || cmd.Args != "now" {
+			t.Errorf("unexpected command %+v", cmd)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("command not handled")
+	}
+}
@@ -0,0 +1,285 @@
+// Package telemetry records OpenTelemetry-compatible trace spans and exports
+// them to an OTLP/HTTP collector using the JSON encoding.
+//
+// Tracing is off until Setup is called with an endpoint; until then Start
+// returns a nil *Span, whose methods are no-ops, so instrumented code costs
+// next to nothing when no collector is configured.
+package telemetry
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config configures the OTLP exporter.
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces.
+	// Tracing is disabled when empty.
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+}
+
+// ConfigFromEnv reads the standard OTEL_EXPORTER_OTLP_* and OTEL_SERVICE_NAME
+// environment variables.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		Headers:     parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	}
+	if cfg.Endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			cfg.Endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "codeburg"
+	}
+	return cfg
+}
+
+// BindFlags registers -otlp-endpoint, -otlp-headers and -otel-service-name
+// on fs. cfg's current values, typically from ConfigFromEnv, are the
+// defaults, and parsed flags are written back into cfg.
+func (cfg *Config) BindFlags(fs *flag.FlagSet) {
+	fs.Func("otlp-endpoint", "OTLP/HTTP collector URL; a bare origin gets /v1/traces appended (default $OTEL_EXPORTER_OTLP_ENDPOINT)", func(value string) error {
+		endpoint, err := tracesEndpoint(value)
+		if err != nil {
+			return err
+		}
+		cfg.Endpoint = endpoint
+		return nil
+	})
+	fs.Func("otlp-headers", "extra exporter headers as k1=v1,k2=v2 (default $OTEL_EXPORTER_OTLP_HEADERS)", func(value string) error {
+		if cfg.Headers == nil {
+			cfg.Headers = make(map[string]string)
+		}
+		for k, v := range parseHeaders(value) {
+			cfg.Headers[k] = v
+		}
+		return nil
+	})
+	fs.StringVar(&cfg.ServiceName, "otel-service-name", cfg.ServiceName, "service name reported on spans")
+}
+
+// tracesEndpoint accepts either a full traces URL or a collector origin.
+func tracesEndpoint(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q", value)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// parseHeaders parses "k1=v1,k2=v2".
+func parseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
+
+var active atomic.Pointer[exporter]
+
+// Setup starts exporting spans and returns a function that flushes pending
+// spans and stops the exporter. With an empty endpoint it does nothing.
+func Setup(cfg Config) (shutdown func(context.Context) error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+	exp := newExporter(cfg)
+	active.Store(exp)
+	go exp.run()
+	return func(ctx context.Context) error {
+		active.CompareAndSwap(exp, nil)
+		return exp.shutdown(ctx)
+	}
+}
+
+// Enabled reports whether spans are being exported.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// SpanKind mirrors the OTLP span kinds used here.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Attr is a span attribute. Values are strings, ints, bools or float64s.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attr    { return Attr{key, value} }
+func Int(key string, value int) Attr   { return Attr{key, value} }
+func Bool(key string, value bool) Attr { return Attr{key, value} }
+
+// Span is an in-progress operation. A nil *Span is valid and records nothing.
+type Span struct {
+	exp      *exporter
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	kind     SpanKind
+	start    time.Time
+
+	mu     sync.Mutex
+	name   string
+	end    time.Time
+	attrs  []Attr
+	errMsg string
+	failed bool
+	ended  bool
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the current span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start begins an internal span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	return StartKind(ctx, KindInternal, name, attrs...)
+}
+
+// StartKind begins a span of the given kind as a child of the span in ctx,
+// or of a remote parent set with ContextWithTraceParent.
+func StartKind(ctx context.Context, kind SpanKind, name string, attrs ...Attr) (context.Context, *Span) {
+	exp := active.Load()
+	if exp == nil {
+		return ctx, nil
+	}
+
+	span := &Span{exp: exp, name: name, kind: kind, start: time.Now(), attrs: attrs}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else if remote, ok := ctx.Value(remoteKey{}).(remoteParent); ok {
+		span.traceID = remote.traceID
+		span.parentID = remote.spanID
+	} else {
+		fillRandom(span.traceID[:])
+	}
+	fillRandom(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetName renames the span, e.g. once an HTTP route is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed = true
+	s.errMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.exp.enqueue(s)
+}
+
+// TraceParent returns the W3C traceparent header value for the span.
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+type remoteKey struct{}
+
+type remoteParent struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// ContextWithTraceParent makes a W3C traceparent header the parent of the
+// next span started from the returned context. Invalid values are ignored.
+func ContextWithTraceParent(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var remote remoteParent
+	if _, err := hex.Decode(remote.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(remote.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	if remote.traceID == ([16]byte{}) || remote.spanID == ([8]byte{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, remote)
+}
+
+func fillRandom(b []byte) {
+	for i := 0; i < len(b); i += 8 {
+		v := rand.Uint64()
+		for j := 0; j < 8 && i+j < len(b); j++ {
+			b[i+j] = byte(v >> (8 * j))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// collector is a fake OTLP/HTTP endpoint recording exported spans.
+type collector struct {
+	mu      sync.Mutex
+	spans   []otlpSpan
+	headers http.Header
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	t.Helper()
+	c := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode export: %v", err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.headers = r.Header.Clone()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func TestSpansAreExported(t *testing.T) {
+	c, srv := newCollector(t)
+	shutdown := Setup(Config{Endpoint: srv.URL + "/v1/traces", Headers: map[string]string{"Authorization": "Bearer x"}, ServiceName: "test"})
+
+	ctx := ContextWithTraceParent(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	ctx, parent := StartKind(ctx, KindServer, "GET /api/projects")
+	_, child := Start(ctx, "git status", String("git.args", "status"))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	parent.End()
+	parent.End() // ignored
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled() {
+		t.Error("expected tracing to be disabled after shutdown")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(c.spans))
+	}
+	gotChild, gotParent := c.spans[0], c.spans[1]
+	if gotParent.TraceID != "0af7651916cd43dd8448eb211c80319c" || gotParent.ParentSpanID != "b7ad6b7169203331" {
+		t.Errorf("expected the remote parent to be continued, got %+v", gotParent)
+	}
+	if gotChild.TraceID != gotParent.TraceID || gotChild.ParentSpanID != gotParent.SpanID {
+		t.Errorf("expected child of %s, got %+v", gotParent.SpanID, gotChild)
+	}
+	if gotChild.Status == nil || gotChild.Status.Message != "boom" {
+		t.Errorf("expected error status, got %+v", gotChild.Status)
+	}
+	if c.headers.Get("Authorization") != "Bearer x" {
+		t.Errorf("expected configured headers, got %v", c.headers)
+	}
+}
+
+func TestDisabledSpansAreNoops(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("expected no span while tracing is disabled")
+	}
+	span.SetAttributes(Int("n", 1))
+	span.RecordError(errors.New("ignored"))
+	span.End()
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "x-api-key=abc, tenant = t1")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	cfg := ConfigFromEnv()
+	if cfg.Endpoint != "http://collector:4318/v1/traces" || cfg.ServiceName != "codeburg" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if cfg.Headers["x-api-key"] != "abc" || cfg.Headers["tenant"] != "t1" {
+		t.Errorf("unexpected headers: %v", cfg.Headers)
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	cfg := Config{Endpoint: "http://env:4318/v1/traces", ServiceName: "codeburg", Headers: map[string]string{"a": "1"}}
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	cfg.BindFlags(fs)
+
+	if err := fs.Parse(nil); err != nil || cfg.Endpoint != "http://env:4318/v1/traces" {
+		t.Fatalf("expected env defaults to survive without flags, got %+v (%v)", cfg, err)
+	}
+
+	err := fs.Parse([]string{"-otlp-endpoint", "http://collector:4318", "-otlp-headers", "b=2", "-otel-service-name", "cb-dev"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg.Endpoint != "http://collector:4318/v1/traces" {
+		t.Errorf("expected /v1/traces appended to a bare origin, got %q", cfg.Endpoint)
+	}
+	if cfg.Headers["a"] != "1" || cfg.Headers["b"] != "2" || cfg.ServiceName != "cb-dev" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	fs = flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg.BindFlags(fs)
+	if err := fs.Parse([]string{"-otlp-endpoint", "collector:4318"}); err == nil {
+		t.Error("expected an endpoint without scheme to be rejected")
+	}
+}
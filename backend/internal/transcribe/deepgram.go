@@ -0,0 +1,76 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultDeepgramBaseURL = "https://api.deepgram.com"
+	DefaultDeepgramModel   = "nova-2"
+)
+
+// Deepgram transcribes with Deepgram's pre-recorded audio API.
+type Deepgram struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+func (d *Deepgram) Name() string { return "deepgram" }
+
+func (d *Deepgram) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	if d.APIKey == "" {
+		return "", fmt.Errorf("deepgram: api key is required")
+	}
+	baseURL := strings.TrimSuffix(d.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultDeepgramBaseURL
+	}
+	model := d.Model
+	if model == "" {
+		model = DefaultDeepgramModel
+	}
+	query := url.Values{"model": {model}, "smart_format": {"true"}}
+	if audio.Language != "" {
+		query.Set("language", audio.Language)
+	} else {
+		query.Set("detect_language", "true")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/listen?"+query.Encode(), bytes.NewReader(audio.Data))
+	if err != nil {
+		return "", fmt.Errorf("deepgram: %w", err)
+	}
+	if audio.MimeType != "" {
+		req.Header.Set("Content-Type", audio.MimeType)
+	}
+	req.Header.Set("Authorization", "Token "+d.APIKey)
+
+	data, err := do(d.Client, d.Name(), req)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Results struct {
+			Channels []struct {
+				Alternatives []struct {
+					Transcript string `json:"transcript"`
+				} `json:"alternatives"`
+			} `json:"channels"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("deepgram: invalid response: %w", err)
+	}
+	if len(result.Results.Channels) == 0 || len(result.Results.Channels[0].Alternatives) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(result.Results.Channels[0].Alternatives[0].Transcript), nil
+}
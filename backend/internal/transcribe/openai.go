@@ -0,0 +1,93 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	DefaultOpenAIModel   = "whisper-1"
+	DefaultGroqBaseURL   = "https://api.groq.com/openai/v1"
+	DefaultGroqModel     = "whisper-large-v3-turbo"
+)
+
+// OpenAI transcribes with an OpenAI-compatible /audio/transcriptions
+// endpoint, which OpenAI, Groq and several self-hosted servers provide.
+type OpenAI struct {
+	// Provider names the service for Name; it defaults to "openai".
+	Provider string
+	BaseURL  string
+	APIKey   string
+	Model    string
+	Client   *http.Client
+}
+
+// NewGroq returns a transcriber for Groq's OpenAI-compatible API.
+func NewGroq(apiKey, model string) *OpenAI {
+	if model == "" {
+		model = DefaultGroqModel
+	}
+	return &OpenAI{Provider: "groq", BaseURL: DefaultGroqBaseURL, APIKey: apiKey, Model: model}
+}
+
+func (o *OpenAI) Name() string {
+	if o.Provider != "" {
+		return o.Provider
+	}
+	return "openai"
+}
+
+func (o *OpenAI) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	if o.APIKey == "" {
+		return "", fmt.Errorf("%s: api key is required", o.Name())
+	}
+	baseURL := strings.TrimSuffix(o.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	model := o.Model
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("model", model)
+	form.WriteField("response_format", "json")
+	if audio.Language != "" {
+		form.WriteField("language", audio.Language)
+	}
+	part, err := form.CreateFormFile("file", fileNameOrDefault(audio))
+	if err != nil {
+		return "", err
+	}
+	part.Write(audio.Data)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", o.Name(), err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+o.APIKey)
+
+	data, err := do(o.Client, o.Name(), req)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("%s: invalid response: %w", o.Name(), err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
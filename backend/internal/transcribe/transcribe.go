@@ -0,0 +1,63 @@
+// Package transcribe turns speech into text through pluggable providers:
+// OpenAI-compatible APIs (OpenAI, Groq), Deepgram and a local whisper.cpp
+// server.
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Audio is a recording to transcribe.
+type Audio struct {
+	Data     []byte
+	FileName string // e.g. "voice.ogg"; some providers infer the format from it
+	MimeType string
+	// Language is an optional ISO-639-1 hint such as "en".
+	Language string
+}
+
+// Transcriber is a speech-to-text provider.
+type Transcriber interface {
+	// Name identifies the provider in logs and replies.
+	Name() string
+	Transcribe(ctx context.Context, audio Audio) (string, error)
+}
+
+var defaultClient = &http.Client{Timeout: 2 * time.Minute}
+
+func clientOrDefault(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return defaultClient
+}
+
+// do sends req and returns the response body, or an error naming the
+// provider for non-2xx responses.
+func do(client *http.Client, name string, req *http.Request) ([]byte, error) {
+	resp, err := clientOrDefault(client).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s: %s", name, resp.Status, strings.TrimSpace(string(body[:min(len(body), 1024)])))
+	}
+	return body, nil
+}
+
+func fileNameOrDefault(audio Audio) string {
+	if audio.FileName != "" {
+		return audio.FileName
+	}
+	return "audio.ogg"
+}
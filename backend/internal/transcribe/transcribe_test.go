@@ -0,0 +1,113 @@
+package transcribe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAITranscribe(t *testing.T) {
+	var auth, model, fileName string
+	var data []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		model = r.FormValue("model")
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("form file: %v", err)
+		}
+		fileName = header.Filename
+		data, _ = io.ReadAll(file)
+		json.NewEncoder(w).Encode(map[string]string{"text": " run the tests \n"})
+	}))
+	defer srv.Close()
+
+	groq := NewGroq("gsk-key", "")
+	groq.BaseURL = srv.URL
+	text, err := groq.Transcribe(context.Background(), Audio{Data: []byte("ogg"), FileName: "voice.ogg"})
+	if err != nil {
+		t.Fatalf("transcribe: %v", err)
+	}
+	if text != "run the tests" {
+		t.Errorf("text = %q", text)
+	}
+	if auth != "Bearer gsk-key" || model != DefaultGroqModel || fileName != "voice.ogg" || string(data) != "ogg" {
+		t.Errorf("unexpected request: auth=%q model=%q file=%q data=%q", auth, model, fileName, data)
+	}
+	if groq.Name() != "groq" || (&OpenAI{}).Name() != "openai" {
+		t.Errorf("unexpected names %q, %q", groq.Name(), (&OpenAI{}).Name())
+	}
+
+	if _, err := (&OpenAI{BaseURL: srv.URL}).Transcribe(context.Background(), Audio{}); err == nil {
+		t.Error("expected an error without an api key")
+	}
+}
+
+func TestDeepgramTranscribe(t *testing.T) {
+	var auth, contentType string
+	var query map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		contentType = r.Header.Get("Content-Type")
+		query = r.URL.Query()
+		io.WriteString(w, `{"results":{"channels":[{"alternatives":[{"transcript":"stop the session"}]}]}}`)
+	}))
+	defer srv.Close()
+
+	d := &Deepgram{BaseURL: srv.URL, APIKey: "dg-key"}
+	text, err := d.Transcribe(context.Background(), Audio{Data: []byte("ogg"), MimeType: "audio/ogg", Language: "en"})
+	if err != nil {
+		t.Fatalf("transcribe: %v", err)
+	}
+	if text != "stop the session" {
+		t.Errorf("text = %q", text)
+	}
+	if auth != "Token dg-key" || contentType != "audio/ogg" {
+		t.Errorf("unexpected headers: auth=%q content-type=%q", auth, contentType)
+	}
+	if query["model"][0] != DefaultDeepgramModel || query["language"][0] != "en" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestWhisperCppTranscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.FormValue("language") == "xx" {
+			io.WriteString(w, `{"error":"unsupported language"}`)
+			return
+		}
+		io.WriteString(w, `{"text":" commit and push"}`)
+	}))
+	defer srv.Close()
+
+	w := &WhisperCpp{URL: srv.URL}
+	text, err := w.Transcribe(context.Background(), Audio{Data: []byte("wav")})
+	if err != nil || text != "commit and push" {
+		t.Fatalf("transcribe = %q, %v", text, err)
+	}
+	if _, err := w.Transcribe(context.Background(), Audio{Data: []byte("wav"), Language: "xx"}); err == nil || !strings.Contains(err.Error(), "unsupported language") {
+		t.Errorf("expected the server's error, got %v", err)
+	}
+}
+
+func TestTranscribeErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := (&OpenAI{BaseURL: srv.URL, APIKey: "bad"}).Transcribe(context.Background(), Audio{Data: []byte("x")})
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid key") {
+		t.Errorf("expected a 401 error with the body, got %v", err)
+	}
+}
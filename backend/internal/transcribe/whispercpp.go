@@ -0,0 +1,68 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// DefaultWhisperCppURL is where whisper.cpp's server listens by default.
+const DefaultWhisperCppURL = "http://127.0.0.1:8080"
+
+// WhisperCpp transcribes with a local whisper.cpp server (examples/server).
+// Telegram voice notes are OGG/Opus, so start the server with --convert to
+// have it decode them with ffmpeg.
+type WhisperCpp struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WhisperCpp) Name() string { return "whisper.cpp" }
+
+func (w *WhisperCpp) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	server := strings.TrimSuffix(w.URL, "/")
+	if server == "" {
+		server = DefaultWhisperCppURL
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("response_format", "json")
+	if audio.Language != "" {
+		form.WriteField("language", audio.Language)
+	}
+	part, err := form.CreateFormFile("file", fileNameOrDefault(audio))
+	if err != nil {
+		return "", err
+	}
+	part.Write(audio.Data)
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+"/inference", &body)
+	if err != nil {
+		return "", fmt.Errorf("whisper.cpp: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	data, err := do(w.Client, w.Name(), req)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("whisper.cpp: invalid response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("whisper.cpp: %s", result.Error)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
@@ -0,0 +1,156 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+)
+
+// AuthMode selects how a tunneled port is protected.
+type AuthMode string
+
+const (
+	AuthNone  AuthMode = ""
+	AuthBasic AuthMode = "basic"
+	AuthToken AuthMode = "token"
+)
+
+// TokenParam is the query parameter carrying a tunnel's access token. The
+// token is moved into a cookie on first use so assets and links keep working.
+const (
+	TokenParam  = "codeburg_token"
+	tokenCookie = "codeburg_tunnel"
+)
+
+// Auth protects a tunnel with HTTP basic auth or a signed access token.
+type Auth struct {
+	Mode     AuthMode
+	Username string
+	Password string
+}
+
+// authProxy is a local reverse proxy in front of a tunneled port. cloudflared
+// points at the proxy instead of the port when a tunnel has auth enabled.
+type authProxy struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// tokenFor signs a tunnel ID with the manager's key.
+func (m *Manager) tokenFor(tunnelID string) string {
+	mac := hmac.New(sha256.New, m.tokenKey)
+	mac.Write([]byte(tunnelID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTokenKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("tunnel: generate token key: %v", err))
+	}
+	return key
+}
+
+// startAuthProxy listens on a free loopback port and forwards authorized
+// requests to the target port.
+func startAuthProxy(targetPort int, auth Auth, token string) (*authProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	target := &url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", targetPort)}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		// Keep the tunnel's credentials away from the dev server.
+		r.Header.Del("Authorization")
+		removeCookie(r, tokenCookie)
+	}
+
+	p := &authProxy{
+		listener: listener,
+		server: &http.Server{
+			Handler:           authHandler(auth, token, proxy),
+			ReadHeaderTimeout: 30 * time.Second,
+		},
+	}
+	go p.server.Serve(listener)
+	return p, nil
+}
+
+// Port returns the loopback port the proxy listens on.
+func (p *authProxy) Port() int {
+	return p.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (p *authProxy) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = p.server.Shutdown(ctx)
+}
+
+func authHandler(auth Auth, token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch auth.Mode {
+		case AuthBasic:
+			user, pass, ok := r.BasicAuth()
+			if !ok || !constantTimeEqual(user, auth.Username) || !constantTimeEqual(pass, auth.Password) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="codeburg tunnel", charset="UTF-8"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+		case AuthToken:
+			if given := r.URL.Query().Get(TokenParam); given != "" {
+				if !constantTimeEqual(given, token) {
+					http.Error(w, "invalid token", http.StatusForbidden)
+					return
+				}
+				http.SetCookie(w, &http.Cookie{
+					Name:     tokenCookie,
+					Value:    token,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   true,
+					SameSite: http.SameSiteLaxMode,
+				})
+				query := r.URL.Query()
+				query.Del(TokenParam)
+				r.URL.RawQuery = query.Encode()
+				break
+			}
+			cookie, err := r.Cookie(tokenCookie)
+			if err != nil || !constantTimeEqual(cookie.Value, token) {
+				http.Error(w, "this tunnel requires an access token", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// removeCookie drops one cookie from a request's Cookie header.
+func removeCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != name {
+			r.AddCookie(c)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// Provider names.
+const (
+	ProviderCloudflared = "cloudflared"
+	ProviderTailscale   = "tailscale"
+	ProviderNgrok       = "ngrok"
+)
+
+// DefaultProvider is used when a tunnel doesn't name one.
+const DefaultProvider = ProviderCloudflared
+
+// Provider exposes local ports on public URLs through an external tool.
+type Provider interface {
+	// Name identifies the provider in options and tunnel info.
+	Name() string
+	// Available reports whether the provider's tool is installed.
+	Available() bool
+	// Command builds the process exposing port. authToken is the account
+	// token for providers that take one. release, when not nil, is called
+	// once the process has exited.
+	Command(ctx context.Context, port int, authToken string) (cmd *exec.Cmd, release func(), err error)
+	// ParseURL finds the public URL in a line of the process output, or
+	// returns "".
+	ParseURL(line string) string
+	// Down reports whether a response from the public URL says the tunnel
+	// is gone, although the provider's edge answered.
+	Down(resp *http.Response) bool
+}
+
+// ProviderStatus describes a provider for clients choosing one.
+type ProviderStatus struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+}
+
+func defaultProviders() map[string]Provider {
+	return map[string]Provider{
+		ProviderCloudflared: cloudflared{},
+		ProviderTailscale:   &tailscale{inUse: make(map[int]bool)},
+		ProviderNgrok:       ngrok{},
+	}
+}
+
+func commandAvailable(name string, args ...string) bool {
+	return exec.Command(name, args...).Run() == nil
+}
+
+// cloudflared runs Cloudflare quick tunnels, which need no account.
+type cloudflared struct{}
+
+var cloudflaredURL = regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`)
+
+func (cloudflared) Name() string    { return ProviderCloudflared }
+func (cloudflared) Available() bool { return commandAvailable("cloudflared", "--version") }
+
+func (cloudflared) Command(ctx context.Context, port int, _ string) (*exec.Cmd, func(), error) {
+	return exec.CommandContext(ctx, "cloudflared", "tunnel", "--url", fmt.Sprintf("http://localhost:%d", port)), nil, nil
+}
+
+func (cloudflared) ParseURL(line string) string { return cloudflaredURL.FindString(line) }
+
+// Down matches Cloudflare's 530 for a tunnel that is no longer connected.
+func (cloudflared) Down(resp *http.Response) bool { return resp.StatusCode == 530 }
+
+// tailscale serves ports with Tailscale Funnel on the machine's tailnet
+// name. Funnel listens on three HTTPS ports only, so at most three tunnels
+// run at once.
+type tailscale struct {
+	mu    sync.Mutex
+	inUse map[int]bool
+}
+
+var (
+	tailscaleURL         = regexp.MustCompile(`https://[a-zA-Z0-9.-]+\.ts\.net(:[0-9]+)?`)
+	tailscaleFunnelPorts = []int{443, 8443, 10000}
+)
+
+func (*tailscale) Name() string    { return ProviderTailscale }
+func (*tailscale) Available() bool { return commandAvailable("tailscale", "version") }
+
+func (t *tailscale) Command(ctx context.Context, port int, _ string) (*exec.Cmd, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, https := range tailscaleFunnelPorts {
+		if t.inUse[https] {
+			continue
+		}
+		t.inUse[https] = true
+		release := func() {
+			t.mu.Lock()
+			delete(t.inUse, https)
+			t.mu.Unlock()
+		}
+		cmd := exec.CommandContext(ctx, "tailscale", "funnel", "--https="+strconv.Itoa(https), "--yes", strconv.Itoa(port))
+		return cmd, release, nil
+	}
+	return nil, nil, fmt.Errorf("tailscale funnel is already serving %d tunnels", len(tailscaleFunnelPorts))
+}
+
+func (*tailscale) ParseURL(line string) string { return tailscaleURL.FindString(line) }
+
+// Down never matches: a funnel that is gone refuses connections instead.
+func (*tailscale) Down(*http.Response) bool { return false }
+
+// ngrok runs ngrok agent tunnels. The auth token may also come from ngrok's
+// own config file.
+type ngrok struct{}
+
+var ngrokURL = regexp.MustCompile(`url=(https://[^\s"]+)`)
+
+func (ngrok) Name() string    { return ProviderNgrok }
+func (ngrok) Available() bool { return commandAvailable("ngrok", "version") }
+
+func (ngrok) Command(ctx context.Context, port int, authToken string) (*exec.Cmd, func(), error) {
+	cmd := exec.CommandContext(ctx, "ngrok", "http", strconv.Itoa(port), "--log", "stdout", "--log-format", "logfmt")
+	if authToken != "" {
+		cmd.Env = append(os.Environ(), "NGROK_AUTHTOKEN="+authToken)
+	}
+	return cmd, nil, nil
+}
+
+func (ngrok) ParseURL(line string) string {
+	if m := ngrokURL.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Down matches ngrok's error for an endpoint that is offline.
+func (ngrok) Down(resp *http.Response) bool {
+	return resp.Header.Get("Ngrok-Error-Code") == "ERR_NGROK_3200"
+}
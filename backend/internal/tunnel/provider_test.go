@@ -0,0 +1,194 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProviderParseURL(t *testing.T) {
+	cases := []struct {
+		provider Provider
+		line     string
+		want     string
+	}{
+		{cloudflared{}, "2024-01-01T00:00:00Z INF |  https://calm-river-42.trycloudflare.com  |", "https://calm-river-42.trycloudflare.com"},
+		{&tailscale{}, "https://laptop.tail1234.ts.net:8443/", "https://laptop.tail1234.ts.net:8443"},
+		{&tailscale{}, "|-- proxy http://127.0.0.1:3000", ""},
+		{ngrok{}, `t=2024-01-01T00:00:00+0000 lvl=info msg="started tunnel" obj=tunnels name=command_line addr=http://localhost:3000 url=https://ab12.ngrok-free.app`, "https://ab12.ngrok-free.app"},
+		{ngrok{}, `lvl=info msg="client session established"`, ""},
+	}
+	for _, tc := range cases {
+		if got := tc.provider.ParseURL(tc.line); got != tc.want {
+			t.Errorf("%s.ParseURL(%q) = %q, want %q", tc.provider.Name(), tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestTailscaleFunnelPorts(t *testing.T) {
+	ts := &tailscale{inUse: make(map[int]bool)}
+	var releases []func()
+	for _, want := range tailscaleFunnelPorts {
+		cmd, release, err := ts.Command(context.Background(), 3000, "")
+		if err != nil {
+			t.Fatalf("command: %v", err)
+		}
+		if got := cmd.Args[2]; got != fmt.Sprintf("--https=%d", want) {
+			t.Errorf("expected funnel port %d, got %s", want, got)
+		}
+		releases = append(releases, release)
+	}
+	if _, _, err := ts.Command(context.Background(), 3000, ""); err == nil {
+		t.Fatal("expected an error once every funnel port is taken")
+	}
+	releases[1]()
+	cmd, _, err := ts.Command(context.Background(), 3000, "")
+	if err != nil || cmd.Args[2] != "--https=8443" {
+		t.Fatalf("expected the released port reused, got %v (%v)", cmd, err)
+	}
+}
+
+// fakeProvider prints a URL numbered by launch, then stays up unless fail
+// is set.
+type fakeProvider struct {
+	mu       sync.Mutex
+	launches int
+	fail     bool
+	base     string
+}
+
+func (p *fakeProvider) Name() string    { return "fake" }
+func (p *fakeProvider) Available() bool { return true }
+
+func (p *fakeProvider) Command(ctx context.Context, port int, _ string) (*exec.Cmd, func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return exec.CommandContext(ctx, "/bin/sh", "-c", "echo connection refused; exit 1"), nil, nil
+	}
+	p.launches++
+	return exec.CommandContext(ctx, "/bin/sh", "-c", fmt.Sprintf("echo url=%s/%d; exec sleep 60", p.base, p.launches)), nil, nil
+}
+
+func (p *fakeProvider) ParseURL(line string) string {
+	if url, ok := strings.CutPrefix(line, "url="); ok {
+		return url
+	}
+	return ""
+}
+
+func (p *fakeProvider) Down(resp *http.Response) bool { return resp.StatusCode == 530 }
+
+func newFakeManager(p *fakeProvider) *Manager {
+	m := NewManager()
+	m.providers = map[string]Provider{"fake": p}
+	m.backoff = func(int) time.Duration { return time.Millisecond }
+	return m
+}
+
+func TestManager_ReestablishesDroppedTunnel(t *testing.T) {
+	p := &fakeProvider{base: "https://fake.example"}
+	m := newFakeManager(p)
+	reconnected := make(chan TunnelInfo, 1)
+	m.SetReconnectHandler(func(info TunnelInfo) { reconnected <- info })
+
+	tun, err := m.CreateWithOptions("t1", "task-1", 3000, "", Options{Provider: "fake"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer m.StopAll()
+	if tun.Info().URL != "https://fake.example/1" || tun.Info().Provider != "fake" {
+		t.Fatalf("unexpected tunnel %+v", tun.Info())
+	}
+
+	tun.mu.Lock()
+	tun.Cmd.Process.Kill()
+	tun.mu.Unlock()
+
+	select {
+	case info := <-reconnected:
+		if info.URL != "https://fake.example/2" || info.Restarts != 1 {
+			t.Fatalf("unexpected reconnected tunnel %+v", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the tunnel to be re-established")
+	}
+	if m.Get("t1") != tun {
+		t.Fatal("expected the tunnel to stay registered")
+	}
+}
+
+func TestManager_GivesUpAfterRelaunchesFail(t *testing.T) {
+	p := &fakeProvider{base: "https://fake.example"}
+	m := newFakeManager(p)
+	failed := make(chan error, 1)
+	m.SetFailHandler(func(_ TunnelInfo, err error) { failed <- err })
+
+	tun, err := m.CreateWithOptions("t1", "", 3000, "", Options{Provider: "fake"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	p.mu.Lock()
+	p.fail = true
+	p.mu.Unlock()
+	tun.mu.Lock()
+	tun.Cmd.Process.Kill()
+	tun.mu.Unlock()
+
+	select {
+	case err := <-failed:
+		if err == nil {
+			t.Fatal("expected the last relaunch error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the tunnel to be given up")
+	}
+	if m.Get("t1") != nil || m.FindByPort(3000) != nil {
+		t.Fatal("expected the tunnel removed")
+	}
+}
+
+func TestManager_HealthCheckRestarts(t *testing.T) {
+	edge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(530)
+	}))
+	defer edge.Close()
+
+	p := &fakeProvider{base: edge.URL}
+	m := newFakeManager(p)
+	reconnected := make(chan TunnelInfo, 1)
+	m.SetReconnectHandler(func(info TunnelInfo) { reconnected <- info })
+
+	if _, err := m.CreateWithOptions("t1", "", 3000, "", Options{Provider: "fake"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer m.StopAll()
+
+	for range maxHealthFailures {
+		m.CheckHealth(context.Background())
+	}
+	select {
+	case info := <-reconnected:
+		if info.Restarts != 1 {
+			t.Fatalf("expected one restart, got %+v", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the unhealthy tunnel to restart")
+	}
+}
+
+func TestManager_UnknownProvider(t *testing.T) {
+	m := NewManager()
+	_, err := m.CreateWithOptions("t1", "", 3000, "", Options{Provider: "carrier-pigeon"})
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Missing {
+		t.Fatalf("expected an unknown provider error, got %v", err)
+	}
+}
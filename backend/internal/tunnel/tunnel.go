@@ -3,37 +3,95 @@ package tunnel
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os/exec"
-	"regexp"
 	"sync"
+	"time"
 )
 
-// Tunnel represents an active cloudflared tunnel
+// Tunnel represents an active tunnel
 type Tunnel struct {
 	ID        string
 	TaskID    string
 	ProjectID string
 	Port      int
 	URL       string
+	Provider  string
+	Auth      AuthMode
+	CreatedAt time.Time
+	ExpiresAt time.Time // zero when the tunnel has no TTL
+	Restarts  int       // times the tunnel was re-established after dropping
+	Warned    bool      // the tunnel has been flagged as open for a long time
 	Cmd       *exec.Cmd
 	Cancel    context.CancelFunc
 	mu        sync.Mutex
 	stopped   bool
+
+	provider    Provider
+	authToken   string        // the provider's account token, if any
+	exposedPort int           // the port the provider points at: Port, or the auth proxy's
+	exited      chan struct{} // closed when the current process exits
+	done        chan struct{} // closed by shutdown
+	failures    int           // consecutive failed health checks
+	token       string        // access token for AuthToken tunnels
+	proxy       *authProxy    // nil unless auth is enabled
+	expiry      *time.Timer   // nil unless the tunnel has a TTL
 }
 
-// Manager manages cloudflared tunnels
+// Manager manages tunnels
 type Manager struct {
 	tunnels map[string]*Tunnel
 	ports   map[int]string // port -> tunnel ID
 	mu      sync.RWMutex
+
+	providers    map[string]Provider
+	tokenKey     []byte // signs tunnel access tokens
+	onExpire     func(TunnelInfo)
+	onReconnect  func(TunnelInfo)
+	onFail       func(TunnelInfo, error)
+	healthClient *http.Client
+	backoff      func(attempt int) time.Duration
+}
+
+// Options configures a new tunnel.
+type Options struct {
+	Provider  string        // empty for DefaultProvider
+	AuthToken string        // the provider's account token, for providers that take one
+	Auth      *Auth         // nil leaves the tunnel public
+	TTL       time.Duration // zero keeps the tunnel until it is stopped
 }
 
+const (
+	// urlTimeout bounds how long a provider may take to report its URL.
+	urlTimeout = 30 * time.Second
+	// maxRelaunches is how many times in a row a dropped tunnel is
+	// re-established before it is given up.
+	maxRelaunches = 5
+	// maxHealthFailures is how many health checks in a row a tunnel may
+	// fail before it is re-established.
+	maxHealthFailures = 3
+)
+
 // NewManager creates a new tunnel manager
 func NewManager() *Manager {
 	return &Manager{
-		tunnels: make(map[string]*Tunnel),
-		ports:   make(map[int]string),
+		tunnels:   make(map[string]*Tunnel),
+		ports:     make(map[int]string),
+		providers: defaultProviders(),
+		tokenKey:  newTokenKey(),
+		healthClient: &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		backoff: func(attempt int) time.Duration {
+			return time.Duration(1<<min(attempt-1, 5)) * time.Second
+		},
 	}
 }
 
@@ -47,14 +105,87 @@ func (e *PortConflictError) Error() string {
 	return fmt.Sprintf("port %d already tunneled", e.Port)
 }
 
-// Available checks if cloudflared is installed
+// ProviderError reports a provider that is unknown or not installed.
+type ProviderError struct {
+	Provider string
+	Missing  bool // known but not installed
+}
+
+func (e *ProviderError) Error() string {
+	if e.Missing {
+		return e.Provider + " is not installed"
+	}
+	return fmt.Sprintf("unknown tunnel provider %q", e.Provider)
+}
+
+// Available reports whether any tunnel provider is installed
 func (m *Manager) Available() bool {
-	cmd := exec.Command("cloudflared", "--version")
-	return cmd.Run() == nil
+	for _, p := range m.providers {
+		if p.Available() {
+			return true
+		}
+	}
+	return false
+}
+
+// Providers lists the tunnel providers by name.
+func (m *Manager) Providers() []ProviderStatus {
+	statuses := make([]ProviderStatus, 0, len(m.providers))
+	for _, name := range []string{ProviderCloudflared, ProviderTailscale, ProviderNgrok} {
+		if p, ok := m.providers[name]; ok {
+			statuses = append(statuses, ProviderStatus{Name: name, Available: p.Available()})
+		}
+	}
+	return statuses
 }
 
-// Create starts a new cloudflared tunnel
+// Create starts a new tunnel with the default provider
 func (m *Manager) Create(id, taskID string, port int, projectID ...string) (*Tunnel, error) {
+	projID := ""
+	if len(projectID) > 0 {
+		projID = projectID[0]
+	}
+	return m.CreateWithOptions(id, taskID, port, projID, Options{})
+}
+
+// SetExpireHandler registers fn to be called after a tunnel is stopped
+// because its TTL ran out.
+func (m *Manager) SetExpireHandler(fn func(TunnelInfo)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExpire = fn
+}
+
+// SetReconnectHandler registers fn to be called after a dropped tunnel is
+// re-established, possibly on a new URL.
+func (m *Manager) SetReconnectHandler(fn func(TunnelInfo)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onReconnect = fn
+}
+
+// SetFailHandler registers fn to be called after a dropped tunnel could not
+// be re-established and was removed.
+func (m *Manager) SetFailHandler(fn func(TunnelInfo, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onFail = fn
+}
+
+// CreateWithOptions starts a new tunnel. With auth set, the port is exposed
+// through a local proxy that enforces it; with a TTL, the tunnel is stopped
+// once it elapses. If the provider's process exits, the tunnel is
+// re-established.
+func (m *Manager) CreateWithOptions(id, taskID string, port int, projectID string, opts Options) (*Tunnel, error) {
+	providerName := opts.Provider
+	if providerName == "" {
+		providerName = DefaultProvider
+	}
+	provider, ok := m.providers[providerName]
+	if !ok {
+		return nil, &ProviderError{Provider: providerName}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -72,85 +203,242 @@ func (m *Manager) Create(id, taskID string, port int, projectID ...string) (*Tun
 		delete(m.ports, port)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	tunnel := &Tunnel{
+		ID:          id,
+		TaskID:      taskID,
+		ProjectID:   projectID,
+		Port:        port,
+		Provider:    providerName,
+		CreatedAt:   time.Now(),
+		provider:    provider,
+		authToken:   opts.AuthToken,
+		exposedPort: port,
+		done:        make(chan struct{}),
+	}
+
+	if auth := opts.Auth; auth != nil && auth.Mode != AuthNone {
+		tunnel.Auth = auth.Mode
+		if auth.Mode == AuthToken {
+			tunnel.token = m.tokenFor(id)
+		}
+		proxy, err := startAuthProxy(port, *auth, tunnel.token)
+		if err != nil {
+			return nil, fmt.Errorf("start auth proxy: %w", err)
+		}
+		tunnel.proxy = proxy
+		tunnel.exposedPort = proxy.Port()
+	}
+
+	if err := tunnel.launch(); err != nil {
+		tunnel.closeProxy()
+		return nil, err
+	}
+
+	m.tunnels[id] = tunnel
+	m.ports[port] = id
+	if opts.TTL > 0 {
+		m.scheduleExpiry(tunnel, opts.TTL)
+	}
+	go m.monitor(tunnel)
 
-	// Start cloudflared tunnel
-	cmd := exec.CommandContext(ctx, "cloudflared", "tunnel", "--url", fmt.Sprintf("http://localhost:%d", port))
+	return tunnel, nil
+}
 
-	// Get stderr to capture the URL
-	stderr, err := cmd.StderrPipe()
+// launch starts the tunnel's provider process and waits for its public URL.
+func (t *Tunnel) launch() error {
+	name := t.provider.Name()
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, release, err := t.provider.Command(ctx, t.exposedPort, t.authToken)
 	if err != nil {
 		cancel()
-		return nil, fmt.Errorf("create stderr pipe: %w", err)
+		return err
 	}
 
+	// Providers log their URL to stdout or stderr; both are read to the end
+	// so the process never blocks on a full pipe.
+	output, writer := io.Pipe()
+	cmd.Stdout, cmd.Stderr = writer, writer
 	if err := cmd.Start(); err != nil {
 		cancel()
-		return nil, fmt.Errorf("start cloudflared: %w", err)
+		if release != nil {
+			release()
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return &ProviderError{Provider: name, Missing: true}
+		}
+		return fmt.Errorf("start %s: %w", name, err)
 	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		writer.Close()
+		if release != nil {
+			release()
+		}
+		close(exited)
+	}()
 
-	projID := ""
-	if len(projectID) > 0 {
-		projID = projectID[0]
+	urlChan := make(chan string, 1)
+	lastLine := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(output)
+		var found bool
+		var last string
+		for scanner.Scan() {
+			last = scanner.Text()
+			if !found {
+				if url := t.provider.ParseURL(last); url != "" {
+					found = true
+					urlChan <- url
+				}
+			}
+		}
+		lastLine <- last
+		io.Copy(io.Discard, output)
+	}()
+
+	var url string
+	select {
+	case url = <-urlChan:
+	case <-exited:
+		cancel()
+		if line := <-lastLine; line != "" {
+			return fmt.Errorf("%s exited before reporting a URL: %s", name, line)
+		}
+		return fmt.Errorf("%s exited before reporting a URL", name)
+	case <-time.After(urlTimeout):
+		cancel()
+		<-exited
+		return fmt.Errorf("timed out waiting for the %s URL", name)
 	}
 
-	tunnel := &Tunnel{
-		ID:        id,
-		TaskID:    taskID,
-		ProjectID: projID,
-		Port:      port,
-		Cmd:       cmd,
-		Cancel:    cancel,
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		cancel()
+		return errors.New("tunnel stopped")
 	}
+	t.Cmd, t.Cancel, t.URL, t.exited = cmd, cancel, url, exited
+	t.failures = 0
+	return nil
+}
 
-	// Parse URL from cloudflared output
-	// URL appears in format: "INF | https://something.trycloudflare.com"
-	urlChan := make(chan string, 1)
-	errChan := make(chan error, 1)
+// monitor re-establishes the tunnel each time its process exits while the
+// tunnel is still open, and removes it once that fails maxRelaunches times
+// in a row.
+func (m *Manager) monitor(t *Tunnel) {
+	for {
+		t.mu.Lock()
+		exited := t.exited
+		t.mu.Unlock()
 
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		urlRegex := regexp.MustCompile(`https://[a-zA-Z0-9-]+\.trycloudflare\.com`)
+		select {
+		case <-t.done:
+			return
+		case <-exited:
+		}
+		if !m.isOpen(t) {
+			t.shutdown()
+			return
+		}
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			if match := urlRegex.FindString(line); match != "" {
-				urlChan <- match
+		var err error
+		for attempt := 1; attempt <= maxRelaunches; attempt++ {
+			select {
+			case <-t.done:
 				return
+			case <-time.After(m.backoff(attempt)):
+			}
+			if err = t.launch(); err == nil {
+				break
 			}
+			slog.Warn("failed to re-establish tunnel", "tunnel_id", t.ID, "provider", t.Provider, "attempt", attempt, "error", err)
 		}
-		if err := scanner.Err(); err != nil {
-			errChan <- err
+		if err != nil {
+			if m.remove(t) {
+				t.shutdown()
+				m.mu.RLock()
+				onFail := m.onFail
+				m.mu.RUnlock()
+				if onFail != nil {
+					onFail(t.Info(), err)
+				}
+			}
+			return
 		}
-	}()
 
-	// Wait for URL with timeout
-	select {
-	case url := <-urlChan:
-		tunnel.URL = url
-	case err := <-errChan:
-		cmd.Process.Kill()
-		cancel()
-		return nil, fmt.Errorf("read cloudflared output: %w", err)
-	case <-ctx.Done():
-		cmd.Process.Kill()
-		cancel()
-		return nil, fmt.Errorf("context cancelled")
+		t.mu.Lock()
+		t.Restarts++
+		t.mu.Unlock()
+		info := t.Info()
+		slog.Info("tunnel re-established", "tunnel_id", t.ID, "provider", t.Provider, "url", info.URL)
+		m.mu.RLock()
+		onReconnect := m.onReconnect
+		m.mu.RUnlock()
+		if onReconnect != nil {
+			onReconnect(info)
+		}
 	}
+}
 
-	m.tunnels[id] = tunnel
-	m.ports[port] = id
+// isOpen reports whether t is still registered.
+func (m *Manager) isOpen(t *Tunnel) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tunnels[t.ID] == t
+}
 
-	// Monitor tunnel and clean up on exit
-	go func() {
-		cmd.Wait()
-		m.mu.Lock()
-		delete(m.ports, port)
-		delete(m.tunnels, id)
-		m.mu.Unlock()
-	}()
+// remove unregisters t, reporting whether it was registered.
+func (m *Manager) remove(t *Tunnel) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tunnels[t.ID] != t {
+		return false
+	}
+	delete(m.ports, t.Port)
+	delete(m.tunnels, t.ID)
+	return true
+}
 
-	return tunnel, nil
+// CheckHealth requests each tunnel's public URL. A tunnel whose URL can't be
+// reached, or whose provider says it is gone, maxHealthFailures times in a
+// row has its process restarted, which re-establishes it.
+func (m *Manager) CheckHealth(ctx context.Context) {
+	for _, t := range m.List() {
+		t.mu.Lock()
+		url, cmd := t.URL, t.Cmd
+		t.mu.Unlock()
+		if url == "" {
+			continue
+		}
+
+		healthy := false
+		if req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil); err == nil {
+			if resp, err := m.healthClient.Do(req); err == nil {
+				resp.Body.Close()
+				healthy = !t.provider.Down(resp)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		t.mu.Lock()
+		if healthy {
+			t.failures = 0
+		} else {
+			t.failures++
+		}
+		restart := t.failures >= maxHealthFailures && cmd != nil && cmd.Process != nil
+		if restart {
+			t.failures = 0
+		}
+		t.mu.Unlock()
+		if restart {
+			slog.Warn("tunnel failed health checks, restarting", "tunnel_id", t.ID, "provider", t.Provider, "url", url)
+			cmd.Process.Kill()
+		}
+	}
 }
 
 // Get returns a tunnel by ID
@@ -198,20 +486,30 @@ func (m *Manager) Stop(id string) error {
 	delete(m.tunnels, id)
 	m.mu.Unlock()
 
-	tunnel.mu.Lock()
-	defer tunnel.mu.Unlock()
-
-	if tunnel.stopped {
-		return nil
-	}
-	tunnel.stopped = true
+	tunnel.shutdown()
+	return nil
+}
 
-	tunnel.Cancel()
-	if tunnel.Cmd.Process != nil {
-		tunnel.Cmd.Process.Kill()
-	}
+// scheduleExpiry stops the tunnel after ttl and reports it to the expire
+// handler. Callers hold m.mu.
+func (m *Manager) scheduleExpiry(tunnel *Tunnel, ttl time.Duration) {
+	tunnel.ExpiresAt = time.Now().Add(ttl)
+	tunnel.expiry = time.AfterFunc(ttl, func() {
+		m.mu.Lock()
+		if m.tunnels[tunnel.ID] != tunnel {
+			m.mu.Unlock()
+			return
+		}
+		delete(m.ports, tunnel.Port)
+		delete(m.tunnels, tunnel.ID)
+		onExpire := m.onExpire
+		m.mu.Unlock()
 
-	return nil
+		tunnel.shutdown()
+		if onExpire != nil {
+			onExpire(tunnel.Info())
+		}
+	})
 }
 
 // StopAll stops all tunnels
@@ -226,15 +524,7 @@ func (m *Manager) StopAll() {
 	m.mu.Unlock()
 
 	for _, t := range tunnels {
-		t.mu.Lock()
-		if !t.stopped {
-			t.stopped = true
-			t.Cancel()
-			if t.Cmd.Process != nil {
-				t.Cmd.Process.Kill()
-			}
-		}
-		t.mu.Unlock()
+		t.shutdown()
 	}
 }
 
@@ -271,20 +561,97 @@ func (m *Manager) ListForProject(projectID string) []*Tunnel {
 
 // TunnelInfo is a serializable representation of a tunnel
 type TunnelInfo struct {
-	ID        string `json:"id"`
-	TaskID    string `json:"taskId,omitempty"`
-	ProjectID string `json:"projectId,omitempty"`
-	Port      int    `json:"port"`
-	URL       string `json:"url"`
+	ID        string     `json:"id"`
+	TaskID    string     `json:"taskId,omitempty"`
+	ProjectID string     `json:"projectId,omitempty"`
+	Port      int        `json:"port"`
+	URL       string     `json:"url"`
+	Provider  string     `json:"provider"`
+	Auth      string     `json:"auth,omitempty"`     // "basic" or "token" when protected
+	ShareURL  string     `json:"shareUrl,omitempty"` // URL with the access token, for token auth
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // nil when the tunnel has no TTL
+	Restarts  int        `json:"restarts,omitempty"`  // times the tunnel was re-established
+	LongOpen  bool       `json:"longOpen,omitempty"`  // open long enough to be worth a warning
 }
 
 // Info returns the serializable info for a tunnel
 func (t *Tunnel) Info() TunnelInfo {
-	return TunnelInfo{
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info := TunnelInfo{
 		ID:        t.ID,
 		TaskID:    t.TaskID,
 		ProjectID: t.ProjectID,
 		Port:      t.Port,
 		URL:       t.URL,
+		Provider:  t.Provider,
+		Auth:      string(t.Auth),
+		ShareURL:  t.ShareURL(),
+		CreatedAt: t.CreatedAt,
+		Restarts:  t.Restarts,
+		LongOpen:  t.Warned,
+	}
+	if !t.ExpiresAt.IsZero() {
+		expiresAt := t.ExpiresAt
+		info.ExpiresAt = &expiresAt
+	}
+	return info
+}
+
+// WarnIfOpenSince flags the tunnel as open for a long time when it was
+// created before cutoff, and reports whether it was flagged just now.
+func (t *Tunnel) WarnIfOpenSince(cutoff time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Warned || t.stopped || !t.CreatedAt.Before(cutoff) {
+		return false
+	}
+	t.Warned = true
+	return true
+}
+
+// ShareURL returns the tunnel URL carrying its access token, or "" when the
+// tunnel does not use token auth.
+func (t *Tunnel) ShareURL() string {
+	if t.Auth != AuthToken || t.URL == "" {
+		return ""
+	}
+	return t.URL + "/?" + TokenParam + "=" + t.token
+}
+
+// shutdown kills the provider's process and releases the tunnel's
+// resources once.
+func (t *Tunnel) shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	if t.done != nil {
+		close(t.done)
+	}
+
+	t.stopExpiry()
+	if t.Cancel != nil {
+		t.Cancel()
+	}
+	if t.Cmd != nil && t.Cmd.Process != nil {
+		t.Cmd.Process.Kill()
+	}
+	t.closeProxy()
+}
+
+func (t *Tunnel) stopExpiry() {
+	if t.expiry != nil {
+		t.expiry.Stop()
+	}
+}
+
+func (t *Tunnel) closeProxy() {
+	if t.proxy != nil {
+		t.proxy.Close()
 	}
 }
@@ -2,8 +2,11 @@ package tunnel
 
 import (
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"testing"
+	"time"
 )
 
 // The regex used by the tunnel manager to extract cloudflared URLs
@@ -139,3 +142,152 @@ func TestCreate_PortConflict(t *testing.T) {
 		t.Fatalf("expected existing tunnel id, got %q", conflict.Existing.ID)
 	}
 }
+
+func TestAuthHandler_Basic(t *testing.T) {
+	var forwardedAuth string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedAuth = r.Header.Get("Authorization")
+	})
+	handler := authHandler(Auth{Mode: AuthBasic, Username: "demo", Password: "s3cret"}, "", next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("expected basic auth challenge, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("demo", "wrong")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for wrong password, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("demo", "s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || forwardedAuth == "" {
+		t.Fatalf("expected request to be forwarded, got %d", rec.Code)
+	}
+}
+
+func TestAuthHandler_Token(t *testing.T) {
+	mgr := NewManager()
+	token := mgr.tokenFor("t-1")
+	if token == mgr.tokenFor("t-2") {
+		t.Fatal("expected tokens to differ per tunnel")
+	}
+
+	var forwardedQuery string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedQuery = r.URL.RawQuery
+	})
+	handler := authHandler(Auth{Mode: AuthToken}, token, next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?"+TokenParam+"=bogus", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for bad token, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?page=2&"+TokenParam+"="+token, nil))
+	if rec.Code != http.StatusOK || forwardedQuery != "page=2" {
+		t.Fatalf("expected forwarded request without token, got %d query %q", rec.Code, forwardedQuery)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != token {
+		t.Fatalf("expected access cookie, got %+v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected cookie to authorize follow-up requests, got %d", rec.Code)
+	}
+}
+
+func TestTunnelInfo_ShareURL(t *testing.T) {
+	tunnel := &Tunnel{ID: "t-1", URL: "https://a.trycloudflare.com", Auth: AuthToken, token: "abc"}
+	info := tunnel.Info()
+	if info.Auth != "token" || info.ShareURL != "https://a.trycloudflare.com/?codeburg_token=abc" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+}
+
+func TestScheduleExpiry(t *testing.T) {
+	mgr := NewManager()
+	expired := make(chan TunnelInfo, 1)
+	mgr.SetExpireHandler(func(info TunnelInfo) { expired <- info })
+
+	tunnel := &Tunnel{ID: "t-1", TaskID: "task-1", Port: 3000, URL: "https://a.trycloudflare.com"}
+	mgr.mu.Lock()
+	mgr.tunnels["t-1"] = tunnel
+	mgr.ports[3000] = "t-1"
+	mgr.scheduleExpiry(tunnel, 10*time.Millisecond)
+	mgr.mu.Unlock()
+
+	if info := tunnel.Info(); info.ExpiresAt == nil {
+		t.Fatal("expected expiresAt on a tunnel with a TTL")
+	}
+
+	select {
+	case info := <-expired:
+		if info.ID != "t-1" {
+			t.Errorf("unexpected expired tunnel: %+v", info)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected tunnel to expire")
+	}
+	if mgr.Get("t-1") != nil || mgr.FindByPort(3000) != nil {
+		t.Error("expected expired tunnel to be removed")
+	}
+}
+
+func TestStop_CancelsExpiry(t *testing.T) {
+	mgr := NewManager()
+	expired := make(chan TunnelInfo, 1)
+	mgr.SetExpireHandler(func(info TunnelInfo) { expired <- info })
+
+	tunnel := &Tunnel{ID: "t-1", Port: 3000}
+	mgr.mu.Lock()
+	mgr.tunnels["t-1"] = tunnel
+	mgr.ports[3000] = "t-1"
+	mgr.scheduleExpiry(tunnel, 20*time.Millisecond)
+	mgr.mu.Unlock()
+
+	mgr.Stop("t-1")
+	select {
+	case <-expired:
+		t.Error("expected no expiry callback for a stopped tunnel")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWarnIfOpenSince(t *testing.T) {
+	created := time.Now().Add(-3 * time.Hour)
+	tunnel := &Tunnel{ID: "t-1", Port: 3000, CreatedAt: created}
+
+	if tunnel.WarnIfOpenSince(created.Add(-time.Minute)) {
+		t.Fatal("expected no warning for a tunnel opened after the cutoff")
+	}
+	if !tunnel.WarnIfOpenSince(created.Add(time.Minute)) {
+		t.Fatal("expected a warning for a tunnel opened before the cutoff")
+	}
+	if !tunnel.Info().LongOpen {
+		t.Error("expected the info to report the tunnel as long open")
+	}
+	if tunnel.WarnIfOpenSince(time.Now()) {
+		t.Error("expected the warning to be given once")
+	}
+}
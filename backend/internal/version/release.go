@@ -0,0 +1,175 @@
+package version
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Repository is the GitHub repository releases are published to. Each
+// release carries a binary per platform, named as AssetName returns, and
+// checksums.txt with their SHA-256 sums in sha256sum format.
+const Repository = "miguel-bm/codeburg"
+
+const checksumsAsset = "checksums.txt"
+
+// Release is a published release.
+type Release struct {
+	Version     string    `json:"version"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"publishedAt"`
+	assets      map[string]string
+}
+
+// Releases finds releases through the GitHub API.
+type Releases struct {
+	// BaseURL is the GitHub API; https://api.github.com when empty.
+	BaseURL string
+	Client  *http.Client
+}
+
+func (r *Releases) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return &http.Client{Timeout: 5 * time.Minute}
+}
+
+// Latest returns the latest release.
+func (r *Releases) Latest(ctx context.Context) (*Release, error) {
+	base := r.BaseURL
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+"/repos/"+Repository+"/releases/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get latest release: %s", resp.Status)
+	}
+
+	var body struct {
+		TagName     string    `json:"tag_name"`
+		HTMLURL     string    `json:"html_url"`
+		PublishedAt time.Time `json:"published_at"`
+		Assets      []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode latest release: %w", err)
+	}
+	release := &Release{Version: body.TagName, URL: body.HTMLURL, PublishedAt: body.PublishedAt, assets: map[string]string{}}
+	for _, asset := range body.Assets {
+		release.assets[asset.Name] = asset.URL
+	}
+	return release, nil
+}
+
+// AssetName returns the name of the release binary for this platform, e.g.
+// codeburg_linux_amd64.
+func AssetName() string {
+	return "codeburg_" + runtime.GOOS + "_" + runtime.GOARCH
+}
+
+// Install downloads release's binary for this platform, checks it against
+// the release's checksums and replaces the executable at path with it.
+func (r *Releases) Install(ctx context.Context, release *Release, path string) error {
+	name := AssetName()
+	binaryURL, ok := release.assets[name]
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	checksumsURL, ok := release.assets[checksumsAsset]
+	if !ok {
+		return fmt.Errorf("release %s has no %s", release.Version, checksumsAsset)
+	}
+	want, err := r.checksum(ctx, checksumsURL, name)
+	if err != nil {
+		return err
+	}
+
+	// Write next to the executable, so the rename replacing it is atomic.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".codeburg-update-")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	if err := r.download(ctx, binaryURL, io.MultiWriter(tmp, hash)); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o755)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// checksum returns the SHA-256 of name listed in the checksums file.
+func (r *Releases) checksum(ctx context.Context, url, name string) (string, error) {
+	var buf strings.Builder
+	if err := r.download(ctx, url, &buf); err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", errors.New(checksumsAsset + " lists no checksum for " + name)
+}
+
+func (r *Releases) download(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("download %s: %w", url, err)
+	}
+	return nil
+}
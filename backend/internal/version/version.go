@@ -0,0 +1,93 @@
+// Package version describes the running Codeburg build, finds newer
+// releases on GitHub and installs them over a release binary.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the release the binary was built as, set when building a
+// release:
+//
+//	go build -ldflags "-X github.com/miguel-bm/codeburg/internal/version.Version=v0.4.0"
+//
+// Builds from source are "dev".
+var Version = "dev"
+
+// Info describes the running build.
+type Info struct {
+	Version    string     `json:"version"`
+	Commit     string     `json:"commit,omitempty"`
+	CommitTime *time.Time `json:"commitTime,omitempty"`
+	Modified   bool       `json:"modified,omitempty"` // built with uncommitted changes
+	GoVersion  string     `json:"goVersion"`
+	OS         string     `json:"os"`
+	Arch       string     `json:"arch"`
+}
+
+// Get returns the running build's version and the VCS details Go recorded
+// in it.
+func Get() Info {
+	info := Info{Version: Version, GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			if t, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+				info.CommitTime = &t
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// IsRelease reports whether the running binary is a release build.
+func IsRelease() bool {
+	_, ok := parse(Version)
+	return ok
+}
+
+// Newer reports whether version a is a later release than b. Versions that
+// aren't vMAJOR.MINOR.PATCH, such as "dev", are never newer nor older.
+func Newer(a, b string) bool {
+	va, okA := parse(a)
+	vb, okB := parse(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// parse reads "v1.2.3" or "1.2.3". Pre-release suffixes ("-rc.1") are not
+// releases.
+func parse(v string) ([3]int, bool) {
+	var out [3]int
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
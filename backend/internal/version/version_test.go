@@ -0,0 +1,93 @@
+package version
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"1.2.3", "v1.2.3", false},
+		{"v1.1.9", "v1.2.0", false},
+		{"v1.0.0", "dev", false},
+		{"v1.1.0-rc.1", "v1.0.0", false},
+	} {
+		if got := Newer(tc.a, tc.b); got != tc.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestReleasesInstall(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho v1.3.0\n")
+	sum := sha256.Sum256(binary)
+	checksums := hex.EncodeToString(sum[:]) + "  " + AssetName() + "\n"
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + Repository + "/releases/latest":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v1.3.0",
+				"html_url": "https://github.com/" + Repository + "/releases/tag/v1.3.0",
+				"assets": []map[string]string{
+					{"name": AssetName(), "browser_download_url": srv.URL + "/download/binary"},
+					{"name": checksumsAsset, "browser_download_url": srv.URL + "/download/checksums"},
+				},
+			})
+		case "/download/binary":
+			w.Write(binary)
+		case "/download/checksums":
+			w.Write([]byte(checksums))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	releases := &Releases{BaseURL: srv.URL}
+	release, err := releases.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if release.Version != "v1.3.0" || !strings.HasSuffix(release.URL, "/tag/v1.3.0") {
+		t.Errorf("unexpected release %+v", release)
+	}
+
+	path := filepath.Join(t.TempDir(), "codeburg")
+	os.WriteFile(path, []byte("old"), 0o700)
+	if err := releases.Install(context.Background(), release, path); err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	stat, _ := os.Stat(path)
+	if string(data) != string(binary) || stat.Mode().Perm() != 0o700 {
+		t.Errorf("expected the binary installed with the previous mode, got %q (%v)", data, stat.Mode())
+	}
+
+	// A binary that doesn't match its checksum is not installed.
+	checksums = strings.Repeat("0", 64) + "  " + AssetName() + "\n"
+	os.WriteFile(path, []byte("old"), 0o700)
+	if err := releases.Install(context.Background(), release, path); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("expected the executable untouched, got %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected the temporary file removed, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,72 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrCloneUnsupported is returned when the filesystem (or platform) cannot
+// make copy-on-write clones. Heavy directories are never fully copied
+// instead, since that is usually slower than reinstalling them.
+var ErrCloneUnsupported = errors.New("filesystem does not support copy-on-write clones")
+
+// cloneCommand returns the cp invocation that clones src to dst without
+// copying data: reflinks on Linux (btrfs, XFS, bcachefs) and clonefile on
+// macOS (APFS). Replaced in tests.
+var cloneCommand = func(src, dst string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return exec.Command("cp", "-a", "--reflink=always", src, dst), nil
+	case "darwin":
+		return exec.Command("cp", "-c", "-R", "-p", src, dst), nil
+	default:
+		return nil, ErrCloneUnsupported
+	}
+}
+
+// cloneIntoWorktree clones a file or directory from the main checkout into
+// the worktree. It leaves existing destinations alone and removes partial
+// copies when the filesystem refuses to clone.
+func cloneIntoWorktree(projectPath, worktreePath, path string) error {
+	relPath, err := cleanRelativePath(path)
+	if err != nil {
+		return err
+	}
+	src := filepath.Join(projectPath, relPath)
+	dst := filepath.Join(worktreePath, relPath)
+	if _, err := os.Lstat(src); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists in the worktree", relPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	cmd, err := cloneCommand(src, dst)
+	if err != nil {
+		return err
+	}
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	os.RemoveAll(dst)
+
+	msg := strings.TrimSpace(string(output))
+	lower := strings.ToLower(msg)
+	if strings.Contains(lower, "not supported") || strings.Contains(lower, "invalid cross-device") ||
+		strings.Contains(lower, "unrecognized option") || strings.Contains(lower, "illegal option") {
+		return ErrCloneUnsupported
+	}
+	if msg != "" {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return err
+}
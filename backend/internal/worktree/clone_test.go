@@ -0,0 +1,79 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreate_WithClonePaths(t *testing.T) {
+	m := newTestManager(t)
+	repo := createTestGitRepo(t)
+
+	// Plain copies stand in for reflinks, which the test filesystem may lack.
+	orig := cloneCommand
+	cloneCommand = func(src, dst string) (*exec.Cmd, error) { return exec.Command("cp", "-R", src, dst), nil }
+	t.Cleanup(func() { cloneCommand = orig })
+
+	pkg := filepath.Join(repo, "node_modules", "left-pad")
+	if err := os.MkdirAll(pkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkg, "index.js"), []byte("module.exports = 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := m.Create(CreateOptions{
+		ProjectPath: repo,
+		ProjectName: "proj",
+		TaskID:      "TASK020",
+		TaskTitle:   "clone test",
+		BaseBranch:  "main",
+		ClonePaths:  []string{"node_modules", ".venv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected missing paths to be skipped silently, got %v", result.Warnings)
+	}
+
+	cloned := filepath.Join(result.WorktreePath, "node_modules", "left-pad", "index.js")
+	info, err := os.Lstat(cloned)
+	if err != nil {
+		t.Fatalf("expected cloned file: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		t.Error("expected a copy, not a symlink")
+	}
+}
+
+func TestCloneIntoWorktree_Unsupported(t *testing.T) {
+	project := t.TempDir()
+	worktree := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(project, "node_modules", "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := cloneCommand
+	cloneCommand = func(src, dst string) (*exec.Cmd, error) {
+		// Simulate cp creating part of the tree before the filesystem refuses.
+		script := `mkdir -p "$1/a"; echo "cp: failed to clone: Operation not supported" >&2; exit 1`
+		return exec.Command("sh", "-c", script, "sh", dst), nil
+	}
+	t.Cleanup(func() { cloneCommand = orig })
+
+	err := cloneIntoWorktree(project, worktree, "node_modules")
+	if err != ErrCloneUnsupported {
+		t.Fatalf("expected ErrCloneUnsupported, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(worktree, "node_modules")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial copy to be removed, got %v", err)
+	}
+
+	if err := cloneIntoWorktree(project, worktree, "../outside"); err == nil || !strings.Contains(err.Error(), "traversal") {
+		t.Errorf("expected path traversal to be rejected, got %v", err)
+	}
+}
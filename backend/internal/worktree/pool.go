@@ -0,0 +1,129 @@
+package worktree
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// PoolBranchPrefix starts the placeholder branch names of pooled worktrees.
+const PoolBranchPrefix = "codeburg-pool/"
+
+// PoolPath returns where Create puts a pooled worktree with the given ID,
+// i.e. one on the branch PoolBranchPrefix+id.
+func (m *Manager) PoolPath(projectName, id string) string {
+	return filepath.Join(m.config.BaseDir, projectName, worktreeDirName(PoolBranchPrefix+id))
+}
+
+// ClaimOptions holds options for handing a pooled worktree to a task
+type ClaimOptions struct {
+	// ProjectPath is the path to the main git repository
+	ProjectPath string
+	// ProjectName is the name of the project (for organizing worktrees)
+	ProjectName string
+	// TaskID is the task identifier
+	TaskID string
+	// BranchName is an explicit branch name (set by user). If empty, auto-generated from TaskTitle.
+	BranchName string
+	// TaskTitle is used to generate a slugified branch name when BranchName is empty.
+	TaskTitle string
+	// BaseBranch is the branch the pooled worktree was created from
+	BaseBranch string
+	// PoolPath is the path of the pooled worktree
+	PoolPath string
+	// PoolBranch is the placeholder branch checked out in the pooled worktree
+	PoolBranch string
+}
+
+// Claim turns a pooled worktree into a task's worktree: it moves it to the
+// task's path, renames its placeholder branch, and fast-forwards it to the
+// base branch as last fetched. Nothing is fetched, so claiming stays quick.
+func (m *Manager) Claim(opts ClaimOptions) (*CreateResult, error) {
+	if !dirExists(opts.PoolPath) {
+		return nil, fmt.Errorf("pooled worktree %s is missing", opts.PoolPath)
+	}
+	branchName, worktreePath := m.taskTarget(opts.ProjectPath, opts.ProjectName, opts.TaskID, opts.BranchName, opts.TaskTitle, false)
+	if _, err := os.Stat(worktreePath); err == nil {
+		return nil, fmt.Errorf("worktree already exists at %s", worktreePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
+		return nil, fmt.Errorf("create worktree directory: %w", err)
+	}
+
+	if err := runGitIn(opts.ProjectPath, "worktree", "move", opts.PoolPath, worktreePath); err != nil {
+		return nil, fmt.Errorf("move pooled worktree: %w", err)
+	}
+	if err := runGitIn(opts.ProjectPath, "branch", "-m", opts.PoolBranch, branchName); err != nil {
+		// Put the worktree back so the pool entry can still be cleaned up.
+		runGitIn(opts.ProjectPath, "worktree", "move", worktreePath, opts.PoolPath)
+		return nil, fmt.Errorf("rename pooled branch: %w", err)
+	}
+
+	var warnings []string
+	baseRef := opts.BaseBranch
+	if m.branchExists(opts.ProjectPath, "origin/"+opts.BaseBranch) {
+		baseRef = "origin/" + opts.BaseBranch
+	}
+	if err := runGitIn(worktreePath, "merge", "--ff-only", "--quiet", baseRef); err != nil {
+		warnings = append(warnings, fmt.Sprintf("could not update pooled worktree to %s: %v", baseRef, err))
+	}
+
+	return &CreateResult{
+		WorktreePath: worktreePath,
+		BranchName:   branchName,
+		Warnings:     warnings,
+	}, nil
+}
+
+// RemovePooled deletes a pooled worktree and its placeholder branch. It
+// succeeds if either is already gone.
+func (m *Manager) RemovePooled(projectPath, poolPath, poolBranch, teardownScript string) error {
+	if !dirExists(poolPath) {
+		teardownScript = ""
+	}
+	if err := m.Delete(DeleteOptions{
+		ProjectPath:    projectPath,
+		WorktreePath:   poolPath,
+		TeardownScript: teardownScript,
+	}); err != nil {
+		return err
+	}
+	if m.branchExists(projectPath, poolBranch) {
+		return m.deleteBranch(projectPath, poolBranch)
+	}
+	return nil
+}
+
+// DirSize returns the apparent size of the files under path, without
+// following symlinks. Copy-on-write clones count in full, so this
+// overestimates the space they actually use.
+func DirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+func runGitIn(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
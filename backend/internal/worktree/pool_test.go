@@ -0,0 +1,148 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClaim_MovesPooledWorktreeToTask(t *testing.T) {
+	repo := createTestGitRepo(t)
+	m := newTestManager(t)
+
+	pooled, err := m.Create(CreateOptions{
+		ProjectPath: repo,
+		ProjectName: "proj",
+		TaskID:      "pool01",
+		BranchName:  PoolBranchPrefix + "pool01",
+		SetupScript: "mkdir -p node_modules && echo dep > node_modules/dep.js",
+	})
+	if err != nil {
+		t.Fatalf("create pooled worktree: %v", err)
+	}
+	if want := m.PoolPath("proj", "pool01"); pooled.WorktreePath != want {
+		t.Fatalf("pooled worktree at %s, want %s", pooled.WorktreePath, want)
+	}
+
+	// The base branch moves on after the pool entry was created.
+	if err := os.WriteFile(filepath.Join(repo, "NEW.md"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitExec(t, repo, "add", ".")
+	gitExec(t, repo, "commit", "-m", "second")
+
+	result, err := m.Claim(ClaimOptions{
+		ProjectPath: repo,
+		ProjectName: "proj",
+		TaskID:      "task01",
+		TaskTitle:   "Fix login bug",
+		BaseBranch:  "main",
+		PoolPath:    pooled.WorktreePath,
+		PoolBranch:  pooled.BranchName,
+	})
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(result.Warnings) > 0 {
+		t.Fatalf("unexpected warnings: %v", result.Warnings)
+	}
+	if result.BranchName != "fix-login-bug" {
+		t.Errorf("branch = %q, want fix-login-bug", result.BranchName)
+	}
+	if dirExists(pooled.WorktreePath) {
+		t.Error("pooled worktree path should be gone")
+	}
+	if m.branchExists(repo, pooled.BranchName) {
+		t.Error("placeholder branch should be renamed")
+	}
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, "node_modules", "dep.js")); err != nil {
+		t.Errorf("setup output should be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(result.WorktreePath, "NEW.md")); err != nil {
+		t.Errorf("worktree should be fast-forwarded to main: %v", err)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = result.WorktreePath
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "fix-login-bug" {
+		t.Errorf("checked out branch = %q", got)
+	}
+}
+
+func TestClaim_MissingPoolPath(t *testing.T) {
+	repo := createTestGitRepo(t)
+	m := newTestManager(t)
+
+	_, err := m.Claim(ClaimOptions{
+		ProjectPath: repo,
+		ProjectName: "proj",
+		TaskID:      "task01",
+		TaskTitle:   "anything",
+		BaseBranch:  "main",
+		PoolPath:    filepath.Join(t.TempDir(), "gone"),
+		PoolBranch:  PoolBranchPrefix + "gone",
+	})
+	if err == nil {
+		t.Fatal("expected an error for a missing pooled worktree")
+	}
+}
+
+func TestRemovePooled(t *testing.T) {
+	repo := createTestGitRepo(t)
+	m := newTestManager(t)
+
+	pooled, err := m.Create(CreateOptions{
+		ProjectPath: repo,
+		ProjectName: "proj",
+		TaskID:      "pool02",
+		BranchName:  PoolBranchPrefix + "pool02",
+	})
+	if err != nil {
+		t.Fatalf("create pooled worktree: %v", err)
+	}
+
+	if err := m.RemovePooled(repo, pooled.WorktreePath, pooled.BranchName, ""); err != nil {
+		t.Fatalf("RemovePooled: %v", err)
+	}
+	if dirExists(pooled.WorktreePath) {
+		t.Error("pooled worktree should be removed")
+	}
+	if m.branchExists(repo, pooled.BranchName) {
+		t.Error("placeholder branch should be deleted")
+	}
+
+	// Removing again is a no-op.
+	if err := m.RemovePooled(repo, pooled.WorktreePath, pooled.BranchName, ""); err != nil {
+		t.Fatalf("second RemovePooled: %v", err)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b"), make([]byte, 50), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := DirSize(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 150 {
+		t.Errorf("size = %d, want 150", size)
+	}
+}
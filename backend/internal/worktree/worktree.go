@@ -2,6 +2,9 @@ package worktree
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -58,8 +61,14 @@ type CreateOptions struct {
 	// AdoptBranch indicates BranchName refers to a pre-existing branch to adopt
 	// rather than a new branch to create from BaseBranch.
 	AdoptBranch bool
+	// LocalBase creates the branch from BaseBranch as it is locally, without
+	// fetching; BaseBranch may then be any commit-ish.
+	LocalBase bool
 	// SymlinkPaths are files/dirs to symlink from the main repo
 	SymlinkPaths []string
+	// ClonePaths are ignored files/dirs (e.g. node_modules) to clone from the
+	// main repo as copy-on-write copies, where the filesystem supports it
+	ClonePaths []string
 	// SetupScript is a command to run after worktree creation
 	SetupScript string
 	// SecretFiles are secret file mappings to materialize into the worktree
@@ -91,23 +100,7 @@ func (m *Manager) Create(opts CreateOptions) (*CreateResult, error) {
 		opts.BaseBranch = "main"
 	}
 
-	branchName := opts.BranchName
-	if branchName == "" {
-		branchName = Slugify(opts.TaskTitle)
-	}
-
-	// Check for collision — if branch or worktree dir already exists, append short ID.
-	// Skip this when adopting an existing branch (we *want* it to exist).
-	dirName := worktreeDirName(branchName)
-	worktreePath := filepath.Join(m.config.BaseDir, opts.ProjectName, dirName)
-	if !opts.AdoptBranch {
-		if m.branchExists(opts.ProjectPath, branchName) || dirExists(worktreePath) {
-			suffix := shortID(opts.TaskID)
-			branchName = branchName + "-" + suffix
-			dirName = worktreeDirName(branchName)
-			worktreePath = filepath.Join(m.config.BaseDir, opts.ProjectName, dirName)
-		}
-	}
+	branchName, worktreePath := m.taskTarget(opts.ProjectPath, opts.ProjectName, opts.TaskID, opts.BranchName, opts.TaskTitle, opts.AdoptBranch)
 
 	// Ensure worktree base directory exists
 	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
@@ -132,18 +125,20 @@ func (m *Manager) Create(opts CreateOptions) (*CreateResult, error) {
 	var warnings []string
 
 	// Fetch latest from remote to ensure we have up-to-date refs
-	fetchFailed := false
-	if err := m.gitFetch(opts.ProjectPath); err != nil {
-		fetchFailed = true
-		warnings = append(warnings, fmt.Sprintf("could not fetch from remote: %v — worktree may be based on stale %s", err, opts.BaseBranch))
-	}
-
-	// Prefer creating from origin/<base> when available so we don't depend on
-	// mutating local checked-out refs (which can be blocked by git/worktree rules).
 	baseRef := opts.BaseBranch
-	remoteBaseRef := "origin/" + opts.BaseBranch
-	if !fetchFailed && m.branchExists(opts.ProjectPath, remoteBaseRef) {
-		baseRef = remoteBaseRef
+	if !opts.LocalBase {
+		fetchFailed := false
+		if err := m.gitFetch(opts.ProjectPath); err != nil {
+			fetchFailed = true
+			warnings = append(warnings, fmt.Sprintf("could not fetch from remote: %v — worktree may be based on stale %s", err, opts.BaseBranch))
+		}
+
+		// Prefer creating from origin/<base> when available so we don't depend on
+		// mutating local checked-out refs (which can be blocked by git/worktree rules).
+		remoteBaseRef := "origin/" + opts.BaseBranch
+		if !fetchFailed && m.branchExists(opts.ProjectPath, remoteBaseRef) {
+			baseRef = remoteBaseRef
+		}
 	}
 
 	// When adopting a pre-existing branch, ensure it exists locally.
@@ -179,6 +174,14 @@ func (m *Manager) Create(opts CreateOptions) (*CreateResult, error) {
 		}
 	}
 
+	// Clone heavy ignored directories copy-on-write. Paths missing from the
+	// main checkout are skipped silently.
+	for _, path := range opts.ClonePaths {
+		if err := cloneIntoWorktree(opts.ProjectPath, worktreePath, path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			warnings = append(warnings, fmt.Sprintf("could not clone %s: %v", path, err))
+		}
+	}
+
 	// Materialize configured secret files (copy/symlink).
 	for _, sf := range opts.SecretFiles {
 		if !sf.Enabled {
@@ -247,6 +250,27 @@ func (m *Manager) Create(opts CreateOptions) (*CreateResult, error) {
 	}, nil
 }
 
+// taskTarget picks the branch name and worktree path for a task.
+func (m *Manager) taskTarget(projectPath, projectName, taskID, branchName, taskTitle string, adopt bool) (string, string) {
+	if branchName == "" {
+		branchName = Slugify(taskTitle)
+	}
+
+	// Check for collision — if branch or worktree dir already exists, append short ID.
+	// Skip this when adopting an existing branch (we *want* it to exist).
+	dirName := worktreeDirName(branchName)
+	worktreePath := filepath.Join(m.config.BaseDir, projectName, dirName)
+	if !adopt {
+		if m.branchExists(projectPath, branchName) || dirExists(worktreePath) {
+			suffix := shortID(taskID)
+			branchName = branchName + "-" + suffix
+			dirName = worktreeDirName(branchName)
+			worktreePath = filepath.Join(m.config.BaseDir, projectName, dirName)
+		}
+	}
+	return branchName, worktreePath
+}
+
 // Delete removes a worktree and optionally its branch
 type DeleteOptions struct {
 	// ProjectPath is the path to the main git repository
@@ -463,6 +487,52 @@ func (m *Manager) DiffStats(worktreePath, baseBranch string) (additions, deletio
 	return a, d, nil
 }
 
+// DiffStatsKey fingerprints what DiffStats depends on: HEAD, the base
+// branch, the index, and the files git status lists, by size and
+// modification time. It changes with any commit, checkout, staging or edit,
+// whoever made it, and is cheaper to compute than the stats.
+func (m *Manager) DiffStatsKey(worktreePath, baseBranch string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	revCmd := exec.CommandContext(ctx, "git", "rev-parse", "--absolute-git-dir", "HEAD", baseBranch)
+	revCmd.Dir = worktreePath
+	revs, err := revCmd.Output()
+	if err != nil {
+		return "", err
+	}
+	// Without optional locks status doesn't refresh the index, which would
+	// change its modification time.
+	statusCmd := exec.CommandContext(ctx, "git", "--no-optional-locks", "status", "--porcelain=v1", "-z", "--untracked-files=no")
+	statusCmd.Dir = worktreePath
+	status, err := statusCmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(revs)
+	h.Write(status)
+	gitDir, _, _ := strings.Cut(string(revs), "\n")
+	if info, err := os.Stat(filepath.Join(gitDir, "index")); err == nil {
+		fmt.Fprintf(h, "index %d %d\n", info.Size(), info.ModTime().UnixNano())
+	}
+	fields := strings.Split(string(status), "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // the original path follows a rename or copy
+		}
+		if info, err := os.Lstat(filepath.Join(worktreePath, entry[3:])); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", entry[3:], info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // parseShortStat parses git diff --shortstat output like:
 // " 3 files changed, 42 insertions(+), 15 deletions(-)"
 func parseShortStat(s string) (additions, deletions int) {
@@ -581,3 +581,52 @@ func TestDiffStats_WithChanges(t *testing.T) {
 		t.Error("expected non-zero additions or deletions after modifying a file")
 	}
 }
+
+func TestDiffStatsKey(t *testing.T) {
+	m := newTestManager(t)
+	repo := createTestGitRepo(t)
+
+	result, err := m.Create(CreateOptions{
+		ProjectPath: repo,
+		ProjectName: "proj",
+		TaskID:      "TASK022",
+		TaskTitle:   "fingerprint",
+		BaseBranch:  "main",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func() string {
+		t.Helper()
+		k, err := m.DiffStatsKey(result.WorktreePath, "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	clean := key()
+	if key() != clean {
+		t.Fatal("expected the same key for an unchanged worktree")
+	}
+
+	readme := filepath.Join(result.WorktreePath, "README.md")
+	os.WriteFile(readme, []byte("# Modified\n"), 0644)
+	edited := key()
+	if edited == clean {
+		t.Fatal("expected an edit to change the key")
+	}
+
+	// A second edit to an already modified file changes it too.
+	os.WriteFile(readme, []byte("# Modified again, longer\n"), 0644)
+	if key() == edited {
+		t.Fatal("expected another edit to change the key")
+	}
+
+	gitExec(t, result.WorktreePath, "commit", "-am", "edit")
+	committed := key()
+	gitExec(t, result.WorktreePath, "commit", "--amend", "-m", "reworded")
+	if key() == committed {
+		t.Error("expected a new commit to change the key")
+	}
+}
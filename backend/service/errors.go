@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// Kinds of errors services return, for errors.Is. Other errors are
+// unexpected failures.
+var (
+	ErrNotFound  = db.ErrNotFound
+	ErrInvalid   = errors.New("invalid argument")
+	ErrConflict  = errors.New("conflict")
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrStale is the conflict of a write that expected a version of a
+	// record that has changed since.
+	ErrStale = fmt.Errorf("%w: changed since read", ErrConflict)
+
+	// ErrInvalidCursor is returned for a page cursor that isn't one.
+	ErrInvalidCursor = db.ErrInvalidCursor
+)
+
+// Error is an error of a known kind with a message fit to show the user.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// NotFound reports that what doesn't exist, e.g. NotFound("task").
+func NotFound(what string) error {
+	return &Error{Kind: ErrNotFound, Message: what + " not found"}
+}
+
+// Invalid reports an invalid argument.
+func Invalid(format string, args ...any) error {
+	return &Error{Kind: ErrInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict reports a request that clashes with the current state, such as a
+// full board column.
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Stale reports a write expecting a version of a record that has changed.
+func Stale(format string, args ...any) error {
+	return &Error{Kind: ErrStale, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden reports a request a policy refuses, such as writing a protected
+// path.
+func Forbidden(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,163 @@
+// Package service is Codeburg's business logic for tasks, their git
+// worktrees and agent sessions, without HTTP. The API handlers are thin
+// wrappers around these services, and other front ends (a Slack bot, an MCP
+// server, a CLI) can call them the same way. Get them from a running server
+// with embed.Open.
+package service
+
+import (
+	"context"
+
+	"github.com/miguel-bm/codeburg/internal/db"
+)
+
+// The records services take and return, named here so code outside this
+// module can use them.
+type (
+	Task            = db.Task
+	TaskStatus      = db.TaskStatus
+	TaskFilter      = db.TaskFilter
+	CreateTaskInput = db.CreateTaskInput
+	UpdateTaskInput = db.UpdateTaskInput
+	Session         = db.AgentSession
+	SessionStatus   = db.SessionStatus
+	SessionFilter   = db.SessionFilter
+	Page            = db.Page
+)
+
+// Services bundles the services of one server.
+type Services struct {
+	Tasks    TaskService
+	Git      GitService
+	Sessions SessionService
+}
+
+// TaskService manages tasks on project boards.
+type TaskService interface {
+	// List returns a page of tasks matching filter, with their labels, and
+	// the cursor of the next page ("" on the last one).
+	List(ctx context.Context, filter TaskFilter, page Page) ([]*Task, string, error)
+	// Get returns a task with its labels.
+	Get(ctx context.Context, id string) (*Task, error)
+	Create(ctx context.Context, input CreateTaskInput) (*Task, error)
+	// Update changes a task. Moving it to another column runs the project's
+	// workflow, which may create a worktree, start a session or open a pull
+	// request; TaskUpdate says what it did.
+	Update(ctx context.Context, id string, input UpdateTaskInput) (*TaskUpdate, error)
+	// Reorder moves a task to an index in a column, renumbering the
+	// column, and runs the workflow like Update when the column changes. A
+	// task no longer at input.Version is a conflict.
+	Reorder(ctx context.Context, id string, input ReorderTaskInput) (*TaskUpdate, error)
+	// Delete stops the task's sessions and tunnels, removes its worktree and
+	// deletes it.
+	Delete(ctx context.Context, id string) error
+}
+
+// TaskUpdate is a task after an update, with what its workflow did.
+type TaskUpdate struct {
+	*Task
+	WorkflowAction  *string  `json:"workflowAction,omitempty"`  // "ask" when user should pick provider
+	SessionStarted  *string  `json:"sessionStarted,omitempty"`  // session ID if auto-started
+	PRCreated       *string  `json:"prCreated,omitempty"`       // PR URL if auto-created
+	WorkflowError   *string  `json:"workflowError,omitempty"`   // non-fatal workflow error message
+	WorktreeWarning []string `json:"worktreeWarning,omitempty"` // non-fatal worktree creation warnings
+}
+
+// ReorderTaskInput places a task on its board.
+type ReorderTaskInput struct {
+	Status      TaskStatus `json:"status,omitempty"`      // the column; the task's own if empty
+	Index       int        `json:"index"`                 // among the column's unarchived tasks of the task's project
+	AllProjects bool       `json:"allProjects,omitempty"` // count the column's tasks of every project instead
+	Version     *int       `json:"version,omitempty"`     // the task's version last read
+}
+
+// GitService runs git in a task's worktree. Tasks without a worktree are
+// invalid arguments.
+type GitService interface {
+	Status(ctx context.Context, taskID string) (*GitStatus, error)
+	Diff(ctx context.Context, taskID string, opts DiffOptions) (string, error)
+	Commit(ctx context.Context, taskID string, opts CommitOptions) (*GitCommit, error)
+	// Pull fast-forwards the task's branch.
+	Pull(ctx context.Context, taskID string) error
+	// Push pushes the task's branch and sets its upstream.
+	Push(ctx context.Context, taskID string, force bool) error
+}
+
+// GitFileEntry is a changed file.
+type GitFileEntry struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"` // M, A, D, R, C, etc.
+	Additions int    `json:"additions,omitempty"`
+	Deletions int    `json:"deletions,omitempty"`
+}
+
+// GitStatus is the state of a worktree.
+type GitStatus struct {
+	Branch      string         `json:"branch"`
+	Upstream    string         `json:"upstream,omitempty"`
+	HasUpstream bool           `json:"hasUpstream"`
+	Ahead       int            `json:"ahead"`
+	Behind      int            `json:"behind"`
+	Staged      []GitFileEntry `json:"staged"`
+	Unstaged    []GitFileEntry `json:"unstaged"`
+	Untracked   []string       `json:"untracked"`
+}
+
+// DiffOptions picks a diff: of one commit, of the branch against the
+// project's default branch, of the index or of the worktree, in that order
+// of precedence.
+type DiffOptions struct {
+	Commit string
+	Base   bool
+	Staged bool
+	// File limits the diff to one path (optional).
+	File string
+}
+
+// CommitOptions describes a commit.
+type CommitOptions struct {
+	Message string `json:"message"`
+	Amend   bool   `json:"amend,omitempty"`
+	// SessionID attributes the commit to that agent session when its project
+	// has an agent identity enabled.
+	SessionID string `json:"sessionId,omitempty"`
+}
+
+// GitCommit is a commit that was made.
+type GitCommit struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+// SessionService starts and drives agent sessions.
+type SessionService interface {
+	// List returns a page of sessions matching filter and the cursor of
+	// the next page.
+	List(ctx context.Context, filter SessionFilter, page Page) ([]*Session, string, error)
+	Get(ctx context.Context, id string) (*Session, error)
+	// StartInTask starts a session in a task's worktree, or in its
+	// project's directory when it has none.
+	StartInTask(ctx context.Context, taskID string, opts StartSessionOptions) (*Session, error)
+	// StartInProject starts a session in a project's directory, outside
+	// any task.
+	StartInProject(ctx context.Context, projectID string, opts StartSessionOptions) (*Session, error)
+	// Send delivers user input to an active session.
+	Send(ctx context.Context, id, content string) error
+	// Stop stops a session and marks it completed.
+	Stop(ctx context.Context, id string) error
+	// Resize sets a running terminal session's PTY size.
+	Resize(ctx context.Context, id string, cols, rows uint16) error
+}
+
+// StartSessionOptions describes a session to start.
+type StartSessionOptions struct {
+	Provider        string `json:"provider"`        // "claude", "codex", "terminal" (default: "claude")
+	SessionType     string `json:"sessionType"`     // "chat" or "terminal" (default: chat for claude/codex, terminal for terminal provider)
+	Prompt          string `json:"prompt"`          // Initial prompt (claude/codex sessions)
+	Model           string `json:"model"`           // Optional model override
+	ResumeSessionID string `json:"resumeSessionId"` // Codeburg session ID to resume
+	AutoApprove     *bool  `json:"autoApprove"`     // Skip permission prompts (nil = true)
+	Mode            string `json:"mode"`            // "ask" for a read-only Q&A chat session
+	Cols            uint16 `json:"cols"`            // Initial terminal size (terminal sessions; default 120x40)
+	Rows            uint16 `json:"rows"`
+}
@@ -0,0 +1,47 @@
+import { api } from './client';
+
+export interface ActivityCounts {
+  messages: number;
+  toolCalls: number;
+  commits: number;
+}
+
+export interface ActivityDay extends ActivityCounts {
+  date: string;
+  total: number;
+}
+
+export interface ActivityHour extends ActivityCounts {
+  hour: string;
+  total: number;
+}
+
+export interface Activity {
+  from: string;
+  to: string;
+  timezone: string;
+  totals: ActivityCounts;
+  days: ActivityDay[];
+  hours: ActivityHour[];
+  providers: Record<string, ActivityCounts>;
+  projects: Record<string, ActivityCounts>;
+}
+
+export interface ActivityQuery {
+  projectId?: string;
+  provider?: string;
+  days?: number;
+  tz?: string;
+}
+
+export const activityApi = {
+  // Agent activity per day and hour, for a heatmap
+  get: ({ projectId, provider, days, tz }: ActivityQuery = {}) => {
+    const params = new URLSearchParams();
+    if (provider) params.set('provider', provider);
+    if (days) params.set('days', String(days));
+    params.set('tz', tz ?? Intl.DateTimeFormat().resolvedOptions().timeZone);
+    const base = projectId ? `/projects/${projectId}/activity` : '/activity';
+    return api.get<Activity>(`${base}?${params}`);
+  },
+};
@@ -1,5 +1,5 @@
 import { api } from './client';
-import type { AuthStatus, AuthToken, PasskeyInfo } from './types';
+import type { AuthStatus, AuthToken, CreateDeepLinkInput, DeepLink, LoginLinkToken, PasskeyInfo } from './types';
 
 export const authApi = {
   getStatus: () => api.get<AuthStatus>('/auth/status'),
@@ -12,6 +12,9 @@ export const authApi = {
 
   me: () => api.get<{ user: string }>('/auth/me'),
 
+  // Clears the session cookie, when the server sets one
+  logout: () => api.post<void>('/auth/logout'),
+
   changePassword: (currentPassword: string, newPassword: string) =>
     api.post<{ status: string }>('/auth/password', { currentPassword, newPassword }),
 
@@ -43,9 +46,21 @@ export const authApi = {
   telegramAuth: (initData: string) =>
     api.post<AuthToken>('/auth/telegram', { initData }),
 
+  // One-time login links (public)
+  loginLink: (token: string) =>
+    api.post<LoginLinkToken>('/auth/login-link', { token }),
+
+  // Deep links to continue on another device (protected)
+  createDeepLink: (input: CreateDeepLinkInput) =>
+    api.post<DeepLink>('/deep-links', input),
+
   // Telegram bot management (protected)
   restartTelegramBot: () =>
     api.post<{ status: string }>('/telegram/bot/restart'),
+
+  // Re-read the server configuration file (protected); warnings name settings that need a restart
+  reloadConfig: () =>
+    api.post<{ status: string; warnings: string[] }>('/config/reload'),
 };
 
 // WebAuthn JSON types (from W3C spec)
@@ -0,0 +1,27 @@
+import { api } from './client';
+
+export interface Bookmark {
+  id: string;
+  sessionId: string;
+  label: string;
+  // Matches ChatMessage.seq, so the UI can scroll to the marked message
+  seq: number;
+  gitHead?: string;
+  createdAt: string;
+}
+
+export const bookmarksApi = {
+  // List a session's bookmarks in message order
+  list: (sessionId: string) =>
+    api.get<Bookmark[]>(`/sessions/${sessionId}/bookmarks`),
+
+  // Mark the latest message, or the one at seq
+  create: (sessionId: string, label: string, seq?: number) =>
+    api.post<Bookmark>(`/sessions/${sessionId}/bookmarks`, seq === undefined ? { label } : { label, seq }),
+
+  rename: (id: string, label: string) =>
+    api.patch<Bookmark>(`/bookmarks/${id}`, { label }),
+
+  delete: (id: string) =>
+    api.delete(`/bookmarks/${id}`),
+};
@@ -0,0 +1,49 @@
+import { api } from './client';
+
+export interface UsageTotals {
+  inputTokens: number;
+  outputTokens: number;
+  costUsd: number;
+}
+
+export interface BudgetStatus {
+  key: string;
+  provider?: string;
+  projectId?: string;
+  monthlyTokens?: number;
+  monthlyUsd?: number;
+  month: string;
+  used: UsageTotals;
+  percent: number;
+  exceeded: boolean;
+  overrideUntil?: string;
+  blocking: boolean;
+}
+
+export interface Budgets {
+  enforce: boolean;
+  month: string;
+  budgets: BudgetStatus[];
+}
+
+export interface BudgetOverrideInput {
+  provider?: string;
+  projectId?: string;
+  hours?: number;
+}
+
+export const budgetsApi = {
+  // This month's usage against each limit of the budgets preference
+  get: () => api.get<Budgets>('/budgets'),
+
+  // Let sessions start despite an exceeded limit, for hours or the rest of the month
+  override: (input: BudgetOverrideInput) =>
+    api.post<{ key: string; month: string; overrideUntil: string }>('/budgets/override', input),
+
+  clearOverride: ({ provider, projectId }: BudgetOverrideInput) => {
+    const params = new URLSearchParams();
+    if (provider) params.set('provider', provider);
+    if (projectId) params.set('projectId', projectId);
+    return api.delete(`/budgets/override?${params}`);
+  },
+};
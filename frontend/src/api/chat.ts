@@ -1,5 +1,6 @@
-export type ChatMessageKind = 'user-text' | 'agent-text' | 'tool-call' | 'system' | 'result';
+export type ChatMessageKind = 'user-text' | 'agent-text' | 'tool-call' | 'system' | 'result' | 'permission-request';
 export type ChatToolState = 'running' | 'completed' | 'error';
+export type ChatPermissionStatus = 'pending' | 'allowed' | 'denied' | 'expired';
 
 export interface ChatToolCall {
   callId: string;
@@ -12,6 +13,15 @@ export interface ChatToolCall {
   isError?: boolean;
 }
 
+export interface ChatPermissionRequest {
+  requestId: string;
+  toolName: string;
+  toolUseId?: string;
+  input?: unknown;
+  status: ChatPermissionStatus;
+  message?: string;
+}
+
 export interface ChatMessage {
   id: string;
   sessionId?: string;
@@ -22,7 +32,22 @@ export interface ChatMessage {
   text?: string;
   isThinking?: boolean;
   tool?: ChatToolCall;
+  permission?: ChatPermissionRequest;
   data?: Record<string, unknown>;
   createdAt?: string;
 }
 
+
+// A Claude subagent started with the Task tool. Its messages carry its id
+// as data.subagentId, and the subagent that started it as data.parentSubagentId.
+export interface ChatSubagent {
+  id: string;
+  parentId?: string;
+  title?: string;
+  status: 'running' | 'completed';
+  messageCount: number;
+  startedAt: string;
+  lastActiveAt: string;
+  durationMs: number;
+  children: ChatSubagent[];
+}
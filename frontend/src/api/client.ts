@@ -21,14 +21,12 @@ class ApiError extends Error {
 /** Paths that should not trigger the 401 interceptor */
 const AUTH_PATHS = ['/auth/login', '/auth/setup', '/auth/status', '/auth/me', '/auth/passkey/login/begin', '/auth/passkey/login/finish', '/auth/telegram'];
 
-async function request<T>(
-  path: string,
-  options: RequestInit = {}
-): Promise<T> {
+async function send(path: string, options: RequestInit = {}): Promise<Response> {
   const token = getAuthToken();
 
+  // Multipart bodies need the browser to set the boundary.
   const headers: HeadersInit = {
-    'Content-Type': 'application/json',
+    ...(options.body instanceof FormData ? {} : { 'Content-Type': 'application/json' }),
     ...options.headers,
   };
 
@@ -49,6 +47,15 @@ async function request<T>(
     throw new ApiError(response.status, error.error || 'Request failed');
   }
 
+  return response;
+}
+
+async function request<T>(
+  path: string,
+  options: RequestInit = {}
+): Promise<T> {
+  const response = await send(path, options);
+
   if (response.status === 204) {
     return undefined as T;
   }
@@ -79,6 +86,12 @@ export const api = {
 
   delete: (path: string) =>
     request<void>(path, { method: 'DELETE' }),
+
+  upload: <T>(path: string, form: FormData) =>
+    request<T>(path, { method: 'POST', body: form }),
+
+  blob: (path: string) =>
+    send(path).then((response) => response.blob()),
 };
 
 export { ApiError };
@@ -0,0 +1,66 @@
+import { api } from './client';
+import type { GitStructuredFile } from './git';
+import type { SessionStatus } from './sessions';
+
+export type ComparisonStatus = 'running' | 'picked' | 'discarded';
+
+export interface ComparisonVariant {
+  provider: 'claude' | 'codex';
+  model?: string;
+}
+
+export interface ComparisonEntry {
+  comparisonId: string;
+  sessionId: string;
+  provider: 'claude' | 'codex';
+  model?: string;
+  worktreePath: string;
+  branch: string;
+  status: SessionStatus;
+  result?: string;
+  additions: number;
+  deletions: number;
+  files?: GitStructuredFile[];
+  error?: string;
+}
+
+export interface SessionComparison {
+  id: string;
+  taskId: string;
+  prompt: string;
+  baseCommit: string;
+  status: ComparisonStatus;
+  pickedSessionId?: string;
+  entries: ComparisonEntry[];
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface StartComparisonInput {
+  prompt: string;
+  variants?: ComparisonVariant[];
+  autoApprove?: boolean;
+}
+
+export const comparisonsApi = {
+  // Run one prompt against several providers in throwaway worktrees
+  start: (taskId: string, input: StartComparisonInput) =>
+    api.post<SessionComparison>(`/tasks/${taskId}/sessions/compare`, input),
+
+  list: (taskId: string) =>
+    api.get<SessionComparison[]>(`/tasks/${taskId}/comparisons`),
+
+  get: (id: string) =>
+    api.get<SessionComparison>(`/comparisons/${id}`),
+
+  // Each entry's changes against the comparison's base commit
+  diff: (id: string, view: 'unified' | 'split' = 'unified') =>
+    api.get<SessionComparison>(`/comparisons/${id}/diff?view=${view}`),
+
+  // Keep one entry's changes in the task and discard the rest
+  pick: (id: string, sessionId: string) =>
+    api.post<{ worktreePath: string; branchName: string }>(`/comparisons/${id}/pick`, { sessionId }),
+
+  discard: (id: string) =>
+    api.post(`/comparisons/${id}/discard`),
+};
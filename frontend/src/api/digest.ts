@@ -0,0 +1,29 @@
+import { api } from './client';
+
+export type DigestSchedule = 'daily' | 'weekly';
+
+export interface DigestItem {
+  taskId?: string;
+  sessionId?: string;
+  title: string;
+  project?: string;
+}
+
+export interface DigestPreview {
+  schedule: DigestSchedule;
+  since: string;
+  until: string;
+  done: DigestItem[];
+  inReview: DigestItem[];
+  waiting: DigestItem[];
+  sessions: Record<string, number>; // sessions started, per provider
+  commits: number;
+  title: string;
+  body: string;
+}
+
+export const digestApi = {
+  // The digest that would go out now, for the configured schedule or the one given
+  preview: (schedule?: DigestSchedule) =>
+    api.get<DigestPreview>(`/digest/preview${schedule ? `?schedule=${schedule}` : ''}`),
+};
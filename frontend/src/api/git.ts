@@ -27,6 +27,53 @@ export interface GitDiffContent {
   modified: string;
 }
 
+export interface GitDiffRange {
+  start: number;
+  end: number;
+}
+
+export interface GitDiffLine {
+  type: 'context' | 'add' | 'delete';
+  oldLine?: number;
+  newLine?: number;
+  text: string;
+  changes?: GitDiffRange[];
+  noNewline?: boolean;
+}
+
+export interface GitDiffRow {
+  old?: GitDiffLine;
+  new?: GitDiffLine;
+}
+
+export interface GitStructuredHunk {
+  header: string;
+  oldStart: number;
+  oldLines: number;
+  newStart: number;
+  newLines: number;
+  section?: string;
+  lines?: GitDiffLine[];
+  rows?: GitDiffRow[];
+}
+
+export interface GitStructuredFile {
+  path: string;
+  oldPath?: string;
+  status: 'M' | 'A' | 'D' | 'R';
+  similarity?: number;
+  binary?: boolean;
+  oldMode?: string;
+  newMode?: string;
+  additions: number;
+  deletions: number;
+  hunks: GitStructuredHunk[];
+}
+
+export interface GitStructuredDiff {
+  files: GitStructuredFile[];
+}
+
 export interface GitCommitResult {
   hash: string;
   message: string;
@@ -41,6 +88,13 @@ export interface GitStashResponse {
   entries?: GitStashEntry[];
 }
 
+export interface GitRetargetResult {
+  baseBranch: string;
+  onto: string;
+  head: string;
+  warnings?: string[];
+}
+
 export interface GitLogEntry {
   hash: string;
   shortHash: string;
@@ -58,6 +112,35 @@ export interface GitLogResponse {
   commits: GitLogEntry[];
 }
 
+export interface GitBlameLine {
+  line: number;
+  origLine: number;
+  hash: string;
+  content: string;
+}
+
+export interface GitBlameCommit {
+  hash: string;
+  shortHash: string;
+  author: string;
+  authorEmail: string;
+  date: string;
+  summary: string;
+  path: string;
+  uncommitted?: boolean;
+}
+
+export interface GitBlame {
+  file: string;
+  lines: GitBlameLine[];
+  commits: Record<string, GitBlameCommit>;
+}
+
+export interface GitFileLog {
+  file: string;
+  commits: (GitLogEntry & { path: string })[];
+}
+
 export const gitApi = {
   status: (taskId: string) =>
     api.get<GitStatus>(`/tasks/${taskId}/git/status`),
@@ -71,6 +154,17 @@ export const gitApi = {
     return api.get<GitDiff>(`/tasks/${taskId}/git/diff${qs ? `?${qs}` : ''}`);
   },
 
+  structuredDiff: (taskId: string, opts?: { file?: string; staged?: boolean; base?: boolean; commit?: string; view?: 'unified' | 'split' }) => {
+    const params = new URLSearchParams();
+    if (opts?.file) params.set('file', opts.file);
+    if (opts?.staged) params.set('staged', 'true');
+    if (opts?.base) params.set('base', 'true');
+    if (opts?.commit) params.set('commit', opts.commit);
+    if (opts?.view) params.set('view', opts.view);
+    const qs = params.toString();
+    return api.get<GitStructuredDiff>(`/tasks/${taskId}/git/diff/structured${qs ? `?${qs}` : ''}`);
+  },
+
   stage: (taskId: string, files: string[]) =>
     api.post<void>(`/tasks/${taskId}/git/stage`, { files }),
 
@@ -91,4 +185,25 @@ export const gitApi = {
 
   stash: (taskId: string, action: 'push' | 'pop' | 'list') =>
     api.post<GitStashResponse>(`/tasks/${taskId}/git/stash`, { action }),
+
+  renameBranch: (taskId: string, name: string) =>
+    api.post<{ branch: string }>(`/tasks/${taskId}/git/branch/rename`, { name }),
+
+  retarget: (taskId: string, baseBranch: string) =>
+    api.post<GitRetargetResult>(`/tasks/${taskId}/git/retarget`, { baseBranch }),
+
+  blame: (taskId: string, file: string, opts?: { rev?: string; start?: number; end?: number }) => {
+    const params = new URLSearchParams({ file });
+    if (opts?.rev) params.set('rev', opts.rev);
+    if (opts?.start) params.set('start', String(opts.start));
+    if (opts?.end) params.set('end', String(opts.end));
+    return api.get<GitBlame>(`/tasks/${taskId}/git/blame?${params}`);
+  },
+
+  fileLog: (taskId: string, file: string, opts?: { rev?: string; limit?: number }) => {
+    const params = new URLSearchParams({ file });
+    if (opts?.rev) params.set('rev', opts.rev);
+    if (opts?.limit) params.set('limit', String(opts.limit));
+    return api.get<GitFileLog>(`/tasks/${taskId}/git/file-log?${params}`);
+  },
 };
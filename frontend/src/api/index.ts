@@ -5,6 +5,8 @@ export { tasksApi, invalidateTaskQueries } from './tasks';
 export { sessionsApi } from './sessions';
 export { justfileApi } from './justfile';
 export { recipesApi } from './recipes';
+export { pipelinesApi } from './pipelines';
+export { sessionTemplatesApi } from './sessionTemplates';
 export { portsApi } from './ports';
 export { tunnelsApi } from './tunnels';
 export { sidebarApi } from './sidebar';
@@ -12,14 +14,40 @@ export { preferencesApi } from './preferences';
 export type { EditorConfig, EditorType } from './preferences';
 export { gitApi } from './git';
 export { labelsApi } from './labels';
+export { comparisonsApi } from './comparisons';
+export { snippetsApi } from './snippets';
+export { bookmarksApi } from './bookmarks';
+export { activityApi } from './activity';
+export { budgetsApi } from './budgets';
+export { digestApi } from './digest';
+export { providersApi } from './providers';
+export { timeApi } from './time';
+export { llmApi } from './llm';
+export { systemApi } from './system';
+export { webhooksApi } from './webhooks';
+export { jobsApi } from './jobs';
 export { TASK_STATUS, ALL_TASK_STATUSES } from './types';
 export * from './types';
 export type { AgentSession, SessionStatus, SessionProvider, SessionType, StartSessionInput } from './sessions';
 export type { Recipe, JustfileInfo, RunResult } from './justfile';
-export type { TaskRecipe, TaskRecipesInfo } from './recipes';
+export type { TaskRecipe, TaskRecipesInfo, RecipeParam, RecipeFavorite } from './recipes';
+export type { Pipeline, PipelineStep, PipelineRun, PipelineRunStep, PipelineStatus } from './pipelines';
+export type { SessionTemplate, SessionTemplateInput } from './sessionTemplates';
 export type { PortSuggestion, PortSuggestionStatus, ScanPortsResult, ExistingTunnelRef } from './ports';
 export type { TunnelInfo } from './tunnels';
-export type { GitStatus, GitFileStatus, GitDiff, GitCommitResult, GitStashEntry, GitLogEntry, GitLogResponse } from './git';
+export type { Snippet } from './snippets';
+export type { Bookmark } from './bookmarks';
+export type { Activity, ActivityCounts, ActivityDay, ActivityHour, ActivityQuery } from './activity';
+export type { BudgetOverrideInput, BudgetStatus, Budgets, UsageTotals } from './budgets';
+export type { DigestItem, DigestPreview, DigestSchedule } from './digest';
+export type { CatalogModel, ModelProvider, ProviderModels } from './providers';
+export type { CreateTimeEntryInput, ProjectTime, TaskTime, TaskTimeSummary, TimeEntry, TimeTotals, WeeklyTime } from './time';
+export type { LLMConfig, LLMEndpointConfig, LLMFeatureStatus } from './llm';
+export type { Release, UpdateStatus, VersionInfo } from './system';
+export type { Webhook, WebhookDelivery, WebhookEvent, WebhookInput } from './webhooks';
+export type { Job, JobKind, JobQuery, JobStatus } from './jobs';
+export type { SessionComparison, ComparisonEntry, ComparisonStatus, ComparisonVariant, StartComparisonInput } from './comparisons';
+export type { GitStatus, GitFileStatus, GitDiff, GitStructuredDiff, GitStructuredFile, GitStructuredHunk, GitDiffLine, GitDiffRow, GitDiffRange, GitCommitResult, GitStashEntry, GitLogEntry, GitLogResponse, GitBlame, GitBlameLine, GitBlameCommit, GitFileLog } from './git';
 export type {
   CreateProjectFileEntryInput,
   ProjectFileEntry,
@@ -31,4 +59,12 @@ export type {
   ProjectSecretContentResponse,
   ProjectSecretResolveResult,
   ProjectSecretResolveResponse,
+  WorktreePoolResponse,
+  ProjectBoardColumn,
+  SemanticIndexStats,
+  SemanticSearchResult,
+  SemanticSearchResponse,
+  ImportSource,
+  ImportIssuesInput,
+  ImportIssuesResult,
 } from './projects';
@@ -0,0 +1,52 @@
+import { api } from './client';
+
+export type JobKind = 'project.clone' | 'project.unshallow' | 'worktree.create' | 'pull_request.create' | 'worktree_pool.remove' | 'provision.teardown' | 'devcontainer.up' | 'transcripts.prune' | 'digest';
+
+export type JobStatus = 'pending' | 'running' | 'succeeded' | 'failed' | 'cancelled';
+
+/** A long-running operation run in the background; updates arrive as job_updated. */
+export interface Job {
+  id: string;
+  kind: JobKind;
+  projectId?: string;
+  payload: unknown;
+  status: JobStatus;
+  progress?: string; // e.g. "Receiving objects: 45% (450/1000)"
+  result?: unknown; // e.g. the Project a clone created
+  attempts: number;
+  maxAttempts: number;
+  lastError?: string;
+  runAt?: string;
+  createdAt: string;
+  updatedAt: string;
+  finishedAt?: string;
+}
+
+export interface JobQuery {
+  kind?: JobKind;
+  status?: JobStatus;
+  projectId?: string;
+  limit?: number;
+}
+
+export const jobsApi = {
+  list: (query: JobQuery = {}) => {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query)) {
+      if (value !== undefined) params.set(key, String(value));
+    }
+    const qs = params.toString();
+    return api.get<Job[]>(`/jobs${qs ? `?${qs}` : ''}`);
+  },
+
+  get: (id: string) => api.get<Job>(`/jobs/${id}`),
+
+  // Queue a failed or cancelled job again
+  retry: (id: string) => api.post<Job>(`/jobs/${id}/retry`),
+
+  // Cancel a pending job or stop a running one
+  cancel: (id: string) => api.post<Job>(`/jobs/${id}/cancel`),
+
+  // Prune chat transcripts now, vacuuming the database whether or not it is due
+  pruneTranscripts: (vacuum = false) => api.post<Job>(`/transcripts/prune${vacuum ? '?vacuum=true' : ''}`),
+};
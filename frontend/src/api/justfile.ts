@@ -33,6 +33,10 @@ export const justfileApi = {
   runTaskRecipe: (taskId: string, recipe: string, args?: string[]) =>
     api.post<RunResult>(`/tasks/${taskId}/just/${recipe}`, { args }),
 
+  // Run a recipe for a task with named parameters
+  runTaskRecipeWithParams: (taskId: string, recipe: string, params: Record<string, string>) =>
+    api.post<RunResult>(`/tasks/${taskId}/just/${recipe}`, { params }),
+
   // Get streaming URL for a recipe (SSE)
   getStreamUrl: (taskId: string, recipe: string, args?: string[]) => {
     const params = new URLSearchParams();
@@ -0,0 +1,28 @@
+import { api } from './client';
+
+export interface LLMEndpointConfig {
+  url?: string; // an OpenAI-compatible API; OpenAI when absent
+  apiKey?: string;
+  model?: string;
+}
+
+// The llm preference: shared settings, and each feature's own on top.
+export interface LLMConfig extends LLMEndpointConfig {
+  embeddingModel?: string;
+  features?: Record<string, LLMEndpointConfig>;
+}
+
+export interface LLMFeatureStatus {
+  feature: string;
+  embeddings?: boolean;
+  url: string;
+  model?: string;
+  configured: boolean;
+  error?: string;
+}
+
+export const llmApi = {
+  getConfig: () => api.get<LLMConfig>('/preferences/llm'),
+  setConfig: (config: LLMConfig) => api.put<LLMConfig>('/preferences/llm', config),
+  listFeatures: () => api.get<LLMFeatureStatus[]>('/llm/features'),
+};
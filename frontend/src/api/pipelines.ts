@@ -0,0 +1,69 @@
+import { api } from './client';
+
+export interface PipelineStep {
+  source: string;
+  recipe: string;
+  args?: Record<string, string>;
+  /** Paths or globs, relative to the worktree, published as task artifacts when the step succeeds. */
+  artifacts?: string[];
+}
+
+export interface Pipeline {
+  id: string;
+  projectId: string;
+  name: string;
+  steps: PipelineStep[];
+  continueOnError: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export type PipelineStatus = 'pending' | 'running' | 'succeeded' | 'failed' | 'skipped' | 'canceled';
+
+export interface PipelineRunStep {
+  source: string;
+  recipe: string;
+  command?: string;
+  status: PipelineStatus;
+  exitCode?: number;
+  error?: string;
+  output?: string;
+  startedAt?: string;
+  finishedAt?: string;
+}
+
+export interface PipelineRun {
+  id: string;
+  pipelineId: string;
+  pipelineName: string;
+  taskId: string;
+  status: PipelineStatus;
+  steps: PipelineRunStep[];
+  startedAt: string;
+  finishedAt?: string;
+}
+
+export interface PipelineInput {
+  name: string;
+  steps: PipelineStep[];
+  continueOnError?: boolean;
+}
+
+export const pipelinesApi = {
+  list: (projectId: string) =>
+    api.get<Pipeline[]>(`/projects/${projectId}/pipelines`),
+  create: (projectId: string, input: PipelineInput) =>
+    api.post<Pipeline>(`/projects/${projectId}/pipelines`, input),
+  update: (id: string, input: Partial<PipelineInput>) =>
+    api.patch<Pipeline>(`/pipelines/${id}`, input),
+  delete: (id: string) =>
+    api.delete(`/pipelines/${id}`),
+  run: (taskId: string, pipelineId: string) =>
+    api.post<PipelineRun>(`/tasks/${taskId}/pipelines/${pipelineId}/run`),
+  listRuns: (taskId: string) =>
+    api.get<PipelineRun[]>(`/tasks/${taskId}/pipeline-runs`),
+  getRun: (runId: string) =>
+    api.get<PipelineRun>(`/pipeline-runs/${runId}`),
+  cancelRun: (runId: string) =>
+    api.post(`/pipeline-runs/${runId}/cancel`),
+};
@@ -16,10 +16,20 @@ export interface ExistingTunnelRef {
 export interface PortSuggestion {
   port: number;
   sources: string[];
+  services?: string[];
   firstSeenAt: string;
   lastSeenAt: string;
   status: PortSuggestionStatus;
   existingTunnel?: ExistingTunnelRef;
+  claimedBy?: string[];
+  assignment?: PortAssignment;
+}
+
+export interface PortAssignment {
+  port: number;
+  assignedPort: number;
+  envVar: string;
+  createdAt: string;
 }
 
 export interface TaskPortSuggestionsResponse {
@@ -30,6 +40,13 @@ export interface ScanPortsResult {
   scannedAt: string;
   portsFound: number[];
   suggestionsUpdated: number;
+  docker?: DockerPort[];
+}
+
+export interface DockerPort {
+  port: number;
+  service?: string;
+  source: 'compose' | 'dockerfile' | 'container';
 }
 
 export const portsApi = {
@@ -38,4 +55,14 @@ export const portsApi = {
 
   scanTaskPorts: (taskId: string) =>
     api.post<ScanPortsResult>(`/tasks/${taskId}/ports/scan`),
+
+  listAssignments: (taskId: string) =>
+    api.get<PortAssignment[]>(`/tasks/${taskId}/port-assignments`),
+
+  // Omit assignedPort to have the server pick a free alternate
+  assignPort: (taskId: string, port: number, input: { assignedPort?: number; envVar?: string } = {}) =>
+    api.put<PortAssignment>(`/tasks/${taskId}/port-assignments/${port}`, input),
+
+  unassignPort: (taskId: string, port: number) =>
+    api.delete(`/tasks/${taskId}/port-assignments/${port}`),
 };
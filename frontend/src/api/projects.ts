@@ -1,6 +1,7 @@
 import { api } from './client';
 import { ApiError } from './client';
-import type { Project, CreateProjectInput, UpdateProjectInput, ProjectSecretFile, ArchiveInfo } from './types';
+import type { Job } from './jobs';
+import type { Project, BoardColumn, CloneShape, CreateProjectInput, ProjectDevcontainerInfo, UpdateProjectInput, ProjectSecretFile, ArchiveInfo, WorktreePoolConfig, PooledWorktree } from './types';
 
 export interface ProjectFileEntry {
   name: string;
@@ -34,6 +35,60 @@ export interface WriteProjectFileInput {
   content: string;
 }
 
+export interface WorktreePoolResponse {
+  config?: WorktreePoolConfig;
+  worktrees: PooledWorktree[];
+}
+
+export interface ProjectBoardColumn extends Required<Pick<BoardColumn, 'id' | 'name' | 'category'>> {
+  wipLimit?: number;
+  builtin: boolean;
+  count: number;
+}
+
+export interface SemanticIndexStats {
+  files: number;
+  embedded: number;
+  removed: number;
+  capped?: boolean;
+}
+
+export interface SemanticSearchResult {
+  path: string;
+  startLine: number;
+  endLine: number;
+  score: number;
+  preview: string;
+}
+
+export interface SemanticSearchResponse {
+  results: SemanticSearchResult[];
+  index: SemanticIndexStats;
+}
+
+export type ImportSource = 'jira' | 'linear' | 'codeburg';
+
+// data is an export (Jira CSV, Linear GraphQL JSON or a board dump);
+// without it, jira or linear say where to fetch issues from.
+export interface ImportIssuesInput {
+  source: ImportSource;
+  data?: string;
+  jira?: { url: string; email?: string; token: string; jql: string };
+  linear?: { apiKey: string; team?: string };
+  update?: boolean;
+  dryRun?: boolean;
+}
+
+export interface ImportIssuesResult {
+  source: ImportSource;
+  created: number;
+  updated: number;
+  skipped: number;
+  labelsCreated: number;
+  columnsCreated?: string[];
+  dryRun?: boolean;
+}
+
 export interface ProjectSecretFileStatus extends ProjectSecretFile {
   managedPath: string;
   managedExists: boolean;
@@ -78,16 +133,47 @@ export interface ProjectPushDefaultBranchResponse {
 export const projectsApi = {
   list: () => api.get<Project[]>('/projects'),
 
+  listArchived: () => api.get<Project[]>('/projects?archived=true'),
+
   get: (id: string) => api.get<Project>(`/projects/${id}`),
 
   create: (input: CreateProjectInput) =>
     api.post<Project>('/projects', input),
 
+  // Clones run as a job; follow it with jobsApi or job_updated events
+  clone: (input: CreateProjectInput & { githubUrl: string }) =>
+    api.post<Job>('/projects', input),
+
+  cloneShape: (id: string) => api.get<CloneShape>(`/projects/${id}/clone`),
+
+  // Fetch what a shallow, single-branch or partial clone left out, in a job
+  unshallow: (id: string) => api.post<Job>(`/projects/${id}/unshallow`),
+
+  // The devcontainer.json found in the project, to offer running in it
+  devcontainer: (id: string) =>
+    api.get<ProjectDevcontainerInfo>(`/projects/${id}/devcontainer`),
+
   update: (id: string, input: UpdateProjectInput) =>
     api.patch<Project>(`/projects/${id}`, input),
 
   delete: (id: string) => api.delete(`/projects/${id}`),
 
+  getBoard: (id: string) => api.get<ProjectBoardColumn[]>(`/projects/${id}/board`),
+
+  exportBoard: (id: string, format: 'json' | 'csv' = 'json', archived = false) =>
+    api.blob(`/projects/${id}/board/export?format=${format}${archived ? '&archived=true' : ''}`),
+
+  importIssues: (id: string, input: ImportIssuesInput) =>
+    api.post<ImportIssuesResult>(`/projects/${id}/import`, input),
+
+  semanticSearch: (id: string, query: string, limit?: number) =>
+    api.post<SemanticSearchResponse>(`/projects/${id}/semantic-search`, { query, limit }),
+
+  refreshSemanticIndex: (id: string) =>
+    api.post<SemanticIndexStats>(`/projects/${id}/semantic-index`),
+
+  deleteSemanticIndex: (id: string) => api.delete(`/projects/${id}/semantic-index`),
+
   listBranches: (id: string) => api.get<string[]>(`/projects/${id}/branches`),
 
   listFiles: (id: string, params?: { path?: string; depth?: number }) => {
@@ -145,13 +231,29 @@ export const projectsApi = {
         throw err;
       }),
 
-  archive: (id: string) =>
-    api.post<{ filename: string; path: string }>(`/projects/${id}/archive`),
+  // Worktrees created ahead of time so tasks start without waiting on setup
+  getWorktreePool: (id: string) =>
+    api.get<WorktreePoolResponse>(`/projects/${id}/worktree-pool`),
+
+  refillWorktreePool: (id: string) =>
+    api.post<void>(`/projects/${id}/worktree-pool/refill`),
+
+  drainWorktreePool: (id: string) =>
+    api.delete(`/projects/${id}/worktree-pool`),
+
+  archive: (id: string, cleanupWorktrees = false) =>
+    api.post<Project>(`/projects/${id}/archive`, cleanupWorktrees ? { cleanupWorktrees } : undefined),
+
+  unarchive: (id: string) =>
+    api.post<Project>(`/projects/${id}/unarchive`),
+
+  exportArchive: (id: string) =>
+    api.post<{ filename: string; path: string }>(`/projects/${id}/export`),
 
   listArchives: () =>
     api.get<ArchiveInfo[]>('/archives'),
 
-  unarchive: (filename: string) =>
+  restoreArchive: (filename: string) =>
     api.post<Project>(`/archives/${encodeURIComponent(filename)}/unarchive`),
 
   deleteArchive: (filename: string) =>
@@ -0,0 +1,21 @@
+import { api } from './client';
+
+export type ModelProvider = 'claude' | 'codex';
+
+export interface CatalogModel {
+  id: string;
+  name?: string;
+  source: 'registry' | 'cli_config' | 'preference';
+}
+
+export interface ProviderModels {
+  provider: ModelProvider;
+  default?: string; // the model the CLI's own settings choose
+  models: CatalogModel[];
+}
+
+export const providersApi = {
+  // Models each provider accepts, for every provider or the one given
+  models: (provider?: ModelProvider) =>
+    api.get<ProviderModels[]>(`/providers/models${provider ? `?provider=${provider}` : ''}`),
+};
@@ -1,10 +1,28 @@
 import { api } from './client';
 
+export interface RecipeParam {
+  name: string;
+  default?: string;
+  required: boolean;
+  variadic?: boolean;
+}
+
 export interface TaskRecipe {
   name: string;
   command: string;
   source: string;
   description?: string;
+  params?: RecipeParam[];
+}
+
+export interface RecipeFavorite {
+  id: string;
+  projectId: string;
+  source: string;
+  name: string;
+  args?: Record<string, string>;
+  command: string;
+  createdAt: string;
 }
 
 export interface TaskRecipesInfo {
@@ -15,4 +33,10 @@ export interface TaskRecipesInfo {
 export const recipesApi = {
   listTaskRecipes: (taskId: string) =>
     api.get<TaskRecipesInfo>(`/tasks/${taskId}/recipes`),
+  listFavorites: (projectId: string) =>
+    api.get<RecipeFavorite[]>(`/projects/${projectId}/recipe-favorites`),
+  addFavorite: (projectId: string, source: string, name: string, args?: Record<string, string>) =>
+    api.post<RecipeFavorite>(`/projects/${projectId}/recipe-favorites`, { source, name, args }),
+  removeFavorite: (projectId: string, favoriteId: string) =>
+    api.delete(`/projects/${projectId}/recipe-favorites/${favoriteId}`),
 };
@@ -0,0 +1,40 @@
+import { api } from './client';
+import type { AgentSession, SessionProvider } from './sessions';
+
+/**
+ * Saved settings and prompt for starting sessions in a project's tasks. The
+ * prompt may use {{task.title}}, {{task.description}}, {{task.id}},
+ * {{task.branch}} and {{project.name}}.
+ */
+export interface SessionTemplate {
+  id: string;
+  projectId: string;
+  name: string;
+  provider: SessionProvider;
+  model: string;
+  autoApprove: boolean;
+  prompt: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface SessionTemplateInput {
+  name: string;
+  provider?: SessionProvider;
+  model?: string;
+  autoApprove?: boolean;
+  prompt?: string;
+}
+
+export const sessionTemplatesApi = {
+  list: (projectId: string) =>
+    api.get<SessionTemplate[]>(`/projects/${projectId}/session-templates`),
+  create: (projectId: string, input: SessionTemplateInput) =>
+    api.post<SessionTemplate>(`/projects/${projectId}/session-templates`, input),
+  update: (id: string, input: Partial<SessionTemplateInput>) =>
+    api.patch<SessionTemplate>(`/session-templates/${id}`, input),
+  delete: (id: string) =>
+    api.delete(`/session-templates/${id}`),
+  start: (taskId: string, templateId: string) =>
+    api.post<AgentSession>(`/tasks/${taskId}/sessions/from-template`, { templateId }),
+};
@@ -1,4 +1,5 @@
 import { api } from './client';
+import type { ChatMessage, ChatSubagent } from './chat';
 
 export type SessionStatus = 'idle' | 'running' | 'waiting_input' | 'completed' | 'error';
 export type SessionProvider = 'claude' | 'codex' | 'terminal';
@@ -16,10 +17,20 @@ export interface AgentSession {
   tmuxPane?: string;
   logFile?: string;
   lastActivityAt?: string;
+  retryHistory?: SessionRetry[];
+  readOnly?: boolean; // an ask session: answers questions, edits nothing
+  title?: string; // from the first prompt, or set by hand
+  idleExempt?: boolean; // never stopped by the idle timeout
   createdAt: string;
   updatedAt: string;
 }
 
+export interface SessionRetry {
+  attempt: number;
+  error: string;
+  createdAt: string;
+}
+
 export interface StartSessionInput {
   provider?: SessionProvider;
   sessionType?: SessionType;
@@ -27,11 +38,21 @@ export interface StartSessionInput {
   model?: string;
   resumeSessionId?: string;
   autoApprove?: boolean;
+  mode?: 'ask';
+  cols?: number; // initial terminal size (terminal sessions; default 120x40)
+  rows?: number;
 }
 
 export const sessionsApi = {
-  list: (taskId: string) =>
-    api.get<AgentSession[]>(`/tasks/${taskId}/sessions`),
+  list: (taskId: string, query?: string) =>
+    api.get<AgentSession[]>(`/tasks/${taskId}/sessions${query ? `?q=${encodeURIComponent(query)}` : ''}`),
+
+  // Sessions across projects whose titles (or IDs, by prefix) match query.
+  search: (query: string, projectId?: string) => {
+    const params = new URLSearchParams({ q: query });
+    if (projectId) params.set('projectId', projectId);
+    return api.get<AgentSession[]>(`/sessions?${params}`);
+  },
 
   get: (id: string) =>
     api.get<AgentSession>(`/sessions/${id}`),
@@ -39,12 +60,35 @@ export const sessionsApi = {
   start: (taskId: string, input: StartSessionInput) =>
     api.post<AgentSession>(`/tasks/${taskId}/sessions`, input),
 
+  // An empty title clears it.
+  rename: (sessionId: string, title: string) =>
+    api.patch<AgentSession>(`/sessions/${sessionId}`, { title }),
+
+  // Exempts a session from the session_idle_timeout preference, or not.
+  setIdleExempt: (sessionId: string, idleExempt: boolean) =>
+    api.patch<AgentSession>(`/sessions/${sessionId}`, { idleExempt }),
+
+  // The session's subagents, nested under the subagent that started each.
+  subagents: (sessionId: string) =>
+    api.get<ChatSubagent[]>(`/sessions/${sessionId}/subagents`),
+
   sendMessage: (sessionId: string, content: string) =>
     api.post<{ status: string }>(`/sessions/${sessionId}/message`, { content }),
 
+  respondPermission: (sessionId: string, requestId: string, decision: 'allow' | 'deny', message?: string) =>
+    api.post<ChatMessage>(`/sessions/${sessionId}/permissions/${requestId}`, { decision, message }),
+
   stop: (sessionId: string) =>
     api.post(`/sessions/${sessionId}/stop`),
 
+  // Sets a running terminal session's PTY size.
+  resize: (sessionId: string, cols: number, rows: number) =>
+    api.post(`/sessions/${sessionId}/resize`, { cols, rows }),
+
   delete: (sessionId: string) =>
     api.delete(`/sessions/${sessionId}`),
+
+  // Terminal sessions' output as an asciicast v2 file.
+  recording: (sessionId: string) =>
+    api.blob(`/sessions/${sessionId}/recording`),
 };
@@ -0,0 +1,23 @@
+import { api } from './client';
+
+export interface Snippet {
+  id: string;
+  taskId: string;
+  content: string;
+  consumedSessionId?: string;
+  consumedAt?: string;
+  createdAt: string;
+}
+
+export const snippetsApi = {
+  // List a task's inbox, newest first
+  list: (taskId: string, unconsumed = false) =>
+    api.get<Snippet[]>(`/tasks/${taskId}/snippets${unconsumed ? '?unconsumed=true' : ''}`),
+
+  // Paste into a task's inbox; the next session started with a prompt takes it
+  create: (taskId: string, content: string) =>
+    api.post<Snippet>(`/tasks/${taskId}/snippets`, { content }),
+
+  delete: (id: string) =>
+    api.delete(`/snippets/${id}`),
+};
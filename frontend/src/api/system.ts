@@ -0,0 +1,31 @@
+import { api } from './client';
+
+export interface Release {
+  version: string;
+  url: string;
+  publishedAt: string;
+}
+
+export interface UpdateStatus {
+  enabled: boolean; // the update_check preference
+  checkedAt?: string;
+  latest?: Release;
+  available: boolean; // latest is newer than the running version
+  error?: string;
+}
+
+export interface VersionInfo {
+  version: string; // "dev" for builds from source
+  commit?: string;
+  commitTime?: string;
+  modified?: boolean;
+  goVersion: string;
+  os: string;
+  arch: string;
+  update: UpdateStatus;
+}
+
+export const systemApi = {
+  // Running build, and the latest release once checked; check=true checks now
+  version: (check = false) => api.get<VersionInfo>(`/system/version${check ? '?check=true' : ''}`),
+};
@@ -1,6 +1,7 @@
 import type { QueryClient } from '@tanstack/react-query';
 import { api } from './client';
-import type { Task, CreateTaskInput, UpdateTaskInput, UpdateTaskResponse, TaskStatus, WorktreeResponse } from './types';
+import type { Task, CreateTaskInput, UpdateTaskInput, UpdateTaskResponse, ReorderTaskInput, TaskStatus, WorktreeResponse, TaskProvision, TaskEnvVar, TaskDevcontainer, CIStatus, WorktreeSnapshot } from './types';
+import type { Job } from './jobs';
 
 /** Invalidate all task-related queries. Call after any task mutation. */
 export function invalidateTaskQueries(queryClient: QueryClient, taskId?: string) {
@@ -10,10 +11,18 @@ export function invalidateTaskQueries(queryClient: QueryClient, taskId?: string)
 }
 
 export const tasksApi = {
-  list: (params?: { project?: string; status?: TaskStatus; archived?: boolean }) => {
+  list: (params?: {
+    project?: string;
+    status?: TaskStatus;
+    priority?: string[];
+    sort?: 'position' | 'priority' | 'created' | 'title' | 'estimate';
+    archived?: boolean;
+  }) => {
     const searchParams = new URLSearchParams();
     if (params?.project) searchParams.set('project', params.project);
     if (params?.status) searchParams.set('status', params.status);
+    if (params?.priority?.length) searchParams.set('priority', params.priority.join(','));
+    if (params?.sort) searchParams.set('sort', params.sort);
     if (params?.archived) searchParams.set('archived', 'true');
     const query = searchParams.toString();
     return api.get<Task[]>(`/tasks${query ? `?${query}` : ''}`);
@@ -27,6 +36,10 @@ export const tasksApi = {
   update: (id: string, input: UpdateTaskInput) =>
     api.patch<UpdateTaskResponse>(`/tasks/${id}`, input),
 
+  // Move a task to an index in a column; a stale version answers 409 with the current task
+  reorder: (id: string, input: ReorderTaskInput) =>
+    api.post<UpdateTaskResponse>(`/tasks/${id}/reorder`, input),
+
   delete: (id: string) => api.delete(`/tasks/${id}`),
 
   // Worktree operations
@@ -36,6 +49,56 @@ export const tasksApi = {
   deleteWorktree: (taskId: string) =>
     api.delete(`/tasks/${taskId}/worktree`),
 
+  // Per-task services started by the project's provisioner
+  getProvision: (taskId: string) =>
+    api.get<TaskProvision>(`/tasks/${taskId}/provision`),
+
+  provision: (taskId: string) =>
+    api.post<TaskProvision>(`/tasks/${taskId}/provision`),
+
+  teardownProvision: (taskId: string) =>
+    api.delete(`/tasks/${taskId}/provision`),
+
+  // The task's devcontainer, when its project runs in one
+  getDevcontainer: (taskId: string) =>
+    api.get<TaskDevcontainer>(`/tasks/${taskId}/devcontainer`),
+
+  // Pull or build the image and start the container, in a job
+  startDevcontainer: (taskId: string) =>
+    api.post<Job>(`/tasks/${taskId}/devcontainer`),
+
+  removeDevcontainer: (taskId: string) =>
+    api.delete(`/tasks/${taskId}/devcontainer`),
+
+  // Variables for the task's sessions and runs, over the project's
+  getEnv: (taskId: string) =>
+    api.get<TaskEnvVar[]>(`/tasks/${taskId}/env`),
+
+  setEnv: (taskId: string, vars: TaskEnvVar[]) =>
+    api.put<TaskEnvVar[]>(`/tasks/${taskId}/env`, vars),
+
   createPR: (taskId: string) =>
     api.post<{ prUrl: string }>(`/tasks/${taskId}/create-pr`, {}),
+
+  // Send the PR's review comments to a chat session (a new one without sessionId)
+  sendReviewComments: (taskId: string, input: { sessionId?: string; provider?: string; model?: string; reviewId?: number } = {}) =>
+    api.post<{ sessionId: string; started: boolean; comments: number }>(`/tasks/${taskId}/review-comments`, input),
+
+  // Worktree snapshots, to roll back to after an agent run
+  snapshot: (taskId: string, label = '') =>
+    api.post<WorktreeSnapshot>(`/tasks/${taskId}/snapshot`, { label }),
+
+  listSnapshots: (taskId: string) =>
+    api.get<WorktreeSnapshot[]>(`/tasks/${taskId}/snapshots`),
+
+  deleteSnapshot: (taskId: string, snapshotId: string) =>
+    api.delete(`/tasks/${taskId}/snapshots/${snapshotId}`),
+
+  // Roll back to a snapshot (default the latest); the backup undoes it
+  rollback: (taskId: string, snapshotId?: string) =>
+    api.post<{ snapshot: WorktreeSnapshot; backup: WorktreeSnapshot }>(`/tasks/${taskId}/rollback`, { snapshotId }),
+
+  // GitHub checks on the head of the task's branch, cached briefly
+  getCIStatus: (taskId: string, refresh = false) =>
+    api.get<{ branch: string; status: CIStatus }>(`/tasks/${taskId}/ci-status${refresh ? '?refresh=true' : ''}`),
 };
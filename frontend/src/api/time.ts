@@ -0,0 +1,66 @@
+import { api } from './client';
+
+export interface TimeEntry {
+  id: string;
+  projectId: string;
+  taskId?: string;
+  sessionId?: string; // absent for manual entries
+  provider?: string;
+  startedAt: string;
+  endedAt?: string; // absent while the session runs
+  note?: string;
+  createdAt: string;
+}
+
+export interface TimeTotals {
+  totalSeconds: number;
+  byProvider: Record<string, number>; // manual entries under "manual"
+}
+
+export interface TaskTime extends TimeTotals {
+  entries: TimeEntry[];
+}
+
+export interface TaskTimeSummary {
+  taskId?: string; // absent for project sessions
+  title?: string;
+  totalSeconds: number;
+}
+
+export interface ProjectTime extends TimeTotals {
+  projectId: string;
+  name: string;
+  tasks: TaskTimeSummary[];
+}
+
+export interface WeeklyTime extends TimeTotals {
+  start: string;
+  end: string;
+  projects: ProjectTime[];
+}
+
+export interface CreateTimeEntryInput {
+  minutes: number;
+  note?: string;
+  startedAt?: string;
+}
+
+export const timeApi = {
+  getTask: (taskId: string) => api.get<TaskTime>(`/tasks/${taskId}/time`),
+
+  addEntry: (taskId: string, input: CreateTimeEntryInput) =>
+    api.post<TimeEntry>(`/tasks/${taskId}/time`, input),
+
+  // Only manual entries can be deleted
+  deleteEntry: (id: string) => api.delete(`/time/${id}`),
+
+  getProject: (projectId: string) => api.get<ProjectTime>(`/projects/${projectId}/time`),
+
+  // The week (from Monday) holding date, this week by default
+  weekly: (params: { date?: string; tz?: string } = {}) => {
+    const search = new URLSearchParams();
+    if (params.date) search.set('date', params.date);
+    if (params.tz) search.set('tz', params.tz);
+    return api.get<WeeklyTime>(`/time/weekly?${search}`);
+  },
+};
@@ -1,10 +1,26 @@
 import { api } from './client';
 
+export type TunnelProviderName = 'cloudflared' | 'tailscale' | 'ngrok';
+
 export interface TunnelInfo {
   id: string;
   taskId: string;
   port: number;
   url: string;
+  provider: TunnelProviderName;
+  auth?: 'basic' | 'token';
+  shareUrl?: string;
+  createdAt: string;
+  expiresAt?: string;
+  restarts?: number;
+  longOpen?: boolean;
+}
+
+export interface TunnelProvider {
+  name: TunnelProviderName;
+  available: boolean;
+  configured: boolean;
+  default: boolean;
 }
 
 export const tunnelsApi = {
@@ -13,8 +29,12 @@ export const tunnelsApi = {
     api.get<TunnelInfo[]>(`/tasks/${taskId}/tunnels`),
 
   // Create a tunnel
-  create: (taskId: string, port: number) =>
-    api.post<TunnelInfo>(`/tasks/${taskId}/tunnels`, { port }),
+  create: (taskId: string, port: number, ttlMinutes?: number, provider?: TunnelProviderName) =>
+    api.post<TunnelInfo>(`/tasks/${taskId}/tunnels`, { port, ttlMinutes, provider }),
+
+  // List tunnel providers and which are installed
+  providers: () =>
+    api.get<TunnelProvider[]>('/tunnels/providers'),
 
   // Stop a tunnel
   stop: (tunnelId: string) =>
@@ -6,6 +6,104 @@ export interface BacklogToProgressConfig {
   promptTemplate?: string;
 }
 
+export interface TunnelAuth {
+  mode: '' | 'none' | 'basic' | 'token';
+  username?: string;
+  password?: string;
+}
+
+export interface ProjectProvisioner {
+  up: string;
+  down?: string;
+  ports?: string[];
+  env?: Record<string, string>;
+}
+
+export interface TaskProvision {
+  taskId: string;
+  status: 'ready' | 'failed' | 'torn_down';
+  env: Record<string, string>;
+  output?: string;
+  error?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+// A variable set for one task. The values of secrets are never returned;
+// leave value out when saving to keep a secret's stored value.
+export interface TaskEnvVar {
+  name: string;
+  value?: string | null;
+  secret?: boolean;
+}
+
+export interface WorktreePoolConfig {
+  size: number;
+  maxDiskMb?: number;
+}
+
+// Paths are globs relative to the worktree root; noForcePush holds branch
+// patterns such as "release/*".
+export interface ProtectionPolicy {
+  paths?: string[];
+  agentPaths?: string[];
+  noForcePush?: string[];
+  guardrails?: AgentGuardrails;
+}
+
+// Checked after each chat turn; a session past them waits for review.
+export interface AgentGuardrails {
+  maxFilesChanged?: number;
+  maxLinesChanged?: number;
+  forbiddenPaths?: string[];
+}
+
+// Runs the project's terminal sessions and recipes in its devcontainer.
+// config is relative to the project root; leave it out to look in
+// .devcontainer/devcontainer.json and .devcontainer.json.
+export interface ProjectDevcontainer {
+  enabled: boolean;
+  config?: string;
+}
+
+// The part of a devcontainer.json Codeburg uses.
+export interface DevcontainerConfig {
+  path: string;
+  name?: string;
+  image?: string;
+  build?: { dockerfile?: string; context?: string; args?: Record<string, string>; target?: string };
+  containerEnv?: Record<string, string>;
+  remoteEnv?: Record<string, string>;
+  workspaceFolder?: string;
+  remoteUser?: string;
+  containerUser?: string;
+  runArgs?: string[];
+}
+
+export interface ProjectDevcontainerInfo {
+  found: boolean;
+  enabled: boolean;
+  config?: DevcontainerConfig;
+  error?: string;
+}
+
+export interface TaskDevcontainer {
+  enabled: boolean;
+  name?: string;
+  state?: string;
+}
+
+export interface PooledWorktree {
+  id: string;
+  projectId: string;
+  path: string;
+  branch: string;
+  baseBranch: string;
+  status: 'creating' | 'ready';
+  sizeBytes: number;
+  createdAt: string;
+}
+
 export interface ProgressToReviewConfig {
   action: 'pr_manual' | 'pr_auto' | 'nothing';
   prBaseBranch?: string;
@@ -25,6 +123,24 @@ export interface ProjectWorkflow {
   reviewToDone?: ReviewToDoneConfig;
 }
 
+export interface AgentGitIdentity {
+  enabled: boolean;
+  name?: string; // supports {provider}
+  email?: string;
+}
+
+export interface AgentInstructions {
+  prelude?: string;
+  defaultModel?: string;
+  defaultAutoApprove?: boolean;
+}
+
+export interface RetryPolicy {
+  maxRetries: number;
+  backoffSeconds?: number;
+  promptTemplate?: string;
+}
+
 export interface ProjectSecretFile {
   path: string;
   mode: 'copy' | 'symlink';
@@ -32,6 +148,15 @@ export interface ProjectSecretFile {
   enabled: boolean;
 }
 
+// A column a project adds to its board, or a built-in one it renames or
+// limits. Tasks in it have its id as their status.
+export interface BoardColumn {
+  id: string;
+  name?: string;
+  category?: TaskStatus;
+  wipLimit?: number;
+}
+
 export interface Project {
   id: string;
   name: string;
@@ -39,25 +164,47 @@ export interface Project {
   gitOrigin?: string;
   defaultBranch: string;
   symlinkPaths?: string[];
+  clonePaths?: string[];
   secretFiles?: ProjectSecretFile[];
   setupScript?: string;
   teardownScript?: string;
   workflow?: ProjectWorkflow;
+  agentIdentity?: AgentGitIdentity;
+  agentInstructions?: AgentInstructions;
+  retryPolicy?: RetryPolicy;
+  tunnelAuth?: TunnelAuth;
+  provisioner?: ProjectProvisioner;
+  worktreePool?: WorktreePoolConfig;
+  protection?: ProtectionPolicy;
+  devcontainer?: ProjectDevcontainer;
+  boardColumns?: BoardColumn[];
   hidden: boolean;
+  archivedAt?: string;
+  version: number;
   createdAt: string;
   updatedAt: string;
 }
 
+/** How much of a repository a clone fetches; the empty shape is a full clone. */
+export interface CloneShape {
+  depth?: number; // latest commits only; reported as 1 for any shallow clone
+  singleBranch?: boolean;
+  partial?: 'blobless' | 'treeless';
+}
+
 export interface CreateProjectInput {
   name: string;
   path?: string;
   githubUrl?: string;
   createRepo?: boolean;
+  wait?: boolean; // for githubUrl: reply with the Project once cloned rather than the clone Job
+  clone?: CloneShape; // for githubUrl; the server's default when omitted
   description?: string;
   private?: boolean;
   gitOrigin?: string;
   defaultBranch?: string;
   symlinkPaths?: string[];
+  clonePaths?: string[];
   secretFiles?: ProjectSecretFile[];
   setupScript?: string;
   teardownScript?: string;
@@ -69,11 +216,22 @@ export interface UpdateProjectInput {
   gitOrigin?: string;
   defaultBranch?: string;
   symlinkPaths?: string[];
+  clonePaths?: string[];
   secretFiles?: ProjectSecretFile[];
   setupScript?: string;
   teardownScript?: string;
   workflow?: ProjectWorkflow;
+  agentIdentity?: AgentGitIdentity;
+  agentInstructions?: AgentInstructions;
+  retryPolicy?: RetryPolicy;
+  tunnelAuth?: TunnelAuth;
+  provisioner?: ProjectProvisioner;
+  worktreePool?: WorktreePoolConfig;
+  protection?: ProtectionPolicy;
+  devcontainer?: ProjectDevcontainer;
+  boardColumns?: BoardColumn[];
   hidden?: boolean;
+  version?: number;
 }
 
 export interface WorktreeResponse {
@@ -102,6 +260,31 @@ export interface DiffStats {
   deletions: number;
 }
 
+export type CIState = 'success' | 'failure' | 'pending' | 'skipped' | 'none';
+
+export interface CICheck {
+  name: string;
+  state: CIState;
+  url?: string;
+}
+
+export interface CIStatus {
+  sha: string;
+  state: CIState;
+  checks: CICheck[];
+}
+
+export interface WorktreeSnapshot {
+  id: string;
+  taskId: string;
+  label: string;
+  branch: string;
+  headCommit: string;
+  stateCommit: string;
+  dirty: boolean;
+  createdAt: string;
+}
+
 export interface Label {
   id: string;
   projectId: string;
@@ -109,19 +292,32 @@ export interface Label {
   color: string;
 }
 
+export interface Attachment {
+  id: string;
+  taskId: string;
+  filename: string;
+  contentType: string;
+  size: number;
+  createdAt: string;
+}
+
 export interface Task {
   id: string;
   projectId: string;
   title: string;
   description?: string;
-  status: TaskStatus;
+  status: TaskStatus; // or a custom column's id
+  category?: TaskStatus; // the built-in status the task's column counts as
   taskType: string;
-  priority?: string;
+  priority?: string; // urgent, high, medium or low (P0 to P3)
+  estimateMinutes?: number;
   branch?: string;
+  baseBranch?: string; // set when retargeted off the project's default branch
   worktreePath?: string;
   prUrl?: string;
   pinned: boolean;
   position: number;
+  version: number; // bumped on every change
   labels: Label[];
   diffStats?: DiffStats;
   createdAt: string;
@@ -134,7 +330,8 @@ export interface CreateTaskInput {
   title: string;
   description?: string;
   taskType?: string;
-  priority?: string;
+  priority?: string; // also accepts p0 to p3
+  estimateMinutes?: number;
   branch?: string;
 }
 
@@ -143,15 +340,24 @@ export interface UpdateTaskInput {
   description?: string;
   status?: TaskStatus;
   taskType?: string;
-  priority?: string;
+  priority?: string; // '' clears it
+  estimateMinutes?: number; // 0 clears it
   branch?: string;
   worktreePath?: string;
   prUrl?: string;
   pinned?: boolean;
   position?: number;
+  version?: number; // the version last read; a changed task answers 409
   archived?: boolean;
 }
 
+export interface ReorderTaskInput {
+  status?: TaskStatus; // defaults to the task's column
+  index: number; // among the column's unarchived tasks of the task's project
+  allProjects?: boolean; // count every project's tasks instead
+  version?: number;
+}
+
 export interface UpdateTaskResponse extends Task {
   workflowAction?: string;
   sessionStarted?: string;
@@ -204,6 +410,7 @@ export interface AuthStatus {
   setup: boolean;
   hasPasskeys: boolean;
   hasTelegram: boolean;
+  forwardAuth?: boolean; // a trusted proxy logged the user in
 }
 
 export interface PasskeyInfo {
@@ -216,3 +423,21 @@ export interface PasskeyInfo {
 export interface AuthToken {
   token: string;
 }
+
+export interface LoginLinkToken extends AuthToken {
+  path: string;
+}
+
+export interface DeepLink {
+  url: string;
+  expiresAt?: string;
+  qr?: string;
+}
+
+export interface CreateDeepLinkInput {
+  taskId?: string;
+  sessionId?: string;
+  path?: string;
+  login?: boolean;
+  qr?: boolean;
+}
@@ -0,0 +1,58 @@
+import { api } from './client';
+
+export type WebhookEvent = 'task.status_changed' | 'session.waiting_input' | 'session.completed' | 'git.pushed';
+
+export interface Webhook {
+  id: string;
+  name: string;
+  url: string;
+  events: WebhookEvent[]; // empty: every event
+  projectId?: string;
+  enabled: boolean;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface WebhookInput {
+  name?: string;
+  url?: string;
+  secret?: string; // generated on create when omitted
+  events?: WebhookEvent[];
+  projectId?: string; // '' removes the project filter
+  enabled?: boolean;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  webhookId: string;
+  event: WebhookEvent | 'ping';
+  payload: unknown;
+  status: 'pending' | 'delivered' | 'failed';
+  attempts: number;
+  nextAttemptAt?: string;
+  responseStatus?: number;
+  lastError?: string;
+  createdAt: string;
+  deliveredAt?: string;
+}
+
+export const webhooksApi = {
+  list: () => api.get<Webhook[]>('/webhooks'),
+
+  // The response carries the signing secret, shown only this once
+  create: (input: WebhookInput & { url: string }) =>
+    api.post<Webhook & { secret: string }>('/webhooks', input),
+
+  update: (id: string, input: WebhookInput) => api.patch<Webhook>(`/webhooks/${id}`, input),
+
+  delete: (id: string) => api.delete(`/webhooks/${id}`),
+
+  deliveries: (id: string, limit?: number) =>
+    api.get<WebhookDelivery[]>(`/webhooks/${id}/deliveries${limit ? `?limit=${limit}` : ''}`),
+
+  // Queue a ping for the webhook
+  test: (id: string) => api.post<WebhookDelivery>(`/webhooks/${id}/test`),
+
+  redeliver: (id: string, deliveryId: string) =>
+    api.post<WebhookDelivery>(`/webhooks/${id}/deliveries/${deliveryId}/redeliver`),
+};
@@ -97,6 +97,38 @@ export interface FileSearchMatch {
 export interface FileSearchResult {
   file: string;
   matches: FileSearchMatch[];
+  truncated?: boolean;
+}
+
+export interface FileSearchOptions {
+  regex?: boolean;
+  caseSensitive?: boolean;
+  maxResults?: number;
+  maxPerFile?: number;
+  include?: string[];
+  exclude?: string[];
+}
+
+export interface FileReplaceLine {
+  line: number;
+  before: string;
+  after: string;
+}
+
+export interface FileReplaceResponse {
+  dryRun: boolean;
+  files: { file: string; replacements: number; lines: FileReplaceLine[] }[];
+  totalFiles: number;
+  replacements: number;
+}
+
+export interface FileReplaceInput {
+  search: string;
+  replace: string;
+  regex?: boolean;
+  caseSensitive?: boolean;
+  paths?: string[];
+  dryRun?: boolean;
 }
 
 export function createFilesApi(type: WorkspaceScopeType, id: string) {
@@ -118,6 +150,22 @@ export function createFilesApi(type: WorkspaceScopeType, id: string) {
     write: (path: string, content: string) =>
       api.put<FileReadResponse>(`${prefix}/file`, { path, content }),
 
+    // Raw bytes, for binary files the JSON read can't carry
+    download: (path: string) => {
+      const params = new URLSearchParams({ path });
+      return api.blob(`${prefix}/file/raw?${params}`);
+    },
+
+    upload: (files: File[], dir?: string, overwrite?: boolean) => {
+      const params = new URLSearchParams();
+      if (dir) params.set('dir', dir);
+      if (overwrite) params.set('overwrite', 'true');
+      const form = new FormData();
+      files.forEach((file) => form.append('file', file));
+      const qs = params.toString();
+      return api.upload<{ files: FileEntry[] }>(`${prefix}/files/upload${qs ? `?${qs}` : ''}`, form);
+    },
+
     create: (path: string, type: 'file' | 'dir') =>
       api.post(`${prefix}/files`, { path, type }),
 
@@ -132,8 +180,12 @@ export function createFilesApi(type: WorkspaceScopeType, id: string) {
     duplicate: (path: string) =>
       api.post<FileEntry>(`${prefix}/file/duplicate`, { path }),
 
-    search: (query: string, opts?: { regex?: boolean; caseSensitive?: boolean; maxResults?: number }) =>
-      api.post<{ results: FileSearchResult[] }>(`${prefix}/files/search`, { query, ...opts }),
+    search: (query: string, opts?: FileSearchOptions) =>
+      api.post<{ results: FileSearchResult[]; truncated?: boolean }>(`${prefix}/files/search`, { query, ...opts }),
+
+    // Replace across the tree; with dryRun, preview the changes only
+    replace: (input: FileReplaceInput) =>
+      api.post<FileReplaceResponse>(`${prefix}/files/replace`, input),
   };
 }
 
@@ -30,6 +30,10 @@ vi.mock('./ToolCallCard', () => ({
   ToolCallCard: () => <div>tool</div>,
 }));
 
+vi.mock('./SessionBookmarks', () => ({
+  SessionBookmarks: () => null,
+}));
+
 function makeSession(status: AgentSession['status'], id = 'session-1'): AgentSession {
   return {
     id,
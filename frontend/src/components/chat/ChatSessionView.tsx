@@ -6,6 +6,8 @@ import { createFilesApi, type FileEntry } from '../../api/workspace';
 import { useChatSession } from '../../hooks/useChatSession';
 import { MarkdownRenderer } from '../ui/MarkdownRenderer';
 import { ToolCallCard } from './ToolCallCard';
+import { PermissionRequestCard } from './PermissionRequestCard';
+import { SessionBookmarks } from './SessionBookmarks';
 import { useMobile } from '../../hooks/useMobile';
 import { useVirtualKeyboard } from '../../hooks/useVirtualKeyboard';
 import { useChatDraftStore } from '../../stores/chatDrafts';
@@ -80,6 +82,10 @@ function shouldRenderMessage(message: ChatMessage): boolean {
     return true;
   }
 
+  if (message.kind === 'permission-request') {
+    return !!message.permission;
+  }
+
   if (message.kind === 'tool-call') {
     if (message.data?.hidden === true) return false;
     return true;
@@ -208,6 +214,10 @@ function MessageItem({ message, nextKind }: MessageItemProps) {
     );
   }
 
+  if (message.kind === 'permission-request' && message.permission && message.sessionId) {
+    return <PermissionRequestCard sessionId={message.sessionId} permission={message.permission} />;
+  }
+
   const isErrorLike = message.kind === 'result' || message.kind === 'system';
   return (
     <div className="flex justify-center">
@@ -447,6 +457,19 @@ export function ChatSessionView({ session, onResume }: ChatSessionViewProps) {
     setShowJumpToLatest(false);
   }, []);
 
+  // Bookmarks mark a message seq; jump to the last visible message at or
+  // before it, since the marked one may be hidden.
+  const jumpToSeq = useCallback((seq: number) => {
+    const node = listRef.current;
+    if (!node) return;
+    const target = Array.from(node.querySelectorAll<HTMLElement>('[data-seq]'))
+      .filter((el) => Number(el.dataset.seq) <= seq)
+      .pop();
+    if (!target) return;
+    autoStickRef.current = false;
+    target.scrollIntoView({ block: 'center', behavior: 'smooth' });
+  }, []);
+
   useEffect(() => {
     const node = listRef.current;
     if (!node) return;
@@ -539,6 +562,7 @@ export function ChatSessionView({ session, onResume }: ChatSessionViewProps) {
 
   return (
     <div className="flex h-full min-h-0 flex-col bg-primary">
+      <SessionBookmarks sessionId={session.id} onJump={jumpToSeq} />
       <div className="relative flex-1 min-h-0">
         <div
           ref={listRef}
@@ -554,11 +578,12 @@ export function ChatSessionView({ session, onResume }: ChatSessionViewProps) {
             </div>
           ) : (
             visibleMessages.map((message, index) => (
-              <MessageItem
-                key={message.id}
-                message={message}
-                nextKind={visibleMessages[index + 1]?.kind}
-              />
+              <div key={message.id} data-seq={message.seq}>
+                <MessageItem
+                  message={message}
+                  nextKind={visibleMessages[index + 1]?.kind}
+                />
+              </div>
             ))
           )}
           {showPendingAssistant && <PendingAssistantRow providerLabel={providerLabel} />}
@@ -0,0 +1,87 @@
+import { useState } from 'react';
+import { ShieldAlert } from 'lucide-react';
+import type { ChatPermissionRequest } from '../../api/chat';
+import { sessionsApi } from '../../api/sessions';
+
+interface PermissionRequestCardProps {
+  sessionId: string;
+  permission: ChatPermissionRequest;
+}
+
+function inputPreview(input: unknown): string {
+  if (input == null) return '';
+  if (typeof input === 'object' && !Array.isArray(input)) {
+    const fields = input as Record<string, unknown>;
+    if (typeof fields.command === 'string') return fields.command;
+    if (typeof fields.file_path === 'string') return fields.file_path;
+  }
+  try {
+    return JSON.stringify(input, null, 2);
+  } catch {
+    return String(input);
+  }
+}
+
+const STATUS_LABELS: Record<ChatPermissionRequest['status'], string> = {
+  pending: 'Waiting for approval',
+  allowed: 'Allowed',
+  denied: 'Denied',
+  expired: 'Expired',
+};
+
+export function PermissionRequestCard({ sessionId, permission }: PermissionRequestCardProps) {
+  const [busy, setBusy] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+  const preview = inputPreview(permission.input);
+  const pending = permission.status === 'pending';
+
+  // The resolved message arrives over the session's websocket.
+  const respond = async (decision: 'allow' | 'deny') => {
+    setBusy(true);
+    setError(null);
+    try {
+      await sessionsApi.respondPermission(sessionId, permission.requestId, decision);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to answer');
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  return (
+    <div className="rounded-lg border border-amber-500/40 bg-amber-500/5 px-3 py-2 text-xs">
+      <div className="flex items-center gap-2">
+        <ShieldAlert size={14} className="text-amber-500 shrink-0" />
+        <span className="font-medium">Use {permission.toolName}?</span>
+        <span className="ml-auto text-[10px] text-dim">{STATUS_LABELS[permission.status]}</span>
+      </div>
+      {preview && (
+        <pre className="mt-2 max-h-40 overflow-auto whitespace-pre-wrap break-all rounded bg-secondary px-2 py-1 font-mono text-[11px]">{preview}</pre>
+      )}
+      {permission.status === 'denied' && permission.message && (
+        <div className="mt-1 text-[11px] text-dim">{permission.message}</div>
+      )}
+      {pending && (
+        <div className="mt-2 flex items-center gap-2">
+          <button
+            type="button"
+            disabled={busy}
+            onClick={() => respond('allow')}
+            className="rounded-md bg-accent px-2.5 py-1 text-[11px] font-medium text-white disabled:opacity-50"
+          >
+            Allow
+          </button>
+          <button
+            type="button"
+            disabled={busy}
+            onClick={() => respond('deny')}
+            className="rounded-md border border-subtle px-2.5 py-1 text-[11px] disabled:opacity-50"
+          >
+            Deny
+          </button>
+          {error && <span className="text-[11px] text-[var(--color-error)]">{error}</span>}
+        </div>
+      )}
+    </div>
+  );
+}
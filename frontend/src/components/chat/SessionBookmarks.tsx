@@ -0,0 +1,85 @@
+import { useCallback, useEffect, useState } from 'react';
+import { Bookmark as BookmarkIcon, BookmarkPlus, X } from 'lucide-react';
+import { bookmarksApi, type Bookmark } from '../../api/bookmarks';
+
+interface SessionBookmarksProps {
+  sessionId: string;
+  onJump: (seq: number) => void;
+}
+
+export function SessionBookmarks({ sessionId, onJump }: SessionBookmarksProps) {
+  const [bookmarks, setBookmarks] = useState<Bookmark[]>([]);
+  const [error, setError] = useState<string | null>(null);
+
+  const load = useCallback(async () => {
+    try {
+      setBookmarks(await bookmarksApi.list(sessionId));
+    } catch {
+      setBookmarks([]);
+    }
+  }, [sessionId]);
+
+  useEffect(() => {
+    void load();
+  }, [load]);
+
+  const add = async () => {
+    const label = window.prompt('Bookmark label')?.trim();
+    if (!label) return;
+    setError(null);
+    try {
+      await bookmarksApi.create(sessionId, label);
+      await load();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to add bookmark');
+    }
+  };
+
+  const remove = async (id: string) => {
+    try {
+      await bookmarksApi.delete(id);
+      setBookmarks((current) => current.filter((b) => b.id !== id));
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to delete bookmark');
+    }
+  };
+
+  return (
+    <div className="flex items-center gap-1.5 overflow-x-auto border-b border-subtle px-3 py-1.5 text-[11px]">
+      <button
+        type="button"
+        onClick={() => void add()}
+        className="inline-flex shrink-0 items-center gap-1 rounded-md px-1.5 py-0.5 text-dim hover:text-[var(--color-text-primary)]"
+        title="Bookmark this point"
+      >
+        <BookmarkPlus size={12} />
+        {bookmarks.length === 0 && 'Bookmark'}
+      </button>
+      {bookmarks.map((bookmark) => (
+        <span
+          key={bookmark.id}
+          className="inline-flex shrink-0 items-center gap-1 rounded-full border border-subtle bg-secondary pl-2 pr-1 py-0.5"
+        >
+          <button
+            type="button"
+            onClick={() => onJump(bookmark.seq)}
+            className="inline-flex items-center gap-1 hover:text-[var(--color-text-primary)]"
+            title={bookmark.gitHead ? `At ${bookmark.gitHead.slice(0, 7)}` : undefined}
+          >
+            <BookmarkIcon size={10} />
+            {bookmark.label}
+          </button>
+          <button
+            type="button"
+            onClick={() => void remove(bookmark.id)}
+            className="text-dim hover:text-[var(--color-error)]"
+            title="Remove bookmark"
+          >
+            <X size={10} />
+          </button>
+        </span>
+      ))}
+      {error && <span className="shrink-0 text-[var(--color-error)]">{error}</span>}
+    </div>
+  );
+}
@@ -1,3 +1,4 @@
 export { ChatSessionView } from './ChatSessionView';
 export { ToolCallCard } from './ToolCallCard';
 
+export { PermissionRequestCard } from './PermissionRequestCard';
@@ -1,21 +1,20 @@
-import { useState, useEffect } from 'react';
+import { useState, useEffect, useCallback } from 'react';
 import { useMutation, useQueryClient } from '@tanstack/react-query';
 import { Download, Plus, Lock, Globe, AlertCircle } from 'lucide-react';
-import { projectsApi } from '../../api';
-import type { CreateProjectInput } from '../../api';
+import { jobsApi, projectsApi } from '../../api';
+import type { CreateProjectInput, Job } from '../../api';
+import { useSharedWebSocket } from '../../hooks/useSharedWebSocket';
 
-function isGitHubURL(s: string): boolean {
+// Remote URLs (GitHub, GitLab, Gitea); the server rejects unsupported hosts.
+function isGitURL(s: string): boolean {
   const trimmed = s.trim();
-  return trimmed.startsWith('https://github.com/') ||
-    trimmed.startsWith('http://github.com/') ||
-    trimmed.startsWith('git@github.com:');
+  return trimmed.startsWith('https://') ||
+    trimmed.startsWith('http://') ||
+    /^git@[^:]+:/.test(trimmed);
 }
 
 function parseRepoName(url: string): string {
-  let cleaned = url.trim().replace(/\/+$/, '').replace(/\.git$/, '');
-  if (cleaned.startsWith('git@github.com:')) {
-    cleaned = cleaned.replace('git@github.com:', '');
-  }
+  const cleaned = url.trim().replace(/\/+$/, '').replace(/\.git$/, '').replace(/^git@[^:]+:/, '');
   const parts = cleaned.split('/');
   return parts[parts.length - 1] || '';
 }
@@ -50,14 +49,16 @@ export function CreateProjectModal({ onClose }: CreateProjectModalProps) {
   const [repoPrivate, setRepoPrivate] = useState(true);
 
   const [error, setError] = useState('');
+  // A clone runs as a job; its progress arrives as job_updated events.
+  const [cloneJob, setCloneJob] = useState<Job | null>(null);
   const queryClient = useQueryClient();
 
-  const isClone = isGitHubURL(source);
+  const isClone = isGitURL(source);
 
   const handleSourceChange = (value: string) => {
     setSource(value);
     if (!nameManuallyEdited) {
-      if (isGitHubURL(value)) {
+      if (isGitURL(value)) {
         setName(parseRepoName(value));
       } else if (value.includes('/')) {
         setName(parseDirName(value));
@@ -70,18 +71,54 @@ export function CreateProjectModal({ onClose }: CreateProjectModalProps) {
     setError('');
   };
 
+  const finishCreate = useCallback(() => {
+    queryClient.invalidateQueries({ queryKey: ['projects'] });
+    queryClient.invalidateQueries({ queryKey: ['sidebar'] });
+    onClose();
+  }, [queryClient, onClose]);
+
   const createMutation = useMutation({
     mutationFn: (input: CreateProjectInput) => projectsApi.create(input),
-    onSuccess: () => {
-      queryClient.invalidateQueries({ queryKey: ['projects'] });
-      queryClient.invalidateQueries({ queryKey: ['sidebar'] });
-      onClose();
-    },
+    onSuccess: finishCreate,
     onError: (err) => {
       setError(err instanceof Error ? err.message : 'Failed to create project');
     },
   });
 
+  const cloneMutation = useMutation({
+    mutationFn: (input: CreateProjectInput & { githubUrl: string }) => projectsApi.clone(input),
+    onSuccess: (job) => setCloneJob(job),
+    onError: (err) => {
+      setError(err instanceof Error ? err.message : 'Failed to clone project');
+    },
+  });
+
+  const cloneJobId = cloneJob?.id;
+  useSharedWebSocket({
+    onMessage: useCallback((data: unknown) => {
+      const msg = data as { type?: string; data?: Job };
+      if (msg.type !== 'job_updated' || !msg.data || msg.data.id !== cloneJobId) return;
+      const job = msg.data;
+      if (job.status === 'succeeded') {
+        finishCreate();
+      } else if (job.status === 'failed' || job.status === 'cancelled') {
+        setCloneJob(null);
+        if (job.status === 'failed') setError(job.lastError || 'Clone failed');
+      } else {
+        setCloneJob(job);
+      }
+    }, [cloneJobId, finishCreate]),
+  });
+
+  const handleCancel = () => {
+    if (cloneJob) {
+      jobsApi.cancel(cloneJob.id).catch(() => {});
+      setCloneJob(null);
+      return;
+    }
+    onClose();
+  };
+
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault();
     setError('');
@@ -93,7 +130,7 @@ export function CreateProjectModal({ onClose }: CreateProjectModalProps) {
         private: repoPrivate,
       });
     } else if (isClone) {
-      createMutation.mutate({ name, githubUrl: source });
+      cloneMutation.mutate({ name, githubUrl: source });
     } else {
       createMutation.mutate({ name, path: source });
     }
@@ -102,6 +139,7 @@ export function CreateProjectModal({ onClose }: CreateProjectModalProps) {
   const loadingText = mode === 'create'
     ? 'Creating repo...'
     : isClone ? 'Cloning...' : 'Creating...';
+  const busy = createMutation.isPending || cloneMutation.isPending || cloneJob !== null;
 
   return (
     <div className="fixed inset-0 bg-[var(--color-bg-primary)]/60 backdrop-blur-sm flex items-center justify-center p-4 z-50">
@@ -149,13 +187,13 @@ export function CreateProjectModal({ onClose }: CreateProjectModalProps) {
           {mode === 'import' ? (
             <>
               <div>
-                <label className="block text-sm text-dim mb-1">Path or GitHub URL</label>
+                <label className="block text-sm text-dim mb-1">Path or Git URL</label>
                 <input
                   type="text"
                   value={source}
                   onChange={(e) => handleSourceChange(e.target.value)}
                   className="block w-full px-3 py-2 border border-subtle bg-primary text-[var(--color-text-primary)] rounded-md focus:outline-none focus:border-[var(--color-text-secondary)]"
-                  placeholder="https://github.com/user/repo or /path/to/project"
+                  placeholder="https://github.com/user/repo, GitLab/Gitea URL, or /path/to/project"
                   required
                 />
                 {isClone && name && (
@@ -240,17 +278,17 @@ export function CreateProjectModal({ onClose }: CreateProjectModalProps) {
           <div className="flex gap-2 pt-2">
             <button
               type="button"
-              onClick={onClose}
+              onClick={handleCancel}
               className="flex-1 py-2 px-4 bg-tertiary text-[var(--color-text-secondary)] rounded-md text-sm hover:bg-[var(--color-border)] transition-colors"
             >
-              Cancel
+              {cloneJob ? 'Stop clone' : 'Cancel'}
             </button>
             <button
               type="submit"
-              disabled={createMutation.isPending}
-              className="flex-1 py-2 px-4 bg-accent text-white rounded-md font-medium text-sm hover:bg-accent-dim transition-colors disabled:opacity-50"
+              disabled={busy}
+              className="flex-1 py-2 px-4 bg-accent text-white rounded-md font-medium text-sm hover:bg-accent-dim transition-colors disabled:opacity-50 truncate"
             >
-              {createMutation.isPending ? loadingText : 'Create'}
+              {busy ? (cloneJob?.progress || loadingText) : 'Create'}
             </button>
           </div>
         </form>
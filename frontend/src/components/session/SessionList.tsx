@@ -32,14 +32,20 @@ export function SessionList({ sessions, activeSessionId, onSelect, onResume }: S
           }`}
         >
           <div className="flex items-center justify-between">
-            <span className="text-sm font-mono">
-              {session.id.slice(0, 8)}...
-            </span>
+            {session.title ? (
+              <span className="text-sm truncate mr-2" title={session.title}>
+                {session.title}
+              </span>
+            ) : (
+              <span className="text-sm font-mono">
+                {session.id.slice(0, 8)}...
+              </span>
+            )}
             <SessionStatusBadge status={session.status} />
           </div>
           <div className="flex items-center justify-between mt-1">
             <span className="text-xs text-dim">
-              {session.provider} · {formatDate(session.createdAt)}
+              {session.provider}{session.readOnly && ' · ask'} · {formatDate(session.createdAt)}
             </span>
             {onResume && session.sessionType === 'chat' && session.status === 'completed' && (
               <button
@@ -231,6 +231,9 @@ function TunnelsSection({ taskId }: { taskId: string }) {
               {suggestions.map((suggestion) => (
                 <div key={suggestion.port} className="flex items-center gap-2">
                   <span className="font-mono text-accent">:{suggestion.port}</span>
+                  {suggestion.services && suggestion.services.length > 0 && (
+                    <span className="text-[10px] text-[var(--color-text-secondary)]">{suggestion.services.join(', ')}</span>
+                  )}
                   <span className="text-[10px] text-dim">
                     {suggestion.sources.join(' + ')}
                   </span>
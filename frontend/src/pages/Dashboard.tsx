@@ -337,7 +337,8 @@ export function Dashboard({ panelOpen = false }: DashboardProps) {
       map.set(col.id, []);
     }
     for (const task of filteredTasks) {
-      const list = map.get(task.status);
+      // Tasks in a project's custom columns go under their category.
+      const list = map.get(task.category ?? task.status);
       if (list) list.push(task);
     }
     return map;
@@ -353,7 +354,7 @@ export function Dashboard({ panelOpen = false }: DashboardProps) {
     return [...filteredTasks].sort((a, b) => {
       const pinnedDelta = Number(b.pinned) - Number(a.pinned);
       if (pinnedDelta !== 0) return pinnedDelta;
-      const rankDelta = (statusRank.get(a.status) ?? 0) - (statusRank.get(b.status) ?? 0);
+      const rankDelta = (statusRank.get(a.category ?? a.status) ?? 0) - (statusRank.get(b.category ?? b.status) ?? 0);
       if (rankDelta !== 0) return rankDelta;
       if (a.position !== b.position) return a.position - b.position;
       return a.title.localeCompare(b.title);
@@ -3,7 +3,7 @@ import { useParams } from 'react-router-dom';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { AlertTriangle, Archive, ArrowRight, CheckCircle2, Eye, EyeOff, KeyRound, Maximize2, Minimize2, Settings, Trash2, X, Zap } from 'lucide-react';
 import { useSetHeader } from '../components/layout/Header';
-import { projectsApi } from '../api';
+import { projectsApi, providersApi } from '../api';
 import { usePanelNavigation } from '../hooks/usePanelNavigation';
 import { useMobile } from '../hooks/useMobile';
 import type { Project, ProjectWorkflow, BacklogToProgressConfig, ProgressToReviewConfig, ReviewToDoneConfig } from '../api';
@@ -282,6 +282,12 @@ function WorkflowSection({ project }: { project: Project }) {
   const [dirty, setDirty] = useState(false);
   const [saved, setSaved] = useState(false);
 
+  const { data: providerModels } = useQuery({
+    queryKey: ['provider-models'],
+    queryFn: () => providersApi.models(),
+    staleTime: 5 * 60 * 1000,
+  });
+
   useEffect(() => {
     const timer = setTimeout(() => {
       setWorkflow(project.workflow ?? {});
@@ -364,7 +370,15 @@ function WorkflowSection({ project }: { project: Project }) {
                     onChange={(e) => updateBacklogToProgress({ defaultModel: e.target.value || undefined })}
                     className={inputClass}
                     placeholder="e.g. claude-sonnet-4-5-20250929"
+                    list="workflow-default-models"
                   />
+                  <datalist id="workflow-default-models">
+                    {providerModels
+                      ?.find((p) => p.provider === (b2p.action === 'auto_codex' ? 'codex' : 'claude'))
+                      ?.models.map((m) => (
+                        <option key={m.id} value={m.id}>{m.name ?? m.id}</option>
+                      ))}
+                  </datalist>
                   <p className="text-xs text-dim mt-1.5">Leave empty for the provider's default</p>
                 </div>
               )}
@@ -505,7 +519,7 @@ function DangerSection({ project }: { project: Project }) {
   });
 
   const archiveMutation = useMutation({
-    mutationFn: () => projectsApi.archive(project.id),
+    mutationFn: () => projectsApi.exportArchive(project.id),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: ['projects'] });
       queryClient.invalidateQueries({ queryKey: ['sidebar'] });
@@ -13,7 +13,7 @@ export function ArchivesSection() {
   });
 
   const restoreMutation = useMutation({
-    mutationFn: (filename: string) => projectsApi.unarchive(filename),
+    mutationFn: (filename: string) => projectsApi.restoreArchive(filename),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: ['archives'] });
       queryClient.invalidateQueries({ queryKey: ['projects'] });
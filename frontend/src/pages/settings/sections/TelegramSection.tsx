@@ -8,6 +8,7 @@ import { SectionBody, SectionCard, SectionHeader } from '../../../components/ui/
 export function TelegramSection() {
   const [botToken, setBotToken] = useState('');
   const [telegramId, setTelegramId] = useState('');
+  const [language, setLanguage] = useState('');
   const [showSetup, setShowSetup] = useState(false);
   const [saved, setSaved] = useState(false);
   const [error, setError] = useState('');
@@ -30,6 +31,15 @@ export function TelegramSection() {
       .catch(() => {
         // Not set yet.
       });
+
+    preferencesApi
+      .get<string>('telegram_language')
+      .then((val) => {
+        if (val) setLanguage(String(val));
+      })
+      .catch(() => {
+        // Not set yet.
+      });
   }, []);
 
   const saveMutation = useMutation({
@@ -46,6 +56,12 @@ export function TelegramSection() {
         await preferencesApi.delete('telegram_user_id').catch(() => {});
       }
 
+      if (language.trim()) {
+        await preferencesApi.set('telegram_language', language.trim());
+      } else {
+        await preferencesApi.delete('telegram_language').catch(() => {});
+      }
+
       await authApi.restartTelegramBot();
     },
     onSuccess: () => {
@@ -143,6 +159,20 @@ export function TelegramSection() {
             <p className="text-xs text-dim mt-1.5">Only this user will be able to log in via Telegram</p>
           </div>
 
+          <div>
+            <label className="block text-sm text-dim mb-1.5">Message Language</label>
+            <input
+              type="text"
+              value={language}
+              onChange={(e) => setLanguage(e.target.value)}
+              className={inputClass}
+              placeholder="es"
+            />
+            <p className="text-xs text-dim mt-1.5">
+              Language code for notifications sent to this chat. Defaults to the <code>language</code> preference.
+            </p>
+          </div>
+
           <Button
             variant="primary"
             size="md"
@@ -8,6 +8,7 @@ vi.mock('../api', () => ({
     me: vi.fn(),
     login: vi.fn(),
     setup: vi.fn(),
+    loginLink: vi.fn(),
   },
 }));
 
@@ -98,6 +99,21 @@ describe('Auth Store', () => {
     expect(mockedAuthApi.me).toHaveBeenCalled();
   });
 
+  it('checkStatus exchanges a deep link login token', async () => {
+    window.history.replaceState(null, '', '/tasks/t1?session=s1&login_token=one-time');
+    mockedAuthApi.getStatus.mockResolvedValue({ setup: true, hasPasskeys: false, hasTelegram: false });
+    mockedAuthApi.loginLink.mockResolvedValue({ token: 'link-token', path: '/tasks/t1?session=s1' });
+
+    await useAuthStore.getState().checkStatus();
+
+    const state = useAuthStore.getState();
+    expect(mockedAuthApi.loginLink).toHaveBeenCalledWith('one-time');
+    expect(state.isAuthenticated).toBe(true);
+    expect(localStorage.getItem('token')).toBe('link-token');
+    expect(window.location.pathname).toBe('/tasks/t1');
+    expect(window.location.search).toBe('?session=s1');
+  });
+
   it('checkStatus clears invalid token', async () => {
     localStorage.setItem('token', 'expired-token');
     mockedAuthApi.getStatus.mockResolvedValue({ setup: true, hasPasskeys: false, hasTelegram: false });
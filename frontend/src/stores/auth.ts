@@ -30,6 +30,18 @@ interface AuthState {
   logout: () => void;
 }
 
+// takeLoginToken returns the one-time login token a deep link carries and
+// removes it from the address bar, leaving the screen the link opens.
+function takeLoginToken(): string | null {
+  const url = new URL(window.location.href);
+  const token = url.searchParams.get('login_token');
+  if (token) {
+    url.searchParams.delete('login_token');
+    window.history.replaceState(window.history.state, '', url.pathname + url.search + url.hash);
+  }
+  return token;
+}
+
 type StartAuthenticationOptionsJSON = Parameters<typeof startAuthentication>[0]['optionsJSON'];
 
 export const useAuthStore = create<AuthState>((set) => ({
@@ -41,6 +53,7 @@ export const useAuthStore = create<AuthState>((set) => ({
   token: getAuthToken(),
 
   checkStatus: async () => {
+    const loginToken = takeLoginToken();
     try {
       const status = await authApi.getStatus();
       set({
@@ -69,12 +82,24 @@ export const useAuthStore = create<AuthState>((set) => ({
         try {
           await authApi.me();
           set({ isAuthenticated: true });
+          return;
         } catch {
           // Token invalid, clear it
           clearAuthToken();
           set({ isAuthenticated: false, token: null });
         }
       }
+
+      // A deep link's one-time token logs in a device that isn't yet
+      if (loginToken && status.setup) {
+        try {
+          const { token: linkToken } = await authApi.loginLink(loginToken);
+          setAuthToken(linkToken);
+          set({ isAuthenticated: true, token: linkToken });
+        } catch {
+          // Expired or already used: show the login screen
+        }
+      }
     } catch {
       set({ isLoading: false });
     }